@@ -1556,6 +1556,7 @@ func (t *tester) registerRaceTests() {
 
 // cgoPackages is the standard packages that use cgo.
 var cgoPackages = []string{
+	"crypto/internal/backend/pkcs11",
 	"net",
 	"os/user",
 }
@@ -1133,6 +1133,8 @@ var hostobj []Hostobj
 // These packages can use internal linking mode.
 // Others trigger external mode.
 var internalpkg = []string{
+	"crypto/internal/backend/openssl",
+	"crypto/internal/backend/pkcs11",
 	"crypto/internal/boring",
 	"crypto/internal/boring/syso",
 	"crypto/x509",
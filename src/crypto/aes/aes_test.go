@@ -5,6 +5,9 @@
 package aes
 
 import (
+	"crypto/cipher"
+	"crypto/internal/boring"
+	"crypto/internal/difftest"
 	"testing"
 )
 
@@ -381,3 +384,43 @@ func BenchmarkExpand(b *testing.B) {
 		expandKey(tt.key, c.enc, c.dec)
 	}
 }
+
+func FuzzBackend(f *testing.F) {
+	if !boring.Enabled {
+		f.Skip("no cryptographic backend in use")
+	}
+	defer boring.AllowGoForTesting()()
+	f.Add([]byte("key"), []byte("abc"))
+	f.Add(make([]byte, 32), make([]byte, 1000))
+	f.Fuzz(func(t *testing.T, keyMaterial, data []byte) {
+		for _, n := range []int{16, 24, 32} {
+			key := make([]byte, n)
+			copy(key, keyMaterial)
+			want, err := newCipher(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewCipher(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := difftest.Block(want, got, data); err != nil {
+				t.Fatalf("AES-%d: %v", n*8, err)
+			}
+
+			wantGCM, err := cipher.NewGCM(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotGCM, err := cipher.NewGCM(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce := make([]byte, wantGCM.NonceSize())
+			copy(nonce, data)
+			if err := difftest.AEAD(wantGCM, gotGCM, nonce, data, keyMaterial); err != nil {
+				t.Fatalf("AES-%d-GCM: %v", n*8, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package age implements file encryption in the age v1 format, specified at
+// https://age-encryption.org/v1.
+//
+// A file is encrypted with a random file key, which is wrapped for each
+// recipient in a stanza of the file header. The header is authenticated
+// with a MAC keyed by the file key. The payload is encrypted with
+// ChaCha20-Poly1305 in chunks of 64 KiB, using the STREAM construction, so
+// that it can be decrypted incrementally, and truncation or reordering of
+// chunks is detected.
+//
+// X25519 recipients, encoded as "age1..." strings, and passphrase
+// recipients, using scrypt, are supported. Files are interoperable with
+// other implementations of the format. The ASCII armor and plugin
+// recipients are not supported.
+package age
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/internal/fips"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// A Recipient is a party that a file can be encrypted to.
+//
+// Implementations other than the ones in this package can be used to
+// produce custom stanzas, as long as a matching Identity is used to
+// decrypt.
+type Recipient interface {
+	// Wrap returns the stanzas that allow an identity matching the
+	// recipient to recover fileKey.
+	Wrap(fileKey []byte) ([]*Stanza, error)
+}
+
+// An Identity is a party that can decrypt files.
+type Identity interface {
+	// Unwrap returns the file key from the matching stanza of a header.
+	// If no stanza matches the identity, it returns ErrIncorrectIdentity.
+	// Any other error aborts decryption.
+	Unwrap(stanzas []*Stanza) (fileKey []byte, err error)
+}
+
+// A Stanza is a section of the file header, holding the file key wrapped
+// for one recipient.
+type Stanza struct {
+	// Type identifies the recipient type, such as "X25519".
+	Type string
+
+	// Args are the arguments of the stanza. Type and each of the Args must
+	// be non-empty and consist of printable ASCII characters, excluding
+	// space.
+	Args []string
+
+	// Body is the wrapped file key.
+	Body []byte
+}
+
+// ErrIncorrectIdentity is returned by Identity.Unwrap if no stanza matches
+// the identity, and by Decrypt if none of the identities match.
+var ErrIncorrectIdentity = errors.New("age: incorrect identity for recipient stanza")
+
+const fileKeySize = 16
+
+// Encrypt encrypts a file to one or more recipients. It writes the header
+// to dst, and returns a WriteCloser that encrypts the data written to it.
+// Close must be called to write the final chunk; it does not close dst.
+//
+// A passphrase recipient must be the only recipient of a file.
+//
+// The format uses ChaCha20-Poly1305, which is not approved in FIPS mode, so
+// Encrypt returns an error matching crypto/fips.ErrNotApproved if a
+// cryptographic module operates in FIPS mode and GODEBUG=fips140=only is
+// set.
+func Encrypt(dst io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("age: no recipients")
+	}
+	if err := fips.Check("ChaCha20-Poly1305"); err != nil {
+		return nil, err
+	}
+	fileKey := make([]byte, fileKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+
+	h := &header{}
+	for _, r := range recipients {
+		stanzas, err := r.Wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		h.stanzas = append(h.stanzas, stanzas...)
+	}
+	for _, s := range h.stanzas {
+		if s.Type == scryptType && len(h.stanzas) != 1 {
+			return nil, errors.New("age: a passphrase recipient must be the only recipient")
+		}
+	}
+	raw, err := h.marshalWithoutMAC()
+	if err != nil {
+		return nil, err
+	}
+	h.mac = headerMAC(fileKey, raw)
+	if _, err := dst.Write(h.appendMAC(raw)); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, streamNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(nonce); err != nil {
+		return nil, err
+	}
+	return newWriter(streamKey(fileKey, nonce), dst), nil
+}
+
+// Decrypt decrypts a file encrypted to one or more recipients. It reads
+// and authenticates the header from src, and returns a Reader that
+// decrypts the payload.
+//
+// Each chunk of the payload is authenticated before it is returned, but
+// the file may still be truncated or corrupted after it: the plaintext is
+// only complete once the Reader returns io.EOF.
+//
+// If none of the identities match the file, Decrypt returns
+// ErrIncorrectIdentity. Like Encrypt, it returns an error matching
+// crypto/fips.ErrNotApproved if only approved algorithms are allowed.
+func Decrypt(src io.Reader, identities ...Identity) (io.Reader, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("age: no identities")
+	}
+	if err := fips.Check("ChaCha20-Poly1305"); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(src)
+	h, raw, err := parseHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileKey []byte
+	for _, id := range identities {
+		fileKey, err = id.Unwrap(h.stanzas)
+		if errors.Is(err, ErrIncorrectIdentity) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if fileKey == nil {
+		return nil, ErrIncorrectIdentity
+	}
+	if len(fileKey) != fileKeySize {
+		return nil, fmt.Errorf("age: identity returned a file key of %d bytes", len(fileKey))
+	}
+	if !hmac.Equal(headerMAC(fileKey, raw), h.mac) {
+		return nil, errors.New("age: bad header MAC")
+	}
+
+	nonce := make([]byte, streamNonceSize)
+	if _, err := io.ReadFull(br, nonce); err != nil {
+		return nil, errTruncated
+	}
+	return newReader(streamKey(fileKey, nonce), br), nil
+}
+
+// headerMAC returns the MAC of the header, from its beginning through the
+// "---" of the last line.
+func headerMAC(fileKey, raw []byte) []byte {
+	h := hmac.New(sha256.New, hkdfKey(fileKey, nil, "header"))
+	h.Write(raw)
+	return h.Sum(nil)
+}
+
+// hkdfKey derives a 32-byte key with HKDF-SHA-256.
+func hkdfKey(secret, salt []byte, info string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		panic("age: internal error: " + err.Error())
+	}
+	return key
+}
+
+// newAEAD returns ChaCha20-Poly1305 keyed with key, whose operations are
+// reported as not approved by crypto/fips.Approved.
+func newAEAD(key []byte) cipher.AEAD {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		panic("age: internal error: " + err.Error())
+	}
+	return fips.AEAD(aead)
+}
+
+// wrapFileKey encrypts fileKey with ChaCha20-Poly1305 and an all-zero
+// nonce, which is safe because each wrapping key is used once.
+func wrapFileKey(key, fileKey []byte) []byte {
+	aead := newAEAD(key)
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	return aead.Seal(nil, nonce, fileKey, nil)
+}
+
+// unwrapFileKey reverses wrapFileKey. It returns ErrIncorrectIdentity if
+// the body can't be decrypted with key.
+func unwrapFileKey(key, body []byte) ([]byte, error) {
+	if len(body) != fileKeySize+chacha20poly1305.Overhead {
+		return nil, errors.New("age: invalid stanza body size")
+	}
+	aead := newAEAD(key)
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	fileKey, err := aead.Open(nil, nonce, body, nil)
+	if err != nil {
+		return nil, ErrIncorrectIdentity
+	}
+	return fileKey, nil
+}
@@ -0,0 +1,395 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func encrypt(t *testing.T, plaintext []byte, recipients ...Recipient) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := Encrypt(&buf, recipients...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(file []byte, identities ...Identity) ([]byte, error) {
+	r, err := Decrypt(bytes.NewReader(file), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestX25519RoundTrip(t *testing.T) {
+	id1, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 2 * chunkSize, 3*chunkSize + 5} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		file := encrypt(t, plaintext, id1.Recipient(), id2.Recipient())
+		if !bytes.HasPrefix(file, []byte(intro)) {
+			t.Fatalf("file does not start with the intro line")
+		}
+
+		for _, id := range []Identity{id1, id2} {
+			got, err := decrypt(file, other, id)
+			if err != nil {
+				t.Fatalf("size %d: %v", size, err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("size %d: decrypted plaintext does not match", size)
+			}
+		}
+		if _, err := decrypt(file, other); err != ErrIncorrectIdentity {
+			t.Errorf("size %d: decrypting with another identity = %v, want ErrIncorrectIdentity", size, err)
+		}
+	}
+}
+
+func TestSmallWrites(t *testing.T) {
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := make([]byte, 2*chunkSize+100)
+	rand.Read(plaintext)
+
+	var buf bytes.Buffer
+	w, err := Encrypt(&buf, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := plaintext; len(p) > 0; {
+		n := 1000
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("more")); err == nil {
+		t.Errorf("Write after Close succeeded")
+	}
+
+	r, err := Decrypt(&buf, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(bufio.NewReaderSize(r, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted plaintext does not match")
+	}
+}
+
+func TestScrypt(t *testing.T) {
+	r, err := NewScryptRecipient("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetWorkFactor(10)
+	file := encrypt(t, []byte("hello"), r)
+
+	id, err := NewScryptIdentity("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decrypt(file, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("decrypted %q, want %q", got, "hello")
+	}
+
+	wrong, err := NewScryptIdentity("incorrect horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decrypt(file, wrong); err != ErrIncorrectIdentity {
+		t.Errorf("decrypting with the wrong passphrase = %v, want ErrIncorrectIdentity", err)
+	}
+	id.SetMaxWorkFactor(9)
+	if _, err := decrypt(file, id); err == nil || err == ErrIncorrectIdentity {
+		t.Errorf("decrypting above the maximum work factor = %v, want an error", err)
+	}
+
+	x, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Encrypt(io.Discard, r, x.Recipient()); err == nil {
+		t.Errorf("Encrypt with a passphrase and another recipient succeeded")
+	}
+	if _, err := NewScryptRecipient(""); err == nil {
+		t.Errorf("NewScryptRecipient with an empty passphrase succeeded")
+	}
+}
+
+func TestTampering(t *testing.T) {
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := make([]byte, 2*chunkSize)
+	file := encrypt(t, plaintext, id.Recipient())
+	headerLen := bytes.Index(file, []byte("\n---")) + len("\n--- ") + 43 + 1
+	payloadStart := headerLen + streamNonceSize
+	if len(file) != payloadStart+2*encChunkSize {
+		t.Fatalf("unexpected file size %d", len(file))
+	}
+
+	for name, f := range map[string][]byte{
+		"truncated last byte":  file[:len(file)-1],
+		"truncated last chunk": file[:payloadStart+encChunkSize],
+		"truncated payload":    file[:payloadStart],
+		"truncated nonce":      file[:headerLen+5],
+		"truncated header":     file[:headerLen-1],
+		"appended data":        append(append([]byte{}, file...), 0),
+		"appended chunk":       append(append([]byte{}, file...), file[payloadStart:payloadStart+encChunkSize]...),
+		"swapped chunks": append(append(append([]byte{}, file[:payloadStart]...),
+			file[payloadStart+encChunkSize:]...), file[payloadStart:payloadStart+encChunkSize]...),
+	} {
+		if _, err := decrypt(f, id); err == nil {
+			t.Errorf("%s: decryption succeeded", name)
+		}
+	}
+
+	for i := 0; i < len(file); i += 7 {
+		f := append([]byte{}, file...)
+		f[i] ^= 0x04
+		if _, err := decrypt(f, id); err == nil {
+			t.Errorf("flipping a bit at offset %d went unnoticed", i)
+		}
+	}
+}
+
+func TestHeaderEncoding(t *testing.T) {
+	for _, bodySize := range []int{0, 1, 47, 48, 49, 96, 100} {
+		h := &header{stanzas: []*Stanza{
+			{Type: "test", Args: []string{"a", "b!"}, Body: bytes.Repeat([]byte{0xaa}, bodySize)},
+			{Type: "grease", Body: nil},
+		}}
+		raw, err := h.marshalWithoutMAC()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.mac = make([]byte, sha256Size)
+		encoded := h.appendMAC(raw)
+		lines := strings.Split(string(encoded), "\n")
+		for _, l := range lines {
+			if len(l) > bodyColumns && !strings.HasPrefix(l, "---") {
+				t.Errorf("line %q is too long", l)
+			}
+		}
+
+		got, gotRaw, err := parseHeader(bufio.NewReader(bytes.NewReader(append(encoded, "payload"...))))
+		if err != nil {
+			t.Fatalf("body size %d: %v\n%s", bodySize, err, encoded)
+		}
+		if !bytes.Equal(gotRaw, raw) {
+			t.Errorf("body size %d: parsed MAC input differs", bodySize)
+		}
+		if len(got.stanzas) != 2 || got.stanzas[0].Type != "test" ||
+			strings.Join(got.stanzas[0].Args, " ") != "a b!" ||
+			!bytes.Equal(got.stanzas[0].Body, h.stanzas[0].Body) || len(got.stanzas[1].Body) != 0 {
+			t.Errorf("body size %d: parsed stanzas differ", bodySize)
+		}
+	}
+
+	if _, err := (&header{stanzas: []*Stanza{{Type: "a b"}}}).marshalWithoutMAC(); err == nil {
+		t.Errorf("marshaling a type with a space succeeded")
+	}
+	if _, err := (&header{stanzas: []*Stanza{{Type: "a", Args: []string{""}}}}).marshalWithoutMAC(); err == nil {
+		t.Errorf("marshaling an empty argument succeeded")
+	}
+}
+
+func TestParseHeaderErrors(t *testing.T) {
+	const mac = "--- AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\n"
+	full := strings.Repeat("A", 64)
+	for _, h := range []string{
+		"",
+		"age-encryption.org/v2\n-> X\n\n" + mac,
+		"age-encryption.org/v1\n" + mac,
+		"age-encryption.org/v1\n-> X\n",
+		"age-encryption.org/v1\n-> X\n\n--- AAAA\n",
+		"age-encryption.org/v1\n-> X\n\n---AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\n",
+		"age-encryption.org/v1\n->  X\n\n" + mac,
+		"age-encryption.org/v1\n-> X \n\n" + mac,
+		"age-encryption.org/v1\n-> Xé\n\n" + mac,
+		"age-encryption.org/v1\n-> X\n" + full + "\n" + mac,
+		"age-encryption.org/v1\n-> X\n" + full + "A\n\n" + mac,
+		"age-encryption.org/v1\n-> X\nAAA=\n" + mac,
+		"age-encryption.org/v1\n-> X\nAB\n" + mac,
+		"age-encryption.org/v1\n-> X\nAA\r\n" + mac,
+		"age-encryption.org/v1\n-> X\n\n" + strings.Repeat("A", 5000) + "\n",
+		"age-encryption.org/v1\r\n-> X\n\n" + mac,
+	} {
+		if _, _, err := parseHeader(bufio.NewReader(strings.NewReader(h))); err == nil {
+			t.Errorf("parseHeader(%q) succeeded", h)
+		}
+	}
+}
+
+func TestX25519Encoding(t *testing.T) {
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := id.String()
+	if !strings.HasPrefix(s, "AGE-SECRET-KEY-1") || strings.ToUpper(s) != s {
+		t.Errorf("identity encoding %q", s)
+	}
+	id2, err := ParseX25519Identity(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id2.PrivateKey().Equal(id.PrivateKey()) {
+		t.Errorf("parsed identity differs")
+	}
+
+	r := id.Recipient().String()
+	if !strings.HasPrefix(r, "age1") || len(r) != 62 {
+		t.Errorf("recipient encoding %q", r)
+	}
+	rec, err := ParseX25519Recipient(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.PublicKey().Equal(id.PrivateKey().PublicKey()) {
+		t.Errorf("parsed recipient differs")
+	}
+
+	for _, bad := range []string{
+		strings.ToUpper(r),
+		corruptLast(r),
+		"age1",
+		corruptLast(r[:len(r)-1]) + r[len(r)-1:],
+	} {
+		if _, err := ParseX25519Recipient(bad); err == nil {
+			t.Errorf("ParseX25519Recipient(%q) succeeded", bad)
+		}
+	}
+	for _, bad := range []string{strings.ToLower(s), r, corruptLast(s)} {
+		if _, err := ParseX25519Identity(bad); err == nil {
+			t.Errorf("ParseX25519Identity(%q) succeeded", bad)
+		}
+	}
+}
+
+// corruptLast replaces the last character of a Bech32 string with a
+// different one of the same case, which breaks its checksum.
+func corruptLast(s string) string {
+	c := s[len(s)-1]
+	switch {
+	case c == 'q':
+		c = 'p'
+	case c == 'Q':
+		c = 'P'
+	case c >= 'A' && c <= 'Z':
+		c = 'Q'
+	default:
+		c = 'q'
+	}
+	return s[:len(s)-1] + string(c)
+}
+
+func TestBech32(t *testing.T) {
+	// Valid checksums from BIP 173.
+	for _, s := range []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	} {
+		if _, _, err := bech32Decode(s); err != nil {
+			t.Errorf("bech32Decode(%q): %v", s, err)
+		}
+	}
+	for _, n := range []int{0, 1, 5, 20, 32, 100} {
+		data := make([]byte, n)
+		rand.Read(data)
+		s, err := bech32Encode("test", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hrp, got, err := bech32Decode(s)
+		if err != nil || hrp != "test" || !bytes.Equal(got, data) {
+			t.Errorf("bech32Decode(%q) = %q, %x, %v; want %x", s, hrp, got, err, data)
+		}
+	}
+	for _, s := range []string{
+		"pzry9x0s0muk",
+		"1pzry9x0s0muk",
+		"x1b4n0q5v",
+		"li1dgmt3",
+		"A1G7SGD8",
+		"10a06t8",
+		"1qzzfhee",
+		"A12uEL5L",
+	} {
+		if _, _, err := bech32Decode(s); err == nil {
+			t.Errorf("bech32Decode(%q) succeeded", s)
+		}
+	}
+}
+
+type errIdentity struct{}
+
+func (errIdentity) Unwrap([]*Stanza) ([]byte, error) { return nil, errors.New("broken") }
+
+func TestIdentityErrors(t *testing.T) {
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := encrypt(t, []byte("hello"), id.Recipient())
+	if _, err := decrypt(file, errIdentity{}, id); err == nil || err.Error() != "broken" {
+		t.Errorf("decrypting with a failing identity = %v, want its error", err)
+	}
+	if _, err := decrypt(file); err == nil {
+		t.Errorf("decrypting without identities succeeded")
+	}
+	if _, err := Encrypt(io.Discard); err == nil {
+		t.Errorf("encrypting without recipients succeeded")
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const (
+	intro        = "age-encryption.org/v1\n"
+	stanzaPrefix = "-> "
+	footerPrefix = "---"
+
+	// bodyColumns is the length of the base64 lines of a stanza body.
+	bodyColumns = 64
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+var (
+	errMalformedHeader = errors.New("age: malformed header")
+	errTruncated       = errors.New("age: file is truncated")
+)
+
+type header struct {
+	stanzas []*Stanza
+	mac     []byte
+}
+
+// marshalWithoutMAC returns the encoding of h through the "---" of the
+// last line, which is the input of the header MAC.
+func (h *header) marshalWithoutMAC() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(intro)
+	for _, s := range h.stanzas {
+		if !isValidArg(s.Type) {
+			return nil, errors.New("age: invalid stanza type")
+		}
+		b.WriteString(stanzaPrefix)
+		b.WriteString(s.Type)
+		for _, a := range s.Args {
+			if !isValidArg(a) {
+				return nil, errors.New("age: invalid stanza argument")
+			}
+			b.WriteByte(' ')
+			b.WriteString(a)
+		}
+		b.WriteByte('\n')
+
+		// The body ends with a line shorter than a full line, which is
+		// empty if the body fills its last line.
+		body := b64.EncodeToString(s.Body)
+		for len(body) >= bodyColumns {
+			b.WriteString(body[:bodyColumns])
+			b.WriteByte('\n')
+			body = body[bodyColumns:]
+		}
+		b.WriteString(body)
+		b.WriteByte('\n')
+	}
+	b.WriteString(footerPrefix)
+	return b.Bytes(), nil
+}
+
+// appendMAC completes the output of marshalWithoutMAC.
+func (h *header) appendMAC(raw []byte) []byte {
+	raw = append(raw, ' ')
+	raw = append(raw, b64.EncodeToString(h.mac)...)
+	return append(raw, '\n')
+}
+
+// parseHeader reads a header from r, leaving r at the start of the
+// payload. It also returns the input of the header MAC.
+func parseHeader(r *bufio.Reader) (h *header, raw []byte, err error) {
+	h = &header{}
+	line, err := readLine(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if line+"\n" != intro {
+		return nil, nil, errors.New("age: unsupported format or version")
+	}
+	raw = append(raw, intro...)
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if mac, ok := strings.CutPrefix(line, footerPrefix+" "); ok {
+			if len(h.stanzas) == 0 {
+				return nil, nil, errMalformedHeader
+			}
+			if h.mac, err = decodeBase64(mac); err != nil || len(h.mac) != sha256Size {
+				return nil, nil, errMalformedHeader
+			}
+			raw = append(raw, footerPrefix...)
+			return h, raw, nil
+		}
+
+		args, ok := strings.CutPrefix(line, stanzaPrefix)
+		if !ok {
+			return nil, nil, errMalformedHeader
+		}
+		fields := strings.Split(args, " ")
+		for _, f := range fields {
+			if !isValidArg(f) {
+				return nil, nil, errMalformedHeader
+			}
+		}
+		s := &Stanza{Type: fields[0], Args: fields[1:], Body: []byte{}}
+		raw = append(raw, line...)
+		raw = append(raw, '\n')
+
+		for {
+			line, err := readLine(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(line) > bodyColumns {
+				return nil, nil, errMalformedHeader
+			}
+			b, err := decodeBase64(line)
+			if err != nil {
+				return nil, nil, errMalformedHeader
+			}
+			s.Body = append(s.Body, b...)
+			raw = append(raw, line...)
+			raw = append(raw, '\n')
+			if len(line) < bodyColumns {
+				break
+			}
+		}
+		h.stanzas = append(h.stanzas, s)
+	}
+}
+
+const sha256Size = 32
+
+// readLine returns the next line of r, without its newline. Lines longer
+// than the buffer of r are rejected.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	switch {
+	case err == bufio.ErrBufferFull:
+		return "", errors.New("age: header line too long")
+	case err != nil:
+		return "", errTruncated
+	}
+	return string(line[:len(line)-1]), nil
+}
+
+// decodeBase64 decodes canonical, unpadded base64. Unlike b64, it rejects
+// newlines.
+func decodeBase64(s string) ([]byte, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return nil, errors.New("age: unexpected newline")
+	}
+	return b64.DecodeString(s)
+}
+
+// isValidArg reports whether s is a valid stanza type or argument: a
+// non-empty string of printable ASCII characters, excluding space.
+func isValidArg(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
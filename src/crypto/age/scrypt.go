@@ -0,0 +1,150 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package age
+
+import (
+	"crypto/internal/scrypt"
+	"crypto/rand"
+	"errors"
+	"strconv"
+)
+
+const (
+	scryptType  = "scrypt"
+	scryptLabel = "age-encryption.org/v1/scrypt"
+
+	scryptSaltSize         = 16
+	defaultWorkFactor      = 18
+	defaultMaxWorkFactor   = 22
+	maxSupportedWorkFactor = 30
+	scryptR, scryptP       = 8, 1
+)
+
+// A ScryptRecipient encrypts a file with a passphrase. It must be the only
+// recipient of the file.
+type ScryptRecipient struct {
+	password   []byte
+	workFactor int
+}
+
+// NewScryptRecipient returns a recipient that encrypts with password, with
+// a default work factor of 18.
+func NewScryptRecipient(password string) (*ScryptRecipient, error) {
+	if password == "" {
+		return nil, errors.New("age: empty passphrase")
+	}
+	return &ScryptRecipient{password: []byte(password), workFactor: defaultWorkFactor}, nil
+}
+
+// SetWorkFactor sets the scrypt work factor to 2^logN. Each increment
+// doubles the time and memory needed to encrypt and decrypt. It panics if
+// logN is not between 1 and 30.
+//
+// Files encrypted with a work factor above 22 are rejected by default by
+// ScryptIdentity.
+func (r *ScryptRecipient) SetWorkFactor(logN int) {
+	if logN < 1 || logN > maxSupportedWorkFactor {
+		panic("age: invalid scrypt work factor")
+	}
+	r.workFactor = logN
+}
+
+// Wrap implements Recipient.
+func (r *ScryptRecipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+	salt := make([]byte, scryptSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := scryptKey(r.password, salt, r.workFactor)
+	if err != nil {
+		return nil, err
+	}
+	return []*Stanza{{
+		Type: scryptType,
+		Args: []string{b64.EncodeToString(salt), strconv.Itoa(r.workFactor)},
+		Body: wrapFileKey(key, fileKey),
+	}}, nil
+}
+
+// A ScryptIdentity decrypts a file encrypted with a passphrase.
+type ScryptIdentity struct {
+	password      []byte
+	maxWorkFactor int
+}
+
+// NewScryptIdentity returns an identity that decrypts with password. It
+// rejects files with a work factor above 22.
+func NewScryptIdentity(password string) (*ScryptIdentity, error) {
+	if password == "" {
+		return nil, errors.New("age: empty passphrase")
+	}
+	return &ScryptIdentity{password: []byte(password), maxWorkFactor: defaultMaxWorkFactor}, nil
+}
+
+// SetMaxWorkFactor sets the largest accepted work factor to 2^logN. This
+// bounds the time and memory that decrypting a file can take. It panics if
+// logN is not between 1 and 30.
+func (i *ScryptIdentity) SetMaxWorkFactor(logN int) {
+	if logN < 1 || logN > maxSupportedWorkFactor {
+		panic("age: invalid scrypt work factor")
+	}
+	i.maxWorkFactor = logN
+}
+
+// Unwrap implements Identity. A passphrase-encrypted file must have a
+// single stanza, so that a passphrase can't be mixed with public key
+// recipients.
+func (i *ScryptIdentity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type != scryptType {
+			continue
+		}
+		if len(stanzas) != 1 {
+			return nil, errors.New("age: an scrypt recipient must be the only one")
+		}
+		if len(s.Args) != 2 {
+			return nil, errors.New("age: invalid scrypt recipient stanza")
+		}
+		salt, err := decodeBase64(s.Args[0])
+		if err != nil || len(salt) != scryptSaltSize {
+			return nil, errors.New("age: invalid scrypt recipient stanza")
+		}
+		logN, err := parseWorkFactor(s.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		if logN > i.maxWorkFactor {
+			return nil, errors.New("age: scrypt work factor too large: " + s.Args[1])
+		}
+		key, err := scryptKey(i.password, salt, logN)
+		if err != nil {
+			return nil, err
+		}
+		return unwrapFileKey(key, s.Body)
+	}
+	return nil, ErrIncorrectIdentity
+}
+
+// parseWorkFactor parses a decimal work factor without leading zeros.
+func parseWorkFactor(s string) (int, error) {
+	if s == "" || s[0] == '0' || len(s) > 2 {
+		return 0, errors.New("age: invalid scrypt work factor")
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, errors.New("age: invalid scrypt work factor")
+		}
+	}
+	logN, _ := strconv.Atoi(s)
+	if logN > maxSupportedWorkFactor {
+		return 0, errors.New("age: invalid scrypt work factor")
+	}
+	return logN, nil
+}
+
+func scryptKey(password, salt []byte, logN int) ([]byte, error) {
+	s := append([]byte(scryptLabel), salt...)
+	return scrypt.Key(password, s, 1<<logN, scryptR, scryptP, 32)
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package age
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The payload is encrypted with the STREAM construction of "Online
+// Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance"
+// (Hoang, Reyhanitabar, Rogaway, Vizár). Each chunk is encrypted with a
+// nonce made of an 88-bit big-endian counter and a byte that is 1 for the
+// last chunk and 0 otherwise. Only the last chunk may be shorter than
+// chunkSize, and it may only be empty if the whole payload is.
+const (
+	chunkSize       = 64 * 1024
+	encChunkSize    = chunkSize + chacha20poly1305.Overhead
+	streamNonceSize = 16
+	lastChunkFlag   = 0x01
+)
+
+var errPayload = errors.New("age: failed to decrypt and authenticate payload chunk")
+
+// streamKey derives the payload key from the file key and the payload
+// nonce.
+func streamKey(fileKey, nonce []byte) []byte {
+	return hkdfKey(fileKey, nonce, "payload")
+}
+
+// chunkNonce returns the nonce of the chunk with the given index.
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = lastChunkFlag
+	}
+	return nonce
+}
+
+type writer struct {
+	aead    cipher.AEAD
+	dst     io.Writer
+	buf     []byte // plaintext of the pending chunk
+	out     []byte
+	counter uint64
+	err     error
+}
+
+func newWriter(key []byte, dst io.Writer) *writer {
+	return &writer{
+		aead: newAEAD(key),
+		dst:  dst,
+		buf:  make([]byte, 0, chunkSize),
+		out:  make([]byte, 0, encChunkSize),
+	}
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	for len(p) > 0 {
+		// A full chunk is only flushed once more data arrives, since it
+		// might be the last one.
+		if len(w.buf) == chunkSize {
+			if err := w.flushChunk(false); err != nil {
+				w.err = err
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close writes the last chunk. It does not close the underlying Writer.
+func (w *writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flushChunk(true); err != nil {
+		w.err = err
+		return err
+	}
+	w.err = errors.New("age: write on closed Writer")
+	return nil
+}
+
+func (w *writer) flushChunk(last bool) error {
+	w.out = w.aead.Seal(w.out[:0], chunkNonce(w.counter, last), w.buf, nil)
+	if _, err := w.dst.Write(w.out); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	w.counter++
+	return nil
+}
+
+type reader struct {
+	aead cipher.AEAD
+	src  io.Reader
+
+	// in holds the pending ciphertext, including one byte of the next
+	// chunk that is read ahead to find out whether a chunk is the last.
+	in      []byte
+	out     []byte
+	unread  []byte // decrypted plaintext not yet returned
+	counter uint64
+	err     error
+}
+
+func newReader(key []byte, src io.Reader) *reader {
+	return &reader{
+		aead: newAEAD(key),
+		src:  src,
+		in:   make([]byte, 0, encChunkSize+1),
+		out:  make([]byte, 0, chunkSize),
+	}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.unread) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.readChunk()
+	}
+	n := copy(p, r.unread)
+	r.unread = r.unread[n:]
+	return n, nil
+}
+
+// readChunk decrypts the next chunk into r.unread. After the last chunk,
+// it returns io.EOF.
+func (r *reader) readChunk() error {
+	n, err := io.ReadFull(r.src, r.in[len(r.in):cap(r.in)])
+	r.in = r.in[:len(r.in)+n]
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	chunk := r.in
+	if !last {
+		chunk = r.in[:encChunkSize]
+	}
+	if len(chunk) < chacha20poly1305.Overhead {
+		return errTruncated
+	}
+	out, err := r.aead.Open(r.out[:0], chunkNonce(r.counter, last), chunk, nil)
+	if err != nil {
+		return errPayload
+	}
+	if last && len(out) == 0 && r.counter != 0 {
+		return errors.New("age: last chunk is empty")
+	}
+	r.unread = out
+	r.counter++
+
+	if last {
+		return io.EOF
+	}
+	r.in = append(r.in[:0], r.in[encChunkSize])
+	return nil
+}
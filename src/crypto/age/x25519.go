@@ -0,0 +1,174 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package age
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+)
+
+const (
+	x25519Type  = "X25519"
+	x25519Label = "age-encryption.org/v1/X25519"
+
+	recipientHRP = "age"
+	identityHRP  = "AGE-SECRET-KEY-"
+)
+
+// An X25519Recipient is the public key of an X25519Identity. Its string
+// encoding starts with "age1".
+type X25519Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// NewX25519Recipient returns the recipient of an X25519 public key.
+func NewX25519Recipient(key *ecdh.PublicKey) (*X25519Recipient, error) {
+	if key.Curve() != ecdh.X25519() {
+		return nil, errors.New("age: recipient key is not an X25519 key")
+	}
+	return &X25519Recipient{key: key}, nil
+}
+
+// ParseX25519Recipient parses an "age1..." recipient string.
+func ParseX25519Recipient(s string) (*X25519Recipient, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, errors.New("age: malformed recipient: " + err.Error())
+	}
+	if hrp != recipientHRP {
+		return nil, errors.New("age: malformed recipient: unexpected type " + hrp)
+	}
+	key, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, errors.New("age: malformed recipient: " + err.Error())
+	}
+	return &X25519Recipient{key: key}, nil
+}
+
+// PublicKey returns the X25519 public key of r.
+func (r *X25519Recipient) PublicKey() *ecdh.PublicKey {
+	return r.key
+}
+
+// String returns the "age1..." encoding of r.
+func (r *X25519Recipient) String() string {
+	s, err := bech32Encode(recipientHRP, r.key.Bytes())
+	if err != nil {
+		panic("age: internal error: " + err.Error())
+	}
+	return s
+}
+
+// Wrap implements Recipient. It wraps fileKey with a key agreed between a
+// fresh ephemeral key and r.
+func (r *X25519Recipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(r.key)
+	if err != nil {
+		return nil, err
+	}
+	share := ephemeral.PublicKey().Bytes()
+	salt := append(append([]byte{}, share...), r.key.Bytes()...)
+	wrappingKey := hkdfKey(shared, salt, x25519Label)
+	return []*Stanza{{
+		Type: x25519Type,
+		Args: []string{b64.EncodeToString(share)},
+		Body: wrapFileKey(wrappingKey, fileKey),
+	}}, nil
+}
+
+// An X25519Identity is an X25519 private key that decrypts files encrypted
+// to its recipient. Its string encoding starts with "AGE-SECRET-KEY-1".
+type X25519Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// GenerateX25519Identity returns a new random X25519Identity.
+func GenerateX25519Identity() (*X25519Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &X25519Identity{key: key}, nil
+}
+
+// NewX25519Identity returns the identity of an X25519 private key.
+func NewX25519Identity(key *ecdh.PrivateKey) (*X25519Identity, error) {
+	if key.Curve() != ecdh.X25519() {
+		return nil, errors.New("age: identity key is not an X25519 key")
+	}
+	return &X25519Identity{key: key}, nil
+}
+
+// ParseX25519Identity parses an "AGE-SECRET-KEY-1..." identity string.
+func ParseX25519Identity(s string) (*X25519Identity, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, errors.New("age: malformed secret key: " + err.Error())
+	}
+	if hrp != identityHRP {
+		return nil, errors.New("age: malformed secret key: unexpected type " + hrp)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, errors.New("age: malformed secret key: " + err.Error())
+	}
+	return &X25519Identity{key: key}, nil
+}
+
+// PrivateKey returns the X25519 private key of i.
+func (i *X25519Identity) PrivateKey() *ecdh.PrivateKey {
+	return i.key
+}
+
+// Recipient returns the recipient that files must be encrypted to for i
+// to decrypt them.
+func (i *X25519Identity) Recipient() *X25519Recipient {
+	return &X25519Recipient{key: i.key.PublicKey()}
+}
+
+// String returns the "AGE-SECRET-KEY-1..." encoding of i.
+func (i *X25519Identity) String() string {
+	s, err := bech32Encode(identityHRP, i.key.Bytes())
+	if err != nil {
+		panic("age: internal error: " + err.Error())
+	}
+	return s
+}
+
+// Unwrap implements Identity.
+func (i *X25519Identity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type != x25519Type {
+			continue
+		}
+		if len(s.Args) != 1 {
+			return nil, errors.New("age: invalid X25519 recipient stanza")
+		}
+		share, err := decodeBase64(s.Args[0])
+		if err != nil {
+			return nil, errors.New("age: invalid X25519 recipient stanza")
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(share)
+		if err != nil {
+			return nil, errors.New("age: invalid X25519 recipient stanza")
+		}
+		shared, err := i.key.ECDH(ephemeral)
+		if err != nil {
+			return nil, errors.New("age: invalid X25519 recipient stanza")
+		}
+		salt := append(append([]byte{}, share...), i.key.PublicKey().Bytes()...)
+		fileKey, err := unwrapFileKey(hkdfKey(shared, salt, x25519Label), s.Body)
+		if err == ErrIncorrectIdentity {
+			continue
+		}
+		return fileKey, err
+	}
+	return nil, ErrIncorrectIdentity
+}
@@ -0,0 +1,226 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bigmod implements constant-time modular arithmetic on natural
+// numbers, for use in cryptographic protocols that need to operate on secret
+// values. It is backed by the same code used by the crypto/rsa and
+// crypto/ecdsa packages.
+//
+// Unlike math/big, the time taken by the operations on Nat values depends
+// only on the size of the modulus and, for Exp, the length of the exponent,
+// and not on the values of the operands. The value of the modulus is not
+// protected: NewModulus and NewModulusFromBig process it with math/big, which
+// is not constant time. Moduli must be public, like an RSA modulus or the
+// order of an elliptic curve group.
+//
+// Every Nat is bound to the Modulus it was set for, by SetBytes, Mod or an
+// arithmetic operation, and is always fully reduced modulo it. Using a Nat
+// with a different Modulus panics.
+package bigmod
+
+import (
+	"crypto/internal/bigmod"
+	"errors"
+	"math/big"
+)
+
+// Modulus is an odd modulus greater than one, with precomputed values to
+// speed up modular arithmetic. The value of a Modulus is public; see the
+// package documentation.
+//
+// A Modulus is safe for concurrent use. The zero value is not usable, and
+// using it panics; a Modulus must be made with NewModulus or
+// NewModulusFromBig.
+type Modulus struct {
+	m *bigmod.Modulus
+}
+
+// NewModulus returns a Modulus for the big-endian value b, which must be odd
+// and greater than one. Leading zero bytes in b are ignored.
+func NewModulus(b []byte) (*Modulus, error) {
+	return NewModulusFromBig(new(big.Int).SetBytes(b))
+}
+
+// NewModulusFromBig returns a Modulus for n, which must be odd and greater
+// than one.
+func NewModulusFromBig(n *big.Int) (*Modulus, error) {
+	if n.Sign() <= 0 || n.Bit(0) != 1 || n.BitLen() < 2 {
+		return nil, errors.New("bigmod: modulus must be odd and greater than one")
+	}
+	return &Modulus{bigmod.NewModulusFromBig(n)}, nil
+}
+
+// BitLen returns the size of m in bits.
+func (m *Modulus) BitLen() int {
+	m.check()
+	return m.m.BitLen()
+}
+
+// Size returns the size of m in bytes.
+func (m *Modulus) Size() int {
+	m.check()
+	return m.m.Size()
+}
+
+// Bytes returns m as a big-endian byte slice of length m.Size().
+func (m *Modulus) Bytes() []byte {
+	m.check()
+	return m.m.Nat().Bytes(m.m)
+}
+
+// Nat is a natural number reduced modulo a Modulus.
+//
+// The zero value is not usable until set with SetBytes, SetOverflowingBytes,
+// Set or Mod.
+type Nat struct {
+	n *bigmod.Nat
+	m *Modulus
+}
+
+// NewNat returns a new, unset Nat.
+func NewNat() *Nat {
+	return &Nat{}
+}
+
+// SetBytes sets x to the big-endian value b, and binds it to m. It returns an
+// error if b is not less than m. Leading zero bytes in b are ignored, but the
+// time taken depends on len(b).
+func (x *Nat) SetBytes(b []byte, m *Modulus) (*Nat, error) {
+	m.check()
+	n, err := bigmod.NewNat().SetBytes(b, m.m)
+	if err != nil {
+		return nil, errors.New("bigmod: value overflows the modulus")
+	}
+	x.n, x.m = n, m
+	return x, nil
+}
+
+// SetOverflowingBytes sets x to the big-endian value b reduced modulo m, and
+// binds it to m. b may be at most m.BitLen() bits long; for arbitrary values,
+// use Mod instead.
+func (x *Nat) SetOverflowingBytes(b []byte, m *Modulus) (*Nat, error) {
+	m.check()
+	n, err := bigmod.NewNat().SetOverflowingBytes(b, m.m)
+	if err != nil {
+		return nil, errors.New("bigmod: value overflows the modulus")
+	}
+	x.n, x.m = n, m
+	return x, nil
+}
+
+// Set sets x to y, and binds it to the same Modulus as y.
+func (x *Nat) Set(y *Nat) *Nat {
+	y.check(y.m)
+	if x != y {
+		x.n, _ = bigmod.NewNat().SetBytes(y.n.Bytes(y.m.m), y.m.m)
+		x.m = y.m
+	}
+	return x
+}
+
+// Bytes returns x as a big-endian byte slice of length m.Size(), where m is
+// the Modulus x is bound to.
+func (x *Nat) Bytes() []byte {
+	x.check(x.m)
+	return x.n.Bytes(x.m.m)
+}
+
+// Modulus returns the Modulus x is bound to, or nil if x is not set.
+func (x *Nat) Modulus() *Modulus {
+	return x.m
+}
+
+// Equal returns 1 if x == y, and 0 otherwise. x and y must be bound to the
+// same Modulus.
+func (x *Nat) Equal(y *Nat) int {
+	x.check(y.m)
+	return int(x.n.Equal(y.n))
+}
+
+// IsZero returns 1 if x == 0, and 0 otherwise.
+func (x *Nat) IsZero() int {
+	x.check(x.m)
+	return int(x.n.IsZero())
+}
+
+// Add sets x = x + y mod m, and returns x.
+func (x *Nat) Add(y *Nat, m *Modulus) *Nat {
+	x.check(m)
+	y.check(m)
+	x.n.Add(y.n, m.m)
+	return x
+}
+
+// Sub sets x = x - y mod m, and returns x.
+func (x *Nat) Sub(y *Nat, m *Modulus) *Nat {
+	x.check(m)
+	y.check(m)
+	x.n.Sub(y.n, m.m)
+	return x
+}
+
+// Mul sets x = x * y mod m, and returns x.
+func (x *Nat) Mul(y *Nat, m *Modulus) *Nat {
+	x.check(m)
+	y.check(m)
+	if x == y {
+		y = NewNat().Set(y)
+	}
+	x.n.Mul(y.n, m.m)
+	return x
+}
+
+// Exp sets x = y^e mod m, where e is a big-endian exponent, and returns x.
+// The time taken depends on len(e), but not on its value.
+func (x *Nat) Exp(y *Nat, e []byte, m *Modulus) *Nat {
+	m.check()
+	y.check(m)
+	n := bigmod.NewNat().Exp(y.n, e, m.m)
+	x.n, x.m = n, m
+	return x
+}
+
+// Inverse sets x = y⁻¹ mod m and returns x and true, if y is invertible
+// modulo m. Otherwise, it returns x unchanged and false. The time taken
+// depends only on the size of m.
+func (x *Nat) Inverse(y *Nat, m *Modulus) (*Nat, bool) {
+	m.check()
+	y.check(m)
+	n, ok := bigmod.NewNat().Inverse(y.n, m.m)
+	if ok == 0 {
+		return x, false
+	}
+	x.n, x.m = n, m
+	return x, true
+}
+
+// Mod sets x = y mod m, binds x to m, and returns x. y may be bound to any
+// Modulus.
+func (x *Nat) Mod(y *Nat, m *Modulus) *Nat {
+	m.check()
+	y.check(y.m)
+	n := bigmod.NewNat().Mod(y.n, m.m)
+	x.n, x.m = n, m
+	return x
+}
+
+// check panics if m was not made by NewModulus or NewModulusFromBig.
+func (m *Modulus) check() {
+	if m == nil || m.m == nil {
+		panic("bigmod: use of uninitialized Modulus")
+	}
+}
+
+// check panics if x is not set or not bound to m.
+func (x *Nat) check(m *Modulus) {
+	if x == nil {
+		panic("bigmod: use of nil Nat")
+	}
+	if x.m == nil {
+		panic("bigmod: use of unset Nat")
+	}
+	if x.m != m {
+		panic("bigmod: Nat is bound to a different Modulus")
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bigmod_test
+
+import (
+	"bytes"
+	. "crypto/bigmod"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func randomModulus(t *testing.T, r *rand.Rand, bits int) (*Modulus, *big.Int) {
+	t.Helper()
+	n := new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	n.SetBit(n, 0, 1)
+	n.SetBit(n, bits-1, 1)
+	m, err := NewModulusFromBig(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, n
+}
+
+func natFromBig(t *testing.T, x *big.Int, m *Modulus) *Nat {
+	t.Helper()
+	n, err := NewNat().SetBytes(x.Bytes(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func checkNat(t *testing.T, op string, got *Nat, want *big.Int, m *Modulus) {
+	t.Helper()
+	wantBytes := want.FillBytes(make([]byte, m.Size()))
+	if !bytes.Equal(got.Bytes(), wantBytes) {
+		t.Errorf("%s = %x, want %x", op, got.Bytes(), wantBytes)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for _, bits := range []int{2, 8, 63, 64, 65, 255, 521, 2048} {
+		m, n := randomModulus(t, r, bits)
+		if m.BitLen() != bits {
+			t.Errorf("BitLen() = %d, want %d", m.BitLen(), bits)
+		}
+		if !bytes.Equal(m.Bytes(), n.FillBytes(make([]byte, m.Size()))) {
+			t.Errorf("Bytes() = %x, want %x", m.Bytes(), n.Bytes())
+		}
+		for i := 0; i < 10; i++ {
+			a := new(big.Int).Rand(r, n)
+			b := new(big.Int).Rand(r, n)
+			e := new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), 300))
+
+			x := natFromBig(t, a, m)
+			y := natFromBig(t, b, m)
+			checkNat(t, "a", x, a, m)
+
+			sum := NewNat().Set(x).Add(y, m)
+			checkNat(t, "a + b", sum, new(big.Int).Mod(new(big.Int).Add(a, b), n), m)
+			diff := NewNat().Set(x).Sub(y, m)
+			checkNat(t, "a - b", diff, new(big.Int).Mod(new(big.Int).Sub(a, b), n), m)
+			prod := NewNat().Set(x).Mul(y, m)
+			checkNat(t, "a * b", prod, new(big.Int).Mod(new(big.Int).Mul(a, b), n), m)
+			sq := NewNat().Set(x)
+			sq.Mul(sq, m)
+			checkNat(t, "a * a", sq, new(big.Int).Mod(new(big.Int).Mul(a, a), n), m)
+			exp := NewNat().Exp(x, e.Bytes(), m)
+			checkNat(t, "a ^ e", exp, new(big.Int).Exp(a, e, n), m)
+
+			if x.Equal(NewNat().Set(x)) != 1 {
+				t.Error("a != a")
+			}
+			if x.Equal(y) != btoi(a.Cmp(b) == 0) {
+				t.Error("Equal returned the wrong result")
+			}
+			if x.IsZero() != btoi(a.Sign() == 0) {
+				t.Error("IsZero returned the wrong result")
+			}
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestMod(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	large, largeN := randomModulus(t, r, 2048)
+	small, smallN := randomModulus(t, r, 1024)
+	a := new(big.Int).Rand(r, largeN)
+	x := NewNat().Mod(natFromBig(t, a, large), small)
+	if x.Modulus() != small {
+		t.Error("Mod result is not bound to the new modulus")
+	}
+	checkNat(t, "a mod m", x, new(big.Int).Mod(a, smallN), small)
+}
+
+func TestInverse(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, bits := range []int{2, 8, 63, 64, 65, 255, 1024} {
+		m, n := randomModulus(t, r, bits)
+		for i := 0; i < 10; i++ {
+			a := new(big.Int).Rand(r, n)
+			if i == 0 {
+				a.SetInt64(0)
+			}
+			if i == 1 {
+				a.SetInt64(1)
+			}
+			x, ok := NewNat().Inverse(natFromBig(t, a, m), m)
+			want := new(big.Int).ModInverse(a, n)
+			if ok != (want != nil) {
+				t.Errorf("%d-bit modulus: Inverse(%x) ok = %v, want %v", bits, a, ok, want != nil)
+				continue
+			}
+			if ok {
+				checkNat(t, "a^-1", x, want, m)
+			}
+		}
+	}
+
+	// A composite modulus, with values that share a factor with it.
+	m, _ := NewModulus([]byte{3 * 5 * 7})
+	for a := 1; a < 3*5*7; a++ {
+		x, ok := NewNat().Inverse(natFromBig(t, big.NewInt(int64(a)), m), m)
+		if wantOK := a%3 != 0 && a%5 != 0 && a%7 != 0; ok != wantOK {
+			t.Errorf("Inverse(%d) mod 105 ok = %v, want %v", a, ok, wantOK)
+		} else if ok && int(x.Bytes()[0])*a%105 != 1 {
+			t.Errorf("Inverse(%d) mod 105 = %d", a, x.Bytes()[0])
+		}
+	}
+}
+
+func TestSetBytes(t *testing.T) {
+	m, err := NewModulus([]byte{0, 0, 13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.BitLen() != 4 || m.Size() != 1 {
+		t.Errorf("got BitLen %d, Size %d; want 4, 1", m.BitLen(), m.Size())
+	}
+	if _, err := NewNat().SetBytes([]byte{13}, m); err == nil {
+		t.Error("SetBytes accepted the modulus")
+	}
+	if x, err := NewNat().SetBytes([]byte{0, 0, 12}, m); err != nil || x.Bytes()[0] != 12 {
+		t.Errorf("SetBytes(12) = %v, %v", x, err)
+	}
+	x, err := NewNat().SetOverflowingBytes([]byte{15}, m)
+	if err != nil || x.Bytes()[0] != 2 {
+		t.Errorf("SetOverflowingBytes(15) = %v, %v; want 2", x, err)
+	}
+	if _, err := NewNat().SetOverflowingBytes([]byte{16}, m); err == nil {
+		t.Error("SetOverflowingBytes accepted a value longer than the modulus")
+	}
+
+	for _, bad := range [][]byte{nil, {0}, {1}, {2}, {0x10, 0x00}} {
+		if _, err := NewModulus(bad); err == nil {
+			t.Errorf("NewModulus(%x) succeeded", bad)
+		}
+	}
+}
+
+func TestModulusMismatch(t *testing.T) {
+	m1, _ := NewModulus([]byte{13})
+	m2, _ := NewModulus([]byte{13})
+	x, _ := NewNat().SetBytes([]byte{5}, m1)
+	y, _ := NewNat().SetBytes([]byte{5}, m2)
+
+	expectPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s did not panic", name)
+			}
+		}()
+		f()
+	}
+	expectPanic("Add", func() { x.Add(y, m1) })
+	expectPanic("Equal", func() { x.Equal(y) })
+	expectPanic("Exp", func() { NewNat().Exp(x, []byte{3}, m2) })
+	expectPanic("Inverse", func() { NewNat().Inverse(x, m2) })
+	expectPanic("Bytes of unset Nat", func() { NewNat().Bytes() })
+}
+
+func TestUnsetValues(t *testing.T) {
+	m, _ := NewModulus([]byte{13})
+	x, _ := NewNat().SetBytes([]byte{5}, m)
+
+	expectPanic := func(name, want string, f func()) {
+		t.Helper()
+		defer func() {
+			t.Helper()
+			if got, _ := recover().(string); got != want {
+				t.Errorf("%s panicked with %q, want %q", name, got, want)
+			}
+		}()
+		f()
+	}
+	const unsetNat = "bigmod: use of unset Nat"
+	expectPanic("Exp", unsetNat, func() { NewNat().Exp(NewNat(), []byte{3}, m) })
+	expectPanic("Inverse", unsetNat, func() { NewNat().Inverse(new(Nat), m) })
+	expectPanic("Mod", unsetNat, func() { NewNat().Mod(NewNat(), m) })
+	expectPanic("Mul", unsetNat, func() { NewNat().Mul(x, m) })
+	expectPanic("Exp with nil Nat", "bigmod: use of nil Nat", func() { NewNat().Exp(nil, []byte{3}, m) })
+
+	const zeroModulus = "bigmod: use of uninitialized Modulus"
+	expectPanic("Exp", zeroModulus, func() { NewNat().Exp(x, []byte{3}, &Modulus{}) })
+	expectPanic("Inverse", zeroModulus, func() { NewNat().Inverse(x, new(Modulus)) })
+	expectPanic("Mod", zeroModulus, func() { NewNat().Mod(x, nil) })
+	expectPanic("SetBytes", zeroModulus, func() { NewNat().SetBytes([]byte{1}, &Modulus{}) })
+	expectPanic("BitLen", zeroModulus, func() { new(Modulus).BitLen() })
+}
@@ -0,0 +1,398 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package blake2b implements the BLAKE2b hash algorithm defined by RFC 7693
+// and the extendable output function (XOF) BLAKE2Xb.
+//
+// BLAKE2b is optimized for 64-bit platforms—including NEON-enabled ARMs—and
+// produces digests of any size between 1 and 64 bytes.
+// For a detailed specification of BLAKE2b see https://blake2.net/blake2.pdf
+// and for BLAKE2Xb see https://blake2.net/blake2x.pdf
+//
+// If you aren't sure which function you need, use BLAKE2b (Sum512 or New512).
+// If you need a secret-key MAC (message authentication code), use the New512
+// function with a non-nil key.
+//
+// BLAKE2X is a construction to compute hash values larger than 64 bytes. It
+// can produce hash values between 0 and 4 GiB.
+//
+// NewWithParams supports the salt, personalization, and tree hashing
+// parameters of the BLAKE2 specification.
+//
+// BLAKE2b is not supported by BoringCrypto, so with GOEXPERIMENT=boringcrypto
+// every hash is computed in Go and recorded as a fallback.
+package blake2b
+
+import (
+	"crypto/internal/boring"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+)
+
+const (
+	// The blocksize of BLAKE2b in bytes.
+	BlockSize = 128
+	// The hash size of BLAKE2b-512 in bytes.
+	Size = 64
+	// The hash size of BLAKE2b-384 in bytes.
+	Size384 = 48
+	// The hash size of BLAKE2b-256 in bytes.
+	Size256 = 32
+)
+
+var (
+	useAVX2 bool
+	useAVX  bool
+	useSSE4 bool
+)
+
+var (
+	errKeySize  = errors.New("blake2b: invalid key size")
+	errHashSize = errors.New("blake2b: invalid hash size")
+	errSalt     = errors.New("blake2b: invalid salt size")
+	errPersonal = errors.New("blake2b: invalid personalization size")
+	errTree     = errors.New("blake2b: invalid tree parameters")
+)
+
+var iv = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+// Sum512 returns the BLAKE2b-512 checksum of the data.
+func Sum512(data []byte) [Size]byte {
+	recordFallback()
+	var sum [Size]byte
+	checkSum(&sum, Size, data)
+	return sum
+}
+
+// Sum384 returns the BLAKE2b-384 checksum of the data.
+func Sum384(data []byte) [Size384]byte {
+	recordFallback()
+	var sum [Size]byte
+	var sum384 [Size384]byte
+	checkSum(&sum, Size384, data)
+	copy(sum384[:], sum[:Size384])
+	return sum384
+}
+
+// Sum256 returns the BLAKE2b-256 checksum of the data.
+func Sum256(data []byte) [Size256]byte {
+	recordFallback()
+	var sum [Size]byte
+	var sum256 [Size256]byte
+	checkSum(&sum, Size256, data)
+	copy(sum256[:], sum[:Size256])
+	return sum256
+}
+
+// New512 returns a new hash.Hash computing the BLAKE2b-512 checksum. A non-nil
+// key turns the hash into a MAC. The key must be between zero and 64 bytes long.
+func New512(key []byte) (hash.Hash, error) { return newDigest(Size, key) }
+
+// New384 returns a new hash.Hash computing the BLAKE2b-384 checksum. A non-nil
+// key turns the hash into a MAC. The key must be between zero and 64 bytes long.
+func New384(key []byte) (hash.Hash, error) { return newDigest(Size384, key) }
+
+// New256 returns a new hash.Hash computing the BLAKE2b-256 checksum. A non-nil
+// key turns the hash into a MAC. The key must be between zero and 64 bytes long.
+func New256(key []byte) (hash.Hash, error) { return newDigest(Size256, key) }
+
+// New returns a new hash.Hash computing the BLAKE2b checksum with a custom length.
+// A non-nil key turns the hash into a MAC. The key must be between zero and 64 bytes long.
+// The hash size can be a value between 1 and 64 but it is highly recommended to use
+// values equal or greater than:
+// - 32 if BLAKE2b is used as a hash function (The key is zero bytes long).
+// - 16 if BLAKE2b is used as a MAC function (The key is at least 16 bytes long).
+// When the key is nil, the returned hash.Hash implements BinaryMarshaler
+// and BinaryUnmarshaler for state (de)serialization as documented by hash.Hash.
+func New(size int, key []byte) (hash.Hash, error) { return newDigest(size, key) }
+
+// Params holds the optional parameters of a BLAKE2b hash, as described in
+// the BLAKE2 specification. The zero Params describes BLAKE2b-512.
+type Params struct {
+	// Size is the digest size in bytes, between 1 and 64. Zero means 64.
+	Size int
+
+	// Key, if not empty, turns the hash into a MAC. It can be at most 64
+	// bytes long.
+	Key []byte
+
+	// Salt and Personal, if not empty, select distinct hash functions for
+	// randomized hashing and domain separation. Each can be at most 16
+	// bytes long, and shorter values are padded with zeros.
+	Salt, Personal []byte
+
+	// Tree, if not nil, makes the hash compute a node of a hash tree
+	// instead of hashing its input sequentially.
+	Tree *Tree
+}
+
+// Tree holds the parameters of a node of a BLAKE2b hash tree. Each node is
+// computed by its own hash, whose input is a leaf of the message or the
+// concatenated digests of the node's children.
+type Tree struct {
+	Fanout     uint8  // maximum number of children of a node, or 0 for unlimited
+	MaxDepth   uint8  // maximum depth of the tree, at least 1
+	LeafSize   uint32 // maximum leaf size in bytes, or 0 for unlimited
+	NodeOffset uint64 // offset of the node within its level, from 0
+	NodeDepth  uint8  // depth of the node, from 0 for leaves
+	InnerSize  uint8  // digest size of inner nodes, between 0 and 64
+	IsLastNode bool   // whether the node is the last one of its level
+}
+
+// NewWithParams returns a new hash.Hash computing the BLAKE2b checksum with
+// the given parameters. Like New, it implements BinaryMarshaler and
+// BinaryUnmarshaler if p.Key is empty; the state can only be restored into
+// a hash created with the same parameters.
+func NewWithParams(p *Params) (hash.Hash, error) {
+	return newDigestParams(p)
+}
+
+func newDigest(hashSize int, key []byte) (*digest, error) {
+	if hashSize < 1 {
+		return nil, errHashSize
+	}
+	return newDigestParams(&Params{Size: hashSize, Key: key})
+}
+
+func newDigestParams(p *Params) (*digest, error) {
+	size := p.Size
+	if size == 0 {
+		size = Size
+	}
+	if size < 1 || size > Size {
+		return nil, errHashSize
+	}
+	if len(p.Key) > Size {
+		return nil, errKeySize
+	}
+	if len(p.Salt) > 16 {
+		return nil, errSalt
+	}
+	if len(p.Personal) > 16 {
+		return nil, errPersonal
+	}
+
+	var param [64]byte
+	param[0] = byte(size)
+	param[1] = byte(len(p.Key))
+	param[2], param[3] = 1, 1 // sequential mode
+	copy(param[32:], p.Salt)
+	copy(param[48:], p.Personal)
+	d := &digest{
+		size:   size,
+		keyLen: len(p.Key),
+	}
+	if t := p.Tree; t != nil {
+		if t.MaxDepth == 0 || t.InnerSize > Size {
+			return nil, errTree
+		}
+		param[2] = t.Fanout
+		param[3] = t.MaxDepth
+		binary.LittleEndian.PutUint32(param[4:], t.LeafSize)
+		binary.LittleEndian.PutUint64(param[8:], t.NodeOffset)
+		param[16] = t.NodeDepth
+		param[17] = t.InnerSize
+		d.lastNode = t.IsLastNode
+	}
+	for i := range d.h0 {
+		d.h0[i] = iv[i] ^ binary.LittleEndian.Uint64(param[8*i:])
+	}
+	copy(d.key[:], p.Key)
+	d.Reset()
+	recordFallback()
+	return d, nil
+}
+
+func recordFallback() {
+	if boring.Enabled {
+		boring.RecordFallback(cryptometrics.BLAKE2b, "unsupported algorithm")
+	}
+}
+
+func checkSum(sum *[Size]byte, hashSize int, data []byte) {
+	h := iv
+	h[0] ^= uint64(hashSize) | (1 << 16) | (1 << 24)
+	var c [2]uint64
+
+	if length := len(data); length > BlockSize {
+		n := length &^ (BlockSize - 1)
+		if length == n {
+			n -= BlockSize
+		}
+		hashBlocks(&h, &c, 0, data[:n])
+		data = data[n:]
+	}
+
+	var block [BlockSize]byte
+	offset := copy(block[:], data)
+	remaining := uint64(BlockSize - offset)
+	if c[0] < remaining {
+		c[1]--
+	}
+	c[0] -= remaining
+
+	hashBlocks(&h, &c, 0xFFFFFFFFFFFFFFFF, block[:])
+
+	for i, v := range h[:(hashSize+7)/8] {
+		binary.LittleEndian.PutUint64(sum[8*i:], v)
+	}
+}
+
+type digest struct {
+	h      [8]uint64
+	c      [2]uint64
+	size   int
+	block  [BlockSize]byte
+	offset int
+
+	key    [BlockSize]byte
+	keyLen int
+
+	h0       [8]uint64 // initial state, from the parameter block
+	lastNode bool
+}
+
+const (
+	magic         = "b2b"
+	marshaledSize = len(magic) + 8*8 + 2*8 + 1 + BlockSize + 1
+)
+
+func (d *digest) MarshalBinary() ([]byte, error) {
+	if d.keyLen != 0 {
+		return nil, errors.New("crypto/blake2b: cannot marshal MACs")
+	}
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	for i := 0; i < 8; i++ {
+		b = appendUint64(b, d.h[i])
+	}
+	b = appendUint64(b, d.c[0])
+	b = appendUint64(b, d.c[1])
+	// Maximum value for size is 64
+	b = append(b, byte(d.size))
+	b = append(b, d.block[:]...)
+	b = append(b, byte(d.offset))
+	return b, nil
+}
+
+func (d *digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("crypto/blake2b: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("crypto/blake2b: invalid hash state size")
+	}
+	b = b[len(magic):]
+	for i := 0; i < 8; i++ {
+		b, d.h[i] = consumeUint64(b)
+	}
+	b, d.c[0] = consumeUint64(b)
+	b, d.c[1] = consumeUint64(b)
+	d.size = int(b[0])
+	b = b[1:]
+	copy(d.block[:], b[:BlockSize])
+	b = b[BlockSize:]
+	d.offset = int(b[0])
+	return nil
+}
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Size() int { return d.size }
+
+func (d *digest) Reset() {
+	d.h = d.h0
+	d.offset, d.c[0], d.c[1] = 0, 0, 0
+	if d.keyLen > 0 {
+		d.block = d.key
+		d.offset = BlockSize
+	}
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	n = len(p)
+
+	if d.offset > 0 {
+		remaining := BlockSize - d.offset
+		if n <= remaining {
+			d.offset += copy(d.block[d.offset:], p)
+			return
+		}
+		copy(d.block[d.offset:], p[:remaining])
+		hashBlocks(&d.h, &d.c, 0, d.block[:])
+		d.offset = 0
+		p = p[remaining:]
+	}
+
+	if length := len(p); length > BlockSize {
+		nn := length &^ (BlockSize - 1)
+		if length == nn {
+			nn -= BlockSize
+		}
+		hashBlocks(&d.h, &d.c, 0, p[:nn])
+		p = p[nn:]
+	}
+
+	if len(p) > 0 {
+		d.offset += copy(d.block[:], p)
+	}
+
+	return
+}
+
+func (d *digest) Sum(sum []byte) []byte {
+	var hash [Size]byte
+	d.finalize(&hash)
+	return append(sum, hash[:d.size]...)
+}
+
+func (d *digest) finalize(hash *[Size]byte) {
+	var block [BlockSize]byte
+	copy(block[:], d.block[:d.offset])
+	remaining := uint64(BlockSize - d.offset)
+
+	c := d.c
+	if c[0] < remaining {
+		c[1]--
+	}
+	c[0] -= remaining
+
+	h := d.h
+	if d.lastNode {
+		hashBlocksGenericLastNode(&h, &c, 0xFFFFFFFFFFFFFFFF, block[:])
+	} else {
+		hashBlocks(&h, &c, 0xFFFFFFFFFFFFFFFF, block[:])
+	}
+
+	for i, v := range h {
+		binary.LittleEndian.PutUint64(hash[8*i:], v)
+	}
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var a [8]byte
+	binary.BigEndian.PutUint64(a[:], x)
+	return append(b, a[:]...)
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], x)
+	return append(b, a[:]...)
+}
+
+func consumeUint64(b []byte) ([]byte, uint64) {
+	x := binary.BigEndian.Uint64(b)
+	return b[8:], x
+}
+
+func consumeUint32(b []byte) ([]byte, uint32) {
+	x := binary.BigEndian.Uint32(b)
+	return b[4:], x
+}
@@ -0,0 +1,354 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package blake2s implements the BLAKE2s hash algorithm defined by RFC 7693
+// and the extendable output function (XOF) BLAKE2Xs.
+//
+// BLAKE2s is optimized for 8- to 32-bit platforms and produces digests of any
+// size between 1 and 32 bytes.
+// For a detailed specification of BLAKE2s see https://blake2.net/blake2.pdf
+// and for BLAKE2Xs see https://blake2.net/blake2x.pdf
+//
+// If you aren't sure which function you need, use BLAKE2s (Sum256 or New256).
+// If you need a secret-key MAC (message authentication code), use the New256
+// function with a non-nil key.
+//
+// BLAKE2X is a construction to compute hash values larger than 32 bytes. It
+// can produce hash values between 0 and 65535 bytes.
+//
+// NewWithParams supports the salt, personalization, and tree hashing
+// parameters of the BLAKE2 specification.
+//
+// BLAKE2s is not supported by BoringCrypto, so with GOEXPERIMENT=boringcrypto
+// every hash is computed in Go and recorded as a fallback.
+package blake2s
+
+import (
+	"crypto/internal/boring"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+)
+
+const (
+	// The blocksize of BLAKE2s in bytes.
+	BlockSize = 64
+
+	// The hash size of BLAKE2s-256 in bytes.
+	Size = 32
+
+	// The hash size of BLAKE2s-128 in bytes.
+	Size128 = 16
+)
+
+var (
+	errKeySize  = errors.New("blake2s: invalid key size")
+	errHashSize = errors.New("blake2s: invalid hash size")
+	errSalt     = errors.New("blake2s: invalid salt size")
+	errPersonal = errors.New("blake2s: invalid personalization size")
+	errTree     = errors.New("blake2s: invalid tree parameters")
+)
+
+var iv = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+// Sum256 returns the BLAKE2s-256 checksum of the data.
+func Sum256(data []byte) [Size]byte {
+	recordFallback()
+	var sum [Size]byte
+	checkSum(&sum, Size, data)
+	return sum
+}
+
+// New256 returns a new hash.Hash computing the BLAKE2s-256 checksum. A non-nil
+// key turns the hash into a MAC. The key must between zero and 32 bytes long.
+// When the key is nil, the returned hash.Hash implements BinaryMarshaler
+// and BinaryUnmarshaler for state (de)serialization as documented by hash.Hash.
+func New256(key []byte) (hash.Hash, error) { return newDigest(Size, key) }
+
+// New128 returns a new hash.Hash computing the BLAKE2s-128 checksum given a
+// non-empty key. Note that a 128-bit digest is too small to be secure as a
+// cryptographic hash and should only be used as a MAC, thus the key argument
+// is not optional.
+func New128(key []byte) (hash.Hash, error) {
+	if len(key) == 0 {
+		return nil, errors.New("blake2s: a key is required for a 128-bit hash")
+	}
+	return newDigest(Size128, key)
+}
+
+// Params holds the optional parameters of a BLAKE2s hash, as described in
+// the BLAKE2 specification. The zero Params describes BLAKE2s-256.
+type Params struct {
+	// Size is the digest size in bytes, between 1 and 32. Zero means 32.
+	Size int
+
+	// Key, if not empty, turns the hash into a MAC. It can be at most 32
+	// bytes long.
+	Key []byte
+
+	// Salt and Personal, if not empty, select distinct hash functions for
+	// randomized hashing and domain separation. Each can be at most 8
+	// bytes long, and shorter values are padded with zeros.
+	Salt, Personal []byte
+
+	// Tree, if not nil, makes the hash compute a node of a hash tree
+	// instead of hashing its input sequentially.
+	Tree *Tree
+}
+
+// Tree holds the parameters of a node of a BLAKE2s hash tree. Each node is
+// computed by its own hash, whose input is a leaf of the message or the
+// concatenated digests of the node's children.
+type Tree struct {
+	Fanout     uint8  // maximum number of children of a node, or 0 for unlimited
+	MaxDepth   uint8  // maximum depth of the tree, at least 1
+	LeafSize   uint32 // maximum leaf size in bytes, or 0 for unlimited
+	NodeOffset uint64 // offset of the node within its level, less than 2⁴⁸
+	NodeDepth  uint8  // depth of the node, from 0 for leaves
+	InnerSize  uint8  // digest size of inner nodes, between 0 and 32
+	IsLastNode bool   // whether the node is the last one of its level
+}
+
+// NewWithParams returns a new hash.Hash computing the BLAKE2s checksum with
+// the given parameters. Like New256, it implements BinaryMarshaler and
+// BinaryUnmarshaler if p.Key is empty; the state can only be restored into
+// a hash created with the same parameters.
+func NewWithParams(p *Params) (hash.Hash, error) {
+	return newDigestParams(p)
+}
+
+func newDigest(hashSize int, key []byte) (*digest, error) {
+	return newDigestParams(&Params{Size: hashSize, Key: key})
+}
+
+func newDigestParams(p *Params) (*digest, error) {
+	size := p.Size
+	if size == 0 {
+		size = Size
+	}
+	if size < 1 || size > Size {
+		return nil, errHashSize
+	}
+	if len(p.Key) > Size {
+		return nil, errKeySize
+	}
+	if len(p.Salt) > 8 {
+		return nil, errSalt
+	}
+	if len(p.Personal) > 8 {
+		return nil, errPersonal
+	}
+
+	var param [32]byte
+	param[0] = byte(size)
+	param[1] = byte(len(p.Key))
+	param[2], param[3] = 1, 1 // sequential mode
+	copy(param[16:], p.Salt)
+	copy(param[24:], p.Personal)
+	d := &digest{
+		size:   size,
+		keyLen: len(p.Key),
+	}
+	if t := p.Tree; t != nil {
+		if t.MaxDepth == 0 || t.InnerSize > Size || t.NodeOffset >= 1<<48 {
+			return nil, errTree
+		}
+		param[2] = t.Fanout
+		param[3] = t.MaxDepth
+		binary.LittleEndian.PutUint32(param[4:], t.LeafSize)
+		binary.LittleEndian.PutUint64(param[8:], t.NodeOffset)
+		param[14] = t.NodeDepth
+		param[15] = t.InnerSize
+		d.lastNode = t.IsLastNode
+	}
+	for i := range d.h0 {
+		d.h0[i] = iv[i] ^ binary.LittleEndian.Uint32(param[4*i:])
+	}
+	copy(d.key[:], p.Key)
+	d.Reset()
+	recordFallback()
+	return d, nil
+}
+
+func recordFallback() {
+	if boring.Enabled {
+		boring.RecordFallback(cryptometrics.BLAKE2s, "unsupported algorithm")
+	}
+}
+
+func checkSum(sum *[Size]byte, hashSize int, data []byte) {
+	var (
+		h [8]uint32
+		c [2]uint32
+	)
+
+	h = iv
+	h[0] ^= uint32(hashSize) | (1 << 16) | (1 << 24)
+
+	if length := len(data); length > BlockSize {
+		n := length &^ (BlockSize - 1)
+		if length == n {
+			n -= BlockSize
+		}
+		hashBlocks(&h, &c, 0, data[:n])
+		data = data[n:]
+	}
+
+	var block [BlockSize]byte
+	offset := copy(block[:], data)
+	remaining := uint32(BlockSize - offset)
+
+	if c[0] < remaining {
+		c[1]--
+	}
+	c[0] -= remaining
+
+	hashBlocks(&h, &c, 0xFFFFFFFF, block[:])
+
+	for i, v := range h {
+		binary.LittleEndian.PutUint32(sum[4*i:], v)
+	}
+}
+
+type digest struct {
+	h      [8]uint32
+	c      [2]uint32
+	size   int
+	block  [BlockSize]byte
+	offset int
+
+	key    [BlockSize]byte
+	keyLen int
+
+	h0       [8]uint32 // initial state, from the parameter block
+	lastNode bool
+}
+
+const (
+	magic         = "b2s"
+	marshaledSize = len(magic) + 8*4 + 2*4 + 1 + BlockSize + 1
+)
+
+func (d *digest) MarshalBinary() ([]byte, error) {
+	if d.keyLen != 0 {
+		return nil, errors.New("crypto/blake2s: cannot marshal MACs")
+	}
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	for i := 0; i < 8; i++ {
+		b = appendUint32(b, d.h[i])
+	}
+	b = appendUint32(b, d.c[0])
+	b = appendUint32(b, d.c[1])
+	// Maximum value for size is 32
+	b = append(b, byte(d.size))
+	b = append(b, d.block[:]...)
+	b = append(b, byte(d.offset))
+	return b, nil
+}
+
+func (d *digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("crypto/blake2s: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("crypto/blake2s: invalid hash state size")
+	}
+	b = b[len(magic):]
+	for i := 0; i < 8; i++ {
+		b, d.h[i] = consumeUint32(b)
+	}
+	b, d.c[0] = consumeUint32(b)
+	b, d.c[1] = consumeUint32(b)
+	d.size = int(b[0])
+	b = b[1:]
+	copy(d.block[:], b[:BlockSize])
+	b = b[BlockSize:]
+	d.offset = int(b[0])
+	return nil
+}
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Size() int { return d.size }
+
+func (d *digest) Reset() {
+	d.h = d.h0
+	d.offset, d.c[0], d.c[1] = 0, 0, 0
+	if d.keyLen > 0 {
+		d.block = d.key
+		d.offset = BlockSize
+	}
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	n = len(p)
+
+	if d.offset > 0 {
+		remaining := BlockSize - d.offset
+		if n <= remaining {
+			d.offset += copy(d.block[d.offset:], p)
+			return
+		}
+		copy(d.block[d.offset:], p[:remaining])
+		hashBlocks(&d.h, &d.c, 0, d.block[:])
+		d.offset = 0
+		p = p[remaining:]
+	}
+
+	if length := len(p); length > BlockSize {
+		nn := length &^ (BlockSize - 1)
+		if length == nn {
+			nn -= BlockSize
+		}
+		hashBlocks(&d.h, &d.c, 0, p[:nn])
+		p = p[nn:]
+	}
+
+	d.offset += copy(d.block[:], p)
+	return
+}
+
+func (d *digest) Sum(sum []byte) []byte {
+	var hash [Size]byte
+	d.finalize(&hash)
+	return append(sum, hash[:d.size]...)
+}
+
+func (d *digest) finalize(hash *[Size]byte) {
+	var block [BlockSize]byte
+	h := d.h
+	c := d.c
+
+	copy(block[:], d.block[:d.offset])
+	remaining := uint32(BlockSize - d.offset)
+	if c[0] < remaining {
+		c[1]--
+	}
+	c[0] -= remaining
+
+	if d.lastNode {
+		hashBlocksGenericLastNode(&h, &c, 0xFFFFFFFF, block[:])
+	} else {
+		hashBlocks(&h, &c, 0xFFFFFFFF, block[:])
+	}
+	for i, v := range h {
+		binary.LittleEndian.PutUint32(hash[4*i:], v)
+	}
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	var a [4]byte
+	binary.BigEndian.PutUint32(a[:], x)
+	return append(b, a[:]...)
+}
+
+func consumeUint32(b []byte) ([]byte, uint32) {
+	x := binary.BigEndian.Uint32(b)
+	return b[4:], x
+}
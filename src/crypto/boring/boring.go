@@ -7,15 +7,59 @@
 // Package boring exposes functions that are only available when building with
 // Go+BoringCrypto. This package is available on all targets as long as the
 // Go+BoringCrypto toolchain is used. Use the Enabled function to determine
-// whether the BoringCrypto core is actually in use.
+// whether the BoringCrypto core is actually in use, or crypto/fips.CurrentStatus
+// for details about the module and which operations it handles.
+//
+// The /crypto/backend metrics of package runtime/metrics count, for every
+// algorithm, the operations performed by BoringCrypto, such as
+// /crypto/backend/sha256/operations:calls, and those that fell back to the
+// pure Go implementation because BoringCrypto does not support their
+// parameters, such as /crypto/backend/hmac/fallbacks:calls.
 //
 // Any time the Go+BoringCrypto toolchain is used, the "boringcrypto" build tag
 // is satisfied, so that applications can tag files that use this package.
 package boring
 
-import "crypto/internal/boring"
+import (
+	"crypto"
+	"crypto/internal/boring"
+)
 
 // Enabled reports whether BoringCrypto handles supported crypto operations.
 func Enabled() bool {
 	return boring.Enabled
 }
+
+// Supports reports whether BoringCrypto computes the hash function h,
+// including as part of HMAC and signatures. It returns false if
+// BoringCrypto is not in use.
+func Supports(h crypto.Hash) bool {
+	if !boring.Enabled {
+		return false
+	}
+	name := h.String()
+	for _, r := range boring.Routes {
+		if r.Name == name {
+			return r.Routing == boring.RouteModule
+		}
+	}
+	return false
+}
+
+// SupportedAlgorithms returns the names of the algorithms, such as "SHA-256"
+// or "AES-GCM", for which BoringCrypto handles some or all operations,
+// sorted by name. It returns nil if BoringCrypto is not in use. Use
+// crypto/fips.CurrentStatus to find out which operations of an algorithm
+// BoringCrypto handles.
+func SupportedAlgorithms() []string {
+	if !boring.Enabled {
+		return nil
+	}
+	var names []string
+	for _, r := range boring.Routes {
+		if r.Routing != boring.RouteGo {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
@@ -7,6 +7,7 @@
 package boring_test
 
 import (
+	"crypto"
 	"crypto/boring"
 	"runtime"
 	"testing"
@@ -20,3 +21,38 @@ func TestEnabled(t *testing.T) {
 		t.Error("Enabled returned true on an unsupported platform")
 	}
 }
+
+func TestSupports(t *testing.T) {
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA512_224} {
+		if boring.Supports(h) != boring.Enabled() {
+			t.Errorf("Supports(%v) = %v, want %v", h, boring.Supports(h), boring.Enabled())
+		}
+	}
+	for _, h := range []crypto.Hash{crypto.MD5, crypto.SHA3_256, crypto.BLAKE2b_256, 0} {
+		if boring.Supports(h) {
+			t.Errorf("Supports(%v) = true", h)
+		}
+	}
+}
+
+func TestSupportedAlgorithms(t *testing.T) {
+	names := boring.SupportedAlgorithms()
+	if !boring.Enabled() {
+		if names != nil {
+			t.Errorf("SupportedAlgorithms = %q, want nil", names)
+		}
+		return
+	}
+	supported := make(map[string]bool)
+	for i, name := range names {
+		if i > 0 && names[i-1] >= name {
+			t.Errorf("SupportedAlgorithms not sorted: %q before %q", names[i-1], name)
+		}
+		supported[name] = true
+	}
+	for name, want := range map[string]bool{"SHA-256": true, "AES-GCM": true, "ECDSA": true, "Ed25519": false, "MD5": false} {
+		if supported[name] != want {
+			t.Errorf("SupportedAlgorithms includes %q: %v, want %v", name, supported[name], want)
+		}
+	}
+}
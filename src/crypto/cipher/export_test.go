@@ -7,3 +7,11 @@ package cipher
 // Export internal functions for testing.
 var NewCBCGenericEncrypter = newCBCGenericEncrypter
 var NewCBCGenericDecrypter = newCBCGenericDecrypter
+
+// SetMaxCounterNonces lowers the number of nonces available to
+// NewGCMWithCounterNonce, returning a function that restores it.
+func SetMaxCounterNonces(n uint64) (restore func()) {
+	old := maxCounterNonces
+	maxCounterNonces = n
+	return func() { maxCounterNonces = old }
+}
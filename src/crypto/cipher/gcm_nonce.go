@@ -0,0 +1,134 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/internal/alias"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// NewGCMWithRandomNonce returns the given 128-bit block cipher wrapped in
+// Galois Counter Mode, with nonces generated by reading from rand, which
+// should be a cryptographically secure source such as crypto/rand.Reader.
+//
+// The returned AEAD has a NonceSize of zero: Seal and Open must be passed
+// an empty nonce. Seal generates a random 96-bit nonce and prepends it to
+// the ciphertext, and Open reads it back from there, so Overhead includes
+// the nonce as well as the tag. Seal panics if reading from rand fails.
+// Since the nonce shifts the ciphertext, encrypting or decrypting in place
+// is not supported: the output of Seal may not overlap plaintext, nor the
+// output of Open ciphertext.
+//
+// Random nonces may collide once a key has been used for about 2³²
+// messages (NIST SP 800-38D, Section 8.3), so keys should be rotated well
+// before that.
+func NewGCMWithRandomNonce(cipher Block, rand io.Reader) (AEAD, error) {
+	if rand == nil {
+		return nil, errors.New("cipher: nil random source given to NewGCMWithRandomNonce")
+	}
+	g, err := NewGCM(cipher)
+	if err != nil {
+		return nil, err
+	}
+	return &gcmWithNonce{gcm: g, nextNonce: func(nonce []byte) {
+		if _, err := io.ReadFull(rand, nonce); err != nil {
+			panic("crypto/cipher: failed to read random nonce: " + err.Error())
+		}
+	}}, nil
+}
+
+// ErrNonceExhausted is the value of the panic raised by the Seal method of
+// an AEAD returned by NewGCMWithCounterNonce once every nonce has been used.
+var ErrNonceExhausted = errors.New("cipher: GCM nonces exhausted, the key must be replaced")
+
+// NewGCMWithCounterNonce returns the given 128-bit block cipher wrapped in
+// Galois Counter Mode, with nonces made of the 4-byte fixed field prefix
+// followed by a 64-bit big-endian invocation counter starting at zero, as
+// described in NIST SP 800-38D, Section 8.2.1.
+//
+// Nonces are managed and prepended to the ciphertext as for
+// NewGCMWithRandomNonce. Each key must be used with a single AEAD returned
+// by this function, or with several that have distinct prefixes; the
+// counter is safe for concurrent use. Seal panics with ErrNonceExhausted
+// instead of reusing a nonce.
+func NewGCMWithCounterNonce(cipher Block, prefix []byte) (AEAD, error) {
+	if len(prefix) != gcmStandardNonceSize-8 {
+		return nil, errors.New("cipher: GCM nonce prefix must be 4 bytes")
+	}
+	g, err := NewGCM(cipher)
+	if err != nil {
+		return nil, err
+	}
+	c := &gcmCounter{}
+	copy(c.prefix[:], prefix)
+	return &gcmWithNonce{gcm: g, nextNonce: c.next}, nil
+}
+
+// gcmCounter generates the deterministic nonces of NewGCMWithCounterNonce.
+type gcmCounter struct {
+	prefix [4]byte
+	// used is the number of nonces handed out.
+	used atomic.Uint64
+}
+
+// maxCounterNonces is the number of distinct counter values. It is a
+// variable so that tests can reach it.
+var maxCounterNonces uint64 = 1<<64 - 1
+
+func (c *gcmCounter) next(nonce []byte) {
+	n := c.used.Add(1)
+	if n == 0 || n > maxCounterNonces {
+		// Stay exhausted, even if Add wraps around.
+		c.used.Store(maxCounterNonces)
+		panic(ErrNonceExhausted)
+	}
+	copy(nonce, c.prefix[:])
+	binary.BigEndian.PutUint64(nonce[len(c.prefix):], n-1)
+}
+
+// gcmWithNonce is a GCM AEAD whose nonces are generated by nextNonce and
+// carried at the start of the ciphertext.
+type gcmWithNonce struct {
+	gcm       AEAD
+	nextNonce func(nonce []byte)
+}
+
+func (g *gcmWithNonce) NonceSize() int {
+	return 0
+}
+
+func (g *gcmWithNonce) Overhead() int {
+	return gcmStandardNonceSize + g.gcm.Overhead()
+}
+
+func (g *gcmWithNonce) Seal(dst, nonce, plaintext, data []byte) []byte {
+	if len(nonce) != 0 {
+		panic("crypto/cipher: non-empty nonce passed to GCM with managed nonces")
+	}
+	ret, out := sliceForAppend(dst, gcmStandardNonceSize+len(plaintext)+g.gcm.Overhead())
+	if alias.AnyOverlap(out, plaintext) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	nonce = out[:gcmStandardNonceSize]
+	g.nextNonce(nonce)
+	g.gcm.Seal(out[gcmStandardNonceSize:gcmStandardNonceSize], nonce, plaintext, data)
+	return ret
+}
+
+func (g *gcmWithNonce) Open(dst, nonce, ciphertext, data []byte) ([]byte, error) {
+	if len(nonce) != 0 {
+		panic("crypto/cipher: non-empty nonce passed to GCM with managed nonces")
+	}
+	if len(ciphertext) < g.Overhead() {
+		return nil, errOpen
+	}
+	if _, out := sliceForAppend(dst, len(ciphertext)-g.Overhead()); alias.AnyOverlap(out, ciphertext) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	return g.gcm.Open(dst, ciphertext[:gcmStandardNonceSize], ciphertext[gcmStandardNonceSize:], data)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cipher_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func newManagedGCMs(t *testing.T) map[string]cipher.AEAD {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	random, err := cipher.NewGCMWithRandomNonce(block, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter, err := cipher.NewGCMWithCounterNonce(block, []byte{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]cipher.AEAD{"random": random, "counter": counter}
+}
+
+func TestGCMManagedNonce(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 16))
+	plain, _ := cipher.NewGCM(block)
+	for name, aead := range newManagedGCMs(t) {
+		if aead.NonceSize() != 0 || aead.Overhead() != 12+16 {
+			t.Errorf("%s: NonceSize, Overhead = %d, %d", name, aead.NonceSize(), aead.Overhead())
+		}
+		msg, ad := []byte("hello"), []byte("header")
+		c1 := aead.Seal([]byte("prefix"), nil, msg, ad)
+		if !bytes.HasPrefix(c1, []byte("prefix")) {
+			t.Fatalf("%s: Seal didn't append to dst", name)
+		}
+		c1 = c1[len("prefix"):]
+		c2 := aead.Seal(nil, nil, msg, ad)
+		if len(c1) != len(msg)+aead.Overhead() {
+			t.Errorf("%s: ciphertext length = %d", name, len(c1))
+		}
+		if bytes.Equal(c1[:12], c2[:12]) {
+			t.Errorf("%s: nonce reused", name)
+		}
+
+		out, err := aead.Open(nil, nil, c1, ad)
+		if err != nil || !bytes.Equal(out, msg) {
+			t.Errorf("%s: Open = %q, %v", name, out, err)
+		}
+		// The prepended nonce is usable with a plain GCM.
+		out, err = plain.Open(nil, c2[:12], c2[12:], ad)
+		if err != nil || !bytes.Equal(out, msg) {
+			t.Errorf("%s: Open with plain GCM = %q, %v", name, out, err)
+		}
+
+		c1[0] ^= 1
+		if _, err := aead.Open(nil, nil, c1, ad); err == nil {
+			t.Errorf("%s: Open accepted a modified nonce", name)
+		}
+		if _, err := aead.Open(nil, nil, c1[:aead.Overhead()-1], ad); err == nil {
+			t.Errorf("%s: Open accepted a short ciphertext", name)
+		}
+	}
+}
+
+func TestGCMManagedNonceMisuse(t *testing.T) {
+	const (
+		errNonce   = "crypto/cipher: non-empty nonce passed to GCM with managed nonces"
+		errOverlap = "crypto/cipher: invalid buffer overlap"
+	)
+	for _, aead := range newManagedGCMs(t) {
+		mustPanic(t, errNonce, func() { aead.Seal(nil, make([]byte, 12), nil, nil) })
+		mustPanic(t, errNonce, func() { aead.Open(nil, make([]byte, 12), make([]byte, 28), nil) })
+		buf := make([]byte, 64)
+		mustPanic(t, errOverlap, func() { aead.Seal(buf[:0], nil, buf[:16], nil) })
+		ct := aead.Seal(nil, nil, []byte("message"), nil)
+		mustPanic(t, errOverlap, func() { aead.Open(ct[:0], nil, ct, nil) })
+	}
+}
+
+func TestGCMCounterNonce(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 16))
+	if _, err := cipher.NewGCMWithCounterNonce(block, make([]byte, 12)); err == nil {
+		t.Error("NewGCMWithCounterNonce accepted a 12-byte prefix")
+	}
+
+	defer cipher.SetMaxCounterNonces(3)()
+	aead, err := cipher.NewGCMWithCounterNonce(block, []byte{0xde, 0xad, 0xbe, 0xef})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		c := aead.Seal(nil, nil, nil, nil)
+		if got, want := hex.EncodeToString(c[:12]), "deadbeef000000000000000"+string(rune('0'+i)); got != want {
+			t.Errorf("nonce %d = %s, want %s", i, got, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); !errors.Is(err, cipher.ErrNonceExhausted) {
+					t.Errorf("Seal after exhaustion panicked with %v, want ErrNonceExhausted", err)
+				}
+			}()
+			aead.Seal(nil, nil, nil, nil)
+		}()
+	}
+}
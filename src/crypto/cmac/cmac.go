@@ -0,0 +1,133 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cmac implements the CMAC message authentication code of NIST
+// SP 800-38B, also known as OMAC1. With AES, it is AES-CMAC, as specified
+// by RFC 4493.
+//
+// CMAC is used by protocols such as GlobalPlatform SCP03, EMV, and
+// several IoT protocols. Where there is a choice, crypto/hmac should be
+// preferred. Receivers should compare MACs with crypto/hmac.Equal, which
+// doesn't leak timing information.
+//
+// AES-CMAC is performed by the cryptographic backend when one that
+// implements it is in use. Otherwise, it is implemented over the
+// crypto/aes block cipher, which is itself provided by the backend, if any.
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/boring"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+)
+
+// NewAES returns a new hash.Hash computing AES-CMAC with key, an AES key
+// of 16, 24, or 32 bytes.
+func NewAES(key []byte) (hash.Hash, error) {
+	if boring.Enabled {
+		if boring.SupportsCMAC() {
+			return boring.NewCMAC(key)
+		}
+		boring.RecordFallback(cryptometrics.AESCMAC, "unsupported by the module")
+	}
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return New(b)
+}
+
+// New returns a new hash.Hash computing the CMAC with the block cipher b,
+// whose block size must be 8 or 16 bytes, such as Triple DES for EMV.
+// For AES, NewAES allows a cryptographic backend to compute the MAC.
+func New(b cipher.Block) (hash.Hash, error) {
+	// rb is the last byte of the constant R_b of SP 800-38B, Section 5.3.
+	var rb byte
+	switch b.BlockSize() {
+	case 8:
+		rb = 0x1b
+	case 16:
+		rb = 0x87
+	default:
+		return nil, errors.New("cmac: block size must be 8 or 16 bytes")
+	}
+	bs := b.BlockSize()
+	c := &cmac{
+		b:   b,
+		k1:  make([]byte, bs),
+		k2:  make([]byte, bs),
+		x:   make([]byte, bs),
+		buf: make([]byte, bs),
+	}
+	// Generate the subkeys, from SP 800-38B, Section 6.1.
+	l := make([]byte, bs)
+	b.Encrypt(l, l)
+	double(c.k1, l, rb)
+	double(c.k2, c.k1, rb)
+	clear(l)
+	return c, nil
+}
+
+// double sets dst to the doubling of src in GF(2^n), in constant time.
+func double(dst, src []byte, rb byte) {
+	msb := src[0] >> 7
+	for i := 0; i < len(src)-1; i++ {
+		dst[i] = src[i]<<1 | src[i+1]>>7
+	}
+	dst[len(src)-1] = src[len(src)-1]<<1 ^ rb&-msb
+}
+
+type cmac struct {
+	b      cipher.Block
+	k1, k2 []byte
+
+	// x is the chaining value, and buf holds the last n bytes of input,
+	// which are only processed by Sum or by the next Write, since the
+	// last block is processed differently.
+	x   []byte
+	buf []byte
+	n   int
+}
+
+func (c *cmac) Size() int      { return len(c.x) }
+func (c *cmac) BlockSize() int { return len(c.x) }
+
+func (c *cmac) Reset() {
+	clear(c.x)
+	clear(c.buf)
+	c.n = 0
+}
+
+func (c *cmac) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if c.n == len(c.buf) {
+			subtle.XORBytes(c.x, c.x, c.buf)
+			c.b.Encrypt(c.x, c.x)
+			c.n = 0
+		}
+		m := copy(c.buf[c.n:], p)
+		c.n += m
+		p = p[m:]
+	}
+	return n, nil
+}
+
+func (c *cmac) Sum(in []byte) []byte {
+	last := make([]byte, len(c.buf))
+	copy(last, c.buf[:c.n])
+	if c.n == len(last) {
+		subtle.XORBytes(last, last, c.k1)
+	} else {
+		last[c.n] = 0x80
+		subtle.XORBytes(last, last, c.k2)
+	}
+	subtle.XORBytes(last, last, c.x)
+	c.b.Encrypt(last, last)
+	return append(in, last...)
+}
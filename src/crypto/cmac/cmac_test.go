@@ -0,0 +1,123 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/des"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// message is the message of the examples of RFC 4493 and SP 800-38B.
+const message = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+	"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+var aesTests = []struct {
+	key string
+	n   int // length of the message
+	mac string
+}{
+	// RFC 4493, Section 4.
+	{"2b7e151628aed2a6abf7158809cf4f3c", 0, "bb1d6929e95937287fa37d129b756746"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", 16, "070a16b46b4d4144f79bdd9dd04a287c"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", 40, "dfa66747de9ae63030ca32611497c827"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", 64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	// SP 800-38B, Appendix D.3.
+	{"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", 40, "aaf3d8f1de5640c232f5b169b9c911e6"},
+}
+
+func TestAES(t *testing.T) {
+	msg := decodeHex(t, message)
+	for _, tt := range aesTests {
+		key, want := decodeHex(t, tt.key), decodeHex(t, tt.mac)
+		b, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generic, err := New(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := NewAES(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range []hash.Hash{generic, h} {
+			h.Write(msg[:tt.n])
+			if got := h.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("%T: AES-%d-CMAC of %d bytes = %x, want %x", h, len(key)*8, tt.n, got, want)
+			}
+		}
+	}
+}
+
+func TestTripleDES(t *testing.T) {
+	b, err := des.NewTripleDESCipher(decodeHex(t, "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := New(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write(decodeHex(t, message)[:16])
+	if got, want := hex.EncodeToString(h.Sum(nil)), "286d394673448197"; got != want {
+		t.Errorf("TDEA-CMAC = %s, want %s", got, want)
+	}
+}
+
+// TestStreaming checks that the MAC doesn't depend on how the message is
+// split into writes, that Sum doesn't change the state, and that Reset
+// restarts it.
+func TestStreaming(t *testing.T) {
+	key := decodeHex(t, aesTests[0].key)
+	msg := decodeHex(t, message)
+	h, err := NewAES(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write(msg)
+	want := h.Sum(nil)
+	for _, split := range []int{1, 7, 15, 16, 17, 32} {
+		h.Reset()
+		for p := msg; len(p) > 0; {
+			n := split
+			if n > len(p) {
+				n = len(p)
+			}
+			h.Write(p[:n])
+			p = p[n:]
+			h.Sum(nil)
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("with writes of %d bytes: CMAC = %x, want %x", split, got, want)
+		}
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	if _, err := NewAES(make([]byte, 15)); err == nil {
+		t.Error("NewAES with a 15-byte key succeeded")
+	}
+	b, err := des.NewCipher(make([]byte, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(b); err != nil {
+		t.Errorf("New with DES: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cms implements a subset of the Cryptographic Message Syntax, as
+// defined in RFC 5652, the format used by S/MIME, PKCS #7 signatures and
+// many firmware signing schemes.
+//
+// The SignedData content type is supported for creation and verification,
+// with attached or detached content. Encrypted messages are created as
+// AuthEnvelopedData (RFC 5083) with AES-GCM, or as EnvelopedData with
+// AES-CBC, for recipients identified by a key-encryption key
+// (KEKRecipientInfo) or by an RSA certificate (KeyTransRecipientInfo).
+//
+// Messages must be DER encoded. Indefinite-length BER encodings, as
+// produced by some streaming encoders, are not supported.
+package cms
+
+import (
+	"crypto"
+	"crypto/digest"
+	"encoding/asn1"
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var (
+	// OIDData is the id-data content type, used for arbitrary octet strings.
+	OIDData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+
+	oidSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidAuthEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 23}
+
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidSHA256, _ = oidForHash(crypto.SHA256)
+
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSAESOAEP       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidMGF1            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	oidSHA1WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+	oidAES128CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidAES128GCM       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 6}
+	oidAES256GCM       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+	oidAES128Wrap      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 5}
+	oidAES192Wrap      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 25}
+	oidAES256Wrap      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 45}
+	oidPSpecified      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 9}
+)
+
+var errUnsupported = errors.New("cms: unsupported algorithm")
+
+// digestAlgorithms are the supported digest algorithms. Their OIDs come
+// from the crypto/digest registry.
+var digestAlgorithms = []crypto.Hash{
+	crypto.SHA1,
+	crypto.SHA224,
+	crypto.SHA256,
+	crypto.SHA384,
+	crypto.SHA512,
+}
+
+func hashForOID(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	a, ok := digest.LookupOID(oid)
+	if !ok {
+		return 0, false
+	}
+	for _, h := range digestAlgorithms {
+		if a.Hash() == h {
+			return h, true
+		}
+	}
+	return 0, false
+}
+
+func oidForHash(h crypto.Hash) (asn1.ObjectIdentifier, bool) {
+	for _, d := range digestAlgorithms {
+		if d == h {
+			a, _ := digest.Lookup(h)
+			return a.OID(), true
+		}
+	}
+	return nil, false
+}
+
+// addAlgorithmIdentifier adds an AlgorithmIdentifier with the given OID and,
+// if params is not nil, parameters. A nil params omits the parameters, while
+// an empty non-nil params adds an explicit NULL.
+func addAlgorithmIdentifier(b *cryptobyte.Builder, oid asn1.ObjectIdentifier, params []byte) {
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oid)
+		if params != nil {
+			if len(params) == 0 {
+				b.AddASN1NULL()
+			} else {
+				b.AddBytes(params)
+			}
+		}
+	})
+}
+
+// readAlgorithmIdentifier reads an AlgorithmIdentifier, returning its OID
+// and its parameters, if any, as a complete DER element.
+func readAlgorithmIdentifier(s *cryptobyte.String) (oid asn1.ObjectIdentifier, params cryptobyte.String, ok bool) {
+	var alg cryptobyte.String
+	if !s.ReadASN1(&alg, cryptobyte_asn1.SEQUENCE) || !alg.ReadASN1ObjectIdentifier(&oid) {
+		return nil, nil, false
+	}
+	if !alg.Empty() {
+		var tag cryptobyte_asn1.Tag
+		if !alg.ReadAnyASN1Element(&params, &tag) || !alg.Empty() {
+			return nil, nil, false
+		}
+		if tag == cryptobyte_asn1.NULL {
+			params = nil
+		}
+	}
+	return oid, params, true
+}
+
+// marshalContentInfo wraps content in a ContentInfo of the given type.
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(contentType)
+		b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddBytes(content)
+		})
+	})
+	return b.Bytes()
+}
+
+// parseContentInfo parses a ContentInfo, returning its type and the DER
+// encoding of its content.
+func parseContentInfo(der []byte) (asn1.ObjectIdentifier, cryptobyte.String, error) {
+	input := cryptobyte.String(der)
+	var ci, content cryptobyte.String
+	var contentType asn1.ObjectIdentifier
+	if !input.ReadASN1(&ci, cryptobyte_asn1.SEQUENCE) || !input.Empty() ||
+		!ci.ReadASN1ObjectIdentifier(&contentType) ||
+		!ci.ReadASN1(&content, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!ci.Empty() {
+		return nil, nil, errors.New("cms: malformed ContentInfo")
+	}
+	return contentType, content, nil
+}
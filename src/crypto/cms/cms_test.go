@@ -0,0 +1,96 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readTestFile(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// testOpenSSLIdentity returns the certificate and key used to generate the
+// OpenSSL test vectors in testdata.
+func testOpenSSLIdentity(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	block, _ := pem.Decode(readTestFile(t, "cert.pem"))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(testingKey(string(readTestFile(t, "key.pem")))))
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key.(*rsa.PrivateKey)
+}
+
+func testingKey(s string) string { return strings.ReplaceAll(s, "TESTING KEY", "KEY") }
+
+var (
+	testRSAKeyOnce sync.Once
+	testRSAKey     *rsa.PrivateKey
+)
+
+// testIdentity returns a self-signed certificate and key of the given type,
+// "RSA", "ECDSA" or "Ed25519".
+func testIdentity(t *testing.T, keyType string) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	var key crypto.Signer
+	var err error
+	switch keyType {
+	case "RSA":
+		testRSAKeyOnce.Do(func() {
+			testRSAKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		})
+		key = testRSAKey
+	case "ECDSA":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "Ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: keyType + " signer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
@@ -0,0 +1,643 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/keyguard"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// A Cipher is a content-encryption algorithm.
+type Cipher int
+
+const (
+	// AES256GCM produces AuthEnvelopedData with AES-256 in GCM mode.
+	AES256GCM Cipher = iota
+	// AES128GCM produces AuthEnvelopedData with AES-128 in GCM mode.
+	AES128GCM
+	// AES256CBC produces EnvelopedData with AES-256 in CBC mode. It
+	// provides no integrity protection, and should only be used for
+	// compatibility with recipients that don't support AuthEnvelopedData.
+	AES256CBC
+	// AES128CBC produces EnvelopedData with AES-128 in CBC mode. See
+	// AES256CBC.
+	AES128CBC
+)
+
+func (c Cipher) keySize() int {
+	switch c {
+	case AES128GCM, AES128CBC:
+		return 16
+	default:
+		return 32
+	}
+}
+
+func (c Cipher) oid() asn1.ObjectIdentifier {
+	switch c {
+	case AES128GCM:
+		return oidAES128GCM
+	case AES256CBC:
+		return oidAES256CBC
+	case AES128CBC:
+		return oidAES128CBC
+	default:
+		return oidAES256GCM
+	}
+}
+
+// EncryptOptions configures Encrypt.
+type EncryptOptions struct {
+	// Cipher is the content-encryption algorithm. The default is
+	// AES256GCM.
+	Cipher Cipher
+
+	// ContentType is the type of the encrypted content. If nil, OIDData is
+	// used.
+	ContentType asn1.ObjectIdentifier
+}
+
+// A Recipient is a party that can decrypt a message. It is implemented by
+// *KEKRecipient and *KeyTransRecipient.
+type Recipient interface {
+	// marshalRecipientInfo returns the DER encoding of a RecipientInfo
+	// carrying cek for this recipient, and its version.
+	marshalRecipientInfo(rand io.Reader, cek []byte) (ri []byte, version int64, err error)
+
+	// decryptKey returns the content-encryption key of length keySize from
+	// the RecipientInfo ri, or errNotRecipient if ri is not addressed to
+	// this recipient.
+	decryptKey(ri cryptobyte.String, keySize int) ([]byte, error)
+}
+
+var errNotRecipient = errors.New("cms: not a matching recipient")
+
+// KEKRecipient is a recipient that shares a symmetric key-encryption key
+// with the sender, identified by a KEKRecipientInfo. The content-encryption
+// key is wrapped with the AES Key Wrap algorithm of RFC 3394.
+type KEKRecipient struct {
+	// KeyID identifies the key-encryption key.
+	KeyID []byte
+
+	// Key is the AES key-encryption key, which must be 16, 24 or 32 bytes.
+	Key []byte
+}
+
+func (r *KEKRecipient) wrapOID() (asn1.ObjectIdentifier, error) {
+	switch len(r.Key) {
+	case 16:
+		return oidAES128Wrap, nil
+	case 24:
+		return oidAES192Wrap, nil
+	case 32:
+		return oidAES256Wrap, nil
+	}
+	return nil, errors.New("cms: invalid key-encryption key length")
+}
+
+func (r *KEKRecipient) marshalRecipientInfo(rand io.Reader, cek []byte) ([]byte, int64, error) {
+	oid, err := r.wrapOID()
+	if err != nil {
+		return nil, 0, err
+	}
+	wrapped, err := wrapKey(r.Key, cek)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// KEKRecipientInfo ::= SEQUENCE {
+	//      version CMSVersion,  -- always set to 4
+	//      kekid KEKIdentifier,
+	//      keyEncryptionAlgorithm KeyEncryptionAlgorithmIdentifier,
+	//      encryptedKey EncryptedKey }
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.Tag(2).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(4)
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1OctetString(r.KeyID)
+		})
+		addAlgorithmIdentifier(b, oid, nil)
+		b.AddASN1OctetString(wrapped)
+	})
+	ri, err := b.Bytes()
+	return ri, 4, err
+}
+
+func (r *KEKRecipient) decryptKey(ri cryptobyte.String, keySize int) ([]byte, error) {
+	var kekri, kekid, keyID, encryptedKey cryptobyte.String
+	var version int64
+	if !ri.ReadASN1(&kekri, cryptobyte_asn1.Tag(2).Constructed().ContextSpecific()) {
+		return nil, errNotRecipient
+	}
+	if !kekri.ReadASN1Int64WithTag(&version, cryptobyte_asn1.INTEGER) ||
+		!kekri.ReadASN1(&kekid, cryptobyte_asn1.SEQUENCE) ||
+		!kekid.ReadASN1(&keyID, cryptobyte_asn1.OCTET_STRING) {
+		return nil, errors.New("cms: malformed KEKRecipientInfo")
+	}
+	if !bytes.Equal(keyID, r.KeyID) {
+		return nil, errNotRecipient
+	}
+	oid, _, ok := readAlgorithmIdentifier(&kekri)
+	if !ok || !kekri.ReadASN1(&encryptedKey, cryptobyte_asn1.OCTET_STRING) || !kekri.Empty() {
+		return nil, errors.New("cms: malformed KEKRecipientInfo")
+	}
+	if want, err := r.wrapOID(); err != nil {
+		return nil, err
+	} else if !oid.Equal(want) {
+		return nil, errUnsupported
+	}
+	cek, err := unwrapKey(r.Key, encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(cek) != keySize {
+		return nil, errors.New("cms: content-encryption key has wrong length")
+	}
+	return cek, nil
+}
+
+// KeyTransRecipient is a recipient identified by an RSA certificate, for
+// which a KeyTransRecipientInfo is generated.
+//
+// Encrypt uses RSAES-OAEP with SHA-256. Decrypt also accepts RSAES-OAEP with
+// other hash functions, and RSAES-PKCS1-v1_5 for compatibility with other
+// implementations.
+type KeyTransRecipient struct {
+	// Certificate is the recipient's certificate, which must hold an RSA
+	// public key.
+	Certificate *x509.Certificate
+
+	// Key is the recipient's private key. It is only needed by Decrypt,
+	// and must implement RSA decryption with *rsa.OAEPOptions and
+	// *rsa.PKCS1v15DecryptOptions, as *rsa.PrivateKey does.
+	Key crypto.Decrypter
+}
+
+func (r *KeyTransRecipient) marshalRecipientInfo(rand io.Reader, cek []byte) ([]byte, int64, error) {
+	pub, ok := r.Certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, 0, errors.New("cms: recipient certificate does not hold an RSA key")
+	}
+	encryptedKey, err := rsa.EncryptOAEP(crypto.SHA256.New(), rand, pub, cek, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// RSAES-OAEP-params ::= SEQUENCE {
+	//      hashAlgorithm      [0] HashAlgorithm     DEFAULT sha1,
+	//      maskGenAlgorithm   [1] MaskGenAlgorithm  DEFAULT mgf1SHA1,
+	//      pSourceAlgorithm   [2] PSourceAlgorithm  DEFAULT pSpecifiedEmpty }
+	var params cryptobyte.Builder
+	params.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			addAlgorithmIdentifier(b, oidSHA256, nil)
+		})
+		b.AddASN1(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1ObjectIdentifier(oidMGF1)
+				addAlgorithmIdentifier(b, oidSHA256, nil)
+			})
+		})
+	})
+
+	// KeyTransRecipientInfo ::= SEQUENCE {
+	//      version CMSVersion,  -- always set to 0 or 2
+	//      rid RecipientIdentifier,
+	//      keyEncryptionAlgorithm KeyEncryptionAlgorithmIdentifier,
+	//      encryptedKey EncryptedKey }
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(0)
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddBytes(r.Certificate.RawIssuer)
+			b.AddASN1BigInt(r.Certificate.SerialNumber)
+		})
+		addAlgorithmIdentifier(b, oidRSAESOAEP, params.BytesOrPanic())
+		b.AddASN1OctetString(encryptedKey)
+	})
+	ri, err := b.Bytes()
+	return ri, 0, err
+}
+
+func (r *KeyTransRecipient) decryptKey(ri cryptobyte.String, keySize int) ([]byte, error) {
+	var ktri, encryptedKey cryptobyte.String
+	var version int64
+	if !ri.ReadASN1(&ktri, cryptobyte_asn1.SEQUENCE) {
+		return nil, errNotRecipient
+	}
+	errMalformed := errors.New("cms: malformed KeyTransRecipientInfo")
+	if !ktri.ReadASN1Int64WithTag(&version, cryptobyte_asn1.INTEGER) {
+		return nil, errMalformed
+	}
+	switch {
+	case ktri.PeekASN1Tag(cryptobyte_asn1.SEQUENCE):
+		var ias, issuer cryptobyte.String
+		serial := new(big.Int)
+		if !ktri.ReadASN1(&ias, cryptobyte_asn1.SEQUENCE) ||
+			!ias.ReadASN1Element(&issuer, cryptobyte_asn1.SEQUENCE) ||
+			!ias.ReadASN1Integer(serial) || !ias.Empty() {
+			return nil, errMalformed
+		}
+		if !bytes.Equal(issuer, r.Certificate.RawIssuer) || serial.Cmp(r.Certificate.SerialNumber) != 0 {
+			return nil, errNotRecipient
+		}
+	case ktri.PeekASN1Tag(cryptobyte_asn1.Tag(0).ContextSpecific()):
+		var skid cryptobyte.String
+		if !ktri.ReadASN1(&skid, cryptobyte_asn1.Tag(0).ContextSpecific()) {
+			return nil, errMalformed
+		}
+		if len(r.Certificate.SubjectKeyId) == 0 || !bytes.Equal(skid, r.Certificate.SubjectKeyId) {
+			return nil, errNotRecipient
+		}
+	default:
+		return nil, errMalformed
+	}
+
+	oid, params, ok := readAlgorithmIdentifier(&ktri)
+	if !ok || !ktri.ReadASN1(&encryptedKey, cryptobyte_asn1.OCTET_STRING) || !ktri.Empty() {
+		return nil, errMalformed
+	}
+	if r.Key == nil {
+		return nil, errors.New("cms: recipient has no private key")
+	}
+
+	var cek []byte
+	var err error
+	switch {
+	case oid.Equal(oidRSAEncryption):
+		// With SessionKeyLen set, a padding error results in a random key
+		// rather than an error, so that decryption fails in constant time
+		// later on, when the content is decrypted.
+		cek, err = r.Key.Decrypt(rand.Reader, encryptedKey, &rsa.PKCS1v15DecryptOptions{SessionKeyLen: keySize})
+	case oid.Equal(oidRSAESOAEP):
+		var opts *rsa.OAEPOptions
+		if opts, err = parseOAEPParams(params); err != nil {
+			return nil, err
+		}
+		cek, err = r.Key.Decrypt(rand.Reader, encryptedKey, opts)
+	default:
+		return nil, errUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cek) != keySize {
+		return nil, errors.New("cms: content-encryption key has wrong length")
+	}
+	return cek, nil
+}
+
+func parseOAEPParams(params cryptobyte.String) (*rsa.OAEPOptions, error) {
+	opts := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	if params == nil {
+		return opts, nil
+	}
+	errMalformed := errors.New("cms: malformed RSAES-OAEP parameters")
+	var seq, hashAlg, mgfAlg, pSource cryptobyte.String
+	var hasHash, hasMGF, hasPSource bool
+	if !params.ReadASN1(&seq, cryptobyte_asn1.SEQUENCE) ||
+		!seq.ReadOptionalASN1(&hashAlg, &hasHash, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!seq.ReadOptionalASN1(&mgfAlg, &hasMGF, cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) ||
+		!seq.ReadOptionalASN1(&pSource, &hasPSource, cryptobyte_asn1.Tag(2).Constructed().ContextSpecific()) ||
+		!seq.Empty() {
+		return nil, errMalformed
+	}
+	if hasHash {
+		oid, _, ok := readAlgorithmIdentifier(&hashAlg)
+		if !ok || !hashAlg.Empty() {
+			return nil, errMalformed
+		}
+		if opts.Hash, ok = hashForOID(oid); !ok {
+			return nil, errUnsupported
+		}
+	}
+	if hasMGF {
+		oid, mgfParams, ok := readAlgorithmIdentifier(&mgfAlg)
+		if !ok || !mgfAlg.Empty() {
+			return nil, errMalformed
+		}
+		if !oid.Equal(oidMGF1) {
+			return nil, errUnsupported
+		}
+		hashOID, _, ok := readAlgorithmIdentifier(&mgfParams)
+		if !ok || !mgfParams.Empty() {
+			return nil, errMalformed
+		}
+		if opts.MGFHash, ok = hashForOID(hashOID); !ok {
+			return nil, errUnsupported
+		}
+	}
+	if hasPSource {
+		oid, label, ok := readAlgorithmIdentifier(&pSource)
+		if !ok || !pSource.Empty() {
+			return nil, errMalformed
+		}
+		if !oid.Equal(oidPSpecified) {
+			return nil, errUnsupported
+		}
+		if label != nil && !label.ReadASN1Bytes(&opts.Label, cryptobyte_asn1.OCTET_STRING) {
+			return nil, errMalformed
+		}
+	}
+	return opts, nil
+}
+
+// Encrypt returns a DER encoded ContentInfo holding content encrypted for
+// recipients, using a freshly generated content-encryption key. With a GCM
+// cipher, the result is an AuthEnvelopedData, otherwise it is an
+// EnvelopedData.
+func Encrypt(rand io.Reader, content []byte, recipients []Recipient, opts *EncryptOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &EncryptOptions{}
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("cms: no recipients")
+	}
+	contentType := opts.ContentType
+	if contentType == nil {
+		contentType = OIDData
+	}
+
+	cek := keyguard.Make(opts.Cipher.keySize())
+	if _, err := io.ReadFull(rand, cek); err != nil {
+		return nil, err
+	}
+	defer keyguard.Free(cek)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipientInfos [][]byte
+	version := int64(0)
+	for _, r := range recipients {
+		ri, v, err := r.marshalRecipientInfo(rand, cek)
+		if err != nil {
+			return nil, err
+		}
+		if v != 0 {
+			version = 2
+		}
+		recipientInfos = append(recipientInfos, ri)
+	}
+	sortDER(recipientInfos)
+	addRecipientInfos := func(b *cryptobyte.Builder) {
+		b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+			for _, ri := range recipientInfos {
+				b.AddBytes(ri)
+			}
+		})
+	}
+
+	var b cryptobyte.Builder
+	var outerType asn1.ObjectIdentifier
+	switch opts.Cipher {
+	case AES128GCM, AES256GCM:
+		outerType = oidAuthEnvelopedData
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand, nonce); err != nil {
+			return nil, err
+		}
+		sealed := gcm.Seal(nil, nonce, content, nil)
+		ciphertext, tag := sealed[:len(content)], sealed[len(content):]
+
+		// GCMParameters ::= SEQUENCE {
+		//      aes-nonce        OCTET STRING,
+		//      aes-ICVlen       AES-GCM-ICVlen DEFAULT 12 }
+		var params cryptobyte.Builder
+		params.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1OctetString(nonce)
+			b.AddASN1Int64(int64(len(tag)))
+		})
+
+		// AuthEnvelopedData ::= SEQUENCE {
+		//      version CMSVersion,
+		//      originatorInfo [0] IMPLICIT OriginatorInfo OPTIONAL,
+		//      recipientInfos RecipientInfos,
+		//      authEncryptedContentInfo EncryptedContentInfo,
+		//      authAttrs [1] IMPLICIT AuthAttributes OPTIONAL,
+		//      mac MessageAuthenticationCode,
+		//      unauthAttrs [2] IMPLICIT UnauthAttributes OPTIONAL }
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1Int64(0)
+			addRecipientInfos(b)
+			addEncryptedContentInfo(b, contentType, opts.Cipher.oid(), params.BytesOrPanic(), ciphertext)
+			b.AddASN1OctetString(tag)
+		})
+	default:
+		outerType = oidEnvelopedData
+		iv := make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(rand, iv); err != nil {
+			return nil, err
+		}
+		padLen := aes.BlockSize - len(content)%aes.BlockSize
+		ciphertext := make([]byte, len(content)+padLen)
+		copy(ciphertext, content)
+		for i := len(content); i < len(ciphertext); i++ {
+			ciphertext[i] = byte(padLen)
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+		var params cryptobyte.Builder
+		params.AddASN1OctetString(iv)
+
+		// EnvelopedData ::= SEQUENCE {
+		//      version CMSVersion,
+		//      originatorInfo [0] IMPLICIT OriginatorInfo OPTIONAL,
+		//      recipientInfos RecipientInfos,
+		//      encryptedContentInfo EncryptedContentInfo,
+		//      unprotectedAttrs [1] IMPLICIT UnprotectedAttributes OPTIONAL }
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1Int64(version)
+			addRecipientInfos(b)
+			addEncryptedContentInfo(b, contentType, opts.Cipher.oid(), params.BytesOrPanic(), ciphertext)
+		})
+	}
+	data, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return marshalContentInfo(outerType, data)
+}
+
+func addEncryptedContentInfo(b *cryptobyte.Builder, contentType, alg asn1.ObjectIdentifier, params, ciphertext []byte) {
+	// EncryptedContentInfo ::= SEQUENCE {
+	//      contentType ContentType,
+	//      contentEncryptionAlgorithm ContentEncryptionAlgorithmIdentifier,
+	//      encryptedContent [0] IMPLICIT EncryptedContent OPTIONAL }
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(contentType)
+		addAlgorithmIdentifier(b, alg, params)
+		b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddBytes(ciphertext)
+		})
+	})
+}
+
+// Decrypt decrypts a DER encoded ContentInfo holding an EnvelopedData or an
+// AuthEnvelopedData addressed to recipient, and returns the content.
+func Decrypt(der []byte, recipient Recipient) ([]byte, error) {
+	contentType, content, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	authenticated := contentType.Equal(oidAuthEnvelopedData)
+	if !authenticated && !contentType.Equal(oidEnvelopedData) {
+		return nil, errors.New("cms: content is not EnvelopedData or AuthEnvelopedData")
+	}
+
+	errMalformed := errors.New("cms: malformed EnvelopedData")
+	var ed, recipientInfos, eci, ciphertext, authAttrs, mac cryptobyte.String
+	var version int64
+	var hasAuthAttrs bool
+	if !content.ReadASN1(&ed, cryptobyte_asn1.SEQUENCE) || !content.Empty() ||
+		!ed.ReadASN1Int64WithTag(&version, cryptobyte_asn1.INTEGER) ||
+		!ed.SkipOptionalASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!ed.ReadASN1(&recipientInfos, cryptobyte_asn1.SET) ||
+		!ed.ReadASN1(&eci, cryptobyte_asn1.SEQUENCE) {
+		return nil, errMalformed
+	}
+	if authenticated {
+		if !ed.ReadOptionalASN1(&authAttrs, &hasAuthAttrs, cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) ||
+			!ed.ReadASN1(&mac, cryptobyte_asn1.OCTET_STRING) ||
+			!ed.SkipOptionalASN1(cryptobyte_asn1.Tag(2).Constructed().ContextSpecific()) {
+			return nil, errMalformed
+		}
+	} else if !ed.SkipOptionalASN1(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) {
+		return nil, errMalformed
+	}
+	if !ed.Empty() {
+		return nil, errMalformed
+	}
+
+	var hasCiphertext bool
+	if !eci.SkipASN1(cryptobyte_asn1.OBJECT_IDENTIFIER) {
+		return nil, errMalformed
+	}
+	algOID, params, ok := readAlgorithmIdentifier(&eci)
+	if !ok || !eci.ReadOptionalASN1(&ciphertext, &hasCiphertext, cryptobyte_asn1.Tag(0).ContextSpecific()) ||
+		!eci.Empty() {
+		return nil, errMalformed
+	}
+	if !hasCiphertext {
+		return nil, errors.New("cms: encrypted content is detached")
+	}
+
+	var keySize int
+	var gcm bool
+	switch {
+	case algOID.Equal(oidAES128GCM):
+		keySize, gcm = 16, true
+	case algOID.Equal(oidAES256GCM):
+		keySize, gcm = 32, true
+	case algOID.Equal(oidAES128CBC):
+		keySize = 16
+	case algOID.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, errUnsupported
+	}
+	if gcm != authenticated {
+		return nil, errUnsupported
+	}
+
+	var cek []byte
+	for !recipientInfos.Empty() {
+		var ri cryptobyte.String
+		var tag cryptobyte_asn1.Tag
+		if !recipientInfos.ReadAnyASN1Element(&ri, &tag) {
+			return nil, errMalformed
+		}
+		cek, err = recipient.decryptKey(ri, keySize)
+		if err == errNotRecipient {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if cek == nil {
+		return nil, errors.New("cms: no RecipientInfo for recipient")
+	}
+	defer keyguard.Free(cek)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	if gcm {
+		var gcmParams, nonce cryptobyte.String
+		tagSize := int64(12)
+		if params == nil || !params.ReadASN1(&gcmParams, cryptobyte_asn1.SEQUENCE) ||
+			!gcmParams.ReadASN1(&nonce, cryptobyte_asn1.OCTET_STRING) ||
+			(!gcmParams.Empty() && !gcmParams.ReadASN1Integer(&tagSize)) ||
+			!gcmParams.Empty() {
+			return nil, errors.New("cms: malformed GCM parameters")
+		}
+		if len(nonce) != 12 || tagSize < 12 || tagSize > 16 || int64(len(mac)) != tagSize {
+			return nil, errUnsupported
+		}
+		aead, err := cipher.NewGCMWithTagSize(block, int(tagSize))
+		if err != nil {
+			return nil, err
+		}
+		// RFC 5083, Section 2.1: the authenticated attributes are the
+		// additional authenticated data, with their SET tag.
+		var aad []byte
+		if hasAuthAttrs {
+			var b cryptobyte.Builder
+			b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+				b.AddBytes(authAttrs)
+			})
+			aad = b.BytesOrPanic()
+		}
+		sealed := make([]byte, 0, len(ciphertext)+len(mac))
+		sealed = append(append(sealed, ciphertext...), mac...)
+		plaintext, err := aead.Open(nil, nonce, sealed, aad)
+		if err != nil {
+			return nil, errors.New("cms: decryption failed")
+		}
+		return plaintext, nil
+	}
+
+	var iv cryptobyte.String
+	if params == nil || !params.ReadASN1(&iv, cryptobyte_asn1.OCTET_STRING) || !params.Empty() ||
+		len(iv) != aes.BlockSize {
+		return nil, errors.New("cms: malformed CBC parameters")
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("cms: decryption failed")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	padLen := int(plaintext[len(plaintext)-1])
+	if padLen == 0 || padLen > aes.BlockSize {
+		return nil, errors.New("cms: decryption failed")
+	}
+	padding := bytes.Repeat([]byte{byte(padLen)}, padLen)
+	if subtle.ConstantTimeCompare(plaintext[len(plaintext)-padLen:], padding) != 1 {
+		return nil, errors.New("cms: decryption failed")
+	}
+	return plaintext[:len(plaintext)-padLen], nil
+}
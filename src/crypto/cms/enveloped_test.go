@@ -0,0 +1,85 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	content := []byte("attack at dawn")
+	cert, key := testOpenSSLIdentity(t)
+	kek := &KEKRecipient{KeyID: []byte("kek"), Key: make([]byte, 32)}
+	ktri := &KeyTransRecipient{Certificate: cert, Key: key}
+	for _, c := range []Cipher{AES256GCM, AES128GCM, AES256CBC, AES128CBC} {
+		der, err := Encrypt(rand.Reader, content, []Recipient{kek, ktri}, &EncryptOptions{Cipher: c})
+		if err != nil {
+			t.Fatalf("cipher %d: Encrypt: %v", c, err)
+		}
+		for _, r := range []Recipient{kek, ktri} {
+			got, err := Decrypt(der, r)
+			if err != nil {
+				t.Errorf("cipher %d: Decrypt with %T: %v", c, r, err)
+				continue
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("cipher %d: Decrypt with %T = %q, want %q", c, r, got, content)
+			}
+		}
+
+		other := &KEKRecipient{KeyID: []byte("other"), Key: make([]byte, 32)}
+		if _, err := Decrypt(der, other); err == nil {
+			t.Errorf("cipher %d: Decrypt succeeded for a non-recipient", c)
+		}
+		wrongKey := &KEKRecipient{KeyID: []byte("kek"), Key: bytes.Repeat([]byte{1}, 32)}
+		if _, err := Decrypt(der, wrongKey); err == nil {
+			t.Errorf("cipher %d: Decrypt succeeded with the wrong key", c)
+		}
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	kek := &KEKRecipient{KeyID: []byte("kek"), Key: make([]byte, 16)}
+	der, err := Encrypt(rand.Reader, []byte("attack at dawn"), []Recipient{kek}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a bit in the authentication tag, which is last.
+	der[len(der)-1] ^= 1
+	if _, err := Decrypt(der, kek); err == nil {
+		t.Error("Decrypt succeeded with a tampered tag")
+	}
+}
+
+func TestDecryptOpenSSL(t *testing.T) {
+	content := readTestFile(t, "msg.txt")
+	cert, key := testOpenSSLIdentity(t)
+	kek256, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	kek128, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	ktri := &KeyTransRecipient{Certificate: cert, Key: key}
+
+	tests := []struct {
+		file      string
+		recipient Recipient
+	}{
+		{"kek_gcm.der", &KEKRecipient{KeyID: []byte("key"), Key: kek256}},
+		{"kek_cbc.der", &KEKRecipient{KeyID: []byte("key"), Key: kek128}},
+		{"ktri_gcm.der", ktri},
+		{"ktri_oaep.der", ktri},
+	}
+	for _, test := range tests {
+		got, err := Decrypt(readTestFile(t, test.file), test.recipient)
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("%s: got %q, want %q", test.file, got, content)
+		}
+	}
+}
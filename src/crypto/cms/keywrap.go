@@ -0,0 +1,25 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"crypto/keywrap"
+	"errors"
+)
+
+// wrapKey wraps key with kek using the AES Key Wrap algorithm of RFC 3394.
+func wrapKey(kek, key []byte) ([]byte, error) {
+	return keywrap.Wrap(kek, key)
+}
+
+// unwrapKey unwraps wrapped with kek using the AES Key Wrap algorithm of
+// RFC 3394.
+func unwrapKey(kek, wrapped []byte) ([]byte, error) {
+	key, err := keywrap.Unwrap(kek, wrapped)
+	if err != nil {
+		return nil, errors.New("cms: key unwrap failed: " + err.Error())
+	}
+	return key, nil
+}
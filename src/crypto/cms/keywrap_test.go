@@ -0,0 +1,76 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vectors from RFC 3394, Section 4.
+var keyWrapTests = []struct {
+	kek, key, wrapped string
+}{
+	{
+		"000102030405060708090A0B0C0D0E0F",
+		"00112233445566778899AABBCCDDEEFF",
+		"1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+	},
+	{
+		"000102030405060708090A0B0C0D0E0F1011121314151617",
+		"00112233445566778899AABBCCDDEEFF",
+		"96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D",
+	},
+	{
+		"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		"00112233445566778899AABBCCDDEEFF",
+		"64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7",
+	},
+	{
+		"000102030405060708090A0B0C0D0E0F1011121314151617",
+		"00112233445566778899AABBCCDDEEFF0001020304050607",
+		"031D33264E15D33268F24EC260743EDCE1C6C7DDEE725A936BA814915C6762D2",
+	},
+	{
+		"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		"00112233445566778899AABBCCDDEEFF0001020304050607",
+		"A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1",
+	},
+	{
+		"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		"00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+		"28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+	},
+}
+
+func TestKeyWrap(t *testing.T) {
+	for i, test := range keyWrapTests {
+		kek, _ := hex.DecodeString(test.kek)
+		key, _ := hex.DecodeString(test.key)
+		want, _ := hex.DecodeString(test.wrapped)
+
+		wrapped, err := wrapKey(kek, key)
+		if err != nil {
+			t.Fatalf("#%d: wrapKey: %v", i, err)
+		}
+		if !bytes.Equal(wrapped, want) {
+			t.Errorf("#%d: wrapKey = %x, want %x", i, wrapped, want)
+		}
+
+		unwrapped, err := unwrapKey(kek, want)
+		if err != nil {
+			t.Fatalf("#%d: unwrapKey: %v", i, err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Errorf("#%d: unwrapKey = %x, want %x", i, unwrapped, key)
+		}
+
+		want[len(want)-1] ^= 1
+		if _, err := unwrapKey(kek, want); err == nil {
+			t.Errorf("#%d: unwrapKey succeeded with corrupted input", i)
+		}
+	}
+}
@@ -0,0 +1,611 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// SignOptions configures Sign.
+type SignOptions struct {
+	// Hash is the digest algorithm. If zero, SHA-256 is used. It is ignored
+	// for Ed25519 keys, which always use SHA-512, as required by RFC 8419.
+	Hash crypto.Hash
+
+	// Detached omits the content from the SignedData, which then has to be
+	// provided separately to VerifyDetached.
+	Detached bool
+
+	// ContentType is the type of the signed content. If nil, OIDData is
+	// used.
+	ContentType asn1.ObjectIdentifier
+
+	// Certificates are additional certificates to include, such as the
+	// intermediates needed to verify the signer's certificate.
+	Certificates []*x509.Certificate
+
+	// SigningTime is the value of the signingTime attribute. If zero, the
+	// current time is used.
+	SigningTime time.Time
+}
+
+// Sign returns a DER encoded ContentInfo holding a SignedData over content,
+// signed by key, whose certificate is cert. key must be an RSA, ECDSA or
+// Ed25519 private key matching cert. RSA signatures use PKCS #1 v1.5.
+//
+// The contentType, messageDigest and signingTime attributes are always
+// signed.
+func Sign(rand io.Reader, content []byte, cert *x509.Certificate, key crypto.Signer, opts *SignOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &SignOptions{}
+	}
+	if k, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !k.Equal(cert.PublicKey) {
+		return nil, errors.New("cms: private key does not match certificate")
+	}
+
+	hash := opts.Hash
+	if hash == 0 {
+		hash = crypto.SHA256
+	}
+	var sigAlgOID asn1.ObjectIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			sigAlgOID = oidSHA256WithRSA
+		case crypto.SHA384:
+			sigAlgOID = oidSHA384WithRSA
+		case crypto.SHA512:
+			sigAlgOID = oidSHA512WithRSA
+		}
+	case *ecdsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			sigAlgOID = oidECDSAWithSHA256
+		case crypto.SHA384:
+			sigAlgOID = oidECDSAWithSHA384
+		case crypto.SHA512:
+			sigAlgOID = oidECDSAWithSHA512
+		}
+	case ed25519.PublicKey:
+		hash = crypto.SHA512
+		sigAlgOID = oidEd25519
+	default:
+		return nil, errors.New("cms: unsupported key type")
+	}
+	if sigAlgOID == nil {
+		return nil, errors.New("cms: unsupported hash function for key type")
+	}
+	digestAlgOID, _ := oidForHash(hash)
+
+	contentType := opts.ContentType
+	if contentType == nil {
+		contentType = OIDData
+	}
+	signingTime := opts.SigningTime
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+
+	signedAttrs, err := marshalSignedAttributes(contentType, digest, signingTime)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if sigAlgOID.Equal(oidEd25519) {
+		signature, err = key.Sign(rand, signedAttrs, crypto.Hash(0))
+	} else {
+		h := hash.New()
+		h.Write(signedAttrs)
+		signature, err = key.Sign(rand, h.Sum(nil), hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	certs := [][]byte{cert.Raw}
+	for _, c := range opts.Certificates {
+		certs = append(certs, c.Raw)
+	}
+	sortDER(certs)
+
+	version := int64(1)
+	if !contentType.Equal(OIDData) {
+		version = 3
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(version)
+		b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+			addAlgorithmIdentifier(b, digestAlgOID, nil)
+		})
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(contentType)
+			if !opts.Detached {
+				b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddASN1OctetString(content)
+				})
+			}
+		})
+		b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			for _, c := range certs {
+				b.AddBytes(c)
+			}
+		})
+		b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+			// SignerInfo
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(1)
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddBytes(cert.RawIssuer)
+					b.AddASN1BigInt(cert.SerialNumber)
+				})
+				addAlgorithmIdentifier(b, digestAlgOID, nil)
+				// The signed attributes are [0] IMPLICIT, and were
+				// signed with their SET tag.
+				b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+					attrs := cryptobyte.String(signedAttrs)
+					attrs.ReadASN1(&attrs, cryptobyte_asn1.SET)
+					b.AddBytes(attrs)
+				})
+				addAlgorithmIdentifier(b, sigAlgOID, nil)
+				b.AddASN1OctetString(signature)
+			})
+		})
+	})
+	signedData, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return marshalContentInfo(oidSignedData, signedData)
+}
+
+// marshalSignedAttributes returns the DER encoding of the SET OF Attribute
+// over which a SignerInfo signature is computed.
+func marshalSignedAttributes(contentType asn1.ObjectIdentifier, digest []byte, signingTime time.Time) ([]byte, error) {
+	attr := func(oid asn1.ObjectIdentifier, value func(*cryptobyte.Builder)) []byte {
+		var b cryptobyte.Builder
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(oid)
+			b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+				value(b)
+			})
+		})
+		return b.BytesOrPanic()
+	}
+	signingTime = signingTime.UTC()
+	attrs := [][]byte{
+		attr(oidAttributeContentType, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(contentType)
+		}),
+		attr(oidAttributeMessageDigest, func(b *cryptobyte.Builder) {
+			b.AddASN1OctetString(digest)
+		}),
+		attr(oidAttributeSigningTime, func(b *cryptobyte.Builder) {
+			// RFC 5652, Section 11.3: dates between 1950 and 2049 MUST be
+			// encoded as UTCTime.
+			if y := signingTime.Year(); y >= 1950 && y < 2050 {
+				b.AddASN1UTCTime(signingTime)
+			} else {
+				b.AddASN1GeneralizedTime(signingTime)
+			}
+		}),
+	}
+	sortDER(attrs)
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+		for _, a := range attrs {
+			b.AddBytes(a)
+		}
+	})
+	return b.Bytes()
+}
+
+// sortDER sorts the elements of a DER SET OF.
+func sortDER(elements [][]byte) {
+	sort.Slice(elements, func(i, j int) bool {
+		return bytes.Compare(elements[i], elements[j]) < 0
+	})
+}
+
+// SignedData is a parsed SignedData content.
+type SignedData struct {
+	// ContentType is the type of the signed content.
+	ContentType asn1.ObjectIdentifier
+
+	// Content is the signed content, or nil if the signature is detached.
+	Content []byte
+
+	// Certificates are the certificates included in the SignedData.
+	Certificates []*x509.Certificate
+
+	// Signers are the signatures over the content.
+	Signers []*SignerInfo
+}
+
+// SignerInfo is a signature in a SignedData.
+type SignerInfo struct {
+	// Certificate is the certificate of the signer, if it was found in the
+	// Certificates of the SignedData.
+	Certificate *x509.Certificate
+
+	// Hash is the digest algorithm used for the content.
+	Hash crypto.Hash
+
+	// SignatureAlgorithm is the signature algorithm.
+	SignatureAlgorithm x509.SignatureAlgorithm
+
+	// SigningTime is the value of the signingTime attribute, or the zero
+	// time if absent.
+	SigningTime time.Time
+
+	// Signature is the signature value.
+	Signature []byte
+
+	// Identification of the signer's certificate, either issuer and serial
+	// or subjectKeyID.
+	issuer       []byte
+	serial       *big.Int
+	subjectKeyID []byte
+
+	// signedAttrs is the DER encoding of the signed attributes with a SET
+	// tag, or nil if absent.
+	signedAttrs   []byte
+	contentType   asn1.ObjectIdentifier
+	messageDigest []byte
+}
+
+// ParseSignedData parses a DER encoded ContentInfo holding a SignedData.
+func ParseSignedData(der []byte) (*SignedData, error) {
+	contentType, content, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	if !contentType.Equal(oidSignedData) {
+		return nil, errors.New("cms: content is not SignedData")
+	}
+
+	errMalformed := errors.New("cms: malformed SignedData")
+
+	// SignedData ::= SEQUENCE {
+	//      version CMSVersion,
+	//      digestAlgorithms DigestAlgorithmIdentifiers,
+	//      encapContentInfo EncapsulatedContentInfo,
+	//      certificates [0] IMPLICIT CertificateSet OPTIONAL,
+	//      crls [1] IMPLICIT RevocationInfoChoices OPTIONAL,
+	//      signerInfos SignerInfos }
+	var sd, digestAlgs, encap, certs, signerInfos cryptobyte.String
+	var version int64
+	var hasCerts bool
+	if !content.ReadASN1(&sd, cryptobyte_asn1.SEQUENCE) || !content.Empty() ||
+		!sd.ReadASN1Int64WithTag(&version, cryptobyte_asn1.INTEGER) ||
+		!sd.ReadASN1(&digestAlgs, cryptobyte_asn1.SET) ||
+		!sd.ReadASN1(&encap, cryptobyte_asn1.SEQUENCE) ||
+		!sd.ReadOptionalASN1(&certs, &hasCerts, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!sd.SkipOptionalASN1(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) ||
+		!sd.ReadASN1(&signerInfos, cryptobyte_asn1.SET) ||
+		!sd.Empty() {
+		return nil, errMalformed
+	}
+
+	out := &SignedData{}
+	var eContent cryptobyte.String
+	var hasContent bool
+	if !encap.ReadASN1ObjectIdentifier(&out.ContentType) ||
+		!encap.ReadOptionalASN1(&eContent, &hasContent, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!encap.Empty() {
+		return nil, errMalformed
+	}
+	if hasContent {
+		if !eContent.ReadASN1Bytes(&out.Content, cryptobyte_asn1.OCTET_STRING) || !eContent.Empty() {
+			return nil, errMalformed
+		}
+		if out.Content == nil {
+			out.Content = []byte{}
+		}
+	}
+
+	for !certs.Empty() {
+		var certDER cryptobyte.String
+		var tag cryptobyte_asn1.Tag
+		if !certs.ReadAnyASN1Element(&certDER, &tag) {
+			return nil, errMalformed
+		}
+		if tag != cryptobyte_asn1.SEQUENCE {
+			// Other certificate formats are ignored.
+			continue
+		}
+		cert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return nil, err
+		}
+		out.Certificates = append(out.Certificates, cert)
+	}
+
+	for !signerInfos.Empty() {
+		si, err := parseSignerInfo(&signerInfos)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range out.Certificates {
+			if si.matches(cert) {
+				si.Certificate = cert
+				break
+			}
+		}
+		out.Signers = append(out.Signers, si)
+	}
+
+	return out, nil
+}
+
+func parseSignerInfo(s *cryptobyte.String) (*SignerInfo, error) {
+	errMalformed := errors.New("cms: malformed SignerInfo")
+
+	// SignerInfo ::= SEQUENCE {
+	//      version CMSVersion,
+	//      sid SignerIdentifier,
+	//      digestAlgorithm DigestAlgorithmIdentifier,
+	//      signedAttrs [0] IMPLICIT SignedAttributes OPTIONAL,
+	//      signatureAlgorithm SignatureAlgorithmIdentifier,
+	//      signature SignatureValue,
+	//      unsignedAttrs [1] IMPLICIT UnsignedAttributes OPTIONAL }
+	var si cryptobyte.String
+	var version int64
+	if !s.ReadASN1(&si, cryptobyte_asn1.SEQUENCE) ||
+		!si.ReadASN1Int64WithTag(&version, cryptobyte_asn1.INTEGER) {
+		return nil, errMalformed
+	}
+
+	out := &SignerInfo{}
+	switch {
+	case si.PeekASN1Tag(cryptobyte_asn1.SEQUENCE):
+		var ias, issuer cryptobyte.String
+		out.serial = new(big.Int)
+		if !si.ReadASN1(&ias, cryptobyte_asn1.SEQUENCE) ||
+			!ias.ReadASN1Element(&issuer, cryptobyte_asn1.SEQUENCE) ||
+			!ias.ReadASN1Integer(out.serial) || !ias.Empty() {
+			return nil, errMalformed
+		}
+		out.issuer = issuer
+	case si.PeekASN1Tag(cryptobyte_asn1.Tag(0).ContextSpecific()):
+		var skid cryptobyte.String
+		if !si.ReadASN1(&skid, cryptobyte_asn1.Tag(0).ContextSpecific()) {
+			return nil, errMalformed
+		}
+		out.subjectKeyID = skid
+	default:
+		return nil, errMalformed
+	}
+
+	digestOID, _, ok := readAlgorithmIdentifier(&si)
+	if !ok {
+		return nil, errMalformed
+	}
+	if out.Hash, ok = hashForOID(digestOID); !ok {
+		return nil, errUnsupported
+	}
+
+	var attrs cryptobyte.String
+	var hasAttrs bool
+	if !si.ReadOptionalASN1(&attrs, &hasAttrs, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+		return nil, errMalformed
+	}
+	if hasAttrs {
+		var b cryptobyte.Builder
+		b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+			b.AddBytes(attrs)
+		})
+		out.signedAttrs = b.BytesOrPanic()
+		if err := out.parseSignedAttributes(attrs); err != nil {
+			return nil, err
+		}
+	}
+
+	sigOID, _, ok := readAlgorithmIdentifier(&si)
+	if !ok {
+		return nil, errMalformed
+	}
+	var err error
+	if out.SignatureAlgorithm, err = signatureAlgorithm(sigOID, out.Hash); err != nil {
+		return nil, err
+	}
+
+	if !si.ReadASN1Bytes(&out.Signature, cryptobyte_asn1.OCTET_STRING) ||
+		!si.SkipOptionalASN1(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) ||
+		!si.Empty() {
+		return nil, errMalformed
+	}
+	return out, nil
+}
+
+func (si *SignerInfo) parseSignedAttributes(attrs cryptobyte.String) error {
+	errMalformed := errors.New("cms: malformed signed attributes")
+	for !attrs.Empty() {
+		var attr, values cryptobyte.String
+		var oid asn1.ObjectIdentifier
+		if !attrs.ReadASN1(&attr, cryptobyte_asn1.SEQUENCE) ||
+			!attr.ReadASN1ObjectIdentifier(&oid) ||
+			!attr.ReadASN1(&values, cryptobyte_asn1.SET) || !attr.Empty() {
+			return errMalformed
+		}
+		switch {
+		case oid.Equal(oidAttributeContentType):
+			if si.contentType != nil || !values.ReadASN1ObjectIdentifier(&si.contentType) || !values.Empty() {
+				return errMalformed
+			}
+		case oid.Equal(oidAttributeMessageDigest):
+			if si.messageDigest != nil || !values.ReadASN1Bytes(&si.messageDigest, cryptobyte_asn1.OCTET_STRING) || !values.Empty() {
+				return errMalformed
+			}
+		case oid.Equal(oidAttributeSigningTime):
+			var ok bool
+			switch {
+			case values.PeekASN1Tag(cryptobyte_asn1.UTCTime):
+				ok = values.ReadASN1UTCTime(&si.SigningTime)
+			case values.PeekASN1Tag(cryptobyte_asn1.GeneralizedTime):
+				ok = values.ReadASN1GeneralizedTime(&si.SigningTime)
+			}
+			if !ok || !values.Empty() {
+				return errMalformed
+			}
+		}
+	}
+	// RFC 5652, Section 5.3: if signed attributes are present, they must
+	// include the content type and the message digest.
+	if si.contentType == nil || si.messageDigest == nil {
+		return errMalformed
+	}
+	return nil
+}
+
+// signatureAlgorithm returns the x509.SignatureAlgorithm for the signature
+// algorithm OID of a SignerInfo with the given digest algorithm.
+func signatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	var algo x509.SignatureAlgorithm
+	var algoHash crypto.Hash
+	switch {
+	case oid.Equal(oidRSAEncryption):
+		// The hash is given by the digest algorithm.
+		algoHash = hash
+		switch hash {
+		case crypto.SHA1:
+			algo = x509.SHA1WithRSA
+		case crypto.SHA256:
+			algo = x509.SHA256WithRSA
+		case crypto.SHA384:
+			algo = x509.SHA384WithRSA
+		case crypto.SHA512:
+			algo = x509.SHA512WithRSA
+		}
+	case oid.Equal(oidSHA1WithRSA):
+		algo, algoHash = x509.SHA1WithRSA, crypto.SHA1
+	case oid.Equal(oidSHA256WithRSA):
+		algo, algoHash = x509.SHA256WithRSA, crypto.SHA256
+	case oid.Equal(oidSHA384WithRSA):
+		algo, algoHash = x509.SHA384WithRSA, crypto.SHA384
+	case oid.Equal(oidSHA512WithRSA):
+		algo, algoHash = x509.SHA512WithRSA, crypto.SHA512
+	case oid.Equal(oidECDSAWithSHA1):
+		algo, algoHash = x509.ECDSAWithSHA1, crypto.SHA1
+	case oid.Equal(oidECDSAWithSHA256):
+		algo, algoHash = x509.ECDSAWithSHA256, crypto.SHA256
+	case oid.Equal(oidECDSAWithSHA384):
+		algo, algoHash = x509.ECDSAWithSHA384, crypto.SHA384
+	case oid.Equal(oidECDSAWithSHA512):
+		algo, algoHash = x509.ECDSAWithSHA512, crypto.SHA512
+	case oid.Equal(oidEd25519):
+		// RFC 8419, Section 3: the message digest is computed with
+		// SHA-512, and the signature is over the signed attributes.
+		algo, algoHash = x509.PureEd25519, crypto.SHA512
+	}
+	if algo == x509.UnknownSignatureAlgorithm || algoHash != hash {
+		return x509.UnknownSignatureAlgorithm, errUnsupported
+	}
+	return algo, nil
+}
+
+func (si *SignerInfo) matches(cert *x509.Certificate) bool {
+	if si.subjectKeyID != nil {
+		return bytes.Equal(si.subjectKeyID, cert.SubjectKeyId)
+	}
+	return bytes.Equal(si.issuer, cert.RawIssuer) && si.serial.Cmp(cert.SerialNumber) == 0
+}
+
+// Verify checks that every signer of sd signed the attached content, and
+// verifies the signers' certificates with opts. The Certificates of sd are
+// added to the intermediates of opts. If opts.KeyUsages is empty, any
+// extended key usage is accepted, instead of the x509 default of
+// ExtKeyUsageServerAuth.
+//
+// Certificates are verified at opts.CurrentTime, or at the current time if
+// zero, and not at the signing time claimed by the signer.
+func (sd *SignedData) Verify(opts x509.VerifyOptions) error {
+	if sd.Content == nil {
+		return errors.New("cms: SignedData has detached content")
+	}
+	return sd.verify(sd.Content, opts)
+}
+
+// VerifyDetached is like Verify, but verifies the signatures over content,
+// which was signed separately from sd.
+func (sd *SignedData) VerifyDetached(content []byte, opts x509.VerifyOptions) error {
+	if sd.Content != nil {
+		return errors.New("cms: SignedData has attached content")
+	}
+	return sd.verify(content, opts)
+}
+
+func (sd *SignedData) verify(content []byte, opts x509.VerifyOptions) error {
+	if len(sd.Signers) == 0 {
+		return errors.New("cms: SignedData has no signers")
+	}
+
+	if opts.Intermediates == nil {
+		opts.Intermediates = x509.NewCertPool()
+	} else {
+		opts.Intermediates = opts.Intermediates.Clone()
+	}
+	for _, cert := range sd.Certificates {
+		opts.Intermediates.AddCert(cert)
+	}
+	if len(opts.KeyUsages) == 0 {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	for _, si := range sd.Signers {
+		if si.Certificate == nil {
+			return errors.New("cms: signer certificate not found")
+		}
+
+		h := si.Hash.New()
+		h.Write(content)
+		digest := h.Sum(nil)
+
+		signed := content
+		if si.signedAttrs != nil {
+			if !si.contentType.Equal(sd.ContentType) {
+				return errors.New("cms: content type attribute does not match content")
+			}
+			if subtle.ConstantTimeCompare(si.messageDigest, digest) != 1 {
+				return errors.New("cms: message digest does not match content")
+			}
+			signed = si.signedAttrs
+		} else if !sd.ContentType.Equal(OIDData) {
+			// RFC 5652, Section 5.3: signed attributes MUST be present if
+			// the content type is not id-data.
+			return errors.New("cms: missing signed attributes")
+		}
+
+		if err := si.Certificate.CheckSignature(si.SignatureAlgorithm, signed, si.Signature); err != nil {
+			return err
+		}
+		if _, err := si.Certificate.Verify(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
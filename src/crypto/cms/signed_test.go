@@ -0,0 +1,180 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	content := []byte("firmware image")
+	for _, keyType := range []string{"RSA", "ECDSA", "Ed25519"} {
+		for _, detached := range []bool{false, true} {
+			cert, key := testIdentity(t, keyType)
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+			opts := x509.VerifyOptions{Roots: roots}
+
+			signingTime := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+			der, err := Sign(rand.Reader, content, cert, key, &SignOptions{
+				Hash:        crypto.SHA384,
+				Detached:    detached,
+				SigningTime: signingTime,
+			})
+			if err != nil {
+				t.Fatalf("%s: Sign: %v", keyType, err)
+			}
+			sd, err := ParseSignedData(der)
+			if err != nil {
+				t.Fatalf("%s: ParseSignedData: %v", keyType, err)
+			}
+			if !sd.ContentType.Equal(OIDData) {
+				t.Errorf("%s: ContentType = %v, want %v", keyType, sd.ContentType, OIDData)
+			}
+			if len(sd.Signers) != 1 || sd.Signers[0].Certificate == nil {
+				t.Fatalf("%s: signer certificate not found", keyType)
+			}
+			si := sd.Signers[0]
+			if !si.SigningTime.Equal(signingTime) {
+				t.Errorf("%s: SigningTime = %v, want %v", keyType, si.SigningTime, signingTime)
+			}
+			wantHash := crypto.SHA384
+			if keyType == "Ed25519" {
+				wantHash = crypto.SHA512
+			}
+			if si.Hash != wantHash {
+				t.Errorf("%s: Hash = %v, want %v", keyType, si.Hash, wantHash)
+			}
+
+			if detached {
+				if sd.Content != nil {
+					t.Errorf("%s: detached SignedData has content", keyType)
+				}
+				if err := sd.Verify(opts); err == nil {
+					t.Errorf("%s: Verify succeeded on detached SignedData", keyType)
+				}
+				if err := sd.VerifyDetached(content, opts); err != nil {
+					t.Errorf("%s: VerifyDetached: %v", keyType, err)
+				}
+				if err := sd.VerifyDetached([]byte("other image"), opts); err == nil {
+					t.Errorf("%s: VerifyDetached succeeded with wrong content", keyType)
+				}
+				continue
+			}
+
+			if !bytes.Equal(sd.Content, content) {
+				t.Errorf("%s: Content = %q, want %q", keyType, sd.Content, content)
+			}
+			if err := sd.Verify(opts); err != nil {
+				t.Errorf("%s: Verify: %v", keyType, err)
+			}
+			if err := sd.Verify(x509.VerifyOptions{Roots: x509.NewCertPool()}); err == nil {
+				t.Errorf("%s: Verify succeeded with untrusted signer", keyType)
+			}
+			sd.Content = []byte("tampered image")
+			if err := sd.Verify(opts); err == nil {
+				t.Errorf("%s: Verify succeeded with tampered content", keyType)
+			}
+		}
+	}
+}
+
+func TestSignTamperedSignature(t *testing.T) {
+	cert, key := testIdentity(t, "ECDSA")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	der, err := Sign(rand.Reader, []byte("content"), cert, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := ParseSignedData(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.Signers[0].signedAttrs[len(sd.Signers[0].signedAttrs)-1] ^= 1
+	if err := sd.Verify(x509.VerifyOptions{Roots: roots}); err == nil {
+		t.Error("Verify succeeded with tampered signed attributes")
+	}
+}
+
+func TestSignContentType(t *testing.T) {
+	cert, key := testIdentity(t, "ECDSA")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	contentType := asn1.ObjectIdentifier{1, 2, 3, 4}
+	der, err := Sign(rand.Reader, []byte("content"), cert, key, &SignOptions{ContentType: contentType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := ParseSignedData(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sd.ContentType.Equal(contentType) {
+		t.Errorf("ContentType = %v, want %v", sd.ContentType, contentType)
+	}
+	if err := sd.Verify(x509.VerifyOptions{Roots: roots}); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	sd.ContentType = OIDData
+	if err := sd.Verify(x509.VerifyOptions{Roots: roots}); err == nil {
+		t.Error("Verify succeeded with mismatched content type")
+	}
+}
+
+func TestSignKeyMismatch(t *testing.T) {
+	cert, _ := testIdentity(t, "ECDSA")
+	_, key := testIdentity(t, "ECDSA")
+	if _, err := Sign(rand.Reader, []byte("content"), cert, key, nil); err == nil {
+		t.Error("Sign succeeded with a key not matching the certificate")
+	}
+}
+
+func TestVerifyOpenSSL(t *testing.T) {
+	cert, _ := testOpenSSLIdentity(t)
+	content := readTestFile(t, "msg.txt")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	opts := x509.VerifyOptions{Roots: roots, CurrentTime: cert.NotBefore.Add(time.Hour)}
+
+	sd, err := ParseSignedData(readTestFile(t, "signed.der"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sd.Content, content) {
+		t.Errorf("Content = %q, want %q", sd.Content, content)
+	}
+	if err := sd.Verify(opts); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	sd, err = ParseSignedData(readTestFile(t, "detached.der"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sd.VerifyDetached(content, opts); err != nil {
+		t.Errorf("VerifyDetached: %v", err)
+	}
+}
+
+func TestParseSignedDataMalformed(t *testing.T) {
+	der := readTestFile(t, "signed.der")
+	for i := 0; i < len(der); i += 7 {
+		if _, err := ParseSignedData(der[:i]); err == nil {
+			t.Errorf("ParseSignedData succeeded on input truncated to %d bytes", i)
+		}
+	}
+	if _, err := ParseSignedData(readTestFile(t, "kek_gcm.der")); err == nil {
+		t.Error("ParseSignedData succeeded on AuthEnvelopedData")
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cose implements the COSE_Sign1 and COSE_Encrypt0 structures of
+// CBOR Object Signing and Encryption (RFC 9052), and COSE_Key public keys.
+//
+// COSE is the CBOR counterpart of JOSE. It is used by WebAuthn, C2PA
+// content credentials, and mobile driving licenses, among others.
+//
+// The ES256, ES384, ES512, EdDSA (with Ed25519), PS256, and RS256
+// signature algorithms, and the A128GCM, A192GCM, and A256GCM content
+// encryption algorithms are supported. ECDSA algorithms require the
+// matching curve, and RSA keys must be at least 2048 bits. RS256 is only
+// meant for verifying WebAuthn attestations, per RFC 8812.
+//
+// The algorithm of a message must be in its protected header. Messages
+// with critical header parameters are rejected, since none are supported.
+package cose
+
+import (
+	"crypto/internal/cbor"
+	"errors"
+	"strconv"
+)
+
+// An Algorithm is a COSE algorithm identifier, from the IANA COSE
+// Algorithms registry.
+type Algorithm int64
+
+const (
+	ES256   Algorithm = -7   // ECDSA with P-256 and SHA-256
+	EdDSA   Algorithm = -8   // EdDSA, with Ed25519
+	ES384   Algorithm = -35  // ECDSA with P-384 and SHA-384
+	ES512   Algorithm = -36  // ECDSA with P-521 and SHA-512
+	PS256   Algorithm = -37  // RSASSA-PSS with SHA-256
+	RS256   Algorithm = -257 // RSASSA-PKCS1-v1_5 with SHA-256
+	A128GCM Algorithm = 1    // AES-GCM with a 128-bit key
+	A192GCM Algorithm = 2    // AES-GCM with a 192-bit key
+	A256GCM Algorithm = 3    // AES-GCM with a 256-bit key
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case ES256:
+		return "ES256"
+	case EdDSA:
+		return "EdDSA"
+	case ES384:
+		return "ES384"
+	case ES512:
+		return "ES512"
+	case PS256:
+		return "PS256"
+	case RS256:
+		return "RS256"
+	case A128GCM:
+		return "A128GCM"
+	case A192GCM:
+		return "A192GCM"
+	case A256GCM:
+		return "A256GCM"
+	}
+	return "Algorithm(" + strconv.FormatInt(int64(a), 10) + ")"
+}
+
+// Header parameter labels, from RFC 9052, Section 3.1, and RFC 9360.
+const (
+	labelAlgorithm = 1
+	labelCritical  = 2
+	labelKeyID     = 4
+	labelIV        = 5
+	labelX5Chain   = 33
+)
+
+// CBOR tags of the message structures, from RFC 9052, Section 2.
+const (
+	tagEncrypt0 = 16
+	tagSign1    = 18
+)
+
+// A Header holds the header parameters of a COSE message that this package
+// understands. Other parameters are ignored when parsing.
+type Header struct {
+	// Algorithm is the "alg" parameter. It is only allowed in protected
+	// headers.
+	Algorithm Algorithm
+
+	// KeyID is the "kid" parameter, a hint of which key was used. It is
+	// not authenticated until the message is verified or decrypted, even
+	// if it is in the protected header.
+	KeyID []byte
+
+	// IV is the "IV" parameter of an encrypted message. It is set by
+	// Encrypt0, in the unprotected header.
+	IV []byte
+
+	// X509Chain is the "x5chain" parameter of RFC 9360, a list of DER
+	// certificates, starting with the one of the signing key.
+	X509Chain [][]byte
+}
+
+var errMalformed = errors.New("cose: malformed message")
+
+// encode returns the CBOR encoding of h, with its parameters in the
+// deterministic order.
+func (h *Header) encode() cbor.Map {
+	var m cbor.Map
+	if h == nil {
+		return cbor.Map{}
+	}
+	if h.Algorithm != 0 {
+		m = append(m, cbor.Entry{Key: int64(labelAlgorithm), Value: int64(h.Algorithm)})
+	}
+	if h.KeyID != nil {
+		m = append(m, cbor.Entry{Key: int64(labelKeyID), Value: h.KeyID})
+	}
+	if h.IV != nil {
+		m = append(m, cbor.Entry{Key: int64(labelIV), Value: h.IV})
+	}
+	switch len(h.X509Chain) {
+	case 0:
+	case 1:
+		m = append(m, cbor.Entry{Key: int64(labelX5Chain), Value: h.X509Chain[0]})
+	default:
+		chain := make([]any, len(h.X509Chain))
+		for i, c := range h.X509Chain {
+			chain[i] = c
+		}
+		m = append(m, cbor.Entry{Key: int64(labelX5Chain), Value: chain})
+	}
+	if m == nil {
+		return cbor.Map{}
+	}
+	return m
+}
+
+// encodeProtected returns the serialized protected header, which is empty
+// if h has no parameters, per RFC 9052, Section 3.
+func encodeProtected(h *Header) ([]byte, error) {
+	m := h.encode()
+	if len(m) == 0 {
+		return []byte{}, nil
+	}
+	return cbor.Marshal(m)
+}
+
+// decodeHeaders parses the serialized protected header and the unprotected
+// header map of a message.
+func decodeHeaders(protected []byte, unprotected any) (p, u Header, err error) {
+	pm := cbor.Map{}
+	if len(protected) != 0 {
+		v, err := cbor.Unmarshal(protected)
+		if err != nil {
+			return p, u, errMalformed
+		}
+		var ok bool
+		if pm, ok = v.(cbor.Map); !ok {
+			return p, u, errMalformed
+		}
+	}
+	um, ok := unprotected.(cbor.Map)
+	if !ok {
+		return p, u, errMalformed
+	}
+	for _, e := range um {
+		if _, ok := pm.Get(e.Key); ok {
+			return p, u, errors.New("cose: header parameter in both protected and unprotected headers")
+		}
+	}
+	if err := p.decode(pm); err != nil {
+		return p, u, err
+	}
+	if err := u.decode(um); err != nil {
+		return p, u, err
+	}
+	if u.Algorithm != 0 {
+		return p, u, errors.New("cose: algorithm is not in the protected header")
+	}
+	return p, u, nil
+}
+
+func (h *Header) decode(m cbor.Map) error {
+	for _, e := range m {
+		var ok bool
+		switch e.Key {
+		case int64(labelAlgorithm):
+			var alg int64
+			alg, ok = e.Value.(int64)
+			h.Algorithm = Algorithm(alg)
+		case int64(labelCritical):
+			return errors.New("cose: critical header parameters are not supported")
+		case int64(labelKeyID):
+			h.KeyID, ok = e.Value.([]byte)
+		case int64(labelIV):
+			h.IV, ok = e.Value.([]byte)
+		case int64(labelX5Chain):
+			switch v := e.Value.(type) {
+			case []byte:
+				h.X509Chain, ok = [][]byte{v}, true
+			case []any:
+				ok = len(v) > 0
+				for _, c := range v {
+					cert, isBytes := c.([]byte)
+					ok = ok && isBytes
+					h.X509Chain = append(h.X509Chain, cert)
+				}
+			}
+		default:
+			ok = true
+		}
+		if !ok {
+			return errors.New("cose: malformed header parameter")
+		}
+	}
+	return nil
+}
+
+// parseMessage decodes a COSE message with n fields, which may be tagged
+// with tag.
+func parseMessage(data []byte, tag uint64, n int) ([]any, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, errMalformed
+	}
+	if t, ok := v.(cbor.Tag); ok {
+		if t.Number != tag {
+			return nil, errors.New("cose: unexpected message type")
+		}
+		v = t.Content
+	}
+	fields, ok := v.([]any)
+	if !ok || len(fields) != n {
+		return nil, errMalformed
+	}
+	return fields, nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cose
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/internal/cbor"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// TestSign1Vector checks the example of RFC 9052, Appendix C.2.1.
+func TestSign1Vector(t *testing.T) {
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(fromHex("bac5b11cad8f99f9c72b05cf4b9e26d244dc189f745228255a219a86d6a09eff")),
+		Y:     new(big.Int).SetBytes(fromHex("20138bf82dc1b6d562be0fa54ab7804a3a64b6d72ccfed6b6fb6ed28bbfc117e")),
+	}
+	msg := fromHex("d28443a10126a10442313154546869732069732074686520636f6e74656e742e" +
+		"58408eb33e4ca31d1c465ab05aac34cc6b23d58fef5c083106c4d25a91aef0b0117e" +
+		"2af9a291aa32e14ab834dc56ed2a223444547e01f11d3b0916e5a4c345cacb36")
+	m, err := ParseSign1(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Protected.Algorithm != ES256 || string(m.Unprotected.KeyID) != "11" ||
+		string(m.Payload) != "This is the content." {
+		t.Errorf("ParseSign1 = %+v", m)
+	}
+	if err := m.Verify(pub, nil); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := m.Verify(pub, []byte("aad")); err != ErrVerification {
+		t.Errorf("Verify with external AAD = %v, want ErrVerification", err)
+	}
+	m.Payload = []byte("This is the content!")
+	if err := m.Verify(pub, nil); err != ErrVerification {
+		t.Errorf("Verify of a modified payload = %v, want ErrVerification", err)
+	}
+}
+
+func TestSign1(t *testing.T) {
+	var signers []crypto.Signer
+	algs := []Algorithm{ES256, ES384, ES512, EdDSA, PS256, RS256}
+	for _, c := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		k, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, k)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers = append(signers, edKey, rsaKey, rsaKey)
+
+	payload := []byte("hello, world")
+	for i, alg := range algs {
+		key := signers[i]
+		opts := &Sign1Options{
+			Unprotected: &Header{KeyID: []byte("k1")},
+			ExternalAAD: []byte("context"),
+		}
+		protected := &Header{Algorithm: alg, X509Chain: [][]byte{{1, 2}, {3}}}
+		msg, err := Sign1(rand.Reader, key, protected, payload, opts)
+		if err != nil {
+			t.Fatalf("Sign1(%v): %v", alg, err)
+		}
+		m, err := ParseSign1(msg)
+		if err != nil {
+			t.Fatalf("ParseSign1(%v): %v", alg, err)
+		}
+		if m.Protected.Algorithm != alg || len(m.Protected.X509Chain) != 2 ||
+			!bytes.Equal(m.Unprotected.KeyID, []byte("k1")) {
+			t.Errorf("ParseSign1(%v) headers = %+v, %+v", alg, m.Protected, m.Unprotected)
+		}
+		if err := m.Verify(key.Public(), []byte("context")); err != nil {
+			t.Errorf("Verify(%v): %v", alg, err)
+		}
+		if err := m.Verify(key.Public(), nil); err != ErrVerification {
+			t.Errorf("Verify(%v) without external AAD = %v, want ErrVerification", alg, err)
+		}
+		other := signers[(i+3)%len(signers)].Public()
+		if err := m.Verify(other, []byte("context")); err == nil {
+			t.Errorf("Verify(%v) with another key succeeded", alg)
+		}
+	}
+
+	// Detached payloads.
+	msg, err := Sign1(rand.Reader, edKey, &Header{Algorithm: EdDSA}, payload, &Sign1Options{Detached: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseSign1(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Payload != nil {
+		t.Errorf("detached payload is included")
+	}
+	if err := m.Verify(edKey.Public(), nil); err == nil {
+		t.Errorf("Verify without the detached payload succeeded")
+	}
+	m.Payload = payload
+	if err := m.Verify(edKey.Public(), nil); err != nil {
+		t.Errorf("Verify with the detached payload: %v", err)
+	}
+
+	// Key and algorithm mismatches.
+	if _, err := Sign1(rand.Reader, signers[1], &Header{Algorithm: ES256}, payload, nil); err == nil {
+		t.Errorf("Sign1 with ES256 and a P-384 key succeeded")
+	}
+	if _, err := Sign1(rand.Reader, edKey, &Header{}, payload, nil); err == nil {
+		t.Errorf("Sign1 without an algorithm succeeded")
+	}
+	if _, err := Sign1(rand.Reader, edKey, &Header{Algorithm: EdDSA}, payload,
+		&Sign1Options{Unprotected: &Header{Algorithm: EdDSA}}); err == nil {
+		t.Errorf("Sign1 with an unprotected algorithm succeeded")
+	}
+}
+
+func TestParseSign1Errors(t *testing.T) {
+	protected, _ := cbor.Marshal(cbor.Map{{Key: int64(1), Value: int64(-8)}})
+	sig := make([]byte, 64)
+	for _, msg := range []any{
+		[]any{protected, cbor.Map{}, []byte("payload")},
+		cbor.Tag{Number: tagEncrypt0, Content: []any{protected, cbor.Map{}, []byte("payload"), sig}},
+		[]any{protected, []any{}, []byte("payload"), sig},
+		[]any{protected, cbor.Map{}, "payload", sig},
+		[]any{[]byte{0xa1}, cbor.Map{}, []byte("payload"), sig},
+		// Parameters in both headers.
+		[]any{protected, cbor.Map{{Key: int64(1), Value: int64(-8)}}, []byte("payload"), sig},
+		// Critical parameters.
+		[]any{mustMarshal(cbor.Map{{Key: int64(1), Value: int64(-8)}, {Key: int64(2), Value: []any{int64(99)}}}),
+			cbor.Map{}, []byte("payload"), sig},
+		// Algorithm in the unprotected header.
+		[]any{[]byte{}, cbor.Map{{Key: int64(1), Value: int64(-8)}}, []byte("payload"), sig},
+		// Malformed kid.
+		[]any{protected, cbor.Map{{Key: int64(4), Value: "k1"}}, []byte("payload"), sig},
+	} {
+		data := mustMarshal(msg)
+		if _, err := ParseSign1(data); err == nil {
+			t.Errorf("ParseSign1(%x) succeeded", data)
+		}
+	}
+}
+
+func mustMarshal(v any) []byte {
+	b, err := cbor.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestEncrypt0(t *testing.T) {
+	plaintext := []byte("This is the content.")
+	for _, alg := range []Algorithm{A128GCM, A192GCM, A256GCM} {
+		key := make([]byte, map[Algorithm]int{A128GCM: 16, A192GCM: 24, A256GCM: 32}[alg])
+		if _, err := rand.Read(key); err != nil {
+			t.Fatal(err)
+		}
+		opts := &Encrypt0Options{Unprotected: &Header{KeyID: []byte("our-secret")}, ExternalAAD: []byte("aad")}
+		msg, err := Encrypt0(rand.Reader, key, &Header{Algorithm: alg}, plaintext, opts)
+		if err != nil {
+			t.Fatalf("Encrypt0(%v): %v", alg, err)
+		}
+		m, err := ParseEncrypt0(msg)
+		if err != nil {
+			t.Fatalf("ParseEncrypt0(%v): %v", alg, err)
+		}
+		if len(m.Unprotected.IV) != 12 || string(m.Unprotected.KeyID) != "our-secret" {
+			t.Errorf("ParseEncrypt0(%v) unprotected header = %+v", alg, m.Unprotected)
+		}
+		got, err := m.Decrypt(key, []byte("aad"))
+		if err != nil {
+			t.Fatalf("Decrypt(%v): %v", alg, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("Decrypt(%v) = %q, want %q", alg, got, plaintext)
+		}
+		if _, err := m.Decrypt(key, nil); err != ErrDecryption {
+			t.Errorf("Decrypt(%v) without external AAD = %v, want ErrDecryption", alg, err)
+		}
+		m.Ciphertext[0] ^= 1
+		if _, err := m.Decrypt(key, []byte("aad")); err != ErrDecryption {
+			t.Errorf("Decrypt(%v) of modified ciphertext = %v, want ErrDecryption", alg, err)
+		}
+	}
+
+	key := make([]byte, 16)
+	if _, err := Encrypt0(rand.Reader, key, &Header{Algorithm: A256GCM}, plaintext, nil); err == nil {
+		t.Errorf("Encrypt0 with the wrong key size succeeded")
+	}
+	if _, err := Encrypt0(rand.Reader, key, &Header{Algorithm: A128GCM, IV: make([]byte, 12)}, plaintext, nil); err == nil {
+		t.Errorf("Encrypt0 with a caller IV succeeded")
+	}
+	if _, err := Encrypt0(rand.Reader, key, &Header{Algorithm: ES256}, plaintext, nil); err == nil {
+		t.Errorf("Encrypt0 with ES256 succeeded")
+	}
+}
+
+func TestKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []*Key{
+		{Public: &ecKey.PublicKey, Algorithm: ES256, KeyID: []byte("ec")},
+		{Public: edPub},
+		{Public: &rsaKey.PublicKey, Algorithm: RS256},
+	} {
+		data, err := k.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%T): %v", k.Public, err)
+		}
+		got, err := ParseKey(data)
+		if err != nil {
+			t.Fatalf("ParseKey(%x): %v", data, err)
+		}
+		if !got.Public.(interface{ Equal(crypto.PublicKey) bool }).Equal(k.Public) ||
+			got.Algorithm != k.Algorithm || !bytes.Equal(got.KeyID, k.KeyID) {
+			t.Errorf("ParseKey(%x) = %+v, want %+v", data, got, k)
+		}
+	}
+
+	// A key from RFC 9052, Appendix C.7.1, in canonical order.
+	data := mustMarshal(cbor.Map{
+		{Key: int64(1), Value: int64(2)},
+		{Key: int64(2), Value: []byte("11")},
+		{Key: int64(-1), Value: int64(1)},
+		{Key: int64(-2), Value: fromHex("bac5b11cad8f99f9c72b05cf4b9e26d244dc189f745228255a219a86d6a09eff")},
+		{Key: int64(-3), Value: fromHex("20138bf82dc1b6d562be0fa54ab7804a3a64b6d72ccfed6b6fb6ed28bbfc117e")},
+	})
+	k, err := ParseKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := k.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Errorf("MarshalBinary = %x, want %x", again, data)
+	}
+
+	for _, m := range []cbor.Map{
+		{},
+		{{Key: int64(1), Value: int64(4)}},
+		// Compressed point.
+		{{Key: int64(1), Value: int64(2)}, {Key: int64(-1), Value: int64(1)},
+			{Key: int64(-2), Value: make([]byte, 32)}, {Key: int64(-3), Value: true}},
+		// Not on the curve.
+		{{Key: int64(1), Value: int64(2)}, {Key: int64(-1), Value: int64(1)},
+			{Key: int64(-2), Value: make([]byte, 32)}, {Key: int64(-3), Value: make([]byte, 32)}},
+		// X25519.
+		{{Key: int64(1), Value: int64(1)}, {Key: int64(-1), Value: int64(4)}, {Key: int64(-2), Value: make([]byte, 32)}},
+		// Private key.
+		{{Key: int64(1), Value: int64(1)}, {Key: int64(-1), Value: int64(6)},
+			{Key: int64(-2), Value: make([]byte, 32)}, {Key: int64(-4), Value: make([]byte, 32)}},
+		// Algorithm mismatch.
+		{{Key: int64(1), Value: int64(1)}, {Key: int64(3), Value: int64(-7)},
+			{Key: int64(-1), Value: int64(6)}, {Key: int64(-2), Value: make([]byte, 32)}},
+	} {
+		if _, err := ParseKey(mustMarshal(m)); err == nil {
+			t.Errorf("ParseKey(%v) succeeded", m)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cose
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/cbor"
+	"errors"
+	"io"
+)
+
+// ErrDecryption is returned by Encrypt0Message.Decrypt when the message
+// can't be decrypted, because it was modified or encrypted with another
+// key.
+var ErrDecryption = errors.New("cose: decryption failed")
+
+const gcmNonceSize = 12
+
+// An Encrypt0Message is a parsed COSE_Encrypt0 message.
+type Encrypt0Message struct {
+	Protected   Header
+	Unprotected Header
+
+	// Ciphertext is the encrypted content, including the authentication
+	// tag.
+	Ciphertext []byte
+
+	protected []byte // the serialized protected header
+}
+
+// Encrypt0Options are optional parameters of Encrypt0.
+type Encrypt0Options struct {
+	// Unprotected is the unprotected header. It must not have an
+	// Algorithm or an IV.
+	Unprotected *Header
+
+	// ExternalAAD is additional data that is authenticated, but not
+	// included in the message.
+	ExternalAAD []byte
+}
+
+// Encrypt0 encrypts plaintext with key and returns a tagged COSE_Encrypt0
+// message. protected is the protected header, and its Algorithm must be
+// A128GCM, A192GCM, or A256GCM, with a key of the matching size. A random
+// IV is read from rand and stored in the unprotected header. opts may be
+// nil.
+func Encrypt0(rand io.Reader, key []byte, protected *Header, plaintext []byte, opts *Encrypt0Options) ([]byte, error) {
+	if opts == nil {
+		opts = &Encrypt0Options{}
+	}
+	var unprotected Header
+	if opts.Unprotected != nil {
+		unprotected = *opts.Unprotected
+	}
+	if unprotected.Algorithm != 0 {
+		return nil, errors.New("cose: algorithm is not in the protected header")
+	}
+	if protected.IV != nil || unprotected.IV != nil {
+		return nil, errors.New("cose: the IV is generated by Encrypt0")
+	}
+	aead, err := newGCM(protected.Algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	p, err := encodeProtected(protected)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := encStructure(p, opts.ExternalAAD)
+	if err != nil {
+		return nil, err
+	}
+	unprotected.IV = make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand, unprotected.IV); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, unprotected.IV, plaintext, aad)
+	return cbor.Marshal(cbor.Tag{Number: tagEncrypt0, Content: []any{
+		p, unprotected.encode(), ciphertext,
+	}})
+}
+
+// ParseEncrypt0 parses a COSE_Encrypt0 message, tagged or untagged. It does
+// not decrypt it.
+func ParseEncrypt0(data []byte) (*Encrypt0Message, error) {
+	fields, err := parseMessage(data, tagEncrypt0, 3)
+	if err != nil {
+		return nil, err
+	}
+	m := &Encrypt0Message{}
+	var ok1, ok2 bool
+	m.protected, ok1 = fields[0].([]byte)
+	m.Ciphertext, ok2 = fields[2].([]byte)
+	if !ok1 || !ok2 {
+		return nil, errMalformed
+	}
+	if m.Protected, m.Unprotected, err = decodeHeaders(m.protected, fields[1]); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Decrypt decrypts m with key, which must match the algorithm of m.
+// externalAAD must be the additional data used when encrypting, if any.
+//
+// If the message can't be decrypted, Decrypt returns ErrDecryption.
+func (m *Encrypt0Message) Decrypt(key, externalAAD []byte) ([]byte, error) {
+	aead, err := newGCM(m.Protected.Algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	iv := m.Unprotected.IV
+	if iv == nil {
+		iv = m.Protected.IV
+	}
+	if len(iv) != gcmNonceSize {
+		return nil, errMalformed
+	}
+	aad, err := encStructure(m.protected, externalAAD)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, m.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+// encStructure returns the Enc_structure of a COSE_Encrypt0 message, from
+// RFC 9052, Section 5.3.
+func encStructure(protected, externalAAD []byte) ([]byte, error) {
+	if externalAAD == nil {
+		externalAAD = []byte{}
+	}
+	return cbor.Marshal([]any{"Encrypt0", protected, externalAAD})
+}
+
+func newGCM(alg Algorithm, key []byte) (cipher.AEAD, error) {
+	var size int
+	switch alg {
+	case A128GCM:
+		size = 16
+	case A192GCM:
+		size = 24
+	case A256GCM:
+		size = 32
+	default:
+		return nil, errors.New("cose: unsupported content encryption algorithm " + alg.String())
+	}
+	if len(key) != size {
+		return nil, errors.New("cose: key size does not match " + alg.String())
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/internal/cbor"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+)
+
+// A Key is a public key in the COSE_Key format of RFC 9052, Section 7.
+type Key struct {
+	// Public is the key, an *ecdsa.PublicKey on P-256, P-384, or P-521,
+	// an ed25519.PublicKey, or an *rsa.PublicKey.
+	Public crypto.PublicKey
+
+	// Algorithm is the algorithm the key is restricted to, if not zero.
+	Algorithm Algorithm
+
+	// KeyID is the key identifier, if any.
+	KeyID []byte
+}
+
+// COSE_Key labels and values, from RFC 9052, Section 7.1, RFC 9053,
+// Section 7, and RFC 8230, Section 4.
+const (
+	keyLabelType      = 1
+	keyLabelID        = 2
+	keyLabelAlgorithm = 3
+	keyLabelCurve     = -1 // OKP and EC2
+	keyLabelX         = -2 // OKP and EC2
+	keyLabelY         = -3 // EC2
+	keyLabelD         = -4 // OKP and EC2
+	keyLabelN         = -1 // RSA
+	keyLabelE         = -2 // RSA
+	keyLabelRSAD      = -3 // RSA
+
+	keyTypeOKP = 1
+	keyTypeEC2 = 2
+	keyTypeRSA = 3
+
+	curveP256    = 1
+	curveP384    = 2
+	curveP521    = 3
+	curveEd25519 = 6
+)
+
+// ParseKey parses a COSE_Key holding a public key. EC2 keys must use the
+// uncompressed form. If the key has an algorithm, it must be a supported
+// signature algorithm matching the key.
+func ParseKey(data []byte) (*Key, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, errors.New("cose: malformed key")
+	}
+	m, ok := v.(cbor.Map)
+	if !ok {
+		return nil, errors.New("cose: malformed key")
+	}
+	k := &Key{}
+	if v, ok := m.Get(int64(keyLabelID)); ok {
+		if k.KeyID, ok = v.([]byte); !ok {
+			return nil, errors.New("cose: malformed key ID")
+		}
+	}
+	if v, ok := m.Get(int64(keyLabelAlgorithm)); ok {
+		alg, ok := v.(int64)
+		if !ok {
+			return nil, errors.New("cose: malformed key algorithm")
+		}
+		k.Algorithm = Algorithm(alg)
+	}
+
+	kty, _ := m.Get(int64(keyLabelType))
+	switch kty {
+	case int64(keyTypeEC2):
+		if _, ok := m.Get(int64(keyLabelD)); ok {
+			return nil, errors.New("cose: private keys are not supported")
+		}
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch crv, _ := m.Get(int64(keyLabelCurve)); crv {
+		case int64(curveP256):
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case int64(curveP384):
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case int64(curveP521):
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, errors.New("cose: unsupported EC2 curve")
+		}
+		size := curveSize(curve)
+		xv, _ := m.Get(int64(keyLabelX))
+		yv, _ := m.Get(int64(keyLabelY))
+		x, ok1 := xv.([]byte)
+		y, ok2 := yv.([]byte)
+		if !ok1 || !ok2 || len(x) != size || len(y) != size {
+			return nil, errors.New("cose: malformed or compressed EC2 key")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, errors.New("cose: invalid EC2 key")
+		}
+		k.Public = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case int64(keyTypeOKP):
+		if _, ok := m.Get(int64(keyLabelD)); ok {
+			return nil, errors.New("cose: private keys are not supported")
+		}
+		if crv, _ := m.Get(int64(keyLabelCurve)); crv != int64(curveEd25519) {
+			return nil, errors.New("cose: unsupported OKP curve")
+		}
+		xv, _ := m.Get(int64(keyLabelX))
+		x, ok := xv.([]byte)
+		if !ok || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("cose: malformed OKP key")
+		}
+		k.Public = ed25519.PublicKey(x)
+	case int64(keyTypeRSA):
+		if _, ok := m.Get(int64(keyLabelRSAD)); ok {
+			return nil, errors.New("cose: private keys are not supported")
+		}
+		nv, _ := m.Get(int64(keyLabelN))
+		ev, _ := m.Get(int64(keyLabelE))
+		n, ok1 := nv.([]byte)
+		e, ok2 := ev.([]byte)
+		if !ok1 || !ok2 || len(n) == 0 || n[0] == 0 || len(e) == 0 || e[0] == 0 || len(e) > 4 {
+			return nil, errors.New("cose: malformed RSA key")
+		}
+		E := new(big.Int).SetBytes(e)
+		if E.Int64() < 3 || E.Int64() > 1<<31-1 || E.Bit(0) != 1 {
+			return nil, errors.New("cose: unsupported RSA public exponent")
+		}
+		k.Public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(E.Int64())}
+	default:
+		return nil, errors.New("cose: unsupported key type")
+	}
+
+	if k.Algorithm != 0 {
+		alg, err := signatureAlgorithm(k.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		if err := alg.checkKey(k.Public); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// MarshalBinary returns the deterministic encoding of k as a COSE_Key.
+func (k *Key) MarshalBinary() ([]byte, error) {
+	var m cbor.Map
+	add := func(label int64, v any) {
+		m = append(m, cbor.Entry{Key: label, Value: v})
+	}
+	var params cbor.Map
+	addParam := func(label int64, v any) {
+		params = append(params, cbor.Entry{Key: label, Value: v})
+	}
+	var kty int64
+	switch pub := k.Public.(type) {
+	case *ecdsa.PublicKey:
+		kty = keyTypeEC2
+		switch pub.Curve {
+		case elliptic.P256():
+			addParam(keyLabelCurve, int64(curveP256))
+		case elliptic.P384():
+			addParam(keyLabelCurve, int64(curveP384))
+		case elliptic.P521():
+			addParam(keyLabelCurve, int64(curveP521))
+		default:
+			return nil, errors.New("cose: unsupported elliptic curve")
+		}
+		size := curveSize(pub.Curve)
+		addParam(keyLabelX, pub.X.FillBytes(make([]byte, size)))
+		addParam(keyLabelY, pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		kty = keyTypeOKP
+		addParam(keyLabelCurve, int64(curveEd25519))
+		addParam(keyLabelX, []byte(pub))
+	case *rsa.PublicKey:
+		kty = keyTypeRSA
+		addParam(keyLabelN, pub.N.Bytes())
+		addParam(keyLabelE, big.NewInt(int64(pub.E)).Bytes())
+	default:
+		return nil, errors.New("cose: unsupported public key type")
+	}
+	add(keyLabelType, kty)
+	if k.KeyID != nil {
+		add(keyLabelID, k.KeyID)
+	}
+	if k.Algorithm != 0 {
+		add(keyLabelAlgorithm, int64(k.Algorithm))
+	}
+	return cbor.Marshal(append(m, params...))
+}
@@ -0,0 +1,276 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/internal/cbor"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// ErrVerification is returned by Sign1Message.Verify when the signature is
+// invalid.
+var ErrVerification = errors.New("cose: signature verification failed")
+
+// A Sign1Message is a parsed COSE_Sign1 message. Its contents are not
+// authenticated until it is verified.
+type Sign1Message struct {
+	Protected   Header
+	Unprotected Header
+
+	// Payload is the signed content. It is nil if the payload is
+	// detached, in which case it must be set before calling Verify.
+	Payload []byte
+
+	protected []byte // the serialized protected header
+	signature []byte
+}
+
+// Sign1Options are optional parameters of Sign1.
+type Sign1Options struct {
+	// Unprotected is the unprotected header. It must not have an
+	// Algorithm.
+	Unprotected *Header
+
+	// ExternalAAD is additional data that is authenticated by the
+	// signature, but not included in the message.
+	ExternalAAD []byte
+
+	// Detached, if true, causes the payload to be omitted from the
+	// message. It must be conveyed to the verifier by other means.
+	Detached bool
+}
+
+// Sign1 signs payload with key and returns a tagged COSE_Sign1 message.
+// protected is the protected header, and its Algorithm must be set to a
+// signature algorithm matching the public key of key. opts may be nil.
+//
+// rand is passed to key.Sign.
+func Sign1(rand io.Reader, key crypto.Signer, protected *Header, payload []byte, opts *Sign1Options) ([]byte, error) {
+	if opts == nil {
+		opts = &Sign1Options{}
+	}
+	if opts.Unprotected != nil && opts.Unprotected.Algorithm != 0 {
+		return nil, errors.New("cose: algorithm is not in the protected header")
+	}
+	alg, err := signatureAlgorithm(protected.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := alg.checkKey(key.Public()); err != nil {
+		return nil, err
+	}
+	p, err := encodeProtected(protected)
+	if err != nil {
+		return nil, err
+	}
+	tbs, err := sigStructure(p, opts.ExternalAAD, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig []byte
+	switch alg.kind {
+	case sigECDSA:
+		sig, err = key.Sign(rand, alg.digest(tbs), alg.hash)
+		if err == nil {
+			sig, err = ecdsaSignatureFromASN1(sig, curveSize(alg.curve))
+		}
+	case sigEdDSA:
+		sig, err = key.Sign(rand, tbs, crypto.Hash(0))
+	case sigPSS:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: alg.hash}
+		sig, err = key.Sign(rand, alg.digest(tbs), opts)
+	case sigPKCS1v15:
+		sig, err = key.Sign(rand, alg.digest(tbs), alg.hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var body any = payload
+	if opts.Detached {
+		body = nil
+	} else if payload == nil {
+		body = []byte{}
+	}
+	return cbor.Marshal(cbor.Tag{Number: tagSign1, Content: []any{
+		p, opts.Unprotected.encode(), body, sig,
+	}})
+}
+
+// ParseSign1 parses a COSE_Sign1 message, tagged or untagged. It does not
+// verify it.
+func ParseSign1(data []byte) (*Sign1Message, error) {
+	fields, err := parseMessage(data, tagSign1, 4)
+	if err != nil {
+		return nil, err
+	}
+	m := &Sign1Message{}
+	var ok1, ok2, ok3 bool
+	m.protected, ok1 = fields[0].([]byte)
+	m.signature, ok2 = fields[3].([]byte)
+	switch payload := fields[2].(type) {
+	case []byte:
+		m.Payload, ok3 = payload, true
+	case nil:
+		ok3 = true
+	}
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errMalformed
+	}
+	if m.Protected, m.Unprotected, err = decodeHeaders(m.protected, fields[1]); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Verify checks the signature of m with key, an *ecdsa.PublicKey,
+// ed25519.PublicKey, or *rsa.PublicKey, which must match the algorithm of
+// m. externalAAD must be the additional data used when signing, if any.
+//
+// If the signature is invalid, Verify returns ErrVerification.
+func (m *Sign1Message) Verify(key crypto.PublicKey, externalAAD []byte) error {
+	alg, err := signatureAlgorithm(m.Protected.Algorithm)
+	if err != nil {
+		return err
+	}
+	if err := alg.checkKey(key); err != nil {
+		return err
+	}
+	if m.Payload == nil {
+		return errors.New("cose: missing detached payload")
+	}
+	tbs, err := sigStructure(m.protected, externalAAD, m.Payload)
+	if err != nil {
+		return err
+	}
+
+	ok := false
+	switch alg.kind {
+	case sigECDSA:
+		size := curveSize(alg.curve)
+		if len(m.signature) == 2*size {
+			r := new(big.Int).SetBytes(m.signature[:size])
+			s := new(big.Int).SetBytes(m.signature[size:])
+			ok = ecdsa.Verify(key.(*ecdsa.PublicKey), alg.digest(tbs), r, s)
+		}
+	case sigEdDSA:
+		ok = ed25519.Verify(key.(ed25519.PublicKey), tbs, m.signature)
+	case sigPSS:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: alg.hash}
+		ok = rsa.VerifyPSS(key.(*rsa.PublicKey), alg.hash, alg.digest(tbs), m.signature, opts) == nil
+	case sigPKCS1v15:
+		ok = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), alg.hash, alg.digest(tbs), m.signature) == nil
+	}
+	if !ok {
+		return ErrVerification
+	}
+	return nil
+}
+
+// sigStructure returns the Sig_structure of a COSE_Sign1 message, from
+// RFC 9052, Section 4.4.
+func sigStructure(protected, externalAAD, payload []byte) ([]byte, error) {
+	if externalAAD == nil {
+		externalAAD = []byte{}
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	return cbor.Marshal([]any{"Signature1", protected, externalAAD, payload})
+}
+
+type sigKind int
+
+const (
+	sigECDSA sigKind = iota
+	sigEdDSA
+	sigPSS
+	sigPKCS1v15
+)
+
+type sigAlgorithm struct {
+	alg   Algorithm
+	kind  sigKind
+	hash  crypto.Hash
+	curve elliptic.Curve // for ECDSA
+}
+
+func signatureAlgorithm(alg Algorithm) (sigAlgorithm, error) {
+	switch alg {
+	case ES256:
+		return sigAlgorithm{alg, sigECDSA, crypto.SHA256, elliptic.P256()}, nil
+	case ES384:
+		return sigAlgorithm{alg, sigECDSA, crypto.SHA384, elliptic.P384()}, nil
+	case ES512:
+		return sigAlgorithm{alg, sigECDSA, crypto.SHA512, elliptic.P521()}, nil
+	case EdDSA:
+		return sigAlgorithm{alg: alg, kind: sigEdDSA}, nil
+	case PS256:
+		return sigAlgorithm{alg: alg, kind: sigPSS, hash: crypto.SHA256}, nil
+	case RS256:
+		return sigAlgorithm{alg: alg, kind: sigPKCS1v15, hash: crypto.SHA256}, nil
+	}
+	return sigAlgorithm{}, errors.New("cose: unsupported signature algorithm " + alg.String())
+}
+
+// checkKey checks that pub is appropriate for the algorithm.
+func (a sigAlgorithm) checkKey(pub crypto.PublicKey) error {
+	switch a.kind {
+	case sigECDSA:
+		if key, ok := pub.(*ecdsa.PublicKey); !ok || key.Curve != a.curve {
+			return errors.New("cose: " + a.alg.String() + " requires an ECDSA key on the matching curve")
+		}
+	case sigEdDSA:
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return errors.New("cose: EdDSA requires an Ed25519 key")
+		}
+	case sigPSS, sigPKCS1v15:
+		if key, ok := pub.(*rsa.PublicKey); !ok || key.N.BitLen() < 2048 {
+			return errors.New("cose: " + a.alg.String() + " requires an RSA key of at least 2048 bits")
+		}
+	}
+	return nil
+}
+
+func (a sigAlgorithm) digest(data []byte) []byte {
+	h := a.hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func curveSize(c elliptic.Curve) int {
+	return (c.Params().BitSize + 7) / 8
+}
+
+// ecdsaSignatureFromASN1 converts an ASN.1 ECDSA signature, as returned by
+// crypto.Signer, to the fixed-size r || s encoding of RFC 9053,
+// Section 2.1.
+func ecdsaSignatureFromASN1(sig []byte, size int) ([]byte, error) {
+	var r, s big.Int
+	input := cryptobyte.String(sig)
+	var inner cryptobyte.String
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) || !input.Empty() ||
+		!inner.ReadASN1Integer(&r) || !inner.ReadASN1Integer(&s) || !inner.Empty() ||
+		r.Sign() <= 0 || s.Sign() <= 0 || r.BitLen() > 8*size || s.BitLen() > 8*size {
+		return nil, errors.New("cose: signer returned a malformed ECDSA signature")
+	}
+	out := make([]byte, 2*size)
+	r.FillBytes(out[:size])
+	s.FillBytes(out[size:])
+	return out, nil
+}
@@ -6,6 +6,8 @@
 package crypto
 
 import (
+	"context"
+	"errors"
 	"hash"
 	"io"
 	"strconv"
@@ -75,16 +77,16 @@ const (
 	SHA512                      // import crypto/sha512
 	MD5SHA1                     // no implementation; MD5+SHA1 used for TLS RSA
 	RIPEMD160                   // import golang.org/x/crypto/ripemd160
-	SHA3_224                    // import golang.org/x/crypto/sha3
-	SHA3_256                    // import golang.org/x/crypto/sha3
-	SHA3_384                    // import golang.org/x/crypto/sha3
-	SHA3_512                    // import golang.org/x/crypto/sha3
+	SHA3_224                    // import crypto/sha3
+	SHA3_256                    // import crypto/sha3
+	SHA3_384                    // import crypto/sha3
+	SHA3_512                    // import crypto/sha3
 	SHA512_224                  // import crypto/sha512
 	SHA512_256                  // import crypto/sha512
-	BLAKE2s_256                 // import golang.org/x/crypto/blake2s
-	BLAKE2b_256                 // import golang.org/x/crypto/blake2b
-	BLAKE2b_384                 // import golang.org/x/crypto/blake2b
-	BLAKE2b_512                 // import golang.org/x/crypto/blake2b
+	BLAKE2s_256                 // import crypto/blake2s
+	BLAKE2b_256                 // import crypto/blake2b
+	BLAKE2b_384                 // import crypto/blake2b
+	BLAKE2b_512                 // import crypto/blake2b
 	maxHash
 )
 
@@ -206,6 +208,77 @@ type SignerOpts interface {
 	HashFunc() Hash
 }
 
+// SignerCtx is an optional interface for a Signer whose operations may be
+// slow or remote, such as a key kept in a network HSM or a cloud key
+// management service, and which can honor a deadline or cancellation.
+//
+// Callers that hold a context should use SignContext, which prefers this
+// interface when it is implemented.
+type SignerCtx interface {
+	Signer
+
+	// SignContext is like Sign, but aborts the operation and returns an
+	// error if ctx is done before the signature is produced.
+	SignContext(ctx context.Context, rand io.Reader, digest []byte, opts SignerOpts) (signature []byte, err error)
+}
+
+// SignContext signs digest with signer. If signer implements SignerCtx,
+// its SignContext method is called with ctx. Otherwise, SignContext returns
+// ctx.Err() if ctx is already done, and calls Sign if not.
+func SignContext(ctx context.Context, signer Signer, rand io.Reader, digest []byte, opts SignerOpts) (signature []byte, err error) {
+	if s, ok := signer.(SignerCtx); ok {
+		return s.SignContext(ctx, rand, digest, opts)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand, digest, opts)
+}
+
+// MessageSigner is an interface for an opaque private key that must be
+// given the whole message to sign rather than its digest, for example a
+// hardware token that hashes internally.
+//
+// Callers that hold the message should use SignMessage, which prefers this
+// interface when it is implemented.
+type MessageSigner interface {
+	Signer
+
+	// SignMessage is like Sign, but msg is the message itself. If
+	// opts.HashFunc() is not zero, SignMessage hashes msg with that hash
+	// function, and the signature is the one that Sign would produce for
+	// the resulting digest.
+	SignMessage(rand io.Reader, msg []byte, opts SignerOpts) (signature []byte, err error)
+}
+
+// SignMessage signs msg with signer. If signer implements MessageSigner,
+// its SignMessage method is called with msg. Otherwise, msg is hashed with
+// opts.HashFunc(), unless it is zero, and the result is passed to Sign.
+func SignMessage(signer Signer, rand io.Reader, msg []byte, opts SignerOpts) (signature []byte, err error) {
+	if s, ok := signer.(MessageSigner); ok {
+		return s.SignMessage(rand, msg, opts)
+	}
+	digest, err := hashMessage(msg, opts)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand, digest, opts)
+}
+
+// hashMessage returns the digest of msg to pass to Signer.Sign.
+func hashMessage(msg []byte, opts SignerOpts) ([]byte, error) {
+	hash := opts.HashFunc()
+	if hash == 0 {
+		return msg, nil
+	}
+	if !hash.Available() {
+		return nil, errors.New("crypto: requested hash function #" + strconv.Itoa(int(hash)) + " is unavailable")
+	}
+	h := hash.New()
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
 // Decrypter is an interface for an opaque private key that can be used for
 // asymmetric decryption operations. An example would be an RSA key
 // kept in a hardware module.
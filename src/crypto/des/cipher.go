@@ -7,6 +7,7 @@ package des
 import (
 	"crypto/cipher"
 	"crypto/internal/alias"
+	"crypto/internal/boring"
 	"encoding/binary"
 	"strconv"
 )
@@ -48,6 +49,8 @@ func (c *desCipher) Encrypt(dst, src []byte) {
 	if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
 		panic("crypto/des: invalid buffer overlap")
 	}
+	// DES and TDEA are not approved for use with the module.
+	boring.SetNotApproved()
 	encryptBlock(c.subkeys[:], dst, src)
 }
 
@@ -61,6 +64,7 @@ func (c *desCipher) Decrypt(dst, src []byte) {
 	if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
 		panic("crypto/des: invalid buffer overlap")
 	}
+	boring.SetNotApproved()
 	decryptBlock(c.subkeys[:], dst, src)
 }
 
@@ -94,6 +98,7 @@ func (c *tripleDESCipher) Encrypt(dst, src []byte) {
 	if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
 		panic("crypto/des: invalid buffer overlap")
 	}
+	boring.SetNotApproved()
 
 	b := binary.BigEndian.Uint64(src)
 	b = permuteInitialBlock(b)
@@ -129,6 +134,7 @@ func (c *tripleDESCipher) Decrypt(dst, src []byte) {
 	if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
 		panic("crypto/des: invalid buffer overlap")
 	}
+	boring.SetNotApproved()
 
 	b := binary.BigEndian.Uint64(src)
 	b = permuteInitialBlock(b)
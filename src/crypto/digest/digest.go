@@ -0,0 +1,183 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package digest is a registry of digest algorithms, keyed by crypto.Hash
+// value, ASN.1 object identifier, and name.
+//
+// Protocols identify hash functions by OID (X.509, CMS, PKCS #12), by
+// name (HTTP Digest fields, SRI, multihash tables), or by crypto.Hash
+// value. This package maps between them, so that each protocol does not
+// need its own table, and returns constructors that go through the same
+// implementations as crypto.Hash.New, including the FIPS backend when it
+// is enabled.
+//
+// Names are those of the IANA Hash Function Textual Names registry, such
+// as "sha-256", extended in the same style for algorithms that are not in
+// it. They are matched case-insensitively, and the names returned by
+// crypto.Hash.String and the unhyphenated forms, such as "sha256", are
+// accepted as aliases.
+package digest
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"hash"
+	"strings"
+	"sync"
+)
+
+// An Algorithm is a registered digest algorithm. Algorithms are
+// immutable.
+type Algorithm struct {
+	name string
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash
+	size int
+	new  func() hash.Hash
+}
+
+// Name returns the canonical, lowercase name of a.
+func (a *Algorithm) Name() string { return a.name }
+
+// OID returns the object identifier of a, or nil if it has none.
+func (a *Algorithm) OID() asn1.ObjectIdentifier {
+	if a.oid == nil {
+		return nil
+	}
+	return append(asn1.ObjectIdentifier(nil), a.oid...)
+}
+
+// Hash returns the crypto.Hash value of a, or zero if a was added with
+// Register.
+func (a *Algorithm) Hash() crypto.Hash { return a.hash }
+
+// Size returns the length, in bytes, of the digests of a.
+func (a *Algorithm) Size() int { return a.size }
+
+// String returns the name of a.
+func (a *Algorithm) String() string { return a.name }
+
+// New returns a new hash.Hash computing a. It returns an error if the
+// implementation of a is not linked into the binary: for a crypto.Hash,
+// the package that implements it must be imported.
+func (a *Algorithm) New() (hash.Hash, error) {
+	if a.new != nil {
+		return a.new(), nil
+	}
+	if !a.hash.Available() {
+		return nil, errors.New("digest: " + a.name + " is not linked into the binary")
+	}
+	return a.hash.New(), nil
+}
+
+var builtin = []struct {
+	hash    crypto.Hash
+	name    string
+	oid     asn1.ObjectIdentifier
+	aliases []string
+}{
+	{crypto.MD4, "md4", asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 4}, nil},
+	{crypto.MD5, "md5", asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 5}, nil},
+	{crypto.SHA1, "sha-1", asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}, []string{"sha1"}},
+	{crypto.SHA224, "sha-224", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 4}, []string{"sha224"}},
+	{crypto.SHA256, "sha-256", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, []string{"sha256"}},
+	{crypto.SHA384, "sha-384", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}, []string{"sha384"}},
+	{crypto.SHA512, "sha-512", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}, []string{"sha512"}},
+	{crypto.SHA512_224, "sha-512/224", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 5}, []string{"sha512/224"}},
+	{crypto.SHA512_256, "sha-512/256", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 6}, []string{"sha512/256"}},
+	{crypto.SHA3_224, "sha3-224", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 7}, nil},
+	{crypto.SHA3_256, "sha3-256", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 8}, nil},
+	{crypto.SHA3_384, "sha3-384", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 9}, nil},
+	{crypto.SHA3_512, "sha3-512", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 10}, nil},
+	{crypto.RIPEMD160, "ripemd-160", asn1.ObjectIdentifier{1, 3, 36, 3, 2, 1}, []string{"ripemd160"}},
+	// RFC 7693, Appendix C.
+	{crypto.BLAKE2s_256, "blake2s-256", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 1722, 12, 2, 2, 8}, nil},
+	{crypto.BLAKE2b_256, "blake2b-256", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 1722, 12, 2, 1, 8}, nil},
+	{crypto.BLAKE2b_384, "blake2b-384", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 1722, 12, 2, 1, 12}, nil},
+	{crypto.BLAKE2b_512, "blake2b-512", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 1722, 12, 2, 1, 16}, nil},
+}
+
+var registry struct {
+	sync.RWMutex
+	byHash map[crypto.Hash]*Algorithm
+	byName map[string]*Algorithm
+	byOID  map[string]*Algorithm
+}
+
+func init() {
+	registry.byHash = make(map[crypto.Hash]*Algorithm)
+	registry.byName = make(map[string]*Algorithm)
+	registry.byOID = make(map[string]*Algorithm)
+	for _, b := range builtin {
+		a := &Algorithm{name: b.name, oid: b.oid, hash: b.hash, size: b.hash.Size()}
+		registry.byHash[b.hash] = a
+		registry.byOID[b.oid.String()] = a
+		registry.byName[b.name] = a
+		registry.byName[strings.ToLower(b.hash.String())] = a
+		for _, alias := range b.aliases {
+			registry.byName[alias] = a
+		}
+	}
+}
+
+// Lookup returns the algorithm of h.
+func Lookup(h crypto.Hash) (*Algorithm, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	a, ok := registry.byHash[h]
+	return a, ok
+}
+
+// LookupName returns the algorithm with the given name or alias, matched
+// case-insensitively.
+func LookupName(name string) (*Algorithm, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	a, ok := registry.byName[strings.ToLower(name)]
+	return a, ok
+}
+
+// LookupOID returns the algorithm with the given object identifier.
+func LookupOID(oid asn1.ObjectIdentifier) (*Algorithm, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	a, ok := registry.byOID[oid.String()]
+	return a, ok
+}
+
+// Register adds a digest algorithm that has no crypto.Hash value, such as
+// a national standard or one implemented by a hardware module, so that it
+// can be looked up by name and OID. oid may be nil. size is the length of
+// the digests returned by new.
+//
+// Register returns an error if the name or the OID is already registered.
+// It is meant to be called from init functions.
+func Register(name string, oid asn1.ObjectIdentifier, size int, new func() hash.Hash) (*Algorithm, error) {
+	if name == "" || size <= 0 || new == nil {
+		return nil, errors.New("digest: invalid algorithm")
+	}
+	a := &Algorithm{
+		name: strings.ToLower(name),
+		size: size,
+		new:  new,
+	}
+	if oid != nil {
+		a.oid = append(asn1.ObjectIdentifier(nil), oid...)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.byName[a.name]; ok {
+		return nil, errors.New("digest: " + a.name + " is already registered")
+	}
+	if oid != nil {
+		if _, ok := registry.byOID[oid.String()]; ok {
+			return nil, errors.New("digest: OID " + oid.String() + " is already registered")
+		}
+		registry.byOID[oid.String()] = a
+	}
+	registry.byName[a.name] = a
+	return a, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package digest
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha3"
+	_ "crypto/sha512"
+	"encoding/asn1"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		alias string
+		oid   asn1.ObjectIdentifier
+		hash  crypto.Hash
+	}{
+		{"sha-256", "SHA256", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, crypto.SHA256},
+		{"sha-1", "SHA1", asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}, crypto.SHA1},
+		{"sha-512/256", "SHA-512/256", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 6}, crypto.SHA512_256},
+		{"sha3-384", "SHA3-384", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 9}, crypto.SHA3_384},
+		{"blake2b-512", "BLAKE2b-512", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 1722, 12, 2, 1, 16}, crypto.BLAKE2b_512},
+	} {
+		a, ok := Lookup(tt.hash)
+		if !ok {
+			t.Fatalf("Lookup(%v) failed", tt.hash)
+		}
+		if a.Name() != tt.name || !a.OID().Equal(tt.oid) || a.Hash() != tt.hash || a.Size() != tt.hash.Size() {
+			t.Errorf("Lookup(%v) = %v, %v, %v, %d", tt.hash, a.Name(), a.OID(), a.Hash(), a.Size())
+		}
+		for _, name := range []string{tt.name, tt.alias} {
+			if b, ok := LookupName(name); !ok || b != a {
+				t.Errorf("LookupName(%q) = %v, %v", name, b, ok)
+			}
+		}
+		if b, ok := LookupOID(tt.oid); !ok || b != a {
+			t.Errorf("LookupOID(%v) = %v, %v", tt.oid, b, ok)
+		}
+	}
+
+	if _, ok := LookupName("sha-257"); ok {
+		t.Errorf("LookupName(sha-257) succeeded")
+	}
+	if _, ok := LookupOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 100}); ok {
+		t.Errorf("LookupOID of an unknown OID succeeded")
+	}
+	if _, ok := Lookup(crypto.MD5SHA1); ok {
+		t.Errorf("Lookup(MD5SHA1) succeeded")
+	}
+
+	// The OID must not alias the registry.
+	a, _ := Lookup(crypto.SHA256)
+	a.OID()[0] = 5
+	if b, ok := LookupOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}); !ok || b != a {
+		t.Errorf("modifying the returned OID changed the registry")
+	}
+}
+
+func TestNew(t *testing.T) {
+	a, _ := LookupName("sha3-256")
+	h, err := a.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("abc"))
+	want := crypto.SHA3_256.New()
+	want.Write([]byte("abc"))
+	if !bytes.Equal(h.Sum(nil), want.Sum(nil)) {
+		t.Errorf("New returned the wrong hash function")
+	}
+
+	a, _ = Lookup(crypto.RIPEMD160)
+	if _, err := a.New(); err == nil {
+		t.Errorf("New of an unlinked hash function succeeded")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 99, 1}
+	newCRC := func() hash.Hash { return crc32.NewIEEE() }
+	a, err := Register("Test-CRC32", oid, 4, newCRC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name() != "test-crc32" || a.Hash() != 0 || a.Size() != 4 {
+		t.Errorf("Register returned %v, %v, %d", a.Name(), a.Hash(), a.Size())
+	}
+	if b, ok := LookupName("TEST-CRC32"); !ok || b != a {
+		t.Errorf("LookupName of a registered algorithm = %v, %v", b, ok)
+	}
+	if b, ok := LookupOID(oid); !ok || b != a {
+		t.Errorf("LookupOID of a registered algorithm = %v, %v", b, ok)
+	}
+	if h, err := a.New(); err != nil || h.Size() != 4 {
+		t.Errorf("New of a registered algorithm = %v, %v", h, err)
+	}
+
+	if _, err := Register("test-crc32", nil, 4, newCRC); err == nil {
+		t.Errorf("registering a duplicate name succeeded")
+	}
+	if _, err := Register("sha256", nil, 4, newCRC); err == nil {
+		t.Errorf("registering a builtin alias succeeded")
+	}
+	if _, err := Register("other", oid, 4, newCRC); err == nil {
+		t.Errorf("registering a duplicate OID succeeded")
+	}
+	if _, err := Register("other", nil, 0, newCRC); err == nil {
+		t.Errorf("registering a zero size succeeded")
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"bytes"
+	"errors"
+)
+
+// This file implements the PKCS #8 (RFC 5208, RFC 5958) and PKIX
+// SubjectPublicKeyInfo (RFC 5280, RFC 5480, RFC 8410) encodings of the keys
+// of this package. They produce the same DER as the corresponding
+// crypto/x509 functions, which can't be used here without an import cycle.
+
+// ASN.1 DER tags.
+const (
+	tagInteger     = 0x02
+	tagBitString   = 0x03
+	tagOctetString = 0x04
+	tagOID         = 0x06
+	tagSequence    = 0x30
+	tagContext0    = 0xa0 // [0] EXPLICIT
+	tagContext1    = 0xa1 // [1] EXPLICIT
+	tagImplicit1   = 0x81 // [1] IMPLICIT, primitive
+)
+
+// Encoded object identifiers, without tag and length.
+var (
+	oidPublicKeyEC = []byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x02, 0x01} // 1.2.840.10045.2.1
+	oidX25519      = []byte{0x2b, 0x65, 0x6e}                         // 1.3.101.110
+)
+
+// curveInfo holds the identifiers of a curve in the key encodings.
+type curveInfo struct {
+	curve Curve
+	// oid is the named curve OID for NIST curves, and the algorithm
+	// OID for X25519.
+	oid []byte
+	// name is the JWK "crv" value.
+	name string
+	// scalarSize is the length of the private key encoding.
+	scalarSize int
+}
+
+var curves = []curveInfo{
+	{p256, []byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}, "P-256", 32}, // 1.2.840.10045.3.1.7
+	{p384, []byte{0x2b, 0x81, 0x04, 0x00, 0x22}, "P-384", 48},                   // 1.3.132.0.34
+	{p521, []byte{0x2b, 0x81, 0x04, 0x00, 0x23}, "P-521", 66},                   // 1.3.132.0.35
+	{x25519, oidX25519, "X25519", 32},
+}
+
+func infoForCurve(c Curve) (curveInfo, error) {
+	for _, info := range curves {
+		if info.curve == c {
+			return info, nil
+		}
+	}
+	return curveInfo{}, errors.New("crypto/ecdh: unsupported curve")
+}
+
+// algorithmIdentifier returns the DER AlgorithmIdentifier for keys on info.
+func (info curveInfo) algorithmIdentifier() []byte {
+	if info.curve == x25519 {
+		return derTLV(tagSequence, derTLV(tagOID, oidX25519))
+	}
+	return derTLV(tagSequence, derTLV(tagOID, oidPublicKeyEC), derTLV(tagOID, info.oid))
+}
+
+// MarshalPKIX returns the PKIX, ASN.1 DER SubjectPublicKeyInfo encoding of k,
+// as produced by crypto/x509.MarshalPKIXPublicKey.
+//
+// This kind of key is commonly encoded in PEM blocks of type "PUBLIC KEY".
+func (k *PublicKey) MarshalPKIX() ([]byte, error) {
+	info, err := infoForCurve(k.curve)
+	if err != nil {
+		return nil, err
+	}
+	return derTLV(tagSequence,
+		info.algorithmIdentifier(),
+		derTLV(tagBitString, []byte{0}, k.publicKey),
+	), nil
+}
+
+// ParsePKIXPublicKey parses a public key in PKIX, ASN.1 DER form, for one of
+// the curves of this package.
+//
+// This kind of key is commonly encoded in PEM blocks of type "PUBLIC KEY".
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	errInvalid := errors.New("crypto/ecdh: invalid PKIX public key")
+	spki, ok := derReader(der).readOnly(tagSequence)
+	if !ok {
+		return nil, errInvalid
+	}
+	algo, ok := spki.read(tagSequence)
+	if !ok {
+		return nil, errInvalid
+	}
+	bits, ok := spki.read(tagBitString)
+	if !ok || !spki.empty() || len(bits) < 1 || bits[0] != 0 {
+		return nil, errInvalid
+	}
+	info, err := parseAlgorithmIdentifier(algo)
+	if err != nil {
+		return nil, err
+	}
+	return info.curve.NewPublicKey(bits[1:])
+}
+
+func parseAlgorithmIdentifier(algo derReader) (curveInfo, error) {
+	errInvalid := errors.New("crypto/ecdh: invalid algorithm identifier")
+	oid, ok := algo.read(tagOID)
+	if !ok {
+		return curveInfo{}, errInvalid
+	}
+	switch {
+	case bytes.Equal(oid, oidX25519):
+		// RFC 8410, Section 3: parameters MUST be absent.
+		if !algo.empty() {
+			return curveInfo{}, errInvalid
+		}
+		return curves[len(curves)-1], nil
+	case bytes.Equal(oid, oidPublicKeyEC):
+		named, ok := algo.read(tagOID)
+		if !ok || !algo.empty() {
+			return curveInfo{}, errInvalid
+		}
+		for _, info := range curves {
+			if info.curve != x25519 && bytes.Equal(named, info.oid) {
+				return info, nil
+			}
+		}
+		return curveInfo{}, errors.New("crypto/ecdh: unsupported curve")
+	}
+	return curveInfo{}, errors.New("crypto/ecdh: unsupported public key algorithm")
+}
+
+// MarshalPKCS8 returns the PKCS #8, ASN.1 DER encoding of k, as produced by
+// crypto/x509.MarshalPKCS8PrivateKey. For NIST curves, the inner SEC 1
+// structure includes the public key and omits the curve parameters.
+//
+// This kind of key is commonly encoded in PEM blocks of type "PRIVATE KEY".
+func (k *PrivateKey) MarshalPKCS8() ([]byte, error) {
+	info, err := infoForCurve(k.curve)
+	if err != nil {
+		return nil, err
+	}
+	var inner []byte
+	if info.curve == x25519 {
+		// RFC 8410, Section 7: CurvePrivateKey ::= OCTET STRING.
+		inner = derTLV(tagOctetString, k.privateKey)
+	} else {
+		// RFC 5915, Section 3: ECPrivateKey.
+		inner = derTLV(tagSequence,
+			derTLV(tagInteger, []byte{1}),
+			derTLV(tagOctetString, k.privateKey),
+			derTLV(tagContext1, derTLV(tagBitString, []byte{0}, k.PublicKey().publicKey)),
+		)
+	}
+	return derTLV(tagSequence,
+		derTLV(tagInteger, []byte{0}),
+		info.algorithmIdentifier(),
+		derTLV(tagOctetString, inner),
+	), nil
+}
+
+// ParsePKCS8PrivateKey parses a private key in PKCS #8, ASN.1 DER form, for
+// one of the curves of this package. Attributes and, for NIST curves, the
+// optional public key are ignored.
+//
+// This kind of key is commonly encoded in PEM blocks of type "PRIVATE KEY".
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	errInvalid := errors.New("crypto/ecdh: invalid PKCS #8 private key")
+	p, ok := derReader(der).readOnly(tagSequence)
+	if !ok {
+		return nil, errInvalid
+	}
+	version, ok := p.read(tagInteger)
+	// Version 1 is OneAsymmetricKey from RFC 5958, which may carry a
+	// public key after the attributes.
+	if !ok || len(version) != 1 || version[0] > 1 {
+		return nil, errInvalid
+	}
+	algo, ok := p.read(tagSequence)
+	if !ok {
+		return nil, errInvalid
+	}
+	inner, ok := p.read(tagOctetString)
+	if !ok {
+		return nil, errInvalid
+	}
+	p.skipOptional(tagContext0)
+	if version[0] == 1 {
+		p.skipOptional(tagImplicit1)
+	}
+	if !p.empty() {
+		return nil, errInvalid
+	}
+	info, err := parseAlgorithmIdentifier(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.curve == x25519 {
+		key, ok := derReader(inner).readOnly(tagOctetString)
+		if !ok {
+			return nil, errInvalid
+		}
+		return x25519.NewPrivateKey(key)
+	}
+
+	ec, ok := derReader(inner).readOnly(tagSequence)
+	if !ok {
+		return nil, errInvalid
+	}
+	if v, ok := ec.read(tagInteger); !ok || len(v) != 1 || v[0] != 1 {
+		return nil, errInvalid
+	}
+	key, ok := ec.read(tagOctetString)
+	if !ok || len(key) > info.scalarSize {
+		return nil, errInvalid
+	}
+	if params, ok := ec.readOptional(tagContext0); ok {
+		named, ok := params.readOnly(tagOID)
+		if !ok || !bytes.Equal(named, info.oid) {
+			return nil, errInvalid
+		}
+	}
+	ec.skipOptional(tagContext1)
+	if !ec.empty() {
+		return nil, errInvalid
+	}
+	// Some encoders strip leading zeroes from the scalar.
+	if len(key) < info.scalarSize {
+		key = append(make([]byte, info.scalarSize-len(key)), key...)
+	}
+	return info.curve.NewPrivateKey(key)
+}
+
+// derTLV returns the DER encoding of an element with the given tag whose
+// contents are the concatenation of contents.
+func derTLV(tag byte, contents ...[]byte) []byte {
+	n := 0
+	for _, c := range contents {
+		n += len(c)
+	}
+	b := make([]byte, 0, 4+n)
+	b = append(b, tag)
+	switch {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n < 0x100:
+		b = append(b, 0x81, byte(n))
+	default:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	}
+	for _, c := range contents {
+		b = append(b, c...)
+	}
+	return b
+}
+
+// derReader parses a sequence of DER elements. It accepts only the
+// definite, minimal length encodings that DER requires.
+type derReader []byte
+
+func (r derReader) empty() bool { return len(r) == 0 }
+
+// peek returns the tag, contents and total size of the next element.
+func (r derReader) peek() (tag byte, contents []byte, size int, ok bool) {
+	if len(r) < 2 {
+		return 0, nil, 0, false
+	}
+	tag, n, hdr := r[0], int(r[1]), 2
+	switch {
+	case n < 0x80:
+	case n == 0x81:
+		if len(r) < 3 || r[2] < 0x80 {
+			return 0, nil, 0, false
+		}
+		n, hdr = int(r[2]), 3
+	case n == 0x82:
+		if len(r) < 4 || r[2] == 0 {
+			return 0, nil, 0, false
+		}
+		n, hdr = int(r[2])<<8|int(r[3]), 4
+	default:
+		return 0, nil, 0, false
+	}
+	if len(r)-hdr < n {
+		return 0, nil, 0, false
+	}
+	return tag, r[hdr : hdr+n], hdr + n, true
+}
+
+// read consumes the next element, which must have the given tag, and
+// returns its contents.
+func (r *derReader) read(tag byte) (derReader, bool) {
+	t, contents, size, ok := r.peek()
+	if !ok || t != tag {
+		return nil, false
+	}
+	*r = (*r)[size:]
+	return contents, true
+}
+
+// readOnly reads an element that must be the only one in r.
+func (r derReader) readOnly(tag byte) (derReader, bool) {
+	contents, ok := r.read(tag)
+	if !ok || !r.empty() {
+		return nil, false
+	}
+	return contents, true
+}
+
+// readOptional reads the next element if it has the given tag.
+func (r *derReader) readOptional(tag byte) (derReader, bool) {
+	if t, _, _, ok := r.peek(); !ok || t != tag {
+		return nil, false
+	}
+	return r.read(tag)
+}
+
+func (r *derReader) skipOptional(tag byte) {
+	r.readOptional(tag)
+}
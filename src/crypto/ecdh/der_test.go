@@ -0,0 +1,109 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+)
+
+var encodingCurves = []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.P521(), ecdh.X25519()}
+
+func TestPKCS8(t *testing.T) {
+	for _, curve := range encodingCurves {
+		t.Run(curve.(interface{ String() string }).String(), func(t *testing.T) {
+			k, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			der, err := k.MarshalPKCS8()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := x509.MarshalPKCS8PrivateKey(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(der, want) {
+				t.Errorf("MarshalPKCS8 = %x, want %x", der, want)
+			}
+			k2, err := ecdh.ParsePKCS8PrivateKey(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !k.Equal(k2) {
+				t.Error("ParsePKCS8PrivateKey returned a different key")
+			}
+			for i := range der {
+				if _, err := ecdh.ParsePKCS8PrivateKey(der[:i]); err == nil {
+					t.Fatalf("ParsePKCS8PrivateKey accepted a truncated key of length %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestPKIX(t *testing.T) {
+	for _, curve := range encodingCurves {
+		t.Run(curve.(interface{ String() string }).String(), func(t *testing.T) {
+			k, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			der, err := k.PublicKey().MarshalPKIX()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := x509.MarshalPKIXPublicKey(k.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(der, want) {
+				t.Errorf("MarshalPKIX = %x, want %x", der, want)
+			}
+			pub, err := ecdh.ParsePKIXPublicKey(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !k.PublicKey().Equal(pub) {
+				t.Error("ParsePKIXPublicKey returned a different key")
+			}
+			if _, err := ecdh.ParsePKIXPublicKey(append(der, 0)); err == nil {
+				t.Error("ParsePKIXPublicKey accepted trailing data")
+			}
+		})
+	}
+}
+
+func TestParsePKCS8External(t *testing.T) {
+	// An OpenSSL-style P-256 key whose ECPrivateKey carries the curve
+	// parameters and the public key, and an RFC 8410 X25519 key.
+	for _, s := range []string{
+		"308193020100301306072a8648ce3d020106082a8648ce3d03010704793077020101" +
+			"0420c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721" +
+			"a00a06082a8648ce3d030107a14403420004" +
+			"60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6" +
+			"7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299",
+		"302e020100300506032b656e04220420" +
+			"77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a",
+	} {
+		der, _ := hex.DecodeString(s)
+		k, err := ecdh.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			t.Fatalf("ParsePKCS8PrivateKey(%s): %v", s, err)
+		}
+		want, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wk, ok := want.(*ecdh.PrivateKey); ok && !k.Equal(wk) {
+			t.Errorf("ParsePKCS8PrivateKey(%s) disagrees with crypto/x509", s)
+		}
+	}
+}
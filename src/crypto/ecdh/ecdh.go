@@ -75,6 +75,36 @@ func (k *PublicKey) Bytes() []byte {
 	return append(buf[:0], k.publicKey...)
 }
 
+// BytesCompressed returns the SEC 1, Version 2.0, Section 2.3.3
+// compressed encoding of the public key, which is about half the size of
+// the one returned by Bytes. It returns an error for X25519 keys, which have
+// no compressed form.
+func (k *PublicKey) BytesCompressed() ([]byte, error) {
+	c, ok := k.curve.(compressor)
+	if !ok {
+		return nil, errors.New("crypto/ecdh: curve has no compressed point encoding")
+	}
+	return c.compressPublicKey(k), nil
+}
+
+// DecompressPublicKey decodes a SEC 1, Version 2.0, Section 2.3.4 compressed
+// public key for one of the NIST curves, and returns it like NewPublicKey
+// would return its uncompressed form. It returns an error for X25519, for
+// encodings that are not compressed, and for points not on the curve.
+func DecompressPublicKey(c Curve, key []byte) (*PublicKey, error) {
+	cc, ok := c.(compressor)
+	if !ok {
+		return nil, errors.New("crypto/ecdh: curve has no compressed point encoding")
+	}
+	return cc.decompressPublicKey(key)
+}
+
+// compressor is implemented by the curves that have compressed points.
+type compressor interface {
+	compressPublicKey(*PublicKey) []byte
+	decompressPublicKey([]byte) (*PublicKey, error)
+}
+
 // Equal returns whether x represents the same public key as k.
 //
 // Note that there can be equivalent public keys with different encodings which
@@ -523,3 +523,50 @@ func TestMismatchedCurves(t *testing.T) {
 		}
 	}
 }
+
+func TestCompressedPublicKey(t *testing.T) {
+	// The P-256 generator, from SEC 2, Version 2.0, Section 2.4.2.
+	g, _ := hex.DecodeString("036b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296")
+	pub, err := ecdh.DecompressPublicKey(ecdh.P256(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := hex.DecodeString("046b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296" +
+		"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5")
+	if !bytes.Equal(pub.Bytes(), want) {
+		t.Errorf("DecompressPublicKey = %x, want %x", pub.Bytes(), want)
+	}
+
+	testAllCurves(t, func(t *testing.T, curve ecdh.Curve) {
+		k, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := k.PublicKey().BytesCompressed()
+		if curve == ecdh.X25519() {
+			if err == nil {
+				t.Error("BytesCompressed succeeded for X25519")
+			}
+			if _, err := ecdh.DecompressPublicKey(curve, k.PublicKey().Bytes()); err == nil {
+				t.Error("DecompressPublicKey succeeded for X25519")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := ecdh.DecompressPublicKey(curve, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(k.PublicKey()) {
+			t.Error("DecompressPublicKey returned a different key")
+		}
+		if _, err := ecdh.DecompressPublicKey(curve, k.PublicKey().Bytes()); err == nil {
+			t.Error("DecompressPublicKey accepted an uncompressed point")
+		}
+		if _, err := ecdh.DecompressPublicKey(curve, b[:len(b)-1]); err == nil {
+			t.Error("DecompressPublicKey accepted a truncated point")
+		}
+	})
+}
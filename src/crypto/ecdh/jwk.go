@@ -0,0 +1,411 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// This file implements the JSON Web Key encoding of RFC 7517, with the
+// "EC" key type of RFC 7518, Section 6.2 for NIST curves and the "OKP" key
+// type of RFC 8037 for X25519. encoding/json is not available at this
+// level of the standard library, so the few JSON shapes involved are
+// handled directly.
+
+var b64 = base64.RawURLEncoding.Strict()
+
+// MarshalJWK returns the JSON Web Key encoding of k, holding the public
+// key parameters only.
+func (k *PublicKey) MarshalJWK() ([]byte, error) {
+	return marshalJWK(k.curve, k.publicKey, nil)
+}
+
+// MarshalJWK returns the JSON Web Key encoding of k, including the private
+// parameter "d". The result must be protected like the key itself.
+func (k *PrivateKey) MarshalJWK() ([]byte, error) {
+	return marshalJWK(k.curve, k.PublicKey().publicKey, k.privateKey)
+}
+
+func marshalJWK(c Curve, pub, priv []byte) ([]byte, error) {
+	info, err := infoForCurve(c)
+	if err != nil {
+		return nil, err
+	}
+	b := []byte(`{"kty":"`)
+	if c == x25519 {
+		b = append(b, `OKP","crv":"`...)
+		b = append(b, info.name...)
+		b = appendJWKMember(b, "x", pub)
+	} else {
+		// Skip the 0x04 uncompressed point prefix.
+		x, y := pub[1:1+info.scalarSize], pub[1+info.scalarSize:]
+		b = append(b, `EC","crv":"`...)
+		b = append(b, info.name...)
+		b = appendJWKMember(b, "x", x)
+		b = appendJWKMember(b, "y", y)
+	}
+	if priv != nil {
+		b = appendJWKMember(b, "d", priv)
+	}
+	return append(b, `"}`...), nil
+}
+
+func appendJWKMember(b []byte, name string, value []byte) []byte {
+	b = append(b, `","`...)
+	b = append(b, name...)
+	b = append(b, `":"`...)
+	n := len(b)
+	b = append(b, make([]byte, b64.EncodedLen(len(value)))...)
+	b64.Encode(b[n:], value)
+	return b
+}
+
+// ParseJWKPublicKey parses a JSON Web Key holding a public key on one of the
+// curves of this package. Members other than "kty", "crv", "x", and "y"
+// are ignored, so the public key of a private JWK can be parsed as well.
+func ParseJWKPublicKey(data []byte) (*PublicKey, error) {
+	info, members, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := jwkPublicKey(info, members)
+	if err != nil {
+		return nil, err
+	}
+	return info.curve.NewPublicKey(pub)
+}
+
+// ParseJWKPrivateKey parses a JSON Web Key holding a private key on one of
+// the curves of this package. It returns an error if the public parameters
+// in the JWK don't match the private key.
+func ParseJWKPrivateKey(data []byte) (*PrivateKey, error) {
+	info, members, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := jwkPublicKey(info, members)
+	if err != nil {
+		return nil, err
+	}
+	d, err := jwkParameter(members, "d", info.scalarSize)
+	if err != nil {
+		return nil, err
+	}
+	k, err := info.curve.NewPrivateKey(d)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(k.PublicKey().publicKey, pub) != 1 {
+		return nil, errors.New("crypto/ecdh: JWK public key does not match private key")
+	}
+	return k, nil
+}
+
+func parseJWK(data []byte) (curveInfo, map[string]string, error) {
+	members, err := parseJSONObject(data)
+	if err != nil {
+		return curveInfo{}, nil, err
+	}
+	kty, crv := members["kty"], members["crv"]
+	for _, info := range curves {
+		if crv != info.name {
+			continue
+		}
+		if (info.curve == x25519 && kty != "OKP") || (info.curve != x25519 && kty != "EC") {
+			return curveInfo{}, nil, errors.New("crypto/ecdh: JWK key type does not match curve")
+		}
+		return info, members, nil
+	}
+	return curveInfo{}, nil, errors.New("crypto/ecdh: unsupported JWK curve")
+}
+
+// jwkPublicKey returns the public key encoding accepted by NewPublicKey.
+func jwkPublicKey(info curveInfo, members map[string]string) ([]byte, error) {
+	x, err := jwkParameter(members, "x", info.scalarSize)
+	if err != nil {
+		return nil, err
+	}
+	if info.curve == x25519 {
+		return x, nil
+	}
+	y, err := jwkParameter(members, "y", info.scalarSize)
+	if err != nil {
+		return nil, err
+	}
+	pub := append([]byte{4}, x...)
+	return append(pub, y...), nil
+}
+
+// jwkParameter decodes a base64url member which RFC 7518 requires to be
+// exactly size bytes long.
+func jwkParameter(members map[string]string, name string, size int) ([]byte, error) {
+	s, ok := members[name]
+	if !ok {
+		return nil, errors.New("crypto/ecdh: JWK is missing parameter " + name)
+	}
+	v, err := b64.DecodeString(s)
+	if err != nil || len(v) != size {
+		return nil, errors.New("crypto/ecdh: invalid JWK parameter " + name)
+	}
+	return v, nil
+}
+
+var errInvalidJSON = errors.New("crypto/ecdh: invalid JWK JSON")
+
+// parseJSONObject parses a JSON object and returns its string members.
+// Members with other values are validated and skipped. Duplicate member
+// names are rejected, since implementations disagree on which one wins.
+func parseJSONObject(data []byte) (map[string]string, error) {
+	p := &jsonParser{data: data}
+	if !p.consume('{') {
+		return nil, errInvalidJSON
+	}
+	members := make(map[string]string)
+	seen := make(map[string]bool)
+	if !p.consume('}') {
+		for {
+			name, ok := p.string()
+			if !ok || seen[name] || !p.consume(':') {
+				return nil, errInvalidJSON
+			}
+			seen[name] = true
+			if p.peek() == '"' {
+				value, ok := p.string()
+				if !ok {
+					return nil, errInvalidJSON
+				}
+				members[name] = value
+			} else if !p.skipValue(0) {
+				return nil, errInvalidJSON
+			}
+			if p.consume('}') {
+				break
+			}
+			if !p.consume(',') {
+				return nil, errInvalidJSON
+			}
+		}
+	}
+	p.skipSpace()
+	if p.off != len(p.data) {
+		return nil, errInvalidJSON
+	}
+	return members, nil
+}
+
+type jsonParser struct {
+	data []byte
+	off  int
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.off < len(p.data) {
+		switch p.data[p.off] {
+		case ' ', '\t', '\n', '\r':
+			p.off++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end of the input.
+func (p *jsonParser) peek() byte {
+	p.skipSpace()
+	if p.off == len(p.data) {
+		return 0
+	}
+	return p.data[p.off]
+}
+
+func (p *jsonParser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.off++
+	return true
+}
+
+func (p *jsonParser) literal(s string) bool {
+	if len(p.data)-p.off < len(s) || string(p.data[p.off:p.off+len(s)]) != s {
+		return false
+	}
+	p.off += len(s)
+	return true
+}
+
+// maxJSONDepth bounds the nesting of skipped values.
+const maxJSONDepth = 16
+
+func (p *jsonParser) skipValue(depth int) bool {
+	if depth > maxJSONDepth {
+		return false
+	}
+	switch c := p.peek(); {
+	case c == '"':
+		_, ok := p.string()
+		return ok
+	case c == '{' || c == '[':
+		p.off++
+		end := byte('}')
+		if c == '[' {
+			end = ']'
+		}
+		if p.consume(end) {
+			return true
+		}
+		for {
+			if c == '{' {
+				if _, ok := p.string(); !ok || !p.consume(':') {
+					return false
+				}
+			}
+			if !p.skipValue(depth + 1) {
+				return false
+			}
+			if p.consume(end) {
+				return true
+			}
+			if !p.consume(',') {
+				return false
+			}
+		}
+	case c == 't':
+		return p.literal("true")
+	case c == 'f':
+		return p.literal("false")
+	case c == 'n':
+		return p.literal("null")
+	case c == '-' || '0' <= c && c <= '9':
+		return p.number()
+	}
+	return false
+}
+
+func (p *jsonParser) digits() bool {
+	start := p.off
+	for p.off < len(p.data) && '0' <= p.data[p.off] && p.data[p.off] <= '9' {
+		p.off++
+	}
+	return p.off > start
+}
+
+func (p *jsonParser) number() bool {
+	p.literal("-")
+	if p.literal("0") {
+		// No leading zeroes.
+	} else if !p.digits() {
+		return false
+	}
+	if p.literal(".") && !p.digits() {
+		return false
+	}
+	if p.literal("e") || p.literal("E") {
+		if !p.literal("+") {
+			p.literal("-")
+		}
+		if !p.digits() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *jsonParser) string() (string, bool) {
+	if !p.consume('"') {
+		return "", false
+	}
+	var b []byte
+	for p.off < len(p.data) {
+		c := p.data[p.off]
+		switch {
+		case c == '"':
+			p.off++
+			return string(b), true
+		case c < 0x20:
+			return "", false
+		case c == '\\':
+			r, ok := p.escape()
+			if !ok {
+				return "", false
+			}
+			b = utf8.AppendRune(b, r)
+		default:
+			r, size := utf8.DecodeRune(p.data[p.off:])
+			if r == utf8.RuneError && size == 1 {
+				return "", false
+			}
+			b = append(b, p.data[p.off:p.off+size]...)
+			p.off += size
+		}
+	}
+	return "", false
+}
+
+// escape decodes the escape sequence at p.off.
+func (p *jsonParser) escape() (rune, bool) {
+	if len(p.data)-p.off < 2 {
+		return 0, false
+	}
+	c := p.data[p.off+1]
+	p.off += 2
+	switch c {
+	case '"', '\\', '/':
+		return rune(c), true
+	case 'b':
+		return '\b', true
+	case 'f':
+		return '\f', true
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case 'u':
+		r, ok := p.hex4()
+		if !ok {
+			return 0, false
+		}
+		if utf16.IsSurrogate(r) {
+			if !p.literal(`\u`) {
+				return utf8.RuneError, true
+			}
+			r2, ok := p.hex4()
+			if !ok {
+				return 0, false
+			}
+			return utf16.DecodeRune(r, r2), true
+		}
+		return r, true
+	}
+	return 0, false
+}
+
+func (p *jsonParser) hex4() (rune, bool) {
+	if len(p.data)-p.off < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range p.data[p.off : p.off+4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	p.off += 4
+	return r, true
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJWK(t *testing.T) {
+	for _, curve := range encodingCurves {
+		k, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := k.MarshalJWK()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := k.PublicKey().MarshalJWK()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range [][]byte{priv, pub} {
+			var m map[string]string
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Fatalf("MarshalJWK = %s: %v", b, err)
+			}
+		}
+
+		k2, err := ecdh.ParseJWKPrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !k.Equal(k2) {
+			t.Errorf("%v: ParseJWKPrivateKey returned a different key", curve)
+		}
+		for _, b := range [][]byte{priv, pub} {
+			p, err := ecdh.ParseJWKPublicKey(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !k.PublicKey().Equal(p) {
+				t.Errorf("%v: ParseJWKPublicKey returned a different key", curve)
+			}
+		}
+		if _, err := ecdh.ParseJWKPrivateKey(pub); err == nil {
+			t.Errorf("%v: ParseJWKPrivateKey accepted a public key", curve)
+		}
+	}
+}
+
+func TestJWKVectors(t *testing.T) {
+	// RFC 8037, Appendix A.6, with the members a WebCrypto export adds.
+	alice := `{
+		"kty": "OKP", "crv": "X25519", "ext": true, "key_ops": ["deriveBits"],
+		"d": "dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo",
+		"x": "hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"
+	}`
+	bob := `{"kty":"OKP","crv":"X25519","kid":"Bob","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`
+	k, err := ecdh.ParseJWKPrivateKey([]byte(alice))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ecdh.ParseJWKPublicKey([]byte(bob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := k.ECDH(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := hex.DecodeString("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+	if !bytes.Equal(shared, want) {
+		t.Errorf("shared secret = %x, want %x", shared, want)
+	}
+}
+
+func TestParseJWKInvalid(t *testing.T) {
+	k, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, _ := k.MarshalJWK()
+	other, _ := ecdh.P256().GenerateKey(rand.Reader)
+	otherPub, _ := other.PublicKey().MarshalJWK()
+	s := string(valid)
+	for name, b := range map[string]string{
+		"empty":           "",
+		"not an object":   `["kty"]`,
+		"trailing data":   s + "x",
+		"trailing comma":  strings.TrimSuffix(s, "}") + ",}",
+		"duplicate":       strings.Replace(s, "{", `{"crv":"P-384",`, 1),
+		"wrong kty":       strings.Replace(s, `"EC"`, `"OKP"`, 1),
+		"unknown crv":     strings.Replace(s, `"P-256"`, `"P-255"`, 1),
+		"padded":          strings.Replace(s, `","y"`, `=","y"`, 1),
+		"mismatched d":    string(otherPub[:len(otherPub)-1]) + s[strings.Index(s, `,"d"`):],
+		"deep nesting":    `{"a":` + strings.Repeat("[", 100) + strings.Repeat("]", 100) + s[1:],
+		"bad escape":      strings.Replace(s, "kty", `k\qty`, 1),
+		"control in name": strings.Replace(s, "kty", "k\nty", 1),
+	} {
+		if _, err := ecdh.ParseJWKPrivateKey([]byte(b)); err == nil {
+			t.Errorf("%s: ParseJWKPrivateKey(%s) succeeded", name, b)
+		}
+	}
+
+	escaped := strings.Replace(s, `"kty"`, `"\u006bty"`, 1)
+	escaped = strings.ReplaceAll(escaped, `:"`, ` : "`)
+	if _, err := ecdh.ParseJWKPrivateKey([]byte(escaped)); err != nil {
+		t.Errorf("ParseJWKPrivateKey(%s): %v", escaped, err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"crypto/internal/randutil"
 	"encoding/binary"
 	"errors"
+	"internal/cryptometrics"
 	"io"
 	"math/bits"
 )
@@ -23,6 +24,7 @@ type nistCurve[Point nistPoint[Point]] struct {
 // nistPoint is a generic constraint for the nistec Point types.
 type nistPoint[T any] interface {
 	Bytes() []byte
+	BytesCompressed() []byte
 	BytesX() ([]byte, error)
 	SetBytes([]byte) (T, error)
 	ScalarMult(T, []byte) (T, error)
@@ -43,6 +45,9 @@ func (c *nistCurve[Point]) GenerateKey(rand io.Reader) (*PrivateKey, error) {
 		}
 		return newBoringPrivateKey(c, key, bytes)
 	}
+	if boring.Enabled {
+		boring.RecordFallback(cryptometrics.ECDH, "random source other than crypto/rand.Reader")
+	}
 
 	key := make([]byte, len(c.scalarOrder))
 	randutil.MaybeReadByte(rand)
@@ -188,6 +193,26 @@ func (c *nistCurve[Point]) NewPublicKey(key []byte) (*PublicKey, error) {
 	return k, nil
 }
 
+func (c *nistCurve[Point]) compressPublicKey(k *PublicKey) []byte {
+	// NewPublicKey validated the point, so SetBytes can't fail.
+	p, err := c.newPoint().SetBytes(k.publicKey)
+	if err != nil {
+		panic("crypto/ecdh: internal error: invalid public key")
+	}
+	return p.BytesCompressed()
+}
+
+func (c *nistCurve[Point]) decompressPublicKey(key []byte) (*PublicKey, error) {
+	if len(key) == 0 || (key[0] != 2 && key[0] != 3) {
+		return nil, errors.New("crypto/ecdh: invalid compressed public key")
+	}
+	p, err := c.newPoint().SetBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewPublicKey(p.Bytes())
+}
+
 func (c *nistCurve[Point]) ecdh(local *PrivateKey, remote *PublicKey) ([]byte, error) {
 	// Note that this function can't return an error, as NewPublicKey rejects
 	// invalid points and the point at infinity, and NewPrivateKey rejects
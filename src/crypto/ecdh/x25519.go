@@ -5,6 +5,7 @@
 package ecdh
 
 import (
+	"crypto/internal/boring"
 	"crypto/internal/edwards25519/field"
 	"crypto/internal/randutil"
 	"errors"
@@ -61,6 +62,7 @@ func (c *x25519Curve) privateKeyToPublicKey(key *PrivateKey) *PublicKey {
 	}
 	x25519Basepoint := [32]byte{9}
 	x25519ScalarMult(k.publicKey, key.privateKey, x25519Basepoint[:])
+	boring.SetNotApproved()
 	return k
 }
 
@@ -77,6 +79,8 @@ func (c *x25519Curve) NewPublicKey(key []byte) (*PublicKey, error) {
 func (c *x25519Curve) ecdh(local *PrivateKey, remote *PublicKey) ([]byte, error) {
 	out := make([]byte, x25519SharedSecretSize)
 	x25519ScalarMult(out, local.privateKey, remote.publicKey)
+	// X25519 is not approved, and never computed by the module.
+	boring.SetNotApproved()
 	if isZero(out) {
 		return nil, errors.New("crypto/ecdh: bad X25519 remote ECDH input: low order point")
 	}
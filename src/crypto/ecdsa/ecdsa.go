@@ -7,7 +7,9 @@
 //
 // Signatures generated by this package are not deterministic, but entropy is
 // mixed with the private key and the message, achieving the same level of
-// security in case of randomness source failure.
+// security in case of randomness source failure. Deterministic signatures, as
+// specified by RFC 6979, are produced by PrivateKey.Sign when its random
+// source is nil.
 package ecdsa
 
 // [FIPS 186-4] references ANSI X9.62-2005 for the bulk of the ECDSA algorithm.
@@ -26,6 +28,7 @@ import (
 	"crypto/cipher"
 	"crypto/ecdh"
 	"crypto/elliptic"
+	"crypto/hmac"
 	"crypto/internal/bigmod"
 	"crypto/internal/boring"
 	"crypto/internal/boring/bbig"
@@ -34,6 +37,7 @@ import (
 	"crypto/sha512"
 	"crypto/subtle"
 	"errors"
+	"internal/cryptometrics"
 	"io"
 	"math/big"
 	"sync"
@@ -65,6 +69,27 @@ func (k *PublicKey) ECDH() (*ecdh.PublicKey, error) {
 	return c.NewPublicKey(elliptic.Marshal(k.Curve, k.X, k.Y))
 }
 
+// BytesCompressed returns the SEC 1, Version 2.0, Section 2.3.3 compressed
+// encoding of the public key. It returns an error if the key is not a valid
+// point on its curve.
+func (k *PublicKey) BytesCompressed() ([]byte, error) {
+	if k.X == nil || k.Y == nil || !k.Curve.IsOnCurve(k.X, k.Y) {
+		return nil, errors.New("ecdsa: invalid public key")
+	}
+	return elliptic.MarshalCompressed(k.Curve, k.X, k.Y), nil
+}
+
+// DecompressPublicKey decodes a SEC 1, Version 2.0, Section 2.3.4 compressed
+// public key on curve c. It returns an error if key is not in compressed
+// form or is not a point on the curve.
+func DecompressPublicKey(c elliptic.Curve, key []byte) (*PublicKey, error) {
+	x, y := elliptic.UnmarshalCompressed(c, key)
+	if x == nil {
+		return nil, errors.New("ecdsa: invalid compressed public key")
+	}
+	return &PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
 // Equal reports whether pub and x have the same value.
 //
 // Two keys are only considered to have the same value if they have the same Curve value.
@@ -140,13 +165,26 @@ func bigIntEqual(a, b *big.Int) bool {
 }
 
 // Sign signs digest with priv, reading randomness from rand. The opts argument
-// is not currently used but, in keeping with the crypto.Signer interface,
 // should be the hash function used to digest the message.
 //
+// If rand is nil, Sign produces a deterministic signature, with the nonce
+// derived from priv and digest as specified by RFC 6979. The signature is
+// then the same every time the same digest is signed, which is useful for
+// reproducible signatures, and does not depend on a source of randomness.
+// opts.HashFunc() must return the hash function that produced digest, which
+// RFC 6979 also uses to derive the nonce. Deterministic signatures are only
+// supported for the P-224, P-256, P-384, and P-521 curves.
+//
 // This method implements crypto.Signer, which is an interface to support keys
 // where the private part is kept in, for example, a hardware module. Common
 // uses can use the SignASN1 function in this package directly.
 func (priv *PrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if rand == nil {
+		if opts == nil {
+			return nil, errors.New("ecdsa: deterministic signatures require a hash function")
+		}
+		return signDeterministic(priv, opts.HashFunc(), digest)
+	}
 	return SignASN1(rand, priv, digest)
 }
 
@@ -280,13 +318,60 @@ func SignASN1(rand io.Reader, priv *PrivateKey, hash []byte) ([]byte, error) {
 	}
 }
 
-func signNISTEC[Point nistPoint[Point]](c *nistCurve[Point], priv *PrivateKey, csprng io.Reader, hash []byte) (sig []byte, err error) {
-	// SEC 1, Version 2.0, Section 4.1.3
+// signDeterministic signs hash, the digest of a message with h, with the
+// nonce specified by RFC 6979.
+func signDeterministic(priv *PrivateKey, h crypto.Hash, hash []byte) ([]byte, error) {
+	if h == 0 || !h.Available() {
+		return nil, errors.New("ecdsa: hash function not available for deterministic signature")
+	}
+	if len(hash) != h.Size() {
+		return nil, errors.New("ecdsa: digest length does not match the hash function")
+	}
 
+	if boring.Enabled {
+		if boring.SupportsDeterministicECDSA() {
+			b, err := boringPrivateKey(priv)
+			if err != nil {
+				return nil, err
+			}
+			return boring.SignMarshalECDSADeterministic(b, h, hash)
+		}
+		boring.RecordFallback(cryptometrics.ECDSA, "deterministic signatures unsupported by the module")
+	}
+
+	switch priv.Curve.Params() {
+	case elliptic.P224().Params():
+		return signDeterministicNISTEC(p224(), priv, h, hash)
+	case elliptic.P256().Params():
+		return signDeterministicNISTEC(p256(), priv, h, hash)
+	case elliptic.P384().Params():
+		return signDeterministicNISTEC(p384(), priv, h, hash)
+	case elliptic.P521().Params():
+		return signDeterministicNISTEC(p521(), priv, h, hash)
+	default:
+		return nil, errors.New("ecdsa: deterministic signatures are not supported for this curve")
+	}
+}
+
+func signNISTEC[Point nistPoint[Point]](c *nistCurve[Point], priv *PrivateKey, csprng io.Reader, hash []byte) (sig []byte, err error) {
 	k, R, err := randomPoint(c, csprng)
 	if err != nil {
 		return nil, err
 	}
+	return signNISTECWithNonce(c, priv, k, R, hash)
+}
+
+func signDeterministicNISTEC[Point nistPoint[Point]](c *nistCurve[Point], priv *PrivateKey, h crypto.Hash, hash []byte) (sig []byte, err error) {
+	k, R, err := rfc6979Point(c, priv, h, hash)
+	if err != nil {
+		return nil, err
+	}
+	return signNISTECWithNonce(c, priv, k, R, hash)
+}
+
+// signNISTECWithNonce signs hash with the nonce k, where R = k × G.
+func signNISTECWithNonce[Point nistPoint[Point]](c *nistCurve[Point], priv *PrivateKey, k *bigmod.Nat, R Point, hash []byte) (sig []byte, err error) {
+	// SEC 1, Version 2.0, Section 4.1.3
 
 	// kInv = k⁻¹
 	kInv := bigmod.NewNat()
@@ -380,22 +465,76 @@ func hashToNat[Point nistPoint[Point]](c *nistCurve[Point], e *bigmod.Nat, hash
 	// an integer modulo N. This is the absolute worst of all worlds: we still
 	// have to reduce, because the result might still overflow N, but to take
 	// the left-most bits for P-521 we have to do a right shift.
-	if size := c.N.Size(); len(hash) > size {
-		hash = hash[:size]
-		if excess := len(hash)*8 - c.N.BitLen(); excess > 0 {
-			hash = bytes.Clone(hash)
-			for i := len(hash) - 1; i >= 0; i-- {
-				hash[i] >>= excess
+	_, err := e.SetOverflowingBytes(leftmostBits(c, hash), c.N)
+	if err != nil {
+		panic("ecdsa: internal error: truncated hash is too long")
+	}
+}
+
+// leftmostBits returns the left-most log2(N) bits of b, right-aligned in a
+// big-endian byte slice no longer than N. It is the bits2int function of
+// RFC 6979, Section 2.3.2, without the conversion to an integer.
+func leftmostBits[Point nistPoint[Point]](c *nistCurve[Point], b []byte) []byte {
+	if size := c.N.Size(); len(b) > size {
+		b = b[:size]
+		if excess := len(b)*8 - c.N.BitLen(); excess > 0 {
+			b = bytes.Clone(b)
+			for i := len(b) - 1; i >= 0; i-- {
+				b[i] >>= excess
 				if i > 0 {
-					hash[i] |= hash[i-1] << (8 - excess)
+					b[i] |= b[i-1] << (8 - excess)
 				}
 			}
 		}
 	}
-	_, err := e.SetOverflowingBytes(hash, c.N)
+	return b
+}
+
+// rfc6979Point returns the nonce for priv and hash, the digest of a message
+// with h, as specified by RFC 6979, Section 3.2, and the corresponding point.
+func rfc6979Point[Point nistPoint[Point]](c *nistCurve[Point], priv *PrivateKey, h crypto.Hash, hash []byte) (k *bigmod.Nat, p Point, err error) {
+	x, err := bigmod.NewNat().SetBytes(priv.D.Bytes(), c.N)
 	if err != nil {
-		panic("ecdsa: internal error: truncated hash is too long")
+		return
+	}
+	e := bigmod.NewNat()
+	hashToNat(c, e, hash)
+	// int2octets(x) || bits2octets(h1)
+	seed := append(x.Bytes(c.N), e.Bytes(c.N)...)
+
+	mac := func(key []byte, data ...[]byte) []byte {
+		m := hmac.New(h.New, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+
+	// Steps b. to g.
+	V := bytes.Repeat([]byte{0x01}, h.Size())
+	K := make([]byte, h.Size())
+	K = mac(K, V, []byte{0x00}, seed)
+	V = mac(K, V)
+	K = mac(K, V, []byte{0x01}, seed)
+	V = mac(K, V)
+
+	// Step h. The candidates are rejected unless 0 < k < N.
+	k = bigmod.NewNat()
+	for {
+		var T []byte
+		for len(T) < c.N.Size() {
+			V = mac(K, V)
+			T = append(T, V...)
+		}
+		if _, err = k.SetBytes(leftmostBits(c, T), c.N); err == nil && k.IsZero() == 0 {
+			break
+		}
+		K = mac(K, V, []byte{0x00})
+		V = mac(K, V)
 	}
+
+	p, err = c.newPoint().ScalarBaseMult(k.Bytes(c.N))
+	return
 }
 
 // mixedCSPRNG returns a CSPRNG that mixes entropy from rand with the message
@@ -466,7 +605,11 @@ func VerifyASN1(pub *PublicKey, hash, sig []byte) bool {
 		return boring.VerifyECDSA(key, hash, sig)
 	}
 	boring.UnreachableExceptTests()
+	return verifyGo(pub, hash, sig)
+}
 
+// verifyGo is the pure Go implementation of VerifyASN1.
+func verifyGo(pub *PublicKey, hash, sig []byte) bool {
 	if err := verifyAsm(pub, hash, sig); err != errNoAsm {
 		return err == nil
 	}
@@ -8,7 +8,10 @@ import (
 	"bufio"
 	"bytes"
 	"compress/bzip2"
+	"crypto"
 	"crypto/elliptic"
+	"crypto/internal/boring"
+	"crypto/internal/difftest"
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -294,6 +297,107 @@ func TestVectors(t *testing.T) {
 	}
 }
 
+func TestRFC6979(t *testing.T) {
+	// Test vectors from RFC 6979, Appendix A.2.5 and A.2.7.
+	p256 := fromHex("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	p521 := fromHex("0FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75C" +
+		"AA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538")
+	tests := []struct {
+		curve elliptic.Curve
+		d     *big.Int
+		hash  crypto.Hash
+		msg   string
+		r, s  string
+	}{
+		{elliptic.P256(), p256, crypto.SHA1, "sample",
+			"61340C88C3AAEBEB4F6D667F672CA9759A6CCAA9FA8811313039EE4A35471D32",
+			"6D7F147DAC089441BB2E2FE8F7A3FA264B9C475098FDCF6E00D7C996E1B8B7EB"},
+		{elliptic.P256(), p256, crypto.SHA256, "sample",
+			"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"},
+		{elliptic.P256(), p256, crypto.SHA256, "test",
+			"F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			"019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083"},
+		{elliptic.P256(), p256, crypto.SHA512, "test",
+			"461D93F31B6540894788FD206C07CFA0CC35F46FA3C91816FFF1040AD1581A04",
+			"39AF9F15DE0DB8D97E72719C74820D304CE5226E32DEDAE67519E840D1194E55"},
+		{elliptic.P521(), p521, crypto.SHA256, "sample",
+			"1511BB4D675114FE266FC4372B87682BAECC01D3CC62CF2303C92B3526012659D16876E25C7C1E57648F23B73564D67F61C6F14D527D54972810421E7D87589E1A7",
+			"04A171143A83163D6DF460AAF61522695F207A58B95C0644D87E52AA1A347916E4F7A72930B1BC06DBE22CE3F58264AFD23704CBB63B29B931F7DE6C9D949A7ECFC"},
+		{elliptic.P521(), p521, crypto.SHA512, "sample",
+			"0C328FAFCBD79DD77850370C46325D987CB525569FB63C5D3BC53950E6D4C5F174E25A1EE9017B5D450606ADD152B534931D7D4E8455CC91F9B15BF05EC36E377FA",
+			"0617CCE7CF5064806C467F678D3B4080D6F1CC50AF26CA209417308281B68AF282623EAA63E5B5C0723D8B8C37FF0777B1A20F8CCB1DCCC43997F1EE0E44DA4A67A"},
+		{elliptic.P521(), p521, crypto.SHA512, "test",
+			"13E99020ABF5CEE7525D16B69B229652AB6BDF2AFFCAEF38773B4B7D08725F10CDB93482FDCC54EDCEE91ECA4166B2A7C6265EF0CE2BD7051B7CEF945BABD47EE6D",
+			"1FBD0013C674AA79CB39849527916CE301C66EA7CE8B80682786AD60F98F7E78A19CA69EFF5C57400E3B3A0AD66CE0978214D13BAF4E9AC60752F7B155E2DE4DCE3"},
+	}
+	for _, tt := range tests {
+		priv := &PrivateKey{D: tt.d}
+		priv.Curve = tt.curve
+		priv.X, priv.Y = tt.curve.ScalarBaseMult(tt.d.Bytes())
+
+		h := tt.hash.New()
+		h.Write([]byte(tt.msg))
+		digest := h.Sum(nil)
+
+		sig, err := priv.Sign(nil, digest, tt.hash)
+		if err != nil {
+			t.Errorf("%s/%v/%s: %v", tt.curve.Params().Name, tt.hash, tt.msg, err)
+			continue
+		}
+		r, s, err := parseSignature(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := new(big.Int).SetBytes(r), fromHex(tt.r); got.Cmp(want) != 0 {
+			t.Errorf("%s/%v/%s: r = %X, want %X", tt.curve.Params().Name, tt.hash, tt.msg, got, want)
+		}
+		if got, want := new(big.Int).SetBytes(s), fromHex(tt.s); got.Cmp(want) != 0 {
+			t.Errorf("%s/%v/%s: s = %X, want %X", tt.curve.Params().Name, tt.hash, tt.msg, got, want)
+		}
+		if !VerifyASN1(&priv.PublicKey, digest, sig) {
+			t.Errorf("%s/%v/%s: VerifyASN1 failed", tt.curve.Params().Name, tt.hash, tt.msg)
+		}
+	}
+}
+
+func TestSignDeterministic(t *testing.T) {
+	testAllCurves(t, testSignDeterministic)
+}
+
+func testSignDeterministic(t *testing.T, c elliptic.Curve) {
+	priv, _ := GenerateKey(c, rand.Reader)
+	digest := sha256.Sum256([]byte("testing"))
+
+	sig, err := priv.Sign(nil, digest[:], crypto.SHA256)
+	if _, generic := c.(*elliptic.CurveParams); generic {
+		if err == nil {
+			t.Error("deterministic signature with a generic curve succeeded")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := priv.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, sig2) {
+		t.Error("deterministic signatures of the same digest differ")
+	}
+	if !VerifyASN1(&priv.PublicKey, digest[:], sig) {
+		t.Error("VerifyASN1 failed")
+	}
+
+	if _, err := priv.Sign(nil, digest[:], nil); err == nil {
+		t.Error("deterministic signature without a hash function succeeded")
+	}
+	if _, err := priv.Sign(nil, digest[:20], crypto.SHA256); err == nil {
+		t.Error("deterministic signature of a short digest succeeded")
+	}
+}
+
 func TestNegativeInputs(t *testing.T) {
 	testAllCurves(t, testNegativeInputs)
 }
@@ -572,3 +676,75 @@ func BenchmarkGenerateKey(b *testing.B) {
 		}
 	})
 }
+
+func FuzzBackend(f *testing.F) {
+	if !boring.Enabled {
+		f.Skip("no cryptographic backend in use")
+	}
+	defer boring.AllowGoForTesting()()
+	var keys []*PrivateKey
+	for _, c := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		priv, err := GenerateKey(c, rand.Reader)
+		if err != nil {
+			f.Fatal(err)
+		}
+		keys = append(keys, priv)
+	}
+	f.Add([]byte("abc"))
+	f.Add(make([]byte, 64))
+	f.Fuzz(func(t *testing.T, digest []byte) {
+		for _, priv := range keys {
+			priv := priv
+			goSigner := difftest.Signer{
+				// A random source other than crypto/rand.Reader selects
+				// the pure Go implementation.
+				Sign:   func(d []byte) ([]byte, error) { return SignASN1(zeroReader, priv, d) },
+				Verify: func(d, sig []byte) bool { return verifyGo(&priv.PublicKey, d, sig) },
+			}
+			backendSigner := difftest.Signer{
+				Sign:   func(d []byte) ([]byte, error) { return SignASN1(rand.Reader, priv, d) },
+				Verify: func(d, sig []byte) bool { return VerifyASN1(&priv.PublicKey, d, sig) },
+			}
+			if err := difftest.Signatures(goSigner, backendSigner, digest); err != nil {
+				t.Fatalf("%s: %v", priv.Curve.Params().Name, err)
+			}
+		}
+	})
+}
+
+func TestCompressedPublicKey(t *testing.T) {
+	testAllCurves(t, testCompressedPublicKey)
+}
+
+func testCompressedPublicKey(t *testing.T, c elliptic.Curve) {
+	priv, err := GenerateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := priv.PublicKey.BytesCompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 1+(c.Params().BitSize+7)/8 || (b[0] != 2 && b[0] != 3) {
+		t.Fatalf("BytesCompressed = %x", b)
+	}
+	pub, err := DecompressPublicKey(c, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(&priv.PublicKey) {
+		t.Error("DecompressPublicKey returned a different key")
+	}
+
+	b[len(b)-1] ^= 1
+	if pub, err := DecompressPublicKey(c, b); err == nil && pub.Equal(&priv.PublicKey) {
+		t.Error("DecompressPublicKey ignored a modified encoding")
+	}
+	if _, err := DecompressPublicKey(c, elliptic.Marshal(c, priv.X, priv.Y)); err == nil {
+		t.Error("DecompressPublicKey accepted an uncompressed point")
+	}
+	bad := &PublicKey{Curve: c, X: priv.X, Y: new(big.Int).Add(priv.Y, big.NewInt(1))}
+	if _, err := bad.BytesCompressed(); err == nil {
+		t.Error("BytesCompressed accepted a point not on the curve")
+	}
+}
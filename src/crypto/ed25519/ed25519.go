@@ -15,6 +15,7 @@ package ed25519
 import (
 	"bytes"
 	"crypto"
+	"crypto/internal/boring"
 	"crypto/internal/edwards25519"
 	cryptorand "crypto/rand"
 	"crypto/sha512"
@@ -175,6 +176,9 @@ func newKeyFromSeed(privateKey, seed []byte) {
 
 	copy(privateKey, seed)
 	copy(privateKey[32:], publicKey)
+
+	// Ed25519 is computed in Go, even though SHA-512 may not be.
+	boring.SetNotApproved()
 }
 
 // Sign signs the message with privateKey and returns a signature. It will
@@ -201,6 +205,7 @@ const (
 )
 
 func sign(signature, privateKey, message []byte, domPrefix, context string) {
+	defer boring.SetNotApproved()
 	if l := len(privateKey); l != PrivateKeySize {
 		panic("ed25519: bad private key length: " + strconv.Itoa(l))
 	}
@@ -298,6 +303,7 @@ func VerifyWithOptions(publicKey PublicKey, message, sig []byte, opts *Options)
 }
 
 func verify(publicKey PublicKey, message, sig []byte, domPrefix, context string) bool {
+	defer boring.SetNotApproved()
 	if l := len(publicKey); l != PublicKeySize {
 		panic("ed25519: bad public key length: " + strconv.Itoa(l))
 	}
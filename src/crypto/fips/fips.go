@@ -0,0 +1,164 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fips reports which cryptographic module implements the algorithms
+// in the crypto packages.
+//
+// Binaries built with GOEXPERIMENT=boringcrypto route supported operations
+// to the BoringCrypto module. Use CurrentStatus to find out whether the
+// module is in use and which operations it handles. Unlike
+// crypto/boring.Enabled, this package is available in every build.
+//
+// RunKATs runs known-answer tests against the implementations in use, so
+// that programs can check for correct operation at startup, and
+// SelfTestReport reports the self-tests that the module ran by itself.
+//
+// Approved reports whether the last cryptographic operation of the calling
+// goroutine was an approved service of the module, as the service indicator
+// required by FIPS 140-3.
+//
+// Operations whose parameters the module does not support are performed by
+// the pure Go implementation, and counted in AlgorithmUsage.GoFallbacks.
+// Setting GODEBUG=boringfallback=log also reports the first fallback from
+// each call site on standard error, as well as binaries built with
+// GOEXPERIMENT=boringcrypto in which the module is not available.
+//
+// Small writes to the hashes computed by BoringCrypto are buffered, so that
+// they are passed to the module in fewer calls. Setting
+// GODEBUG=boringbuffer=0 passes every write to the module as it happens.
+//
+// Setting GODEBUG=cryptokeyguard=1 at startup enables a debugging mode, meant
+// for development only, in which the short-lived buffers that the crypto
+// packages use for key material are placed between inaccessible guard pages on
+// Linux and macOS, and are made inaccessible once freed, so that overflows and
+// uses after free fault immediately. The mode is slow and deliberately leaks
+// address space.
+package fips
+
+import (
+	"crypto/internal/boring"
+	"internal/itoa"
+)
+
+// Failure classes of the errors returned, and of the values of the panics
+// raised, by the crypto packages when the module fails. Use errors.Is to
+// check whether an error belongs to a class.
+var (
+	// ErrBackendFailure is the class of unexpected failures of the module.
+	ErrBackendFailure = boring.ErrBackendFailure
+
+	// ErrNotApproved is the class of operations that are rejected
+	// because they are not approved by the security policy of the module.
+	// When the module operates in FIPS mode and GODEBUG=fips140=only is
+	// set, the crypto packages reject ChaCha20-Poly1305 with such an error.
+	ErrNotApproved = boring.ErrNotApproved
+
+	// ErrUnsupportedParameter is the class of operations that the module
+	// rejects because it does not support their parameters, such as a hash
+	// function or an elliptic curve.
+	ErrUnsupportedParameter = boring.ErrUnsupportedParameter
+)
+
+// Routing describes which implementation handles an algorithm.
+type Routing int
+
+const (
+	// RoutingGo means the pure Go implementation handles every operation.
+	RoutingGo Routing = boring.RouteGo
+
+	// RoutingModule means the module handles every operation.
+	RoutingModule Routing = boring.RouteModule
+
+	// RoutingPartial means the module handles some operations, as
+	// described by Algorithm.Condition, and the pure Go implementation
+	// handles the rest.
+	RoutingPartial Routing = boring.RoutePartial
+)
+
+func (r Routing) String() string {
+	switch r {
+	case RoutingGo:
+		return "go"
+	case RoutingModule:
+		return "module"
+	case RoutingPartial:
+		return "partial"
+	}
+	return "Routing(" + itoa.Itoa(int(r)) + ")"
+}
+
+// An Algorithm reports how an algorithm is routed.
+type Algorithm struct {
+	// Name is the algorithm name, such as "SHA-256" or "AES-GCM".
+	Name string
+
+	// Routing is the implementation that handles the algorithm.
+	Routing Routing
+
+	// Condition describes the operations handled by the module
+	// when Routing is RoutingPartial. It is empty otherwise.
+	Condition string
+}
+
+// Status describes the cryptographic module in use.
+type Status struct {
+	// Enabled reports whether a cryptographic module handles
+	// supported operations. If Enabled is false, the other fields
+	// are zero except for Algorithms, which are all routed to Go.
+	Enabled bool
+
+	// Module and Version identify the module, such as
+	// "BoringCrypto" and "fips-20210429", "OpenSSL" and the
+	// version string of the system libcrypto, "CNG" and the version
+	// of Windows, or "CommonCrypto" and the version of macOS.
+	Module  string
+	Version string
+
+	// SelfTestPassed reports whether the module's power-on
+	// self-test, and the conditional algorithm self-tests described by
+	// SelfTestReport, completed successfully.
+	SelfTestPassed bool
+
+	// FIPSMode reports whether the module operates in FIPS mode.
+	FIPSMode bool
+
+	// Algorithms lists the algorithms implemented by the standard
+	// library, sorted by name, and how each is routed.
+	Algorithms []Algorithm
+}
+
+// CurrentStatus returns the status of the cryptographic module
+// linked into the program.
+func CurrentStatus() Status {
+	s := Status{
+		Enabled:    boring.Enabled,
+		Algorithms: make([]Algorithm, len(boring.Routes)),
+	}
+	for i, r := range boring.Routes {
+		a := Algorithm{Name: r.Name}
+		if boring.Enabled {
+			a.Routing = Routing(r.Routing)
+			a.Condition = r.Condition
+		}
+		s.Algorithms[i] = a
+	}
+	if boring.Enabled {
+		s.Module = boring.ModuleName
+		s.Version = boring.ModuleVersion
+		s.SelfTestPassed = boring.SelfTestPassed && boring.CASTPassed()
+		s.FIPSMode = boring.FIPSMode()
+	}
+	return s
+}
+
+// Algorithm returns the routing of the named algorithm, and whether
+// the name is known.
+func (s Status) Algorithm(name string) (Algorithm, bool) {
+	for _, a := range s.Algorithms {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Algorithm{}, false
+}
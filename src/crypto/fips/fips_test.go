@@ -0,0 +1,264 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips_test
+
+import (
+	"crypto"
+	"crypto/des"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/fips"
+	"crypto/hmac"
+	"crypto/internal/boring"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestCurrentStatus(t *testing.T) {
+	s := fips.CurrentStatus()
+	if s.Enabled != boring.Enabled {
+		t.Fatalf("Enabled = %v, want %v", s.Enabled, boring.Enabled)
+	}
+	if len(s.Algorithms) == 0 {
+		t.Fatal("no algorithms reported")
+	}
+	if !sort.SliceIsSorted(s.Algorithms, func(i, j int) bool {
+		return s.Algorithms[i].Name < s.Algorithms[j].Name
+	}) {
+		t.Error("Algorithms are not sorted by name")
+	}
+	for _, a := range s.Algorithms {
+		if (a.Routing == fips.RoutingPartial) != (a.Condition != "") {
+			t.Errorf("%s: Routing %v with Condition %q", a.Name, a.Routing, a.Condition)
+		}
+		if !s.Enabled && a.Routing != fips.RoutingGo {
+			t.Errorf("%s: Routing = %v without a module", a.Name, a.Routing)
+		}
+	}
+
+	if !s.Enabled {
+		if s.Module != "" || s.Version != "" || s.SelfTestPassed || s.FIPSMode {
+			t.Errorf("disabled status has module details: %+v", s)
+		}
+		return
+	}
+	if s.Module != boring.ModuleName || s.Version == "" {
+		t.Errorf("Module, Version = %q, %q; want module %q", s.Module, s.Version, boring.ModuleName)
+	}
+	if !s.SelfTestPassed {
+		t.Error("SelfTestPassed = false, want true")
+	}
+	// The system modules only operate in FIPS mode when configured to.
+	if !s.FIPSMode && s.Module == "BoringCrypto" {
+		t.Error("FIPSMode = false, want true")
+	}
+	for name, want := range map[string]fips.Routing{
+		"SHA-256": fips.RoutingModule,
+		"SHA-384": fips.RoutingModule,
+		"Ed25519": fips.RoutingGo,
+		"AES-GCM": fips.RoutingPartial,
+	} {
+		if a, ok := s.Algorithm(name); !ok || a.Routing != want {
+			t.Errorf("Algorithm(%q) = %+v, %v; want Routing %v", name, a, ok, want)
+		}
+	}
+}
+
+func TestAlgorithmUnknown(t *testing.T) {
+	if a, ok := fips.CurrentStatus().Algorithm("ROT13"); ok {
+		t.Errorf("Algorithm(ROT13) = %+v, true", a)
+	}
+}
+
+func TestRoutingString(t *testing.T) {
+	for r, want := range map[fips.Routing]string{
+		fips.RoutingGo:      "go",
+		fips.RoutingModule:  "module",
+		fips.RoutingPartial: "partial",
+		7:                   "Routing(7)",
+	} {
+		if got := r.String(); got != want {
+			t.Errorf("Routing(%d).String() = %q, want %q", int(r), got, want)
+		}
+	}
+}
+
+func TestRunKATs(t *testing.T) {
+	r := fips.RunKATs()
+	if len(r.Results) == 0 {
+		t.Fatal("no known-answer tests run")
+	}
+	for _, res := range r.Results {
+		if res.Err != nil {
+			t.Errorf("%s, %s: %v", res.Algorithm, res.Vector, res.Err)
+		}
+		if _, ok := fips.CurrentStatus().Algorithm(res.Algorithm); !ok {
+			t.Errorf("%s, %s: unknown algorithm", res.Algorithm, res.Vector)
+		}
+	}
+	if !r.Passed() {
+		t.Error("Passed = false")
+	}
+
+	r.Results = append(r.Results, fips.KATResult{Err: errors.New("failed")})
+	if r.Passed() {
+		t.Error("Passed = true with a failed result")
+	}
+}
+
+func TestSelfTestReport(t *testing.T) {
+	r := fips.SelfTestReport()
+	if !boring.Enabled {
+		if len(r.Results) != 0 {
+			t.Errorf("SelfTestReport without a module = %+v", r)
+		}
+		return
+	}
+	algs := make(map[string]bool)
+	for _, res := range r.Results {
+		if res.Err != nil {
+			t.Errorf("%s, %s: %v", res.Algorithm, res.Vector, res.Err)
+		}
+		if _, ok := fips.CurrentStatus().Algorithm(res.Algorithm); !ok {
+			t.Errorf("%s, %s: unknown algorithm", res.Algorithm, res.Vector)
+		}
+		algs[res.Algorithm] = true
+	}
+	for _, alg := range []string{"SHA-256", "HMAC", "AES-GCM", "RSA", "ECDSA", "Random"} {
+		if !algs[alg] {
+			t.Errorf("no self-test of %s", alg)
+		}
+	}
+	if !r.Passed() {
+		t.Error("Passed = false")
+	}
+}
+
+func TestApproved(t *testing.T) {
+	fips.ResetApproved()
+	if fips.Approved() {
+		t.Error("Approved = true after ResetApproved")
+	}
+	sha256.Sum256([]byte("approved"))
+	if got := fips.Approved(); got != boring.Enabled {
+		t.Errorf("Approved after SHA-256 = %v, want %v", got, boring.Enabled)
+	}
+
+	c := make(chan bool)
+	go func() { c <- fips.Approved() }()
+	if <-c {
+		t.Error("Approved = true in a new goroutine")
+	}
+
+	hmac.New(md5.New, []byte("key")).Sum(nil)
+	if fips.Approved() {
+		t.Error("Approved = true after HMAC-MD5")
+	}
+}
+
+func TestApprovedGo(t *testing.T) {
+	// The algorithms that are always computed in Go are not approved, even
+	// right after an approved operation.
+	for name, op := range map[string]func(){
+		"MD5": func() { md5.Sum(nil) },
+		"DES": func() {
+			b, _ := des.NewCipher(make([]byte, 8))
+			b.Encrypt(make([]byte, 8), make([]byte, 8))
+		},
+		"RC4": func() {
+			c, _ := rc4.NewCipher(make([]byte, 16))
+			c.XORKeyStream(make([]byte, 1), make([]byte, 1))
+		},
+		"Ed25519": func() {
+			ed25519.Sign(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), nil)
+		},
+		"X25519": func() {
+			k, err := ecdh.X25519().GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := k.ECDH(k.PublicKey()); err != nil {
+				t.Fatal(err)
+			}
+		},
+	} {
+		sha256.Sum256(nil)
+		op()
+		if fips.Approved() {
+			t.Errorf("Approved = true after %s", name)
+		}
+	}
+}
+
+func TestApprovedRSA(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping RSA key generation in short mode")
+	}
+	hashed := sha256.Sum256([]byte("approved"))
+	for _, tt := range []struct {
+		bits     int
+		hash     crypto.Hash
+		approved bool
+	}{
+		{2048, crypto.SHA256, true},
+		{2048, crypto.SHA1, false},
+		{1024, crypto.SHA256, false},
+	} {
+		k, err := rsa.GenerateKey(rand.Reader, tt.bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := hashed[:tt.hash.Size()]
+		fips.ResetApproved()
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, tt.hash, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fips.Approved(), tt.approved && boring.Enabled; got != want {
+			t.Errorf("Approved after signing with a %d-bit key and %v = %v, want %v", tt.bits, tt.hash, got, want)
+		}
+		// SP 800-131A still approves verifying legacy signatures.
+		fips.ResetApproved()
+		if err := rsa.VerifyPKCS1v15(&k.PublicKey, tt.hash, h, sig); err != nil {
+			t.Fatal(err)
+		}
+		if got := fips.Approved(); got != boring.Enabled {
+			t.Errorf("Approved after verifying with a %d-bit key and %v = %v, want %v", tt.bits, tt.hash, got, boring.Enabled)
+		}
+		fips.ResetApproved()
+		if _, err := rsa.EncryptPKCS1v15(rand.Reader, &k.PublicKey, []byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+		if fips.Approved() {
+			t.Errorf("Approved = true after PKCS #1 v1.5 encryption")
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	u := fips.Usage()
+	if !boring.Enabled {
+		if u != nil {
+			t.Errorf("Usage = %v without a module, want nil", u)
+		}
+		return
+	}
+	sha256.Sum256([]byte("usage"))
+	var found bool
+	for _, au := range fips.Usage() {
+		if au.Name == "SHA-256" {
+			found = au.ModuleOperations > 0
+		}
+	}
+	if !found {
+		t.Error("Usage does not report SHA-256 operations")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips
+
+import "crypto/internal/boring"
+
+// Approved is the service indicator required by FIPS 140-3. It reports
+// whether the last cryptographic operation performed by the calling
+// goroutine was an approved service of the module: an operation routed to
+// the module, with parameters that the module supports, that succeeded.
+//
+// The indicator is kept separately for each goroutine, and is false in a
+// new goroutine and after ResetApproved until its next operation. It is
+// always false if Status.Enabled is false. Operations that are handled by
+// the pure Go implementation make it false, both those of algorithms with
+// RoutingGo, such as Ed25519, X25519, MD5 or SHA-3, and those whose
+// parameters the module does not support, such as AES-GCM with a
+// non-standard tag size. So do RSA operations that the module performs
+// with parameters that SP 800-131A does not approve: signing with keys
+// shorter than 2048 bits or with SHA-1, verifying with keys shorter than
+// 1024 bits, OAEP with keys shorter than 2048 bits, and PKCS #1 v1.5
+// encryption. The hash of an ECDSA signature is not known to
+// crypto/ecdsa, so ECDSA signing with SHA-1 is reported as approved.
+//
+// The indicator is set by the methods that produce a result, such as Sum,
+// Seal, or Sign, rather than by methods like Write of a hash, and is
+// cleared as soon as a function, including a constructor, selects the pure
+// Go implementation.
+func Approved() bool {
+	return boring.ServiceIndicator()
+}
+
+// ResetApproved makes Approved report false for the calling goroutine until
+// its next cryptographic operation.
+func ResetApproved() {
+	boring.ResetServiceIndicator()
+}
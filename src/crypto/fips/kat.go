@@ -0,0 +1,217 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/internal/boring"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+)
+
+// A KATResult is the outcome of a single known-answer test.
+type KATResult struct {
+	// Algorithm is the name of the algorithm under test, as in
+	// Algorithm.Name.
+	Algorithm string
+
+	// Vector identifies the test vector and its source.
+	Vector string
+
+	// Err is nil if the test passed, and describes the failure otherwise.
+	Err error
+}
+
+// A KATReport is the outcome of RunKATs.
+type KATReport struct {
+	Results []KATResult
+}
+
+// Passed reports whether all known-answer tests passed.
+func (r KATReport) Passed() bool {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// errMismatch is reported by known-answer tests that produced an
+// unexpected output.
+var errMismatch = errors.New("crypto/fips: known-answer test output mismatch")
+
+// RunKATs runs known-answer tests for the hash functions, HMAC, and AES,
+// using the same implementations as the crypto packages, and reports their
+// outcome. The test vectors are embedded in the program, so RunKATs can
+// run at startup without network or file system access.
+func RunKATs() KATReport {
+	r := KATReport{Results: make([]KATResult, 0, len(kats))}
+	for _, k := range kats {
+		res := KATResult{Algorithm: k.alg, Vector: k.vector}
+		got, err := k.run()
+		if err != nil {
+			res.Err = err
+		} else if !bytes.Equal(got, decodeHex(k.want)) {
+			res.Err = errMismatch
+		}
+		r.Results = append(r.Results, res)
+	}
+	return r
+}
+
+// SelfTestReport returns the outcome of the conditional algorithm
+// self-tests (CASTs) that the module ran, with its own entry points, when
+// the program started. They cover SHA, HMAC, AES-GCM, RSA, ECDSA, and the
+// random number generator. The report is empty if Status.Enabled is false.
+//
+// A failed self-test is only reported by SelfTestReport and
+// Status.SelfTestPassed, unless GODEBUG=boringcast=panic is set at
+// startup, in which case the program panics during initialization instead,
+// as FIPS 140-3 requires of a module that fails a self-test.
+func SelfTestReport() KATReport {
+	var r KATReport
+	for _, c := range boring.CASTResults {
+		r.Results = append(r.Results, KATResult{Algorithm: c.Algorithm, Vector: c.Vector, Err: c.Err})
+	}
+	return r
+}
+
+type kat struct {
+	alg, vector string
+	run         func() ([]byte, error)
+	want        string // hex
+}
+
+const (
+	abc         = "abc"
+	twoBlock    = "abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq"
+	fips197Key  = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	fips197Text = "00112233445566778899aabbccddeeff"
+)
+
+var kats = []kat{
+	{"SHA-1", "FIPS 180-4 example \"abc\"", digest(sha1.New, abc),
+		"a9993e364706816aba3e25717850c26c9cd0d89d"},
+	{"SHA-224", "FIPS 180-4 example \"abc\"", digest(sha256.New224, abc),
+		"23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7"},
+	{"SHA-256", "FIPS 180-4 example \"abc\"", digest(sha256.New, abc),
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	{"SHA-256", "FIPS 180-4 example, two blocks", digest(sha256.New, twoBlock),
+		"248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1"},
+	{"SHA-384", "FIPS 180-4 example \"abc\"", digest(sha512.New384, abc),
+		"cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed" +
+			"8086072ba1e7cc2358baeca134c825a7"},
+	{"SHA-512", "FIPS 180-4 example \"abc\"", digest(sha512.New, abc),
+		"ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a" +
+			"2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+	{"SHA-512/224", "FIPS 180-4 example \"abc\"", digest(sha512.New512_224, abc),
+		"4634270f707b6a54daae7530460842e20e37ed265ceee9a43e8924aa"},
+	{"SHA-512/256", "FIPS 180-4 example \"abc\"", digest(sha512.New512_256, abc),
+		"53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+
+	{"HMAC", "RFC 4231 test case 1, SHA-224", mac(sha256.New224),
+		"896fb1128abbdf196832107cd49df33f47b4b1169912ba4f53684b22"},
+	{"HMAC", "RFC 4231 test case 1, SHA-256", mac(sha256.New),
+		"b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7"},
+	{"HMAC", "RFC 4231 test case 1, SHA-384", mac(sha512.New384),
+		"afd03944d84895626b0825f4ab46907f15f9dadbe4101ec682aa034c7cebc59c" +
+			"faea9ea9076ede7f4af152e8b2fa9cb6"},
+	{"HMAC", "RFC 4231 test case 1, SHA-512", mac(sha512.New),
+		"87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cde" +
+			"daa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854"},
+
+	{"AES", "FIPS 197 appendix C.1, AES-128 encryption", encrypt(16),
+		"69c4e0d86a7b0430d8cdb78070b4c55a"},
+	{"AES", "FIPS 197 appendix C.1, AES-128 decryption", decrypt(16, "69c4e0d86a7b0430d8cdb78070b4c55a"),
+		fips197Text},
+	{"AES", "FIPS 197 appendix C.2, AES-192 encryption", encrypt(24),
+		"dda97ca4864cdfe06eaf70a0ec0d7191"},
+	{"AES", "FIPS 197 appendix C.2, AES-192 decryption", decrypt(24, "dda97ca4864cdfe06eaf70a0ec0d7191"),
+		fips197Text},
+	{"AES", "FIPS 197 appendix C.3, AES-256 encryption", encrypt(32),
+		"8ea2b7ca516745bfeafc49904b496089"},
+	{"AES", "FIPS 197 appendix C.3, AES-256 decryption", decrypt(32, "8ea2b7ca516745bfeafc49904b496089"),
+		fips197Text},
+	{"AES-GCM", "GCM specification test case 2", gcmSeal,
+		"0388dace60b6a392f328c2b971b2fe78ab6e47d42cec13bdf53a67b21257bddf"},
+}
+
+func digest(h func() hash.Hash, msg string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		d := h()
+		d.Write([]byte(msg))
+		return d.Sum(nil), nil
+	}
+}
+
+func mac(h func() hash.Hash) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		m := hmac.New(h, bytes.Repeat([]byte{0x0b}, 20))
+		m.Write([]byte("Hi There"))
+		return m.Sum(nil), nil
+	}
+}
+
+func encrypt(keySize int) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		b, err := aes.NewCipher(decodeHex(fips197Key)[:keySize])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, aes.BlockSize)
+		b.Encrypt(out, decodeHex(fips197Text))
+		return out, nil
+	}
+}
+
+func decrypt(keySize int, ciphertext string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		b, err := aes.NewCipher(decodeHex(fips197Key)[:keySize])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, aes.BlockSize)
+		b.Decrypt(out, decodeHex(ciphertext))
+		return out, nil
+	}
+}
+
+func gcmSeal() ([]byte, error) {
+	b, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		return nil, err
+	}
+	g, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, err
+	}
+	return g.Seal(nil, make([]byte, g.NonceSize()), make([]byte, 16), nil), nil
+}
+
+// decodeHex decodes a well-formed hex string from the test vectors.
+func decodeHex(s string) []byte {
+	b := make([]byte, len(s)/2)
+	for i := range b {
+		b[i] = fromHexChar(s[2*i])<<4 | fromHexChar(s[2*i+1])
+	}
+	return b
+}
+
+func fromHexChar(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	panic("crypto/fips: invalid test vector")
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips
+
+import (
+	"crypto/internal/boring"
+	"internal/cryptometrics"
+	"sort"
+)
+
+// An AlgorithmUsage reports how an algorithm was used since the program
+// started.
+type AlgorithmUsage struct {
+	// Name is the algorithm name, as in Algorithm.Name.
+	Name string
+
+	// ModuleOperations is the number of operations performed by
+	// the module.
+	ModuleOperations uint64
+
+	// GoFallbacks is the number of operations performed by the pure Go
+	// implementation because the module does not support their parameters.
+	GoFallbacks uint64
+
+	// Failures is the number of operations that the module failed or
+	// rejected, such as invalid signatures.
+	Failures uint64
+}
+
+// Usage reports the algorithms that were routed to the module or fell back
+// to the pure Go implementation since the program started, sorted by name.
+// It returns nil if Status.Enabled is false, because operations performed
+// by pure Go without a module are not counted.
+//
+// Setting GODEBUG=cryptosummary=1 at startup prints the same information
+// to standard error when the program exits.
+func Usage() []AlgorithmUsage {
+	if !boring.Enabled {
+		return nil
+	}
+	var u []AlgorithmUsage
+	for alg, a := range cryptometrics.Algorithms {
+		au := AlgorithmUsage{
+			Name:             a.Display,
+			ModuleOperations: boring.Counter(alg, cryptometrics.Operations),
+			GoFallbacks:      boring.Counter(alg, cryptometrics.Fallbacks),
+			Failures:         boring.Counter(alg, cryptometrics.Failures),
+		}
+		if au.ModuleOperations == 0 && au.GoFallbacks == 0 {
+			continue
+		}
+		u = append(u, au)
+	}
+	sort.Slice(u, func(i, j int) bool { return u[i].Name < u[j].Name })
+	return u
+}
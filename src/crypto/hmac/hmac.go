@@ -8,15 +8,12 @@ defined in U.S. Federal Information Processing Standards Publication 198.
 An HMAC is a cryptographic hash that uses a key to sign a message.
 The receiver verifies the hash by recomputing it using the same key.
 
-Receivers should be careful to use Equal to compare MACs in order to avoid
-timing side-channels:
+Receivers should be careful to use Verify, a Verifier, or Equal to compare
+MACs in order to avoid timing side-channels:
 
 	// ValidMAC reports whether messageMAC is a valid HMAC tag for message.
 	func ValidMAC(message, messageMAC, key []byte) bool {
-		mac := hmac.New(sha256.New, key)
-		mac.Write(message)
-		expectedMAC := mac.Sum(nil)
-		return hmac.Equal(messageMAC, expectedMAC)
+		return hmac.Verify(sha256.New, key, message, messageMAC)
 	}
 */
 package hmac
@@ -24,7 +21,9 @@ package hmac
 import (
 	"crypto/internal/boring"
 	"crypto/subtle"
+	"errors"
 	"hash"
+	"internal/cryptometrics"
 )
 
 // FIPS 198-1:
@@ -46,6 +45,7 @@ type marshalable interface {
 type hmac struct {
 	opad, ipad   []byte
 	outer, inner hash.Hash
+	newHash      func() hash.Hash
 
 	// If marshaled is true, then opad and ipad do not contain a padded
 	// copy of the key, but rather the marshaled state of outer/inner after
@@ -120,12 +120,147 @@ func (h *hmac) Reset() {
 	h.marshaled = true
 }
 
+// padStates returns the marshaled state of the underlying hash after
+// absorbing the inner and outer key pads.
+func (h *hmac) padStates() (istate, ostate []byte, err error) {
+	if h.marshaled {
+		return h.ipad, h.opad, nil
+	}
+	// Before the first Reset, outer is only scratch space.
+	marshalableOuter, ok := h.outer.(marshalable)
+	if !ok {
+		return nil, nil, errUnmarshalable
+	}
+	h.outer.Reset()
+	h.outer.Write(h.ipad)
+	if istate, err = marshalableOuter.MarshalBinary(); err != nil {
+		return nil, nil, err
+	}
+	h.outer.Reset()
+	h.outer.Write(h.opad)
+	if ostate, err = marshalableOuter.MarshalBinary(); err != nil {
+		return nil, nil, err
+	}
+	return istate, ostate, nil
+}
+
+var errUnmarshalable = errors.New("crypto/hmac: underlying hash does not support marshaling")
+
+const (
+	magic = "hmac\x01"
+	// maxStateLen bounds each marshaled hash state, to reject
+	// corrupt length prefixes early.
+	maxStateLen = 1 << 16
+)
+
+// MarshalBinary returns the state of h in the format documented on New.
+func (h *hmac) MarshalBinary() ([]byte, error) {
+	marshalableInner, ok := h.inner.(marshalable)
+	if !ok {
+		return nil, errUnmarshalable
+	}
+	istate, ostate, err := h.padStates()
+	if err != nil {
+		return nil, err
+	}
+	state, err := marshalableInner.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, len(magic)+3*4+len(istate)+len(ostate)+len(state))
+	b = append(b, magic...)
+	for _, s := range [][]byte{istate, ostate, state} {
+		b = appendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary restores a state produced by MarshalBinary. The state
+// carries the key, so h continues as if it had been created with the key
+// of the marshaled HMAC; the underlying hash function must match.
+func (h *hmac) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("crypto/hmac: invalid hash state identifier")
+	}
+	b = b[len(magic):]
+	var states [3][]byte
+	for i := range states {
+		if len(b) < 4 {
+			return errors.New("crypto/hmac: invalid hash state size")
+		}
+		n := consumeUint32(b)
+		b = b[4:]
+		if n > maxStateLen || uint32(len(b)) < n {
+			return errors.New("crypto/hmac: invalid hash state size")
+		}
+		states[i], b = b[:n:n], b[n:]
+	}
+	if len(b) != 0 {
+		return errors.New("crypto/hmac: invalid hash state size")
+	}
+	marshalableInner, innerOK := h.inner.(marshalable)
+	marshalableOuter, outerOK := h.outer.(marshalable)
+	if !innerOK || !outerOK {
+		return errUnmarshalable
+	}
+	// Check both pad states before changing anything, leaving outer
+	// primed with the outer pad as Sum expects.
+	if err := marshalableOuter.UnmarshalBinary(states[0]); err != nil {
+		return err
+	}
+	if err := marshalableOuter.UnmarshalBinary(states[1]); err != nil {
+		return err
+	}
+	if err := marshalableInner.UnmarshalBinary(states[2]); err != nil {
+		return err
+	}
+	h.ipad = append([]byte(nil), states[0]...)
+	h.opad = append([]byte(nil), states[1]...)
+	h.marshaled = true
+	return nil
+}
+
+// Clone returns an independent copy of h. It fails if the underlying hash
+// does not support marshaling.
+func (h *hmac) Clone() (hash.Hash, error) {
+	state, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	c := &hmac{outer: h.newHash(), inner: h.newHash(), newHash: h.newHash}
+	if err := c.UnmarshalBinary(state); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	return append(b, byte(x>>24), byte(x>>16), byte(x>>8), byte(x))
+}
+
+func consumeUint32(b []byte) uint32 {
+	_ = b[3]
+	return uint32(b[3]) | uint32(b[2])<<8 | uint32(b[1])<<16 | uint32(b[0])<<24
+}
+
 // New returns a new HMAC hash using the given hash.Hash type and key.
 // New functions like sha256.New from crypto/sha256 can be used as h.
 // h must return a new Hash every time it is called.
-// Note that unlike other hash implementations in the standard library,
-// the returned Hash does not implement encoding.BinaryMarshaler
-// or encoding.BinaryUnmarshaler.
+//
+// If the hashes returned by h implement encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler, as those in the standard library do, so does
+// the returned Hash, and it also has a method
+//
+//	Clone() (hash.Hash, error)
+//
+// returning an independent copy of its state. The marshaled state is the
+// five bytes "hmac\x01" followed by three fields, each a 4-byte big-endian
+// length and the underlying hash's marshaled state: after absorbing the
+// inner key pad, after absorbing the outer key pad, and of the message
+// written so far. The first two are derived from the key, so marshaled
+// states must be protected like the key itself. The format is the same
+// whether or not the HMAC is computed by BoringCrypto.
 func New(h func() hash.Hash, key []byte) hash.Hash {
 	if boring.Enabled {
 		hm := boring.NewHMAC(h, key)
@@ -133,10 +268,17 @@ func New(h func() hash.Hash, key []byte) hash.Hash {
 			return hm
 		}
 		// BoringCrypto did not recognize h, so fall through to standard Go code.
+		boring.RecordFallback(cryptometrics.HMAC, "unsupported hash")
 	}
+	return newHMAC(h, key)
+}
+
+// newHMAC returns the pure Go implementation of HMAC.
+func newHMAC(h func() hash.Hash, key []byte) *hmac {
 	hm := new(hmac)
 	hm.outer = h()
 	hm.inner = h()
+	hm.newHash = h
 	unique := true
 	func() {
 		defer func() {
@@ -171,6 +313,45 @@ func New(h func() hash.Hash, key []byte) hash.Hash {
 	return hm
 }
 
+// Verify reports whether mac is the HMAC of msg with key, using the hash h,
+// like New. The comparison does not leak timing information.
+func Verify(h func() hash.Hash, key, msg, mac []byte) bool {
+	m := New(h, key)
+	m.Write(msg)
+	return Equal(m.Sum(nil), mac)
+}
+
+// A Verifier checks the HMAC of a message that is written to it in pieces,
+// for messages that are too large to hold in memory or that arrive as a
+// stream.
+type Verifier struct {
+	h hash.Hash
+}
+
+// NewVerifier returns a Verifier computing the HMAC with key, using the hash
+// h, like New.
+func NewVerifier(h func() hash.Hash, key []byte) *Verifier {
+	return &Verifier{New(h, key)}
+}
+
+// Write adds more data to the message. It never returns an error.
+func (v *Verifier) Write(p []byte) (int, error) {
+	return v.h.Write(p)
+}
+
+// Verify reports whether mac is the HMAC of the message written so far. The
+// comparison does not leak timing information. More data may be written
+// after Verify, to verify a longer message.
+func (v *Verifier) Verify(mac []byte) bool {
+	return Equal(v.h.Sum(nil), mac)
+}
+
+// Reset discards the message written so far, so that v can be used to
+// verify another message with the same key.
+func (v *Verifier) Reset() {
+	v.h.Reset()
+}
+
 // Equal compares two MACs for equality without leaking timing information.
 func Equal(mac1, mac2 []byte) bool {
 	// We don't have to be constant time if the lengths of the MACs are
@@ -7,10 +7,12 @@ package hmac
 import (
 	"bytes"
 	"crypto/internal/boring"
+	"crypto/internal/difftest"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
 	"fmt"
 	"hash"
 	"testing"
@@ -621,6 +623,43 @@ func TestEqual(t *testing.T) {
 	}
 }
 
+func TestVerify(t *testing.T) {
+	for i, tt := range hmacTests {
+		mac, err := hex.DecodeString(tt.out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Verify(tt.hash, tt.key, tt.in, mac) {
+			t.Errorf("test %d: Verify failed", i)
+		}
+		bad := bytes.Clone(mac)
+		bad[len(bad)-1] ^= 1
+		if Verify(tt.hash, tt.key, tt.in, bad) {
+			t.Errorf("test %d: Verify accepted a modified MAC", i)
+		}
+		if Verify(tt.hash, tt.key, tt.in, mac[:len(mac)-1]) {
+			t.Errorf("test %d: Verify accepted a truncated MAC", i)
+		}
+
+		v := NewVerifier(tt.hash, tt.key)
+		for j := 0; j < 2; j++ {
+			in := tt.in
+			for len(in) > 7 {
+				v.Write(in[:7])
+				in = in[7:]
+			}
+			v.Write(in)
+			if !v.Verify(mac) {
+				t.Errorf("test %d.%d: Verifier.Verify failed", i, j)
+			}
+			if v.Verify(bad) {
+				t.Errorf("test %d.%d: Verifier.Verify accepted a modified MAC", i, j)
+			}
+			v.Reset()
+		}
+	}
+}
+
 func TestWriteAfterSum(t *testing.T) {
 	h := New(sha1.New, nil)
 	h.Write([]byte("hello"))
@@ -693,3 +732,157 @@ func BenchmarkNewWriteSum(b *testing.B) {
 		buf[0] = mac[0]
 	}
 }
+
+type marshalHMAC interface {
+	hash.Hash
+	marshalable
+	Clone() (hash.Hash, error)
+}
+
+func TestMarshal(t *testing.T) {
+	for _, newHash := range []func() hash.Hash{sha1.New, sha256.New224, sha256.New, sha512.New384, sha512.New} {
+		h := New(newHash, []byte("key")).(marshalHMAC)
+		h.Write([]byte("hello"))
+		state, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Restoring into an HMAC with another key also restores the key.
+		h2 := New(newHash, []byte("other key")).(marshalHMAC)
+		if err := h2.UnmarshalBinary(state); err != nil {
+			t.Fatal(err)
+		}
+		h.Write([]byte(" world"))
+		h2.Write([]byte(" world"))
+		if sum, sum2 := h.Sum(nil), h2.Sum(nil); !bytes.Equal(sum, sum2) {
+			t.Errorf("restored Sum = %x, want %x", sum2, sum)
+		}
+		h.Reset()
+		h2.Reset()
+		h.Write([]byte("again"))
+		h2.Write([]byte("again"))
+		if sum, sum2 := h.Sum(nil), h2.Sum(nil); !bytes.Equal(sum, sum2) {
+			t.Errorf("restored Sum after Reset = %x, want %x", sum2, sum)
+		}
+
+		state2, err := h2.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, _ = h.MarshalBinary()
+		if !bytes.Equal(state, state2) {
+			t.Errorf("restored state = %x, want %x", state2, state)
+		}
+
+		for _, bad := range [][]byte{nil, state[:len(state)-1], append(state, 0), []byte("hmac\x01\xff\xff\xff\xff")} {
+			if err := h2.UnmarshalBinary(bad); err == nil {
+				t.Errorf("UnmarshalBinary(%x) succeeded", bad)
+			}
+		}
+	}
+}
+
+func TestMarshalMismatchedHash(t *testing.T) {
+	state, err := New(sha256.New, []byte("key")).(marshalHMAC).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New(sha512.New, []byte("key")).(marshalHMAC).UnmarshalBinary(state); err == nil {
+		t.Error("UnmarshalBinary of a SHA-256 state into an HMAC-SHA-512 succeeded")
+	}
+}
+
+func TestClone(t *testing.T) {
+	h := New(sha256.New, []byte("key")).(marshalHMAC)
+	h.Write([]byte("hello"))
+	c, err := h.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte(" world"))
+
+	want := New(sha256.New, []byte("key"))
+	want.Write([]byte("hello"))
+	if sum := h.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+		t.Errorf("original Sum after writing to clone = %x, want %x", sum, want.Sum(nil))
+	}
+	want.Write([]byte(" world"))
+	if sum := c.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+		t.Errorf("clone Sum = %x, want %x", sum, want.Sum(nil))
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	if boring.Enabled {
+		t.Skip("BoringCrypto does not accept hashes it did not create")
+	}
+	h := New(func() hash.Hash { return justHash{sha256.New()} }, []byte("key")).(marshalHMAC)
+	if _, err := h.MarshalBinary(); err == nil {
+		t.Error("MarshalBinary succeeded with an unmarshalable hash")
+	}
+	if _, err := h.Clone(); err == nil {
+		t.Error("Clone succeeded with an unmarshalable hash")
+	}
+}
+
+func TestMarshalBackend(t *testing.T) {
+	if !boring.Enabled {
+		t.Skip("no cryptographic backend in use")
+	}
+	defer boring.AllowGoForTesting()()
+	for _, newHash := range []func() hash.Hash{sha1.New, sha256.New, sha512.New} {
+		module := New(newHash, []byte("key")).(marshalHMAC)
+		pure := newHMAC(newHash, []byte("key"))
+		module.Write([]byte("hello"))
+		pure.Write([]byte("hello"))
+		ms, err := module.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps, err := pure.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ms, ps) {
+			t.Fatalf("module state = %x, want %x", ms, ps)
+		}
+
+		restored := New(newHash, []byte("other")).(marshalHMAC)
+		if err := restored.UnmarshalBinary(ps); err != nil {
+			t.Fatal(err)
+		}
+		restored.Write([]byte(" world"))
+		pure.Write([]byte(" world"))
+		if sum, want := restored.Sum(nil), pure.Sum(nil); !bytes.Equal(sum, want) {
+			t.Errorf("module Sum from Go state = %x, want %x", sum, want)
+		}
+	}
+}
+
+func TestBackendHashes(t *testing.T) {
+	if !boring.Enabled {
+		t.Skip("no cryptographic backend in use")
+	}
+	for _, newHash := range []func() hash.Hash{sha1.New, sha256.New224, sha256.New, sha512.New384, sha512.New} {
+		if h, ok := New(newHash, []byte("key")).(*hmac); ok {
+			t.Errorf("HMAC-%T is computed by Go code", h.inner)
+		}
+	}
+}
+
+func FuzzBackend(f *testing.F) {
+	if !boring.Enabled {
+		f.Skip("no cryptographic backend in use")
+	}
+	defer boring.AllowGoForTesting()()
+	f.Add([]byte("key"), []byte("abc"))
+	f.Add(make([]byte, 200), make([]byte, 1000))
+	f.Fuzz(func(t *testing.T, key, data []byte) {
+		for _, h := range []func() hash.Hash{sha1.New, sha256.New, sha512.New} {
+			if err := difftest.Hash(newHMAC(h, key), New(h, key), data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
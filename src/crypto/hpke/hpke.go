@@ -0,0 +1,454 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hpke implements Hybrid Public Key Encryption, as specified in
+// RFC 9180.
+//
+// HPKE encrypts to a public key by combining a key encapsulation mechanism
+// (KEM), a key derivation function (KDF), and an AEAD, chosen together as a
+// Suite. A sender encapsulates a shared secret to the public key of a
+// receiver, and uses the resulting context to seal any number of messages
+// in order; the receiver decapsulates the same secret with its private key
+// and opens them in the same order. Both contexts can also export secrets
+// bound to the exchange, and the ExportOnly AEAD makes a suite that can
+// only be used for that.
+//
+// All four modes of RFC 9180 are supported: base, PSK, where both parties
+// also hold a pre-shared key, auth, where the sender is authenticated by
+// its own key pair, and auth PSK, which combines the two. All the DHKEMs
+// are supported except for DHKEM(X448, HKDF-SHA512).
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/internal/fips"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+	"strconv"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// A KDF is an HPKE key derivation function, identified by its registered
+// value.
+type KDF uint16
+
+const (
+	HKDF_SHA256 KDF = 0x0001
+	HKDF_SHA384 KDF = 0x0002
+	HKDF_SHA512 KDF = 0x0003
+)
+
+func (k KDF) hash() func() hash.Hash {
+	switch k {
+	case HKDF_SHA256:
+		return sha256.New
+	case HKDF_SHA384:
+		return sha512.New384
+	case HKDF_SHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+func (k KDF) String() string {
+	switch k {
+	case HKDF_SHA256:
+		return "HKDF-SHA256"
+	case HKDF_SHA384:
+		return "HKDF-SHA384"
+	case HKDF_SHA512:
+		return "HKDF-SHA512"
+	}
+	return "KDF(0x" + strconv.FormatUint(uint64(k), 16) + ")"
+}
+
+// An AEAD is an HPKE authenticated encryption algorithm, identified by its
+// registered value.
+type AEAD uint16
+
+const (
+	AES128GCM AEAD = 0x0001
+	AES256GCM AEAD = 0x0002
+
+	// ChaCha20Poly1305 is not approved in FIPS mode. Setting up a context
+	// with it returns an error matching crypto/fips.ErrNotApproved if a
+	// cryptographic module operates in FIPS mode and GODEBUG=fips140=only
+	// is set.
+	ChaCha20Poly1305 AEAD = 0x0003
+
+	// ExportOnly makes a suite that can export secrets, but can't seal or
+	// open messages.
+	ExportOnly AEAD = 0xffff
+)
+
+// keySize returns Nk, or -1 if a is not supported.
+func (a AEAD) keySize() int {
+	switch a {
+	case AES128GCM:
+		return 16
+	case AES256GCM, ChaCha20Poly1305:
+		return 32
+	case ExportOnly:
+		return 0
+	}
+	return -1
+}
+
+func (a AEAD) String() string {
+	switch a {
+	case AES128GCM:
+		return "AES-128-GCM"
+	case AES256GCM:
+		return "AES-256-GCM"
+	case ChaCha20Poly1305:
+		return "ChaCha20Poly1305"
+	case ExportOnly:
+		return "Export-only"
+	}
+	return "AEAD(0x" + strconv.FormatUint(uint64(a), 16) + ")"
+}
+
+func (a AEAD) new(key []byte) (cipher.AEAD, error) {
+	switch a {
+	case AES128GCM, AES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case ChaCha20Poly1305:
+		aead, err := chacha20poly1305.New(key)
+		return fips.NewAEAD("ChaCha20-Poly1305", aead, err)
+	}
+	panic("hpke: internal error: unexpected AEAD")
+}
+
+// A Suite is a combination of a KEM, a KDF, and an AEAD. Any combination
+// of the supported algorithms is valid.
+type Suite struct {
+	KEM  KEM
+	KDF  KDF
+	AEAD AEAD
+}
+
+func (s Suite) check() error {
+	if _, ok := s.KEM.params(); !ok {
+		return errors.New("hpke: unsupported KEM " + s.KEM.String())
+	}
+	if s.KDF.hash() == nil {
+		return errors.New("hpke: unsupported KDF " + s.KDF.String())
+	}
+	if s.AEAD.keySize() < 0 {
+		return errors.New("hpke: unsupported AEAD " + s.AEAD.String())
+	}
+	return nil
+}
+
+func (s Suite) suiteID() []byte {
+	id := []byte("HPKE")
+	id = binary.BigEndian.AppendUint16(id, uint16(s.KEM))
+	id = binary.BigEndian.AppendUint16(id, uint16(s.KDF))
+	return binary.BigEndian.AppendUint16(id, uint16(s.AEAD))
+}
+
+const (
+	modeBase    = 0x00
+	modePSK     = 0x01
+	modeAuth    = 0x02
+	modeAuthPSK = 0x03
+)
+
+// ErrOpen is returned when a message can't be opened, because it was not
+// sealed by the matching sender context, or it or its additional data was
+// modified.
+var ErrOpen = errors.New("hpke: message authentication failed")
+
+// context is the state shared by senders and receivers.
+type context struct {
+	suite          Suite
+	aead           cipher.AEAD // nil for ExportOnly
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+}
+
+func (s Suite) keySchedule(mode byte, sharedSecret, info, psk, pskID []byte) (*context, error) {
+	if (len(psk) == 0) != (len(pskID) == 0) {
+		return nil, errors.New("hpke: inconsistent PSK inputs")
+	}
+	if hasPSK := mode == modePSK || mode == modeAuthPSK; hasPSK != (len(psk) != 0) {
+		if hasPSK {
+			return nil, errors.New("hpke: missing PSK")
+		}
+		panic("hpke: internal error: PSK in a non-PSK mode")
+	}
+
+	h, suiteID := s.KDF.hash(), s.suiteID()
+	pskIDHash := labeledExtract(h, suiteID, nil, "psk_id_hash", pskID)
+	infoHash := labeledExtract(h, suiteID, nil, "info_hash", info)
+	ksContext := append([]byte{mode}, pskIDHash...)
+	ksContext = append(ksContext, infoHash...)
+	secret := labeledExtract(h, suiteID, sharedSecret, "secret", psk)
+
+	c := &context{suite: s}
+	var err error
+	if s.AEAD != ExportOnly {
+		key, err := labeledExpand(h, suiteID, secret, "key", ksContext, s.AEAD.keySize())
+		if err != nil {
+			return nil, err
+		}
+		if c.aead, err = s.AEAD.new(key); err != nil {
+			return nil, err
+		}
+		c.baseNonce, err = labeledExpand(h, suiteID, secret, "base_nonce", ksContext, c.aead.NonceSize())
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.exporterSecret, err = labeledExpand(h, suiteID, secret, "exp", ksContext, h().Size())
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// nextNonce returns the nonce of the current sequence number, and
+// increments it.
+func (c *context) nextNonce() ([]byte, error) {
+	if c.aead == nil {
+		return nil, errors.New("hpke: can't seal or open with an export-only suite")
+	}
+	if c.seq == math.MaxUint64 {
+		return nil, errors.New("hpke: message limit reached")
+	}
+	nonce := make([]byte, len(c.baseNonce))
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], c.seq)
+	for i := range nonce {
+		nonce[i] ^= c.baseNonce[i]
+	}
+	c.seq++
+	return nonce, nil
+}
+
+func (c *context) export(exporterContext []byte, length int) ([]byte, error) {
+	h := c.suite.KDF.hash()
+	if length < 0 || length > 255*h().Size() {
+		return nil, errors.New("hpke: invalid export length")
+	}
+	return labeledExpand(h, c.suite.suiteID(), c.exporterSecret, "sec", exporterContext, length)
+}
+
+// A Sender is the context of the sender of an HPKE exchange. It is not
+// safe for concurrent use.
+type Sender struct {
+	c *context
+}
+
+// NewSender sets up a base mode sender context for the receiver's public
+// key pkR, and returns the encapsulated key to send to the receiver along
+// with the messages.
+func (s Suite) NewSender(pkR *ecdh.PublicKey, info []byte) (enc []byte, sender *Sender, err error) {
+	return s.newSender(modeBase, pkR, nil, nil, info, nil, nil)
+}
+
+// NewSenderPSK is like NewSender, but also authenticates the sender with a
+// pre-shared key, which should have at least 32 bytes of entropy, and its
+// identifier.
+func (s Suite) NewSenderPSK(pkR *ecdh.PublicKey, info, psk, pskID []byte) (enc []byte, sender *Sender, err error) {
+	return s.newSender(modePSK, pkR, nil, nil, info, psk, pskID)
+}
+
+// NewSenderAuth is like NewSender, but also authenticates the sender with
+// its private key skS.
+func (s Suite) NewSenderAuth(pkR *ecdh.PublicKey, skS *ecdh.PrivateKey, info []byte) (enc []byte, sender *Sender, err error) {
+	if skS == nil {
+		return nil, nil, errors.New("hpke: missing sender key")
+	}
+	return s.newSender(modeAuth, pkR, skS, nil, info, nil, nil)
+}
+
+// NewSenderAuthPSK combines NewSenderAuth and NewSenderPSK.
+func (s Suite) NewSenderAuthPSK(pkR *ecdh.PublicKey, skS *ecdh.PrivateKey, info, psk, pskID []byte) (enc []byte, sender *Sender, err error) {
+	if skS == nil {
+		return nil, nil, errors.New("hpke: missing sender key")
+	}
+	return s.newSender(modeAuthPSK, pkR, skS, nil, info, psk, pskID)
+}
+
+// newSender sets up a sender context with the ephemeral key skE, or a new
+// random one if skE is nil.
+func (s Suite) newSender(mode byte, pkR *ecdh.PublicKey, skS, skE *ecdh.PrivateKey, info, psk, pskID []byte) ([]byte, *Sender, error) {
+	if err := s.check(); err != nil {
+		return nil, nil, err
+	}
+	if skE == nil {
+		var err error
+		if skE, err = s.KEM.GenerateKey(); err != nil {
+			return nil, nil, err
+		}
+	}
+	sharedSecret, enc, err := s.KEM.encap(pkR, skS, skE)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &Sender{c}, nil
+}
+
+// Seal encrypts and authenticates plaintext and authenticates
+// additionalData, and returns the ciphertext. Messages must be opened in
+// the order they were sealed.
+func (s *Sender) Seal(additionalData, plaintext []byte) ([]byte, error) {
+	nonce, err := s.c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return s.c.aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// Export returns a secret of the given length bound to the exchange and
+// to exporterContext. The receiver derives the same secret with the same
+// exporterContext.
+func (s *Sender) Export(exporterContext []byte, length int) ([]byte, error) {
+	return s.c.export(exporterContext, length)
+}
+
+// A Receiver is the context of the receiver of an HPKE exchange. It is not
+// safe for concurrent use.
+type Receiver struct {
+	c *context
+}
+
+// NewReceiver sets up a base mode receiver context for the encapsulated
+// key enc, sent to the public key of skR.
+func (s Suite) NewReceiver(skR *ecdh.PrivateKey, enc, info []byte) (*Receiver, error) {
+	return s.newReceiver(modeBase, skR, enc, nil, info, nil, nil)
+}
+
+// NewReceiverPSK is like NewReceiver, but also authenticates the sender
+// with a pre-shared key and its identifier.
+func (s Suite) NewReceiverPSK(skR *ecdh.PrivateKey, enc, info, psk, pskID []byte) (*Receiver, error) {
+	return s.newReceiver(modePSK, skR, enc, nil, info, psk, pskID)
+}
+
+// NewReceiverAuth is like NewReceiver, but also authenticates the sender
+// with its public key pkS.
+func (s Suite) NewReceiverAuth(skR *ecdh.PrivateKey, enc []byte, pkS *ecdh.PublicKey, info []byte) (*Receiver, error) {
+	if pkS == nil {
+		return nil, errors.New("hpke: missing sender key")
+	}
+	return s.newReceiver(modeAuth, skR, enc, pkS, info, nil, nil)
+}
+
+// NewReceiverAuthPSK combines NewReceiverAuth and NewReceiverPSK.
+func (s Suite) NewReceiverAuthPSK(skR *ecdh.PrivateKey, enc []byte, pkS *ecdh.PublicKey, info, psk, pskID []byte) (*Receiver, error) {
+	if pkS == nil {
+		return nil, errors.New("hpke: missing sender key")
+	}
+	return s.newReceiver(modeAuthPSK, skR, enc, pkS, info, psk, pskID)
+}
+
+func (s Suite) newReceiver(mode byte, skR *ecdh.PrivateKey, enc []byte, pkS *ecdh.PublicKey, info, psk, pskID []byte) (*Receiver, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	sharedSecret, err := s.KEM.decap(enc, skR, pkS)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Receiver{c}, nil
+}
+
+// Open decrypts and authenticates ciphertext and authenticates
+// additionalData. It returns ErrOpen if they were modified, or if the
+// ciphertext is not the next message sealed by the sender.
+func (r *Receiver) Open(additionalData, ciphertext []byte) ([]byte, error) {
+	if r.c.aead == nil {
+		return nil, errors.New("hpke: can't seal or open with an export-only suite")
+	}
+	// The sequence number only advances on success, so that a forged
+	// message does not desynchronize the context.
+	seq := r.c.seq
+	nonce, err := r.c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := r.c.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		r.c.seq = seq
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+// Export returns a secret of the given length bound to the exchange and
+// to exporterContext. It is the same as that of the sender.
+func (r *Receiver) Export(exporterContext []byte, length int) ([]byte, error) {
+	return r.c.export(exporterContext, length)
+}
+
+// Seal encrypts a single message to pkR in base mode, and returns the
+// encapsulated key and the ciphertext.
+func (s Suite) Seal(pkR *ecdh.PublicKey, info, additionalData, plaintext []byte) (enc, ciphertext []byte, err error) {
+	enc, sender, err := s.NewSender(pkR, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err = sender.Seal(additionalData, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ciphertext, nil
+}
+
+// Open decrypts a single message returned by Seal.
+func (s Suite) Open(skR *ecdh.PrivateKey, enc, info, additionalData, ciphertext []byte) ([]byte, error) {
+	r, err := s.NewReceiver(skR, enc, info)
+	if err != nil {
+		return nil, err
+	}
+	return r.Open(additionalData, ciphertext)
+}
+
+const versionLabel = "HPKE-v1"
+
+func labeledExtract(h func() hash.Hash, suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := make([]byte, 0, len(versionLabel)+len(suiteID)+len(label)+len(ikm))
+	labeledIKM = append(labeledIKM, versionLabel...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+	return hkdf.Extract(h, labeledIKM, salt)
+}
+
+func labeledExpand(h func() hash.Hash, suiteID, prk []byte, label string, info []byte, length int) ([]byte, error) {
+	if length > math.MaxUint16 {
+		return nil, errors.New("hpke: invalid expand length")
+	}
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(labeledInfo, versionLabel...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(h, prk, labeledInfo).Read(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
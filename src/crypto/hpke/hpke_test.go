@@ -0,0 +1,262 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// testdata/vectors.json holds the base mode vectors of RFC 9180, Appendix
+// A, for the supported suites. The key material and encapsulated keys are
+// those of the RFC; ct holds the first two messages of the RFC,
+// "Beauty is truth, truth beauty" with additional data "Count-0" and
+// "Count-1", and export is the 32-byte secret exported with the context
+// "TestContext", both checked against an independent implementation.
+type vector struct {
+	KEM    KEM      `json:"kem_id"`
+	KDF    KDF      `json:"kdf_id"`
+	AEAD   AEAD     `json:"aead_id"`
+	Info   string   `json:"info"`
+	IKME   string   `json:"ikmE"`
+	IKMR   string   `json:"ikmR"`
+	SKRm   string   `json:"skRm"`
+	PKRm   string   `json:"pkRm"`
+	Enc    string   `json:"enc"`
+	CT     []string `json:"ct"`
+	Export string   `json:"export"`
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		s := Suite{v.KEM, v.KDF, v.AEAD}
+		t.Run(fmt.Sprintf("%v/%v/%v", v.KEM, v.KDF, v.AEAD), func(t *testing.T) {
+			skR, err := s.KEM.DeriveKeyPair(decodeHex(t, v.IKMR))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := skR.Bytes(); !bytes.Equal(got, decodeHex(t, v.SKRm)) {
+				t.Errorf("DeriveKeyPair private key = %x, want %s", got, v.SKRm)
+			}
+			if got := skR.PublicKey().Bytes(); !bytes.Equal(got, decodeHex(t, v.PKRm)) {
+				t.Errorf("DeriveKeyPair public key = %x, want %s", got, v.PKRm)
+			}
+			skE, err := s.KEM.DeriveKeyPair(decodeHex(t, v.IKME))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info := decodeHex(t, v.Info)
+			enc, sender, err := s.newSender(modeBase, skR.PublicKey(), nil, skE, info, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(enc, decodeHex(t, v.Enc)) {
+				t.Errorf("enc = %x, want %s", enc, v.Enc)
+			}
+			receiver, err := s.NewReceiver(skR, enc, info)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range v.CT {
+				ad := []byte(fmt.Sprintf("Count-%d", i))
+				ct, err := sender.Seal(ad, []byte("Beauty is truth, truth beauty"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(ct, decodeHex(t, want)) {
+					t.Errorf("message %d = %x, want %s", i, ct, want)
+				}
+				pt, err := receiver.Open(ad, ct)
+				if err != nil || string(pt) != "Beauty is truth, truth beauty" {
+					t.Errorf("Open of message %d = %q, %v", i, pt, err)
+				}
+			}
+			if v.AEAD == ExportOnly {
+				if _, err := sender.Seal(nil, nil); err == nil {
+					t.Errorf("Seal with an export-only suite succeeded")
+				}
+				if _, err := receiver.Open(nil, nil); err == nil {
+					t.Errorf("Open with an export-only suite succeeded")
+				}
+			}
+
+			for _, c := range []interface {
+				Export([]byte, int) ([]byte, error)
+			}{sender, receiver} {
+				exp, err := c.Export([]byte("TestContext"), 32)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(exp, decodeHex(t, v.Export)) {
+					t.Errorf("Export = %x, want %s", exp, v.Export)
+				}
+			}
+		})
+	}
+}
+
+var suites = []Suite{
+	{DHKEM_P256_HKDF_SHA256, HKDF_SHA256, AES128GCM},
+	{DHKEM_P384_HKDF_SHA384, HKDF_SHA384, AES256GCM},
+	{DHKEM_P521_HKDF_SHA512, HKDF_SHA512, AES256GCM},
+	{DHKEM_X25519_HKDF_SHA256, HKDF_SHA256, ChaCha20Poly1305},
+}
+
+func TestModes(t *testing.T) {
+	info, psk, pskID := []byte("info"), bytes.Repeat([]byte{42}, 32), []byte("psk id")
+	for _, s := range suites {
+		skR, _ := s.KEM.GenerateKey()
+		skS, _ := s.KEM.GenerateKey()
+		other, _ := s.KEM.GenerateKey()
+		pkR, pkS := skR.PublicKey(), skS.PublicKey()
+
+		for _, tt := range []struct {
+			name  string
+			send  func() ([]byte, *Sender, error)
+			recv  func(enc []byte) (*Receiver, error)
+			wrong func(enc []byte) (*Receiver, error)
+		}{
+			{"base",
+				func() ([]byte, *Sender, error) { return s.NewSender(pkR, info) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiver(skR, enc, info) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiver(skR, enc, []byte("other")) },
+			},
+			{"psk",
+				func() ([]byte, *Sender, error) { return s.NewSenderPSK(pkR, info, psk, pskID) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiverPSK(skR, enc, info, psk, pskID) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiverPSK(skR, enc, info, psk[1:], pskID) },
+			},
+			{"auth",
+				func() ([]byte, *Sender, error) { return s.NewSenderAuth(pkR, skS, info) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiverAuth(skR, enc, pkS, info) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiverAuth(skR, enc, other.PublicKey(), info) },
+			},
+			{"authpsk",
+				func() ([]byte, *Sender, error) { return s.NewSenderAuthPSK(pkR, skS, info, psk, pskID) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiverAuthPSK(skR, enc, pkS, info, psk, pskID) },
+				func(enc []byte) (*Receiver, error) { return s.NewReceiverAuth(skR, enc, pkS, info) },
+			},
+		} {
+			t.Run(s.KEM.String()+"/"+tt.name, func(t *testing.T) {
+				enc, sender, err := tt.send()
+				if err != nil {
+					t.Fatal(err)
+				}
+				receiver, err := tt.recv(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 3; i++ {
+					msg := []byte(fmt.Sprintf("message %d", i))
+					ct, err := sender.Seal([]byte("ad"), msg)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if _, err := receiver.Open([]byte("bad"), ct); err != ErrOpen {
+						t.Errorf("Open with the wrong additional data: %v", err)
+					}
+					// A failed Open must not advance the sequence number.
+					if pt, err := receiver.Open([]byte("ad"), ct); err != nil || !bytes.Equal(pt, msg) {
+						t.Errorf("Open = %q, %v", pt, err)
+					}
+				}
+
+				ct, _ := sender.Seal(nil, []byte("next"))
+				wrong, err := tt.wrong(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 3; i++ {
+					wrong.c.nextNonce()
+				}
+				if _, err := wrong.Open(nil, ct); err != ErrOpen {
+					t.Errorf("Open with the wrong parameters: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestSingleShot(t *testing.T) {
+	s := Suite{DHKEM_X25519_HKDF_SHA256, HKDF_SHA256, AES128GCM}
+	skR, _ := s.KEM.GenerateKey()
+	enc, ct, err := s.Seal(skR.PublicKey(), []byte("info"), []byte("ad"), []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := s.Open(skR, enc, []byte("info"), []byte("ad"), ct)
+	if err != nil || string(pt) != "hello" {
+		t.Errorf("Open = %q, %v", pt, err)
+	}
+	enc[0] ^= 1
+	if _, err := s.Open(skR, enc, []byte("info"), []byte("ad"), ct); err == nil {
+		t.Errorf("Open with a modified encapsulated key succeeded")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := Suite{DHKEM_P256_HKDF_SHA256, HKDF_SHA256, AES128GCM}
+	skR, _ := s.KEM.GenerateKey()
+	x, _ := DHKEM_X25519_HKDF_SHA256.GenerateKey()
+	if _, _, err := s.NewSender(x.PublicKey(), nil); err == nil {
+		t.Errorf("NewSender with a key of the wrong curve succeeded")
+	}
+	if _, _, err := (Suite{s.KEM, 0x0004, s.AEAD}).NewSender(skR.PublicKey(), nil); err == nil {
+		t.Errorf("NewSender with an unsupported KDF succeeded")
+	}
+	if _, _, err := (Suite{s.KEM, s.KDF, 0x0004}).NewSender(skR.PublicKey(), nil); err == nil {
+		t.Errorf("NewSender with an unsupported AEAD succeeded")
+	}
+	if _, _, err := (Suite{0x0021, s.KDF, s.AEAD}).NewSender(skR.PublicKey(), nil); err == nil {
+		t.Errorf("NewSender with an unsupported KEM succeeded")
+	}
+	if _, _, err := s.NewSenderPSK(skR.PublicKey(), nil, []byte("psk"), nil); err == nil {
+		t.Errorf("NewSenderPSK without a PSK ID succeeded")
+	}
+	if _, _, err := s.NewSenderPSK(skR.PublicKey(), nil, nil, nil); err == nil {
+		t.Errorf("NewSenderPSK without a PSK succeeded")
+	}
+	if _, err := s.NewReceiver(skR, []byte{4, 1, 2, 3}, nil); err == nil {
+		t.Errorf("NewReceiver with an invalid encapsulated key succeeded")
+	}
+	if _, err := s.KEM.DeriveKeyPair(make([]byte, 16)); err == nil {
+		t.Errorf("DeriveKeyPair with a short input succeeded")
+	}
+
+	_, sender, _ := s.NewSender(skR.PublicKey(), nil)
+	if _, err := sender.Export(nil, 255*32+1); err == nil {
+		t.Errorf("Export of a too long secret succeeded")
+	}
+	if _, err := sender.Export(nil, 255*32); err != nil {
+		t.Errorf("Export of the longest secret: %v", err)
+	}
+	sender.c.seq = 1<<64 - 1
+	if _, err := sender.Seal(nil, nil); err == nil {
+		t.Errorf("Seal past the message limit succeeded")
+	}
+}
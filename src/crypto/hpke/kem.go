@@ -0,0 +1,184 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"strconv"
+)
+
+// A KEM is an HPKE key encapsulation mechanism, identified by its
+// registered value.
+type KEM uint16
+
+const (
+	DHKEM_P256_HKDF_SHA256   KEM = 0x0010
+	DHKEM_P384_HKDF_SHA384   KEM = 0x0011
+	DHKEM_P521_HKDF_SHA512   KEM = 0x0012
+	DHKEM_X25519_HKDF_SHA256 KEM = 0x0020
+)
+
+type kemParams struct {
+	curve      ecdh.Curve
+	hash       func() hash.Hash
+	secretSize int  // Nsecret
+	skSize     int  // Nsk
+	bitmask    byte // of DeriveKeyPair, zero for X25519
+}
+
+func (k KEM) params() (kemParams, bool) {
+	switch k {
+	case DHKEM_P256_HKDF_SHA256:
+		return kemParams{ecdh.P256(), sha256.New, 32, 32, 0xff}, true
+	case DHKEM_P384_HKDF_SHA384:
+		return kemParams{ecdh.P384(), sha512.New384, 48, 48, 0xff}, true
+	case DHKEM_P521_HKDF_SHA512:
+		return kemParams{ecdh.P521(), sha512.New, 64, 66, 0x01}, true
+	case DHKEM_X25519_HKDF_SHA256:
+		return kemParams{ecdh.X25519(), sha256.New, 32, 32, 0}, true
+	}
+	return kemParams{}, false
+}
+
+func (k KEM) String() string {
+	switch k {
+	case DHKEM_P256_HKDF_SHA256:
+		return "DHKEM(P-256, HKDF-SHA256)"
+	case DHKEM_P384_HKDF_SHA384:
+		return "DHKEM(P-384, HKDF-SHA384)"
+	case DHKEM_P521_HKDF_SHA512:
+		return "DHKEM(P-521, HKDF-SHA512)"
+	case DHKEM_X25519_HKDF_SHA256:
+		return "DHKEM(X25519, HKDF-SHA256)"
+	}
+	return "KEM(0x" + strconv.FormatUint(uint64(k), 16) + ")"
+}
+
+func (k KEM) suiteID() []byte {
+	return binary.BigEndian.AppendUint16([]byte("KEM"), uint16(k))
+}
+
+// Curve returns the curve of the keys of k, or nil if k is not supported.
+func (k KEM) Curve() ecdh.Curve {
+	p, _ := k.params()
+	return p.curve
+}
+
+// GenerateKey returns a new random key pair for k.
+func (k KEM) GenerateKey() (*ecdh.PrivateKey, error) {
+	p, ok := k.params()
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM " + k.String())
+	}
+	return p.curve.GenerateKey(rand.Reader)
+}
+
+// DeriveKeyPair deterministically derives a key pair for k from ikm, which
+// must be secret and uniformly random, and at least as long as the
+// private keys of k.
+func (k KEM) DeriveKeyPair(ikm []byte) (*ecdh.PrivateKey, error) {
+	p, ok := k.params()
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM " + k.String())
+	}
+	if len(ikm) < p.skSize {
+		return nil, errors.New("hpke: DeriveKeyPair input keying material too short")
+	}
+	suiteID := k.suiteID()
+	prk := labeledExtract(p.hash, suiteID, nil, "dkp_prk", ikm)
+	if p.bitmask == 0 {
+		sk, err := labeledExpand(p.hash, suiteID, prk, "sk", nil, p.skSize)
+		if err != nil {
+			return nil, err
+		}
+		return p.curve.NewPrivateKey(sk)
+	}
+	for counter := 0; counter < 256; counter++ {
+		sk, err := labeledExpand(p.hash, suiteID, prk, "candidate", []byte{byte(counter)}, p.skSize)
+		if err != nil {
+			return nil, err
+		}
+		sk[0] &= p.bitmask
+		// NewPrivateKey rejects zero and values not below the order.
+		if key, err := p.curve.NewPrivateKey(sk); err == nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("hpke: DeriveKeyPair failed")
+}
+
+// encap returns the shared secret and the encapsulated key of the
+// ephemeral key skE for pkR. If skS is not nil, it authenticates the
+// sender.
+func (k KEM) encap(pkR *ecdh.PublicKey, skS, skE *ecdh.PrivateKey) (sharedSecret, enc []byte, err error) {
+	p, ok := k.params()
+	if !ok {
+		return nil, nil, errors.New("hpke: unsupported KEM " + k.String())
+	}
+	if pkR.Curve() != p.curve || skE.Curve() != p.curve || skS != nil && skS.Curve() != p.curve {
+		return nil, nil, errors.New("hpke: key does not match KEM " + k.String())
+	}
+	dh, err := skE.ECDH(pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc = skE.PublicKey().Bytes()
+	kemContext := append(enc[:len(enc):len(enc)], pkR.Bytes()...)
+	if skS != nil {
+		dhS, err := skS.ECDH(pkR)
+		if err != nil {
+			return nil, nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, skS.PublicKey().Bytes()...)
+	}
+	sharedSecret, err = k.extractAndExpand(p, dh, kemContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sharedSecret, enc, nil
+}
+
+// decap returns the shared secret of the encapsulated key enc for skR. If
+// pkS is not nil, it authenticates the sender.
+func (k KEM) decap(enc []byte, skR *ecdh.PrivateKey, pkS *ecdh.PublicKey) ([]byte, error) {
+	p, ok := k.params()
+	if !ok {
+		return nil, errors.New("hpke: unsupported KEM " + k.String())
+	}
+	if skR.Curve() != p.curve || pkS != nil && pkS.Curve() != p.curve {
+		return nil, errors.New("hpke: key does not match KEM " + k.String())
+	}
+	pkE, err := p.curve.NewPublicKey(enc)
+	if err != nil {
+		return nil, errors.New("hpke: invalid encapsulated key")
+	}
+	dh, err := skR.ECDH(pkE)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(enc[:len(enc):len(enc)], skR.PublicKey().Bytes()...)
+	if pkS != nil {
+		dhS, err := skR.ECDH(pkS)
+		if err != nil {
+			return nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, pkS.Bytes()...)
+	}
+	return k.extractAndExpand(p, dh, kemContext)
+}
+
+func (k KEM) extractAndExpand(p kemParams, dh, kemContext []byte) ([]byte, error) {
+	suiteID := k.suiteID()
+	prk := labeledExtract(p.hash, suiteID, nil, "eae_prk", dh)
+	return labeledExpand(p.hash, suiteID, prk, "shared_secret", kemContext, p.secretSize)
+}
@@ -0,0 +1,424 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"crypto/cipher"
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+type aesKeySizeError int
+
+func (k aesKeySizeError) Error() string {
+	return "crypto/aes: invalid key size " + strconv.Itoa(int(k))
+}
+
+const aesBlockSize = 16
+
+// initAES sets the chaining modes of the AES providers, which apply to the
+// keys they generate.
+func initAES() error {
+	for p, mode := range map[*provider]string{aesECBAlg: "ChainingModeECB", aesCBCAlg: "ChainingModeCBC", aesGCMAlg: "ChainingModeGCM"} {
+		// The property is a NUL-terminated UTF-16 string.
+		v, _ := syscall.UTF16FromString(mode)
+		if s := bcryptSetProperty(p.h, utf16("ChainingMode"), (*byte)(unsafe.Pointer(&v[0])), uint32(2*len(v)), 0); s != statusSuccess {
+			return newFail("BCryptSetProperty("+mode+")", s)
+		}
+	}
+	return nil
+}
+
+// newSymmetricKey returns a key of the provider p, which CNG allocates,
+// and zeroes when it is destroyed.
+func newSymmetricKey(p *provider, key []byte) (bcryptHandle, error) {
+	var h bcryptHandle
+	if s := bcryptGenerateSymmetricKey(p.h, &h, nil, 0, base(key), len32(key), 0); s != statusSuccess {
+		return 0, newFail("BCryptGenerateSymmetricKey", s)
+	}
+	return h, nil
+}
+
+// An ECB key is stateless, so CNG lets aesCipher use it concurrently.
+type aesCipher struct {
+	key []byte
+	ecb bcryptHandle
+}
+
+type extraModes interface {
+	// Copied out of crypto/aes/modes.go.
+	NewCBCEncrypter(iv []byte) cipher.BlockMode
+	NewCBCDecrypter(iv []byte) cipher.BlockMode
+	NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+}
+
+var _ extraModes = (*aesCipher)(nil)
+
+// NewAESCipher returns an AES cipher using CNG. It doesn't implement CTR,
+// which crypto/cipher builds on its blocks.
+func NewAESCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, aesKeySizeError(len(key))
+	}
+	c := &aesCipher{key: append([]byte(nil), key...)}
+	var err error
+	if c.ecb, err = newSymmetricKey(aesECBAlg, c.key); err != nil {
+		return nil, err
+	}
+	// Note: Because of the finalizer, any time c.ecb is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(c),
+	// to make sure c is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(c, (*aesCipher).finalize)
+	return c, nil
+}
+
+func (c *aesCipher) finalize() {
+	bcryptDestroyKey(c.ecb)
+}
+
+func (c *aesCipher) BlockSize() int { return aesBlockSize }
+
+// Close zeroes the key and destroys the key object of c, which CNG
+// zeroes, immediately rather than when c is garbage collected. c must not
+// be used afterwards. The modes created from c hold their own key
+// objects, and are not affected.
+//
+// Close must not be called concurrently with the other methods of c.
+func (c *aesCipher) Close() error {
+	if c.key != nil {
+		runtime.SetFinalizer(c, nil)
+		c.finalize()
+		clear(c.key)
+		c.key, c.ecb = nil, 0
+	}
+	return nil
+}
+
+func (c *aesCipher) checkOpen() {
+	if c.key == nil {
+		panic("crypto/aes: use of closed cipher")
+	}
+}
+
+func (c *aesCipher) Encrypt(dst, src []byte) { c.crypt(true, dst, src) }
+func (c *aesCipher) Decrypt(dst, src []byte) { c.crypt(false, dst, src) }
+
+// symCrypt encrypts, if enc is true, or decrypts src into dst with key,
+// which must not be larger than 4 GiB. iv is nil, or the IV of CBC, which
+// CNG updates.
+func symCrypt(enc bool, key bcryptHandle, dst, src, iv []byte) {
+	var n uint32
+	if enc {
+		if s := bcryptEncrypt(key, base(src), len32(src), nil, base(iv), len32(iv), base(dst), len32(src), &n, 0); s != statusSuccess {
+			panic(newFail("BCryptEncrypt", s))
+		}
+	} else {
+		if s := bcryptDecrypt(key, base(src), len32(src), nil, base(iv), len32(iv), base(dst), len32(src), &n, 0); s != statusSuccess {
+			panic(newFail("BCryptDecrypt", s))
+		}
+	}
+}
+
+func (c *aesCipher) crypt(enc bool, dst, src []byte) {
+	if len(src) < aesBlockSize {
+		panic("crypto/aes: input not full block")
+	}
+	if len(dst) < aesBlockSize {
+		panic("crypto/aes: output not full block")
+	}
+	if inexactOverlap(dst[:aesBlockSize], src[:aesBlockSize]) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	countOp(cryptometrics.AES, aesBlockSize)
+	c.checkOpen()
+	symCrypt(enc, c.ecb, dst[:aesBlockSize], src[:aesBlockSize], nil)
+	runtime.KeepAlive(c)
+}
+
+// A cbcMode is a CBC encrypter or decrypter, with its own key object. CNG
+// updates iv, which it is given with every call, so that the key object
+// holds no state. cbcMode doesn't implement XORKeyStream, because callers
+// such as crypto/tls tell block modes and streams apart by their methods.
+type cbcMode struct {
+	key bcryptHandle
+	enc bool
+	iv  [aesBlockSize]byte
+}
+
+func (c *aesCipher) newCBC(enc bool, iv []byte) *cbcMode {
+	c.checkOpen()
+	if len(iv) != aesBlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	key, err := newSymmetricKey(aesCBCAlg, c.key)
+	if err != nil {
+		panic(err)
+	}
+	x := &cbcMode{key: key, enc: enc}
+	copy(x.iv[:], iv)
+	runtime.SetFinalizer(x, (*cbcMode).finalize)
+	return x
+}
+
+func (c *aesCipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	return c.newCBC(true, iv)
+}
+
+func (c *aesCipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	return c.newCBC(false, iv)
+}
+
+func (x *cbcMode) finalize() {
+	bcryptDestroyKey(x.key)
+}
+
+func (x *cbcMode) BlockSize() int { return aesBlockSize }
+
+func (x *cbcMode) CryptBlocks(dst, src []byte) {
+	if len(src)%aesBlockSize != 0 {
+		panic("crypto/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypto/cipher: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	countOp(cryptometrics.AES, len(src))
+	for len(src) > 0 {
+		// BCryptEncrypt takes a 32-bit length.
+		n := len(src)
+		if n > 1<<30 {
+			n = 1 << 30
+		}
+		symCrypt(x.enc, x.key, dst[:n], src[:n], x.iv[:])
+		dst, src = dst[n:], src[n:]
+	}
+	runtime.KeepAlive(x)
+}
+
+func (x *cbcMode) SetIV(iv []byte) {
+	if len(iv) != aesBlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	copy(x.iv[:], iv)
+}
+
+// A GCM key is stateless, since every call gets the whole message, so gcm
+// can be used concurrently.
+type gcm struct {
+	key bcryptHandle
+
+	// tls enforces the strictly increasing nonces of TLS 1.2, which
+	// FIPS 140-3 requires; next is the smallest valid explicit nonce.
+	tls  bool
+	mu   sync.Mutex
+	next uint64
+}
+
+const (
+	gcmTagSize           = 16
+	gcmStandardNonceSize = 12
+)
+
+type noGCM struct {
+	cipher.Block
+}
+
+func (c *aesCipher) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
+	if nonceSize != gcmStandardNonceSize && tagSize != gcmTagSize {
+		return nil, unsupported("crypto/aes: GCM tag and nonce sizes can't be non-standard at the same time")
+	}
+	// Fall back to standard library for GCM with non-standard nonce or tag size.
+	if nonceSize != gcmStandardNonceSize {
+		recordFallback(cryptometrics.AESGCM, "non-standard nonce size")
+		return cipher.NewGCMWithNonceSize(&noGCM{c}, nonceSize)
+	}
+	if tagSize != gcmTagSize {
+		recordFallback(cryptometrics.AESGCM, "non-standard tag size")
+		return cipher.NewGCMWithTagSize(&noGCM{c}, tagSize)
+	}
+	return c.newGCM(false)
+}
+
+// NewGCMTLS returns an AES-GCM AEAD for TLS 1.2, which panics if the
+// explicit part of the nonce, its last 8 bytes, doesn't increase between
+// calls to Seal.
+func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
+	return c.(*aesCipher).newGCM(true)
+}
+
+func (c *aesCipher) newGCM(tls bool) (cipher.AEAD, error) {
+	c.checkOpen()
+	key, err := newSymmetricKey(aesGCMAlg, c.key)
+	if err != nil {
+		return nil, err
+	}
+	g := &gcm{key: key, tls: tls}
+	// Note: Because of the finalizer, any time g.key is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(g),
+	// to make sure g is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(g, (*gcm).finalize)
+	return g, nil
+}
+
+func (g *gcm) finalize() {
+	bcryptDestroyKey(g.key)
+}
+
+// Close destroys the key object of g, which CNG zeroes, immediately
+// rather than when g is garbage collected. g must not be used afterwards.
+func (g *gcm) Close() error {
+	if g.key != 0 {
+		runtime.SetFinalizer(g, nil)
+		g.finalize()
+		g.key = 0
+	}
+	return nil
+}
+
+func (g *gcm) checkOpen() {
+	if g.key == 0 {
+		panic("crypto/cipher: use of closed GCM")
+	}
+}
+
+func (g *gcm) NonceSize() int { return gcmStandardNonceSize }
+func (g *gcm) Overhead() int  { return gcmTagSize }
+
+// modeInfo returns the authenticated cipher mode information of a message.
+func modeInfo(nonce, additionalData, tag []byte) *bcryptAuthenticatedCipherModeInfo {
+	return &bcryptAuthenticatedCipherModeInfo{
+		Size:        uint32(unsafe.Sizeof(bcryptAuthenticatedCipherModeInfo{})),
+		InfoVersion: bcryptAuthModeInfoVersion,
+		Nonce:       &nonce[0],
+		NNonce:      uint32(len(nonce)),
+		AuthData:    base(additionalData),
+		NAuthData:   len32(additionalData),
+		Tag:         &tag[0],
+		NTag:        uint32(len(tag)),
+	}
+}
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	g.checkOpen()
+	if len(nonce) != gcmStandardNonceSize {
+		panic("cipher: incorrect nonce length given to GCM")
+	}
+	if uint64(len(plaintext)) > ((1<<32)-2)*aesBlockSize || len(plaintext)+gcmTagSize < len(plaintext) {
+		panic("cipher: message too large for GCM")
+	}
+	if len(dst)+len(plaintext)+gcmTagSize < len(dst) {
+		panic("cipher: message too large for buffer")
+	}
+	if g.tls {
+		counter := uint64(0)
+		for _, b := range nonce[gcmStandardNonceSize-8:] {
+			counter = counter<<8 | uint64(b)
+		}
+		g.mu.Lock()
+		ok := counter >= g.next && counter != 1<<64-1
+		if ok {
+			g.next = counter + 1
+		}
+		g.mu.Unlock()
+		if !ok {
+			panic("crypto/cipher: TLS GCM nonce not increasing")
+		}
+	}
+
+	// Make room in dst to append plaintext+overhead.
+	n := len(dst)
+	for cap(dst) < n+len(plaintext)+gcmTagSize {
+		dst = append(dst[:cap(dst)], 0)
+	}
+	dst = dst[:n+len(plaintext)+gcmTagSize]
+
+	// Check delayed until now to make sure len(dst) is accurate.
+	if inexactOverlap(dst[n:], plaintext) {
+		panic("cipher: invalid buffer overlap")
+	}
+
+	countOp(cryptometrics.AESGCM, len(plaintext))
+	out := dst[n : n+len(plaintext)]
+	info := modeInfo(nonce, additionalData, dst[n+len(plaintext):])
+	var m uint32
+	s := bcryptEncrypt(g.key, base(plaintext), len32(plaintext), unsafe.Pointer(info), nil, 0, base(out), len32(out), &m, 0)
+	runtime.KeepAlive(g)
+	if s != statusSuccess {
+		panic(newFail("BCryptEncrypt", s))
+	}
+	return dst
+}
+
+var errOpen = errors.New("cipher: message authentication failed")
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	g.checkOpen()
+	if len(nonce) != gcmStandardNonceSize {
+		panic("cipher: incorrect nonce length given to GCM")
+	}
+	countOp(cryptometrics.AESGCM, len(ciphertext))
+	if len(ciphertext) < gcmTagSize {
+		countFailure(cryptometrics.AESGCM)
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > ((1<<32)-2)*aesBlockSize+gcmTagSize {
+		countFailure(cryptometrics.AESGCM)
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-gcmTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-gcmTagSize]
+
+	// Make room in dst to append ciphertext without tag.
+	n := len(dst)
+	for cap(dst) < n+len(ciphertext) {
+		dst = append(dst[:cap(dst)], 0)
+	}
+	dst = dst[:n+len(ciphertext)]
+
+	// Check delayed until now to make sure len(dst) is accurate.
+	if inexactOverlap(dst[n:], ciphertext) {
+		panic("cipher: invalid buffer overlap")
+	}
+
+	info := modeInfo(nonce, additionalData, tag)
+	var m uint32
+	s := bcryptDecrypt(g.key, base(ciphertext), len32(ciphertext), unsafe.Pointer(info), nil, 0, base(dst[n:]), len32(dst[n:]), &m, 0)
+	runtime.KeepAlive(g)
+	if s != statusSuccess {
+		countFailure(cryptometrics.AESGCM)
+		clear(dst[n:])
+		if s != statusAuthTagMismatch {
+			panic(newFail("BCryptDecrypt", s))
+		}
+		return nil, errOpen
+	}
+	return dst, nil
+}
+
+func anyOverlap(x, y []byte) bool {
+	return len(x) > 0 && len(y) > 0 &&
+		uintptr(unsafe.Pointer(&x[0])) <= uintptr(unsafe.Pointer(&y[len(y)-1])) &&
+		uintptr(unsafe.Pointer(&y[0])) <= uintptr(unsafe.Pointer(&x[len(x)-1]))
+}
+
+func inexactOverlap(x, y []byte) bool {
+	if len(x) == 0 || len(y) == 0 || &x[0] == &y[0] {
+		return false
+	}
+	return anyOverlap(x, y)
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"errors"
+	"math/bits"
+	"strconv"
+	"unsafe"
+)
+
+// Failure classes of the errors returned by this package, as in
+// crypto/internal/boring.
+var (
+	ErrBackendFailure       = errors.New("cng: backend failure")
+	ErrUnsupportedParameter = errors.New("cng: unsupported parameter")
+)
+
+var version string
+
+// Init opens the algorithm providers used by the package. It must be
+// called, and succeed, before any other function.
+func Init() error {
+	var major, minor, build uint32
+	rtlGetNtVersionNumbers(&major, &minor, &build)
+	version = "Windows " + strconv.Itoa(int(major)) + "." + strconv.Itoa(int(minor)) +
+		"." + strconv.Itoa(int(build&0xffff))
+	for _, p := range providers {
+		if err := p.open(); err != nil {
+			return err
+		}
+	}
+	return initAES()
+}
+
+// Version returns the version of Windows, whose bcrypt.dll implements the
+// algorithms, such as "Windows 10.0.19045".
+func Version() string { return version }
+
+// FIPS reports whether the system FIPS policy is enabled, which restricts
+// CNG to the approved algorithms.
+func FIPS() bool {
+	var enabled uint8
+	return bcryptGetFipsAlgorithmMode(&enabled) == statusSuccess && enabled != 0
+}
+
+// A provider is a CNG algorithm provider, opened once by Init.
+type provider struct {
+	id    string // the CNG algorithm identifier
+	flags uint32
+	h     bcryptHandle
+}
+
+var (
+	sha1Alg       = &provider{id: "SHA1"}
+	sha256Alg     = &provider{id: "SHA256"}
+	sha384Alg     = &provider{id: "SHA384"}
+	sha512Alg     = &provider{id: "SHA512"}
+	hmacSHA1Alg   = &provider{id: "SHA1", flags: bcryptAlgHandleHMACFlag}
+	hmacSHA256Alg = &provider{id: "SHA256", flags: bcryptAlgHandleHMACFlag}
+	hmacSHA384Alg = &provider{id: "SHA384", flags: bcryptAlgHandleHMACFlag}
+	hmacSHA512Alg = &provider{id: "SHA512", flags: bcryptAlgHandleHMACFlag}
+	aesECBAlg     = &provider{id: "AES"}
+	aesCBCAlg     = &provider{id: "AES"}
+	aesGCMAlg     = &provider{id: "AES"}
+	rsaAlg        = &provider{id: "RSA"}
+	ecdsaP256Alg  = &provider{id: "ECDSA_P256"}
+	ecdsaP384Alg  = &provider{id: "ECDSA_P384"}
+	ecdsaP521Alg  = &provider{id: "ECDSA_P521"}
+	ecdhP256Alg   = &provider{id: "ECDH_P256"}
+	ecdhP384Alg   = &provider{id: "ECDH_P384"}
+	ecdhP521Alg   = &provider{id: "ECDH_P521"}
+)
+
+var providers = []*provider{
+	sha1Alg, sha256Alg, sha384Alg, sha512Alg,
+	hmacSHA1Alg, hmacSHA256Alg, hmacSHA384Alg, hmacSHA512Alg,
+	aesECBAlg, aesCBCAlg, aesGCMAlg,
+	rsaAlg,
+	ecdsaP256Alg, ecdsaP384Alg, ecdsaP521Alg,
+	ecdhP256Alg, ecdhP384Alg, ecdhP521Alg,
+}
+
+func (p *provider) open() error {
+	if s := bcryptOpenAlgorithmProvider(&p.h, utf16(p.id), nil, p.flags); s != statusSuccess {
+		return newFail("BCryptOpenAlgorithmProvider("+p.id+")", s)
+	}
+	return nil
+}
+
+// fail is a failed CNG call.
+type fail struct {
+	fn     string
+	status ntStatus
+}
+
+func (e *fail) Error() string {
+	return "cng: " + e.fn + " failed: " + e.status.String()
+}
+
+func (e *fail) Unwrap() error {
+	if e.status == statusNotSupported {
+		return ErrUnsupportedParameter
+	}
+	return ErrBackendFailure
+}
+
+// newFail returns an error for a call to fn that returned s.
+func newFail(fn string, s ntStatus) error {
+	return &fail{fn: fn, status: s}
+}
+
+// unsupported is an error about a parameter that the package does not
+// support.
+type unsupported string
+
+func (e unsupported) Error() string { return string(e) }
+
+func (e unsupported) Unwrap() error { return ErrUnsupportedParameter }
+
+// base returns the address of the underlying array in b,
+// being careful not to panic when b has zero length.
+func base(b []byte) *byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return &b[0]
+}
+
+// len32 returns len(b), for the CNG functions which take 32-bit lengths.
+// The callers check that b is small enough, or split it.
+func len32(b []byte) uint32 {
+	if uint64(len(b)) > 1<<32-1 {
+		panic("cng: buffer too large")
+	}
+	return uint32(len(b))
+}
+
+type randReader int
+
+func (randReader) Read(b []byte) (int, error) {
+	for p := b; len(p) > 0; {
+		n := len(p)
+		if n > 1<<30 {
+			n = 1 << 30
+		}
+		if s := bcryptGenRandom(0, base(p), uint32(n), bcryptUseSystemPreferredRNG); s != statusSuccess {
+			return 0, newFail("BCryptGenRandom", s)
+		}
+		p = p[n:]
+	}
+	return len(b), nil
+}
+
+const RandReader = randReader(0)
+
+// bigToBytes returns x as a big-endian byte slice of the given size, or
+// of its minimal size if size is zero.
+func bigToBytes(x BigInt, size int) []byte {
+	if size == 0 {
+		size = (bigBitLen(x) + 7) / 8
+	}
+	b := make([]byte, size)
+	for i, w := range x {
+		for j := 0; j < bits.UintSize/8; j++ {
+			if k := size - 1 - i*bits.UintSize/8 - j; k >= 0 {
+				b[k] = byte(w >> (8 * j))
+			}
+		}
+	}
+	return b
+}
+
+// bigBitLen returns the length of x in bits.
+func bigBitLen(x BigInt) int {
+	for i := len(x) - 1; i >= 0; i-- {
+		if x[i] != 0 {
+			return i*bits.UintSize + bits.Len(x[i])
+		}
+	}
+	return 0
+}
+
+// bytesToBig returns the big-endian b as a BigInt.
+func bytesToBig(b []byte) BigInt {
+	x := make(BigInt, (len(b)+bits.UintSize/8-1)/(bits.UintSize/8))
+	for i, c := range b {
+		j := len(b) - 1 - i // byte index, least significant first
+		x[j/(bits.UintSize/8)] |= uint(c) << (8 * (j % (bits.UintSize / 8)))
+	}
+	return x
+}
+
+// exportKey returns the blobType blob of key.
+func exportKey(key bcryptHandle, blobType string) ([]byte, error) {
+	t := utf16(blobType)
+	var n uint32
+	if s := bcryptExportKey(key, 0, t, nil, 0, &n, 0); s != statusSuccess {
+		return nil, newFail("BCryptExportKey", s)
+	}
+	b := make([]byte, n)
+	if s := bcryptExportKey(key, 0, t, base(b), n, &n, 0); s != statusSuccess {
+		clear(b)
+		return nil, newFail("BCryptExportKey", s)
+	}
+	return b[:n], nil
+}
+
+// importKey imports the blobType blob b with the provider p.
+func importKey(p *provider, blobType string, b []byte) (bcryptHandle, error) {
+	var key bcryptHandle
+	if s := bcryptImportKeyPair(p.h, 0, utf16(blobType), &key, base(b), len32(b), 0); s != statusSuccess {
+		return 0, newFail("BCryptImportKeyPair", s)
+	}
+	return key, nil
+}
+
+// blobHeader returns a pointer to the header of the key blob b, which must
+// be large enough to hold it.
+func blobHeader[T any](b []byte) *T {
+	var h T
+	_ = b[unsafe.Sizeof(h)-1]
+	return (*T)(unsafe.Pointer(&b[0]))
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+// Most functionality in this package is tested through crypto/internal/boring
+// by the tests of the crypto packages. The tests shared by all backends are in
+// backendtest, and the tests here check what is specific to CNG.
+
+package cng
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/internal/backend/internal/backendtest"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if err := Init(); err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSHA256(t *testing.T) { backendtest.TestSHA256(t, SHA256, NewSHA256) }
+func TestHMAC(t *testing.T)   { backendtest.TestHMAC(t, NewHMAC, NewSHA256) }
+func TestAESGCM(t *testing.T) { backendtest.TestAESGCM(t, NewAESCipher) }
+func TestAESCBC(t *testing.T) { backendtest.TestAESCBC(t, NewAESCipher) }
+
+// TestAESHandleReuse interleaves the modes of one cipher. Each mode opens
+// its own key on the shared algorithm provider of the mode, so that the
+// chaining mode of one never leaks into another.
+func TestAESHandleReuse(t *testing.T) {
+	key := decodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	iv := decodeHex(t, "000102030405060708090a0b0c0d0e0f")
+	block := decodeHex(t, "6bc1bee22e409f96e93d7e117393172a")
+	wantECB := decodeHex(t, "3ad77bb40d7a3660a89ecaf32466ef97") // SP 800-38A, F.1.1
+	wantCBC := decodeHex(t, "7649abac8119b246cee98e9b12e9197d") // SP 800-38A, F.2.1
+
+	c, err := NewAESCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := c.(interface {
+		NewCBCEncrypter(iv []byte) cipher.BlockMode
+		NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+	})
+	for i := 0; i < 3; i++ {
+		g, err := modes.NewGCM(12, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sealed := g.Seal(nil, make([]byte, 12), block, nil)
+		got := make([]byte, 16)
+		modes.NewCBCEncrypter(iv).CryptBlocks(got, block)
+		if !bytes.Equal(got, wantCBC) {
+			t.Errorf("CBC encryption after GCM = %x, want %x", got, wantCBC)
+		}
+		c.Encrypt(got, block)
+		if !bytes.Equal(got, wantECB) {
+			t.Errorf("block encryption after CBC = %x, want %x", got, wantECB)
+		}
+		if opened, err := g.Open(nil, make([]byte, 12), sealed, nil); err != nil || !bytes.Equal(opened, block) {
+			t.Errorf("GCM Open after CBC = %x, %v, want %x", opened, err, block)
+		}
+	}
+}
+
+// TestHashHandleReuse hashes concurrently with the algorithm providers
+// shared by the package.
+func TestHashHandleReuse(t *testing.T) {
+	want := SHA256([]byte("abc"))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h := NewSHA256()
+				h.Write([]byte("abc"))
+				if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+					t.Errorf("SHA256(abc) = %x, want %x", got, want)
+					return
+				}
+				m := NewHMAC(NewSHA256, []byte("key"))
+				m.Write([]byte("abc"))
+				m.Sum(nil)
+				m.(interface{ Close() error }).Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRSA(t *testing.T) {
+	priv, pub := backendtest.TestRSA(t, backendtest.RSA[BigInt, *PrivateKeyRSA, *PublicKeyRSA]{
+		GenerateKey:    GenerateKeyRSA,
+		NewPrivateKey:  NewPrivateKeyRSA,
+		NewPublicKey:   NewPublicKeyRSA,
+		SignPKCS1v15:   SignRSAPKCS1v15,
+		VerifyPKCS1v15: VerifyRSAPKCS1v15,
+		SignPSS:        SignRSAPSS,
+		VerifyPSS:      VerifyRSAPSS,
+		EncryptOAEP:    EncryptRSAOAEP,
+		DecryptOAEP:    DecryptRSAOAEP,
+		NewSHA256:      NewSHA256,
+	})
+	defer priv.Close()
+
+	// CNG imports full private key blobs only, and checks their CRT values.
+	N, E, D, P, Q, Dp, Dq, _, err := GenerateKeyRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPrivateKeyRSA(N, E, D, nil, nil, nil, nil, nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPrivateKeyRSA without CRT values: %v, want ErrUnsupportedParameter", err)
+	}
+	if _, err := NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, BigInt{42}); err == nil {
+		t.Error("NewPrivateKeyRSA with a wrong Qinv succeeded")
+	}
+
+	// CNG signs with any explicit salt length.
+	hashed := SHA256([]byte("hello"))
+	for _, saltLen := range []int{0, 10} {
+		sig, err := SignRSAPSS(priv, crypto.SHA256, hashed[:], saltLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyRSAPSS(pub, crypto.SHA256, hashed[:], sig, saltLen); err != nil {
+			t.Errorf("VerifyRSAPSS with salt length %d: %v", saltLen, err)
+		}
+	}
+
+	ciphertext, err := EncryptRSAOAEP(NewSHA256(), NewSHA256(), pub, []byte("msg"), []byte("label"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := DecryptRSAOAEP(NewSHA256(), NewSHA256(), priv, ciphertext, []byte("label"))
+	if err != nil || string(msg) != "msg" {
+		t.Errorf("DecryptRSAOAEP = %q, %v, want %q", msg, err, "msg")
+	}
+	if _, err := EncryptRSAOAEP(NewSHA256(), NewSHA1(), pub, []byte("msg"), nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("EncryptRSAOAEP with a different MGF1 hash: %v, want ErrUnsupportedParameter", err)
+	}
+}
+
+func TestECDSA(t *testing.T) {
+	backendtest.TestECDSA(t, backendtest.ECDSA[BigInt, *PrivateKeyECDSA, *PublicKeyECDSA]{
+		GenerateKey:   GenerateKeyECDSA,
+		NewPrivateKey: NewPrivateKeyECDSA,
+		NewPublicKey:  NewPublicKeyECDSA,
+		Sign:          SignMarshalECDSA,
+		Verify:        VerifyECDSA,
+	}, "P-256", "P-384", "P-521")
+	if _, err := NewPublicKeyECDSA("P-224", BigInt{1}, BigInt{2}); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPublicKeyECDSA(P-224): %v, want ErrUnsupportedParameter", err)
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	// r and s are two bytes long, as they would be on a 16-bit curve.
+	for _, tt := range []struct{ rs, der string }{
+		{"0001007f", "300602010102017f"},
+		{"008000ff", "300802020080020200ff"},
+	} {
+		rs, der := decodeHex(t, tt.rs), decodeHex(t, tt.der)
+		if got := encodeSignature(rs[:2], rs[2:]); !bytes.Equal(got, der) {
+			t.Errorf("encodeSignature(%s) = %x, want %x", tt.rs, got, der)
+		}
+		if got, ok := decodeSignature(der, 2); !ok || !bytes.Equal(got, rs) {
+			t.Errorf("decodeSignature(%s) = %x, %v, want %s", tt.der, got, ok, tt.rs)
+		}
+	}
+	for _, der := range []string{
+		"3006020101020180",     // negative s
+		"300702020001020101",   // r not minimally encoded
+		"300602010102010100",   // trailing data
+		"308106020101020101",   // length not minimally encoded
+		"30080203010000020101", // r longer than the field
+	} {
+		if _, ok := decodeSignature(decodeHex(t, der), 2); ok {
+			t.Errorf("decodeSignature(%s) succeeded", der)
+		}
+	}
+}
+
+func TestECDH(t *testing.T) {
+	backendtest.TestECDH(t, backendtest.ECDH[*PrivateKeyECDH, *PublicKeyECDH]{
+		GenerateKey:   GenerateKeyECDH,
+		NewPrivateKey: NewPrivateKeyECDH,
+		ECDH:          ECDH,
+	}, "P-256", "P-384", "P-521")
+}
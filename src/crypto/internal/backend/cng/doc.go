@@ -0,0 +1,19 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cng provides access to the cryptographic implementations of
+// Windows Cryptography API: Next Generation (CNG), through bcrypt.dll. It
+// is the backend of crypto/internal/boring when the cngcrypto GOEXPERIMENT
+// is enabled on windows, and otherwise it is empty.
+//
+// The CNG primitives are the platform's FIPS 140 validated module, and
+// they enforce the approved algorithms when the system FIPS policy is
+// enabled. CNG doesn't implement SHA-224, SHA-512/224, SHA-512/256 or the
+// P-224 curve, for which the crypto packages use the pure Go
+// implementations, or reject the keys. The hashes keep their state in
+// opaque CNG objects, so they don't implement encoding.BinaryMarshaler.
+package cng
+
+// A BigInt is the raw words from a BigInt, as in crypto/internal/boring.
+type BigInt []uint
@@ -0,0 +1,165 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+type PublicKeyECDH struct {
+	curve *ecCurve
+	key   bcryptHandle
+	bytes []byte
+}
+
+func (k *PublicKeyECDH) finalize() {
+	bcryptDestroyKey(k.key)
+}
+
+type PrivateKeyECDH struct {
+	curve  *ecCurve
+	key    bcryptHandle
+	pub    []byte // the encoding of the public key
+	closed bool
+}
+
+func (k *PrivateKeyECDH) finalize() {
+	bcryptDestroyKey(k.key)
+}
+
+// Close destroys the key, which CNG zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyECDH) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
+	if len(bytes) < 1 {
+		return nil, errors.New("NewPublicKeyECDH: missing key")
+	}
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	// CNG only imports uncompressed points.
+	if len(bytes) != 1+2*c.size || bytes[0] != 4 {
+		return nil, errors.New("point not on curve")
+	}
+	key, err := importKey(c.ecdh, eccPublicBlob, c.eccBlob(c.ecdhPub, bytes[1:]))
+	if err != nil {
+		return nil, errors.New("point not on curve")
+	}
+	k := &PublicKeyECDH{c, key, append([]byte(nil), bytes...)}
+	// Note: Because of the finalizer, any time k.key is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(k, (*PublicKeyECDH).finalize)
+	return k, nil
+}
+
+func (k *PublicKeyECDH) Bytes() []byte { return k.bytes }
+
+func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) != c.size {
+		return nil, errors.New("NewPrivateKeyECDH: invalid key size")
+	}
+	// CNG derives the public key of a private key blob whose coordinates
+	// are zero, which it then exports.
+	zero := make([]byte, c.size)
+	blob := c.eccBlob(c.ecdhPriv, zero, zero, bytes)
+	key, err := importKey(c.ecdh, eccPrivateBlob, blob)
+	clear(blob)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := exportKey(key, eccPublicBlob)
+	if err != nil {
+		bcryptDestroyKey(key)
+		return nil, err
+	}
+	coords, err := c.eccCoords(pub, 2)
+	if err != nil {
+		bcryptDestroyKey(key)
+		return nil, err
+	}
+	k := &PrivateKeyECDH{curve: c, key: key, pub: append(append([]byte{4}, coords[0]...), coords[1]...)}
+	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
+	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
+	return k, nil
+}
+
+func (k *PrivateKeyECDH) PublicKey() (*PublicKeyECDH, error) {
+	checkOpen(k.closed)
+	return NewPublicKeyECDH(k.curve.name, k.pub)
+}
+
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) (_ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+	checkOpen(priv.closed)
+
+	var secret bcryptHandle
+	s := bcryptSecretAgreement(priv.key, pub.key, &secret, 0)
+	runtime.KeepAlive(priv)
+	runtime.KeepAlive(pub)
+	if s != statusSuccess {
+		return nil, newFail("BCryptSecretAgreement", s)
+	}
+	defer bcryptDestroySecret(secret)
+	// BCRYPT_KDF_RAW_SECRET, "TRUNCATE", returns the shared X coordinate,
+	// in little-endian order.
+	kdf := utf16("TRUNCATE")
+	var n uint32
+	if s := bcryptDeriveKey(secret, kdf, nil, nil, 0, &n, 0); s != statusSuccess {
+		return nil, newFail("BCryptDeriveKey", s)
+	}
+	out := make([]byte, n)
+	if s := bcryptDeriveKey(secret, kdf, nil, &out[0], n, &n, 0); s != statusSuccess {
+		return nil, newFail("BCryptDeriveKey", s)
+	}
+	out = out[:n]
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+func GenerateKeyECDH(curve string) (_ *PrivateKeyECDH, _ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, blob, err := c.generateKey(c.ecdh)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(blob)
+	coords, err := c.eccCoords(blob, 3)
+	if err != nil {
+		bcryptDestroyKey(key)
+		return nil, nil, err
+	}
+	k := &PrivateKeyECDH{curve: c, key: key, pub: append(append([]byte{4}, coords[0]...), coords[1]...)}
+	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
+	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
+	return k, append([]byte(nil), coords[2]...), nil
+}
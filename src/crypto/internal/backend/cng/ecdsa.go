@@ -0,0 +1,297 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+	"unsafe"
+)
+
+const (
+	eccPublicBlob  = "ECCPUBLICBLOB"
+	eccPrivateBlob = "ECCPRIVATEBLOB"
+)
+
+// An ecCurve is a curve of CNG, with the providers and the key blob magic
+// numbers of ECDSA and ECDH. CNG uses distinct algorithms, and keys, for
+// the two.
+type ecCurve struct {
+	name      string
+	bits      int // the bit size of the field
+	size      int // the byte size of the field
+	ecdsa     *provider
+	ecdh      *provider
+	ecdsaPub  uint32
+	ecdsaPriv uint32
+	ecdhPub   uint32
+	ecdhPriv  uint32
+}
+
+var (
+	curveP256 = &ecCurve{"P-256", 256, 32, ecdsaP256Alg, ecdhP256Alg, 0x31534345, 0x32534345, 0x314B4345, 0x324B4345}
+	curveP384 = &ecCurve{"P-384", 384, 48, ecdsaP384Alg, ecdhP384Alg, 0x33534345, 0x34534345, 0x334B4345, 0x344B4345}
+	curveP521 = &ecCurve{"P-521", 521, 66, ecdsaP521Alg, ecdhP521Alg, 0x35534345, 0x36534345, 0x354B4345, 0x364B4345}
+)
+
+var errUnknownCurve = unsupported("cng: unknown elliptic curve")
+
+// curveByName returns the curve named curve. CNG doesn't implement P-224.
+func curveByName(curve string) (*ecCurve, error) {
+	switch curve {
+	case "P-256":
+		return curveP256, nil
+	case "P-384":
+		return curveP384, nil
+	case "P-521":
+		return curveP521, nil
+	}
+	return nil, errUnknownCurve
+}
+
+// eccBlob returns the key blob with magic and the coordinates, each of the
+// field size, that follow its header.
+func (c *ecCurve) eccBlob(magic uint32, coords ...[]byte) []byte {
+	hdr := int(unsafe.Sizeof(bcryptECCKeyBlob{}))
+	blob := make([]byte, hdr, hdr+len(coords)*c.size)
+	*blobHeader[bcryptECCKeyBlob](blob) = bcryptECCKeyBlob{Magic: magic, KeySize: uint32(c.size)}
+	for _, x := range coords {
+		blob = append(blob, x...)
+	}
+	return blob
+}
+
+// eccCoords returns the coordinates, each of the field size, that follow
+// the header of the key blob b.
+func (c *ecCurve) eccCoords(b []byte, n int) ([][]byte, error) {
+	b = b[unsafe.Sizeof(bcryptECCKeyBlob{}):]
+	if len(b) != n*c.size {
+		return nil, errors.New("cng: invalid EC key blob")
+	}
+	coords := make([][]byte, n)
+	for i := range coords {
+		coords[i], b = b[:c.size:c.size], b[c.size:]
+	}
+	return coords, nil
+}
+
+// generateKey returns a new key of the provider p, on curve c, and the key
+// exported as an ECCPRIVATEBLOB.
+func (c *ecCurve) generateKey(p *provider) (bcryptHandle, []byte, error) {
+	var key bcryptHandle
+	if s := bcryptGenerateKeyPair(p.h, &key, uint32(c.bits), 0); s != statusSuccess {
+		return 0, nil, newFail("BCryptGenerateKeyPair", s)
+	}
+	if s := bcryptFinalizeKeyPair(key, 0); s != statusSuccess {
+		bcryptDestroyKey(key)
+		return 0, nil, newFail("BCryptFinalizeKeyPair", s)
+	}
+	blob, err := exportKey(key, eccPrivateBlob)
+	if err != nil {
+		bcryptDestroyKey(key)
+		return 0, nil, err
+	}
+	return key, blob, nil
+}
+
+type PrivateKeyECDSA struct {
+	key    bcryptHandle
+	curve  *ecCurve
+	closed bool
+}
+
+func (k *PrivateKeyECDSA) finalize() {
+	bcryptDestroyKey(k.key)
+}
+
+// Close destroys the key, which CNG zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyECDSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+type PublicKeyECDSA struct {
+	key   bcryptHandle
+	curve *ecCurve
+}
+
+func (k *PublicKeyECDSA) finalize() {
+	bcryptDestroyKey(k.key)
+}
+
+func NewPublicKeyECDSA(curve string, X, Y BigInt) (*PublicKeyECDSA, error) {
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	key, err := importKey(c.ecdsa, eccPublicBlob, c.eccBlob(c.ecdsaPub, bigToBytes(X, c.size), bigToBytes(Y, c.size)))
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKeyECDSA{key, c}
+	// Note: Because of the finalizer, any time k.key is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(k, (*PublicKeyECDSA).finalize)
+	return k, nil
+}
+
+func NewPrivateKeyECDSA(curve string, X, Y BigInt, D BigInt) (*PrivateKeyECDSA, error) {
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	d := bigToBytes(D, c.size)
+	blob := c.eccBlob(c.ecdsaPriv, bigToBytes(X, c.size), bigToBytes(Y, c.size), d)
+	clear(d)
+	key, err := importKey(c.ecdsa, eccPrivateBlob, blob)
+	clear(blob)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyECDSA{key: key, curve: c}
+	// Note: Because of the finalizer, any time k.key is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(k, (*PrivateKeyECDSA).finalize)
+	return k, nil
+}
+
+// truncateHash truncates hash to the size of the field of c. The orders of
+// P-256 and P-384 are as long as their fields, and no supported hash is
+// longer than the order of P-521, so this is the truncation of FIPS 186-4.
+func (c *ecCurve) truncateHash(hash []byte) []byte {
+	if len(hash) > c.size {
+		return hash[:c.size]
+	}
+	return hash
+}
+
+// SignMarshalECDSA signs hash, which is truncated to the size of the
+// curve, and returns the ASN.1 DER encoded signature.
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) (_ []byte, err error) {
+	countOp(cryptometrics.ECDSA, len(hash))
+	defer countResult(cryptometrics.ECDSA, &err)
+	checkOpen(priv.closed)
+
+	sig, err := signHash(priv.key, 0, nil, priv.curve.truncateHash(hash))
+	runtime.KeepAlive(priv)
+	if err != nil {
+		return nil, err
+	}
+	// CNG returns r and s, each of the field size.
+	return encodeSignature(sig[:len(sig)/2], sig[len(sig)/2:]), nil
+}
+
+func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	countOp(cryptometrics.ECDSA, len(hash))
+	rs, ok := decodeSignature(sig, pub.curve.size)
+	ok = ok && verifySignature(pub.key, 0, nil, pub.curve.truncateHash(hash), rs) == nil
+	runtime.KeepAlive(pub)
+	if !ok {
+		countFailure(cryptometrics.ECDSA)
+	}
+	return ok
+}
+
+func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	countOp(cryptometrics.ECDSA, 0)
+	defer countResult(cryptometrics.ECDSA, &err)
+
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, blob, err := c.generateKey(c.ecdsa)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bcryptDestroyKey(key)
+	defer clear(blob)
+	coords, err := c.eccCoords(blob, 3)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return bytesToBig(coords[0]), bytesToBig(coords[1]), bytesToBig(coords[2]), nil
+}
+
+// encodeSignature returns the ASN.1 DER encoding of the ECDSA signature
+// (r, s), whose big-endian values are unsigned.
+func encodeSignature(r, s []byte) []byte {
+	body := appendASN1Int(appendASN1Int(nil, r), s)
+	sig := []byte{0x30} // SEQUENCE
+	if len(body) >= 0x80 {
+		sig = append(sig, 0x81)
+	}
+	sig = append(sig, byte(len(body)))
+	return append(sig, body...)
+}
+
+// appendASN1Int appends the minimal ASN.1 DER encoding of the unsigned
+// big-endian x, which is shorter than 127 bytes, to b.
+func appendASN1Int(b, x []byte) []byte {
+	for len(x) > 1 && x[0] == 0 {
+		x = x[1:]
+	}
+	if len(x) == 0 || x[0]&0x80 != 0 {
+		b = append(b, 0x02, byte(len(x)+1), 0)
+	} else {
+		b = append(b, 0x02, byte(len(x)))
+	}
+	return append(b, x...)
+}
+
+// decodeSignature returns r and s, each of size bytes, from the ASN.1 DER
+// encoded ECDSA signature sig. It reports whether sig is a valid encoding.
+func decodeSignature(sig []byte, size int) ([]byte, bool) {
+	if len(sig) < 2 || sig[0] != 0x30 {
+		return nil, false
+	}
+	n, body := int(sig[1]), sig[2:]
+	if n == 0x81 && len(body) > 0 && body[0] >= 0x80 {
+		n, body = int(body[0]), body[1:]
+	} else if n >= 0x80 {
+		return nil, false
+	}
+	if len(body) != n {
+		return nil, false
+	}
+	rs := make([]byte, 2*size)
+	for i := 0; i < 2; i++ {
+		if len(body) < 2 || body[0] != 0x02 {
+			return nil, false
+		}
+		n := int(body[1])
+		if n == 0 || n >= 0x80 || len(body) < 2+n {
+			return nil, false
+		}
+		x := body[2 : 2+n]
+		body = body[2+n:]
+		if x[0]&0x80 != 0 { // negative
+			return nil, false
+		}
+		if len(x) > 1 && x[0] == 0 {
+			if x[1]&0x80 == 0 { // not minimal
+				return nil, false
+			}
+			x = x[1:]
+		}
+		if len(x) > size {
+			return nil, false
+		}
+		copy(rs[(i+1)*size-len(x):], x)
+	}
+	return rs, len(body) == 0
+}
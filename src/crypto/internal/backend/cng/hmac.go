@@ -0,0 +1,103 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+// NewHMAC returns a new HMAC using CNG.
+// The function h must return a hash implemented by
+// this package (for example, h could be cng.NewSHA256).
+// If h is not recognized, NewHMAC returns nil.
+func NewHMAC(h func() hash.Hash, key []byte) hash.Hash {
+	ch, ok := h().(*cngHash)
+	if !ok {
+		return nil
+	}
+	hm := &cngHMAC{d: ch.d}
+	// CNG copies the key into the HMAC object, and restarts with it when a
+	// reusable object is finished.
+	if s := bcryptCreateHash(ch.d.hmac.h, &hm.h, nil, 0, base(key), len32(key), bcryptHashReusableFlag); s != statusSuccess {
+		panic(newFail("BCryptCreateHash", s))
+	}
+	// Note: Because of the finalizer, any time hm.h is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(hm),
+	// to make sure hm is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(hm, (*cngHMAC).finalize)
+	return hm
+}
+
+// A cngHMAC is an HMAC backed by a reusable CNG hash object, which holds
+// its own copy of the key.
+type cngHMAC struct {
+	d *digest
+	h bcryptHandle
+}
+
+func (h *cngHMAC) finalize() {
+	bcryptDestroyHash(h.h)
+}
+
+// Close destroys the HMAC object, which CNG zeroes, immediately rather
+// than when h is garbage collected. h must not be used afterwards.
+func (h *cngHMAC) Close() error {
+	if h.h != 0 {
+		runtime.SetFinalizer(h, nil)
+		h.finalize()
+		h.h = 0
+	}
+	return nil
+}
+
+func (h *cngHMAC) checkOpen() {
+	if h.h == 0 {
+		panic("cng: use of closed HMAC")
+	}
+}
+
+func (h *cngHMAC) Size() int      { return h.d.size }
+func (h *cngHMAC) BlockSize() int { return h.d.blockSize }
+
+func (h *cngHMAC) Reset() {
+	h.checkOpen()
+	var out [64]byte // the largest digest size
+	if s := bcryptFinishHash(h.h, &out[0], uint32(h.d.size), 0); s != statusSuccess {
+		panic(newFail("BCryptFinishHash", s))
+	}
+	runtime.KeepAlive(h)
+}
+
+func (h *cngHMAC) Write(p []byte) (int, error) {
+	h.checkOpen()
+	countBytes(cryptometrics.HMAC, len(p))
+	hashData(h.h, p)
+	runtime.KeepAlive(h)
+	return len(p), nil
+}
+
+func (h *cngHMAC) Sum(in []byte) []byte {
+	h.checkOpen()
+	countOp(cryptometrics.HMAC, 0)
+	defer runtime.KeepAlive(h)
+	return sumDuplicate(h.h, h.d.size, in)
+}
+
+// Clone returns an independent copy of h.
+func (h *cngHMAC) Clone() (hash.Hash, error) {
+	h.checkOpen()
+	c := &cngHMAC{d: h.d}
+	if s := bcryptDuplicateHash(h.h, &c.h, nil, 0, 0); s != statusSuccess {
+		return nil, newFail("BCryptDuplicateHash", s)
+	}
+	runtime.KeepAlive(h)
+	runtime.SetFinalizer(c, (*cngHMAC).finalize)
+	return c, nil
+}
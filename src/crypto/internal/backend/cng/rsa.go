@@ -0,0 +1,489 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+	"unsafe"
+)
+
+const (
+	rsaPublicBlob      = "RSAPUBLICBLOB"
+	rsaFullPrivateBlob = "RSAFULLPRIVATEBLOB"
+)
+
+func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	countOp(cryptometrics.RSA, 0)
+	defer countResult(cryptometrics.RSA, &err)
+
+	bad := func(e error) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+		return nil, nil, nil, nil, nil, nil, nil, nil, e
+	}
+
+	var key bcryptHandle
+	if s := bcryptGenerateKeyPair(rsaAlg.h, &key, uint32(bits), 0); s != statusSuccess {
+		return bad(newFail("BCryptGenerateKeyPair", s))
+	}
+	defer bcryptDestroyKey(key)
+	if s := bcryptFinalizeKeyPair(key, 0); s != statusSuccess {
+		return bad(newFail("BCryptFinalizeKeyPair", s))
+	}
+	blob, err := exportKey(key, rsaFullPrivateBlob)
+	if err != nil {
+		return bad(err)
+	}
+	defer clear(blob)
+	h := blobHeader[bcryptRSAKeyBlob](blob)
+	// The header is followed by E, N, P, Q, Dp, Dq, Qinv and D.
+	sizes := [8]uint32{h.PublicExpSize, h.ModulusSize, h.Prime1Size, h.Prime2Size,
+		h.Prime1Size, h.Prime2Size, h.Prime1Size, h.ModulusSize}
+	var out [8]BigInt
+	b := blob[unsafe.Sizeof(*h):]
+	for i, n := range sizes {
+		if uint32(len(b)) < n {
+			return bad(errors.New("cng: invalid RSA key blob"))
+		}
+		out[i], b = bytesToBig(b[:n]), b[n:]
+	}
+	return out[1], out[0], out[7], out[2], out[3], out[4], out[5], out[6], nil
+}
+
+type PublicKeyRSA struct {
+	key  bcryptHandle
+	bits int
+}
+
+func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
+	e, n := bigToBytes(E, 0), bigToBytes(N, 0)
+	blob := make([]byte, unsafe.Sizeof(bcryptRSAKeyBlob{}), int(unsafe.Sizeof(bcryptRSAKeyBlob{}))+len(e)+len(n))
+	*blobHeader[bcryptRSAKeyBlob](blob) = bcryptRSAKeyBlob{
+		Magic:         bcryptRSAPublicMagic,
+		BitLength:     uint32(bigBitLen(N)),
+		PublicExpSize: uint32(len(e)),
+		ModulusSize:   uint32(len(n)),
+	}
+	blob = append(append(blob, e...), n...)
+	key, err := importKey(rsaAlg, rsaPublicBlob, blob)
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKeyRSA{key: key, bits: bigBitLen(N)}
+	// Note: Because of the finalizer, any time k.key is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(k, (*PublicKeyRSA).finalize)
+	return k, nil
+}
+
+func (k *PublicKeyRSA) finalize() {
+	bcryptDestroyKey(k.key)
+}
+
+type PrivateKeyRSA struct {
+	key    bcryptHandle
+	bits   int
+	closed bool
+}
+
+var errRSAConsistency = errors.New("cng: RSA private key failed the pairwise consistency test")
+
+// NewPrivateKeyRSA returns a private key with two primes and their CRT
+// values, which CNG requires. It returns an error wrapping
+// ErrUnsupportedParameter for other keys, which crypto/rsa passes with nil
+// CRT values.
+func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
+	if P == nil || Q == nil || Dp == nil || Dq == nil || Qinv == nil {
+		return nil, unsupported("cng: RSA private keys without two primes and their CRT values are not supported")
+	}
+	e, n := bigToBytes(E, 0), bigToBytes(N, 0)
+	p, q := bigToBytes(P, 0), bigToBytes(Q, 0)
+	hdr := int(unsafe.Sizeof(bcryptRSAKeyBlob{}))
+	blob := make([]byte, hdr, hdr+len(e)+2*len(n)+3*len(p)+2*len(q))
+	defer func() { clear(blob[:cap(blob)]) }()
+	*blobHeader[bcryptRSAKeyBlob](blob) = bcryptRSAKeyBlob{
+		Magic:         bcryptRSAFullPrivateMagic,
+		BitLength:     uint32(bigBitLen(N)),
+		PublicExpSize: uint32(len(e)),
+		ModulusSize:   uint32(len(n)),
+		Prime1Size:    uint32(len(p)),
+		Prime2Size:    uint32(len(q)),
+	}
+	blob = append(blob, e...)
+	blob = append(blob, n...)
+	blob = append(blob, p...)
+	blob = append(blob, q...)
+	for _, x := range []struct {
+		v    BigInt
+		size int
+	}{{Dp, len(p)}, {Dq, len(q)}, {Qinv, len(p)}, {D, len(n)}} {
+		if bigBitLen(x.v) > 8*x.size {
+			return nil, errors.New("cng: invalid RSA private key")
+		}
+		b := bigToBytes(x.v, x.size)
+		blob = append(blob, b...)
+		clear(b)
+	}
+	clear(p)
+	clear(q)
+	key, err := importKey(rsaAlg, rsaFullPrivateBlob, blob)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyRSA{key: key, bits: bigBitLen(N)}
+	// Note: Because of the finalizer, any time k.key is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(k, (*PrivateKeyRSA).finalize)
+	// CNG doesn't check the values of the blob, so check them once here,
+	// as BoringCrypto does on every operation.
+	if err := k.checkConsistency(); err != nil {
+		k.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+// checkConsistency checks that the private values invert E, by decrypting
+// the encryption of a small message.
+func (k *PrivateKeyRSA) checkConsistency() error {
+	msg := make([]byte, (k.bits+7)/8)
+	msg[len(msg)-1] = 2
+	c, err := rsaCrypt(true, k.key, bcryptPadNone, nil, msg)
+	if err != nil {
+		return err
+	}
+	m, err := rsaCrypt(false, k.key, bcryptPadNone, nil, c)
+	runtime.KeepAlive(k)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(m, msg) {
+		return errRSAConsistency
+	}
+	return nil
+}
+
+func (k *PrivateKeyRSA) finalize() {
+	bcryptDestroyKey(k.key)
+}
+
+// Close destroys the key, which CNG zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyRSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+// checkOpen panics if a private key has been closed, since its CNG object
+// has been destroyed.
+func checkOpen(closed bool) {
+	if closed {
+		panic("cng: use of closed private key")
+	}
+}
+
+// cryptoHashToDigest returns the digest of ch, or nil.
+func cryptoHashToDigest(ch crypto.Hash) *digest {
+	switch ch {
+	case crypto.SHA1:
+		return sha1MD
+	case crypto.SHA256:
+		return sha256MD
+	case crypto.SHA384:
+		return sha384MD
+	case crypto.SHA512:
+		return sha512MD
+	}
+	return nil
+}
+
+var errUnsupportedHash = unsupported("cng: unsupported hash function")
+
+// rsaCrypt encrypts, if enc is true, or decrypts in with key, using the
+// padding and its information info.
+func rsaCrypt(enc bool, key bcryptHandle, padding uint32, info unsafe.Pointer, in []byte) ([]byte, error) {
+	fn, name := bcryptDecrypt, "BCryptDecrypt"
+	if enc {
+		fn, name = bcryptEncrypt, "BCryptEncrypt"
+	}
+	var n uint32
+	if s := fn(key, base(in), len32(in), info, nil, 0, nil, 0, &n, padding); s != statusSuccess {
+		return nil, newFail(name, s)
+	}
+	out := make([]byte, n)
+	if s := fn(key, base(in), len32(in), info, nil, 0, base(out), n, &n, padding); s != statusSuccess {
+		return nil, newFail(name, s)
+	}
+	return out[:n], nil
+}
+
+// oaepInfo returns the OAEP padding information for h and label. CNG uses
+// the same hash for OAEP and MGF1.
+func oaepInfo(h, mgfHash hash.Hash, label []byte) (unsafe.Pointer, error) {
+	hc, ok := h.(*cngHash)
+	if !ok {
+		return nil, errUnsupportedHash
+	}
+	if mc, ok := mgfHash.(*cngHash); mgfHash != nil && (!ok || mc.d != hc.d) {
+		return nil, unsupported("cng: OAEP with a different MGF1 hash is not supported")
+	}
+	return unsafe.Pointer(&bcryptOAEPPaddingInfo{AlgID: utf16(hc.d.p.id), Label: base(label), NLabel: len32(label)}), nil
+}
+
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP
+// support h, mgfHash and label.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool {
+	_, err := oaepInfo(h, mgfHash, label)
+	return err == nil
+}
+
+func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(ciphertext))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+	info, err := oaepInfo(h, mgfHash, label)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(priv)
+	return rsaCrypt(false, priv.key, bcryptPadOAEP, info, ciphertext)
+}
+
+func EncryptRSAOAEP(h, mgfHash hash.Hash, pub *PublicKeyRSA, msg, label []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(msg))
+	defer countResult(cryptometrics.RSA, &err)
+	info, err := oaepInfo(h, mgfHash, label)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(pub)
+	return rsaCrypt(true, pub.key, bcryptPadOAEP, info, msg)
+}
+
+func DecryptRSAPKCS1(priv *PrivateKeyRSA, ciphertext []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(ciphertext))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+	defer runtime.KeepAlive(priv)
+	return rsaCrypt(false, priv.key, bcryptPadPKCS1, nil, ciphertext)
+}
+
+func EncryptRSAPKCS1(pub *PublicKeyRSA, msg []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(msg))
+	defer countResult(cryptometrics.RSA, &err)
+	defer runtime.KeepAlive(pub)
+	return rsaCrypt(true, pub.key, bcryptPadPKCS1, nil, msg)
+}
+
+func DecryptRSANoPadding(priv *PrivateKeyRSA, ciphertext []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(ciphertext))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+	defer runtime.KeepAlive(priv)
+	return rsaCrypt(false, priv.key, bcryptPadNone, nil, ciphertext)
+}
+
+func EncryptRSANoPadding(pub *PublicKeyRSA, msg []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(msg))
+	defer countResult(cryptometrics.RSA, &err)
+	defer runtime.KeepAlive(pub)
+	return rsaCrypt(true, pub.key, bcryptPadNone, nil, msg)
+}
+
+// signHash signs hashed with key, using the padding and its information
+// info. Callers must keep key alive.
+func signHash(key bcryptHandle, padding uint32, info unsafe.Pointer, hashed []byte) ([]byte, error) {
+	var n uint32
+	if s := bcryptSignHash(key, info, base(hashed), len32(hashed), nil, 0, &n, padding); s != statusSuccess {
+		return nil, newFail("BCryptSignHash", s)
+	}
+	out := make([]byte, n)
+	if s := bcryptSignHash(key, info, base(hashed), len32(hashed), base(out), n, &n, padding); s != statusSuccess {
+		return nil, newFail("BCryptSignHash", s)
+	}
+	return out[:n], nil
+}
+
+// verifySignature verifies sig over hashed with key, using the padding and
+// its information info. Callers must keep key alive.
+func verifySignature(key bcryptHandle, padding uint32, info unsafe.Pointer, hashed, sig []byte) error {
+	if s := bcryptVerifySignature(key, info, base(hashed), len32(hashed), base(sig), len32(sig), padding); s != statusSuccess {
+		return newFail("BCryptVerifySignature", s)
+	}
+	return nil
+}
+
+var invalidSaltLenErr = errors.New("crypto/rsa: PSSOptions.SaltLength cannot be negative")
+
+// pssInfo returns the PSS padding information for h and a salt length,
+// which CNG needs explicitly.
+func pssInfo(h crypto.Hash, saltLen int) (unsafe.Pointer, error) {
+	d := cryptoHashToDigest(h)
+	if d == nil {
+		return nil, errUnsupportedHash
+	}
+	return unsafe.Pointer(&bcryptPSSPaddingInfo{AlgID: utf16(d.p.id), Salt: uint32(saltLen)}), nil
+}
+
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+
+	// crypto/rsa uses -1 for a salt as long as the hash, and 0 for the
+	// maximal salt when signing.
+	switch {
+	case saltLen < -1:
+		return nil, invalidSaltLenErr
+	case saltLen == -1:
+		saltLen = len(hashed)
+	case saltLen == 0:
+		saltLen = (priv.bits-1+7)/8 - len(hashed) - 2
+		if saltLen < 0 {
+			return nil, errors.New("crypto/rsa: key size too small for PSS signature")
+		}
+	}
+	info, err := pssInfo(h, saltLen)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(priv)
+	return signHash(priv.key, bcryptPadPSS, info, hashed)
+}
+
+// VerifyRSAPSS verifies a PSS signature. CNG needs the salt length, so a
+// saltLen of 0, for any salt, is first read from the encoded message of
+// sig.
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+	defer runtime.KeepAlive(pub)
+
+	switch {
+	case saltLen < -1:
+		return invalidSaltLenErr
+	case saltLen == -1:
+		saltLen = len(hashed)
+	case saltLen == 0:
+		if saltLen, err = pub.pssSaltLen(h, sig); err != nil {
+			return err
+		}
+	}
+	info, err := pssInfo(h, saltLen)
+	if err != nil {
+		return err
+	}
+	return verifySignature(pub.key, bcryptPadPSS, info, hashed, sig)
+}
+
+var errVerification = errors.New("cng: verification error")
+
+// pssSaltLen returns the salt length of the PSS signature sig, following
+// the steps of the EMSA-PSS verification in RFC 8017, Section 9.1.2. It
+// returns an error if sig is not a PSS signature with h, whose digest is
+// checked by CNG. Callers must keep k alive.
+func (k *PublicKeyRSA) pssSaltLen(h crypto.Hash, sig []byte) (int, error) {
+	d := cryptoHashToDigest(h)
+	if d == nil {
+		return 0, errUnsupportedHash
+	}
+	if len(sig) != (k.bits+7)/8 {
+		return 0, errVerification
+	}
+	m, err := rsaCrypt(true, k.key, bcryptPadNone, nil, sig)
+	if err != nil {
+		return 0, err
+	}
+	emBits := k.bits - 1
+	emLen := (emBits + 7) / 8
+	em := m[len(m)-emLen:]
+	if (len(m) > emLen && m[0] != 0) || emLen < d.size+2 || em[emLen-1] != 0xbc {
+		return 0, errVerification
+	}
+	db := em[:emLen-d.size-1]
+	H := em[emLen-d.size-1 : emLen-1]
+	// db ^= MGF1(H), using counter as the suffix of the MGF1 input.
+	in := append(append([]byte(nil), H...), 0, 0, 0, 0)
+	counter := in[len(H):]
+	var mask [64]byte
+	for i := 0; i < len(db); i += d.size {
+		d.sum(mask[:], in)
+		for j := 0; j < d.size && i+j < len(db); j++ {
+			db[i+j] ^= mask[j]
+		}
+		for j := 3; j >= 0; j-- {
+			if counter[j]++; counter[j] != 0 {
+				break
+			}
+		}
+	}
+	db[0] &= 0xff >> (8*emLen - emBits)
+	i := 0
+	for i < len(db) && db[i] == 0 {
+		i++
+	}
+	if i == len(db) || db[i] != 1 {
+		return 0, errVerification
+	}
+	return len(db) - i - 1, nil
+}
+
+// pkcs1Info returns the PKCS #1 v1.5 padding information for h, or for no
+// DigestInfo prefix if h is zero or MD5SHA1.
+func pkcs1Info(h crypto.Hash) (unsafe.Pointer, error) {
+	info := new(bcryptPKCS1PaddingInfo)
+	switch h {
+	case 0, crypto.MD5SHA1:
+	case crypto.MD5:
+		info.AlgID = utf16("MD5")
+	default:
+		d := cryptoHashToDigest(h)
+		if d == nil {
+			return nil, errUnsupportedHash
+		}
+		info.AlgID = utf16(d.p.id)
+	}
+	return unsafe.Pointer(info), nil
+}
+
+// SignRSAPKCS1v15 signs hashed, the digest of the message with h, or the
+// message itself if h is zero.
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+
+	info, err := pkcs1Info(h)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(priv)
+	return signHash(priv.key, bcryptPadPKCS1, info, hashed)
+}
+
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	info, err := pkcs1Info(h)
+	if err != nil {
+		return err
+	}
+	defer runtime.KeepAlive(pub)
+	return verifySignature(pub.key, bcryptPadPKCS1, info, hashed, sig)
+}
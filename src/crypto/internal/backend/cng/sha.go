@@ -0,0 +1,174 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !cmd_go_bootstrap
+
+package cng
+
+import (
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+// A digest is a hash algorithm of CNG, with the providers of the hash and
+// of its HMAC.
+type digest struct {
+	alg       int // internal/cryptometrics identifier
+	p, hmac   *provider
+	size      int
+	blockSize int
+}
+
+var (
+	sha1MD   = &digest{alg: cryptometrics.SHA1, p: sha1Alg, hmac: hmacSHA1Alg, size: 20, blockSize: 64}
+	sha256MD = &digest{alg: cryptometrics.SHA256, p: sha256Alg, hmac: hmacSHA256Alg, size: 32, blockSize: 64}
+	sha384MD = &digest{alg: cryptometrics.SHA384, p: sha384Alg, hmac: hmacSHA384Alg, size: 48, blockSize: 128}
+	sha512MD = &digest{alg: cryptometrics.SHA512, p: sha512Alg, hmac: hmacSHA512Alg, size: 64, blockSize: 128}
+)
+
+// sum writes the digest of p to out, which must be large enough to hold it.
+func (d *digest) sum(out, p []byte) {
+	_ = out[d.size-1]
+	countOp(d.alg, len(p))
+	var h bcryptHandle
+	if s := bcryptCreateHash(d.p.h, &h, nil, 0, nil, 0, 0); s != statusSuccess {
+		panic(newFail("BCryptCreateHash", s))
+	}
+	defer bcryptDestroyHash(h)
+	hashData(h, p)
+	if s := bcryptFinishHash(h, &out[0], uint32(d.size), 0); s != statusSuccess {
+		panic(newFail("BCryptFinishHash", s))
+	}
+}
+
+// hashData writes p to the hash or HMAC h.
+func hashData(h bcryptHandle, p []byte) {
+	for len(p) > 0 {
+		// BCryptHashData takes a 32-bit length.
+		n := len(p)
+		if n > 1<<30 {
+			n = 1 << 30
+		}
+		if s := bcryptHashData(h, &p[0], uint32(n), 0); s != statusSuccess {
+			panic(newFail("BCryptHashData", s))
+		}
+		p = p[n:]
+	}
+}
+
+func SHA1(p []byte) (sum [20]byte)   { sha1MD.sum(sum[:], p); return }
+func SHA256(p []byte) (sum [32]byte) { sha256MD.sum(sum[:], p); return }
+func SHA384(p []byte) (sum [48]byte) { sha384MD.sum(sum[:], p); return }
+func SHA512(p []byte) (sum [64]byte) { sha512MD.sum(sum[:], p); return }
+
+// SHA1Into, SHA256Into, SHA384Into, and SHA512Into write the digest of p
+// to the beginning of dst, which must be large enough to hold it.
+
+func SHA1Into(dst, p []byte)   { sha1MD.sum(dst, p) }
+func SHA256Into(dst, p []byte) { sha256MD.sum(dst, p) }
+func SHA384Into(dst, p []byte) { sha384MD.sum(dst, p) }
+func SHA512Into(dst, p []byte) { sha512MD.sum(dst, p) }
+
+// SHA256Batch returns the SHA-256 digests of msgs.
+func SHA256Batch(msgs [][]byte) [][32]byte {
+	sums := make([][32]byte, len(msgs))
+	for i, m := range msgs {
+		sha256MD.sum(sums[i][:], m)
+	}
+	return sums
+}
+
+func NewSHA1() hash.Hash   { return newCNGHash(sha1MD) }
+func NewSHA256() hash.Hash { return newCNGHash(sha256MD) }
+func NewSHA384() hash.Hash { return newCNGHash(sha384MD) }
+func NewSHA512() hash.Hash { return newCNGHash(sha512MD) }
+
+// A cngHash is a hash.Hash backed by a reusable CNG hash object, which
+// CNG allocates and zeroes. Its state can't be marshaled.
+type cngHash struct {
+	d *digest
+	h bcryptHandle
+}
+
+func newCNGHash(d *digest) *cngHash {
+	h := &cngHash{d: d}
+	if s := bcryptCreateHash(d.p.h, &h.h, nil, 0, nil, 0, bcryptHashReusableFlag); s != statusSuccess {
+		panic(newFail("BCryptCreateHash", s))
+	}
+	// Note: Because of the finalizer, any time h.h is passed to CNG,
+	// that call must be followed by a call to runtime.KeepAlive(h),
+	// to make sure h is not collected (and finalized) before the call
+	// returns.
+	runtime.SetFinalizer(h, (*cngHash).finalize)
+	return h
+}
+
+func (h *cngHash) finalize() {
+	bcryptDestroyHash(h.h)
+}
+
+func (h *cngHash) Size() int      { return h.d.size }
+func (h *cngHash) BlockSize() int { return h.d.blockSize }
+
+// Reset finishes the reusable hash into a scratch buffer, which restarts
+// it.
+func (h *cngHash) Reset() {
+	var out [64]byte // the largest digest size
+	if s := bcryptFinishHash(h.h, &out[0], uint32(h.d.size), 0); s != statusSuccess {
+		panic(newFail("BCryptFinishHash", s))
+	}
+	runtime.KeepAlive(h)
+}
+
+// Wipe resets the state of h, which CNG zeroes.
+func (h *cngHash) Wipe() { h.Reset() }
+
+func (h *cngHash) Write(p []byte) (int, error) {
+	countBytes(h.d.alg, len(p))
+	hashData(h.h, p)
+	runtime.KeepAlive(h)
+	return len(p), nil
+}
+
+func (h *cngHash) WriteString(s string) (int, error) {
+	return h.Write([]byte(s))
+}
+
+func (h *cngHash) WriteByte(c byte) error {
+	h.Write([]byte{c})
+	return nil
+}
+
+func (h *cngHash) Sum(in []byte) []byte {
+	countOp(h.d.alg, 0)
+	return sumDuplicate(h.h, h.d.size, in)
+}
+
+// sumDuplicate appends the digest of a duplicate of the hash or HMAC h, of
+// size bytes, to in, so that writes to h can continue. Callers must keep h
+// alive.
+func sumDuplicate(h bcryptHandle, size int, in []byte) []byte {
+	var dup bcryptHandle
+	if s := bcryptDuplicateHash(h, &dup, nil, 0, 0); s != statusSuccess {
+		panic(newFail("BCryptDuplicateHash", s))
+	}
+	defer bcryptDestroyHash(dup)
+	var out [64]byte // the largest digest size
+	if s := bcryptFinishHash(dup, &out[0], uint32(size), 0); s != statusSuccess {
+		panic(newFail("BCryptFinishHash", s))
+	}
+	return append(in, out[:size]...)
+}
+
+// Clone returns an independent copy of h.
+func (h *cngHash) Clone() (hash.Hash, error) {
+	c := &cngHash{d: h.d}
+	if s := bcryptDuplicateHash(h.h, &c.h, nil, 0, 0); s != statusSuccess {
+		return nil, newFail("BCryptDuplicateHash", s)
+	}
+	runtime.KeepAlive(h)
+	runtime.SetFinalizer(c, (*cngHash).finalize)
+	return c, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cng
+
+import (
+	"strconv"
+	"syscall"
+)
+
+// An ntStatus is the NTSTATUS returned by the CNG functions, which is zero
+// on success.
+type ntStatus uint32
+
+const (
+	statusSuccess          ntStatus = 0x00000000
+	statusInvalidSignature ntStatus = 0xC000A000
+	statusAuthTagMismatch  ntStatus = 0xC000A002
+	statusNotSupported     ntStatus = 0xC00000BB
+	statusInvalidParameter ntStatus = 0xC000000D
+)
+
+func (s ntStatus) String() string {
+	return "NTSTATUS 0x" + strconv.FormatUint(uint64(s), 16)
+}
+
+// A bcryptHandle is a BCRYPT_HANDLE: an algorithm provider, hash, key or
+// secret.
+type bcryptHandle uintptr
+
+// Algorithm identifiers, property names and key blob types are UTF-16
+// strings in CNG; utf16 converts the constant s.
+func utf16(s string) *uint16 {
+	p, err := syscall.UTF16PtrFromString(s)
+	if err != nil {
+		panic("cng: invalid constant " + s)
+	}
+	return p
+}
+
+const (
+	bcryptUseSystemPreferredRNG = 0x00000002
+	bcryptAlgHandleHMACFlag     = 0x00000008
+	bcryptHashReusableFlag      = 0x00000020
+
+	bcryptPadNone  = 0x00000001
+	bcryptPadPKCS1 = 0x00000002
+	bcryptPadOAEP  = 0x00000004
+	bcryptPadPSS   = 0x00000008
+
+	bcryptRSAPublicMagic      = 0x31415352 // "RSA1"
+	bcryptRSAFullPrivateMagic = 0x33415352 // "RSA3"
+
+	bcryptAuthModeInfoVersion = 1
+)
+
+// bcryptRSAKeyBlob is BCRYPT_RSAKEY_BLOB, the header of the RSA key blobs.
+// It is followed by the big-endian public exponent and modulus and, in
+// BCRYPT_RSAFULLPRIVATE_BLOB, by P, Q, Dp, Dq, Qinv and D.
+type bcryptRSAKeyBlob struct {
+	Magic         uint32
+	BitLength     uint32
+	PublicExpSize uint32
+	ModulusSize   uint32
+	Prime1Size    uint32
+	Prime2Size    uint32
+}
+
+// bcryptECCKeyBlob is BCRYPT_ECCKEY_BLOB, the header of the elliptic curve
+// key blobs. It is followed by the big-endian X and Y and, in
+// BCRYPT_ECCPRIVATE_BLOB, by D, each KeySize bytes long.
+type bcryptECCKeyBlob struct {
+	Magic   uint32
+	KeySize uint32
+}
+
+type bcryptPKCS1PaddingInfo struct {
+	AlgID *uint16
+}
+
+type bcryptPSSPaddingInfo struct {
+	AlgID *uint16
+	Salt  uint32
+}
+
+type bcryptOAEPPaddingInfo struct {
+	AlgID  *uint16
+	Label  *byte
+	NLabel uint32
+}
+
+// bcryptAuthenticatedCipherModeInfo is
+// BCRYPT_AUTHENTICATED_CIPHER_MODE_INFO, for the GCM encryptions.
+type bcryptAuthenticatedCipherModeInfo struct {
+	Size        uint32
+	InfoVersion uint32
+	Nonce       *byte
+	NNonce      uint32
+	AuthData    *byte
+	NAuthData   uint32
+	Tag         *byte
+	NTag        uint32
+	MacContext  *byte
+	NMacContext uint32
+	AAD         uint32
+	Data        uint64
+	Flags       uint32
+}
+
+//sys	bcryptOpenAlgorithmProvider(alg *bcryptHandle, algID *uint16, implementation *uint16, flags uint32) (s ntStatus) = bcrypt.BCryptOpenAlgorithmProvider
+//sys	bcryptSetProperty(obj bcryptHandle, property *uint16, input *byte, inputLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptSetProperty
+//sys	bcryptGetProperty(obj bcryptHandle, property *uint16, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) = bcrypt.BCryptGetProperty
+//sys	bcryptGetFipsAlgorithmMode(enabled *uint8) (s ntStatus) = bcrypt.BCryptGetFipsAlgorithmMode
+//sys	bcryptGenRandom(alg bcryptHandle, buf *byte, bufLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptGenRandom
+//sys	bcryptCreateHash(alg bcryptHandle, hash *bcryptHandle, hashObject *byte, hashObjectLen uint32, secret *byte, secretLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptCreateHash
+//sys	bcryptHashData(hash bcryptHandle, input *byte, inputLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptHashData
+//sys	bcryptFinishHash(hash bcryptHandle, output *byte, outputLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptFinishHash
+//sys	bcryptDuplicateHash(hash bcryptHandle, newHash *bcryptHandle, hashObject *byte, hashObjectLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptDuplicateHash
+//sys	bcryptDestroyHash(hash bcryptHandle) (s ntStatus) = bcrypt.BCryptDestroyHash
+//sys	bcryptGenerateSymmetricKey(alg bcryptHandle, key *bcryptHandle, keyObject *byte, keyObjectLen uint32, secret *byte, secretLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptGenerateSymmetricKey
+//sys	bcryptGenerateKeyPair(alg bcryptHandle, key *bcryptHandle, length uint32, flags uint32) (s ntStatus) = bcrypt.BCryptGenerateKeyPair
+//sys	bcryptFinalizeKeyPair(key bcryptHandle, flags uint32) (s ntStatus) = bcrypt.BCryptFinalizeKeyPair
+//sys	bcryptImportKeyPair(alg bcryptHandle, importKey bcryptHandle, blobType *uint16, key *bcryptHandle, input *byte, inputLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptImportKeyPair
+//sys	bcryptExportKey(key bcryptHandle, exportKey bcryptHandle, blobType *uint16, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) = bcrypt.BCryptExportKey
+//sys	bcryptDestroyKey(key bcryptHandle) (s ntStatus) = bcrypt.BCryptDestroyKey
+//sys	bcryptEncrypt(key bcryptHandle, input *byte, inputLen uint32, paddingInfo unsafe.Pointer, iv *byte, ivLen uint32, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) = bcrypt.BCryptEncrypt
+//sys	bcryptDecrypt(key bcryptHandle, input *byte, inputLen uint32, paddingInfo unsafe.Pointer, iv *byte, ivLen uint32, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) = bcrypt.BCryptDecrypt
+//sys	bcryptSignHash(key bcryptHandle, paddingInfo unsafe.Pointer, input *byte, inputLen uint32, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) = bcrypt.BCryptSignHash
+//sys	bcryptVerifySignature(key bcryptHandle, paddingInfo unsafe.Pointer, hash *byte, hashLen uint32, sig *byte, sigLen uint32, flags uint32) (s ntStatus) = bcrypt.BCryptVerifySignature
+//sys	bcryptSecretAgreement(privKey bcryptHandle, pubKey bcryptHandle, secret *bcryptHandle, flags uint32) (s ntStatus) = bcrypt.BCryptSecretAgreement
+//sys	bcryptDeriveKey(secret bcryptHandle, kdf *uint16, params unsafe.Pointer, derivedKey *byte, derivedKeyLen uint32, result *uint32, flags uint32) (s ntStatus) = bcrypt.BCryptDeriveKey
+//sys	bcryptDestroySecret(secret bcryptHandle) (s ntStatus) = bcrypt.BCryptDestroySecret
+//sys	rtlGetNtVersionNumbers(major *uint32, minor *uint32, build *uint32) = ntdll.RtlGetNtVersionNumbers
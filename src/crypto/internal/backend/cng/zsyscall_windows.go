@@ -0,0 +1,210 @@
+// Code generated by 'go generate'; DO NOT EDIT.
+
+package cng
+
+import (
+	"internal/syscall/windows/sysdll"
+	"syscall"
+	"unsafe"
+)
+
+var _ unsafe.Pointer
+
+// Do the interface allocations only once for common
+// Errno values.
+const (
+	errnoERROR_IO_PENDING = 997
+)
+
+var (
+	errERROR_IO_PENDING error = syscall.Errno(errnoERROR_IO_PENDING)
+	errERROR_EINVAL     error = syscall.EINVAL
+)
+
+// errnoErr returns common boxed Errno values, to prevent
+// allocations at runtime.
+func errnoErr(e syscall.Errno) error {
+	switch e {
+	case 0:
+		return errERROR_EINVAL
+	case errnoERROR_IO_PENDING:
+		return errERROR_IO_PENDING
+	}
+	// TODO: add more here, after collecting data on the common
+	// error values see on Windows. (perhaps when running
+	// all.bat?)
+	return e
+}
+
+var (
+	modbcrypt = syscall.NewLazyDLL(sysdll.Add("bcrypt.dll"))
+	modntdll  = syscall.NewLazyDLL(sysdll.Add("ntdll.dll"))
+
+	procBCryptCreateHash            = modbcrypt.NewProc("BCryptCreateHash")
+	procBCryptDecrypt               = modbcrypt.NewProc("BCryptDecrypt")
+	procBCryptDeriveKey             = modbcrypt.NewProc("BCryptDeriveKey")
+	procBCryptDestroyHash           = modbcrypt.NewProc("BCryptDestroyHash")
+	procBCryptDestroyKey            = modbcrypt.NewProc("BCryptDestroyKey")
+	procBCryptDestroySecret         = modbcrypt.NewProc("BCryptDestroySecret")
+	procBCryptDuplicateHash         = modbcrypt.NewProc("BCryptDuplicateHash")
+	procBCryptEncrypt               = modbcrypt.NewProc("BCryptEncrypt")
+	procBCryptExportKey             = modbcrypt.NewProc("BCryptExportKey")
+	procBCryptFinalizeKeyPair       = modbcrypt.NewProc("BCryptFinalizeKeyPair")
+	procBCryptFinishHash            = modbcrypt.NewProc("BCryptFinishHash")
+	procBCryptGenRandom             = modbcrypt.NewProc("BCryptGenRandom")
+	procBCryptGenerateKeyPair       = modbcrypt.NewProc("BCryptGenerateKeyPair")
+	procBCryptGenerateSymmetricKey  = modbcrypt.NewProc("BCryptGenerateSymmetricKey")
+	procBCryptGetFipsAlgorithmMode  = modbcrypt.NewProc("BCryptGetFipsAlgorithmMode")
+	procBCryptGetProperty           = modbcrypt.NewProc("BCryptGetProperty")
+	procBCryptHashData              = modbcrypt.NewProc("BCryptHashData")
+	procBCryptImportKeyPair         = modbcrypt.NewProc("BCryptImportKeyPair")
+	procBCryptOpenAlgorithmProvider = modbcrypt.NewProc("BCryptOpenAlgorithmProvider")
+	procBCryptSecretAgreement       = modbcrypt.NewProc("BCryptSecretAgreement")
+	procBCryptSetProperty           = modbcrypt.NewProc("BCryptSetProperty")
+	procBCryptSignHash              = modbcrypt.NewProc("BCryptSignHash")
+	procBCryptVerifySignature       = modbcrypt.NewProc("BCryptVerifySignature")
+	procRtlGetNtVersionNumbers      = modntdll.NewProc("RtlGetNtVersionNumbers")
+)
+
+func bcryptCreateHash(alg bcryptHandle, hash *bcryptHandle, hashObject *byte, hashObjectLen uint32, secret *byte, secretLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptCreateHash.Addr(), uintptr(alg), uintptr(unsafe.Pointer(hash)), uintptr(unsafe.Pointer(hashObject)), uintptr(hashObjectLen), uintptr(unsafe.Pointer(secret)), uintptr(secretLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptDecrypt(key bcryptHandle, input *byte, inputLen uint32, paddingInfo unsafe.Pointer, iv *byte, ivLen uint32, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptDecrypt.Addr(), uintptr(key), uintptr(unsafe.Pointer(input)), uintptr(inputLen), uintptr(paddingInfo), uintptr(unsafe.Pointer(iv)), uintptr(ivLen), uintptr(unsafe.Pointer(output)), uintptr(outputLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptDeriveKey(secret bcryptHandle, kdf *uint16, params unsafe.Pointer, derivedKey *byte, derivedKeyLen uint32, result *uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptDeriveKey.Addr(), uintptr(secret), uintptr(unsafe.Pointer(kdf)), uintptr(params), uintptr(unsafe.Pointer(derivedKey)), uintptr(derivedKeyLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptDestroyHash(hash bcryptHandle) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptDestroyHash.Addr(), uintptr(hash))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptDestroyKey(key bcryptHandle) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptDestroyKey.Addr(), uintptr(key))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptDestroySecret(secret bcryptHandle) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptDestroySecret.Addr(), uintptr(secret))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptDuplicateHash(hash bcryptHandle, newHash *bcryptHandle, hashObject *byte, hashObjectLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptDuplicateHash.Addr(), uintptr(hash), uintptr(unsafe.Pointer(newHash)), uintptr(unsafe.Pointer(hashObject)), uintptr(hashObjectLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptEncrypt(key bcryptHandle, input *byte, inputLen uint32, paddingInfo unsafe.Pointer, iv *byte, ivLen uint32, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptEncrypt.Addr(), uintptr(key), uintptr(unsafe.Pointer(input)), uintptr(inputLen), uintptr(paddingInfo), uintptr(unsafe.Pointer(iv)), uintptr(ivLen), uintptr(unsafe.Pointer(output)), uintptr(outputLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptExportKey(key bcryptHandle, exportKey bcryptHandle, blobType *uint16, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptExportKey.Addr(), uintptr(key), uintptr(exportKey), uintptr(unsafe.Pointer(blobType)), uintptr(unsafe.Pointer(output)), uintptr(outputLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptFinalizeKeyPair(key bcryptHandle, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptFinalizeKeyPair.Addr(), uintptr(key), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptFinishHash(hash bcryptHandle, output *byte, outputLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptFinishHash.Addr(), uintptr(hash), uintptr(unsafe.Pointer(output)), uintptr(outputLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptGenRandom(alg bcryptHandle, buf *byte, bufLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptGenRandom.Addr(), uintptr(alg), uintptr(unsafe.Pointer(buf)), uintptr(bufLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptGenerateKeyPair(alg bcryptHandle, key *bcryptHandle, length uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptGenerateKeyPair.Addr(), uintptr(alg), uintptr(unsafe.Pointer(key)), uintptr(length), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptGenerateSymmetricKey(alg bcryptHandle, key *bcryptHandle, keyObject *byte, keyObjectLen uint32, secret *byte, secretLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptGenerateSymmetricKey.Addr(), uintptr(alg), uintptr(unsafe.Pointer(key)), uintptr(unsafe.Pointer(keyObject)), uintptr(keyObjectLen), uintptr(unsafe.Pointer(secret)), uintptr(secretLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptGetFipsAlgorithmMode(enabled *uint8) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptGetFipsAlgorithmMode.Addr(), uintptr(unsafe.Pointer(enabled)))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptGetProperty(obj bcryptHandle, property *uint16, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptGetProperty.Addr(), uintptr(obj), uintptr(unsafe.Pointer(property)), uintptr(unsafe.Pointer(output)), uintptr(outputLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptHashData(hash bcryptHandle, input *byte, inputLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptHashData.Addr(), uintptr(hash), uintptr(unsafe.Pointer(input)), uintptr(inputLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptImportKeyPair(alg bcryptHandle, importKey bcryptHandle, blobType *uint16, key *bcryptHandle, input *byte, inputLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptImportKeyPair.Addr(), uintptr(alg), uintptr(importKey), uintptr(unsafe.Pointer(blobType)), uintptr(unsafe.Pointer(key)), uintptr(unsafe.Pointer(input)), uintptr(inputLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptOpenAlgorithmProvider(alg *bcryptHandle, algID *uint16, implementation *uint16, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptOpenAlgorithmProvider.Addr(), uintptr(unsafe.Pointer(alg)), uintptr(unsafe.Pointer(algID)), uintptr(unsafe.Pointer(implementation)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptSecretAgreement(privKey bcryptHandle, pubKey bcryptHandle, secret *bcryptHandle, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptSecretAgreement.Addr(), uintptr(privKey), uintptr(pubKey), uintptr(unsafe.Pointer(secret)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptSetProperty(obj bcryptHandle, property *uint16, input *byte, inputLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptSetProperty.Addr(), uintptr(obj), uintptr(unsafe.Pointer(property)), uintptr(unsafe.Pointer(input)), uintptr(inputLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptSignHash(key bcryptHandle, paddingInfo unsafe.Pointer, input *byte, inputLen uint32, output *byte, outputLen uint32, result *uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptSignHash.Addr(), uintptr(key), uintptr(paddingInfo), uintptr(unsafe.Pointer(input)), uintptr(inputLen), uintptr(unsafe.Pointer(output)), uintptr(outputLen), uintptr(unsafe.Pointer(result)), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func bcryptVerifySignature(key bcryptHandle, paddingInfo unsafe.Pointer, hash *byte, hashLen uint32, sig *byte, sigLen uint32, flags uint32) (s ntStatus) {
+	r0, _, _ := syscall.SyscallN(procBCryptVerifySignature.Addr(), uintptr(key), uintptr(paddingInfo), uintptr(unsafe.Pointer(hash)), uintptr(hashLen), uintptr(unsafe.Pointer(sig)), uintptr(sigLen), uintptr(flags))
+	s = ntStatus(r0)
+	return
+}
+
+func rtlGetNtVersionNumbers(major *uint32, minor *uint32, build *uint32) {
+	syscall.SyscallN(procRtlGetNtVersionNumbers.Addr(), uintptr(unsafe.Pointer(major)), uintptr(unsafe.Pointer(minor)), uintptr(unsafe.Pointer(build)))
+	return
+}
@@ -0,0 +1,259 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"crypto/cipher"
+	"internal/cryptometrics"
+	"runtime"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+type aesKeySizeError int
+
+func (k aesKeySizeError) Error() string {
+	return "crypto/aes: invalid key size " + strconv.Itoa(int(k))
+}
+
+const aesBlockSize = 16
+
+// A CCCryptorRef holds the state of a mode, so it can't be used
+// concurrently, even in ECB mode. aesCipher guards its ECB cryptors with
+// mu, so that it can be used concurrently like the other cipher.Blocks.
+type aesCipher struct {
+	key []byte
+
+	mu       sync.Mutex
+	enc, dec ccCryptor
+}
+
+type extraModes interface {
+	// Copied out of crypto/aes/modes.go.
+	NewCBCEncrypter(iv []byte) cipher.BlockMode
+	NewCBCDecrypter(iv []byte) cipher.BlockMode
+	NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+}
+
+var _ extraModes = (*aesCipher)(nil)
+
+// NewAESCipher returns an AES cipher using CommonCrypto. It doesn't
+// implement CTR, which crypto/cipher builds on its blocks.
+func NewAESCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, aesKeySizeError(len(key))
+	}
+	c := &aesCipher{key: append([]byte(nil), key...)}
+	var err error
+	if c.enc, err = ccCryptorCreate(ccEncrypt, ccAlgorithmAES, ccOptionECBMode, c.key, nil); err != nil {
+		return nil, err
+	}
+	if c.dec, err = ccCryptorCreate(ccDecrypt, ccAlgorithmAES, ccOptionECBMode, c.key, nil); err != nil {
+		ccCryptorRelease(c.enc)
+		return nil, err
+	}
+	// Note: Because of the finalizer, any time c.enc or c.dec is passed
+	// to CommonCrypto, that call must be followed by a call to
+	// runtime.KeepAlive(c), to make sure c is not collected (and
+	// finalized) before the call returns.
+	runtime.SetFinalizer(c, (*aesCipher).finalize)
+	return c, nil
+}
+
+func (c *aesCipher) finalize() {
+	ccCryptorRelease(c.enc)
+	ccCryptorRelease(c.dec)
+}
+
+func (c *aesCipher) BlockSize() int { return aesBlockSize }
+
+// Close zeroes the key and releases the cryptors of c, which
+// CommonCrypto zeroes, immediately rather than when c is garbage
+// collected. c must not be used afterwards. The modes created from c hold
+// their own cryptors, and are not affected.
+//
+// Close must not be called concurrently with the other methods of c.
+func (c *aesCipher) Close() error {
+	if c.key != nil {
+		runtime.SetFinalizer(c, nil)
+		c.finalize()
+		clear(c.key)
+		c.key, c.enc, c.dec = nil, 0, 0
+	}
+	return nil
+}
+
+func (c *aesCipher) checkOpen() {
+	if c.key == nil {
+		panic("crypto/aes: use of closed cipher")
+	}
+}
+
+func (c *aesCipher) Encrypt(dst, src []byte) { c.crypt(true, dst, src) }
+func (c *aesCipher) Decrypt(dst, src []byte) { c.crypt(false, dst, src) }
+
+func (c *aesCipher) crypt(enc bool, dst, src []byte) {
+	if len(src) < aesBlockSize {
+		panic("crypto/aes: input not full block")
+	}
+	if len(dst) < aesBlockSize {
+		panic("crypto/aes: output not full block")
+	}
+	if inexactOverlap(dst[:aesBlockSize], src[:aesBlockSize]) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	countOp(cryptometrics.AES, aesBlockSize)
+	c.checkOpen()
+	cryptor := c.dec
+	if enc {
+		cryptor = c.enc
+	}
+	c.mu.Lock()
+	ccCryptorUpdate(cryptor, dst[:aesBlockSize], src[:aesBlockSize])
+	c.mu.Unlock()
+	runtime.KeepAlive(c)
+}
+
+// A cbcMode is a CBC encrypter or decrypter, with its own cryptor, which
+// holds the chaining value. cbcMode doesn't implement XORKeyStream,
+// because callers such as crypto/tls tell block modes and streams apart by
+// their methods.
+type cbcMode struct {
+	cryptor ccCryptor
+}
+
+func (c *aesCipher) newCBC(op uint32, iv []byte) *cbcMode {
+	c.checkOpen()
+	if len(iv) != aesBlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	// Without ccOptionECBMode, the cryptor uses CBC, without padding.
+	cryptor, err := ccCryptorCreate(op, ccAlgorithmAES, 0, c.key, iv)
+	if err != nil {
+		panic(err)
+	}
+	x := &cbcMode{cryptor: cryptor}
+	runtime.SetFinalizer(x, (*cbcMode).finalize)
+	return x
+}
+
+func (c *aesCipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	return c.newCBC(ccEncrypt, iv)
+}
+
+func (c *aesCipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	return c.newCBC(ccDecrypt, iv)
+}
+
+func (x *cbcMode) finalize() {
+	ccCryptorRelease(x.cryptor)
+}
+
+func (x *cbcMode) BlockSize() int { return aesBlockSize }
+
+func (x *cbcMode) CryptBlocks(dst, src []byte) {
+	if len(src)%aesBlockSize != 0 {
+		panic("crypto/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypto/cipher: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	countOp(cryptometrics.AES, len(src))
+	if len(src) > 0 {
+		ccCryptorUpdate(x.cryptor, dst[:len(src)], src)
+	}
+	runtime.KeepAlive(x)
+}
+
+func (x *cbcMode) SetIV(iv []byte) {
+	if len(iv) != aesBlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	ccCryptorReset(x.cryptor, iv)
+	runtime.KeepAlive(x)
+}
+
+const (
+	gcmTagSize           = 16
+	gcmStandardNonceSize = 12
+)
+
+// noGCM hides the NewGCM method of a cipher, so that crypto/cipher
+// implements GCM on top of its blocks.
+type noGCM struct {
+	cipher.Block
+}
+
+// NewGCM returns the GCM implementation of crypto/cipher, on top of the
+// blocks of c. CommonCrypto only implements GCM in private interfaces.
+func (c *aesCipher) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
+	c.checkOpen()
+	recordFallback(cryptometrics.AESGCM, "GCM not implemented by CommonCrypto")
+	if tagSize != gcmTagSize {
+		return cipher.NewGCMWithTagSize(&noGCM{c}, tagSize)
+	}
+	return cipher.NewGCMWithNonceSize(&noGCM{c}, nonceSize)
+}
+
+// NewGCMTLS returns an AES-GCM AEAD for TLS 1.2, which panics if the
+// explicit part of the nonce, its last 8 bytes, doesn't increase between
+// calls to Seal.
+func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
+	g, err := c.(*aesCipher).NewGCM(gcmStandardNonceSize, gcmTagSize)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsGCM{AEAD: g}, nil
+}
+
+// tlsGCM enforces the strictly increasing nonces of TLS 1.2, which
+// FIPS 140-3 requires; next is the smallest valid explicit nonce.
+type tlsGCM struct {
+	cipher.AEAD
+
+	mu   sync.Mutex
+	next uint64
+}
+
+func (g *tlsGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmStandardNonceSize {
+		panic("cipher: incorrect nonce length given to GCM")
+	}
+	counter := uint64(0)
+	for _, b := range nonce[gcmStandardNonceSize-8:] {
+		counter = counter<<8 | uint64(b)
+	}
+	g.mu.Lock()
+	ok := counter >= g.next && counter != 1<<64-1
+	if ok {
+		g.next = counter + 1
+	}
+	g.mu.Unlock()
+	if !ok {
+		panic("crypto/cipher: TLS GCM nonce not increasing")
+	}
+	return g.AEAD.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func anyOverlap(x, y []byte) bool {
+	return len(x) > 0 && len(y) > 0 &&
+		uintptr(unsafe.Pointer(&x[0])) <= uintptr(unsafe.Pointer(&y[len(y)-1])) &&
+		uintptr(unsafe.Pointer(&y[0])) <= uintptr(unsafe.Pointer(&x[len(x)-1]))
+}
+
+func inexactOverlap(x, y []byte) bool {
+	if len(x) == 0 || len(y) == 0 || &x[0] == &y[0] {
+		return false
+	}
+	return anyOverlap(x, y)
+}
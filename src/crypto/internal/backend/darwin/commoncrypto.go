@@ -0,0 +1,215 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"internal/abi"
+	"runtime"
+	"unsafe"
+)
+
+// The CommonCrypto functions are part of libSystem, like the libc
+// functions of package syscall. See
+// https://opensource.apple.com/source/CommonCrypto/ for their headers.
+
+// syscall is implemented in the runtime package (runtime/sys_darwin.go).
+// It calls fn with the C calling convention and returns its result, whose
+// upper bits are unspecified if it is narrower than a uintptr.
+func syscall(fn, a1, a2, a3, a4, a5, a6, a7 uintptr) uintptr
+
+// A ccStatus is a CCCryptorStatus, or a CCRNGStatus, which are zero on
+// success.
+type ccStatus int32
+
+const (
+	ccSuccess       ccStatus = 0
+	ccUnimplemented ccStatus = -4305
+)
+
+// The digest functions of CommonDigest.h. The contexts are C structures
+// allocated by the caller, which hold no pointers, and the functions
+// always return 1.
+
+//go:cgo_import_dynamic darwin_CC_SHA1_Init CC_SHA1_Init "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA1_Update CC_SHA1_Update "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA1_Final CC_SHA1_Final "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA224_Init CC_SHA224_Init "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA224_Update CC_SHA224_Update "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA224_Final CC_SHA224_Final "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA256_Init CC_SHA256_Init "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA256_Update CC_SHA256_Update "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA256_Final CC_SHA256_Final "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA384_Init CC_SHA384_Init "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA384_Update CC_SHA384_Update "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA384_Final CC_SHA384_Final "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA512_Init CC_SHA512_Init "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA512_Update CC_SHA512_Update "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic darwin_CC_SHA512_Final CC_SHA512_Final "/usr/lib/libSystem.B.dylib"
+
+func darwin_CC_SHA1_Init_trampoline()
+func darwin_CC_SHA1_Update_trampoline()
+func darwin_CC_SHA1_Final_trampoline()
+func darwin_CC_SHA224_Init_trampoline()
+func darwin_CC_SHA224_Update_trampoline()
+func darwin_CC_SHA224_Final_trampoline()
+func darwin_CC_SHA256_Init_trampoline()
+func darwin_CC_SHA256_Update_trampoline()
+func darwin_CC_SHA256_Final_trampoline()
+func darwin_CC_SHA384_Init_trampoline()
+func darwin_CC_SHA384_Update_trampoline()
+func darwin_CC_SHA384_Final_trampoline()
+func darwin_CC_SHA512_Init_trampoline()
+func darwin_CC_SHA512_Update_trampoline()
+func darwin_CC_SHA512_Final_trampoline()
+
+// ccDigestFuncs are the addresses of the Init, Update and Final functions
+// of a digest.
+type ccDigestFuncs struct {
+	init, update, final uintptr
+}
+
+func (f *ccDigestFuncs) Init(ctx unsafe.Pointer) {
+	syscall(f.init, uintptr(ctx), 0, 0, 0, 0, 0, 0)
+}
+
+// Update hashes p, which must be shorter than 4 GiB, into ctx.
+func (f *ccDigestFuncs) Update(ctx unsafe.Pointer, p []byte) {
+	syscall(f.update, uintptr(ctx), uintptr(unsafe.Pointer(base(p))), uintptr(len(p)), 0, 0, 0, 0)
+	runtime.KeepAlive(p)
+}
+
+// Final writes the digest of ctx to md, which must be large enough to
+// hold it, and clears ctx.
+func (f *ccDigestFuncs) Final(md []byte, ctx unsafe.Pointer) {
+	syscall(f.final, uintptr(unsafe.Pointer(&md[0])), uintptr(ctx), 0, 0, 0, 0, 0)
+	runtime.KeepAlive(md)
+}
+
+//go:cgo_import_dynamic darwin_CCHmacInit CCHmacInit "/usr/lib/libSystem.B.dylib"
+
+func ccHmacInit(ctx unsafe.Pointer, alg uint32, key []byte) {
+	syscall(abi.FuncPCABI0(darwin_CCHmacInit_trampoline), uintptr(ctx), uintptr(alg),
+		uintptr(unsafe.Pointer(base(key))), uintptr(len(key)), 0, 0, 0)
+	runtime.KeepAlive(key)
+}
+func darwin_CCHmacInit_trampoline()
+
+//go:cgo_import_dynamic darwin_CCHmacUpdate CCHmacUpdate "/usr/lib/libSystem.B.dylib"
+
+func ccHmacUpdate(ctx unsafe.Pointer, p []byte) {
+	syscall(abi.FuncPCABI0(darwin_CCHmacUpdate_trampoline), uintptr(ctx),
+		uintptr(unsafe.Pointer(base(p))), uintptr(len(p)), 0, 0, 0, 0)
+	runtime.KeepAlive(p)
+}
+func darwin_CCHmacUpdate_trampoline()
+
+//go:cgo_import_dynamic darwin_CCHmacFinal CCHmacFinal "/usr/lib/libSystem.B.dylib"
+
+func ccHmacFinal(ctx unsafe.Pointer, mac []byte) {
+	syscall(abi.FuncPCABI0(darwin_CCHmacFinal_trampoline), uintptr(ctx),
+		uintptr(unsafe.Pointer(&mac[0])), 0, 0, 0, 0, 0)
+	runtime.KeepAlive(mac)
+}
+func darwin_CCHmacFinal_trampoline()
+
+// A ccCryptor is a CCCryptorRef, a CommonCrypto cipher context, which it
+// zeroes when it is released.
+type ccCryptor uintptr
+
+const (
+	ccEncrypt = 0 // kCCEncrypt
+	ccDecrypt = 1 // kCCDecrypt
+
+	ccAlgorithmAES = 0 // kCCAlgorithmAES
+
+	ccOptionECBMode = 2 // kCCOptionECBMode
+)
+
+//go:cgo_import_dynamic darwin_CCCryptorCreate CCCryptorCreate "/usr/lib/libSystem.B.dylib"
+
+func ccCryptorCreate(op, alg, options uint32, key, iv []byte) (ccCryptor, error) {
+	var c ccCryptor
+	s := ccStatus(syscall(abi.FuncPCABI0(darwin_CCCryptorCreate_trampoline), uintptr(op), uintptr(alg), uintptr(options),
+		uintptr(unsafe.Pointer(base(key))), uintptr(len(key)), uintptr(unsafe.Pointer(base(iv))), uintptr(unsafe.Pointer(&c))))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(iv)
+	if s != ccSuccess {
+		return 0, newFail("CCCryptorCreate", s)
+	}
+	return c, nil
+}
+func darwin_CCCryptorCreate_trampoline()
+
+//go:cgo_import_dynamic darwin_CCCryptorUpdate CCCryptorUpdate "/usr/lib/libSystem.B.dylib"
+
+// ccCryptorUpdate encrypts or decrypts in to out, which must be as long.
+func ccCryptorUpdate(c ccCryptor, out, in []byte) {
+	var moved uintptr
+	s := ccStatus(syscall(abi.FuncPCABI0(darwin_CCCryptorUpdate_trampoline), uintptr(c),
+		uintptr(unsafe.Pointer(base(in))), uintptr(len(in)),
+		uintptr(unsafe.Pointer(base(out))), uintptr(len(out)), uintptr(unsafe.Pointer(&moved)), 0))
+	runtime.KeepAlive(in)
+	runtime.KeepAlive(out)
+	if s != ccSuccess {
+		panic(newFail("CCCryptorUpdate", s))
+	}
+	if moved != uintptr(len(in)) {
+		panic("darwin: CCCryptorUpdate buffered input")
+	}
+}
+func darwin_CCCryptorUpdate_trampoline()
+
+//go:cgo_import_dynamic darwin_CCCryptorReset CCCryptorReset "/usr/lib/libSystem.B.dylib"
+
+func ccCryptorReset(c ccCryptor, iv []byte) {
+	s := ccStatus(syscall(abi.FuncPCABI0(darwin_CCCryptorReset_trampoline), uintptr(c),
+		uintptr(unsafe.Pointer(base(iv))), 0, 0, 0, 0, 0))
+	runtime.KeepAlive(iv)
+	if s != ccSuccess {
+		panic(newFail("CCCryptorReset", s))
+	}
+}
+func darwin_CCCryptorReset_trampoline()
+
+//go:cgo_import_dynamic darwin_CCCryptorRelease CCCryptorRelease "/usr/lib/libSystem.B.dylib"
+
+func ccCryptorRelease(c ccCryptor) {
+	syscall(abi.FuncPCABI0(darwin_CCCryptorRelease_trampoline), uintptr(c), 0, 0, 0, 0, 0, 0)
+}
+func darwin_CCCryptorRelease_trampoline()
+
+//go:cgo_import_dynamic darwin_CCRandomGenerateBytes CCRandomGenerateBytes "/usr/lib/libSystem.B.dylib"
+
+func ccRandomGenerateBytes(b []byte) error {
+	s := ccStatus(syscall(abi.FuncPCABI0(darwin_CCRandomGenerateBytes_trampoline),
+		uintptr(unsafe.Pointer(base(b))), uintptr(len(b)), 0, 0, 0, 0, 0))
+	runtime.KeepAlive(b)
+	if s != ccSuccess {
+		return newFail("CCRandomGenerateBytes", s)
+	}
+	return nil
+}
+func darwin_CCRandomGenerateBytes_trampoline()
+
+//go:cgo_import_dynamic darwin_sysctlbyname sysctlbyname "/usr/lib/libSystem.B.dylib"
+
+// sysctlString returns the string value of the sysctl name, or "" if it
+// can't be read.
+func sysctlString(name string) string {
+	n := append([]byte(name), 0)
+	var buf [64]byte
+	size := uintptr(len(buf))
+	r := int32(syscall(abi.FuncPCABI0(darwin_sysctlbyname_trampoline), uintptr(unsafe.Pointer(&n[0])),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)), 0, 0, 0, 0))
+	runtime.KeepAlive(n)
+	if r != 0 || size == 0 || size > uintptr(len(buf)) {
+		return ""
+	}
+	// The value is NUL-terminated.
+	return string(buf[:size-1])
+}
+func darwin_sysctlbyname_trampoline()
@@ -0,0 +1,59 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+#include "textflag.h"
+
+// Each trampoline jumps to the function of the same name that the
+// //go:cgo_import_dynamic directives of the Go files import.
+
+TEXT ·darwin_CC_SHA1_Init_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA1_Init(SB)
+TEXT ·darwin_CC_SHA1_Update_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA1_Update(SB)
+TEXT ·darwin_CC_SHA1_Final_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA1_Final(SB)
+TEXT ·darwin_CC_SHA224_Init_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA224_Init(SB)
+TEXT ·darwin_CC_SHA224_Update_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA224_Update(SB)
+TEXT ·darwin_CC_SHA224_Final_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA224_Final(SB)
+TEXT ·darwin_CC_SHA256_Init_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA256_Init(SB)
+TEXT ·darwin_CC_SHA256_Update_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA256_Update(SB)
+TEXT ·darwin_CC_SHA256_Final_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA256_Final(SB)
+TEXT ·darwin_CC_SHA384_Init_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA384_Init(SB)
+TEXT ·darwin_CC_SHA384_Update_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA384_Update(SB)
+TEXT ·darwin_CC_SHA384_Final_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA384_Final(SB)
+TEXT ·darwin_CC_SHA512_Init_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA512_Init(SB)
+TEXT ·darwin_CC_SHA512_Update_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA512_Update(SB)
+TEXT ·darwin_CC_SHA512_Final_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CC_SHA512_Final(SB)
+TEXT ·darwin_CCHmacInit_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCHmacInit(SB)
+TEXT ·darwin_CCHmacUpdate_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCHmacUpdate(SB)
+TEXT ·darwin_CCHmacFinal_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCHmacFinal(SB)
+TEXT ·darwin_CCCryptorCreate_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCCryptorCreate(SB)
+TEXT ·darwin_CCCryptorUpdate_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCCryptorUpdate(SB)
+TEXT ·darwin_CCCryptorReset_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCCryptorReset(SB)
+TEXT ·darwin_CCCryptorRelease_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCCryptorRelease(SB)
+TEXT ·darwin_CCRandomGenerateBytes_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CCRandomGenerateBytes(SB)
+TEXT ·darwin_sysctlbyname_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_sysctlbyname(SB)
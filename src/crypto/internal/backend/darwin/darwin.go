@@ -0,0 +1,187 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// Failure classes of the errors returned by this package, as in
+// crypto/internal/boring.
+var (
+	ErrBackendFailure       = errors.New("darwin: backend failure")
+	ErrUnsupportedParameter = errors.New("darwin: unsupported parameter")
+)
+
+var version string
+
+// Init creates the Security.framework constants used by the package. It
+// must be called, and succeed, before any other function.
+func Init() error {
+	if v := sysctlString("kern.osproductversion"); v != "" {
+		version = "macOS " + v
+	} else {
+		version = "Darwin " + sysctlString("kern.osrelease")
+	}
+	initSecurity()
+	return nil
+}
+
+// Version returns the version of the operating system, whose corecrypto
+// implements the algorithms, such as "macOS 14.4.1".
+func Version() string { return version }
+
+// FIPS reports whether the module operates in FIPS mode. corecrypto has
+// no separate FIPS mode: its validated configuration is always in effect.
+func FIPS() bool { return true }
+
+// fail is a failed CommonCrypto call.
+type fail struct {
+	fn     string
+	status ccStatus
+}
+
+func (e *fail) Error() string {
+	return "darwin: " + e.fn + " failed: status " + itoa(int(e.status))
+}
+
+func (e *fail) Unwrap() error {
+	if e.status == ccUnimplemented {
+		return ErrUnsupportedParameter
+	}
+	return ErrBackendFailure
+}
+
+// newFail returns an error for a call to fn that returned s.
+func newFail(fn string, s ccStatus) error {
+	return &fail{fn: fn, status: s}
+}
+
+// secFail is a failed Security.framework call, with the code of its
+// CFError, which is an OSStatus.
+type secFail struct {
+	fn   string
+	code int
+}
+
+const (
+	errSecUnimplemented        = -4
+	errSecParam                = -50
+	errSecUnsupportedAlgorithm = -67848
+)
+
+func (e *secFail) Error() string {
+	return "darwin: " + e.fn + " failed: OSStatus " + itoa(e.code)
+}
+
+func (e *secFail) Unwrap() error {
+	if e.code == errSecUnimplemented || e.code == errSecUnsupportedAlgorithm {
+		return ErrUnsupportedParameter
+	}
+	return ErrBackendFailure
+}
+
+// newSecFail returns an error for a call to fn that failed with the
+// CFError cfErr, which it releases.
+func newSecFail(fn string, cfErr cfRef) error {
+	e := &secFail{fn: fn, code: errSecParam}
+	if cfErr != 0 {
+		e.code = cfErrorGetCode(cfErr)
+		cfRelease(cfErr)
+	}
+	return e
+}
+
+// unsupported is an error about a parameter that the package does not
+// support.
+type unsupported string
+
+func (e unsupported) Error() string { return string(e) }
+
+func (e unsupported) Unwrap() error { return ErrUnsupportedParameter }
+
+// checkOpen panics if a private key has been closed, since its
+// Security.framework object has been released.
+func checkOpen(closed bool) {
+	if closed {
+		panic("darwin: use of closed private key")
+	}
+}
+
+// base returns the address of the underlying array in b,
+// being careful not to panic when b has zero length.
+func base(b []byte) *byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return &b[0]
+}
+
+// itoa converts val to a decimal string.
+func itoa(val int) string {
+	if val < 0 {
+		return "-" + itoa(-val)
+	}
+	var buf [20]byte
+	i := len(buf) - 1
+	for val >= 10 {
+		buf[i] = byte(val%10 + '0')
+		i--
+		val /= 10
+	}
+	buf[i] = byte(val + '0')
+	return string(buf[i:])
+}
+
+type randReader int
+
+func (randReader) Read(b []byte) (int, error) {
+	if err := ccRandomGenerateBytes(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+const RandReader = randReader(0)
+
+// bigToBytes returns x as a big-endian byte slice of the given size, or
+// of its minimal size if size is zero.
+func bigToBytes(x BigInt, size int) []byte {
+	if size == 0 {
+		size = (bigBitLen(x) + 7) / 8
+	}
+	b := make([]byte, size)
+	for i, w := range x {
+		for j := 0; j < bits.UintSize/8; j++ {
+			if k := size - 1 - i*bits.UintSize/8 - j; k >= 0 {
+				b[k] = byte(w >> (8 * j))
+			}
+		}
+	}
+	return b
+}
+
+// bigBitLen returns the length of x in bits.
+func bigBitLen(x BigInt) int {
+	for i := len(x) - 1; i >= 0; i-- {
+		if x[i] != 0 {
+			return i*bits.UintSize + bits.Len(x[i])
+		}
+	}
+	return 0
+}
+
+// bytesToBig returns the big-endian b as a BigInt.
+func bytesToBig(b []byte) BigInt {
+	x := make(BigInt, (len(b)+bits.UintSize/8-1)/(bits.UintSize/8))
+	for i, c := range b {
+		j := len(b) - 1 - i // byte index, least significant first
+		x[j/(bits.UintSize/8)] |= uint(c) << (8 * (j % (bits.UintSize / 8)))
+	}
+	return x
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+// Most functionality in this package is tested through crypto/internal/boring
+// by the tests of the crypto packages. The tests shared by all backends are in
+// backendtest, and the tests here check what is specific to CommonCrypto.
+
+package darwin
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/internal/backend/internal/backendtest"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if err := Init(); err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSHA256(t *testing.T) { backendtest.TestSHA256(t, SHA256, NewSHA256) }
+func TestHMAC(t *testing.T)   { backendtest.TestHMAC(t, NewHMAC, NewSHA256) }
+func TestAESGCM(t *testing.T) { backendtest.TestAESGCM(t, NewAESCipher) }
+func TestAESCBC(t *testing.T) { backendtest.TestAESCBC(t, NewAESCipher) }
+
+// TestUpdateLimit checks that inputs longer than a single CC_SHA*_Update
+// call accepts are split without changing the digest, in one-shot and
+// streaming hashing.
+func TestUpdateLimit(t *testing.T) {
+	defer func(n int) { maxUpdate = n }(maxUpdate)
+	maxUpdate = 5
+
+	// FIPS 180-2, Appendix B.2 and C.2.
+	msg := []byte("abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq")
+	want256 := decodeHex(t, "248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1")
+	if got := SHA256(msg); !bytes.Equal(got[:], want256) {
+		t.Errorf("SHA256 = %x, want %x", got, want256)
+	}
+	h := NewSHA256()
+	h.Write(msg[:7])
+	h.Write(msg[7:])
+	if got := h.Sum(nil); !bytes.Equal(got, want256) {
+		t.Errorf("streaming SHA256 = %x, want %x", got, want256)
+	}
+	msg = []byte("abcdefghbcdefghicdefghijdefghijkefghijklfghijklmghijklmnhijklmnoijklmnopjklmnopqklmnopqrlmnopqrsmnopqrstnopqrstu")
+	want512 := decodeHex(t, "8e959b75dae313da8cf4f72814fc143f8f7779c6eb9f7fa17299aeadb6889018501d289e4900f7e4331b99dec4b5433ac7d329eeb6dd26545e96e55b874be909")
+	if got := SHA512(msg); !bytes.Equal(got[:], want512) {
+		t.Errorf("SHA512 = %x, want %x", got, want512)
+	}
+}
+
+func TestRSA(t *testing.T) {
+	priv, pub := backendtest.TestRSA(t, backendtest.RSA[BigInt, *PrivateKeyRSA, *PublicKeyRSA]{
+		GenerateKey:    GenerateKeyRSA,
+		NewPrivateKey:  NewPrivateKeyRSA,
+		NewPublicKey:   NewPublicKeyRSA,
+		SignPKCS1v15:   SignRSAPKCS1v15,
+		VerifyPKCS1v15: VerifyRSAPKCS1v15,
+		SignPSS:        SignRSAPSS,
+		VerifyPSS:      VerifyRSAPSS,
+		EncryptOAEP:    EncryptRSAOAEP,
+		DecryptOAEP:    DecryptRSAOAEP,
+		NewSHA256:      NewSHA256,
+	})
+	defer priv.Close()
+
+	// Security.framework imports full private keys only, and checks their
+	// CRT values.
+	N, E, D, P, Q, Dp, Dq, _, err := GenerateKeyRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPrivateKeyRSA(N, E, D, nil, nil, nil, nil, nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPrivateKeyRSA without CRT values: %v, want ErrUnsupportedParameter", err)
+	}
+	if _, err := NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, BigInt{42}); err == nil {
+		t.Error("NewPrivateKeyRSA with a wrong Qinv succeeded")
+	}
+
+	// PSS salts are always as long as the hash, so other lengths are
+	// unsupported when signing and fail when verifying.
+	hashed := SHA256([]byte("hello"))
+	sig, err := SignRSAPSS(priv, crypto.SHA256, hashed[:], -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyRSAPSS(pub, crypto.SHA256, hashed[:], sig, 32); err != nil {
+		t.Errorf("VerifyRSAPSS with salt length 32: %v", err)
+	}
+	if err := VerifyRSAPSS(pub, crypto.SHA256, hashed[:], sig, 10); err == nil {
+		t.Error("VerifyRSAPSS with a wrong salt length succeeded")
+	}
+	if _, err := SignRSAPSS(priv, crypto.SHA256, hashed[:], 10); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("SignRSAPSS with salt length 10: %v, want ErrUnsupportedParameter", err)
+	}
+
+	if _, err := EncryptRSAOAEP(NewSHA256(), NewSHA1(), pub, []byte("msg"), nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("EncryptRSAOAEP with a different MGF1 hash: %v, want ErrUnsupportedParameter", err)
+	}
+	if _, err := EncryptRSAOAEP(NewSHA256(), NewSHA256(), pub, []byte("msg"), []byte("label")); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("EncryptRSAOAEP with a label: %v, want ErrUnsupportedParameter", err)
+	}
+}
+
+func TestECDSA(t *testing.T) {
+	backendtest.TestECDSA(t, backendtest.ECDSA[BigInt, *PrivateKeyECDSA, *PublicKeyECDSA]{
+		GenerateKey:   GenerateKeyECDSA,
+		NewPrivateKey: NewPrivateKeyECDSA,
+		NewPublicKey:  NewPublicKeyECDSA,
+		Sign:          SignMarshalECDSA,
+		Verify:        VerifyECDSA,
+	}, "P-256", "P-384", "P-521")
+	if _, err := NewPublicKeyECDSA("P-224", BigInt{1}, BigInt{2}); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPublicKeyECDSA(P-224): %v, want ErrUnsupportedParameter", err)
+	}
+}
+
+func TestDER(t *testing.T) {
+	der := derSequence([]byte{0}, []byte{0x80}, []byte{0, 0, 1})
+	if want := decodeHex(t, "300a02010002020080020101"); !bytes.Equal(der, want) {
+		t.Errorf("derSequence = %x, want %x", der, want)
+	}
+	ints, ok := parseDERSequence(der, 3)
+	if !ok || len(ints) != 3 || !bytes.Equal(ints[1], []byte{0, 0x80}) || !bytes.Equal(ints[2], []byte{1}) {
+		t.Errorf("parseDERSequence = %x, %v", ints, ok)
+	}
+	if _, ok := parseDERSequence(der, 2); ok {
+		t.Error("parseDERSequence with the wrong count succeeded")
+	}
+}
+
+func TestECDH(t *testing.T) {
+	backendtest.TestECDH(t, backendtest.ECDH[*PrivateKeyECDH, *PublicKeyECDH]{
+		GenerateKey:   GenerateKeyECDH,
+		NewPrivateKey: NewPrivateKeyECDH,
+		ECDH:          ECDH,
+	}, "P-256", "P-384", "P-521")
+}
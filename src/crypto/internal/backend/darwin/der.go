@@ -0,0 +1,93 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+// Security.framework imports and exports RSA keys as the ASN.1 DER
+// encodings of PKCS #1 RSAPublicKey and RSAPrivateKey, which are
+// sequences of INTEGERs. These are the few DER functions they need.
+
+// appendDERLength appends the DER encoding of the length n to b.
+func appendDERLength(b []byte, n int) []byte {
+	if n < 0x80 {
+		return append(b, byte(n))
+	}
+	var l []byte
+	for ; n > 0; n >>= 8 {
+		l = append([]byte{byte(n)}, l...)
+	}
+	return append(append(b, 0x80|byte(len(l))), l...)
+}
+
+// appendDERInt appends the DER encoding of the unsigned big-endian x as
+// an INTEGER to b.
+func appendDERInt(b, x []byte) []byte {
+	for len(x) > 1 && x[0] == 0 {
+		x = x[1:]
+	}
+	if len(x) == 0 || x[0]&0x80 != 0 {
+		b = appendDERLength(append(b, 0x02), len(x)+1)
+		b = append(b, 0)
+	} else {
+		b = appendDERLength(append(b, 0x02), len(x))
+	}
+	return append(b, x...)
+}
+
+// derSequence returns the DER encoding of the SEQUENCE of the unsigned
+// big-endian INTEGERs ints.
+func derSequence(ints ...[]byte) []byte {
+	var body []byte
+	for _, x := range ints {
+		body = appendDERInt(body, x)
+	}
+	b := appendDERLength([]byte{0x30}, len(body))
+	b = append(b, body...)
+	clear(body)
+	return b
+}
+
+// readDERElement reads an element with the tag from b, and returns its
+// contents and the rest of b.
+func readDERElement(b []byte, tag byte) (contents, rest []byte, ok bool) {
+	if len(b) < 2 || b[0] != tag {
+		return nil, nil, false
+	}
+	n, b := int(b[1]), b[2:]
+	if n >= 0x80 {
+		l := n & 0x7f
+		if l == 0 || l > 4 || len(b) < l {
+			return nil, nil, false
+		}
+		n = 0
+		for _, c := range b[:l] {
+			n = n<<8 | int(c)
+		}
+		b = b[l:]
+	}
+	if len(b) < n {
+		return nil, nil, false
+	}
+	return b[:n], b[n:], true
+}
+
+// parseDERSequence returns the n non-negative INTEGERs of the DER encoded
+// SEQUENCE b, as big-endian byte slices.
+func parseDERSequence(b []byte, n int) ([][]byte, bool) {
+	body, rest, ok := readDERElement(b, 0x30)
+	if !ok || len(rest) != 0 {
+		return nil, false
+	}
+	ints := make([][]byte, n)
+	for i := range ints {
+		var x []byte
+		if x, body, ok = readDERElement(body, 0x02); !ok || len(x) == 0 || x[0]&0x80 != 0 {
+			return nil, false
+		}
+		ints[i] = x
+	}
+	return ints, len(body) == 0
+}
@@ -0,0 +1,21 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package darwin provides access to the cryptographic implementations of
+// macOS, CommonCrypto and Security.framework, without cgo, similarly to
+// how crypto/x509/internal/macos provides access to Security.framework. It
+// is the backend of crypto/internal/boring when the darwincrypto
+// GOEXPERIMENT is enabled on darwin, and otherwise it is empty.
+//
+// CommonCrypto implements the hashes, HMAC and AES, and Security.framework
+// the RSA and elliptic curve keys, both on top of corecrypto, the
+// platform's FIPS 140 validated module. They don't implement SHA-512/224,
+// SHA-512/256, P-224 or AES-GCM, for which the crypto packages use the
+// pure Go implementations, or reject the keys. The hashes keep their state
+// in CommonCrypto contexts whose layout is private to corecrypto, so they
+// don't implement encoding.BinaryMarshaler.
+package darwin
+
+// A BigInt is the raw words from a BigInt, as in crypto/internal/boring.
+type BigInt []uint
@@ -0,0 +1,183 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"crypto/internal/nistec"
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+type PublicKeyECDH struct {
+	curve *ecCurve
+	key   cfRef
+	bytes []byte
+}
+
+func (k *PublicKeyECDH) finalize() {
+	cfRelease(k.key)
+}
+
+type PrivateKeyECDH struct {
+	curve  *ecCurve
+	key    cfRef
+	pub    []byte // the encoding of the public key
+	closed bool
+}
+
+func (k *PrivateKeyECDH) finalize() {
+	cfRelease(k.key)
+}
+
+// Close releases the key immediately rather than when k is garbage
+// collected. k must not be used afterwards.
+func (k *PrivateKeyECDH) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+// checkPoint reports whether b is the uncompressed encoding of a point of
+// c, which Security.framework doesn't check when importing a public key.
+func (c *ecCurve) checkPoint(b []byte) bool {
+	if len(b) != 1+2*c.size || b[0] != 4 {
+		return false
+	}
+	var err error
+	switch c {
+	case curveP256:
+		_, err = nistec.NewP256Point().SetBytes(b)
+	case curveP384:
+		_, err = nistec.NewP384Point().SetBytes(b)
+	case curveP521:
+		_, err = nistec.NewP521Point().SetBytes(b)
+	}
+	return err == nil
+}
+
+// publicPoint returns the uncompressed encoding of the public point of the
+// private scalar d on c. Security.framework only imports private keys
+// with their public point.
+func (c *ecCurve) publicPoint(d []byte) ([]byte, error) {
+	switch c {
+	case curveP256:
+		p, err := nistec.NewP256Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, err
+		}
+		return p.Bytes(), nil
+	case curveP384:
+		p, err := nistec.NewP384Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, err
+		}
+		return p.Bytes(), nil
+	case curveP521:
+		p, err := nistec.NewP521Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, err
+		}
+		return p.Bytes(), nil
+	}
+	return nil, errUnknownCurve
+}
+
+func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
+	if len(bytes) < 1 {
+		return nil, errors.New("NewPublicKeyECDH: missing key")
+	}
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	if !c.checkPoint(bytes) {
+		return nil, errors.New("point not on curve")
+	}
+	key, err := newSecKey(secAttrKeyTypeECSECPrimeRandom, secAttrKeyClassPublic, bytes)
+	if err != nil {
+		return nil, errors.New("point not on curve")
+	}
+	k := &PublicKeyECDH{c, key, append([]byte(nil), bytes...)}
+	// Note: Because of the finalizer, any time k.key is passed to
+	// Security.framework, that call must be followed by a call to
+	// runtime.KeepAlive(k), to make sure k is not collected (and
+	// finalized) before the call returns.
+	runtime.SetFinalizer(k, (*PublicKeyECDH).finalize)
+	return k, nil
+}
+
+func (k *PublicKeyECDH) Bytes() []byte { return k.bytes }
+
+func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) != c.size {
+		return nil, errors.New("NewPrivateKeyECDH: invalid key size")
+	}
+	pub, err := c.publicPoint(bytes)
+	if err != nil {
+		return nil, errors.New("NewPrivateKeyECDH: invalid private key")
+	}
+	b := append(append([]byte(nil), pub...), bytes...)
+	key, err := newSecKey(secAttrKeyTypeECSECPrimeRandom, secAttrKeyClassPrivate, b)
+	clear(b)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyECDH{curve: c, key: key, pub: pub}
+	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
+	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
+	return k, nil
+}
+
+func (k *PrivateKeyECDH) PublicKey() (*PublicKeyECDH, error) {
+	checkOpen(k.closed)
+	return NewPublicKeyECDH(k.curve.name, k.pub)
+}
+
+// ECDH returns the shared X coordinate of priv and pub.
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) (_ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+	checkOpen(priv.closed)
+
+	out, err := secKeyCopyKeyExchangeResult(priv.key, secAlgECDHKeyExchangeStandard, pub.key, secEmptyDictionary)
+	runtime.KeepAlive(priv)
+	runtime.KeepAlive(pub)
+	return out, err
+}
+
+func GenerateKeyECDH(curve string) (_ *PrivateKeyECDH, _ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := generateSecKey(secAttrKeyTypeECSECPrimeRandom, c.bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(b)
+	coords, err := c.ecCoords(b, 3)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := append([]byte(nil), coords[2]...)
+	k, err := NewPrivateKeyECDH(c.name, d)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, d, nil
+}
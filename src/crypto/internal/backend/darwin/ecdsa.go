@@ -0,0 +1,187 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+// An ecCurve is a curve of Security.framework, which uses the same keys
+// for ECDSA and ECDH.
+type ecCurve struct {
+	name string
+	bits int // the bit size of the field
+	size int // the byte size of the field
+}
+
+var (
+	curveP256 = &ecCurve{"P-256", 256, 32}
+	curveP384 = &ecCurve{"P-384", 384, 48}
+	curveP521 = &ecCurve{"P-521", 521, 66}
+)
+
+var errUnknownCurve = unsupported("darwin: unknown elliptic curve")
+
+// curveByName returns the curve named curve. Security.framework doesn't
+// implement P-224.
+func curveByName(curve string) (*ecCurve, error) {
+	switch curve {
+	case "P-256":
+		return curveP256, nil
+	case "P-384":
+		return curveP384, nil
+	case "P-521":
+		return curveP521, nil
+	}
+	return nil, errUnknownCurve
+}
+
+// ecKey returns the X9.63 encoding of a key, which Security.framework
+// imports and exports: the uncompressed point, followed by the private
+// scalar for private keys, each coordinate of the field size.
+func (c *ecCurve) ecKey(coords ...[]byte) []byte {
+	b := make([]byte, 1, 1+len(coords)*c.size)
+	b[0] = 4
+	for _, x := range coords {
+		b = append(b, x...)
+	}
+	return b
+}
+
+// ecCoords returns the n coordinates of the X9.63 encoded key b.
+func (c *ecCurve) ecCoords(b []byte, n int) ([][]byte, error) {
+	if len(b) != 1+n*c.size || b[0] != 4 {
+		return nil, errors.New("darwin: invalid EC key encoding")
+	}
+	b = b[1:]
+	coords := make([][]byte, n)
+	for i := range coords {
+		coords[i], b = b[:c.size:c.size], b[c.size:]
+	}
+	return coords, nil
+}
+
+type PrivateKeyECDSA struct {
+	key    cfRef
+	curve  *ecCurve
+	closed bool
+}
+
+func (k *PrivateKeyECDSA) finalize() {
+	cfRelease(k.key)
+}
+
+// Close releases the key immediately rather than when k is garbage
+// collected. k must not be used afterwards.
+func (k *PrivateKeyECDSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+type PublicKeyECDSA struct {
+	key   cfRef
+	curve *ecCurve
+}
+
+func (k *PublicKeyECDSA) finalize() {
+	cfRelease(k.key)
+}
+
+func NewPublicKeyECDSA(curve string, X, Y BigInt) (*PublicKeyECDSA, error) {
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newSecKey(secAttrKeyTypeECSECPrimeRandom, secAttrKeyClassPublic, c.ecKey(bigToBytes(X, c.size), bigToBytes(Y, c.size)))
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKeyECDSA{key, c}
+	// Note: Because of the finalizer, any time k.key is passed to
+	// Security.framework, that call must be followed by a call to
+	// runtime.KeepAlive(k), to make sure k is not collected (and
+	// finalized) before the call returns.
+	runtime.SetFinalizer(k, (*PublicKeyECDSA).finalize)
+	return k, nil
+}
+
+func NewPrivateKeyECDSA(curve string, X, Y BigInt, D BigInt) (*PrivateKeyECDSA, error) {
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, err
+	}
+	d := bigToBytes(D, c.size)
+	b := c.ecKey(bigToBytes(X, c.size), bigToBytes(Y, c.size), d)
+	clear(d)
+	key, err := newSecKey(secAttrKeyTypeECSECPrimeRandom, secAttrKeyClassPrivate, b)
+	clear(b)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyECDSA{key: key, curve: c}
+	// Note: Same as in NewPublicKeyECDSA regarding finalizer and KeepAlive.
+	runtime.SetFinalizer(k, (*PrivateKeyECDSA).finalize)
+	return k, nil
+}
+
+// truncateHash truncates hash to the size of the field of c. The orders of
+// P-256 and P-384 are as long as their fields, and no supported hash is
+// longer than the order of P-521, so this is the truncation of FIPS 186-4.
+func (c *ecCurve) truncateHash(hash []byte) []byte {
+	if len(hash) > c.size {
+		return hash[:c.size]
+	}
+	return hash
+}
+
+// SignMarshalECDSA signs hash, which is truncated to the size of the
+// curve, and returns the ASN.1 DER encoded signature, which
+// Security.framework produces itself.
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) (_ []byte, err error) {
+	countOp(cryptometrics.ECDSA, len(hash))
+	defer countResult(cryptometrics.ECDSA, &err)
+	checkOpen(priv.closed)
+
+	defer runtime.KeepAlive(priv)
+	return secKeyCreateSignature(priv.key, secAlgECDSASignatureDigestX962, priv.curve.truncateHash(hash))
+}
+
+func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	countOp(cryptometrics.ECDSA, len(hash))
+	ok := secKeyVerifySignature(pub.key, secAlgECDSASignatureDigestX962, pub.curve.truncateHash(hash), sig) == nil
+	runtime.KeepAlive(pub)
+	if !ok {
+		countFailure(cryptometrics.ECDSA)
+	}
+	return ok
+}
+
+func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	countOp(cryptometrics.ECDSA, 0)
+	defer countResult(cryptometrics.ECDSA, &err)
+
+	c, err := curveByName(curve)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	b, err := generateSecKey(secAttrKeyTypeECSECPrimeRandom, c.bits)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer clear(b)
+	coords, err := c.ecCoords(b, 3)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return bytesToBig(coords[0]), bytesToBig(coords[1]), bytesToBig(coords[2]), nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"hash"
+	"internal/cryptometrics"
+	"unsafe"
+)
+
+// NewHMAC returns a new HMAC using CommonCrypto.
+// The function h must return a hash implemented by
+// this package (for example, h could be darwin.NewSHA256).
+// If h is not recognized, NewHMAC returns nil.
+func NewHMAC(h func() hash.Hash, key []byte) hash.Hash {
+	ch, ok := h().(*ccHash)
+	if !ok {
+		return nil
+	}
+	hm := &ccHMAC{d: ch.d, key: append([]byte(nil), key...)}
+	hm.Reset()
+	return hm
+}
+
+// A ccHMAC is an HMAC backed by a CCHmacContext, which holds the padded
+// key and, besides it, only a pointer to static data, so that it can be
+// copied. The key is kept for Reset.
+type ccHMAC struct {
+	d   *digest
+	key []byte
+	ctx [48]uint64 // CCHmacContext
+}
+
+func (h *ccHMAC) ptr() unsafe.Pointer { return unsafe.Pointer(&h.ctx) }
+
+// Close zeroes the key and the context of h immediately rather than
+// when h is garbage collected. h must not be used afterwards.
+func (h *ccHMAC) Close() error {
+	if h.key != nil {
+		clear(h.key)
+		clear(h.ctx[:])
+		h.key = nil
+	}
+	return nil
+}
+
+func (h *ccHMAC) checkOpen() {
+	if h.key == nil {
+		panic("darwin: use of closed HMAC")
+	}
+}
+
+func (h *ccHMAC) Size() int      { return h.d.size }
+func (h *ccHMAC) BlockSize() int { return h.d.blockSize }
+
+func (h *ccHMAC) Reset() {
+	h.checkOpen()
+	ccHmacInit(h.ptr(), h.d.hmacAlg, h.key)
+}
+
+func (h *ccHMAC) Write(p []byte) (int, error) {
+	h.checkOpen()
+	countBytes(cryptometrics.HMAC, len(p))
+	ccHmacUpdate(h.ptr(), p)
+	return len(p), nil
+}
+
+func (h *ccHMAC) Sum(in []byte) []byte {
+	h.checkOpen()
+	countOp(cryptometrics.HMAC, 0)
+	// Finish a copy of the context, so that writes to h can continue.
+	c := h.ctx
+	var out [64]byte // the largest digest size
+	ccHmacFinal(unsafe.Pointer(&c), out[:])
+	clear(c[:])
+	return append(in, out[:h.d.size]...)
+}
+
+// Clone returns an independent copy of h.
+func (h *ccHMAC) Clone() (hash.Hash, error) {
+	h.checkOpen()
+	c := *h
+	c.key = append([]byte(nil), h.key...)
+	return &c, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+// The metrics hooks record operations, identified by their
+// internal/cryptometrics algorithm, as in crypto/internal/boring, which
+// sets them before calling Init and maintains the counters and the service
+// indicator for all backends.
+var (
+	countOp        = func(alg, n int) {}
+	countBytes     = func(alg, n int) {}
+	countFailure   = func(alg int) {}
+	recordFallback = func(alg int, reason string) {}
+)
+
+// SetMetricsHooks sets the functions called to record an operation of alg
+// on n bytes of input, n more bytes of input to an operation counted
+// separately, a failed operation, and an operation performed by the pure
+// Go implementation instead.
+func SetMetricsHooks(op, bytes func(alg, n int), failure func(alg int), fallback func(alg int, reason string)) {
+	countOp, countBytes, countFailure, recordFallback = op, bytes, failure, fallback
+}
+
+// countResult records a failed operation of algorithm alg if *err is not
+// nil. It is meant to be deferred.
+func countResult(alg int, err *error) {
+	if *err != nil {
+		countFailure(alg)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+// newSecKey returns the Security.framework key of the type and class
+// encoded in der.
+func newSecKey(keyType, keyClass cfRef, der []byte) (cfRef, error) {
+	attrs := cfDictionary(secAttrKeyType, keyType, secAttrKeyClass, keyClass)
+	defer cfRelease(attrs)
+	return secKeyCreateWithData(der, attrs)
+}
+
+// generateSecKey returns a new private key of the type and size, and its
+// encoding.
+func generateSecKey(keyType cfRef, bits int) ([]byte, error) {
+	size := cfNumber(int64(bits))
+	defer cfRelease(size)
+	params := cfDictionary(secAttrKeyType, keyType, secAttrKeySizeInBits, size)
+	defer cfRelease(params)
+	key, err := secKeyCreateRandomKey(params)
+	if err != nil {
+		return nil, err
+	}
+	defer cfRelease(key)
+	return secKeyCopyExternalRepresentation(key)
+}
+
+func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	countOp(cryptometrics.RSA, 0)
+	defer countResult(cryptometrics.RSA, &err)
+
+	bad := func(e error) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+		return nil, nil, nil, nil, nil, nil, nil, nil, e
+	}
+
+	der, err := generateSecKey(secAttrKeyTypeRSA, bits)
+	if err != nil {
+		return bad(err)
+	}
+	defer clear(der)
+	// The RSAPrivateKey is the version, N, E, D, P, Q, Dp, Dq and Qinv.
+	ints, ok := parseDERSequence(der, 9)
+	if !ok {
+		return bad(errors.New("darwin: invalid RSA private key encoding"))
+	}
+	var out [8]BigInt
+	for i := range out {
+		out[i] = bytesToBig(ints[i+1])
+	}
+	return out[0], out[1], out[2], out[3], out[4], out[5], out[6], out[7], nil
+}
+
+type PublicKeyRSA struct {
+	key  cfRef
+	bits int
+}
+
+func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
+	key, err := newSecKey(secAttrKeyTypeRSA, secAttrKeyClassPublic, derSequence(bigToBytes(N, 0), bigToBytes(E, 0)))
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKeyRSA{key: key, bits: bigBitLen(N)}
+	// Note: Because of the finalizer, any time k.key is passed to
+	// Security.framework, that call must be followed by a call to
+	// runtime.KeepAlive(k), to make sure k is not collected (and
+	// finalized) before the call returns.
+	runtime.SetFinalizer(k, (*PublicKeyRSA).finalize)
+	return k, nil
+}
+
+func (k *PublicKeyRSA) finalize() {
+	cfRelease(k.key)
+}
+
+// A PrivateKeyRSA holds the private key, and the public key, which
+// Security.framework uses for the public operations of the consistency
+// test.
+type PrivateKeyRSA struct {
+	key    cfRef
+	pub    *PublicKeyRSA
+	closed bool
+}
+
+var errRSAConsistency = errors.New("darwin: RSA private key failed the pairwise consistency test")
+
+// NewPrivateKeyRSA returns a private key with two primes and their CRT
+// values, which the RSAPrivateKey encoding requires. It returns an error
+// wrapping ErrUnsupportedParameter for other keys, which crypto/rsa
+// passes with nil CRT values.
+func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
+	if P == nil || Q == nil || Dp == nil || Dq == nil || Qinv == nil {
+		return nil, unsupported("darwin: RSA private keys without two primes and their CRT values are not supported")
+	}
+	pub, err := NewPublicKeyRSA(N, E)
+	if err != nil {
+		return nil, err
+	}
+	ints := [][]byte{{0}}
+	for _, x := range []BigInt{N, E, D, P, Q, Dp, Dq, Qinv} {
+		ints = append(ints, bigToBytes(x, 0))
+	}
+	der := derSequence(ints...)
+	for _, x := range ints {
+		clear(x)
+	}
+	key, err := newSecKey(secAttrKeyTypeRSA, secAttrKeyClassPrivate, der)
+	clear(der)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyRSA{key: key, pub: pub}
+	// Note: Because of the finalizer, any time k.key is passed to
+	// Security.framework, that call must be followed by a call to
+	// runtime.KeepAlive(k), to make sure k is not collected (and
+	// finalized) before the call returns.
+	runtime.SetFinalizer(k, (*PrivateKeyRSA).finalize)
+	// Check the private values once here, as BoringCrypto does on every
+	// operation.
+	if err := k.checkConsistency(); err != nil {
+		k.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+// checkConsistency checks that the private values invert E, by decrypting
+// the encryption of a small message.
+func (k *PrivateKeyRSA) checkConsistency() error {
+	msg := make([]byte, (k.pub.bits+7)/8)
+	msg[len(msg)-1] = 2
+	c, err := secKeyCreateEncryptedData(k.pub.key, secAlgRSAEncryptionRaw, msg)
+	runtime.KeepAlive(k.pub)
+	if err != nil {
+		return err
+	}
+	m, err := secKeyCreateDecryptedData(k.key, secAlgRSAEncryptionRaw, c)
+	runtime.KeepAlive(k)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(m, msg) {
+		return errRSAConsistency
+	}
+	return nil
+}
+
+func (k *PrivateKeyRSA) finalize() {
+	cfRelease(k.key)
+}
+
+// Close releases the key immediately rather than when k is garbage
+// collected. k must not be used afterwards.
+func (k *PrivateKeyRSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+// cryptoHashToDigest returns the digest of ch, or nil.
+func cryptoHashToDigest(ch crypto.Hash) *digest {
+	switch ch {
+	case crypto.SHA1:
+		return sha1MD
+	case crypto.SHA224:
+		return sha224MD
+	case crypto.SHA256:
+		return sha256MD
+	case crypto.SHA384:
+		return sha384MD
+	case crypto.SHA512:
+		return sha512MD
+	}
+	return nil
+}
+
+var errUnsupportedHash = unsupported("darwin: unsupported hash function")
+
+// oaepAlgorithm returns the OAEP algorithm for h, mgfHash and label.
+// Security.framework uses the same hash for OAEP and MGF1, and no label.
+func oaepAlgorithm(h, mgfHash hash.Hash, label []byte) (cfRef, error) {
+	hc, ok := h.(*ccHash)
+	if !ok {
+		return 0, errUnsupportedHash
+	}
+	if mc, ok := mgfHash.(*ccHash); mgfHash != nil && (!ok || mc.d != hc.d) {
+		return 0, unsupported("darwin: OAEP with a different MGF1 hash is not supported")
+	}
+	if len(label) != 0 {
+		return 0, unsupported("darwin: OAEP with a label is not supported")
+	}
+	return secAlgRSAEncryptionOAEP[hc.d], nil
+}
+
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP
+// support h, mgfHash and label.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool {
+	_, err := oaepAlgorithm(h, mgfHash, label)
+	return err == nil
+}
+
+func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(ciphertext))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+	alg, err := oaepAlgorithm(h, mgfHash, label)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(priv)
+	return secKeyCreateDecryptedData(priv.key, alg, ciphertext)
+}
+
+func EncryptRSAOAEP(h, mgfHash hash.Hash, pub *PublicKeyRSA, msg, label []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(msg))
+	defer countResult(cryptometrics.RSA, &err)
+	alg, err := oaepAlgorithm(h, mgfHash, label)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(pub)
+	return secKeyCreateEncryptedData(pub.key, alg, msg)
+}
+
+func DecryptRSAPKCS1(priv *PrivateKeyRSA, ciphertext []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(ciphertext))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+	defer runtime.KeepAlive(priv)
+	return secKeyCreateDecryptedData(priv.key, secAlgRSAEncryptionPKCS1, ciphertext)
+}
+
+func EncryptRSAPKCS1(pub *PublicKeyRSA, msg []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(msg))
+	defer countResult(cryptometrics.RSA, &err)
+	defer runtime.KeepAlive(pub)
+	return secKeyCreateEncryptedData(pub.key, secAlgRSAEncryptionPKCS1, msg)
+}
+
+func DecryptRSANoPadding(priv *PrivateKeyRSA, ciphertext []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(ciphertext))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+	defer runtime.KeepAlive(priv)
+	return secKeyCreateDecryptedData(priv.key, secAlgRSAEncryptionRaw, ciphertext)
+}
+
+func EncryptRSANoPadding(pub *PublicKeyRSA, msg []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(msg))
+	defer countResult(cryptometrics.RSA, &err)
+	defer runtime.KeepAlive(pub)
+	return secKeyCreateEncryptedData(pub.key, secAlgRSAEncryptionRaw, msg)
+}
+
+var invalidSaltLenErr = errors.New("crypto/rsa: PSSOptions.SaltLength cannot be negative")
+
+// SignRSAPSS signs hashed with a salt as long as the hash, the only one
+// that Security.framework supports. It returns an error wrapping
+// ErrUnsupportedParameter for other salt lengths.
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+
+	d := cryptoHashToDigest(h)
+	if d == nil {
+		return nil, errUnsupportedHash
+	}
+	if len(hashed) != d.size {
+		return nil, errors.New("crypto/rsa: input must be hashed with given hash")
+	}
+	// crypto/rsa uses -1 for a salt as long as the hash, and 0 for the
+	// maximal salt when signing.
+	switch {
+	case saltLen < -1:
+		return nil, invalidSaltLenErr
+	case saltLen == -1:
+		saltLen = d.size
+	case saltLen == 0:
+		saltLen = (priv.pub.bits-1+7)/8 - d.size - 2
+		if saltLen < 0 {
+			return nil, errors.New("crypto/rsa: key size too small for PSS signature")
+		}
+	}
+	if saltLen != d.size {
+		return nil, unsupported("darwin: PSS salts not as long as the hash are not supported")
+	}
+	defer runtime.KeepAlive(priv)
+	return secKeyCreateSignature(priv.key, secAlgRSASignatureDigestPSS[d], hashed)
+}
+
+var errVerification = errors.New("darwin: verification error")
+
+// VerifyRSAPSS verifies a PSS signature with any salt length.
+// Security.framework only verifies salts as long as the hash, so the
+// EMSA-PSS verification of RFC 8017, Section 9.1.2, is performed here, on
+// the encoded message recovered by Security.framework, and with the
+// hashes of CommonCrypto.
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	d := cryptoHashToDigest(h)
+	if d == nil {
+		return errUnsupportedHash
+	}
+	switch {
+	case saltLen < -1:
+		return invalidSaltLenErr
+	case saltLen == -1:
+		saltLen = d.size
+	}
+	if len(hashed) != d.size || len(sig) != (pub.bits+7)/8 {
+		return errVerification
+	}
+	m, err := secKeyCreateEncryptedData(pub.key, secAlgRSAEncryptionRaw, sig)
+	runtime.KeepAlive(pub)
+	if err != nil {
+		return err
+	}
+	emBits := pub.bits - 1
+	emLen := (emBits + 7) / 8
+	if len(m) < emLen {
+		return errVerification
+	}
+	em := m[len(m)-emLen:]
+	if (len(m) > emLen && m[0] != 0) || emLen < d.size+2 || em[emLen-1] != 0xbc {
+		return errVerification
+	}
+	db := em[:emLen-d.size-1]
+	H := em[emLen-d.size-1 : emLen-1]
+	if em[0]&^(0xff>>(8*emLen-emBits)) != 0 {
+		return errVerification
+	}
+	// db ^= MGF1(H), using counter as the suffix of the MGF1 input.
+	var counter [4]byte
+	var mask [64]byte
+	for i := 0; i < len(db); i += d.size {
+		d.hash(mask[:], H, counter[:])
+		for j := 0; j < d.size && i+j < len(db); j++ {
+			db[i+j] ^= mask[j]
+		}
+		for j := 3; j >= 0; j-- {
+			if counter[j]++; counter[j] != 0 {
+				break
+			}
+		}
+	}
+	db[0] &= 0xff >> (8*emLen - emBits)
+	i := 0
+	for i < len(db) && db[i] == 0 {
+		i++
+	}
+	if i == len(db) || db[i] != 1 {
+		return errVerification
+	}
+	salt := db[i+1:]
+	if saltLen != 0 && len(salt) != saltLen {
+		return errVerification
+	}
+	// H must be the hash of eight zero bytes, hashed and the salt.
+	var h0 [64]byte
+	d.hash(h0[:], make([]byte, 8), hashed, salt)
+	if !bytes.Equal(h0[:d.size], H) {
+		return errVerification
+	}
+	return nil
+}
+
+// hashPrefixes are the DigestInfo prefixes of the hashes, copied from
+// crypto/rsa/pkcs1v15.go. Security.framework only adds the prefixes of
+// SHA-1 and SHA-2, so the prefix is always prepended here, and hashed is
+// signed with the raw PKCS #1 v1.5 algorithm.
+var hashPrefixes = map[crypto.Hash][]byte{
+	crypto.MD5:       {0x30, 0x20, 0x30, 0x0c, 0x06, 0x08, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x02, 0x05, 0x05, 0x00, 0x04, 0x10},
+	crypto.SHA1:      {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA224:    {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	crypto.SHA256:    {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384:    {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512:    {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	crypto.MD5SHA1:   {}, // A special TLS case which doesn't use an ASN1 prefix.
+	crypto.RIPEMD160: {0x30, 0x20, 0x30, 0x08, 0x06, 0x06, 0x28, 0xcf, 0x06, 0x03, 0x00, 0x31, 0x04, 0x14},
+}
+
+// pkcs1DigestInfo returns hashed, the digest of the message with h, with
+// its DigestInfo prefix, or hashed itself if h is zero.
+func pkcs1DigestInfo(h crypto.Hash, hashed []byte) ([]byte, error) {
+	if h == 0 {
+		return hashed, nil
+	}
+	prefix, ok := hashPrefixes[h]
+	if !ok {
+		return nil, errUnsupportedHash
+	}
+	if len(hashed) != h.Size() {
+		return nil, errors.New("crypto/rsa: input must be hashed message")
+	}
+	return append(append([]byte(nil), prefix...), hashed...), nil
+}
+
+// SignRSAPKCS1v15 signs hashed, the digest of the message with h, or the
+// message itself if h is zero.
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+	checkOpen(priv.closed)
+
+	data, err := pkcs1DigestInfo(h, hashed)
+	if err != nil {
+		return nil, err
+	}
+	defer runtime.KeepAlive(priv)
+	return secKeyCreateSignature(priv.key, secAlgRSASignatureDigestPKCS1v15Raw, data)
+}
+
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	data, err := pkcs1DigestInfo(h, hashed)
+	if err != nil {
+		return err
+	}
+	defer runtime.KeepAlive(pub)
+	return secKeyVerifySignature(pub.key, secAlgRSASignatureDigestPKCS1v15Raw, data, sig)
+}
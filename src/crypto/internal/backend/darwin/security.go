@@ -0,0 +1,304 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"internal/abi"
+	"runtime"
+	"unsafe"
+)
+
+// Core Foundation and Security.framework linker flags for the external
+// linker. See Issue 42459.
+//
+//go:cgo_ldflag "-framework"
+//go:cgo_ldflag "CoreFoundation"
+//go:cgo_ldflag "-framework"
+//go:cgo_ldflag "Security"
+
+// A cfRef is a reference to a Core Foundation object, such as a CFData,
+// a CFString, a CFError or a SecKeyRef. It is a pointer, but to memory not
+// owned by Go, so not an unsafe.Pointer.
+type cfRef uintptr
+
+const kCFAllocatorDefault = 0
+
+//go:cgo_import_dynamic darwin_CFRelease CFRelease "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func cfRelease(ref cfRef) {
+	syscall(abi.FuncPCABI0(darwin_CFRelease_trampoline), uintptr(ref), 0, 0, 0, 0, 0, 0)
+}
+func darwin_CFRelease_trampoline()
+
+//go:cgo_import_dynamic darwin_CFDataCreate CFDataCreate "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+// cfDataCreate returns a CFData holding a copy of b.
+func cfDataCreate(b []byte) cfRef {
+	ret := syscall(abi.FuncPCABI0(darwin_CFDataCreate_trampoline), kCFAllocatorDefault,
+		uintptr(unsafe.Pointer(base(b))), uintptr(len(b)), 0, 0, 0, 0)
+	runtime.KeepAlive(b)
+	return cfRef(ret)
+}
+func darwin_CFDataCreate_trampoline()
+
+//go:cgo_import_dynamic darwin_CFDataGetLength CFDataGetLength "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func cfDataGetLength(data cfRef) int {
+	return int(syscall(abi.FuncPCABI0(darwin_CFDataGetLength_trampoline), uintptr(data), 0, 0, 0, 0, 0, 0))
+}
+func darwin_CFDataGetLength_trampoline()
+
+//go:cgo_import_dynamic darwin_CFDataGetBytePtr CFDataGetBytePtr "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func cfDataGetBytePtr(data cfRef) uintptr {
+	return syscall(abi.FuncPCABI0(darwin_CFDataGetBytePtr_trampoline), uintptr(data), 0, 0, 0, 0, 0, 0)
+}
+func darwin_CFDataGetBytePtr_trampoline()
+
+// cfDataToSlice returns a copy of the contents of data, and releases it.
+// The CFData may hold key material, which CoreFoundation doesn't zero.
+func cfDataToSlice(data cfRef) []byte {
+	defer cfRelease(data)
+	n := cfDataGetLength(data)
+	if n == 0 {
+		return []byte{}
+	}
+	p := cfDataGetBytePtr(data)
+	return append([]byte(nil), unsafe.Slice((*byte)(unsafe.Pointer(p)), n)...)
+}
+
+const kCFStringEncodingUTF8 = 0x08000100
+
+//go:cgo_import_dynamic darwin_CFStringCreateWithBytes CFStringCreateWithBytes "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+// cfString returns a copy of the UTF-8 contents of s as a new CFString.
+func cfString(s string) cfRef {
+	p := unsafe.Pointer(unsafe.StringData(s))
+	ret := syscall(abi.FuncPCABI0(darwin_CFStringCreateWithBytes_trampoline), kCFAllocatorDefault, uintptr(p),
+		uintptr(len(s)), kCFStringEncodingUTF8, 0 /* isExternalRepresentation */, 0, 0)
+	runtime.KeepAlive(p)
+	return cfRef(ret)
+}
+func darwin_CFStringCreateWithBytes_trampoline()
+
+const kCFNumberSInt64Type = 4
+
+//go:cgo_import_dynamic darwin_CFNumberCreate CFNumberCreate "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func cfNumber(v int64) cfRef {
+	ret := syscall(abi.FuncPCABI0(darwin_CFNumberCreate_trampoline), kCFAllocatorDefault, kCFNumberSInt64Type,
+		uintptr(unsafe.Pointer(&v)), 0, 0, 0, 0)
+	return cfRef(ret)
+}
+func darwin_CFNumberCreate_trampoline()
+
+//go:cgo_import_dynamic darwin_CFEqual CFEqual "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+//go:cgo_import_dynamic darwin_CFHash CFHash "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func darwin_CFEqual_trampoline()
+func darwin_CFHash_trampoline()
+
+// cfDictionaryKeyCallBacks is a CFDictionaryKeyCallBacks that compares the
+// keys with CFEqual and CFHash, like kCFTypeDictionaryKeyCallBacks, but
+// doesn't retain them. The trampolines only jump to the C functions, so
+// they can be called from C.
+var cfDictionaryKeyCallBacks struct {
+	version, retain, release, copyDescription, equal, hash uintptr
+}
+
+//go:cgo_import_dynamic darwin_CFDictionaryCreateMutable CFDictionaryCreateMutable "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+// cfDictionary returns a new CFDictionary with the keys and values of kv.
+// It doesn't retain them, so they must outlive it.
+func cfDictionary(kv ...cfRef) cfRef {
+	dict := cfRef(syscall(abi.FuncPCABI0(darwin_CFDictionaryCreateMutable_trampoline), kCFAllocatorDefault,
+		uintptr(len(kv)/2), uintptr(unsafe.Pointer(&cfDictionaryKeyCallBacks)), 0, 0, 0, 0))
+	for i := 0; i < len(kv); i += 2 {
+		syscall(abi.FuncPCABI0(darwin_CFDictionarySetValue_trampoline), uintptr(dict), uintptr(kv[i]), uintptr(kv[i+1]), 0, 0, 0, 0)
+	}
+	return dict
+}
+func darwin_CFDictionaryCreateMutable_trampoline()
+
+//go:cgo_import_dynamic darwin_CFDictionarySetValue CFDictionarySetValue "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func darwin_CFDictionarySetValue_trampoline()
+
+//go:cgo_import_dynamic darwin_CFErrorGetCode CFErrorGetCode "/System/Library/Frameworks/CoreFoundation.framework/Versions/A/CoreFoundation"
+
+func cfErrorGetCode(err cfRef) int {
+	return int(syscall(abi.FuncPCABI0(darwin_CFErrorGetCode_trampoline), uintptr(err), 0, 0, 0, 0, 0, 0))
+}
+func darwin_CFErrorGetCode_trampoline()
+
+// The Security.framework key functions return NULL, or false, and a
+// CFError on failure.
+
+// secCall returns the result of the function fn, called with args and the
+// address of a CFError, or an error if the result is zero.
+func secCall(fn uintptr, name string, args ...uintptr) (uintptr, error) {
+	var cfErr cfRef
+	var a [7]uintptr
+	copy(a[:], args)
+	a[len(args)] = uintptr(unsafe.Pointer(&cfErr))
+	ret := syscall(fn, a[0], a[1], a[2], a[3], a[4], a[5], a[6])
+	if ret == 0 {
+		return 0, newSecFail(name, cfErr)
+	}
+	return ret, nil
+}
+
+//go:cgo_import_dynamic darwin_SecKeyCreateWithData SecKeyCreateWithData "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+// secKeyCreateWithData returns the key encoded in data, with the
+// attributes attrs, as a SecKeyRef.
+func secKeyCreateWithData(data []byte, attrs cfRef) (cfRef, error) {
+	d := cfDataCreate(data)
+	defer cfRelease(d)
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCreateWithData_trampoline), "SecKeyCreateWithData", uintptr(d), uintptr(attrs))
+	return cfRef(ret), err
+}
+func darwin_SecKeyCreateWithData_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyCreateRandomKey SecKeyCreateRandomKey "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+func secKeyCreateRandomKey(params cfRef) (cfRef, error) {
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCreateRandomKey_trampoline), "SecKeyCreateRandomKey", uintptr(params))
+	return cfRef(ret), err
+}
+func darwin_SecKeyCreateRandomKey_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyCopyExternalRepresentation SecKeyCopyExternalRepresentation "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+// secKeyCopyExternalRepresentation returns the encoding of key, which is
+// X9.63 for elliptic curve keys and PKCS #1 for RSA keys.
+func secKeyCopyExternalRepresentation(key cfRef) ([]byte, error) {
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCopyExternalRepresentation_trampoline), "SecKeyCopyExternalRepresentation", uintptr(key))
+	if err != nil {
+		return nil, err
+	}
+	return cfDataToSlice(cfRef(ret)), nil
+}
+func darwin_SecKeyCopyExternalRepresentation_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyCreateSignature SecKeyCreateSignature "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+func secKeyCreateSignature(key, alg cfRef, data []byte) ([]byte, error) {
+	d := cfDataCreate(data)
+	defer cfRelease(d)
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCreateSignature_trampoline), "SecKeyCreateSignature", uintptr(key), uintptr(alg), uintptr(d))
+	if err != nil {
+		return nil, err
+	}
+	return cfDataToSlice(cfRef(ret)), nil
+}
+func darwin_SecKeyCreateSignature_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyVerifySignature SecKeyVerifySignature "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+func secKeyVerifySignature(key, alg cfRef, data, sig []byte) error {
+	d, s := cfDataCreate(data), cfDataCreate(sig)
+	defer cfRelease(d)
+	defer cfRelease(s)
+	var cfErr cfRef
+	// The result is a Boolean, an unsigned char.
+	ok := uint8(syscall(abi.FuncPCABI0(darwin_SecKeyVerifySignature_trampoline), uintptr(key), uintptr(alg),
+		uintptr(d), uintptr(s), uintptr(unsafe.Pointer(&cfErr)), 0, 0))
+	if ok == 0 {
+		return newSecFail("SecKeyVerifySignature", cfErr)
+	}
+	return nil
+}
+func darwin_SecKeyVerifySignature_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyCreateEncryptedData SecKeyCreateEncryptedData "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+func secKeyCreateEncryptedData(key, alg cfRef, plaintext []byte) ([]byte, error) {
+	d := cfDataCreate(plaintext)
+	defer cfRelease(d)
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCreateEncryptedData_trampoline), "SecKeyCreateEncryptedData", uintptr(key), uintptr(alg), uintptr(d))
+	if err != nil {
+		return nil, err
+	}
+	return cfDataToSlice(cfRef(ret)), nil
+}
+func darwin_SecKeyCreateEncryptedData_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyCreateDecryptedData SecKeyCreateDecryptedData "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+func secKeyCreateDecryptedData(key, alg cfRef, ciphertext []byte) ([]byte, error) {
+	d := cfDataCreate(ciphertext)
+	defer cfRelease(d)
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCreateDecryptedData_trampoline), "SecKeyCreateDecryptedData", uintptr(key), uintptr(alg), uintptr(d))
+	if err != nil {
+		return nil, err
+	}
+	return cfDataToSlice(cfRef(ret)), nil
+}
+func darwin_SecKeyCreateDecryptedData_trampoline()
+
+//go:cgo_import_dynamic darwin_SecKeyCopyKeyExchangeResult SecKeyCopyKeyExchangeResult "/System/Library/Frameworks/Security.framework/Versions/A/Security"
+
+func secKeyCopyKeyExchangeResult(priv, alg, pub, params cfRef) ([]byte, error) {
+	ret, err := secCall(abi.FuncPCABI0(darwin_SecKeyCopyKeyExchangeResult_trampoline), "SecKeyCopyKeyExchangeResult",
+		uintptr(priv), uintptr(alg), uintptr(pub), uintptr(params))
+	if err != nil {
+		return nil, err
+	}
+	return cfDataToSlice(cfRef(ret)), nil
+}
+func darwin_SecKeyCopyKeyExchangeResult_trampoline()
+
+// The dictionary keys and the algorithms are CFString constants of
+// Security.framework, but as in crypto/x509/internal/macos, the Go
+// linker's internal linking mode can't handle the CFSTR relocations, so
+// Init creates dynamic strings equal to them instead, which are never
+// released.
+var (
+	secAttrKeyType                 cfRef // kSecAttrKeyType
+	secAttrKeyTypeRSA              cfRef // kSecAttrKeyTypeRSA
+	secAttrKeyTypeECSECPrimeRandom cfRef // kSecAttrKeyTypeECSECPrimeRandom
+	secAttrKeyClass                cfRef // kSecAttrKeyClass
+	secAttrKeyClassPublic          cfRef // kSecAttrKeyClassPublic
+	secAttrKeyClassPrivate         cfRef // kSecAttrKeyClassPrivate
+	secAttrKeySizeInBits           cfRef // kSecAttrKeySizeInBits
+
+	secAlgRSASignatureDigestPKCS1v15Raw cfRef // kSecKeyAlgorithmRSASignatureDigestPKCS1v15Raw
+	secAlgRSAEncryptionRaw              cfRef // kSecKeyAlgorithmRSAEncryptionRaw
+	secAlgRSAEncryptionPKCS1            cfRef // kSecKeyAlgorithmRSAEncryptionPKCS1
+	secAlgECDSASignatureDigestX962      cfRef // kSecKeyAlgorithmECDSASignatureDigestX962
+	secAlgECDHKeyExchangeStandard       cfRef // kSecKeyAlgorithmECDHKeyExchangeStandard
+	secAlgRSAEncryptionOAEP             map[*digest]cfRef
+	secAlgRSASignatureDigestPSS         map[*digest]cfRef
+	secEmptyDictionary                  cfRef
+)
+
+func initSecurity() {
+	cfDictionaryKeyCallBacks.equal = abi.FuncPCABI0(darwin_CFEqual_trampoline)
+	cfDictionaryKeyCallBacks.hash = abi.FuncPCABI0(darwin_CFHash_trampoline)
+
+	secAttrKeyType = cfString("type")
+	secAttrKeyTypeRSA = cfString("42")
+	secAttrKeyTypeECSECPrimeRandom = cfString("73")
+	secAttrKeyClass = cfString("kcls")
+	secAttrKeyClassPublic = cfString("0")
+	secAttrKeyClassPrivate = cfString("1")
+	secAttrKeySizeInBits = cfString("bsiz")
+
+	secAlgRSASignatureDigestPKCS1v15Raw = cfString("algid:sign:RSA:digest-PKCS1v15")
+	secAlgRSAEncryptionRaw = cfString("algid:encrypt:RSA:raw")
+	secAlgRSAEncryptionPKCS1 = cfString("algid:encrypt:RSA:PKCS1")
+	secAlgECDSASignatureDigestX962 = cfString("algid:sign:ECDSA:digest-X962")
+	secAlgECDHKeyExchangeStandard = cfString("algid:keyexchange:ECDH")
+	secAlgRSAEncryptionOAEP = make(map[*digest]cfRef)
+	secAlgRSASignatureDigestPSS = make(map[*digest]cfRef)
+	for _, d := range []*digest{sha1MD, sha224MD, sha256MD, sha384MD, sha512MD} {
+		secAlgRSAEncryptionOAEP[d] = cfString("algid:encrypt:RSA:OAEP:" + d.name)
+		secAlgRSASignatureDigestPSS[d] = cfString("algid:sign:RSA:digest-PSS:" + d.name + ":" + d.name + ":" + itoa(d.size))
+	}
+	secEmptyDictionary = cfDictionary()
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+#include "textflag.h"
+
+// Each trampoline jumps to the function of the same name that the
+// //go:cgo_import_dynamic directives of the Go files import.
+
+TEXT ·darwin_CFRelease_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFRelease(SB)
+TEXT ·darwin_CFDataCreate_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFDataCreate(SB)
+TEXT ·darwin_CFDataGetLength_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFDataGetLength(SB)
+TEXT ·darwin_CFDataGetBytePtr_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFDataGetBytePtr(SB)
+TEXT ·darwin_CFStringCreateWithBytes_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFStringCreateWithBytes(SB)
+TEXT ·darwin_CFNumberCreate_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFNumberCreate(SB)
+TEXT ·darwin_CFEqual_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFEqual(SB)
+TEXT ·darwin_CFHash_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFHash(SB)
+TEXT ·darwin_CFDictionaryCreateMutable_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFDictionaryCreateMutable(SB)
+TEXT ·darwin_CFDictionarySetValue_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFDictionarySetValue(SB)
+TEXT ·darwin_CFErrorGetCode_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_CFErrorGetCode(SB)
+TEXT ·darwin_SecKeyCreateWithData_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCreateWithData(SB)
+TEXT ·darwin_SecKeyCreateRandomKey_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCreateRandomKey(SB)
+TEXT ·darwin_SecKeyCopyExternalRepresentation_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCopyExternalRepresentation(SB)
+TEXT ·darwin_SecKeyCreateSignature_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCreateSignature(SB)
+TEXT ·darwin_SecKeyVerifySignature_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyVerifySignature(SB)
+TEXT ·darwin_SecKeyCreateEncryptedData_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCreateEncryptedData(SB)
+TEXT ·darwin_SecKeyCreateDecryptedData_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCreateDecryptedData(SB)
+TEXT ·darwin_SecKeyCopyKeyExchangeResult_trampoline(SB),NOSPLIT,$0-0
+	JMP	darwin_SecKeyCopyKeyExchangeResult(SB)
@@ -0,0 +1,183 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !cmd_go_bootstrap
+
+package darwin
+
+import (
+	"hash"
+	"internal/abi"
+	"internal/cryptometrics"
+	"unsafe"
+)
+
+// A digest is a hash algorithm of CommonCrypto, with its HMAC algorithm.
+type digest struct {
+	name      string // as in the Security.framework algorithm names
+	alg       int    // internal/cryptometrics identifier
+	funcs     ccDigestFuncs
+	hmacAlg   uint32 // CCHmacAlgorithm
+	size      int
+	blockSize int
+}
+
+var (
+	sha1MD = &digest{"SHA1", cryptometrics.SHA1, ccDigestFuncs{
+		abi.FuncPCABI0(darwin_CC_SHA1_Init_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA1_Update_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA1_Final_trampoline),
+	}, 0, 20, 64}
+	sha224MD = &digest{"SHA224", cryptometrics.SHA224, ccDigestFuncs{
+		abi.FuncPCABI0(darwin_CC_SHA224_Init_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA224_Update_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA224_Final_trampoline),
+	}, 5, 28, 64}
+	sha256MD = &digest{"SHA256", cryptometrics.SHA256, ccDigestFuncs{
+		abi.FuncPCABI0(darwin_CC_SHA256_Init_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA256_Update_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA256_Final_trampoline),
+	}, 2, 32, 64}
+	sha384MD = &digest{"SHA384", cryptometrics.SHA384, ccDigestFuncs{
+		abi.FuncPCABI0(darwin_CC_SHA384_Init_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA384_Update_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA384_Final_trampoline),
+	}, 3, 48, 128}
+	sha512MD = &digest{"SHA512", cryptometrics.SHA512, ccDigestFuncs{
+		abi.FuncPCABI0(darwin_CC_SHA512_Init_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA512_Update_trampoline),
+		abi.FuncPCABI0(darwin_CC_SHA512_Final_trampoline),
+	}, 4, 64, 128}
+)
+
+// A ccContext holds a CC_SHA1_CTX, CC_SHA256_CTX or CC_SHA512_CTX, the
+// largest of which is 208 bytes.
+type ccContext [26]uint64
+
+func (c *ccContext) ptr() unsafe.Pointer { return unsafe.Pointer(c) }
+
+// maxUpdate is the longest input passed to a single Update call, as
+// CC_SHA256_Update takes a 32-bit length. It is a variable for testing.
+var maxUpdate = 1 << 30
+
+// update writes p to the context c of d.
+func (d *digest) update(c *ccContext, p []byte) {
+	for len(p) > 0 {
+		n := len(p)
+		if n > maxUpdate {
+			n = maxUpdate
+		}
+		d.funcs.Update(c.ptr(), p[:n])
+		p = p[n:]
+	}
+}
+
+// sum writes the digest of p to out, which must be large enough to hold it.
+func (d *digest) sum(out, p []byte) {
+	_ = out[d.size-1]
+	countOp(d.alg, len(p))
+	var c ccContext
+	d.funcs.Init(c.ptr())
+	d.update(&c, p)
+	d.funcs.Final(out, c.ptr())
+}
+
+// hash writes the digest of the concatenation of parts to out, which must
+// be large enough to hold it, without counting an operation, for the
+// hashes computed within other operations.
+func (d *digest) hash(out []byte, parts ...[]byte) {
+	var c ccContext
+	d.funcs.Init(c.ptr())
+	for _, p := range parts {
+		d.update(&c, p)
+	}
+	d.funcs.Final(out, c.ptr())
+}
+
+func SHA1(p []byte) (sum [20]byte)   { sha1MD.sum(sum[:], p); return }
+func SHA224(p []byte) (sum [28]byte) { sha224MD.sum(sum[:], p); return }
+func SHA256(p []byte) (sum [32]byte) { sha256MD.sum(sum[:], p); return }
+func SHA384(p []byte) (sum [48]byte) { sha384MD.sum(sum[:], p); return }
+func SHA512(p []byte) (sum [64]byte) { sha512MD.sum(sum[:], p); return }
+
+// SHA1Into, SHA224Into, SHA256Into, SHA384Into, and SHA512Into write the
+// digest of p to the beginning of dst, which must be large enough to hold
+// it.
+
+func SHA1Into(dst, p []byte)   { sha1MD.sum(dst, p) }
+func SHA224Into(dst, p []byte) { sha224MD.sum(dst, p) }
+func SHA256Into(dst, p []byte) { sha256MD.sum(dst, p) }
+func SHA384Into(dst, p []byte) { sha384MD.sum(dst, p) }
+func SHA512Into(dst, p []byte) { sha512MD.sum(dst, p) }
+
+// SHA256Batch returns the SHA-256 digests of msgs.
+func SHA256Batch(msgs [][]byte) [][32]byte {
+	sums := make([][32]byte, len(msgs))
+	for i, m := range msgs {
+		sha256MD.sum(sums[i][:], m)
+	}
+	return sums
+}
+
+func NewSHA1() hash.Hash   { return newCCHash(sha1MD) }
+func NewSHA224() hash.Hash { return newCCHash(sha224MD) }
+func NewSHA256() hash.Hash { return newCCHash(sha256MD) }
+func NewSHA384() hash.Hash { return newCCHash(sha384MD) }
+func NewSHA512() hash.Hash { return newCCHash(sha512MD) }
+
+// A ccHash is a hash.Hash backed by a CommonCrypto context, which holds no
+// pointers, so that it can be copied. Its state can't be marshaled.
+type ccHash struct {
+	d   *digest
+	ctx ccContext
+}
+
+func newCCHash(d *digest) *ccHash {
+	h := &ccHash{d: d}
+	h.Reset()
+	return h
+}
+
+func (h *ccHash) Size() int      { return h.d.size }
+func (h *ccHash) BlockSize() int { return h.d.blockSize }
+
+func (h *ccHash) Reset() {
+	h.d.funcs.Init(h.ctx.ptr())
+}
+
+// Wipe zeroes the state of h, and resets it.
+func (h *ccHash) Wipe() {
+	clear(h.ctx[:])
+	h.Reset()
+}
+
+func (h *ccHash) Write(p []byte) (int, error) {
+	countBytes(h.d.alg, len(p))
+	h.d.update(&h.ctx, p)
+	return len(p), nil
+}
+
+func (h *ccHash) WriteString(s string) (int, error) {
+	return h.Write([]byte(s))
+}
+
+func (h *ccHash) WriteByte(c byte) error {
+	h.Write([]byte{c})
+	return nil
+}
+
+func (h *ccHash) Sum(in []byte) []byte {
+	countOp(h.d.alg, 0)
+	// Finish a copy of the context, so that writes to h can continue.
+	c := h.ctx
+	var out [64]byte // the largest digest size
+	h.d.funcs.Final(out[:], c.ptr())
+	return append(in, out[:h.d.size]...)
+}
+
+// Clone returns an independent copy of h.
+func (h *ccHash) Clone() (hash.Hash, error) {
+	c := *h
+	return &c, nil
+}
@@ -0,0 +1,312 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package backendtest implements the tests shared by the cryptographic
+// backends in crypto/internal/backend.
+//
+// Every backend exposes the same functions, on its own key types. The
+// tests here check them against known answers and round trips that all
+// backends must pass. Each backend then only tests what is specific to it,
+// such as the parameters it rejects.
+package backendtest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestSHA256 tests a one-shot and a streaming SHA-256 implementation.
+func TestSHA256(t *testing.T, sum func([]byte) [32]byte, newHash func() hash.Hash) {
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := sum([]byte("abc")); hex.EncodeToString(got[:]) != want {
+		t.Errorf("SHA256(abc) = %x, want %s", got, want)
+	}
+	h := newHash()
+	h.Write([]byte("a"))
+	h.Sum(nil) // must not affect the state
+	h.Write([]byte("bc"))
+	if got := h.Sum(nil); hex.EncodeToString(got) != want {
+		t.Errorf("streaming SHA256(abc) = %x, want %s", got, want)
+	}
+	h.Reset()
+	if got, empty := h.Sum(nil), sum(nil); !bytes.Equal(got, empty[:]) {
+		t.Errorf("SHA256 after Reset = %x, want %x", got, empty)
+	}
+}
+
+// TestHMAC tests HMAC-SHA256, with newSHA256 as the hash, and checks that
+// using an HMAC after Close panics.
+func TestHMAC(t *testing.T, newHMAC func(func() hash.Hash, []byte) hash.Hash, newSHA256 func() hash.Hash) {
+	// RFC 4231, Test Case 2.
+	h := newHMAC(newSHA256, []byte("Jefe"))
+	h.Write([]byte("what do ya want for nothing?"))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got := h.Sum(nil); hex.EncodeToString(got) != want {
+		t.Errorf("HMAC-SHA256 = %x, want %s", got, want)
+	}
+	h.Reset()
+	h.Write([]byte("what do ya want for nothing?"))
+	if got := h.Sum(nil); hex.EncodeToString(got) != want {
+		t.Errorf("HMAC-SHA256 after Reset = %x, want %s", got, want)
+	}
+	h.(interface{ Close() error }).Close()
+	defer func() {
+		if recover() == nil {
+			t.Error("Write after Close did not panic")
+		}
+	}()
+	h.Write(nil)
+}
+
+// TestAESGCM tests AES-GCM, as returned by the NewGCM method of the ciphers
+// made by newCipher.
+func TestAESGCM(t *testing.T, newCipher func([]byte) (cipher.Block, error)) {
+	// The Test Case 4 of the GCM specification.
+	key := decodeHex(t, "feffe9928665731c6d6a8f9467308308")
+	nonce := decodeHex(t, "cafebabefacedbaddecaf888")
+	plaintext := decodeHex(t, "d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39")
+	aad := decodeHex(t, "feedfacedeadbeeffeedfacedeadbeefabaddad2")
+	want := decodeHex(t, "42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091"+
+		"5bc94fbc3221a5db94fae95ae7121a47")
+
+	c, err := newCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := c.(interface {
+		NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+	}).NewGCM(len(nonce), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Seal(nil, nonce, plaintext, aad); !bytes.Equal(got, want) {
+		t.Errorf("Seal = %x, want %x", got, want)
+	}
+	got, err := g.Open(nil, nonce, want, aad)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open = %x, %v, want %x", got, err, plaintext)
+	}
+	want[0] ^= 1
+	if _, err := g.Open(nil, nonce, want, aad); err == nil {
+		t.Error("Open of a modified ciphertext succeeded")
+	}
+}
+
+// TestAESCBC tests AES-CBC, as returned by the NewCBCEncrypter and
+// NewCBCDecrypter methods of the ciphers made by newCipher.
+func TestAESCBC(t *testing.T, newCipher func([]byte) (cipher.Block, error)) {
+	// NIST SP 800-38A, F.2.1 and F.2.2.
+	key := decodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	iv := decodeHex(t, "000102030405060708090a0b0c0d0e0f")
+	plaintext := decodeHex(t, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51")
+	want := decodeHex(t, "7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b2")
+
+	c, err := newCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := c.(interface {
+		NewCBCEncrypter(iv []byte) cipher.BlockMode
+		NewCBCDecrypter(iv []byte) cipher.BlockMode
+	})
+	enc := modes.NewCBCEncrypter(iv)
+	// crypto/tls only pads the records of a block mode that is not also a
+	// stream.
+	if _, ok := enc.(cipher.Stream); ok {
+		t.Error("CBC mode implements cipher.Stream")
+	}
+	// Encrypt one block at a time, to check that the IV is carried over.
+	got := make([]byte, len(plaintext))
+	enc.CryptBlocks(got[:16], plaintext[:16])
+	enc.CryptBlocks(got[16:], plaintext[16:])
+	if !bytes.Equal(got, want) {
+		t.Errorf("CBC encryption = %x, want %x", got, want)
+	}
+	modes.NewCBCDecrypter(iv).CryptBlocks(got, got)
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("CBC decryption = %x, want %x", got, plaintext)
+	}
+}
+
+// RSA holds the RSA functions of a backend.
+type RSA[BigInt ~[]uint, Priv interface{ Close() error }, Pub any] struct {
+	GenerateKey    func(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error)
+	NewPrivateKey  func(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (Priv, error)
+	NewPublicKey   func(N, E BigInt) (Pub, error)
+	SignPKCS1v15   func(Priv, crypto.Hash, []byte) ([]byte, error)
+	VerifyPKCS1v15 func(Pub, crypto.Hash, []byte, []byte) error
+	SignPSS        func(Priv, crypto.Hash, []byte, int) ([]byte, error)
+	VerifyPSS      func(Pub, crypto.Hash, []byte, []byte, int) error
+	EncryptOAEP    func(h, mgfHash hash.Hash, pub Pub, msg, label []byte) ([]byte, error)
+	DecryptOAEP    func(h, mgfHash hash.Hash, priv Priv, ciphertext, label []byte) ([]byte, error)
+	NewSHA256      func() hash.Hash
+}
+
+// TestRSA generates a 2048-bit key and tests the operations that every
+// backend supports with it: PKCS #1 v1.5 signatures, PSS signatures with a
+// salt as long as the hash, and OAEP with SHA-256 and no label.
+//
+// It returns the key, for the tests of the backend, which must close priv.
+func TestRSA[BigInt ~[]uint, Priv interface{ Close() error }, Pub any](t *testing.T, r RSA[BigInt, Priv, Pub]) (priv Priv, pub Pub) {
+	N, E, D, P, Q, Dp, Dq, Qinv, err := r.GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err = r.NewPrivateKey(N, E, D, P, Q, Dp, Dq, Qinv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err = r.NewPublicKey(N, E)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashed := decodeHex(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824") // SHA-256("hello")
+	sig, err := r.SignPKCS1v15(priv, crypto.SHA256, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifyPKCS1v15(pub, crypto.SHA256, hashed, sig); err != nil {
+		t.Errorf("VerifyRSAPKCS1v15: %v", err)
+	}
+
+	sig, err = r.SignPSS(priv, crypto.SHA256, hashed, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A salt length of 0 accepts any salt when verifying.
+	for _, saltLen := range []int{-1, 0} {
+		if err := r.VerifyPSS(pub, crypto.SHA256, hashed, sig, saltLen); err != nil {
+			t.Errorf("VerifyRSAPSS with salt length %d: %v", saltLen, err)
+		}
+	}
+	sig[0] ^= 1
+	if err := r.VerifyPSS(pub, crypto.SHA256, hashed, sig, 0); err == nil {
+		t.Error("VerifyRSAPSS of a modified signature succeeded")
+	}
+	if _, err := r.SignPSS(priv, crypto.SHA256, hashed, -3); err == nil {
+		t.Error("SignRSAPSS with an invalid salt length succeeded")
+	}
+
+	ciphertext, err := r.EncryptOAEP(r.NewSHA256(), r.NewSHA256(), pub, []byte("msg"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := r.DecryptOAEP(r.NewSHA256(), r.NewSHA256(), priv, ciphertext, nil)
+	if err != nil || string(msg) != "msg" {
+		t.Errorf("DecryptRSAOAEP = %q, %v, want %q", msg, err, "msg")
+	}
+	ciphertext[0] ^= 1
+	if _, err := r.DecryptOAEP(r.NewSHA256(), r.NewSHA256(), priv, ciphertext, nil); err == nil {
+		t.Error("DecryptRSAOAEP of a modified ciphertext succeeded")
+	}
+	return priv, pub
+}
+
+// ECDSA holds the ECDSA functions of a backend.
+type ECDSA[BigInt ~[]uint, Priv interface{ Close() error }, Pub any] struct {
+	GenerateKey   func(curve string) (X, Y, D BigInt, err error)
+	NewPrivateKey func(curve string, X, Y, D BigInt) (Priv, error)
+	NewPublicKey  func(curve string, X, Y BigInt) (Pub, error)
+	Sign          func(Priv, []byte) ([]byte, error)
+	Verify        func(Pub, []byte, []byte) bool
+}
+
+// TestECDSA tests signing and verifying with a new key on each of curves.
+// The signed hash is as long as a SHA-512 hash, so that it must be
+// truncated on the smaller curves.
+func TestECDSA[BigInt ~[]uint, Priv interface{ Close() error }, Pub any](t *testing.T, e ECDSA[BigInt, Priv, Pub], curves ...string) {
+	for _, curve := range curves {
+		X, Y, D, err := e.GenerateKey(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := e.NewPrivateKey(curve, X, Y, D)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := e.NewPublicKey(curve, X, Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashed := bytes.Repeat([]byte{0xa5}, 64)
+		sig, err := e.Sign(priv, hashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !e.Verify(pub, hashed, sig) {
+			t.Errorf("%s: VerifyECDSA failed", curve)
+		}
+		hashed[0] ^= 1
+		if e.Verify(pub, hashed, sig) {
+			t.Errorf("%s: VerifyECDSA of a modified hash succeeded", curve)
+		}
+		priv.Close()
+	}
+}
+
+// ECDH holds the ECDH functions of a backend.
+type ECDH[Priv interface{ PublicKey() (Pub, error) }, Pub interface{ Bytes() []byte }] struct {
+	GenerateKey   func(curve string) (Priv, []byte, error)
+	NewPrivateKey func(curve string, bytes []byte) (Priv, error)
+	ECDH          func(Priv, Pub) ([]byte, error)
+}
+
+// TestECDH tests a key exchange between two new keys on each of curves.
+func TestECDH[Priv interface{ PublicKey() (Pub, error) }, Pub interface{ Bytes() []byte }](t *testing.T, e ECDH[Priv, Pub], curves ...string) {
+	for _, curve := range curves {
+		a, aBytes, err := e.GenerateKey(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _, err := e.GenerateKey(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aPub, err := a.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bPub, err := b.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Reimport a, to check that the public key is derived correctly.
+		a2, err := e.NewPrivateKey(curve, aBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a2Pub, err := a2.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(a2Pub.Bytes(), aPub.Bytes()) {
+			t.Errorf("%s: public key of reimported key = %x, want %x", curve, a2Pub.Bytes(), aPub.Bytes())
+		}
+		s1, err := e.ECDH(a, bPub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, err := e.ECDH(b, a2Pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(s1, s2) {
+			t.Errorf("%s: shared secrets differ: %x, %x", curve, s1, s2)
+		}
+	}
+}
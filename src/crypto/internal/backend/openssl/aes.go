@@ -0,0 +1,455 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"crypto/cipher"
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+type aesKeySizeError int
+
+func (k aesKeySizeError) Error() string {
+	return "crypto/aes: invalid key size " + strconv.Itoa(int(k))
+}
+
+const aesBlockSize = 16
+
+// The AES ciphers, indexed by mode and then by key size: 128, 192, or 256
+// bits.
+var (
+	aesECB [3]*C.EVP_CIPHER
+	aesCBC [3]*C.EVP_CIPHER
+	aesCTR [3]*C.EVP_CIPHER
+	aesGCM [3]*C.EVP_CIPHER
+	aesKW  [3]*C.EVP_CIPHER
+	aesKWP [3]*C.EVP_CIPHER
+)
+
+func initAES() error {
+	for mode, ciphers := range map[string]*[3]*C.EVP_CIPHER{"ECB": &aesECB, "CBC": &aesCBC, "CTR": &aesCTR, "GCM": &aesGCM, "WRAP": &aesKW, "WRAP-PAD": &aesKWP} {
+		for i := range ciphers {
+			name := "AES-" + strconv.Itoa(128+64*i) + "-" + mode
+			cname := C.CString(name)
+			ciphers[i] = C.go_openssl_EVP_CIPHER_fetch(nil, cname, nil)
+			C.free(unsafe.Pointer(cname))
+			if ciphers[i] == nil {
+				return newFail("EVP_CIPHER_fetch(" + name + ")")
+			}
+		}
+	}
+	return nil
+}
+
+type aesCipher struct {
+	key []byte
+	idx int // index in the cipher arrays
+
+	// mu guards enc and dec, which hold the key schedule, since an
+	// EVP_CIPHER_CTX can't be used concurrently.
+	mu       sync.Mutex
+	enc, dec *C.EVP_CIPHER_CTX
+}
+
+type extraModes interface {
+	// Copied out of crypto/aes/modes.go.
+	NewCBCEncrypter(iv []byte) cipher.BlockMode
+	NewCBCDecrypter(iv []byte) cipher.BlockMode
+	NewCTR(iv []byte) cipher.Stream
+	NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+}
+
+var _ extraModes = (*aesCipher)(nil)
+
+func NewAESCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, aesKeySizeError(len(key))
+	}
+	c := &aesCipher{key: append([]byte(nil), key...), idx: len(key)/8 - 2}
+	var err error
+	if c.enc, err = newCipherCtx(aesECB[c.idx], c.key, nil, 1); err != nil {
+		return nil, err
+	}
+	if c.dec, err = newCipherCtx(aesECB[c.idx], c.key, nil, 0); err != nil {
+		C.go_openssl_EVP_CIPHER_CTX_free(c.enc)
+		return nil, err
+	}
+	// Note: Because of the finalizer, any time c.enc or c.dec is passed
+	// to cgo, that call must be followed by a call to runtime.KeepAlive(c),
+	// to make sure c is not collected (and finalized) before the cgo call
+	// returns.
+	runtime.SetFinalizer(c, (*aesCipher).finalize)
+	return c, nil
+}
+
+// newCipherCtx returns a context for cipher, keyed with key, without
+// padding, and for encryption if enc is 1 or decryption if enc is 0.
+func newCipherCtx(cipher *C.EVP_CIPHER, key, iv []byte, enc C.int) (*C.EVP_CIPHER_CTX, error) {
+	ctx := C.go_openssl_EVP_CIPHER_CTX_new()
+	if ctx == nil {
+		return nil, newFail("EVP_CIPHER_CTX_new")
+	}
+	if C.go_openssl_EVP_CipherInit_ex(ctx, cipher, nil, base(key), base(iv), enc) != 1 {
+		C.go_openssl_EVP_CIPHER_CTX_free(ctx)
+		return nil, newFail("EVP_CipherInit_ex")
+	}
+	if C.go_openssl_EVP_CIPHER_CTX_set_padding(ctx, 0) != 1 {
+		C.go_openssl_EVP_CIPHER_CTX_free(ctx)
+		return nil, newFail("EVP_CIPHER_CTX_set_padding")
+	}
+	return ctx, nil
+}
+
+func (c *aesCipher) finalize() {
+	C.go_openssl_EVP_CIPHER_CTX_free(c.enc)
+	C.go_openssl_EVP_CIPHER_CTX_free(c.dec)
+}
+
+func (c *aesCipher) BlockSize() int { return aesBlockSize }
+
+// Close zeroes the key and frees the key schedules of c, which OpenSSL
+// zeroes, immediately rather than when c is garbage collected. c must not
+// be used afterwards. The modes created from c hold their own copy of the
+// key, and are not affected.
+func (c *aesCipher) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != nil {
+		runtime.SetFinalizer(c, nil)
+		c.finalize()
+		clear(c.key)
+		c.key, c.enc, c.dec = nil, nil, nil
+	}
+	return nil
+}
+
+func (c *aesCipher) checkOpen() {
+	if c.key == nil {
+		panic("crypto/aes: use of closed cipher")
+	}
+}
+
+func (c *aesCipher) Encrypt(dst, src []byte) { c.crypt(c.enc, dst, src) }
+func (c *aesCipher) Decrypt(dst, src []byte) { c.crypt(c.dec, dst, src) }
+
+func (c *aesCipher) crypt(ctx *C.EVP_CIPHER_CTX, dst, src []byte) {
+	if len(src) < aesBlockSize {
+		panic("crypto/aes: input not full block")
+	}
+	if len(dst) < aesBlockSize {
+		panic("crypto/aes: output not full block")
+	}
+	if inexactOverlap(dst[:aesBlockSize], src[:aesBlockSize]) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	countOp(cryptometrics.AES, aesBlockSize)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkOpen()
+	var n C.int
+	if C.go_openssl_EVP_CipherUpdate(ctx, base(dst), &n, base(src), aesBlockSize) != 1 {
+		panic(newFail("EVP_CipherUpdate"))
+	}
+	runtime.KeepAlive(c)
+}
+
+// A cipherMode is a CBC or CTR mode, backed by its own EVP_CIPHER_CTX.
+// The modes are distinct types, because callers such as crypto/tls tell
+// block modes and streams apart by their methods.
+type cipherMode struct {
+	ctx *C.EVP_CIPHER_CTX
+}
+
+func (c *aesCipher) newMode(ciphers *[3]*C.EVP_CIPHER, iv []byte, enc C.int) cipherMode {
+	c.checkOpen()
+	if len(iv) != aesBlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	ctx, err := newCipherCtx(ciphers[c.idx], c.key, iv, enc)
+	if err != nil {
+		panic(err)
+	}
+	return cipherMode{ctx}
+}
+
+func (x *cipherMode) finalize() {
+	C.go_openssl_EVP_CIPHER_CTX_free(x.ctx)
+}
+
+type cbcMode struct {
+	cipherMode
+}
+
+type ctrStream struct {
+	cipherMode
+}
+
+func (c *aesCipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	x := &cbcMode{c.newMode(&aesCBC, iv, 1)}
+	runtime.SetFinalizer(x, (*cbcMode).finalize)
+	return x
+}
+
+func (c *aesCipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	x := &cbcMode{c.newMode(&aesCBC, iv, 0)}
+	runtime.SetFinalizer(x, (*cbcMode).finalize)
+	return x
+}
+
+func (c *aesCipher) NewCTR(iv []byte) cipher.Stream {
+	x := &ctrStream{c.newMode(&aesCTR, iv, 1)}
+	runtime.SetFinalizer(x, (*ctrStream).finalize)
+	return x
+}
+
+func (x *cbcMode) BlockSize() int { return aesBlockSize }
+
+func (x *cbcMode) CryptBlocks(dst, src []byte) {
+	if len(src)%aesBlockSize != 0 {
+		panic("crypto/cipher: input not full blocks")
+	}
+	x.crypt(dst, src)
+	runtime.KeepAlive(x)
+}
+
+func (x *cbcMode) SetIV(iv []byte) {
+	if len(iv) != aesBlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	if C.go_openssl_EVP_CipherInit_ex(x.ctx, nil, nil, nil, base(iv), -1) != 1 {
+		panic(newFail("EVP_CipherInit_ex"))
+	}
+	runtime.KeepAlive(x)
+}
+
+func (x *ctrStream) XORKeyStream(dst, src []byte) {
+	x.crypt(dst, src)
+	runtime.KeepAlive(x)
+}
+
+// crypt runs the mode over src. Callers must keep x alive.
+func (x *cipherMode) crypt(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("crypto/cipher: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+	countOp(cryptometrics.AES, len(src))
+	for len(src) > 0 {
+		// EVP_CipherUpdate takes an int length.
+		n := len(src)
+		if n > 1<<30 {
+			n = 1 << 30
+		}
+		var outl C.int
+		if C.go_openssl_EVP_CipherUpdate(x.ctx, base(dst), &outl, base(src), C.int(n)) != 1 {
+			panic(newFail("EVP_CipherUpdate"))
+		}
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+type gcm struct {
+	// enc is keyed for encryption with the cipher, and copied by each Seal
+	// and Open, so that gcm can be used concurrently.
+	enc *C.EVP_CIPHER_CTX
+
+	// tls enforces the strictly increasing nonces of TLS 1.2, which
+	// FIPS 140-3 requires; next is the smallest valid explicit nonce.
+	tls  bool
+	mu   sync.Mutex
+	next uint64
+}
+
+const (
+	gcmBlockSize         = 16
+	gcmTagSize           = 16
+	gcmStandardNonceSize = 12
+)
+
+type noGCM struct {
+	cipher.Block
+}
+
+func (c *aesCipher) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
+	if nonceSize != gcmStandardNonceSize && tagSize != gcmTagSize {
+		return nil, unsupported("crypto/aes: GCM tag and nonce sizes can't be non-standard at the same time")
+	}
+	// Fall back to standard library for GCM with non-standard nonce or tag size.
+	if nonceSize != gcmStandardNonceSize {
+		recordFallback(cryptometrics.AESGCM, "non-standard nonce size")
+		return cipher.NewGCMWithNonceSize(&noGCM{c}, nonceSize)
+	}
+	if tagSize != gcmTagSize {
+		recordFallback(cryptometrics.AESGCM, "non-standard tag size")
+		return cipher.NewGCMWithTagSize(&noGCM{c}, tagSize)
+	}
+	return c.newGCM(false)
+}
+
+// NewGCMTLS returns an AES-GCM AEAD for TLS 1.2, which panics if the
+// explicit part of the nonce, its last 8 bytes, doesn't increase between
+// calls to Seal.
+func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
+	return c.(*aesCipher).newGCM(true)
+}
+
+func (c *aesCipher) newGCM(tls bool) (cipher.AEAD, error) {
+	c.checkOpen()
+	enc, err := newCipherCtx(aesGCM[c.idx], c.key, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	g := &gcm{enc: enc, tls: tls}
+	// Note: Because of the finalizer, any time g.enc is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(g),
+	// to make sure g is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(g, (*gcm).finalize)
+	return g, nil
+}
+
+func (g *gcm) finalize() {
+	C.go_openssl_EVP_CIPHER_CTX_free(g.enc)
+}
+
+// Close frees the key schedule of g, which OpenSSL zeroes, immediately
+// rather than when g is garbage collected. g must not be used afterwards.
+func (g *gcm) Close() error {
+	if g.enc != nil {
+		runtime.SetFinalizer(g, nil)
+		g.finalize()
+		g.enc = nil
+	}
+	return nil
+}
+
+func (g *gcm) checkOpen() {
+	if g.enc == nil {
+		panic("crypto/cipher: use of closed GCM")
+	}
+}
+
+func (g *gcm) NonceSize() int { return gcmStandardNonceSize }
+func (g *gcm) Overhead() int  { return gcmTagSize }
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	g.checkOpen()
+	if len(nonce) != gcmStandardNonceSize {
+		panic("cipher: incorrect nonce length given to GCM")
+	}
+	if uint64(len(plaintext)) > ((1<<32)-2)*aesBlockSize || len(plaintext)+gcmTagSize < len(plaintext) {
+		panic("cipher: message too large for GCM")
+	}
+	if len(dst)+len(plaintext)+gcmTagSize < len(dst) {
+		panic("cipher: message too large for buffer")
+	}
+	if g.tls {
+		counter := uint64(0)
+		for _, b := range nonce[gcmStandardNonceSize-8:] {
+			counter = counter<<8 | uint64(b)
+		}
+		g.mu.Lock()
+		ok := counter >= g.next && counter != 1<<64-1
+		if ok {
+			g.next = counter + 1
+		}
+		g.mu.Unlock()
+		if !ok {
+			panic("crypto/cipher: TLS GCM nonce not increasing")
+		}
+	}
+
+	// Make room in dst to append plaintext+overhead.
+	n := len(dst)
+	for cap(dst) < n+len(plaintext)+gcmTagSize {
+		dst = append(dst[:cap(dst)], 0)
+	}
+	dst = dst[:n+len(plaintext)+gcmTagSize]
+
+	// Check delayed until now to make sure len(dst) is accurate.
+	if inexactOverlap(dst[n:], plaintext) {
+		panic("cipher: invalid buffer overlap")
+	}
+
+	countOp(cryptometrics.AESGCM, len(plaintext))
+	ok := C.go_openssl_gcm_seal(g.enc, base(dst[n:]),
+		base(nonce), C.size_t(len(nonce)),
+		base(plaintext), C.size_t(len(plaintext)),
+		base(additionalData), C.size_t(len(additionalData)))
+	runtime.KeepAlive(g)
+	if ok != 1 {
+		panic(newFail("EVP_CipherFinal_ex"))
+	}
+	return dst
+}
+
+var errOpen = errors.New("cipher: message authentication failed")
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	g.checkOpen()
+	if len(nonce) != gcmStandardNonceSize {
+		panic("cipher: incorrect nonce length given to GCM")
+	}
+	countOp(cryptometrics.AESGCM, len(ciphertext))
+	if len(ciphertext) < gcmTagSize {
+		countFailure(cryptometrics.AESGCM)
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > ((1<<32)-2)*aesBlockSize+gcmTagSize {
+		countFailure(cryptometrics.AESGCM)
+		return nil, errOpen
+	}
+
+	// Make room in dst to append ciphertext without tag.
+	n := len(dst)
+	for cap(dst) < n+len(ciphertext)-gcmTagSize {
+		dst = append(dst[:cap(dst)], 0)
+	}
+	dst = dst[:n+len(ciphertext)-gcmTagSize]
+
+	// Check delayed until now to make sure len(dst) is accurate.
+	if inexactOverlap(dst[n:], ciphertext) {
+		panic("cipher: invalid buffer overlap")
+	}
+
+	ok := C.go_openssl_gcm_open(g.enc, base(dst[n:]),
+		base(nonce), C.size_t(len(nonce)),
+		base(ciphertext), C.size_t(len(ciphertext)),
+		base(additionalData), C.size_t(len(additionalData)))
+	runtime.KeepAlive(g)
+	if ok != 1 {
+		C.go_openssl_ERR_clear_error()
+		countFailure(cryptometrics.AESGCM)
+		clear(dst[n:])
+		return nil, errOpen
+	}
+	return dst, nil
+}
+
+func anyOverlap(x, y []byte) bool {
+	return len(x) > 0 && len(y) > 0 &&
+		uintptr(unsafe.Pointer(&x[0])) <= uintptr(unsafe.Pointer(&y[len(y)-1])) &&
+		uintptr(unsafe.Pointer(&y[0])) <= uintptr(unsafe.Pointer(&x[len(x)-1]))
+}
+
+func inexactOverlap(x, y []byte) bool {
+	if len(x) == 0 || len(y) == 0 || &x[0] == &y[0] {
+		return false
+	}
+	return anyOverlap(x, y)
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+	"unsafe"
+)
+
+var cmacMAC *C.EVP_MAC
+
+func initCMAC() error {
+	name := C.CString("CMAC")
+	defer C.free(unsafe.Pointer(name))
+	if cmacMAC = C.go_openssl_EVP_MAC_fetch(nil, name, nil); cmacMAC == nil {
+		return newFail("EVP_MAC_fetch(CMAC)")
+	}
+	return nil
+}
+
+// cmacCiphers are the names of the CBC ciphers CMAC is keyed with,
+// indexed like the AES cipher arrays.
+var cmacCiphers = [3]*C.char{C.CString("AES-128-CBC"), C.CString("AES-192-CBC"), C.CString("AES-256-CBC")}
+
+// NewCMAC returns a new AES-CMAC, as specified by NIST SP 800-38B, keyed
+// with the AES key key.
+func NewCMAC(key []byte) (hash.Hash, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, aesKeySizeError(len(key))
+	}
+	h := &evpCMAC{ctx: C.go_openssl_EVP_MAC_CTX_new(cmacMAC)}
+	if h.ctx == nil {
+		return nil, newFail("EVP_MAC_CTX_new")
+	}
+	// Note: Because of the finalizer, any time h.ctx is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(h),
+	// to make sure h is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(h, (*evpCMAC).finalize)
+	if C.go_openssl_cmac_init(h.ctx, cmacCiphers[len(key)/8-2], base(key), C.size_t(len(key))) != 1 {
+		return nil, newFail("EVP_MAC_init")
+	}
+	runtime.KeepAlive(h)
+	return h, nil
+}
+
+// An evpCMAC is a CMAC backed by an EVP_MAC_CTX, which holds its own copy
+// of the key.
+type evpCMAC struct {
+	ctx *C.EVP_MAC_CTX
+}
+
+func (h *evpCMAC) finalize() {
+	C.go_openssl_EVP_MAC_CTX_free(h.ctx)
+}
+
+// Close frees the context, which OpenSSL zeroes, immediately rather than
+// when h is garbage collected. h must not be used afterwards.
+func (h *evpCMAC) Close() error {
+	if h.ctx != nil {
+		runtime.SetFinalizer(h, nil)
+		h.finalize()
+		h.ctx = nil
+	}
+	return nil
+}
+
+func (h *evpCMAC) checkOpen() {
+	if h.ctx == nil {
+		panic("openssl: use of closed CMAC")
+	}
+}
+
+func (h *evpCMAC) Size() int      { return aesBlockSize }
+func (h *evpCMAC) BlockSize() int { return aesBlockSize }
+
+func (h *evpCMAC) Reset() {
+	h.checkOpen()
+	// A NULL key restarts the MAC with the current key.
+	if C.go_openssl_EVP_MAC_init(h.ctx, nil, 0, nil) != 1 {
+		panic(newFail("EVP_MAC_init"))
+	}
+	runtime.KeepAlive(h)
+}
+
+func (h *evpCMAC) Write(p []byte) (int, error) {
+	h.checkOpen()
+	countBytes(cryptometrics.AESCMAC, len(p))
+	if len(p) > 0 && C.go_openssl_EVP_MAC_update(h.ctx, base(p), C.size_t(len(p))) != 1 {
+		panic(newFail("EVP_MAC_update"))
+	}
+	runtime.KeepAlive(h)
+	return len(p), nil
+}
+
+func (h *evpCMAC) Sum(in []byte) []byte {
+	h.checkOpen()
+	countOp(cryptometrics.AESCMAC, 0)
+	ctx := C.go_openssl_EVP_MAC_CTX_dup(h.ctx)
+	runtime.KeepAlive(h)
+	if ctx == nil {
+		panic(newFail("EVP_MAC_CTX_dup"))
+	}
+	defer C.go_openssl_EVP_MAC_CTX_free(ctx)
+	var out [aesBlockSize]byte
+	var n C.size_t
+	if C.go_openssl_EVP_MAC_final(ctx, base(out[:]), &n, C.size_t(len(out))) != 1 {
+		panic(newFail("EVP_MAC_final"))
+	}
+	return append(in, out[:n]...)
+}
+
+// Clone returns an independent copy of h.
+func (h *evpCMAC) Clone() (hash.Hash, error) {
+	h.checkOpen()
+	c := &evpCMAC{ctx: C.go_openssl_EVP_MAC_CTX_dup(h.ctx)}
+	runtime.KeepAlive(h)
+	if c.ctx == nil {
+		return nil, newFail("EVP_MAC_CTX_dup")
+	}
+	runtime.SetFinalizer(c, (*evpCMAC).finalize)
+	return c, nil
+}
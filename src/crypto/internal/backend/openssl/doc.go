@@ -0,0 +1,16 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package openssl provides access to the cryptographic implementations of
+// the system OpenSSL 3 libcrypto, which it loads at run time. It is the
+// backend of crypto/internal/boring when the opensslcrypto GOEXPERIMENT is
+// enabled on linux with cgo, and otherwise it is empty.
+//
+// libcrypto is used through its EVP interfaces and providers, so that,
+// when the system is configured to use the OpenSSL FIPS provider, all
+// operations are performed by the FIPS module.
+package openssl
+
+// A BigInt is the raw words from a BigInt, as in crypto/internal/boring.
+type BigInt []uint
@@ -0,0 +1,183 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"errors"
+	"internal/cryptometrics"
+	"runtime"
+)
+
+type PublicKeyECDH struct {
+	curve string
+	pkey  *C.EVP_PKEY
+	bytes []byte
+}
+
+func (k *PublicKeyECDH) finalize() {
+	C.go_openssl_EVP_PKEY_free(k.pkey)
+}
+
+type PrivateKeyECDH struct {
+	curve  string
+	pkey   *C.EVP_PKEY
+	pub    []byte // the encoding of the public key
+	closed bool
+}
+
+func (k *PrivateKeyECDH) finalize() {
+	C.go_openssl_EVP_PKEY_free(k.pkey)
+}
+
+// Close frees the key, which OpenSSL zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyECDH) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+// ecdhCurveSize returns the byte size of the field of curve, and an error
+// for the curves that crypto/ecdh doesn't implement with this package.
+func ecdhCurveSize(curve string) (C.int, int, error) {
+	if curve == "P-224" {
+		return 0, 0, errUnknownCurve
+	}
+	return curveNID(curve)
+}
+
+func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
+	if len(bytes) < 1 {
+		return nil, errors.New("NewPublicKeyECDH: missing key")
+	}
+	if _, _, err := ecdhCurveSize(curve); err != nil {
+		return nil, err
+	}
+	pkey, err := fromData(ecType, C.GO_EVP_PKEY_PUBLIC_KEY,
+		keyParam{name: paramGroup, str: []byte(curve), utf8: true},
+		keyParam{name: paramPub, str: bytes})
+	if err != nil {
+		return nil, errors.New("point not on curve")
+	}
+	k := &PublicKeyECDH{curve, pkey, append([]byte(nil), bytes...)}
+	// Note: Because of the finalizer, any time k.pkey is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(k, (*PublicKeyECDH).finalize)
+	return k, nil
+}
+
+func (k *PublicKeyECDH) Bytes() []byte { return k.bytes }
+
+func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
+	nid, size, err := ecdhCurveSize(curve)
+	if err != nil {
+		return nil, err
+	}
+	// OpenSSL doesn't derive the public key when importing a private key,
+	// so compute it first.
+	bn := bytesToBN(bytes)
+	if bn == nil {
+		return nil, newFail("BN_lebin2bn")
+	}
+	pub := make([]byte, 1+2*size)
+	n := C.go_openssl_ec_pub_from_priv(nid, bn, base(pub), C.size_t(len(pub)))
+	priv := bnToBig(bn)
+	C.go_openssl_BN_clear_free(bn)
+	if int(n) != len(pub) {
+		clear(priv)
+		return nil, newFail("EC_POINT_mul")
+	}
+	pkey, err := fromData(ecType, C.GO_EVP_PKEY_KEYPAIR,
+		keyParam{name: paramGroup, str: []byte(curve), utf8: true},
+		keyParam{name: paramPub, str: pub},
+		keyParam{name: paramPriv, bn: priv})
+	clear(priv)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyECDH{curve: curve, pkey: pkey, pub: pub}
+	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
+	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
+	return k, nil
+}
+
+func (k *PrivateKeyECDH) PublicKey() (*PublicKeyECDH, error) {
+	checkOpen(k.closed)
+	return NewPublicKeyECDH(k.curve, k.pub)
+}
+
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) (_ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+	checkOpen(priv.closed)
+
+	ctx := C.go_openssl_EVP_PKEY_CTX_new(priv.pkey, nil)
+	runtime.KeepAlive(priv)
+	if ctx == nil {
+		return nil, newFail("EVP_PKEY_CTX_new")
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	if C.go_openssl_EVP_PKEY_derive_init(ctx) != 1 {
+		return nil, newFail("EVP_PKEY_derive_init")
+	}
+	ok := C.go_openssl_EVP_PKEY_derive_set_peer(ctx, pub.pkey)
+	runtime.KeepAlive(pub)
+	if ok != 1 {
+		return nil, newFail("EVP_PKEY_derive_set_peer")
+	}
+	var outLen C.size_t
+	if C.go_openssl_EVP_PKEY_derive(ctx, nil, &outLen) != 1 {
+		return nil, newFail("EVP_PKEY_derive")
+	}
+	out := make([]byte, outLen)
+	if C.go_openssl_EVP_PKEY_derive(ctx, base(out), &outLen) != 1 {
+		return nil, newFail("EVP_PKEY_derive")
+	}
+	return out[:outLen], nil
+}
+
+func GenerateKeyECDH(curve string) (_ *PrivateKeyECDH, _ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+
+	_, size, err := ecdhCurveSize(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkey, err := generateKeyEC(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	x, err := getBN(pkey, paramQx)
+	if err != nil {
+		C.go_openssl_EVP_PKEY_free(pkey)
+		return nil, nil, err
+	}
+	y, err := getBN(pkey, paramQy)
+	if err != nil {
+		C.go_openssl_EVP_PKEY_free(pkey)
+		return nil, nil, err
+	}
+	d, err := getBN(pkey, paramPriv)
+	if err != nil {
+		C.go_openssl_EVP_PKEY_free(pkey)
+		return nil, nil, err
+	}
+	k := &PrivateKeyECDH{curve: curve, pkey: pkey, pub: encodePoint(size, x, y)}
+	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
+	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
+	bytes := bigBytes(d, size)
+	clear(d)
+	return k, bytes, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"crypto"
+	"internal/cryptometrics"
+	"math/bits"
+	"runtime"
+	"strconv"
+	"unsafe"
+)
+
+var (
+	ecType = C.CString("EC")
+
+	paramGroup = C.CString("group")
+	paramPub   = C.CString("pub")
+	paramPriv  = C.CString("priv")
+	paramQx    = C.CString("qx")
+	paramQy    = C.CString("qy")
+)
+
+var errUnknownCurve = unsupported("openssl: unknown elliptic curve")
+
+// curveNID returns the NID and the byte size of the field of curve.
+func curveNID(curve string) (C.int, int, error) {
+	switch curve {
+	case "P-224":
+		return C.GO_NID_secp224r1, 28, nil
+	case "P-256":
+		return C.GO_NID_X9_62_prime256v1, 32, nil
+	case "P-384":
+		return C.GO_NID_secp384r1, 48, nil
+	case "P-521":
+		return C.GO_NID_secp521r1, 66, nil
+	}
+	return 0, 0, errUnknownCurve
+}
+
+// bigBytes returns x as a big-endian byte slice of the given size.
+func bigBytes(x BigInt, size int) []byte {
+	b := make([]byte, size)
+	for i, w := range x {
+		for j := 0; j < bits.UintSize/8; j++ {
+			if k := size - 1 - i*bits.UintSize/8 - j; k >= 0 {
+				b[k] = byte(w >> (8 * j))
+			}
+		}
+	}
+	return b
+}
+
+// encodePoint returns the uncompressed encoding of the point (X, Y).
+func encodePoint(size int, X, Y BigInt) []byte {
+	return append(append([]byte{4}, bigBytes(X, size)...), bigBytes(Y, size)...)
+}
+
+type PrivateKeyECDSA struct {
+	pkey   *C.EVP_PKEY
+	closed bool
+}
+
+func (k *PrivateKeyECDSA) finalize() {
+	C.go_openssl_EVP_PKEY_free(k.pkey)
+}
+
+// Close frees the key, which OpenSSL zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyECDSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+type PublicKeyECDSA struct {
+	pkey *C.EVP_PKEY
+}
+
+func (k *PublicKeyECDSA) finalize() {
+	C.go_openssl_EVP_PKEY_free(k.pkey)
+}
+
+func NewPublicKeyECDSA(curve string, X, Y BigInt) (*PublicKeyECDSA, error) {
+	_, size, err := curveNID(curve)
+	if err != nil {
+		return nil, err
+	}
+	pkey, err := fromData(ecType, C.GO_EVP_PKEY_PUBLIC_KEY,
+		keyParam{name: paramGroup, str: []byte(curve), utf8: true},
+		keyParam{name: paramPub, str: encodePoint(size, X, Y)})
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKeyECDSA{pkey}
+	// Note: Because of the finalizer, any time k.pkey is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(k, (*PublicKeyECDSA).finalize)
+	return k, nil
+}
+
+func NewPrivateKeyECDSA(curve string, X, Y BigInt, D BigInt) (*PrivateKeyECDSA, error) {
+	_, size, err := curveNID(curve)
+	if err != nil {
+		return nil, err
+	}
+	pkey, err := fromData(ecType, C.GO_EVP_PKEY_KEYPAIR,
+		keyParam{name: paramGroup, str: []byte(curve), utf8: true},
+		keyParam{name: paramPub, str: encodePoint(size, X, Y)},
+		keyParam{name: paramPriv, bn: D})
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyECDSA{pkey: pkey}
+	// Note: Because of the finalizer, any time k.pkey is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(k),
+	// to make sure k is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(k, (*PrivateKeyECDSA).finalize)
+	return k, nil
+}
+
+// SignMarshalECDSA signs hash, which is truncated to the size of the
+// curve, and returns the ASN.1 DER encoded signature.
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) (_ []byte, err error) {
+	countOp(cryptometrics.ECDSA, len(hash))
+	defer countResult(cryptometrics.ECDSA, &err)
+	checkOpen(priv.closed)
+
+	ctx := C.go_openssl_EVP_PKEY_CTX_new(priv.pkey, nil)
+	runtime.KeepAlive(priv)
+	if ctx == nil {
+		return nil, newFail("EVP_PKEY_CTX_new")
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	if C.go_openssl_EVP_PKEY_sign_init(ctx) != 1 {
+		return nil, newFail("EVP_PKEY_sign_init")
+	}
+	return signPKey(ctx, hash)
+}
+
+// SupportsDeterministicECDSA reports whether SignMarshalECDSADeterministic
+// is available, which requires OpenSSL 3.2 or later.
+func SupportsDeterministicECDSA() bool { return deterministicECDSA }
+
+// SignMarshalECDSADeterministic signs hash, the digest of a message with
+// h, with the nonce specified by RFC 6979, and returns the ASN.1 DER
+// encoded signature.
+func SignMarshalECDSADeterministic(priv *PrivateKeyECDSA, h crypto.Hash, hash []byte) (_ []byte, err error) {
+	countOp(cryptometrics.ECDSA, len(hash))
+	defer countResult(cryptometrics.ECDSA, &err)
+	checkOpen(priv.closed)
+
+	if !deterministicECDSA {
+		return nil, unsupported("openssl: deterministic ECDSA requires OpenSSL 3.2")
+	}
+	md := cryptoHashToMD(h)
+	if md == nil {
+		return nil, unsupported("openssl: unsupported hash function: " + strconv.Itoa(int(h)))
+	}
+	ctx := C.go_openssl_EVP_PKEY_CTX_new(priv.pkey, nil)
+	runtime.KeepAlive(priv)
+	if ctx == nil {
+		return nil, newFail("EVP_PKEY_CTX_new")
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	if C.go_openssl_EVP_PKEY_sign_init(ctx) != 1 {
+		return nil, newFail("EVP_PKEY_sign_init")
+	}
+	if C.go_openssl_EVP_PKEY_CTX_set_signature_md(ctx, md) != 1 {
+		return nil, newFail("EVP_PKEY_CTX_set_signature_md")
+	}
+	if C.go_openssl_ecdsa_set_deterministic(ctx) != 1 {
+		return nil, newFail("EVP_PKEY_CTX_set_params")
+	}
+	return signPKey(ctx, hash)
+}
+
+func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	countOp(cryptometrics.ECDSA, len(hash))
+	ctx := C.go_openssl_EVP_PKEY_CTX_new(pub.pkey, nil)
+	runtime.KeepAlive(pub)
+	ok := ctx != nil && C.go_openssl_EVP_PKEY_verify_init(ctx) == 1 && verifyPKey(ctx, hash, sig) == nil
+	C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	if !ok {
+		C.go_openssl_ERR_clear_error()
+		countFailure(cryptometrics.ECDSA)
+	}
+	return ok
+}
+
+// generateKeyEC returns a new EC key on curve.
+func generateKeyEC(curve string) (*C.EVP_PKEY, error) {
+	if _, _, err := curveNID(curve); err != nil {
+		return nil, err
+	}
+	return generateKey(ecType, func(ctx *C.EVP_PKEY_CTX) error {
+		name := C.CString(curve)
+		defer C.free(unsafe.Pointer(name))
+		if C.go_openssl_EVP_PKEY_CTX_set_group_name(ctx, name) != 1 {
+			return newFail("EVP_PKEY_CTX_set_group_name")
+		}
+		return nil
+	})
+}
+
+func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	countOp(cryptometrics.ECDSA, 0)
+	defer countResult(cryptometrics.ECDSA, &err)
+
+	pkey, err := generateKeyEC(curve)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer C.go_openssl_EVP_PKEY_free(pkey)
+	if X, err = getBN(pkey, paramQx); err != nil {
+		return nil, nil, nil, err
+	}
+	if Y, err = getBN(pkey, paramQy); err != nil {
+		return nil, nil, nil, err
+	}
+	if D, err = getBN(pkey, paramPriv); err != nil {
+		return nil, nil, nil, err
+	}
+	return X, Y, D, nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+#include <dlfcn.h>
+#include <string.h>
+#include "goopenssl.h"
+
+#define DEFINEFUNC(ret, func, args, argscall) ret (*_g_##func) args;
+#define DEFINEFUNC_VOID(func, args, argscall) void (*_g_##func) args;
+FOR_ALL_OPENSSL_FUNCTIONS
+#undef DEFINEFUNC
+#undef DEFINEFUNC_VOID
+
+const char *
+go_openssl_load(const char *file)
+{
+	void *handle = dlopen(file, RTLD_NOW | RTLD_GLOBAL);
+	if (handle == NULL)
+		return file;
+
+#define DEFINEFUNC(ret, func, args, argscall) \
+	_g_##func = dlsym(handle, #func); \
+	if (_g_##func == NULL) \
+		return #func;
+#define DEFINEFUNC_VOID(func, args, argscall) DEFINEFUNC(void, func, args, argscall)
+FOR_ALL_OPENSSL_FUNCTIONS
+#undef DEFINEFUNC
+#undef DEFINEFUNC_VOID
+
+	return NULL;
+}
+
+int
+go_openssl_hmac_init(EVP_MAC_CTX *ctx, const char *digest, const unsigned char *key, size_t keylen)
+{
+	OSSL_PARAM params[2];
+
+	params[0] = go_openssl_OSSL_PARAM_construct_utf8_string("digest", (char *)digest, 0);
+	params[1] = go_openssl_OSSL_PARAM_construct_end();
+	return go_openssl_EVP_MAC_init(ctx, key, keylen, params);
+}
+
+int
+go_openssl_cmac_init(EVP_MAC_CTX *ctx, const char *cipher, const unsigned char *key, size_t keylen)
+{
+	OSSL_PARAM params[2];
+
+	params[0] = go_openssl_OSSL_PARAM_construct_utf8_string("cipher", (char *)cipher, 0);
+	params[1] = go_openssl_OSSL_PARAM_construct_end();
+	return go_openssl_EVP_MAC_init(ctx, key, keylen, params);
+}
+
+// go_openssl_ecdsa_set_deterministic makes ctx derive ECDSA nonces as
+// specified by RFC 6979, which OpenSSL supports since 3.2. Earlier
+// versions ignore the parameter.
+int
+go_openssl_ecdsa_set_deterministic(EVP_PKEY_CTX *ctx)
+{
+	OSSL_PARAM params[2];
+	unsigned int nonce_type = 1;
+
+	params[0] = go_openssl_OSSL_PARAM_construct_uint("nonce-type", &nonce_type);
+	params[1] = go_openssl_OSSL_PARAM_construct_end();
+	return go_openssl_EVP_PKEY_CTX_set_params(ctx, params);
+}
+
+// go_openssl_gcm_crypt seals or opens in with a copy of the keyed context
+// tmpl, so that the AEAD can be used concurrently. The tag follows the
+// ciphertext, in out when sealing and in in when opening.
+static int
+go_openssl_gcm_crypt(const EVP_CIPHER_CTX *tmpl, int enc, unsigned char *out,
+	const unsigned char *nonce, size_t nonce_len,
+	const unsigned char *in, size_t in_len,
+	const unsigned char *aad, size_t aad_len)
+{
+	enum { tag_len = 16 };
+	unsigned char tag[tag_len];
+	EVP_CIPHER_CTX *ctx;
+	int ok = 0, n;
+
+	if (!enc) {
+		if (in_len < tag_len)
+			return 0;
+		in_len -= tag_len;
+	}
+	ctx = go_openssl_EVP_CIPHER_CTX_new();
+	if (ctx == NULL)
+		return 0;
+	if (go_openssl_EVP_CIPHER_CTX_copy(ctx, tmpl) != 1 ||
+		go_openssl_EVP_CIPHER_CTX_ctrl(ctx, GO_EVP_CTRL_AEAD_SET_IVLEN, (int)nonce_len, NULL) != 1 ||
+		go_openssl_EVP_CipherInit_ex(ctx, NULL, NULL, NULL, nonce, enc) != 1)
+		goto done;
+	if (aad_len > 0 && go_openssl_EVP_CipherUpdate(ctx, NULL, &n, aad, (int)aad_len) != 1)
+		goto done;
+	if (in_len > 0 && go_openssl_EVP_CipherUpdate(ctx, out, &n, in, (int)in_len) != 1)
+		goto done;
+	if (enc) {
+		if (go_openssl_EVP_CipherFinal_ex(ctx, tag, &n) != 1 ||
+			go_openssl_EVP_CIPHER_CTX_ctrl(ctx, GO_EVP_CTRL_AEAD_GET_TAG, tag_len, out + in_len) != 1)
+			goto done;
+	} else {
+		for (n = 0; n < tag_len; n++)
+			tag[n] = in[in_len + n];
+		if (go_openssl_EVP_CIPHER_CTX_ctrl(ctx, GO_EVP_CTRL_AEAD_SET_TAG, tag_len, tag) != 1 ||
+			go_openssl_EVP_CipherFinal_ex(ctx, tag, &n) != 1)
+			goto done;
+	}
+	ok = 1;
+done:
+	go_openssl_EVP_CIPHER_CTX_free(ctx);
+	return ok;
+}
+
+int
+go_openssl_gcm_seal(const EVP_CIPHER_CTX *tmpl, unsigned char *out,
+	const unsigned char *nonce, size_t nonce_len,
+	const unsigned char *in, size_t in_len,
+	const unsigned char *aad, size_t aad_len)
+{
+	return go_openssl_gcm_crypt(tmpl, 1, out, nonce, nonce_len, in, in_len, aad, aad_len);
+}
+
+int
+go_openssl_gcm_open(const EVP_CIPHER_CTX *tmpl, unsigned char *out,
+	const unsigned char *nonce, size_t nonce_len,
+	const unsigned char *in, size_t in_len,
+	const unsigned char *aad, size_t aad_len)
+{
+	return go_openssl_gcm_crypt(tmpl, 0, out, nonce, nonce_len, in, in_len, aad, aad_len);
+}
+
+// go_openssl_aes_wrap wraps (enc = 1) or unwraps (enc = 0) in with cipher,
+// an AES-WRAP or AES-WRAP-PAD cipher, keyed with key. It returns the length
+// written to out, or -1 on failure, which includes a failed integrity check.
+int
+go_openssl_aes_wrap(const EVP_CIPHER *cipher, const unsigned char *key, int enc,
+	unsigned char *out, const unsigned char *in, size_t in_len)
+{
+	EVP_CIPHER_CTX *ctx;
+	int n = -1, outl, finl;
+
+	ctx = go_openssl_EVP_CIPHER_CTX_new();
+	if (ctx == NULL)
+		return -1;
+	go_openssl_EVP_CIPHER_CTX_set_flags(ctx, GO_EVP_CIPHER_CTX_FLAG_WRAP_ALLOW);
+	if (go_openssl_EVP_CipherInit_ex(ctx, cipher, NULL, key, NULL, enc) == 1 &&
+		go_openssl_EVP_CipherUpdate(ctx, out, &outl, in, (int)in_len) == 1 &&
+		go_openssl_EVP_CipherFinal_ex(ctx, out + outl, &finl) == 1)
+		n = outl + finl;
+	go_openssl_EVP_CIPHER_CTX_free(ctx);
+	return n;
+}
+
+EVP_PKEY *
+go_openssl_pkey_fromdata(const char *type, int selection, OSSL_PARAM_BLD *bld)
+{
+	EVP_PKEY_CTX *ctx = NULL;
+	EVP_PKEY *pkey = NULL;
+	OSSL_PARAM *params;
+
+	params = go_openssl_OSSL_PARAM_BLD_to_param(bld);
+	if (params == NULL)
+		return NULL;
+	ctx = go_openssl_EVP_PKEY_CTX_new_from_name(NULL, type, NULL);
+	if (ctx == NULL ||
+		go_openssl_EVP_PKEY_fromdata_init(ctx) != 1 ||
+		go_openssl_EVP_PKEY_fromdata(ctx, &pkey, selection, params) != 1)
+		pkey = NULL;
+	go_openssl_EVP_PKEY_CTX_free(ctx);
+	go_openssl_OSSL_PARAM_free(params);
+	return pkey;
+}
+
+size_t
+go_openssl_ec_pub_from_priv(int nid, const BIGNUM *priv, unsigned char *out, size_t out_len)
+{
+	EC_GROUP *group;
+	EC_POINT *pt = NULL;
+	size_t n = 0;
+
+	group = go_openssl_EC_GROUP_new_by_curve_name(nid);
+	if (group == NULL)
+		return 0;
+	pt = go_openssl_EC_POINT_new(group);
+	if (pt != NULL && go_openssl_EC_POINT_mul(group, pt, priv, NULL, NULL, NULL) == 1)
+		n = go_openssl_EC_POINT_point2oct(group, pt, GO_POINT_CONVERSION_UNCOMPRESSED, out, out_len, NULL);
+	go_openssl_EC_POINT_free(pt);
+	go_openssl_EC_GROUP_free(group);
+	return n;
+}
+
+// The structures below mirror the private structures of libcrypto and of
+// the default and FIPS providers that lead to the state of a digest,
+// which have been unchanged since OpenSSL 3.0. They are only used with
+// those providers, and Init checks that the digest states they lead to
+// are the expected ones.
+
+// go_evp_md_ctx mirrors struct evp_md_ctx_st, from crypto/evp/evp_local.h.
+struct go_evp_md_ctx {
+	const EVP_MD *reqdigest;
+	const EVP_MD *digest;
+	ENGINE *engine;
+	unsigned long flags;
+	void *md_data;
+	EVP_PKEY_CTX *pctx;
+	int (*update)(EVP_MD_CTX *ctx, const void *data, size_t count);
+	void *algctx;
+};
+
+// go_evp_mac_ctx mirrors struct evp_mac_ctx_st, from crypto/evp/evp_local.h.
+struct go_evp_mac_ctx {
+	EVP_MAC *meth;
+	void *algctx;
+};
+
+// go_hmac_data mirrors the beginning of struct hmac_data_st, from
+// providers/implementations/macs/hmac_prov.c.
+struct go_hmac_data {
+	void *provctx;
+	struct go_hmac_ctx *ctx;
+};
+
+// go_hmac_ctx mirrors struct hmac_ctx_st, from crypto/hmac/hmac_local.h.
+struct go_hmac_ctx {
+	const EVP_MD *md;
+	EVP_MD_CTX *md_ctx[3]; // md_ctx, i_ctx and o_ctx
+};
+
+static int
+go_openssl_builtin(const OSSL_PROVIDER *prov)
+{
+	const char *name;
+
+	if (prov == NULL)
+		return 0;
+	name = go_openssl_OSSL_PROVIDER_get0_name(prov);
+	return name != NULL && (strcmp(name, "default") == 0 || strcmp(name, "fips") == 0);
+}
+
+void *
+go_openssl_md_state(const EVP_MD_CTX *ctx)
+{
+	const EVP_MD *md = go_openssl_EVP_MD_CTX_get0_md(ctx);
+
+	if (md == NULL || !go_openssl_builtin(go_openssl_EVP_MD_get0_provider(md)))
+		return NULL;
+	return ((const struct go_evp_md_ctx *)ctx)->algctx;
+}
+
+EVP_MD_CTX *
+go_openssl_hmac_md_ctx(EVP_MAC_CTX *ctx, int i)
+{
+	struct go_hmac_data *data;
+
+	if (i < 0 || i > 2 || !go_openssl_builtin(go_openssl_EVP_MAC_get0_provider(go_openssl_EVP_MAC_CTX_get0_mac(ctx))))
+		return NULL;
+	data = ((struct go_evp_mac_ctx *)ctx)->algctx;
+	if (data == NULL || data->ctx == NULL)
+		return NULL;
+	return data->ctx->md_ctx[i];
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This header file describes the subset of the OpenSSL 3 libcrypto ABI
+// used by package openssl. libcrypto is not linked into the binary:
+// go_openssl_load opens it with dlopen at run time and resolves each of
+// the functions listed in FOR_ALL_OPENSSL_FUNCTIONS with dlsym, so that
+// building Go does not require the OpenSSL headers or libraries.
+//
+// The types are opaque, except for OSSL_PARAM, and the constants are
+// copied from the OpenSSL 3.0 headers, whose ABI is stable across 3.x
+// releases.
+
+#include <stdlib.h> // size_t
+#include <stdint.h> // uint8_t
+
+// The opaque types are given a placeholder definition rather than left
+// incomplete. cgo checks arguments that point to incomplete types, and
+// the check allocates, which would make every call on a hash or cipher
+// context allocate. Go never looks inside them.
+#define GO_OPAQUE(tag, name) typedef struct tag { char _opaque; } name
+
+GO_OPAQUE(ossl_lib_ctx_st, OSSL_LIB_CTX);
+GO_OPAQUE(evp_md_st, EVP_MD);
+GO_OPAQUE(evp_md_ctx_st, EVP_MD_CTX);
+GO_OPAQUE(evp_mac_st, EVP_MAC);
+GO_OPAQUE(evp_mac_ctx_st, EVP_MAC_CTX);
+GO_OPAQUE(evp_cipher_st, EVP_CIPHER);
+GO_OPAQUE(evp_cipher_ctx_st, EVP_CIPHER_CTX);
+GO_OPAQUE(evp_pkey_st, EVP_PKEY);
+GO_OPAQUE(evp_pkey_ctx_st, EVP_PKEY_CTX);
+GO_OPAQUE(engine_st, ENGINE);
+GO_OPAQUE(bignum_st, BIGNUM);
+GO_OPAQUE(bignum_ctx, BN_CTX);
+GO_OPAQUE(ec_group_st, EC_GROUP);
+GO_OPAQUE(ec_point_st, EC_POINT);
+GO_OPAQUE(ossl_param_bld_st, OSSL_PARAM_BLD);
+GO_OPAQUE(ossl_provider_st, OSSL_PROVIDER);
+
+typedef struct ossl_param_st {
+	const char *key;
+	unsigned int data_type;
+	void *data;
+	size_t data_size;
+	size_t return_size;
+} OSSL_PARAM;
+
+enum {
+	GO_OPENSSL_VERSION = 0,
+
+	GO_EVP_PKEY_PUBLIC_KEY = 0x86,
+	GO_EVP_PKEY_KEYPAIR = 0x87,
+
+	GO_EVP_CTRL_AEAD_SET_IVLEN = 0x9,
+	GO_EVP_CTRL_AEAD_GET_TAG = 0x10,
+	GO_EVP_CTRL_AEAD_SET_TAG = 0x11,
+
+	GO_EVP_CIPHER_CTX_FLAG_WRAP_ALLOW = 0x1,
+
+	GO_RSA_PKCS1_PADDING = 1,
+	GO_RSA_NO_PADDING = 3,
+	GO_RSA_PKCS1_OAEP_PADDING = 4,
+	GO_RSA_PKCS1_PSS_PADDING = 6,
+
+	GO_RSA_PSS_SALTLEN_DIGEST = -1,
+	GO_RSA_PSS_SALTLEN_AUTO = -2,
+	GO_RSA_PSS_SALTLEN_MAX = -3,
+
+	GO_NID_X9_62_prime256v1 = 415,
+	GO_NID_secp224r1 = 713,
+	GO_NID_secp384r1 = 715,
+	GO_NID_secp521r1 = 716,
+
+	GO_POINT_CONVERSION_UNCOMPRESSED = 4,
+};
+
+// FOR_ALL_OPENSSL_FUNCTIONS lists the libcrypto functions used by the
+// package, as DEFINEFUNC(return type, name, parameters, arguments), or
+// DEFINEFUNC_VOID(name, parameters, arguments) for functions that do not
+// return a value.
+#define FOR_ALL_OPENSSL_FUNCTIONS \
+DEFINEFUNC(unsigned long, OpenSSL_version_num, (void), ()) \
+DEFINEFUNC(const char *, OpenSSL_version, (int t), (t)) \
+DEFINEFUNC(int, EVP_default_properties_is_fips_enabled, (OSSL_LIB_CTX *libctx), (libctx)) \
+DEFINEFUNC(unsigned long, ERR_get_error, (void), ()) \
+DEFINEFUNC_VOID(ERR_error_string_n, (unsigned long e, char *buf, size_t len), (e, buf, len)) \
+DEFINEFUNC_VOID(ERR_clear_error, (void), ()) \
+DEFINEFUNC(const char *, OSSL_PROVIDER_get0_name, (const OSSL_PROVIDER *prov), (prov)) \
+DEFINEFUNC(void *, CRYPTO_malloc, (size_t num, const char *file, int line), (num, file, line)) \
+DEFINEFUNC(int, RAND_bytes, (unsigned char *buf, int num), (buf, num)) \
+DEFINEFUNC(EVP_MD *, EVP_MD_fetch, (OSSL_LIB_CTX *ctx, const char *algorithm, const char *properties), (ctx, algorithm, properties)) \
+DEFINEFUNC(int, EVP_MD_get_size, (const EVP_MD *md), (md)) \
+DEFINEFUNC(int, EVP_MD_get_block_size, (const EVP_MD *md), (md)) \
+DEFINEFUNC(const OSSL_PROVIDER *, EVP_MD_get0_provider, (const EVP_MD *md), (md)) \
+DEFINEFUNC(const EVP_MD *, EVP_MD_CTX_get0_md, (const EVP_MD_CTX *ctx), (ctx)) \
+DEFINEFUNC(EVP_MD_CTX *, EVP_MD_CTX_new, (void), ()) \
+DEFINEFUNC_VOID(EVP_MD_CTX_free, (EVP_MD_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_MD_CTX_copy_ex, (EVP_MD_CTX *out, const EVP_MD_CTX *in), (out, in)) \
+DEFINEFUNC(int, EVP_DigestInit_ex, (EVP_MD_CTX *ctx, const EVP_MD *type, ENGINE *impl), (ctx, type, impl)) \
+DEFINEFUNC(int, EVP_DigestUpdate, (EVP_MD_CTX *ctx, const void *d, size_t cnt), (ctx, d, cnt)) \
+DEFINEFUNC(int, EVP_DigestFinal_ex, (EVP_MD_CTX *ctx, unsigned char *md, unsigned int *s), (ctx, md, s)) \
+DEFINEFUNC(int, EVP_Digest, (const void *data, size_t count, unsigned char *md, unsigned int *size, const EVP_MD *type, ENGINE *impl), (data, count, md, size, type, impl)) \
+DEFINEFUNC(EVP_MAC *, EVP_MAC_fetch, (OSSL_LIB_CTX *libctx, const char *algorithm, const char *properties), (libctx, algorithm, properties)) \
+DEFINEFUNC(const OSSL_PROVIDER *, EVP_MAC_get0_provider, (const EVP_MAC *mac), (mac)) \
+DEFINEFUNC(EVP_MAC_CTX *, EVP_MAC_CTX_new, (EVP_MAC *mac), (mac)) \
+DEFINEFUNC(EVP_MAC *, EVP_MAC_CTX_get0_mac, (EVP_MAC_CTX *ctx), (ctx)) \
+DEFINEFUNC_VOID(EVP_MAC_CTX_free, (EVP_MAC_CTX *ctx), (ctx)) \
+DEFINEFUNC(EVP_MAC_CTX *, EVP_MAC_CTX_dup, (const EVP_MAC_CTX *src), (src)) \
+DEFINEFUNC(int, EVP_MAC_init, (EVP_MAC_CTX *ctx, const unsigned char *key, size_t keylen, const OSSL_PARAM params[]), (ctx, key, keylen, params)) \
+DEFINEFUNC(int, EVP_MAC_update, (EVP_MAC_CTX *ctx, const unsigned char *data, size_t datalen), (ctx, data, datalen)) \
+DEFINEFUNC(int, EVP_MAC_final, (EVP_MAC_CTX *ctx, unsigned char *out, size_t *outl, size_t outsize), (ctx, out, outl, outsize)) \
+DEFINEFUNC(EVP_CIPHER *, EVP_CIPHER_fetch, (OSSL_LIB_CTX *ctx, const char *algorithm, const char *properties), (ctx, algorithm, properties)) \
+DEFINEFUNC(EVP_CIPHER_CTX *, EVP_CIPHER_CTX_new, (void), ()) \
+DEFINEFUNC_VOID(EVP_CIPHER_CTX_free, (EVP_CIPHER_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_CIPHER_CTX_copy, (EVP_CIPHER_CTX *out, const EVP_CIPHER_CTX *in), (out, in)) \
+DEFINEFUNC_VOID(EVP_CIPHER_CTX_set_flags, (EVP_CIPHER_CTX *ctx, int flags), (ctx, flags)) \
+DEFINEFUNC(int, EVP_CIPHER_CTX_set_padding, (EVP_CIPHER_CTX *ctx, int pad), (ctx, pad)) \
+DEFINEFUNC(int, EVP_CIPHER_CTX_ctrl, (EVP_CIPHER_CTX *ctx, int type, int arg, void *ptr), (ctx, type, arg, ptr)) \
+DEFINEFUNC(int, EVP_CipherInit_ex, (EVP_CIPHER_CTX *ctx, const EVP_CIPHER *cipher, ENGINE *impl, const unsigned char *key, const unsigned char *iv, int enc), (ctx, cipher, impl, key, iv, enc)) \
+DEFINEFUNC(int, EVP_CipherUpdate, (EVP_CIPHER_CTX *ctx, unsigned char *out, int *outl, const unsigned char *in, int inl), (ctx, out, outl, in, inl)) \
+DEFINEFUNC(int, EVP_CipherFinal_ex, (EVP_CIPHER_CTX *ctx, unsigned char *outm, int *outl), (ctx, outm, outl)) \
+DEFINEFUNC_VOID(EVP_PKEY_free, (EVP_PKEY *key), (key)) \
+DEFINEFUNC(int, EVP_PKEY_get_size, (const EVP_PKEY *pkey), (pkey)) \
+DEFINEFUNC(int, EVP_PKEY_get_bn_param, (const EVP_PKEY *pkey, const char *key_name, BIGNUM **bn), (pkey, key_name, bn)) \
+DEFINEFUNC(EVP_PKEY_CTX *, EVP_PKEY_CTX_new, (EVP_PKEY *pkey, ENGINE *e), (pkey, e)) \
+DEFINEFUNC(EVP_PKEY_CTX *, EVP_PKEY_CTX_new_from_name, (OSSL_LIB_CTX *libctx, const char *name, const char *propquery), (libctx, name, propquery)) \
+DEFINEFUNC_VOID(EVP_PKEY_CTX_free, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_fromdata_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_fromdata, (EVP_PKEY_CTX *ctx, EVP_PKEY **ppkey, int selection, OSSL_PARAM params[]), (ctx, ppkey, selection, params)) \
+DEFINEFUNC(int, EVP_PKEY_keygen_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_keygen, (EVP_PKEY_CTX *ctx, EVP_PKEY **ppkey), (ctx, ppkey)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_rsa_keygen_bits, (EVP_PKEY_CTX *ctx, int bits), (ctx, bits)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_group_name, (EVP_PKEY_CTX *ctx, const char *name), (ctx, name)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_params, (EVP_PKEY_CTX *ctx, const OSSL_PARAM *params), (ctx, params)) \
+DEFINEFUNC(int, EVP_PKEY_encrypt_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_encrypt, (EVP_PKEY_CTX *ctx, unsigned char *out, size_t *outlen, const unsigned char *in, size_t inlen), (ctx, out, outlen, in, inlen)) \
+DEFINEFUNC(int, EVP_PKEY_decrypt_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_decrypt, (EVP_PKEY_CTX *ctx, unsigned char *out, size_t *outlen, const unsigned char *in, size_t inlen), (ctx, out, outlen, in, inlen)) \
+DEFINEFUNC(int, EVP_PKEY_sign_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_sign, (EVP_PKEY_CTX *ctx, unsigned char *sig, size_t *siglen, const unsigned char *tbs, size_t tbslen), (ctx, sig, siglen, tbs, tbslen)) \
+DEFINEFUNC(int, EVP_PKEY_verify_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_verify, (EVP_PKEY_CTX *ctx, const unsigned char *sig, size_t siglen, const unsigned char *tbs, size_t tbslen), (ctx, sig, siglen, tbs, tbslen)) \
+DEFINEFUNC(int, EVP_PKEY_derive_init, (EVP_PKEY_CTX *ctx), (ctx)) \
+DEFINEFUNC(int, EVP_PKEY_derive_set_peer, (EVP_PKEY_CTX *ctx, EVP_PKEY *peer), (ctx, peer)) \
+DEFINEFUNC(int, EVP_PKEY_derive, (EVP_PKEY_CTX *ctx, unsigned char *key, size_t *keylen), (ctx, key, keylen)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_rsa_padding, (EVP_PKEY_CTX *ctx, int pad_mode), (ctx, pad_mode)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_rsa_pss_saltlen, (EVP_PKEY_CTX *ctx, int saltlen), (ctx, saltlen)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_signature_md, (EVP_PKEY_CTX *ctx, const EVP_MD *md), (ctx, md)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_rsa_mgf1_md, (EVP_PKEY_CTX *ctx, const EVP_MD *md), (ctx, md)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set_rsa_oaep_md, (EVP_PKEY_CTX *ctx, const EVP_MD *md), (ctx, md)) \
+DEFINEFUNC(int, EVP_PKEY_CTX_set0_rsa_oaep_label, (EVP_PKEY_CTX *ctx, void *label, int llen), (ctx, label, llen)) \
+DEFINEFUNC(BIGNUM *, BN_lebin2bn, (const unsigned char *s, int len, BIGNUM *ret), (s, len, ret)) \
+DEFINEFUNC(int, BN_bn2lebinpad, (const BIGNUM *a, unsigned char *to, int tolen), (a, to, tolen)) \
+DEFINEFUNC(int, BN_bn2binpad, (const BIGNUM *a, unsigned char *to, int tolen), (a, to, tolen)) \
+DEFINEFUNC(int, BN_num_bits, (const BIGNUM *a), (a)) \
+DEFINEFUNC_VOID(BN_free, (BIGNUM *a), (a)) \
+DEFINEFUNC_VOID(BN_clear_free, (BIGNUM *a), (a)) \
+DEFINEFUNC(EC_GROUP *, EC_GROUP_new_by_curve_name, (int nid), (nid)) \
+DEFINEFUNC_VOID(EC_GROUP_free, (EC_GROUP *group), (group)) \
+DEFINEFUNC(EC_POINT *, EC_POINT_new, (const EC_GROUP *group), (group)) \
+DEFINEFUNC_VOID(EC_POINT_free, (EC_POINT *point), (point)) \
+DEFINEFUNC(int, EC_POINT_mul, (const EC_GROUP *group, EC_POINT *r, const BIGNUM *n, const EC_POINT *q, const BIGNUM *m, BN_CTX *ctx), (group, r, n, q, m, ctx)) \
+DEFINEFUNC(size_t, EC_POINT_point2oct, (const EC_GROUP *group, const EC_POINT *p, int form, unsigned char *buf, size_t len, BN_CTX *ctx), (group, p, form, buf, len, ctx)) \
+DEFINEFUNC(OSSL_PARAM_BLD *, OSSL_PARAM_BLD_new, (void), ()) \
+DEFINEFUNC_VOID(OSSL_PARAM_BLD_free, (OSSL_PARAM_BLD *bld), (bld)) \
+DEFINEFUNC(int, OSSL_PARAM_BLD_push_BN, (OSSL_PARAM_BLD *bld, const char *key, const BIGNUM *bn), (bld, key, bn)) \
+DEFINEFUNC(int, OSSL_PARAM_BLD_push_utf8_string, (OSSL_PARAM_BLD *bld, const char *key, const char *buf, size_t bsize), (bld, key, buf, bsize)) \
+DEFINEFUNC(int, OSSL_PARAM_BLD_push_octet_string, (OSSL_PARAM_BLD *bld, const char *key, const void *buf, size_t bsize), (bld, key, buf, bsize)) \
+DEFINEFUNC(OSSL_PARAM *, OSSL_PARAM_BLD_to_param, (OSSL_PARAM_BLD *bld), (bld)) \
+DEFINEFUNC_VOID(OSSL_PARAM_free, (OSSL_PARAM *p), (p)) \
+DEFINEFUNC(OSSL_PARAM, OSSL_PARAM_construct_utf8_string, (const char *key, char *buf, size_t bsize), (key, buf, bsize)) \
+DEFINEFUNC(OSSL_PARAM, OSSL_PARAM_construct_uint, (const char *key, unsigned int *buf), (key, buf)) \
+DEFINEFUNC(OSSL_PARAM, OSSL_PARAM_construct_end, (void), ()) \
+
+#define DEFINEFUNC(ret, func, args, argscall) \
+	extern ret (*_g_##func) args; \
+	static inline ret go_openssl_##func args { return _g_##func argscall; }
+#define DEFINEFUNC_VOID(func, args, argscall) \
+	extern void (*_g_##func) args; \
+	static inline void go_openssl_##func args { _g_##func argscall; }
+
+FOR_ALL_OPENSSL_FUNCTIONS
+
+#undef DEFINEFUNC
+#undef DEFINEFUNC_VOID
+
+// go_openssl_load opens the shared library named by file and resolves
+// the functions above. It returns NULL on success, or the name of the
+// library or function that could not be loaded.
+const char *go_openssl_load(const char *file);
+
+// The helpers below group calls that would otherwise each cross the cgo
+// boundary, or that build OSSL_PARAM arrays on the C stack.
+
+int go_openssl_hmac_init(EVP_MAC_CTX *ctx, const char *digest, const unsigned char *key, size_t keylen);
+int go_openssl_cmac_init(EVP_MAC_CTX *ctx, const char *cipher, const unsigned char *key, size_t keylen);
+int go_openssl_gcm_seal(const EVP_CIPHER_CTX *tmpl, unsigned char *out, const unsigned char *nonce, size_t nonce_len, const unsigned char *in, size_t in_len, const unsigned char *aad, size_t aad_len);
+int go_openssl_gcm_open(const EVP_CIPHER_CTX *tmpl, unsigned char *out, const unsigned char *nonce, size_t nonce_len, const unsigned char *in, size_t in_len, const unsigned char *aad, size_t aad_len);
+int go_openssl_aes_wrap(const EVP_CIPHER *cipher, const unsigned char *key, int enc, unsigned char *out, const unsigned char *in, size_t in_len);
+EVP_PKEY *go_openssl_pkey_fromdata(const char *type, int selection, OSSL_PARAM_BLD *bld);
+size_t go_openssl_ec_pub_from_priv(int nid, const BIGNUM *priv, unsigned char *out, size_t out_len);
+int go_openssl_ecdsa_set_deterministic(EVP_PKEY_CTX *ctx);
+
+// go_openssl_md_state returns the state of the digest in ctx, which is a
+// SHA_CTX, SHA256_CTX or SHA512_CTX for the SHA implementations of the
+// default and FIPS providers, or NULL for other providers.
+void *go_openssl_md_state(const EVP_MD_CTX *ctx);
+
+// go_openssl_hmac_md_ctx returns the running digest (i = 0), or the
+// digest primed with the inner (i = 1) or outer (i = 2) key pad, of an
+// HMAC of the default or FIPS provider, or NULL for other providers.
+EVP_MD_CTX *go_openssl_hmac_md_ctx(EVP_MAC_CTX *ctx, int i);
@@ -0,0 +1,213 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+	"unsafe"
+)
+
+var hmacMAC *C.EVP_MAC
+
+func initHMAC() error {
+	name := C.CString("HMAC")
+	defer C.free(unsafe.Pointer(name))
+	if hmacMAC = C.go_openssl_EVP_MAC_fetch(nil, name, nil); hmacMAC == nil {
+		return newFail("EVP_MAC_fetch(HMAC)")
+	}
+	return nil
+}
+
+// NewHMAC returns a new HMAC using OpenSSL.
+// The function h must return a hash implemented by
+// this package (for example, h could be openssl.NewSHA256).
+// If h is not recognized, NewHMAC returns nil.
+func NewHMAC(h func() hash.Hash, key []byte) hash.Hash {
+	eh, ok := h().(*evpHash)
+	if !ok {
+		return nil
+	}
+	hm := &evpHMAC{d: eh.d, ctx: C.go_openssl_EVP_MAC_CTX_new(hmacMAC)}
+	if hm.ctx == nil {
+		panic(newFail("EVP_MAC_CTX_new"))
+	}
+	// Note: Because of the finalizer, any time hm.ctx is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(hm),
+	// to make sure hm is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(hm, (*evpHMAC).finalize)
+	// The key must not be NULL, which would reuse the previous key,
+	// even when it is empty.
+	if C.go_openssl_hmac_init(hm.ctx, hm.d.name, (*C.uchar)(unsafe.Pointer(&*addr(key))), C.size_t(len(key))) != 1 {
+		panic(newFail("EVP_MAC_init"))
+	}
+	runtime.KeepAlive(hm)
+	return hm
+}
+
+// An evpHMAC is an HMAC backed by an EVP_MAC_CTX, which holds its own
+// copy of the key.
+type evpHMAC struct {
+	d   *digest
+	ctx *C.EVP_MAC_CTX
+}
+
+func (h *evpHMAC) finalize() {
+	C.go_openssl_EVP_MAC_CTX_free(h.ctx)
+}
+
+// Close frees the context, which OpenSSL zeroes, immediately rather than
+// when h is garbage collected. h must not be used afterwards.
+func (h *evpHMAC) Close() error {
+	if h.ctx != nil {
+		runtime.SetFinalizer(h, nil)
+		h.finalize()
+		h.ctx = nil
+	}
+	return nil
+}
+
+func (h *evpHMAC) checkOpen() {
+	if h.ctx == nil {
+		panic("openssl: use of closed HMAC")
+	}
+}
+
+func (h *evpHMAC) Size() int      { return h.d.size }
+func (h *evpHMAC) BlockSize() int { return h.d.blockSize }
+
+func (h *evpHMAC) Reset() {
+	h.checkOpen()
+	// A NULL key restarts the MAC with the current key.
+	if C.go_openssl_EVP_MAC_init(h.ctx, nil, 0, nil) != 1 {
+		panic(newFail("EVP_MAC_init"))
+	}
+	runtime.KeepAlive(h)
+}
+
+func (h *evpHMAC) Write(p []byte) (int, error) {
+	h.checkOpen()
+	countBytes(cryptometrics.HMAC, len(p))
+	if len(p) > 0 && C.go_openssl_EVP_MAC_update(h.ctx, (*C.uchar)(unsafe.Pointer(&*addr(p))), C.size_t(len(p))) != 1 {
+		panic(newFail("EVP_MAC_update"))
+	}
+	runtime.KeepAlive(h)
+	return len(p), nil
+}
+
+func (h *evpHMAC) Sum(in []byte) []byte {
+	h.checkOpen()
+	countOp(cryptometrics.HMAC, 0)
+	ctx := C.go_openssl_EVP_MAC_CTX_dup(h.ctx)
+	runtime.KeepAlive(h)
+	if ctx == nil {
+		panic(newFail("EVP_MAC_CTX_dup"))
+	}
+	defer C.go_openssl_EVP_MAC_CTX_free(ctx)
+	var out [64]byte // the largest digest size
+	var n C.size_t
+	if C.go_openssl_EVP_MAC_final(ctx, (*C.uchar)(unsafe.Pointer(&*addr(out[:]))), &n, C.size_t(len(out))) != 1 {
+		panic(newFail("EVP_MAC_final"))
+	}
+	return append(in, out[:n]...)
+}
+
+// Clone returns an independent copy of h.
+func (h *evpHMAC) Clone() (hash.Hash, error) {
+	h.checkOpen()
+	c := &evpHMAC{d: h.d, ctx: C.go_openssl_EVP_MAC_CTX_dup(h.ctx)}
+	runtime.KeepAlive(h)
+	if c.ctx == nil {
+		return nil, newFail("EVP_MAC_CTX_dup")
+	}
+	runtime.SetFinalizer(c, (*evpHMAC).finalize)
+	return c, nil
+}
+
+// hmacMagic and the layout below must match crypto/hmac, so that states
+// move freely between the OpenSSL and Go implementations.
+const (
+	hmacMagic       = "hmac\x01"
+	maxHMACStateLen = 1 << 16
+)
+
+// hmacStates lists the digests of the HMAC state, in the order of the
+// marshaled state: the inner and outer digests, then the running one.
+var hmacStates = [3]C.int{1, 2, 0}
+
+// digestState returns the state of the digest i of h, as numbered by
+// go_openssl_hmac_md_ctx. Callers must keep h alive while using it.
+func (h *evpHMAC) digestState(i C.int) (unsafe.Pointer, error) {
+	ctx := C.go_openssl_hmac_md_ctx(h.ctx, i)
+	if ctx == nil {
+		return nil, errNotMarshalable
+	}
+	return h.d.state(ctx)
+}
+
+func (h *evpHMAC) MarshalBinary() ([]byte, error) {
+	h.checkOpen()
+	defer runtime.KeepAlive(h)
+	b := []byte(hmacMagic)
+	for _, i := range hmacStates {
+		p, err := h.digestState(i)
+		if err != nil {
+			return nil, err
+		}
+		b = appendUint32(b, uint32(h.d.marshaledSize()))
+		b = h.d.appendState(b, p)
+	}
+	return b, nil
+}
+
+func (h *evpHMAC) UnmarshalBinary(b []byte) error {
+	h.checkOpen()
+	if len(b) < len(hmacMagic) || string(b[:len(hmacMagic)]) != hmacMagic {
+		return errors.New("crypto/hmac: invalid hash state identifier")
+	}
+	b = b[len(hmacMagic):]
+	var states [3][]byte
+	for i := range states {
+		if len(b) < 4 {
+			return errors.New("crypto/hmac: invalid hash state size")
+		}
+		var n uint32
+		b, n = consumeUint32(b)
+		if n > maxHMACStateLen || uint32(len(b)) < n {
+			return errors.New("crypto/hmac: invalid hash state size")
+		}
+		states[i], b = b[:n], b[n:]
+	}
+	if len(b) != 0 {
+		return errors.New("crypto/hmac: invalid hash state size")
+	}
+	// Check everything before touching h, so that a bad state leaves it
+	// unchanged.
+	defer runtime.KeepAlive(h)
+	var ps [3]unsafe.Pointer
+	for j, i := range hmacStates {
+		if err := h.d.checkState(states[j]); err != nil {
+			return err
+		}
+		p, err := h.digestState(i)
+		if err != nil {
+			return err
+		}
+		ps[j] = p
+	}
+	// Reset copies the restored inner digest to the running one, since
+	// the provider only rekeys when given a key.
+	for j := range ps {
+		h.d.setState(ps[j], states[j])
+	}
+	return nil
+}
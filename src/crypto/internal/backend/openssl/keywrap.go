@@ -0,0 +1,66 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"errors"
+	"internal/cryptometrics"
+)
+
+var errUnwrap = errors.New("keywrap: integrity check failed")
+
+// keyWrapCipher returns the KW, or if pad is true the KWP, cipher for the
+// key encryption key kek.
+func keyWrapCipher(kek []byte, pad bool) (*C.EVP_CIPHER, error) {
+	switch len(kek) {
+	case 16, 24, 32:
+	default:
+		return nil, aesKeySizeError(len(kek))
+	}
+	if pad {
+		return aesKWP[len(kek)/8-2], nil
+	}
+	return aesKW[len(kek)/8-2], nil
+}
+
+// WrapKeyAES wraps key with the AES key encryption key kek, with the KW
+// mode of SP 800-38F, or with the KWP mode if pad is true. The length of
+// key must be valid for the mode.
+func WrapKeyAES(kek, key []byte, pad bool) ([]byte, error) {
+	cipher, err := keyWrapCipher(kek, pad)
+	if err != nil {
+		return nil, err
+	}
+	countOp(cryptometrics.AESKW, len(key))
+	out := make([]byte, (len(key)+7)/8*8+8)
+	n := C.go_openssl_aes_wrap(cipher, base(kek), 1, base(out), base(key), C.size_t(len(key)))
+	if n < 0 {
+		return nil, newFail("EVP_CipherUpdate")
+	}
+	return out[:n], nil
+}
+
+// UnwrapKeyAES unwraps wrapped with the AES key encryption key kek, with
+// the KW mode of SP 800-38F, or with the KWP mode if pad is true.
+func UnwrapKeyAES(kek, wrapped []byte, pad bool) ([]byte, error) {
+	cipher, err := keyWrapCipher(kek, pad)
+	if err != nil {
+		return nil, err
+	}
+	countOp(cryptometrics.AESKW, len(wrapped))
+	out := make([]byte, len(wrapped))
+	n := C.go_openssl_aes_wrap(cipher, base(kek), 0, base(out), base(wrapped), C.size_t(len(wrapped)))
+	if n < 0 {
+		C.go_openssl_ERR_clear_error()
+		countFailure(cryptometrics.AESKW)
+		clear(out)
+		return nil, errUnwrap
+	}
+	return out[:n], nil
+}
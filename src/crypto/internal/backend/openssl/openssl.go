@@ -0,0 +1,179 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #cgo LDFLAGS: -ldl
+// #include "goopenssl.h"
+import "C"
+import (
+	"errors"
+	"math/bits"
+	"unsafe"
+)
+
+// LibraryName is the shared library loaded by Init.
+const LibraryName = "libcrypto.so.3"
+
+// Failure classes of the errors returned by this package, as in
+// crypto/internal/boring.
+var (
+	ErrBackendFailure       = errors.New("openssl: backend failure")
+	ErrUnsupportedParameter = errors.New("openssl: unsupported parameter")
+)
+
+var version string
+
+// deterministicECDSA is whether libcrypto derives ECDSA nonces as
+// specified by RFC 6979 when asked to, which it does since OpenSSL 3.2.
+var deterministicECDSA bool
+
+// Init loads libcrypto and fetches the algorithms used by the package.
+// It must be called, and succeed, before any other function.
+func Init() error {
+	name := C.CString(LibraryName)
+	defer C.free(unsafe.Pointer(name))
+	if missing := C.go_openssl_load(name); missing != nil {
+		return errors.New("openssl: cannot load " + C.GoString(missing) + " from " + LibraryName)
+	}
+	if v := C.go_openssl_OpenSSL_version_num(); v>>28 != 3 {
+		return errors.New("openssl: " + LibraryName + " is not OpenSSL 3")
+	}
+	version = C.GoString(C.go_openssl_OpenSSL_version(C.GO_OPENSSL_VERSION))
+	deterministicECDSA = C.go_openssl_OpenSSL_version_num() >= 0x30200000
+	if err := initSHA(); err != nil {
+		return err
+	}
+	if err := initHMAC(); err != nil {
+		return err
+	}
+	if err := initCMAC(); err != nil {
+		return err
+	}
+	return initAES()
+}
+
+// Version returns the version string of the loaded libcrypto, such as
+// "OpenSSL 3.0.2 15 Mar 2022".
+func Version() string { return version }
+
+// FIPS reports whether libcrypto fetches algorithms from the FIPS
+// provider by default, as configured by the system.
+func FIPS() bool {
+	return C.go_openssl_EVP_default_properties_is_fips_enabled(nil) == 1
+}
+
+// fail is a failed libcrypto call, with the error queued by libcrypto.
+type fail struct {
+	fn  string
+	msg string
+}
+
+func (e *fail) Error() string {
+	if e.msg == "" {
+		return "openssl: " + e.fn + " failed"
+	}
+	return "openssl: " + e.fn + " failed: " + e.msg
+}
+
+func (e *fail) Unwrap() error { return ErrBackendFailure }
+
+// newFail returns an error for a failed call to fn, and clears the
+// libcrypto error queue.
+func newFail(fn string) error {
+	e := &fail{fn: fn}
+	if code := C.go_openssl_ERR_get_error(); code != 0 {
+		var buf [256]C.char
+		C.go_openssl_ERR_error_string_n(code, &buf[0], C.size_t(len(buf)))
+		e.msg = C.GoString(&buf[0])
+	}
+	C.go_openssl_ERR_clear_error()
+	return e
+}
+
+// unsupported is an error about a parameter that the package does not
+// support.
+type unsupported string
+
+func (e unsupported) Error() string { return string(e) }
+
+func (e unsupported) Unwrap() error { return ErrUnsupportedParameter }
+
+// base returns the address of the underlying array in b,
+// being careful not to panic when b has zero length.
+func base(b []byte) *C.uchar {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uchar)(unsafe.Pointer(&b[0]))
+}
+
+type randReader int
+
+func (randReader) Read(b []byte) (int, error) {
+	for p := b; len(p) > 0; {
+		n := len(p)
+		if n > 1<<30 {
+			n = 1 << 30
+		}
+		if C.go_openssl_RAND_bytes(base(p), C.int(n)) != 1 {
+			return 0, newFail("RAND_bytes")
+		}
+		p = p[n:]
+	}
+	return len(b), nil
+}
+
+const RandReader = randReader(0)
+
+// bigToBN returns x as a newly allocated BIGNUM.
+func bigToBN(x BigInt) *C.BIGNUM {
+	b := make([]byte, len(x)*bits.UintSize/8)
+	for i, w := range x {
+		for j := 0; j < bits.UintSize/8; j++ {
+			b[i*bits.UintSize/8+j] = byte(w >> (8 * j))
+		}
+	}
+	bn := C.go_openssl_BN_lebin2bn(base(b), C.int(len(b)), nil)
+	clear(b)
+	return bn
+}
+
+// bnToBig returns the value of bn.
+func bnToBig(bn *C.BIGNUM) BigInt {
+	n := (int(C.go_openssl_BN_num_bits(bn)) + bits.UintSize - 1) / bits.UintSize
+	b := make([]byte, n*bits.UintSize/8)
+	C.go_openssl_BN_bn2lebinpad(bn, base(b), C.int(len(b)))
+	x := make(BigInt, n)
+	for i := range x {
+		for j := 0; j < bits.UintSize/8; j++ {
+			x[i] |= uint(b[i*bits.UintSize/8+j]) << (8 * j)
+		}
+	}
+	clear(b)
+	return x
+}
+
+// bytesToBN returns the big-endian b as a newly allocated BIGNUM.
+func bytesToBN(b []byte) *C.BIGNUM {
+	le := make([]byte, len(b))
+	for i, c := range b {
+		le[len(b)-1-i] = c
+	}
+	bn := C.go_openssl_BN_lebin2bn(base(le), C.int(len(le)), nil)
+	clear(le)
+	return bn
+}
+
+// getBN returns the BIGNUM parameter name of pkey.
+func getBN(pkey *C.EVP_PKEY, name *C.char) (BigInt, error) {
+	var bn *C.BIGNUM
+	if C.go_openssl_EVP_PKEY_get_bn_param(pkey, name, &bn) != 1 {
+		return nil, newFail("EVP_PKEY_get_bn_param")
+	}
+	defer C.go_openssl_BN_clear_free(bn)
+	return bnToBig(bn), nil
+}
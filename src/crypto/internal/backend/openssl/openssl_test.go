@@ -0,0 +1,252 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+// Most functionality in this package is tested through crypto/internal/boring
+// by the tests of the crypto packages. The tests shared by all backends are in
+// backendtest, and the tests here check what is specific to OpenSSL.
+
+package openssl
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/internal/backend/internal/backendtest"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if err := Init(); err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSHA256(t *testing.T) { backendtest.TestSHA256(t, SHA256, NewSHA256) }
+func TestHMAC(t *testing.T)   { backendtest.TestHMAC(t, NewHMAC, NewSHA256) }
+func TestAESGCM(t *testing.T) { backendtest.TestAESGCM(t, NewAESCipher) }
+func TestAESCBC(t *testing.T) { backendtest.TestAESCBC(t, NewAESCipher) }
+
+func TestProviders(t *testing.T) {
+	// The digests are fetched once by Init, and their sizes read from the
+	// providers rather than hardcoded.
+	for _, tt := range []struct {
+		name            string
+		d               *digest
+		size, blockSize int
+	}{
+		{"SHA-1", sha1MD, 20, 64},
+		{"SHA-224", sha224MD, 28, 64},
+		{"SHA-256", sha256MD, 32, 64},
+		{"SHA-384", sha384MD, 48, 128},
+		{"SHA-512", sha512MD, 64, 128},
+		{"SHA-512/224", sha512_224MD, 28, 128},
+		{"SHA-512/256", sha512_256MD, 32, 128},
+	} {
+		if tt.d.md == nil || tt.d.size != tt.size || tt.d.blockSize != tt.blockSize {
+			t.Errorf("%s: fetched %p with size %d and block size %d, want %d and %d",
+				tt.name, tt.d.md, tt.d.size, tt.d.blockSize, tt.size, tt.blockSize)
+		}
+	}
+
+	// MD5 is only fetched if the configured providers implement it, which
+	// the FIPS provider does not.
+	if FIPS() && md5MD != nil {
+		t.Error("MD5 fetched from the FIPS provider")
+	}
+	N, E, D, P, Q, Dp, Dq, Qinv, err := GenerateKeyRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer priv.Close()
+	_, err = SignRSAPKCS1v15(priv, crypto.MD5, make([]byte, 16))
+	if md5MD == nil && !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("SignRSAPKCS1v15 with MD5 without a provider: %v, want ErrUnsupportedParameter", err)
+	}
+	if md5MD != nil && err != nil {
+		t.Errorf("SignRSAPKCS1v15 with MD5: %v", err)
+	}
+}
+
+func TestKeyWrap(t *testing.T) {
+	for _, tt := range []struct {
+		pad               bool
+		kek, key, wrapped string
+	}{
+		// RFC 3394, Section 4.1, and RFC 5649, Section 6.
+		{false, "000102030405060708090a0b0c0d0e0f", "00112233445566778899aabbccddeeff", "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5"},
+		{true, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", "466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f"},
+	} {
+		kek, key, want := decodeHex(t, tt.kek), decodeHex(t, tt.key), decodeHex(t, tt.wrapped)
+		wrapped, err := WrapKeyAES(kek, key, tt.pad)
+		if err != nil || !bytes.Equal(wrapped, want) {
+			t.Errorf("WrapKeyAES(pad=%v) = %x, %v, want %x", tt.pad, wrapped, err, want)
+		}
+		got, err := UnwrapKeyAES(kek, want, tt.pad)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("UnwrapKeyAES(pad=%v) = %x, %v, want %x", tt.pad, got, err, key)
+		}
+		want[0] ^= 1
+		if _, err := UnwrapKeyAES(kek, want, tt.pad); err == nil || errors.Is(err, ErrBackendFailure) {
+			t.Errorf("UnwrapKeyAES(pad=%v) of a modified key: %v, want an integrity error", tt.pad, err)
+		}
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493, Section 4, Example 2.
+	h, err := NewCMAC(decodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.(*evpCMAC).Close()
+	h.Write(decodeHex(t, "6bc1bee22e409f96e93d7e117393172a"))
+	want := decodeHex(t, "070a16b46b4d4144f79bdd9dd04a287c")
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("CMAC = %x, want %x", got, want)
+	}
+	// Sum doesn't change the state, and Reset restarts it.
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("second Sum = %x, want %x", got, want)
+	}
+	h.Reset()
+	if got, want := h.Sum(nil), decodeHex(t, "bb1d6929e95937287fa37d129b756746"); !bytes.Equal(got, want) {
+		t.Errorf("CMAC after Reset = %x, want %x", got, want)
+	}
+	if _, err := NewCMAC(make([]byte, 15)); err == nil {
+		t.Error("NewCMAC with a 15-byte key succeeded")
+	}
+}
+
+func TestAESCTR(t *testing.T) {
+	c, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr := c.(interface {
+		NewCTR(iv []byte) cipher.Stream
+	}).NewCTR(make([]byte, 16))
+	if _, ok := ctr.(cipher.BlockMode); ok {
+		t.Error("CTR mode implements cipher.BlockMode")
+	}
+}
+
+func TestRSA(t *testing.T) {
+	priv, pub := backendtest.TestRSA(t, backendtest.RSA[BigInt, *PrivateKeyRSA, *PublicKeyRSA]{
+		GenerateKey:    GenerateKeyRSA,
+		NewPrivateKey:  NewPrivateKeyRSA,
+		NewPublicKey:   NewPublicKeyRSA,
+		SignPKCS1v15:   SignRSAPKCS1v15,
+		VerifyPKCS1v15: VerifyRSAPKCS1v15,
+		SignPSS:        SignRSAPSS,
+		VerifyPSS:      VerifyRSAPSS,
+		EncryptOAEP:    EncryptRSAOAEP,
+		DecryptOAEP:    DecryptRSAOAEP,
+		NewSHA256:      NewSHA256,
+	})
+	defer priv.Close()
+
+	// OpenSSL computes the CRT values of a key that lacks them, and checks
+	// the ones it is given.
+	N, E, D, _, _, _, _, _, err := GenerateKeyRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPrivateKeyRSA(N, E, D, nil, nil, nil, nil, nil); err != nil {
+		t.Errorf("NewPrivateKeyRSA without CRT values: %v", err)
+	}
+	if _, err := NewPrivateKeyRSA(N, E, BigInt{42}, nil, nil, nil, nil, nil); err == nil {
+		t.Error("NewPrivateKeyRSA with a wrong D succeeded")
+	}
+
+	// OpenSSL supports OAEP labels, which CommonCrypto does not.
+	ciphertext, err := EncryptRSAOAEP(NewSHA256(), NewSHA256(), pub, []byte("msg"), []byte("label"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := DecryptRSAOAEP(NewSHA256(), NewSHA256(), priv, ciphertext, []byte("label"))
+	if err != nil || string(msg) != "msg" {
+		t.Errorf("DecryptRSAOAEP = %q, %v, want %q", msg, err, "msg")
+	}
+	if _, err := DecryptRSAOAEP(NewSHA256(), NewSHA256(), priv, ciphertext, nil); err == nil {
+		t.Error("DecryptRSAOAEP with the wrong label succeeded")
+	}
+}
+
+func TestECDSA(t *testing.T) {
+	backendtest.TestECDSA(t, backendtest.ECDSA[BigInt, *PrivateKeyECDSA, *PublicKeyECDSA]{
+		GenerateKey:   GenerateKeyECDSA,
+		NewPrivateKey: NewPrivateKeyECDSA,
+		NewPublicKey:  NewPublicKeyECDSA,
+		Sign:          SignMarshalECDSA,
+		Verify:        VerifyECDSA,
+	}, "P-224", "P-256", "P-384", "P-521")
+	if _, err := NewPublicKeyECDSA("P-192", BigInt{1}, BigInt{2}); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPublicKeyECDSA(P-192): %v, want ErrUnsupportedParameter", err)
+	}
+}
+
+func TestECDSADeterministic(t *testing.T) {
+	X, Y, D, err := GenerateKeyECDSA("P-256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := NewPrivateKeyECDSA("P-256", X, Y, D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer priv.Close()
+	hashed := SHA256([]byte("hello"))
+	sig, err := SignMarshalECDSADeterministic(priv, crypto.SHA256, hashed[:])
+	if !SupportsDeterministicECDSA() {
+		if !errors.Is(err, ErrUnsupportedParameter) {
+			t.Errorf("SignMarshalECDSADeterministic: %v, want ErrUnsupportedParameter", err)
+		}
+		t.Skipf("%s does not support deterministic ECDSA", Version())
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := SignMarshalECDSADeterministic(priv, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, sig2) {
+		t.Error("deterministic signatures of the same hash differ")
+	}
+	pub, err := NewPublicKeyECDSA("P-256", X, Y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyECDSA(pub, hashed[:], sig) {
+		t.Error("VerifyECDSA failed")
+	}
+}
+
+func TestECDH(t *testing.T) {
+	backendtest.TestECDH(t, backendtest.ECDH[*PrivateKeyECDH, *PublicKeyECDH]{
+		GenerateKey:   GenerateKeyECDH,
+		NewPrivateKey: NewPrivateKeyECDH,
+		ECDH:          ECDH,
+	}, "P-256", "P-384", "P-521")
+}
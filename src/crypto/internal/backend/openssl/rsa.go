@@ -0,0 +1,545 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+	"strconv"
+	"unsafe"
+)
+
+// Key types and parameter names, as in the OpenSSL core_names.h header.
+var (
+	rsaType = C.CString("RSA")
+
+	paramN    = C.CString("n")
+	paramE    = C.CString("e")
+	paramD    = C.CString("d")
+	paramP    = C.CString("rsa-factor1")
+	paramQ    = C.CString("rsa-factor2")
+	paramDp   = C.CString("rsa-exponent1")
+	paramDq   = C.CString("rsa-exponent2")
+	paramQinv = C.CString("rsa-coefficient1")
+)
+
+// A keyParam is a key parameter for fromData, either a BigInt or a string.
+type keyParam struct {
+	name *C.char
+	bn   BigInt
+	str  []byte // octet string, or UTF-8 string if utf8 is set
+	utf8 bool
+}
+
+// fromData returns a new EVP_PKEY of type typ, from params.
+func fromData(typ *C.char, selection C.int, params ...keyParam) (*C.EVP_PKEY, error) {
+	bld := C.go_openssl_OSSL_PARAM_BLD_new()
+	if bld == nil {
+		return nil, newFail("OSSL_PARAM_BLD_new")
+	}
+	defer C.go_openssl_OSSL_PARAM_BLD_free(bld)
+	// The builder keeps pointers to the values until the parameters are
+	// built, so they are allocated by C.
+	var bns []*C.BIGNUM
+	var strs []unsafe.Pointer
+	defer func() {
+		for _, bn := range bns {
+			C.go_openssl_BN_clear_free(bn)
+		}
+		for _, s := range strs {
+			C.free(s)
+		}
+	}()
+	for _, p := range params {
+		var ok C.int
+		if p.str != nil {
+			s := C.CBytes(p.str)
+			strs = append(strs, s)
+			if p.utf8 {
+				ok = C.go_openssl_OSSL_PARAM_BLD_push_utf8_string(bld, p.name, (*C.char)(s), C.size_t(len(p.str)))
+			} else {
+				ok = C.go_openssl_OSSL_PARAM_BLD_push_octet_string(bld, p.name, s, C.size_t(len(p.str)))
+			}
+		} else {
+			bn := bigToBN(p.bn)
+			if bn == nil {
+				return nil, newFail("BN_lebin2bn")
+			}
+			bns = append(bns, bn)
+			ok = C.go_openssl_OSSL_PARAM_BLD_push_BN(bld, p.name, bn)
+		}
+		if ok != 1 {
+			return nil, newFail("OSSL_PARAM_BLD_push")
+		}
+	}
+	pkey := C.go_openssl_pkey_fromdata(typ, selection, bld)
+	if pkey == nil {
+		return nil, newFail("EVP_PKEY_fromdata")
+	}
+	return pkey, nil
+}
+
+// generateKey returns a new EVP_PKEY of type typ, after calling setup on the
+// key generation context.
+func generateKey(typ *C.char, setup func(*C.EVP_PKEY_CTX) error) (*C.EVP_PKEY, error) {
+	ctx := C.go_openssl_EVP_PKEY_CTX_new_from_name(nil, typ, nil)
+	if ctx == nil {
+		return nil, newFail("EVP_PKEY_CTX_new_from_name")
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	if C.go_openssl_EVP_PKEY_keygen_init(ctx) != 1 {
+		return nil, newFail("EVP_PKEY_keygen_init")
+	}
+	if err := setup(ctx); err != nil {
+		return nil, err
+	}
+	var pkey *C.EVP_PKEY
+	if C.go_openssl_EVP_PKEY_keygen(ctx, &pkey) != 1 {
+		return nil, newFail("EVP_PKEY_keygen")
+	}
+	return pkey, nil
+}
+
+func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	countOp(cryptometrics.RSA, 0)
+	defer countResult(cryptometrics.RSA, &err)
+
+	bad := func(e error) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+		return nil, nil, nil, nil, nil, nil, nil, nil, e
+	}
+
+	pkey, err := generateKey(rsaType, func(ctx *C.EVP_PKEY_CTX) error {
+		if C.go_openssl_EVP_PKEY_CTX_set_rsa_keygen_bits(ctx, C.int(bits)) != 1 {
+			return newFail("EVP_PKEY_CTX_set_rsa_keygen_bits")
+		}
+		return nil
+	})
+	if err != nil {
+		return bad(err)
+	}
+	defer C.go_openssl_EVP_PKEY_free(pkey)
+
+	var out [8]BigInt
+	for i, name := range []*C.char{paramN, paramE, paramD, paramP, paramQ, paramDp, paramDq, paramQinv} {
+		if out[i], err = getBN(pkey, name); err != nil {
+			return bad(err)
+		}
+	}
+	return out[0], out[1], out[2], out[3], out[4], out[5], out[6], out[7], nil
+}
+
+type PublicKeyRSA struct {
+	// _pkey MUST NOT be accessed directly. Instead, use the withKey method.
+	_pkey *C.EVP_PKEY
+}
+
+func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
+	pkey, err := fromData(rsaType, C.GO_EVP_PKEY_PUBLIC_KEY,
+		keyParam{name: paramN, bn: N}, keyParam{name: paramE, bn: E})
+	if err != nil {
+		return nil, err
+	}
+	k := &PublicKeyRSA{_pkey: pkey}
+	runtime.SetFinalizer(k, (*PublicKeyRSA).finalize)
+	return k, nil
+}
+
+func (k *PublicKeyRSA) finalize() {
+	C.go_openssl_EVP_PKEY_free(k._pkey)
+}
+
+func (k *PublicKeyRSA) withKey(f func(*C.EVP_PKEY) C.int) C.int {
+	// Because of the finalizer, any time _pkey is passed to cgo, that call
+	// must be followed by a call to runtime.KeepAlive, to make sure k is not
+	// collected (and finalized) before the cgo call returns.
+	defer runtime.KeepAlive(k)
+	return f(k._pkey)
+}
+
+type PrivateKeyRSA struct {
+	// _pkey MUST NOT be accessed directly. Instead, use the withKey method.
+	_pkey  *C.EVP_PKEY
+	closed bool
+}
+
+var errRSAConsistency = errors.New("openssl: RSA private key failed the pairwise consistency test")
+
+func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
+	params := []keyParam{{name: paramN, bn: N}, {name: paramE, bn: E}, {name: paramD, bn: D}}
+	// Keys without two primes and their CRT values, which crypto/rsa
+	// passes as nil, are used without CRT.
+	crt := P != nil && Q != nil && Dp != nil && Dq != nil && Qinv != nil
+	if crt {
+		params = append(params,
+			keyParam{name: paramP, bn: P}, keyParam{name: paramQ, bn: Q},
+			keyParam{name: paramDp, bn: Dp}, keyParam{name: paramDq, bn: Dq},
+			keyParam{name: paramQinv, bn: Qinv})
+	}
+	pkey, err := fromData(rsaType, C.GO_EVP_PKEY_KEYPAIR, params...)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKeyRSA{_pkey: pkey}
+	runtime.SetFinalizer(k, (*PrivateKeyRSA).finalize)
+	// OpenSSL checks the result of CRT operations, but would use a wrong D
+	// without any error, so check it once here, as BoringCrypto does on
+	// every operation.
+	if !crt {
+		if err := k.checkConsistency(); err != nil {
+			k.Close()
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// checkConsistency checks that D inverts E, by decrypting the encryption
+// of a small message.
+func (k *PrivateKeyRSA) checkConsistency() error {
+	msg := make([]byte, k.withKey(func(pkey *C.EVP_PKEY) C.int {
+		return C.go_openssl_EVP_PKEY_get_size(pkey)
+	}))
+	if len(msg) == 0 {
+		return newFail("EVP_PKEY_get_size")
+	}
+	msg[len(msg)-1] = 2
+	c, err := rawCryptRSA(k.withKey, C.GO_RSA_NO_PADDING, nil, nil, nil, encryptInit, encrypt, msg)
+	if err != nil {
+		return err
+	}
+	m, err := rawCryptRSA(k.withKey, C.GO_RSA_NO_PADDING, nil, nil, nil, decryptInit, decrypt, c)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(m, msg) {
+		return errRSAConsistency
+	}
+	return nil
+}
+
+func (k *PrivateKeyRSA) finalize() {
+	C.go_openssl_EVP_PKEY_free(k._pkey)
+}
+
+// Close frees the key, which OpenSSL zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyRSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
+}
+
+func (k *PrivateKeyRSA) withKey(f func(*C.EVP_PKEY) C.int) C.int {
+	checkOpen(k.closed)
+	// Because of the finalizer, any time _pkey is passed to cgo, that call
+	// must be followed by a call to runtime.KeepAlive, to make sure k is not
+	// collected (and finalized) before the cgo call returns.
+	defer runtime.KeepAlive(k)
+	return f(k._pkey)
+}
+
+// checkOpen panics if a private key has been closed, since its C object
+// has been freed.
+func checkOpen(closed bool) {
+	if closed {
+		panic("openssl: use of closed private key")
+	}
+}
+
+// hashToMD converts a hash.Hash implementation from this package
+// to an OpenSSL *C.EVP_MD.
+func hashToMD(h hash.Hash) *C.EVP_MD {
+	if h, ok := h.(*evpHash); ok {
+		return h.d.md
+	}
+	return nil
+}
+
+// cryptoHashToMD converts a crypto.Hash to an OpenSSL *C.EVP_MD.
+// MD5 and MD5-SHA1 are nil if the configured providers lack them,
+// for example in FIPS mode.
+func cryptoHashToMD(ch crypto.Hash) *C.EVP_MD {
+	switch ch {
+	case crypto.MD5:
+		return md5MD
+	case crypto.MD5SHA1:
+		return md5sha1MD
+	case crypto.SHA1:
+		return sha1MD.md
+	case crypto.SHA224:
+		return sha224MD.md
+	case crypto.SHA256:
+		return sha256MD.md
+	case crypto.SHA384:
+		return sha384MD.md
+	case crypto.SHA512:
+		return sha512MD.md
+	}
+	return nil
+}
+
+// newPKeyCtx returns a new context for pkey, initialized for an operation
+// by init and set up with the RSA padding and its parameters.
+func newPKeyCtx(withKey func(func(*C.EVP_PKEY) C.int) C.int,
+	padding C.int, h, mgfHash hash.Hash, label []byte, saltLen int, ch crypto.Hash,
+	init func(*C.EVP_PKEY_CTX) C.int) (ctx *C.EVP_PKEY_CTX, err error) {
+	defer func() {
+		if err != nil && ctx != nil {
+			C.go_openssl_EVP_PKEY_CTX_free(ctx)
+			ctx = nil
+		}
+	}()
+
+	withKey(func(pkey *C.EVP_PKEY) C.int {
+		ctx = C.go_openssl_EVP_PKEY_CTX_new(pkey, nil)
+		return 1
+	})
+	if ctx == nil {
+		return nil, newFail("EVP_PKEY_CTX_new")
+	}
+	if init(ctx) != 1 {
+		return nil, newFail("EVP_PKEY_operation_init")
+	}
+	if padding == 0 {
+		return ctx, nil
+	}
+	if C.go_openssl_EVP_PKEY_CTX_set_rsa_padding(ctx, padding) != 1 {
+		return nil, newFail("EVP_PKEY_CTX_set_rsa_padding")
+	}
+	if padding == C.GO_RSA_PKCS1_OAEP_PADDING {
+		md := hashToMD(h)
+		if md == nil {
+			return nil, unsupported("crypto/rsa: unsupported hash function")
+		}
+		mgfMD := hashToMD(mgfHash)
+		if mgfMD == nil {
+			return nil, unsupported("crypto/rsa: unsupported hash function")
+		}
+		if C.go_openssl_EVP_PKEY_CTX_set_rsa_oaep_md(ctx, md) != 1 {
+			return nil, newFail("EVP_PKEY_CTX_set_rsa_oaep_md")
+		}
+		if C.go_openssl_EVP_PKEY_CTX_set_rsa_mgf1_md(ctx, mgfMD) != 1 {
+			return nil, newFail("EVP_PKEY_CTX_set_rsa_mgf1_md")
+		}
+		if len(label) > 0 {
+			// ctx takes ownership of label, so malloc a copy for OpenSSL to free.
+			clabel := C.go_openssl_CRYPTO_malloc(C.size_t(len(label)), nil, 0)
+			if clabel == nil {
+				return nil, newFail("CRYPTO_malloc")
+			}
+			copy(unsafe.Slice((*byte)(clabel), len(label)), label)
+			if C.go_openssl_EVP_PKEY_CTX_set0_rsa_oaep_label(ctx, clabel, C.int(len(label))) != 1 {
+				return nil, newFail("EVP_PKEY_CTX_set0_rsa_oaep_label")
+			}
+		}
+	}
+	if ch != 0 {
+		md := cryptoHashToMD(ch)
+		if md == nil {
+			return nil, unsupported("crypto/rsa: unsupported hash function: " + strconv.Itoa(int(ch)))
+		}
+		if C.go_openssl_EVP_PKEY_CTX_set_signature_md(ctx, md) != 1 {
+			return nil, newFail("EVP_PKEY_CTX_set_signature_md")
+		}
+	}
+	if padding == C.GO_RSA_PKCS1_PSS_PADDING {
+		if C.go_openssl_EVP_PKEY_CTX_set_rsa_mgf1_md(ctx, cryptoHashToMD(ch)) != 1 {
+			return nil, newFail("EVP_PKEY_CTX_set_rsa_mgf1_md")
+		}
+		if C.go_openssl_EVP_PKEY_CTX_set_rsa_pss_saltlen(ctx, C.int(saltLen)) != 1 {
+			return nil, newFail("EVP_PKEY_CTX_set_rsa_pss_saltlen")
+		}
+	}
+	return ctx, nil
+}
+
+func cryptRSA(withKey func(func(*C.EVP_PKEY) C.int) C.int,
+	padding C.int, h, mgfHash hash.Hash, label []byte,
+	init func(*C.EVP_PKEY_CTX) C.int,
+	crypt func(*C.EVP_PKEY_CTX, *C.uchar, *C.size_t, *C.uchar, C.size_t) C.int,
+	in []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(in))
+	defer countResult(cryptometrics.RSA, &err)
+	return rawCryptRSA(withKey, padding, h, mgfHash, label, init, crypt, in)
+}
+
+// rawCryptRSA is cryptRSA without the metrics, for the key checks.
+func rawCryptRSA(withKey func(func(*C.EVP_PKEY) C.int) C.int,
+	padding C.int, h, mgfHash hash.Hash, label []byte,
+	init func(*C.EVP_PKEY_CTX) C.int,
+	crypt func(*C.EVP_PKEY_CTX, *C.uchar, *C.size_t, *C.uchar, C.size_t) C.int,
+	in []byte) ([]byte, error) {
+	ctx, err := newPKeyCtx(withKey, padding, h, mgfHash, label, 0, 0, init)
+	if err != nil {
+		return nil, err
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+
+	var outLen C.size_t
+	if crypt(ctx, nil, &outLen, base(in), C.size_t(len(in))) != 1 {
+		return nil, newFail("EVP_PKEY_decrypt/encrypt")
+	}
+	out := make([]byte, outLen)
+	if crypt(ctx, base(out), &outLen, base(in), C.size_t(len(in))) != 1 {
+		return nil, newFail("EVP_PKEY_decrypt/encrypt")
+	}
+	return out[:outLen], nil
+}
+
+func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) ([]byte, error) {
+	return cryptRSA(priv.withKey, C.GO_RSA_PKCS1_OAEP_PADDING, h, mgfHash, label, decryptInit, decrypt, ciphertext)
+}
+
+func EncryptRSAOAEP(h, mgfHash hash.Hash, pub *PublicKeyRSA, msg, label []byte) ([]byte, error) {
+	return cryptRSA(pub.withKey, C.GO_RSA_PKCS1_OAEP_PADDING, h, mgfHash, label, encryptInit, encrypt, msg)
+}
+
+func DecryptRSAPKCS1(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	return cryptRSA(priv.withKey, C.GO_RSA_PKCS1_PADDING, nil, nil, nil, decryptInit, decrypt, ciphertext)
+}
+
+func EncryptRSAPKCS1(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	return cryptRSA(pub.withKey, C.GO_RSA_PKCS1_PADDING, nil, nil, nil, encryptInit, encrypt, msg)
+}
+
+func DecryptRSANoPadding(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	return cryptRSA(priv.withKey, C.GO_RSA_NO_PADDING, nil, nil, nil, decryptInit, decrypt, ciphertext)
+}
+
+func EncryptRSANoPadding(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	return cryptRSA(pub.withKey, C.GO_RSA_NO_PADDING, nil, nil, nil, encryptInit, encrypt, msg)
+}
+
+// These dumb wrappers work around the fact that cgo functions cannot be used as values directly.
+
+func decryptInit(ctx *C.EVP_PKEY_CTX) C.int {
+	return C.go_openssl_EVP_PKEY_decrypt_init(ctx)
+}
+
+func decrypt(ctx *C.EVP_PKEY_CTX, out *C.uchar, outLen *C.size_t, in *C.uchar, inLen C.size_t) C.int {
+	return C.go_openssl_EVP_PKEY_decrypt(ctx, out, outLen, in, inLen)
+}
+
+func encryptInit(ctx *C.EVP_PKEY_CTX) C.int {
+	return C.go_openssl_EVP_PKEY_encrypt_init(ctx)
+}
+
+func encrypt(ctx *C.EVP_PKEY_CTX, out *C.uchar, outLen *C.size_t, in *C.uchar, inLen C.size_t) C.int {
+	return C.go_openssl_EVP_PKEY_encrypt(ctx, out, outLen, in, inLen)
+}
+
+func signInit(ctx *C.EVP_PKEY_CTX) C.int {
+	return C.go_openssl_EVP_PKEY_sign_init(ctx)
+}
+
+func verifyInit(ctx *C.EVP_PKEY_CTX) C.int {
+	return C.go_openssl_EVP_PKEY_verify_init(ctx)
+}
+
+// signPKey signs hashed with a context set up by newPKeyCtx.
+func signPKey(ctx *C.EVP_PKEY_CTX, hashed []byte) ([]byte, error) {
+	var outLen C.size_t
+	if C.go_openssl_EVP_PKEY_sign(ctx, nil, &outLen, base(hashed), C.size_t(len(hashed))) != 1 {
+		return nil, newFail("EVP_PKEY_sign")
+	}
+	out := make([]byte, outLen)
+	if C.go_openssl_EVP_PKEY_sign(ctx, base(out), &outLen, base(hashed), C.size_t(len(hashed))) != 1 {
+		return nil, newFail("EVP_PKEY_sign")
+	}
+	return out[:outLen], nil
+}
+
+// verifyPKey verifies sig over hashed with a context set up by newPKeyCtx.
+func verifyPKey(ctx *C.EVP_PKEY_CTX, hashed, sig []byte) error {
+	if C.go_openssl_EVP_PKEY_verify(ctx, base(sig), C.size_t(len(sig)), base(hashed), C.size_t(len(hashed))) != 1 {
+		return newFail("EVP_PKEY_verify")
+	}
+	return nil
+}
+
+var invalidSaltLenErr = errors.New("crypto/rsa: PSSOptions.SaltLength cannot be negative")
+
+// pssSaltLen converts a crypto/rsa salt length to the OpenSSL one. Both use
+// -1 for a salt as long as the hash, but OpenSSL uses -3 for the maximal
+// salt when signing, and -2 for any salt when verifying, where crypto/rsa
+// uses 0 for both.
+func pssSaltLen(saltLen int, sign bool) (int, error) {
+	switch {
+	case saltLen < -1:
+		return 0, invalidSaltLenErr
+	case saltLen == 0 && sign:
+		return C.GO_RSA_PSS_SALTLEN_MAX, nil
+	case saltLen == 0:
+		return C.GO_RSA_PSS_SALTLEN_AUTO, nil
+	}
+	return saltLen, nil
+}
+
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	if saltLen, err = pssSaltLen(saltLen, true); err != nil {
+		return nil, err
+	}
+	ctx, err := newPKeyCtx(priv.withKey, C.GO_RSA_PKCS1_PSS_PADDING, nil, nil, nil, saltLen, h, signInit)
+	if err != nil {
+		return nil, err
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	return signPKey(ctx, hashed)
+}
+
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	if saltLen, err = pssSaltLen(saltLen, false); err != nil {
+		return err
+	}
+	ctx, err := newPKeyCtx(pub.withKey, C.GO_RSA_PKCS1_PSS_PADDING, nil, nil, nil, saltLen, h, verifyInit)
+	if err != nil {
+		return err
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	return verifyPKey(ctx, hashed, sig)
+}
+
+// SignRSAPKCS1v15 signs hashed, the digest of the message with h, or the
+// message itself if h is zero.
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	ctx, err := newPKeyCtx(priv.withKey, C.GO_RSA_PKCS1_PADDING, nil, nil, nil, 0, h, signInit)
+	if err != nil {
+		return nil, err
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	return signPKey(ctx, hashed)
+}
+
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
+	ctx, err := newPKeyCtx(pub.withKey, C.GO_RSA_PKCS1_PADDING, nil, nil, nil, 0, h, verifyInit)
+	if err != nil {
+		return err
+	}
+	defer C.go_openssl_EVP_PKEY_CTX_free(ctx)
+	return verifyPKey(ctx, hashed, sig)
+}
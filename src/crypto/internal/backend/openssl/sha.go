@@ -0,0 +1,429 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package openssl
+
+// #include "goopenssl.h"
+import "C"
+import (
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"runtime"
+	"unsafe"
+)
+
+// A digest is a hash algorithm fetched once by initSHA. Fetching
+// explicitly, rather than through EVP_sha256 and the like, avoids a
+// provider lookup on every EVP_DigestInit_ex.
+type digest struct {
+	alg       int     // internal/cryptometrics identifier
+	name      *C.char // the OpenSSL name, for HMAC
+	md        *C.EVP_MD
+	size      int
+	blockSize int
+
+	// magic identifies the marshaled state, which is the same as that
+	// of the pure Go implementation in package pkg.
+	magic string
+	pkg   string
+}
+
+var (
+	sha1MD       = &digest{alg: cryptometrics.SHA1, name: C.CString("SHA1"), magic: "sha\x01", pkg: "crypto/sha1"}
+	sha224MD     = &digest{alg: cryptometrics.SHA224, name: C.CString("SHA2-224"), magic: "sha\x02", pkg: "crypto/sha256"}
+	sha256MD     = &digest{alg: cryptometrics.SHA256, name: C.CString("SHA2-256"), magic: "sha\x03", pkg: "crypto/sha256"}
+	sha384MD     = &digest{alg: cryptometrics.SHA384, name: C.CString("SHA2-384"), magic: "sha\x04", pkg: "crypto/sha512"}
+	sha512MD     = &digest{alg: cryptometrics.SHA512, name: C.CString("SHA2-512"), magic: "sha\x07", pkg: "crypto/sha512"}
+	sha512_224MD = &digest{alg: cryptometrics.SHA512_224, name: C.CString("SHA2-512/224"), magic: "sha\x05", pkg: "crypto/sha512"}
+	sha512_256MD = &digest{alg: cryptometrics.SHA512_256, name: C.CString("SHA2-512/256"), magic: "sha\x06", pkg: "crypto/sha512"}
+)
+
+// md5MD and md5sha1MD, for RSA PKCS #1 v1.5 signatures, are nil if the
+// providers don't implement them, as in FIPS mode.
+var md5MD, md5sha1MD *C.EVP_MD
+
+func initSHA() error {
+	md5, md5sha1 := C.CString("MD5"), C.CString("MD5-SHA1")
+	md5MD = C.go_openssl_EVP_MD_fetch(nil, md5, nil)
+	md5sha1MD = C.go_openssl_EVP_MD_fetch(nil, md5sha1, nil)
+	C.free(unsafe.Pointer(md5))
+	C.free(unsafe.Pointer(md5sha1))
+	C.go_openssl_ERR_clear_error()
+	for _, d := range []*digest{sha1MD, sha224MD, sha256MD, sha384MD, sha512MD, sha512_224MD, sha512_256MD} {
+		if d.md = C.go_openssl_EVP_MD_fetch(nil, d.name, nil); d.md == nil {
+			return newFail("EVP_MD_fetch(" + C.GoString(d.name) + ")")
+		}
+		d.size = int(C.go_openssl_EVP_MD_get_size(d.md))
+		d.blockSize = int(C.go_openssl_EVP_MD_get_block_size(d.md))
+	}
+	marshalable = checkStates()
+	return nil
+}
+
+// sum writes the digest of p to out, which must be large enough to hold it.
+func (d *digest) sum(out, p []byte) {
+	_ = out[d.size-1]
+	countOp(d.alg, len(p))
+	if C.go_openssl_EVP_Digest(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), (*C.uchar)(unsafe.Pointer(&*addr(out))), nil, d.md, nil) != 1 {
+		panic(newFail("EVP_Digest"))
+	}
+}
+
+// noescape hides a pointer from escape analysis.  noescape is
+// the identity function but escape analysis doesn't think the
+// output depends on the input.  noescape is inlined and currently
+// compiles down to zero instructions.
+// USE CAREFULLY!
+//
+//go:nosplit
+func noescape(p unsafe.Pointer) unsafe.Pointer {
+	x := uintptr(p)
+	return unsafe.Pointer(x ^ 0)
+}
+
+var zero byte
+
+// addr converts p to its base addr, including a noescape along the way.
+// If p is nil, addr returns a non-nil pointer, as EVP_Digest and
+// EVP_DigestUpdate require.
+//
+// Callers pass &*addr(p) to cgo, so that cgocheck only checks the
+// array of p, and not the rest of the object it might be part of.
+//
+//go:nosplit
+func addr(p []byte) *byte {
+	if len(p) == 0 {
+		return &zero
+	}
+	return (*byte)(noescape(unsafe.Pointer(&p[0])))
+}
+
+func SHA1(p []byte) (sum [20]byte)       { sha1MD.sum(sum[:], p); return }
+func SHA224(p []byte) (sum [28]byte)     { sha224MD.sum(sum[:], p); return }
+func SHA256(p []byte) (sum [32]byte)     { sha256MD.sum(sum[:], p); return }
+func SHA384(p []byte) (sum [48]byte)     { sha384MD.sum(sum[:], p); return }
+func SHA512(p []byte) (sum [64]byte)     { sha512MD.sum(sum[:], p); return }
+func SHA512_224(p []byte) (sum [28]byte) { sha512_224MD.sum(sum[:], p); return }
+func SHA512_256(p []byte) (sum [32]byte) { sha512_256MD.sum(sum[:], p); return }
+
+// SHA1Into, SHA224Into, SHA256Into, SHA384Into, and SHA512Into write the
+// digest of p to the beginning of dst, which must be large enough to hold
+// it.
+
+func SHA1Into(dst, p []byte)   { sha1MD.sum(dst, p) }
+func SHA224Into(dst, p []byte) { sha224MD.sum(dst, p) }
+func SHA256Into(dst, p []byte) { sha256MD.sum(dst, p) }
+func SHA384Into(dst, p []byte) { sha384MD.sum(dst, p) }
+func SHA512Into(dst, p []byte) { sha512MD.sum(dst, p) }
+
+// SHA256Batch returns the SHA-256 digests of msgs.
+func SHA256Batch(msgs [][]byte) [][32]byte {
+	sums := make([][32]byte, len(msgs))
+	for i, m := range msgs {
+		sha256MD.sum(sums[i][:], m)
+	}
+	return sums
+}
+
+func NewSHA1() hash.Hash       { return newEVPHash(sha1MD) }
+func NewSHA224() hash.Hash     { return newEVPHash(sha224MD) }
+func NewSHA256() hash.Hash     { return newEVPHash(sha256MD) }
+func NewSHA384() hash.Hash     { return newEVPHash(sha384MD) }
+func NewSHA512() hash.Hash     { return newEVPHash(sha512MD) }
+func NewSHA512_224() hash.Hash { return newEVPHash(sha512_224MD) }
+func NewSHA512_256() hash.Hash { return newEVPHash(sha512_256MD) }
+
+// An evpHash is a hash.Hash backed by an EVP_MD_CTX.
+type evpHash struct {
+	d   *digest
+	ctx *C.EVP_MD_CTX
+	// ctx2 is a copy of ctx, finalized by Sum so that writes can continue.
+	ctx2 *C.EVP_MD_CTX
+}
+
+func newEVPHash(d *digest) *evpHash {
+	h := &evpHash{d: d, ctx: C.go_openssl_EVP_MD_CTX_new(), ctx2: C.go_openssl_EVP_MD_CTX_new()}
+	if h.ctx == nil || h.ctx2 == nil {
+		h.finalize()
+		panic(newFail("EVP_MD_CTX_new"))
+	}
+	// Note: Because of the finalizer, any time h.ctx is passed to cgo,
+	// that call must be followed by a call to runtime.KeepAlive(h),
+	// to make sure h is not collected (and finalized) before the cgo
+	// call returns.
+	runtime.SetFinalizer(h, (*evpHash).finalize)
+	h.Reset()
+	return h
+}
+
+func (h *evpHash) finalize() {
+	C.go_openssl_EVP_MD_CTX_free(h.ctx)
+	C.go_openssl_EVP_MD_CTX_free(h.ctx2)
+}
+
+func (h *evpHash) Size() int      { return h.d.size }
+func (h *evpHash) BlockSize() int { return h.d.blockSize }
+
+func (h *evpHash) Reset() {
+	if C.go_openssl_EVP_DigestInit_ex(h.ctx, h.d.md, nil) != 1 {
+		panic(newFail("EVP_DigestInit_ex"))
+	}
+	runtime.KeepAlive(h)
+}
+
+// Wipe resets the state of h, which the provider zeroes.
+func (h *evpHash) Wipe() { h.Reset() }
+
+func (h *evpHash) Write(p []byte) (int, error) {
+	countBytes(h.d.alg, len(p))
+	if len(p) > 0 && C.go_openssl_EVP_DigestUpdate(h.ctx, unsafe.Pointer(&*addr(p)), C.size_t(len(p))) != 1 {
+		panic(newFail("EVP_DigestUpdate"))
+	}
+	runtime.KeepAlive(h)
+	return len(p), nil
+}
+
+func (h *evpHash) WriteString(s string) (int, error) {
+	return h.Write([]byte(s))
+}
+
+func (h *evpHash) WriteByte(c byte) error {
+	h.Write([]byte{c})
+	return nil
+}
+
+func (h *evpHash) Sum(in []byte) []byte {
+	countOp(h.d.alg, 0)
+	var out [64]byte // the largest digest size
+	if C.go_openssl_EVP_MD_CTX_copy_ex(h.ctx2, h.ctx) != 1 ||
+		C.go_openssl_EVP_DigestFinal_ex(h.ctx2, (*C.uchar)(unsafe.Pointer(&*addr(out[:]))), nil) != 1 {
+		panic(newFail("EVP_DigestFinal_ex"))
+	}
+	runtime.KeepAlive(h)
+	return append(in, out[:h.d.size]...)
+}
+
+// Clone returns an independent copy of h.
+func (h *evpHash) Clone() (hash.Hash, error) {
+	c := newEVPHash(h.d)
+	if C.go_openssl_EVP_MD_CTX_copy_ex(c.ctx, h.ctx) != 1 {
+		return nil, newFail("EVP_MD_CTX_copy_ex")
+	}
+	runtime.KeepAlive(h)
+	return c, nil
+}
+
+func (h *evpHash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, h.d.marshaledSize()))
+}
+
+func (h *evpHash) AppendBinary(b []byte) ([]byte, error) {
+	defer runtime.KeepAlive(h)
+	p, err := h.d.state(h.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.d.appendState(b, p), nil
+}
+
+func (h *evpHash) UnmarshalBinary(b []byte) error {
+	if err := h.d.checkState(b); err != nil {
+		return err
+	}
+	defer runtime.KeepAlive(h)
+	p, err := h.d.state(h.ctx)
+	if err != nil {
+		return err
+	}
+	h.d.setState(p, b)
+	return nil
+}
+
+// sha1State, sha256State and sha512State mirror SHA_CTX, SHA256_CTX and
+// SHA512_CTX, from openssl/sha.h, which hold the state of the SHA
+// implementations of the default and FIPS providers. The buffered input
+// is held in x, which OpenSSL declares as an array of words.
+type sha1State struct {
+	h      [5]uint32
+	nl, nh uint32
+	x      [64]byte
+	nx     uint32
+}
+
+type sha256State struct {
+	h      [8]uint32
+	nl, nh uint32
+	x      [64]byte
+	nx     uint32
+	mdLen  uint32
+}
+
+type sha512State struct {
+	h      [8]uint64
+	nl, nh uint64
+	x      [128]byte
+	nx     uint32
+	mdLen  uint32
+}
+
+// marshalable reports whether the states of the digests are laid out as
+// expected, so that they can be marshaled.
+var marshalable bool
+
+var errNotMarshalable = errors.New("openssl: hash state is not marshalable with this libcrypto")
+
+// checkStates reports whether the states of new SHA-1, SHA-256 and SHA-512
+// digests hold their initial hash values where this package expects them.
+func checkStates() bool {
+	ctx := C.go_openssl_EVP_MD_CTX_new()
+	if ctx == nil {
+		return false
+	}
+	defer C.go_openssl_EVP_MD_CTX_free(ctx)
+	for _, d := range []*digest{sha1MD, sha256MD, sha512MD} {
+		if C.go_openssl_EVP_DigestInit_ex(ctx, d.md, nil) != 1 {
+			C.go_openssl_ERR_clear_error()
+			return false
+		}
+		p := C.go_openssl_md_state(ctx)
+		if p == nil {
+			return false
+		}
+		var ok bool
+		switch d {
+		case sha1MD:
+			ok = (*sha1State)(p).h[0] == 0x67452301
+		case sha256MD:
+			ok = (*sha256State)(p).h[0] == 0x6a09e667 && (*sha256State)(p).mdLen == 32
+		case sha512MD:
+			ok = (*sha512State)(p).h[0] == 0x6a09e667f3bcc908 && (*sha512State)(p).mdLen == 64
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// state returns the state of the digest d in ctx.
+// Callers must keep ctx alive while using it.
+func (d *digest) state(ctx *C.EVP_MD_CTX) (unsafe.Pointer, error) {
+	if !marshalable {
+		return nil, errNotMarshalable
+	}
+	p := C.go_openssl_md_state(ctx)
+	if p == nil {
+		return nil, errNotMarshalable
+	}
+	return p, nil
+}
+
+func (d *digest) marshaledSize() int {
+	if d.blockSize == 128 {
+		return len(d.magic) + 8*8 + 128 + 8
+	}
+	if d == sha1MD {
+		return len(d.magic) + 5*4 + 64 + 8
+	}
+	return len(d.magic) + 8*4 + 64 + 8
+}
+
+// appendState appends the state p of d to b, in the format of package d.pkg.
+func (d *digest) appendState(b []byte, p unsafe.Pointer) []byte {
+	b = append(b, d.magic...)
+	switch {
+	case d == sha1MD:
+		s := (*sha1State)(p)
+		for _, x := range s.h {
+			b = appendUint32(b, x)
+		}
+		b = append(b, s.x[:s.nx]...)
+		b = append(b, make([]byte, len(s.x)-int(s.nx))...)
+		b = appendUint64(b, uint64(s.nl)>>3|uint64(s.nh)<<29)
+	case d.blockSize == 64:
+		s := (*sha256State)(p)
+		for _, x := range s.h {
+			b = appendUint32(b, x)
+		}
+		b = append(b, s.x[:s.nx]...)
+		b = append(b, make([]byte, len(s.x)-int(s.nx))...)
+		b = appendUint64(b, uint64(s.nl)>>3|uint64(s.nh)<<29)
+	default:
+		s := (*sha512State)(p)
+		for _, x := range s.h {
+			b = appendUint64(b, x)
+		}
+		b = append(b, s.x[:s.nx]...)
+		b = append(b, make([]byte, len(s.x)-int(s.nx))...)
+		b = appendUint64(b, s.nl>>3|s.nh<<61)
+	}
+	return b
+}
+
+// checkState returns an error if b is not a marshaled state of d.
+func (d *digest) checkState(b []byte) error {
+	if len(b) < len(d.magic) || string(b[:len(d.magic)]) != d.magic {
+		return errors.New(d.pkg + ": invalid hash state identifier")
+	}
+	if len(b) != d.marshaledSize() {
+		return errors.New(d.pkg + ": invalid hash state size")
+	}
+	return nil
+}
+
+// setState sets the state p of d to b, which checkState accepted.
+func (d *digest) setState(p unsafe.Pointer, b []byte) {
+	b = b[len(d.magic):]
+	switch {
+	case d == sha1MD:
+		s := (*sha1State)(p)
+		for i := range s.h {
+			b, s.h[i] = consumeUint32(b)
+		}
+		b = b[copy(s.x[:], b):]
+		_, n := consumeUint64(b)
+		s.nl, s.nh, s.nx = uint32(n<<3), uint32(n>>29), uint32(n%64)
+	case d.blockSize == 64:
+		s := (*sha256State)(p)
+		for i := range s.h {
+			b, s.h[i] = consumeUint32(b)
+		}
+		b = b[copy(s.x[:], b):]
+		_, n := consumeUint64(b)
+		s.nl, s.nh, s.nx = uint32(n<<3), uint32(n>>29), uint32(n%64)
+	default:
+		s := (*sha512State)(p)
+		for i := range s.h {
+			b, s.h[i] = consumeUint64(b)
+		}
+		b = b[copy(s.x[:], b):]
+		_, n := consumeUint64(b)
+		s.nl, s.nh, s.nx = n<<3, n>>61, uint32(n%128)
+	}
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	return append(b, byte(x>>56), byte(x>>48), byte(x>>40), byte(x>>32),
+		byte(x>>24), byte(x>>16), byte(x>>8), byte(x))
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	return append(b, byte(x>>24), byte(x>>16), byte(x>>8), byte(x))
+}
+
+func consumeUint64(b []byte) ([]byte, uint64) {
+	_ = b[7]
+	x := uint64(b[7]) | uint64(b[6])<<8 | uint64(b[5])<<16 | uint64(b[4])<<24 |
+		uint64(b[3])<<32 | uint64(b[2])<<40 | uint64(b[1])<<48 | uint64(b[0])<<56
+	return b[8:], x
+}
+
+func consumeUint32(b []byte) ([]byte, uint32) {
+	_ = b[3]
+	x := uint32(b[3]) | uint32(b[2])<<8 | uint32(b[1])<<16 | uint32(b[0])<<24
+	return b[4:], x
+}
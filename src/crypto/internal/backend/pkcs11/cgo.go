@@ -0,0 +1,321 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix && cgo && !cmd_go_bootstrap
+
+package pkcs11
+
+// #cgo linux LDFLAGS: -ldl
+// #include "gopkcs11.h"
+import "C"
+import (
+	"sync"
+	"unsafe"
+)
+
+// A Module is a loaded PKCS #11 module.
+type Module struct {
+	f *C.CK_FUNCTION_LIST
+}
+
+// modules are the loaded modules, by path. A module is initialized once
+// per process, and never finalized, as other packages may use it too.
+var modules struct {
+	sync.Mutex
+	m map[string]*Module
+}
+
+// Load loads and initializes the module at path, or returns the module
+// loaded by a previous call.
+func Load(path string) (*Module, error) {
+	modules.Lock()
+	defer modules.Unlock()
+	if m, ok := modules.m[path]; ok {
+		return m, nil
+	}
+	file := C.CString(path)
+	defer C.free(unsafe.Pointer(file))
+	var f *C.CK_FUNCTION_LIST
+	var fn *C.char
+	if rv := C.go_pkcs11_load(file, &f, &fn); rv != C.GO_CKR_OK {
+		if C.GoString(fn) == "dlopen" {
+			return nil, newFail("dlopen of "+path, uint(rv))
+		}
+		return nil, newFail(C.GoString(fn), uint(rv))
+	}
+	m := &Module{f}
+	if modules.m == nil {
+		modules.m = make(map[string]*Module)
+	}
+	modules.m[path] = m
+	return m, nil
+}
+
+// A Session is a read-write session with a token, logged in as the
+// normal user. PKCS #11 sessions perform one operation at a time, so a
+// Session serializes its operations, and is safe for concurrent use.
+type Session struct {
+	m      *Module
+	h      C.CK_SESSION_HANDLE
+	mu     sync.Mutex
+	closed bool
+}
+
+// OpenSession opens a session with the token in slot, and logs in with
+// pin, unless pin is empty, for tokens that authenticate users otherwise
+// or that have no private objects.
+func (m *Module) OpenSession(slot uint, pin string) (*Session, error) {
+	var h C.CK_SESSION_HANDLE
+	if rv := C.go_pkcs11_open_session(m.f, C.CK_SLOT_ID(slot), &h); rv != C.GO_CKR_OK {
+		return nil, newFail("C_OpenSession", uint(rv))
+	}
+	s := &Session{m: m, h: h}
+	if pin != "" {
+		p := C.CBytes([]byte(pin))
+		rv := C.go_pkcs11_login(m.f, h, (*C.CK_BYTE)(p), C.CK_ULONG(len(pin)))
+		C.free(p)
+		if rv != C.GO_CKR_OK && rv != C.GO_CKR_USER_ALREADY_LOGGED_IN {
+			s.Close()
+			return nil, newFail("C_Login", uint(rv))
+		}
+	}
+	return s, nil
+}
+
+// Close closes the session. It does not log out, as the login state is
+// shared by all the sessions of the application with the token.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if rv := C.go_pkcs11_close_session(s.m.f, s.h); rv != C.GO_CKR_OK {
+		return newFail("C_CloseSession", uint(rv))
+	}
+	return nil
+}
+
+// lock locks s for an operation, and panics if s is closed.
+func (s *Session) lock() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		panic("pkcs11: use of a closed session")
+	}
+}
+
+// An attribute is a CK_ATTRIBUTE of a template, with its value in Go
+// memory.
+type attribute struct {
+	typ   uint
+	value []byte
+}
+
+func bytesAttr(typ uint, v []byte) attribute { return attribute{typ, v} }
+
+func boolAttr(typ uint, v bool) attribute {
+	if v {
+		return attribute{typ, []byte{1}}
+	}
+	return attribute{typ, []byte{0}}
+}
+
+func ulongAttr(typ uint, v uint) attribute {
+	x := C.CK_ULONG(v)
+	b := unsafe.Slice((*byte)(unsafe.Pointer(&x)), unsafe.Sizeof(x))
+	return attribute{typ, append([]byte(nil), b...)}
+}
+
+// Attribute types of templates.
+const (
+	attrClass       = 0x0   // CKA_CLASS
+	attrToken       = 0x1   // CKA_TOKEN
+	attrPrivate     = 0x2   // CKA_PRIVATE
+	attrLabel       = 0x3   // CKA_LABEL
+	attrSensitive   = 0x103 // CKA_SENSITIVE
+	attrSign        = 0x108 // CKA_SIGN
+	attrVerify      = 0x10a // CKA_VERIFY
+	attrModulusBits = 0x121 // CKA_MODULUS_BITS
+	attrExtractable = 0x162 // CKA_EXTRACTABLE
+)
+
+// cTemplate returns attrs as a CK_ATTRIBUTE array in C memory, with the
+// values, because cgo doesn't allow passing the Go memory of the array
+// when it points to other Go memory. The array must be freed with
+// freeTemplate.
+func cTemplate(attrs []attribute) *C.CK_ATTRIBUTE {
+	if len(attrs) == 0 {
+		return nil
+	}
+	t := (*C.CK_ATTRIBUTE)(C.calloc(C.size_t(len(attrs)), C.size_t(unsafe.Sizeof(C.CK_ATTRIBUTE{}))))
+	ct := unsafe.Slice(t, len(attrs))
+	for i, a := range attrs {
+		ct[i]._type = C.CK_ATTRIBUTE_TYPE(a.typ)
+		if len(a.value) > 0 {
+			ct[i].pValue = C.CBytes(a.value)
+		}
+		ct[i].ulValueLen = C.CK_ULONG(len(a.value))
+	}
+	return t
+}
+
+// freeTemplate frees the template t of n attributes returned by
+// cTemplate.
+func freeTemplate(t *C.CK_ATTRIBUTE, n int) {
+	if t == nil {
+		return
+	}
+	for _, a := range unsafe.Slice(t, n) {
+		C.free(a.pValue)
+	}
+	C.free(unsafe.Pointer(t))
+}
+
+// FindKey returns the key of class and keyType labeled label. It returns
+// ErrNotFound if the token has no such key, and the first one if it has
+// several.
+func (s *Session) FindKey(class, keyType uint, label string) (Object, error) {
+	attrs := []attribute{
+		ulongAttr(attrClass, class),
+		ulongAttr(AttrKeyType, keyType),
+		bytesAttr(attrLabel, []byte(label)),
+	}
+	t := cTemplate(attrs)
+	defer freeTemplate(t, len(attrs))
+
+	s.lock()
+	defer s.mu.Unlock()
+	var obj C.CK_OBJECT_HANDLE
+	var n C.CK_ULONG
+	if rv := C.go_pkcs11_find_objects(s.m.f, s.h, t, C.CK_ULONG(len(attrs)), &obj, 1, &n); rv != C.GO_CKR_OK {
+		return 0, newFail("C_FindObjects", uint(rv))
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+	return Object(obj), nil
+}
+
+// Attributes returns the values of the attributes of obj with types.
+func (s *Session) Attributes(obj Object, types ...uint) ([][]byte, error) {
+	attrs := make([]attribute, len(types))
+	for i, typ := range types {
+		attrs[i] = attribute{typ: typ}
+	}
+	t := cTemplate(attrs)
+	defer freeTemplate(t, len(attrs))
+
+	s.lock()
+	defer s.mu.Unlock()
+	// The first call returns the lengths of the values, and the second
+	// one the values.
+	if rv := C.go_pkcs11_get_attribute_value(s.m.f, s.h, C.CK_OBJECT_HANDLE(obj), t, C.CK_ULONG(len(attrs))); rv != C.GO_CKR_OK {
+		return nil, newFail("C_GetAttributeValue", uint(rv))
+	}
+	ct := unsafe.Slice(t, len(attrs))
+	for i := range ct {
+		if ct[i].ulValueLen == ^C.CK_ULONG(0) {
+			return nil, newFail("C_GetAttributeValue", ckrAttributeTypeInvalid)
+		}
+		if ct[i].ulValueLen > 0 {
+			ct[i].pValue = C.malloc(C.size_t(ct[i].ulValueLen))
+		}
+	}
+	if rv := C.go_pkcs11_get_attribute_value(s.m.f, s.h, C.CK_OBJECT_HANDLE(obj), t, C.CK_ULONG(len(attrs))); rv != C.GO_CKR_OK {
+		return nil, newFail("C_GetAttributeValue", uint(rv))
+	}
+	values := make([][]byte, len(ct))
+	for i := range ct {
+		values[i] = C.GoBytes(ct[i].pValue, C.int(ct[i].ulValueLen))
+	}
+	return values, nil
+}
+
+// Key pair generation mechanisms.
+const (
+	mechRSAPKCSKeyPairGen = 0x0    // CKM_RSA_PKCS_KEY_PAIR_GEN
+	mechECKeyPairGen      = 0x1040 // CKM_EC_KEY_PAIR_GEN
+)
+
+// GenerateRSAKeyPair generates a key pair of bits with the public exponent
+// 65537, stored on the token with label. The private key is sensitive and
+// not extractable.
+func (s *Session) GenerateRSAKeyPair(label string, bits int) (pub, priv Object, err error) {
+	return s.generateKeyPair(mechRSAPKCSKeyPairGen, label,
+		ulongAttr(attrModulusBits, uint(bits)),
+		bytesAttr(AttrPublicExponent, []byte{1, 0, 1}))
+}
+
+// GenerateECKeyPair generates a key pair on the curve of params, the DER
+// encoding of its object identifier, stored on the token with label. The
+// private key is sensitive and not extractable.
+func (s *Session) GenerateECKeyPair(label string, params []byte) (pub, priv Object, err error) {
+	return s.generateKeyPair(mechECKeyPairGen, label, bytesAttr(AttrECParams, params))
+}
+
+func (s *Session) generateKeyPair(mech uint, label string, pubAttrs ...attribute) (pub, priv Object, err error) {
+	pubAttrs = append(pubAttrs,
+		boolAttr(attrToken, true),
+		boolAttr(attrVerify, true),
+		bytesAttr(attrLabel, []byte(label)))
+	privAttrs := []attribute{
+		boolAttr(attrToken, true),
+		boolAttr(attrPrivate, true),
+		boolAttr(attrSign, true),
+		boolAttr(attrSensitive, true),
+		boolAttr(attrExtractable, false),
+		bytesAttr(attrLabel, []byte(label)),
+	}
+	pt := cTemplate(pubAttrs)
+	defer freeTemplate(pt, len(pubAttrs))
+	st := cTemplate(privAttrs)
+	defer freeTemplate(st, len(privAttrs))
+
+	s.lock()
+	defer s.mu.Unlock()
+	var hpub, hpriv C.CK_OBJECT_HANDLE
+	if rv := C.go_pkcs11_generate_key_pair(s.m.f, s.h, C.CK_MECHANISM_TYPE(mech),
+		pt, C.CK_ULONG(len(pubAttrs)), st, C.CK_ULONG(len(privAttrs)), &hpub, &hpriv); rv != C.GO_CKR_OK {
+		return 0, 0, newFail("C_GenerateKeyPair", uint(rv))
+	}
+	return Object(hpub), Object(hpriv), nil
+}
+
+// Sign signs data with key, using mech, and pss for MechRSAPKCSPSS.
+// sigLen is the maximum length of the signature, the size of the modulus
+// for RSA, and twice the size of the order for ECDSA, whose signature is
+// the concatenation of r and s.
+func (s *Session) Sign(key Object, mech uint, pss *PSSParams, data []byte, sigLen int) ([]byte, error) {
+	var param unsafe.Pointer
+	var paramLen C.CK_ULONG
+	if pss != nil {
+		if pss.SaltLen < 0 {
+			return nil, unsupported("pkcs11: negative PSS salt length")
+		}
+		// The parameters hold no pointers, so they can be passed in Go
+		// memory.
+		p := &C.CK_RSA_PKCS_PSS_PARAMS{
+			hashAlg: C.CK_MECHANISM_TYPE(pss.Hash),
+			mgf:     C.CK_ULONG(pss.MGF),
+			sLen:    C.CK_ULONG(pss.SaltLen),
+		}
+		param, paramLen = unsafe.Pointer(p), C.CK_ULONG(unsafe.Sizeof(*p))
+	}
+	if len(data) == 0 {
+		return nil, unsupported("pkcs11: empty input")
+	}
+	sig := make([]byte, sigLen)
+	n := C.CK_ULONG(len(sig))
+
+	s.lock()
+	defer s.mu.Unlock()
+	if rv := C.go_pkcs11_sign(s.m.f, s.h, C.CK_OBJECT_HANDLE(key), C.CK_MECHANISM_TYPE(mech), param, paramLen,
+		(*C.CK_BYTE)(unsafe.Pointer(&data[0])), C.CK_ULONG(len(data)),
+		(*C.CK_BYTE)(unsafe.Pointer(&sig[0])), &n); rv != C.GO_CKR_OK {
+		return nil, newFail("C_Sign", uint(rv))
+	}
+	return sig[:n], nil
+}
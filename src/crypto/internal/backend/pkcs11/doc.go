@@ -0,0 +1,14 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pkcs11 provides access to the private keys of PKCS #11 tokens,
+// such as hardware security modules, through a module (shared library)
+// that it loads at run time. It is the backend of crypto/pkcs11.
+//
+// Unlike the other backends, it is not used by crypto/internal/boring:
+// tokens only sign digests computed by the caller, and generate keys,
+// and the crypto packages keep using their own implementations, or
+// BoringCrypto, for everything else. Loading a module requires cgo on a
+// Unix system; elsewhere Load returns an error.
+package pkcs11
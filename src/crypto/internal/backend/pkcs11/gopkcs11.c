@@ -0,0 +1,73 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix && cgo && !cmd_go_bootstrap
+
+#include <dlfcn.h>
+#include <string.h>
+#include "gopkcs11.h"
+
+CK_RV
+go_pkcs11_load(const char *file, CK_FUNCTION_LIST **list, const char **fn)
+{
+	CK_RV (*get_function_list)(CK_FUNCTION_LIST **);
+	CK_C_INITIALIZE_ARGS args;
+	void *handle;
+	CK_RV rv;
+
+	*fn = "dlopen";
+	handle = dlopen(file, RTLD_NOW | RTLD_LOCAL);
+	if (handle == NULL)
+		return ~(CK_RV)0;
+	*fn = "C_GetFunctionList";
+	get_function_list = dlsym(handle, "C_GetFunctionList");
+	if (get_function_list == NULL)
+		return ~(CK_RV)0;
+	rv = get_function_list(list);
+	if (rv != GO_CKR_OK)
+		return rv;
+
+	// Go calls the module from many threads, and has no mutex callbacks
+	// to offer: ask the module to use the locking of the OS.
+	memset(&args, 0, sizeof args);
+	args.flags = GO_CKF_OS_LOCKING_OK;
+	*fn = "C_Initialize";
+	rv = (*list)->C_Initialize(&args);
+	if (rv == GO_CKR_CRYPTOKI_ALREADY_INITIALIZED)
+		rv = GO_CKR_OK;
+	return rv;
+}
+
+CK_RV
+go_pkcs11_sign(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_OBJECT_HANDLE key,
+	CK_MECHANISM_TYPE mech, void *param, CK_ULONG param_len,
+	CK_BYTE *data, CK_ULONG data_len, CK_BYTE *sig, CK_ULONG *sig_len)
+{
+	CK_MECHANISM m;
+	CK_RV rv;
+
+	m.mechanism = mech;
+	m.pParameter = param;
+	m.ulParameterLen = param_len;
+	rv = f->C_SignInit(s, &m, key);
+	if (rv != GO_CKR_OK)
+		return rv;
+	return f->C_Sign(s, data, data_len, sig, sig_len);
+}
+
+CK_RV
+go_pkcs11_find_objects(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ATTRIBUTE *tmpl, CK_ULONG n,
+	CK_OBJECT_HANDLE *objs, CK_ULONG max, CK_ULONG *count)
+{
+	CK_RV rv, rv2;
+
+	rv = f->C_FindObjectsInit(s, tmpl, n);
+	if (rv != GO_CKR_OK)
+		return rv;
+	rv = f->C_FindObjects(s, objs, max, count);
+	rv2 = f->C_FindObjectsFinal(s);
+	if (rv == GO_CKR_OK)
+		rv = rv2;
+	return rv;
+}
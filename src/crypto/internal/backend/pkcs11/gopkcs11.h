@@ -0,0 +1,198 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This header file describes the subset of the PKCS #11 v2.40 ABI used
+// by package pkcs11. Modules are not linked into the binary:
+// go_pkcs11_load opens one with dlopen at run time and fetches its
+// function list with C_GetFunctionList, so that building Go does not
+// require the PKCS #11 headers.
+//
+// The types and constants are copied from the OASIS pkcs11t.h and
+// pkcs11f.h headers, with the structure packing of Unix platforms.
+
+#include <stdlib.h> // size_t
+
+typedef unsigned char CK_BYTE;
+typedef unsigned char CK_BBOOL;
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef CK_ULONG CK_FLAGS;
+typedef CK_ULONG CK_SLOT_ID;
+typedef CK_ULONG CK_SESSION_HANDLE;
+typedef CK_ULONG CK_OBJECT_HANDLE;
+typedef CK_ULONG CK_USER_TYPE;
+typedef CK_ULONG CK_ATTRIBUTE_TYPE;
+typedef CK_ULONG CK_MECHANISM_TYPE;
+
+typedef struct {
+	CK_ATTRIBUTE_TYPE type;
+	void *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_MECHANISM_TYPE mechanism;
+	void *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	CK_MECHANISM_TYPE hashAlg;
+	CK_ULONG mgf;
+	CK_ULONG sLen;
+} CK_RSA_PKCS_PSS_PARAMS;
+
+typedef struct {
+	void *CreateMutex;
+	void *DestroyMutex;
+	void *LockMutex;
+	void *UnlockMutex;
+	CK_FLAGS flags;
+	void *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+enum {
+	GO_CKR_OK = 0,
+	GO_CKR_CRYPTOKI_ALREADY_INITIALIZED = 0x191,
+	GO_CKR_USER_ALREADY_LOGGED_IN = 0x100,
+
+	GO_CKF_RW_SESSION = 0x2,
+	GO_CKF_SERIAL_SESSION = 0x4,
+	GO_CKF_OS_LOCKING_OK = 0x2,
+
+	GO_CKU_USER = 1,
+};
+
+// CK_FUNCTION_LIST lists the functions of a module in the order of the
+// specification. The functions that package pkcs11 does not call are
+// declared as plain pointers.
+typedef struct CK_FUNCTION_LIST {
+	CK_BYTE version[2];
+	CK_RV (*C_Initialize)(void *pInitArgs);
+	void *C_Finalize;
+	void *C_GetInfo;
+	void *C_GetFunctionList;
+	void *C_GetSlotList;
+	void *C_GetSlotInfo;
+	void *C_GetTokenInfo;
+	void *C_GetMechanismList;
+	void *C_GetMechanismInfo;
+	void *C_InitToken;
+	void *C_InitPIN;
+	void *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_SLOT_ID slotID, CK_FLAGS flags, void *pApplication, void *Notify, CK_SESSION_HANDLE *phSession);
+	CK_RV (*C_CloseSession)(CK_SESSION_HANDLE hSession);
+	void *C_CloseAllSessions;
+	void *C_GetSessionInfo;
+	void *C_GetOperationState;
+	void *C_SetOperationState;
+	CK_RV (*C_Login)(CK_SESSION_HANDLE hSession, CK_USER_TYPE userType, CK_BYTE *pPin, CK_ULONG ulPinLen);
+	void *C_Logout;
+	void *C_CreateObject;
+	void *C_CopyObject;
+	void *C_DestroyObject;
+	void *C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_SESSION_HANDLE hSession, CK_OBJECT_HANDLE hObject, CK_ATTRIBUTE *pTemplate, CK_ULONG ulCount);
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_SESSION_HANDLE hSession, CK_ATTRIBUTE *pTemplate, CK_ULONG ulCount);
+	CK_RV (*C_FindObjects)(CK_SESSION_HANDLE hSession, CK_OBJECT_HANDLE *phObject, CK_ULONG ulMaxObjectCount, CK_ULONG *pulObjectCount);
+	CK_RV (*C_FindObjectsFinal)(CK_SESSION_HANDLE hSession);
+	void *C_EncryptInit;
+	void *C_Encrypt;
+	void *C_EncryptUpdate;
+	void *C_EncryptFinal;
+	void *C_DecryptInit;
+	void *C_Decrypt;
+	void *C_DecryptUpdate;
+	void *C_DecryptFinal;
+	void *C_DigestInit;
+	void *C_Digest;
+	void *C_DigestUpdate;
+	void *C_DigestKey;
+	void *C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_SESSION_HANDLE hSession, CK_MECHANISM *pMechanism, CK_OBJECT_HANDLE hKey);
+	CK_RV (*C_Sign)(CK_SESSION_HANDLE hSession, CK_BYTE *pData, CK_ULONG ulDataLen, CK_BYTE *pSignature, CK_ULONG *pulSignatureLen);
+	void *C_SignUpdate;
+	void *C_SignFinal;
+	void *C_SignRecoverInit;
+	void *C_SignRecover;
+	void *C_VerifyInit;
+	void *C_Verify;
+	void *C_VerifyUpdate;
+	void *C_VerifyFinal;
+	void *C_VerifyRecoverInit;
+	void *C_VerifyRecover;
+	void *C_DigestEncryptUpdate;
+	void *C_DecryptDigestUpdate;
+	void *C_SignEncryptUpdate;
+	void *C_DecryptVerifyUpdate;
+	void *C_GenerateKey;
+	CK_RV (*C_GenerateKeyPair)(CK_SESSION_HANDLE hSession, CK_MECHANISM *pMechanism,
+		CK_ATTRIBUTE *pPublicKeyTemplate, CK_ULONG ulPublicKeyAttributeCount,
+		CK_ATTRIBUTE *pPrivateKeyTemplate, CK_ULONG ulPrivateKeyAttributeCount,
+		CK_OBJECT_HANDLE *phPublicKey, CK_OBJECT_HANDLE *phPrivateKey);
+	void *C_WrapKey;
+	void *C_UnwrapKey;
+	void *C_DeriveKey;
+	void *C_SeedRandom;
+	void *C_GenerateRandom;
+	void *C_GetFunctionStatus;
+	void *C_CancelFunction;
+	void *C_WaitForSlotEvent;
+} CK_FUNCTION_LIST;
+
+// The wrappers below call the functions of the list f, which cgo can't
+// call directly.
+
+static inline CK_RV
+go_pkcs11_open_session(CK_FUNCTION_LIST *f, CK_SLOT_ID slot, CK_SESSION_HANDLE *s)
+{
+	return f->C_OpenSession(slot, GO_CKF_SERIAL_SESSION | GO_CKF_RW_SESSION, NULL, NULL, s);
+}
+
+static inline CK_RV
+go_pkcs11_close_session(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s)
+{
+	return f->C_CloseSession(s);
+}
+
+static inline CK_RV
+go_pkcs11_login(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_BYTE *pin, CK_ULONG pin_len)
+{
+	return f->C_Login(s, GO_CKU_USER, pin, pin_len);
+}
+
+static inline CK_RV
+go_pkcs11_get_attribute_value(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_OBJECT_HANDLE obj, CK_ATTRIBUTE *tmpl, CK_ULONG n)
+{
+	return f->C_GetAttributeValue(s, obj, tmpl, n);
+}
+
+static inline CK_RV
+go_pkcs11_generate_key_pair(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_MECHANISM_TYPE mech,
+	CK_ATTRIBUTE *pub_tmpl, CK_ULONG pub_n, CK_ATTRIBUTE *priv_tmpl, CK_ULONG priv_n,
+	CK_OBJECT_HANDLE *pub, CK_OBJECT_HANDLE *priv)
+{
+	CK_MECHANISM m = {mech, NULL, 0};
+
+	return f->C_GenerateKeyPair(s, &m, pub_tmpl, pub_n, priv_tmpl, priv_n, pub, priv);
+}
+
+// go_pkcs11_load opens the module named by file, fetches its function
+// list and initializes it for use by multiple threads. It returns CKR_OK,
+// or another return value and sets *fn to the name of the function that
+// failed. A module that was already initialized is not an error.
+CK_RV go_pkcs11_load(const char *file, CK_FUNCTION_LIST **list, const char **fn);
+
+// go_pkcs11_sign signs data with key, using the mechanism mech and its
+// parameters, into sig, whose length is *sig_len and must be large enough
+// for the signature. It stores the length of the signature in *sig_len.
+CK_RV go_pkcs11_sign(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_OBJECT_HANDLE key,
+	CK_MECHANISM_TYPE mech, void *param, CK_ULONG param_len,
+	CK_BYTE *data, CK_ULONG data_len, CK_BYTE *sig, CK_ULONG *sig_len);
+
+// go_pkcs11_find_objects stores in objs up to max objects that match the
+// template, and their number in *count.
+CK_RV go_pkcs11_find_objects(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ATTRIBUTE *tmpl, CK_ULONG n,
+	CK_OBJECT_HANDLE *objs, CK_ULONG max, CK_ULONG *count);
@@ -0,0 +1,44 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix || !cgo || cmd_go_bootstrap
+
+package pkcs11
+
+import "errors"
+
+// A Module is a loaded PKCS #11 module.
+type Module struct{}
+
+// Load returns an error: loading a module requires cgo on a Unix system.
+func Load(path string) (*Module, error) {
+	return nil, errors.New("pkcs11: loading " + path + " requires cgo on a Unix system")
+}
+
+// A Session is a session with a token. Without cgo, none can be opened.
+type Session struct{}
+
+func (m *Module) OpenSession(slot uint, pin string) (*Session, error) { panic("pkcs11: unreachable") }
+
+func (s *Session) Close() error { panic("pkcs11: unreachable") }
+
+func (s *Session) FindKey(class, keyType uint, label string) (Object, error) {
+	panic("pkcs11: unreachable")
+}
+
+func (s *Session) Attributes(obj Object, types ...uint) ([][]byte, error) {
+	panic("pkcs11: unreachable")
+}
+
+func (s *Session) GenerateRSAKeyPair(label string, bits int) (pub, priv Object, err error) {
+	panic("pkcs11: unreachable")
+}
+
+func (s *Session) GenerateECKeyPair(label string, params []byte) (pub, priv Object, err error) {
+	panic("pkcs11: unreachable")
+}
+
+func (s *Session) Sign(key Object, mech uint, pss *PSSParams, data []byte, sigLen int) ([]byte, error) {
+	panic("pkcs11: unreachable")
+}
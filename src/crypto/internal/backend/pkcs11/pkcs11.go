@@ -0,0 +1,177 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs11
+
+import "errors"
+
+// Failure classes of the errors returned by this package, as in
+// crypto/internal/boring.
+var (
+	ErrBackendFailure       = errors.New("pkcs11: backend failure")
+	ErrUnsupportedParameter = errors.New("pkcs11: unsupported parameter")
+)
+
+// ErrNotFound is returned by FindKey when the token has no matching key.
+var ErrNotFound = errors.New("pkcs11: key not found")
+
+// An Object is the handle of an object of a token, valid for all the
+// sessions with the token.
+type Object uint
+
+// Object classes and key types, for FindKey.
+const (
+	ClassPublicKey  = 2 // CKO_PUBLIC_KEY
+	ClassPrivateKey = 3 // CKO_PRIVATE_KEY
+
+	KeyTypeRSA = 0 // CKK_RSA
+	KeyTypeEC  = 3 // CKK_EC
+)
+
+// Attribute types, for Attributes.
+const (
+	AttrKeyType        = 0x100 // CKA_KEY_TYPE
+	AttrModulus        = 0x120 // CKA_MODULUS
+	AttrPublicExponent = 0x122 // CKA_PUBLIC_EXPONENT
+	AttrECParams       = 0x180 // CKA_EC_PARAMS
+	AttrECPoint        = 0x181 // CKA_EC_POINT
+)
+
+// Signature mechanisms, for Sign, and the hash mechanisms and mask
+// generation functions of PSSParams.
+const (
+	MechRSAPKCS    = 0x1    // CKM_RSA_PKCS
+	MechRSAPKCSPSS = 0xd    // CKM_RSA_PKCS_PSS
+	MechECDSA      = 0x1041 // CKM_ECDSA
+
+	MechSHA1   = 0x220 // CKM_SHA_1
+	MechSHA224 = 0x255 // CKM_SHA224
+	MechSHA256 = 0x250 // CKM_SHA256
+	MechSHA384 = 0x260 // CKM_SHA384
+	MechSHA512 = 0x270 // CKM_SHA512
+
+	MGF1SHA1   = 1 // CKG_MGF1_SHA1
+	MGF1SHA224 = 5 // CKG_MGF1_SHA224
+	MGF1SHA256 = 2 // CKG_MGF1_SHA256
+	MGF1SHA384 = 3 // CKG_MGF1_SHA384
+	MGF1SHA512 = 4 // CKG_MGF1_SHA512
+)
+
+// PSSParams are the parameters of MechRSAPKCSPSS: the hash mechanism of
+// the digest, the mask generation function, and the salt length.
+type PSSParams struct {
+	Hash    uint
+	MGF     uint
+	SaltLen int
+}
+
+// Return values of PKCS #11 functions that the package names in errors or
+// classifies.
+const (
+	ckrOK                         = 0x0
+	ckrSlotIDInvalid              = 0x3
+	ckrArgumentsBad               = 0x7
+	ckrAttributeTypeInvalid       = 0x12
+	ckrDeviceError                = 0x30
+	ckrFunctionNotSupported       = 0x54
+	ckrKeyHandleInvalid           = 0x60
+	ckrKeySizeRange               = 0x62
+	ckrKeyTypeInconsistent        = 0x63
+	ckrKeyFunctionNotPermitted    = 0x68
+	ckrMechanismInvalid           = 0x70
+	ckrMechanismParamInvalid      = 0x71
+	ckrPinIncorrect               = 0xa0
+	ckrPinLocked                  = 0xa4
+	ckrSessionHandleInvalid       = 0xb3
+	ckrTemplateIncomplete         = 0xd0
+	ckrTemplateInconsistent       = 0xd1
+	ckrTokenNotPresent            = 0xe0
+	ckrUserNotLoggedIn            = 0x101
+	ckrBufferTooSmall             = 0x150
+	ckrCryptokiNotInitialized     = 0x190
+	ckrCryptokiAlreadyInitialized = 0x191
+	ckrDomainParamsInvalid        = 0x130
+	ckrCurveNotSupported          = 0x140
+	ckrUnavailable                = ^uint(0) // a failure of dlopen or dlsym
+)
+
+var ckrNames = map[uint]string{
+	ckrArgumentsBad:               "CKR_ARGUMENTS_BAD",
+	ckrAttributeTypeInvalid:       "CKR_ATTRIBUTE_TYPE_INVALID",
+	ckrDeviceError:                "CKR_DEVICE_ERROR",
+	ckrFunctionNotSupported:       "CKR_FUNCTION_NOT_SUPPORTED",
+	ckrKeyHandleInvalid:           "CKR_KEY_HANDLE_INVALID",
+	ckrKeySizeRange:               "CKR_KEY_SIZE_RANGE",
+	ckrKeyTypeInconsistent:        "CKR_KEY_TYPE_INCONSISTENT",
+	ckrKeyFunctionNotPermitted:    "CKR_KEY_FUNCTION_NOT_PERMITTED",
+	ckrMechanismInvalid:           "CKR_MECHANISM_INVALID",
+	ckrMechanismParamInvalid:      "CKR_MECHANISM_PARAM_INVALID",
+	ckrPinIncorrect:               "CKR_PIN_INCORRECT",
+	ckrPinLocked:                  "CKR_PIN_LOCKED",
+	ckrSessionHandleInvalid:       "CKR_SESSION_HANDLE_INVALID",
+	ckrSlotIDInvalid:              "CKR_SLOT_ID_INVALID",
+	ckrTemplateIncomplete:         "CKR_TEMPLATE_INCOMPLETE",
+	ckrTemplateInconsistent:       "CKR_TEMPLATE_INCONSISTENT",
+	ckrTokenNotPresent:            "CKR_TOKEN_NOT_PRESENT",
+	ckrUserNotLoggedIn:            "CKR_USER_NOT_LOGGED_IN",
+	ckrBufferTooSmall:             "CKR_BUFFER_TOO_SMALL",
+	ckrCryptokiNotInitialized:     "CKR_CRYPTOKI_NOT_INITIALIZED",
+	ckrCryptokiAlreadyInitialized: "CKR_CRYPTOKI_ALREADY_INITIALIZED",
+	ckrDomainParamsInvalid:        "CKR_DOMAIN_PARAMS_INVALID",
+	ckrCurveNotSupported:          "CKR_CURVE_NOT_SUPPORTED",
+}
+
+// fail is a failed call to a function of a module.
+type fail struct {
+	fn string
+	rv uint
+}
+
+func (e *fail) Error() string {
+	if e.rv == ckrUnavailable {
+		return "pkcs11: " + e.fn + " failed"
+	}
+	name, ok := ckrNames[e.rv]
+	if !ok {
+		name = "CKR 0x" + hex(e.rv)
+	}
+	return "pkcs11: " + e.fn + " failed: " + name
+}
+
+// Unwrap classifies the return values that reject the mechanism, its
+// parameters or the key as unsupported parameters.
+func (e *fail) Unwrap() error {
+	switch e.rv {
+	case ckrMechanismInvalid, ckrMechanismParamInvalid, ckrKeyTypeInconsistent,
+		ckrKeyFunctionNotPermitted, ckrKeySizeRange, ckrCurveNotSupported,
+		ckrDomainParamsInvalid, ckrFunctionNotSupported:
+		return ErrUnsupportedParameter
+	}
+	return ErrBackendFailure
+}
+
+func newFail(fn string, rv uint) error {
+	return &fail{fn, rv}
+}
+
+// unsupported is an error wrapping ErrUnsupportedParameter.
+type unsupported string
+
+func (e unsupported) Error() string { return string(e) }
+
+func (e unsupported) Unwrap() error { return ErrUnsupportedParameter }
+
+func hex(v uint) string {
+	const digits = "0123456789abcdef"
+	var b [16]byte
+	i := len(b)
+	for {
+		i--
+		b[i] = digits[v&0xf]
+		v >>= 4
+		if v == 0 {
+			return string(b[i:])
+		}
+	}
+}
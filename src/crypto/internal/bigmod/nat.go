@@ -555,6 +555,67 @@ func (x *Nat) Add(y *Nat, m *Modulus) *Nat {
 	return x
 }
 
+// shiftRightOne computes x = x >> 1, shifting carry (0 or 1) into the top bit.
+func (x *Nat) shiftRightOne(carry uint) {
+	// Eliminate bounds checks in the loop.
+	size := len(x.limbs)
+	xLimbs := x.limbs[:size]
+
+	for i := size - 1; i >= 0; i-- {
+		l := xLimbs[i]
+		xLimbs[i] = l>>1 | carry<<(_W-1)
+		carry = l & 1
+	}
+}
+
+// Inverse calculates x = a⁻¹ mod m, and returns 1 if a is invertible modulo m,
+// and 0 otherwise, in which case x is unspecified.
+//
+// The length of a must be the same as the modulus, and a must already be
+// reduced modulo m. The execution time depends only on the size of m.
+func (x *Nat) Inverse(a *Nat, m *Modulus) (*Nat, choice) {
+	// This is a constant-time binary extended GCD. It maintains
+	//
+	//   x1 * a = u mod m
+	//   x2 * a = v mod m
+	//
+	// and each iteration at least halves u * v, so after 2 * m.BitLen()
+	// iterations u is zero and v is gcd(a, m).
+	size := len(m.nat.limbs)
+	u := NewNat().set(a)
+	v := NewNat().set(m.nat)
+	x1 := NewNat().reset(size)
+	x1.limbs[0] = 1
+	x2 := NewNat().reset(size)
+	t := NewNat().reset(size)
+
+	for i := 0; i < 2*m.BitLen(); i++ {
+		// If u is odd, make sure u >= v and set u = u - v.
+		odd := choice(u.limbs[0] & 1)
+		swap := odd & not(u.cmpGeq(v))
+		t.set(u)
+		u.assign(swap, v)
+		v.assign(swap, t)
+		t.set(x1)
+		x1.assign(swap, x2)
+		x2.assign(swap, t)
+		u.sub(odd, v)
+		t.set(x1).Sub(x2, m)
+		x1.assign(odd, t)
+
+		// Now u is even, so halve it, and halve x1 mod m by adding m first
+		// if it is odd.
+		u.shiftRightOne(0)
+		x1odd := choice(x1.limbs[0] & 1)
+		c := x1.add(x1odd, m.nat)
+		x1.shiftRightOne(c & uint(x1odd))
+	}
+
+	t.reset(size)
+	t.limbs[0] = 1
+	return x.set(x2), v.Equal(t)
+}
+
 // montgomeryRepresentation calculates x = x * R mod m, with R = 2^(_W * n) and
 // n = len(m.nat.limbs).
 //
@@ -0,0 +1,704 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo
+
+package boring
+
+// This file implements the module wrapper protocol of BoringSSL's acvptool
+// (util/fipstools/acvp/ACVP.md), so that labs can validate the algorithms of
+// this package, through these exact Go bindings, with NIST ACVP vector
+// sets. acvptool parses the vector sets and writes the response files; it
+// runs the wrapper as a subprocess, and sends it one request per operation.
+//
+// To build the wrapper and run acvptool against it:
+//
+//	GOEXPERIMENT=boringcrypto go test -c -o boring.test crypto/internal/boring
+//	printf '#!/bin/sh\nACVP_WRAPPER=1 exec ./boring.test\n' > wrapper.sh
+//	chmod +x wrapper.sh
+//	acvptool -json vectors.json -wrapper ./wrapper.sh > responses.json
+//
+// Each request and response is a count of arguments, the length of each
+// argument, and the arguments, with integers encoded as little-endian
+// uint32. The first argument of a request is the name of a command.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/internal/nistec"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("ACVP_WRAPPER") == "1" {
+		if err := processACVP(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "ACVP wrapper: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// maxACVPArgs bounds the number of arguments of a request.
+const maxACVPArgs = 16
+
+// processACVP serves the requests read from r until it returns io.EOF,
+// and writes the responses to w.
+func processACVP(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		args, err := readACVPRequest(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cmd, ok := acvpCommands[string(args[0])]
+		if !ok {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+		if len(args)-1 != cmd.args {
+			return fmt.Errorf("%s: got %d arguments, want %d", args[0], len(args)-1, cmd.args)
+		}
+		resp, err := cmd.run(args[1:])
+		if err != nil {
+			return fmt.Errorf("%s: %v", args[0], err)
+		}
+		if err := writeACVPResponse(bw, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func readACVPRequest(r io.Reader) ([][]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	if n == 0 || n > maxACVPArgs {
+		return nil, fmt.Errorf("invalid number of arguments %d", n)
+	}
+	lens := make([]uint32, n)
+	if err := binary.Read(r, binary.LittleEndian, lens); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	args := make([][]byte, n)
+	for i, l := range lens {
+		args[i] = make([]byte, l)
+		if _, err := io.ReadFull(r, args[i]); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+	return args, nil
+}
+
+// writeACVPResponse writes and flushes a response, since acvptool waits for
+// it before sending the next request.
+func writeACVPResponse(w *bufio.Writer, args [][]byte) error {
+	binary.Write(w, binary.LittleEndian, uint32(len(args)))
+	for _, a := range args {
+		binary.Write(w, binary.LittleEndian, uint32(len(a)))
+	}
+	for _, a := range args {
+		w.Write(a)
+	}
+	return w.Flush()
+}
+
+type acvpCommand struct {
+	args int
+	run  func(args [][]byte) ([][]byte, error)
+}
+
+var acvpCommands = map[string]acvpCommand{
+	"getConfig": {0, func([][]byte) ([][]byte, error) { return [][]byte{[]byte(acvpConfig)}, nil }},
+
+	"SHA-1":        acvpHash(NewSHA1),
+	"SHA2-224":     acvpHash(NewSHA224),
+	"SHA2-256":     acvpHash(NewSHA256),
+	"SHA2-384":     acvpHash(NewSHA384),
+	"SHA2-512":     acvpHash(NewSHA512),
+	"SHA2-512/224": acvpHash(NewSHA512_224),
+	"SHA2-512/256": acvpHash(NewSHA512_256),
+
+	"SHA-1/MCT":        acvpHashMCT(NewSHA1),
+	"SHA2-224/MCT":     acvpHashMCT(NewSHA224),
+	"SHA2-256/MCT":     acvpHashMCT(NewSHA256),
+	"SHA2-384/MCT":     acvpHashMCT(NewSHA384),
+	"SHA2-512/MCT":     acvpHashMCT(NewSHA512),
+	"SHA2-512/224/MCT": acvpHashMCT(NewSHA512_224),
+	"SHA2-512/256/MCT": acvpHashMCT(NewSHA512_256),
+
+	"HMAC-SHA-1":    acvpHMAC(NewSHA1),
+	"HMAC-SHA2-224": acvpHMAC(NewSHA224),
+	"HMAC-SHA2-256": acvpHMAC(NewSHA256),
+	"HMAC-SHA2-384": acvpHMAC(NewSHA384),
+	"HMAC-SHA2-512": acvpHMAC(NewSHA512),
+
+	"AES/encrypt":  {3, acvpAES(true)},
+	"AES/decrypt":  {3, acvpAES(false)},
+	"AES-GCM/seal": {5, acvpGCMSeal},
+	"AES-GCM/open": {5, acvpGCMOpen},
+
+	"ECDSA/keyGen": {1, acvpECDSAKeyGen},
+	"ECDSA/keyVer": {3, acvpECDSAKeyVer},
+	"ECDSA/sigGen": {4, acvpECDSASigGen},
+	"ECDSA/sigVer": {7, acvpECDSASigVer},
+
+	"RSA/keyGen": {1, acvpRSAKeyGen},
+}
+
+func init() {
+	for name := range acvpHashes {
+		if name == "SHA-1" {
+			continue // not approved for signature generation
+		}
+		acvpCommands["RSA/sigGen/"+name+"/pkcs1v1.5"] = acvpCommand{2, acvpRSASigGen(name, false)}
+		acvpCommands["RSA/sigGen/"+name+"/pss"] = acvpCommand{2, acvpRSASigGen(name, true)}
+		acvpCommands["RSA/sigVer/"+name+"/pkcs1v1.5"] = acvpCommand{4, acvpRSASigVer(name, false)}
+		acvpCommands["RSA/sigVer/"+name+"/pss"] = acvpCommand{4, acvpRSASigVer(name, true)}
+	}
+}
+
+// acvpConfig is the response to getConfig: the capabilities of the module
+// that acvptool registers with the ACVP server.
+const acvpConfig = `[
+	{"algorithm": "SHA-1", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "SHA2-224", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "SHA2-256", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "SHA2-384", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "SHA2-512", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "SHA2-512/224", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "SHA2-512/256", "revision": "1.0", "messageLength": [{"min": 0, "max": 65528, "increment": 8}]},
+	{"algorithm": "HMAC-SHA-1", "revision": "1.0", "keyLen": [{"min": 8, "max": 2048, "increment": 8}], "macLen": [{"min": 32, "max": 160, "increment": 8}]},
+	{"algorithm": "HMAC-SHA2-224", "revision": "1.0", "keyLen": [{"min": 8, "max": 2048, "increment": 8}], "macLen": [{"min": 32, "max": 224, "increment": 8}]},
+	{"algorithm": "HMAC-SHA2-256", "revision": "1.0", "keyLen": [{"min": 8, "max": 2048, "increment": 8}], "macLen": [{"min": 32, "max": 256, "increment": 8}]},
+	{"algorithm": "HMAC-SHA2-384", "revision": "1.0", "keyLen": [{"min": 8, "max": 2048, "increment": 8}], "macLen": [{"min": 32, "max": 384, "increment": 8}]},
+	{"algorithm": "HMAC-SHA2-512", "revision": "1.0", "keyLen": [{"min": 8, "max": 2048, "increment": 8}], "macLen": [{"min": 32, "max": 512, "increment": 8}]},
+	{"algorithm": "ACVP-AES-ECB", "revision": "1.0", "direction": ["encrypt", "decrypt"], "keyLen": [128, 192, 256]},
+	{"algorithm": "ACVP-AES-GCM", "revision": "1.0", "direction": ["encrypt", "decrypt"], "keyLen": [128, 256],
+		"payloadLen": [{"min": 0, "max": 65536, "increment": 8}], "aadLen": [{"min": 0, "max": 65536, "increment": 8}],
+		"tagLen": [128], "ivLen": [96], "ivGen": "external"},
+	{"algorithm": "ECDSA", "mode": "keyGen", "revision": "FIPS186-4", "curve": ["P-224", "P-256", "P-384", "P-521"],
+		"secretGenerationMode": ["testing candidates"]},
+	{"algorithm": "ECDSA", "mode": "keyVer", "revision": "FIPS186-4", "curve": ["P-224", "P-256", "P-384", "P-521"]},
+	{"algorithm": "ECDSA", "mode": "sigGen", "revision": "FIPS186-4", "capabilities": [{"curve": ["P-224", "P-256", "P-384", "P-521"],
+		"hashAlg": ["SHA2-224", "SHA2-256", "SHA2-384", "SHA2-512"]}]},
+	{"algorithm": "ECDSA", "mode": "sigVer", "revision": "FIPS186-4", "capabilities": [{"curve": ["P-224", "P-256", "P-384", "P-521"],
+		"hashAlg": ["SHA-1", "SHA2-224", "SHA2-256", "SHA2-384", "SHA2-512"]}]},
+	{"algorithm": "RSA", "mode": "keyGen", "revision": "FIPS186-4", "infoGeneratedByServer": true, "pubExpMode": "fixed",
+		"fixedPubExp": "010001", "keyFormat": "standard", "capabilities": [{"randPQ": "B.3.3",
+		"properties": [{"modulo": 2048, "primeTest": ["tblC2"]}, {"modulo": 3072, "primeTest": ["tblC2"]}]}]},
+	{"algorithm": "RSA", "mode": "sigGen", "revision": "FIPS186-4", "capabilities": [
+		{"sigType": "pkcs1v1.5", "properties": [{"modulo": 2048, "hashPair": [{"hashAlg": "SHA2-224"}, {"hashAlg": "SHA2-256"}, {"hashAlg": "SHA2-384"}, {"hashAlg": "SHA2-512"}]},
+			{"modulo": 3072, "hashPair": [{"hashAlg": "SHA2-224"}, {"hashAlg": "SHA2-256"}, {"hashAlg": "SHA2-384"}, {"hashAlg": "SHA2-512"}]}]},
+		{"sigType": "pss", "properties": [{"modulo": 2048, "hashPair": [{"hashAlg": "SHA2-224", "saltLen": 28}, {"hashAlg": "SHA2-256", "saltLen": 32}, {"hashAlg": "SHA2-384", "saltLen": 48}, {"hashAlg": "SHA2-512", "saltLen": 64}]},
+			{"modulo": 3072, "hashPair": [{"hashAlg": "SHA2-224", "saltLen": 28}, {"hashAlg": "SHA2-256", "saltLen": 32}, {"hashAlg": "SHA2-384", "saltLen": 48}, {"hashAlg": "SHA2-512", "saltLen": 64}]}]}]},
+	{"algorithm": "RSA", "mode": "sigVer", "revision": "FIPS186-4", "pubExpMode": "random", "capabilities": [
+		{"sigType": "pkcs1v1.5", "properties": [{"modulo": 2048, "hashPair": [{"hashAlg": "SHA2-224"}, {"hashAlg": "SHA2-256"}, {"hashAlg": "SHA2-384"}, {"hashAlg": "SHA2-512"}]},
+			{"modulo": 3072, "hashPair": [{"hashAlg": "SHA2-224"}, {"hashAlg": "SHA2-256"}, {"hashAlg": "SHA2-384"}, {"hashAlg": "SHA2-512"}]}]},
+		{"sigType": "pss", "properties": [{"modulo": 2048, "hashPair": [{"hashAlg": "SHA2-224", "saltLen": 28}, {"hashAlg": "SHA2-256", "saltLen": 32}, {"hashAlg": "SHA2-384", "saltLen": 48}, {"hashAlg": "SHA2-512", "saltLen": 64}]},
+			{"modulo": 3072, "hashPair": [{"hashAlg": "SHA2-224", "saltLen": 28}, {"hashAlg": "SHA2-256", "saltLen": 32}, {"hashAlg": "SHA2-384", "saltLen": 48}, {"hashAlg": "SHA2-512", "saltLen": 64}]}]}]}
+]`
+
+// acvpHashes maps the ACVP names of the hashes used in signatures to their
+// implementations.
+var acvpHashes = map[string]struct {
+	new func() hash.Hash
+	id  crypto.Hash
+}{
+	"SHA-1":    {NewSHA1, crypto.SHA1},
+	"SHA2-224": {NewSHA224, crypto.SHA224},
+	"SHA2-256": {NewSHA256, crypto.SHA256},
+	"SHA2-384": {NewSHA384, crypto.SHA384},
+	"SHA2-512": {NewSHA512, crypto.SHA512},
+}
+
+func acvpDigest(h func() hash.Hash, msg []byte) []byte {
+	d := h()
+	d.Write(msg)
+	return d.Sum(nil)
+}
+
+func acvpHash(h func() hash.Hash) acvpCommand {
+	return acvpCommand{1, func(args [][]byte) ([][]byte, error) {
+		return [][]byte{acvpDigest(h, args[0])}, nil
+	}}
+}
+
+// acvpHashMCT runs the inner loop of the Monte Carlo test of SHAVS 6.4:
+// the digest of each of 1000 iterations is the hash of the concatenation of
+// the previous three, starting from the seed.
+func acvpHashMCT(h func() hash.Hash) acvpCommand {
+	return acvpCommand{1, func(args [][]byte) ([][]byte, error) {
+		md := [3][]byte{args[0], args[0], args[0]}
+		d := h()
+		for i := 0; i < 1000; i++ {
+			d.Reset()
+			d.Write(md[0])
+			d.Write(md[1])
+			d.Write(md[2])
+			md[0], md[1], md[2] = md[1], md[2], d.Sum(nil)
+		}
+		return [][]byte{md[2]}, nil
+	}}
+}
+
+func acvpHMAC(h func() hash.Hash) acvpCommand {
+	return acvpCommand{2, func(args [][]byte) ([][]byte, error) {
+		msg, key := args[0], args[1]
+		m := NewHMAC(h, key)
+		m.Write(msg)
+		return [][]byte{m.Sum(nil)}, nil
+	}}
+}
+
+// acvpUint32 decodes a little-endian uint32 argument.
+func acvpUint32(b []byte) (uint32, error) {
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid uint32 argument of %d bytes", len(b))
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// acvpAES encrypts or decrypts a whole number of blocks, as many times as
+// requested, and returns the last two results, for the Monte Carlo tests.
+func acvpAES(encrypt bool) func(args [][]byte) ([][]byte, error) {
+	return func(args [][]byte) ([][]byte, error) {
+		key, input := args[0], args[1]
+		n, err := acvpUint32(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if len(input)%aesBlockSize != 0 {
+			return nil, errors.New("input is not a whole number of blocks")
+		}
+		b, err := NewAESCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		result, prev := bytes.Clone(input), make([]byte, len(input))
+		for i := uint32(0); i < n; i++ {
+			copy(prev, result)
+			for j := 0; j < len(result); j += aesBlockSize {
+				if encrypt {
+					b.Encrypt(result[j:], prev[j:])
+				} else {
+					b.Decrypt(result[j:], prev[j:])
+				}
+			}
+		}
+		return [][]byte{result, prev}, nil
+	}
+}
+
+func acvpGCM(tagLen, key []byte) (cipher.AEAD, error) {
+	n, err := acvpUint32(tagLen)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return b.(*aesCipher).NewGCM(gcmStandardNonceSize, int(n))
+}
+
+func acvpGCMSeal(args [][]byte) ([][]byte, error) {
+	g, err := acvpGCM(args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	plaintext, nonce, ad := args[2], args[3], args[4]
+	if len(nonce) != gcmStandardNonceSize {
+		return nil, fmt.Errorf("unsupported nonce size %d", len(nonce))
+	}
+	return [][]byte{g.Seal(nil, nonce, plaintext, ad)}, nil
+}
+
+func acvpGCMOpen(args [][]byte) ([][]byte, error) {
+	g, err := acvpGCM(args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, nonce, ad := args[2], args[3], args[4]
+	if len(nonce) != gcmStandardNonceSize {
+		return nil, fmt.Errorf("unsupported nonce size %d", len(nonce))
+	}
+	plaintext, err := g.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return [][]byte{{0}, nil}, nil
+	}
+	return [][]byte{{1}, plaintext}, nil
+}
+
+// acvpCurveSize returns the size in bytes of the scalars and coordinates
+// of curve.
+func acvpCurveSize(curve string) (int, error) {
+	switch curve {
+	case "P-224":
+		return 28, nil
+	case "P-256":
+		return 32, nil
+	case "P-384":
+		return 48, nil
+	case "P-521":
+		return 66, nil
+	}
+	return 0, fmt.Errorf("unsupported curve %q", curve)
+}
+
+// acvpPublicKey computes the public point of the private key d.
+func acvpPublicKey(curve string, d []byte) (x, y []byte, err error) {
+	size, err := acvpCurveSize(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	d = acvpPad(d, size)
+	var p []byte
+	switch curve {
+	case "P-224":
+		q, err := nistec.NewP224Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		p = q.Bytes()
+	case "P-256":
+		q, err := nistec.NewP256Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		p = q.Bytes()
+	case "P-384":
+		q, err := nistec.NewP384Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		p = q.Bytes()
+	case "P-521":
+		q, err := nistec.NewP521Point().ScalarBaseMult(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		p = q.Bytes()
+	}
+	if len(p) != 1+2*size {
+		return nil, nil, errors.New("private key is zero")
+	}
+	return p[1 : 1+size], p[1+size:], nil
+}
+
+func acvpECDSAKeyGen(args [][]byte) ([][]byte, error) {
+	curve := string(args[0])
+	size, err := acvpCurveSize(curve)
+	if err != nil {
+		return nil, err
+	}
+	x, y, d, err := GenerateKeyECDSA(curve)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{acvpBigBytes(d, size), acvpBigBytes(x, size), acvpBigBytes(y, size)}, nil
+}
+
+func acvpECDSAKeyVer(args [][]byte) ([][]byte, error) {
+	curve := string(args[0])
+	if _, err := acvpCurveSize(curve); err != nil {
+		return nil, err
+	}
+	if _, err := NewPublicKeyECDSA(curve, acvpBig(args[1]), acvpBig(args[2])); err != nil {
+		return [][]byte{{0}}, nil
+	}
+	return [][]byte{{1}}, nil
+}
+
+func acvpECDSASigGen(args [][]byte) ([][]byte, error) {
+	curve, d, hashName, msg := string(args[0]), args[1], string(args[2]), args[3]
+	size, err := acvpCurveSize(curve)
+	if err != nil {
+		return nil, err
+	}
+	h, ok := acvpHashes[hashName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash %q", hashName)
+	}
+	x, y, err := acvpPublicKey(curve, d)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := NewPrivateKeyECDSA(curve, acvpBig(x), acvpBig(y), acvpBig(d))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := SignMarshalECDSA(priv, acvpDigest(h.new, msg))
+	if err != nil {
+		return nil, err
+	}
+	var r, s []byte
+	var inner cryptobyte.String
+	input := cryptobyte.String(sig)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!inner.ReadASN1Integer(&r) ||
+		!inner.ReadASN1Integer(&s) {
+		return nil, errors.New("invalid signature encoding")
+	}
+	return [][]byte{acvpPad(r, size), acvpPad(s, size)}, nil
+}
+
+func acvpECDSASigVer(args [][]byte) ([][]byte, error) {
+	curve, hashName, msg := string(args[0]), string(args[1]), args[2]
+	x, y, r, s := args[3], args[4], args[5], args[6]
+	if _, err := acvpCurveSize(curve); err != nil {
+		return nil, err
+	}
+	h, ok := acvpHashes[hashName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash %q", hashName)
+	}
+	pub, err := NewPublicKeyECDSA(curve, acvpBig(x), acvpBig(y))
+	if err != nil {
+		return [][]byte{{0}}, nil
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(new(big.Int).SetBytes(r))
+		b.AddASN1BigInt(new(big.Int).SetBytes(s))
+	})
+	sig, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if !VerifyECDSA(pub, acvpDigest(h.new, msg), sig) {
+		return [][]byte{{0}}, nil
+	}
+	return [][]byte{{1}}, nil
+}
+
+func acvpRSAKeyGen(args [][]byte) ([][]byte, error) {
+	bits, err := acvpUint32(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, e, d, p, q, _, _, _, err := GenerateKeyRSA(int(bits))
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{acvpBigBytes(e, 0), acvpBigBytes(p, 0), acvpBigBytes(q, 0),
+		acvpBigBytes(n, 0), acvpBigBytes(d, 0)}, nil
+}
+
+// acvpRSAKeys caches a key of each size for signature generation, since a
+// test group shares one key, which the response reports.
+var acvpRSAKeys = map[uint32]*struct {
+	priv *PrivateKeyRSA
+	n, e []byte
+}{}
+
+func acvpRSASigGen(hashName string, pss bool) func(args [][]byte) ([][]byte, error) {
+	return func(args [][]byte) ([][]byte, error) {
+		bits, err := acvpUint32(args[0])
+		if err != nil {
+			return nil, err
+		}
+		key, ok := acvpRSAKeys[bits]
+		if !ok {
+			N, E, D, P, Q, Dp, Dq, Qinv, err := GenerateKeyRSA(int(bits))
+			if err != nil {
+				return nil, err
+			}
+			priv, err := NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv)
+			if err != nil {
+				return nil, err
+			}
+			key = &struct {
+				priv *PrivateKeyRSA
+				n, e []byte
+			}{priv, acvpBigBytes(N, 0), acvpBigBytes(E, 0)}
+			acvpRSAKeys[bits] = key
+		}
+		h := acvpHashes[hashName]
+		hashed := acvpDigest(h.new, args[1])
+		var sig []byte
+		if pss {
+			sig, err = SignRSAPSS(key.priv, h.id, hashed, h.id.Size())
+		} else {
+			sig, err = SignRSAPKCS1v15(key.priv, h.id, hashed)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{key.n, key.e, sig}, nil
+	}
+}
+
+func acvpRSASigVer(hashName string, pss bool) func(args [][]byte) ([][]byte, error) {
+	return func(args [][]byte) ([][]byte, error) {
+		n, e, msg, sig := args[0], args[1], args[2], args[3]
+		pub, err := NewPublicKeyRSA(acvpBig(n), acvpBig(e))
+		if err != nil {
+			return [][]byte{{0}}, nil
+		}
+		h := acvpHashes[hashName]
+		hashed := acvpDigest(h.new, msg)
+		if pss {
+			err = VerifyRSAPSS(pub, h.id, hashed, sig, h.id.Size())
+		} else {
+			err = VerifyRSAPKCS1v15(pub, h.id, hashed, sig)
+		}
+		if err != nil {
+			return [][]byte{{0}}, nil
+		}
+		return [][]byte{{1}}, nil
+	}
+}
+
+// acvpBig decodes a big-endian integer into the little-endian words of a
+// BigInt.
+func acvpBig(b []byte) BigInt {
+	x := make(BigInt, (len(b)+wordBytes-1)/wordBytes)
+	for i, c := range b {
+		j := len(b) - 1 - i // byte index, least significant first
+		x[j/wordBytes] |= uint(c) << (8 * (j % wordBytes))
+	}
+	return x
+}
+
+// acvpBigBytes encodes x as a big-endian integer of at least size bytes.
+func acvpBigBytes(x BigInt, size int) []byte {
+	b := make([]byte, len(x)*wordBytes)
+	for i, w := range x {
+		for j := 0; j < wordBytes; j++ {
+			b[len(b)-1-i*wordBytes-j] = byte(w >> (8 * j))
+		}
+	}
+	return acvpPad(bytes.TrimLeft(b, "\x00"), size)
+}
+
+// acvpPad left-pads b with zeros to size bytes.
+func acvpPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// acvpCall runs a single request through processACVP.
+func acvpCall(t *testing.T, cmd string, args ...[]byte) [][]byte {
+	t.Helper()
+	var req, resp bytes.Buffer
+	args = append([][]byte{[]byte(cmd)}, args...)
+	if err := writeACVPResponse(bufio.NewWriter(&req), args); err != nil {
+		t.Fatal(err)
+	}
+	if err := processACVP(&req, &resp); err != nil {
+		t.Fatalf("%s: %v", cmd, err)
+	}
+	out, err := readACVPRequest(&resp)
+	if err != nil {
+		t.Fatalf("%s: reading response: %v", cmd, err)
+	}
+	return out
+}
+
+func acvpUint32Arg(n uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, n)
+}
+
+func TestACVP(t *testing.T) {
+	config := acvpCall(t, "getConfig")
+	var algs []struct{ Algorithm, Mode string }
+	if err := json.Unmarshal(config[0], &algs); err != nil || len(algs) == 0 {
+		t.Fatalf("getConfig: %v", err)
+	}
+
+	if got := acvpCall(t, "SHA2-256", []byte("abc")); len(got) != 1 ||
+		!bytes.Equal(got[0], castHex("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")) {
+		t.Errorf("SHA2-256 = %x", got)
+	}
+	if got := acvpCall(t, "SHA2-256/MCT", make([]byte, 32)); len(got) != 1 || len(got[0]) != 32 {
+		t.Errorf("SHA2-256/MCT = %x", got)
+	}
+	if got := acvpCall(t, "HMAC-SHA2-256", []byte("Hi There"), bytes.Repeat([]byte{0x0b}, 20)); len(got) != 1 ||
+		!bytes.Equal(got[0], castHex("b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7")) {
+		t.Errorf("HMAC-SHA2-256 = %x", got)
+	}
+
+	key := castHex("000102030405060708090a0b0c0d0e0f")
+	block := castHex("00112233445566778899aabbccddeeff")
+	enc := acvpCall(t, "AES/encrypt", key, block, acvpUint32Arg(1))
+	if len(enc) != 2 || !bytes.Equal(enc[0], castHex("69c4e0d86a7b0430d8cdb78070b4c55a")) || !bytes.Equal(enc[1], block) {
+		t.Errorf("AES/encrypt = %x", enc)
+	}
+	if dec := acvpCall(t, "AES/decrypt", key, enc[0], acvpUint32Arg(1)); !bytes.Equal(dec[0], block) {
+		t.Errorf("AES/decrypt = %x", dec)
+	}
+
+	nonce := make([]byte, 12)
+	sealed := acvpCall(t, "AES-GCM/seal", acvpUint32Arg(16), make([]byte, 16), make([]byte, 16), nonce, nil)
+	if !bytes.Equal(sealed[0], castHex("0388dace60b6a392f328c2b971b2fe78ab6e47d42cec13bdf53a67b21257bddf")) {
+		t.Errorf("AES-GCM/seal = %x", sealed)
+	}
+	if got := acvpCall(t, "AES-GCM/open", acvpUint32Arg(16), make([]byte, 16), sealed[0], nonce, nil); got[0][0] != 1 {
+		t.Errorf("AES-GCM/open = %x", got)
+	}
+	sealed[0][0] ^= 1
+	if got := acvpCall(t, "AES-GCM/open", acvpUint32Arg(16), make([]byte, 16), sealed[0], nonce, nil); got[0][0] != 0 {
+		t.Errorf("AES-GCM/open of a modified ciphertext = %x", got)
+	}
+
+	for _, curve := range []string{"P-224", "P-256", "P-384", "P-521"} {
+		k := acvpCall(t, "ECDSA/keyGen", []byte(curve))
+		d, x, y := k[0], k[1], k[2]
+		if px, py, err := acvpPublicKey(curve, d); err != nil || !bytes.Equal(px, x) || !bytes.Equal(py, y) {
+			t.Errorf("%s: public key of ECDSA/keyGen private key = %x, %x, %v", curve, px, py, err)
+		}
+		if got := acvpCall(t, "ECDSA/keyVer", []byte(curve), x, y); got[0][0] != 1 {
+			t.Errorf("%s: ECDSA/keyVer = %x", curve, got)
+		}
+		sig := acvpCall(t, "ECDSA/sigGen", []byte(curve), d, []byte("SHA2-256"), []byte("message"))
+		if got := acvpCall(t, "ECDSA/sigVer", []byte(curve), []byte("SHA2-256"), []byte("message"), x, y, sig[0], sig[1]); got[0][0] != 1 {
+			t.Errorf("%s: ECDSA/sigVer = %x", curve, got)
+		}
+		if got := acvpCall(t, "ECDSA/sigVer", []byte(curve), []byte("SHA2-256"), []byte("other"), x, y, sig[0], sig[1]); got[0][0] != 0 {
+			t.Errorf("%s: ECDSA/sigVer of another message = %x", curve, got)
+		}
+	}
+
+	for _, typ := range []string{"pkcs1v1.5", "pss"} {
+		sig := acvpCall(t, "RSA/sigGen/SHA2-256/"+typ, acvpUint32Arg(2048), []byte("message"))
+		n, e := sig[0], sig[1]
+		if len(n) != 256 || len(sig[2]) != 256 {
+			t.Errorf("RSA/sigGen/SHA2-256/%s: %d-byte modulus and %d-byte signature", typ, len(n), len(sig[2]))
+		}
+		if got := acvpCall(t, "RSA/sigVer/SHA2-256/"+typ, n, e, []byte("message"), sig[2]); got[0][0] != 1 {
+			t.Errorf("RSA/sigVer/SHA2-256/%s = %x", typ, got)
+		}
+		if got := acvpCall(t, "RSA/sigVer/SHA2-256/"+typ, n, e, []byte("other"), sig[2]); got[0][0] != 0 {
+			t.Errorf("RSA/sigVer/SHA2-256/%s of another message = %x", typ, got)
+		}
+	}
+
+	var req bytes.Buffer
+	writeACVPResponse(bufio.NewWriter(&req), [][]byte{[]byte("SHA3-256"), nil})
+	if err := processACVP(&req, io.Discard); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("unknown command: %v", err)
+	}
+}
@@ -46,7 +46,10 @@ import "C"
 import (
 	"bytes"
 	"crypto/cipher"
+	"crypto/internal/keyguard"
 	"errors"
+	"hash"
+	"internal/cryptometrics"
 	"runtime"
 	"strconv"
 	"unsafe"
@@ -88,7 +91,25 @@ func NewAESCipher(key []byte) (cipher.Block, error) {
 
 func (c *aesCipher) BlockSize() int { return aesBlockSize }
 
+// Close zeroes the key and the key schedules of c immediately, rather than
+// when c is garbage collected. c, and the CBC and CTR modes created from it,
+// must not be used afterwards. AEADs created by NewGCM hold their own copy
+// of the key, and have their own Close method.
+func (c *aesCipher) Close() error {
+	keyguard.Free(c.key)
+	c.key = nil
+	c.enc, c.dec = C.GO_AES_KEY{}, C.GO_AES_KEY{}
+	return nil
+}
+
+func (c *aesCipher) checkOpen() {
+	if c.key == nil {
+		panic("crypto/aes: use of closed cipher")
+	}
+}
+
 func (c *aesCipher) Encrypt(dst, src []byte) {
+	c.checkOpen()
 	if inexactOverlap(dst, src) {
 		panic("crypto/cipher: invalid buffer overlap")
 	}
@@ -98,6 +119,7 @@ func (c *aesCipher) Encrypt(dst, src []byte) {
 	if len(dst) < aesBlockSize {
 		panic("crypto/aes: output not full block")
 	}
+	countOp(cryptometrics.AES, aesBlockSize)
 	C._goboringcrypto_AES_encrypt(
 		(*C.uint8_t)(unsafe.Pointer(&src[0])),
 		(*C.uint8_t)(unsafe.Pointer(&dst[0])),
@@ -105,6 +127,7 @@ func (c *aesCipher) Encrypt(dst, src []byte) {
 }
 
 func (c *aesCipher) Decrypt(dst, src []byte) {
+	c.checkOpen()
 	if inexactOverlap(dst, src) {
 		panic("crypto/cipher: invalid buffer overlap")
 	}
@@ -114,6 +137,7 @@ func (c *aesCipher) Decrypt(dst, src []byte) {
 	if len(dst) < aesBlockSize {
 		panic("crypto/aes: output not full block")
 	}
+	countOp(cryptometrics.AES, aesBlockSize)
 	C._goboringcrypto_AES_decrypt(
 		(*C.uint8_t)(unsafe.Pointer(&src[0])),
 		(*C.uint8_t)(unsafe.Pointer(&dst[0])),
@@ -138,6 +162,7 @@ func (x *aesCBC) CryptBlocks(dst, src []byte) {
 	if len(dst) < len(src) {
 		panic("crypto/cipher: output smaller than input")
 	}
+	countOp(cryptometrics.AES, len(src))
 	if len(src) > 0 {
 		C._goboringcrypto_AES_cbc_encrypt(
 			(*C.uint8_t)(unsafe.Pointer(&src[0])),
@@ -180,6 +205,7 @@ func (x *aesCTR) XORKeyStream(dst, src []byte) {
 	if len(dst) < len(src) {
 		panic("crypto/cipher: output smaller than input")
 	}
+	countOp(cryptometrics.AES, len(src))
 	if len(src) == 0 {
 		return
 	}
@@ -191,6 +217,10 @@ func (x *aesCTR) XORKeyStream(dst, src []byte) {
 }
 
 func (c *aesCipher) NewCTR(iv []byte) cipher.Stream {
+	// crypto/cipher.NewCTR leaves the check of the IV to ctrAble ciphers.
+	if len(iv) != aesBlockSize {
+		panic("cipher.NewCTR: IV length must equal block size")
+	}
 	x := &aesCTR{key: &c.enc}
 	copy(x.iv[:], iv)
 	return x
@@ -199,6 +229,12 @@ func (c *aesCipher) NewCTR(iv []byte) cipher.Stream {
 type aesGCM struct {
 	ctx  C.GO_EVP_AEAD_CTX
 	aead *C.GO_EVP_AEAD
+
+	// nonceSize is the length of the nonces passed to Seal and Open. The
+	// EVP_aead_aes_*_gcm AEADs report a nonce length of 12 bytes, but
+	// accept nonces of any non-zero length, which they hash with GHASH
+	// into the initial counter block as specified by SP 800-38D.
+	nonceSize int
 }
 
 const (
@@ -219,23 +255,44 @@ type noGCM struct {
 
 func (c *aesCipher) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
 	if nonceSize != gcmStandardNonceSize && tagSize != gcmTagSize {
-		return nil, errors.New("crypto/aes: GCM tag and nonce sizes can't be non-standard at the same time")
-	}
-	// Fall back to standard library for GCM with non-standard nonce or tag size.
-	if nonceSize != gcmStandardNonceSize {
-		return cipher.NewGCMWithNonceSize(&noGCM{c}, nonceSize)
+		return nil, unsupported("crypto/aes: GCM tag and nonce sizes can't be non-standard at the same time")
 	}
+	// Fall back to standard library for GCM with non-standard tag size.
 	if tagSize != gcmTagSize {
+		RecordFallback(cryptometrics.AESGCM, "non-standard tag size")
 		return cipher.NewGCMWithTagSize(&noGCM{c}, tagSize)
 	}
-	return c.newGCM(false)
+	return c.newGCM(nonceSize, false)
 }
 
 func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
-	return c.(*aesCipher).newGCM(true)
+	return c.(*aesCipher).newGCM(gcmStandardNonceSize, true)
 }
 
-func (c *aesCipher) newGCM(tls bool) (cipher.AEAD, error) {
+// SupportsAESKeyWrap reports whether the module implements the KW and KWP
+// modes of SP 800-38F. The module does, but the syso files don't export
+// AES_wrap_key and AES_unwrap_key.
+func SupportsAESKeyWrap() bool { return false }
+
+func WrapKeyAES(kek, key []byte, pad bool) ([]byte, error) {
+	return nil, unsupported("boringcrypto: AES key wrap not supported")
+}
+
+func UnwrapKeyAES(kek, wrapped []byte, pad bool) ([]byte, error) {
+	return nil, unsupported("boringcrypto: AES key wrap not supported")
+}
+
+// SupportsCMAC reports whether the module implements AES-CMAC. The module
+// does, but the syso files don't export the CMAC_CTX functions.
+func SupportsCMAC() bool { return false }
+
+func NewCMAC(key []byte) (hash.Hash, error) {
+	return nil, unsupported("boringcrypto: AES-CMAC not supported")
+}
+
+// newGCM returns the AES-GCM AEAD of c for nonces of nonceSize bytes. The
+// TLS 1.2 AEADs only accept standard nonces.
+func (c *aesCipher) newGCM(nonceSize int, tls bool) (cipher.AEAD, error) {
 	var aead *C.GO_EVP_AEAD
 	switch len(c.key) * 8 {
 	case 128:
@@ -252,10 +309,11 @@ func (c *aesCipher) newGCM(tls bool) (cipher.AEAD, error) {
 		}
 	default:
 		// Fall back to standard library for GCM with non-standard key size.
-		return cipher.NewGCMWithNonceSize(&noGCM{c}, gcmStandardNonceSize)
+		RecordFallback(cryptometrics.AESGCM, "non-standard key size")
+		return cipher.NewGCMWithNonceSize(&noGCM{c}, nonceSize)
 	}
 
-	g := &aesGCM{aead: aead}
+	g := &aesGCM{aead: aead, nonceSize: nonceSize}
 	if C._goboringcrypto_EVP_AEAD_CTX_init(&g.ctx, aead, (*C.uint8_t)(unsafe.Pointer(&c.key[0])), C.size_t(len(c.key)), C.GO_EVP_AEAD_DEFAULT_TAG_LENGTH, nil) == 0 {
 		return nil, fail("EVP_AEAD_CTX_init")
 	}
@@ -264,7 +322,7 @@ func (c *aesCipher) newGCM(tls bool) (cipher.AEAD, error) {
 	// to make sure g is not collected (and finalized) before the cgo
 	// call returns.
 	runtime.SetFinalizer(g, (*aesGCM).finalize)
-	if g.NonceSize() != gcmStandardNonceSize {
+	if C._goboringcrypto_EVP_AEAD_nonce_length(aead) != gcmStandardNonceSize {
 		panic("boringcrypto: internal confusion about nonce size")
 	}
 	if g.Overhead() != gcmTagSize {
@@ -278,8 +336,27 @@ func (g *aesGCM) finalize() {
 	C._goboringcrypto_EVP_AEAD_CTX_cleanup(&g.ctx)
 }
 
+// Close zeroes the key and the state of g immediately, rather than when g is
+// garbage collected. g must not be used afterwards.
+func (g *aesGCM) Close() error {
+	if g.aead == nil {
+		return nil
+	}
+	C._goboringcrypto_EVP_AEAD_CTX_cleanup(&g.ctx)
+	g.ctx = C.GO_EVP_AEAD_CTX{} // the cleanup leaves the AES-GCM key in place
+	g.aead = nil
+	runtime.SetFinalizer(g, nil)
+	return nil
+}
+
+func (g *aesGCM) checkOpen() {
+	if g.aead == nil {
+		panic("crypto/cipher: use of closed GCM")
+	}
+}
+
 func (g *aesGCM) NonceSize() int {
-	return int(C._goboringcrypto_EVP_AEAD_nonce_length(g.aead))
+	return g.nonceSize
 }
 
 func (g *aesGCM) Overhead() int {
@@ -296,7 +373,8 @@ func base(b []byte) *C.uint8_t {
 }
 
 func (g *aesGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
-	if len(nonce) != gcmStandardNonceSize {
+	g.checkOpen()
+	if len(nonce) != g.nonceSize {
 		panic("cipher: incorrect nonce length given to GCM")
 	}
 	if uint64(len(plaintext)) > ((1<<32)-2)*aesBlockSize || len(plaintext)+gcmTagSize < len(plaintext) {
@@ -318,6 +396,7 @@ func (g *aesGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 		panic("cipher: invalid buffer overlap")
 	}
 
+	countOp(cryptometrics.AESGCM, len(plaintext))
 	outLen := C.size_t(len(plaintext) + gcmTagSize)
 	ok := C.EVP_AEAD_CTX_seal_wrapper(
 		&g.ctx,
@@ -335,13 +414,17 @@ func (g *aesGCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 var errOpen = errors.New("cipher: message authentication failed")
 
 func (g *aesGCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
-	if len(nonce) != gcmStandardNonceSize {
+	g.checkOpen()
+	if len(nonce) != g.nonceSize {
 		panic("cipher: incorrect nonce length given to GCM")
 	}
+	countOp(cryptometrics.AESGCM, len(ciphertext))
 	if len(ciphertext) < gcmTagSize {
+		countFailure(cryptometrics.AESGCM)
 		return nil, errOpen
 	}
 	if uint64(len(ciphertext)) > ((1<<32)-2)*aesBlockSize+gcmTagSize {
+		countFailure(cryptometrics.AESGCM)
 		return nil, errOpen
 	}
 
@@ -366,6 +449,7 @@ func (g *aesGCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, er
 		base(additionalData), C.size_t(len(additionalData)))
 	runtime.KeepAlive(g)
 	if ok == 0 {
+		countFailure(cryptometrics.AESGCM)
 		return nil, errOpen
 	}
 	return dst[:n+int(outLen)], nil
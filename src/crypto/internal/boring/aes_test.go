@@ -0,0 +1,103 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo
+
+package boring
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"internal/cryptometrics"
+	"testing"
+)
+
+// TestGCMNonceSizes checks that BoringCrypto handles AES-GCM with
+// non-standard nonce sizes, and that it agrees with the generic Go GCM.
+func TestGCMNonceSizes(t *testing.T) {
+	for _, keySize := range []int{16, 32} {
+		c, err := NewAESCipher(bytes.Repeat([]byte{0x42}, keySize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, nonceSize := range []int{1, 8, 12, 16, 64} {
+			fallbacks := readCounter(cryptometrics.AESGCM, cryptometrics.Fallbacks)
+			g, err := c.(extraModes).NewGCM(nonceSize, gcmTagSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := g.(*aesGCM); !ok {
+				t.Fatalf("AES-%d GCM with %d-byte nonces: got %T, want *aesGCM", keySize*8, nonceSize, g)
+			}
+			if got := readCounter(cryptometrics.AESGCM, cryptometrics.Fallbacks) - fallbacks; got != 0 {
+				t.Errorf("AES-%d GCM with %d-byte nonces: %d fallbacks", keySize*8, nonceSize, got)
+			}
+			if g.NonceSize() != nonceSize {
+				t.Errorf("AES-%d GCM: NonceSize() = %d, want %d", keySize*8, g.NonceSize(), nonceSize)
+			}
+			want, err := cipher.NewGCMWithNonceSize(&noGCM{c}, nonceSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nonce := bytes.Repeat([]byte{0x17}, nonceSize)
+			plaintext, ad := []byte("plaintext of an odd size"), []byte("additional data")
+			ciphertext := g.Seal(nil, nonce, plaintext, ad)
+			if expected := want.Seal(nil, nonce, plaintext, ad); !bytes.Equal(ciphertext, expected) {
+				t.Errorf("AES-%d GCM with %d-byte nonces: Seal = %x, want %x", keySize*8, nonceSize, ciphertext, expected)
+			}
+			if got, err := g.Open(nil, nonce, ciphertext, ad); err != nil || !bytes.Equal(got, plaintext) {
+				t.Errorf("AES-%d GCM with %d-byte nonces: Open = %q, %v", keySize*8, nonceSize, got, err)
+			}
+			ciphertext[0] ^= 1
+			if _, err := g.Open(nil, nonce, ciphertext, ad); err == nil {
+				t.Errorf("AES-%d GCM with %d-byte nonces: Open of a modified ciphertext succeeded", keySize*8, nonceSize)
+			}
+		}
+	}
+}
+
+// TestCBCCTR checks that crypto/cipher uses the BoringCrypto CBC and CTR
+// modes, and that they agree with the generic Go modes.
+func TestCBCCTR(t *testing.T) {
+	for _, keySize := range []int{16, 24, 32} {
+		c, err := NewAESCipher(bytes.Repeat([]byte{0x42}, keySize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		generic := &noGCM{c}
+		iv := bytes.Repeat([]byte{0x17}, aesBlockSize)
+		src := bytes.Repeat([]byte("sixteen byte msg"), 5)
+
+		enc, dec := cipher.NewCBCEncrypter(c, iv), cipher.NewCBCDecrypter(c, iv)
+		if _, ok := enc.(*aesCBC); !ok {
+			t.Fatalf("AES-%d: NewCBCEncrypter returned %T, want *aesCBC", keySize*8, enc)
+		}
+		if _, ok := dec.(*aesCBC); !ok {
+			t.Fatalf("AES-%d: NewCBCDecrypter returned %T, want *aesCBC", keySize*8, dec)
+		}
+		got, want := make([]byte, len(src)), make([]byte, len(src))
+		enc.CryptBlocks(got, src)
+		cipher.NewCBCEncrypter(generic, iv).CryptBlocks(want, src)
+		if !bytes.Equal(got, want) {
+			t.Errorf("AES-%d: CBC encryption = %x, want %x", keySize*8, got, want)
+		}
+		dec.CryptBlocks(got, got)
+		if !bytes.Equal(got, src) {
+			t.Errorf("AES-%d: CBC decryption = %x, want %x", keySize*8, got, src)
+		}
+
+		ctr := cipher.NewCTR(c, iv)
+		if _, ok := ctr.(*aesCTR); !ok {
+			t.Fatalf("AES-%d: NewCTR returned %T, want *aesCTR", keySize*8, ctr)
+		}
+		// Split the input at an odd offset to exercise the partial blocks.
+		ctr.XORKeyStream(got[:7], src[:7])
+		ctr.XORKeyStream(got[7:], src[7:])
+		cipher.NewCTR(generic, iv).XORKeyStream(want, src)
+		if !bytes.Equal(got, want) {
+			t.Errorf("AES-%d: CTR = %x, want %x", keySize*8, got, want)
+		}
+	}
+}
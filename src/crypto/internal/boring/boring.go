@@ -27,30 +27,26 @@ func init() {
 	if C._goboringcrypto_FIPS_mode() != 1 {
 		panic("boringcrypto: not in FIPS mode")
 	}
+	initSHA()
+	runCASTs()
 	sig.BoringCrypto()
 }
 
-// Unreachable marks code that should be unreachable
-// when BoringCrypto is in use. It panics.
-func Unreachable() {
-	panic("boringcrypto: invalid code execution")
+// FIPSMode reports whether the module is operating in FIPS mode.
+func FIPSMode() bool {
+	return C._goboringcrypto_FIPS_mode() == 1
 }
 
-// provided by runtime to avoid os import.
-func runtime_arg0() string
+// SupportsHash reports whether the module implements the hash alg, an
+// internal/cryptometrics algorithm. BoringCrypto implements all the SHA-1
+// and SHA-2 hashes.
+func SupportsHash(alg int) bool { return true }
 
-func hasSuffix(s, t string) bool {
-	return len(s) > len(t) && s[len(s)-len(t):] == t
-}
-
-// UnreachableExceptTests marks code that should be unreachable
-// when BoringCrypto is in use. It panics.
-func UnreachableExceptTests() {
-	name := runtime_arg0()
-	// If BoringCrypto ran on Windows we'd need to allow _test.exe and .test.exe as well.
-	if !hasSuffix(name, "_test") && !hasSuffix(name, ".test") {
-		println("boringcrypto: unexpected code execution in", name)
-		panic("boringcrypto: invalid code execution")
+// checkOpen panics if a private key has been closed, since its C object
+// has been freed.
+func checkOpen(closed bool) {
+	if closed {
+		panic("boringcrypto: use of closed private key")
 	}
 }
 
@@ -58,6 +54,8 @@ type fail string
 
 func (e fail) Error() string { return "boringcrypto: " + string(e) + " failed" }
 
+func (e fail) Unwrap() error { return ErrBackendFailure }
+
 func wbase(b BigInt) *C.uint8_t {
 	if len(b) == 0 {
 		return nil
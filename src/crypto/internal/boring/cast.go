@@ -0,0 +1,275 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan) || (goexperiment.opensslcrypto && !boringcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan) || (goexperiment.cngcrypto && windows && !boringcrypto && !goexperiment.opensslcrypto && !cmd_go_bootstrap) || (goexperiment.darwincrypto && darwin && !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !cmd_go_bootstrap)
+
+package boring
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"errors"
+	"hash"
+	"internal/godebug"
+)
+
+// boringcast=panic makes a failed conditional algorithm self-test (CAST)
+// panic during initialization, rather than only being reported by
+// CASTResults, so that the program never uses a module in an error state.
+var boringcast = godebug.New("boringcast")
+
+// errCASTMismatch is reported by CASTs that produced an unexpected output.
+var errCASTMismatch = errors.New("boringcrypto: self-test output mismatch")
+
+type cast struct {
+	alg, vector string
+	run         func() error
+}
+
+// casts are the CASTs run by runCASTs. They complement the module's
+// power-on self-test by exercising the same entry points that the crypto
+// packages use. The RSA and ECDSA vectors were produced with the pure Go
+// implementations.
+var casts = []cast{
+	{"SHA-1", "FIPS 180-4 example \"abc\"", castDigest(NewSHA1,
+		"a9993e364706816aba3e25717850c26c9cd0d89d")},
+	{"SHA-256", "FIPS 180-4 example \"abc\"", castDigest(NewSHA256,
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")},
+	{"SHA-512", "FIPS 180-4 example \"abc\"", castDigest(NewSHA512,
+		"ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f")},
+	{"HMAC", "RFC 4231 test case 1, SHA-256", castHMAC},
+	{"AES-GCM", "GCM specification test case 2", castGCM},
+	{"RSA", "PKCS #1 v1.5 SHA-256 signature, 2048-bit key", castRSA},
+	{"ECDSA", "P-256 SHA-256 signature verification and pairwise consistency", castECDSA},
+	{"Random", "continuous output test", castRandom},
+}
+
+// runCASTs runs the CASTs and records their outcome in CASTResults.
+// It is called during initialization, after the power-on self-test.
+func runCASTs() {
+	CASTResults = make([]CASTResult, 0, len(casts))
+	for _, c := range casts {
+		r := CASTResult{Algorithm: c.alg, Vector: c.vector, Err: c.run()}
+		if r.Err != nil && boringcast.Value() == "panic" {
+			panic("boringcrypto: self-test failed: " + c.alg + ", " + c.vector + ": " + r.Err.Error())
+		}
+		CASTResults = append(CASTResults, r)
+	}
+
+	// The self-tests are not operations of the program.
+	for alg := range counters {
+		for counter := range counters[alg] {
+			counters[alg][counter].Store(0)
+		}
+	}
+	ResetServiceIndicator()
+}
+
+const castMessage = "abc"
+
+func castDigest(h func() hash.Hash, want string) func() error {
+	return func() error {
+		d := h()
+		d.Write([]byte(castMessage))
+		return castCheck(d.Sum(nil), want)
+	}
+}
+
+func castHMAC() error {
+	m := NewHMAC(NewSHA256, bytes.Repeat([]byte{0x0b}, 20))
+	if m == nil {
+		return fail("HMAC_Init_ex")
+	}
+	m.Write([]byte("Hi There"))
+	return castCheck(m.Sum(nil),
+		"b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7")
+}
+
+func castGCM() error {
+	b, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		return err
+	}
+	g, err := b.(interface {
+		NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+	}).NewGCM(gcmStandardNonceSize, gcmTagSize)
+	if err != nil {
+		return err
+	}
+	out := g.Seal(nil, make([]byte, g.NonceSize()), make([]byte, 16), nil)
+	if err := castCheck(out,
+		"0388dace60b6a392f328c2b971b2fe78ab6e47d42cec13bdf53a67b21257bddf"); err != nil {
+		return err
+	}
+	_, err = g.Open(nil, make([]byte, g.NonceSize()), out, nil)
+	return err
+}
+
+// castRSAKey is a 2048-bit RSA key, with the public exponent 65537.
+var castRSAKey = struct{ N, D, P, Q, Dp, Dq, Qinv string }{
+	N: "bf145b5f55de6b78fdf76bbc439cc4469074a6b16688100c7a5986ad5a10b73c" +
+		"44c0758366ceeb634d2e03592d43b0aa467d264f2da6feb4e74bba728d0de3b5" +
+		"3c95699d0426987ddb262c9b313b4c720e7675303377a2b313e02fed95885fe7" +
+		"8b4fb838d0bf28c8b18cbac2455ebf6f1456c2b382b9a533137da8b939a4f9c9" +
+		"27f0a3cd8904367c462e578e7fa78d5b8b08e5bfc5c1a637d877f02899c90f80" +
+		"2286dfc35e94b21d1781222c58847bf7a894780d9a81258663012f6b99d15b1e" +
+		"2263c1fc23133d3094ef994619da3330e9876a615f77a72a8fdf9116a7c421ab" +
+		"060fac70ecdfafeb7fbd5765031238973e759c39369bcc0848ae1ce82cf48781",
+	D: "a75bb4200f3cad531ed5eb85cc8e6d17050536cf2067c1eebbe6d7dfd387da85" +
+		"ae0e079f1c97d5717732faf9cb7c832ad06c62f66c9cdc7024399ec720266f2f" +
+		"d92d608087172aeee8d849af6ff2692568e77602cec1553e960500d58de8115f" +
+		"e313e2ddd86fe5cfca3ae184b06fa92a285d4951230968d910943a0869a5c6a4" +
+		"541de402c899fe9279218b6579120a4b0560fd76b5f467693e73d6e4a2cc6d6a" +
+		"1932af7a877e3182dd63c45f4dae214ed82e4041aa16e0e6957dee17da3d7945" +
+		"e2ae073d083eac8a2cb2ec45d947590ad84f67f74cd95dc0344c3bde83267b8d" +
+		"919002c40f8c322b82cdd289df744a063558078299ccfd8ba1e2e7e8d9d2e625",
+	P: "c53bb9a4881b8321ab9df8a831220a21f54c9641c44befb647e5d19d086be76c" +
+		"dfb4de728e09908c976ce5bbbadbe2ba5dd85db2a8400caee98626af41c7769b" +
+		"b851f38a1a4f9c488543216760635926d08dd770838c6940060e2a274c6dccc5" +
+		"a17a2787a1e23aa792b83f725251d332a9cf36fa807e9d9de88179631f10e367",
+	Q: "f8033e1529d441802f0dfe54931256f958be1aa4f037af03df9159c63d128888" +
+		"f8bd1a6ebfd0d8f656474b25ef7592cef51c116f992cdbb7a48f7c018bc3370d" +
+		"000052fccf2f301184d9f7c0bac095c4649854835db4e1eb94ecee988df973ba" +
+		"0f7a8368736ae09a171fb67f43d87eb6b62fe0246ebb3dcc9b418f92688294d7",
+	Dp: "a090cbc4074e2685fdb9c019e4cdea63d4d801a70ed027194514f27d9dc28287" +
+		"dee2d709795a8e03f6ae9fa4222e2a39407709688d927f00d279f4fa4e59bcd4" +
+		"6eccc0b05855214ce4b84ef462c2de6c3bf28d463ac1cf5960d49d3a9afcf46c" +
+		"9bd5df628ac6aaab8b4555d992ecac3c1d83e525300063fe8eaf010c51d924d7",
+	Dq: "ebf5a9cb58d0a8c0dc03e44572e7aff7eb8672b31a18e05a3f0a7aef308bc17b" +
+		"0fac5ac9a3957fd65e9fd5cf71d3daa92c6a57ec653f588b8ff533760512e5fd" +
+		"6852e6a1c03c94ef16068ac0c2ed179092649b83a053c7ddeb02c0be43d56155" +
+		"5404aba164adfaa29ffd7c13f8e8b126048fe820c1794382b6f3d934e259da61",
+	Qinv: "7c3d1fcab846f7c9a178f97167d69dc552c0fa067e4dadab53fc7a3ff89c27c4" +
+		"b2b4fad328805908899f57bda3a2454301327159e0029f836cff84c5b6d5ec01" +
+		"ea343f6974862a06920020076fe49ab0f6ca62f709c64ff255deb77651d442ff" +
+		"8ad1a8b04fb7fce58a41f75a6c40a7ecc4a8480e540ce58bd0656da2b3651667",
+}
+
+// castRSASig is the signature of castMessage by castRSAKey.
+const castRSASig = "6db0646f77d250e4601c64538d1d03ea7ece3d080c7e15629abf1bda622790be" +
+	"999400f8d614f47064e9ad3701802fcc7523f7711c16966c0d74f2da94c8a7e4" +
+	"4eef3121ee3148f7a085af26d32dd9ea6fd089ebf3cb19c0c75aba1548e7dee3" +
+	"95e73f4367b3e1f0e02d2d24b9a4831a72bac938b34868d5531e1e00d54c28c7" +
+	"deeb590db69745054e2cfce6c8552ec3eaa064a4785e67a7850e41cefa809d3b" +
+	"02b33f0288178640d02c3e6db325661334fec40eb9b77a8dff8687cddeb9d933" +
+	"f26bece4aaf0724085cb8b1954b13d78c787ec54504083cbcead478328749fb0" +
+	"61872831dee11c31da9ca4171e3f0e31356250d582f4803b965697298a034d38"
+
+func castRSA() error {
+	k := castRSAKey
+	priv, err := NewPrivateKeyRSA(castBig(k.N), BigInt{65537}, castBig(k.D),
+		castBig(k.P), castBig(k.Q), castBig(k.Dp), castBig(k.Dq), castBig(k.Qinv))
+	if err != nil {
+		return err
+	}
+	pub, err := NewPublicKeyRSA(castBig(k.N), BigInt{65537})
+	if err != nil {
+		return err
+	}
+	hashed := SHA256([]byte(castMessage))
+	sig, err := SignRSAPKCS1v15(priv, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	if err := castCheck(sig, castRSASig); err != nil {
+		return err
+	}
+	return VerifyRSAPKCS1v15(pub, crypto.SHA256, hashed[:], sig)
+}
+
+// castECDSAKey is a P-256 key, and castECDSASig a signature of
+// castMessage by it, in ASN.1 form.
+var castECDSAKey = struct{ X, Y, D string }{
+	X: "64599e87aed70637b4e047d77b90a5bef058e422960dbda41d18b9711c76649a",
+	Y: "acfacd1cfa489232979095075ece89317f9d202fd4cdf9523444ef445a18cb85",
+	D: "c1b8e1d2db8f18aa3cfe53c27c9a23110ee98b5f3132e91534f6461b76fe247e",
+}
+
+const castECDSASig = "304502210081eb3673fdae7ad4f321f53eaa0eece7447518f44ec3056b731e84" +
+	"7fb88c4de9022036b0ea67211c16d188569457b4a33c946ba936e88cbf8f0d1b" +
+	"cccdaebf5b3b63"
+
+func castECDSA() error {
+	k := castECDSAKey
+	x, y, d := castBig(k.X), castBig(k.Y), castBig(k.D)
+	pub, err := NewPublicKeyECDSA("P-256", x, y)
+	if err != nil {
+		return err
+	}
+	hashed := SHA256([]byte(castMessage))
+	if !VerifyECDSA(pub, hashed[:], castHex(castECDSASig)) {
+		return errCASTMismatch
+	}
+
+	// ECDSA signatures are randomized, so signing is tested by verifying
+	// a fresh signature.
+	priv, err := NewPrivateKeyECDSA("P-256", x, y, d)
+	if err != nil {
+		return err
+	}
+	sig, err := SignMarshalECDSA(priv, hashed[:])
+	if err != nil {
+		return err
+	}
+	if !VerifyECDSA(pub, hashed[:], sig) {
+		return errCASTMismatch
+	}
+	return nil
+}
+
+// castRandom checks that the DRBG returns distinct, non-zero blocks. The
+// DRBG can't be seeded from Go, so its known-answer test is part of the
+// module's power-on self-test.
+func castRandom() error {
+	var a, b [32]byte
+	if _, err := RandReader.Read(a[:]); err != nil {
+		return err
+	}
+	if _, err := RandReader.Read(b[:]); err != nil {
+		return err
+	}
+	if a == b || a == [32]byte{} {
+		return errCASTMismatch
+	}
+	return nil
+}
+
+func castCheck(got []byte, want string) error {
+	if !bytes.Equal(got, castHex(want)) {
+		return errCASTMismatch
+	}
+	return nil
+}
+
+// castHex decodes a well-formed hex string from the test vectors.
+func castHex(s string) []byte {
+	b := make([]byte, len(s)/2)
+	for i := range b {
+		b[i] = castHexDigit(s[2*i])<<4 | castHexDigit(s[2*i+1])
+	}
+	return b
+}
+
+func castHexDigit(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	panic("boringcrypto: invalid test vector")
+}
+
+// castBig decodes a big-endian hex string from the test vectors into
+// the little-endian words of a BigInt.
+func castBig(s string) BigInt {
+	b := castHex(s)
+	x := make(BigInt, (len(b)+wordBytes-1)/wordBytes)
+	for i, c := range b {
+		j := len(b) - 1 - i // byte index, least significant first
+		x[j/wordBytes] |= uint(c) << (8 * (j % wordBytes))
+	}
+	return x
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo
+
+package boring
+
+import (
+	"crypto"
+	"hash"
+	"strings"
+	"testing"
+)
+
+// mustPanicClosed checks that f panics because it uses a closed object.
+func mustPanicClosed(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if e, ok := recover().(string); !ok || !strings.Contains(e, "closed") {
+			t.Errorf("%s after Close: panic %v, want a use of closed object panic", name, e)
+		}
+	}()
+	f()
+}
+
+func TestHMACClose(t *testing.T) {
+	h := NewHMAC(NewSHA256, []byte("key"))
+	c, err := h.(interface{ Clone() (hash.Hash, error) }).Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := h.(*boringHMAC).key
+	if err := h.(interface{ Close() error }).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "\x00\x00\x00" {
+		t.Errorf("key after Close = %q", key)
+	}
+	h.(interface{ Close() error }).Close() // no-op
+	mustPanicClosed(t, "Write", func() { h.Write(nil) })
+	mustPanicClosed(t, "Sum", func() { h.Sum(nil) })
+	mustPanicClosed(t, "Reset", h.Reset)
+
+	// The clone has its own copy of the key.
+	want := NewHMAC(NewSHA256, []byte("key"))
+	c.Reset()
+	if string(c.Sum(nil)) != string(want.Sum(nil)) {
+		t.Error("clone was changed by Close")
+	}
+}
+
+func TestAESClose(t *testing.T) {
+	b, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := b.(*aesCipher).NewGCM(gcmStandardNonceSize, gcmTagSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := b.(*aesCipher)
+	c.key[0] = 1
+	key := c.key
+	c.Close()
+	if key[0] != 0 || c.enc != (aesCipher{}).enc || c.dec != (aesCipher{}).dec {
+		t.Error("key not zeroed by Close")
+	}
+	buf := make([]byte, 16)
+	mustPanicClosed(t, "Encrypt", func() { b.Encrypt(buf, buf) })
+	mustPanicClosed(t, "Decrypt", func() { b.Decrypt(buf, buf) })
+
+	// The AEAD has its own copy of the key.
+	nonce := make([]byte, gcmStandardNonceSize)
+	sealed := g.Seal(nil, nonce, nil, nil)
+	g.(*aesGCM).Close()
+	g.(*aesGCM).Close() // no-op
+	mustPanicClosed(t, "Seal", func() { g.Seal(nil, nonce, nil, nil) })
+	mustPanicClosed(t, "Open", func() { g.Open(nil, nonce, sealed, nil) })
+}
+
+func TestPrivateKeyClose(t *testing.T) {
+	hashed := SHA256([]byte("message"))
+
+	N, E, D, P, Q, Dp, Dq, Qinv, err := GenerateKeyRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey.Close()
+	rsaKey.Close() // no-op
+	mustPanicClosed(t, "SignRSAPKCS1v15", func() { SignRSAPKCS1v15(rsaKey, crypto.SHA256, hashed[:]) })
+
+	X, Y, D, err := GenerateKeyECDSA("P-256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := NewPrivateKeyECDSA("P-256", X, Y, D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey.Close()
+	mustPanicClosed(t, "SignMarshalECDSA", func() { SignMarshalECDSA(ecdsaKey, hashed[:]) })
+
+	ecdhKey, _, err := GenerateKeyECDH("P-256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := ecdhKey.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhKey.Close()
+	mustPanicClosed(t, "PublicKey", func() { ecdhKey.PublicKey() })
+	mustPanicClosed(t, "ECDH", func() { ECDH(ecdhKey, peer) })
+}
@@ -0,0 +1,309 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.cngcrypto && windows && !boringcrypto && !goexperiment.opensslcrypto && !cmd_go_bootstrap
+
+package boring
+
+// With GOEXPERIMENT=cngcrypto, this package is a thin layer over
+// crypto/internal/backend/cng, which implements the same operations with
+// Windows CNG, so that the crypto packages don't need to know which
+// backend is in use. CNG lacks SHA-224, SHA-512/224, SHA-512/256 and
+// P-224: SupportsHash reports the hashes, which the crypto packages then
+// compute with Go, and the functions of this package return errors
+// wrapping ErrUnsupportedParameter for P-224 keys.
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/internal/backend/cng"
+	"crypto/internal/boring/sig"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"math/bits"
+)
+
+const available = true
+
+func init() {
+	cng.SetMetricsHooks(countOp, countBytes, countFailure, RecordFallback)
+	if err := cng.Init(); err != nil {
+		panic("cngcrypto: " + err.Error())
+	}
+	ModuleName = "CNG"
+	ModuleVersion = cng.Version()
+	for i, r := range Routes {
+		if c, ok := cngRoutes[r.Name]; ok {
+			Routes[i] = c
+		}
+	}
+	sig.BoringCrypto()
+	runCASTs()
+}
+
+// cngRoutes are the routes that differ from those of BoringCrypto.
+var cngRoutes = map[string]Route{
+	"AES-CTR":     {"AES-CTR", RouteGo, ""},
+	"AES-KW":      {"AES-KW", RouteGo, ""},
+	"ECDSA":       {"ECDSA", RoutePartial, "P-256, P-384 and P-521, with key generation and signing only from crypto/rand.Reader; P-224 keys are rejected"},
+	"HMAC":        {"HMAC", RoutePartial, "SHA-1, SHA-256, SHA-384 and SHA-512"},
+	"RSA":         {"RSA", RoutePartial, "private keys only with two primes and their CRT values; key generation only for 2048- and 3072-bit keys from crypto/rand.Reader; encryption and PSS signing only with crypto/rand.Reader; OAEP only with the same hash for MGF1"},
+	"SHA-224":     {"SHA-224", RouteGo, ""},
+	"SHA-512/224": {"SHA-512/224", RouteGo, ""},
+	"SHA-512/256": {"SHA-512/256", RouteGo, ""},
+}
+
+// FIPSMode reports whether the system FIPS policy is enabled.
+func FIPSMode() bool {
+	return cng.FIPS()
+}
+
+// SupportsHash reports whether the module implements the hash alg, an
+// internal/cryptometrics algorithm.
+func SupportsHash(alg int) bool {
+	switch alg {
+	case cryptometrics.SHA224, cryptometrics.SHA512_224, cryptometrics.SHA512_256:
+		return false
+	}
+	return true
+}
+
+// backendError is an error from the CNG backend, which also matches
+// the corresponding failure class of this package.
+type backendError struct {
+	err   error
+	class error
+}
+
+func (e *backendError) Error() string   { return e.err.Error() }
+func (e *backendError) Unwrap() []error { return []error{e.err, e.class} }
+
+// convertError returns err, classified as the errors of this package.
+func convertError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, cng.ErrUnsupportedParameter):
+		return &backendError{err, ErrUnsupportedParameter}
+	case errors.Is(err, cng.ErrBackendFailure):
+		return &backendError{err, ErrBackendFailure}
+	}
+	return err
+}
+
+type fail string
+
+func (e fail) Error() string { return "cngcrypto: " + string(e) + " failed" }
+
+func (e fail) Unwrap() error { return ErrBackendFailure }
+
+const wordBytes = bits.UintSize / 8
+
+const (
+	gcmTagSize           = 16
+	gcmStandardNonceSize = 12
+)
+
+const RandReader = cng.RandReader
+
+func NewSHA1() hash.Hash   { return cng.NewSHA1() }
+func NewSHA256() hash.Hash { return cng.NewSHA256() }
+func NewSHA384() hash.Hash { return cng.NewSHA384() }
+func NewSHA512() hash.Hash { return cng.NewSHA512() }
+
+func SHA1(p []byte) [20]byte   { return cng.SHA1(p) }
+func SHA256(p []byte) [32]byte { return cng.SHA256(p) }
+func SHA384(p []byte) [48]byte { return cng.SHA384(p) }
+func SHA512(p []byte) [64]byte { return cng.SHA512(p) }
+
+func SHA1Into(dst, p []byte)   { cng.SHA1Into(dst, p) }
+func SHA256Into(dst, p []byte) { cng.SHA256Into(dst, p) }
+func SHA384Into(dst, p []byte) { cng.SHA384Into(dst, p) }
+func SHA512Into(dst, p []byte) { cng.SHA512Into(dst, p) }
+
+func SHA256Batch(msgs [][]byte) [][32]byte { return cng.SHA256Batch(msgs) }
+
+// The crypto packages check SupportsHash before calling these.
+
+func NewSHA224() hash.Hash     { panic("cngcrypto: SHA-224 not available") }
+func NewSHA512_224() hash.Hash { panic("cngcrypto: SHA-512/224 not available") }
+func NewSHA512_256() hash.Hash { panic("cngcrypto: SHA-512/256 not available") }
+
+func SHA224([]byte) [28]byte     { panic("cngcrypto: SHA-224 not available") }
+func SHA512_224([]byte) [28]byte { panic("cngcrypto: SHA-512/224 not available") }
+func SHA512_256([]byte) [32]byte { panic("cngcrypto: SHA-512/256 not available") }
+
+func SHA224Into(dst, p []byte) { panic("cngcrypto: SHA-224 not available") }
+
+func NewHMAC(h func() hash.Hash, key []byte) hash.Hash { return cng.NewHMAC(h, key) }
+
+func NewAESCipher(key []byte) (cipher.Block, error) {
+	c, err := cng.NewAESCipher(key)
+	return c, convertError(err)
+}
+
+func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
+	g, err := cng.NewGCMTLS(c)
+	return g, convertError(err)
+}
+
+// SupportsAESKeyWrap reports whether the module implements the KW and KWP
+// modes of SP 800-38F. CNG only wraps keys as key blobs of
+// BCryptExportKey.
+func SupportsAESKeyWrap() bool { return false }
+
+func WrapKeyAES(kek, key []byte, pad bool) ([]byte, error) {
+	return nil, unsupported("cngcrypto: AES key wrap not supported")
+}
+
+func UnwrapKeyAES(kek, wrapped []byte, pad bool) ([]byte, error) {
+	return nil, unsupported("cngcrypto: AES key wrap not supported")
+}
+
+// SupportsCMAC reports whether the module implements AES-CMAC. CNG has an
+// AES-CMAC provider, which the cng backend doesn't use yet.
+func SupportsCMAC() bool { return false }
+
+func NewCMAC(key []byte) (hash.Hash, error) {
+	return nil, unsupported("cngcrypto: AES-CMAC not supported")
+}
+
+type PublicKeyECDSA = cng.PublicKeyECDSA
+type PrivateKeyECDSA = cng.PrivateKeyECDSA
+
+func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	x, y, d, err := cng.GenerateKeyECDSA(curve)
+	return BigInt(x), BigInt(y), BigInt(d), convertError(err)
+}
+
+func NewPrivateKeyECDSA(curve string, X, Y, D BigInt) (*PrivateKeyECDSA, error) {
+	k, err := cng.NewPrivateKeyECDSA(curve, cng.BigInt(X), cng.BigInt(Y), cng.BigInt(D))
+	return k, convertError(err)
+}
+
+func NewPublicKeyECDSA(curve string, X, Y BigInt) (*PublicKeyECDSA, error) {
+	k, err := cng.NewPublicKeyECDSA(curve, cng.BigInt(X), cng.BigInt(Y))
+	return k, convertError(err)
+}
+
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) ([]byte, error) {
+	sig, err := cng.SignMarshalECDSA(priv, hash)
+	return sig, convertError(err)
+}
+
+// SupportsDeterministicECDSA reports whether SignMarshalECDSADeterministic
+// is available. CNG has no API for RFC 6979 nonces.
+func SupportsDeterministicECDSA() bool { return false }
+
+func SignMarshalECDSADeterministic(priv *PrivateKeyECDSA, h crypto.Hash, hash []byte) ([]byte, error) {
+	return nil, unsupported("cngcrypto: deterministic ECDSA not supported")
+}
+
+func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	return cng.VerifyECDSA(pub, hash, sig)
+}
+
+type PublicKeyRSA = cng.PublicKeyRSA
+type PrivateKeyRSA = cng.PrivateKeyRSA
+
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP
+// support h, mgfHash and label. CNG uses the same hash for OAEP and MGF1.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool {
+	return cng.SupportsRSAOAEP(h, mgfHash, label)
+}
+
+func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) ([]byte, error) {
+	out, err := cng.DecryptRSAOAEP(h, mgfHash, priv, ciphertext, label)
+	return out, convertError(err)
+}
+
+func DecryptRSAPKCS1(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	out, err := cng.DecryptRSAPKCS1(priv, ciphertext)
+	return out, convertError(err)
+}
+
+func DecryptRSANoPadding(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	out, err := cng.DecryptRSANoPadding(priv, ciphertext)
+	return out, convertError(err)
+}
+
+func EncryptRSAOAEP(h, mgfHash hash.Hash, pub *PublicKeyRSA, msg, label []byte) ([]byte, error) {
+	out, err := cng.EncryptRSAOAEP(h, mgfHash, pub, msg, label)
+	return out, convertError(err)
+}
+
+func EncryptRSAPKCS1(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	out, err := cng.EncryptRSAPKCS1(pub, msg)
+	return out, convertError(err)
+}
+
+func EncryptRSANoPadding(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	out, err := cng.EncryptRSANoPadding(pub, msg)
+	return out, convertError(err)
+}
+
+func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	n, e, d, p, q, dp, dq, qinv, err := cng.GenerateKeyRSA(bits)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, nil, convertError(err)
+	}
+	return BigInt(n), BigInt(e), BigInt(d), BigInt(p), BigInt(q), BigInt(dp), BigInt(dq), BigInt(qinv), nil
+}
+
+func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
+	k, err := cng.NewPrivateKeyRSA(cng.BigInt(N), cng.BigInt(E), cng.BigInt(D),
+		cng.BigInt(P), cng.BigInt(Q), cng.BigInt(Dp), cng.BigInt(Dq), cng.BigInt(Qinv))
+	return k, convertError(err)
+}
+
+func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
+	k, err := cng.NewPublicKeyRSA(cng.BigInt(N), cng.BigInt(E))
+	return k, convertError(err)
+}
+
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) ([]byte, error) {
+	sig, err := cng.SignRSAPKCS1v15(priv, h, hashed)
+	return sig, convertError(err)
+}
+
+// SupportsRSAPSSSaltLength reports whether SignRSAPSS supports the
+// crypto/rsa salt length saltLen with h. CNG supports all of them.
+func SupportsRSAPSSSaltLength(h crypto.Hash, saltLen int) bool { return true }
+
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) ([]byte, error) {
+	sig, err := cng.SignRSAPSS(priv, h, hashed, saltLen)
+	return sig, convertError(err)
+}
+
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) error {
+	return convertError(cng.VerifyRSAPKCS1v15(pub, h, hashed, sig))
+}
+
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) error {
+	return convertError(cng.VerifyRSAPSS(pub, h, hashed, sig, saltLen))
+}
+
+type PublicKeyECDH = cng.PublicKeyECDH
+type PrivateKeyECDH = cng.PrivateKeyECDH
+
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) ([]byte, error) {
+	out, err := cng.ECDH(priv, pub)
+	return out, convertError(err)
+}
+
+func GenerateKeyECDH(curve string) (*PrivateKeyECDH, []byte, error) {
+	k, bytes, err := cng.GenerateKeyECDH(curve)
+	return k, bytes, convertError(err)
+}
+
+func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
+	k, err := cng.NewPrivateKeyECDH(curve, bytes)
+	return k, convertError(err)
+}
+
+func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
+	k, err := cng.NewPublicKeyECDH(curve, bytes)
+	return k, convertError(err)
+}
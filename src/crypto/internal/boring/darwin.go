@@ -0,0 +1,312 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !cmd_go_bootstrap
+
+package boring
+
+// With GOEXPERIMENT=darwincrypto, this package is a thin layer over
+// crypto/internal/backend/darwin, which implements the same operations
+// with CommonCrypto and Security.framework, so that the crypto packages
+// don't need to know which backend is in use. macOS lacks SHA-512/224,
+// SHA-512/256, P-224 and AES-GCM: SupportsHash reports the hashes, which
+// the crypto packages then compute with Go, the functions of this package
+// return errors wrapping ErrUnsupportedParameter for P-224 keys, and
+// NewGCMTLS records a fallback and returns the Go GCM over the AES block
+// of the backend.
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/internal/backend/darwin"
+	"crypto/internal/boring/sig"
+	"errors"
+	"hash"
+	"internal/cryptometrics"
+	"math/bits"
+)
+
+const available = true
+
+func init() {
+	darwin.SetMetricsHooks(countOp, countBytes, countFailure, RecordFallback)
+	if err := darwin.Init(); err != nil {
+		panic("darwincrypto: " + err.Error())
+	}
+	ModuleName = "CommonCrypto"
+	ModuleVersion = darwin.Version()
+	for i, r := range Routes {
+		if c, ok := darwinRoutes[r.Name]; ok {
+			Routes[i] = c
+		}
+	}
+	sig.BoringCrypto()
+	runCASTs()
+}
+
+// darwinRoutes are the routes that differ from those of BoringCrypto.
+var darwinRoutes = map[string]Route{
+	"AES-CTR":     {"AES-CTR", RouteGo, ""},
+	"AES-GCM":     {"AES-GCM", RouteGo, ""},
+	"AES-KW":      {"AES-KW", RouteGo, ""},
+	"ECDSA":       {"ECDSA", RoutePartial, "P-256, P-384 and P-521, with key generation and signing only from crypto/rand.Reader; P-224 keys are rejected"},
+	"RSA":         {"RSA", RoutePartial, "private keys only with two primes and their CRT values; key generation and encryption only from crypto/rand.Reader; PSS signing only with salts as long as the hash; OAEP only with the same hash for MGF1 and no label"},
+	"SHA-512/224": {"SHA-512/224", RouteGo, ""},
+	"SHA-512/256": {"SHA-512/256", RouteGo, ""},
+}
+
+// FIPSMode reports whether the system FIPS policy is enabled.
+func FIPSMode() bool {
+	return darwin.FIPS()
+}
+
+// SupportsHash reports whether the module implements the hash alg, an
+// internal/cryptometrics algorithm.
+func SupportsHash(alg int) bool {
+	switch alg {
+	case cryptometrics.SHA512_224, cryptometrics.SHA512_256:
+		return false
+	}
+	return true
+}
+
+// backendError is an error from the darwin backend, which also matches
+// the corresponding failure class of this package.
+type backendError struct {
+	err   error
+	class error
+}
+
+func (e *backendError) Error() string   { return e.err.Error() }
+func (e *backendError) Unwrap() []error { return []error{e.err, e.class} }
+
+// convertError returns err, classified as the errors of this package.
+func convertError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, darwin.ErrUnsupportedParameter):
+		return &backendError{err, ErrUnsupportedParameter}
+	case errors.Is(err, darwin.ErrBackendFailure):
+		return &backendError{err, ErrBackendFailure}
+	}
+	return err
+}
+
+type fail string
+
+func (e fail) Error() string { return "darwincrypto: " + string(e) + " failed" }
+
+func (e fail) Unwrap() error { return ErrBackendFailure }
+
+const wordBytes = bits.UintSize / 8
+
+const (
+	gcmTagSize           = 16
+	gcmStandardNonceSize = 12
+)
+
+const RandReader = darwin.RandReader
+
+func NewSHA1() hash.Hash   { return darwin.NewSHA1() }
+func NewSHA224() hash.Hash { return darwin.NewSHA224() }
+func NewSHA256() hash.Hash { return darwin.NewSHA256() }
+func NewSHA384() hash.Hash { return darwin.NewSHA384() }
+func NewSHA512() hash.Hash { return darwin.NewSHA512() }
+
+func SHA1(p []byte) [20]byte   { return darwin.SHA1(p) }
+func SHA224(p []byte) [28]byte { return darwin.SHA224(p) }
+func SHA256(p []byte) [32]byte { return darwin.SHA256(p) }
+func SHA384(p []byte) [48]byte { return darwin.SHA384(p) }
+func SHA512(p []byte) [64]byte { return darwin.SHA512(p) }
+
+func SHA1Into(dst, p []byte)   { darwin.SHA1Into(dst, p) }
+func SHA224Into(dst, p []byte) { darwin.SHA224Into(dst, p) }
+func SHA256Into(dst, p []byte) { darwin.SHA256Into(dst, p) }
+func SHA384Into(dst, p []byte) { darwin.SHA384Into(dst, p) }
+func SHA512Into(dst, p []byte) { darwin.SHA512Into(dst, p) }
+
+func SHA256Batch(msgs [][]byte) [][32]byte { return darwin.SHA256Batch(msgs) }
+
+// The crypto packages check SupportsHash before calling these.
+
+func NewSHA512_224() hash.Hash { panic("darwincrypto: SHA-512/224 not available") }
+func NewSHA512_256() hash.Hash { panic("darwincrypto: SHA-512/256 not available") }
+
+func SHA512_224([]byte) [28]byte { panic("darwincrypto: SHA-512/224 not available") }
+func SHA512_256([]byte) [32]byte { panic("darwincrypto: SHA-512/256 not available") }
+
+func NewHMAC(h func() hash.Hash, key []byte) hash.Hash { return darwin.NewHMAC(h, key) }
+
+func NewAESCipher(key []byte) (cipher.Block, error) {
+	c, err := darwin.NewAESCipher(key)
+	return c, convertError(err)
+}
+
+func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
+	g, err := darwin.NewGCMTLS(c)
+	return g, convertError(err)
+}
+
+// SupportsAESKeyWrap reports whether the module implements the KW and KWP
+// modes of SP 800-38F. CommonCrypto implements KW, but not KWP.
+func SupportsAESKeyWrap() bool { return false }
+
+func WrapKeyAES(kek, key []byte, pad bool) ([]byte, error) {
+	return nil, unsupported("darwincrypto: AES key wrap not supported")
+}
+
+func UnwrapKeyAES(kek, wrapped []byte, pad bool) ([]byte, error) {
+	return nil, unsupported("darwincrypto: AES key wrap not supported")
+}
+
+// SupportsCMAC reports whether the module implements AES-CMAC, which the
+// public CommonCrypto API doesn't.
+func SupportsCMAC() bool { return false }
+
+func NewCMAC(key []byte) (hash.Hash, error) {
+	return nil, unsupported("darwincrypto: AES-CMAC not supported")
+}
+
+type PublicKeyECDSA = darwin.PublicKeyECDSA
+type PrivateKeyECDSA = darwin.PrivateKeyECDSA
+
+func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	x, y, d, err := darwin.GenerateKeyECDSA(curve)
+	return BigInt(x), BigInt(y), BigInt(d), convertError(err)
+}
+
+func NewPrivateKeyECDSA(curve string, X, Y, D BigInt) (*PrivateKeyECDSA, error) {
+	k, err := darwin.NewPrivateKeyECDSA(curve, darwin.BigInt(X), darwin.BigInt(Y), darwin.BigInt(D))
+	return k, convertError(err)
+}
+
+func NewPublicKeyECDSA(curve string, X, Y BigInt) (*PublicKeyECDSA, error) {
+	k, err := darwin.NewPublicKeyECDSA(curve, darwin.BigInt(X), darwin.BigInt(Y))
+	return k, convertError(err)
+}
+
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) ([]byte, error) {
+	sig, err := darwin.SignMarshalECDSA(priv, hash)
+	return sig, convertError(err)
+}
+
+// SupportsDeterministicECDSA reports whether SignMarshalECDSADeterministic
+// is available. Security.framework has no API for RFC 6979 nonces.
+func SupportsDeterministicECDSA() bool { return false }
+
+func SignMarshalECDSADeterministic(priv *PrivateKeyECDSA, h crypto.Hash, hash []byte) ([]byte, error) {
+	return nil, unsupported("darwincrypto: deterministic ECDSA not supported")
+}
+
+func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	return darwin.VerifyECDSA(pub, hash, sig)
+}
+
+type PublicKeyRSA = darwin.PublicKeyRSA
+type PrivateKeyRSA = darwin.PrivateKeyRSA
+
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP
+// support h, mgfHash and label. Security.framework uses the same hash for
+// OAEP and MGF1, and no label.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool {
+	return darwin.SupportsRSAOAEP(h, mgfHash, label)
+}
+
+func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) ([]byte, error) {
+	out, err := darwin.DecryptRSAOAEP(h, mgfHash, priv, ciphertext, label)
+	return out, convertError(err)
+}
+
+func DecryptRSAPKCS1(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	out, err := darwin.DecryptRSAPKCS1(priv, ciphertext)
+	return out, convertError(err)
+}
+
+func DecryptRSANoPadding(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	out, err := darwin.DecryptRSANoPadding(priv, ciphertext)
+	return out, convertError(err)
+}
+
+func EncryptRSAOAEP(h, mgfHash hash.Hash, pub *PublicKeyRSA, msg, label []byte) ([]byte, error) {
+	out, err := darwin.EncryptRSAOAEP(h, mgfHash, pub, msg, label)
+	return out, convertError(err)
+}
+
+func EncryptRSAPKCS1(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	out, err := darwin.EncryptRSAPKCS1(pub, msg)
+	return out, convertError(err)
+}
+
+func EncryptRSANoPadding(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	out, err := darwin.EncryptRSANoPadding(pub, msg)
+	return out, convertError(err)
+}
+
+func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	n, e, d, p, q, dp, dq, qinv, err := darwin.GenerateKeyRSA(bits)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, nil, convertError(err)
+	}
+	return BigInt(n), BigInt(e), BigInt(d), BigInt(p), BigInt(q), BigInt(dp), BigInt(dq), BigInt(qinv), nil
+}
+
+func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
+	k, err := darwin.NewPrivateKeyRSA(darwin.BigInt(N), darwin.BigInt(E), darwin.BigInt(D),
+		darwin.BigInt(P), darwin.BigInt(Q), darwin.BigInt(Dp), darwin.BigInt(Dq), darwin.BigInt(Qinv))
+	return k, convertError(err)
+}
+
+func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
+	k, err := darwin.NewPublicKeyRSA(darwin.BigInt(N), darwin.BigInt(E))
+	return k, convertError(err)
+}
+
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) ([]byte, error) {
+	sig, err := darwin.SignRSAPKCS1v15(priv, h, hashed)
+	return sig, convertError(err)
+}
+
+// SupportsRSAPSSSaltLength reports whether SignRSAPSS supports the
+// crypto/rsa salt length saltLen with h. Security.framework only signs with
+// salts as long as the hash.
+func SupportsRSAPSSSaltLength(h crypto.Hash, saltLen int) bool {
+	return saltLen == -1 || saltLen == h.Size()
+}
+
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) ([]byte, error) {
+	sig, err := darwin.SignRSAPSS(priv, h, hashed, saltLen)
+	return sig, convertError(err)
+}
+
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) error {
+	return convertError(darwin.VerifyRSAPKCS1v15(pub, h, hashed, sig))
+}
+
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) error {
+	return convertError(darwin.VerifyRSAPSS(pub, h, hashed, sig, saltLen))
+}
+
+type PublicKeyECDH = darwin.PublicKeyECDH
+type PrivateKeyECDH = darwin.PrivateKeyECDH
+
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) ([]byte, error) {
+	out, err := darwin.ECDH(priv, pub)
+	return out, convertError(err)
+}
+
+func GenerateKeyECDH(curve string) (*PrivateKeyECDH, []byte, error) {
+	k, bytes, err := darwin.GenerateKeyECDH(curve)
+	return k, bytes, convertError(err)
+}
+
+func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
+	k, err := darwin.NewPrivateKeyECDH(curve, bytes)
+	return k, convertError(err)
+}
+
+func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
+	k, err := darwin.NewPublicKeyECDH(curve, bytes)
+	return k, convertError(err)
+}
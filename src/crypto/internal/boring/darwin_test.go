@@ -0,0 +1,50 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.darwincrypto && darwin && !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !cmd_go_bootstrap
+
+package boring
+
+import (
+	"errors"
+	"internal/cryptometrics"
+	"strings"
+	"testing"
+)
+
+func TestDarwinModule(t *testing.T) {
+	if ModuleName != "CommonCrypto" || !strings.HasPrefix(ModuleVersion, "macOS ") {
+		t.Errorf("module = %q %q, want CommonCrypto on macOS", ModuleName, ModuleVersion)
+	}
+	for _, r := range Routes {
+		if r.Name == "AES-GCM" && r.Routing != RouteGo {
+			t.Errorf("AES-GCM routing = %d, want RouteGo", r.Routing)
+		}
+	}
+}
+
+func TestDarwinErrorClasses(t *testing.T) {
+	if _, err := NewPublicKeyECDSA("P-224", BigInt{1}, BigInt{2}); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPublicKeyECDSA(P-224) = %v, want ErrUnsupportedParameter", err)
+	}
+	if _, err := NewPrivateKeyRSA(BigInt{3233}, BigInt{17}, BigInt{413}, nil, nil, nil, nil, nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPrivateKeyRSA without CRT values = %v, want ErrUnsupportedParameter", err)
+	}
+	if _, err := NewPublicKeyECDH("P-256", []byte{4, 1, 2}); err == nil {
+		t.Error("NewPublicKeyECDH with an invalid point succeeded")
+	}
+}
+
+func TestDarwinSupportsHash(t *testing.T) {
+	for alg, want := range map[int]bool{
+		cryptometrics.SHA256:     true,
+		cryptometrics.SHA512:     true,
+		cryptometrics.SHA224:     true,
+		cryptometrics.SHA512_256: false,
+	} {
+		if got := SupportsHash(alg); got != want {
+			t.Errorf("SupportsHash(%s) = %v, want %v", cryptometrics.Algorithms[alg].Display, got, want)
+		}
+	}
+}
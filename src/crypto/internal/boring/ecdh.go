@@ -9,7 +9,9 @@ package boring
 // #include "goboringcrypto.h"
 import "C"
 import (
+	"crypto/internal/keyguard"
 	"errors"
+	"internal/cryptometrics"
 	"runtime"
 	"unsafe"
 )
@@ -23,15 +25,29 @@ type PublicKeyECDH struct {
 
 func (k *PublicKeyECDH) finalize() {
 	C._goboringcrypto_EC_POINT_free(k.key)
+	k.key = (*C.GO_EC_POINT)(keyguard.Poison())
 }
 
 type PrivateKeyECDH struct {
-	curve string
-	key   *C.GO_EC_KEY
+	curve  string
+	key    *C.GO_EC_KEY
+	closed bool
 }
 
 func (k *PrivateKeyECDH) finalize() {
 	C._goboringcrypto_EC_KEY_free(k.key)
+	k.key = (*C.GO_EC_KEY)(keyguard.Poison())
+}
+
+// Close frees the key, which BoringCrypto zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyECDH) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
 }
 
 func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
@@ -88,13 +104,14 @@ func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
 		C._goboringcrypto_EC_KEY_free(key)
 		return nil, fail("EC_KEY_set_private_key")
 	}
-	k := &PrivateKeyECDH{curve, key}
+	k := &PrivateKeyECDH{curve: curve, key: key}
 	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
 	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
 	return k, nil
 }
 
 func (k *PrivateKeyECDH) PublicKey() (*PublicKeyECDH, error) {
+	checkOpen(k.closed)
 	defer runtime.KeepAlive(k)
 
 	group := C._goboringcrypto_EC_KEY_get0_group(k.key)
@@ -133,7 +150,11 @@ func pointBytesECDH(curve string, group *C.GO_EC_GROUP, pt *C.GO_EC_POINT) ([]by
 	return out, nil
 }
 
-func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) ([]byte, error) {
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) (_ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+	checkOpen(priv.closed)
+
 	group := C._goboringcrypto_EC_KEY_get0_group(priv.key)
 	if group == nil {
 		return nil, fail("EC_KEY_get0_group")
@@ -187,7 +208,10 @@ func curveSize(curve string) int {
 	}
 }
 
-func GenerateKeyECDH(curve string) (*PrivateKeyECDH, []byte, error) {
+func GenerateKeyECDH(curve string) (_ *PrivateKeyECDH, _ []byte, err error) {
+	countOp(cryptometrics.ECDH, 0)
+	defer countResult(cryptometrics.ECDH, &err)
+
 	nid, err := curveNID(curve)
 	if err != nil {
 		return nil, nil, err
@@ -217,7 +241,7 @@ func GenerateKeyECDH(curve string) (*PrivateKeyECDH, []byte, error) {
 		return nil, nil, err
 	}
 
-	k := &PrivateKeyECDH{curve, key}
+	k := &PrivateKeyECDH{curve: curve, key: key}
 	// Note: Same as in NewPublicKeyECDH regarding finalizer and KeepAlive.
 	runtime.SetFinalizer(k, (*PrivateKeyECDH).finalize)
 	return k, bytes, nil
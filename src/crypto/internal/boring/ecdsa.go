@@ -9,7 +9,9 @@ package boring
 // #include "goboringcrypto.h"
 import "C"
 import (
-	"errors"
+	"crypto"
+	"crypto/internal/keyguard"
+	"internal/cryptometrics"
 	"runtime"
 )
 
@@ -18,11 +20,24 @@ type ecdsaSignature struct {
 }
 
 type PrivateKeyECDSA struct {
-	key *C.GO_EC_KEY
+	key    *C.GO_EC_KEY
+	closed bool
 }
 
 func (k *PrivateKeyECDSA) finalize() {
 	C._goboringcrypto_EC_KEY_free(k.key)
+	k.key = (*C.GO_EC_KEY)(keyguard.Poison())
+}
+
+// Close frees the key, which BoringCrypto zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyECDSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
 }
 
 type PublicKeyECDSA struct {
@@ -31,9 +46,10 @@ type PublicKeyECDSA struct {
 
 func (k *PublicKeyECDSA) finalize() {
 	C._goboringcrypto_EC_KEY_free(k.key)
+	k.key = (*C.GO_EC_KEY)(keyguard.Poison())
 }
 
-var errUnknownCurve = errors.New("boringcrypto: unknown elliptic curve")
+var errUnknownCurve = unsupported("boringcrypto: unknown elliptic curve")
 
 func curveNID(curve string) (C.int, error) {
 	switch curve {
@@ -110,7 +126,7 @@ func NewPrivateKeyECDSA(curve string, X, Y BigInt, D BigInt) (*PrivateKeyECDSA,
 		C._goboringcrypto_EC_KEY_free(key)
 		return nil, fail("EC_KEY_set_private_key")
 	}
-	k := &PrivateKeyECDSA{key}
+	k := &PrivateKeyECDSA{key: key}
 	// Note: Because of the finalizer, any time k.key is passed to cgo,
 	// that call must be followed by a call to runtime.KeepAlive(k),
 	// to make sure k is not collected (and finalized) before the cgo
@@ -119,7 +135,11 @@ func NewPrivateKeyECDSA(curve string, X, Y BigInt, D BigInt) (*PrivateKeyECDSA,
 	return k, nil
 }
 
-func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) ([]byte, error) {
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) (_ []byte, err error) {
+	countOp(cryptometrics.ECDSA, len(hash))
+	defer countResult(cryptometrics.ECDSA, &err)
+	checkOpen(priv.closed)
+
 	size := C._goboringcrypto_ECDSA_size(priv.key)
 	sig := make([]byte, size)
 	var sigLen C.uint
@@ -130,13 +150,28 @@ func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) ([]byte, error) {
 	return sig[:sigLen], nil
 }
 
+// SupportsDeterministicECDSA reports whether SignMarshalECDSADeterministic
+// is available. BoringCrypto has no API for RFC 6979 nonces.
+func SupportsDeterministicECDSA() bool { return false }
+
+func SignMarshalECDSADeterministic(priv *PrivateKeyECDSA, h crypto.Hash, hash []byte) ([]byte, error) {
+	return nil, unsupported("boringcrypto: deterministic ECDSA not supported")
+}
+
 func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	countOp(cryptometrics.ECDSA, len(hash))
 	ok := C._goboringcrypto_ECDSA_verify(0, base(hash), C.size_t(len(hash)), base(sig), C.size_t(len(sig)), pub.key) != 0
 	runtime.KeepAlive(pub)
+	if !ok {
+		countFailure(cryptometrics.ECDSA)
+	}
 	return ok
 }
 
 func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	countOp(cryptometrics.ECDSA, 0)
+	defer countResult(cryptometrics.ECDSA, &err)
+
 	nid, err := curveNID(curve)
 	if err != nil {
 		return nil, nil, nil, err
@@ -0,0 +1,26 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package boring
+
+import "errors"
+
+// Failure classes of the errors returned and the panics raised by this
+// package, exported by crypto/fips. Every error from a failed BoringCrypto
+// call matches ErrBackendFailure according to errors.Is, and every error
+// about parameters that BoringCrypto does not support matches
+// ErrUnsupportedParameter.
+var (
+	ErrBackendFailure       = errors.New("crypto: cryptographic backend failure")
+	ErrNotApproved          = errors.New("crypto: operation not approved by the cryptographic module")
+	ErrUnsupportedParameter = errors.New("crypto: parameter not supported by the cryptographic backend")
+)
+
+// unsupported is an error about a parameter that BoringCrypto does not
+// support.
+type unsupported string
+
+func (e unsupported) Error() string { return string(e) }
+
+func (e unsupported) Unwrap() error { return ErrUnsupportedParameter }
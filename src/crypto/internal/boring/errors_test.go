@@ -0,0 +1,40 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo
+
+package boring
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+)
+
+func TestErrorClasses(t *testing.T) {
+	if _, err := NewPublicKeyECDSA("P-192", nil, nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPublicKeyECDSA with unknown curve = %v, want ErrUnsupportedParameter", err)
+	}
+	c, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.(extraModes).NewGCM(16, 12); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewGCM with non-standard sizes = %v, want ErrUnsupportedParameter", err)
+	}
+	N, E, _, _, _, _, _, _, err := GenerateKeyRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := NewPublicKeyRSA(N, E)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyRSAPKCS1v15(pub, crypto.BLAKE2b_256, nil, nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("VerifyRSAPKCS1v15 with unsupported hash = %v, want ErrUnsupportedParameter", err)
+	}
+	if err := fail("X"); !errors.Is(err, ErrBackendFailure) || errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("fail does not match only ErrBackendFailure")
+	}
+}
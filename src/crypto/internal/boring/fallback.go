@@ -0,0 +1,61 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package boring
+
+import (
+	"internal/cryptometrics"
+	"internal/godebug"
+	"internal/itoa"
+	"runtime"
+	"sync"
+)
+
+// boringfallback=log reports, once per call site, operations that are
+// performed by the pure Go implementation while BoringCrypto is in use.
+var boringfallback = godebug.New("boringfallback")
+
+var fallbackLog struct {
+	sync.Mutex
+	seen map[uintptr]bool
+}
+
+// printFallback writes a fallback report. It is replaced in tests.
+var printFallback = func(msg string) { print(msg) }
+
+// logFallback reports a fallback of an alg operation, if enabled by the
+// boringfallback setting and not already reported for the call site.
+// The call site is the first caller outside the crypto packages.
+func logFallback(alg int, reason string) {
+	if boringfallback.Value() != "log" {
+		return
+	}
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	var site runtime.Frame
+	for {
+		f, more := frames.Next()
+		site = f
+		if !hasPrefix(f.Function, "crypto/") || !more {
+			break
+		}
+	}
+
+	fallbackLog.Lock()
+	defer fallbackLog.Unlock()
+	if fallbackLog.seen[site.PC] {
+		return
+	}
+	if fallbackLog.seen == nil {
+		fallbackLog.seen = make(map[uintptr]bool)
+	}
+	fallbackLog.seen[site.PC] = true
+	printFallback("boringcrypto: " + cryptometrics.Algorithms[alg].Display +
+		" performed by pure Go (" + reason + ") at " + site.Function + " (" +
+		site.File + ":" + itoa.Itoa(site.Line) + ")\n")
+}
+
+func hasPrefix(s, t string) bool {
+	return len(s) >= len(t) && s[:len(t)] == t
+}
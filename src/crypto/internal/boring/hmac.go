@@ -11,7 +11,10 @@ import "C"
 import (
 	"bytes"
 	"crypto"
+	"crypto/internal/keyguard"
+	"errors"
 	"hash"
+	"internal/cryptometrics"
 	"runtime"
 	"unsafe"
 )
@@ -71,6 +74,7 @@ func NewHMAC(h func() hash.Hash, key []byte) hash.Hash {
 	hkey := bytes.Clone(key)
 	hmac := &boringHMAC{
 		md:        md,
+		newHash:   h,
 		size:      ch.Size(),
 		blockSize: ch.BlockSize(),
 		key:       hkey,
@@ -81,6 +85,7 @@ func NewHMAC(h func() hash.Hash, key []byte) hash.Hash {
 
 type boringHMAC struct {
 	md          *C.GO_EVP_MD
+	newHash     func() hash.Hash
 	ctx         C.GO_HMAC_CTX
 	ctx2        C.GO_HMAC_CTX
 	size        int
@@ -88,9 +93,22 @@ type boringHMAC struct {
 	key         []byte
 	sum         []byte
 	needCleanup bool
+
+	// restored is set once UnmarshalBinary has replaced the keyed
+	// state, after which Reset must not rekey from key.
+	restored bool
+
+	closed bool // set by Close
 }
 
 func (h *boringHMAC) Reset() {
+	h.checkOpen()
+	if h.restored {
+		copy(h.digestState(hmacMDCtx), h.digestState(hmacICtx))
+		runtime.KeepAlive(h)
+		h.sum = nil
+		return
+	}
 	if h.needCleanup {
 		C._goboringcrypto_HMAC_CTX_cleanup(&h.ctx)
 	} else {
@@ -104,7 +122,7 @@ func (h *boringHMAC) Reset() {
 	C._goboringcrypto_HMAC_CTX_init(&h.ctx)
 
 	if C._goboringcrypto_HMAC_Init(&h.ctx, unsafe.Pointer(base(h.key)), C.int(len(h.key)), h.md) == 0 {
-		panic("boringcrypto: HMAC_Init failed")
+		panic(fail("HMAC_Init"))
 	}
 	if int(C._goboringcrypto_HMAC_size(&h.ctx)) != h.size {
 		println("boringcrypto: HMAC size:", C._goboringcrypto_HMAC_size(&h.ctx), "!=", h.size)
@@ -118,7 +136,34 @@ func (h *boringHMAC) finalize() {
 	C._goboringcrypto_HMAC_CTX_cleanup(&h.ctx)
 }
 
+// Close zeroes the key and the keyed state of h immediately, rather than
+// when h is garbage collected. h must not be used afterwards.
+func (h *boringHMAC) Close() error {
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	if h.needCleanup {
+		// HMAC_CTX_cleanup zeroes the context.
+		C._goboringcrypto_HMAC_CTX_cleanup(&h.ctx)
+		h.needCleanup = false
+		runtime.SetFinalizer(h, nil)
+	}
+	keyguard.Free(h.key)
+	keyguard.Free(h.sum)
+	h.key, h.sum = nil, nil
+	return nil
+}
+
+func (h *boringHMAC) checkOpen() {
+	if h.closed {
+		panic("boringcrypto: use of closed HMAC")
+	}
+}
+
 func (h *boringHMAC) Write(p []byte) (int, error) {
+	h.checkOpen()
+	countBytes(cryptometrics.HMAC, len(p))
 	if len(p) > 0 {
 		C._goboringcrypto_HMAC_Update(&h.ctx, (*C.uint8_t)(unsafe.Pointer(&p[0])), C.size_t(len(p)))
 	}
@@ -135,6 +180,7 @@ func (h *boringHMAC) BlockSize() int {
 }
 
 func (h *boringHMAC) Sum(in []byte) []byte {
+	h.checkOpen()
 	if h.sum == nil {
 		size := h.Size()
 		h.sum = make([]byte, size)
@@ -143,11 +189,139 @@ func (h *boringHMAC) Sum(in []byte) []byte {
 	// that Sum has no effect on the underlying stream.
 	// In particular it is OK to Sum, then Write more, then Sum again,
 	// and the second Sum acts as if the first didn't happen.
+	countOp(cryptometrics.HMAC, 0)
 	C._goboringcrypto_HMAC_CTX_init(&h.ctx2)
 	if C._goboringcrypto_HMAC_CTX_copy_ex(&h.ctx2, &h.ctx) == 0 {
-		panic("boringcrypto: HMAC_CTX_copy_ex failed")
+		panic(fail("HMAC_CTX_copy_ex"))
 	}
 	C._goboringcrypto_HMAC_Final(&h.ctx2, (*C.uint8_t)(unsafe.Pointer(&h.sum[0])), nil)
 	C._goboringcrypto_HMAC_CTX_cleanup(&h.ctx2)
 	return append(in, h.sum...)
 }
+
+// A BoringCrypto HMAC_CTX holds the EVP_MD, then three EVP_MD_CTX: the
+// running digest and the digests primed with the inner and outer key
+// pads. The second word of an EVP_MD_CTX points at the SHA context.
+const (
+	hmacMDCtx = 8 + 0*32
+	hmacICtx  = 8 + 1*32
+	hmacOCtx  = 8 + 2*32
+)
+
+// digestState returns the memory of the SHA context of the EVP_MD_CTX
+// at offset off in h.ctx. Callers must keep h alive while using it.
+func (h *boringHMAC) digestState(off uintptr) []byte {
+	md := *(*unsafe.Pointer)(unsafe.Add(unsafe.Pointer(&h.ctx), off+8))
+	return unsafe.Slice((*byte)(md), len(digestCtx(h.newHash())))
+}
+
+// digestCtx returns the memory of the BoringCrypto context inside h,
+// which must be a hash from this package.
+func digestCtx(h hash.Hash) []byte {
+	switch h := h.(type) {
+	case *sha1Hash:
+		return unsafe.Slice((*byte)(unsafe.Pointer(&h.ctx)), unsafe.Sizeof(h.ctx))
+	case *sha224Hash:
+		return unsafe.Slice((*byte)(unsafe.Pointer(&h.ctx)), unsafe.Sizeof(h.ctx))
+	case *sha256Hash:
+		return unsafe.Slice((*byte)(unsafe.Pointer(&h.ctx)), unsafe.Sizeof(h.ctx))
+	case *sha384Hash:
+		return unsafe.Slice((*byte)(unsafe.Pointer(&h.ctx)), unsafe.Sizeof(h.ctx))
+	case *sha512Hash:
+		return unsafe.Slice((*byte)(unsafe.Pointer(&h.ctx)), unsafe.Sizeof(h.ctx))
+	}
+	panic("boringcrypto: unknown HMAC hash")
+}
+
+type marshalable interface {
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
+}
+
+// hmacMagic and the layout below must match crypto/hmac, so that states
+// move freely between the BoringCrypto and Go implementations.
+const (
+	hmacMagic       = "hmac\x01"
+	maxHMACStateLen = 1 << 16
+)
+
+var hmacStateOffsets = [3]uintptr{hmacICtx, hmacOCtx, hmacMDCtx}
+
+func (h *boringHMAC) MarshalBinary() ([]byte, error) {
+	h.checkOpen()
+	t := h.newHash()
+	ctx := digestCtx(t)
+	b := []byte(hmacMagic)
+	for _, off := range hmacStateOffsets {
+		copy(ctx, h.digestState(off))
+		s, err := t.(marshalable).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		b = appendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	runtime.KeepAlive(h)
+	return b, nil
+}
+
+func (h *boringHMAC) UnmarshalBinary(b []byte) error {
+	h.checkOpen()
+	if len(b) < len(hmacMagic) || string(b[:len(hmacMagic)]) != hmacMagic {
+		return errors.New("crypto/hmac: invalid hash state identifier")
+	}
+	b = b[len(hmacMagic):]
+	var states [3][]byte
+	for i := range states {
+		if len(b) < 4 {
+			return errors.New("crypto/hmac: invalid hash state size")
+		}
+		var n uint32
+		b, n = consumeUint32(b)
+		if n > maxHMACStateLen || uint32(len(b)) < n {
+			return errors.New("crypto/hmac: invalid hash state size")
+		}
+		states[i], b = b[:n], b[n:]
+	}
+	if len(b) != 0 {
+		return errors.New("crypto/hmac: invalid hash state size")
+	}
+	// Decode everything before touching h, so that a bad state
+	// leaves it unchanged.
+	var ctxs [3][]byte
+	for i, s := range states {
+		t := h.newHash()
+		if err := t.(marshalable).UnmarshalBinary(s); err != nil {
+			return err
+		}
+		ctxs[i] = digestCtx(t)
+	}
+	for i, off := range hmacStateOffsets {
+		copy(h.digestState(off), ctxs[i])
+	}
+	runtime.KeepAlive(h)
+	h.restored = true
+	h.sum = nil
+	return nil
+}
+
+// Clone returns an independent copy of h.
+func (h *boringHMAC) Clone() (hash.Hash, error) {
+	h.checkOpen()
+	c := &boringHMAC{
+		md:          h.md,
+		newHash:     h.newHash,
+		size:        h.size,
+		blockSize:   h.blockSize,
+		key:         bytes.Clone(h.key), // Close zeroes it
+		needCleanup: true,
+		restored:    h.restored,
+	}
+	C._goboringcrypto_HMAC_CTX_init(&c.ctx)
+	if C._goboringcrypto_HMAC_CTX_copy_ex(&c.ctx, &h.ctx) == 0 {
+		return nil, fail("HMAC_CTX_copy_ex")
+	}
+	runtime.SetFinalizer(c, (*boringHMAC).finalize)
+	runtime.KeepAlive(h)
+	return c, nil
+}
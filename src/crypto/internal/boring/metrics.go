@@ -0,0 +1,115 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo) || (goexperiment.opensslcrypto && !boringcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan) || (goexperiment.cngcrypto && windows && !boringcrypto && !goexperiment.opensslcrypto && !cmd_go_bootstrap) || (goexperiment.darwincrypto && darwin && !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !cmd_go_bootstrap)
+
+package boring
+
+import (
+	"internal/cryptometrics"
+	"sync/atomic"
+	_ "unsafe" // for linkname
+)
+
+// counters holds the runtime/metrics counters described by
+// internal/cryptometrics.
+var counters [cryptometrics.NumAlgorithms][cryptometrics.NumCounters]atomic.Uint64
+
+func init() {
+	for alg := range counters {
+		for counter := range counters[alg] {
+			registerMetric(cryptometrics.Name(alg, counter), counters[alg][counter].Load)
+		}
+	}
+}
+
+// registerMetric is provided by package runtime.
+//
+//go:linkname registerMetric
+func registerMetric(name string, read func() uint64)
+
+// Counter returns the current value of a counter for algorithm alg.
+// alg and counter are internal/cryptometrics identifiers.
+func Counter(alg, counter int) uint64 {
+	return counters[alg][counter].Load()
+}
+
+// countOp records an operation of algorithm alg on n bytes of input.
+func countOp(alg, n int) {
+	setIndicator(indicatorApproved)
+	counters[alg][cryptometrics.Operations].Add(1)
+	countBytes(alg, n)
+}
+
+// countBytes records n bytes of input processed by algorithm alg, as part of
+// an operation counted separately, such as a streaming digest.
+func countBytes(alg, n int) {
+	if n > 0 {
+		counters[alg][cryptometrics.Processed].Add(uint64(n))
+	}
+}
+
+// countFailure records a failed operation of algorithm alg.
+func countFailure(alg int) {
+	setIndicator(indicatorNotApproved)
+	counters[alg][cryptometrics.Failures].Add(1)
+}
+
+// countResult records a failed operation of algorithm alg if *err is not
+// nil. It is meant to be deferred.
+func countResult(alg int, err *error) {
+	if *err != nil {
+		countFailure(alg)
+	}
+}
+
+// RecordFallback records that an alg operation was performed by the pure Go
+// implementation because BoringCrypto does not support its parameters,
+// described by reason. alg is one of the internal/cryptometrics algorithm
+// identifiers. With GODEBUG=boringfallback=log, the first fallback from
+// each call site is also reported on standard error.
+func RecordFallback(alg int, reason string) {
+	setIndicator(indicatorNotApproved)
+	counters[alg][cryptometrics.Fallbacks].Add(1)
+	logFallback(alg, reason)
+}
+
+// Values of the service indicator, which the runtime keeps for each
+// goroutine. It starts unset, and records whether the last operation of the
+// goroutine was an approved service of the module.
+const (
+	indicatorUnset = iota
+	indicatorApproved
+	indicatorNotApproved
+)
+
+// getIndicator and setIndicator are provided by package runtime.
+//
+//go:linkname getIndicator
+func getIndicator() uint8
+
+//go:linkname setIndicator
+func setIndicator(indicator uint8)
+
+// ServiceIndicator reports whether the last operation of the calling
+// goroutine was performed by BoringCrypto and succeeded. Operations that
+// fell back to the pure Go implementation, recorded with RecordFallback,
+// and failed operations are not approved.
+func ServiceIndicator() bool {
+	return getIndicator() == indicatorApproved
+}
+
+// SetNotApproved makes the service indicator of the calling goroutine
+// report that its last operation was not approved. It is called by the
+// operations of algorithms that are always computed in Go, and after module
+// operations whose parameters are not approved.
+func SetNotApproved() {
+	setIndicator(indicatorNotApproved)
+}
+
+// ResetServiceIndicator unsets the service indicator of the calling
+// goroutine, until its next operation.
+func ResetServiceIndicator() {
+	setIndicator(indicatorUnset)
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo
+
+package boring
+
+import (
+	"internal/cryptometrics"
+	"runtime/metrics"
+	"strings"
+	"testing"
+)
+
+func readCounter(alg, counter int) uint64 {
+	s := []metrics.Sample{{Name: cryptometrics.Name(alg, counter)}}
+	metrics.Read(s)
+	return s[0].Value.Uint64()
+}
+
+func TestMetrics(t *testing.T) {
+	ops := readCounter(cryptometrics.SHA256, cryptometrics.Operations)
+	processed := readCounter(cryptometrics.SHA256, cryptometrics.Processed)
+	SHA256(make([]byte, 100))
+	h := NewSHA256()
+	h.Write(make([]byte, 10))
+	h.Sum(nil)
+	if got := readCounter(cryptometrics.SHA256, cryptometrics.Operations) - ops; got != 2 {
+		t.Errorf("sha256 operations increased by %d, want 2", got)
+	}
+	if got := readCounter(cryptometrics.SHA256, cryptometrics.Processed) - processed; got != 110 {
+		t.Errorf("sha256 processed bytes increased by %d, want 110", got)
+	}
+
+	failures := readCounter(cryptometrics.AESGCM, cryptometrics.Failures)
+	c, err := NewAESCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := c.(extraModes).NewGCM(gcmStandardNonceSize, gcmTagSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Open(nil, make([]byte, gcmStandardNonceSize), make([]byte, 32), nil); err == nil {
+		t.Fatal("Open of invalid ciphertext succeeded")
+	}
+	if got := readCounter(cryptometrics.AESGCM, cryptometrics.Failures) - failures; got != 1 {
+		t.Errorf("aes-gcm failures increased by %d, want 1", got)
+	}
+
+	fallbacks := readCounter(cryptometrics.AESGCM, cryptometrics.Fallbacks)
+	if _, err := c.(extraModes).NewGCM(gcmStandardNonceSize, 12); err != nil {
+		t.Fatal(err)
+	}
+	if got := readCounter(cryptometrics.AESGCM, cryptometrics.Fallbacks) - fallbacks; got != 1 {
+		t.Errorf("aes-gcm fallbacks increased by %d, want 1", got)
+	}
+}
+
+func TestFallbackLog(t *testing.T) {
+	var logged []string
+	defer func(f func(string)) { printFallback = f }(printFallback)
+	printFallback = func(msg string) { logged = append(logged, msg) }
+
+	RecordFallback(cryptometrics.HMAC, "test")
+	if len(logged) != 0 {
+		t.Fatalf("fallback logged without GODEBUG=boringfallback=log: %q", logged)
+	}
+
+	t.Setenv("GODEBUG", "boringfallback=log")
+	for i := 0; i < 3; i++ {
+		RecordFallback(cryptometrics.HMAC, "test")
+	}
+	if len(logged) != 1 {
+		t.Fatalf("fallback logged %d times from one call site, want 1: %q", len(logged), logged)
+	}
+	if want := "boringcrypto: HMAC performed by pure Go (test) at testing.tRunner"; !strings.HasPrefix(logged[0], want) {
+		t.Errorf("logged %q, want prefix %q", logged[0], want)
+	}
+}
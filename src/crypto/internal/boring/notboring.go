@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build !(boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo)
+//go:build !(boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo) && !(goexperiment.opensslcrypto && !boringcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan) && !(goexperiment.cngcrypto && windows && !boringcrypto && !goexperiment.opensslcrypto && !cmd_go_bootstrap) && !(goexperiment.darwincrypto && darwin && !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !cmd_go_bootstrap)
 
 package boring
 
@@ -11,10 +11,36 @@ import (
 	"crypto/cipher"
 	"crypto/internal/boring/sig"
 	"hash"
+	"internal/goexperiment"
 )
 
 const available = false
 
+func init() {
+	// BoringCrypto, OpenSSL, CNG or CommonCrypto was requested but is not supported by this
+	// build, for example because of -msan or the target platform.
+	if boringfallback.Value() != "log" {
+		return
+	}
+	if goexperiment.BoringCrypto {
+		printFallback("boringcrypto: not available in this build; all operations performed by pure Go\n")
+	} else if goexperiment.OpenSSLCrypto {
+		printFallback("opensslcrypto: not available in this build; all operations performed by pure Go\n")
+	} else if goexperiment.CNGCrypto {
+		printFallback("cngcrypto: not available in this build; all operations performed by pure Go\n")
+	} else if goexperiment.DarwinCrypto {
+		printFallback("darwincrypto: not available in this build; all operations performed by pure Go\n")
+	}
+}
+
+// FIPSMode reports whether the module is operating in FIPS mode.
+// It is always false without BoringCrypto.
+func FIPSMode() bool { return false }
+
+// SupportsHash reports whether the module implements the hash alg.
+// It is always false without BoringCrypto.
+func SupportsHash(alg int) bool { return false }
+
 // Unreachable marks code that should be unreachable
 // when BoringCrypto is in use. It is a no-op without BoringCrypto.
 func Unreachable() {
@@ -28,29 +54,81 @@ func Unreachable() {
 // when BoringCrypto is in use. It is a no-op without BoringCrypto.
 func UnreachableExceptTests() {}
 
+// AllowGoForTesting permits the pure Go implementations to run in tests
+// while BoringCrypto is in use. It is a no-op without BoringCrypto.
+func AllowGoForTesting() (restore func()) { return func() {} }
+
+// RecordFallback records that an alg operation was performed by the pure Go
+// implementation. It is a no-op without BoringCrypto.
+func RecordFallback(alg int, reason string) {}
+
+// Counter returns the current value of a counter for algorithm alg.
+// It is always zero without BoringCrypto.
+func Counter(alg, counter int) uint64 { return 0 }
+
+// ServiceIndicator reports whether the last operation of the calling
+// goroutine was performed by BoringCrypto. It is always false without
+// BoringCrypto.
+func ServiceIndicator() bool { return false }
+
+// SetNotApproved is a no-op without BoringCrypto.
+func SetNotApproved() {}
+
+// ResetServiceIndicator is a no-op without BoringCrypto.
+func ResetServiceIndicator() {}
+
 type randReader int
 
 func (randReader) Read(b []byte) (int, error) { panic("boringcrypto: not available") }
 
 const RandReader = randReader(0)
 
-func NewSHA1() hash.Hash   { panic("boringcrypto: not available") }
-func NewSHA224() hash.Hash { panic("boringcrypto: not available") }
-func NewSHA256() hash.Hash { panic("boringcrypto: not available") }
-func NewSHA384() hash.Hash { panic("boringcrypto: not available") }
-func NewSHA512() hash.Hash { panic("boringcrypto: not available") }
-
-func SHA1([]byte) [20]byte   { panic("boringcrypto: not available") }
-func SHA224([]byte) [28]byte { panic("boringcrypto: not available") }
-func SHA256([]byte) [32]byte { panic("boringcrypto: not available") }
-func SHA384([]byte) [48]byte { panic("boringcrypto: not available") }
-func SHA512([]byte) [64]byte { panic("boringcrypto: not available") }
+func NewSHA1() hash.Hash       { panic("boringcrypto: not available") }
+func NewSHA224() hash.Hash     { panic("boringcrypto: not available") }
+func NewSHA256() hash.Hash     { panic("boringcrypto: not available") }
+func NewSHA384() hash.Hash     { panic("boringcrypto: not available") }
+func NewSHA512() hash.Hash     { panic("boringcrypto: not available") }
+func NewSHA512_224() hash.Hash { panic("boringcrypto: not available") }
+func NewSHA512_256() hash.Hash { panic("boringcrypto: not available") }
+
+func SHA1([]byte) [20]byte       { panic("boringcrypto: not available") }
+func SHA224([]byte) [28]byte     { panic("boringcrypto: not available") }
+func SHA256([]byte) [32]byte     { panic("boringcrypto: not available") }
+func SHA384([]byte) [48]byte     { panic("boringcrypto: not available") }
+func SHA512([]byte) [64]byte     { panic("boringcrypto: not available") }
+func SHA512_224([]byte) [28]byte { panic("boringcrypto: not available") }
+func SHA512_256([]byte) [32]byte { panic("boringcrypto: not available") }
+
+func SHA1Into(dst, p []byte)   { panic("boringcrypto: not available") }
+func SHA224Into(dst, p []byte) { panic("boringcrypto: not available") }
+func SHA256Into(dst, p []byte) { panic("boringcrypto: not available") }
+func SHA384Into(dst, p []byte) { panic("boringcrypto: not available") }
+func SHA512Into(dst, p []byte) { panic("boringcrypto: not available") }
+
+func SHA256Batch([][]byte) [][32]byte { panic("boringcrypto: not available") }
 
 func NewHMAC(h func() hash.Hash, key []byte) hash.Hash { panic("boringcrypto: not available") }
 
 func NewAESCipher(key []byte) (cipher.Block, error) { panic("boringcrypto: not available") }
 func NewGCMTLS(cipher.Block) (cipher.AEAD, error)   { panic("boringcrypto: not available") }
 
+// SupportsAESKeyWrap reports whether the module implements AES key wrap.
+// It is always false without BoringCrypto.
+func SupportsAESKeyWrap() bool { return false }
+
+func WrapKeyAES(kek, key []byte, pad bool) ([]byte, error) {
+	panic("boringcrypto: not available")
+}
+func UnwrapKeyAES(kek, wrapped []byte, pad bool) ([]byte, error) {
+	panic("boringcrypto: not available")
+}
+
+// SupportsCMAC reports whether the module implements AES-CMAC.
+// It is always false without BoringCrypto.
+func SupportsCMAC() bool { return false }
+
+func NewCMAC(key []byte) (hash.Hash, error) { panic("boringcrypto: not available") }
+
 type PublicKeyECDSA struct{ _ int }
 type PrivateKeyECDSA struct{ _ int }
 
@@ -70,6 +148,14 @@ func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
 	panic("boringcrypto: not available")
 }
 
+// SupportsDeterministicECDSA reports whether SignMarshalECDSADeterministic
+// is available. It is always false without BoringCrypto.
+func SupportsDeterministicECDSA() bool { return false }
+
+func SignMarshalECDSADeterministic(priv *PrivateKeyECDSA, h crypto.Hash, hash []byte) ([]byte, error) {
+	panic("boringcrypto: not available")
+}
+
 type PublicKeyRSA struct{ _ int }
 type PrivateKeyRSA struct{ _ int }
 
@@ -111,6 +197,14 @@ func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen
 	panic("boringcrypto: not available")
 }
 
+// SupportsRSAPSSSaltLength reports whether SignRSAPSS supports the salt
+// length saltLen with h. It is always false without BoringCrypto.
+func SupportsRSAPSSSaltLength(h crypto.Hash, saltLen int) bool { return false }
+
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP support
+// h, mgfHash and label. It is always false without BoringCrypto.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool { return false }
+
 type PublicKeyECDH struct{}
 type PrivateKeyECDH struct{}
 
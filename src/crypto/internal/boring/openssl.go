@@ -0,0 +1,291 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && !boringcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package boring
+
+// With GOEXPERIMENT=opensslcrypto, this package is a thin layer over
+// crypto/internal/backend/openssl, which implements the same operations
+// with the system libcrypto, so that the crypto packages don't need to
+// know which backend is in use.
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/internal/backend/openssl"
+	"crypto/internal/boring/sig"
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+const available = true
+
+func init() {
+	openssl.SetMetricsHooks(countOp, countBytes, countFailure, RecordFallback)
+	if err := openssl.Init(); err != nil {
+		panic("opensslcrypto: " + err.Error())
+	}
+	ModuleName = "OpenSSL"
+	ModuleVersion = openssl.Version()
+	for i, r := range Routes {
+		if c, ok := opensslRoutes[r.Name]; ok {
+			Routes[i] = c
+		}
+	}
+	sig.BoringCrypto()
+	runCASTs()
+}
+
+// opensslRoutes are the routes that differ from those of BoringCrypto.
+var opensslRoutes = map[string]Route{
+	"AES-CMAC": {"AES-CMAC", RouteModule, ""},
+	"AES-KW":   {"AES-KW", RouteModule, ""},
+	"ECDSA":    {"ECDSA", RoutePartial, "P-224, P-256, P-384 and P-521, with key generation and signing only from crypto/rand.Reader, and deterministic signing only with OpenSSL 3.2 or later"},
+}
+
+// FIPSMode reports whether libcrypto uses the FIPS provider by default.
+func FIPSMode() bool {
+	return openssl.FIPS()
+}
+
+// SupportsHash reports whether the module implements the hash alg, an
+// internal/cryptometrics algorithm. libcrypto implements all the SHA-1
+// and SHA-2 hashes.
+func SupportsHash(alg int) bool { return true }
+
+// backendError is an error from the OpenSSL backend, which also matches
+// the corresponding failure class of this package.
+type backendError struct {
+	err   error
+	class error
+}
+
+func (e *backendError) Error() string   { return e.err.Error() }
+func (e *backendError) Unwrap() []error { return []error{e.err, e.class} }
+
+// convertError returns err, classified as the errors of this package.
+func convertError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, openssl.ErrUnsupportedParameter):
+		return &backendError{err, ErrUnsupportedParameter}
+	case errors.Is(err, openssl.ErrBackendFailure):
+		return &backendError{err, ErrBackendFailure}
+	}
+	return err
+}
+
+type fail string
+
+func (e fail) Error() string { return "opensslcrypto: " + string(e) + " failed" }
+
+func (e fail) Unwrap() error { return ErrBackendFailure }
+
+const wordBytes = bits.UintSize / 8
+
+const (
+	gcmTagSize           = 16
+	gcmStandardNonceSize = 12
+)
+
+const RandReader = openssl.RandReader
+
+func NewSHA1() hash.Hash       { return openssl.NewSHA1() }
+func NewSHA224() hash.Hash     { return openssl.NewSHA224() }
+func NewSHA256() hash.Hash     { return openssl.NewSHA256() }
+func NewSHA384() hash.Hash     { return openssl.NewSHA384() }
+func NewSHA512() hash.Hash     { return openssl.NewSHA512() }
+func NewSHA512_224() hash.Hash { return openssl.NewSHA512_224() }
+func NewSHA512_256() hash.Hash { return openssl.NewSHA512_256() }
+
+func SHA1(p []byte) [20]byte       { return openssl.SHA1(p) }
+func SHA224(p []byte) [28]byte     { return openssl.SHA224(p) }
+func SHA256(p []byte) [32]byte     { return openssl.SHA256(p) }
+func SHA384(p []byte) [48]byte     { return openssl.SHA384(p) }
+func SHA512(p []byte) [64]byte     { return openssl.SHA512(p) }
+func SHA512_224(p []byte) [28]byte { return openssl.SHA512_224(p) }
+func SHA512_256(p []byte) [32]byte { return openssl.SHA512_256(p) }
+
+func SHA1Into(dst, p []byte)   { openssl.SHA1Into(dst, p) }
+func SHA224Into(dst, p []byte) { openssl.SHA224Into(dst, p) }
+func SHA256Into(dst, p []byte) { openssl.SHA256Into(dst, p) }
+func SHA384Into(dst, p []byte) { openssl.SHA384Into(dst, p) }
+func SHA512Into(dst, p []byte) { openssl.SHA512Into(dst, p) }
+
+func SHA256Batch(msgs [][]byte) [][32]byte { return openssl.SHA256Batch(msgs) }
+
+func NewHMAC(h func() hash.Hash, key []byte) hash.Hash { return openssl.NewHMAC(h, key) }
+
+func NewAESCipher(key []byte) (cipher.Block, error) {
+	c, err := openssl.NewAESCipher(key)
+	return c, convertError(err)
+}
+
+func NewGCMTLS(c cipher.Block) (cipher.AEAD, error) {
+	g, err := openssl.NewGCMTLS(c)
+	return g, convertError(err)
+}
+
+// SupportsAESKeyWrap reports whether the module implements the KW and KWP
+// modes of SP 800-38F, which libcrypto does.
+func SupportsAESKeyWrap() bool { return true }
+
+func WrapKeyAES(kek, key []byte, pad bool) ([]byte, error) {
+	w, err := openssl.WrapKeyAES(kek, key, pad)
+	return w, convertError(err)
+}
+
+func UnwrapKeyAES(kek, wrapped []byte, pad bool) ([]byte, error) {
+	k, err := openssl.UnwrapKeyAES(kek, wrapped, pad)
+	return k, convertError(err)
+}
+
+// SupportsCMAC reports whether the module implements AES-CMAC, which
+// libcrypto does.
+func SupportsCMAC() bool { return true }
+
+func NewCMAC(key []byte) (hash.Hash, error) {
+	h, err := openssl.NewCMAC(key)
+	return h, convertError(err)
+}
+
+type PublicKeyECDSA = openssl.PublicKeyECDSA
+type PrivateKeyECDSA = openssl.PrivateKeyECDSA
+
+func GenerateKeyECDSA(curve string) (X, Y, D BigInt, err error) {
+	x, y, d, err := openssl.GenerateKeyECDSA(curve)
+	return BigInt(x), BigInt(y), BigInt(d), convertError(err)
+}
+
+func NewPrivateKeyECDSA(curve string, X, Y, D BigInt) (*PrivateKeyECDSA, error) {
+	k, err := openssl.NewPrivateKeyECDSA(curve, openssl.BigInt(X), openssl.BigInt(Y), openssl.BigInt(D))
+	return k, convertError(err)
+}
+
+func NewPublicKeyECDSA(curve string, X, Y BigInt) (*PublicKeyECDSA, error) {
+	k, err := openssl.NewPublicKeyECDSA(curve, openssl.BigInt(X), openssl.BigInt(Y))
+	return k, convertError(err)
+}
+
+func SignMarshalECDSA(priv *PrivateKeyECDSA, hash []byte) ([]byte, error) {
+	sig, err := openssl.SignMarshalECDSA(priv, hash)
+	return sig, convertError(err)
+}
+
+// SupportsDeterministicECDSA reports whether SignMarshalECDSADeterministic
+// is available, which requires OpenSSL 3.2 or later.
+func SupportsDeterministicECDSA() bool { return openssl.SupportsDeterministicECDSA() }
+
+func SignMarshalECDSADeterministic(priv *PrivateKeyECDSA, h crypto.Hash, hash []byte) ([]byte, error) {
+	sig, err := openssl.SignMarshalECDSADeterministic(priv, h, hash)
+	return sig, convertError(err)
+}
+
+func VerifyECDSA(pub *PublicKeyECDSA, hash []byte, sig []byte) bool {
+	return openssl.VerifyECDSA(pub, hash, sig)
+}
+
+type PublicKeyRSA = openssl.PublicKeyRSA
+type PrivateKeyRSA = openssl.PrivateKeyRSA
+
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP
+// support h, mgfHash and label. OpenSSL supports all of them.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool { return true }
+
+func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) ([]byte, error) {
+	out, err := openssl.DecryptRSAOAEP(h, mgfHash, priv, ciphertext, label)
+	return out, convertError(err)
+}
+
+func DecryptRSAPKCS1(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	out, err := openssl.DecryptRSAPKCS1(priv, ciphertext)
+	return out, convertError(err)
+}
+
+func DecryptRSANoPadding(priv *PrivateKeyRSA, ciphertext []byte) ([]byte, error) {
+	out, err := openssl.DecryptRSANoPadding(priv, ciphertext)
+	return out, convertError(err)
+}
+
+func EncryptRSAOAEP(h, mgfHash hash.Hash, pub *PublicKeyRSA, msg, label []byte) ([]byte, error) {
+	out, err := openssl.EncryptRSAOAEP(h, mgfHash, pub, msg, label)
+	return out, convertError(err)
+}
+
+func EncryptRSAPKCS1(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	out, err := openssl.EncryptRSAPKCS1(pub, msg)
+	return out, convertError(err)
+}
+
+func EncryptRSANoPadding(pub *PublicKeyRSA, msg []byte) ([]byte, error) {
+	out, err := openssl.EncryptRSANoPadding(pub, msg)
+	return out, convertError(err)
+}
+
+func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	n, e, d, p, q, dp, dq, qinv, err := openssl.GenerateKeyRSA(bits)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, nil, convertError(err)
+	}
+	return BigInt(n), BigInt(e), BigInt(d), BigInt(p), BigInt(q), BigInt(dp), BigInt(dq), BigInt(qinv), nil
+}
+
+func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
+	k, err := openssl.NewPrivateKeyRSA(openssl.BigInt(N), openssl.BigInt(E), openssl.BigInt(D),
+		openssl.BigInt(P), openssl.BigInt(Q), openssl.BigInt(Dp), openssl.BigInt(Dq), openssl.BigInt(Qinv))
+	return k, convertError(err)
+}
+
+func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
+	k, err := openssl.NewPublicKeyRSA(openssl.BigInt(N), openssl.BigInt(E))
+	return k, convertError(err)
+}
+
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) ([]byte, error) {
+	sig, err := openssl.SignRSAPKCS1v15(priv, h, hashed)
+	return sig, convertError(err)
+}
+
+// SupportsRSAPSSSaltLength reports whether SignRSAPSS supports the
+// crypto/rsa salt length saltLen with h. OpenSSL supports all of them.
+func SupportsRSAPSSSaltLength(h crypto.Hash, saltLen int) bool { return true }
+
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) ([]byte, error) {
+	sig, err := openssl.SignRSAPSS(priv, h, hashed, saltLen)
+	return sig, convertError(err)
+}
+
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) error {
+	return convertError(openssl.VerifyRSAPKCS1v15(pub, h, hashed, sig))
+}
+
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) error {
+	return convertError(openssl.VerifyRSAPSS(pub, h, hashed, sig, saltLen))
+}
+
+type PublicKeyECDH = openssl.PublicKeyECDH
+type PrivateKeyECDH = openssl.PrivateKeyECDH
+
+func ECDH(priv *PrivateKeyECDH, pub *PublicKeyECDH) ([]byte, error) {
+	out, err := openssl.ECDH(priv, pub)
+	return out, convertError(err)
+}
+
+func GenerateKeyECDH(curve string) (*PrivateKeyECDH, []byte, error) {
+	k, bytes, err := openssl.GenerateKeyECDH(curve)
+	return k, bytes, convertError(err)
+}
+
+func NewPrivateKeyECDH(curve string, bytes []byte) (*PrivateKeyECDH, error) {
+	k, err := openssl.NewPrivateKeyECDH(curve, bytes)
+	return k, convertError(err)
+}
+
+func NewPublicKeyECDH(curve string, bytes []byte) (*PublicKeyECDH, error) {
+	k, err := openssl.NewPublicKeyECDH(curve, bytes)
+	return k, convertError(err)
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.opensslcrypto && !boringcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan
+
+package boring
+
+import (
+	"errors"
+	"internal/cryptometrics"
+	"strings"
+	"testing"
+)
+
+func TestOpenSSLModule(t *testing.T) {
+	if ModuleName != "OpenSSL" || !strings.HasPrefix(ModuleVersion, "OpenSSL 3.") {
+		t.Errorf("module = %q %q, want OpenSSL 3", ModuleName, ModuleVersion)
+	}
+}
+
+func TestOpenSSLErrorClasses(t *testing.T) {
+	if _, err := NewPublicKeyECDSA("P-192", nil, nil); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("NewPublicKeyECDSA with unknown curve = %v, want ErrUnsupportedParameter", err)
+	}
+	if _, err := NewPublicKeyECDH("P-256", []byte{4, 1, 2}); err == nil {
+		t.Error("NewPublicKeyECDH with an invalid point succeeded")
+	}
+}
+
+func TestOpenSSLMetrics(t *testing.T) {
+	ops := Counter(cryptometrics.SHA256, cryptometrics.Operations)
+	processed := Counter(cryptometrics.SHA256, cryptometrics.Processed)
+	ResetServiceIndicator()
+	SHA256(make([]byte, 100))
+	if !ServiceIndicator() {
+		t.Error("ServiceIndicator() = false after SHA256")
+	}
+	if got := Counter(cryptometrics.SHA256, cryptometrics.Operations) - ops; got != 1 {
+		t.Errorf("sha256 operations increased by %d, want 1", got)
+	}
+	if got := Counter(cryptometrics.SHA256, cryptometrics.Processed) - processed; got != 100 {
+		t.Errorf("sha256 processed bytes increased by %d, want 100", got)
+	}
+}
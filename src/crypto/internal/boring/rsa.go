@@ -10,15 +10,20 @@ package boring
 import "C"
 import (
 	"crypto"
+	"crypto/internal/keyguard"
 	"crypto/subtle"
 	"errors"
 	"hash"
+	"internal/cryptometrics"
 	"runtime"
 	"strconv"
 	"unsafe"
 )
 
 func GenerateKeyRSA(bits int) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
+	countOp(cryptometrics.RSA, 0)
+	defer countResult(cryptometrics.RSA, &err)
+
 	bad := func(e error) (N, E, D, P, Q, Dp, Dq, Qinv BigInt, err error) {
 		return nil, nil, nil, nil, nil, nil, nil, nil, e
 	}
@@ -61,6 +66,7 @@ func NewPublicKeyRSA(N, E BigInt) (*PublicKeyRSA, error) {
 
 func (k *PublicKeyRSA) finalize() {
 	C._goboringcrypto_RSA_free(k._key)
+	k._key = (*C.GO_RSA)(keyguard.Poison())
 }
 
 func (k *PublicKeyRSA) withKey(f func(*C.GO_RSA) C.int) C.int {
@@ -73,7 +79,8 @@ func (k *PublicKeyRSA) withKey(f func(*C.GO_RSA) C.int) C.int {
 
 type PrivateKeyRSA struct {
 	// _key MUST NOT be accessed directly. Instead, use the withKey method.
-	_key *C.GO_RSA
+	_key   *C.GO_RSA
+	closed bool
 }
 
 func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error) {
@@ -98,9 +105,22 @@ func NewPrivateKeyRSA(N, E, D, P, Q, Dp, Dq, Qinv BigInt) (*PrivateKeyRSA, error
 
 func (k *PrivateKeyRSA) finalize() {
 	C._goboringcrypto_RSA_free(k._key)
+	k._key = (*C.GO_RSA)(keyguard.Poison())
+}
+
+// Close frees the key, which BoringCrypto zeroes, immediately rather than
+// when k is garbage collected. k must not be used afterwards.
+func (k *PrivateKeyRSA) Close() error {
+	if !k.closed {
+		k.closed = true
+		runtime.SetFinalizer(k, nil)
+		k.finalize()
+	}
+	return nil
 }
 
 func (k *PrivateKeyRSA) withKey(f func(*C.GO_RSA) C.int) C.int {
+	checkOpen(k.closed)
 	// Because of the finalizer, any time _key is passed to cgo, that call must
 	// be followed by a call to runtime.KeepAlive, to make sure k is not
 	// collected (and finalized) before the cgo call returns.
@@ -146,11 +166,11 @@ func setupRSA(withKey func(func(*C.GO_RSA) C.int) C.int,
 	if padding == C.GO_RSA_PKCS1_OAEP_PADDING {
 		md := hashToMD(h)
 		if md == nil {
-			return nil, nil, errors.New("crypto/rsa: unsupported hash function")
+			return nil, nil, unsupported("crypto/rsa: unsupported hash function")
 		}
 		mgfMD := hashToMD(mgfHash)
 		if mgfMD == nil {
-			return nil, nil, errors.New("crypto/rsa: unsupported hash function")
+			return nil, nil, unsupported("crypto/rsa: unsupported hash function")
 		}
 		if C._goboringcrypto_EVP_PKEY_CTX_set_rsa_oaep_md(ctx, md) == 0 {
 			return nil, nil, fail("EVP_PKEY_set_rsa_oaep_md")
@@ -176,7 +196,7 @@ func setupRSA(withKey func(func(*C.GO_RSA) C.int) C.int,
 		}
 		md := cryptoHashToMD(ch)
 		if md == nil {
-			return nil, nil, errors.New("crypto/rsa: unsupported hash function")
+			return nil, nil, unsupported("crypto/rsa: unsupported hash function")
 		}
 		if C._goboringcrypto_EVP_PKEY_CTX_set_rsa_mgf1_md(ctx, md) == 0 {
 			return nil, nil, fail("EVP_PKEY_set_rsa_mgf1_md")
@@ -190,7 +210,9 @@ func cryptRSA(withKey func(func(*C.GO_RSA) C.int) C.int,
 	padding C.int, h, mgfHash hash.Hash, label []byte, saltLen int, ch crypto.Hash,
 	init func(*C.GO_EVP_PKEY_CTX) C.int,
 	crypt func(*C.GO_EVP_PKEY_CTX, *C.uint8_t, *C.size_t, *C.uint8_t, C.size_t) C.int,
-	in []byte) ([]byte, error) {
+	in []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(in))
+	defer countResult(cryptometrics.RSA, &err)
 
 	pkey, ctx, err := setupRSA(withKey, padding, h, mgfHash, label, saltLen, ch, init)
 	if err != nil {
@@ -210,6 +232,10 @@ func cryptRSA(withKey func(func(*C.GO_RSA) C.int) C.int,
 	return out[:outLen], nil
 }
 
+// SupportsRSAOAEP reports whether DecryptRSAOAEP and EncryptRSAOAEP
+// support h, mgfHash and label. BoringCrypto supports all of them.
+func SupportsRSAOAEP(h, mgfHash hash.Hash, label []byte) bool { return true }
+
 func DecryptRSAOAEP(h, mgfHash hash.Hash, priv *PrivateKeyRSA, ciphertext, label []byte) ([]byte, error) {
 	return cryptRSA(priv.withKey, C.GO_RSA_PKCS1_OAEP_PADDING, h, mgfHash, label, 0, 0, decryptInit, decrypt, ciphertext)
 }
@@ -252,12 +278,19 @@ func encrypt(ctx *C.GO_EVP_PKEY_CTX, out *C.uint8_t, outLen *C.size_t, in *C.uin
 	return C._goboringcrypto_EVP_PKEY_encrypt(ctx, out, outLen, in, inLen)
 }
 
+// SupportsRSAPSSSaltLength reports whether SignRSAPSS supports the
+// crypto/rsa salt length saltLen with h. BoringCrypto supports all of them.
+func SupportsRSAPSSSaltLength(h crypto.Hash, saltLen int) bool { return true }
+
 var invalidSaltLenErr = errors.New("crypto/rsa: PSSOptions.SaltLength cannot be negative")
 
-func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) ([]byte, error) {
+func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
 	md := cryptoHashToMD(h)
 	if md == nil {
-		return nil, errors.New("crypto/rsa: unsupported hash function")
+		return nil, unsupported("crypto/rsa: unsupported hash function")
 	}
 
 	// A salt length of -2 is valid in BoringSSL, but not in crypto/rsa, so reject
@@ -287,10 +320,13 @@ func SignRSAPSS(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte, saltLen int)
 	return out[:outLen], nil
 }
 
-func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) error {
+func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen int) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
 	md := cryptoHashToMD(h)
 	if md == nil {
-		return errors.New("crypto/rsa: unsupported hash function")
+		return unsupported("crypto/rsa: unsupported hash function")
 	}
 
 	// A salt length of -2 is valid in BoringSSL, but not in crypto/rsa, so reject
@@ -316,7 +352,10 @@ func VerifyRSAPSS(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte, saltLen
 	return nil
 }
 
-func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) ([]byte, error) {
+func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) (_ []byte, err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
 	if h == 0 {
 		// No hashing.
 		var out []byte
@@ -333,7 +372,7 @@ func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) ([]byte,
 
 	md := cryptoHashToMD(h)
 	if md == nil {
-		return nil, errors.New("crypto/rsa: unsupported hash function: " + strconv.Itoa(int(h)))
+		return nil, unsupported("crypto/rsa: unsupported hash function: " + strconv.Itoa(int(h)))
 	}
 	nid := C._goboringcrypto_EVP_MD_type(md)
 	var out []byte
@@ -348,7 +387,10 @@ func SignRSAPKCS1v15(priv *PrivateKeyRSA, h crypto.Hash, hashed []byte) ([]byte,
 	return out[:outLen], nil
 }
 
-func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) error {
+func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) (err error) {
+	countOp(cryptometrics.RSA, len(hashed))
+	defer countResult(cryptometrics.RSA, &err)
+
 	if h == 0 {
 		var out []byte
 		var outLen C.size_t
@@ -366,7 +408,7 @@ func VerifyRSAPKCS1v15(pub *PublicKeyRSA, h crypto.Hash, hashed, sig []byte) err
 	}
 	md := cryptoHashToMD(h)
 	if md == nil {
-		return errors.New("crypto/rsa: unsupported hash function")
+		return unsupported("crypto/rsa: unsupported hash function")
 	}
 	nid := C._goboringcrypto_EVP_MD_type(md)
 	if pub.withKey(func(key *C.GO_RSA) C.int {
@@ -7,6 +7,8 @@
 package boring
 
 /*
+#include <string.h>
+
 #include "goboringcrypto.h"
 
 int
@@ -54,11 +56,45 @@ _goboringcrypto_gosha512(void *p, size_t n, void *out)
 		_goboringcrypto_SHA512_Final(out, &ctx);
 }
 
+// _goboringcrypto_gosha256batch computes the SHA-256 digests of n messages
+// stored back to back at p, with lengths lens, into consecutive 32-byte
+// digests at out.
+int
+_goboringcrypto_gosha256batch(void *p, const size_t *lens, size_t n, void *out)
+{
+	uint8_t *pp = p;
+	uint8_t *o = out;
+	for (size_t i = 0; i < n; i++) {
+		if (!_goboringcrypto_gosha256(pp, lens[i], o))
+			return 0;
+		pp += lens[i];
+		o += 32;
+	}
+	return 1;
+}
+
+// _goboringcrypto_gosha512iv computes SHA-512 from the initial hash value
+// iv, for the truncated SHA-512/t variants, which the module doesn't export.
+// The hash value is the first field of SHA512_CTX.
+int
+_goboringcrypto_gosha512iv(const void *iv, void *p, size_t n, void *out)
+{
+	GO_SHA512_CTX ctx;
+	_goboringcrypto_SHA512_Init(&ctx);
+	memcpy(&ctx, iv, 64);
+	return _goboringcrypto_SHA512_Update(&ctx, p, n) &&
+		_goboringcrypto_SHA512_Final(out, &ctx);
+}
+
 */
 import "C"
 import (
 	"errors"
 	"hash"
+	"internal/cryptometrics"
+	"internal/godebug"
+	"io"
+	"sync"
 	"unsafe"
 )
 
@@ -72,41 +108,192 @@ import (
 // and applying a noescape along the way.
 // This is all to preserve compatibility with the allocation behavior of the non-boring implementations.
 
+// The BoringCrypto module, built from the fips-20210429 tag of BoringSSL,
+// only implements SHA-1 and SHA-2: it has no SHA-3 or SHAKE, neither as
+// EVP_MD nor as a low-level API, so there are no bindings for them here.
+// crypto/sha3 always uses its Go implementation, and reports a fallback.
+//
+// The module doesn't export SHA-512/224 and SHA-512/256 either, but they
+// are SHA-512 with a different initial hash value and a truncated output
+// (FIPS 180-4, Section 5.3.6), so they are computed with the SHA-512
+// functions after overwriting the hash value set by SHA512_Init.
+
 func SHA1(p []byte) (sum [20]byte) {
+	countOp(cryptometrics.SHA1, len(p))
 	if C._goboringcrypto_gosha1(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(sum[:]))) == 0 {
-		panic("boringcrypto: SHA1 failed")
+		panic(fail("SHA1"))
 	}
 	return
 }
 
 func SHA224(p []byte) (sum [28]byte) {
+	countOp(cryptometrics.SHA224, len(p))
 	if C._goboringcrypto_gosha224(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(sum[:]))) == 0 {
-		panic("boringcrypto: SHA224 failed")
+		panic(fail("SHA224"))
 	}
 	return
 }
 
 func SHA256(p []byte) (sum [32]byte) {
+	countOp(cryptometrics.SHA256, len(p))
 	if C._goboringcrypto_gosha256(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(sum[:]))) == 0 {
-		panic("boringcrypto: SHA256 failed")
+		panic(fail("SHA256"))
 	}
 	return
 }
 
+// Messages up to sha256BatchMax bytes are hashed by SHA256Batch in groups
+// of up to sha256BatchBuf bytes per cgo call. Larger messages are hashed
+// one per call, since the call overhead is small in comparison.
+const (
+	sha256BatchMax = 4 << 10
+	sha256BatchBuf = 64 << 10
+)
+
+// SHA256Batch returns the SHA-256 digests of msgs. It amortizes the cgo call
+// overhead of hashing many small messages. The module has no multi-buffer
+// implementation, so the messages are hashed one after the other.
+func SHA256Batch(msgs [][]byte) [][32]byte {
+	sums := make([][32]byte, len(msgs))
+	var buf []byte
+	var lens []C.size_t
+	var first int // index of the first message in buf
+	flush := func() {
+		if len(lens) > 0 && C._goboringcrypto_gosha256batch(unsafe.Pointer(&*addr(buf)), &lens[0], C.size_t(len(lens)), unsafe.Pointer(&sums[first])) == 0 {
+			panic(fail("SHA256"))
+		}
+		buf, lens = buf[:0], lens[:0]
+	}
+	for i, m := range msgs {
+		if len(m) > sha256BatchMax {
+			flush()
+			sums[i] = SHA256(m)
+			continue
+		}
+		if len(buf)+len(m) > sha256BatchBuf {
+			flush()
+		}
+		if len(lens) == 0 {
+			first = i
+		}
+		if buf == nil {
+			buf = make([]byte, 0, sha256BatchBuf)
+		}
+		countOp(cryptometrics.SHA256, len(m))
+		buf = append(buf, m...)
+		lens = append(lens, C.size_t(len(m)))
+	}
+	flush()
+	return sums
+}
+
 func SHA384(p []byte) (sum [48]byte) {
+	countOp(cryptometrics.SHA384, len(p))
 	if C._goboringcrypto_gosha384(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(sum[:]))) == 0 {
-		panic("boringcrypto: SHA384 failed")
+		panic(fail("SHA384"))
 	}
 	return
 }
 
 func SHA512(p []byte) (sum [64]byte) {
+	countOp(cryptometrics.SHA512, len(p))
 	if C._goboringcrypto_gosha512(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(sum[:]))) == 0 {
-		panic("boringcrypto: SHA512 failed")
+		panic(fail("SHA512"))
+	}
+	return
+}
+
+func SHA512_224(p []byte) (sum [28]byte) {
+	countOp(cryptometrics.SHA512_224, len(p))
+	var out [64]byte
+	if C._goboringcrypto_gosha512iv(unsafe.Pointer(&sha512_224IV), unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(out[:]))) == 0 {
+		panic(fail("SHA512_224"))
+	}
+	copy(sum[:], out[:])
+	return
+}
+
+func SHA512_256(p []byte) (sum [32]byte) {
+	countOp(cryptometrics.SHA512_256, len(p))
+	var out [64]byte
+	if C._goboringcrypto_gosha512iv(unsafe.Pointer(&sha512_256IV), unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(out[:]))) == 0 {
+		panic(fail("SHA512_256"))
 	}
+	copy(sum[:], out[:])
 	return
 }
 
+// SHA1Into, SHA224Into, SHA256Into, SHA384Into, and SHA512Into write the
+// digest of p to the beginning of dst, which must be large enough to hold
+// it, without copying it through a return value.
+
+func SHA1Into(dst, p []byte) {
+	_ = dst[20-1]
+	countOp(cryptometrics.SHA1, len(p))
+	if C._goboringcrypto_gosha1(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(dst))) == 0 {
+		panic(fail("SHA1"))
+	}
+}
+
+func SHA224Into(dst, p []byte) {
+	_ = dst[28-1]
+	countOp(cryptometrics.SHA224, len(p))
+	if C._goboringcrypto_gosha224(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(dst))) == 0 {
+		panic(fail("SHA224"))
+	}
+}
+
+func SHA256Into(dst, p []byte) {
+	_ = dst[32-1]
+	countOp(cryptometrics.SHA256, len(p))
+	if C._goboringcrypto_gosha256(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(dst))) == 0 {
+		panic(fail("SHA256"))
+	}
+}
+
+func SHA384Into(dst, p []byte) {
+	_ = dst[48-1]
+	countOp(cryptometrics.SHA384, len(p))
+	if C._goboringcrypto_gosha384(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(dst))) == 0 {
+		panic(fail("SHA384"))
+	}
+}
+
+func SHA512Into(dst, p []byte) {
+	_ = dst[64-1]
+	countOp(cryptometrics.SHA512, len(p))
+	if C._goboringcrypto_gosha512(unsafe.Pointer(&*addr(p)), C.size_t(len(p)), unsafe.Pointer(&*addr(dst))) == 0 {
+		panic(fail("SHA512"))
+	}
+}
+
+// The initial contexts of the hashes, set up once by the module, so that
+// Reset is a copy rather than a cgo call, and reusing a hash, for example
+// from a sync.Pool, costs less than allocating a new one.
+var (
+	sha1Init       C.GO_SHA_CTX
+	sha224Init     C.GO_SHA256_CTX
+	sha256Init     C.GO_SHA256_CTX
+	sha384Init     C.GO_SHA512_CTX
+	sha512Init     C.GO_SHA512_CTX
+	sha512_224Init C.GO_SHA512_CTX
+	sha512_256Init C.GO_SHA512_CTX
+)
+
+// initSHA fills in the initial contexts. It is called during
+// initialization, before the hashes are first used by runCASTs.
+func initSHA() {
+	C._goboringcrypto_SHA1_Init(&sha1Init)
+	C._goboringcrypto_SHA224_Init(&sha224Init)
+	C._goboringcrypto_SHA256_Init(&sha256Init)
+	C._goboringcrypto_SHA384_Init(&sha384Init)
+	C._goboringcrypto_SHA512_Init(&sha512Init)
+	C._goboringcrypto_SHA512_Init(&sha512_224Init)
+	(*sha512Ctx)(unsafe.Pointer(&sha512_224Init)).h = sha512_224IV
+	C._goboringcrypto_SHA512_Init(&sha512_256Init)
+	(*sha512Ctx)(unsafe.Pointer(&sha512_256Init)).h = sha512_256IV
+}
+
 // NewSHA1 returns a new SHA1 hash.
 func NewSHA1() hash.Hash {
 	h := new(sha1Hash)
@@ -117,6 +304,7 @@ func NewSHA1() hash.Hash {
 type sha1Hash struct {
 	ctx C.GO_SHA_CTX
 	out [20]byte
+	wb  writeBuffer
 }
 
 type sha1Ctx struct {
@@ -131,7 +319,8 @@ func (h *sha1Hash) noescapeCtx() *C.GO_SHA_CTX {
 }
 
 func (h *sha1Hash) Reset() {
-	C._goboringcrypto_SHA1_Init(h.noescapeCtx())
+	h.wb.n = 0
+	h.ctx = sha1Init
 }
 
 func (h *sha1Hash) Size() int             { return 20 }
@@ -139,30 +328,66 @@ func (h *sha1Hash) BlockSize() int        { return 64 }
 func (h *sha1Hash) Sum(dst []byte) []byte { return h.sum(dst) }
 
 func (h *sha1Hash) Write(p []byte) (int, error) {
-	if len(p) > 0 && C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
-		panic("boringcrypto: SHA1_Update failed")
+	countBytes(cryptometrics.SHA1, len(p))
+	if !bufferWrite(&h.wb, p, 64) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 64) && len(p) > 0 && C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA1_Update"))
+		}
 	}
 	return len(p), nil
 }
 
 func (h *sha1Hash) WriteString(s string) (int, error) {
-	if len(s) > 0 && C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
-		panic("boringcrypto: SHA1_Update failed")
+	countBytes(cryptometrics.SHA1, len(s))
+	if !bufferWrite(&h.wb, s, 64) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 64) && len(s) > 0 && C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA1_Update"))
+		}
 	}
 	return len(s), nil
 }
 
 func (h *sha1Hash) WriteByte(c byte) error {
-	if C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
-		panic("boringcrypto: SHA1_Update failed")
+	countBytes(cryptometrics.SHA1, 1)
+	if !h.wb.addByte(c, 64) {
+		h.flush()
+		if !h.wb.addByte(c, 64) && C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA1_Update"))
+		}
 	}
 	return nil
 }
 
+func (h *sha1Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA1_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA1_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha1Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha1Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha1Hash) Wipe() {
+	*h = sha1Hash{}
+	h.Reset()
+}
+
 func (h0 *sha1Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA1, 0)
 	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
 	if C._goboringcrypto_SHA1_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
-		panic("boringcrypto: SHA1_Final failed")
+		panic(fail("SHA1_Final"))
 	}
 	return append(dst, h.out[:]...)
 }
@@ -173,8 +398,12 @@ const (
 )
 
 func (h *sha1Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, sha1MarshaledSize))
+}
+
+func (h *sha1Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
 	d := (*sha1Ctx)(unsafe.Pointer(&h.ctx))
-	b := make([]byte, 0, sha1MarshaledSize)
 	b = append(b, sha1Magic...)
 	b = appendUint32(b, d.h[0])
 	b = appendUint32(b, d.h[1])
@@ -182,7 +411,7 @@ func (h *sha1Hash) MarshalBinary() ([]byte, error) {
 	b = appendUint32(b, d.h[3])
 	b = appendUint32(b, d.h[4])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-int(d.nx)] // already zero
+	b = append(b, make([]byte, len(d.x)-int(d.nx))...)
 	b = appendUint64(b, uint64(d.nl)>>3|uint64(d.nh)<<29)
 	return b, nil
 }
@@ -194,6 +423,7 @@ func (h *sha1Hash) UnmarshalBinary(b []byte) error {
 	if len(b) != sha1MarshaledSize {
 		return errors.New("crypto/sha1: invalid hash state size")
 	}
+	h.wb.n = 0
 	d := (*sha1Ctx)(unsafe.Pointer(&h.ctx))
 	b = b[len(sha1Magic):]
 	b, d.h[0] = consumeUint32(b)
@@ -219,6 +449,7 @@ func NewSHA224() hash.Hash {
 type sha224Hash struct {
 	ctx C.GO_SHA256_CTX
 	out [224 / 8]byte
+	wb  writeBuffer
 }
 
 func (h *sha224Hash) noescapeCtx() *C.GO_SHA256_CTX {
@@ -226,23 +457,74 @@ func (h *sha224Hash) noescapeCtx() *C.GO_SHA256_CTX {
 }
 
 func (h *sha224Hash) Reset() {
-	C._goboringcrypto_SHA224_Init(h.noescapeCtx())
+	h.wb.n = 0
+	h.ctx = sha224Init
 }
 func (h *sha224Hash) Size() int             { return 224 / 8 }
 func (h *sha224Hash) BlockSize() int        { return 64 }
 func (h *sha224Hash) Sum(dst []byte) []byte { return h.sum(dst) }
 
 func (h *sha224Hash) Write(p []byte) (int, error) {
-	if len(p) > 0 && C._goboringcrypto_SHA224_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
-		panic("boringcrypto: SHA224_Update failed")
+	countBytes(cryptometrics.SHA224, len(p))
+	if !bufferWrite(&h.wb, p, 64) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 64) && len(p) > 0 && C._goboringcrypto_SHA224_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA224_Update"))
+		}
 	}
 	return len(p), nil
 }
 
+func (h *sha224Hash) WriteString(s string) (int, error) {
+	countBytes(cryptometrics.SHA224, len(s))
+	if !bufferWrite(&h.wb, s, 64) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 64) && len(s) > 0 && C._goboringcrypto_SHA224_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA224_Update"))
+		}
+	}
+	return len(s), nil
+}
+
+func (h *sha224Hash) WriteByte(c byte) error {
+	countBytes(cryptometrics.SHA224, 1)
+	if !h.wb.addByte(c, 64) {
+		h.flush()
+		if !h.wb.addByte(c, 64) && C._goboringcrypto_SHA224_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA224_Update"))
+		}
+	}
+	return nil
+}
+
+func (h *sha224Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA224_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA224_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha224Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha224Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha224Hash) Wipe() {
+	*h = sha224Hash{}
+	h.Reset()
+}
+
 func (h0 *sha224Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA224, 0)
 	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
 	if C._goboringcrypto_SHA224_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
-		panic("boringcrypto: SHA224_Final failed")
+		panic(fail("SHA224_Final"))
 	}
 	return append(dst, h.out[:]...)
 }
@@ -257,6 +539,7 @@ func NewSHA256() hash.Hash {
 type sha256Hash struct {
 	ctx C.GO_SHA256_CTX
 	out [256 / 8]byte
+	wb  writeBuffer
 }
 
 func (h *sha256Hash) noescapeCtx() *C.GO_SHA256_CTX {
@@ -264,37 +547,74 @@ func (h *sha256Hash) noescapeCtx() *C.GO_SHA256_CTX {
 }
 
 func (h *sha256Hash) Reset() {
-	C._goboringcrypto_SHA256_Init(h.noescapeCtx())
+	h.wb.n = 0
+	h.ctx = sha256Init
 }
 func (h *sha256Hash) Size() int             { return 256 / 8 }
 func (h *sha256Hash) BlockSize() int        { return 64 }
 func (h *sha256Hash) Sum(dst []byte) []byte { return h.sum(dst) }
 
 func (h *sha256Hash) Write(p []byte) (int, error) {
-	if len(p) > 0 && C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
-		panic("boringcrypto: SHA256_Update failed")
+	countBytes(cryptometrics.SHA256, len(p))
+	if !bufferWrite(&h.wb, p, 64) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 64) && len(p) > 0 && C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA256_Update"))
+		}
 	}
 	return len(p), nil
 }
 
 func (h *sha256Hash) WriteString(s string) (int, error) {
-	if len(s) > 0 && C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
-		panic("boringcrypto: SHA256_Update failed")
+	countBytes(cryptometrics.SHA256, len(s))
+	if !bufferWrite(&h.wb, s, 64) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 64) && len(s) > 0 && C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA256_Update"))
+		}
 	}
 	return len(s), nil
 }
 
 func (h *sha256Hash) WriteByte(c byte) error {
-	if C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
-		panic("boringcrypto: SHA256_Update failed")
+	countBytes(cryptometrics.SHA256, 1)
+	if !h.wb.addByte(c, 64) {
+		h.flush()
+		if !h.wb.addByte(c, 64) && C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA256_Update"))
+		}
 	}
 	return nil
 }
 
+func (h *sha256Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA256_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA256_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha256Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha256Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha256Hash) Wipe() {
+	*h = sha256Hash{}
+	h.Reset()
+}
+
 func (h0 *sha256Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA256, 0)
 	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
 	if C._goboringcrypto_SHA256_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
-		panic("boringcrypto: SHA256_Final failed")
+		panic(fail("SHA256_Final"))
 	}
 	return append(dst, h.out[:]...)
 }
@@ -313,8 +633,12 @@ type sha256Ctx struct {
 }
 
 func (h *sha224Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, marshaledSize256))
+}
+
+func (h *sha224Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
 	d := (*sha256Ctx)(unsafe.Pointer(&h.ctx))
-	b := make([]byte, 0, marshaledSize256)
 	b = append(b, magic224...)
 	b = appendUint32(b, d.h[0])
 	b = appendUint32(b, d.h[1])
@@ -325,14 +649,18 @@ func (h *sha224Hash) MarshalBinary() ([]byte, error) {
 	b = appendUint32(b, d.h[6])
 	b = appendUint32(b, d.h[7])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-int(d.nx)] // already zero
+	b = append(b, make([]byte, len(d.x)-int(d.nx))...)
 	b = appendUint64(b, uint64(d.nl)>>3|uint64(d.nh)<<29)
 	return b, nil
 }
 
 func (h *sha256Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, marshaledSize256))
+}
+
+func (h *sha256Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
 	d := (*sha256Ctx)(unsafe.Pointer(&h.ctx))
-	b := make([]byte, 0, marshaledSize256)
 	b = append(b, magic256...)
 	b = appendUint32(b, d.h[0])
 	b = appendUint32(b, d.h[1])
@@ -343,7 +671,7 @@ func (h *sha256Hash) MarshalBinary() ([]byte, error) {
 	b = appendUint32(b, d.h[6])
 	b = appendUint32(b, d.h[7])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-int(d.nx)] // already zero
+	b = append(b, make([]byte, len(d.x)-int(d.nx))...)
 	b = appendUint64(b, uint64(d.nl)>>3|uint64(d.nh)<<29)
 	return b, nil
 }
@@ -355,6 +683,7 @@ func (h *sha224Hash) UnmarshalBinary(b []byte) error {
 	if len(b) != marshaledSize256 {
 		return errors.New("crypto/sha256: invalid hash state size")
 	}
+	h.wb.n = 0
 	d := (*sha256Ctx)(unsafe.Pointer(&h.ctx))
 	b = b[len(magic224):]
 	b, d.h[0] = consumeUint32(b)
@@ -380,6 +709,7 @@ func (h *sha256Hash) UnmarshalBinary(b []byte) error {
 	if len(b) != marshaledSize256 {
 		return errors.New("crypto/sha256: invalid hash state size")
 	}
+	h.wb.n = 0
 	d := (*sha256Ctx)(unsafe.Pointer(&h.ctx))
 	b = b[len(magic256):]
 	b, d.h[0] = consumeUint32(b)
@@ -408,6 +738,7 @@ func NewSHA384() hash.Hash {
 type sha384Hash struct {
 	ctx C.GO_SHA512_CTX
 	out [384 / 8]byte
+	wb  writeBuffer
 }
 
 func (h *sha384Hash) noescapeCtx() *C.GO_SHA512_CTX {
@@ -415,37 +746,74 @@ func (h *sha384Hash) noescapeCtx() *C.GO_SHA512_CTX {
 }
 
 func (h *sha384Hash) Reset() {
-	C._goboringcrypto_SHA384_Init(h.noescapeCtx())
+	h.wb.n = 0
+	h.ctx = sha384Init
 }
 func (h *sha384Hash) Size() int             { return 384 / 8 }
 func (h *sha384Hash) BlockSize() int        { return 128 }
 func (h *sha384Hash) Sum(dst []byte) []byte { return h.sum(dst) }
 
 func (h *sha384Hash) Write(p []byte) (int, error) {
-	if len(p) > 0 && C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
-		panic("boringcrypto: SHA384_Update failed")
+	countBytes(cryptometrics.SHA384, len(p))
+	if !bufferWrite(&h.wb, p, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 128) && len(p) > 0 && C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA384_Update"))
+		}
 	}
 	return len(p), nil
 }
 
 func (h *sha384Hash) WriteString(s string) (int, error) {
-	if len(s) > 0 && C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
-		panic("boringcrypto: SHA384_Update failed")
+	countBytes(cryptometrics.SHA384, len(s))
+	if !bufferWrite(&h.wb, s, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 128) && len(s) > 0 && C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA384_Update"))
+		}
 	}
 	return len(s), nil
 }
 
 func (h *sha384Hash) WriteByte(c byte) error {
-	if C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
-		panic("boringcrypto: SHA384_Update failed")
+	countBytes(cryptometrics.SHA384, 1)
+	if !h.wb.addByte(c, 128) {
+		h.flush()
+		if !h.wb.addByte(c, 128) && C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA384_Update"))
+		}
 	}
 	return nil
 }
 
+func (h *sha384Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA384_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA384_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha384Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha384Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha384Hash) Wipe() {
+	*h = sha384Hash{}
+	h.Reset()
+}
+
 func (h0 *sha384Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA384, 0)
 	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
 	if C._goboringcrypto_SHA384_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
-		panic("boringcrypto: SHA384_Final failed")
+		panic(fail("SHA384_Final"))
 	}
 	return append(dst, h.out[:]...)
 }
@@ -460,6 +828,7 @@ func NewSHA512() hash.Hash {
 type sha512Hash struct {
 	ctx C.GO_SHA512_CTX
 	out [512 / 8]byte
+	wb  writeBuffer
 }
 
 func (h *sha512Hash) noescapeCtx() *C.GO_SHA512_CTX {
@@ -467,41 +836,328 @@ func (h *sha512Hash) noescapeCtx() *C.GO_SHA512_CTX {
 }
 
 func (h *sha512Hash) Reset() {
-	C._goboringcrypto_SHA512_Init(h.noescapeCtx())
+	h.wb.n = 0
+	h.ctx = sha512Init
 }
 func (h *sha512Hash) Size() int             { return 512 / 8 }
 func (h *sha512Hash) BlockSize() int        { return 128 }
 func (h *sha512Hash) Sum(dst []byte) []byte { return h.sum(dst) }
 
 func (h *sha512Hash) Write(p []byte) (int, error) {
-	if len(p) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
-		panic("boringcrypto: SHA512_Update failed")
+	countBytes(cryptometrics.SHA512, len(p))
+	if !bufferWrite(&h.wb, p, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 128) && len(p) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA512_Update"))
+		}
 	}
 	return len(p), nil
 }
 
 func (h *sha512Hash) WriteString(s string) (int, error) {
-	if len(s) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
-		panic("boringcrypto: SHA512_Update failed")
+	countBytes(cryptometrics.SHA512, len(s))
+	if !bufferWrite(&h.wb, s, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 128) && len(s) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA512_Update"))
+		}
 	}
 	return len(s), nil
 }
 
 func (h *sha512Hash) WriteByte(c byte) error {
-	if C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
-		panic("boringcrypto: SHA512_Update failed")
+	countBytes(cryptometrics.SHA512, 1)
+	if !h.wb.addByte(c, 128) {
+		h.flush()
+		if !h.wb.addByte(c, 128) && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA512_Update"))
+		}
 	}
 	return nil
 }
 
+func (h *sha512Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA512_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha512Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha512Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha512Hash) Wipe() {
+	*h = sha512Hash{}
+	h.Reset()
+}
+
 func (h0 *sha512Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA512, 0)
 	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
 	if C._goboringcrypto_SHA512_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
-		panic("boringcrypto: SHA512_Final failed")
+		panic(fail("SHA512_Final"))
 	}
 	return append(dst, h.out[:]...)
 }
 
+// The initial hash values of SHA-512/224 and SHA-512/256,
+// from FIPS 180-4, Sections 5.3.6.1 and 5.3.6.2.
+var (
+	sha512_224IV = [8]uint64{
+		0x8c3d37c819544da2, 0x73e1996689dcd4d6, 0x1dfab7ae32ff9c82, 0x679dd514582f9fcf,
+		0x0f6d2b697bd44da8, 0x77e36f7304c48942, 0x3f9d85a86a1d36c8, 0x1112e6ad91d692a1,
+	}
+	sha512_256IV = [8]uint64{
+		0x22312194fc2bf72c, 0x9f555fa3c84c64c2, 0x2393b86b6f53b151, 0x963877195940eabd,
+		0x96283ee2a88effe3, 0xbe5e1e2553863992, 0x2b0199fc2c85b8aa, 0x0eb72ddc81c52ca2,
+	}
+)
+
+// NewSHA512_224 returns a new SHA512/224 hash.
+func NewSHA512_224() hash.Hash {
+	h := new(sha512_224Hash)
+	h.Reset()
+	return h
+}
+
+type sha512_224Hash struct {
+	ctx C.GO_SHA512_CTX
+	out [512 / 8]byte
+	wb  writeBuffer
+}
+
+func (h *sha512_224Hash) noescapeCtx() *C.GO_SHA512_CTX {
+	return (*C.GO_SHA512_CTX)(noescape(unsafe.Pointer(&h.ctx)))
+}
+
+func (h *sha512_224Hash) Reset() {
+	h.wb.n = 0
+	h.ctx = sha512_224Init
+}
+func (h *sha512_224Hash) Size() int             { return 224 / 8 }
+func (h *sha512_224Hash) BlockSize() int        { return 128 }
+func (h *sha512_224Hash) Sum(dst []byte) []byte { return h.sum(dst) }
+
+func (h *sha512_224Hash) Write(p []byte) (int, error) {
+	countBytes(cryptometrics.SHA512_224, len(p))
+	if !bufferWrite(&h.wb, p, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 128) && len(p) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA512_Update"))
+		}
+	}
+	return len(p), nil
+}
+
+func (h *sha512_224Hash) WriteString(s string) (int, error) {
+	countBytes(cryptometrics.SHA512_224, len(s))
+	if !bufferWrite(&h.wb, s, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 128) && len(s) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA512_Update"))
+		}
+	}
+	return len(s), nil
+}
+
+func (h *sha512_224Hash) WriteByte(c byte) error {
+	countBytes(cryptometrics.SHA512_224, 1)
+	if !h.wb.addByte(c, 128) {
+		h.flush()
+		if !h.wb.addByte(c, 128) && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA512_Update"))
+		}
+	}
+	return nil
+}
+
+func (h *sha512_224Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA512_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha512_224Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha512_224Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha512_224Hash) Wipe() {
+	*h = sha512_224Hash{}
+	h.Reset()
+}
+
+func (h0 *sha512_224Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA512_224, 0)
+	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
+	if C._goboringcrypto_SHA512_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
+		panic(fail("SHA512_Final"))
+	}
+	return append(dst, h.out[:224/8]...)
+}
+
+// NewSHA512_256 returns a new SHA512/256 hash.
+func NewSHA512_256() hash.Hash {
+	h := new(sha512_256Hash)
+	h.Reset()
+	return h
+}
+
+type sha512_256Hash struct {
+	ctx C.GO_SHA512_CTX
+	out [512 / 8]byte
+	wb  writeBuffer
+}
+
+func (h *sha512_256Hash) noescapeCtx() *C.GO_SHA512_CTX {
+	return (*C.GO_SHA512_CTX)(noescape(unsafe.Pointer(&h.ctx)))
+}
+
+func (h *sha512_256Hash) Reset() {
+	h.wb.n = 0
+	h.ctx = sha512_256Init
+}
+func (h *sha512_256Hash) Size() int             { return 256 / 8 }
+func (h *sha512_256Hash) BlockSize() int        { return 128 }
+func (h *sha512_256Hash) Sum(dst []byte) []byte { return h.sum(dst) }
+
+func (h *sha512_256Hash) Write(p []byte) (int, error) {
+	countBytes(cryptometrics.SHA512_256, len(p))
+	if !bufferWrite(&h.wb, p, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, p, 128) && len(p) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(p)), C.size_t(len(p))) == 0 {
+			panic(fail("SHA512_Update"))
+		}
+	}
+	return len(p), nil
+}
+
+func (h *sha512_256Hash) WriteString(s string) (int, error) {
+	countBytes(cryptometrics.SHA512_256, len(s))
+	if !bufferWrite(&h.wb, s, 128) {
+		h.flush()
+		if !bufferWrite(&h.wb, s, 128) && len(s) > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(unsafe.StringData(s)), C.size_t(len(s))) == 0 {
+			panic(fail("SHA512_Update"))
+		}
+	}
+	return len(s), nil
+}
+
+func (h *sha512_256Hash) WriteByte(c byte) error {
+	countBytes(cryptometrics.SHA512_256, 1)
+	if !h.wb.addByte(c, 128) {
+		h.flush()
+		if !h.wb.addByte(c, 128) && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&c), 1) == 0 {
+			panic(fail("SHA512_Update"))
+		}
+	}
+	return nil
+}
+
+func (h *sha512_256Hash) flush() {
+	if h.wb.n > 0 && C._goboringcrypto_SHA512_Update(h.noescapeCtx(), unsafe.Pointer(&*addr(h.wb.b[:h.wb.n])), C.size_t(h.wb.n)) == 0 {
+		panic(fail("SHA512_Update"))
+	}
+	h.wb.n = 0
+}
+
+func (h *sha512_256Hash) ReadFrom(r io.Reader) (int64, error) { return readFrom(h, r) }
+
+// Clone returns an independent copy of h.
+func (h *sha512_256Hash) Clone() (hash.Hash, error) {
+	c := *h // the context holds no pointers
+	return &c, nil
+}
+
+// Wipe zeroes h, including the input that Reset leaves buffered, and
+// resets it.
+func (h *sha512_256Hash) Wipe() {
+	*h = sha512_256Hash{}
+	h.Reset()
+}
+
+func (h0 *sha512_256Hash) sum(dst []byte) []byte {
+	countOp(cryptometrics.SHA512_256, 0)
+	h := *h0 // make copy so future Write+Sum is valid
+	h.flush()
+	if C._goboringcrypto_SHA512_Final((*C.uint8_t)(noescape(unsafe.Pointer(&h.out[0]))), h.noescapeCtx()) == 0 {
+		panic(fail("SHA512_Final"))
+	}
+	return append(dst, h.out[:256/8]...)
+}
+
+// boringbuffer=0 disables the buffering of small writes to hashes.
+var boringbuffer = godebug.New("boringbuffer")
+
+// A writeBuffer collects small writes to a hash, so that they are passed to
+// BoringCrypto in a single cgo call, which costs much more than copying them.
+type writeBuffer struct {
+	n int
+	b [128]byte // large enough for the block size of every hash
+}
+
+// bufferWrite appends p to w, and reports whether it did. The buffer holds
+// at most size bytes, the block size of the hash, and writes of that size
+// or larger are not buffered. If bufferWrite returns false, the caller must
+// flush w and call bufferWrite again, then write p directly if it returns
+// false again.
+func bufferWrite[S []byte | string](w *writeBuffer, p S, size int) bool {
+	if len(p) >= size || w.n+len(p) > size || boringbuffer.Value() == "0" {
+		return false
+	}
+	w.n += copy(w.b[w.n:], p)
+	return true
+}
+
+// addByte is like bufferWrite for a single byte.
+func (w *writeBuffer) addByte(c byte, size int) bool {
+	if w.n == size || boringbuffer.Value() == "0" {
+		return false
+	}
+	w.b[w.n] = c
+	w.n++
+	return true
+}
+
+// readBufPool holds the buffers used by readFrom.
+var readBufPool = sync.Pool{
+	New: func() any { return new([64 << 10]byte) },
+}
+
+// readFrom implements ReadFrom for the hashes. It reads r into a large
+// buffer before writing it to h, so that hashing a stream with io.Copy
+// makes one cgo call per 64 kB, rather than one per read.
+func readFrom(h io.Writer, r io.Reader) (n int64, err error) {
+	buf := readBufPool.Get().(*[64 << 10]byte)
+	defer readBufPool.Put(buf)
+	for {
+		m, err := io.ReadFull(r, buf[:])
+		h.Write(buf[:m])
+		n += int64(m)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
 type sha512Ctx struct {
 	h      [8]uint64
 	nl, nh uint64
@@ -518,8 +1174,12 @@ const (
 )
 
 func (h *sha384Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, marshaledSize512))
+}
+
+func (h *sha384Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
 	d := (*sha512Ctx)(unsafe.Pointer(&h.ctx))
-	b := make([]byte, 0, marshaledSize512)
 	b = append(b, magic384...)
 	b = appendUint64(b, d.h[0])
 	b = appendUint64(b, d.h[1])
@@ -530,14 +1190,18 @@ func (h *sha384Hash) MarshalBinary() ([]byte, error) {
 	b = appendUint64(b, d.h[6])
 	b = appendUint64(b, d.h[7])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-int(d.nx)] // already zero
+	b = append(b, make([]byte, len(d.x)-int(d.nx))...)
 	b = appendUint64(b, d.nl>>3|d.nh<<61)
 	return b, nil
 }
 
 func (h *sha512Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, marshaledSize512))
+}
+
+func (h *sha512Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
 	d := (*sha512Ctx)(unsafe.Pointer(&h.ctx))
-	b := make([]byte, 0, marshaledSize512)
 	b = append(b, magic512...)
 	b = appendUint64(b, d.h[0])
 	b = appendUint64(b, d.h[1])
@@ -548,7 +1212,7 @@ func (h *sha512Hash) MarshalBinary() ([]byte, error) {
 	b = appendUint64(b, d.h[6])
 	b = appendUint64(b, d.h[7])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-int(d.nx)] // already zero
+	b = append(b, make([]byte, len(d.x)-int(d.nx))...)
 	b = appendUint64(b, d.nl>>3|d.nh<<61)
 	return b, nil
 }
@@ -563,6 +1227,7 @@ func (h *sha384Hash) UnmarshalBinary(b []byte) error {
 	if len(b) != marshaledSize512 {
 		return errors.New("crypto/sha512: invalid hash state size")
 	}
+	h.wb.n = 0
 	d := (*sha512Ctx)(unsafe.Pointer(&h.ctx))
 	b = b[len(magic512):]
 	b, d.h[0] = consumeUint64(b)
@@ -591,6 +1256,7 @@ func (h *sha512Hash) UnmarshalBinary(b []byte) error {
 	if len(b) != marshaledSize512 {
 		return errors.New("crypto/sha512: invalid hash state size")
 	}
+	h.wb.n = 0
 	d := (*sha512Ctx)(unsafe.Pointer(&h.ctx))
 	b = b[len(magic512):]
 	b, d.h[0] = consumeUint64(b)
@@ -609,6 +1275,72 @@ func (h *sha512Hash) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+func (h *sha512_224Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, marshaledSize512))
+}
+
+func (h *sha512_224Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
+	return appendSHA512(b, magic512_224, (*sha512Ctx)(unsafe.Pointer(&h.ctx))), nil
+}
+
+func (h *sha512_256Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, marshaledSize512))
+}
+
+func (h *sha512_256Hash) AppendBinary(b []byte) ([]byte, error) {
+	h.flush()
+	return appendSHA512(b, magic512_256, (*sha512Ctx)(unsafe.Pointer(&h.ctx))), nil
+}
+
+func (h *sha512_224Hash) UnmarshalBinary(b []byte) error {
+	if err := unmarshalSHA512(magic512_224, (*sha512Ctx)(unsafe.Pointer(&h.ctx)), b); err != nil {
+		return err
+	}
+	h.wb.n = 0
+	return nil
+}
+
+func (h *sha512_256Hash) UnmarshalBinary(b []byte) error {
+	if err := unmarshalSHA512(magic512_256, (*sha512Ctx)(unsafe.Pointer(&h.ctx)), b); err != nil {
+		return err
+	}
+	h.wb.n = 0
+	return nil
+}
+
+// appendSHA512 and unmarshalSHA512 implement AppendBinary and
+// UnmarshalBinary for the SHA-512/t hashes, which share the state of SHA-512.
+func appendSHA512(b []byte, magic string, d *sha512Ctx) []byte {
+	b = append(b, magic...)
+	for _, x := range d.h {
+		b = appendUint64(b, x)
+	}
+	b = append(b, d.x[:d.nx]...)
+	b = append(b, make([]byte, len(d.x)-int(d.nx))...)
+	b = appendUint64(b, d.nl>>3|d.nh<<61)
+	return b
+}
+
+func unmarshalSHA512(magic string, d *sha512Ctx, b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("crypto/sha512: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize512 {
+		return errors.New("crypto/sha512: invalid hash state size")
+	}
+	b = b[len(magic):]
+	for i := range d.h {
+		b, d.h[i] = consumeUint64(b)
+	}
+	b = b[copy(d.x[:], b):]
+	b, n := consumeUint64(b)
+	d.nl = n << 3
+	d.nh = n >> 61
+	d.nx = uint32(n) % 128
+	return nil
+}
+
 func appendUint64(b []byte, x uint64) []byte {
 	var a [8]byte
 	putUint64(a[:], x)
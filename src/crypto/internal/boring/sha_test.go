@@ -0,0 +1,178 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo
+
+package boring
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var shaHashes = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"SHA1", NewSHA1},
+	{"SHA224", NewSHA224},
+	{"SHA256", NewSHA256},
+	{"SHA384", NewSHA384},
+	{"SHA512", NewSHA512},
+	{"SHA512_224", NewSHA512_224},
+	{"SHA512_256", NewSHA512_256},
+}
+
+func TestSHAClone(t *testing.T) {
+	for _, tt := range shaHashes {
+		h := tt.new()
+		h.Write([]byte("hello"))
+		c, err := h.(interface{ Clone() (hash.Hash, error) }).Clone()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		c.Write([]byte(" world"))
+
+		want := tt.new()
+		want.Write([]byte("hello"))
+		if sum := h.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+			t.Errorf("%s: original Sum after writing to clone = %x, want %x", tt.name, sum, want.Sum(nil))
+		}
+		want.Write([]byte(" world"))
+		if sum := c.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+			t.Errorf("%s: clone Sum = %x, want %x", tt.name, sum, want.Sum(nil))
+		}
+	}
+}
+
+func TestSHAReadFrom(t *testing.T) {
+	data := strings.Repeat("gopher", 30000)
+	for _, tt := range shaHashes {
+		want := tt.new()
+		io.WriteString(want, data)
+
+		h := tt.new()
+		n, err := io.Copy(h, iotest.HalfReader(strings.NewReader(data)))
+		if n != int64(len(data)) || err != nil {
+			t.Fatalf("%s: io.Copy = %d, %v", tt.name, n, err)
+		}
+		if _, ok := h.(io.ReaderFrom); !ok {
+			t.Errorf("%s: not an io.ReaderFrom", tt.name)
+		}
+		if sum := h.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+			t.Errorf("%s: Sum = %x, want %x", tt.name, sum, want.Sum(nil))
+		}
+
+		errRead := errors.New("read error")
+		r := io.MultiReader(strings.NewReader(data[:100]), iotest.ErrReader(errRead))
+		if n, err := tt.new().(io.ReaderFrom).ReadFrom(r); n != 100 || err != errRead {
+			t.Errorf("%s: ReadFrom = %d, %v, want 100, %v", tt.name, n, err, errRead)
+		}
+	}
+}
+
+func TestSHAWriteBuffer(t *testing.T) {
+	data := make([]byte, 2000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	for _, godebug := range []string{"", "boringbuffer=0"} {
+		t.Setenv("GODEBUG", godebug)
+		for _, tt := range shaHashes {
+			want := tt.new()
+			want.Write(data)
+
+			// Write fragments of growing sizes, in every way, and check that
+			// Sum and MarshalBinary see the buffered data.
+			h := tt.new()
+			for i, n := 0, 0; i < len(data); i, n = i+n, n+1 {
+				p := data[i:]
+				if len(p) > n {
+					p = p[:n]
+				}
+				switch n % 3 {
+				case 0:
+					h.Write(p)
+				case 1:
+					io.WriteString(h, string(p))
+				case 2:
+					for _, c := range p {
+						h.(io.ByteWriter).WriteByte(c)
+					}
+				}
+				if n == 10 {
+					h.Sum(nil)
+				}
+			}
+			state, err := h.(interface{ MarshalBinary() ([]byte, error) }).MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum := h.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+				t.Errorf("%s %s: Sum = %x, want %x", godebug, tt.name, sum, want.Sum(nil))
+			}
+
+			h2 := tt.new()
+			h2.Write([]byte("discarded by UnmarshalBinary"))
+			if err := h2.(interface{ UnmarshalBinary([]byte) error }).UnmarshalBinary(state); err != nil {
+				t.Fatal(err)
+			}
+			if sum := h2.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+				t.Errorf("%s %s: Sum after UnmarshalBinary = %x, want %x", godebug, tt.name, sum, want.Sum(nil))
+			}
+			h2.Write([]byte("discarded by Reset"))
+			h2.Reset()
+			h2.Write(data)
+			if sum := h2.Sum(nil); !bytes.Equal(sum, want.Sum(nil)) {
+				t.Errorf("%s %s: Sum after Reset = %x, want %x", godebug, tt.name, sum, want.Sum(nil))
+			}
+		}
+	}
+}
+
+func TestSHAResetAllocs(t *testing.T) {
+	data := []byte("reused through a sync.Pool")
+	out := make([]byte, 0, 64)
+	for _, tt := range shaHashes {
+		h := tt.new()
+		fresh := tt.new()
+		fresh.Write(data)
+		h.Write([]byte("previous use"))
+		if n := testing.AllocsPerRun(10, func() {
+			h.Reset()
+			h.Write(data)
+			h.Sum(out[:0])
+		}); n > 0 {
+			t.Errorf("%s: Reset, Write, and Sum allocate %v times, want 0", tt.name, n)
+		}
+		if sum := h.Sum(nil); !bytes.Equal(sum, fresh.Sum(nil)) {
+			t.Errorf("%s: Sum after Reset = %x, want %x", tt.name, sum, fresh.Sum(nil))
+		}
+	}
+}
+
+func TestSHAWipe(t *testing.T) {
+	for _, tt := range shaHashes {
+		h := tt.new()
+		h.Write([]byte("secret"))
+		h.(interface{ Wipe() }).Wipe()
+		if sum, want := h.Sum(nil), tt.new().Sum(nil); !bytes.Equal(sum, want) {
+			t.Errorf("%s: Sum after Wipe = %x, want %x", tt.name, sum, want)
+		}
+	}
+
+	// Reset only discards the buffered input, while Wipe zeroes it.
+	h := NewSHA256().(*sha256Hash)
+	h.Write([]byte("secret"))
+	h.Sum(nil)
+	h.Wipe()
+	if h.wb.b != [len(h.wb.b)]byte{} || h.out != [len(h.out)]byte{} {
+		t.Errorf("buffers not zeroed by Wipe: %x, %x", h.wb.b, h.out)
+	}
+}
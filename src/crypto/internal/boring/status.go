@@ -0,0 +1,90 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package boring
+
+// ModuleName and ModuleVersion identify the cryptographic module in use:
+// the BoringCrypto module linked into Go+BoringCrypto binaries, where
+// ModuleVersion is the BoringSSL tag the syso files are built from (see
+// Dockerfile), with GOEXPERIMENT=opensslcrypto, the system libcrypto,
+// where ModuleVersion is the version string of the loaded library, with
+// GOEXPERIMENT=cngcrypto, Windows CNG, where ModuleVersion is the version
+// of Windows, or, with GOEXPERIMENT=darwincrypto, CommonCrypto and
+// Security.framework, where ModuleVersion is the version of macOS.
+var (
+	ModuleName    = "BoringCrypto"
+	ModuleVersion = "fips-20210429"
+)
+
+// SelfTestPassed reports whether the module's power-on self-test passed.
+// The self-test runs during package initialization and aborts the program
+// on failure, so it passed whenever BoringCrypto is available.
+const SelfTestPassed = available
+
+// A CASTResult is the outcome of one of the conditional algorithm
+// self-tests (CASTs) that run during package initialization, after the
+// power-on self-test, when BoringCrypto is available.
+type CASTResult struct {
+	Algorithm string // as in Route.Name
+	Vector    string
+	Err       error
+}
+
+// CASTResults lists the outcome of the CASTs, in the order they ran.
+// It is empty when BoringCrypto is not available.
+var CASTResults []CASTResult
+
+// CASTPassed reports whether all the CASTs passed.
+func CASTPassed() bool {
+	for _, r := range CASTResults {
+		if r.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Routing values for Route.Routing.
+const (
+	RouteGo      = iota // always handled by the pure Go implementation
+	RouteModule         // always handled by BoringCrypto
+	RoutePartial        // handled by BoringCrypto subject to Route.Condition
+)
+
+// A Route describes which implementation handles an algorithm
+// when BoringCrypto is available.
+type Route struct {
+	Name      string
+	Routing   int
+	Condition string
+}
+
+// Routes lists the algorithms implemented by the standard library and
+// how they are routed when BoringCrypto is available. It must be kept in
+// sync with the boring.Enabled checks in the crypto packages.
+var Routes = []Route{
+	{"AES", RouteModule, ""},
+	{"AES-CBC", RouteModule, ""},
+	{"AES-CMAC", RouteGo, ""},
+	{"AES-CTR", RouteModule, ""},
+	{"AES-GCM", RoutePartial, "128- and 256-bit keys and 16-byte tags"},
+	{"AES-KW", RouteGo, ""},
+	{"ChaCha20-Poly1305", RouteGo, ""},
+	{"DES", RouteGo, ""},
+	{"ECDH", RoutePartial, "P-256, P-384 and P-521, with key generation only from crypto/rand.Reader; X25519 uses Go"},
+	{"ECDSA", RoutePartial, "P-224, P-256, P-384 and P-521, with key generation and signing only from crypto/rand.Reader"},
+	{"Ed25519", RouteGo, ""},
+	{"HMAC", RoutePartial, "SHA-1, SHA-224, SHA-256, SHA-384 and SHA-512"},
+	{"MD5", RouteGo, ""},
+	{"RC4", RouteGo, ""},
+	{"RSA", RoutePartial, "key generation only for 2048- and 3072-bit two-prime keys from crypto/rand.Reader; encryption and PSS signing only with crypto/rand.Reader"},
+	{"Random", RouteModule, ""},
+	{"SHA-1", RouteModule, ""},
+	{"SHA-224", RouteModule, ""},
+	{"SHA-256", RouteModule, ""},
+	{"SHA-384", RouteModule, ""},
+	{"SHA-512", RouteModule, ""},
+	{"SHA-512/224", RouteModule, ""},
+	{"SHA-512/256", RouteModule, ""},
+}
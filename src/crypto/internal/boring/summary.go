@@ -0,0 +1,50 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package boring
+
+import (
+	"internal/cryptometrics"
+	"internal/godebug"
+	_ "unsafe" // for linkname
+)
+
+// cryptosummary=1 prints a summary of the operations performed by
+// BoringCrypto and by pure Go fallbacks when the program exits.
+var cryptosummary = godebug.New("cryptosummary")
+
+func init() {
+	if cryptosummary.Value() == "1" {
+		addExitHook(printSummary, true)
+	}
+}
+
+// addExitHook is provided by package runtime.
+//
+//go:linkname addExitHook runtime.addExitHook
+func addExitHook(f func(), runOnNonZeroExit bool)
+
+func printSummary() {
+	if !available {
+		print("crypto: no cryptographic module in use; all operations performed by pure Go\n")
+		return
+	}
+	print("crypto: ", ModuleName, " ", ModuleVersion, " in use\n")
+	used := false
+	for alg := 0; alg < cryptometrics.NumAlgorithms; alg++ {
+		ops := Counter(alg, cryptometrics.Operations)
+		fallbacks := Counter(alg, cryptometrics.Fallbacks)
+		if ops == 0 && fallbacks == 0 {
+			continue
+		}
+		used = true
+		print("crypto: ", cryptometrics.Algorithms[alg].Display, ": ",
+			ops, " operations by ", ModuleName, ", ",
+			fallbacks, " by pure Go fallback, ",
+			Counter(alg, cryptometrics.Failures), " failed\n")
+	}
+	if !used {
+		print("crypto: no operations performed\n")
+	}
+}
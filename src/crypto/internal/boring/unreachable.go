@@ -0,0 +1,53 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (boringcrypto && linux && (amd64 || arm64) && !android && !cmd_go_bootstrap && !msan && cgo) || (goexperiment.opensslcrypto && !boringcrypto && linux && cgo && !android && !cmd_go_bootstrap && !msan) || (goexperiment.cngcrypto && windows && !boringcrypto && !goexperiment.opensslcrypto && !cmd_go_bootstrap) || (goexperiment.darwincrypto && darwin && !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !cmd_go_bootstrap)
+
+package boring
+
+import (
+	"sync/atomic"
+	_ "unsafe" // for linkname
+)
+
+// Unreachable marks code that should be unreachable
+// when BoringCrypto is in use. It panics, unless a test
+// has called AllowGoForTesting.
+func Unreachable() {
+	if allowGo.Load() > 0 {
+		return
+	}
+	panic("boringcrypto: invalid code execution")
+}
+
+var allowGo atomic.Int32
+
+// AllowGoForTesting makes Unreachable a no-op until the returned function
+// is called, so that tests can run the pure Go implementations and compare
+// them against BoringCrypto. It panics outside of test binaries.
+func AllowGoForTesting() (restore func()) {
+	UnreachableExceptTests()
+	allowGo.Add(1)
+	return func() { allowGo.Add(-1) }
+}
+
+// runtime_arg0 is provided by package runtime to avoid an os import.
+//
+//go:linkname runtime_arg0
+func runtime_arg0() string
+
+func hasSuffix(s, t string) bool {
+	return len(s) > len(t) && s[len(s)-len(t):] == t
+}
+
+// UnreachableExceptTests marks code that should be unreachable
+// when BoringCrypto is in use. It panics.
+func UnreachableExceptTests() {
+	name := runtime_arg0()
+	// If BoringCrypto ran on Windows we'd need to allow _test.exe and .test.exe as well.
+	if !hasSuffix(name, "_test") && !hasSuffix(name, ".test") {
+		println("boringcrypto: unexpected code execution in", name)
+		panic("boringcrypto: invalid code execution")
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cbor implements the subset of the Concise Binary Object
+// Representation (RFC 8949) used by COSE and WebAuthn.
+//
+// Decoded values have the following Go types:
+//
+//	unsigned and negative integers    int64
+//	byte strings                      []byte
+//	text strings                      string
+//	arrays                            []any
+//	maps                              Map
+//	tagged values                     Tag
+//	false, true                       bool
+//	null, undefined                   nil
+//	floating-point numbers            float64
+//
+// Decoding is strict: indefinite-length items, integers that don't fit in
+// an int64, invalid UTF-8 in text strings, duplicate map keys, and
+// nesting deeper than 64 levels are rejected. Encoding uses the shortest
+// form of each argument and definite lengths, and preserves the order of
+// map entries, so callers can produce the deterministic encoding of
+// RFC 8949, Section 4.2, by ordering their map keys.
+package cbor
+
+import (
+	"errors"
+	"math"
+	"unicode/utf8"
+)
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+)
+
+const maxDepth = 64
+
+// A Map is a CBOR map. Its entries keep their encoded order.
+type Map []Entry
+
+// An Entry is a key-value pair of a Map.
+type Entry struct {
+	Key, Value any
+}
+
+// Get returns the value of the first entry of m whose key is equal to
+// key, which must be an int64 or a string.
+func (m Map) Get(key any) (any, bool) {
+	for _, e := range m {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// A Tag is a tagged data item.
+type Tag struct {
+	Number  uint64
+	Content any
+}
+
+var errMalformed = errors.New("cbor: malformed data item")
+
+// Unmarshal decodes data, which must hold exactly one data item.
+func Unmarshal(data []byte) (any, error) {
+	v, rest, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cbor: trailing data")
+	}
+	return v, nil
+}
+
+// Decode decodes the data item at the start of data, and returns the rest
+// of data.
+func Decode(data []byte) (v any, rest []byte, err error) {
+	d := &decoder{data: data}
+	v, err = d.value(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, d.data, nil
+}
+
+type decoder struct {
+	data []byte
+}
+
+// head reads the initial byte and argument of a data item.
+func (d *decoder) head() (major byte, info byte, arg uint64, err error) {
+	if len(d.data) < 1 {
+		return 0, 0, 0, errMalformed
+	}
+	major, info = d.data[0]>>5, d.data[0]&0x1f
+	d.data = d.data[1:]
+	var n int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		// Reserved values and indefinite lengths.
+		return 0, 0, 0, errors.New("cbor: unsupported additional information")
+	}
+	if len(d.data) < n {
+		return 0, 0, 0, errMalformed
+	}
+	for _, b := range d.data[:n] {
+		arg = arg<<8 | uint64(b)
+	}
+	d.data = d.data[n:]
+	return major, info, arg, nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, errMalformed
+	}
+	b := d.data[:n:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflows int64")
+		}
+		return int64(arg), nil
+	case majorNegative:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflows int64")
+		}
+		return -1 - int64(arg), nil
+	case majorBytes:
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case majorText:
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, errors.New("cbor: invalid UTF-8 in text string")
+		}
+		return string(b), nil
+	case majorArray:
+		// Each item takes at least one byte.
+		if arg > uint64(len(d.data)) {
+			return nil, errMalformed
+		}
+		a := make([]any, arg)
+		for i := range a {
+			if a[i], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case majorMap:
+		if arg > uint64(len(d.data))/2 {
+			return nil, errMalformed
+		}
+		m := make(Map, arg)
+		seen := make(map[string]bool, arg)
+		for i := range m {
+			start := d.data
+			if m[i].Key, err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+			key := string(start[:len(start)-len(d.data)])
+			if seen[key] {
+				return nil, errors.New("cbor: duplicate map key")
+			}
+			seen[key] = true
+			if m[i].Value, err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case majorTag:
+		content, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Tag{Number: arg, Content: content}, nil
+	default: // majorSimple
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return float16(uint16(arg)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		}
+		return nil, errors.New("cbor: unsupported simple value")
+	}
+}
+
+// float16 converts an IEEE 754 half-precision number.
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
+
+// Marshal encodes v, which must be made of the types listed in the package
+// documentation, int, and uint64. Floating-point numbers are encoded in
+// double precision.
+func Marshal(v any) ([]byte, error) {
+	return appendValue(nil, v)
+}
+
+func appendHead(b []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(b, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return append(b, major|25, byte(arg>>8), byte(arg))
+	case arg <= math.MaxUint32:
+		return append(b, major|26, byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+	}
+	return append(b, major|27, byte(arg>>56), byte(arg>>48), byte(arg>>40), byte(arg>>32),
+		byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+}
+
+func appendValue(b []byte, v any) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case int:
+		return appendValue(b, int64(v))
+	case int64:
+		if v < 0 {
+			return appendHead(b, majorNegative, uint64(-1-v)), nil
+		}
+		return appendHead(b, majorUnsigned, uint64(v)), nil
+	case uint64:
+		return appendHead(b, majorUnsigned, v), nil
+	case []byte:
+		return append(appendHead(b, majorBytes, uint64(len(v))), v...), nil
+	case string:
+		return append(appendHead(b, majorText, uint64(len(v))), v...), nil
+	case []any:
+		b = appendHead(b, majorArray, uint64(len(v)))
+		for _, e := range v {
+			if b, err = appendValue(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case Map:
+		b = appendHead(b, majorMap, uint64(len(v)))
+		for _, e := range v {
+			if b, err = appendValue(b, e.Key); err != nil {
+				return nil, err
+			}
+			if b, err = appendValue(b, e.Value); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case Tag:
+		return appendValue(appendHead(b, majorTag, v.Number), v.Content)
+	case bool:
+		if v {
+			return append(b, majorSimple<<5|21), nil
+		}
+		return append(b, majorSimple<<5|20), nil
+	case nil:
+		return append(b, majorSimple<<5|22), nil
+	case float64:
+		f := math.Float64bits(v)
+		return append(b, majorSimple<<5|27, byte(f>>56), byte(f>>48), byte(f>>40), byte(f>>32),
+			byte(f>>24), byte(f>>16), byte(f>>8), byte(f)), nil
+	}
+	return nil, errors.New("cbor: unsupported type")
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Examples from RFC 8949, Appendix A.
+var roundTripTests = []struct {
+	hex string
+	v   any
+}{
+	{"00", int64(0)},
+	{"17", int64(23)},
+	{"1818", int64(24)},
+	{"1903e8", int64(1000)},
+	{"1a000f4240", int64(1000000)},
+	{"1b000000e8d4a51000", int64(1000000000000)},
+	{"20", int64(-1)},
+	{"3863", int64(-100)},
+	{"3b7fffffffffffffff", int64(math.MinInt64)},
+	{"40", []byte{}},
+	{"4401020304", []byte{1, 2, 3, 4}},
+	{"60", ""},
+	{"6449455446", "IETF"},
+	{"62c3bc", "ü"},
+	{"80", []any{}},
+	{"83010203", []any{int64(1), int64(2), int64(3)}},
+	{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+	{"a0", Map{}},
+	{"a201020304", Map{{int64(1), int64(2)}, {int64(3), int64(4)}}},
+	{"a26161016162820203", Map{{"a", int64(1)}, {"b", []any{int64(2), int64(3)}}}},
+	{"c074323031332d30332d32315432303a30343a30305a", Tag{0, "2013-03-21T20:04:00Z"}},
+	{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", Tag{32, "http://www.example.com"}},
+	{"f4", false},
+	{"f5", true},
+	{"f6", nil},
+	{"fb3ff199999999999a", 1.1},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range roundTripTests {
+		data, _ := hex.DecodeString(tt.hex)
+		v, err := Unmarshal(data)
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.hex, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.v) {
+			t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.hex, v, tt.v)
+		}
+		got, err := Marshal(tt.v)
+		if err != nil {
+			t.Errorf("Marshal(%#v): %v", tt.v, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Marshal(%#v) = %x, want %s", tt.v, got, tt.hex)
+		}
+	}
+}
+
+func TestFloats(t *testing.T) {
+	for _, tt := range []struct {
+		hex string
+		v   float64
+	}{
+		{"f90000", 0},
+		{"f93c00", 1},
+		{"f97bff", 65504},
+		{"f90001", 5.960464477539063e-8},
+		{"f9c400", -4},
+		{"f97c00", math.Inf(1)},
+		{"fa47c35000", 100000},
+	} {
+		data, _ := hex.DecodeString(tt.hex)
+		v, err := Unmarshal(data)
+		if err != nil || v != tt.v {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", tt.hex, v, err, tt.v)
+		}
+	}
+}
+
+func TestDecodeRest(t *testing.T) {
+	data, _ := hex.DecodeString("a1016161ff")
+	v, rest, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, Map{{int64(1), "a"}}) || !bytes.Equal(rest, []byte{0xff}) {
+		t.Errorf("Decode = %#v, %x", v, rest)
+	}
+	if _, err := Unmarshal(data); err == nil {
+		t.Errorf("Unmarshal with trailing data succeeded")
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for _, h := range []string{
+		"",
+		"18",                 // truncated argument
+		"1bffffffffffffffff", // overflows int64
+		"3bffffffffffffffff", // overflows int64
+		"5f42010243030405ff", // indefinite length
+		"9f01ff",             // indefinite length
+		"1c",                 // reserved
+		"4401",               // truncated byte string
+		"62c328",             // invalid UTF-8
+		"9bffffffffffffffff", // huge array
+		"a201020103",         // duplicate key
+		"a1",                 // truncated map
+		"f0",                 // unassigned simple value
+		"f820",               // unassigned simple value
+		"c1",                 // tag without content
+		"8181818181818181818181818181818181818181818181818181818181818181" +
+			"8181818181818181818181818181818181818181818181818181818181818181" +
+			"8100", // too deep
+	} {
+		data, _ := hex.DecodeString(h)
+		if v, err := Unmarshal(data); err == nil {
+			t.Errorf("Unmarshal(%s) = %#v, want error", h, v)
+		}
+	}
+}
+
+func TestMapGet(t *testing.T) {
+	m := Map{{int64(1), "one"}, {"two", int64(2)}, {int64(-1), []byte{3}}}
+	if v, ok := m.Get(int64(1)); !ok || v != "one" {
+		t.Errorf("Get(1) = %v, %v", v, ok)
+	}
+	if v, ok := m.Get("two"); !ok || v != int64(2) {
+		t.Errorf("Get(\"two\") = %v, %v", v, ok)
+	}
+	if _, ok := m.Get(int64(2)); ok {
+		t.Errorf("Get(2) found a value")
+	}
+}
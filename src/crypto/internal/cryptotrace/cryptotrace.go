@@ -0,0 +1,68 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cryptotrace emits execution trace regions around expensive
+// cryptographic operations, so that latency investigations with runtime/trace
+// can attribute pauses to crypto work.
+//
+// Regions are only emitted if GODEBUG=cryptotrace=1 is set and an execution
+// trace is being collected.
+//
+// This package does not import runtime/trace, so that it can be used by the
+// low-level crypto packages, and its regions are not associated with a task.
+// Packages that have a context.Context at hand should check Enabled and call
+// runtime/trace directly instead.
+package cryptotrace
+
+import (
+	"internal/godebug"
+	_ "unsafe" // for linkname
+)
+
+var cryptotrace = godebug.New("cryptotrace")
+
+// LargeInput is the input size from which hashing operations are traced.
+const LargeInput = 1 << 20
+
+// Enabled reports whether the cryptotrace GODEBUG setting is enabled.
+// It does not report whether an execution trace is being collected.
+func Enabled() bool {
+	return cryptotrace.Value() == "1"
+}
+
+// A Region is a traced region of code. The zero value is a no-op region.
+type Region struct {
+	regionType string
+}
+
+// Region modes, matching runtime/trace.
+const (
+	regionStartCode = 0
+	regionEndCode   = 1
+)
+
+// StartRegion starts a region of the given type, if Enabled. Its End method
+// must be called from the same goroutine, following the rules of
+// runtime/trace.StartRegion.
+func StartRegion(regionType string) Region {
+	if !Enabled() {
+		return Region{}
+	}
+	userRegion(0, regionStartCode, regionType)
+	return Region{regionType}
+}
+
+// End marks the end of the region.
+func (r Region) End() {
+	if r.regionType == "" {
+		return
+	}
+	userRegion(0, regionEndCode, r.regionType)
+}
+
+// userRegion emits a UserRegion event if tracing is enabled.
+// It is provided by package runtime.
+//
+//go:linkname userRegion runtime/trace.userRegion
+func userRegion(id, mode uint64, regionType string)
@@ -0,0 +1,59 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cryptotrace_test
+
+import (
+	"bytes"
+	"crypto/internal/cryptotrace"
+	"internal/trace"
+	"os"
+	rtrace "runtime/trace"
+	"testing"
+)
+
+func TestRegions(t *testing.T) {
+	if os.Getenv("GODEBUG") != "" {
+		t.Skip("GODEBUG is set")
+	}
+	t.Setenv("GODEBUG", "cryptotrace=1")
+	if !cryptotrace.Enabled() {
+		t.Fatal("Enabled() = false with GODEBUG=cryptotrace=1")
+	}
+
+	var buf bytes.Buffer
+	if err := rtrace.Start(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cryptotrace.StartRegion("crypto/test.Op").End()
+	rtrace.Stop()
+
+	res, err := trace.Parse(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start, end bool
+	for _, ev := range res.Events {
+		if ev.Type != trace.EvUserRegion || ev.SArgs[0] != "crypto/test.Op" {
+			continue
+		}
+		switch ev.Args[1] {
+		case 0:
+			start = true
+		case 1:
+			end = true
+		}
+	}
+	if !start || !end {
+		t.Errorf("region events found: start %v, end %v; want both", start, end)
+	}
+
+	t.Setenv("GODEBUG", "cryptotrace=0")
+	if cryptotrace.Enabled() {
+		t.Error("Enabled() = true with GODEBUG=cryptotrace=0")
+	}
+	if r := cryptotrace.StartRegion("crypto/test.Op"); r != (cryptotrace.Region{}) {
+		t.Error("StartRegion returned a live region while disabled")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package difftest compares two implementations of the same cryptographic
+// algorithms, such as a cryptographic backend and the pure Go code, and
+// reports the inputs on which they disagree.
+//
+// The functions in this package are meant to be called from fuzz tests, so
+// that a backend can be validated continuously against the standard library.
+// Each function returns a *Mismatch error describing the first disagreement,
+// or nil. When BoringCrypto is in use, tests must call
+// crypto/internal/boring.AllowGoForTesting to run the pure Go implementations.
+package difftest
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// A Mismatch describes an input on which two implementations disagree.
+type Mismatch struct {
+	Op     string // operation that disagreed ("Sum after 3 writes")
+	Input  []byte // input of the operation
+	Want   []byte // result of the reference implementation
+	Got    []byte // result of the implementation under test
+	Detail string // additional information, if any
+}
+
+func (m *Mismatch) Error() string {
+	s := fmt.Sprintf("difftest: %s mismatch\ninput: %s\nwant:  %s\ngot:   %s",
+		m.Op, hex.EncodeToString(m.Input), hex.EncodeToString(m.Want), hex.EncodeToString(m.Got))
+	if m.Detail != "" {
+		s += "\n" + m.Detail
+	}
+	return s
+}
+
+// chunks splits data into pieces of varying length, derived from the data
+// itself, to exercise buffering in streaming implementations.
+func chunks(data []byte) [][]byte {
+	var cs [][]byte
+	for i := 0; len(data) > 0; i++ {
+		n := 1 + int(data[0])*(i+1)%131
+		if n > len(data) {
+			n = len(data)
+		}
+		cs = append(cs, data[:n])
+		data = data[n:]
+	}
+	return cs
+}
+
+// Hash checks that want and got, which must be freshly reset, produce the
+// same sizes and digests when data is written to them in one or more
+// chunks, including intermediate digests and digests after Reset.
+func Hash(want, got hash.Hash, data []byte) error {
+	if want.Size() != got.Size() || want.BlockSize() != got.BlockSize() {
+		return &Mismatch{Op: "Size and BlockSize",
+			Detail: fmt.Sprintf("want %d, %d; got %d, %d", want.Size(), want.BlockSize(), got.Size(), got.BlockSize())}
+	}
+	for i, c := range chunks(data) {
+		want.Write(c)
+		got.Write(c)
+		if w, g := want.Sum(nil), got.Sum(nil); !bytes.Equal(w, g) {
+			return &Mismatch{Op: fmt.Sprintf("Sum after %d writes", i+1), Input: data, Want: w, Got: g}
+		}
+	}
+	want.Reset()
+	got.Reset()
+	want.Write(data)
+	got.Write(data)
+	if w, g := want.Sum(nil), got.Sum(nil); !bytes.Equal(w, g) {
+		return &Mismatch{Op: "Sum after Reset", Input: data, Want: w, Got: g}
+	}
+	return nil
+}
+
+// Block checks that want and got, which must use the same key, agree on the
+// encryption and decryption of every full block of src.
+func Block(want, got cipher.Block, src []byte) error {
+	bs := want.BlockSize()
+	if got.BlockSize() != bs {
+		return &Mismatch{Op: "BlockSize", Detail: fmt.Sprintf("want %d, got %d", bs, got.BlockSize())}
+	}
+	w, g := make([]byte, bs), make([]byte, bs)
+	for ; len(src) >= bs; src = src[bs:] {
+		in := src[:bs]
+		want.Encrypt(w, in)
+		got.Encrypt(g, in)
+		if !bytes.Equal(w, g) {
+			return &Mismatch{Op: "Encrypt", Input: in, Want: w, Got: g}
+		}
+		want.Decrypt(w, in)
+		got.Decrypt(g, in)
+		if !bytes.Equal(w, g) {
+			return &Mismatch{Op: "Decrypt", Input: in, Want: w, Got: g}
+		}
+	}
+	return nil
+}
+
+// AEAD checks that want and got, which must use the same key, produce the
+// same ciphertext for plaintext, that each opens the other's ciphertext,
+// and that both reject a tampered ciphertext.
+func AEAD(want, got cipher.AEAD, nonce, plaintext, additionalData []byte) error {
+	if want.NonceSize() != got.NonceSize() || want.Overhead() != got.Overhead() {
+		return &Mismatch{Op: "NonceSize and Overhead",
+			Detail: fmt.Sprintf("want %d, %d; got %d, %d", want.NonceSize(), want.Overhead(), got.NonceSize(), got.Overhead())}
+	}
+	w := want.Seal(nil, nonce, plaintext, additionalData)
+	g := got.Seal(nil, nonce, plaintext, additionalData)
+	if !bytes.Equal(w, g) {
+		return &Mismatch{Op: "Seal", Input: plaintext, Want: w, Got: g}
+	}
+	if out, err := got.Open(nil, nonce, w, additionalData); err != nil || !bytes.Equal(out, plaintext) {
+		return &Mismatch{Op: "Open", Input: w, Want: plaintext, Got: out, Detail: errDetail(err)}
+	}
+	if out, err := want.Open(nil, nonce, g, additionalData); err != nil || !bytes.Equal(out, plaintext) {
+		return &Mismatch{Op: "Open by reference", Input: g, Want: plaintext, Got: out, Detail: errDetail(err)}
+	}
+	w[len(w)-1] ^= 1
+	if _, err := got.Open(nil, nonce, w, additionalData); err == nil {
+		return &Mismatch{Op: "Open of tampered ciphertext", Input: w, Detail: "unexpected success"}
+	}
+	return nil
+}
+
+// Func checks that two implementations of a deterministic function, such as
+// a raw public key operation, agree on in, including on whether it fails.
+func Func(op string, want, got func(in []byte) ([]byte, error), in []byte) error {
+	w, werr := want(in)
+	g, gerr := got(in)
+	if (werr == nil) != (gerr == nil) {
+		return &Mismatch{Op: op, Input: in, Want: w, Got: g,
+			Detail: fmt.Sprintf("want error %v, got error %v", werr, gerr)}
+	}
+	if werr == nil && !bytes.Equal(w, g) {
+		return &Mismatch{Op: op, Input: in, Want: w, Got: g}
+	}
+	return nil
+}
+
+// A Signer is one implementation of a signature scheme for a fixed key.
+type Signer struct {
+	Sign   func(digest []byte) ([]byte, error)
+	Verify func(digest, sig []byte) bool
+}
+
+// Signatures checks that the signatures of digest produced by each of want
+// and got verify with the other, and that neither accepts a signature of a
+// different digest. It is meant for randomized signature schemes, whose
+// outputs can't be compared directly.
+func Signatures(want, got Signer, digest []byte) error {
+	w, err := want.Sign(digest)
+	if err != nil {
+		return &Mismatch{Op: "Sign by reference", Input: digest, Detail: errDetail(err)}
+	}
+	g, err := got.Sign(digest)
+	if err != nil {
+		return &Mismatch{Op: "Sign", Input: digest, Detail: errDetail(err)}
+	}
+	if !got.Verify(digest, w) {
+		return &Mismatch{Op: "Verify", Input: digest, Want: w, Detail: "reference signature rejected"}
+	}
+	if !want.Verify(digest, g) {
+		return &Mismatch{Op: "Verify by reference", Input: digest, Got: g, Detail: "signature rejected"}
+	}
+	other := append([]byte{}, digest...)
+	if len(other) == 0 {
+		other = append(other, 0)
+	}
+	other[0] ^= 0x80
+	if got.Verify(other, w) {
+		return &Mismatch{Op: "Verify of other digest", Input: other, Want: w, Detail: "unexpected success"}
+	}
+	if want.Verify(other, g) {
+		return &Mismatch{Op: "Verify of other digest by reference", Input: other, Got: g, Detail: "unexpected success"}
+	}
+	return nil
+}
+
+func errDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return "error: " + err.Error()
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package difftest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"testing"
+)
+
+// brokenHash corrupts the digest once more than limit bytes were written.
+type brokenHash struct {
+	hash.Hash
+	n, limit int
+}
+
+func (h *brokenHash) Write(p []byte) (int, error) {
+	h.n += len(p)
+	return h.Hash.Write(p)
+}
+
+func (h *brokenHash) Sum(b []byte) []byte {
+	out := h.Hash.Sum(b)
+	if h.n > h.limit {
+		out[len(out)-1] ^= 1
+	}
+	return out
+}
+
+func (h *brokenHash) Reset() {
+	h.n = 0
+	h.Hash.Reset()
+}
+
+func TestHash(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := Hash(sha256.New(), sha256.New(), data); err != nil {
+		t.Errorf("matching implementations: %v", err)
+	}
+	err := Hash(sha256.New(), &brokenHash{Hash: sha256.New(), limit: 100}, data)
+	var m *Mismatch
+	if !errors.As(err, &m) || m.Op == "Sum after 1 writes" {
+		t.Errorf("broken implementation: got %v, want a Sum mismatch after some writes", err)
+	}
+	if err := Hash(sha256.New(), sha256.New224(), data); err == nil {
+		t.Error("different sizes: got nil error")
+	}
+}
+
+func TestBlockAndAEAD(t *testing.T) {
+	key := make([]byte, 16)
+	b1, _ := aes.NewCipher(key)
+	b2, _ := aes.NewCipher(key)
+	if err := Block(b1, b2, make([]byte, 100)); err != nil {
+		t.Errorf("Block: %v", err)
+	}
+	key[0] = 1
+	b3, _ := aes.NewCipher(key)
+	if err := Block(b1, b3, make([]byte, 16)); err == nil {
+		t.Error("Block with different keys: got nil error")
+	}
+
+	g1, _ := cipher.NewGCM(b1)
+	g2, _ := cipher.NewGCM(b2)
+	nonce := make([]byte, g1.NonceSize())
+	if err := AEAD(g1, g2, nonce, []byte("plaintext"), []byte("ad")); err != nil {
+		t.Errorf("AEAD: %v", err)
+	}
+	g3, _ := cipher.NewGCM(b3)
+	if err := AEAD(g1, g3, nonce, []byte("plaintext"), nil); err == nil {
+		t.Error("AEAD with different keys: got nil error")
+	}
+}
+
+func TestFunc(t *testing.T) {
+	double := func(in []byte) ([]byte, error) { return append(in, in...), nil }
+	fail := func(in []byte) ([]byte, error) { return nil, errors.New("fail") }
+	if err := Func("double", double, double, []byte("x")); err != nil {
+		t.Errorf("matching functions: %v", err)
+	}
+	if err := Func("double", double, fail, []byte("x")); err == nil {
+		t.Error("failing function: got nil error")
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	k1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := func(k *ecdsa.PrivateKey) Signer {
+		return Signer{
+			Sign:   func(d []byte) ([]byte, error) { return ecdsa.SignASN1(rand.Reader, k, d) },
+			Verify: func(d, sig []byte) bool { return ecdsa.VerifyASN1(&k.PublicKey, d, sig) },
+		}
+	}
+	digest := sha256.Sum256([]byte("message"))
+	if err := Signatures(signer(k1), signer(k1), digest[:]); err != nil {
+		t.Errorf("same key: %v", err)
+	}
+	if err := Signatures(signer(k1), signer(k2), digest[:]); err == nil {
+		t.Error("different keys: got nil error")
+	}
+}
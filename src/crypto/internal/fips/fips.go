@@ -0,0 +1,103 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fips implements the policy of the crypto packages on algorithms
+// that are not approved by the security policy of the cryptographic
+// module, such as ChaCha20-Poly1305.
+//
+// While a module is in use, every operation of a non-approved algorithm
+// makes the service indicator of the calling goroutine report that its
+// last operation was not approved, as crypto/fips.Approved describes. If
+// the module operates in FIPS mode and GODEBUG=fips140=only is set, the
+// constructors of non-approved algorithms return an error matching
+// ErrNotApproved instead, so that a program can't mix approved and
+// non-approved services.
+package fips
+
+import (
+	"crypto/cipher"
+	"crypto/internal/boring"
+	"internal/godebug"
+)
+
+// fips140=only rejects non-approved algorithms in FIPS mode.
+var fips140 = godebug.New("fips140")
+
+// ErrNotApproved is the class of the errors returned by Check.
+var ErrNotApproved = boring.ErrNotApproved
+
+// notApprovedError is the error about a non-approved algorithm.
+type notApprovedError string
+
+func (e notApprovedError) Error() string {
+	return "crypto: " + string(e) + " is not approved in FIPS mode"
+}
+
+func (e notApprovedError) Unwrap() error { return ErrNotApproved }
+
+// Enforced reports whether non-approved algorithms are rejected: a module is
+// in use, it operates in FIPS mode, and GODEBUG=fips140=only is set.
+func Enforced() bool {
+	return boring.Enabled && fips140.Value() == "only" && boring.FIPSMode()
+}
+
+// Check applies the policy to the non-approved algorithm name, named as in
+// crypto/fips.Algorithm, before it is used. It returns an error matching
+// ErrNotApproved if the policy rejects the algorithm, and otherwise makes
+// the service indicator report a non-approved operation.
+//
+// Constructors that return an error call Check themselves. Packages that
+// select an algorithm from a configuration should call Check when they
+// validate the configuration, rather than when they use the algorithm.
+func Check(name string) error {
+	if !boring.Enabled {
+		return nil
+	}
+	if Enforced() {
+		return notApprovedError(name)
+	}
+	boring.SetNotApproved()
+	return nil
+}
+
+// NewAEAD applies the policy to a constructor of the non-approved AEAD
+// name. aead and err are the results of the constructor. If the policy
+// accepts the algorithm, NewAEAD returns aead, wrapped so that Seal and
+// Open make the service indicator report a non-approved operation while a
+// module is in use.
+func NewAEAD(name string, aead cipher.AEAD, err error) (cipher.AEAD, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := Check(name); err != nil {
+		return nil, err
+	}
+	return AEAD(aead), nil
+}
+
+// AEAD returns aead, an instance of a non-approved AEAD that was accepted
+// by Check, wrapped so that Seal and Open make the service indicator
+// report a non-approved operation while a module is in use.
+func AEAD(aead cipher.AEAD) cipher.AEAD {
+	if !boring.Enabled {
+		return aead
+	}
+	return notApprovedAEAD{aead}
+}
+
+type notApprovedAEAD struct {
+	cipher.AEAD
+}
+
+func (a notApprovedAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	out := a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+	boring.SetNotApproved()
+	return out
+}
+
+func (a notApprovedAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	out, err := a.AEAD.Open(dst, nonce, ciphertext, additionalData)
+	boring.SetNotApproved()
+	return out, err
+}
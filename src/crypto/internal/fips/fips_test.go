@@ -0,0 +1,73 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fips
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/boring"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	for _, godebug := range []string{"", "fips140=only"} {
+		t.Setenv("GODEBUG", godebug)
+		want := godebug != "" && boring.Enabled && boring.FIPSMode()
+		if got := Enforced(); got != want {
+			t.Errorf("%q: Enforced() = %v, want %v", godebug, got, want)
+		}
+		err := Check("ChaCha20-Poly1305")
+		if want && !errors.Is(err, ErrNotApproved) {
+			t.Errorf("%q: Check = %v, want ErrNotApproved", godebug, err)
+		}
+		if !want && err != nil {
+			t.Errorf("%q: Check = %v", godebug, err)
+		}
+		if _, err2 := NewAEAD("ChaCha20-Poly1305", newAEAD(t), nil); (err2 != nil) != (err != nil) {
+			t.Errorf("%q: NewAEAD = %v, Check = %v", godebug, err2, err)
+		}
+	}
+}
+
+func TestNewAEADError(t *testing.T) {
+	want := errors.New("constructor error")
+	if _, err := NewAEAD("ChaCha20-Poly1305", nil, want); err != want {
+		t.Errorf("NewAEAD = %v, want %v", err, want)
+	}
+}
+
+func TestAEADIndicator(t *testing.T) {
+	aead := AEAD(newAEAD(t))
+	nonce := make([]byte, aead.NonceSize())
+	ciphertext := aead.Seal(nil, nonce, []byte("plaintext"), nil)
+	for name, op := range map[string]func(){
+		"Seal": func() { aead.Seal(nil, nonce, []byte("plaintext"), nil) },
+		"Open": func() { aead.Open(nil, nonce, ciphertext, nil) },
+	} {
+		sha256.Sum256(nil)
+		if got := boring.ServiceIndicator(); got != boring.Enabled {
+			t.Fatalf("after SHA-256: ServiceIndicator = %v, want %v", got, boring.Enabled)
+		}
+		op()
+		if boring.ServiceIndicator() {
+			t.Errorf("after %s: ServiceIndicator = true", name)
+		}
+	}
+}
+
+// newAEAD returns an AEAD that stands for a non-approved one.
+func newAEAD(t *testing.T) cipher.AEAD {
+	b, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keccak implements the Keccak-p[1600] permutations defined in
+// FIPS 202, Section 3.3, for crypto/sha3 and for other sponge and duplex
+// constructions in the standard library.
+//
+// The state is 25 lanes of 64 bits, in the order of FIPS 202, Section 3.1.2:
+// lane (x, y) is a[x+5*y]. A byte string is mapped onto the state by reading
+// consecutive little-endian 64-bit words into consecutive lanes.
+//
+// The API of this package is kept stable, so that other packages may depend
+// on it in the same way as crypto/sha3 does.
+package keccak
+
+// F1600 applies the Keccak-f[1600] permutation, which is Keccak-p[1600, 24],
+// to a. It is the permutation used by SHA-3 and SHAKE.
+func F1600(a *[25]uint64) {
+	f1600(a)
+}
+
+// P1600 applies the Keccak-p[1600, rounds] permutation to a, which consists
+// of the last rounds rounds of Keccak-f[1600]. It is used with 12 rounds by
+// KangarooTwelve and TurboSHAKE.
+//
+// rounds must be a positive multiple of four, no larger than 24.
+func P1600(a *[25]uint64, rounds int) {
+	if rounds <= 0 || rounds > 24 || rounds%4 != 0 {
+		panic("keccak: invalid number of rounds")
+	}
+	if rounds == 24 {
+		f1600(a)
+		return
+	}
+	p1600Generic(a, rounds)
+}
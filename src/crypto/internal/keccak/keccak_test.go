@@ -0,0 +1,100 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keccak
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+func TestF1600(t *testing.T) {
+	// Keccak-f[1600] applied to the all-zero state, from the intermediate
+	// values published by the Keccak team.
+	want := [4]uint64{0xF1258F7940E1DDE7, 0x84D5CCF933C0478A, 0xD598261EA65AA9EE, 0xBD1547306F80494D}
+	var a [25]uint64
+	F1600(&a)
+	if got := [4]uint64(a[:4]); got != want {
+		t.Errorf("F1600(0) = %x..., want %x...", got, want)
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		var a, b [25]uint64
+		for j := range a {
+			a[j] = r.Uint64()
+		}
+		b = a
+		F1600(&a)
+		p1600Generic(&b, 24)
+		if a != b {
+			t.Fatalf("F1600 and p1600Generic disagree")
+		}
+	}
+}
+
+// turboSHAKE128 computes TurboSHAKE128 of an empty message with domain
+// separation byte D, from RFC 9861.
+func turboSHAKE128(D byte, n int) []byte {
+	const rate = 168
+	var a [25]uint64
+	a[0] ^= uint64(D)
+	a[rate/8-1] ^= 0x80 << 56
+	out := make([]byte, 0, n)
+	for {
+		P1600(&a, 12)
+		for i := 0; i < rate/8 && len(out) < n; i++ {
+			out = binary.LittleEndian.AppendUint64(out, a[i])
+		}
+		if len(out) >= n {
+			return out[:n]
+		}
+	}
+}
+
+func TestP1600(t *testing.T) {
+	want, _ := hex.DecodeString("1e415f1c5983aff2169217277d17bb538cd945a397ddec541f1ce41af2c1b74c")
+	if got := turboSHAKE128(0x1F, 32); !bytes.Equal(got, want) {
+		t.Errorf("TurboSHAKE128(\"\", 0x1F) = %x, want %x", got, want)
+	}
+
+	var a, b [25]uint64
+	P1600(&a, 24)
+	F1600(&b)
+	if a != b {
+		t.Errorf("P1600(24) and F1600 disagree")
+	}
+
+	for _, rounds := range []int{-4, 0, 3, 13, 28} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("P1600 with %d rounds did not panic", rounds)
+				}
+			}()
+			P1600(&a, rounds)
+		}()
+	}
+}
+
+func BenchmarkF1600(b *testing.B) {
+	b.SetBytes(200)
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		F1600(&a)
+	}
+}
+
+func BenchmarkP1600x12(b *testing.B) {
+	b.SetBytes(200)
+	var a [25]uint64
+	for i := 0; i < b.N; i++ {
+		P1600(&a, 12)
+	}
+}
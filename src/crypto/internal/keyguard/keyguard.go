@@ -0,0 +1,99 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keyguard allocates short-lived buffers for key material and
+// provides a debugging mode to catch their misuse.
+//
+// The mode is enabled by setting GODEBUG=cryptokeyguard=1, and is meant for
+// development only: it is slow and deliberately leaks address space. On
+// Linux and macOS, each buffer returned by Make then gets its own pages,
+// placed between two inaccessible guard pages, and Free poisons the buffer
+// and makes its pages inaccessible instead of returning them, so that
+// overflows and uses after free fault immediately instead of silently
+// reading or corrupting memory. The BoringCrypto wrappers also overwrite the
+// pointers to their C objects with Poison once they are freed.
+//
+// Otherwise, Make allocates from the Go heap and Free only zeroes the buffer.
+package keyguard
+
+import (
+	"crypto/subtle"
+	"internal/godebug"
+	"sync"
+	"unsafe"
+)
+
+// guarded is whether the debugging mode is enabled. It is only set at
+// initialization, except by tests.
+var guarded = supported && godebug.New("cryptokeyguard").Value() == "1"
+
+// poisonByte is the value freed buffers are filled with in guarded mode,
+// chosen to be recognizable in a debugger and unlikely to be a valid key.
+const poisonByte = 0xa5
+
+var (
+	mu   sync.Mutex
+	live = map[*byte][]byte{} // guarded buffers by first byte, to their mappings
+)
+
+// Enabled reports whether the debugging mode is enabled.
+func Enabled() bool {
+	return guarded
+}
+
+// Make returns a zeroed buffer of length n for key material. The buffer must
+// be released with Free once it is no longer needed.
+func Make(n int) []byte {
+	if !guarded || n == 0 {
+		return make([]byte, n)
+	}
+	mem, b := alloc(n)
+	mu.Lock()
+	live[&b[0]] = mem
+	mu.Unlock()
+	return b
+}
+
+// Free zeroes b. If b was returned by Make in guarded mode, Free instead
+// poisons it and makes it inaccessible, so that any later use faults.
+// Freeing such a buffer twice also faults.
+//
+// Free may also be called on buffers that were not returned by Make, to zero
+// them once they are no longer needed.
+func Free(b []byte) {
+	if !guarded || len(b) == 0 {
+		subtle.Zeroize(b)
+		return
+	}
+	mu.Lock()
+	mem, ok := live[&b[0]]
+	delete(live, &b[0])
+	mu.Unlock()
+	if !ok {
+		subtle.Zeroize(b)
+		return
+	}
+	for i := range b {
+		b[i] = poisonByte
+	}
+	protect(mem)
+}
+
+var (
+	poisonOnce sync.Once
+	poisonPtr  unsafe.Pointer
+)
+
+// Poison returns a pointer that C object pointers should be overwritten with
+// after the object is freed. In guarded mode, it points into an inaccessible
+// page, so dereferencing it faults. Otherwise, it is nil.
+func Poison() unsafe.Pointer {
+	if !guarded {
+		return nil
+	}
+	poisonOnce.Do(func() {
+		poisonPtr = trapPage()
+	})
+	return poisonPtr
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || linux
+
+package keyguard
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const supported = true
+
+// alloc maps n bytes of memory between two inaccessible guard pages. The
+// returned buffer is placed at the end of the mapping so that overflows
+// immediately hit the trailing guard page. Failures are fatal, as this is
+// only used for debugging.
+func alloc(n int) (mem, b []byte) {
+	pageSize := syscall.Getpagesize()
+	dataPages := (n + pageSize - 1) / pageSize
+	mem, err := syscall.Mmap(-1, 0, (dataPages+2)*pageSize,
+		syscall.PROT_NONE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		panic("keyguard: mmap failed: " + err.Error())
+	}
+	inner := mem[pageSize : len(mem)-pageSize]
+	if err := syscall.Mprotect(inner, syscall.PROT_READ|syscall.PROT_WRITE); err != nil {
+		panic("keyguard: mprotect failed: " + err.Error())
+	}
+	return mem, inner[len(inner)-n:]
+}
+
+// protect makes a mapping returned by alloc inaccessible. The mapping is
+// never unmapped, so that its addresses are not reused.
+func protect(mem []byte) {
+	if err := syscall.Mprotect(mem, syscall.PROT_NONE); err != nil {
+		panic("keyguard: mprotect failed: " + err.Error())
+	}
+}
+
+// trapPage returns a pointer to the middle of an inaccessible page.
+func trapPage() unsafe.Pointer {
+	pageSize := syscall.Getpagesize()
+	mem, err := syscall.Mmap(-1, 0, pageSize, syscall.PROT_NONE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		panic("keyguard: mmap failed: " + err.Error())
+	}
+	return unsafe.Pointer(&mem[pageSize/2])
+}
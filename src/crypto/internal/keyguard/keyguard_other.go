@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !linux
+
+package keyguard
+
+import "unsafe"
+
+const supported = false
+
+func alloc(n int) (mem, b []byte) {
+	panic("keyguard: guarded mode is not supported")
+}
+
+func protect(mem []byte) {
+	panic("keyguard: guarded mode is not supported")
+}
+
+func trapPage() unsafe.Pointer {
+	panic("keyguard: guarded mode is not supported")
+}
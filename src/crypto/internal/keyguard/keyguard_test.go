@@ -0,0 +1,96 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyguard
+
+import (
+	"runtime/debug"
+	"testing"
+	"unsafe"
+)
+
+func setGuarded(t *testing.T, v bool) {
+	if v && !supported {
+		t.Skip("guarded mode is not supported on this platform")
+	}
+	old := guarded
+	guarded = v
+	t.Cleanup(func() { guarded = old })
+}
+
+func TestUnguarded(t *testing.T) {
+	setGuarded(t, false)
+	b := Make(32)
+	if len(b) != 32 {
+		t.Fatalf("len(Make(32)) = %d", len(b))
+	}
+	for i := range b {
+		b[i] = byte(i) + 1
+	}
+	Free(b)
+	for i, v := range b {
+		if v != 0 {
+			t.Fatalf("b[%d] = %#x after Free, want 0", i, v)
+		}
+	}
+	if Poison() != nil {
+		t.Error("Poison() != nil in unguarded mode")
+	}
+}
+
+var sink byte
+
+// expectFault calls f and reports whether it caused a memory fault.
+func expectFault(t *testing.T, f func()) {
+	t.Helper()
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	faulted := func() (faulted bool) {
+		defer func() { faulted = recover() != nil }()
+		f()
+		return false
+	}()
+	if !faulted {
+		t.Error("access did not fault")
+	}
+}
+
+func TestGuarded(t *testing.T) {
+	setGuarded(t, true)
+	b := Make(100)
+	if len(b) != 100 {
+		t.Fatalf("len(Make(100)) = %d", len(b))
+	}
+	for i, v := range b {
+		if v != 0 {
+			t.Fatalf("b[%d] = %#x, want 0", i, v)
+		}
+		b[i] = byte(i)
+	}
+
+	// The buffer ends at a guard page.
+	expectFault(t, func() {
+		sink = *(*byte)(unsafe.Add(unsafe.Pointer(&b[0]), len(b)))
+	})
+
+	Free(b)
+	if _, ok := live[&b[0]]; ok {
+		t.Error("buffer still live after Free")
+	}
+	expectFault(t, func() { sink = b[0] })
+	expectFault(t, func() { b[len(b)-1] = 1 })
+	expectFault(t, func() { Free(b) })
+
+	// Buffers not returned by Make are zeroed.
+	h := []byte{1, 2, 3}
+	Free(h)
+	if h[0]|h[1]|h[2] != 0 {
+		t.Errorf("heap buffer = %v after Free, want zeroes", h)
+	}
+
+	p := Poison()
+	if p == nil || p != Poison() {
+		t.Fatal("Poison() is not a stable non-nil pointer")
+	}
+	expectFault(t, func() { sink = *(*byte)(p) })
+}
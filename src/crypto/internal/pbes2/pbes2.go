@@ -0,0 +1,427 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pbes2 implements the PBES2 password-based encryption scheme
+// defined in RFC 8018, Section 6.2, as used by PKCS #8 and PKCS #12.
+//
+// Keys are derived with PBKDF2 or with scrypt (RFC 7914), and content is
+// encrypted with AES-CBC or with AES-GCM (RFC 5084).
+package pbes2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/keyguard"
+	"crypto/internal/pbkdf2"
+	"crypto/internal/scrypt"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"hash"
+	"io"
+)
+
+var (
+	// OID is the algorithm identifier of PBES2.
+	OID = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidAES128GCM = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 6}
+	oidAES256GCM = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+)
+
+// MaxIterations is a safety limit that prevents CPU exhaustion when
+// decrypting crafted inputs with unreasonable iteration counts.
+const MaxIterations = 1 << 24
+
+// MaxScryptCost is a safety limit on the scrypt N*r product, which bounds
+// the memory used to decrypt crafted inputs to 128 * MaxScryptCost bytes.
+const MaxScryptCost = 1 << 23
+
+var (
+	// ErrDecryption is returned when the ciphertext cannot be decrypted,
+	// which usually indicates that the password is incorrect.
+	ErrDecryption = errors.New("pbes2: decryption error, incorrect padding")
+
+	errUnsupportedKDF    = errors.New("pbes2: unsupported key derivation function")
+	errUnsupportedPRF    = errors.New("pbes2: unsupported PBKDF2 pseudorandom function")
+	errUnsupportedCipher = errors.New("pbes2: unsupported encryption scheme")
+)
+
+type params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+type gcmParams struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+// Cipher identifies the content encryption scheme used with PBES2.
+type Cipher int
+
+const (
+	AES128CBC Cipher = iota + 1
+	AES192CBC
+	AES256CBC
+	AES128GCM
+	AES256GCM
+)
+
+var ciphers = []struct {
+	c       Cipher
+	oid     asn1.ObjectIdentifier
+	keySize int
+	gcm     bool
+}{
+	{AES128CBC, oidAES128CBC, 16, false},
+	{AES192CBC, oidAES192CBC, 24, false},
+	{AES256CBC, oidAES256CBC, 32, false},
+	{AES128GCM, oidAES128GCM, 16, true},
+	{AES256GCM, oidAES256GCM, 32, true},
+}
+
+// KDF identifies the key derivation function used with PBES2.
+type KDF int
+
+const (
+	PBKDF2 KDF = iota
+	Scrypt
+)
+
+// Options configures encryption with PBES2.
+type Options struct {
+	Cipher   Cipher
+	KDF      KDF
+	SaltSize int
+
+	// Hash and Iterations configure PBKDF2. Hash must be one of SHA-1,
+	// SHA-224, SHA-256, SHA-384, or SHA-512.
+	Hash       func() hash.Hash
+	Iterations int
+
+	// N, R, and P configure scrypt.
+	N, R, P int
+}
+
+func prfOID(h func() hash.Hash) (asn1.ObjectIdentifier, error) {
+	switch h().Size() {
+	case sha1.Size:
+		return oidHMACWithSHA1, nil
+	case sha256.Size224:
+		return oidHMACWithSHA224, nil
+	case sha256.Size:
+		return oidHMACWithSHA256, nil
+	case sha512.Size384:
+		return oidHMACWithSHA384, nil
+	case sha512.Size:
+		return oidHMACWithSHA512, nil
+	}
+	return nil, errUnsupportedPRF
+}
+
+func prfHash(alg pkix.AlgorithmIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(alg.Algorithm) == 0, alg.Algorithm.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case alg.Algorithm.Equal(oidHMACWithSHA224):
+		return sha256.New224, nil
+	case alg.Algorithm.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case alg.Algorithm.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case alg.Algorithm.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	}
+	return nil, errUnsupportedPRF
+}
+
+// Encrypt encrypts plaintext under a key derived from password, and returns
+// the PBES2 algorithm identifier and the ciphertext. The salt and IV or
+// nonce are read from rand.
+func Encrypt(rand io.Reader, password, plaintext []byte, opts *Options) (pkix.AlgorithmIdentifier, []byte, error) {
+	var alg pkix.AlgorithmIdentifier
+
+	var keySize int
+	var gcm bool
+	var encOID asn1.ObjectIdentifier
+	for _, c := range ciphers {
+		if c.c == opts.Cipher {
+			keySize, gcm, encOID = c.keySize, c.gcm, c.oid
+		}
+	}
+	if keySize == 0 {
+		return alg, nil, errUnsupportedCipher
+	}
+	if opts.SaltSize <= 0 {
+		return alg, nil, errors.New("pbes2: invalid salt size")
+	}
+
+	salt := make([]byte, opts.SaltSize)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return alg, nil, err
+	}
+
+	var kdf pkix.AlgorithmIdentifier
+	var key []byte
+	switch opts.KDF {
+	case PBKDF2:
+		prf, err := prfOID(opts.Hash)
+		if err != nil {
+			return alg, nil, err
+		}
+		if opts.Iterations <= 0 {
+			return alg, nil, errors.New("pbes2: invalid iteration count")
+		}
+		kdfParams, err := asn1.Marshal(pbkdf2Params{
+			Salt:           salt,
+			IterationCount: opts.Iterations,
+			PRF: pkix.AlgorithmIdentifier{
+				Algorithm:  prf,
+				Parameters: asn1.NullRawValue,
+			},
+		})
+		if err != nil {
+			return alg, nil, err
+		}
+		kdf.Algorithm = oidPBKDF2
+		kdf.Parameters.FullBytes = kdfParams
+		key = pbkdf2.Key(password, salt, opts.Iterations, keySize, opts.Hash)
+	case Scrypt:
+		kdfParams, err := asn1.Marshal(scryptParams{
+			Salt:                     salt,
+			CostParameter:            opts.N,
+			BlockSize:                opts.R,
+			ParallelizationParameter: opts.P,
+			KeyLength:                keySize,
+		})
+		if err != nil {
+			return alg, nil, err
+		}
+		kdf.Algorithm = oidScrypt
+		kdf.Parameters.FullBytes = kdfParams
+		if key, err = scrypt.Key(password, salt, opts.N, opts.R, opts.P, keySize); err != nil {
+			return alg, nil, err
+		}
+	default:
+		return alg, nil, errUnsupportedKDF
+	}
+
+	defer keyguard.Free(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return alg, nil, err
+	}
+
+	var enc pkix.AlgorithmIdentifier
+	var ciphertext []byte
+	if gcm {
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return alg, nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand, nonce); err != nil {
+			return alg, nil, err
+		}
+		encParams, err := asn1.Marshal(gcmParams{Nonce: nonce, ICVLen: aead.Overhead()})
+		if err != nil {
+			return alg, nil, err
+		}
+		enc.Parameters.FullBytes = encParams
+		ciphertext = aead.Seal(nil, nonce, plaintext, nil)
+	} else {
+		iv := make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(rand, iv); err != nil {
+			return alg, nil, err
+		}
+		encParams, err := asn1.Marshal(iv)
+		if err != nil {
+			return alg, nil, err
+		}
+		enc.Parameters.FullBytes = encParams
+
+		padLen := aes.BlockSize - len(plaintext)%aes.BlockSize
+		ciphertext = make([]byte, len(plaintext)+padLen)
+		copy(ciphertext, plaintext)
+		for i := len(plaintext); i < len(ciphertext); i++ {
+			ciphertext[i] = byte(padLen)
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	}
+	enc.Algorithm = encOID
+
+	p, err := asn1.Marshal(params{
+		KeyDerivationFunc: kdf,
+		EncryptionScheme:  enc,
+	})
+	if err != nil {
+		return alg, nil, err
+	}
+	alg.Algorithm = OID
+	alg.Parameters.FullBytes = p
+	return alg, ciphertext, nil
+}
+
+// Decrypt decrypts ciphertext that was encrypted with the PBES2 parameters
+// described by alg under a key derived from password.
+func Decrypt(alg pkix.AlgorithmIdentifier, password, ciphertext []byte) ([]byte, error) {
+	if !alg.Algorithm.Equal(OID) {
+		return nil, errors.New("pbes2: algorithm " + alg.Algorithm.String() + " is not PBES2")
+	}
+	var p params
+	if err := unmarshal(alg.Parameters.FullBytes, &p); err != nil {
+		return nil, err
+	}
+
+	var keySize int
+	var gcm bool
+	for _, c := range ciphers {
+		if p.EncryptionScheme.Algorithm.Equal(c.oid) {
+			keySize, gcm = c.keySize, c.gcm
+		}
+	}
+	if keySize == 0 {
+		return nil, errUnsupportedCipher
+	}
+
+	key, err := deriveKey(p.KeyDerivationFunc, password, keySize)
+	if err != nil {
+		return nil, err
+	}
+	defer keyguard.Free(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if gcm {
+		var gp gcmParams
+		if err := unmarshal(p.EncryptionScheme.Parameters.FullBytes, &gp); err != nil {
+			return nil, err
+		}
+		if len(gp.Nonce) != 12 || gp.ICVLen < 12 || gp.ICVLen > 16 {
+			return nil, errors.New("pbes2: invalid AES-GCM parameters")
+		}
+		aead, err := cipher.NewGCMWithTagSize(block, gp.ICVLen)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := aead.Open(nil, gp.Nonce, ciphertext, nil)
+		if err != nil {
+			return nil, ErrDecryption
+		}
+		return plaintext, nil
+	}
+
+	var iv []byte
+	if err := unmarshal(p.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("pbes2: invalid IV length")
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("pbes2: input is not a multiple of the block size")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	return unpad(plaintext, aes.BlockSize)
+}
+
+func deriveKey(alg pkix.AlgorithmIdentifier, password []byte, keySize int) ([]byte, error) {
+	switch {
+	case alg.Algorithm.Equal(oidPBKDF2):
+		var kdf pbkdf2Params
+		if err := unmarshal(alg.Parameters.FullBytes, &kdf); err != nil {
+			return nil, err
+		}
+		if kdf.IterationCount <= 0 || kdf.IterationCount > MaxIterations {
+			return nil, errors.New("pbes2: iteration count is invalid or too high")
+		}
+		if kdf.KeyLength != 0 && kdf.KeyLength != keySize {
+			return nil, errors.New("pbes2: key length does not match encryption scheme")
+		}
+		h, err := prfHash(kdf.PRF)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(password, kdf.Salt, kdf.IterationCount, keySize, h), nil
+
+	case alg.Algorithm.Equal(oidScrypt):
+		var kdf scryptParams
+		if err := unmarshal(alg.Parameters.FullBytes, &kdf); err != nil {
+			return nil, err
+		}
+		if kdf.CostParameter <= 0 || kdf.BlockSize <= 0 || kdf.ParallelizationParameter <= 0 ||
+			kdf.CostParameter > MaxScryptCost/kdf.BlockSize || kdf.ParallelizationParameter > 16 {
+			return nil, errors.New("pbes2: scrypt parameters are invalid or too high")
+		}
+		if kdf.KeyLength != 0 && kdf.KeyLength != keySize {
+			return nil, errors.New("pbes2: key length does not match encryption scheme")
+		}
+		return scrypt.Key(password, kdf.Salt, kdf.CostParameter, kdf.BlockSize, kdf.ParallelizationParameter, keySize)
+	}
+	return nil, errUnsupportedKDF
+}
+
+// unpad removes PKCS #7 padding from b.
+func unpad(b []byte, blockSize int) ([]byte, error) {
+	psLen := int(b[len(b)-1])
+	if psLen == 0 || psLen > blockSize || psLen > len(b) {
+		return nil, ErrDecryption
+	}
+	var bad byte
+	for _, c := range b[len(b)-psLen:] {
+		bad |= c ^ byte(psLen)
+	}
+	if bad != 0 {
+		return nil, ErrDecryption
+	}
+	return b[:len(b)-psLen], nil
+}
+
+// unmarshal calls asn1.Unmarshal, but also returns an error if there is any
+// trailing data after unmarshaling.
+func unmarshal(in []byte, out any) error {
+	trailing, err := asn1.Unmarshal(in, out)
+	if err != nil {
+		return err
+	}
+	if len(trailing) != 0 {
+		return errors.New("pbes2: trailing data found")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jose implements a strict subset of JSON Object Signing and
+// Encryption: JSON Web Signatures (RFC 7515) and JSON Web Encryption
+// (RFC 7516) in the compact serialization, and JSON Web Keys (RFC 7517).
+//
+// The package is designed to make the common mistakes of JOSE libraries
+// impossible:
+//
+//   - Verify and Decrypt take the algorithm the caller expects, and reject
+//     tokens that claim any other. The "none" algorithm is not supported.
+//   - Keys are checked against the algorithm, so that, for example, an RSA
+//     public key can't be used as an HMAC secret.
+//   - Keys embedded in or referenced by headers ("jwk", "jku", "x5c", ...)
+//     are never used. Headers with "crit" or "zip" are rejected.
+//   - Only approved algorithms are implemented for encryption.
+//
+// The signature algorithms of RFC 7518 and RFC 8037 are supported: HS256,
+// HS384, HS512, RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384,
+// ES512, and EdDSA (with Ed25519). RSA keys must be at least 2048 bits,
+// and HMAC keys at least as long as the hash.
+//
+// For encryption, the key management algorithms dir, RSA-OAEP-256, and
+// ECDH-ES (with P-256, P-384, and P-521), and the content encryption
+// algorithms A128GCM, A192GCM, and A256GCM are supported.
+//
+// JSON Web Tokens (RFC 7519) are JWS or JWE payloads. This package does
+// not process their claims.
+package jose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// A Header holds the protected header parameters of a JWS or JWE that are
+// relevant to applications. Other parameters are ignored when parsing.
+type Header struct {
+	// Algorithm is the "alg" parameter, the signature or key management
+	// algorithm.
+	Algorithm string
+
+	// Encryption is the "enc" parameter, the content encryption algorithm
+	// of a JWE. It must be empty for a JWS.
+	Encryption string
+
+	// KeyID is the "kid" parameter, a hint of which key was used. It is
+	// not authenticated until the token is verified or decrypted.
+	KeyID string
+
+	// Type is the "typ" parameter, such as "JWT".
+	Type string
+
+	// ContentType is the "cty" parameter.
+	ContentType string
+}
+
+var (
+	// ErrVerification is returned by Verify when the signature is invalid.
+	ErrVerification = errors.New("jose: signature verification failed")
+
+	// ErrDecryption is returned by Decrypt when the token can't be
+	// decrypted, because it was modified or encrypted for another key.
+	ErrDecryption = errors.New("jose: decryption failed")
+
+	errMalformed = errors.New("jose: malformed token")
+)
+
+// b64 is the base64url encoding without padding of RFC 7515, Section 2.
+// Strict decoding rejects non-canonical encodings, which would otherwise
+// allow modifying a token without invalidating it.
+var b64 = base64.RawURLEncoding.Strict()
+
+// rawHeader is the JSON encoding of the protected header.
+type rawHeader struct {
+	Algorithm   string          `json:"alg"`
+	Encryption  string          `json:"enc,omitempty"`
+	KeyID       string          `json:"kid,omitempty"`
+	Type        string          `json:"typ,omitempty"`
+	ContentType string          `json:"cty,omitempty"`
+	EPK         *jsonWebKey     `json:"epk,omitempty"`
+	APU         string          `json:"apu,omitempty"`
+	APV         string          `json:"apv,omitempty"`
+	Critical    json.RawMessage `json:"crit,omitempty"`
+	Zip         json.RawMessage `json:"zip,omitempty"`
+}
+
+func (h *Header) raw() *rawHeader {
+	return &rawHeader{
+		Algorithm:   h.Algorithm,
+		Encryption:  h.Encryption,
+		KeyID:       h.KeyID,
+		Type:        h.Type,
+		ContentType: h.ContentType,
+	}
+}
+
+func (r *rawHeader) header() *Header {
+	return &Header{
+		Algorithm:   r.Algorithm,
+		Encryption:  r.Encryption,
+		KeyID:       r.KeyID,
+		Type:        r.Type,
+		ContentType: r.ContentType,
+	}
+}
+
+// parseHeader decodes the base64url-encoded protected header of a token.
+func parseHeader(encoded string) (*rawHeader, error) {
+	data, err := b64.DecodeString(encoded)
+	if err != nil {
+		return nil, errMalformed
+	}
+	h := &rawHeader{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, errMalformed
+	}
+	if h.Critical != nil {
+		return nil, errors.New("jose: critical header extensions are not supported")
+	}
+	if h.Zip != nil {
+		return nil, errors.New("jose: compression is not supported")
+	}
+	return h, nil
+}
+
+// splitToken splits a compact serialization into its n parts.
+func splitToken(token string, n int) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != n {
+		return nil, errMalformed
+	}
+	return parts, nil
+}
@@ -0,0 +1,323 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jose
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+const (
+	gcmNonceSize = 12
+	gcmTagSize   = 16
+)
+
+// contentKeySize returns the key size of a content encryption algorithm.
+func contentKeySize(enc string) (int, error) {
+	switch enc {
+	case "A128GCM":
+		return 16, nil
+	case "A192GCM":
+		return 24, nil
+	case "A256GCM":
+		return 32, nil
+	}
+	return 0, errors.New("jose: unsupported content encryption algorithm " + enc)
+}
+
+// Encrypt returns the compact serialization of a JWE of plaintext with the
+// protected header h, encrypted for key according to h.Algorithm and
+// h.Encryption.
+//
+// For dir, key is a []byte of the size of the content encryption key. For
+// RSA-OAEP-256, it is an *rsa.PublicKey of at least 2048 bits. For ECDH-ES,
+// it is an *ecdsa.PublicKey or an *ecdh.PublicKey on P-256, P-384, or
+// P-521.
+func Encrypt(plaintext []byte, key any, h *Header) (string, error) {
+	keySize, err := contentKeySize(h.Encryption)
+	if err != nil {
+		return "", err
+	}
+	raw := h.raw()
+
+	var cek, encryptedKey []byte
+	switch h.Algorithm {
+	case "dir":
+		k, ok := key.([]byte)
+		if !ok || len(k) != keySize {
+			return "", errors.New("jose: dir requires a []byte key of the content encryption key size")
+		}
+		cek = k
+	case "RSA-OAEP-256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || pub.N.BitLen() < 2048 {
+			return "", errors.New("jose: RSA-OAEP-256 requires an RSA public key of at least 2048 bits")
+		}
+		cek = make([]byte, keySize)
+		if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+			return "", err
+		}
+		encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil)
+		if err != nil {
+			return "", err
+		}
+	case "ECDH-ES":
+		pub, err := ecdhPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		ephemeral, err := pub.Curve().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		z, err := ephemeral.ECDH(pub)
+		if err != nil {
+			return "", err
+		}
+		epk, err := ecdhJSONWebKey(ephemeral.PublicKey())
+		if err != nil {
+			return "", err
+		}
+		raw.EPK = epk
+		cek = concatKDF(z, h.Encryption, nil, nil, keySize)
+	default:
+		return "", errors.New("jose: unsupported key management algorithm " + h.Algorithm)
+	}
+
+	header, err := json.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := b64.EncodeToString(header)
+	aead, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, nonce, plaintext, []byte(encodedHeader))
+	ciphertext, tag := sealed[:len(sealed)-gcmTagSize], sealed[len(sealed)-gcmTagSize:]
+	return encodedHeader + "." + b64.EncodeToString(encryptedKey) + "." +
+		b64.EncodeToString(nonce) + "." + b64.EncodeToString(ciphertext) + "." +
+		b64.EncodeToString(tag), nil
+}
+
+// Decrypt decrypts the compact serialization of a JWE with key, and returns
+// the plaintext and the protected header. The header's key management
+// algorithm must be alg.
+//
+// For dir, key is a []byte. For RSA-OAEP-256, it is an *rsa.PrivateKey or
+// a crypto.Decrypter with an RSA public key. For ECDH-ES, it is an
+// *ecdsa.PrivateKey or an *ecdh.PrivateKey.
+//
+// If the token can't be decrypted, Decrypt returns ErrDecryption.
+func Decrypt(token string, key any, alg string) (plaintext []byte, h *Header, err error) {
+	parts, err := splitToken(token, 5)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := parseHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if raw.Algorithm != alg {
+		return nil, nil, errors.New("jose: token algorithm " + raw.Algorithm + " is not " + alg)
+	}
+	keySize, err := contentKeySize(raw.Encryption)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields [4][]byte
+	for i := range fields {
+		if fields[i], err = b64.DecodeString(parts[i+1]); err != nil {
+			return nil, nil, errMalformed
+		}
+	}
+	encryptedKey, nonce, ciphertext, tag := fields[0], fields[1], fields[2], fields[3]
+	if len(nonce) != gcmNonceSize || len(tag) != gcmTagSize {
+		return nil, nil, errMalformed
+	}
+
+	var cek []byte
+	switch alg {
+	case "dir":
+		k, ok := key.([]byte)
+		if !ok || len(k) != keySize {
+			return nil, nil, errors.New("jose: dir requires a []byte key of the content encryption key size")
+		}
+		if len(encryptedKey) != 0 {
+			return nil, nil, errMalformed
+		}
+		cek = k
+	case "RSA-OAEP-256":
+		d, ok := key.(crypto.Decrypter)
+		if !ok {
+			return nil, nil, errors.New("jose: RSA-OAEP-256 requires an RSA private key")
+		}
+		if pub, ok := d.Public().(*rsa.PublicKey); !ok || pub.N.BitLen() < 2048 {
+			return nil, nil, errors.New("jose: RSA-OAEP-256 requires an RSA private key of at least 2048 bits")
+		}
+		// To avoid revealing whether the key decryption failed, a random
+		// key is used instead, as recommended by RFC 7516, Section 11.5.
+		cek = make([]byte, keySize)
+		if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+			return nil, nil, err
+		}
+		k, err := d.Decrypt(rand.Reader, encryptedKey, &rsa.OAEPOptions{Hash: crypto.SHA256})
+		if err == nil && len(k) == keySize {
+			cek = k
+		}
+	case "ECDH-ES":
+		priv, err := ecdhPrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(encryptedKey) != 0 || raw.EPK == nil || raw.EPK.KeyType != "EC" || raw.EPK.D != "" {
+			return nil, nil, errMalformed
+		}
+		epk, err := raw.EPK.ecdsaKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		pub, err := epk.(*ecdsa.PublicKey).ECDH()
+		if err != nil {
+			return nil, nil, err
+		}
+		if pub.Curve() != priv.Curve() {
+			return nil, nil, errors.New("jose: ephemeral key is on the wrong curve")
+		}
+		apu, err1 := b64.DecodeString(raw.APU)
+		apv, err2 := b64.DecodeString(raw.APV)
+		if err1 != nil || err2 != nil {
+			return nil, nil, errMalformed
+		}
+		z, err := priv.ECDH(pub)
+		if err != nil {
+			return nil, nil, ErrDecryption
+		}
+		cek = concatKDF(z, raw.Encryption, apu, apv, keySize)
+	default:
+		return nil, nil, errors.New("jose: unsupported key management algorithm " + alg)
+	}
+
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(append(sealed, ciphertext...), tag...)
+	plaintext, err = aead.Open(nil, nonce, sealed, []byte(parts[0]))
+	if err != nil {
+		return nil, nil, ErrDecryption
+	}
+	return plaintext, raw.header(), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func ecdhPublicKey(key any) (*ecdh.PublicKey, error) {
+	var pub *ecdh.PublicKey
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		var err error
+		if pub, err = key.ECDH(); err != nil {
+			return nil, err
+		}
+	case *ecdh.PublicKey:
+		pub = key
+	default:
+		return nil, errors.New("jose: ECDH-ES requires an ECDSA or ECDH public key")
+	}
+	if !isNISTCurve(pub.Curve()) {
+		return nil, errors.New("jose: unsupported ECDH curve")
+	}
+	return pub, nil
+}
+
+func ecdhPrivateKey(key any) (*ecdh.PrivateKey, error) {
+	var priv *ecdh.PrivateKey
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		var err error
+		if priv, err = key.ECDH(); err != nil {
+			return nil, err
+		}
+	case *ecdh.PrivateKey:
+		priv = key
+	default:
+		return nil, errors.New("jose: ECDH-ES requires an ECDSA or ECDH private key")
+	}
+	if !isNISTCurve(priv.Curve()) {
+		return nil, errors.New("jose: unsupported ECDH curve")
+	}
+	return priv, nil
+}
+
+func isNISTCurve(c ecdh.Curve) bool {
+	return c == ecdh.P256() || c == ecdh.P384() || c == ecdh.P521()
+}
+
+// ecdhJSONWebKey returns the JSON Web Key of a NIST curve public key.
+func ecdhJSONWebKey(pub *ecdh.PublicKey) (*jsonWebKey, error) {
+	var crv string
+	switch pub.Curve() {
+	case ecdh.P256():
+		crv = "P-256"
+	case ecdh.P384():
+		crv = "P-384"
+	case ecdh.P521():
+		crv = "P-521"
+	default:
+		return nil, errors.New("jose: unsupported ECDH curve")
+	}
+	point := pub.Bytes()[1:] // uncompressed, without the 0x04 prefix
+	size := len(point) / 2
+	return &jsonWebKey{
+		KeyType: "EC",
+		Curve:   crv,
+		X:       b64.EncodeToString(point[:size]),
+		Y:       b64.EncodeToString(point[size:]),
+	}, nil
+}
+
+// concatKDF derives a content encryption key from the shared secret z for
+// ECDH-ES in Direct Key Agreement mode, with the Concat KDF of NIST
+// SP 800-56A, Section 5.8.1, as specified by RFC 7518, Section 4.6.2.
+func concatKDF(z []byte, enc string, apu, apv []byte, keySize int) []byte {
+	var otherInfo []byte
+	for _, v := range [][]byte{[]byte(enc), apu, apv} {
+		otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(v)))
+		otherInfo = append(otherInfo, v...)
+	}
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keySize*8))
+
+	var out []byte
+	for counter := uint32(1); len(out) < keySize; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(z)
+		h.Write(otherInfo)
+		out = h.Sum(out)
+	}
+	return out[:keySize]
+}
@@ -0,0 +1,356 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jose
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// A JSONWebKey is a key in the JSON Web Key format of RFC 7517.
+type JSONWebKey struct {
+	// Key is the key, one of *ecdsa.PublicKey, *ecdsa.PrivateKey,
+	// *rsa.PublicKey, *rsa.PrivateKey, ed25519.PublicKey,
+	// ed25519.PrivateKey, or []byte for a symmetric key.
+	Key any
+
+	// KeyID is the "kid" parameter.
+	KeyID string
+
+	// Algorithm is the "alg" parameter, the algorithm the key is meant
+	// for, if restricted.
+	Algorithm string
+
+	// Use is the "use" parameter, "sig" or "enc", if restricted.
+	Use string
+}
+
+// jsonWebKey is the JSON encoding of a JSONWebKey, with the members of
+// RFC 7518, Section 6, and RFC 8037, Section 2.
+type jsonWebKey struct {
+	KeyType   string          `json:"kty"`
+	Use       string          `json:"use,omitempty"`
+	KeyID     string          `json:"kid,omitempty"`
+	Algorithm string          `json:"alg,omitempty"`
+	Curve     string          `json:"crv,omitempty"`
+	X         string          `json:"x,omitempty"`
+	Y         string          `json:"y,omitempty"`
+	N         string          `json:"n,omitempty"`
+	E         string          `json:"e,omitempty"`
+	D         string          `json:"d,omitempty"`
+	P         string          `json:"p,omitempty"`
+	Q         string          `json:"q,omitempty"`
+	DP        string          `json:"dp,omitempty"`
+	DQ        string          `json:"dq,omitempty"`
+	QI        string          `json:"qi,omitempty"`
+	K         string          `json:"k,omitempty"`
+	Other     json.RawMessage `json:"oth,omitempty"`
+}
+
+var errUnsupportedKeyType = errors.New("jose: unsupported key type")
+
+// ParseJSONWebKey parses a JSON Web Key. Keys of type EC (with the P-256,
+// P-384, and P-521 curves), RSA (with two primes), OKP (with Ed25519), and
+// oct are supported. Public keys are checked to be valid, and private keys
+// to be consistent with their public parts.
+func ParseJSONWebKey(data []byte) (*JSONWebKey, error) {
+	var j jsonWebKey
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, errors.New("jose: malformed JSON Web Key")
+	}
+	return j.key()
+}
+
+// ParseJSONWebKeySet parses a JSON Web Key Set, a JSON object with a
+// "keys" member that lists JSON Web Keys. As required by RFC 7517,
+// Section 5, keys of unsupported types are skipped. Invalid keys of
+// supported types are an error.
+func ParseJSONWebKeySet(data []byte) ([]*JSONWebKey, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil || set.Keys == nil {
+		return nil, errors.New("jose: malformed JSON Web Key Set")
+	}
+	var keys []*JSONWebKey
+	for _, raw := range set.Keys {
+		var j jsonWebKey
+		if err := json.Unmarshal(raw, &j); err != nil {
+			return nil, errors.New("jose: malformed JSON Web Key")
+		}
+		k, err := j.key()
+		if err == errUnsupportedKeyType {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Public returns a copy of k with only the public key, or nil if k holds a
+// symmetric key.
+func (k *JSONWebKey) Public() *JSONWebKey {
+	public := *k
+	switch key := k.Key.(type) {
+	case *ecdsa.PrivateKey:
+		public.Key = &key.PublicKey
+	case *rsa.PrivateKey:
+		public.Key = &key.PublicKey
+	case ed25519.PrivateKey:
+		public.Key = key.Public()
+	case []byte:
+		return nil
+	}
+	return &public
+}
+
+// MarshalJSON implements json.Marshaler, encoding k as a JSON Web Key.
+func (k *JSONWebKey) MarshalJSON() ([]byte, error) {
+	j := jsonWebKey{KeyID: k.KeyID, Algorithm: k.Algorithm, Use: k.Use}
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		if err := j.setECDSA(key); err != nil {
+			return nil, err
+		}
+	case *ecdsa.PrivateKey:
+		if err := j.setECDSA(&key.PublicKey); err != nil {
+			return nil, err
+		}
+		j.D = b64.EncodeToString(key.D.FillBytes(make([]byte, curveSize(key.Curve))))
+	case *rsa.PublicKey:
+		j.setRSA(key)
+	case *rsa.PrivateKey:
+		if len(key.Primes) != 2 {
+			return nil, errors.New("jose: multi-prime RSA keys are not supported")
+		}
+		key.Precompute()
+		j.setRSA(&key.PublicKey)
+		j.D = b64.EncodeToString(key.D.Bytes())
+		j.P = b64.EncodeToString(key.Primes[0].Bytes())
+		j.Q = b64.EncodeToString(key.Primes[1].Bytes())
+		j.DP = b64.EncodeToString(key.Precomputed.Dp.Bytes())
+		j.DQ = b64.EncodeToString(key.Precomputed.Dq.Bytes())
+		j.QI = b64.EncodeToString(key.Precomputed.Qinv.Bytes())
+	case ed25519.PublicKey:
+		j.KeyType, j.Curve = "OKP", "Ed25519"
+		j.X = b64.EncodeToString(key)
+	case ed25519.PrivateKey:
+		j.KeyType, j.Curve = "OKP", "Ed25519"
+		j.X = b64.EncodeToString(key.Public().(ed25519.PublicKey))
+		j.D = b64.EncodeToString(key.Seed())
+	case []byte:
+		j.KeyType = "oct"
+		j.K = b64.EncodeToString(key)
+	default:
+		return nil, errUnsupportedKeyType
+	}
+	return json.Marshal(&j)
+}
+
+func (j *jsonWebKey) setECDSA(key *ecdsa.PublicKey) error {
+	crv, err := curveName(key.Curve)
+	if err != nil {
+		return err
+	}
+	size := curveSize(key.Curve)
+	j.KeyType, j.Curve = "EC", crv
+	j.X = b64.EncodeToString(key.X.FillBytes(make([]byte, size)))
+	j.Y = b64.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	return nil
+}
+
+func (j *jsonWebKey) setRSA(key *rsa.PublicKey) {
+	j.KeyType = "RSA"
+	j.N = b64.EncodeToString(key.N.Bytes())
+	j.E = b64.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+}
+
+func (j *jsonWebKey) key() (*JSONWebKey, error) {
+	k := &JSONWebKey{KeyID: j.KeyID, Algorithm: j.Algorithm, Use: j.Use}
+	var err error
+	switch j.KeyType {
+	case "EC":
+		k.Key, err = j.ecdsaKey()
+	case "RSA":
+		k.Key, err = j.rsaKey()
+	case "OKP":
+		k.Key, err = j.ed25519Key()
+	case "oct":
+		k.Key, err = decodeMember(j.K)
+		if err == nil && len(k.Key.([]byte)) == 0 {
+			err = errors.New("jose: empty symmetric key")
+		}
+	default:
+		return nil, errUnsupportedKeyType
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (j *jsonWebKey) ecdsaKey() (any, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	switch j.Curve {
+	case "P-256":
+		curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, errors.New("jose: unsupported elliptic curve " + j.Curve)
+	}
+	size := curveSize(curve)
+	x, err := decodeFixed(j.X, size)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFixed(j.Y, size)
+	if err != nil {
+		return nil, err
+	}
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, errors.New("jose: invalid EC public key")
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if j.D == "" {
+		return pub, nil
+	}
+	d, err := decodeFixed(j.D, size)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := ecdhCurve.NewPrivateKey(d)
+	if err != nil {
+		return nil, errors.New("jose: invalid EC private key")
+	}
+	if !bytes.Equal(priv.PublicKey().Bytes(), point) {
+		return nil, errors.New("jose: EC private key does not match public key")
+	}
+	return &ecdsa.PrivateKey{PublicKey: *pub, D: new(big.Int).SetBytes(d)}, nil
+}
+
+func (j *jsonWebKey) rsaKey() (any, error) {
+	if j.Other != nil {
+		return nil, errors.New("jose: multi-prime RSA keys are not supported")
+	}
+	n, err := decodeInt(j.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeInt(j.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 || e.Bit(0) != 1 {
+		return nil, errors.New("jose: unsupported RSA public exponent")
+	}
+	pub := &rsa.PublicKey{N: n, E: int(e.Int64())}
+	if j.D == "" {
+		if j.P != "" || j.Q != "" || j.DP != "" || j.DQ != "" || j.QI != "" {
+			return nil, errors.New("jose: RSA private key without private exponent")
+		}
+		return pub, nil
+	}
+	var v [6]*big.Int
+	for i, s := range []string{j.D, j.P, j.Q, j.DP, j.DQ, j.QI} {
+		if v[i], err = decodeInt(s); err != nil {
+			return nil, errors.New("jose: RSA private keys must include all CRT parameters")
+		}
+	}
+	priv := &rsa.PrivateKey{PublicKey: *pub, D: v[0], Primes: []*big.Int{v[1], v[2]}}
+	if err := priv.Validate(); err != nil {
+		return nil, errors.New("jose: invalid RSA private key")
+	}
+	priv.Precompute()
+	if priv.Precomputed.Dp.Cmp(v[3]) != 0 || priv.Precomputed.Dq.Cmp(v[4]) != 0 ||
+		priv.Precomputed.Qinv.Cmp(v[5]) != 0 {
+		return nil, errors.New("jose: inconsistent RSA CRT parameters")
+	}
+	return priv, nil
+}
+
+func (j *jsonWebKey) ed25519Key() (any, error) {
+	if j.Curve != "Ed25519" {
+		return nil, errors.New("jose: unsupported OKP curve " + j.Curve)
+	}
+	x, err := decodeFixed(j.X, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if j.D == "" {
+		return ed25519.PublicKey(x), nil
+	}
+	d, err := decodeFixed(j.D, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+	priv := ed25519.NewKeyFromSeed(d)
+	if !bytes.Equal(priv.Public().(ed25519.PublicKey), x) {
+		return nil, errors.New("jose: Ed25519 private key does not match public key")
+	}
+	return priv, nil
+}
+
+func decodeMember(s string) ([]byte, error) {
+	b, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("jose: malformed JSON Web Key member")
+	}
+	return b, nil
+}
+
+func decodeFixed(s string, size int) ([]byte, error) {
+	b, err := decodeMember(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, errors.New("jose: JSON Web Key member has the wrong length")
+	}
+	return b, nil
+}
+
+// decodeInt decodes a positive integer, which RFC 7518 requires to be
+// encoded in the minimum number of octets.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := decodeMember(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || b[0] == 0 {
+		return nil, errors.New("jose: malformed JSON Web Key integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func curveName(c elliptic.Curve) (string, error) {
+	switch c {
+	case elliptic.P256():
+		return "P-256", nil
+	case elliptic.P384():
+		return "P-384", nil
+	case elliptic.P521():
+		return "P-521", nil
+	}
+	return "", errors.New("jose: unsupported elliptic curve")
+}
+
+func curveSize(c elliptic.Curve) int {
+	return (c.Params().BitSize + 7) / 8
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+type sigKind int
+
+const (
+	sigHMAC sigKind = iota
+	sigPKCS1v15
+	sigPSS
+	sigECDSA
+	sigEdDSA
+)
+
+type sigAlgorithm struct {
+	kind  sigKind
+	hash  crypto.Hash
+	curve elliptic.Curve // for ECDSA
+}
+
+func signatureAlgorithm(name string) (sigAlgorithm, error) {
+	switch name {
+	case "HS256":
+		return sigAlgorithm{kind: sigHMAC, hash: crypto.SHA256}, nil
+	case "HS384":
+		return sigAlgorithm{kind: sigHMAC, hash: crypto.SHA384}, nil
+	case "HS512":
+		return sigAlgorithm{kind: sigHMAC, hash: crypto.SHA512}, nil
+	case "RS256":
+		return sigAlgorithm{kind: sigPKCS1v15, hash: crypto.SHA256}, nil
+	case "RS384":
+		return sigAlgorithm{kind: sigPKCS1v15, hash: crypto.SHA384}, nil
+	case "RS512":
+		return sigAlgorithm{kind: sigPKCS1v15, hash: crypto.SHA512}, nil
+	case "PS256":
+		return sigAlgorithm{kind: sigPSS, hash: crypto.SHA256}, nil
+	case "PS384":
+		return sigAlgorithm{kind: sigPSS, hash: crypto.SHA384}, nil
+	case "PS512":
+		return sigAlgorithm{kind: sigPSS, hash: crypto.SHA512}, nil
+	case "ES256":
+		return sigAlgorithm{kind: sigECDSA, hash: crypto.SHA256, curve: elliptic.P256()}, nil
+	case "ES384":
+		return sigAlgorithm{kind: sigECDSA, hash: crypto.SHA384, curve: elliptic.P384()}, nil
+	case "ES512":
+		return sigAlgorithm{kind: sigECDSA, hash: crypto.SHA512, curve: elliptic.P521()}, nil
+	case "EdDSA":
+		return sigAlgorithm{kind: sigEdDSA}, nil
+	}
+	return sigAlgorithm{}, errors.New("jose: unsupported signature algorithm " + name)
+}
+
+// checkKey checks that the public key pub, or the HMAC key, is appropriate
+// for the algorithm.
+func (alg sigAlgorithm) checkKey(pub any) error {
+	switch alg.kind {
+	case sigHMAC:
+		if key, ok := pub.([]byte); !ok || len(key) < alg.hash.Size() {
+			return errors.New("jose: HMAC algorithms require a []byte key at least as long as the hash")
+		}
+	case sigPKCS1v15, sigPSS:
+		if key, ok := pub.(*rsa.PublicKey); !ok || key.N.BitLen() < 2048 {
+			return errors.New("jose: RSA algorithms require an RSA key of at least 2048 bits")
+		}
+	case sigECDSA:
+		if key, ok := pub.(*ecdsa.PublicKey); !ok || key.Curve != alg.curve {
+			return errors.New("jose: ECDSA algorithm does not match the key")
+		}
+	case sigEdDSA:
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return errors.New("jose: EdDSA requires an Ed25519 key")
+		}
+	}
+	return nil
+}
+
+func (alg sigAlgorithm) digest(input string) []byte {
+	h := alg.hash.New()
+	h.Write([]byte(input))
+	return h.Sum(nil)
+}
+
+// Sign returns the compact serialization of a JWS of payload with the
+// protected header h, signed with key according to h.Algorithm.
+//
+// For the HMAC algorithms key is a []byte. For the others, it is a
+// crypto.Signer, such as an *rsa.PrivateKey, *ecdsa.PrivateKey, or
+// ed25519.PrivateKey, or a signer returned by a key management service,
+// with a public key of the matching type.
+func Sign(payload []byte, key any, h *Header) (string, error) {
+	if h.Encryption != "" {
+		return "", errors.New("jose: JWS header has an enc parameter")
+	}
+	alg, err := signatureAlgorithm(h.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	pub := key
+	if alg.kind != sigHMAC {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return "", errors.New("jose: key is not a crypto.Signer")
+		}
+		pub = signer.Public()
+	}
+	if err := alg.checkKey(pub); err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(h.raw())
+	if err != nil {
+		return "", err
+	}
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+
+	var sig []byte
+	switch alg.kind {
+	case sigHMAC:
+		mac := hmac.New(alg.hash.New, key.([]byte))
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case sigPKCS1v15:
+		sig, err = key.(crypto.Signer).Sign(rand.Reader, alg.digest(input), alg.hash)
+	case sigPSS:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: alg.hash}
+		sig, err = key.(crypto.Signer).Sign(rand.Reader, alg.digest(input), opts)
+	case sigECDSA:
+		sig, err = key.(crypto.Signer).Sign(rand.Reader, alg.digest(input), alg.hash)
+		if err == nil {
+			sig, err = ecdsaSignatureFromASN1(sig, curveSize(alg.curve))
+		}
+	case sigEdDSA:
+		sig, err = key.(crypto.Signer).Sign(rand.Reader, []byte(input), crypto.Hash(0))
+	}
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64.EncodeToString(sig), nil
+}
+
+// Verify verifies the compact serialization of a JWS with key, and returns
+// its payload and protected header. The header's algorithm must be alg.
+//
+// For the HMAC algorithms key is a []byte. For the others, it is an
+// *rsa.PublicKey, *ecdsa.PublicKey, or ed25519.PublicKey.
+//
+// If the signature is invalid, Verify returns ErrVerification.
+func Verify(token string, key any, alg string) (payload []byte, h *Header, err error) {
+	a, err := signatureAlgorithm(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.checkKey(key); err != nil {
+		return nil, nil, err
+	}
+	parts, err := splitToken(token, 3)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := parseHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if raw.Algorithm != alg {
+		return nil, nil, errors.New("jose: token algorithm " + raw.Algorithm + " is not " + alg)
+	}
+	if raw.Encryption != "" {
+		return nil, nil, errMalformed
+	}
+	payload, err = b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errMalformed
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, errMalformed
+	}
+
+	input := parts[0] + "." + parts[1]
+	ok := false
+	switch a.kind {
+	case sigHMAC:
+		mac := hmac.New(a.hash.New, key.([]byte))
+		mac.Write([]byte(input))
+		ok = subtle.ConstantTimeCompare(mac.Sum(nil), sig) == 1
+	case sigPKCS1v15:
+		ok = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), a.hash, a.digest(input), sig) == nil
+	case sigPSS:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: a.hash}
+		ok = rsa.VerifyPSS(key.(*rsa.PublicKey), a.hash, a.digest(input), sig, opts) == nil
+	case sigECDSA:
+		size := curveSize(a.curve)
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(key.(*ecdsa.PublicKey), a.digest(input), r, s)
+		}
+	case sigEdDSA:
+		ok = ed25519.Verify(key.(ed25519.PublicKey), []byte(input), sig)
+	}
+	if !ok {
+		return nil, nil, ErrVerification
+	}
+	return payload, raw.header(), nil
+}
+
+// ecdsaSignatureFromASN1 converts an ASN.1 ECDSA signature, as returned by
+// crypto.Signer, to the fixed-size R || S encoding of RFC 7518,
+// Section 3.4.
+func ecdsaSignatureFromASN1(sig []byte, size int) ([]byte, error) {
+	var r, s big.Int
+	input := cryptobyte.String(sig)
+	var inner cryptobyte.String
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) || !input.Empty() ||
+		!inner.ReadASN1Integer(&r) || !inner.ReadASN1Integer(&s) || !inner.Empty() ||
+		r.Sign() <= 0 || s.Sign() <= 0 || r.BitLen() > 8*size || s.BitLen() > 8*size {
+		return nil, errors.New("jose: signer returned a malformed ECDSA signature")
+	}
+	out := make([]byte, 2*size)
+	r.FillBytes(out[:size])
+	s.FillBytes(out[size:])
+	return out, nil
+}
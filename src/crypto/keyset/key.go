@@ -0,0 +1,210 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyset
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// A KeyType is the algorithm of the keys of a keyset.
+type KeyType uint8
+
+const (
+	// AES256GCM keys encrypt with AES-256 in GCM mode, with random 96-bit
+	// nonces.
+	AES256GCM KeyType = 1
+	// Ed25519 keys sign with Ed25519.
+	Ed25519 KeyType = 2
+	// ECDSAP256 keys sign with ECDSA over P-256 and SHA-256, with ASN.1
+	// signatures.
+	ECDSAP256 KeyType = 3
+)
+
+func (t KeyType) String() string {
+	switch t {
+	case AES256GCM:
+		return "AES256GCM"
+	case Ed25519:
+		return "Ed25519"
+	case ECDSAP256:
+		return "ECDSAP256"
+	}
+	return "KeyType(" + strconv.Itoa(int(t)) + ")"
+}
+
+func (t KeyType) valid() bool {
+	return t == AES256GCM || t == Ed25519 || t == ECDSAP256
+}
+
+func (t KeyType) isSignature() bool {
+	return t == Ed25519 || t == ECDSAP256
+}
+
+// A KeyStatus is the status of a key in a keyset.
+type KeyStatus uint8
+
+const (
+	// Enabled keys are used to decrypt and verify.
+	Enabled KeyStatus = 1
+	// Disabled keys are kept in the keyset, but are not used. They can be
+	// enabled again, for example if a key was disabled too early.
+	Disabled KeyStatus = 2
+)
+
+func (s KeyStatus) String() string {
+	switch s {
+	case Enabled:
+		return "Enabled"
+	case Disabled:
+		return "Disabled"
+	}
+	return "KeyStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// A key is one version of the keys of a keyset. Its fields other than
+// status are immutable.
+type key struct {
+	id     uint32
+	status KeyStatus
+
+	// material is the serialization of the key: the AES key, the Ed25519
+	// seed or public key, or the P-256 scalar or uncompressed point.
+	material []byte
+
+	aead   cipher.AEAD
+	signer crypto.Signer // nil for public keys
+	public crypto.PublicKey
+}
+
+// generateKey returns a new random key of type t.
+func generateKey(t KeyType, id uint32) (*key, error) {
+	var material []byte
+	switch t {
+	case AES256GCM:
+		material = make([]byte, 32)
+	case Ed25519:
+		material = make([]byte, ed25519.SeedSize)
+	case ECDSAP256:
+		k, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		material = k.Bytes()
+	default:
+		return nil, errors.New("keyset: unsupported key type " + t.String())
+	}
+	if t != ECDSAP256 {
+		if _, err := io.ReadFull(rand.Reader, material); err != nil {
+			return nil, err
+		}
+	}
+	return newKey(t, id, Enabled, material, false)
+}
+
+// newKey returns the key with the given material, which is a public key
+// if public is true.
+func newKey(t KeyType, id uint32, status KeyStatus, material []byte, public bool) (*key, error) {
+	k := &key{id: id, status: status, material: material}
+	switch {
+	case t == AES256GCM && !public:
+		block, err := aes.NewCipher(material)
+		if err != nil || len(material) != 32 {
+			return nil, errors.New("keyset: invalid AES-256-GCM key")
+		}
+		k.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	case t == Ed25519 && !public:
+		if len(material) != ed25519.SeedSize {
+			return nil, errors.New("keyset: invalid Ed25519 private key")
+		}
+		priv := ed25519.NewKeyFromSeed(material)
+		k.signer, k.public = priv, priv.Public()
+	case t == Ed25519 && public:
+		if len(material) != ed25519.PublicKeySize {
+			return nil, errors.New("keyset: invalid Ed25519 public key")
+		}
+		k.public = ed25519.PublicKey(material)
+	case t == ECDSAP256 && !public:
+		priv, err := ecdh.P256().NewPrivateKey(material)
+		if err != nil {
+			return nil, errors.New("keyset: invalid ECDSA P-256 private key")
+		}
+		pub := ecdsaPublicKey(priv.PublicKey().Bytes())
+		k.signer = &ecdsa.PrivateKey{PublicKey: *pub, D: new(big.Int).SetBytes(material)}
+		k.public = pub
+	case t == ECDSAP256 && public:
+		if _, err := ecdh.P256().NewPublicKey(material); err != nil {
+			return nil, errors.New("keyset: invalid ECDSA P-256 public key")
+		}
+		k.public = ecdsaPublicKey(material)
+	default:
+		return nil, errors.New("keyset: unsupported key type " + t.String())
+	}
+	return k, nil
+}
+
+// ecdsaPublicKey returns the P-256 public key of a valid uncompressed
+// point.
+func ecdsaPublicKey(point []byte) *ecdsa.PublicKey {
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(point[1:33]),
+		Y:     new(big.Int).SetBytes(point[33:]),
+	}
+}
+
+// publicKey returns the public version of a signature key.
+func (k *key) publicKey(t KeyType) *key {
+	pk := &key{id: k.id, status: k.status, public: k.public}
+	switch t {
+	case Ed25519:
+		pk.material = []byte(k.public.(ed25519.PublicKey))
+	case ECDSAP256:
+		pub, err := k.public.(*ecdsa.PublicKey).ECDH()
+		if err != nil {
+			panic("keyset: internal error: invalid ECDSA public key")
+		}
+		pk.material = pub.Bytes()
+	default:
+		panic("keyset: internal error: public key of a symmetric key")
+	}
+	return pk
+}
+
+func (k *key) sign(t KeyType, message []byte) ([]byte, error) {
+	switch t {
+	case Ed25519:
+		return k.signer.Sign(rand.Reader, message, crypto.Hash(0))
+	case ECDSAP256:
+		h := sha256.Sum256(message)
+		return k.signer.Sign(rand.Reader, h[:], crypto.SHA256)
+	}
+	panic("keyset: internal error: signing with a symmetric key")
+}
+
+func (k *key) verify(t KeyType, message, sig []byte) bool {
+	switch t {
+	case Ed25519:
+		return ed25519.Verify(k.public.(ed25519.PublicKey), message, sig)
+	case ECDSAP256:
+		h := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(k.public.(*ecdsa.PublicKey), h[:], sig)
+	}
+	panic("keyset: internal error: verifying with a symmetric key")
+}
@@ -0,0 +1,379 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keyset implements keysets, collections of versions of a key
+// with one primary version, which make key rotation safe by default.
+//
+// A keyset encrypts or signs with its primary key, and prefixes the output
+// with the ID of the key, so that data made with any enabled key of the
+// keyset can be decrypted or verified after the primary key changes. A
+// typical rotation adds a key with Add, distributes the keyset to every
+// reader, promotes the new key with SetPrimary, and eventually disables
+// and deletes the old key once no data depends on it.
+//
+// Keysets are stored encrypted by a key encryption key, usually held by a
+// key management service (see package crypto/kms), with Seal and Open.
+// The public keyset of a signature keyset, which holds no secrets, can be
+// stored in the clear with MarshalBinary and ParsePublic.
+//
+// The design follows the keysets of the Tink library, but the
+// serialization and the output prefix are not compatible with it.
+package keyset
+
+import (
+	"context"
+	"crypto/kms"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// prefixVersion is the first byte of ciphertexts and signatures, followed
+// by the big-endian ID of the key.
+const (
+	prefixVersion = 1
+	prefixSize    = 5
+)
+
+var (
+	// ErrDecryption is returned by Decrypt when the ciphertext was not
+	// made by an enabled key of the keyset, or was modified.
+	ErrDecryption = errors.New("keyset: decryption failed")
+
+	// ErrVerification is returned by Verify when the signature was not
+	// made by an enabled key of the keyset, or the message was modified.
+	ErrVerification = errors.New("keyset: verification failed")
+)
+
+// KeyInfo describes a key of a keyset.
+type KeyInfo struct {
+	ID      uint32
+	Type    KeyType
+	Status  KeyStatus
+	Primary bool
+}
+
+// A Keyset is a collection of keys of one type, one of which is the
+// primary key. It is safe for concurrent use by multiple goroutines.
+type Keyset struct {
+	mu      sync.RWMutex
+	typ     KeyType
+	public  bool
+	primary uint32
+	keys    []*key // in order of addition
+}
+
+// New returns a keyset with a single new primary key of type t.
+func New(t KeyType) (*Keyset, error) {
+	if !t.valid() {
+		return nil, errors.New("keyset: unsupported key type " + t.String())
+	}
+	ks := &Keyset{typ: t}
+	id, err := ks.Add()
+	if err != nil {
+		return nil, err
+	}
+	ks.primary = id
+	return ks, nil
+}
+
+// Type returns the type of the keys of ks.
+func (ks *Keyset) Type() KeyType { return ks.typ }
+
+// IsPublic reports whether ks is a public keyset, which can only verify.
+func (ks *Keyset) IsPublic() bool { return ks.public }
+
+// Primary returns the ID of the primary key of ks.
+func (ks *Keyset) Primary() uint32 {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.primary
+}
+
+// Keys describes the keys of ks, in the order they were added.
+func (ks *Keyset) Keys() []KeyInfo {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	info := make([]KeyInfo, len(ks.keys))
+	for i, k := range ks.keys {
+		info[i] = KeyInfo{ID: k.id, Type: ks.typ, Status: k.status, Primary: k.id == ks.primary}
+	}
+	return info
+}
+
+// Add generates a new enabled key, which is not the primary key, and
+// returns its ID. The key can be promoted with SetPrimary once the keyset
+// has been distributed to every party that decrypts or verifies.
+func (ks *Keyset) Add() (uint32, error) {
+	if ks.public {
+		return 0, errors.New("keyset: can't add a key to a public keyset")
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	id, err := ks.newID()
+	if err != nil {
+		return 0, err
+	}
+	k, err := generateKey(ks.typ, id)
+	if err != nil {
+		return 0, err
+	}
+	ks.keys = append(ks.keys, k)
+	return id, nil
+}
+
+// newID returns a random, non-zero ID not used by another key of ks.
+func (ks *Keyset) newID() (uint32, error) {
+	var b [4]byte
+	for {
+		if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+			return 0, err
+		}
+		id := binary.BigEndian.Uint32(b[:])
+		if id != 0 && ks.find(id) == nil {
+			return id, nil
+		}
+	}
+}
+
+// Rotate generates a new key, makes it the primary key, and returns its ID.
+// It is equivalent to Add followed by SetPrimary, and is only safe if every
+// party that decrypts or verifies is using the same Keyset.
+func (ks *Keyset) Rotate() (uint32, error) {
+	id, err := ks.Add()
+	if err != nil {
+		return 0, err
+	}
+	if err := ks.SetPrimary(id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (ks *Keyset) find(id uint32) *key {
+	for _, k := range ks.keys {
+		if k.id == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// SetPrimary makes the enabled key with the given ID the primary key.
+func (ks *Keyset) SetPrimary(id uint32) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	k := ks.find(id)
+	if k == nil {
+		return errors.New("keyset: no such key")
+	}
+	if k.status != Enabled {
+		return errors.New("keyset: the primary key must be enabled")
+	}
+	ks.primary = id
+	return nil
+}
+
+// Enable enables the key with the given ID.
+func (ks *Keyset) Enable(id uint32) error {
+	return ks.setStatus(id, Enabled)
+}
+
+// Disable disables the key with the given ID, which must not be the
+// primary key. Data made with a disabled key can't be decrypted or
+// verified.
+func (ks *Keyset) Disable(id uint32) error {
+	return ks.setStatus(id, Disabled)
+}
+
+func (ks *Keyset) setStatus(id uint32, status KeyStatus) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	k := ks.find(id)
+	if k == nil {
+		return errors.New("keyset: no such key")
+	}
+	if id == ks.primary && status != Enabled {
+		return errors.New("keyset: can't disable the primary key")
+	}
+	k.status = status
+	return nil
+}
+
+// Delete removes the key with the given ID, which must not be the primary
+// key, from ks. Data made with it can no longer be decrypted or verified.
+func (ks *Keyset) Delete(id uint32) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if id == ks.primary {
+		return errors.New("keyset: can't delete the primary key")
+	}
+	for i, k := range ks.keys {
+		if k.id == id {
+			ks.keys = append(ks.keys[:i:i], ks.keys[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("keyset: no such key")
+}
+
+// Public returns the public keyset of a signature keyset, which holds the
+// public keys of ks, and can verify but not sign.
+func (ks *Keyset) Public() (*Keyset, error) {
+	if !ks.typ.isSignature() {
+		return nil, errors.New("keyset: " + ks.typ.String() + " keysets have no public keyset")
+	}
+	if ks.public {
+		return ks, nil
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	pub := &Keyset{typ: ks.typ, public: true, primary: ks.primary}
+	for _, k := range ks.keys {
+		pub.keys = append(pub.keys, k.publicKey(ks.typ))
+	}
+	return pub, nil
+}
+
+func appendPrefix(b []byte, id uint32) []byte {
+	b = append(b, prefixVersion)
+	return binary.BigEndian.AppendUint32(b, id)
+}
+
+// lookup returns the enabled key named by the prefix of data, and the rest
+// of data, or nil if there is no such key.
+func (ks *Keyset) lookup(data []byte) (*key, []byte) {
+	if len(data) < prefixSize || data[0] != prefixVersion {
+		return nil, nil
+	}
+	k := ks.find(binary.BigEndian.Uint32(data[1:prefixSize]))
+	if k == nil || k.status != Enabled {
+		return nil, nil
+	}
+	return k, data[prefixSize:]
+}
+
+// Encrypt encrypts and authenticates plaintext and authenticates
+// additionalData with the primary key of an AES256GCM keyset.
+func (ks *Keyset) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	if ks.typ != AES256GCM {
+		return nil, errors.New("keyset: Encrypt requires an AES256GCM keyset")
+	}
+	ks.mu.RLock()
+	k := ks.find(ks.primary)
+	ks.mu.RUnlock()
+
+	prefix := appendPrefix(nil, k.id)
+	out := make([]byte, prefixSize+k.aead.NonceSize(), prefixSize+k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+	copy(out, prefix)
+	nonce := out[prefixSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(out, nonce, plaintext, append(prefix, additionalData...)), nil
+}
+
+// Decrypt decrypts and authenticates a ciphertext returned by Encrypt with
+// any enabled key of ks, and authenticates additionalData. It returns
+// ErrDecryption if the key is unknown or disabled, or if the ciphertext or
+// additionalData were modified.
+func (ks *Keyset) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	if ks.typ != AES256GCM {
+		return nil, errors.New("keyset: Decrypt requires an AES256GCM keyset")
+	}
+	ks.mu.RLock()
+	k, rest := ks.lookup(ciphertext)
+	ks.mu.RUnlock()
+	if k == nil || len(rest) < k.aead.NonceSize() {
+		return nil, ErrDecryption
+	}
+	nonce, rest := rest[:k.aead.NonceSize()], rest[k.aead.NonceSize():]
+	ad := append(ciphertext[:prefixSize:prefixSize], additionalData...)
+	plaintext, err := k.aead.Open(nil, nonce, rest, ad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+// Sign signs message with the primary key of a signature keyset, which
+// must not be a public keyset.
+func (ks *Keyset) Sign(message []byte) ([]byte, error) {
+	if !ks.typ.isSignature() {
+		return nil, errors.New("keyset: Sign requires a signature keyset")
+	}
+	if ks.public {
+		return nil, errors.New("keyset: can't sign with a public keyset")
+	}
+	ks.mu.RLock()
+	k := ks.find(ks.primary)
+	ks.mu.RUnlock()
+
+	sig, err := k.sign(ks.typ, message)
+	if err != nil {
+		return nil, err
+	}
+	return append(appendPrefix(nil, k.id), sig...), nil
+}
+
+// Verify checks that sig is a signature of message returned by Sign with
+// any enabled key of ks. It returns ErrVerification if it isn't.
+func (ks *Keyset) Verify(message, sig []byte) error {
+	if !ks.typ.isSignature() {
+		return errors.New("keyset: Verify requires a signature keyset")
+	}
+	ks.mu.RLock()
+	k, rest := ks.lookup(sig)
+	ks.mu.RUnlock()
+	if k == nil || !k.verify(ks.typ, message, rest) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// sealAD is the additional data of sealed keysets.
+var sealAD = []byte("crypto/keyset sealed keyset")
+
+// Seal serializes ks, which may hold private keys, and encrypts it with
+// envelope encryption under kek.
+func (ks *Keyset) Seal(ctx context.Context, kek kms.KeyEncryptionKey) ([]byte, error) {
+	ks.mu.RLock()
+	b := ks.marshal()
+	ks.mu.RUnlock()
+	return kms.Seal(ctx, kek, b, sealAD)
+}
+
+// Open decrypts and parses a keyset returned by Seal with kek.
+func Open(ctx context.Context, kek kms.KeyEncryptionKey, sealed []byte) (*Keyset, error) {
+	b, err := kms.Open(ctx, kek, sealed, sealAD)
+	if err != nil {
+		return nil, err
+	}
+	return parse(b)
+}
+
+// MarshalBinary serializes a public keyset. It returns an error if ks is
+// not a public keyset: keysets with secret keys must be stored with Seal.
+func (ks *Keyset) MarshalBinary() ([]byte, error) {
+	if !ks.public {
+		return nil, errors.New("keyset: only public keysets can be serialized without encryption")
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.marshal(), nil
+}
+
+// ParsePublic parses a public keyset returned by MarshalBinary.
+func ParsePublic(data []byte) (*Keyset, error) {
+	ks, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if !ks.public {
+		return nil, errors.New("keyset: not a public keyset")
+	}
+	return ks, nil
+}
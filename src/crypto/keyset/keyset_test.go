@@ -0,0 +1,220 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyset
+
+import (
+	"bytes"
+	"context"
+	"crypto/kms"
+	"errors"
+	"testing"
+)
+
+func TestEncryptRotation(t *testing.T) {
+	ks, err := New(AES256GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := ks.Primary()
+	ad := []byte("ad")
+	ct1, err := ks.Encrypt([]byte("one"), ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := ks.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.Primary() != old {
+		t.Errorf("Add changed the primary key")
+	}
+	if err := ks.SetPrimary(id); err != nil {
+		t.Fatal(err)
+	}
+	ct2, err := ks.Encrypt([]byte("two"), ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ct1[:prefixSize], ct2[:prefixSize]) {
+		t.Errorf("ciphertexts of different keys have the same prefix")
+	}
+	for ct, want := range map[*[]byte]string{&ct1: "one", &ct2: "two"} {
+		pt, err := ks.Decrypt(*ct, ad)
+		if err != nil || string(pt) != want {
+			t.Errorf("Decrypt = %q, %v; want %q", pt, err, want)
+		}
+		if _, err := ks.Decrypt(*ct, []byte("other")); err != ErrDecryption {
+			t.Errorf("Decrypt with the wrong additional data: %v", err)
+		}
+	}
+
+	if err := ks.Disable(id); err == nil {
+		t.Errorf("disabling the primary key succeeded")
+	}
+	if err := ks.Disable(old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Decrypt(ct1, ad); err != ErrDecryption {
+		t.Errorf("Decrypt with a disabled key: %v", err)
+	}
+	if err := ks.SetPrimary(old); err == nil {
+		t.Errorf("promoting a disabled key succeeded")
+	}
+	if err := ks.Enable(old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Decrypt(ct1, ad); err != nil {
+		t.Errorf("Decrypt with a re-enabled key: %v", err)
+	}
+	if err := ks.Delete(id); err == nil {
+		t.Errorf("deleting the primary key succeeded")
+	}
+	if err := ks.Delete(old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Decrypt(ct1, ad); err != ErrDecryption {
+		t.Errorf("Decrypt with a deleted key: %v", err)
+	}
+	if keys := ks.Keys(); len(keys) != 1 || keys[0].ID != id || !keys[0].Primary {
+		t.Errorf("Keys() = %v", keys)
+	}
+
+	if _, err := ks.Sign([]byte("message")); err == nil {
+		t.Errorf("Sign with an AEAD keyset succeeded")
+	}
+	if _, err := ks.Public(); err == nil {
+		t.Errorf("Public of an AEAD keyset succeeded")
+	}
+}
+
+func TestSignRotation(t *testing.T) {
+	for _, typ := range []KeyType{Ed25519, ECDSAP256} {
+		t.Run(typ.String(), func(t *testing.T) {
+			ks, err := New(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := []byte("message")
+			sig1, err := ks.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ks.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+			sig2, err := ks.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pub, err := ks.Public()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := pub.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, err = ParsePublic(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range []*Keyset{ks, pub} {
+				for _, sig := range [][]byte{sig1, sig2} {
+					if err := v.Verify(msg, sig); err != nil {
+						t.Errorf("Verify: %v", err)
+					}
+					if err := v.Verify([]byte("other"), sig); err != ErrVerification {
+						t.Errorf("Verify of the wrong message: %v", err)
+					}
+				}
+			}
+			if _, err := pub.Sign(msg); err == nil {
+				t.Errorf("Sign with a public keyset succeeded")
+			}
+			if _, err := pub.Add(); err == nil {
+				t.Errorf("Add to a public keyset succeeded")
+			}
+			if _, err := ks.MarshalBinary(); err == nil {
+				t.Errorf("MarshalBinary of a private keyset succeeded")
+			}
+
+			other, _ := New(typ)
+			if err := other.Verify(msg, sig1); err != ErrVerification {
+				t.Errorf("Verify with another keyset: %v", err)
+			}
+		})
+	}
+}
+
+func TestSeal(t *testing.T) {
+	kek, err := kms.NewLocalKey(make([]byte, 32), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, typ := range []KeyType{AES256GCM, Ed25519, ECDSAP256} {
+		ks, err := New(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old := ks.Primary()
+		ks.Rotate()
+		ks.Disable(old)
+
+		sealed, err := ks.Seal(ctx, kek)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Open(ctx, kek, sealed)
+		if err != nil {
+			t.Fatalf("%v: Open: %v", typ, err)
+		}
+		if got.Type() != typ || got.Primary() != ks.Primary() || len(got.Keys()) != 2 || got.Keys()[0].Status != Disabled {
+			t.Errorf("%v: Open returned %v, %v", typ, got.Type(), got.Keys())
+		}
+
+		if typ == AES256GCM {
+			ct, _ := ks.Encrypt([]byte("secret"), nil)
+			if pt, err := got.Decrypt(ct, nil); err != nil || string(pt) != "secret" {
+				t.Errorf("Decrypt with the opened keyset = %q, %v", pt, err)
+			}
+		} else {
+			sig, _ := got.Sign([]byte("message"))
+			if err := ks.Verify([]byte("message"), sig); err != nil {
+				t.Errorf("Verify of a signature by the opened keyset: %v", err)
+			}
+		}
+
+		sealed[len(sealed)-1] ^= 1
+		if _, err := Open(ctx, kek, sealed); !errors.Is(err, kms.ErrDecryption) {
+			t.Errorf("Open of a modified keyset: %v", err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	ks, _ := New(Ed25519)
+	pub, _ := ks.Public()
+	b, _ := pub.MarshalBinary()
+	if _, err := ParsePublic(b); err != nil {
+		t.Fatal(err)
+	}
+	for i, mutate := range []func([]byte) []byte{
+		func(b []byte) []byte { return b[:len(b)-1] },
+		func(b []byte) []byte { return append(b, 0) },
+		func(b []byte) []byte { b[0] = 2; return b },
+		func(b []byte) []byte { b[1] = byte(AES256GCM); return b },
+		func(b []byte) []byte { b[2] = 0; return b },
+		func(b []byte) []byte { b[3] ^= 1; return b },
+		func(b []byte) []byte { b[13] = byte(Disabled); return b },
+		func(b []byte) []byte { b[13] = 3; return b },
+	} {
+		if _, err := ParsePublic(mutate(bytes.Clone(b))); err == nil {
+			t.Errorf("mutation %d: ParsePublic succeeded", i)
+		}
+	}
+}
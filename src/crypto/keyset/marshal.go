@@ -0,0 +1,88 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyset
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A serialized keyset is
+//
+//	version   uint8   1
+//	type      uint8   KeyType
+//	public    uint8   0 or 1
+//	primary   uint32
+//	count     uint16
+//	keys      [count]struct {
+//	    id        uint32
+//	    status    uint8   KeyStatus
+//	    length    uint16
+//	    material  [length]byte
+//	}
+//
+// with integers in big-endian order.
+const serializationVersion = 1
+
+var errMalformed = errors.New("keyset: malformed or unsupported keyset")
+
+// marshal serializes ks. The caller must hold ks.mu.
+func (ks *Keyset) marshal() []byte {
+	b := []byte{serializationVersion, byte(ks.typ), 0}
+	if ks.public {
+		b[2] = 1
+	}
+	b = binary.BigEndian.AppendUint32(b, ks.primary)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(ks.keys)))
+	for _, k := range ks.keys {
+		b = binary.BigEndian.AppendUint32(b, k.id)
+		b = append(b, byte(k.status))
+		b = binary.BigEndian.AppendUint16(b, uint16(len(k.material)))
+		b = append(b, k.material...)
+	}
+	return b
+}
+
+func parse(b []byte) (*Keyset, error) {
+	if len(b) < 9 || b[0] != serializationVersion || b[2] > 1 {
+		return nil, errMalformed
+	}
+	ks := &Keyset{
+		typ:     KeyType(b[1]),
+		public:  b[2] == 1,
+		primary: binary.BigEndian.Uint32(b[3:]),
+	}
+	if !ks.typ.valid() || ks.public && !ks.typ.isSignature() {
+		return nil, errMalformed
+	}
+	count := int(binary.BigEndian.Uint16(b[7:]))
+	b = b[9:]
+	for i := 0; i < count; i++ {
+		if len(b) < 7 {
+			return nil, errMalformed
+		}
+		id := binary.BigEndian.Uint32(b)
+		status := KeyStatus(b[4])
+		n := int(binary.BigEndian.Uint16(b[5:]))
+		if len(b) < 7+n || id == 0 || ks.find(id) != nil ||
+			(status != Enabled && status != Disabled) {
+			return nil, errMalformed
+		}
+		material := append([]byte(nil), b[7:7+n]...)
+		b = b[7+n:]
+		k, err := newKey(ks.typ, id, status, material, ks.public)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys = append(ks.keys, k)
+	}
+	if len(b) != 0 {
+		return nil, errMalformed
+	}
+	if p := ks.find(ks.primary); p == nil || p.status != Enabled {
+		return nil, errMalformed
+	}
+	return ks, nil
+}
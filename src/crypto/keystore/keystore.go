@@ -0,0 +1,322 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keystore stores private keys and other secrets in a directory,
+// encrypted at rest with a password.
+//
+// Each entry is a file named after the entry, with the ".key" extension,
+// encrypted with AES-256-GCM under a key derived from the password with
+// Argon2id and a random per-entry salt. The name of the entry is
+// authenticated, so entries can't be renamed or swapped on disk without
+// being detected.
+//
+// An entry file starts with a header:
+//
+//	magic      [6]byte  "GOKEYS"
+//	version    uint8    1
+//	kind       uint8    1 for a private key, 2 for a secret
+//	time       uint32   Argon2id passes
+//	memory     uint32   Argon2id memory in KiB
+//	threads    uint8    Argon2id parallelism
+//	salt       [16]byte
+//	nonce      [12]byte
+//
+// followed by the AES-GCM encryption of the PKCS #8 encoding of the private
+// key, or of the secret. Integers are big-endian. The additional data is
+// the header followed by the entry name.
+//
+// A Store reads and writes the files of its directory directly, so it can
+// be shared by processes that know the password, but concurrent writes to
+// the same entry are not coordinated; the last one wins.
+package keystore
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/argon2"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDecryption is returned when an entry can't be decrypted, because the
+// password is wrong or the entry was modified.
+var ErrDecryption = errors.New("keystore: wrong password or corrupted entry")
+
+const (
+	magic   = "GOKEYS"
+	version = 1
+
+	kindPrivateKey = 1
+	kindSecret     = 2
+
+	saltSize   = 16
+	nonceSize  = 12
+	headerSize = len(magic) + 1 + 1 + 4 + 4 + 1 + saltSize + nonceSize
+
+	extension = ".key"
+)
+
+// Params are the Argon2id parameters used to derive the encryption key of
+// new entries from the password, as defined in RFC 9106. Entries record
+// the parameters they were written with, so they can be changed at any
+// time.
+type Params struct {
+	Time    uint32 // number of passes over the memory
+	Memory  uint32 // size of the memory in KiB
+	Threads uint8  // degree of parallelism
+}
+
+// defaultParams are the second recommended option of RFC 9106, Section 4.
+var defaultParams = Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+func (p *Params) check() error {
+	if p.Time < 1 || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) {
+		return errors.New("keystore: invalid Argon2id parameters")
+	}
+	return nil
+}
+
+// A Store is a directory of encrypted entries. It is safe for concurrent
+// use by multiple goroutines.
+type Store struct {
+	dir      string
+	password []byte
+	params   Params
+}
+
+// Open returns a Store for the entries in dir, creating the directory with
+// mode 0700 if it doesn't exist. New entries are encrypted with password,
+// using Argon2id with params. If params is nil, Open uses t=3, m=65536
+// (64 MiB), and p=4, as recommended by RFC 9106.
+//
+// Open does not check the password; entries written with a different
+// password fail to decrypt with ErrDecryption.
+func Open(dir string, password []byte, params *Params) (*Store, error) {
+	if params == nil {
+		params = &defaultParams
+	}
+	if err := params.check(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir:      dir,
+		password: append([]byte(nil), password...),
+		params:   *params,
+	}, nil
+}
+
+// PutKey encrypts key and stores it as the entry name, replacing any
+// existing entry. key must be an *rsa.PrivateKey, *ecdsa.PrivateKey, or
+// ed25519.PrivateKey.
+//
+// Entry names are made of ASCII letters, digits, '.', '-', and '_', and
+// don't start with '.'.
+func (s *Store) PutKey(name string, key crypto.PrivateKey) error {
+	if _, ok := key.(crypto.Signer); !ok {
+		return errors.New("keystore: private key is not a crypto.Signer")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return s.put(name, kindPrivateKey, der)
+}
+
+// Signer decrypts the private key stored as the entry name. If the entry
+// does not exist, the error satisfies errors.Is(err, fs.ErrNotExist).
+func (s *Store) Signer(name string) (crypto.Signer, error) {
+	der, err := s.get(name, kindPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("keystore: entry " + name + " is not a signing key")
+	}
+	return signer, nil
+}
+
+// PutSecret encrypts secret and stores it as the entry name, replacing any
+// existing entry. Entry names are restricted as documented on PutKey.
+func (s *Store) PutSecret(name string, secret []byte) error {
+	return s.put(name, kindSecret, secret)
+}
+
+// Secret decrypts the secret stored as the entry name. If the entry does
+// not exist, the error satisfies errors.Is(err, fs.ErrNotExist).
+func (s *Store) Secret(name string) ([]byte, error) {
+	return s.get(name, kindSecret)
+}
+
+// Delete removes the entry name.
+func (s *Store) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Names returns the names of the entries in the store, in lexical order.
+// It does not decrypt them.
+func (s *Store) Names() ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), extension)
+		if ok && f.Type().IsRegular() && validName(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (s *Store) path(name string) (string, error) {
+	if !validName(name) {
+		return "", errors.New("keystore: invalid entry name " + name)
+	}
+	return filepath.Join(s.dir, name+extension), nil
+}
+
+func validName(name string) bool {
+	if name == "" || name[0] == '.' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Store) put(name string, kind byte, plaintext []byte) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, version, kind)
+	header = binary.BigEndian.AppendUint32(header, s.params.Time)
+	header = binary.BigEndian.AppendUint32(header, s.params.Memory)
+	header = append(header, s.params.Threads)
+	random := make([]byte, saltSize+nonceSize)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return err
+	}
+	header = append(header, random...)
+	salt, nonce := random[:saltSize], random[saltSize:]
+
+	aead, err := s.aead(&s.params, salt)
+	if err != nil {
+		return err
+	}
+	data := aead.Seal(header, nonce, plaintext, additionalData(header, name))
+	return writeFile(path, data)
+}
+
+func (s *Store) get(name string, kind byte) ([]byte, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, errors.New("keystore: entry " + name + " is malformed")
+	}
+	header, ciphertext := data[:headerSize], data[headerSize:]
+	h := header[len(magic):]
+	if h[0] != version {
+		return nil, errors.New("keystore: entry " + name + " has an unsupported version")
+	}
+	if h[1] != kind {
+		if kind == kindPrivateKey {
+			return nil, errors.New("keystore: entry " + name + " is not a private key")
+		}
+		return nil, errors.New("keystore: entry " + name + " is not a secret")
+	}
+	params := Params{
+		Time:    binary.BigEndian.Uint32(h[2:]),
+		Memory:  binary.BigEndian.Uint32(h[6:]),
+		Threads: h[10],
+	}
+	if err := params.check(); err != nil {
+		return nil, errors.New("keystore: entry " + name + " is malformed")
+	}
+	salt := h[11 : 11+saltSize]
+	nonce := h[11+saltSize:]
+
+	aead, err := s.aead(&params, salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(header, name))
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+func (s *Store) aead(p *Params, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(s.password, salt, p.Time, p.Memory, p.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(header []byte, name string) []byte {
+	ad := make([]byte, 0, len(header)+len(name))
+	ad = append(ad, header...)
+	return append(ad, name...)
+}
+
+// writeFile atomically replaces the file at path with data, so that a
+// failed write doesn't destroy an existing entry.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testParams are cheap parameters, to keep the tests fast.
+var testParams = &Params{Time: 1, Memory: 64, Threads: 1}
+
+func testStore(t *testing.T) *Store {
+	s, err := Open(t.TempDir(), []byte("password"), testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKeys(t *testing.T) {
+	s := testStore(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecKey, "ed25519": edKey}
+	for name, key := range keys {
+		if err := s.PutKey(name, key); err != nil {
+			t.Fatalf("PutKey(%q): %v", name, err)
+		}
+	}
+	for name, key := range keys {
+		signer, err := s.Signer(name)
+		if err != nil {
+			t.Fatalf("Signer(%q): %v", name, err)
+		}
+		if !key.(interface{ Equal(crypto.PrivateKey) bool }).Equal(signer) {
+			t.Errorf("Signer(%q) returned a different key", name)
+		}
+	}
+
+	// The handle signs with the stored key.
+	signer, err := s.Signer("ecdsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], sig) {
+		t.Errorf("signature by the stored key does not verify")
+	}
+
+	names, err := s.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ecdsa", "ed25519", "rsa"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Names() = %q, want %q", names, want)
+	}
+
+	ecdhKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutKey("x25519", ecdhKey); err == nil {
+		t.Errorf("PutKey with an ECDH key succeeded")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	s := testStore(t)
+	secret := []byte("hunter2")
+	if err := s.PutSecret("db-password", secret); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Secret("db-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("Secret = %q, want %q", got, secret)
+	}
+	if _, err := s.Signer("db-password"); err == nil {
+		t.Errorf("Signer of a secret succeeded")
+	}
+
+	// Replacing an entry.
+	if err := s.PutSecret("db-password", []byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Secret("db-password"); err != nil || string(got) != "correct horse" {
+		t.Errorf("Secret after replacing = %q, %v", got, err)
+	}
+
+	if err := s.Delete("db-password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Secret("db-password"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Secret of a deleted entry = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	s := testStore(t)
+	if err := s.PutSecret("a", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	other, err := Open(s.dir, []byte("passw0rd"), testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Secret("a"); err != ErrDecryption {
+		t.Errorf("Secret with the wrong password = %v, want ErrDecryption", err)
+	}
+
+	// Entries record their parameters, so they can be read with others.
+	again, err := Open(s.dir, []byte("password"), &Params{Time: 2, Memory: 128, Threads: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.Secret("a"); err != nil {
+		t.Errorf("Secret with different parameters: %v", err)
+	}
+}
+
+func TestTampering(t *testing.T) {
+	s := testStore(t)
+	if err := s.PutSecret("a", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(s.dir, "a.key")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Renamed entries are detected.
+	if err := os.WriteFile(filepath.Join(s.dir, "b.key"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Secret("b"); err != ErrDecryption {
+		t.Errorf("Secret of a renamed entry = %v, want ErrDecryption", err)
+	}
+
+	// Modifying the Argon2id parameters can make the key derivation
+	// arbitrarily expensive, so only the passes are incremented.
+	params := data[len(magic)+2 : len(magic)+2+9]
+	params[3]++
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Secret("a"); err != ErrDecryption {
+		t.Errorf("Secret with modified parameters = %v, want ErrDecryption", err)
+	}
+	params[3]--
+
+	for i := range data {
+		if i == len(magic) {
+			continue // the version is checked separately
+		}
+		if len(magic)+2 <= i && i < len(magic)+2+9 {
+			continue
+		}
+		modified := bytes.Clone(data)
+		modified[i] ^= 1
+		if err := os.WriteFile(path, modified, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Secret("a"); err == nil {
+			t.Errorf("Secret succeeded with byte %d modified", i)
+		}
+	}
+
+	data[len(magic)] = 2
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Secret("a"); err == nil || err == ErrDecryption {
+		t.Errorf("Secret of a version 2 entry = %v, want a version error", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	s := testStore(t)
+	for _, name := range []string{"", ".hidden", "a/b", "../a", "a b", "é"} {
+		if err := s.PutSecret(name, nil); err == nil {
+			t.Errorf("PutSecret(%q) succeeded", name)
+		}
+	}
+	if err := s.PutSecret("Key_1.v2-old", nil); err != nil {
+		t.Errorf("PutSecret: %v", err)
+	}
+	// Unrelated files are ignored.
+	if err := os.WriteFile(filepath.Join(s.dir, "README"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	names, err := s.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Key_1.v2-old"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Names() = %q, want %q", names, want)
+	}
+}
+
+func TestBadParams(t *testing.T) {
+	for _, p := range []Params{
+		{Time: 0, Memory: 64, Threads: 1},
+		{Time: 1, Memory: 64, Threads: 0},
+		{Time: 1, Memory: 4, Threads: 1},
+	} {
+		if _, err := Open(t.TempDir(), []byte("password"), &p); err == nil {
+			t.Errorf("Open with %#v succeeded", p)
+		}
+	}
+}
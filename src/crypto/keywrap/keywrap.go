@@ -0,0 +1,193 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keywrap implements the AES Key Wrap (KW) and AES Key Wrap with
+// Padding (KWP) modes of NIST SP 800-38F, also specified by RFC 3394 and
+// RFC 5649.
+//
+// The modes encrypt and authenticate a key under a key encryption key,
+// without a nonce, and are used by key management protocols such as CMS,
+// KMIP, and PKCS #11. Other uses should prefer an AEAD such as AES-GCM.
+//
+// The key encryption key is an AES key of 16, 24, or 32 bytes. When a
+// cryptographic backend that implements the modes is in use, wrapping and
+// unwrapping are performed by the backend. Otherwise, the modes are
+// implemented over the crypto/aes block cipher, which is itself provided
+// by the backend, if any.
+package keywrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/boring"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"internal/cryptometrics"
+)
+
+var errUnwrap = errors.New("keywrap: integrity check failed")
+
+// defaultIV is the initial value of KW, from SP 800-38F, Section 6.2.
+var defaultIV = [8]byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// paddingICV is the first half of the initial value of KWP, which is
+// followed by the length of the key, from SP 800-38F, Section 6.3.
+var paddingICV = [4]byte{0xa6, 0x59, 0x59, 0xa6}
+
+// Wrap wraps key with the key encryption key kek using KW. The length of
+// key must be a multiple of 8 bytes, and at least 16 bytes. The wrapped key
+// is 8 bytes longer than key.
+func Wrap(kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, errors.New("keywrap: key length must be a multiple of 8 bytes and at least 16 bytes")
+	}
+	if boring.Enabled {
+		if boring.SupportsAESKeyWrap() {
+			return boring.WrapKeyAES(kek, key, false)
+		}
+		boring.RecordFallback(cryptometrics.AESKW, "unsupported by the module")
+	}
+	b, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 8+len(key))
+	copy(out[8:], key)
+	wrap(b, defaultIV, out)
+	return out, nil
+}
+
+// Unwrap unwraps wrapped, which was returned by Wrap, with the key
+// encryption key kek. It returns an error if wrapped was not made with kek,
+// or was modified.
+func Unwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, errors.New("keywrap: invalid wrapped key length")
+	}
+	if boring.Enabled {
+		if boring.SupportsAESKeyWrap() {
+			return boring.UnwrapKeyAES(kek, wrapped, false)
+		}
+		boring.RecordFallback(cryptometrics.AESKW, "unsupported by the module")
+	}
+	b, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+	unwrap(b, out)
+	if subtle.ConstantTimeCompare(out[:8], defaultIV[:]) != 1 {
+		subtle.Zeroize(out)
+		return nil, errUnwrap
+	}
+	return out[8:], nil
+}
+
+// WrapWithPadding wraps key with the key encryption key kek using KWP,
+// which accepts keys of any non-zero length. The wrapped key is between 8
+// and 15 bytes longer than key.
+func WrapWithPadding(kek, key []byte) ([]byte, error) {
+	if len(key) == 0 || uint64(len(key)) > 1<<32-1 {
+		return nil, errors.New("keywrap: invalid key length")
+	}
+	if boring.Enabled {
+		if boring.SupportsAESKeyWrap() {
+			return boring.WrapKeyAES(kek, key, true)
+		}
+		boring.RecordFallback(cryptometrics.AESKW, "unsupported by the module")
+	}
+	b, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	var iv [8]byte
+	copy(iv[:], paddingICV[:])
+	binary.BigEndian.PutUint32(iv[4:], uint32(len(key)))
+	out := make([]byte, 8+(len(key)+7)/8*8)
+	copy(out[8:], key)
+	if len(out) == 16 {
+		// A single block of padded key is encrypted with the block cipher.
+		copy(out, iv[:])
+		b.Encrypt(out, out)
+		return out, nil
+	}
+	wrap(b, iv, out)
+	return out, nil
+}
+
+// UnwrapWithPadding unwraps wrapped, which was returned by WrapWithPadding,
+// with the key encryption key kek. It returns an error if wrapped was not
+// made with kek, or was modified.
+func UnwrapWithPadding(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, errors.New("keywrap: invalid wrapped key length")
+	}
+	if boring.Enabled {
+		if boring.SupportsAESKeyWrap() {
+			return boring.UnwrapKeyAES(kek, wrapped, true)
+		}
+		boring.RecordFallback(cryptometrics.AESKW, "unsupported by the module")
+	}
+	b, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+	if len(out) == 16 {
+		b.Decrypt(out, out)
+	} else {
+		unwrap(b, out)
+	}
+
+	// Check the ICV, that the length of the key is within the last
+	// semiblock, and that the padding is zero.
+	n := uint64(binary.BigEndian.Uint32(out[4:8]))
+	padded := uint64(len(out) - 8)
+	if subtle.ConstantTimeCompare(out[:4], paddingICV[:]) != 1 ||
+		n > padded || n <= padded-8 ||
+		subtle.ConstantTimeCompare(out[8+n:], make([]byte, padded-n)) != 1 {
+		subtle.Zeroize(out)
+		return nil, errUnwrap
+	}
+	return out[8 : 8+n], nil
+}
+
+// wrap applies the wrapping function W of SP 800-38F, Section 6.1, to
+// out, whose first semiblock is replaced by iv.
+func wrap(b cipher.Block, iv [8]byte, out []byte) {
+	n := len(out)/8 - 1
+	var buf [16]byte
+	copy(buf[:8], iv[:])
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[8:], out[i*8:i*8+8])
+			b.Encrypt(buf[:], buf[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	copy(out[:8], buf[:8])
+}
+
+// unwrap applies the unwrapping function W⁻¹ of SP 800-38F, Section 6.1,
+// to out, leaving the recovered initial value in its first semiblock.
+func unwrap(b cipher.Block, out []byte) {
+	n := len(out)/8 - 1
+	var buf [16]byte
+	copy(buf[:8], out[:8])
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(buf[8:], out[i*8:i*8+8])
+			b.Decrypt(buf[:], buf[:])
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	copy(out[:8], buf[:8])
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keywrap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var wrapTests = []struct {
+	pad               bool
+	kek, key, wrapped string
+}{
+	// RFC 3394, Section 4.
+	{
+		false,
+		"000102030405060708090a0b0c0d0e0f",
+		"00112233445566778899aabbccddeeff",
+		"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+	},
+	{
+		false,
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"00112233445566778899aabbccddeeff0001020304050607",
+		"031d33264e15d33268f24ec260743edce1c6c7ddee725a936ba814915c6762d2",
+	},
+	{
+		false,
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+		"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+	},
+	// RFC 5649, Section 6.
+	{
+		true,
+		"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+		"c37b7e6492584340bed12207808941155068f738",
+		"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+	},
+	{
+		true,
+		"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+		"466f7250617369",
+		"afbeb0f07dfbf5419200f2ccb50bb24f",
+	},
+}
+
+func TestWrap(t *testing.T) {
+	for i, tt := range wrapTests {
+		kek, key, want := decodeHex(t, tt.kek), decodeHex(t, tt.key), decodeHex(t, tt.wrapped)
+		wrapFn, unwrapFn := Wrap, Unwrap
+		if tt.pad {
+			wrapFn, unwrapFn = WrapWithPadding, UnwrapWithPadding
+		}
+
+		wrapped, err := wrapFn(kek, key)
+		if err != nil {
+			t.Fatalf("#%d: wrap: %v", i, err)
+		}
+		if !bytes.Equal(wrapped, want) {
+			t.Errorf("#%d: wrap = %x, want %x", i, wrapped, want)
+		}
+		key2, err := unwrapFn(kek, want)
+		if err != nil {
+			t.Fatalf("#%d: unwrap: %v", i, err)
+		}
+		if !bytes.Equal(key2, key) {
+			t.Errorf("#%d: unwrap = %x, want %x", i, key2, key)
+		}
+
+		want[len(want)-1] ^= 1
+		if _, err := unwrapFn(kek, want); err == nil {
+			t.Errorf("#%d: unwrap of a modified key succeeded", i)
+		}
+		kek[0] ^= 1
+		if _, err := unwrapFn(kek, wrapped); err == nil {
+			t.Errorf("#%d: unwrap with the wrong key succeeded", i)
+		}
+	}
+}
+
+func TestWrapWithPaddingLengths(t *testing.T) {
+	kek := bytes.Repeat([]byte{0x42}, 16)
+	for n := 1; n <= 40; n++ {
+		key := bytes.Repeat([]byte{byte(n)}, n)
+		wrapped, err := WrapWithPadding(kek, key)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if want := 8 + (n+7)/8*8; len(wrapped) != want {
+			t.Errorf("%d bytes: wrapped to %d bytes, want %d", n, len(wrapped), want)
+		}
+		got, err := UnwrapWithPadding(kek, wrapped)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("%d bytes: unwrap = %x, %v, want %x", n, got, err, key)
+		}
+		// A KWP key is not a valid KW key, even when it needs no padding.
+		if len(wrapped) >= 24 {
+			if _, err := Unwrap(kek, wrapped); err == nil {
+				t.Errorf("%d bytes: Unwrap of a KWP key succeeded", n)
+			}
+		}
+	}
+}
+
+func TestInvalidLengths(t *testing.T) {
+	kek := make([]byte, 16)
+	for _, n := range []int{0, 8, 15, 17} {
+		if _, err := Wrap(kek, make([]byte, n)); err == nil {
+			t.Errorf("Wrap of %d bytes succeeded", n)
+		}
+	}
+	for _, n := range []int{0, 16, 23, 25} {
+		if _, err := Unwrap(kek, make([]byte, n)); err == nil {
+			t.Errorf("Unwrap of %d bytes succeeded", n)
+		}
+	}
+	if _, err := WrapWithPadding(kek, nil); err == nil {
+		t.Error("WrapWithPadding of an empty key succeeded")
+	}
+	for _, n := range []int{0, 8, 17} {
+		if _, err := UnwrapWithPadding(kek, make([]byte, n)); err == nil {
+			t.Errorf("UnwrapWithPadding of %d bytes succeeded", n)
+		}
+	}
+	if _, err := Wrap(make([]byte, 15), make([]byte, 16)); err == nil {
+		t.Error("Wrap with a 15-byte key encryption key succeeded")
+	}
+}
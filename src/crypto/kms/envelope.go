@@ -0,0 +1,125 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// An envelope is
+//
+//	version     uint8   1
+//	wrappedLen  uint16
+//	wrapped     [wrappedLen]byte
+//	nonce       [12]byte
+//	ciphertext  AES-256-GCM, with a 16-byte tag
+//
+// where wrapped is the data encryption key wrapped by the key encryption
+// key. The additional data of AES-GCM is the envelope up to the nonce,
+// followed by the caller's additional data.
+const (
+	envelopeVersion = 1
+	dataKeySize     = 32
+	nonceSize       = 12
+)
+
+// ErrDecryption is returned by Open when the envelope can't be decrypted,
+// because it or the additional data was modified.
+var ErrDecryption = errors.New("kms: envelope decryption failed")
+
+// GenerateDataKey returns a new random 256-bit data encryption key, and
+// the key wrapped by kek. The wrapped key can be stored next to data
+// encrypted with the key, which should be erased from memory when it is
+// no longer needed.
+func GenerateDataKey(ctx context.Context, kek KeyEncryptionKey) (key, wrapped []byte, err error) {
+	key = make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err = kek.Wrap(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, wrapped, nil
+}
+
+// Seal encrypts and authenticates plaintext and authenticates
+// additionalData with a new data encryption key, wraps the data key with
+// kek, and returns the envelope holding both.
+func Seal(ctx context.Context, kek KeyEncryptionKey, plaintext, additionalData []byte) ([]byte, error) {
+	key, wrapped, err := GenerateDataKey(ctx, kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) > 1<<16-1 {
+		return nil, errors.New("kms: wrapped key too long")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 3+len(wrapped)+nonceSize)
+	header = append(header, envelopeVersion)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	ad := append(header[:len(header):len(header)], additionalData...)
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, plaintext, ad), nil
+}
+
+// Open unwraps the data encryption key of an envelope returned by Seal with
+// kek, and uses it to decrypt and authenticate the envelope and
+// additionalData. If they were modified, Open returns ErrDecryption, or the
+// error returned by kek.Unwrap.
+func Open(ctx context.Context, kek KeyEncryptionKey, envelope, additionalData []byte) ([]byte, error) {
+	if len(envelope) < 3 || envelope[0] != envelopeVersion {
+		return nil, errors.New("kms: malformed or unsupported envelope")
+	}
+	n := int(binary.BigEndian.Uint16(envelope[1:]))
+	if len(envelope) < 3+n+nonceSize {
+		return nil, errors.New("kms: malformed or unsupported envelope")
+	}
+	header := envelope[:3+n]
+	wrapped := envelope[3 : 3+n]
+	nonce := envelope[3+n : 3+n+nonceSize]
+	ciphertext := envelope[3+n+nonceSize:]
+
+	key, err := kek.Unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	ad := append(header[:len(header):len(header)], additionalData...)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dataKeySize {
+		return nil, errors.New("kms: unwrapped data key has the wrong size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kms defines an interface to keys held by a key management
+// service, and implements envelope encryption on top of it.
+//
+// A key management service, such as a cloud KMS, HashiCorp Vault, or a
+// hardware security module, holds keys that never leave it. Drivers for
+// those services implement KeyEncryptionKey, and applications use the
+// helpers in this package, or the crypto.Signer returned by SignerFor, so
+// they don't depend on a particular service. For example, a signer may be
+// used as the PrivateKey of a crypto/tls Certificate.
+//
+// With envelope encryption, data is encrypted locally with a fresh data
+// encryption key, which is then wrapped (encrypted) by the key encryption
+// key. Only the short wrapped key makes a round trip to the service, and
+// the service can't read the data.
+package kms
+
+import (
+	"context"
+	"crypto"
+)
+
+// A KeyEncryptionKey is a key held by a key management service.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type KeyEncryptionKey interface {
+	// Wrap encrypts key, which is typically a data encryption key of 16 to
+	// 32 bytes, and returns the wrapped key. The wrapped key must be
+	// authenticated, and may include an identifier or version of the key
+	// encryption key, so that Unwrap can select it after a rotation.
+	Wrap(ctx context.Context, key []byte) (wrapped []byte, err error)
+
+	// Unwrap decrypts a key returned by Wrap. It returns an error if the
+	// wrapped key was not made by this key encryption key, or was
+	// modified.
+	Unwrap(ctx context.Context, wrapped []byte) (key []byte, err error)
+
+	// SignerFor returns a crypto.Signer that signs with the asymmetric key
+	// that the service holds alongside this key, such as another version
+	// of the same key or the key pair it was created with. ctx is used to
+	// look up the key; the Sign method of the returned signer makes its
+	// own requests to the service, without a deadline unless the driver
+	// documents otherwise.
+	//
+	// If there is no such key, SignerFor returns an error that satisfies
+	// errors.Is(err, errors.ErrUnsupported).
+	SignerFor(ctx context.Context) (crypto.Signer, error)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func testKEK(t *testing.T, signer crypto.Signer) KeyEncryptionKey {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	kek, err := NewLocalKey(key, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kek
+}
+
+func TestEnvelope(t *testing.T) {
+	ctx := context.Background()
+	kek := testKEK(t, nil)
+	plaintext := []byte("attack at dawn")
+	ad := []byte("record 42")
+
+	envelope, err := Seal(ctx, kek, plaintext, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(ctx, kek, envelope, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Open = %q, want %q", got, plaintext)
+	}
+
+	again, err := Seal(ctx, kek, plaintext, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(again, envelope) {
+		t.Errorf("Seal is deterministic")
+	}
+
+	if _, err := Open(ctx, kek, envelope, []byte("record 43")); err != ErrDecryption {
+		t.Errorf("Open with the wrong additional data = %v, want ErrDecryption", err)
+	}
+	if _, err := Open(ctx, testKEK(t, nil), envelope, ad); err == nil {
+		t.Errorf("Open with another key succeeded")
+	}
+	for i := range envelope {
+		modified := bytes.Clone(envelope)
+		modified[i] ^= 1
+		if _, err := Open(ctx, kek, modified, ad); err == nil {
+			t.Errorf("Open succeeded with byte %d modified", i)
+		}
+	}
+	for n := 0; n < len(envelope); n++ {
+		if _, err := Open(ctx, kek, envelope[:n], ad); err == nil {
+			t.Errorf("Open succeeded with the envelope truncated to %d bytes", n)
+		}
+	}
+}
+
+func TestGenerateDataKey(t *testing.T) {
+	ctx := context.Background()
+	kek := testKEK(t, nil)
+	key, wrapped, err := GenerateDataKey(ctx, kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Errorf("len(key) = %d, want 32", len(key))
+	}
+	got, err := kek.Unwrap(ctx, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("Unwrap returned a different key")
+	}
+}
+
+func TestSignerFor(t *testing.T) {
+	ctx := context.Background()
+	if _, err := testKEK(t, nil).SignerFor(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("SignerFor without a signer = %v, want errors.ErrUnsupported", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := testKEK(t, priv).SignerFor(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hello")
+	sig, err := signer.Sign(nil, msg, crypto.Hash(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(priv.Public().(ed25519.PublicKey), msg, sig) {
+		t.Errorf("signature does not verify")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// NewLocalKey returns a KeyEncryptionKey that wraps keys in memory with
+// AES-GCM under key, which must be 16, 24, or 32 bytes long. Its SignerFor
+// method returns signer, or an error if signer is nil.
+//
+// A local key provides no more protection than the storage of key. It is
+// meant for tests and development, and for applications that hold their
+// key encryption key in a local secret store.
+func NewLocalKey(key []byte, signer crypto.Signer) (KeyEncryptionKey, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &localKey{aead: aead, signer: signer}, nil
+}
+
+type localKey struct {
+	aead   cipher.AEAD
+	signer crypto.Signer
+}
+
+// localWrapAD is the additional data of wrapped keys, which separates them
+// from any other use of the key.
+var localWrapAD = []byte("crypto/kms local key wrap")
+
+func (k *localKey) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(key)+k.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, key, localWrapAD), nil
+}
+
+func (k *localKey) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < k.aead.NonceSize() {
+		return nil, errors.New("kms: malformed wrapped key")
+	}
+	nonce, ciphertext := wrapped[:k.aead.NonceSize()], wrapped[k.aead.NonceSize():]
+	key, err := k.aead.Open(nil, nonce, ciphertext, localWrapAD)
+	if err != nil {
+		return nil, errors.New("kms: wrapped key was not made by this key or was modified")
+	}
+	return key, nil
+}
+
+func (k *localKey) SignerFor(ctx context.Context) (crypto.Signer, error) {
+	if k.signer == nil {
+		return nil, errNoSigner
+	}
+	return k.signer, nil
+}
+
+var errNoSigner = noSignerError{}
+
+type noSignerError struct{}
+
+func (noSignerError) Error() string { return "kms: key has no associated signer" }
+
+func (noSignerError) Is(err error) bool { return err == errors.ErrUnsupported }
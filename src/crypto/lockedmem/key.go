@@ -0,0 +1,169 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedmem
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// ErrDestroyed is returned by the methods of a PrivateKey after Destroy.
+var ErrDestroyed = errors.New("lockedmem: private key was destroyed")
+
+// A PrivateKey is an RSA or ECDSA private key whose secret values are stored
+// only in locked memory. It implements crypto.Signer, and crypto.Decrypter
+// for RSA keys, so it can be used as the PrivateKey of a tls.Certificate.
+//
+// Each operation rebuilds the key in ordinary memory and erases that copy
+// when the operation completes. Intermediate values computed by crypto/rsa
+// and crypto/ecdsa, and keys cached by a cryptographic module such as
+// BoringCrypto, are not erased.
+//
+// A PrivateKey is safe for concurrent use, including Destroy, which waits
+// for operations in progress to complete.
+type PrivateKey struct {
+	mu  sync.RWMutex
+	pub crypto.PublicKey
+	buf *Buffer
+
+	// size is the length of each secret value in buf: the size of the
+	// modulus for RSA, or of the group order for ECDSA.
+	size int
+}
+
+// NewPrivateKey copies the secret values of key, which must be an
+// *rsa.PrivateKey with two primes or an *ecdsa.PrivateKey, into locked
+// memory. The returned PrivateKey does not retain key, which is not modified
+// and should be discarded by the caller.
+func NewPrivateKey(key crypto.PrivateKey) (*PrivateKey, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if len(key.Primes) != 2 {
+			return nil, errors.New("lockedmem: multi-prime RSA keys are not supported")
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		// Compute the CRT values here rather than with Precompute,
+		// which would modify key.
+		p, q := key.Primes[0], key.Primes[1]
+		one := big.NewInt(1)
+		dp := new(big.Int).Mod(key.D, new(big.Int).Sub(p, one))
+		dq := new(big.Int).Mod(key.D, new(big.Int).Sub(q, one))
+		qinv := new(big.Int).ModInverse(q, p)
+		defer erase(dp, dq, qinv)
+		pub := key.PublicKey
+		size := (key.N.BitLen() + 7) / 8
+		return newPrivateKey(&pub, size, key.D, p, q, dp, dq, qinv)
+	case *ecdsa.PrivateKey:
+		pub := key.PublicKey
+		size := (key.Curve.Params().N.BitLen() + 7) / 8
+		return newPrivateKey(&pub, size, key.D)
+	default:
+		return nil, errors.New("lockedmem: unsupported private key type")
+	}
+}
+
+func newPrivateKey(pub crypto.PublicKey, size int, values ...*big.Int) (*PrivateKey, error) {
+	buf, err := New(size * len(values))
+	if err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	for i, v := range values {
+		v.FillBytes(data[i*size : (i+1)*size])
+	}
+	return &PrivateKey{pub: pub, buf: buf, size: size}, nil
+}
+
+// value returns a new big.Int holding the i-th secret value of k.
+// k.mu must be held.
+func (k *PrivateKey) value(i int) *big.Int {
+	return new(big.Int).SetBytes(k.buf.Bytes()[i*k.size : (i+1)*k.size])
+}
+
+// erase overwrites the words of the given values.
+func erase(values ...*big.Int) {
+	for _, v := range values {
+		clear(v.Bits())
+	}
+}
+
+// Public returns the public key corresponding to k.
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return k.pub
+}
+
+// Sign signs digest with k. See rsa.PrivateKey.Sign and
+// ecdsa.PrivateKey.Sign.
+func (k *PrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.buf.Len() == 0 {
+		return nil, ErrDestroyed
+	}
+	switch pub := k.pub.(type) {
+	case *rsa.PublicKey:
+		priv := k.rsaKey(pub)
+		defer eraseRSA(priv)
+		return priv.Sign(rand, digest, opts)
+	case *ecdsa.PublicKey:
+		priv := &ecdsa.PrivateKey{PublicKey: *pub, D: k.value(0)}
+		defer erase(priv.D)
+		return priv.Sign(rand, digest, opts)
+	}
+	panic("unreachable")
+}
+
+// Decrypt decrypts msg with k, which must be an RSA key. See
+// rsa.PrivateKey.Decrypt.
+func (k *PrivateKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.buf.Len() == 0 {
+		return nil, ErrDestroyed
+	}
+	pub, ok := k.pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("lockedmem: decryption requires an RSA key")
+	}
+	priv := k.rsaKey(pub)
+	defer eraseRSA(priv)
+	return priv.Decrypt(rand, msg, opts)
+}
+
+// rsaKey rebuilds the RSA private key stored in k. k.mu must be held.
+func (k *PrivateKey) rsaKey(pub *rsa.PublicKey) *rsa.PrivateKey {
+	priv := &rsa.PrivateKey{
+		PublicKey: *pub,
+		D:         k.value(0),
+		Primes:    []*big.Int{k.value(1), k.value(2)},
+	}
+	priv.Precomputed.Dp = k.value(3)
+	priv.Precomputed.Dq = k.value(4)
+	priv.Precomputed.Qinv = k.value(5)
+	priv.Precompute()
+	return priv
+}
+
+func eraseRSA(priv *rsa.PrivateKey) {
+	erase(priv.D, priv.Primes[0], priv.Primes[1],
+		priv.Precomputed.Dp, priv.Precomputed.Dq, priv.Precomputed.Qinv)
+}
+
+// Destroy erases the secret values of k and releases its locked memory.
+// Later calls to Sign and Decrypt return ErrDestroyed.
+//
+// Calling Destroy more than once is a no-op.
+func (k *PrivateKey) Destroy() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.buf.Destroy()
+}
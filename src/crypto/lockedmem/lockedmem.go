@@ -0,0 +1,81 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lockedmem provides memory buffers for key material that are locked
+// into physical memory, so that they are never written to swap, and that are
+// surrounded by inaccessible guard pages, so that out-of-bounds accesses fault
+// instead of silently reading or corrupting secrets.
+//
+// Locked memory is not managed by the garbage collector. Each Buffer must be
+// released explicitly with Destroy, which also erases its contents.
+//
+// Locked memory is a limited resource: most operating systems restrict the
+// amount of memory a process may lock (see RLIMIT_MEMLOCK on Unix), and each
+// Buffer uses at least one full page of it.
+//
+// PrivateKey keeps RSA and ECDSA private keys in locked memory, and
+// crypto/tls.NewLockedClientSessionCache does the same for the secrets of TLS
+// client sessions.
+//
+// Locked memory is currently only supported on Linux and Darwin.
+package lockedmem
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// A Buffer is a fixed-size region of locked memory.
+//
+// Destroy is not safe for concurrent use: it must not be called while
+// another goroutine uses the Buffer or a slice returned by Bytes.
+type Buffer struct {
+	mem  []byte // the whole mapping, including guard pages
+	data []byte // the usable part of mem, adjacent to the trailing guard page
+}
+
+// New returns a Buffer holding size bytes of zeroed, locked memory.
+//
+// If locked memory is not supported on the current platform, New returns an
+// error that wraps errors.ErrUnsupported.
+func New(size int) (*Buffer, error) {
+	if size < 0 {
+		return nil, errors.New("lockedmem: negative size")
+	}
+	mem, data, err := alloc(size)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer{mem: mem, data: data}, nil
+}
+
+// Bytes returns the contents of the buffer. The returned slice aliases the
+// locked memory and must not be used after Destroy is called. Bytes returns
+// nil after Destroy.
+//
+// The slice does not keep the Buffer alive; the Buffer must remain reachable,
+// and not be destroyed, for as long as the slice is in use.
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Len returns the size of the buffer, or zero after Destroy.
+func (b *Buffer) Len() int {
+	return len(b.data)
+}
+
+// Destroy erases the contents of the buffer, unlocks it, and returns the
+// memory to the operating system. Any later access to the memory through
+// slices returned by Bytes faults.
+//
+// Calling Destroy more than once is a no-op.
+func (b *Buffer) Destroy() error {
+	if b.mem == nil {
+		return nil
+	}
+	subtle.Zeroize(b.data)
+	err := free(b.mem)
+	b.mem, b.data = nil, nil
+	return err
+}
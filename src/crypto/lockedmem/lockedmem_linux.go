@@ -0,0 +1,16 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedmem
+
+import "syscall"
+
+// madvDontDump is MADV_DONTDUMP, which has the same value on all
+// architectures but is missing from package syscall on some of them.
+const madvDontDump = 0x10
+
+// dontDump excludes b from core dumps, on a best effort basis.
+func dontDump(b []byte) {
+	syscall.Madvise(b, madvDontDump)
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || linux
+
+package lockedmem
+
+import "syscall"
+
+// alloc maps size bytes of locked memory, between two inaccessible guard
+// pages. The returned data is placed at the end of the mapping so that
+// overflows immediately hit the trailing guard page.
+func alloc(size int) (mem, data []byte, err error) {
+	pageSize := syscall.Getpagesize()
+	dataPages := (size + pageSize - 1) / pageSize
+	if dataPages == 0 {
+		dataPages = 1
+	}
+	mem, err = syscall.Mmap(-1, 0, (dataPages+2)*pageSize,
+		syscall.PROT_NONE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, nil, err
+	}
+	inner := mem[pageSize : len(mem)-pageSize]
+	if err := syscall.Mprotect(inner, syscall.PROT_READ|syscall.PROT_WRITE); err != nil {
+		syscall.Munmap(mem)
+		return nil, nil, err
+	}
+	if err := syscall.Mlock(inner); err != nil {
+		syscall.Munmap(mem)
+		return nil, nil, err
+	}
+	dontDump(inner)
+	return mem, inner[len(inner)-size:], nil
+}
+
+// free unlocks and unmaps memory returned by alloc.
+func free(mem []byte) error {
+	pageSize := syscall.Getpagesize()
+	inner := mem[pageSize : len(mem)-pageSize]
+	err := syscall.Munlock(inner)
+	if err2 := syscall.Munmap(mem); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !linux
+
+package lockedmem
+
+import (
+	"errors"
+	"fmt"
+)
+
+func alloc(size int) (mem, data []byte, err error) {
+	return nil, nil, fmt.Errorf("lockedmem: locked memory %w on this platform", errors.ErrUnsupported)
+}
+
+func free(mem []byte) error {
+	panic("unreachable")
+}
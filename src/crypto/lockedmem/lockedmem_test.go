@@ -0,0 +1,167 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedmem
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"runtime/debug"
+	"testing"
+	"unsafe"
+)
+
+func newBuffer(t *testing.T, size int) *Buffer {
+	t.Helper()
+	b, err := New(size)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("locked memory is not supported on this platform")
+	}
+	if err != nil {
+		// Most likely RLIMIT_MEMLOCK is too low.
+		t.Skipf("failed to allocate locked memory: %v", err)
+	}
+	return b
+}
+
+func TestBuffer(t *testing.T) {
+	for _, size := range []int{0, 1, 31, 4096, 10000} {
+		b := newBuffer(t, size)
+		if b.Len() != size || len(b.Bytes()) != size {
+			t.Errorf("New(%d): Len() = %d, len(Bytes()) = %d", size, b.Len(), len(b.Bytes()))
+		}
+		data := b.Bytes()
+		for i := range data {
+			if data[i] != 0 {
+				t.Fatalf("New(%d): byte %d is not zero", size, i)
+			}
+			data[i] = 0xff
+		}
+		if err := b.Destroy(); err != nil {
+			t.Errorf("Destroy: %v", err)
+		}
+		if b.Len() != 0 || b.Bytes() != nil {
+			t.Errorf("buffer is not empty after Destroy")
+		}
+		if err := b.Destroy(); err != nil {
+			t.Errorf("second Destroy: %v", err)
+		}
+	}
+}
+
+func TestBufferGuardPages(t *testing.T) {
+	b := newBuffer(t, 100)
+	defer b.Destroy()
+	data := b.Bytes()
+
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	access := func(p *byte) (faulted bool) {
+		defer func() {
+			faulted = recover() != nil
+		}()
+		*p = 1
+		return false
+	}
+	after := (*byte)(unsafe.Add(unsafe.Pointer(&data[0]), len(data)))
+	if !access(after) {
+		t.Error("write past the end of the buffer did not fault")
+	}
+	if access(&data[len(data)-1]) {
+		t.Error("write to the last byte of the buffer faulted")
+	}
+}
+
+func lockKey(t *testing.T, key crypto.PrivateKey) *PrivateKey {
+	t.Helper()
+	newBuffer(t, 0).Destroy()
+	k, err := NewPrivateKey(key)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	return k
+}
+
+func TestPrivateKeyRSA(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv.Precomputed = rsa.PrecomputedValues{}
+	k := lockKey(t, priv)
+	defer k.Destroy()
+	if priv.Precomputed.Dp != nil {
+		t.Error("NewPrivateKey modified key")
+	}
+	pub := k.Public().(*rsa.PublicKey)
+	if !pub.Equal(&priv.PublicKey) {
+		t.Fatal("Public returned the wrong key")
+	}
+
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := k.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("VerifyPKCS1v15: %v", err)
+	}
+
+	msg := []byte("secret")
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := k.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Errorf("Decrypt = %q, want %q", plaintext, msg)
+	}
+
+	if err := k.Destroy(); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if _, err := k.Sign(rand.Reader, digest[:], crypto.SHA256); err != ErrDestroyed {
+		t.Errorf("Sign after Destroy: got %v, want ErrDestroyed", err)
+	}
+	if _, err := k.Decrypt(rand.Reader, ciphertext, nil); err != ErrDestroyed {
+		t.Errorf("Decrypt after Destroy: got %v, want ErrDestroyed", err)
+	}
+}
+
+func TestPrivateKeyECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := lockKey(t, priv)
+	defer k.Destroy()
+	pub := k.Public().(*ecdsa.PublicKey)
+
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := k.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !ecdsa.VerifyASN1(pub, digest[:], sig) {
+		t.Error("VerifyASN1 failed")
+	}
+	if _, err := k.Decrypt(rand.Reader, nil, nil); err == nil {
+		t.Error("Decrypt with an ECDSA key succeeded")
+	}
+
+	if err := k.Destroy(); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if _, err := k.Sign(rand.Reader, digest[:], crypto.SHA256); err != ErrDestroyed {
+		t.Errorf("Sign after Destroy: got %v, want ErrDestroyed", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package manifest creates and verifies signed digest manifests of file
+// trees, such as the checksum files published with software releases.
+//
+// A manifest lists the digest of every regular file of a tree, in the
+// tagged format of the GNU and BSD checksum utilities, one file per line:
+//
+//	SHA256 (bin/tool) = 2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+//
+// so that it can also be checked with "sha256sum --check". Any digest
+// algorithm of package crypto/digest can be used, as long as its
+// implementation is linked into the program; SHA-256 and SHA-512 always
+// are. MD5 and SHA-1, which are not collision resistant, are rejected.
+//
+// The digest of a tree, from Manifest.Digest, is the digest of its
+// manifest, so that two trees with the same files have the same digest.
+// CreateWithOptions can exclude files from a manifest, and hashes files in
+// parallel.
+//
+// A manifest is signed as is, with a detached signature, by any
+// crypto.Signer. The signature is the one produced by "openssl dgst
+// -sign" for RSA and ECDSA keys, and "openssl pkeyutl -sign -rawin" for
+// Ed25519 keys, so that it can be verified without this package.
+package manifest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/digest"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// An Entry is the digest of a file.
+type Entry struct {
+	// Path is the slash-separated path of the file, relative to the root
+	// of the tree.
+	Path   string
+	Digest []byte
+}
+
+// A Manifest is a list of file digests.
+type Manifest struct {
+	Algorithm *digest.Algorithm
+
+	// Entries are sorted by path.
+	Entries []Entry
+}
+
+// Create returns the manifest of the regular files of fsys, computed with
+// alg. Files of other types, such as symbolic links, are rejected.
+//
+// Create is equivalent to CreateWithOptions with nil options.
+func Create(fsys fs.FS, alg *digest.Algorithm) (*Manifest, error) {
+	return CreateWithOptions(fsys, alg, nil)
+}
+
+func checkAlgorithm(alg *digest.Algorithm) error {
+	if alg.Hash() == crypto.MD5 || alg.Hash() == crypto.SHA1 {
+		return errors.New("manifest: insecure digest algorithm " + alg.Name())
+	}
+	return nil
+}
+
+// checkPath rejects the paths that the checksum utilities would escape.
+func checkPath(path string) error {
+	if strings.ContainsAny(path, "\\\r\n") {
+		return errors.New("manifest: unsupported character in file name " + path)
+	}
+	return nil
+}
+
+func (m *Manifest) sort() {
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+}
+
+// tag returns the algorithm tag of the checksum utilities, such as
+// "SHA256" for "sha-256".
+func tag(alg *digest.Algorithm) string {
+	t := strings.ToUpper(alg.Name())
+	if rest, ok := strings.CutPrefix(t, "SHA-"); ok {
+		t = "SHA" + rest
+	}
+	return t
+}
+
+// MarshalText returns the text encoding of m, with the entries in order.
+func (m *Manifest) MarshalText() ([]byte, error) {
+	var b bytes.Buffer
+	t := tag(m.Algorithm)
+	for _, e := range m.Entries {
+		if !fs.ValidPath(e.Path) || e.Path == "." {
+			return nil, errors.New("manifest: invalid file name " + e.Path)
+		}
+		if err := checkPath(e.Path); err != nil {
+			return nil, err
+		}
+		if len(e.Digest) != m.Algorithm.Size() {
+			return nil, errors.New("manifest: digest of " + e.Path + " has the wrong length")
+		}
+		b.WriteString(t + " (" + e.Path + ") = " + hex.EncodeToString(e.Digest) + "\n")
+	}
+	return b.Bytes(), nil
+}
+
+// Digest returns the digest of the tree described by m: the digest, with
+// m.Algorithm, of the text encoding of m. Two trees have the same digest if
+// they hold the same files, with the same contents.
+func (m *Manifest) Digest() ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	h, err := m.Algorithm.New()
+	if err != nil {
+		return nil, err
+	}
+	h.Write(text)
+	return h.Sum(nil), nil
+}
+
+// Parse parses the text encoding of a manifest. All the entries must use
+// the same algorithm, and list distinct files.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	seen := make(map[string]bool)
+	text := string(data)
+	for len(text) > 0 {
+		var line string
+		line, text, _ = strings.Cut(text, "\n")
+		t, rest, ok1 := strings.Cut(line, " (")
+		i := strings.LastIndex(rest, ") = ")
+		if !ok1 || i < 0 {
+			return nil, errors.New("manifest: malformed line " + line)
+		}
+		path, hexDigest := rest[:i], rest[i+len(") = "):]
+
+		alg, ok := digest.LookupName(t)
+		if !ok {
+			return nil, errors.New("manifest: unknown digest algorithm " + t)
+		}
+		if err := checkAlgorithm(alg); err != nil {
+			return nil, err
+		}
+		if m.Algorithm == nil {
+			m.Algorithm = alg
+		} else if alg != m.Algorithm {
+			return nil, errors.New("manifest: entries use different digest algorithms")
+		}
+		sum, err := hex.DecodeString(hexDigest)
+		if err != nil || len(sum) != alg.Size() {
+			return nil, errors.New("manifest: malformed digest of " + path)
+		}
+		if !fs.ValidPath(path) || path == "." {
+			return nil, errors.New("manifest: invalid file name " + path)
+		}
+		if seen[path] {
+			return nil, errors.New("manifest: duplicate entry for " + path)
+		}
+		seen[path] = true
+		m.Entries = append(m.Entries, Entry{Path: path, Digest: sum})
+	}
+	if m.Algorithm == nil {
+		return nil, errors.New("manifest: empty manifest")
+	}
+	m.sort()
+	return m, nil
+}
+
+// A MismatchError is returned by Verify when a tree does not match a
+// manifest.
+type MismatchError struct {
+	// Missing are the files of the manifest missing from the tree.
+	Missing []string
+
+	// Extra are the files of the tree missing from the manifest.
+	Extra []string
+
+	// Modified are the files whose digest does not match the manifest.
+	Modified []string
+}
+
+func (e *MismatchError) Error() string {
+	var s []string
+	for _, l := range []struct {
+		what  string
+		files []string
+	}{{"missing", e.Missing}, {"unexpected", e.Extra}, {"modified", e.Modified}} {
+		if len(l.files) > 0 {
+			s = append(s, l.what+" "+strings.Join(l.files, ", "))
+		}
+	}
+	return "manifest: tree does not match: " + strings.Join(s, "; ")
+}
+
+// Verify checks that fsys holds exactly the files of m, with the same
+// digests. If it doesn't, the error is a *MismatchError.
+//
+// Verify is equivalent to VerifyWithOptions with nil options.
+func (m *Manifest) Verify(fsys fs.FS) error {
+	return m.VerifyWithOptions(fsys, nil)
+}
+
+// VerifyWithOptions is like Verify, but skips the files excluded by opts,
+// and hashes files in parallel like CreateWithOptions.
+func (m *Manifest) VerifyWithOptions(fsys fs.FS, opts *Options) error {
+	got, err := CreateWithOptions(fsys, m.Algorithm, opts)
+	if err != nil {
+		return err
+	}
+	want := make(map[string][]byte, len(m.Entries))
+	for _, e := range m.Entries {
+		want[e.Path] = e.Digest
+	}
+	mismatch := &MismatchError{}
+	for _, e := range got.Entries {
+		sum, ok := want[e.Path]
+		switch {
+		case !ok:
+			mismatch.Extra = append(mismatch.Extra, e.Path)
+		case !bytes.Equal(sum, e.Digest):
+			mismatch.Modified = append(mismatch.Modified, e.Path)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		mismatch.Missing = append(mismatch.Missing, path)
+	}
+	if mismatch.Missing == nil && mismatch.Extra == nil && mismatch.Modified == nil {
+		return nil
+	}
+	sort.Strings(mismatch.Missing)
+	return mismatch
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"crypto"
+	"crypto/digest"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testTree() fstest.MapFS {
+	return fstest.MapFS{
+		"README":         {Data: []byte("hello\n")},
+		"bin/tool":       {Data: []byte("foo")},
+		"bin.txt":        {Data: []byte("")},
+		"lib/a/b/c.so":   {Data: []byte("bar")},
+		"name (1) = x":   {Data: []byte("baz")},
+		"empty/.keep":    {Data: nil},
+		"lib/dir/sub/.x": {Data: []byte("x")},
+	}
+}
+
+const testManifest = `SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03
+SHA256 (bin.txt) = e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+SHA256 (bin/tool) = 2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+SHA256 (empty/.keep) = e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+SHA256 (lib/a/b/c.so) = fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9
+SHA256 (lib/dir/sub/.x) = 2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881
+SHA256 (name (1) = x) = baa5a0964d3320fbc0c6a922140453c8513ea24ab8fd0577034804a967248096
+`
+
+func TestCreate(t *testing.T) {
+	sha256, _ := digest.Lookup(crypto.SHA256)
+	m, err := Create(testTree(), sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != testManifest {
+		t.Errorf("manifest:\n%s\nwant:\n%s", text, testManifest)
+	}
+
+	m2, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m2) {
+		t.Errorf("Parse(MarshalText) = %v, want %v", m2, m)
+	}
+	if err := m2.Verify(testTree()); err != nil {
+		t.Error(err)
+	}
+
+	sha512, _ := digest.Lookup(crypto.SHA512)
+	m, err = Create(testTree(), sha512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ = m.MarshalText()
+	if !strings.HasPrefix(string(text), "SHA512 (README) = ") {
+		t.Errorf("SHA-512 manifest:\n%s", text)
+	}
+}
+
+func TestCreateIrregular(t *testing.T) {
+	sha256, _ := digest.Lookup(crypto.SHA256)
+	tree := testTree()
+	tree["link"] = &fstest.MapFile{Data: []byte("README"), Mode: fs.ModeSymlink}
+	if _, err := Create(tree, sha256); err == nil {
+		t.Error("Create of a tree with a symbolic link succeeded")
+	}
+	tree = testTree()
+	tree["a\nb"] = &fstest.MapFile{}
+	if _, err := Create(tree, sha256); err == nil {
+		t.Error("Create of a tree with a newline in a file name succeeded")
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	m, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := testTree()
+	delete(tree, "bin/tool")
+	delete(tree, "README")
+	tree["bin/other"] = &fstest.MapFile{Data: []byte("other")}
+	tree["lib/a/b/c.so"] = &fstest.MapFile{Data: []byte("bar2")}
+	err = m.Verify(tree)
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Verify = %v, want a *MismatchError", err)
+	}
+	want := &MismatchError{
+		Missing:  []string{"README", "bin/tool"},
+		Extra:    []string{"bin/other"},
+		Modified: []string{"lib/a/b/c.so"},
+	}
+	if !reflect.DeepEqual(mismatch, want) {
+		t.Errorf("Verify = %+v, want %+v", mismatch, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	// Entries may be in any order, and the last line ending is optional.
+	lines := strings.Split(strings.TrimSuffix(testManifest, "\n"), "\n")
+	lines[0], lines[len(lines)-1] = lines[len(lines)-1], lines[0]
+	m, err := Parse([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(testTree()); err != nil {
+		t.Error(err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"SHA256 README = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n",
+		"MD5 (README) = b1946ac92492d2347c6235b4d2611184\n",
+		"SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be\n",
+		"SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be0g\n",
+		"SHA256 (../README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n",
+		"SHA256 (/README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n",
+		"SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n\n",
+		"SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n" +
+			"SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n",
+		"SHA256 (README) = 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n" +
+			"SHA512 (bin.txt) = cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, priv := range []crypto.Signer{edKey, ecKey, rsaKey} {
+		sig, err := Sign(rand.Reader, priv, []byte(testManifest))
+		if err != nil {
+			t.Fatalf("%T: %v", priv, err)
+		}
+		m, err := VerifySignature(priv.Public(), []byte(testManifest), sig)
+		if err != nil {
+			t.Fatalf("%T: %v", priv, err)
+		}
+		if err := m.Verify(testTree()); err != nil {
+			t.Errorf("%T: %v", priv, err)
+		}
+
+		modified := strings.Replace(testManifest, "README", "READYOU", 1)
+		if _, err := VerifySignature(priv.Public(), []byte(modified), sig); err != ErrVerification {
+			t.Errorf("%T: verification of a modified manifest: %v", priv, err)
+		}
+	}
+
+	if _, err := VerifySignature(edKey.Public(), []byte(testManifest), nil); err != ErrVerification {
+		t.Errorf("verification of an empty signature: %v", err)
+	}
+	if _, err := VerifySignature(rsaKey.Public(), []byte(testManifest), make([]byte, 64)); err != ErrVerification {
+		t.Errorf("verification of a malformed signature: %v", err)
+	}
+	ecKey224, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if _, err := Sign(rand.Reader, ecKey224, []byte(testManifest)); err == nil {
+		t.Errorf("signature with a P-224 key succeeded")
+	}
+}
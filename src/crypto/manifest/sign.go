@@ -0,0 +1,87 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"io"
+)
+
+// ErrVerification is returned when a manifest signature is invalid.
+var ErrVerification = errors.New("manifest: invalid signature")
+
+// signatureHash returns the hash applied to the manifest before signing
+// with pub, or zero for Ed25519, which signs the manifest itself.
+func signatureHash(pub crypto.PublicKey) (crypto.Hash, error) {
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		return 0, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return crypto.SHA256, nil
+		case elliptic.P384():
+			return crypto.SHA384, nil
+		case elliptic.P521():
+			return crypto.SHA512, nil
+		}
+		return 0, errors.New("manifest: unsupported ECDSA curve")
+	case *rsa.PublicKey:
+		return crypto.SHA256, nil
+	}
+	return 0, errors.New("manifest: unsupported public key type")
+}
+
+// Sign returns the detached signature of the encoded manifest by priv. The
+// public key of priv must be an ed25519.PublicKey, for a pure Ed25519
+// signature, an *ecdsa.PublicKey on P-256, P-384, or P-521, for an ASN.1
+// ECDSA signature over the SHA-256, SHA-384, or SHA-512 hash of manifest,
+// or an *rsa.PublicKey, for a PKCS #1 v1.5 signature over its SHA-256
+// hash. rand is passed to priv.Sign.
+func Sign(rand io.Reader, priv crypto.Signer, manifest []byte) ([]byte, error) {
+	h, err := signatureHash(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	if h == 0 {
+		return priv.Sign(rand, manifest, crypto.Hash(0))
+	}
+	d := h.New()
+	d.Write(manifest)
+	return priv.Sign(rand, d.Sum(nil), h)
+}
+
+// VerifySignature verifies the signature sig of the encoded manifest by
+// pub, as made by Sign, and returns the parsed manifest.
+func VerifySignature(pub crypto.PublicKey, manifest, sig []byte) (*Manifest, error) {
+	h, err := signatureHash(pub)
+	if err != nil {
+		return nil, err
+	}
+	var digest []byte
+	if h != 0 {
+		d := h.New()
+		d.Write(manifest)
+		digest = d.Sum(nil)
+	}
+	var ok bool
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, manifest, sig)
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, digest, sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, h, digest, sig) == nil
+	}
+	if !ok {
+		return nil, ErrVerification
+	}
+	return Parse(manifest)
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"crypto/digest"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Options configure the creation and verification of manifests.
+type Options struct {
+	// Exclude are patterns, in the syntax of path.Match, of the files and
+	// directories to skip. A pattern that contains a slash matches the
+	// whole path of files and directories; otherwise, it matches their
+	// name. The contents of excluded directories are skipped.
+	Exclude []string
+
+	// Concurrency is the maximum number of files hashed in parallel. If
+	// zero, it is runtime.GOMAXPROCS(0).
+	Concurrency int
+}
+
+func (o *Options) excluded(p string) bool {
+	if o == nil {
+		return false
+	}
+	for _, pattern := range o.Exclude {
+		name := path.Base(p)
+		if strings.Contains(pattern, "/") {
+			name = p
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) concurrency() int {
+	if o == nil || o.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Concurrency
+}
+
+// CreateWithOptions returns the manifest of the regular files of fsys that
+// are not excluded by opts, computed with alg. Files of other types, such
+// as symbolic links, are rejected.
+func CreateWithOptions(fsys fs.FS, alg *digest.Algorithm, opts *Options) (*Manifest, error) {
+	if err := checkAlgorithm(alg); err != nil {
+		return nil, err
+	}
+	if _, err := alg.New(); err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		for _, pattern := range opts.Exclude {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, errors.New("manifest: invalid exclude pattern " + pattern)
+			}
+		}
+	}
+
+	m := &Manifest{Algorithm: alg}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && opts.excluded(name) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return errors.New("manifest: " + name + " is not a regular file")
+		}
+		if err := checkPath(name); err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, Entry{Path: name})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := hashFiles(fsys, alg, m.Entries, opts.concurrency()); err != nil {
+		return nil, err
+	}
+	m.sort()
+	return m, nil
+}
+
+// hashFiles fills in the digests of entries, hashing up to n files in
+// parallel, and returns the first error encountered.
+func hashFiles(fsys fs.FS, alg *digest.Algorithm, entries []Entry, n int) error {
+	if n > len(entries) {
+		n = len(entries)
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		next     int
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next == len(entries) || firstErr != nil {
+					mu.Unlock()
+					return
+				}
+				e := &entries[next]
+				next++
+				mu.Unlock()
+
+				sum, err := hashFile(fsys, e.Path, alg)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				e.Digest = sum
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func hashFile(fsys fs.FS, path string, alg *digest.Algorithm) ([]byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h, err := alg.New()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
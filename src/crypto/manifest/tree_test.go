@@ -0,0 +1,130 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/digest"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCreateWithOptions(t *testing.T) {
+	sha256Alg, _ := digest.Lookup(crypto.SHA256)
+	tree := testTree()
+	tree[".git/HEAD"] = &fstest.MapFile{Data: []byte("ref")}
+	tree["lib/a/b/c.o"] = &fstest.MapFile{Data: []byte("obj")}
+	tree["build/out/tool"] = &fstest.MapFile{Data: []byte("bin")}
+	tree["link"] = &fstest.MapFile{Data: []byte("README"), Mode: fs.ModeSymlink}
+	opts := &Options{Exclude: []string{".git", "*.o", "build/out", "link"}, Concurrency: 3}
+	m, err := CreateWithOptions(tree, sha256Alg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := m.MarshalText()
+	if string(text) != testManifest {
+		t.Errorf("manifest:\n%s\nwant:\n%s", text, testManifest)
+	}
+
+	// Patterns with a slash match whole paths, and others match names.
+	for _, tt := range []struct {
+		pattern string
+		n       int
+	}{
+		{"dir", 6},
+		{"lib/dir", 6},
+		{"lib", 5},
+		{"sub/.x", 7},
+		{"lib/*/b", 6},
+		{".*", 5},
+	} {
+		opts := &Options{Exclude: append(opts.Exclude, tt.pattern)}
+		m, err := CreateWithOptions(tree, sha256Alg, opts)
+		if err != nil {
+			t.Errorf("CreateWithOptions excluding %q: %v", tt.pattern, err)
+		} else if len(m.Entries) != tt.n {
+			t.Errorf("CreateWithOptions excluding %q = %d entries, want %d", tt.pattern, len(m.Entries), tt.n)
+		}
+	}
+
+	if err := m.VerifyWithOptions(tree, opts); err != nil {
+		t.Errorf("VerifyWithOptions: %v", err)
+	}
+	var mismatch *MismatchError
+	if err := m.VerifyWithOptions(tree, &Options{Exclude: []string{"link"}}); !errors.As(err, &mismatch) || len(mismatch.Extra) != 3 {
+		t.Errorf("VerifyWithOptions without exclusions = %v", err)
+	}
+
+	if _, err := CreateWithOptions(tree, sha256Alg, &Options{Exclude: []string{"["}}); err == nil {
+		t.Errorf("CreateWithOptions with a malformed pattern succeeded")
+	}
+}
+
+func TestCreateParallel(t *testing.T) {
+	sha256Alg, _ := digest.Lookup(crypto.SHA256)
+	tree := fstest.MapFS{}
+	for i := 0; i < 100; i++ {
+		tree["f"+strconv.Itoa(i)] = &fstest.MapFile{Data: []byte(strconv.Itoa(i))}
+	}
+	serial, err := CreateWithOptions(tree, sha256Alg, &Options{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 2, 7, 200} {
+		m, err := CreateWithOptions(tree, sha256Alg, &Options{Concurrency: n})
+		if err != nil || !reflect.DeepEqual(m, serial) {
+			t.Errorf("concurrency %d: manifests differ, %v", n, err)
+		}
+	}
+
+	errOpen := errors.New("open error")
+	if _, err := CreateWithOptions(failingFS{tree, "f42", errOpen}, sha256Alg, &Options{Concurrency: 4}); err != errOpen {
+		t.Errorf("CreateWithOptions = %v, want %v", err, errOpen)
+	}
+}
+
+type failingFS struct {
+	fs.ReadDirFS
+	name string
+	err  error
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if name == f.name {
+		return nil, f.err
+	}
+	return f.ReadDirFS.Open(name)
+}
+
+func TestDigest(t *testing.T) {
+	m, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := m.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte(testManifest))
+	if !bytes.Equal(d, want[:]) {
+		t.Errorf("Digest = %x, want %x", d, want)
+	}
+
+	sha256Alg, _ := digest.Lookup(crypto.SHA256)
+	tree := testTree()
+	m, _ = Create(tree, sha256Alg)
+	d1, _ := m.Digest()
+	tree["README"] = &fstest.MapFile{Data: []byte("hello!\n")}
+	m, _ = Create(tree, sha256Alg)
+	if d2, _ := m.Digest(); bytes.Equal(d1, d2) {
+		t.Errorf("Digest did not change with the tree")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package merkle implements the Merkle tree of Certificate Transparency,
+// as specified in RFC 6962, Section 2.1 and RFC 9162, Section 2.1, with
+// inclusion and consistency proofs.
+//
+// The tree is an append-only list of leaves, hashed with SHA-256 and
+// domain-separated prefixes for leaves and interior nodes. An inclusion
+// proof shows that a leaf is part of a tree with a given root hash. A
+// consistency proof shows that a tree is an extension of an older one,
+// which lets clients of a transparency log or audit trail check that its
+// history was not rewritten.
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+)
+
+// A Hash is the hash of a leaf or of a tree.
+type Hash [sha256.Size]byte
+
+// String returns the hexadecimal encoding of h.
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// ErrInvalidProof is returned when a proof does not verify.
+var ErrInvalidProof = errors.New("merkle: invalid proof")
+
+// EmptyRoot returns the root hash of the empty tree.
+func EmptyRoot() Hash {
+	return sha256.Sum256(nil)
+}
+
+// LeafHash returns the hash of a leaf with the given data, SHA-256(0x00 ||
+// data).
+func LeafHash(data []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
+
+// NodeHash returns the hash of an interior node with the given children,
+// SHA-256(0x01 || left || right).
+func NodeHash(left, right Hash) Hash {
+	var buf [1 + 2*sha256.Size]byte
+	buf[0] = 1
+	copy(buf[1:], left[:])
+	copy(buf[1+sha256.Size:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// splitPoint returns the largest power of two smaller than n, which must
+// be at least 2.
+func splitPoint(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// VerifyInclusion checks that proof shows the leaf with hash leaf to be at
+// index in the tree of the given size with hash root, per RFC 9162,
+// Section 2.1.3.2.
+func VerifyInclusion(leaf Hash, index, size uint64, proof []Hash, root Hash) error {
+	if index >= size {
+		return ErrInvalidProof
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || r != root {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// VerifyConsistency checks that proof shows the tree of size newSize with
+// hash newRoot to be an extension of the tree of size oldSize with hash
+// oldRoot, per RFC 9162, Section 2.1.4.2. oldSize must not be zero.
+func VerifyConsistency(oldSize, newSize uint64, oldRoot, newRoot Hash, proof []Hash) error {
+	switch {
+	case oldSize == 0 || oldSize > newSize:
+		return ErrInvalidProof
+	case oldSize == newSize:
+		if len(proof) != 0 || oldRoot != newRoot {
+			return ErrInvalidProof
+		}
+		return nil
+	}
+	if oldSize&(oldSize-1) == 0 {
+		// The old tree is a complete subtree of the new one, and its hash
+		// is omitted from the proof.
+		proof = append([]Hash{oldRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return ErrInvalidProof
+	}
+	fn, sn := oldSize-1, newSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = NodeHash(c, fr)
+			sr = NodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = NodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || fr != oldRoot || sr != newRoot {
+		return ErrInvalidProof
+	}
+	return nil
+}
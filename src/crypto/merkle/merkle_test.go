@@ -0,0 +1,169 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package merkle
+
+import (
+	"encoding/hex"
+	"strconv"
+	"testing"
+)
+
+// Test vectors from the Certificate Transparency reference implementation.
+var (
+	testLeaves = []string{
+		"",
+		"00",
+		"10",
+		"2021",
+		"3031",
+		"40414243",
+		"5051525354555657",
+		"606162636465666768696a6b6c6d6e6f",
+	}
+	testRoots = []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func testTree(t *testing.T) *Tree {
+	tree := &Tree{}
+	for i, l := range testLeaves {
+		if index := tree.Append(mustHex(l)); index != uint64(i) {
+			t.Fatalf("Append returned index %d, want %d", index, i)
+		}
+	}
+	return tree
+}
+
+func TestRoots(t *testing.T) {
+	tree := testTree(t)
+	if got := EmptyRoot().String(); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("EmptyRoot() = %s", got)
+	}
+	for i, want := range testRoots {
+		got, err := tree.RootAt(uint64(i + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want {
+			t.Errorf("RootAt(%d) = %s, want %s", i+1, got, want)
+		}
+	}
+	if got := tree.Root().String(); got != testRoots[len(testRoots)-1] {
+		t.Errorf("Root() = %s", got)
+	}
+	if _, err := tree.RootAt(9); err == nil {
+		t.Errorf("RootAt(9) succeeded")
+	}
+}
+
+func TestInclusionProofVector(t *testing.T) {
+	tree := testTree(t)
+	proof, err := tree.InclusionProof(0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+		"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+		"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+	}
+	if len(proof) != len(want) {
+		t.Fatalf("InclusionProof(0, 8) has %d hashes, want %d", len(proof), len(want))
+	}
+	for i := range want {
+		if proof[i].String() != want[i] {
+			t.Errorf("InclusionProof(0, 8)[%d] = %s, want %s", i, proof[i], want[i])
+		}
+	}
+}
+
+func TestProofs(t *testing.T) {
+	tree := &Tree{}
+	const maxSize = 70
+	for i := 0; i < maxSize; i++ {
+		tree.Append([]byte(strconv.Itoa(i)))
+	}
+	for size := uint64(1); size <= maxSize; size++ {
+		root, _ := tree.RootAt(size)
+		for index := uint64(0); index < size; index++ {
+			leaf, _ := tree.LeafHash(index)
+			proof, err := tree.InclusionProof(index, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyInclusion(leaf, index, size, proof, root); err != nil {
+				t.Fatalf("VerifyInclusion(%d, %d): %v", index, size, err)
+			}
+			if VerifyInclusion(leaf, index^1, size, proof, root) == nil && size > 1 && index^1 < size {
+				t.Errorf("VerifyInclusion(%d, %d) accepted the wrong index", index, size)
+			}
+			if len(proof) > 0 {
+				if VerifyInclusion(leaf, index, size, proof[:len(proof)-1], root) == nil {
+					t.Errorf("VerifyInclusion(%d, %d) accepted a truncated proof", index, size)
+				}
+				proof[0][0] ^= 1
+				if VerifyInclusion(leaf, index, size, proof, root) == nil {
+					t.Errorf("VerifyInclusion(%d, %d) accepted a modified proof", index, size)
+				}
+			}
+			if VerifyInclusion(leaf, index, size, append(proof, leaf), root) == nil {
+				t.Errorf("VerifyInclusion(%d, %d) accepted an extended proof", index, size)
+			}
+		}
+
+		for oldSize := uint64(1); oldSize <= size; oldSize++ {
+			oldRoot, _ := tree.RootAt(oldSize)
+			proof, err := tree.ConsistencyProof(oldSize, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyConsistency(oldSize, size, oldRoot, root, proof); err != nil {
+				t.Fatalf("VerifyConsistency(%d, %d): %v", oldSize, size, err)
+			}
+			if oldSize == size {
+				continue
+			}
+			if VerifyConsistency(oldSize, size, root, oldRoot, proof) == nil {
+				t.Errorf("VerifyConsistency(%d, %d) accepted swapped roots", oldSize, size)
+			}
+			if VerifyConsistency(oldSize, size, oldRoot, root, proof[:len(proof)-1]) == nil {
+				t.Errorf("VerifyConsistency(%d, %d) accepted a truncated proof", oldSize, size)
+			}
+			proof[len(proof)-1][0] ^= 1
+			if VerifyConsistency(oldSize, size, oldRoot, root, proof) == nil {
+				t.Errorf("VerifyConsistency(%d, %d) accepted a modified proof", oldSize, size)
+			}
+		}
+	}
+
+	if _, err := tree.ConsistencyProof(0, 5); err == nil {
+		t.Errorf("ConsistencyProof(0, 5) succeeded")
+	}
+	if _, err := tree.ConsistencyProof(6, 5); err == nil {
+		t.Errorf("ConsistencyProof(6, 5) succeeded")
+	}
+	if _, err := tree.InclusionProof(5, 5); err == nil {
+		t.Errorf("InclusionProof(5, 5) succeeded")
+	}
+	if _, err := tree.InclusionProof(0, maxSize+1); err == nil {
+		t.Errorf("InclusionProof(0, %d) succeeded", maxSize+1)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package merkle
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// A Tree is an in-memory Merkle tree, which produces root hashes and proofs
+// for its current size and for any earlier size.
+//
+// A Tree keeps the hashes of all complete subtrees, about two hashes per
+// leaf, so that hashes and proofs take O(log n) time. It is not safe for
+// concurrent use if any goroutine is appending to it.
+type Tree struct {
+	// levels[k][i] is the hash of the complete subtree of 2^k leaves
+	// starting at leaf i*2^k.
+	levels [][]Hash
+}
+
+// Size returns the number of leaves in t.
+func (t *Tree) Size() uint64 {
+	if len(t.levels) == 0 {
+		return 0
+	}
+	return uint64(len(t.levels[0]))
+}
+
+// Append adds a leaf with the given data to t, and returns its index.
+func (t *Tree) Append(data []byte) uint64 {
+	return t.AppendHash(LeafHash(data))
+}
+
+// AppendHash adds a leaf with the given leaf hash, as returned by LeafHash,
+// to t, and returns its index.
+func (t *Tree) AppendHash(leaf Hash) uint64 {
+	index := t.Size()
+	h := leaf
+	for k := 0; ; k++ {
+		if k == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		t.levels[k] = append(t.levels[k], h)
+		n := len(t.levels[k])
+		if n&1 == 1 {
+			break
+		}
+		h = NodeHash(t.levels[k][n-2], h)
+	}
+	return index
+}
+
+// LeafHash returns the hash of the leaf at index.
+func (t *Tree) LeafHash(index uint64) (Hash, error) {
+	if index >= t.Size() {
+		return Hash{}, errors.New("merkle: leaf index out of range")
+	}
+	return t.levels[0][index], nil
+}
+
+// Root returns the root hash of t.
+func (t *Tree) Root() Hash {
+	h, _ := t.RootAt(t.Size())
+	return h
+}
+
+// RootAt returns the root hash that t had when it had size leaves.
+func (t *Tree) RootAt(size uint64) (Hash, error) {
+	if size > t.Size() {
+		return Hash{}, errors.New("merkle: tree size out of range")
+	}
+	if size == 0 {
+		return EmptyRoot(), nil
+	}
+	return t.hash(0, size), nil
+}
+
+// InclusionProof returns the proof that the leaf at index is included in
+// the tree of the given size, per RFC 9162, Section 2.1.3.1.
+func (t *Tree) InclusionProof(index, size uint64) ([]Hash, error) {
+	if size > t.Size() || index >= size {
+		return nil, errors.New("merkle: leaf index or tree size out of range")
+	}
+	var proof []Hash
+	lo, hi := uint64(0), size
+	for hi-lo > 1 {
+		k := splitPoint(hi - lo)
+		if index < lo+k {
+			proof = append(proof, t.hash(lo+k, hi))
+			hi = lo + k
+		} else {
+			proof = append(proof, t.hash(lo, lo+k))
+			lo += k
+		}
+	}
+	reverse(proof)
+	return proof, nil
+}
+
+// ConsistencyProof returns the proof that the tree of newSize leaves is an
+// extension of the tree of oldSize leaves, per RFC 9162, Section
+// 2.1.4.1. oldSize must not be zero.
+func (t *Tree) ConsistencyProof(oldSize, newSize uint64) ([]Hash, error) {
+	if newSize > t.Size() || oldSize == 0 || oldSize > newSize {
+		return nil, errors.New("merkle: tree sizes out of range")
+	}
+	var proof []Hash
+	lo, hi, m := uint64(0), newSize, oldSize
+	complete := true // whether the old tree is a subtree of [lo, hi)
+	for m != hi-lo {
+		k := splitPoint(hi - lo)
+		if m <= k {
+			proof = append(proof, t.hash(lo+k, hi))
+			hi = lo + k
+		} else {
+			proof = append(proof, t.hash(lo, lo+k))
+			lo += k
+			m -= k
+			complete = false
+		}
+	}
+	if !complete {
+		proof = append(proof, t.hash(lo, hi))
+	}
+	reverse(proof)
+	return proof, nil
+}
+
+// hash returns the hash of the leaves [lo, hi), where lo is a multiple of
+// the largest power of two not greater than hi - lo, as it is for every
+// subtree of the tree.
+func (t *Tree) hash(lo, hi uint64) Hash {
+	n := hi - lo
+	if n&(n-1) == 0 {
+		k := bits.TrailingZeros64(n)
+		return t.levels[k][lo>>k]
+	}
+	k := splitPoint(n)
+	return NodeHash(t.hash(lo, lo+k), t.hash(lo+k, hi))
+}
+
+func reverse(s []Hash) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
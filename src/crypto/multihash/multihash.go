@@ -0,0 +1,133 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package multihash implements the multihash self-describing digest
+// format, as specified at https://github.com/multiformats/multihash.
+//
+// A multihash is the varint code of the hash function from the multicodec
+// table, followed by the varint length of the digest and the digest. It is
+// used by content-addressed systems, usually in a multibase encoding (see
+// package encoding/multibase); the base58btc encoding of a SHA-256
+// multihash is, for example, an IPFS CIDv0.
+//
+// This package maps multihash codes to crypto.Hash values. Truncated
+// digests and the identity code are not supported.
+package multihash
+
+import (
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+var codes = map[crypto.Hash]uint64{
+	crypto.MD5:         0xd5,
+	crypto.SHA1:        0x11,
+	crypto.SHA224:      0x1013,
+	crypto.SHA256:      0x12,
+	crypto.SHA384:      0x20,
+	crypto.SHA512:      0x13,
+	crypto.SHA512_224:  0x1014,
+	crypto.SHA512_256:  0x1015,
+	crypto.SHA3_224:    0x17,
+	crypto.SHA3_256:    0x16,
+	crypto.SHA3_384:    0x15,
+	crypto.SHA3_512:    0x14,
+	crypto.RIPEMD160:   0x1053,
+	crypto.BLAKE2s_256: 0xb260,
+	crypto.BLAKE2b_256: 0xb220,
+	crypto.BLAKE2b_384: 0xb230,
+	crypto.BLAKE2b_512: 0xb240,
+}
+
+var hashes = make(map[uint64]crypto.Hash, len(codes))
+
+func init() {
+	for h, c := range codes {
+		hashes[c] = h
+	}
+}
+
+// Code returns the multihash code of h. It reports false if h has no code.
+func Code(h crypto.Hash) (uint64, bool) {
+	c, ok := codes[h]
+	return c, ok
+}
+
+// HashFunc returns the hash function with the multihash code. It reports
+// false if the code is unknown.
+func HashFunc(code uint64) (crypto.Hash, bool) {
+	h, ok := hashes[code]
+	return h, ok
+}
+
+// Encode returns the multihash of a digest computed with h. The digest
+// must have the full size of h.
+func Encode(h crypto.Hash, digest []byte) ([]byte, error) {
+	c, ok := codes[h]
+	if !ok {
+		return nil, errors.New("multihash: unsupported hash function " + h.String())
+	}
+	if len(digest) != h.Size() {
+		return nil, errors.New("multihash: invalid digest size for " + h.String())
+	}
+	out := binary.AppendUvarint(nil, c)
+	out = binary.AppendUvarint(out, uint64(len(digest)))
+	return append(out, digest...), nil
+}
+
+// Sum hashes data with h, which must be available, and returns the
+// multihash of the digest.
+func Sum(h crypto.Hash, data []byte) ([]byte, error) {
+	if _, ok := codes[h]; !ok {
+		return nil, errors.New("multihash: unsupported hash function " + h.String())
+	}
+	if !h.Available() {
+		return nil, errors.New("multihash: hash function " + h.String() + " is not linked into the binary")
+	}
+	d := h.New()
+	d.Write(data)
+	return Encode(h, d.Sum(nil))
+}
+
+// Decode parses a multihash, returning its hash function and digest. The
+// digest aliases mh.
+func Decode(mh []byte) (crypto.Hash, []byte, error) {
+	code, n, err := readUvarint(mh)
+	if err != nil {
+		return 0, nil, err
+	}
+	mh = mh[n:]
+	size, n, err := readUvarint(mh)
+	if err != nil {
+		return 0, nil, err
+	}
+	mh = mh[n:]
+
+	h, ok := hashes[code]
+	if !ok {
+		return 0, nil, errors.New("multihash: unknown code 0x" + strconv.FormatUint(code, 16))
+	}
+	if size != uint64(h.Size()) {
+		return 0, nil, errors.New("multihash: invalid digest size for " + h.String())
+	}
+	if uint64(len(mh)) != size {
+		return 0, nil, errors.New("multihash: digest length does not match")
+	}
+	return h, mh, nil
+}
+
+// readUvarint reads an unsigned varint in the minimal encoding of at most
+// nine bytes required by the multiformats specification.
+func readUvarint(b []byte) (v uint64, n int, err error) {
+	v, n = binary.Uvarint(b)
+	if n <= 0 || n > 9 {
+		return 0, 0, errors.New("multihash: malformed varint")
+	}
+	if n > 1 && b[n-1] == 0 {
+		return 0, 0, errors.New("multihash: non-minimal varint")
+	}
+	return v, n, nil
+}
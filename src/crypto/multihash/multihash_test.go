@@ -0,0 +1,109 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multihash
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/blake2b"
+	_ "crypto/blake2s"
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha3"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"encoding/multibase"
+	"testing"
+)
+
+func TestSumVectors(t *testing.T) {
+	for _, tt := range []struct {
+		h    crypto.Hash
+		data string
+		want string
+	}{
+		// From the multihash specification.
+		{crypto.SHA256, "Merkle–Damgård", "122041dd7b6443542e75701aa98a0c235951a28a0d851b11564d20022ab11d2589a8"},
+		{crypto.SHA1, "multihash", "111488c2f11fb2ce392acb5b2986e640211c4690073e"},
+		{crypto.BLAKE2b_256, "foo", "a0e40220b8fe9f7f6255a6fa08f668ab632a8d081ad87983c77cd274e48ce450f0b349fd"},
+	} {
+		mh, err := Sum(tt.h, []byte(tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(mh); got != tt.want {
+			t.Errorf("Sum(%v, %q) = %s, want %s", tt.h, tt.data, got, tt.want)
+		}
+	}
+
+	// An IPFS CIDv0.
+	mh, err := Sum(crypto.SHA256, []byte("multihash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := multibase.Encode(multibase.Base58BTC, mh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "zQmYtUc4iTCbbfVSDNKvtQqrfyezPPnFvE33wFmutw9PBBk" {
+		t.Errorf("base58btc multihash = %s", s)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for h, code := range codes {
+		if c, ok := Code(h); !ok || c != code {
+			t.Errorf("Code(%v) = %#x, %v", h, c, ok)
+		}
+		if got, ok := HashFunc(code); !ok || got != h {
+			t.Errorf("HashFunc(%#x) = %v, %v", code, got, ok)
+		}
+		if !h.Available() {
+			continue
+		}
+		mh, err := Sum(h, []byte("hello"))
+		if err != nil {
+			t.Fatalf("Sum(%v): %v", h, err)
+		}
+		got, digest, err := Decode(mh)
+		if err != nil {
+			t.Fatalf("Decode(%x): %v", mh, err)
+		}
+		d := h.New()
+		d.Write([]byte("hello"))
+		if got != h || !bytes.Equal(digest, d.Sum(nil)) {
+			t.Errorf("Decode(%x) = %v, %x", mh, got, digest)
+		}
+	}
+	if _, err := Sum(crypto.RIPEMD160, nil); err == nil {
+		t.Errorf("Sum with an unavailable hash succeeded")
+	}
+	if _, err := Encode(crypto.MD5SHA1, make([]byte, 36)); err == nil {
+		t.Errorf("Encode(MD5SHA1) succeeded")
+	}
+	if _, err := Encode(crypto.SHA256, make([]byte, 20)); err == nil {
+		t.Errorf("Encode with a truncated digest succeeded")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	digest := make([]byte, 32)
+	for _, mh := range [][]byte{
+		nil,
+		{0x12},
+		append([]byte{0x12, 0x20}, digest[:31]...),
+		append([]byte{0x12, 0x20}, make([]byte, 33)...),
+		append([]byte{0x12, 0x14}, digest[:20]...),
+		append([]byte{0x92, 0x00, 0x20}, digest...),
+		append([]byte{0x12, 0xa0, 0x00}, digest...),
+		append([]byte{0x00, 0x20}, digest...),
+		append([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x20}, digest...),
+	} {
+		if _, _, err := Decode(mh); err == nil {
+			t.Errorf("Decode(%x) succeeded", mh)
+		}
+	}
+}
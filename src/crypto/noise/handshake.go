@@ -0,0 +1,427 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package noise
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/secret"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	dhLen  = 32 // the size of X25519 public keys and shared secrets
+	tagLen = 16 // the size of the authentication tag of both ciphers
+)
+
+// A Config configures a HandshakeState.
+type Config struct {
+	Suite   Suite
+	Pattern *HandshakePattern
+
+	// Initiator reports whether this party sends the first message.
+	Initiator bool
+
+	// Prologue is data that both parties must agree on, such as the
+	// protocol negotiation that preceded the handshake. It is
+	// authenticated by the handshake but not sent.
+	Prologue []byte
+
+	// StaticKey is the X25519 static key pair of this party. It is
+	// required if the pattern uses it.
+	StaticKey *ecdh.PrivateKey
+
+	// PeerStatic is the X25519 static public key of the peer. It is
+	// required if the pattern has the peer's static key known in
+	// advance, as IK and NK do for the initiator, and is otherwise
+	// ignored.
+	PeerStatic *ecdh.PublicKey
+
+	// PresharedKey, if it holds a non-nil value, is a 32-byte secret
+	// shared by the two parties, mixed into the handshake at
+	// PresharedKeyPlacement as the pskN modifier: 0 is the start of the
+	// first message, and N is the end of the Nth message. It is redacted
+	// when a Config is formatted or marshaled.
+	PresharedKey          secret.Secret[[]byte]
+	PresharedKeyPlacement int
+
+	// Rand is the source of the ephemeral keys. If nil, crypto/rand.Reader
+	// is used.
+	Rand io.Reader
+}
+
+// A HandshakeState is one party of a Noise handshake.
+//
+// A HandshakeState is not safe for concurrent use.
+type HandshakeState struct {
+	hash      func() hash.Hash
+	cs        CipherState
+	ck, h     []byte
+	messages  [][]token
+	initiator bool
+	psk       []byte
+	rand      io.Reader
+
+	s, e   *ecdh.PrivateKey
+	rs, re *ecdh.PublicKey
+
+	msg    int  // index of the next message
+	failed bool // a message failed to be read or written
+}
+
+// NewHandshakeState returns the state of a new handshake described by c.
+// If a cryptographic module operates in FIPS mode and GODEBUG=fips140=only
+// is set, a suite with ChaChaPoly, which is not approved, is rejected with
+// an error matching crypto/fips.ErrNotApproved.
+func NewHandshakeState(c *Config) (*HandshakeState, error) {
+	if err := c.Suite.check(); err != nil {
+		return nil, err
+	}
+	p := c.Pattern
+	if p == nil {
+		return nil, errors.New("noise: missing handshake pattern")
+	}
+	hs := &HandshakeState{
+		hash:      c.Suite.Hash.new(),
+		cs:        CipherState{cipher: c.Suite.Cipher},
+		messages:  p.messages,
+		initiator: c.Initiator,
+		rand:      c.Rand,
+		s:         c.StaticKey,
+	}
+	if hs.rand == nil {
+		hs.rand = rand.Reader
+	}
+	if hs.s != nil && hs.s.Curve() != ecdh.X25519() {
+		return nil, errors.New("noise: static key is not an X25519 key")
+	}
+
+	localPreS, peerPreS := p.initiatorPreS, p.responderPreS
+	if !c.Initiator {
+		localPreS, peerPreS = peerPreS, localPreS
+	}
+	if hs.s == nil && (localPreS || p.sendsStatic(c.Initiator)) {
+		return nil, errors.New("noise: pattern " + p.name + " requires a static key")
+	}
+	if peerPreS {
+		if c.PeerStatic == nil {
+			return nil, errors.New("noise: pattern " + p.name + " requires the peer static key")
+		}
+		if c.PeerStatic.Curve() != ecdh.X25519() {
+			return nil, errors.New("noise: peer static key is not an X25519 key")
+		}
+		hs.rs = c.PeerStatic
+	}
+
+	name := "Noise_" + p.name
+	if psk := c.PresharedKey.Expose(); psk != nil {
+		if len(psk) != 32 {
+			return nil, errors.New("noise: pre-shared key is not 32 bytes")
+		}
+		if c.PresharedKeyPlacement < 0 || c.PresharedKeyPlacement > len(p.messages) {
+			return nil, errors.New("noise: invalid pre-shared key placement")
+		}
+		name += "psk" + strconv.Itoa(c.PresharedKeyPlacement)
+		hs.psk = psk
+		hs.messages = p.withPSK(c.PresharedKeyPlacement)
+	}
+	name += "_" + c.Suite.String()
+
+	// InitializeSymmetric.
+	hashLen := hs.hash().Size()
+	if len(name) <= hashLen {
+		hs.h = make([]byte, hashLen)
+		copy(hs.h, name)
+	} else {
+		hs.h = hs.sum([]byte(name))
+	}
+	hs.ck = hs.h
+	hs.mixHash(c.Prologue)
+
+	// The pre-message keys, the initiator's first.
+	if p.initiatorPreS {
+		hs.mixPreMessageKey(c.Initiator)
+	}
+	if p.responderPreS {
+		hs.mixPreMessageKey(!c.Initiator)
+	}
+	return hs, nil
+}
+
+// mixPreMessageKey mixes the static public key of this party if local,
+// or else of the peer, into the handshake hash.
+func (hs *HandshakeState) mixPreMessageKey(local bool) {
+	if local {
+		hs.mixHash(hs.s.PublicKey().Bytes())
+	} else {
+		hs.mixHash(hs.rs.Bytes())
+	}
+}
+
+func (hs *HandshakeState) sum(data ...[]byte) []byte {
+	h := hs.hash()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func (hs *HandshakeState) mixHash(data []byte) {
+	hs.h = hs.sum(hs.h, data)
+}
+
+// hkdf returns n outputs of the HKDF function of the specification, with
+// chaining key hs.ck and input key material ikm.
+func (hs *HandshakeState) hkdf(ikm []byte, n int) [][]byte {
+	hashLen := hs.hash().Size()
+	prk := hkdf.Extract(hs.hash, ikm, hs.ck)
+	out := make([]byte, n*hashLen)
+	if _, err := io.ReadFull(hkdf.Expand(hs.hash, prk, nil), out); err != nil {
+		panic("noise: internal error: " + err.Error())
+	}
+	outputs := make([][]byte, n)
+	for i := range outputs {
+		outputs[i] = out[i*hashLen : (i+1)*hashLen : (i+1)*hashLen]
+	}
+	return outputs
+}
+
+func (hs *HandshakeState) mixKey(ikm []byte) {
+	out := hs.hkdf(ikm, 2)
+	hs.ck = out[0]
+	hs.cs.initializeKey(out[1][:32])
+}
+
+func (hs *HandshakeState) mixKeyAndHash(ikm []byte) {
+	out := hs.hkdf(ikm, 3)
+	hs.ck = out[0]
+	hs.mixHash(out[1])
+	hs.cs.initializeKey(out[2][:32])
+}
+
+func (hs *HandshakeState) encryptAndHash(out, plaintext []byte) ([]byte, error) {
+	n := len(out)
+	out, err := hs.cs.encryptWithAd(out, hs.h, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	hs.mixHash(out[n:])
+	return out, nil
+}
+
+func (hs *HandshakeState) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	plaintext, err := hs.cs.decryptWithAd(nil, hs.h, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	hs.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+// dh performs the DH operation of a token, from the point of view of
+// this party.
+func (hs *HandshakeState) dh(t token) error {
+	var priv *ecdh.PrivateKey
+	var pub *ecdh.PublicKey
+	switch t {
+	case tokenEE:
+		priv, pub = hs.e, hs.re
+	case tokenSS:
+		priv, pub = hs.s, hs.rs
+	case tokenES:
+		if hs.initiator {
+			priv, pub = hs.e, hs.rs
+		} else {
+			priv, pub = hs.s, hs.re
+		}
+	case tokenSE:
+		if hs.initiator {
+			priv, pub = hs.s, hs.re
+		} else {
+			priv, pub = hs.e, hs.rs
+		}
+	}
+	if priv == nil || pub == nil {
+		panic("noise: internal error: missing key for DH")
+	}
+	shared, err := priv.ECDH(pub)
+	if err != nil {
+		return err
+	}
+	hs.mixKey(shared)
+	return nil
+}
+
+func (hs *HandshakeState) checkTurn(write bool) error {
+	switch {
+	case hs.failed:
+		return errors.New("noise: handshake failed")
+	case hs.msg >= len(hs.messages):
+		return errors.New("noise: handshake is complete")
+	case (hs.msg%2 == 0) != (hs.initiator == write):
+		return errors.New("noise: out of turn handshake message")
+	}
+	return nil
+}
+
+// WriteMessage returns the next handshake message, which carries payload.
+func (hs *HandshakeState) WriteMessage(payload []byte) ([]byte, error) {
+	if err := hs.checkTurn(true); err != nil {
+		return nil, err
+	}
+	msg, err := hs.writeMessage(payload)
+	if err != nil {
+		hs.failed = true
+		return nil, err
+	}
+	if len(msg) > MaxMessageSize {
+		hs.failed = true
+		return nil, errors.New("noise: message too large")
+	}
+	hs.msg++
+	return msg, nil
+}
+
+func (hs *HandshakeState) writeMessage(payload []byte) ([]byte, error) {
+	var out []byte
+	var err error
+	for _, t := range hs.messages[hs.msg] {
+		switch t {
+		case tokenE:
+			seed := make([]byte, dhLen)
+			if _, err := io.ReadFull(hs.rand, seed); err != nil {
+				return nil, err
+			}
+			if hs.e, err = ecdh.X25519().NewPrivateKey(seed); err != nil {
+				return nil, err
+			}
+			pub := hs.e.PublicKey().Bytes()
+			out = append(out, pub...)
+			hs.mixHash(pub)
+			if hs.psk != nil {
+				hs.mixKey(pub)
+			}
+		case tokenS:
+			if out, err = hs.encryptAndHash(out, hs.s.PublicKey().Bytes()); err != nil {
+				return nil, err
+			}
+		case tokenPSK:
+			hs.mixKeyAndHash(hs.psk)
+		default:
+			if err := hs.dh(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hs.encryptAndHash(out, payload)
+}
+
+// ReadMessage processes the next handshake message, sent by the peer, and
+// returns its payload. If ReadMessage fails, the handshake can't proceed.
+func (hs *HandshakeState) ReadMessage(message []byte) ([]byte, error) {
+	if err := hs.checkTurn(false); err != nil {
+		return nil, err
+	}
+	if len(message) > MaxMessageSize {
+		hs.failed = true
+		return nil, errors.New("noise: message too large")
+	}
+	payload, err := hs.readMessage(message)
+	if err != nil {
+		hs.failed = true
+		return nil, err
+	}
+	hs.msg++
+	return payload, nil
+}
+
+func (hs *HandshakeState) readMessage(message []byte) ([]byte, error) {
+	errShort := errors.New("noise: handshake message too short")
+	for _, t := range hs.messages[hs.msg] {
+		switch t {
+		case tokenE:
+			if len(message) < dhLen {
+				return nil, errShort
+			}
+			pub := message[:dhLen]
+			message = message[dhLen:]
+			var err error
+			if hs.re, err = ecdh.X25519().NewPublicKey(pub); err != nil {
+				return nil, err
+			}
+			hs.mixHash(pub)
+			if hs.psk != nil {
+				hs.mixKey(pub)
+			}
+		case tokenS:
+			n := dhLen
+			if hs.cs.aead != nil {
+				n += tagLen
+			}
+			if len(message) < n {
+				return nil, errShort
+			}
+			pub, err := hs.decryptAndHash(message[:n])
+			if err != nil {
+				return nil, err
+			}
+			message = message[n:]
+			if hs.rs, err = ecdh.X25519().NewPublicKey(pub); err != nil {
+				return nil, err
+			}
+		case tokenPSK:
+			hs.mixKeyAndHash(hs.psk)
+		default:
+			if err := hs.dh(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hs.decryptAndHash(message)
+}
+
+// Complete reports whether all the handshake messages have been sent and
+// received.
+func (hs *HandshakeState) Complete() bool {
+	return !hs.failed && hs.msg == len(hs.messages)
+}
+
+// Split returns the CipherStates for the transport messages sent and
+// received by this party. It can only be called once the handshake is
+// complete.
+func (hs *HandshakeState) Split() (send, recv *CipherState, err error) {
+	if !hs.Complete() {
+		return nil, nil, errors.New("noise: handshake is not complete")
+	}
+	out := hs.hkdf(nil, 2)
+	c1 := &CipherState{cipher: hs.cs.cipher}
+	c1.initializeKey(out[0][:32])
+	c2 := &CipherState{cipher: hs.cs.cipher}
+	c2.initializeKey(out[1][:32])
+	if hs.initiator {
+		return c1, c2, nil
+	}
+	return c2, c1, nil
+}
+
+// PeerStatic returns the static public key of the peer, if it was known
+// in advance or has been received, or nil otherwise.
+//
+// Applications must check that a static key received in the handshake
+// belongs to a peer they trust.
+func (hs *HandshakeState) PeerStatic() *ecdh.PublicKey {
+	return hs.rs
+}
+
+// HandshakeHash returns the handshake hash, which uniquely identifies the
+// handshake once it is complete. It can be used for channel binding.
+func (hs *HandshakeState) HandshakeHash() []byte {
+	return append([]byte(nil), hs.h...)
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package noise implements the Noise Protocol Framework, revision 34, as
+// specified at https://noiseprotocol.org/noise.html.
+//
+// A Noise protocol is an authenticated key exchange described by a
+// handshake pattern, such as XX, IK, or NK, and a Suite of cryptographic
+// functions. The two parties each create a HandshakeState from a Config
+// and exchange handshake messages, which can carry payloads, until the
+// handshake is complete. They then Split the handshake into a pair of
+// CipherStates that encrypt the transport messages in each direction.
+//
+// Only the 25519 DH functions are supported, with the ChaChaPoly and
+// AESGCM ciphers and the SHA256 and SHA512 hash functions. Pre-shared
+// keys are supported through the psk modifier, with Config.PresharedKey.
+package noise
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/internal/fips"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+	"strconv"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// MaxMessageSize is the maximum size of a Noise message, including the
+// authentication tag of its payload.
+const MaxMessageSize = 65535
+
+// A Cipher is a Noise cipher function.
+type Cipher int
+
+const (
+	ChaChaPoly Cipher = 1 + iota
+	AESGCM
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case ChaChaPoly:
+		return "ChaChaPoly"
+	case AESGCM:
+		return "AESGCM"
+	}
+	return "Cipher(" + strconv.Itoa(int(c)) + ")"
+}
+
+// A Hash is a Noise hash function.
+type Hash int
+
+const (
+	SHA256 Hash = 1 + iota
+	SHA512
+)
+
+func (h Hash) String() string {
+	switch h {
+	case SHA256:
+		return "SHA256"
+	case SHA512:
+		return "SHA512"
+	}
+	return "Hash(" + strconv.Itoa(int(h)) + ")"
+}
+
+func (h Hash) new() func() hash.Hash {
+	switch h {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+// A Suite is the combination of a cipher and a hash function used with
+// the 25519 DH functions.
+type Suite struct {
+	Cipher Cipher
+	Hash   Hash
+}
+
+// String returns the name of s as used in protocol names, such as
+// "25519_ChaChaPoly_SHA256".
+func (s Suite) String() string {
+	return "25519_" + s.Cipher.String() + "_" + s.Hash.String()
+}
+
+func (s Suite) check() error {
+	if s.Cipher != ChaChaPoly && s.Cipher != AESGCM {
+		return errors.New("noise: unsupported cipher " + s.Cipher.String())
+	}
+	if s.Hash.new() == nil {
+		return errors.New("noise: unsupported hash " + s.Hash.String())
+	}
+	if s.Cipher == ChaChaPoly {
+		return fips.Check("ChaCha20-Poly1305")
+	}
+	return nil
+}
+
+// ErrDecryption is returned when a message fails to decrypt.
+var ErrDecryption = errors.New("noise: message authentication failed")
+
+// A CipherState encrypts or decrypts the transport messages in one
+// direction. Messages must be decrypted in the order they were encrypted,
+// unless the nonce is set explicitly with SetNonce.
+//
+// A CipherState is not safe for concurrent use.
+type CipherState struct {
+	cipher Cipher
+	aead   cipher.AEAD // nil if the key is empty
+	n      uint64
+}
+
+// initializeKey sets the key of c and resets its nonce.
+func (c *CipherState) initializeKey(key []byte) {
+	var aead cipher.AEAD
+	var err error
+	switch c.cipher {
+	case ChaChaPoly:
+		if aead, err = chacha20poly1305.New(key); err == nil {
+			aead = fips.AEAD(aead)
+		}
+	case AESGCM:
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err == nil {
+			aead, err = cipher.NewGCM(block)
+		}
+	default:
+		panic("noise: internal error: unexpected cipher")
+	}
+	if err != nil {
+		panic("noise: internal error: " + err.Error())
+	}
+	c.aead, c.n = aead, 0
+}
+
+func (c *CipherState) nonce(n uint64) []byte {
+	nonce := make([]byte, 12)
+	if c.cipher == AESGCM {
+		binary.BigEndian.PutUint64(nonce[4:], n)
+	} else {
+		binary.LittleEndian.PutUint64(nonce[4:], n)
+	}
+	return nonce
+}
+
+// encryptWithAd is EncryptWithAd from the specification. If c has no key
+// the plaintext is returned unchanged.
+func (c *CipherState) encryptWithAd(out, ad, plaintext []byte) ([]byte, error) {
+	if c.aead == nil {
+		return append(out, plaintext...), nil
+	}
+	// The maximum nonce is reserved for Rekey.
+	if c.n == math.MaxUint64 {
+		return nil, errors.New("noise: nonce exhausted")
+	}
+	out = c.aead.Seal(out, c.nonce(c.n), plaintext, ad)
+	c.n++
+	return out, nil
+}
+
+// decryptWithAd is DecryptWithAd from the specification. The nonce is
+// only advanced if decryption succeeds.
+func (c *CipherState) decryptWithAd(out, ad, ciphertext []byte) ([]byte, error) {
+	if c.aead == nil {
+		return append(out, ciphertext...), nil
+	}
+	if c.n == math.MaxUint64 {
+		return nil, errors.New("noise: nonce exhausted")
+	}
+	out, err := c.aead.Open(out, c.nonce(c.n), ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	c.n++
+	return out, nil
+}
+
+// Encrypt encrypts and authenticates plaintext and authenticates ad with
+// the next nonce, and returns the ciphertext.
+func (c *CipherState) Encrypt(ad, plaintext []byte) ([]byte, error) {
+	if len(plaintext)+c.aead.Overhead() > MaxMessageSize {
+		return nil, errors.New("noise: message too large")
+	}
+	return c.encryptWithAd(nil, ad, plaintext)
+}
+
+// Decrypt decrypts ciphertext and authenticates it and ad with the next
+// nonce, and returns the plaintext. If decryption fails, the error is
+// ErrDecryption and the nonce is not advanced.
+func (c *CipherState) Decrypt(ad, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) > MaxMessageSize {
+		return nil, errors.New("noise: message too large")
+	}
+	return c.decryptWithAd(nil, ad, ciphertext)
+}
+
+// Rekey replaces the key of c with one derived from it, as specified in
+// Section 11.3 of the specification, without changing the nonce. Both
+// parties must rekey at the same point in the message stream.
+func (c *CipherState) Rekey() {
+	var zeros [32]byte
+	k := c.aead.Seal(nil, c.nonce(math.MaxUint64), zeros[:], nil)
+	n := c.n
+	c.initializeKey(k[:32])
+	c.n = n
+}
+
+// Nonce returns the nonce that will be used for the next message.
+func (c *CipherState) Nonce() uint64 {
+	return c.n
+}
+
+// SetNonce sets the nonce that will be used for the next message. It is
+// used by protocols that carry the nonce in their transport messages to
+// tolerate loss and reordering; they must then reject replayed nonces
+// themselves.
+func (c *CipherState) SetNonce(n uint64) {
+	c.n = n
+}
@@ -0,0 +1,405 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package noise
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/secret"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testSuites = []Suite{
+	{ChaChaPoly, SHA256},
+	{ChaChaPoly, SHA512},
+	{AESGCM, SHA256},
+	{AESGCM, SHA512},
+}
+
+var testPatterns = map[string]*HandshakePattern{"NK": NK, "IK": IK, "XX": XX}
+
+// testdata/vectors.txt holds the vectors of github.com/flynn/noise for the
+// supported protocols, with a prologue and payloads. Each handshake is
+// followed by two transport messages, one in each direction.
+func readVectors(t *testing.T) []map[string]string {
+	f, err := os.Open("testdata/vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var vectors []map[string]string
+	v := map[string]string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if s.Text() == "" {
+			vectors = append(vectors, v)
+			v = map[string]string{}
+			continue
+		}
+		key, value, ok := strings.Cut(s.Text(), "=")
+		if !ok {
+			t.Fatalf("malformed line %q", s.Text())
+		}
+		v[key] = value
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return append(vectors, v)
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testKey(t *testing.T, s string) *ecdh.PrivateKey {
+	t.Helper()
+	if s == "" {
+		return nil
+	}
+	k, err := ecdh.X25519().NewPrivateKey(decodeHex(t, s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range readVectors(t) {
+		t.Run(v["handshake"], func(t *testing.T) {
+			parts := strings.Split(v["handshake"], "_")
+			name, psk, _ := strings.Cut(parts[1], "psk")
+			var suite Suite
+			for _, s := range testSuites {
+				if s.String() == strings.Join(parts[2:], "_") {
+					suite = s
+				}
+			}
+			iStatic, rStatic := testKey(t, v["init_static"]), testKey(t, v["resp_static"])
+			ic := &Config{
+				Suite:     suite,
+				Pattern:   testPatterns[name],
+				Initiator: true,
+				Prologue:  decodeHex(t, v["prologue"]),
+				StaticKey: iStatic,
+				Rand:      bytes.NewReader(decodeHex(t, v["gen_init_ephemeral"])),
+			}
+			rc := &Config{
+				Suite:     suite,
+				Pattern:   testPatterns[name],
+				Prologue:  decodeHex(t, v["prologue"]),
+				StaticKey: rStatic,
+				Rand:      bytes.NewReader(decodeHex(t, v["gen_resp_ephemeral"])),
+			}
+			if rStatic != nil {
+				ic.PeerStatic = rStatic.PublicKey()
+			}
+			if psk != "" {
+				ic.PresharedKey = secret.New(decodeHex(t, v["preshared_key"]))
+				rc.PresharedKey = ic.PresharedKey
+				ic.PresharedKeyPlacement, _ = strconv.Atoi(psk)
+				rc.PresharedKeyPlacement = ic.PresharedKeyPlacement
+			}
+			initiator, err := NewHandshakeState(ic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			responder, err := NewHandshakeState(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var iSend, iRecv, rSend, rRecv *CipherState
+			for i := 0; ; i++ {
+				hexPayload, ok := v["msg_"+strconv.Itoa(i)+"_payload"]
+				if !ok {
+					break
+				}
+				payload := decodeHex(t, hexPayload)
+				want := decodeHex(t, v["msg_"+strconv.Itoa(i)+"_ciphertext"])
+				if !initiator.Complete() {
+					w, r := initiator, responder
+					if i%2 == 1 {
+						w, r = r, w
+					}
+					msg, err := w.WriteMessage(payload)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(msg, want) {
+						t.Fatalf("message %d = %x, want %x", i, msg, want)
+					}
+					got, err := r.ReadMessage(msg)
+					if err != nil || !bytes.Equal(got, payload) {
+						t.Fatalf("ReadMessage of message %d = %x, %v", i, got, err)
+					}
+					if initiator.Complete() {
+						if !responder.Complete() {
+							t.Fatal("handshake complete for the initiator only")
+						}
+						if !bytes.Equal(initiator.HandshakeHash(), responder.HandshakeHash()) {
+							t.Error("handshake hashes differ")
+						}
+						if iSend, iRecv, err = initiator.Split(); err != nil {
+							t.Fatal(err)
+						}
+						if rSend, rRecv, err = responder.Split(); err != nil {
+							t.Fatal(err)
+						}
+					}
+					continue
+				}
+				// The transport messages alternate, starting with the
+				// initiator.
+				send, recv := iSend, rRecv
+				if (i-len(initiator.messages))%2 == 1 {
+					send, recv = rSend, iRecv
+				}
+				msg, err := send.Encrypt(nil, payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(msg, want) {
+					t.Fatalf("message %d = %x, want %x", i, msg, want)
+				}
+				got, err := recv.Decrypt(nil, msg)
+				if err != nil || !bytes.Equal(got, payload) {
+					t.Fatalf("Decrypt of message %d = %x, %v", i, got, err)
+				}
+			}
+			if iSend == nil {
+				t.Fatal("handshake did not complete")
+			}
+		})
+	}
+}
+
+func generateKey(t *testing.T) *ecdh.PrivateKey {
+	k, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// handshake runs the handshake between the initiator and the responder.
+func handshake(t *testing.T, initiator, responder *HandshakeState) {
+	t.Helper()
+	w, r := initiator, responder
+	for i := 0; !initiator.Complete(); i++ {
+		payload := []byte("payload " + strconv.Itoa(i))
+		msg, err := w.WriteMessage(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.ReadMessage(msg)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Fatalf("ReadMessage = %q, %v", got, err)
+		}
+		w, r = r, w
+	}
+	if !responder.Complete() {
+		t.Fatal("handshake complete for the initiator only")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	iStatic, rStatic := generateKey(t), generateKey(t)
+	for _, s := range testSuites {
+		for _, p := range []*HandshakePattern{NK, IK, XX} {
+			t.Run(p.Name()+"_"+s.String(), func(t *testing.T) {
+				ic := &Config{Suite: s, Pattern: p, Initiator: true, PeerStatic: rStatic.PublicKey()}
+				if p != NK {
+					ic.StaticKey = iStatic
+				}
+				initiator, err := NewHandshakeState(ic)
+				if err != nil {
+					t.Fatal(err)
+				}
+				responder, err := NewHandshakeState(&Config{Suite: s, Pattern: p, StaticKey: rStatic})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, _, err := initiator.Split(); err == nil {
+					t.Error("Split before the end of the handshake succeeded")
+				}
+				handshake(t, initiator, responder)
+				if p != NK && !responder.PeerStatic().Equal(iStatic.PublicKey()) {
+					t.Error("responder learned the wrong initiator static key")
+				}
+				if !initiator.PeerStatic().Equal(rStatic.PublicKey()) {
+					t.Error("initiator learned the wrong responder static key")
+				}
+				if _, err := initiator.WriteMessage(nil); err == nil {
+					t.Error("WriteMessage after the end of the handshake succeeded")
+				}
+
+				iSend, iRecv, _ := initiator.Split()
+				rSend, rRecv, _ := responder.Split()
+				for _, c := range []struct{ send, recv *CipherState }{{iSend, rRecv}, {rSend, iRecv}} {
+					for i := 0; i < 3; i++ {
+						msg := []byte("message " + strconv.Itoa(i))
+						ct, err := c.send.Encrypt([]byte("ad"), msg)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if _, err := c.recv.Decrypt([]byte("other"), ct); err != ErrDecryption {
+							t.Errorf("Decrypt with the wrong additional data: %v", err)
+						}
+						// A failed Decrypt must not advance the nonce.
+						if pt, err := c.recv.Decrypt([]byte("ad"), ct); err != nil || !bytes.Equal(pt, msg) {
+							t.Errorf("Decrypt = %q, %v", pt, err)
+						}
+						if i == 1 {
+							c.send.Rekey()
+							c.recv.Rekey()
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPresharedKey(t *testing.T) {
+	rStatic := generateKey(t)
+	psk := bytes.Repeat([]byte{42}, 32)
+	for placement := 0; placement <= 2; placement++ {
+		c := Config{Suite: Suite{ChaChaPoly, SHA256}, Pattern: NK, PresharedKey: secret.New(psk), PresharedKeyPlacement: placement}
+		ic, rc := c, c
+		ic.Initiator, ic.PeerStatic = true, rStatic.PublicKey()
+		rc.StaticKey = rStatic
+		initiator, _ := NewHandshakeState(&ic)
+		responder, _ := NewHandshakeState(&rc)
+		handshake(t, initiator, responder)
+
+		rc.PresharedKey = secret.New(bytes.Repeat([]byte{43}, 32))
+		initiator, _ = NewHandshakeState(&ic)
+		responder, _ = NewHandshakeState(&rc)
+		msg, _ := initiator.WriteMessage(nil)
+		if _, err := responder.ReadMessage(msg); err == nil {
+			msg, _ = responder.WriteMessage(nil)
+			_, err = initiator.ReadMessage(msg)
+			if err == nil {
+				t.Errorf("psk%d: handshake with the wrong pre-shared key succeeded", placement)
+			}
+		}
+	}
+}
+
+func TestConfigRedactsPresharedKey(t *testing.T) {
+	psk := bytes.Repeat([]byte{0xa5}, 32)
+	c := &Config{Suite: Suite{AESGCM, SHA256}, Pattern: XX, PresharedKey: secret.New(psk)}
+	for _, format := range []string{"%v", "%+v", "%#v", "%x"} {
+		out := fmt.Sprintf(format, c)
+		if strings.Contains(out, "a5a5") || strings.Contains(out, "165 165") {
+			t.Errorf("Sprintf(%q, c) exposes the pre-shared key: %s", format, out)
+		}
+		if !strings.Contains(out, "[REDACTED]") {
+			t.Errorf("Sprintf(%q, c) = %s, want [REDACTED]", format, out)
+		}
+	}
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"PresharedKey":"[REDACTED]"`) {
+		t.Errorf("json.Marshal(c) = %s, want a redacted PresharedKey", out)
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	s := Suite{AESGCM, SHA256}
+	rStatic := generateKey(t)
+	p256, _ := ecdh.P256().GenerateKey(rand.Reader)
+	for _, c := range []*Config{
+		{Suite: s},
+		{Suite: Suite{0, SHA256}, Pattern: XX},
+		{Suite: Suite{AESGCM, 0}, Pattern: XX},
+		{Suite: s, Pattern: XX, Initiator: true},
+		{Suite: s, Pattern: NK, Initiator: true},
+		{Suite: s, Pattern: NK},
+		{Suite: s, Pattern: NK, Initiator: true, PeerStatic: p256.PublicKey()},
+		{Suite: s, Pattern: XX, StaticKey: p256},
+		{Suite: s, Pattern: XX, StaticKey: rStatic, PresharedKey: secret.New([]byte("short"))},
+		{Suite: s, Pattern: XX, StaticKey: rStatic, PresharedKey: secret.New(make([]byte, 32)), PresharedKeyPlacement: 4},
+	} {
+		if _, err := NewHandshakeState(c); err == nil {
+			t.Errorf("NewHandshakeState(%+v) succeeded", c)
+		}
+	}
+
+	initiator, _ := NewHandshakeState(&Config{Suite: s, Pattern: IK, Initiator: true, StaticKey: generateKey(t), PeerStatic: rStatic.PublicKey()})
+	responder, _ := NewHandshakeState(&Config{Suite: s, Pattern: IK, StaticKey: rStatic})
+	if _, err := responder.WriteMessage(nil); err == nil {
+		t.Error("WriteMessage out of turn succeeded")
+	}
+	if _, err := initiator.ReadMessage(nil); err == nil {
+		t.Error("ReadMessage out of turn succeeded")
+	}
+	msg, err := initiator.WriteMessage([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range [][]byte{msg[:31], msg[:len(msg)-1], append(append([]byte(nil), msg...), 0)} {
+		r, _ := NewHandshakeState(&Config{Suite: s, Pattern: IK, StaticKey: rStatic})
+		if _, err := r.ReadMessage(bad); err == nil {
+			t.Errorf("ReadMessage of a malformed message succeeded")
+		}
+	}
+	msg[len(msg)-1] ^= 1
+	if _, err := responder.ReadMessage(msg); err != ErrDecryption {
+		t.Errorf("ReadMessage of a modified message: %v", err)
+	}
+	msg[len(msg)-1] ^= 1
+	if _, err := responder.ReadMessage(msg); err == nil {
+		t.Error("ReadMessage after a failed message succeeded")
+	}
+
+	if _, err := initiator.WriteMessage(make([]byte, MaxMessageSize)); err == nil {
+		t.Error("WriteMessage of a too large payload succeeded")
+	}
+}
+
+func TestNonceExhaustion(t *testing.T) {
+	initiator, _ := NewHandshakeState(&Config{Suite: Suite{ChaChaPoly, SHA512}, Pattern: XX, Initiator: true, StaticKey: generateKey(t)})
+	responder, _ := NewHandshakeState(&Config{Suite: Suite{ChaChaPoly, SHA512}, Pattern: XX, StaticKey: generateKey(t)})
+	handshake(t, initiator, responder)
+	send, _, _ := initiator.Split()
+	_, recv, _ := responder.Split()
+
+	send.SetNonce(math.MaxUint64 - 1)
+	ct, err := send.Encrypt(nil, []byte("last"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := send.Encrypt(nil, nil); err == nil {
+		t.Error("Encrypt with the maximum nonce succeeded")
+	}
+	recv.SetNonce(math.MaxUint64 - 1)
+	if pt, err := recv.Decrypt(nil, ct); err != nil || string(pt) != "last" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if recv.Nonce() != math.MaxUint64 {
+		t.Errorf("Nonce = %d", recv.Nonce())
+	}
+	if _, err := send.Encrypt(nil, make([]byte, MaxMessageSize)); err == nil {
+		t.Error("Encrypt of a too large message succeeded")
+	}
+}
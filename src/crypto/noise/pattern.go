@@ -0,0 +1,119 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package noise
+
+// A token is a handshake pattern token.
+type token uint8
+
+const (
+	tokenE token = iota
+	tokenS
+	tokenEE
+	tokenES
+	tokenSE
+	tokenSS
+	tokenPSK
+)
+
+// A HandshakePattern is a Noise handshake pattern, which describes the
+// keys exchanged by the two parties and the DH operations they perform.
+type HandshakePattern struct {
+	name string
+
+	// initiatorPreS and responderPreS report whether the static key of
+	// each party is known to its peer before the handshake.
+	initiatorPreS, responderPreS bool
+
+	// messages are the token sequences of the handshake messages, which
+	// are sent alternately by the initiator and the responder.
+	messages [][]token
+}
+
+// Name returns the name of p, such as "XX".
+func (p *HandshakePattern) Name() string {
+	return p.name
+}
+
+var (
+	// NK is the pattern where the initiator knows the static key of the
+	// responder in advance, and stays anonymous.
+	//
+	//	<- s
+	//	...
+	//	-> e, es
+	//	<- e, ee
+	NK = &HandshakePattern{
+		name:          "NK",
+		responderPreS: true,
+		messages: [][]token{
+			{tokenE, tokenES},
+			{tokenE, tokenEE},
+		},
+	}
+
+	// IK is the pattern where the initiator knows the static key of the
+	// responder in advance, and sends its own static key in the first
+	// message. It is the pattern of WireGuard.
+	//
+	//	<- s
+	//	...
+	//	-> e, es, s, ss
+	//	<- e, ee, se
+	IK = &HandshakePattern{
+		name:          "IK",
+		responderPreS: true,
+		messages: [][]token{
+			{tokenE, tokenES, tokenS, tokenSS},
+			{tokenE, tokenEE, tokenSE},
+		},
+	}
+
+	// XX is the pattern where the parties exchange their static keys
+	// during the handshake, and neither needs to know the other's in
+	// advance.
+	//
+	//	-> e
+	//	<- e, ee, s, es
+	//	-> s, se
+	XX = &HandshakePattern{
+		name: "XX",
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE, tokenS, tokenES},
+			{tokenS, tokenSE},
+		},
+	}
+)
+
+// sendsStatic reports whether the initiator, or else the responder, sends
+// its static key in a message of p.
+func (p *HandshakePattern) sendsStatic(initiator bool) bool {
+	for i, msg := range p.messages {
+		if (i%2 == 0) != initiator {
+			continue
+		}
+		for _, t := range msg {
+			if t == tokenS {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withPSK returns the messages of p with a psk token at placement, as the
+// pskN modifier.
+func (p *HandshakePattern) withPSK(placement int) [][]token {
+	messages := make([][]token, len(p.messages))
+	for i, msg := range p.messages {
+		messages[i] = append([]token(nil), msg...)
+	}
+	if placement == 0 {
+		messages[0] = append([]token{tokenPSK}, messages[0]...)
+	} else {
+		messages[placement-1] = append(messages[placement-1], tokenPSK)
+	}
+	return messages
+}
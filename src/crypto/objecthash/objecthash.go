@@ -0,0 +1,379 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package objecthash computes stable SHA-256 digests of structured data,
+// following the objecthash scheme of https://github.com/benlaurie/objecthash,
+// so that equal objects have the same digest however they are encoded.
+//
+// Each value is hashed as SHA-256 of a one-byte tag followed by its
+// canonical encoding:
+//
+//   - null ('n'): nothing. Nil pointers, interfaces, maps, and slices are
+//     null.
+//   - booleans ('b'): "1" for true, "0" for false.
+//   - integers ('i'): their decimal representation.
+//   - floating-point numbers ('f'): their sign, "+" or "-", their binary
+//     exponent e in decimal, a colon, and the binary digits of their
+//     mantissa m, such that 0.5 < m <= 1. Zero is "+0:". NaN and infinities
+//     are not supported.
+//   - strings ('u'): their UTF-8 bytes, without Unicode normalization.
+//   - lists ('l'): the concatenation of the digests of their elements.
+//   - dictionaries ('d'): the concatenation of the digests of their keys,
+//     each followed by the digest of its value, sorted bytewise by pairs.
+//
+// JSON numbers are always floating-point numbers. Go values are mapped to
+// these types by their kind: structs are dictionaries keyed by their field
+// names, or the names in their json tags, like with package encoding/json
+// (embedded fields are not supported), and values that implement
+// json.Marshaler or encoding.TextMarshaler are hashed as the JSON they
+// marshal to, or as strings. Note that a Go integer and the JSON number
+// that encodes it don't have the same digest.
+package objecthash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The size of an object hash in bytes.
+const Size = sha256.Size
+
+// maxDepth is the maximum nesting of lists and dictionaries, which also
+// stops the recursion on cyclic values.
+const maxDepth = 1000
+
+var errDepth = errors.New("objecthash: value too deeply nested, or cyclic")
+
+func sum(tag byte, data []byte) [Size]byte {
+	h := sha256.New()
+	h.Write([]byte{tag})
+	h.Write(data)
+	var s [Size]byte
+	h.Sum(s[:0])
+	return s
+}
+
+func sumNull() [Size]byte { return sum('n', nil) }
+
+func sumBool(b bool) [Size]byte {
+	if b {
+		return sum('b', []byte("1"))
+	}
+	return sum('b', []byte("0"))
+}
+
+func sumString(s string) [Size]byte { return sum('u', []byte(s)) }
+
+func sumFloat(f float64) ([Size]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return [Size]byte{}, errors.New("objecthash: unsupported floating-point value " + strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return sum('f', []byte(normalizeFloat(f))), nil
+}
+
+// normalizeFloat returns the canonical encoding of a finite f.
+func normalizeFloat(f float64) string {
+	if f == 0 {
+		return "+0:"
+	}
+	s := "+"
+	if f < 0 {
+		s = "-"
+		f = -f
+	}
+	// Scaling by two is exact, except for subnormal results, which can't
+	// happen when moving towards (0.5, 1].
+	e := 0
+	for f > 1 {
+		f /= 2
+		e++
+	}
+	for f <= 0.5 {
+		f *= 2
+		e--
+	}
+	b := []byte(s + strconv.Itoa(e) + ":")
+	for f != 0 {
+		if f >= 1 {
+			b = append(b, '1')
+			f--
+		} else {
+			b = append(b, '0')
+		}
+		f *= 2
+	}
+	return string(b)
+}
+
+func sumList(elems [][Size]byte) [Size]byte {
+	var b []byte
+	for _, e := range elems {
+		b = append(b, e[:]...)
+	}
+	return sum('l', b)
+}
+
+// sumDict returns the digest of a dictionary, from the concatenated
+// digests of its key and value pairs.
+func sumDict(pairs [][2 * Size]byte) [Size]byte {
+	sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i][:], pairs[j][:]) < 0 })
+	var b []byte
+	for _, p := range pairs {
+		b = append(b, p[:]...)
+	}
+	return sum('d', b)
+}
+
+func pair(k, v [Size]byte) [2 * Size]byte {
+	var p [2 * Size]byte
+	copy(p[:], k[:])
+	copy(p[Size:], v[:])
+	return p
+}
+
+// Sum returns the object hash of v.
+func Sum(v any) ([Size]byte, error) {
+	return sumValue(reflect.ValueOf(v), 0)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	numberType        = reflect.TypeOf(json.Number(""))
+)
+
+func sumValue(v reflect.Value, depth int) ([Size]byte, error) {
+	if depth > maxDepth {
+		return [Size]byte{}, errDepth
+	}
+	if !v.IsValid() {
+		return sumNull(), nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return sumNull(), nil
+		}
+	}
+	if v.Type() == numberType {
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return [Size]byte{}, errors.New("objecthash: invalid number " + v.String())
+		}
+		return sumFloat(f)
+	}
+	if v.Type().Implements(marshalerType) && v.CanInterface() {
+		b, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return [Size]byte{}, err
+		}
+		return sumJSON(json.NewDecoder(bytes.NewReader(b)))
+	}
+	if v.Type().Implements(textMarshalerType) && v.CanInterface() {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return [Size]byte{}, err
+		}
+		return sumString(string(b)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return sumBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sum('i', []byte(strconv.FormatInt(v.Int(), 10))), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return sum('i', []byte(strconv.FormatUint(v.Uint(), 10))), nil
+	case reflect.Float32, reflect.Float64:
+		return sumFloat(v.Float())
+	case reflect.String:
+		return sumString(v.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		return sumValue(v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		elems := make([][Size]byte, v.Len())
+		for i := range elems {
+			var err error
+			if elems[i], err = sumValue(v.Index(i), depth+1); err != nil {
+				return [Size]byte{}, err
+			}
+		}
+		return sumList(elems), nil
+	case reflect.Map:
+		var pairs [][2 * Size]byte
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := sumValue(iter.Key(), depth+1)
+			if err != nil {
+				return [Size]byte{}, err
+			}
+			e, err := sumValue(iter.Value(), depth+1)
+			if err != nil {
+				return [Size]byte{}, err
+			}
+			pairs = append(pairs, pair(k, e))
+		}
+		return sumDict(pairs), nil
+	case reflect.Struct:
+		return sumStruct(v, depth)
+	}
+	return [Size]byte{}, errors.New("objecthash: unsupported type " + v.Type().String())
+}
+
+// sumStruct hashes the exported fields of a struct, named and omitted
+// according to their json tags.
+func sumStruct(v reflect.Value, depth int) ([Size]byte, error) {
+	var pairs [][2 * Size]byte
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			return [Size]byte{}, errors.New("objecthash: unsupported embedded field " + f.Name + " in " + t.String())
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		var omitEmpty bool
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			tagName, opts, _ := strings.Cut(tag, ",")
+			if tagName != "" {
+				name = tagName
+			}
+			for opts != "" {
+				var opt string
+				opt, opts, _ = strings.Cut(opts, ",")
+				omitEmpty = omitEmpty || opt == "omitempty"
+			}
+		}
+		fv := v.Field(i)
+		if omitEmpty && isEmpty(fv) {
+			continue
+		}
+		e, err := sumValue(fv, depth+1)
+		if err != nil {
+			return [Size]byte{}, err
+		}
+		pairs = append(pairs, pair(sumString(name), e))
+	}
+	return sumDict(pairs), nil
+}
+
+// isEmpty reports whether v is empty, as defined by the omitempty option
+// of package encoding/json.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// SumJSON returns the object hash of the JSON value encoded in data.
+// Objects with duplicate names are rejected.
+func SumJSON(data []byte) ([Size]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	s, err := sumJSON(dec)
+	if err != nil {
+		return [Size]byte{}, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return [Size]byte{}, errors.New("objecthash: trailing data after JSON value")
+	}
+	return s, nil
+}
+
+func sumJSON(dec *json.Decoder) ([Size]byte, error) {
+	dec.UseNumber()
+	return sumJSONValue(dec, 0)
+}
+
+func sumJSONValue(dec *json.Decoder, depth int) ([Size]byte, error) {
+	if depth > maxDepth {
+		return [Size]byte{}, errDepth
+	}
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return [Size]byte{}, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return [Size]byte{}, err
+	}
+	switch tok := tok.(type) {
+	case nil:
+		return sumNull(), nil
+	case bool:
+		return sumBool(tok), nil
+	case string:
+		return sumString(tok), nil
+	case json.Number:
+		f, err := tok.Float64()
+		if err != nil {
+			return [Size]byte{}, errors.New("objecthash: number out of range " + tok.String())
+		}
+		return sumFloat(f)
+	case json.Delim:
+		switch tok {
+		case '[':
+			var elems [][Size]byte
+			for dec.More() {
+				e, err := sumJSONValue(dec, depth+1)
+				if err != nil {
+					return [Size]byte{}, err
+				}
+				elems = append(elems, e)
+			}
+			if _, err := dec.Token(); err != nil {
+				return [Size]byte{}, err
+			}
+			return sumList(elems), nil
+		case '{':
+			var pairs [][2 * Size]byte
+			seen := make(map[string]bool)
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return [Size]byte{}, err
+				}
+				name := tok.(string)
+				if seen[name] {
+					return [Size]byte{}, errors.New("objecthash: duplicate name " + strconv.Quote(name) + " in JSON object")
+				}
+				seen[name] = true
+				e, err := sumJSONValue(dec, depth+1)
+				if err != nil {
+					return [Size]byte{}, err
+				}
+				pairs = append(pairs, pair(sumString(name), e))
+			}
+			if _, err := dec.Token(); err != nil {
+				return [Size]byte{}, err
+			}
+			return sumDict(pairs), nil
+		}
+	}
+	return [Size]byte{}, errors.New("objecthash: malformed JSON")
+}
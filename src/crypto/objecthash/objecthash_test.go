@@ -0,0 +1,159 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package objecthash
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// Test vectors from common_json.test of the objecthash repository.
+var jsonTests = []struct {
+	json, sum string
+}{
+	{`[]`, "acac86c0e609ca906f632b0e2dacccb2b77d22b0621f20ebece1a4835b93f6f0"},
+	{`["foo"]`, "268bc27d4974d9d576222e4cdbb8f7c6bd6791894098645a19eeca9c102d0964"},
+	{`["foo", "bar"]`, "32ae896c413cfdc79eec68be9139c86ded8b279238467c216cf2bec4d5f1e4a2"},
+	{`{}`, "18ac3e7343f016890c510e93f935261169d9e3f565436429830faf0934f4f8e4"},
+	{`{"foo": "bar"}`, "7ef5237c3027d6c58100afadf37796b3d351025cf28038280147d42fdc53b960"},
+	{`{"foo": ["bar", "baz"], "qux": ["norf"]}`, "f1a9389f27558538a064f3cc250f8686a0cebb85f1cab7f4d4dcc416ceda3c92"},
+	{`[null]`, "5fb858ed3ef4275e64c2d5c44b77534181f7722b7765288e76924ce2f9f7f7db"},
+	{`["foo", {"bar": ["baz", null, 1.0, 1.5, 0.0001, 1000.0, 2.0, -23.1234, 2.0]}]`, "783a423b094307bcb28d005bc2f026ff44204442ef3513585e7e73b66e3c2213"},
+	{`"ԱԲաբ"`, "2a2a4485a4e338d8df683971956b1090d2f5d33955a81ecaad1a75125f7a316c"},
+}
+
+func TestSumJSON(t *testing.T) {
+	for _, tt := range jsonTests {
+		s, err := SumJSON([]byte(tt.json))
+		if err != nil {
+			t.Errorf("SumJSON(%s): %v", tt.json, err)
+			continue
+		}
+		if got := hex.EncodeToString(s[:]); got != tt.sum {
+			t.Errorf("SumJSON(%s) = %s, want %s", tt.json, got, tt.sum)
+		}
+	}
+
+	// Formatting, the order of names, and the encoding of numbers don't
+	// matter.
+	a, _ := SumJSON([]byte(`{"a": [1, 2.5e0], "b": "é"}`))
+	b, _ := SumJSON([]byte(`{"b":"é","a":[1.00,25E-1]}`))
+	if a != b {
+		t.Errorf("equivalent JSON documents have different digests")
+	}
+
+	for _, bad := range []string{``, `[`, `{"a": 1, "a": 1}`, `[1] [2]`, `1e400`, `{"a"}`} {
+		if _, err := SumJSON([]byte(bad)); err == nil {
+			t.Errorf("SumJSON(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestNormalizeFloat(t *testing.T) {
+	for _, tt := range []struct {
+		f    float64
+		want string
+	}{
+		{0, "+0:"},
+		{math.Copysign(0, -1), "+0:"},
+		{1, "+0:1"},
+		{0.75, "+0:011"},
+		{-2, "-1:1"},
+		{1.5, "+1:011"},
+		{math.SmallestNonzeroFloat64, "+-1074:1"},
+	} {
+		if got := normalizeFloat(tt.f); got != tt.want {
+			t.Errorf("normalizeFloat(%g) = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+	if _, err := Sum(math.NaN()); err == nil {
+		t.Errorf("Sum(NaN) succeeded")
+	}
+	if _, err := Sum(math.Inf(-1)); err == nil {
+		t.Errorf("Sum(-Inf) succeeded")
+	}
+}
+
+type release struct {
+	Name     string
+	Version  float64   `json:"version"`
+	Files    []string  `json:"files,omitempty"`
+	Draft    bool      `json:"draft,omitempty"`
+	Internal string    `json:"-"`
+	Meta     *metadata `json:"meta"`
+	private  int
+}
+
+type metadata struct {
+	Date  time.Time
+	Score json.Number
+}
+
+func TestSum(t *testing.T) {
+	date := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := release{
+		Name:     "go",
+		Version:  1.21,
+		Internal: "ignored",
+		Meta:     &metadata{Date: date, Score: "2"},
+		private:  1,
+	}
+	s, err := Sum(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := SumJSON([]byte(`{"Name": "go", "version": 1.21, "meta": {"Date": "2023-05-01T12:00:00Z", "Score": 2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != want {
+		t.Errorf("Sum(%+v) does not match its JSON encoding", r)
+	}
+	if ps, err := Sum(&r); err != nil || ps != s {
+		t.Errorf("Sum(&r) = %x, %v, want %x", ps, err, s)
+	}
+
+	m, err := Sum(map[string]any{"Name": "go", "version": 1.21, "meta": map[string]any{"Date": date, "Score": 2.0}})
+	if err != nil || m != s {
+		t.Errorf("Sum of the equivalent map = %x, %v, want %x", m, err, s)
+	}
+
+	// Go integers are not floating-point numbers.
+	i, _ := Sum([]int{1})
+	f, _ := SumJSON([]byte(`[1]`))
+	if i == f {
+		t.Errorf("integer and floating-point number have the same digest")
+	}
+	if i2, _ := Sum([]uint8{1}); i2 != i {
+		t.Errorf("integers of different types have different digests")
+	}
+
+	null, _ := SumJSON([]byte(`null`))
+	for _, v := range []any{nil, (*release)(nil), []string(nil), map[string]int(nil)} {
+		if s, err := Sum(v); err != nil || s != null {
+			t.Errorf("Sum(%#v) = %x, %v, want null", v, s, err)
+		}
+	}
+
+	type cyclic struct {
+		Next *cyclic
+	}
+	c := &cyclic{}
+	c.Next = c
+	if _, err := Sum(c); err == nil {
+		t.Errorf("Sum of a cyclic value succeeded")
+	}
+	type embedded struct {
+		metadata
+	}
+	for _, v := range []any{make(chan int), func() {}, embedded{}, complex(1, 2)} {
+		if _, err := Sum(v); err == nil {
+			t.Errorf("Sum(%T) succeeded", v)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package openpgp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	armorPublicKey = "PGP PUBLIC KEY BLOCK"
+	armorSignature = "PGP SIGNATURE"
+	cleartextBegin = "-----BEGIN PGP SIGNED MESSAGE-----"
+)
+
+// nextLine returns the first line of data, without its line ending, and
+// the data that follows it.
+func nextLine(data []byte) (line, rest []byte) {
+	line, rest, _ = bytes.Cut(data, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r")), rest
+}
+
+// isArmored reports whether data starts with an armor header line.
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("-----BEGIN "))
+}
+
+// decodeArmor decodes the first ASCII armored block of data, of the given
+// type, and returns its contents and the data that follows it. Armor
+// headers and the CRC-24 checksum are ignored.
+func decodeArmor(data []byte, typ string) (body, rest []byte, err error) {
+	errMalformed := errors.New("openpgp: malformed armor")
+	begin, end := "-----BEGIN "+typ+"-----", "-----END "+typ+"-----"
+	var line []byte
+	for {
+		if len(data) == 0 {
+			return nil, nil, errors.New("openpgp: no armored " + typ + " found")
+		}
+		line, data = nextLine(data)
+		if string(bytes.TrimRight(line, " \t")) == begin {
+			break
+		}
+	}
+
+	// Skip the headers, if any, and the blank line that follows them.
+	for rest := data; len(rest) > 0; {
+		line, rest = nextLine(rest)
+		line = bytes.TrimRight(line, " \t")
+		if len(line) == 0 {
+			data = rest
+			break
+		}
+		if !bytes.Contains(line, []byte(": ")) {
+			break
+		}
+	}
+
+	var b64 []byte
+	for {
+		if len(data) == 0 {
+			return nil, nil, errMalformed
+		}
+		line, data = nextLine(data)
+		line = bytes.TrimRight(line, " \t")
+		if string(line) == end {
+			break
+		}
+		if len(line) == 5 && line[0] == '=' {
+			// The checksum, which must be followed by the tail line.
+			if line, data = nextLine(data); string(bytes.TrimRight(line, " \t")) != end {
+				return nil, nil, errMalformed
+			}
+			break
+		}
+		b64 = append(b64, line...)
+	}
+	body = make([]byte, base64.StdEncoding.DecodedLen(len(b64)))
+	n, err := base64.StdEncoding.Decode(body, b64)
+	if err != nil {
+		return nil, nil, errMalformed
+	}
+	return body[:n], data, nil
+}
+
+// A cleartextMessage is a parsed Cleartext Signed Message.
+type cleartextMessage struct {
+	hashes    []string // the values of the Hash headers
+	text      []byte   // the text, with LF line endings
+	signed    []byte   // the signed text, with CRLF line endings
+	signature []byte   // the binary signature packets
+}
+
+// parseCleartext parses a Cleartext Signed Message, from Section 7 of RFC
+// 9580.
+func parseCleartext(data []byte) (*cleartextMessage, error) {
+	errMalformed := errors.New("openpgp: malformed cleartext signed message")
+	var line []byte
+	for {
+		if len(data) == 0 {
+			return nil, errors.New("openpgp: no cleartext signed message found")
+		}
+		line, data = nextLine(data)
+		if string(bytes.TrimRight(line, " \t")) == cleartextBegin {
+			break
+		}
+	}
+
+	m := &cleartextMessage{}
+	for {
+		if len(data) == 0 {
+			return nil, errMalformed
+		}
+		line, data = nextLine(data)
+		line = bytes.TrimRight(line, " \t")
+		if len(line) == 0 {
+			break
+		}
+		name, value, ok := strings.Cut(string(line), ": ")
+		if !ok || name != "Hash" {
+			return nil, errors.New("openpgp: unexpected cleartext armor header")
+		}
+		for _, h := range strings.Split(value, ",") {
+			m.hashes = append(m.hashes, strings.TrimSpace(h))
+		}
+	}
+
+	var lines [][]byte
+	for {
+		if len(data) == 0 {
+			return nil, errMalformed
+		}
+		rest := data
+		line, data = nextLine(data)
+		if bytes.HasPrefix(line, []byte("-----BEGIN "+armorSignature+"-----")) {
+			var err error
+			if m.signature, _, err = decodeArmor(rest, armorSignature); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if bytes.HasPrefix(line, []byte("- ")) {
+			line = line[2:]
+		} else if bytes.HasPrefix(line, []byte("-")) {
+			return nil, errMalformed
+		}
+		// Trailing whitespace is not part of the signed text.
+		lines = append(lines, bytes.TrimRight(line, " \t"))
+	}
+	// The line ending before the signature is not part of the text.
+	m.text = bytes.Join(lines, []byte("\n"))
+	m.signed = bytes.Join(lines, []byte("\r\n"))
+	return m, nil
+}
+
+// textWriter canonicalizes the line endings of text to CRLF, as hashed by
+// text signatures.
+type textWriter struct {
+	w      io.Writer
+	lastCR bool
+}
+
+func (t *textWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			t.w.Write(p)
+			t.lastCR = p[len(p)-1] == '\r'
+			break
+		}
+		cr := t.lastCR
+		if i > 0 {
+			cr = p[i-1] == '\r'
+		}
+		t.w.Write(p[:i])
+		if cr {
+			t.w.Write([]byte("\n"))
+		} else {
+			t.w.Write([]byte("\r\n"))
+		}
+		t.lastCR = false
+		p = p[i+1:]
+	}
+	return n, nil
+}
@@ -0,0 +1,397 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package openpgp implements the verification of OpenPGP signatures, as
+// specified in RFC 9580, such as the signatures of software releases and
+// distribution package repositories.
+//
+// Detached signatures, as produced by "gpg --detach-sign", and cleartext
+// signed messages, as produced by "gpg --clearsign", are verified against a
+// key ring of public keys. Version 4 and version 6 keys and signatures are
+// supported, with the Ed25519, EdDSALegacy (Ed25519), and RSA algorithms.
+// RSA keys must be at least 2048 bits, and data signatures using SHA-1 are
+// rejected.
+//
+// The package does not sign, encrypt, or decrypt, and does not implement
+// the web of trust: every key in the key ring is trusted, once its binding
+// self-signatures are verified.
+package openpgp
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"hash"
+	"io"
+	"time"
+)
+
+var (
+	// ErrUnknownIssuer is returned when a signature was not made by a key
+	// in the key ring.
+	ErrUnknownIssuer = errors.New("openpgp: signature made by unknown key")
+
+	// ErrVerification is returned when a signature is invalid.
+	ErrVerification = errVerification
+)
+
+// An Entity is an OpenPGP certificate: a primary key, with its user IDs
+// and subkeys.
+type Entity struct {
+	PrimaryKey *PublicKey
+
+	// Identities are the user IDs with a valid self-signature.
+	Identities []string
+
+	// Subkeys are the subkeys with a valid binding signature, which have
+	// not been revoked.
+	Subkeys []*PublicKey
+}
+
+// An EntityList is a key ring.
+type EntityList []*Entity
+
+// ReadKeyRing reads a key ring of transferable public keys from data,
+// which may be binary or ASCII armored. Armored data may hold several
+// public key blocks.
+//
+// Keys that are revoked, or that lack a valid self-signature, are skipped,
+// as are subkeys and user IDs without a valid binding signature.
+func ReadKeyRing(data []byte) (EntityList, error) {
+	if !isArmored(data) {
+		return readEntities(data)
+	}
+	var el EntityList
+	for isArmored(data) {
+		body, rest, err := decodeArmor(data, armorPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		entities, err := readEntities(body)
+		if err != nil {
+			return nil, err
+		}
+		el = append(el, entities...)
+		data = rest
+	}
+	return el, nil
+}
+
+func readEntities(data []byte) (EntityList, error) {
+	packets, err := readPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	var el EntityList
+	for len(packets) > 0 {
+		if packets[0].tag != tagPublicKey {
+			return nil, errors.New("openpgp: key ring does not start with a public key")
+		}
+		n := 1
+		for n < len(packets) && packets[n].tag != tagPublicKey {
+			n++
+		}
+		e, err := readEntity(packets[:n])
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			el = append(el, e)
+		}
+		packets = packets[n:]
+	}
+	return el, nil
+}
+
+// readEntity reads a transferable public key, and returns nil if the
+// primary key is not usable.
+func readEntity(packets []packet) (*Entity, error) {
+	primary, err := parsePublicKey(packets[0].body)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entity{PrimaryKey: primary}
+
+	var selfSig *signature // the most recent self-signature
+	var revoked bool
+	var userID []byte
+	var userIDValid bool
+	var subkey *PublicKey
+	var binding *signature // the most recent binding signature of subkey
+	var subkeyRevoked bool
+	addSubkey := func() {
+		if subkey == nil || binding == nil || subkeyRevoked {
+			return
+		}
+		if binding.keyExpiration != 0 {
+			subkey.Expiration = subkey.CreationTime.Add(time.Duration(binding.keyExpiration) * time.Second)
+		}
+		subkey.CanSign = binding.hasKeyFlags && binding.keyFlags&keyFlagSign != 0
+		if subkey.CanSign {
+			// A signing subkey must cross-certify the primary key.
+			back := binding.embedded
+			subkey.CanSign = back != nil && back.sigType == sigPrimaryKeyBinding &&
+				back.verifyKeySignature(subkey, primary, subkey, nil) == nil
+		}
+		e.Subkeys = append(e.Subkeys, subkey)
+	}
+
+	for _, p := range packets[1:] {
+		switch p.tag {
+		case tagUserID:
+			addSubkey()
+			userID, userIDValid, subkey = p.body, false, nil
+		case tagUserAttribute:
+			addSubkey()
+			userID, subkey = nil, nil
+		case tagPublicSubkey:
+			addSubkey()
+			userID, binding, subkeyRevoked = nil, nil, false
+			if subkey, err = parsePublicKey(p.body); err != nil {
+				return nil, err
+			}
+			if subkey.Version != primary.Version {
+				return nil, errors.New("openpgp: subkey version does not match the primary key")
+			}
+		case tagSignature:
+			sig, err := parseSignature(p.body)
+			if err != nil || sig.created.IsZero() {
+				// Unsupported signatures can't be used, and are ignored.
+				continue
+			}
+			switch {
+			case subkey != nil:
+				switch sig.sigType {
+				case sigSubkeyBinding:
+					if (binding == nil || sig.created.After(binding.created)) &&
+						sig.verifyKeySignature(primary, primary, subkey, nil) == nil {
+						binding = sig
+					}
+				case sigSubkeyRevocation:
+					if sig.verifyKeySignature(primary, primary, subkey, nil) == nil {
+						subkeyRevoked = true
+					}
+				}
+			case userID != nil:
+				if sig.sigType >= sigGenericCert && sig.sigType <= sigPositiveCert &&
+					sig.verifyKeySignature(primary, primary, nil, userID) == nil {
+					if !userIDValid {
+						e.Identities = append(e.Identities, string(userID))
+						userIDValid = true
+					}
+					if selfSig == nil || sig.created.After(selfSig.created) {
+						selfSig = sig
+					}
+				}
+			default:
+				switch sig.sigType {
+				case sigDirectKey:
+					if (selfSig == nil || sig.created.After(selfSig.created)) &&
+						sig.verifyKeySignature(primary, primary, nil, nil) == nil {
+						selfSig = sig
+					}
+				case sigKeyRevocation:
+					if sig.verifyKeySignature(primary, primary, nil, nil) == nil {
+						revoked = true
+					}
+				}
+			}
+		case tagTrust, tagMarker:
+		default:
+			return nil, errors.New("openpgp: unexpected packet in public key")
+		}
+	}
+	addSubkey()
+
+	if revoked || selfSig == nil {
+		return nil, nil
+	}
+	if selfSig.keyExpiration != 0 {
+		primary.Expiration = primary.CreationTime.Add(time.Duration(selfSig.keyExpiration) * time.Second)
+	}
+	primary.CanSign = !selfSig.hasKeyFlags || selfSig.keyFlags&keyFlagSign != 0
+	// Subkeys expire with the primary key.
+	for _, k := range e.Subkeys {
+		if !primary.Expiration.IsZero() && (k.Expiration.IsZero() || k.Expiration.After(primary.Expiration)) {
+			k.Expiration = primary.Expiration
+		}
+	}
+	return e, nil
+}
+
+// signingKeys returns the keys of el that may have made sig.
+func (el EntityList) signingKeys(sig *signature) []*PublicKey {
+	var keys []*PublicKey
+	for _, e := range el {
+		for _, k := range append([]*PublicKey{e.PrimaryKey}, e.Subkeys...) {
+			switch {
+			case !k.CanSign || k.PublicKey == nil:
+			case sig.issuerFP != nil:
+				if bytes.Equal(sig.issuerFP, k.Fingerprint) {
+					keys = append(keys, k)
+				}
+			case sig.hasIssuerID:
+				if sig.issuerKeyID == k.KeyID {
+					keys = append(keys, k)
+				}
+			default:
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// entity returns the entity that holds k.
+func (el EntityList) entity(k *PublicKey) *Entity {
+	for _, e := range el {
+		if e.PrimaryKey == k {
+			return e
+		}
+		for _, sk := range e.Subkeys {
+			if sk == k {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// readSignatures reads the data signatures of data, which may be binary or
+// ASCII armored.
+func readSignatures(data []byte) ([]*signature, error) {
+	if isArmored(data) {
+		var err error
+		if data, _, err = decodeArmor(data, armorSignature); err != nil {
+			return nil, err
+		}
+	}
+	packets, err := readPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	var sigs []*signature
+	for _, p := range packets {
+		if p.tag == tagMarker {
+			continue
+		}
+		if p.tag != tagSignature {
+			return nil, errors.New("openpgp: unexpected packet in signature")
+		}
+		sig, err := parseSignature(p.body)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) == 0 {
+		return nil, errors.New("openpgp: no signature found")
+	}
+	return sigs, nil
+}
+
+// A candidate is a signature to verify with a key, and the hash of the
+// signed data.
+type candidate struct {
+	sig *signature
+	key *PublicKey
+	h   hash.Hash
+}
+
+// verify verifies the signatures over data, and returns the entity of the
+// first valid one.
+func (el EntityList) verify(sigs []*signature, data io.Reader) (*Entity, error) {
+	var candidates []candidate
+	var writers []io.Writer
+	var err error
+	for _, sig := range sigs {
+		if sig.sigType != sigBinary && sig.sigType != sigText {
+			err = errors.New("openpgp: signature is not a data signature")
+			continue
+		}
+		if sig.hash == crypto.SHA1 {
+			err = errors.New("openpgp: SHA-1 data signatures are not supported")
+			continue
+		}
+		for _, k := range el.signingKeys(sig) {
+			h := sig.newHash()
+			candidates = append(candidates, candidate{sig, k, h})
+			if sig.sigType == sigText {
+				writers = append(writers, &textWriter{w: h})
+			} else {
+				writers = append(writers, h)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrUnknownIssuer
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), data); err != nil {
+		return nil, err
+	}
+
+	err = ErrVerification
+	for _, c := range candidates {
+		if c.sig.verify(c.key, c.h) != nil {
+			continue
+		}
+		if !c.key.validAt(c.sig.created) {
+			err = errors.New("openpgp: signing key was not valid when the signature was made")
+			continue
+		}
+		if !c.sig.expiration.IsZero() && !time.Now().Before(c.sig.expiration) {
+			err = errors.New("openpgp: signature has expired")
+			continue
+		}
+		return el.entity(c.key), nil
+	}
+	return nil, err
+}
+
+// CheckDetachedSignature verifies signature, which may be binary or ASCII
+// armored, over the data read from signed, and returns the entity of the
+// key that made it. If signature holds several signatures, one valid
+// signature by a key of keyring is sufficient.
+func CheckDetachedSignature(keyring EntityList, signed io.Reader, signature []byte) (*Entity, error) {
+	sigs, err := readSignatures(signature)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.verify(sigs, signed)
+}
+
+// CheckCleartextSignature verifies a cleartext signed message, and returns
+// its text and the entity of the key that signed it. The text is dash
+// unescaped, with LF line endings, and without the trailing whitespace of
+// its lines, which is not signed.
+func CheckCleartextSignature(keyring EntityList, message []byte) (text []byte, signer *Entity, err error) {
+	m, err := parseCleartext(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	sigs, err := readSignatures(m.signature)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.hashes != nil {
+		// The Hash headers are not signed, but must not be misleading.
+		for _, sig := range sigs {
+			found := false
+			for _, h := range m.hashes {
+				found = found || h == sig.hashName
+			}
+			if !found {
+				return nil, nil, errors.New("openpgp: cleartext Hash header does not match the signature")
+			}
+		}
+	}
+	signer, err = keyring.verify(sigs, bytes.NewReader(m.signed))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.text, signer, nil
+}
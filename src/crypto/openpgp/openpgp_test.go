@@ -0,0 +1,225 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package openpgp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The files in testdata were produced with GnuPG 2.2, for version 4 keys,
+// and with github.com/ProtonMail/go-crypto, for version 6 keys, except for
+// rfc9580.asc and rfc9580.clear, which are the sample certificate and
+// cleartext signed message of RFC 9580, Appendix A.
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func readKeyRing(t *testing.T, names ...string) EntityList {
+	t.Helper()
+	var el EntityList
+	for _, name := range names {
+		entities, err := ReadKeyRing(readFile(t, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		el = append(el, entities...)
+	}
+	return el
+}
+
+func TestReadKeyRing(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		version     int
+		alg         PublicKeyAlgorithm
+		identity    string
+		subkeys     int
+		primarySign bool
+	}{
+		{"ed.asc", 4, EdDSALegacy, "Ed Signer <ed@example.com>", 0, true},
+		{"rsa.gpg", 4, RSA, "RSA Release Signer <release@example.com>", 1, false},
+		{"expired.asc", 4, EdDSALegacy, "Old Signer <old@example.com>", 0, true},
+		{"v6ed.gpg", 6, Ed25519, "V6 Signer <v6@example.com>", 1, true},
+		{"v6rsa.gpg", 6, RSA, "V6 Signer <v6@example.com>", 1, true},
+		{"rfc9580.asc", 6, Ed25519, "", 1, true},
+	} {
+		el := readKeyRing(t, tt.name)
+		if len(el) != 1 {
+			t.Errorf("%s: got %d entities", tt.name, len(el))
+			continue
+		}
+		e := el[0]
+		pk := e.PrimaryKey
+		if pk.Version != tt.version || pk.Algorithm != tt.alg || pk.CanSign != tt.primarySign {
+			t.Errorf("%s: primary key is v%d %v, CanSign %v", tt.name, pk.Version, pk.Algorithm, pk.CanSign)
+		}
+		switch pk.PublicKey.(type) {
+		case *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			t.Errorf("%s: unexpected public key type %T", tt.name, pk.PublicKey)
+		}
+		if tt.identity == "" && len(e.Identities) != 0 || tt.identity != "" && (len(e.Identities) != 1 || e.Identities[0] != tt.identity) {
+			t.Errorf("%s: identities = %q", tt.name, e.Identities)
+		}
+		if len(e.Subkeys) != tt.subkeys {
+			t.Errorf("%s: got %d subkeys", tt.name, len(e.Subkeys))
+		}
+	}
+
+	el := readKeyRing(t, "rfc9580.asc")
+	want := "cb186c4f0609a697e4d52dfa6c722b0c1f1e27c18a56708f6525ec27bad9acc9"
+	if got := hex.EncodeToString(el[0].PrimaryKey.Fingerprint); got != want {
+		t.Errorf("fingerprint = %s, want %s", got, want)
+	}
+	if el[0].Subkeys[0].CanSign {
+		t.Errorf("encryption subkey can sign")
+	}
+
+	el = readKeyRing(t, "rsa.gpg")
+	want = "1e212b591bc85a1886afacac4006007b4242de30"
+	if got := hex.EncodeToString(el[0].Subkeys[0].Fingerprint); got != want || el[0].Subkeys[0].KeyID != 0x4006007b4242de30 {
+		t.Errorf("subkey fingerprint = %s, want %s", got, want)
+	}
+	if !el[0].Subkeys[0].CanSign {
+		t.Errorf("signing subkey can't sign")
+	}
+
+	if el := readKeyRing(t, "revoked.asc"); len(el) != 0 {
+		t.Errorf("revoked key was read")
+	}
+
+	// Several armored blocks.
+	multi := append(readFile(t, "ed.asc"), readFile(t, "expired.asc")...)
+	if el, err := ReadKeyRing(multi); err != nil || len(el) != 2 {
+		t.Errorf("ReadKeyRing of two blocks = %d entities, %v", len(el), err)
+	}
+}
+
+func TestCheckDetachedSignature(t *testing.T) {
+	keyring := readKeyRing(t, "ed.asc", "rsa.gpg", "expired.asc", "revoked.asc", "v6ed.gpg", "v6rsa.gpg")
+	message := readFile(t, "message.txt")
+	for _, tt := range []struct {
+		sig      string
+		identity string
+	}{
+		{"message.txt.ed.asc", "Ed Signer <ed@example.com>"},
+		{"message.txt.rsa.sig", "RSA Release Signer <release@example.com>"},
+		{"message.txt.text.asc", "Ed Signer <ed@example.com>"},
+		{"message.txt.expired.asc", "Old Signer <old@example.com>"},
+		{"message.txt.v6ed.sig", "V6 Signer <v6@example.com>"},
+		{"message.txt.v6rsa.sig", "V6 Signer <v6@example.com>"},
+	} {
+		sig := readFile(t, tt.sig)
+		e, err := CheckDetachedSignature(keyring, bytes.NewReader(message), sig)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sig, err)
+			continue
+		}
+		if e.Identities[0] != tt.identity {
+			t.Errorf("%s: signed by %q", tt.sig, e.Identities[0])
+		}
+		modified := append([]byte("!"), message...)
+		if _, err := CheckDetachedSignature(keyring, bytes.NewReader(modified), sig); err != ErrVerification {
+			t.Errorf("%s: verification of a modified message: %v", tt.sig, err)
+		}
+	}
+
+	// A text signature is independent of the line endings.
+	crlf := bytes.ReplaceAll(message, []byte("\n"), []byte("\r\n"))
+	if _, err := CheckDetachedSignature(keyring, bytes.NewReader(crlf), readFile(t, "message.txt.text.asc")); err != nil {
+		t.Errorf("text signature of CRLF message: %v", err)
+	}
+	if _, err := CheckDetachedSignature(keyring, bytes.NewReader(crlf), readFile(t, "message.txt.ed.asc")); err == nil {
+		t.Errorf("binary signature of CRLF message succeeded")
+	}
+
+	if _, err := CheckDetachedSignature(keyring, bytes.NewReader(message), readFile(t, "message.txt.revoked.asc")); err != ErrUnknownIssuer {
+		t.Errorf("signature by a revoked key: %v", err)
+	}
+	if _, err := CheckDetachedSignature(keyring, bytes.NewReader(message), readFile(t, "message.txt.sha1.asc")); err == nil {
+		t.Errorf("SHA-1 signature succeeded")
+	}
+	if _, err := CheckDetachedSignature(readKeyRing(t, "ed.asc"), bytes.NewReader(message), readFile(t, "message.txt.rsa.sig")); err != ErrUnknownIssuer {
+		t.Errorf("signature by a key not in the key ring: %v", err)
+	}
+}
+
+func TestCheckCleartextSignature(t *testing.T) {
+	keyring := readKeyRing(t, "ed.asc", "rsa.gpg", "rfc9580.asc")
+	message := readFile(t, "message.txt")
+	// The trailing whitespace and line ending are not signed.
+	want := strings.Replace(strings.TrimSuffix(string(message), "\n"), "team   ", "team", 1)
+	for _, name := range []string{"message.txt.ed.clear", "message.txt.rsa.clear"} {
+		clear := readFile(t, name)
+		text, e, err := CheckCleartextSignature(keyring, clear)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(text) != want {
+			t.Errorf("%s: text = %q, want %q", name, text, want)
+		}
+		if e != keyring[0] && e != keyring[1] {
+			t.Errorf("%s: unexpected signer", name)
+		}
+
+		modified := bytes.Replace(clear, []byte("tofu"), []byte("tempeh"), 1)
+		if _, _, err := CheckCleartextSignature(keyring, modified); err != ErrVerification {
+			t.Errorf("%s: verification of a modified message: %v", name, err)
+		}
+		modified = bytes.Replace(clear, []byte("Hash: SHA"), []byte("Hash: SHA3-"), 1)
+		if _, _, err := CheckCleartextSignature(keyring, modified); err == nil {
+			t.Errorf("%s: verification with a misleading Hash header succeeded", name)
+		}
+		// Trailing whitespace and line endings are not signed.
+		modified = bytes.ReplaceAll(bytes.Replace(clear, []byte("checksums"), []byte("checksums \t"), 1), []byte("\n"), []byte("\r\n"))
+		if _, _, err := CheckCleartextSignature(keyring, modified); err != nil {
+			t.Errorf("%s: verification with different whitespace: %v", name, err)
+		}
+	}
+
+	text, e, err := CheckCleartextSignature(keyring, readFile(t, "rfc9580.clear"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "What we need from the grocery store:\n\n- tofu\n- vegetables\n- noodles\n"
+	if string(text) != want || e != keyring[2] {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	keyring := readKeyRing(t, "ed.asc")
+	sig := readFile(t, "message.txt.rsa.sig")
+	for i := 0; i < len(sig); i++ {
+		if _, err := CheckDetachedSignature(keyring, strings.NewReader(""), sig[:i]); err == nil {
+			t.Errorf("truncated signature of %d bytes succeeded", i)
+		}
+	}
+	key := readFile(t, "rsa.gpg")
+	for i := 1; i < len(key); i += 7 {
+		if el, err := ReadKeyRing(key[:i]); err == nil && len(el) != 0 && len(el[0].Subkeys) != 0 {
+			t.Errorf("truncated key ring of %d bytes returned a complete entity", i)
+		}
+	}
+	if _, err := ReadKeyRing([]byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nAAAA\n")); err == nil {
+		t.Errorf("unterminated armor succeeded")
+	}
+	if _, _, err := CheckCleartextSignature(keyring, readFile(t, "message.txt.ed.asc")); err == nil {
+		t.Errorf("cleartext verification of a detached signature succeeded")
+	}
+}
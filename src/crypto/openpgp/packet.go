@@ -0,0 +1,580 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package openpgp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/big"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// Packet tags.
+const (
+	tagSignature     = 2
+	tagPublicKey     = 6
+	tagMarker        = 10
+	tagTrust         = 12
+	tagUserID        = 13
+	tagPublicSubkey  = 14
+	tagUserAttribute = 17
+)
+
+type packet struct {
+	tag  uint8
+	body []byte
+}
+
+var errMalformedPacket = errors.New("openpgp: malformed packet")
+
+// readPackets splits data into packets. Partial and indeterminate lengths,
+// which are only permitted for data packets, are rejected.
+func readPackets(data []byte) ([]packet, error) {
+	var packets []packet
+	s := cryptobyte.String(data)
+	for !s.Empty() {
+		var b uint8
+		if !s.ReadUint8(&b) || b&0x80 == 0 {
+			return nil, errMalformedPacket
+		}
+		var p packet
+		var length uint32
+		if b&0x40 != 0 {
+			// OpenPGP format.
+			p.tag = b & 0x3f
+			var l uint8
+			if !s.ReadUint8(&l) {
+				return nil, errMalformedPacket
+			}
+			switch {
+			case l < 192:
+				length = uint32(l)
+			case l < 224:
+				var l2 uint8
+				if !s.ReadUint8(&l2) {
+					return nil, errMalformedPacket
+				}
+				length = (uint32(l)-192)<<8 + uint32(l2) + 192
+			case l == 255:
+				if !s.ReadUint32(&length) {
+					return nil, errMalformedPacket
+				}
+			default:
+				return nil, errors.New("openpgp: unsupported partial packet length")
+			}
+		} else {
+			// Legacy format.
+			p.tag = b >> 2 & 0xf
+			var ok bool
+			switch b & 3 {
+			case 0:
+				var l uint8
+				ok = s.ReadUint8(&l)
+				length = uint32(l)
+			case 1:
+				var l uint16
+				ok = s.ReadUint16(&l)
+				length = uint32(l)
+			case 2:
+				ok = s.ReadUint32(&length)
+			default:
+				return nil, errors.New("openpgp: unsupported indeterminate packet length")
+			}
+			if !ok {
+				return nil, errMalformedPacket
+			}
+		}
+		if !s.ReadBytes(&p.body, int(length)) {
+			return nil, errMalformedPacket
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+// readMPI reads a multiprecision integer, without leading zero bytes.
+func readMPI(s *cryptobyte.String, out *[]byte) bool {
+	var bits uint16
+	if !s.ReadUint16(&bits) || !s.ReadBytes(out, (int(bits)+7)/8) {
+		return false
+	}
+	*out = bytes.TrimLeft(*out, "\x00")
+	return true
+}
+
+func readUint32LengthPrefixed(s *cryptobyte.String, out *cryptobyte.String) bool {
+	var n uint32
+	return s.ReadUint32(&n) && uint64(n) <= uint64(len(*s)) && s.ReadBytes((*[]byte)(out), int(n))
+}
+
+// A PublicKeyAlgorithm is an OpenPGP public key algorithm, identified by
+// its registered value.
+type PublicKeyAlgorithm uint8
+
+const (
+	RSA         PublicKeyAlgorithm = 1
+	EdDSALegacy PublicKeyAlgorithm = 22
+	Ed25519     PublicKeyAlgorithm = 27
+)
+
+func (a PublicKeyAlgorithm) String() string {
+	switch a {
+	case RSA:
+		return "RSA"
+	case EdDSALegacy:
+		return "EdDSALegacy"
+	case Ed25519:
+		return "Ed25519"
+	}
+	return "PublicKeyAlgorithm(" + strconv.Itoa(int(a)) + ")"
+}
+
+// oidEd25519Legacy is the curve OID of EdDSALegacy keys, without its DER
+// tag and length.
+var oidEd25519Legacy = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+
+// minRSABits is the minimum size of the RSA keys that are accepted.
+const minRSABits = 2048
+
+// A PublicKey is a primary key or subkey of an Entity.
+type PublicKey struct {
+	// Version is the key version, 4 or 6.
+	Version int
+
+	Algorithm    PublicKeyAlgorithm
+	CreationTime time.Time
+
+	// Expiration is the time the key expires, or zero if it doesn't.
+	Expiration time.Time
+
+	// Fingerprint is the fingerprint of the key, and KeyID is its key ID.
+	Fingerprint []byte
+	KeyID       uint64
+
+	// PublicKey is an *rsa.PublicKey or an ed25519.PublicKey, or nil if
+	// the algorithm of the key is not supported.
+	PublicKey crypto.PublicKey
+
+	// CanSign reports whether the key is usable for data signatures.
+	CanSign bool
+
+	body []byte
+}
+
+// parsePublicKey parses a Public-Key or Public-Subkey packet body. Keys
+// of unsupported algorithms are returned with a nil PublicKey, so that
+// the other keys of an entity can be used.
+func parsePublicKey(body []byte) (*PublicKey, error) {
+	s := cryptobyte.String(body)
+	var version, algo uint8
+	var created uint32
+	if !s.ReadUint8(&version) || !s.ReadUint32(&created) || !s.ReadUint8(&algo) {
+		return nil, errMalformedPacket
+	}
+	pk := &PublicKey{
+		Version:      int(version),
+		Algorithm:    PublicKeyAlgorithm(algo),
+		CreationTime: time.Unix(int64(created), 0),
+		body:         body,
+	}
+	switch version {
+	case 4:
+		h := sha1.New()
+		pk.writeTo(h)
+		pk.Fingerprint = h.Sum(nil)
+		pk.KeyID = binary.BigEndian.Uint64(pk.Fingerprint[12:])
+	case 6:
+		var material cryptobyte.String
+		if !readUint32LengthPrefixed(&s, &material) || !s.Empty() {
+			return nil, errMalformedPacket
+		}
+		s = material
+		h := sha256.New()
+		pk.writeTo(h)
+		pk.Fingerprint = h.Sum(nil)
+		pk.KeyID = binary.BigEndian.Uint64(pk.Fingerprint[:8])
+	default:
+		return nil, errors.New("openpgp: unsupported key version " + strconv.Itoa(int(version)))
+	}
+
+	switch pk.Algorithm {
+	case RSA:
+		var n, e []byte
+		if !readMPI(&s, &n) || !readMPI(&s, &e) || !s.Empty() {
+			return nil, errMalformedPacket
+		}
+		if len(e) > 4 {
+			return nil, errors.New("openpgp: unsupported RSA exponent")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() >= minRSABits {
+			pk.PublicKey = pub
+		}
+	case EdDSALegacy:
+		var oid, point []byte
+		if !s.ReadUint8LengthPrefixed((*cryptobyte.String)(&oid)) || !readMPI(&s, &point) || !s.Empty() {
+			return nil, errMalformedPacket
+		}
+		// EdDSALegacy is deprecated, and not permitted with version 6 keys.
+		if version == 4 && bytes.Equal(oid, oidEd25519Legacy) && len(point) == 1+ed25519.PublicKeySize && point[0] == 0x40 {
+			pk.PublicKey = ed25519.PublicKey(point[1:])
+		}
+	case Ed25519:
+		var pub []byte
+		if !s.ReadBytes(&pub, ed25519.PublicKeySize) || !s.Empty() {
+			return nil, errMalformedPacket
+		}
+		pk.PublicKey = ed25519.PublicKey(pub)
+	}
+	return pk, nil
+}
+
+// writeTo writes the key as hashed by fingerprints and signatures.
+func (pk *PublicKey) writeTo(h hash.Hash) {
+	if pk.Version == 6 {
+		h.Write([]byte{0x9b})
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(pk.body))))
+	} else {
+		h.Write([]byte{0x99})
+		h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(pk.body))))
+	}
+	h.Write(pk.body)
+}
+
+// validAt reports whether the key was valid at t.
+func (pk *PublicKey) validAt(t time.Time) bool {
+	return !t.Before(pk.CreationTime) && (pk.Expiration.IsZero() || t.Before(pk.Expiration))
+}
+
+// Signature types.
+const (
+	sigBinary            = 0x00
+	sigText              = 0x01
+	sigGenericCert       = 0x10
+	sigPositiveCert      = 0x13
+	sigSubkeyBinding     = 0x18
+	sigPrimaryKeyBinding = 0x19
+	sigDirectKey         = 0x1f
+	sigKeyRevocation     = 0x20
+	sigSubkeyRevocation  = 0x28
+)
+
+// Signature subpacket types.
+const (
+	subCreationTime      = 2
+	subExpirationTime    = 3
+	subKeyExpirationTime = 9
+	subIssuer            = 16
+	subKeyFlags          = 27
+	subEmbeddedSignature = 32
+	subIssuerFingerprint = 33
+)
+
+// knownSubpackets are the subpacket types that may be marked critical.
+// The others are only informational, or not implemented.
+var knownSubpackets = map[uint8]bool{
+	subCreationTime:      true,
+	subExpirationTime:    true,
+	4:                    true, // Exportable Certification
+	7:                    true, // Revocable
+	subKeyExpirationTime: true,
+	11:                   true, // Preferred Symmetric Ciphers
+	subIssuer:            true,
+	21:                   true, // Preferred Hash Algorithms
+	22:                   true, // Preferred Compression Algorithms
+	23:                   true, // Key Server Preferences
+	25:                   true, // Primary User ID
+	subKeyFlags:          true,
+	30:                   true, // Features
+	subEmbeddedSignature: true,
+	subIssuerFingerprint: true,
+	39:                   true, // Preferred AEAD Ciphersuites
+}
+
+// keyFlagSign is the key flag of keys that can sign data.
+const keyFlagSign = 0x02
+
+// A signature is a parsed Signature packet.
+type signature struct {
+	version   int
+	sigType   uint8
+	algorithm PublicKeyAlgorithm
+	hash      crypto.Hash
+	hashName  string
+
+	created       time.Time
+	expiresIn     uint32    // seconds after the creation, or zero
+	expiration    time.Time // zero if the signature doesn't expire
+	keyExpiration uint32    // seconds after the key creation, or zero
+	keyFlags      uint8
+	hasKeyFlags   bool
+	issuerKeyID   uint64
+	hasIssuerID   bool
+	issuerFP      []byte
+	embedded      *signature
+
+	hashed []byte // the hashed part of the packet, for the trailer
+	left16 []byte
+	salt   []byte
+	sig    [][]byte // the signature fields, such as the RSA signature
+}
+
+// hashes maps OpenPGP hash algorithm IDs to hashes and their names, as
+// used in cleartext signatures, and to v6 salt sizes. SHA-1 is parsed, but
+// only accepted in self-signatures.
+var hashes = map[uint8]struct {
+	hash     crypto.Hash
+	name     string
+	saltSize int
+}{
+	2:  {crypto.SHA1, "SHA1", 0},
+	8:  {crypto.SHA256, "SHA256", 16},
+	9:  {crypto.SHA384, "SHA384", 24},
+	10: {crypto.SHA512, "SHA512", 32},
+	11: {crypto.SHA224, "SHA224", 16},
+	12: {crypto.SHA3_256, "SHA3-256", 16},
+	14: {crypto.SHA3_512, "SHA3-512", 32},
+}
+
+func parseSignature(body []byte) (*signature, error) {
+	s := cryptobyte.String(body)
+	var version, sigType, algo, hashID uint8
+	if !s.ReadUint8(&version) || !s.ReadUint8(&sigType) || !s.ReadUint8(&algo) || !s.ReadUint8(&hashID) {
+		return nil, errMalformedPacket
+	}
+	if version != 4 && version != 6 {
+		return nil, errors.New("openpgp: unsupported signature version " + strconv.Itoa(int(version)))
+	}
+	sig := &signature{version: int(version), sigType: sigType, algorithm: PublicKeyAlgorithm(algo)}
+	h, ok := hashes[hashID]
+	if !ok {
+		return nil, errors.New("openpgp: unsupported hash algorithm " + strconv.Itoa(int(hashID)))
+	}
+	sig.hash, sig.hashName = h.hash, h.name
+	if version == 6 && h.saltSize == 0 {
+		return nil, errors.New("openpgp: unsupported hash algorithm for version 6 signature")
+	}
+
+	var hashedSub, unhashedSub cryptobyte.String
+	if version == 4 {
+		if !s.ReadUint16LengthPrefixed(&hashedSub) {
+			return nil, errMalformedPacket
+		}
+		sig.hashed = body[:6+len(hashedSub)]
+		if !s.ReadUint16LengthPrefixed(&unhashedSub) {
+			return nil, errMalformedPacket
+		}
+	} else {
+		if !readUint32LengthPrefixed(&s, &hashedSub) {
+			return nil, errMalformedPacket
+		}
+		sig.hashed = body[:8+len(hashedSub)]
+		if !readUint32LengthPrefixed(&s, &unhashedSub) {
+			return nil, errMalformedPacket
+		}
+	}
+	if !s.ReadBytes(&sig.left16, 2) {
+		return nil, errMalformedPacket
+	}
+	if version == 6 {
+		if !s.ReadUint8LengthPrefixed((*cryptobyte.String)(&sig.salt)) || len(sig.salt) != h.saltSize {
+			return nil, errMalformedPacket
+		}
+	}
+
+	switch sig.algorithm {
+	case RSA:
+		var v []byte
+		if !readMPI(&s, &v) {
+			return nil, errMalformedPacket
+		}
+		sig.sig = [][]byte{v}
+	case EdDSALegacy:
+		var r, ss []byte
+		if !readMPI(&s, &r) || !readMPI(&s, &ss) {
+			return nil, errMalformedPacket
+		}
+		sig.sig = [][]byte{r, ss}
+	case Ed25519:
+		var v []byte
+		if !s.ReadBytes(&v, ed25519.SignatureSize) {
+			return nil, errMalformedPacket
+		}
+		sig.sig = [][]byte{v}
+	default:
+		// The signature can't be verified, but its packet is well-formed.
+		return sig, nil
+	}
+	if !s.Empty() {
+		return nil, errMalformedPacket
+	}
+
+	if err := sig.parseSubpackets(hashedSub, true); err != nil {
+		return nil, err
+	}
+	if err := sig.parseSubpackets(unhashedSub, false); err != nil {
+		return nil, err
+	}
+	if sig.created.IsZero() {
+		return nil, errors.New("openpgp: signature has no creation time")
+	}
+	if sig.expiresIn != 0 {
+		sig.expiration = sig.created.Add(time.Duration(sig.expiresIn) * time.Second)
+	}
+	return sig, nil
+}
+
+// parseSubpackets parses the subpackets of the hashed or unhashed area of
+// a signature. Only the issuer and embedded signature subpackets are used
+// from the unhashed area, since they are verified independently.
+func (sig *signature) parseSubpackets(s cryptobyte.String, hashed bool) error {
+	for !s.Empty() {
+		var l1 uint8
+		var length uint32
+		if !s.ReadUint8(&l1) {
+			return errMalformedPacket
+		}
+		switch {
+		case l1 < 192:
+			length = uint32(l1)
+		case l1 < 255:
+			var l2 uint8
+			if !s.ReadUint8(&l2) {
+				return errMalformedPacket
+			}
+			length = (uint32(l1)-192)<<8 + uint32(l2) + 192
+		default:
+			if !s.ReadUint32(&length) {
+				return errMalformedPacket
+			}
+		}
+		var sub cryptobyte.String
+		var typ uint8
+		if length == 0 || !s.ReadBytes((*[]byte)(&sub), int(length)) || !sub.ReadUint8(&typ) {
+			return errMalformedPacket
+		}
+		critical := typ&0x80 != 0
+		typ &= 0x7f
+		if hashed && critical && !knownSubpackets[typ] {
+			return errors.New("openpgp: unsupported critical signature subpacket " + strconv.Itoa(int(typ)))
+		}
+
+		var ok bool
+		switch {
+		case typ == subIssuer:
+			ok = sub.ReadUint64(&sig.issuerKeyID) && sub.Empty()
+			sig.hasIssuerID = true
+		case typ == subIssuerFingerprint:
+			var v uint8
+			ok = sub.ReadUint8(&v) && (v == 4 && len(sub) == 20 || v == 6 && len(sub) == 32)
+			sig.issuerFP = sub
+		case typ == subEmbeddedSignature:
+			var err error
+			if sig.embedded, err = parseSignature(sub); err != nil {
+				return err
+			}
+			ok = true
+		case !hashed:
+			ok = true
+		case typ == subCreationTime:
+			var t uint32
+			ok = sub.ReadUint32(&t) && sub.Empty()
+			sig.created = time.Unix(int64(t), 0)
+		case typ == subExpirationTime:
+			ok = sub.ReadUint32(&sig.expiresIn) && sub.Empty()
+		case typ == subKeyExpirationTime:
+			ok = sub.ReadUint32(&sig.keyExpiration) && sub.Empty()
+		case typ == subKeyFlags:
+			// Key flags can be longer than one octet, but only the
+			// first is of interest.
+			ok = sub.ReadUint8(&sig.keyFlags)
+			sig.hasKeyFlags = true
+		default:
+			ok = true
+		}
+		if !ok {
+			return errMalformedPacket
+		}
+	}
+	return nil
+}
+
+// newHash returns the hash to write the signed data to, with the salt of
+// version 6 signatures.
+func (sig *signature) newHash() hash.Hash {
+	h := sig.hash.New()
+	h.Write(sig.salt)
+	return h
+}
+
+var errVerification = errors.New("openpgp: invalid signature")
+
+// verify verifies sig by pk over the data already written to h.
+func (sig *signature) verify(pk *PublicKey, h hash.Hash) error {
+	if pk.PublicKey == nil || pk.Algorithm != sig.algorithm || pk.Version != sig.version {
+		return errVerification
+	}
+	h.Write(sig.hashed)
+	h.Write([]byte{byte(sig.version), 0xff})
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(sig.hashed))))
+	digest := h.Sum(nil)
+	if !bytes.Equal(digest[:2], sig.left16) {
+		return errVerification
+	}
+	switch pub := pk.PublicKey.(type) {
+	case *rsa.PublicKey:
+		s := make([]byte, (pub.N.BitLen()+7)/8)
+		if len(sig.sig[0]) > len(s) {
+			return errVerification
+		}
+		copy(s[len(s)-len(sig.sig[0]):], sig.sig[0])
+		if rsa.VerifyPKCS1v15(pub, sig.hash, digest, s) != nil {
+			return errVerification
+		}
+	case ed25519.PublicKey:
+		s := sig.sig[0]
+		if sig.algorithm == EdDSALegacy {
+			r, ss := sig.sig[0], sig.sig[1]
+			if len(r) > 32 || len(ss) > 32 {
+				return errVerification
+			}
+			s = make([]byte, ed25519.SignatureSize)
+			copy(s[32-len(r):32], r)
+			copy(s[64-len(ss):], ss)
+		}
+		if !ed25519.Verify(pub, digest, s) {
+			return errVerification
+		}
+	default:
+		panic("openpgp: internal error: unexpected public key type")
+	}
+	return nil
+}
+
+// verifyKeySignature verifies a signature by signer over the primary key,
+// and the subkey or user ID if not nil.
+func (sig *signature) verifyKeySignature(signer, primary, subkey *PublicKey, userID []byte) error {
+	h := sig.newHash()
+	primary.writeTo(h)
+	if subkey != nil {
+		subkey.writeTo(h)
+	}
+	if userID != nil {
+		h.Write([]byte{0xb4})
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(userID))))
+		h.Write(userID)
+	}
+	return sig.verify(signer, h)
+}
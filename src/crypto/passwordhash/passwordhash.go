@@ -0,0 +1,308 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package passwordhash hashes passwords for storage and verifies passwords
+// against stored hashes.
+//
+// Hashes are encoded in the PHC string format, which records the algorithm
+// and its parameters along with the salt and the derived key, for example
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$...
+//
+// New hashes use Argon2id or scrypt. Verify also accepts bcrypt hashes in
+// the "$2a$", "$2b$", and "$2y$" formats, so that applications can move
+// their users to a new algorithm or to stronger parameters as they log in:
+//
+//	if err := passwordhash.Verify(password, stored); err != nil {
+//		return err
+//	}
+//	if passwordhash.NeedsRehash(stored, nil) {
+//		stored, err = passwordhash.Hash(password, nil)
+//		// ...
+//	}
+//
+// See https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md.
+package passwordhash
+
+import (
+	"crypto/argon2"
+	"crypto/internal/bcrypt"
+	"crypto/internal/scrypt"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// ErrMismatch is returned by Verify when the password does not match the
+// hash.
+var ErrMismatch = errors.New("passwordhash: password does not match hash")
+
+var (
+	errMalformed   = errors.New("passwordhash: malformed hash")
+	errUnsupported = errors.New("passwordhash: unsupported algorithm")
+)
+
+// Params selects the algorithm and parameters used to hash new passwords.
+// It is implemented by Argon2idParams and ScryptParams.
+type Params interface {
+	// check reports whether the parameters are acceptable for new hashes.
+	check() error
+	saltLength() int
+	// key derives the key for password and salt.
+	key(password, salt []byte) ([]byte, error)
+	// encode returns the PHC string for salt and key.
+	encode(salt, key []byte) string
+}
+
+// defaultParams are the parameters used when Hash or NeedsRehash is passed
+// nil Params. They are the second recommended option of RFC 9106, Section 4,
+// and may change in future releases as hardware gets faster.
+var defaultParams = Argon2idParams{
+	Time:       3,
+	Memory:     64 * 1024,
+	Threads:    4,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// Argon2idParams are the parameters of Argon2id, as defined in RFC 9106.
+type Argon2idParams struct {
+	Time       uint32 // number of passes over the memory
+	Memory     uint32 // size of the memory in KiB
+	Threads    uint8  // degree of parallelism
+	SaltLength int    // in bytes, at least 8
+	KeyLength  int    // in bytes, at least 16
+}
+
+func (p Argon2idParams) check() error {
+	if p.Time < 1 || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) {
+		return errors.New("passwordhash: invalid Argon2id parameters")
+	}
+	if p.SaltLength < 8 || p.KeyLength < 16 || p.KeyLength > 1<<20 {
+		return errors.New("passwordhash: invalid Argon2id salt or key length")
+	}
+	return nil
+}
+
+func (p Argon2idParams) saltLength() int { return p.SaltLength }
+
+func (p Argon2idParams) key(password, salt []byte) ([]byte, error) {
+	return argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, uint32(p.KeyLength)), nil
+}
+
+func (p Argon2idParams) encode(salt, key []byte) string {
+	return "$argon2id$v=19$m=" + strconv.FormatUint(uint64(p.Memory), 10) +
+		",t=" + strconv.FormatUint(uint64(p.Time), 10) +
+		",p=" + strconv.Itoa(int(p.Threads)) +
+		"$" + b64.EncodeToString(salt) + "$" + b64.EncodeToString(key)
+}
+
+// ScryptParams are the parameters of scrypt, as defined in RFC 7914.
+type ScryptParams struct {
+	N          int // CPU/memory cost, a power of two greater than one
+	R          int // block size
+	P          int // parallelization
+	SaltLength int // in bytes, at least 8
+	KeyLength  int // in bytes, at least 16
+}
+
+func (p ScryptParams) check() error {
+	if p.N <= 1 || p.N&(p.N-1) != 0 || p.R < 1 || p.P < 1 {
+		return errors.New("passwordhash: invalid scrypt parameters")
+	}
+	if p.SaltLength < 8 || p.KeyLength < 16 || p.KeyLength > 1<<20 {
+		return errors.New("passwordhash: invalid scrypt salt or key length")
+	}
+	return nil
+}
+
+func (p ScryptParams) saltLength() int { return p.SaltLength }
+
+func (p ScryptParams) key(password, salt []byte) ([]byte, error) {
+	return scrypt.Key(password, salt, p.N, p.R, p.P, p.KeyLength)
+}
+
+func (p ScryptParams) encode(salt, key []byte) string {
+	return "$scrypt$ln=" + strconv.Itoa(bits.TrailingZeros(uint(p.N))) +
+		",r=" + strconv.Itoa(p.R) +
+		",p=" + strconv.Itoa(p.P) +
+		"$" + b64.EncodeToString(salt) + "$" + b64.EncodeToString(key)
+}
+
+// b64 is the base64 encoding of the PHC string format.
+var b64 = base64.RawStdEncoding
+
+// Hash returns the PHC string for password, hashed with a new random salt
+// according to p. If p is nil, Hash uses Argon2id with t=3, m=65536 (64 MiB),
+// p=4, a 16-byte salt, and a 32-byte key, as recommended by RFC 9106.
+func Hash(password []byte, p Params) (string, error) {
+	if p == nil {
+		p = defaultParams
+	}
+	if err := p.check(); err != nil {
+		return "", err
+	}
+	salt := make([]byte, p.saltLength())
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	key, err := p.key(password, salt)
+	if err != nil {
+		return "", err
+	}
+	return p.encode(salt, key), nil
+}
+
+// Verify checks password against encoded, which is a hash returned by Hash
+// or a bcrypt hash. It returns nil if they match, ErrMismatch if they don't,
+// and another error if encoded is malformed or uses an unsupported algorithm.
+//
+// The comparison of the derived keys takes constant time.
+func Verify(password []byte, encoded string) error {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), password)
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrMismatch
+		}
+		if err != nil {
+			return errMalformed
+		}
+		return nil
+	}
+	p, salt, key, err := parse(encoded)
+	if err != nil {
+		return err
+	}
+	got, err := p.key(password, salt)
+	if err != nil {
+		return errMalformed
+	}
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// NeedsRehash reports whether encoded was not produced by Hash with
+// parameters p, in which case the application should hash the password
+// again, after it is verified, and store the new hash. If p is nil,
+// NeedsRehash compares against the defaults used by Hash.
+//
+// bcrypt hashes, and hashes that can't be parsed, always need rehashing.
+func NeedsRehash(encoded string, p Params) bool {
+	if p == nil {
+		p = defaultParams
+	}
+	got, _, _, err := parse(encoded)
+	if err != nil {
+		return true
+	}
+	return got != p
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2$") || strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// parse decodes a PHC string produced by Hash. The returned Params have the
+// salt and key lengths of encoded.
+func parse(encoded string) (p Params, salt, key []byte, err error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) < 2 || fields[0] != "" {
+		return nil, nil, nil, errMalformed
+	}
+	switch fields[1] {
+	case "argon2id":
+		// $argon2id$v=19$m=<m>,t=<t>,p=<p>$<salt>$<key>
+		if len(fields) != 6 || fields[2] != "v=19" {
+			return nil, nil, nil, errMalformed
+		}
+		v, ok := parseParams(fields[3], "m", "t", "p")
+		if !ok || v[0] > 1<<32-1 || v[1] > 1<<32-1 || v[2] > 1<<8-1 {
+			return nil, nil, nil, errMalformed
+		}
+		salt, key, ok = parseSaltAndKey(fields[4], fields[5])
+		if !ok {
+			return nil, nil, nil, errMalformed
+		}
+		p := Argon2idParams{
+			Memory:     uint32(v[0]),
+			Time:       uint32(v[1]),
+			Threads:    uint8(v[2]),
+			SaltLength: len(salt),
+			KeyLength:  len(key),
+		}
+		if p.Time < 1 || p.Threads < 1 {
+			return nil, nil, nil, errMalformed
+		}
+		return p, salt, key, nil
+	case "scrypt":
+		// $scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<key>
+		if len(fields) != 5 {
+			return nil, nil, nil, errMalformed
+		}
+		v, ok := parseParams(fields[2], "ln", "r", "p")
+		if !ok || v[0] < 1 || v[0] > 62 || v[1] < 1 || v[1] > 1<<30 || v[2] < 1 || v[2] > 1<<30 {
+			return nil, nil, nil, errMalformed
+		}
+		salt, key, ok = parseSaltAndKey(fields[3], fields[4])
+		if !ok {
+			return nil, nil, nil, errMalformed
+		}
+		return ScryptParams{
+			N:          1 << v[0],
+			R:          int(v[1]),
+			P:          int(v[2]),
+			SaltLength: len(salt),
+			KeyLength:  len(key),
+		}, salt, key, nil
+	default:
+		return nil, nil, nil, errUnsupported
+	}
+}
+
+// parseParams parses a comma-separated list of decimal name=value pairs,
+// which must have exactly the given names in order.
+func parseParams(s string, names ...string) ([]uint64, bool) {
+	values := make([]uint64, 0, len(names))
+	for i, name := range names {
+		field, rest, more := strings.Cut(s, ",")
+		if more != (i < len(names)-1) {
+			return nil, false
+		}
+		s = rest
+		value, ok := strings.CutPrefix(field, name+"=")
+		if !ok {
+			return nil, false
+		}
+		// PHC decimal values have no sign and no leading zeroes.
+		if len(value) > 1 && value[0] == '0' {
+			return nil, false
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, n)
+	}
+	return values, true
+}
+
+func parseSaltAndKey(s, k string) (salt, key []byte, ok bool) {
+	salt, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, nil, false
+	}
+	key, err = b64.DecodeString(k)
+	if err != nil || len(key) == 0 {
+		return nil, nil, false
+	}
+	return salt, key, true
+}
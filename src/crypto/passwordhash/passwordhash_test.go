@@ -0,0 +1,132 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package passwordhash
+
+import (
+	"strings"
+	"testing"
+)
+
+// Cheap parameters, to keep the tests fast.
+var (
+	testArgon2id = Argon2idParams{Time: 1, Memory: 64, Threads: 2, SaltLength: 16, KeyLength: 32}
+	testScrypt   = ScryptParams{N: 1024, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+)
+
+func TestHashAndVerify(t *testing.T) {
+	for _, p := range []Params{testArgon2id, testScrypt} {
+		password := []byte("correct horse battery staple")
+		encoded, err := Hash(password, p)
+		if err != nil {
+			t.Fatalf("Hash(%#v): %v", p, err)
+		}
+		if err := Verify(password, encoded); err != nil {
+			t.Errorf("Verify(%q): %v", encoded, err)
+		}
+		if err := Verify([]byte("Tr0ub4dor&3"), encoded); err != ErrMismatch {
+			t.Errorf("Verify(%q) with wrong password = %v, want ErrMismatch", encoded, err)
+		}
+		if NeedsRehash(encoded, p) {
+			t.Errorf("NeedsRehash(%q, %#v) = true", encoded, p)
+		}
+		if !NeedsRehash(encoded, nil) {
+			t.Errorf("NeedsRehash(%q, nil) = false", encoded)
+		}
+
+		again, err := Hash(password, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again == encoded {
+			t.Errorf("Hash reused a salt")
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if testing.Short() {
+		t.Skip("uses 64 MiB of memory")
+	}
+	encoded, err := Hash([]byte("password"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("Hash(nil) = %q, want Argon2id with the default parameters", encoded)
+	}
+	if NeedsRehash(encoded, nil) {
+		t.Errorf("NeedsRehash(%q, nil) = true", encoded)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	tests := []struct {
+		password, encoded string
+	}{
+		// From golang.org/x/crypto/bcrypt.
+		{"allmine", "$2a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga"},
+		// From Python's hashlib.scrypt.
+		{"password", "$scrypt$ln=10,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$dj05BT7oUTq35qmxXqG/pksYG8IJr8uxtvAzbfGjoic"},
+		// From the crypto/argon2 test vectors.
+		{"password", "$argon2id$v=19$m=256,t=3,p=2$c29tZXNhbHQ$RmjTCsQYfmh47t6s8P2DxaCjDbLMFu8L"},
+	}
+	for _, tt := range tests {
+		if err := Verify([]byte(tt.password), tt.encoded); err != nil {
+			t.Errorf("Verify(%q, %q): %v", tt.password, tt.encoded, err)
+		}
+		if err := Verify([]byte(tt.password+"!"), tt.encoded); err != ErrMismatch {
+			t.Errorf("Verify(%q, %q) = %v, want ErrMismatch", tt.password+"!", tt.encoded, err)
+		}
+		if !NeedsRehash(tt.encoded, nil) {
+			t.Errorf("NeedsRehash(%q, nil) = false", tt.encoded)
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"$",
+		"password",
+		"$argon2i$v=19$m=64,t=1,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=16$m=64,t=1,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$t=1,m=64,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$m=64,t=1,p=1,$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$m=64,t=0,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$m=64,t=01,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$m=64,t=1,p=256$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ=$c29tZWtleQ",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ",
+		"$scrypt$ln=0,r=8,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$scrypt$ln=10,r=8$c29tZXNhbHQ$c29tZWtleQ",
+		"$scrypt$ln=10,r=8,p=1,x=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$2a$10$fooo",
+	} {
+		if err := Verify([]byte("password"), encoded); err == nil || err == ErrMismatch {
+			t.Errorf("Verify(%q) = %v, want a format error", encoded, err)
+		}
+		if !NeedsRehash(encoded, nil) {
+			t.Errorf("NeedsRehash(%q, nil) = false", encoded)
+		}
+	}
+}
+
+func TestBadParams(t *testing.T) {
+	for _, p := range []Params{
+		Argon2idParams{Time: 0, Memory: 64, Threads: 1, SaltLength: 16, KeyLength: 32},
+		Argon2idParams{Time: 1, Memory: 64, Threads: 0, SaltLength: 16, KeyLength: 32},
+		Argon2idParams{Time: 1, Memory: 4, Threads: 1, SaltLength: 16, KeyLength: 32},
+		Argon2idParams{Time: 1, Memory: 64, Threads: 1, SaltLength: 4, KeyLength: 32},
+		Argon2idParams{Time: 1, Memory: 64, Threads: 1, SaltLength: 16, KeyLength: 8},
+		ScryptParams{N: 1000, R: 8, P: 1, SaltLength: 16, KeyLength: 32},
+		ScryptParams{N: 1024, R: 0, P: 1, SaltLength: 16, KeyLength: 32},
+		ScryptParams{N: 1024, R: 8, P: 1, SaltLength: 0, KeyLength: 32},
+	} {
+		if _, err := Hash([]byte("password"), p); err == nil {
+			t.Errorf("Hash(%#v) succeeded", p)
+		}
+	}
+}
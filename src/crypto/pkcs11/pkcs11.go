@@ -0,0 +1,296 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pkcs11 implements crypto.Signer for RSA and ECDSA private keys
+// held by a PKCS #11 token, such as a hardware security module or a smart
+// card, so that the keys never leave the token.
+//
+// Only the private key operation is delegated to the token. The callers of
+// a Signer, such as crypto/tls, hash the messages themselves, with
+// BoringCrypto when it is in use, and the token signs the digests. A Key
+// can be used as the PrivateKey of a crypto/tls Certificate: its Public
+// method returns an *rsa.PublicKey or an *ecdsa.PublicKey, from which
+// crypto/tls selects the signature algorithms.
+//
+// The token is accessed through its PKCS #11 module, a shared library
+// loaded at run time, which requires cgo on a Unix system. Elsewhere, Open
+// returns an error.
+package pkcs11
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/internal/backend/pkcs11"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// A Token is a session with a PKCS #11 token. It is safe for concurrent
+// use by multiple goroutines, but the token performs one operation at a
+// time.
+type Token struct {
+	s *pkcs11.Session
+}
+
+// Open loads the PKCS #11 module at the path, such as
+// "/usr/lib/softhsm/libsofthsm2.so", and opens a session with the token in
+// slot. If pin is not empty, it logs in as the normal user with pin, which
+// tokens require to use their private keys.
+//
+// A module is loaded, and initialized, once per process.
+func Open(module string, slot uint, pin string) (*Token, error) {
+	m, err := pkcs11.Load(module)
+	if err != nil {
+		return nil, err
+	}
+	s, err := m.OpenSession(slot, pin)
+	if err != nil {
+		return nil, err
+	}
+	return &Token{s}, nil
+}
+
+// Close closes the session with the token. The Keys of t must not be used
+// afterwards.
+func (t *Token) Close() error {
+	return t.s.Close()
+}
+
+// A Key is an RSA or ECDSA private key held by a token. It implements
+// crypto.Signer.
+type Key struct {
+	t    *Token
+	priv pkcs11.Object
+	pub  crypto.PublicKey
+}
+
+// Key returns the RSA or ECDSA private key of t labeled label, whose
+// public key must also be on the token, with the same label.
+func (t *Token) Key(label string) (*Key, error) {
+	for _, keyType := range []uint{pkcs11.KeyTypeRSA, pkcs11.KeyTypeEC} {
+		priv, err := t.s.FindKey(pkcs11.ClassPrivateKey, keyType, label)
+		if err == pkcs11.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pubObj, err := t.s.FindKey(pkcs11.ClassPublicKey, keyType, label)
+		if err == pkcs11.ErrNotFound {
+			return nil, errors.New("pkcs11: no public key labeled " + strconv.Quote(label))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return t.newKey(keyType, pubObj, priv)
+	}
+	return nil, errors.New("pkcs11: no RSA or ECDSA private key labeled " + strconv.Quote(label))
+}
+
+// GenerateRSAKey generates an RSA key pair of bits, with the public
+// exponent 65537, on t with label, and returns the private key. The
+// private key can't be extracted from the token.
+func (t *Token) GenerateRSAKey(label string, bits int) (*Key, error) {
+	pub, priv, err := t.s.GenerateRSAKeyPair(label, bits)
+	if err != nil {
+		return nil, err
+	}
+	return t.newKey(pkcs11.KeyTypeRSA, pub, priv)
+}
+
+// GenerateECDSAKey generates an ECDSA key pair on c, one of the curves of
+// crypto/elliptic, on t with label, and returns the private key. The
+// private key can't be extracted from the token.
+func (t *Token) GenerateECDSAKey(label string, c elliptic.Curve) (*Key, error) {
+	params, ok := curveParams(c)
+	if !ok {
+		return nil, errors.New("pkcs11: unsupported curve")
+	}
+	pub, priv, err := t.s.GenerateECKeyPair(label, params)
+	if err != nil {
+		return nil, err
+	}
+	return t.newKey(pkcs11.KeyTypeEC, pub, priv)
+}
+
+// newKey returns the Key of the private key priv, whose public key is pub.
+func (t *Token) newKey(keyType uint, pub, priv pkcs11.Object) (*Key, error) {
+	k := &Key{t: t, priv: priv}
+	switch keyType {
+	case pkcs11.KeyTypeRSA:
+		attrs, err := t.s.Attributes(pub, pkcs11.AttrModulus, pkcs11.AttrPublicExponent)
+		if err != nil {
+			return nil, err
+		}
+		e := new(big.Int).SetBytes(attrs[1])
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("pkcs11: unsupported RSA public exponent")
+		}
+		k.pub = &rsa.PublicKey{N: new(big.Int).SetBytes(attrs[0]), E: int(e.Int64())}
+	case pkcs11.KeyTypeEC:
+		attrs, err := t.s.Attributes(pub, pkcs11.AttrECParams, pkcs11.AttrECPoint)
+		if err != nil {
+			return nil, err
+		}
+		c, ok := curveByParams(attrs[0])
+		if !ok {
+			return nil, errors.New("pkcs11: unsupported curve")
+		}
+		x, y := elliptic.Unmarshal(c, ecPoint(c, attrs[1]))
+		if x == nil {
+			return nil, errors.New("pkcs11: invalid EC public key")
+		}
+		k.pub = &ecdsa.PublicKey{Curve: c, X: x, Y: y}
+	}
+	return k, nil
+}
+
+// Public returns the public key of k, an *rsa.PublicKey or an
+// *ecdsa.PublicKey.
+func (k *Key) Public() crypto.PublicKey {
+	return k.pub
+}
+
+// Sign signs digest, the hash of the message with opts.HashFunc(), with
+// the token. The token uses its own random number generator, so rand is
+// ignored.
+//
+// RSA keys sign with PKCS #1 v1.5, or with PSS if opts is an
+// *rsa.PSSOptions, and ECDSA keys return ASN.1 DER encoded signatures, as
+// the keys of crypto/rsa and crypto/ecdsa do.
+func (k *Key) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	h := opts.HashFunc()
+	if h != 0 && len(digest) != h.Size() {
+		return nil, errors.New("pkcs11: input must be hashed message")
+	}
+	switch pub := k.pub.(type) {
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			return k.signPSS(pub, h, digest, pss)
+		}
+		prefix, ok := hashPrefixes[h]
+		if !ok && h != 0 {
+			return nil, errors.New("pkcs11: unsupported hash function")
+		}
+		return k.t.s.Sign(k.priv, pkcs11.MechRSAPKCS, nil, append(prefix[:len(prefix):len(prefix)], digest...), pub.Size())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		rs, err := k.t.s.Sign(k.priv, pkcs11.MechECDSA, nil, digest, 2*size)
+		if err != nil {
+			return nil, err
+		}
+		if len(rs) != 2*size {
+			return nil, errors.New("pkcs11: invalid ECDSA signature from token")
+		}
+		return encodeSignature(rs[:size], rs[size:])
+	}
+	panic("pkcs11: unreachable")
+}
+
+// pssHashes are the hash mechanisms and the mask generation functions of
+// the hashes, for PSS.
+var pssHashes = map[crypto.Hash][2]uint{
+	crypto.SHA1:   {pkcs11.MechSHA1, pkcs11.MGF1SHA1},
+	crypto.SHA224: {pkcs11.MechSHA224, pkcs11.MGF1SHA224},
+	crypto.SHA256: {pkcs11.MechSHA256, pkcs11.MGF1SHA256},
+	crypto.SHA384: {pkcs11.MechSHA384, pkcs11.MGF1SHA384},
+	crypto.SHA512: {pkcs11.MechSHA512, pkcs11.MGF1SHA512},
+}
+
+func (k *Key) signPSS(pub *rsa.PublicKey, h crypto.Hash, digest []byte, opts *rsa.PSSOptions) ([]byte, error) {
+	mechs, ok := pssHashes[h]
+	if !ok {
+		return nil, errors.New("pkcs11: unsupported hash function")
+	}
+	saltLen := opts.SaltLength
+	switch saltLen {
+	case rsa.PSSSaltLengthAuto:
+		saltLen = (pub.N.BitLen()-1+7)/8 - h.Size() - 2
+		if saltLen < 0 {
+			return nil, rsa.ErrMessageTooLong
+		}
+	case rsa.PSSSaltLengthEqualsHash:
+		saltLen = h.Size()
+	}
+	params := &pkcs11.PSSParams{Hash: mechs[0], MGF: mechs[1], SaltLen: saltLen}
+	return k.t.s.Sign(k.priv, pkcs11.MechRSAPKCSPSS, params, digest, pub.Size())
+}
+
+// hashPrefixes are the DigestInfo prefixes of the hashes, as in
+// crypto/rsa. The token signs the DigestInfo with CKM_RSA_PKCS, so that
+// the hash doesn't need to be computed by the token.
+var hashPrefixes = map[crypto.Hash][]byte{
+	crypto.MD5:       {0x30, 0x20, 0x30, 0x0c, 0x06, 0x08, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x02, 0x05, 0x05, 0x00, 0x04, 0x10},
+	crypto.SHA1:      {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA224:    {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	crypto.SHA256:    {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384:    {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512:    {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	crypto.MD5SHA1:   {}, // A special TLS case which doesn't use an ASN1 prefix.
+	crypto.RIPEMD160: {0x30, 0x20, 0x30, 0x08, 0x06, 0x06, 0x28, 0xcf, 0x06, 0x03, 0x00, 0x31, 0x04, 0x14},
+}
+
+// curveOIDs are the DER encoded object identifiers of the curves, the
+// CKA_EC_PARAMS of their keys.
+var curveOIDs = []struct {
+	curve func() elliptic.Curve
+	oid   []byte
+}{
+	{elliptic.P224, []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x21}},
+	{elliptic.P256, []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}},
+	{elliptic.P384, []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x22}},
+	{elliptic.P521, []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x23}},
+}
+
+func curveParams(c elliptic.Curve) ([]byte, bool) {
+	for _, e := range curveOIDs {
+		if e.curve() == c {
+			return e.oid, true
+		}
+	}
+	return nil, false
+}
+
+func curveByParams(params []byte) (elliptic.Curve, bool) {
+	for _, e := range curveOIDs {
+		if bytes.Equal(e.oid, params) {
+			return e.curve(), true
+		}
+	}
+	return nil, false
+}
+
+// ecPoint returns the uncompressed point of CKA_EC_POINT b, which the
+// specification defines as a DER encoded OCTET STRING, but which some
+// tokens return unwrapped.
+func ecPoint(c elliptic.Curve, b []byte) []byte {
+	if len(b) == 1+2*((c.Params().BitSize+7)/8) {
+		return b
+	}
+	var point []byte
+	s := cryptobyte.String(b)
+	if !s.ReadASN1Bytes(&point, asn1.OCTET_STRING) || !s.Empty() {
+		return nil
+	}
+	return point
+}
+
+// encodeSignature returns the ASN.1 DER encoding of the ECDSA signature
+// (r, s), as crypto/ecdsa does.
+func encodeSignature(r, s []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(new(big.Int).SetBytes(r))
+		b.AddASN1BigInt(new(big.Int).SetBytes(s))
+	})
+	return b.Bytes()
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs11
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOpenMissingModule(t *testing.T) {
+	if _, err := Open("/nonexistent/libpkcs11.so", 0, ""); err == nil {
+		t.Fatal("Open of a missing module succeeded")
+	}
+}
+
+func TestCurveParams(t *testing.T) {
+	for _, c := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		params, ok := curveParams(c)
+		if !ok {
+			t.Fatalf("curveParams(%s) failed", c.Params().Name)
+		}
+		if got, ok := curveByParams(params); !ok || got != c {
+			t.Errorf("curveByParams(%x) = %v, %v, want %s", params, got, ok, c.Params().Name)
+		}
+	}
+}
+
+func TestECPoint(t *testing.T) {
+	c := elliptic.P256()
+	point := elliptic.Marshal(c, c.Params().Gx, c.Params().Gy)
+	wrapped := append([]byte{0x04, byte(len(point))}, point...)
+	for _, b := range [][]byte{point, wrapped} {
+		if got := ecPoint(c, b); !bytes.Equal(got, point) {
+			t.Errorf("ecPoint(%x) = %x, want %x", b, got, point)
+		}
+	}
+	if got := ecPoint(c, wrapped[:len(wrapped)-1]); got != nil {
+		t.Errorf("ecPoint of a truncated point = %x, want nil", got)
+	}
+}
+
+func TestEncodeSignature(t *testing.T) {
+	sig, err := encodeSignature([]byte{0, 1}, []byte{0x80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "300702010102020080"; hex.EncodeToString(sig) != want {
+		t.Errorf("encodeSignature = %x, want %s", sig, want)
+	}
+}
+
+// openTestToken opens the token described by the environment, such as a
+// SoftHSM token: GOPKCS11_MODULE is the path of the module, GOPKCS11_SLOT
+// the slot ID of the token, and GOPKCS11_PIN the user PIN.
+func openTestToken(t *testing.T) *Token {
+	module := os.Getenv("GOPKCS11_MODULE")
+	if module == "" {
+		t.Skip("GOPKCS11_MODULE not set")
+	}
+	slot, err := strconv.ParseUint(os.Getenv("GOPKCS11_SLOT"), 0, 64)
+	if err != nil {
+		t.Fatalf("GOPKCS11_SLOT: %v", err)
+	}
+	tok, err := Open(module, uint(slot), os.Getenv("GOPKCS11_PIN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tok.Close() })
+	return tok
+}
+
+func TestTokenSign(t *testing.T) {
+	tok := openTestToken(t)
+	label := "go-test-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	digest := sha256.Sum256([]byte("hello"))
+
+	rsaKey, err := tok.GenerateRSAKey(label+"-rsa", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub := rsaKey.Public().(*rsa.PublicKey)
+	sig, err := rsaKey.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rsa.VerifyPKCS1v15(rsaPub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("PKCS #1 v1.5 signature: %v", err)
+	}
+	pss := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	sig, err = rsaKey.Sign(nil, digest[:], pss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rsa.VerifyPSS(rsaPub, crypto.SHA256, digest[:], sig, pss); err != nil {
+		t.Errorf("PSS signature: %v", err)
+	}
+
+	ecKey, err := tok.GenerateECDSAKey(label+"-ec", elliptic.P256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err = ecKey.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(ecKey.Public().(*ecdsa.PublicKey), digest[:], sig) {
+		t.Error("ECDSA signature failed to verify")
+	}
+
+	// Key finds the generated keys again.
+	for suffix, k := range map[string]*Key{"-rsa": rsaKey, "-ec": ecKey} {
+		found, err := tok.Key(label + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.priv != k.priv {
+			t.Errorf("Key(%q) = object %d, want %d", label+suffix, found.priv, k.priv)
+		}
+	}
+	if _, err := tok.Key(label + "-missing"); err == nil {
+		t.Error("Key of a missing label succeeded")
+	}
+}
+
+// TestTokenTLS checks that a Key can be used as the private key of a TLS
+// certificate, for both TLS 1.2 and TLS 1.3.
+func TestTokenTLS(t *testing.T) {
+	tok := openTestToken(t)
+	label := "go-test-tls-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	key, err := tok.GenerateECDSAKey(label, elliptic.P256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		c, s := net.Pipe()
+		server := tls.Server(s, &tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+			MaxVersion:   version,
+		})
+		client := tls.Client(c, &tls.Config{RootCAs: roots, ServerName: "example.com", MaxVersion: version})
+		errc := make(chan error, 1)
+		go func() { errc <- server.Handshake() }()
+		if err := client.Handshake(); err != nil {
+			t.Errorf("TLS %x: client handshake: %v", version, err)
+		}
+		if err := <-errc; err != nil {
+			t.Errorf("TLS %x: server handshake: %v", version, err)
+		}
+		c.Close()
+		s.Close()
+	}
+}
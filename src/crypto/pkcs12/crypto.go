@@ -0,0 +1,170 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/internal/pbes2"
+	"crypto/pkcs12/internal/rc2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+)
+
+var (
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+)
+
+// pbeCipher is an abstraction of a PKCS#12 cipher.
+type pbeCipher interface {
+	// create returns a cipher.Block given a key.
+	create(key []byte) (cipher.Block, error)
+	// deriveKey returns a key derived from the given password and salt.
+	deriveKey(salt, password []byte, iterations int) []byte
+	// deriveIV returns an IV derived from the given password and salt.
+	deriveIV(salt, password []byte, iterations int) []byte
+}
+
+type shaWithTripleDESCBC struct{}
+
+func (shaWithTripleDESCBC) create(key []byte) (cipher.Block, error) {
+	return des.NewTripleDESCipher(key)
+}
+
+func (shaWithTripleDESCBC) deriveKey(salt, password []byte, iterations int) []byte {
+	return pbkdf(sha1.New, salt, password, iterations, 1, 24)
+}
+
+func (shaWithTripleDESCBC) deriveIV(salt, password []byte, iterations int) []byte {
+	return pbkdf(sha1.New, salt, password, iterations, 2, 8)
+}
+
+// shaWithRC2CBC is pbeWithSHAAnd40BitRC2-CBC or pbeWithSHAAnd128BitRC2-CBC,
+// depending on the key size in bytes.
+type shaWithRC2CBC int
+
+func (shaWithRC2CBC) create(key []byte) (cipher.Block, error) {
+	return rc2.New(key, len(key)*8)
+}
+
+func (c shaWithRC2CBC) deriveKey(salt, password []byte, iterations int) []byte {
+	return pbkdf(sha1.New, salt, password, iterations, 1, int(c))
+}
+
+func (shaWithRC2CBC) deriveIV(salt, password []byte, iterations int) []byte {
+	return pbkdf(sha1.New, salt, password, iterations, 2, 8)
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+func pbDecrypterFor(algorithm pkix.AlgorithmIdentifier, password []byte) (cipher.BlockMode, int, error) {
+	var cipherType pbeCipher
+
+	switch {
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		cipherType = shaWithTripleDESCBC{}
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		cipherType = shaWithRC2CBC(5)
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+		cipherType = shaWithRC2CBC(16)
+	default:
+		return nil, 0, NotImplementedError("algorithm " + algorithm.Algorithm.String() + " is not supported")
+	}
+
+	var params pbeParams
+	if err := unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, 0, err
+	}
+
+	if params.Iterations < 0 || params.Iterations > maxIterations {
+		return nil, 0, NotImplementedError("iteration count is invalid or too high")
+	}
+
+	key := cipherType.deriveKey(params.Salt, password, params.Iterations)
+	iv := cipherType.deriveIV(params.Salt, password, params.Iterations)
+
+	block, err := cipherType.create(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cipher.NewCBCDecrypter(block, iv), block.BlockSize(), nil
+}
+
+// pbDecrypt decrypts info with password, which is encoded as a BMPString
+// as required by the legacy PKCS #12 encryption schemes.
+func pbDecrypt(info decryptable, password []byte) (decrypted []byte, err error) {
+	if info.Algorithm().Algorithm.Equal(pbes2.OID) {
+		// PBES2 takes the password as a plain byte string rather than a
+		// BMPString. Like OpenSSL, use the UTF-8 encoding.
+		utf8Password, err := decodeBMPString(password)
+		if err != nil {
+			return nil, err
+		}
+		decrypted, err = pbes2.Decrypt(info.Algorithm(), []byte(utf8Password), info.Data())
+		if err == pbes2.ErrDecryption {
+			err = ErrDecryption
+		}
+		return decrypted, err
+	}
+
+	cbc, blockSize, err := pbDecrypterFor(info.Algorithm(), password)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := info.Data()
+	if len(encrypted) == 0 {
+		return nil, errors.New("pkcs12: empty encrypted data")
+	}
+	if len(encrypted)%blockSize != 0 {
+		return nil, errors.New("pkcs12: input is not a multiple of the block size")
+	}
+	decrypted = make([]byte, len(encrypted))
+	cbc.CryptBlocks(decrypted, encrypted)
+
+	psLen := int(decrypted[len(decrypted)-1])
+	if psLen == 0 || psLen > blockSize {
+		return nil, ErrDecryption
+	}
+
+	if len(decrypted) < psLen {
+		return nil, ErrDecryption
+	}
+	ps := decrypted[len(decrypted)-psLen:]
+	decrypted = decrypted[:len(decrypted)-psLen]
+	if !bytes.Equal(ps, bytes.Repeat([]byte{byte(psLen)}, psLen)) {
+		return nil, ErrDecryption
+	}
+
+	return
+}
+
+// pbEncrypt encrypts plaintext with PBES2, using PBKDF2 with HMAC-SHA-256
+// and AES-256-CBC.
+func pbEncrypt(rand io.Reader, plaintext []byte, password string, iterations int) (pkix.AlgorithmIdentifier, []byte, error) {
+	return pbes2.Encrypt(rand, []byte(password), plaintext, &pbes2.Options{
+		Cipher:     pbes2.AES256CBC,
+		Hash:       sha256.New,
+		Iterations: iterations,
+		SaltSize:   16,
+	})
+}
+
+// decryptable abstracts an object that contains ciphertext.
+type decryptable interface {
+	Algorithm() pkix.AlgorithmIdentifier
+	Data() []byte
+}
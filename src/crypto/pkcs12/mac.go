@@ -0,0 +1,98 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"hash"
+	"io"
+)
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+// from PKCS#7:
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+var (
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSHA224 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 4}
+)
+
+// maxIterations is a safety limit to prevent CPU exhaustion from
+// crafted PKCS#12 files with unreasonable iteration counts.
+const maxIterations = 1 << 20 // ~1 million
+
+func macHash(alg asn1.ObjectIdentifier) func() hash.Hash {
+	switch {
+	case alg.Equal(oidSHA1):
+		return sha1.New
+	case alg.Equal(oidSHA224):
+		return sha256.New224
+	case alg.Equal(oidSHA256):
+		return sha256.New
+	case alg.Equal(oidSHA384):
+		return sha512.New384
+	case alg.Equal(oidSHA512):
+		return sha512.New
+	}
+	return nil
+}
+
+func computeMac(h func() hash.Hash, message, salt, password []byte, iterations int) []byte {
+	key := pbkdf(h, salt, password, iterations, 3, h().Size())
+
+	mac := hmac.New(h, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+func verifyMac(macData *macData, message, password []byte) error {
+	h := macHash(macData.Mac.Algorithm.Algorithm)
+	if h == nil {
+		return NotImplementedError("unknown digest algorithm: " + macData.Mac.Algorithm.Algorithm.String())
+	}
+
+	if macData.Iterations < 0 || macData.Iterations > maxIterations {
+		return NotImplementedError("iteration count is invalid or too high")
+	}
+
+	expectedMAC := computeMac(h, message, macData.MacSalt, password, macData.Iterations)
+	if !hmac.Equal(macData.Mac.Digest, expectedMAC) {
+		return ErrIncorrectPassword
+	}
+	return nil
+}
+
+// newMac returns an HMAC-SHA-256 macData over message, matching the default
+// of current OpenSSL releases.
+func newMac(rand io.Reader, message, password []byte, iterations int) (*macData, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, err
+	}
+	return &macData{
+		Mac: digestInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			Digest:    computeMac(sha256.New, message, salt, password, iterations),
+		},
+		MacSalt:    salt,
+		Iterations: iterations,
+	}, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import (
+	"bytes"
+	"hash"
+	"math/big"
+)
+
+var one = big.NewInt(1)
+
+// fillWithRepeats returns v*ceiling(len(pattern) / v) bytes consisting of
+// repeats of pattern.
+func fillWithRepeats(pattern []byte, v int) []byte {
+	if len(pattern) == 0 {
+		return nil
+	}
+	outputLen := v * ((len(pattern) + v - 1) / v)
+	return bytes.Repeat(pattern, (outputLen+len(pattern)-1)/len(pattern))[:outputLen]
+}
+
+// pbkdf implements the PKCS #12 key derivation function described in
+// RFC 7292, Appendix B.2, using the hash function h. The ID byte selects the
+// purpose of the output: 1 for keys, 2 for IVs, and 3 for MAC keys.
+func pbkdf(h func() hash.Hash, salt, password []byte, r int, ID byte, size int) []byte {
+	H := h()
+	// u is the output length of H and v is the input block length of its
+	// compression function, both in bytes.
+	u, v := H.Size(), H.BlockSize()
+
+	// 1. Construct a string, D (the "diversifier"), by concatenating v/8
+	//    copies of ID.
+	D := bytes.Repeat([]byte{ID}, v)
+
+	// 2. Concatenate copies of the salt together to create a string S of
+	//    length v(ceiling(s/v)) bits.
+	S := fillWithRepeats(salt, v)
+
+	// 3. Concatenate copies of the password together to create a string P
+	//    of length v(ceiling(p/v)) bits.
+	P := fillWithRepeats(password, v)
+
+	// 4. Set I=S||P to be the concatenation of S and P.
+	I := append(S, P...)
+
+	// 5. Set c=ceiling(n/u).
+	c := (size + u - 1) / u
+
+	// 6. For i=1, 2, ..., c, do the following:
+	A := make([]byte, c*u)
+	var IjBuf []byte
+	for i := 0; i < c; i++ {
+		// A. Set A_i=H^r(D||I).
+		H.Reset()
+		H.Write(D)
+		H.Write(I)
+		Ai := H.Sum(nil)
+		for j := 1; j < r; j++ {
+			H.Reset()
+			H.Write(Ai)
+			Ai = H.Sum(Ai[:0])
+		}
+		copy(A[i*u:], Ai)
+
+		if i < c-1 { // skip on last iteration
+			// B. Concatenate copies of A_i to create a string B of length v
+			//    bits (the final copy of A_i may be truncated to create B).
+			B := fillWithRepeats(Ai, v)[:v]
+
+			// C. Treating I as a concatenation I_0, I_1, ..., I_(k-1) of v-bit
+			//    blocks, modify I by setting I_j=(I_j+B+1) mod 2^v for each j.
+			Bbi := new(big.Int).SetBytes(B)
+			Ij := new(big.Int)
+			for j := 0; j < len(I)/v; j++ {
+				Ij.SetBytes(I[j*v : (j+1)*v])
+				Ij.Add(Ij, Bbi)
+				Ij.Add(Ij, one)
+				Ijb := Ij.Bytes()
+				// We expect Ijb to be exactly v bytes, if it is longer or
+				// shorter we must adjust it accordingly.
+				if len(Ijb) > v {
+					Ijb = Ijb[len(Ijb)-v:]
+				}
+				if len(Ijb) < v {
+					if IjBuf == nil {
+						IjBuf = make([]byte, v)
+					}
+					bytesShort := v - len(Ijb)
+					for i := 0; i < bytesShort; i++ {
+						IjBuf[i] = 0
+					}
+					copy(IjBuf[bytesShort:], Ijb)
+					Ijb = IjBuf
+				}
+				copy(I[j*v:(j+1)*v], Ijb)
+			}
+		}
+	}
+
+	// 7. Concatenate A_1, A_2, ..., A_c together to form a pseudorandom bit
+	//    string, A.
+	//
+	// 8. Use the first n bits of A as the output of this entire process.
+	return A[:size]
+}
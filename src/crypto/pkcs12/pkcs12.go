@@ -0,0 +1,498 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pkcs12 implements encoding and decoding of PKCS #12 (PFX) files,
+// as defined in RFC 7292, for exchanging private keys and certificates
+// with other software such as Java keystores and Windows certificate stores.
+//
+// Files are written with modern algorithms: keys and certificates are
+// encrypted with PBES2 (PBKDF2 with HMAC-SHA-256 and AES-256-CBC) and the
+// file is authenticated with HMAC-SHA-256. Decoding additionally supports
+// the legacy pbeWithSHAAnd3-KeyTripleDES-CBC and pbeWithSHAAnd40BitRC2-CBC
+// schemes and SHA-1 MACs, which are still produced by older software.
+//
+// Only password integrity mode and password privacy mode are supported.
+package pkcs12
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+)
+
+// DefaultIterations is the PBKDF2 and MAC iteration count used by Encode
+// and EncodeTrustStore.
+const DefaultIterations = 2048
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidLocalKeyID = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+
+	// oidJavaTrustStore marks a certificate bag as a trusted certificate
+	// entry in a Java keystore.
+	oidJavaTrustStore      = asn1.ObjectIdentifier{2, 16, 840, 1, 113894, 746875, 1, 1}
+	oidAnyExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+func (i encryptedContentInfo) Algorithm() pkix.AlgorithmIdentifier {
+	return i.ContentEncryptionAlgorithm
+}
+
+func (i encryptedContentInfo) Data() []byte { return i.EncryptedContent }
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	Id    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+func (i encryptedPrivateKeyInfo) Algorithm() pkix.AlgorithmIdentifier {
+	return i.AlgorithmIdentifier
+}
+
+func (i encryptedPrivateKeyInfo) Data() []byte {
+	return i.EncryptedData
+}
+
+// unmarshal calls asn1.Unmarshal, but also returns an error if there is any
+// trailing data after unmarshaling.
+func unmarshal(in []byte, out any) error {
+	trailing, err := asn1.Unmarshal(in, out)
+	if err != nil {
+		return err
+	}
+	if len(trailing) != 0 {
+		return errors.New("pkcs12: trailing data found")
+	}
+	return nil
+}
+
+// Decode extracts a certificate and private key from pfxData, which must be
+// a DER-encoded PKCS #12 file. This function assumes that there is only one
+// certificate and only one private key in the pfxData. Since PKCS #12 files
+// often contain more than one certificate, you probably want to use
+// DecodeChain instead.
+func Decode(pfxData []byte, password string) (privateKey any, certificate *x509.Certificate, err error) {
+	var caCerts []*x509.Certificate
+	privateKey, certificate, caCerts, err = DecodeChain(pfxData, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(caCerts) != 0 {
+		return nil, nil, errors.New("pkcs12: expected exactly one certificate in the PFX PDU")
+	}
+	return privateKey, certificate, nil
+}
+
+// DecodeChain extracts a private key, the certificate for that key, and any
+// additional CA certificates from pfxData, which must be a DER-encoded
+// PKCS #12 file. The pfxData must contain exactly one private key.
+//
+// The leaf certificate is the one whose public key matches the private key.
+// All other certificates are returned in caCerts, in the order in which they
+// appear in pfxData.
+func DecodeChain(pfxData []byte, password string) (privateKey any, certificate *x509.Certificate, caCerts []*x509.Certificate, err error) {
+	encodedPassword, err := bmpString(password)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bags, encodedPassword, err := getSafeContents(pfxData, encodedPassword)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var certs []*x509.Certificate
+	for _, bag := range bags {
+		switch {
+		case bag.Id.Equal(oidCertBag):
+			certsData, err := decodeCertBag(bag.Value.Bytes)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			parsed, err := x509.ParseCertificates(certsData)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(parsed) != 1 {
+				return nil, nil, nil, errors.New("pkcs12: expected exactly one certificate in the certBag")
+			}
+			certs = append(certs, parsed[0])
+
+		case bag.Id.Equal(oidPKCS8ShroundedKeyBag), bag.Id.Equal(oidKeyBag):
+			if privateKey != nil {
+				return nil, nil, nil, errors.New("pkcs12: expected exactly one key bag")
+			}
+
+			if bag.Id.Equal(oidKeyBag) {
+				privateKey, err = x509.ParsePKCS8PrivateKey(bag.Value.Bytes)
+				if err != nil {
+					return nil, nil, nil, errors.New("pkcs12: error parsing PKCS#8 private key: " + err.Error())
+				}
+			} else if privateKey, err = decodePkcs8ShroudedKeyBag(bag.Value.Bytes, encodedPassword); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+
+	if privateKey == nil {
+		return nil, nil, nil, errors.New("pkcs12: private key missing")
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, nil, errors.New("pkcs12: unsupported private key type")
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, nil, nil, errors.New("pkcs12: unsupported private key type")
+	}
+
+	for _, cert := range certs {
+		if certificate == nil && pub.Equal(cert.PublicKey) {
+			certificate = cert
+			continue
+		}
+		caCerts = append(caCerts, cert)
+	}
+	if certificate == nil {
+		return nil, nil, nil, errors.New("pkcs12: certificate missing")
+	}
+
+	return privateKey, certificate, caCerts, nil
+}
+
+// DecodeTrustStore extracts the certificates from pfxData, which must be a
+// DER-encoded PKCS #12 file containing exclusively certificates, such as a
+// Java trust store written with EncodeTrustStore or keytool.
+func DecodeTrustStore(pfxData []byte, password string) (certs []*x509.Certificate, err error) {
+	encodedPassword, err := bmpString(password)
+	if err != nil {
+		return nil, err
+	}
+
+	bags, _, err := getSafeContents(pfxData, encodedPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bag := range bags {
+		if !bag.Id.Equal(oidCertBag) {
+			return nil, errors.New("pkcs12: expected only certificate bags")
+		}
+		certsData, err := decodeCertBag(bag.Value.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := x509.ParseCertificates(certsData)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, parsed...)
+	}
+	return certs, nil
+}
+
+func getSafeContents(p12Data, password []byte) (bags []safeBag, updatedPassword []byte, err error) {
+	pfx := new(pfxPdu)
+	if err := unmarshal(p12Data, pfx); err != nil {
+		return nil, nil, errors.New("pkcs12: error reading P12 data: " + err.Error())
+	}
+
+	if pfx.Version != 3 {
+		return nil, nil, NotImplementedError("can only decode v3 PFX PDU's")
+	}
+
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, nil, NotImplementedError("only password-protected PFX is implemented")
+	}
+
+	// unmarshal the explicit bytes in the content for type 'data'
+	if err := unmarshal(pfx.AuthSafe.Content.Bytes, &pfx.AuthSafe.Content); err != nil {
+		return nil, nil, err
+	}
+
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) == 0 {
+		return nil, nil, errors.New("pkcs12: no MAC in data")
+	}
+
+	if err := verifyMac(&pfx.MacData, pfx.AuthSafe.Content.Bytes, password); err != nil {
+		if err == ErrIncorrectPassword && len(password) == 2 && password[0] == 0 && password[1] == 0 {
+			// some implementations use an empty byte array
+			// for the empty string password try one more
+			// time with empty-empty password
+			password = nil
+			err = verifyMac(&pfx.MacData, pfx.AuthSafe.Content.Bytes, password)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var authenticatedSafe []contentInfo
+	if err := unmarshal(pfx.AuthSafe.Content.Bytes, &authenticatedSafe); err != nil {
+		return nil, nil, err
+	}
+
+	for _, ci := range authenticatedSafe {
+		var data []byte
+
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if err := unmarshal(ci.Content.Bytes, &data); err != nil {
+				return nil, nil, err
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var encryptedData encryptedData
+			if err := unmarshal(ci.Content.Bytes, &encryptedData); err != nil {
+				return nil, nil, err
+			}
+			if encryptedData.Version != 0 {
+				return nil, nil, NotImplementedError("only version 0 of EncryptedData is supported")
+			}
+			if data, err = pbDecrypt(encryptedData.EncryptedContentInfo, password); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, NotImplementedError("only data and encryptedData content types are supported in authenticated safe")
+		}
+
+		var safeContents []safeBag
+		if err := unmarshal(data, &safeContents); err != nil {
+			return nil, nil, err
+		}
+		bags = append(bags, safeContents...)
+	}
+
+	return bags, password, nil
+}
+
+// Encode produces pfxData containing one private key (privateKey), an
+// end-entity certificate (certificate), and any number of CA certificates
+// (caCerts). The private key must be one of the types supported by
+// x509.MarshalPKCS8PrivateKey.
+//
+// The private key and certificates are encrypted with PBES2 using PBKDF2
+// with HMAC-SHA-256 and AES-256-CBC, and pfxData is authenticated with
+// HMAC-SHA-256, in each case with DefaultIterations iterations. These are
+// the defaults of OpenSSL 3, and are understood by Java 12 and later and by
+// Windows Server 2019 and later. The salts and IVs are read from rand.
+//
+// The password must contain only characters from the Unicode Basic
+// Multilingual Plane.
+func Encode(rand io.Reader, privateKey any, certificate *x509.Certificate, caCerts []*x509.Certificate, password string) (pfxData []byte, err error) {
+	encodedPassword, err := bmpString(password)
+	if err != nil {
+		return nil, err
+	}
+	if certificate == nil {
+		return nil, errors.New("pkcs12: certificate missing")
+	}
+
+	// The localKeyId attribute links the key to its certificate, which is
+	// required by Windows and Java to pair them up on import.
+	keyID := sha1.Sum(certificate.Raw)
+	attributes, err := localKeyIDAttributes(keyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	certBags := make([]safeBag, 0, 1+len(caCerts))
+	bag, err := makeCertBag(certificate.Raw, attributes)
+	if err != nil {
+		return nil, err
+	}
+	certBags = append(certBags, *bag)
+	for _, cert := range caCerts {
+		bag, err := makeCertBag(cert.Raw, nil)
+		if err != nil {
+			return nil, err
+		}
+		certBags = append(certBags, *bag)
+	}
+
+	keyBag := safeBag{
+		Id:         oidPKCS8ShroundedKeyBag,
+		Attributes: attributes,
+	}
+	keyBag.Value.Class = asn1.ClassContextSpecific
+	keyBag.Value.Tag = 0
+	keyBag.Value.IsCompound = true
+	if keyBag.Value.Bytes, err = encodePkcs8ShroudedKeyBag(rand, privateKey, password, DefaultIterations); err != nil {
+		return nil, err
+	}
+
+	var authenticatedSafe [2]contentInfo
+	if authenticatedSafe[0], err = makeSafeContents(rand, certBags, password); err != nil {
+		return nil, err
+	}
+	if authenticatedSafe[1], err = makeSafeContents(nil, []safeBag{keyBag}, ""); err != nil {
+		return nil, err
+	}
+
+	return makePfx(rand, authenticatedSafe[:], encodedPassword)
+}
+
+// EncodeTrustStore produces pfxData containing any number of CA
+// certificates (certs) to be trusted. The certificates are marked as
+// trusted for any purpose by Java, which does not otherwise treat
+// certificates without a private key as trust anchors.
+//
+// The algorithms and requirements on password are the same as for Encode.
+func EncodeTrustStore(rand io.Reader, certs []*x509.Certificate, password string) (pfxData []byte, err error) {
+	encodedPassword, err := bmpString(password)
+	if err != nil {
+		return nil, err
+	}
+
+	trustValue, err := asn1.Marshal(oidAnyExtendedKeyUsage)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pkcs12Attribute{{
+		Id: oidJavaTrustStore,
+		Value: asn1.RawValue{
+			Tag:        asn1.TagSet,
+			IsCompound: true,
+			Bytes:      trustValue,
+		},
+	}}
+
+	certBags := make([]safeBag, 0, len(certs))
+	for _, cert := range certs {
+		bag, err := makeCertBag(cert.Raw, attributes)
+		if err != nil {
+			return nil, err
+		}
+		certBags = append(certBags, *bag)
+	}
+
+	ci, err := makeSafeContents(rand, certBags, password)
+	if err != nil {
+		return nil, err
+	}
+	return makePfx(rand, []contentInfo{ci}, encodedPassword)
+}
+
+func localKeyIDAttributes(keyID []byte) ([]pkcs12Attribute, error) {
+	value, err := asn1.Marshal(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return []pkcs12Attribute{{
+		Id: oidLocalKeyID,
+		Value: asn1.RawValue{
+			Tag:        asn1.TagSet,
+			IsCompound: true,
+			Bytes:      value,
+		},
+	}}, nil
+}
+
+func makeCertBag(certBytes []byte, attributes []pkcs12Attribute) (*safeBag, error) {
+	bag := &safeBag{
+		Id:         oidCertBag,
+		Attributes: attributes,
+	}
+	bag.Value.Class = asn1.ClassContextSpecific
+	bag.Value.Tag = 0
+	bag.Value.IsCompound = true
+	var err error
+	if bag.Value.Bytes, err = encodeCertBag(certBytes); err != nil {
+		return nil, err
+	}
+	return bag, nil
+}
+
+// makeSafeContents returns a contentInfo holding bags. If rand is nil, the
+// bags are stored as plain data, otherwise they are encrypted with password.
+func makeSafeContents(rand io.Reader, bags []safeBag, password string) (ci contentInfo, err error) {
+	data, err := asn1.Marshal(bags)
+	if err != nil {
+		return ci, err
+	}
+
+	if rand == nil {
+		ci.ContentType = oidDataContentType
+		ci.Content.Bytes, err = asn1.Marshal(data)
+	} else {
+		var ed encryptedData
+		ed.EncryptedContentInfo.ContentType = oidDataContentType
+		ed.EncryptedContentInfo.ContentEncryptionAlgorithm, ed.EncryptedContentInfo.EncryptedContent, err = pbEncrypt(rand, data, password, DefaultIterations)
+		if err != nil {
+			return ci, err
+		}
+		ci.ContentType = oidEncryptedDataContentType
+		ci.Content.Bytes, err = asn1.Marshal(ed)
+	}
+	if err != nil {
+		return ci, err
+	}
+	ci.Content.Class = asn1.ClassContextSpecific
+	ci.Content.Tag = 0
+	ci.Content.IsCompound = true
+	return ci, nil
+}
+
+func makePfx(rand io.Reader, authenticatedSafe []contentInfo, password []byte) ([]byte, error) {
+	authenticatedSafeBytes, err := asn1.Marshal(authenticatedSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	var pfx pfxPdu
+	pfx.Version = 3
+	pfx.AuthSafe.ContentType = oidDataContentType
+	pfx.AuthSafe.Content.Class = asn1.ClassContextSpecific
+	pfx.AuthSafe.Content.Tag = 0
+	pfx.AuthSafe.Content.IsCompound = true
+	if pfx.AuthSafe.Content.Bytes, err = asn1.Marshal(authenticatedSafeBytes); err != nil {
+		return nil, err
+	}
+
+	mac, err := newMac(rand, authenticatedSafeBytes, password, DefaultIterations)
+	if err != nil {
+		return nil, err
+	}
+	pfx.MacData = *mac
+
+	return asn1.Marshal(pfx)
+}
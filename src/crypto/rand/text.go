@@ -0,0 +1,191 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rand
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	base32Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	hexAlphabet    = "0123456789abcdef"
+)
+
+// Text returns a random string of n characters from the standard base32
+// alphabet (A-Z and 2-7), each carrying five bits of randomness. A 26
+// character string carries more than 128 bits, enough for session tokens
+// and other secrets that must not be guessed.
+//
+// Text reads from Reader and panics if it fails, or if n is negative.
+func Text(n int) string {
+	if n < 0 {
+		panic("crypto/rand: negative length passed to Text")
+	}
+	b := make([]byte, n)
+	mustRead(b)
+	for i := range b {
+		// 32 divides 256, so the low five bits are uniform.
+		b[i] = base32Alphabet[b[i]%32]
+	}
+	return string(b)
+}
+
+// Hex returns n random bytes from Reader encoded as 2*n lowercase
+// hexadecimal digits.
+//
+// Hex panics if reading from Reader fails, or if n is negative.
+func Hex(n int) string {
+	if n < 0 {
+		panic("crypto/rand: negative length passed to Hex")
+	}
+	b := make([]byte, 2*n)
+	mustRead(b[n:])
+	for i, v := range b[n:] {
+		b[2*i] = hexAlphabet[v>>4]
+		b[2*i+1] = hexAlphabet[v&0x0f]
+	}
+	return string(b)
+}
+
+func mustRead(b []byte) {
+	if _, err := io.ReadFull(Reader, b); err != nil {
+		panic("crypto/rand: failed to read random data: " + err.Error())
+	}
+}
+
+// Character classes used by Password.
+const (
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// A PasswordPolicy describes the passwords generated by Password.
+type PasswordPolicy struct {
+	// Length is the number of characters. If zero, 16 is used.
+	Length int
+
+	// Lower, Upper, Digits, and Symbols select the character classes
+	// to use: ASCII lowercase and uppercase letters, decimal digits, and
+	// ASCII punctuation. Every selected class appears at least once.
+	// If none is selected, all four are.
+	Lower, Upper, Digits, Symbols bool
+
+	// Exclude lists characters that are never used, for example
+	// look-alikes such as "0O1lI".
+	Exclude string
+}
+
+// Password returns a random password satisfying policy. Every password
+// satisfying the policy is equally likely.
+//
+// Password returns an error if the policy cannot be satisfied or if
+// reading from Reader fails.
+func Password(policy PasswordPolicy) (string, error) {
+	length := policy.Length
+	if length == 0 {
+		length = 16
+	}
+	if length < 0 {
+		return "", errors.New("crypto/rand: negative password length")
+	}
+
+	all := !policy.Lower && !policy.Upper && !policy.Digits && !policy.Symbols
+	var classes []string
+	for _, c := range []struct {
+		selected bool
+		chars    string
+	}{
+		{policy.Lower, passwordLower},
+		{policy.Upper, passwordUpper},
+		{policy.Digits, passwordDigits},
+		{policy.Symbols, passwordSymbols},
+	} {
+		if !c.selected && !all {
+			continue
+		}
+		chars := excludeChars(c.chars, policy.Exclude)
+		if chars == "" {
+			return "", errors.New("crypto/rand: password policy excludes an entire character class")
+		}
+		classes = append(classes, chars)
+	}
+	if length < len(classes) {
+		return "", errors.New("crypto/rand: password length too short for the required character classes")
+	}
+	alphabet := strings.Join(classes, "")
+
+	// Draw uniformly from the alphabet and reject passwords missing a
+	// class, which keeps the result uniform over all valid passwords.
+	r := &uniformReader{}
+	b := make([]byte, length)
+	for {
+		for i := range b {
+			j, err := r.intn(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			b[i] = alphabet[j]
+		}
+		if hasEveryClass(b, classes) {
+			return string(b), nil
+		}
+	}
+}
+
+func excludeChars(chars, exclude string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(exclude, r) {
+			return -1
+		}
+		return r
+	}, chars)
+}
+
+func hasEveryClass(b []byte, classes []string) bool {
+	for _, c := range classes {
+		found := false
+		for _, v := range b {
+			if strings.IndexByte(c, v) >= 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// uniformReader draws unbiased small integers from Reader, buffering
+// reads to avoid a system call per character.
+type uniformReader struct {
+	buf [64]byte
+	off int
+}
+
+// intn returns a uniform value in [0, n), for 0 < n <= 256.
+func (r *uniformReader) intn(n int) (int, error) {
+	// Reject bytes at or above the largest multiple of n, so that
+	// every residue is equally likely.
+	limit := 256 - 256%n
+	for {
+		if r.off == 0 || r.off == len(r.buf) {
+			if _, err := io.ReadFull(Reader, r.buf[:]); err != nil {
+				return 0, err
+			}
+			r.off = 0
+		}
+		v := int(r.buf[r.off])
+		r.off++
+		if v < limit {
+			return v % n, nil
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rand_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	seen := make(map[rune]bool)
+	for _, n := range []int{0, 1, 26, 1000} {
+		s := rand.Text(n)
+		if len(s) != n {
+			t.Errorf("len(Text(%d)) = %d", n, len(s))
+		}
+		for _, r := range s {
+			if !strings.ContainsRune(alphabet, r) {
+				t.Fatalf("Text(%d) = %q contains %q", n, s, r)
+			}
+			seen[r] = true
+		}
+	}
+	if len(seen) != len(alphabet) {
+		t.Errorf("Text used %d of %d characters", len(seen), len(alphabet))
+	}
+	if rand.Text(26) == rand.Text(26) {
+		t.Error("Text returned the same string twice")
+	}
+}
+
+func TestHex(t *testing.T) {
+	for _, n := range []int{0, 1, 16, 100} {
+		s := rand.Hex(n)
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("Hex(%d) = %q: %v", n, s, err)
+		}
+		if len(b) != n || strings.ToLower(s) != s {
+			t.Errorf("Hex(%d) = %q", n, s)
+		}
+	}
+}
+
+func TestTextReaderFailure(t *testing.T) {
+	old := rand.Reader
+	rand.Reader = failingReader{}
+	defer func() {
+		rand.Reader = old
+		if recover() == nil {
+			t.Error("Text did not panic when Reader failed")
+		}
+	}()
+	rand.Text(10)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("failing reader") }
+
+func TestPassword(t *testing.T) {
+	classes := []string{
+		"abcdefghijklmnopqrstuvwxyz",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"0123456789",
+		"!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+	}
+	for _, tt := range []struct {
+		policy rand.PasswordPolicy
+		length int
+		want   []string
+	}{
+		{rand.PasswordPolicy{}, 16, classes},
+		{rand.PasswordPolicy{Length: 4}, 4, classes},
+		{rand.PasswordPolicy{Length: 40, Digits: true}, 40, classes[2:3]},
+		{rand.PasswordPolicy{Length: 8, Lower: true, Upper: true}, 8, classes[:2]},
+	} {
+		for i := 0; i < 20; i++ {
+			p, err := rand.Password(tt.policy)
+			if err != nil {
+				t.Fatalf("Password(%+v): %v", tt.policy, err)
+			}
+			if len(p) != tt.length {
+				t.Errorf("Password(%+v) = %q, want length %d", tt.policy, p, tt.length)
+			}
+			for _, c := range tt.want {
+				if !strings.ContainsAny(p, c) {
+					t.Errorf("Password(%+v) = %q, missing one of %q", tt.policy, p, c)
+				}
+			}
+			if strings.ContainsFunc(p, func(r rune) bool {
+				return !strings.ContainsRune(strings.Join(tt.want, ""), r)
+			}) {
+				t.Errorf("Password(%+v) = %q, has characters outside its classes", tt.policy, p)
+			}
+		}
+	}
+}
+
+func TestPasswordExclude(t *testing.T) {
+	p, err := rand.Password(rand.PasswordPolicy{Length: 200, Exclude: "0O1lI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(p, "0O1lI") {
+		t.Errorf("Password = %q, contains excluded characters", p)
+	}
+}
+
+func TestPasswordInvalidPolicy(t *testing.T) {
+	for _, policy := range []rand.PasswordPolicy{
+		{Length: -1},
+		{Length: 3},
+		{Length: 1, Lower: true, Digits: true},
+		{Digits: true, Exclude: "0123456789"},
+	} {
+		if p, err := rand.Password(policy); err == nil {
+			t.Errorf("Password(%+v) = %q, want error", policy, p)
+		}
+	}
+}
@@ -11,6 +11,7 @@ package rc4
 
 import (
 	"crypto/internal/alias"
+	"crypto/internal/boring"
 	"strconv"
 )
 
@@ -65,6 +66,7 @@ func (c *Cipher) XORKeyStream(dst, src []byte) {
 	if alias.InexactOverlap(dst[:len(src)], src) {
 		panic("crypto/rc4: invalid buffer overlap")
 	}
+	boring.SetNotApproved()
 	i, j := c.i, c.j
 	_ = dst[len(src)-1]
 	dst = dst[:len(src)] // eliminate bounds check from loop
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build boringcrypto
+//go:build boringcrypto || goexperiment.opensslcrypto || goexperiment.cngcrypto || goexperiment.darwincrypto
 
 package rsa
 
@@ -25,6 +25,13 @@ import (
 // alongside the cached BoringCrypto key and check that the real key
 // still matches before using the cached key. The theory is that the real
 // operations are significantly more expensive than the comparison.
+//
+// The caches are cleared at the start of every garbage collection, after
+// which each key is converted again on its next use. To avoid this cost,
+// which is significant for large keys, GenerateKey attaches the
+// BoringCrypto key to the keys it returns, in Precomputed.boring, where it
+// lives as long as the PrivateKey itself. The attached key is checked
+// against the original values in the same way as the cached keys.
 
 type boringPub struct {
 	key  *boring.PublicKeyRSA
@@ -62,12 +69,35 @@ type boringPriv struct {
 }
 
 func boringPrivateKey(priv *PrivateKey) (*boring.PrivateKeyRSA, error) {
+	if b := priv.Precomputed.boring; b != nil && privateKeyEqual(&b.orig, priv) {
+		return b.key, nil
+	}
 	b := privCache.Get(priv)
 	if b != nil && privateKeyEqual(&b.orig, priv) {
 		return b.key, nil
 	}
 
-	b = new(boringPriv)
+	b, err := newBoringPriv(priv)
+	if err != nil {
+		return nil, err
+	}
+	privCache.Put(priv, b)
+	return b.key, nil
+}
+
+// attachBoringPrivateKey converts priv, which was just generated, and
+// attaches the BoringCrypto key to it.
+func attachBoringPrivateKey(priv *PrivateKey) error {
+	b, err := newBoringPriv(priv)
+	if err != nil {
+		return err
+	}
+	priv.Precomputed.boring = b
+	return nil
+}
+
+func newBoringPriv(priv *PrivateKey) (*boringPriv, error) {
+	b := new(boringPriv)
 	b.orig = copyPrivateKey(priv)
 
 	var N, E, D, P, Q, Dp, Dq, Qinv *big.Int
@@ -86,8 +116,7 @@ func boringPrivateKey(priv *PrivateKey) (*boring.PrivateKeyRSA, error) {
 		return nil, err
 	}
 	b.key = key
-	privCache.Put(priv, b)
-	return key, nil
+	return b, nil
 }
 
 func publicKeyEqual(k1, k2 *PublicKey) bool {
@@ -11,9 +11,12 @@ package rsa
 
 import (
 	"crypto"
+	"crypto/internal/boring"
+	"crypto/internal/difftest"
 	"crypto/rand"
 	"encoding/asn1"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 	"runtime"
 	"runtime/debug"
@@ -78,6 +81,34 @@ func BenchmarkBoringVerify(b *testing.B) {
 	}
 }
 
+// BenchmarkBoringSignAfterGC measures signing right after the cache is
+// cleared, as it is by every garbage collection, with and without the
+// module key attached by GenerateKey.
+func BenchmarkBoringSignAfterGC(b *testing.B) {
+	for _, bits := range []int{2048, 3072, 4096} {
+		k, err := GenerateKey(rand.Reader, bits)
+		if err != nil {
+			b.Fatal(err)
+		}
+		detached := *k
+		detached.Precomputed.boring = nil
+		hashed := make([]byte, 32)
+		for _, tt := range []struct {
+			name string
+			k    *PrivateKey
+		}{{"Attached", k}, {"Cached", &detached}} {
+			b.Run(fmt.Sprintf("%s/%d", tt.name, bits), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					privCache.Clear()
+					if _, err := SignPKCS1v15(rand.Reader, tt.k, crypto.SHA256, hashed); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
 func TestBoringGenerateKey(t *testing.T) {
 	k, err := GenerateKey(rand.Reader, 2048) // 2048 is smallest size BoringCrypto might kick in for
 	if err != nil {
@@ -90,6 +121,47 @@ func TestBoringGenerateKey(t *testing.T) {
 	}
 }
 
+func TestBoringGenerateKeyAttached(t *testing.T) {
+	k, err := GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Precomputed.boring == nil {
+		t.Fatal("GenerateKey did not attach a BoringCrypto key")
+	}
+	attached := k.Precomputed.boring.key
+
+	// The attached key survives garbage collections.
+	runtime.GC()
+	privCache.Clear()
+	bk, err := boringPrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk != attached {
+		t.Error("boringPrivateKey did not use the attached key")
+	}
+
+	// But it is not used once the key is modified.
+	k2, err := GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.PublicKey = k2.PublicKey
+	k.D = k2.D
+	k.Primes = k2.Primes
+	k.Precomputed.Dp = k2.Precomputed.Dp
+	k.Precomputed.Dq = k2.Precomputed.Dq
+	k.Precomputed.Qinv = k2.Precomputed.Qinv
+	bk, err = boringPrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bk == attached {
+		t.Error("boringPrivateKey used the attached key of a modified key")
+	}
+}
+
 func TestBoringFinalizers(t *testing.T) {
 	if runtime.GOOS == "nacl" || runtime.GOOS == "js" {
 		// Times out on nacl and js/wasm (without BoringCrypto)
@@ -102,6 +174,8 @@ func TestBoringFinalizers(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	// Use the cache, rather than the key attached by GenerateKey.
+	k.Precomputed.boring = nil
 
 	// Run test with GOGC=10, to make bug more likely.
 	// Without the KeepAlives, the loop usually dies after
@@ -131,6 +205,43 @@ func TestBoringFinalizers(t *testing.T) {
 	}
 }
 
+func FuzzBackend(f *testing.F) {
+	defer boring.AllowGoForTesting()()
+	priv, err := GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		f.Fatal(err)
+	}
+	bpub, err := boringPublicKey(&priv.PublicKey)
+	if err != nil {
+		f.Fatal(err)
+	}
+	bpriv, err := boringPrivateKey(priv)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add([]byte("abc"))
+	f.Add(make([]byte, 256))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Build an input of the modulus size that is smaller than the modulus.
+		in := make([]byte, priv.Size())
+		copy(in[1:], data)
+		if err := difftest.Func("RSA encryption", func(in []byte) ([]byte, error) {
+			return encrypt(&priv.PublicKey, in)
+		}, func(in []byte) ([]byte, error) {
+			return boring.EncryptRSANoPadding(bpub, in)
+		}, in); err != nil {
+			t.Fatal(err)
+		}
+		if err := difftest.Func("RSA decryption", func(in []byte) ([]byte, error) {
+			return decrypt(priv, in, withCheck)
+		}, func(in []byte) ([]byte, error) {
+			return boring.DecryptRSANoPadding(bpriv, in)
+		}, in); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
 func bigFromHex(hex string) *big.Int {
 	n, ok := new(big.Int).SetString(hex, 16)
 	if !ok {
@@ -0,0 +1,154 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rsa
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+	_ "unsafe" // for linkname
+)
+
+// ErrRateLimited is returned by private key operations that were rejected
+// by the Limiter installed with SetLimiter.
+var ErrRateLimited = errors.New("crypto/rsa: private key operation rejected by rate limiter")
+
+// A Limiter bounds the rate of private key operations, that is decryption
+// and signing, which are the most expensive RSA operations. It prevents a
+// flood of requests, such as TLS handshakes, from starving the rest of the
+// program.
+//
+// A Limiter enforces a global limit, shared by all keys, and optionally a
+// limit for each key. Both are token buckets: an operation consumes a
+// token, and tokens are replenished at a fixed rate up to a maximum burst.
+//
+// Rejected operations are counted by the /crypto/rsa/limited:calls metric
+// of runtime/metrics.
+type Limiter struct {
+	mu     sync.Mutex
+	global bucket
+	key    bucket // template for per-key buckets
+	keys   map[*PrivateKey]*bucket
+}
+
+// maxIdleKeys is the number of per-key buckets from which the Limiter
+// starts dropping full buckets, which hold no state.
+const maxIdleKeys = 64
+
+// NewLimiter returns a Limiter that allows rate operations per second
+// overall, with bursts of up to burst operations. If rate is zero or
+// negative, the global limit is disabled.
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{global: newBucket(rate, burst)}
+}
+
+// SetKeyLimit sets a limit of rate operations per second for each key,
+// with bursts of up to burst operations, in addition to the global limit.
+// If rate is zero or negative, the per-key limit is disabled.
+func (l *Limiter) SetKeyLimit(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.key = newBucket(rate, burst)
+	l.keys = nil
+}
+
+// Allow reports whether a private key operation with priv may proceed,
+// and if so consumes a token from the global and the per-key buckets.
+func (l *Limiter) Allow(priv *PrivateKey) bool {
+	return l.allow(priv, time.Now())
+}
+
+func (l *Limiter) allow(priv *PrivateKey, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.global.fill(now) {
+		return false
+	}
+	if l.key.rate <= 0 {
+		l.global.tokens--
+		return true
+	}
+	kb := l.keys[priv]
+	if kb == nil {
+		if len(l.keys) >= maxIdleKeys {
+			for k, b := range l.keys {
+				if b.fill(now) && b.tokens >= b.burst {
+					delete(l.keys, k)
+				}
+			}
+		}
+		if l.keys == nil {
+			l.keys = make(map[*PrivateKey]*bucket)
+		}
+		kb = new(bucket)
+		*kb = l.key
+		l.keys[priv] = kb
+	}
+	if !kb.fill(now) {
+		return false
+	}
+	l.global.tokens--
+	kb.tokens--
+	return true
+}
+
+// A bucket is a token bucket. If rate is zero or negative, it is unlimited.
+type bucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) bucket {
+	return bucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// fill replenishes b up to now and reports whether a token is available.
+func (b *bucket) fill(now time.Time) bool {
+	if b.rate <= 0 {
+		return true
+	}
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	return b.tokens >= 1
+}
+
+var limiter atomic.Pointer[Limiter]
+
+// SetLimiter installs l to limit the private key operations of this
+// package, including those performed through the crypto.Signer and
+// crypto.Decrypter interfaces, which then fail with ErrRateLimited when l
+// rejects them. If l is nil, private key operations are not limited.
+func SetLimiter(l *Limiter) {
+	limiter.Store(l)
+}
+
+var limited atomic.Uint64
+
+func init() {
+	registerMetric("/crypto/rsa/limited:calls", limited.Load)
+}
+
+// registerMetric is provided by package runtime.
+//
+//go:linkname registerMetric
+func registerMetric(name string, read func() uint64)
+
+// checkLimit returns ErrRateLimited if the installed Limiter rejects a
+// private key operation with priv.
+func checkLimit(priv *PrivateKey) error {
+	if l := limiter.Load(); l != nil && !l.Allow(priv) {
+		limited.Add(1)
+		return ErrRateLimited
+	}
+	return nil
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build !boringcrypto
+//go:build !boringcrypto && !goexperiment.opensslcrypto && !goexperiment.cngcrypto && !goexperiment.darwincrypto
 
 package rsa
 
@@ -14,3 +14,8 @@ func boringPublicKey(*PublicKey) (*boring.PublicKeyRSA, error) {
 func boringPrivateKey(*PrivateKey) (*boring.PrivateKeyRSA, error) {
 	panic("boringcrypto: not available")
 }
+func attachBoringPrivateKey(*PrivateKey) error {
+	panic("boringcrypto: not available")
+}
+
+type boringPriv struct{}
@@ -51,7 +51,10 @@ func EncryptPKCS1v15(random io.Reader, pub *PublicKey, msg []byte) ([]byte, erro
 		if err != nil {
 			return nil, err
 		}
-		return boring.EncryptRSAPKCS1(bkey, msg)
+		out, err := boring.EncryptRSAPKCS1(bkey, msg)
+		// PKCS #1 v1.5 encryption is not approved by SP 800-131A.
+		boring.SetNotApproved()
+		return out, err
 	}
 	boring.UnreachableExceptTests()
 
@@ -72,7 +75,9 @@ func EncryptPKCS1v15(random io.Reader, pub *PublicKey, msg []byte) ([]byte, erro
 		if err != nil {
 			return nil, err
 		}
-		return boring.EncryptRSANoPadding(bkey, em)
+		out, err := boring.EncryptRSANoPadding(bkey, em)
+		boring.SetNotApproved()
+		return out, err
 	}
 
 	return encrypt(pub, em)
@@ -90,6 +95,9 @@ func DecryptPKCS1v15(random io.Reader, priv *PrivateKey, ciphertext []byte) ([]b
 	if err := checkPub(&priv.PublicKey); err != nil {
 		return nil, err
 	}
+	if err := checkLimit(priv); err != nil {
+		return nil, err
+	}
 
 	if boring.Enabled {
 		bkey, err := boringPrivateKey(priv)
@@ -97,6 +105,7 @@ func DecryptPKCS1v15(random io.Reader, priv *PrivateKey, ciphertext []byte) ([]b
 			return nil, err
 		}
 		out, err := boring.DecryptRSAPKCS1(bkey, ciphertext)
+		boring.SetNotApproved()
 		if err != nil {
 			return nil, ErrDecryption
 		}
@@ -140,6 +149,9 @@ func DecryptPKCS1v15SessionKey(random io.Reader, priv *PrivateKey, ciphertext []
 	if k-(len(key)+3+8) < 0 {
 		return ErrDecryption
 	}
+	if err := checkLimit(priv); err != nil {
+		return err
+	}
 
 	valid, em, index, err := decryptPKCS1v15(priv, ciphertext)
 	if err != nil {
@@ -177,6 +189,7 @@ func decryptPKCS1v15(priv *PrivateKey, ciphertext []byte) (valid int, em []byte,
 			return
 		}
 		em, err = boring.DecryptRSANoPadding(bkey, ciphertext)
+		boring.SetNotApproved()
 		if err != nil {
 			return
 		}
@@ -277,13 +290,18 @@ func SignPKCS1v15(random io.Reader, priv *PrivateKey, hash crypto.Hash, hashed [
 	if k < tLen+11 {
 		return nil, ErrMessageTooLong
 	}
+	if err := checkLimit(priv); err != nil {
+		return nil, err
+	}
 
 	if boring.Enabled {
 		bkey, err := boringPrivateKey(priv)
 		if err != nil {
 			return nil, err
 		}
-		return boring.SignRSAPKCS1v15(bkey, hash, hashed)
+		sig, err := boring.SignRSAPKCS1v15(bkey, hash, hashed)
+		approve(approvedSigning(priv.N.BitLen(), hash))
+		return sig, err
 	}
 
 	// EM = 0x00 || 0x01 || PS || 0x00 || T
@@ -309,7 +327,9 @@ func VerifyPKCS1v15(pub *PublicKey, hash crypto.Hash, hashed []byte, sig []byte)
 		if err != nil {
 			return err
 		}
-		if err := boring.VerifyRSAPKCS1v15(bkey, hash, hashed, sig); err != nil {
+		err = boring.VerifyRSAPKCS1v15(bkey, hash, hashed, sig)
+		approve(approvedVerifying(pub.N.BitLen(), hash))
+		if err != nil {
 			return ErrVerification
 		}
 		return nil
@@ -9,9 +9,11 @@ package rsa
 import (
 	"bytes"
 	"crypto"
+	"crypto/hmac"
 	"crypto/internal/boring"
 	"errors"
 	"hash"
+	"internal/cryptometrics"
 	"io"
 )
 
@@ -222,6 +224,9 @@ func signPSSWithSalt(priv *PrivateKey, hash crypto.Hash, hashed, salt []byte) ([
 		// Note: BoringCrypto always does decrypt "withCheck".
 		// (It's not just decrypt.)
 		s, err := boring.DecryptRSANoPadding(bkey, em)
+		// The module only performs the RSA operation, and the encoding is
+		// done in Go.
+		boring.SetNotApproved()
 		if err != nil {
 			return nil, err
 		}
@@ -264,6 +269,22 @@ type PSSOptions struct {
 	// zero, it overrides the hash function passed to SignPSS. It's required
 	// when using PrivateKey.Sign.
 	Hash crypto.Hash
+
+	// DeterministicSalt, if true, causes SignPSS to derive the salt from the
+	// private key and the digest instead of reading it from rand, so that
+	// signing the same digest with the same key and options always produces
+	// the same signature, for example for reproducible builds. The
+	// signatures are verified like any other PSS signature.
+	//
+	// The salt is the output of HKDF (RFC 5869) with the hash function, the
+	// private exponent D as a big-endian byte string of the size of the
+	// modulus as the input keying material, the string
+	// "crypto/rsa deterministic PSS salt" as the salt, and the digest as the
+	// info. Without the key it is unpredictable, like a random salt, but
+	// signatures of the same digest can be recognized as such.
+	//
+	// It is ignored by VerifyPSS.
+	DeterministicSalt bool
 }
 
 // HashFunc returns opts.Hash so that PSSOptions implements crypto.SignerOpts.
@@ -286,23 +307,21 @@ var invalidSaltLenErr = errors.New("crypto/rsa: PSSOptions.SaltLength cannot be
 // function. The opts argument may be nil, in which case sensible defaults are
 // used. If opts.Hash is set, it overrides hash.
 func SignPSS(rand io.Reader, priv *PrivateKey, hash crypto.Hash, digest []byte, opts *PSSOptions) ([]byte, error) {
-	if boring.Enabled && rand == boring.RandReader {
-		bkey, err := boringPrivateKey(priv)
-		if err != nil {
-			return nil, err
-		}
-		return boring.SignRSAPSS(bkey, hash, digest, opts.saltLength())
+	if err := checkLimit(priv); err != nil {
+		return nil, err
 	}
-	boring.UnreachableExceptTests()
-
 	if opts != nil && opts.Hash != 0 {
 		hash = opts.Hash
 	}
 
+	// The salt length is resolved before the module is called, so that
+	// PSSSaltLengthAuto means the same maximal salt, and keys that are too
+	// small for the salt fail with the same error, with and without it.
+	emLen := (priv.N.BitLen() - 1 + 7) / 8
 	saltLength := opts.saltLength()
 	switch saltLength {
 	case PSSSaltLengthAuto:
-		saltLength = (priv.N.BitLen()-1+7)/8 - 2 - hash.Size()
+		saltLength = emLen - 2 - hash.Size()
 		if saltLength < 0 {
 			return nil, ErrMessageTooLong
 		}
@@ -315,13 +334,70 @@ func SignPSS(rand io.Reader, priv *PrivateKey, hash crypto.Hash, digest []byte,
 			return nil, invalidSaltLenErr
 		}
 	}
-	salt := make([]byte, saltLength)
-	if _, err := io.ReadFull(rand, salt); err != nil {
-		return nil, err
+	if emLen < hash.Size()+saltLength+2 {
+		return nil, ErrMessageTooLong
+	}
+
+	deterministic := opts != nil && opts.DeterministicSalt
+	if boring.Enabled && rand == boring.RandReader && !deterministic {
+		if boring.SupportsRSAPSSSaltLength(hash, saltLength) {
+			bkey, err := boringPrivateKey(priv)
+			if err != nil {
+				return nil, err
+			}
+			sig, err := boring.SignRSAPSS(bkey, hash, digest, saltLength)
+			approve(approvedSigning(priv.N.BitLen(), hash))
+			return sig, err
+		}
+		boring.RecordFallback(cryptometrics.RSA, "unsupported PSS salt length")
+	} else {
+		boring.UnreachableExceptTests()
+	}
+
+	var salt []byte
+	if deterministic {
+		var err error
+		salt, err = deterministicSalt(priv, hash, digest, saltLength)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		salt = make([]byte, saltLength)
+		if _, err := io.ReadFull(rand, salt); err != nil {
+			return nil, err
+		}
 	}
 	return signPSSWithSalt(priv, hash, digest, salt)
 }
 
+// deterministicSalt returns the salt documented on PSSOptions.DeterministicSalt.
+// HKDF can't produce more than 255 times the size of the hash, so longer salts
+// are rejected.
+func deterministicSalt(priv *PrivateKey, hash crypto.Hash, digest []byte, saltLength int) ([]byte, error) {
+	if saltLength > 255*hash.Size() {
+		return nil, errors.New("crypto/rsa: deterministic PSS salt longer than 255 hash outputs")
+	}
+	d := priv.D.FillBytes(make([]byte, priv.Size()))
+
+	// HKDF-Extract and HKDF-Expand, RFC 5869, Section 2.
+	extract := hmac.New(hash.New, []byte("crypto/rsa deterministic PSS salt"))
+	extract.Write(d)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(hash.New, prk)
+	salt := make([]byte, 0, saltLength+expand.Size())
+	var t []byte
+	for i := 1; len(salt) < saltLength; i++ {
+		expand.Reset()
+		expand.Write(t)
+		expand.Write(digest)
+		expand.Write([]byte{byte(i)})
+		t = expand.Sum(t[:0])
+		salt = append(salt, t...)
+	}
+	return salt[:saltLength], nil
+}
+
 // VerifyPSS verifies a PSS signature.
 //
 // A valid signature is indicated by returning a nil error. digest must be the
@@ -329,12 +405,20 @@ func SignPSS(rand io.Reader, priv *PrivateKey, hash crypto.Hash, digest []byte,
 // argument may be nil, in which case sensible defaults are used. opts.Hash is
 // ignored.
 func VerifyPSS(pub *PublicKey, hash crypto.Hash, digest []byte, sig []byte, opts *PSSOptions) error {
+	// Salt length must be either one of the special constants (-1 or 0)
+	// or otherwise positive. If it is < PSSSaltLengthEqualsHash (-1)
+	// we return an error.
+	if opts.saltLength() < PSSSaltLengthEqualsHash {
+		return invalidSaltLenErr
+	}
 	if boring.Enabled {
 		bkey, err := boringPublicKey(pub)
 		if err != nil {
 			return err
 		}
-		if err := boring.VerifyRSAPSS(bkey, hash, digest, sig, opts.saltLength()); err != nil {
+		err = boring.VerifyRSAPSS(bkey, hash, digest, sig, opts.saltLength())
+		approve(approvedVerifying(pub.N.BitLen(), hash))
+		if err != nil {
 			return ErrVerification
 		}
 		return nil
@@ -342,12 +426,6 @@ func VerifyPSS(pub *PublicKey, hash crypto.Hash, digest []byte, sig []byte, opts
 	if len(sig) != pub.Size() {
 		return ErrVerification
 	}
-	// Salt length must be either one of the special constants (-1 or 0)
-	// or otherwise positive. If it is < PSSSaltLengthEqualsHash (-1)
-	// we return an error.
-	if opts.saltLength() < PSSSaltLengthEqualsHash {
-		return invalidSaltLenErr
-	}
 
 	emBits := pub.N.BitLen() - 1
 	emLen := (emBits + 7) / 8
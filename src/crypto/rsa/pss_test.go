@@ -189,6 +189,79 @@ func TestPSSOpenSSL(t *testing.T) {
 	}
 }
 
+// TestPSSOpenSSLSaltLengths checks that signatures made by OpenSSL with
+// different salt lengths verify with the matching salt length options.
+func TestPSSOpenSSLSaltLengths(t *testing.T) {
+	hashed := sha256.Sum256([]byte("testing"))
+
+	// Generated with `echo -n testing | openssl dgst -sha256 -sign key.pem
+	// -sigopt rsa_padding_mode:pss -sigopt rsa_pss_saltlen:<saltlen>`, where
+	// key.pem is test2048Key, a 2047-bit key.
+	for _, tt := range []struct {
+		saltLen string
+		sig     string
+		good    []int // salt length options that accept the signature
+		bad     []int // salt length options that reject it
+	}{
+		{
+			"digest",
+			"658c241ebe127225f1368a790654c5437a78486f925f131da0736cebeff8f0c2" +
+				"b4a6713f9a9914e4c13084f3216540d5435324c72bfd04d7ea5c362f65b346e3" +
+				"be9450715be7aaf9718b08f1fa034ac4b89db746110fc46d25d94ede9c1cd0ec" +
+				"3e9d3be935b6106763737cd4581fbced9ce88142a2c3fe4f2ad01e5fd70db7a0" +
+				"356c66e7539a4fadc4108308c1b8c1aefabe49b9d52250ded0a7352962e8f64f" +
+				"7d006903b70722593c62b5c91f390ab1d98773decbf0b92614879eb714aaf6c4" +
+				"c012e07a0e603b9fff3b05d220319165afd44ffd3b132117693aaaf7cbfed69f" +
+				"ddb8e78788262eda64191e95cd02ed80e00f979542d664843a16fff94ed84199",
+			[]int{PSSSaltLengthAuto, PSSSaltLengthEqualsHash, 32},
+			[]int{10, 222},
+		},
+		{
+			"10",
+			"6b7d27dcf76dc29e7641864b929b1ee55e6bcd3011dbafcd7f992611d2b1e7a2" +
+				"26de17dd030b99b3b83f653a3ba9949d269bf27ff1886ee12a2b485fc55a3151" +
+				"e814849cf5271d41cd6c8881121beb3278e79e4906d270b04a883d81c3605386" +
+				"948b825ab5c1a4fa7017f52b19b1b61a548972648f5c3a0d8730bd2f5ee3cfcd" +
+				"fa6842cfcb296ef69ade29a50467ff32375ad50d9e1ff5b7f85bed894aa4baa9" +
+				"5264310d25d22813c21c912ad038c3abc35e778a53420cc63e14fbda88cc551b" +
+				"e4075e5bc61c5f3b4d3d4994f5624e72127eeaf932f8fe8dd75cbc8b574e03aa" +
+				"b6f7ed5720d28e532735f1df1bde46d4b8f521897e67e2f6ff5ef7b17687fc3e",
+			[]int{PSSSaltLengthAuto, 10},
+			[]int{PSSSaltLengthEqualsHash, 11, 222},
+		},
+		{
+			"max",
+			"4c3f37a43d6940ab5cc4d921faa7008a612cad1d4209ba99e7353369d03c1585" +
+				"b9893b4ac7546924efe1d183d57a7546aa36e6bea20174c3005e2e8de6c53226" +
+				"696dfc69c9ac032a9016226f8a9467712fea07e4b11907148514e76c729dbd68" +
+				"c9cd4c3c97bdad1ad8a7634d2b4f1d934c6c931aa64a20cc20f4b2383181817d" +
+				"e14e2acadfd2d49ad2d0e26a1d1a644006f3a9073a9fc6f9c4b8c9174c5b0b43" +
+				"4ab49055635db85a2e8f7646e74f44aec60a838eda3ad199d5e87563d25f05c7" +
+				"47c376c3c340971616f65e972d6abaae800cf4ddf09a435b5a921447bdcffcec" +
+				"4e49a8956ed17ea277f9dfb5fa102928624326395c0709f0978255b069cf036b",
+			[]int{PSSSaltLengthAuto, 222},
+			[]int{PSSSaltLengthEqualsHash, 221},
+		},
+	} {
+		sig, err := hex.DecodeString(tt.sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, saltLen := range tt.good {
+			opts := &PSSOptions{SaltLength: saltLen}
+			if err := VerifyPSS(&test2048Key.PublicKey, crypto.SHA256, hashed[:], sig, opts); err != nil {
+				t.Errorf("saltlen:%s: VerifyPSS with SaltLength %d: %v", tt.saltLen, saltLen, err)
+			}
+		}
+		for _, saltLen := range tt.bad {
+			opts := &PSSOptions{SaltLength: saltLen}
+			if err := VerifyPSS(&test2048Key.PublicKey, crypto.SHA256, hashed[:], sig, opts); err == nil {
+				t.Errorf("saltlen:%s: VerifyPSS with SaltLength %d succeeded", tt.saltLen, saltLen)
+			}
+		}
+	}
+}
+
 func TestPSSNilOpts(t *testing.T) {
 	hash := crypto.SHA256
 	h := hash.New()
@@ -236,6 +309,94 @@ func TestPSSSigning(t *testing.T) {
 	}
 }
 
+// TestPSSSaltLengths checks that signatures made with each salt length
+// option use that salt length, with the 2047-bit test2048Key, for which the
+// maximal salt with SHA-256 is 222 bytes.
+func TestPSSSaltLengths(t *testing.T) {
+	hashed := sha256.Sum256([]byte("testing"))
+	for _, tt := range []struct {
+		sign, verify int
+	}{
+		{PSSSaltLengthAuto, 222},
+		{PSSSaltLengthEqualsHash, 32},
+		{1, 1},
+		{20, 20},
+		{64, 64},
+		{222, 222},
+	} {
+		sig, err := SignPSS(rand.Reader, test2048Key, crypto.SHA256, hashed[:], &PSSOptions{SaltLength: tt.sign})
+		if err != nil {
+			t.Errorf("SignPSS with SaltLength %d: %v", tt.sign, err)
+			continue
+		}
+		for _, saltLen := range []int{PSSSaltLengthAuto, tt.verify} {
+			if err := VerifyPSS(&test2048Key.PublicKey, crypto.SHA256, hashed[:], sig, &PSSOptions{SaltLength: saltLen}); err != nil {
+				t.Errorf("SignPSS with SaltLength %d: VerifyPSS with SaltLength %d: %v", tt.sign, saltLen, err)
+			}
+		}
+		if err := VerifyPSS(&test2048Key.PublicKey, crypto.SHA256, hashed[:], sig, &PSSOptions{SaltLength: tt.verify + 1}); err == nil {
+			t.Errorf("SignPSS with SaltLength %d: VerifyPSS with SaltLength %d succeeded", tt.sign, tt.verify+1)
+		}
+	}
+
+	// PSSOptions.Hash overrides the hash passed to SignPSS.
+	sig, err := SignPSS(rand.Reader, test2048Key, crypto.SHA1, hashed[:], &PSSOptions{Hash: crypto.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPSS(&test2048Key.PublicKey, crypto.SHA256, hashed[:], sig, nil); err != nil {
+		t.Errorf("SignPSS with PSSOptions.Hash: %v", err)
+	}
+}
+
+func TestPSSDeterministicSalt(t *testing.T) {
+	hash := crypto.SHA1
+	hashed := sha1.Sum([]byte("testing"))
+	other := sha1.Sum([]byte("testing!"))
+
+	for _, saltLength := range []int{PSSSaltLengthAuto, PSSSaltLengthEqualsHash, 8} {
+		opts := &PSSOptions{SaltLength: saltLength, DeterministicSalt: true}
+		// rand is not used.
+		sig1, err := SignPSS(nil, rsaPrivateKey, hash, hashed[:], opts)
+		if err != nil {
+			t.Fatalf("SaltLength %d: %v", saltLength, err)
+		}
+		sig2, err := SignPSS(rand.Reader, rsaPrivateKey, hash, hashed[:], opts)
+		if err != nil {
+			t.Fatalf("SaltLength %d: %v", saltLength, err)
+		}
+		if !bytes.Equal(sig1, sig2) {
+			t.Errorf("SaltLength %d: signatures of the same digest differ", saltLength)
+		}
+		sig3, err := SignPSS(nil, rsaPrivateKey, hash, other[:], opts)
+		if err != nil {
+			t.Fatalf("SaltLength %d: %v", saltLength, err)
+		}
+		if bytes.Equal(sig1, sig3) {
+			t.Errorf("SaltLength %d: signatures of different digests are equal", saltLength)
+		}
+
+		if err := VerifyPSS(&rsaPrivateKey.PublicKey, hash, hashed[:], sig1, &PSSOptions{SaltLength: saltLength}); err != nil {
+			t.Errorf("SaltLength %d: %v", saltLength, err)
+		}
+		if err := VerifyPSS(&rsaPrivateKey.PublicKey, hash, hashed[:], sig1, nil); err != nil {
+			t.Errorf("SaltLength %d: verification with nil options: %v", saltLength, err)
+		}
+	}
+}
+
+func TestPSSDeterministicSaltLimit(t *testing.T) {
+	max := 255 * crypto.SHA1.Size()
+	hashed := sha1.Sum([]byte("testing"))
+	salt, err := DeterministicSalt(rsaPrivateKey, crypto.SHA1, hashed[:], max)
+	if err != nil || len(salt) != max {
+		t.Fatalf("got %d bytes of salt and error %v, want %d bytes", len(salt), err, max)
+	}
+	if _, err := DeterministicSalt(rsaPrivateKey, crypto.SHA1, hashed[:], max+1); err == nil {
+		t.Error("salt longer than 255 hash outputs did not fail")
+	}
+}
+
 func TestPSS513(t *testing.T) {
 	// See Issue 42741, and separately, RFC 8017: "Note that the octet length of
 	// EM will be one less than k if modBits - 1 is divisible by 8 and equal to
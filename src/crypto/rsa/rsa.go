@@ -30,12 +30,14 @@ import (
 	"crypto/internal/bigmod"
 	"crypto/internal/boring"
 	"crypto/internal/boring/bbig"
+	"crypto/internal/cryptotrace"
 	"crypto/internal/randutil"
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/binary"
 	"errors"
 	"hash"
+	"internal/cryptometrics"
 	"io"
 	"math"
 	"math/big"
@@ -68,13 +70,20 @@ func (pub *PublicKey) Equal(x crypto.PublicKey) bool {
 }
 
 // OAEPOptions is an interface for passing options to OAEP decryption using the
-// crypto.Decrypter interface.
+// crypto.Decrypter interface, and to OAEP encryption using
+// EncryptOAEPWithOptions.
+//
+// Implementations of crypto.Decrypter for RSA keys held elsewhere, such as in
+// a hardware token, should honor Label and MGFHash as PrivateKey.Decrypt does,
+// or return an error if they can't, so that callers get the same plaintext
+// regardless of where the key is stored.
 type OAEPOptions struct {
 	// Hash is the hash function that will be used when generating the mask.
 	Hash crypto.Hash
 
 	// MGFHash is the hash function used for MGF1.
-	// If zero, Hash is used instead.
+	// If zero, Hash is used instead. Some protocols, such as XML Encryption
+	// and a number of hardware tokens, use SHA-1 for MGF1 regardless of Hash.
 	MGFHash crypto.Hash
 
 	// Label is an arbitrary byte string that must be equal to the value
@@ -82,6 +91,18 @@ type OAEPOptions struct {
 	Label []byte
 }
 
+// hashes returns new instances of the OAEP and MGF1 hash functions.
+func (opts *OAEPOptions) hashes() (h, mgfHash hash.Hash, err error) {
+	mgf := opts.MGFHash
+	if mgf == 0 {
+		mgf = opts.Hash
+	}
+	if !opts.Hash.Available() || !mgf.Available() {
+		return nil, nil, errors.New("crypto/rsa: OAEP hash function not available")
+	}
+	return opts.Hash.New(), mgf.New(), nil
+}
+
 var (
 	errPublicModulus       = errors.New("crypto/rsa: missing public modulus")
 	errPublicExponentSmall = errors.New("crypto/rsa: public exponent too small")
@@ -170,11 +191,11 @@ func (priv *PrivateKey) Decrypt(rand io.Reader, ciphertext []byte, opts crypto.D
 
 	switch opts := opts.(type) {
 	case *OAEPOptions:
-		if opts.MGFHash == 0 {
-			return decryptOAEP(opts.Hash.New(), opts.Hash.New(), rand, priv, ciphertext, opts.Label)
-		} else {
-			return decryptOAEP(opts.Hash.New(), opts.MGFHash.New(), rand, priv, ciphertext, opts.Label)
+		h, mgfHash, err := opts.hashes()
+		if err != nil {
+			return nil, err
 		}
+		return decryptOAEP(h, mgfHash, rand, priv, ciphertext, opts.Label)
 
 	case *PKCS1v15DecryptOptions:
 		if l := opts.SessionKeyLen; l > 0 {
@@ -211,6 +232,8 @@ type PrecomputedValues struct {
 	CRTValues []CRTValue
 
 	n, p, q *bigmod.Modulus // moduli for CRT with Montgomery precomputed constants
+
+	boring *boringPriv // module key attached by GenerateKey, if any
 }
 
 // CRTValue contains the precomputed Chinese remainder theorem values.
@@ -284,6 +307,7 @@ func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
 //
 // [On the Security of Multi-prime RSA]: http://www.cacr.math.uwaterloo.ca/techreports/2006/cacr2006-16.pdf
 func GenerateMultiPrimeKey(random io.Reader, nprimes int, bits int) (*PrivateKey, error) {
+	defer cryptotrace.StartRegion("crypto/rsa.GenerateKey").End()
 	randutil.MaybeReadByte(random)
 
 	if boring.Enabled && random == boring.RandReader && nprimes == 2 && (bits == 2048 || bits == 3072) {
@@ -320,8 +344,14 @@ func GenerateMultiPrimeKey(random io.Reader, nprimes int, bits int) (*PrivateKey
 				q:         bigmod.NewModulusFromBig(Q),
 			},
 		}
+		if err := attachBoringPrivateKey(key); err != nil {
+			return nil, err
+		}
 		return key, nil
 	}
+	if boring.Enabled {
+		boring.RecordFallback(cryptometrics.RSA, "unsupported key generation parameters")
+	}
 
 	priv := new(PrivateKey)
 	priv.E = 65537
@@ -409,6 +439,11 @@ NextSetOfPrimes:
 	}
 
 	priv.Precompute()
+	if boring.Enabled && nprimes == 2 {
+		// The key is usable without the attached module key, which is
+		// otherwise made on first use, so a conversion error is not fatal.
+		attachBoringPrivateKey(priv)
+	}
 	return priv, nil
 }
 
@@ -494,6 +529,25 @@ func intToBytes(i int) []byte {
 // The message must be no longer than the length of the public modulus minus
 // twice the hash length, minus a further 2.
 func EncryptOAEP(hash hash.Hash, random io.Reader, pub *PublicKey, msg []byte, label []byte) ([]byte, error) {
+	return encryptOAEP(hash, hash, random, pub, msg, label)
+}
+
+// EncryptOAEPWithOptions encrypts the given message with RSA-OAEP, using the
+// hash functions and label of opts. Unlike EncryptOAEP, it supports a hash
+// function for MGF1 that differs from the one used for the label, as
+// required by some protocols. The ciphertext can be decrypted by passing
+// the same opts to PrivateKey.Decrypt.
+//
+// See EncryptOAEP for the other parameters.
+func EncryptOAEPWithOptions(random io.Reader, pub *PublicKey, msg []byte, opts *OAEPOptions) ([]byte, error) {
+	h, mgfHash, err := opts.hashes()
+	if err != nil {
+		return nil, err
+	}
+	return encryptOAEP(h, mgfHash, random, pub, msg, opts.Label)
+}
+
+func encryptOAEP(hash, mgfHash hash.Hash, random io.Reader, pub *PublicKey, msg []byte, label []byte) ([]byte, error) {
 	if err := checkPub(pub); err != nil {
 		return nil, err
 	}
@@ -504,13 +558,19 @@ func EncryptOAEP(hash hash.Hash, random io.Reader, pub *PublicKey, msg []byte, l
 	}
 
 	if boring.Enabled && random == boring.RandReader {
-		bkey, err := boringPublicKey(pub)
-		if err != nil {
-			return nil, err
+		if boring.SupportsRSAOAEP(hash, mgfHash, label) {
+			bkey, err := boringPublicKey(pub)
+			if err != nil {
+				return nil, err
+			}
+			out, err := boring.EncryptRSAOAEP(hash, mgfHash, bkey, msg, label)
+			approve(pub.N.BitLen() >= 2048)
+			return out, err
 		}
-		return boring.EncryptRSAOAEP(hash, hash, bkey, msg, label)
+		boring.RecordFallback(cryptometrics.RSA, "unsupported OAEP parameters")
+	} else {
+		boring.UnreachableExceptTests()
 	}
-	boring.UnreachableExceptTests()
 
 	hash.Write(label)
 	lHash := hash.Sum(nil)
@@ -529,8 +589,8 @@ func EncryptOAEP(hash hash.Hash, random io.Reader, pub *PublicKey, msg []byte, l
 		return nil, err
 	}
 
-	mgf1XOR(db, hash, seed)
-	mgf1XOR(seed, hash, db)
+	mgf1XOR(db, mgfHash, seed)
+	mgf1XOR(seed, mgfHash, db)
 
 	if boring.Enabled {
 		var bkey *boring.PublicKeyRSA
@@ -538,12 +598,46 @@ func EncryptOAEP(hash hash.Hash, random io.Reader, pub *PublicKey, msg []byte, l
 		if err != nil {
 			return nil, err
 		}
-		return boring.EncryptRSANoPadding(bkey, em)
+		out, err := boring.EncryptRSANoPadding(bkey, em)
+		boring.SetNotApproved()
+		return out, err
 	}
 
 	return encrypt(pub, em)
 }
 
+// approve makes the service indicator report that the module operation that
+// just completed was not approved, unless ok. The module performs RSA
+// operations with keys, hashes and paddings that SP 800-131A doesn't approve,
+// and counts all of them as approved services.
+func approve(ok bool) {
+	if !ok {
+		boring.SetNotApproved()
+	}
+}
+
+// approvedSigning reports whether SP 800-131A approves generating signatures
+// of hash with a key of bits bits.
+func approvedSigning(bits int, hash crypto.Hash) bool {
+	return bits >= 2048 && approvedSignatureHash(hash)
+}
+
+// approvedVerifying reports whether SP 800-131A approves verifying
+// signatures of hash with a key of bits bits, which it allows for legacy
+// keys and SHA-1.
+func approvedVerifying(bits int, hash crypto.Hash) bool {
+	return bits >= 1024 && (hash == crypto.SHA1 || approvedSignatureHash(hash))
+}
+
+func approvedSignatureHash(hash crypto.Hash) bool {
+	switch hash {
+	case crypto.SHA224, crypto.SHA256, crypto.SHA384, crypto.SHA512,
+		crypto.SHA512_224, crypto.SHA512_256:
+		return true
+	}
+	return false
+}
+
 // ErrDecryption represents a failure to decrypt a message.
 // It is deliberately vague to avoid adaptive attacks.
 var ErrDecryption = errors.New("crypto/rsa: decryption error")
@@ -673,22 +767,49 @@ func decryptOAEP(hash, mgfHash hash.Hash, random io.Reader, priv *PrivateKey, ci
 		k < hash.Size()*2+2 {
 		return nil, ErrDecryption
 	}
+	if err := checkLimit(priv); err != nil {
+		return nil, err
+	}
 
+	var em []byte
 	if boring.Enabled {
 		bkey, err := boringPrivateKey(priv)
 		if err != nil {
 			return nil, err
 		}
-		out, err := boring.DecryptRSAOAEP(hash, mgfHash, bkey, ciphertext, label)
+		if boring.SupportsRSAOAEP(hash, mgfHash, label) {
+			out, err := boring.DecryptRSAOAEP(hash, mgfHash, bkey, ciphertext, label)
+			approve(priv.N.BitLen() >= 2048)
+			if errors.Is(err, boring.ErrUnsupportedParameter) {
+				// Rejected before touching the ciphertext, so reporting it
+				// reveals nothing about the plaintext.
+				return nil, err
+			}
+			if err != nil {
+				return nil, ErrDecryption
+			}
+			return out, nil
+		}
+		boring.RecordFallback(cryptometrics.RSA, "unsupported OAEP parameters")
+
+		// The module still performs the RSA operation, and only the
+		// padding is checked below.
+		if len(ciphertext) < k {
+			c := make([]byte, k)
+			copy(c[k-len(ciphertext):], ciphertext)
+			ciphertext = c
+		}
+		em, err = boring.DecryptRSANoPadding(bkey, ciphertext)
+		boring.SetNotApproved()
 		if err != nil {
 			return nil, ErrDecryption
 		}
-		return out, nil
-	}
-
-	em, err := decrypt(priv, ciphertext, noCheck)
-	if err != nil {
-		return nil, err
+	} else {
+		var err error
+		em, err = decrypt(priv, ciphertext, noCheck)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	hash.Write(label)
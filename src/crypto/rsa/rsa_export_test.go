@@ -4,7 +4,14 @@
 
 package rsa
 
+import "time"
+
 var NonZeroRandomBytes = nonZeroRandomBytes
 var EMSAPSSEncode = emsaPSSEncode
 var EMSAPSSVerify = emsaPSSVerify
 var InvalidSaltLenErr = invalidSaltLenErr
+var DeterministicSalt = deterministicSalt
+
+func (l *Limiter) AllowAt(priv *PrivateKey, now time.Time) bool {
+	return l.allow(priv, now)
+}
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"internal/testenv"
 	"math/big"
+	"runtime/metrics"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestKeyGeneration(t *testing.T) {
@@ -543,72 +545,102 @@ func BenchmarkEncryptOAEP(b *testing.B) {
 	})
 }
 
-func BenchmarkSignPKCS1v15(b *testing.B) {
-	b.Run("2048", func(b *testing.B) {
-		hashed := sha256.Sum256([]byte("testing"))
+func BenchmarkGenerateKey(b *testing.B) {
+	b.Run("2048", func(b *testing.B) { benchmarkGenerateKey(b, 2048) })
+	b.Run("3072", func(b *testing.B) { benchmarkGenerateKey(b, 3072) })
+	b.Run("4096", func(b *testing.B) { benchmarkGenerateKey(b, 4096) })
+}
 
-		var sink byte
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			s, err := SignPKCS1v15(rand.Reader, test2048Key, crypto.SHA256, hashed[:])
-			if err != nil {
-				b.Fatal(err)
-			}
-			sink ^= s[0]
+func benchmarkGenerateKey(b *testing.B, bits int) {
+	for i := 0; i < b.N; i++ {
+		if _, err := GenerateKey(rand.Reader, bits); err != nil {
+			b.Fatal(err)
 		}
-	})
+	}
 }
 
-func BenchmarkVerifyPKCS1v15(b *testing.B) {
-	b.Run("2048", func(b *testing.B) {
-		hashed := sha256.Sum256([]byte("testing"))
-		s, err := SignPKCS1v15(rand.Reader, test2048Key, crypto.SHA256, hashed[:])
+func BenchmarkSignPKCS1v15(b *testing.B) {
+	b.Run("2048", func(b *testing.B) { benchmarkSignPKCS1v15(b, test2048Key) })
+	b.Run("3072", func(b *testing.B) { benchmarkSignPKCS1v15(b, test3072Key) })
+	b.Run("4096", func(b *testing.B) { benchmarkSignPKCS1v15(b, test4096Key) })
+}
+
+func benchmarkSignPKCS1v15(b *testing.B, k *PrivateKey) {
+	hashed := sha256.Sum256([]byte("testing"))
+
+	var sink byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, err := SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashed[:])
 		if err != nil {
 			b.Fatal(err)
 		}
+		sink ^= s[0]
+	}
+}
 
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			err := VerifyPKCS1v15(&test2048Key.PublicKey, crypto.SHA256, hashed[:], s)
-			if err != nil {
-				b.Fatal(err)
-			}
+func BenchmarkVerifyPKCS1v15(b *testing.B) {
+	b.Run("2048", func(b *testing.B) { benchmarkVerifyPKCS1v15(b, test2048Key) })
+	b.Run("3072", func(b *testing.B) { benchmarkVerifyPKCS1v15(b, test3072Key) })
+	b.Run("4096", func(b *testing.B) { benchmarkVerifyPKCS1v15(b, test4096Key) })
+}
+
+func benchmarkVerifyPKCS1v15(b *testing.B, k *PrivateKey) {
+	hashed := sha256.Sum256([]byte("testing"))
+	s, err := SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashed[:])
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := VerifyPKCS1v15(&k.PublicKey, crypto.SHA256, hashed[:], s)
+		if err != nil {
+			b.Fatal(err)
 		}
-	})
+	}
 }
 
 func BenchmarkSignPSS(b *testing.B) {
-	b.Run("2048", func(b *testing.B) {
-		hashed := sha256.Sum256([]byte("testing"))
+	b.Run("2048", func(b *testing.B) { benchmarkSignPSS(b, test2048Key) })
+	b.Run("3072", func(b *testing.B) { benchmarkSignPSS(b, test3072Key) })
+	b.Run("4096", func(b *testing.B) { benchmarkSignPSS(b, test4096Key) })
+}
 
-		var sink byte
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			s, err := SignPSS(rand.Reader, test2048Key, crypto.SHA256, hashed[:], nil)
-			if err != nil {
-				b.Fatal(err)
-			}
-			sink ^= s[0]
+func benchmarkSignPSS(b *testing.B, k *PrivateKey) {
+	hashed := sha256.Sum256([]byte("testing"))
+
+	var sink byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, err := SignPSS(rand.Reader, k, crypto.SHA256, hashed[:], nil)
+		if err != nil {
+			b.Fatal(err)
 		}
-	})
+		sink ^= s[0]
+	}
 }
 
 func BenchmarkVerifyPSS(b *testing.B) {
-	b.Run("2048", func(b *testing.B) {
-		hashed := sha256.Sum256([]byte("testing"))
-		s, err := SignPSS(rand.Reader, test2048Key, crypto.SHA256, hashed[:], nil)
+	b.Run("2048", func(b *testing.B) { benchmarkVerifyPSS(b, test2048Key) })
+	b.Run("3072", func(b *testing.B) { benchmarkVerifyPSS(b, test3072Key) })
+	b.Run("4096", func(b *testing.B) { benchmarkVerifyPSS(b, test4096Key) })
+}
+
+func benchmarkVerifyPSS(b *testing.B, k *PrivateKey) {
+	hashed := sha256.Sum256([]byte("testing"))
+	s, err := SignPSS(rand.Reader, k, crypto.SHA256, hashed[:], nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := VerifyPSS(&k.PublicKey, crypto.SHA256, hashed[:], s, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
-
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			err := VerifyPSS(&test2048Key.PublicKey, crypto.SHA256, hashed[:], s, nil)
-			if err != nil {
-				b.Fatal(err)
-			}
-		}
-	})
+	}
 }
 
 type testEncryptOAEPMessage struct {
@@ -880,3 +912,137 @@ var testEncryptOAEPData = []testEncryptOAEPStruct{
 		},
 	},
 }
+
+func TestLimiter(t *testing.T) {
+	k1, k2 := new(PrivateKey), new(PrivateKey)
+	t0 := time.Unix(1e9, 0)
+
+	l := NewLimiter(1, 2)
+	for i, want := range []bool{true, true, false} {
+		if got := l.AllowAt(k1, t0); got != want {
+			t.Errorf("global limit: operation %d allowed = %v, want %v", i, got, want)
+		}
+	}
+	if !l.AllowAt(k1, t0.Add(time.Second)) {
+		t.Error("global limit: operation rejected after refill")
+	}
+	if l.AllowAt(k1, t0.Add(time.Second)) {
+		t.Error("global limit: operation allowed after refill was consumed")
+	}
+
+	l = NewLimiter(0, 0)
+	l.SetKeyLimit(1, 1)
+	if !l.AllowAt(k1, t0) || !l.AllowAt(k2, t0) {
+		t.Error("per-key limit: first operation rejected")
+	}
+	if l.AllowAt(k1, t0) || l.AllowAt(k2, t0.Add(time.Second/2)) {
+		t.Error("per-key limit: second operation allowed")
+	}
+	if !l.AllowAt(k2, t0.Add(time.Second)) {
+		t.Error("per-key limit: operation rejected after refill")
+	}
+
+	// Keys with full buckets are dropped without losing state.
+	for i := 0; i < 100; i++ {
+		l.AllowAt(new(PrivateKey), t0)
+	}
+	if l.AllowAt(k1, t0) {
+		t.Error("per-key limit: operation allowed after unrelated keys were used")
+	}
+}
+
+func TestSetLimiter(t *testing.T) {
+	defer SetLimiter(nil)
+	SetLimiter(NewLimiter(1e-9, 1))
+	sample := []metrics.Sample{{Name: "/crypto/rsa/limited:calls"}}
+	metrics.Read(sample)
+	rejected := sample[0].Value.Uint64()
+
+	digest := sha256.Sum256([]byte("hello"))
+	if _, err := SignPKCS1v15(nil, test2048Key, crypto.SHA256, digest[:]); err != nil {
+		t.Fatalf("first SignPKCS1v15: %v", err)
+	}
+	if _, err := SignPSS(rand.Reader, test2048Key, crypto.SHA256, digest[:], nil); err != ErrRateLimited {
+		t.Errorf("SignPSS = %v, want ErrRateLimited", err)
+	}
+	if _, err := test2048Key.Decrypt(nil, make([]byte, 256), nil); err != ErrRateLimited {
+		t.Errorf("Decrypt = %v, want ErrRateLimited", err)
+	}
+	metrics.Read(sample)
+	if got := sample[0].Value.Uint64() - rejected; got != 2 {
+		t.Errorf("limited:calls increased by %d, want 2", got)
+	}
+
+	SetLimiter(nil)
+	if _, err := SignPKCS1v15(nil, test2048Key, crypto.SHA256, digest[:]); err != nil {
+		t.Errorf("SignPKCS1v15 after SetLimiter(nil): %v", err)
+	}
+}
+
+func TestDecryptOAEPOptions(t *testing.T) {
+	priv := test2048Key
+	msg := []byte("envelope key")
+	label := []byte("label")
+	for _, opts := range []*OAEPOptions{
+		{Hash: crypto.SHA256},
+		{Hash: crypto.SHA256, Label: label},
+		{Hash: crypto.SHA256, MGFHash: crypto.SHA1, Label: label},
+		{Hash: crypto.SHA384, MGFHash: crypto.SHA512},
+	} {
+		ct, err := EncryptOAEPWithOptions(rand.Reader, &priv.PublicKey, msg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d crypto.Decrypter = priv
+		out, err := d.Decrypt(rand.Reader, ct, opts)
+		if err != nil {
+			t.Errorf("Decrypt(%+v): %v", opts, err)
+		} else if !bytes.Equal(out, msg) {
+			t.Errorf("Decrypt(%+v) = %q, want %q", opts, out, msg)
+		}
+
+		wrong := *opts
+		wrong.Label = []byte("other")
+		if _, err := d.Decrypt(rand.Reader, ct, &wrong); err != ErrDecryption {
+			t.Errorf("Decrypt(%+v) with wrong label: got %v, want ErrDecryption", opts, err)
+		}
+	}
+
+	if _, err := priv.Decrypt(rand.Reader, make([]byte, priv.Size()), &OAEPOptions{Hash: crypto.Hash(0)}); err == nil {
+		t.Error("Decrypt with an unavailable hash succeeded")
+	}
+	if _, err := EncryptOAEPWithOptions(rand.Reader, &priv.PublicKey, msg, &OAEPOptions{Hash: crypto.SHA256, MGFHash: crypto.Hash(0xff)}); err == nil {
+		t.Error("EncryptOAEPWithOptions with an unavailable hash succeeded")
+	}
+}
+
+// TestDecryptOAEPOpenSSLMGF1 checks the decryption of a ciphertext made by
+// OpenSSL with different hash functions for OAEP and MGF1.
+func TestDecryptOAEPOpenSSLMGF1(t *testing.T) {
+	// Generated with `echo -n "envelope key" | openssl pkeyutl -encrypt
+	// -inkey key.pem -pkeyopt rsa_padding_mode:oaep -pkeyopt rsa_oaep_md:sha256
+	// -pkeyopt rsa_mgf1_md:sha1 -pkeyopt rsa_oaep_label:6c6162656c`, where
+	// key.pem is test2048Key.
+	ct := fromHex("1a18ecd10c8cd7dd5728d9c59df1aff0e2dc60c770194e941ca473029765293f" +
+		"90242fbe44be80c3c23d55ccd73b2834f71a06d5cef3a2942aeeb6507f0fd0eb" +
+		"1c3a88f75df004b52464661cc2b179c54090a90ac631d263b8a79a8c6f062958" +
+		"9b09e6613e9523228d47f61d794e66c298c4f193dd6c5bc3a37e405f4b8b5cfd" +
+		"bd70e6514be2b00b9732832cec5b1f3e9857c83977c1eb99ea0f26684dbb0d26" +
+		"98b0a7a4699a6679ae496dacc92e9550843f6289733faacd69e2f391394e4c9d" +
+		"8f89e4de59138210a67d22c3e1ede95d513dbf132ae1a0a35c6d1fd4e1b8f776" +
+		"3e9de16f8ac5c6c67952d07911a33ba988a352edd546972e7cf617bfe0c0c8f9")
+
+	opts := &OAEPOptions{Hash: crypto.SHA256, MGFHash: crypto.SHA1, Label: []byte("label")}
+	out, err := test2048Key.Decrypt(nil, ct, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "envelope key"; string(out) != want {
+		t.Errorf("Decrypt = %q, want %q", out, want)
+	}
+
+	opts = &OAEPOptions{Hash: crypto.SHA256, Label: []byte("label")}
+	if _, err := test2048Key.Decrypt(nil, ct, opts); err != ErrDecryption {
+		t.Errorf("Decrypt with MGF1-SHA256: got %v, want ErrDecryption", err)
+	}
+}
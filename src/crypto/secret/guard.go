@@ -0,0 +1,111 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// minTagged is the minimum length of a tagged buffer that is redacted.
+// Shorter buffers would match unrelated text.
+const minTagged = 4
+
+var tagged struct {
+	sync.Mutex
+	bufs map[*byte][]byte
+}
+
+// Tag marks the contents of b as secret, so that they are redacted from
+// the panic values and stack traces reported by Guard. It returns a
+// function that removes the tag, which should be called before b is
+// reused or released. Buffers shorter than four bytes are not redacted.
+func Tag(b []byte) (untag func()) {
+	if len(b) < minTagged {
+		return func() {}
+	}
+	p := &b[0]
+	tagged.Lock()
+	defer tagged.Unlock()
+	if tagged.bufs == nil {
+		tagged.bufs = make(map[*byte][]byte)
+	}
+	tagged.bufs[p] = b
+	return func() {
+		tagged.Lock()
+		defer tagged.Unlock()
+		delete(tagged.bufs, p)
+	}
+}
+
+// redact replaces the contents of tagged buffers in b, either raw or hex
+// encoded, with "[REDACTED]".
+func redact(b []byte) []byte {
+	tagged.Lock()
+	defer tagged.Unlock()
+	for _, buf := range tagged.bufs {
+		b = bytes.ReplaceAll(b, buf, []byte(redacted))
+		h := []byte(hex.EncodeToString(buf))
+		b = bytes.ReplaceAll(b, h, []byte(redacted))
+		b = bytes.ReplaceAll(b, bytes.ToUpper(h), []byte(redacted))
+	}
+	return b
+}
+
+// A PanicError is returned by Guard when its function panics. The contents
+// of buffers marked with Tag are redacted from Value and Stack.
+type PanicError struct {
+	// Value is the panic value, formatted with fmt.Sprint.
+	Value string
+
+	// Stack is the stack trace of the panicking goroutine, in the format
+	// of runtime.Stack.
+	Stack []byte
+
+	err error
+}
+
+func (e *PanicError) Error() string {
+	return "crypto/secret: recovered from panic: " + e.Value
+}
+
+// Unwrap returns the panic value if it is an error whose message contains
+// no tagged secret, such as a runtime.Error or a backend failure matching
+// crypto/fips.ErrBackendFailure, and nil otherwise. Other panic values are
+// only reported in redacted form.
+func (e *PanicError) Unwrap() error {
+	return e.err
+}
+
+// Guard calls f and returns its result. If f panics, for example because of
+// a failure in a cryptographic backend, Guard recovers and returns a
+// *PanicError instead of crashing the program.
+//
+// Guard is meant for services that must not crash on a transient failure.
+// The state used by f, such as a hash.Hash or cipher.AEAD, must not be
+// used again after a panic.
+func Guard(f func() error) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		stack := make([]byte, 64<<10)
+		stack = stack[:runtime.Stack(stack, false)]
+		value := fmt.Sprint(v)
+		perr := &PanicError{
+			Value: string(redact([]byte(value))),
+			Stack: redact(stack),
+		}
+		if verr, ok := v.(error); ok && perr.Value == value {
+			perr.err = verr
+		}
+		err = perr
+	}()
+	return f()
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	if err := Guard(func() error { return nil }); err != nil {
+		t.Errorf("Guard of successful function = %v", err)
+	}
+	errTest := errors.New("test")
+	if err := Guard(func() error { return errTest }); err != errTest {
+		t.Errorf("Guard of failing function = %v, want %v", err, errTest)
+	}
+
+	err := Guard(func() error { panic("backend failure") })
+	var perr *PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Guard of panicking function = %v, want *PanicError", err)
+	}
+	if perr.Value != "backend failure" || !strings.Contains(err.Error(), "backend failure") {
+		t.Errorf("Value = %q, Error = %q", perr.Value, err)
+	}
+	if !bytes.Contains(perr.Stack, []byte("TestGuard")) {
+		t.Errorf("Stack does not contain the panicking function:\n%s", perr.Stack)
+	}
+	if errors.Unwrap(err) != nil {
+		t.Errorf("Unwrap = %v, want nil", errors.Unwrap(err))
+	}
+
+	err = Guard(func() error {
+		var s []int
+		_ = s[len(s)]
+		return nil
+	})
+	var rerr runtime.Error
+	if !errors.As(err, &rerr) {
+		t.Errorf("Guard of index out of range = %v, want a runtime.Error", err)
+	}
+}
+
+func TestGuardRedacts(t *testing.T) {
+	key := []byte("supersecretkey")
+	untag := Tag(key)
+	for _, leak := range []string{string(key), hex.EncodeToString(key), strings.ToUpper(hex.EncodeToString(key))} {
+		err := Guard(func() error { panic("bad key " + leak) })
+		var perr *PanicError
+		if !errors.As(err, &perr) {
+			t.Fatalf("Guard = %v, want *PanicError", err)
+		}
+		if perr.Value != "bad key [REDACTED]" || strings.Contains(err.Error(), leak) {
+			t.Errorf("Value = %q, Error = %q; want key redacted", perr.Value, err)
+		}
+	}
+
+	keyErr := errors.New("bad key " + string(key))
+	err := Guard(func() error { panic(keyErr) })
+	if errors.Is(err, keyErr) {
+		t.Error("Guard returned an error that unwraps to an unredacted error")
+	}
+
+	untag()
+	if err := Guard(func() error { panic(keyErr) }); !errors.Is(err, keyErr) {
+		t.Errorf("Guard = %v, want an error that unwraps to the panic value", err)
+	}
+	err = Guard(func() error { panic("bad key " + string(key)) })
+	if !strings.Contains(err.Error(), string(key)) {
+		t.Errorf("Error after untag = %q, want unredacted key", err)
+	}
+}
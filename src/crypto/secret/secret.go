@@ -0,0 +1,58 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package secret provides a wrapper for values, such as keys and passwords,
+// that must not be accidentally exposed in logs, error messages, or
+// serialized output.
+//
+// A Secret formats as "[REDACTED]" with every fmt verb and marshals to JSON
+// as the string "[REDACTED]". The wrapped value can only be retrieved by an
+// explicit call to Expose, which makes uses of it easy to audit.
+//
+// A Secret does not protect the wrapped value from code that inspects memory
+// or uses reflection. In particular, fmt formats unexported struct fields by
+// reflection, without calling their methods, so a Secret stored in an
+// unexported field of a struct that is itself printed is not redacted.
+//
+// Guard converts panics, such as those raised by a failing cryptographic
+// backend, to errors, redacting the contents of buffers marked with Tag.
+package secret
+
+import "fmt"
+
+const redacted = "[REDACTED]"
+
+// Secret holds a value of type T that is redacted when formatted or
+// marshaled. The zero value holds the zero value of T.
+type Secret[T any] struct {
+	v T
+}
+
+// New returns a Secret holding v.
+func New[T any](v T) Secret[T] {
+	return Secret[T]{v}
+}
+
+// Expose returns the value held by s.
+func (s Secret[T]) Expose() T {
+	return s.v
+}
+
+// String returns "[REDACTED]".
+func (s Secret[T]) String() string {
+	return redacted
+}
+
+// Format implements fmt.Formatter. It writes "[REDACTED]" for every verb,
+// including %#v, %x and %d, which would otherwise print the contents of
+// the wrapped value.
+func (s Secret[T]) Format(f fmt.State, verb rune) {
+	f.Write([]byte(redacted))
+}
+
+// MarshalJSON implements encoding/json.Marshaler. It returns the JSON string
+// "[REDACTED]".
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	key := New([]byte("supersecretkey"))
+	pin := New(1234)
+	type holder struct {
+		Name string
+		Key  Secret[[]byte]
+	}
+	h := holder{"k1", key}
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d"} {
+		for _, v := range []any{key, &key, pin, h, &h, []Secret[int]{pin}} {
+			s := fmt.Sprintf(verb, v)
+			if strings.Contains(s, "supersecretkey") || strings.Contains(s, "1234") ||
+				strings.Contains(s, "73757065") || strings.Contains(s, "4d2") {
+				t.Errorf("Sprintf(%q, %T) = %q, exposes secret", verb, v, s)
+			}
+			if !strings.Contains(s, redacted) {
+				t.Errorf("Sprintf(%q, %T) = %q, want it redacted", verb, v, s)
+			}
+		}
+	}
+	if s := key.String(); s != redacted {
+		t.Errorf("String() = %q, want %q", s, redacted)
+	}
+	if err := fmt.Errorf("bad key %v", key); strings.Contains(err.Error(), "supersecret") {
+		t.Errorf("error exposes secret: %v", err)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	out, err := json.Marshal(struct {
+		User     string
+		Password Secret[string]
+	}{"gopher", New("hunter2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"User":"gopher","Password":"[REDACTED]"}`; string(out) != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
+
+func TestExpose(t *testing.T) {
+	b := []byte{1, 2, 3}
+	if got := New(b).Expose(); !bytes.Equal(got, b) {
+		t.Errorf("Expose() = %v, want %v", got, b)
+	}
+	var zero Secret[error]
+	if zero.Expose() != nil {
+		t.Errorf("zero Secret exposes %v", zero.Expose())
+	}
+	err := errors.New("x")
+	if New(err).Expose() != err {
+		t.Error("Expose did not return the wrapped value")
+	}
+}
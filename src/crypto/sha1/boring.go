@@ -23,3 +23,5 @@ func boringNewSHA1() hash.Hash { return boring.NewSHA1() }
 func boringUnreachable() { boring.Unreachable() }
 
 func boringSHA1(p []byte) [20]byte { return boring.SHA1(p) }
+
+func boringSHA1Into(dst, p []byte) { boring.SHA1Into(dst, p) }
@@ -18,3 +18,5 @@ func boringNewSHA1() hash.Hash { panic("boringcrypto: not available") }
 func boringUnreachable() {}
 
 func boringSHA1([]byte) [20]byte { panic("boringcrypto: not available") }
+
+func boringSHA1Into(dst, p []byte) { panic("boringcrypto: not available") }
@@ -48,7 +48,10 @@ const (
 )
 
 func (d *digest) MarshalBinary() ([]byte, error) {
-	b := make([]byte, 0, marshaledSize)
+	return d.AppendBinary(make([]byte, 0, marshaledSize))
+}
+
+func (d *digest) AppendBinary(b []byte) ([]byte, error) {
 	b = append(b, magic...)
 	b = binary.BigEndian.AppendUint32(b, d.h[0])
 	b = binary.BigEndian.AppendUint32(b, d.h[1])
@@ -56,7 +59,7 @@ func (d *digest) MarshalBinary() ([]byte, error) {
 	b = binary.BigEndian.AppendUint32(b, d.h[3])
 	b = binary.BigEndian.AppendUint32(b, d.h[4])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-d.nx] // already zero
+	b = append(b, make([]byte, len(d.x)-d.nx)...)
 	b = binary.BigEndian.AppendUint64(b, d.len)
 	return b, nil
 }
@@ -303,3 +306,15 @@ func Sum(data []byte) [Size]byte {
 	d.Write(data)
 	return d.checkSum()
 }
+
+// AppendSum appends the SHA-1 checksum of the data to b and returns the
+// resulting slice. It does not allocate if b has enough spare capacity.
+func AppendSum(b, data []byte) []byte {
+	if boringEnabled {
+		b = append(b, make([]byte, Size)...)
+		boringSHA1Into(b[len(b)-Size:], data)
+		return b
+	}
+	sum := Sum(data)
+	return append(b, sum[:]...)
+}
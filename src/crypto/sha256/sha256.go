@@ -9,9 +9,11 @@ package sha256
 import (
 	"crypto"
 	"crypto/internal/boring"
+	"crypto/internal/cryptotrace"
 	"encoding/binary"
 	"errors"
 	"hash"
+	"internal/cryptometrics"
 )
 
 func init() {
@@ -64,7 +66,10 @@ const (
 )
 
 func (d *digest) MarshalBinary() ([]byte, error) {
-	b := make([]byte, 0, marshaledSize)
+	return d.AppendBinary(make([]byte, 0, marshaledSize))
+}
+
+func (d *digest) AppendBinary(b []byte) ([]byte, error) {
 	if d.is224 {
 		b = append(b, magic224...)
 	} else {
@@ -79,7 +84,7 @@ func (d *digest) MarshalBinary() ([]byte, error) {
 	b = binary.BigEndian.AppendUint32(b, d.h[6])
 	b = binary.BigEndian.AppendUint32(b, d.h[7])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-d.nx] // already zero
+	b = append(b, make([]byte, len(d.x)-d.nx)...)
 	b = binary.BigEndian.AppendUint64(b, d.len)
 	return b, nil
 }
@@ -159,7 +164,10 @@ func New() hash.Hash {
 // New224 returns a new hash.Hash computing the SHA224 checksum.
 func New224() hash.Hash {
 	if boring.Enabled {
-		return boring.NewSHA224()
+		if boring.SupportsHash(cryptometrics.SHA224) {
+			return boring.NewSHA224()
+		}
+		boring.RecordFallback(cryptometrics.SHA224, "unsupported hash")
 	}
 	d := new(digest)
 	d.is224 = true
@@ -167,6 +175,14 @@ func New224() hash.Hash {
 	return d
 }
 
+// boringUnreachable marks the code of d as unreachable when BoringCrypto
+// is in use and implements d.
+func (d *digest) boringUnreachable() {
+	if !d.is224 || boring.SupportsHash(cryptometrics.SHA224) {
+		boring.Unreachable()
+	}
+}
+
 func (d *digest) Size() int {
 	if !d.is224 {
 		return Size
@@ -177,6 +193,9 @@ func (d *digest) Size() int {
 func (d *digest) BlockSize() int { return BlockSize }
 
 func (d *digest) Write(p []byte) (nn int, err error) {
+	if len(p) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha256.Write").End()
+	}
 	nn = len(p)
 	d.len += uint64(nn)
 	n := fillChunk(d, p)
@@ -216,7 +235,7 @@ func (d *digest) WriteString(p string) (nn int, err error) {
 
 // fillChunk fills the remainder of the current chunk, if any.
 func fillChunk[S []byte | string](d *digest, p S) int {
-	boring.Unreachable()
+	d.boringUnreachable()
 	if d.nx == 0 {
 		return 0
 	}
@@ -230,7 +249,7 @@ func fillChunk[S []byte | string](d *digest, p S) int {
 }
 
 func (d *digest) WriteByte(c byte) error {
-	boring.Unreachable()
+	d.boringUnreachable()
 	d.len++
 	d.x[d.nx] = c
 	d.nx++
@@ -242,7 +261,7 @@ func (d *digest) WriteByte(c byte) error {
 }
 
 func (d *digest) Sum(in []byte) []byte {
-	boring.Unreachable()
+	d.boringUnreachable()
 	// Make a copy of d so that caller can keep writing and summing.
 	d0 := *d
 	hash := d0.checkSum()
@@ -292,6 +311,9 @@ func (d *digest) checkSum() [Size]byte {
 
 // Sum256 returns the SHA256 checksum of the data.
 func Sum256(data []byte) [Size]byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha256.Sum256").End()
+	}
 	if boring.Enabled {
 		return boring.SHA256(data)
 	}
@@ -301,10 +323,51 @@ func Sum256(data []byte) [Size]byte {
 	return d.checkSum()
 }
 
+// AppendSum256 appends the SHA256 checksum of the data to b and returns the
+// resulting slice. It does not allocate if b has enough spare capacity.
+func AppendSum256(b, data []byte) []byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha256.AppendSum256").End()
+	}
+	if boring.Enabled {
+		b = append(b, make([]byte, Size)...)
+		boring.SHA256Into(b[len(b)-Size:], data)
+		return b
+	}
+	var d digest
+	d.Reset()
+	d.Write(data)
+	sum := d.checkSum()
+	return append(b, sum[:]...)
+}
+
+// Sum256Batch returns the SHA256 checksums of the messages in data, in
+// order. It is equivalent to calling Sum256 on each of them, but can be
+// faster when hashing many small messages.
+func Sum256Batch(data [][]byte) [][Size]byte {
+	if boring.Enabled {
+		return boring.SHA256Batch(data)
+	}
+	sums := make([][Size]byte, len(data))
+	var d digest
+	for i, m := range data {
+		d.Reset()
+		d.Write(m)
+		sums[i] = d.checkSum()
+	}
+	return sums
+}
+
 // Sum224 returns the SHA224 checksum of the data.
 func Sum224(data []byte) [Size224]byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha256.Sum224").End()
+	}
 	if boring.Enabled {
-		return boring.SHA224(data)
+		if boring.SupportsHash(cryptometrics.SHA224) {
+			return boring.SHA224(data)
+		}
+		boring.RecordFallback(cryptometrics.SHA224, "unsupported hash")
 	}
 	var d digest
 	d.is224 = true
@@ -314,3 +377,25 @@ func Sum224(data []byte) [Size224]byte {
 	ap := (*[Size224]byte)(sum[:])
 	return *ap
 }
+
+// AppendSum224 appends the SHA224 checksum of the data to b and returns the
+// resulting slice. It does not allocate if b has enough spare capacity.
+func AppendSum224(b, data []byte) []byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha256.AppendSum224").End()
+	}
+	if boring.Enabled {
+		if boring.SupportsHash(cryptometrics.SHA224) {
+			b = append(b, make([]byte, Size224)...)
+			boring.SHA224Into(b[len(b)-Size224:], data)
+			return b
+		}
+		boring.RecordFallback(cryptometrics.SHA224, "unsupported hash")
+	}
+	var d digest
+	d.is224 = true
+	d.Reset()
+	d.Write(data)
+	sum := d.checkSum()
+	return append(b, sum[:Size224]...)
+}
@@ -9,6 +9,7 @@ package sha256
 import (
 	"bytes"
 	"crypto/internal/boring"
+	"crypto/internal/difftest"
 	"crypto/rand"
 	"encoding"
 	"fmt"
@@ -143,6 +144,14 @@ func TestGolden(t *testing.T) {
 			}
 			c.Reset()
 		}
+		bw := c.(io.ByteWriter)
+		for i := 0; i < len(g.in); i++ {
+			bw.WriteByte(g.in[i])
+		}
+		s = fmt.Sprintf("%x", c.Sum(nil))
+		if s != g.out {
+			t.Errorf("sha224[WriteByte](%s) = %s want %s", g.in, s, g.out)
+		}
 	}
 }
 
@@ -301,6 +310,80 @@ func TestLargeHashes(t *testing.T) {
 	}
 }
 
+func TestSum256Batch(t *testing.T) {
+	var data [][]byte
+	for _, n := range []int{0, 1, 55, 56, 64, 100, 4 << 10, 4<<10 + 1, 70 << 10, 3, 60 << 10, 8 << 10} {
+		m := make([]byte, n)
+		for i := range m {
+			m[i] = byte(i * n)
+		}
+		data = append(data, m)
+	}
+	sums := Sum256Batch(data)
+	if len(sums) != len(data) {
+		t.Fatalf("got %d checksums, want %d", len(sums), len(data))
+	}
+	for i, m := range data {
+		if want := Sum256(m); sums[i] != want {
+			t.Errorf("checksum of %d bytes = %x, want %x", len(m), sums[i], want)
+		}
+	}
+	if sums := Sum256Batch(nil); len(sums) != 0 {
+		t.Errorf("got %d checksums of no messages", len(sums))
+	}
+}
+
+func TestAppendSum(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, tt := range []struct {
+		appendSum func(b, data []byte) []byte
+		sum       func(data []byte) []byte
+	}{
+		{AppendSum256, func(data []byte) []byte { s := Sum256(data); return s[:] }},
+		{AppendSum224, func(data []byte) []byte { s := Sum224(data); return s[:] }},
+	} {
+		for _, n := range []int{0, 3, 64, 1000} {
+			want := tt.sum(data[:n])
+			buf := make([]byte, 2, 2+len(want))
+			got := tt.appendSum(buf, data[:n])
+			if !bytes.Equal(got[:2], buf) || !bytes.Equal(got[2:], want) {
+				t.Errorf("AppendSum of %d bytes = %x, want 0000%x", n, got, want)
+			}
+			if allocs := testing.AllocsPerRun(10, func() { tt.appendSum(buf, data[:n]) }); allocs > 0 {
+				t.Errorf("AppendSum allocates %v times, want 0", allocs)
+			}
+		}
+	}
+}
+
+func TestAppendBinary(t *testing.T) {
+	for _, newHash := range []func() hash.Hash{New, New224} {
+		h := newHash()
+		h.Write([]byte("hello"))
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := h.(interface{ AppendBinary([]byte) ([]byte, error) })
+
+		// The spare capacity is not zero, and must be overwritten.
+		buf := bytes.Repeat([]byte{0xff}, 3+len(state))[:3]
+		b, err := a.AppendBinary(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b[:3], []byte{0xff, 0xff, 0xff}) || !bytes.Equal(b[3:], state) {
+			t.Errorf("AppendBinary = %x, want ffffff followed by %x", b, state)
+		}
+		if n := testing.AllocsPerRun(10, func() { a.AppendBinary(buf[:0]) }); n > 0 {
+			t.Errorf("AppendBinary allocates %v times, want 0", n)
+		}
+	}
+}
+
 func TestAllocations(t *testing.T) {
 	if boring.Enabled {
 		t.Skip("BoringCrypto doesn't allocate the same way as stdlib")
@@ -397,3 +480,36 @@ func BenchmarkHash1K(b *testing.B) {
 func BenchmarkHash8K(b *testing.B) {
 	benchmarkSize(b, 8192)
 }
+
+func BenchmarkSum256Batch(b *testing.B) {
+	data := make([][]byte, 1000)
+	for i := range data {
+		data[i] = buf[:64]
+	}
+	b.SetBytes(int64(len(data) * 64))
+	for i := 0; i < b.N; i++ {
+		Sum256Batch(data)
+	}
+}
+
+func FuzzBackend(f *testing.F) {
+	if !boring.Enabled {
+		f.Skip("no cryptographic backend in use")
+	}
+	defer boring.AllowGoForTesting()()
+	f.Add([]byte("abc"))
+	f.Add(make([]byte, 1000))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, is224 := range []bool{false, true} {
+			want := &digest{is224: is224}
+			want.Reset()
+			got := New()
+			if is224 {
+				got = New224()
+			}
+			if err := difftest.Hash(want, got, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
@@ -13,9 +13,11 @@ package sha512
 import (
 	"crypto"
 	"crypto/internal/boring"
+	"crypto/internal/cryptotrace"
 	"encoding/binary"
 	"errors"
 	"hash"
+	"internal/cryptometrics"
 )
 
 func init() {
@@ -140,7 +142,10 @@ const (
 )
 
 func (d *digest) MarshalBinary() ([]byte, error) {
-	b := make([]byte, 0, marshaledSize)
+	return d.AppendBinary(make([]byte, 0, marshaledSize))
+}
+
+func (d *digest) AppendBinary(b []byte) ([]byte, error) {
 	switch d.function {
 	case crypto.SHA384:
 		b = append(b, magic384...)
@@ -162,7 +167,7 @@ func (d *digest) MarshalBinary() ([]byte, error) {
 	b = binary.BigEndian.AppendUint64(b, d.h[6])
 	b = binary.BigEndian.AppendUint64(b, d.h[7])
 	b = append(b, d.x[:d.nx]...)
-	b = b[:len(b)+len(d.x)-d.nx] // already zero
+	b = append(b, make([]byte, len(d.x)-d.nx)...)
 	b = binary.BigEndian.AppendUint64(b, d.len)
 	return b, nil
 }
@@ -216,6 +221,12 @@ func New() hash.Hash {
 
 // New512_224 returns a new hash.Hash computing the SHA-512/224 checksum.
 func New512_224() hash.Hash {
+	if boring.Enabled {
+		if boring.SupportsHash(cryptometrics.SHA512_224) {
+			return boring.NewSHA512_224()
+		}
+		boring.RecordFallback(cryptometrics.SHA512_224, "unsupported hash")
+	}
 	d := &digest{function: crypto.SHA512_224}
 	d.Reset()
 	return d
@@ -223,6 +234,12 @@ func New512_224() hash.Hash {
 
 // New512_256 returns a new hash.Hash computing the SHA-512/256 checksum.
 func New512_256() hash.Hash {
+	if boring.Enabled {
+		if boring.SupportsHash(cryptometrics.SHA512_256) {
+			return boring.NewSHA512_256()
+		}
+		boring.RecordFallback(cryptometrics.SHA512_256, "unsupported hash")
+	}
 	d := &digest{function: crypto.SHA512_256}
 	d.Reset()
 	return d
@@ -238,6 +255,22 @@ func New384() hash.Hash {
 	return d
 }
 
+// boringUnreachable marks the code of d as unreachable when BoringCrypto
+// is in use and implements d.
+func (d *digest) boringUnreachable() {
+	switch d.function {
+	case crypto.SHA512_224:
+		if !boring.SupportsHash(cryptometrics.SHA512_224) {
+			return
+		}
+	case crypto.SHA512_256:
+		if !boring.SupportsHash(cryptometrics.SHA512_256) {
+			return
+		}
+	}
+	boring.Unreachable()
+}
+
 func (d *digest) Size() int {
 	switch d.function {
 	case crypto.SHA512_224:
@@ -254,6 +287,9 @@ func (d *digest) Size() int {
 func (d *digest) BlockSize() int { return BlockSize }
 
 func (d *digest) Write(p []byte) (nn int, err error) {
+	if len(p) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.Write").End()
+	}
 	nn = len(p)
 	d.len += uint64(nn)
 	n := fillChunk(d, p)
@@ -293,9 +329,7 @@ func (d *digest) WriteString(s string) (nn int, err error) {
 
 // fillChunk fills the remainder of the current chunk, if any.
 func fillChunk[S []byte | string](d *digest, p S) int {
-	if d.function != crypto.SHA512_224 && d.function != crypto.SHA512_256 {
-		boring.Unreachable()
-	}
+	d.boringUnreachable()
 	if d.nx == 0 {
 		return 0
 	}
@@ -309,9 +343,7 @@ func fillChunk[S []byte | string](d *digest, p S) int {
 }
 
 func (d *digest) WriteByte(c byte) error {
-	if d.function != crypto.SHA512_224 && d.function != crypto.SHA512_256 {
-		boring.Unreachable()
-	}
+	d.boringUnreachable()
 	d.len++
 	d.x[d.nx] = c
 	d.nx++
@@ -323,9 +355,7 @@ func (d *digest) WriteByte(c byte) error {
 }
 
 func (d *digest) Sum(in []byte) []byte {
-	if d.function != crypto.SHA512_224 && d.function != crypto.SHA512_256 {
-		boring.Unreachable()
-	}
+	d.boringUnreachable()
 	// Make a copy of d so that caller can keep writing and summing.
 	d0 := new(digest)
 	*d0 = *d
@@ -384,6 +414,9 @@ func (d *digest) checkSum() [Size]byte {
 
 // Sum512 returns the SHA512 checksum of the data.
 func Sum512(data []byte) [Size]byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.Sum512").End()
+	}
 	if boring.Enabled {
 		return boring.SHA512(data)
 	}
@@ -395,6 +428,9 @@ func Sum512(data []byte) [Size]byte {
 
 // Sum384 returns the SHA384 checksum of the data.
 func Sum384(data []byte) [Size384]byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.Sum384").End()
+	}
 	if boring.Enabled {
 		return boring.SHA384(data)
 	}
@@ -406,8 +442,53 @@ func Sum384(data []byte) [Size384]byte {
 	return *ap
 }
 
+// AppendSum512 appends the SHA512 checksum of the data to b and returns the
+// resulting slice. It does not allocate if b has enough spare capacity.
+func AppendSum512(b, data []byte) []byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.AppendSum512").End()
+	}
+	if boring.Enabled {
+		b = append(b, make([]byte, Size)...)
+		boring.SHA512Into(b[len(b)-Size:], data)
+		return b
+	}
+	d := digest{function: crypto.SHA512}
+	d.Reset()
+	d.Write(data)
+	sum := d.checkSum()
+	return append(b, sum[:]...)
+}
+
+// AppendSum384 appends the SHA384 checksum of the data to b and returns the
+// resulting slice. It does not allocate if b has enough spare capacity.
+func AppendSum384(b, data []byte) []byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.AppendSum384").End()
+	}
+	if boring.Enabled {
+		b = append(b, make([]byte, Size384)...)
+		boring.SHA384Into(b[len(b)-Size384:], data)
+		return b
+	}
+	d := digest{function: crypto.SHA384}
+	d.Reset()
+	d.Write(data)
+	sum := d.checkSum()
+	return append(b, sum[:Size384]...)
+}
+
 // Sum512_224 returns the Sum512/224 checksum of the data.
 func Sum512_224(data []byte) [Size224]byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.Sum512_224").End()
+	}
+	if boring.Enabled {
+		if boring.SupportsHash(cryptometrics.SHA512_224) {
+			return boring.SHA512_224(data)
+		}
+		boring.RecordFallback(cryptometrics.SHA512_224, "unsupported hash")
+	}
 	d := digest{function: crypto.SHA512_224}
 	d.Reset()
 	d.Write(data)
@@ -418,6 +499,15 @@ func Sum512_224(data []byte) [Size224]byte {
 
 // Sum512_256 returns the Sum512/256 checksum of the data.
 func Sum512_256(data []byte) [Size256]byte {
+	if len(data) >= cryptotrace.LargeInput {
+		defer cryptotrace.StartRegion("crypto/sha512.Sum512_256").End()
+	}
+	if boring.Enabled {
+		if boring.SupportsHash(cryptometrics.SHA512_256) {
+			return boring.SHA512_256(data)
+		}
+		boring.RecordFallback(cryptometrics.SHA512_256, "unsupported hash")
+	}
 	d := digest{function: crypto.SHA512_256}
 	d.Reset()
 	d.Write(data)
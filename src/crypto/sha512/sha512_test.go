@@ -8,7 +8,9 @@ package sha512
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/internal/boring"
+	"crypto/internal/difftest"
 	"crypto/rand"
 	"encoding"
 	"encoding/hex"
@@ -901,6 +903,57 @@ func TestLargeHashes(t *testing.T) {
 	}
 }
 
+func TestAppendSum(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, tt := range []struct {
+		appendSum func(b, data []byte) []byte
+		sum       func(data []byte) []byte
+	}{
+		{AppendSum512, func(data []byte) []byte { s := Sum512(data); return s[:] }},
+		{AppendSum384, func(data []byte) []byte { s := Sum384(data); return s[:] }},
+	} {
+		for _, n := range []int{0, 3, 64, 1000} {
+			want := tt.sum(data[:n])
+			buf := make([]byte, 2, 2+len(want))
+			got := tt.appendSum(buf, data[:n])
+			if !bytes.Equal(got[:2], buf) || !bytes.Equal(got[2:], want) {
+				t.Errorf("AppendSum of %d bytes = %x, want 0000%x", n, got, want)
+			}
+			if allocs := testing.AllocsPerRun(10, func() { tt.appendSum(buf, data[:n]) }); allocs > 0 {
+				t.Errorf("AppendSum allocates %v times, want 0", allocs)
+			}
+		}
+	}
+}
+
+func TestAppendBinary(t *testing.T) {
+	for _, newHash := range []func() hash.Hash{New, New384, New512_224, New512_256} {
+		h := newHash()
+		h.Write([]byte("hello"))
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := h.(interface{ AppendBinary([]byte) ([]byte, error) })
+
+		// The spare capacity is not zero, and must be overwritten.
+		buf := bytes.Repeat([]byte{0xff}, 3+len(state))[:3]
+		b, err := a.AppendBinary(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b[:3], []byte{0xff, 0xff, 0xff}) || !bytes.Equal(b[3:], state) {
+			t.Errorf("AppendBinary = %x, want ffffff followed by %x", b, state)
+		}
+		if n := testing.AllocsPerRun(10, func() { a.AppendBinary(buf[:0]) }); n > 0 {
+			t.Errorf("AppendBinary allocates %v times, want 0", n)
+		}
+	}
+}
+
 func TestAllocations(t *testing.T) {
 	if boring.Enabled {
 		t.Skip("BoringCrypto doesn't allocate the same way as stdlib")
@@ -982,3 +1035,29 @@ func BenchmarkHash1K(b *testing.B) {
 func BenchmarkHash8K(b *testing.B) {
 	benchmarkSize(b, 8192)
 }
+
+func FuzzBackend(f *testing.F) {
+	if !boring.Enabled {
+		f.Skip("no cryptographic backend in use")
+	}
+	defer boring.AllowGoForTesting()()
+	f.Add([]byte("abc"))
+	f.Add(make([]byte, 1000))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, h := range []struct {
+			function crypto.Hash
+			new      func() hash.Hash
+		}{
+			{crypto.SHA384, New384},
+			{crypto.SHA512, New},
+			{crypto.SHA512_224, New512_224},
+			{crypto.SHA512_256, New512_256},
+		} {
+			want := &digest{function: h.function}
+			want.Reset()
+			if err := difftest.Hash(want, h.new(), data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
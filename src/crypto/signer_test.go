@@ -0,0 +1,83 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	_ "crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+)
+
+// digestSigner returns its input as the signature.
+type digestSigner struct{}
+
+func (digestSigner) Public() crypto.PublicKey { return nil }
+
+func (digestSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return append([]byte("digest:"), digest...), nil
+}
+
+type messageSigner struct{ digestSigner }
+
+func (messageSigner) SignMessage(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return append([]byte("message:"), msg...), nil
+}
+
+type ctxSigner struct{ digestSigner }
+
+func (ctxSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return append([]byte("context:"), digest...), nil
+}
+
+func TestSignMessage(t *testing.T) {
+	msg := []byte("hello")
+	h := crypto.SHA256.New()
+	h.Write(msg)
+	digest := h.Sum(nil)
+
+	tests := []struct {
+		signer crypto.Signer
+		opts   crypto.SignerOpts
+		want   []byte
+	}{
+		{digestSigner{}, crypto.SHA256, append([]byte("digest:"), digest...)},
+		{digestSigner{}, crypto.Hash(0), []byte("digest:hello")},
+		{messageSigner{}, crypto.SHA256, []byte("message:hello")},
+	}
+	for _, tt := range tests {
+		sig, err := crypto.SignMessage(tt.signer, nil, msg, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(sig, tt.want) {
+			t.Errorf("SignMessage(%T, %v) = %q, want %q", tt.signer, tt.opts, sig, tt.want)
+		}
+	}
+
+	if _, err := crypto.SignMessage(digestSigner{}, nil, msg, crypto.MD4); err == nil {
+		t.Errorf("SignMessage with an unavailable hash succeeded")
+	}
+}
+
+func TestSignContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig, err := crypto.SignContext(ctx, digestSigner{}, nil, []byte("x"), crypto.Hash(0))
+	if err != nil || string(sig) != "digest:x" {
+		t.Errorf("SignContext(digestSigner) = %q, %v", sig, err)
+	}
+	sig, err = crypto.SignContext(ctx, ctxSigner{}, nil, []byte("x"), crypto.Hash(0))
+	if err != nil || string(sig) != "context:x" {
+		t.Errorf("SignContext(ctxSigner) = %q, %v", sig, err)
+	}
+
+	cancel()
+	if _, err := crypto.SignContext(ctx, digestSigner{}, nil, []byte("x"), crypto.Hash(0)); !errors.Is(err, context.Canceled) {
+		t.Errorf("SignContext after cancel returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sri implements Subresource Integrity metadata, the "integrity"
+// attribute of HTML script and link elements, as specified by
+// https://www.w3.org/TR/SRI/.
+//
+// Integrity metadata is a whitespace-separated list of digests, such as
+// "sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC".
+// The SHA-256, SHA-384, and SHA-512 algorithms are supported. As in
+// browsers, only the digests with the strongest algorithm in a list are
+// used, and a resource matches if any of them matches.
+package sri
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrMismatch is returned when a resource does not match its integrity
+// metadata.
+var ErrMismatch = errors.New("sri: digest mismatch")
+
+// A Digest is a single entry of integrity metadata.
+type Digest struct {
+	Hash  crypto.Hash // crypto.SHA256, crypto.SHA384, or crypto.SHA512
+	Value []byte
+}
+
+// String returns the "alg-base64" encoding of d.
+func (d Digest) String() string {
+	return algorithmName(d.Hash) + "-" + base64.StdEncoding.EncodeToString(d.Value)
+}
+
+// Metadata is parsed integrity metadata.
+type Metadata struct {
+	// Digests are the supported digests of the metadata, in order.
+	Digests []Digest
+}
+
+// Parse parses integrity metadata. Entries with an unsupported algorithm
+// or a malformed value are ignored, as they are by browsers, and options
+// following a "?" are discarded.
+//
+// Unlike browsers, which load a resource without checks if its metadata
+// has no supported entries, Parse returns an error in that case.
+func Parse(s string) (*Metadata, error) {
+	m := &Metadata{}
+	for _, token := range strings.Fields(s) {
+		token, _, _ = strings.Cut(token, "?")
+		alg, value, ok := strings.Cut(token, "-")
+		if !ok {
+			continue
+		}
+		h := algorithmHash(alg)
+		if h == 0 {
+			continue
+		}
+		v, err := decodeBase64(value)
+		if err != nil || len(v) != h.Size() {
+			continue
+		}
+		m.Digests = append(m.Digests, Digest{Hash: h, Value: v})
+	}
+	if len(m.Digests) == 0 {
+		return nil, errors.New("sri: no supported digest in integrity metadata")
+	}
+	return m, nil
+}
+
+// decodeBase64 decodes the standard base64 of the specification, or the
+// URL-safe alphabet that browsers also accept, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	if strings.ContainsAny(s, "-_") {
+		s = strings.NewReplacer("-", "+", "_", "/").Replace(s)
+	}
+	return base64.RawStdEncoding.Strict().DecodeString(strings.TrimRight(s, "="))
+}
+
+// String returns the encoding of m, with its digests separated by spaces.
+func (m *Metadata) String() string {
+	s := make([]string, len(m.Digests))
+	for i, d := range m.Digests {
+		s[i] = d.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// strongest returns the strongest algorithm of m.
+func (m *Metadata) strongest() crypto.Hash {
+	var best crypto.Hash
+	for _, d := range m.Digests {
+		if best == 0 || d.Hash.Size() > best.Size() {
+			best = d.Hash
+		}
+	}
+	return best
+}
+
+// Verify reads r to EOF and checks that its contents match m. It returns
+// ErrMismatch if they don't.
+func (m *Metadata) Verify(r io.Reader) error {
+	h := m.strongest()
+	if h == 0 {
+		return errors.New("sri: no digests")
+	}
+	sum, err := digest(h, r)
+	if err != nil {
+		return err
+	}
+	for _, d := range m.Digests {
+		if d.Hash == h && subtle.ConstantTimeCompare(d.Value, sum) == 1 {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+// VerifyFile checks that the contents of the named file match m. It
+// returns ErrMismatch if they don't.
+func (m *Metadata) VerifyFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Verify(f)
+}
+
+// Sum reads r to EOF and returns the integrity metadata of its contents,
+// hashed with h, which must be crypto.SHA256, crypto.SHA384, or
+// crypto.SHA512.
+func Sum(h crypto.Hash, r io.Reader) (string, error) {
+	sum, err := digest(h, r)
+	if err != nil {
+		return "", err
+	}
+	return Digest{Hash: h, Value: sum}.String(), nil
+}
+
+// SumFile returns the integrity metadata of the contents of the named
+// file, hashed with h, which must be crypto.SHA256, crypto.SHA384, or
+// crypto.SHA512.
+func SumFile(h crypto.Hash, name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return Sum(h, f)
+}
+
+func digest(h crypto.Hash, r io.Reader) ([]byte, error) {
+	var d hash.Hash
+	switch h {
+	case crypto.SHA256:
+		d = sha256.New()
+	case crypto.SHA384:
+		d = sha512.New384()
+	case crypto.SHA512:
+		d = sha512.New()
+	default:
+		return nil, errors.New("sri: unsupported hash function " + h.String())
+	}
+	if _, err := io.Copy(d, r); err != nil {
+		return nil, err
+	}
+	return d.Sum(nil), nil
+}
+
+func algorithmName(h crypto.Hash) string {
+	switch h {
+	case crypto.SHA256:
+		return "sha256"
+	case crypto.SHA384:
+		return "sha384"
+	case crypto.SHA512:
+		return "sha512"
+	}
+	return ""
+}
+
+// algorithmHash returns the hash function of an algorithm name, or zero if
+// it is not supported.
+func algorithmHash(name string) crypto.Hash {
+	switch name {
+	case "sha256":
+		return crypto.SHA256
+	case "sha384":
+		return crypto.SHA384
+	case "sha512":
+		return crypto.SHA512
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sri
+
+import (
+	"crypto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testScript    = "alert('Hello, world.');"
+	testIntegrity = "sha384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO"
+)
+
+func TestSum(t *testing.T) {
+	got, err := Sum(crypto.SHA384, strings.NewReader(testScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != testIntegrity {
+		t.Errorf("Sum = %s, want %s", got, testIntegrity)
+	}
+	if _, err := Sum(crypto.SHA1, strings.NewReader(testScript)); err == nil {
+		t.Errorf("Sum with SHA-1 succeeded")
+	}
+
+	name := filepath.Join(t.TempDir(), "script.js")
+	if err := os.WriteFile(name, []byte(testScript), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := SumFile(crypto.SHA384, name); err != nil || got != testIntegrity {
+		t.Errorf("SumFile = %s, %v; want %s", got, err, testIntegrity)
+	}
+	m, err := Parse(testIntegrity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.VerifyFile(name); err != nil {
+		t.Errorf("VerifyFile: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	sha256, _ := Sum(crypto.SHA256, strings.NewReader(testScript))
+	sha512, _ := Sum(crypto.SHA512, strings.NewReader(testScript))
+	other512, _ := Sum(crypto.SHA512, strings.NewReader("other"))
+	urlSafe := strings.NewReplacer("+", "-", "/", "_").Replace(testIntegrity)
+
+	for _, tt := range []struct {
+		integrity string
+		ok        bool
+	}{
+		{testIntegrity, true},
+		{sha256, true},
+		{sha512, true},
+		{urlSafe, true},
+		{strings.TrimRight(sha256, "="), true},
+		{testIntegrity + "?ct=application/javascript", true},
+		{"  md5-Tm90IGEgcmVhbCBkaWdlc3Q= \t" + testIntegrity + "\n", true},
+		// Only the strongest algorithm counts.
+		{sha256 + " " + other512, false},
+		{other512 + " " + sha512, true},
+		{testIntegrity + " " + other512, false},
+		// Malformed entries are ignored.
+		{testIntegrity + " sha512-!!!", true},
+		{testIntegrity + " sha512-" + strings.Repeat("A", 43), true},
+		{"sha384-" + strings.Repeat("A", 64), false},
+	} {
+		m, err := Parse(tt.integrity)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.integrity, err)
+			continue
+		}
+		err = m.Verify(strings.NewReader(testScript))
+		if tt.ok && err != nil {
+			t.Errorf("Verify(%q): %v", tt.integrity, err)
+		}
+		if !tt.ok && err != ErrMismatch {
+			t.Errorf("Verify(%q) = %v, want ErrMismatch", tt.integrity, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	sha256, _ := Sum(crypto.SHA256, strings.NewReader(testScript))
+	m, err := Parse(" " + sha256 + "?opt  unknown-abc " + testIntegrity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Digests) != 2 || m.Digests[0].Hash != crypto.SHA256 || m.Digests[1].Hash != crypto.SHA384 {
+		t.Errorf("Parse returned %v", m.Digests)
+	}
+	if got, want := m.String(), sha256+" "+testIntegrity; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, s := range []string{
+		"",
+		"   ",
+		"sha1-2jmj7l5rSw0yVb/vlWAYkK/YBwk=",
+		"SHA384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO",
+		"sha384",
+		"sha384-",
+		"sha256-" + strings.Repeat("A", 40),
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
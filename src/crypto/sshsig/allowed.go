@@ -0,0 +1,303 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshsig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// AllowedSigners is a parsed allowed signers file, which maps principals,
+// such as email addresses, to the keys that may sign on their behalf. It is
+// the file named by Git's gpg.ssh.allowedSignersFile setting and read by
+// "ssh-keygen -Y verify". See the ALLOWED SIGNERS section of ssh-keygen(1).
+type AllowedSigners struct {
+	signers []allowedSigner
+}
+
+type allowedSigner struct {
+	principals  string // pattern list
+	namespaces  string // pattern list, or empty to allow any namespace
+	validAfter  time.Time
+	validBefore time.Time
+	key         []byte // SSH encoding of the public key
+}
+
+// ParseAllowedSigners parses the contents of an allowed signers file. Each
+// line holds a comma-separated list of principal patterns, optional options,
+// and a public key in authorized_keys format. The namespaces, valid-after,
+// and valid-before options are supported. Lines with the cert-authority
+// option are accepted but never match, since certificates are not
+// supported.
+func ParseAllowedSigners(data []byte) (*AllowedSigners, error) {
+	a := &AllowedSigners{}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		s, ok, err := parseAllowedSigner(line)
+		if err != nil {
+			return nil, errors.New("sshsig: allowed signers line " + strconv.Itoa(n+1) + ": " + err.Error())
+		}
+		if ok {
+			a.signers = append(a.signers, s)
+		}
+	}
+	return a, nil
+}
+
+// parseAllowedSigner parses a line of an allowed signers file. It reports
+// false for lines that must be ignored.
+func parseAllowedSigner(line string) (s allowedSigner, ok bool, err error) {
+	var certAuthority bool
+	principals, rest := nextField(line)
+	s.principals = unquote(principals)
+	if s.principals == "" {
+		return s, false, errors.New("missing principals")
+	}
+
+	field, rest := nextField(rest)
+	if !isKeyType(field) {
+		// The field is a list of options.
+		for _, opt := range splitOptions(field) {
+			name, value, hasValue := strings.Cut(opt, "=")
+			value = unquote(value)
+			switch strings.ToLower(name) {
+			case "cert-authority":
+				certAuthority = true
+			case "namespaces":
+				s.namespaces = value
+			case "valid-after":
+				s.validAfter, err = parseTimestamp(value)
+			case "valid-before":
+				s.validBefore, err = parseTimestamp(value)
+			default:
+				return s, false, errors.New("unknown option " + name)
+			}
+			if err != nil {
+				return s, false, err
+			}
+			if hasValue == (name == "cert-authority") {
+				return s, false, errors.New("malformed option " + name)
+			}
+		}
+		field, rest = nextField(rest)
+	}
+
+	encoded, _ := nextField(rest)
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return s, false, errors.New("malformed public key")
+	}
+	if certAuthority || !isSupportedKeyType(field) {
+		return s, false, nil
+	}
+	pub, err := parsePublicKey(key)
+	if err != nil {
+		return s, false, err
+	}
+	keyType := cryptobyte.String(key)
+	var name string
+	if !readString(&keyType, &name) || name != field {
+		return s, false, errors.New("key type does not match public key")
+	}
+	// Store the canonical encoding, for comparing with signatures.
+	if s.key, err = marshalPublicKey(pub); err != nil {
+		return s, false, err
+	}
+	return s, true, nil
+}
+
+// Verify checks that armored is a valid signature of message in namespace,
+// by a key that the allowed signers file lists for principal and for
+// namespace at time now. If now is the zero Time, the current time is used.
+func (a *AllowedSigners) Verify(principal, namespace string, armored, message []byte, now time.Time) error {
+	sig, err := ParseSignature(armored)
+	if err != nil {
+		return err
+	}
+	if err := sig.verify(message, namespace); err != nil {
+		return err
+	}
+	for _, s := range a.signers {
+		if s.allows(sig, now) && matchPatternList(principal, s.principals) {
+			return nil
+		}
+	}
+	return errors.New("sshsig: signing key is not allowed for principal " + strconv.Quote(principal))
+}
+
+// Principals returns the principal patterns of the entries of the allowed
+// signers file that match the key and namespace of sig at time now, in file
+// order. If now is the zero Time, the current time is used. It does not
+// verify sig.
+func (a *AllowedSigners) Principals(sig *Signature, now time.Time) []string {
+	var principals []string
+	for _, s := range a.signers {
+		if s.allows(sig, now) {
+			principals = append(principals, s.principals)
+		}
+	}
+	return principals
+}
+
+// allows reports whether the entry allows the key and namespace of sig at
+// time now.
+func (s *allowedSigner) allows(sig *Signature, now time.Time) bool {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if !bytes.Equal(s.key, sig.publicKey) {
+		return false
+	}
+	if s.namespaces != "" && !matchPatternList(sig.Namespace, s.namespaces) {
+		return false
+	}
+	if !s.validAfter.IsZero() && now.Before(s.validAfter) {
+		return false
+	}
+	if !s.validBefore.IsZero() && !now.Before(s.validBefore) {
+		return false
+	}
+	return true
+}
+
+// nextField returns the next space-separated field of s, which may be
+// double-quoted, and the rest of s.
+func nextField(s string) (field, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ' ', '\t':
+			if !quoted {
+				return s[:i], s[i:]
+			}
+		}
+	}
+	return s, ""
+}
+
+// splitOptions splits a comma-separated list of options, which may contain
+// double-quoted values.
+func splitOptions(s string) []string {
+	var opts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				opts = append(opts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(opts, s[start:])
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func isKeyType(s string) bool {
+	// Security keys and certificates are recognized, but ignored.
+	return isSupportedKeyType(s) || strings.HasPrefix(s, "sk-") ||
+		strings.HasSuffix(s, "-cert-v01@openssh.com")
+}
+
+func isSupportedKeyType(s string) bool {
+	switch s {
+	case keyEd25519, keyRSA, keyECDSAP256, keyECDSAP384, keyECDSAP521:
+		return true
+	}
+	return false
+}
+
+// parseTimestamp parses a timestamp of the form YYYYMMDD[HHMM[SS]], in the
+// local time zone or, with a Z suffix, in UTC.
+func parseTimestamp(s string) (time.Time, error) {
+	loc := time.Local
+	if t, ok := strings.CutSuffix(s, "Z"); ok {
+		s, loc = t, time.UTC
+	}
+	var layout string
+	switch len(s) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, errors.New("malformed timestamp " + strconv.Quote(s))
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, errors.New("malformed timestamp " + strconv.Quote(s))
+	}
+	return t, nil
+}
+
+// matchPatternList reports whether s matches the comma-separated list of
+// patterns, which may use the * and ? wildcards. A pattern prefixed with !
+// excludes the strings it matches, even if other patterns match them.
+func matchPatternList(s, patterns string) bool {
+	matched := false
+	for _, p := range strings.Split(patterns, ",") {
+		negated := strings.HasPrefix(p, "!")
+		if negated {
+			p = p[1:]
+		}
+		if !matchPattern(s, p) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// matchPattern reports whether s matches p, in which * matches any sequence
+// of bytes and ? matches any single byte.
+func matchPattern(s, p string) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if matchPattern(s[i:], p[1:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || s[0] != p[0] {
+				return false
+			}
+		}
+		s, p = s[1:], p[1:]
+	}
+	return len(s) == 0
+}
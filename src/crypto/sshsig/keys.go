@@ -0,0 +1,307 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// SSH public key and signature algorithm names, from RFC 4253, RFC 5656,
+// RFC 8332, and RFC 8709.
+const (
+	keyEd25519   = "ssh-ed25519"
+	keyRSA       = "ssh-rsa"
+	keyECDSAP256 = "ecdsa-sha2-nistp256"
+	keyECDSAP384 = "ecdsa-sha2-nistp384"
+	keyECDSAP521 = "ecdsa-sha2-nistp521"
+
+	sigRSASHA256 = "rsa-sha2-256"
+	sigRSASHA512 = "rsa-sha2-512"
+)
+
+// marshalPublicKey returns the SSH wire encoding of pub, as used in
+// signatures and in authorized_keys and allowed_signers files.
+func marshalPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	var b cryptobyte.Builder
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		addString(&b, keyEd25519)
+		addBytes(&b, pub)
+	case *ecdsa.PublicKey:
+		name, curve, err := ecdsaNames(pub.Curve)
+		if err != nil {
+			return nil, err
+		}
+		addString(&b, name)
+		addString(&b, curve)
+		addBytes(&b, elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	case *rsa.PublicKey:
+		addString(&b, keyRSA)
+		addMPInt(&b, big.NewInt(int64(pub.E)))
+		addMPInt(&b, pub.N)
+	default:
+		return nil, errors.New("sshsig: unsupported public key type")
+	}
+	return b.Bytes()
+}
+
+// parsePublicKey parses the SSH wire encoding of a public key.
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	s := cryptobyte.String(data)
+	var name string
+	if !readString(&s, &name) {
+		return nil, errors.New("sshsig: malformed public key")
+	}
+	switch name {
+	case keyEd25519:
+		var key []byte
+		if !readBytes(&s, &key) || !s.Empty() || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("sshsig: malformed Ed25519 public key")
+		}
+		return ed25519.PublicKey(key), nil
+	case keyECDSAP256, keyECDSAP384, keyECDSAP521:
+		var curveName string
+		var point []byte
+		if !readString(&s, &curveName) || !readBytes(&s, &point) || !s.Empty() {
+			return nil, errors.New("sshsig: malformed ECDSA public key")
+		}
+		curve := ecdsaCurve(name)
+		if _, c, _ := ecdsaNames(curve); c != curveName {
+			return nil, errors.New("sshsig: mismatched ECDSA curve")
+		}
+		x, y := elliptic.Unmarshal(curve, point)
+		if x == nil {
+			return nil, errors.New("sshsig: invalid ECDSA public key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case keyRSA:
+		e, n := new(big.Int), new(big.Int)
+		if !readMPInt(&s, e) || !readMPInt(&s, n) || !s.Empty() {
+			return nil, errors.New("sshsig: malformed RSA public key")
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 || e.Bit(0) != 1 {
+			return nil, errors.New("sshsig: unsupported RSA public exponent")
+		}
+		if n.BitLen() < 1024 {
+			return nil, errors.New("sshsig: RSA key too small")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	default:
+		return nil, errors.New("sshsig: unsupported public key algorithm " + name)
+	}
+}
+
+func ecdsaNames(curve elliptic.Curve) (key, curveName string, err error) {
+	switch curve {
+	case elliptic.P256():
+		return keyECDSAP256, "nistp256", nil
+	case elliptic.P384():
+		return keyECDSAP384, "nistp384", nil
+	case elliptic.P521():
+		return keyECDSAP521, "nistp521", nil
+	}
+	return "", "", errors.New("sshsig: unsupported ECDSA curve")
+}
+
+func ecdsaCurve(key string) elliptic.Curve {
+	switch key {
+	case keyECDSAP256:
+		return elliptic.P256()
+	case keyECDSAP384:
+		return elliptic.P384()
+	default:
+		return elliptic.P521()
+	}
+}
+
+// ecdsaHash returns the hash that the SSH ECDSA algorithm for curve uses
+// over the signed data, per RFC 5656, Section 6.2.1.
+func ecdsaHash(curve elliptic.Curve) crypto.Hash {
+	switch curve.Params().BitSize {
+	case 256:
+		return crypto.SHA256
+	case 384:
+		return crypto.SHA384
+	default:
+		return crypto.SHA512
+	}
+}
+
+// sign signs data with priv and returns the SSH signature encoding: the
+// algorithm name and the signature blob.
+func sign(rand io.Reader, priv crypto.Signer, data []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	switch pub := priv.Public().(type) {
+	case ed25519.PublicKey:
+		sig, err := priv.Sign(rand, data, crypto.Hash(0))
+		if err != nil {
+			return nil, err
+		}
+		addString(&b, keyEd25519)
+		addBytes(&b, sig)
+	case *ecdsa.PublicKey:
+		name, _, err := ecdsaNames(pub.Curve)
+		if err != nil {
+			return nil, err
+		}
+		h := ecdsaHash(pub.Curve)
+		sig, err := priv.Sign(rand, hashData(h, data), h)
+		if err != nil {
+			return nil, err
+		}
+		r, s := new(big.Int), new(big.Int)
+		in := cryptobyte.String(sig)
+		var inner cryptobyte.String
+		if !in.ReadASN1(&inner, cryptobyte_asn1.SEQUENCE) || !in.Empty() ||
+			!inner.ReadASN1Integer(r) || !inner.ReadASN1Integer(s) || !inner.Empty() {
+			return nil, errors.New("sshsig: signer returned a malformed ECDSA signature")
+		}
+		addString(&b, name)
+		b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+			addMPInt(b, r)
+			addMPInt(b, s)
+		})
+	case *rsa.PublicKey:
+		sig, err := priv.Sign(rand, hashData(crypto.SHA512, data), crypto.SHA512)
+		if err != nil {
+			return nil, err
+		}
+		addString(&b, sigRSASHA512)
+		addBytes(&b, sig)
+	default:
+		return nil, errors.New("sshsig: unsupported public key type")
+	}
+	return b.Bytes()
+}
+
+// verify checks the SSH signature encoding sig of data by pub.
+func verify(pub crypto.PublicKey, data, sig []byte) error {
+	s := cryptobyte.String(sig)
+	var name string
+	var blob []byte
+	if !readString(&s, &name) || !readBytes(&s, &blob) || !s.Empty() {
+		return errors.New("sshsig: malformed signature")
+	}
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		if name != keyEd25519 {
+			break
+		}
+		if !ed25519.Verify(pub, data, blob) {
+			return errVerification
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if keyName, _, _ := ecdsaNames(pub.Curve); name != keyName {
+			break
+		}
+		r, s := new(big.Int), new(big.Int)
+		in := cryptobyte.String(blob)
+		if !readMPInt(&in, r) || !readMPInt(&in, s) || !in.Empty() {
+			return errors.New("sshsig: malformed ECDSA signature")
+		}
+		if !ecdsa.Verify(pub, hashData(ecdsaHash(pub.Curve), data), r, s) {
+			return errVerification
+		}
+		return nil
+	case *rsa.PublicKey:
+		var h crypto.Hash
+		switch name {
+		case sigRSASHA256:
+			h = crypto.SHA256
+		case sigRSASHA512:
+			h = crypto.SHA512
+		default:
+			// ssh-rsa signatures, which use SHA-1, are not accepted.
+			return errors.New("sshsig: unsupported RSA signature algorithm " + name)
+		}
+		if rsa.VerifyPKCS1v15(pub, h, hashData(h, data), blob) != nil {
+			return errVerification
+		}
+		return nil
+	default:
+		return errors.New("sshsig: unsupported public key type")
+	}
+	return errors.New("sshsig: signature algorithm " + name + " does not match the public key")
+}
+
+func hashData(h crypto.Hash, data []byte) []byte {
+	switch h {
+	case crypto.SHA256:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	default:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+}
+
+// The SSH wire encoding of strings and multiple precision integers, from
+// RFC 4251, Section 5.
+
+func addString(b *cryptobyte.Builder, s string) {
+	addBytes(b, []byte(s))
+}
+
+func addBytes(b *cryptobyte.Builder, v []byte) {
+	b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(v)
+	})
+}
+
+func addMPInt(b *cryptobyte.Builder, n *big.Int) {
+	b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+		v := n.Bytes()
+		if len(v) > 0 && v[0]&0x80 != 0 {
+			b.AddUint8(0)
+		}
+		b.AddBytes(v)
+	})
+}
+
+func readString(s *cryptobyte.String, out *string) bool {
+	var v []byte
+	if !readBytes(s, &v) {
+		return false
+	}
+	*out = string(v)
+	return true
+}
+
+func readBytes(s *cryptobyte.String, out *[]byte) bool {
+	var n uint32
+	if !s.ReadUint32(&n) || uint64(n) > uint64(len(*s)) {
+		return false
+	}
+	return s.ReadBytes(out, int(n))
+}
+
+// readMPInt reads a non-negative mpint.
+func readMPInt(s *cryptobyte.String, out *big.Int) bool {
+	var v []byte
+	if !readBytes(s, &v) {
+		return false
+	}
+	if len(v) > 0 && v[0]&0x80 != 0 {
+		return false
+	}
+	out.SetBytes(v)
+	return true
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sshsig implements the detached signature format of OpenSSH, as
+// produced by "ssh-keygen -Y sign" and used by Git to sign commits and tags
+// with SSH keys.
+//
+// Ed25519, ECDSA (P-256, P-384, and P-521), and RSA keys are supported. RSA
+// signatures are made with rsa-sha2-512; signatures using SHA-1 are rejected.
+// Security keys (sk-*) and certificates are not supported.
+//
+// Every signature is bound to a namespace, such as "git" or "file", so that
+// a signature made for one purpose can't be used for another.
+//
+// See https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig.
+package sshsig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	magicPreamble = "SSHSIG"
+	sigVersion    = 1
+	pemType       = "SSH SIGNATURE"
+)
+
+var errVerification = errors.New("sshsig: verification error")
+
+// A Signature is a parsed SSH signature. Its contents are not authenticated
+// until it is verified.
+type Signature struct {
+	// PublicKey is the key that claims to have made the signature, an
+	// ed25519.PublicKey, *ecdsa.PublicKey, or *rsa.PublicKey.
+	PublicKey crypto.PublicKey
+
+	// Namespace is the namespace the signature is bound to.
+	Namespace string
+
+	// HashAlgorithm is the hash applied to the message, "sha256" or
+	// "sha512".
+	HashAlgorithm string
+
+	publicKey []byte // the SSH encoding of PublicKey
+	signature []byte // the SSH signature encoding
+}
+
+// Sign signs message with priv for use in namespace, which must not be
+// empty, and returns the PEM-armored signature. The message is hashed with
+// SHA-512, like ssh-keygen does.
+//
+// priv must have an ed25519.PublicKey, *ecdsa.PublicKey or *rsa.PublicKey
+// public key. rand is passed to priv.Sign.
+func Sign(rand io.Reader, priv crypto.Signer, namespace string, message []byte) ([]byte, error) {
+	if namespace == "" {
+		return nil, errors.New("sshsig: empty namespace")
+	}
+	pub, err := marshalPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(rand, priv, signedData(namespace, "sha512", message))
+	if err != nil {
+		return nil, err
+	}
+
+	var b cryptobyte.Builder
+	b.AddBytes([]byte(magicPreamble))
+	b.AddUint32(sigVersion)
+	addBytes(&b, pub)
+	addString(&b, namespace)
+	addString(&b, "") // reserved
+	addString(&b, "sha512")
+	addBytes(&b, sig)
+	blob, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: blob}), nil
+}
+
+// ParseSignature parses a PEM-armored SSH signature. It does not verify it.
+func ParseSignature(armored []byte) (*Signature, error) {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != pemType || len(block.Headers) != 0 {
+		return nil, errors.New("sshsig: no SSH SIGNATURE PEM block found")
+	}
+	s := cryptobyte.String(block.Bytes)
+	var preamble []byte
+	var version uint32
+	sig := &Signature{}
+	var reserved []byte
+	if !s.ReadBytes(&preamble, len(magicPreamble)) || string(preamble) != magicPreamble ||
+		!s.ReadUint32(&version) {
+		return nil, errors.New("sshsig: malformed signature")
+	}
+	if version != sigVersion {
+		return nil, errors.New("sshsig: unsupported signature version")
+	}
+	if !readBytes(&s, &sig.publicKey) || !readString(&s, &sig.Namespace) ||
+		!readBytes(&s, &reserved) || !readString(&s, &sig.HashAlgorithm) ||
+		!readBytes(&s, &sig.signature) || !s.Empty() {
+		return nil, errors.New("sshsig: malformed signature")
+	}
+	if sig.HashAlgorithm != "sha256" && sig.HashAlgorithm != "sha512" {
+		return nil, errors.New("sshsig: unsupported hash algorithm " + sig.HashAlgorithm)
+	}
+	pub, err := parsePublicKey(sig.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	sig.PublicKey = pub
+	return sig, nil
+}
+
+// Verify checks that armored is a valid signature of message by pub in
+// namespace.
+func Verify(pub crypto.PublicKey, armored, message []byte, namespace string) error {
+	sig, err := ParseSignature(armored)
+	if err != nil {
+		return err
+	}
+	want, err := marshalPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(want, sig.publicKey) {
+		return errors.New("sshsig: signature made by a different key")
+	}
+	return sig.verify(message, namespace)
+}
+
+// verify checks the signature with its own public key.
+func (sig *Signature) verify(message []byte, namespace string) error {
+	if namespace == "" || sig.Namespace != namespace {
+		return errors.New("sshsig: signature namespace mismatch")
+	}
+	return verify(sig.PublicKey, signedData(namespace, sig.HashAlgorithm, message), sig.signature)
+}
+
+// signedData returns the data covered by the signature, which includes a
+// hash of message.
+func signedData(namespace, hashAlgorithm string, message []byte) []byte {
+	var h []byte
+	if hashAlgorithm == "sha256" {
+		sum := sha256.Sum256(message)
+		h = sum[:]
+	} else {
+		sum := sha512.Sum512(message)
+		h = sum[:]
+	}
+	var b cryptobyte.Builder
+	b.AddBytes([]byte(magicPreamble))
+	addString(&b, namespace)
+	addString(&b, "") // reserved
+	addString(&b, hashAlgorithm)
+	addBytes(&b, h)
+	return b.BytesOrPanic()
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sshsig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"testing"
+	"time"
+)
+
+func testKeys(t *testing.T) []crypto.Signer {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []crypto.Signer{edKey}
+	for _, c := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		k, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(keys, rsaKey)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	message := []byte("hello, world\n")
+	keys := testKeys(t)
+	for i, priv := range keys {
+		sig, err := Sign(rand.Reader, priv, "file", message)
+		if err != nil {
+			t.Fatalf("Sign with %T: %v", priv, err)
+		}
+		if err := Verify(priv.Public(), sig, message, "file"); err != nil {
+			t.Errorf("Verify with %T: %v", priv, err)
+		}
+		if err := Verify(priv.Public(), sig, message, "git"); err == nil {
+			t.Errorf("Verify with %T succeeded in the wrong namespace", priv)
+		}
+		if err := Verify(priv.Public(), sig, []byte("hello, world"), "file"); err == nil {
+			t.Errorf("Verify with %T succeeded for the wrong message", priv)
+		}
+		other := keys[(i+1)%len(keys)]
+		if err := Verify(other.Public(), sig, message, "file"); err == nil {
+			t.Errorf("Verify with %T succeeded for another key", priv)
+		}
+
+		parsed, err := ParseSignature(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Namespace != "file" || parsed.HashAlgorithm != "sha512" {
+			t.Errorf("ParseSignature = %q, %q, want \"file\", \"sha512\"", parsed.Namespace, parsed.HashAlgorithm)
+		}
+		if !priv.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(parsed.PublicKey) {
+			t.Errorf("ParseSignature returned the wrong public key for %T", priv)
+		}
+	}
+
+	if _, err := Sign(rand.Reader, keys[0], "", message); err == nil {
+		t.Errorf("Sign with an empty namespace succeeded")
+	}
+}
+
+func readTestFile(t *testing.T, name string) []byte {
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The testdata files were generated with OpenSSH 9.2 by
+//
+//	ssh-keygen -t <type> -C <type>@example.com -f id_<type>
+//	ssh-keygen -Y sign -f id_<type> -n git message
+//
+// and allowed_signers was checked with "ssh-keygen -Y verify".
+func TestAllowedSigners(t *testing.T) {
+	message := readTestFile(t, "message")
+	allowed, err := ParseAllowedSigners(readTestFile(t, "allowed_signers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		sig, principal, namespace string
+		ok                        bool
+	}{
+		{"ed25519.sig", "ed25519@example.com", "git", true},
+		{"ed25519.sig", "ed25519@example.com", "file", false}, // signed in "git"
+		{"ed25519.sig", "ecdsa@example.com", "git", false},
+		{"ecdsa.sig", "ecdsa@example.com", "git", true},
+		{"ecdsa.sig", "x@ecdsa.example", "git", true},
+		{"ecdsa.sig", "bad@ecdsa.example", "git", false},
+		{"rsa.sig", "rsa@example.com", "git", true},
+		{"rsa.sig", "old-rsa@example.com", "git", false}, // expired
+	}
+	for _, tt := range tests {
+		err := allowed.Verify(tt.principal, tt.namespace, readTestFile(t, tt.sig), message, time.Time{})
+		if (err == nil) != tt.ok {
+			t.Errorf("Verify(%q, %q, %s) = %v, want success %v", tt.principal, tt.namespace, tt.sig, err, tt.ok)
+		}
+	}
+
+	if err := allowed.Verify("rsa@example.com", "git", readTestFile(t, "rsa.sig"), message[1:], time.Time{}); err == nil {
+		t.Errorf("Verify succeeded for the wrong message")
+	}
+	// The rsa@example.com entry is only valid after 2000.
+	before := time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := allowed.Verify("rsa@example.com", "git", readTestFile(t, "rsa.sig"), message, before); err == nil {
+		t.Errorf("Verify succeeded before valid-after")
+	}
+
+	sig, err := ParseSignature(readTestFile(t, "rsa.sig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := allowed.Principals(sig, time.Time{}); len(got) != 1 || got[0] != "rsa@example.com" {
+		t.Errorf("Principals = %q, want [rsa@example.com]", got)
+	}
+	if got := allowed.Principals(sig, before); len(got) != 1 || got[0] != "old-rsa@example.com" {
+		t.Errorf("Principals before 2000 = %q, want [old-rsa@example.com]", got)
+	}
+}
+
+func TestParseAllowedSigners(t *testing.T) {
+	key := "AAAAC3NzaC1lZDI1NTE5AAAAICfSGasy3+jsePWvjNgrY3WzhXmpbbLPLwmTQGAKwc9F"
+	good := []string{
+		"a@example.com ssh-ed25519 " + key,
+		"a@example.com ssh-ed25519 " + key + " comment with spaces",
+		`"a@example.com,b@example.com" namespaces="git" ssh-ed25519 ` + key,
+		"a@example.com cert-authority ssh-ed25519 " + key,
+		"a@example.com sk-ssh-ed25519@openssh.com " + key,
+		"a@example.com valid-after=20230102,valid-before=202301021504Z ssh-ed25519 " + key,
+	}
+	for _, line := range good {
+		if _, err := ParseAllowedSigners([]byte(line)); err != nil {
+			t.Errorf("ParseAllowedSigners(%q): %v", line, err)
+		}
+	}
+	bad := []string{
+		"ssh-ed25519 " + key,
+		"a@example.com ssh-ed25519 AAAA!",
+		"a@example.com ssh-rsa " + key,
+		"a@example.com unknown-option ssh-ed25519 " + key,
+		"a@example.com namespaces ssh-ed25519 " + key,
+		"a@example.com valid-after=2023 ssh-ed25519 " + key,
+	}
+	for _, line := range bad {
+		if _, err := ParseAllowedSigners([]byte(line)); err == nil {
+			t.Errorf("ParseAllowedSigners(%q) succeeded", line)
+		}
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	sig := readTestFile(t, "ed25519.sig")
+	if _, err := ParseSignature(sig); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range [][]byte{
+		nil,
+		bytes.Replace(sig, []byte("SSH SIGNATURE"), []byte("SSH SIG"), -1),
+		bytes.Replace(sig, []byte("U1NIU0lH"), []byte("U1NIU0lI"), 1),
+		bytes.Replace(sig, []byte("AAAAAQ"), []byte("AAAAAg"), 1),
+	} {
+		if _, err := ParseSignature(bad); err == nil {
+			t.Errorf("ParseSignature(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestMatchPatternList(t *testing.T) {
+	tests := []struct {
+		s, patterns string
+		want        bool
+	}{
+		{"a@example.com", "a@example.com", true},
+		{"a@example.com", "b@example.com,a@example.com", true},
+		{"a@example.com", "*@example.com", true},
+		{"a@example.com", "?@example.com", true},
+		{"ab@example.com", "?@example.com", false},
+		{"a@example.com", "*@example.com,!a@example.com", false},
+		{"a@example.com", "!a@example.com", false},
+		{"a@example.com", "!b@example.com", false},
+		{"a/b", "*", true},
+		{"", "*", true},
+		{"", "?", false},
+	}
+	for _, tt := range tests {
+		if got := matchPatternList(tt.s, tt.patterns); got != tt.want {
+			t.Errorf("matchPatternList(%q, %q) = %v, want %v", tt.s, tt.patterns, got, tt.want)
+		}
+	}
+}
@@ -60,3 +60,31 @@ func ConstantTimeLessOrEq(x, y int) int {
 	y32 := int32(y)
 	return int(((x32 - y32 - 1) >> 31) & 1)
 }
+
+// ConstantTimeLessOrEqBytes returns 1 if x <= y and 0 otherwise, where x and
+// y are interpreted as big-endian unsigned integers. The slices may have
+// different lengths, in which case the shorter one is treated as if it were
+// padded with leading zeros. The time taken is a function of the length of
+// the slices and is independent of the contents.
+func ConstantTimeLessOrEqBytes(x, y []byte) int {
+	n := len(x)
+	if len(y) > n {
+		n = len(y)
+	}
+
+	// Compute y - x, one byte at a time starting from the least significant
+	// byte. x <= y if and only if there is no final borrow.
+	var borrow uint32
+	for i := 1; i <= n; i++ {
+		var xb, yb uint32
+		if i <= len(x) {
+			xb = uint32(x[len(x)-i])
+		}
+		if i <= len(y) {
+			yb = uint32(y[len(y)-i])
+		}
+		borrow = (yb - xb - borrow) >> 31
+	}
+
+	return int(borrow ^ 1)
+}
@@ -5,6 +5,7 @@
 package subtle
 
 import (
+	"math/big"
 	"testing"
 	"testing/quick"
 )
@@ -126,6 +127,43 @@ func TestConstantTimeLessOrEq(t *testing.T) {
 	}
 }
 
+var lessOrEqBytesTests = []struct {
+	x, y   []byte
+	result int
+}{
+	{nil, nil, 1},
+	{[]byte{0}, nil, 1},
+	{[]byte{1}, nil, 0},
+	{nil, []byte{0, 0, 1}, 1},
+	{[]byte{0x12, 0x34}, []byte{0x12, 0x34}, 1},
+	{[]byte{0x12, 0x35}, []byte{0x12, 0x34}, 0},
+	{[]byte{0x12, 0x33}, []byte{0x12, 0x34}, 1},
+	{[]byte{0x11, 0xff}, []byte{0x12, 0x00}, 1},
+	{[]byte{0x12, 0x00}, []byte{0x11, 0xff}, 0},
+	{[]byte{0, 0, 0xff}, []byte{0x01, 0x00}, 1},
+	{[]byte{0x01, 0x00}, []byte{0, 0, 0xff}, 0},
+	{[]byte{0xff, 0xff, 0xff}, []byte{0xff, 0xff, 0xfe}, 0},
+}
+
+func TestConstantTimeLessOrEqBytes(t *testing.T) {
+	for i, test := range lessOrEqBytesTests {
+		result := ConstantTimeLessOrEqBytes(test.x, test.y)
+		if result != test.result {
+			t.Errorf("#%d: %x <= %x gave %d, expected %d", i, test.x, test.y, result, test.result)
+		}
+	}
+
+	lessOrEqBig := func(x, y []byte) int {
+		if new(big.Int).SetBytes(x).Cmp(new(big.Int).SetBytes(y)) <= 0 {
+			return 1
+		}
+		return 0
+	}
+	if err := quick.CheckEqual(ConstantTimeLessOrEqBytes, lessOrEqBig, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 var benchmarkGlobal uint8
 
 func BenchmarkConstantTimeByteEq(b *testing.B) {
@@ -0,0 +1,27 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subtle
+
+import "runtime"
+
+// Zeroize sets every byte of b to zero.
+//
+// Unlike a loop or the clear builtin, Zeroize is never removed by the compiler
+// as a dead store, even if b is not used again, so it can be used to erase key
+// material and other secrets as soon as they are no longer needed.
+//
+// Zeroize only erases the memory referenced by b. It can't erase copies made
+// earlier, for example by append growing a slice or by the runtime moving a
+// goroutine stack.
+//
+//go:noinline
+func Zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	// Keep b reachable until after the stores, so that they can't be
+	// discarded even if the caller drops its last reference to b.
+	runtime.KeepAlive(b)
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package subtle_test
+
+import (
+	. "crypto/subtle"
+	"testing"
+)
+
+func TestZeroize(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 8, 31, 64, 1000} {
+		b := make([]byte, n+2)
+		for i := range b {
+			b[i] = 0xff
+		}
+		Zeroize(b[1 : n+1])
+		if b[0] != 0xff || b[n+1] != 0xff {
+			t.Errorf("n=%d: Zeroize wrote outside of its argument", n)
+		}
+		for i, v := range b[1 : n+1] {
+			if v != 0 {
+				t.Errorf("n=%d: byte %d is %#x after Zeroize", n, i, v)
+				break
+			}
+		}
+	}
+}
+
+func TestZeroizeAllocations(t *testing.T) {
+	b := make([]byte, 32)
+	if allocs := testing.AllocsPerRun(10, func() { Zeroize(b) }); allocs > 0 {
+		t.Errorf("expected zero allocations, got %0.1f", allocs)
+	}
+}
+
+func BenchmarkZeroize(b *testing.B) {
+	buf := make([]byte, 4096)
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		Zeroize(buf)
+	}
+}
@@ -6,15 +6,18 @@ package tls
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/internal/cryptotrace"
 	"crypto/rsa"
 	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"runtime/trace"
 )
 
 // verifyHandshakeSignature verifies a signature against pre-hashed
@@ -93,6 +96,25 @@ func signedMessage(sigHash crypto.Hash, context string, transcript hash.Hash) []
 	return h.Sum(nil)
 }
 
+// signHandshakeMessage signs msg, a signature input that has not been hashed
+// yet, with priv. A crypto.MessageSigner is given msg itself. Other signers
+// are given its hash under opts.HashFunc(), or msg if that is zero.
+func signHandshakeMessage(ctx context.Context, priv crypto.Signer, rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if ms, ok := priv.(crypto.MessageSigner); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return ms.SignMessage(rand, msg, opts)
+	}
+	signed := msg
+	if hashFunc := opts.HashFunc(); hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(msg)
+		signed = h.Sum(nil)
+	}
+	return crypto.SignContext(ctx, priv, rand, signed, opts)
+}
+
 // typeAndHashFromSignatureScheme returns the corresponding signature type and
 // crypto.Hash for a given TLS SignatureScheme.
 func typeAndHashFromSignatureScheme(signatureAlgorithm SignatureScheme) (sigType uint8, hash crypto.Hash, err error) {
@@ -291,3 +313,13 @@ func unsupportedCertificateError(cert *Certificate) error {
 
 	return fmt.Errorf("tls: internal error: unsupported key (%T)", cert.PrivateKey)
 }
+
+// startTraceRegion starts an execution trace region of the given type within
+// the handshake task carried by ctx, if the cryptotrace GODEBUG setting is
+// enabled. Otherwise, it returns a no-op region.
+func startTraceRegion(ctx context.Context, regionType string) interface{ End() } {
+	if !cryptotrace.Enabled() {
+		return cryptotrace.Region{}
+	}
+	return trace.StartRegion(ctx, regionType)
+}
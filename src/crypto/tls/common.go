@@ -12,6 +12,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/lockedmem"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha512"
@@ -346,6 +347,10 @@ type ClientSessionState struct {
 	nonce  []byte    // Ticket nonce sent by the server, to derive PSK
 	useBy  time.Time // Expiration of the ticket lifetime as set by the server
 	ageAdd uint32    // Random obfuscation factor for sending the ticket age
+
+	// lockedSecret holds masterSecret for sessions cached by a
+	// ClientSessionCache returned by NewLockedClientSessionCache.
+	lockedSecret *lockedmem.Buffer
 }
 
 // ClientSessionCache is a cache of ClientSessionState objects that can be used
@@ -1406,6 +1411,9 @@ type lruSessionCache struct {
 	m        map[string]*list.Element
 	q        *list.List
 	capacity int
+
+	// locked is set for caches returned by NewLockedClientSessionCache.
+	locked bool
 }
 
 type lruSessionCacheEntry struct {
@@ -1429,18 +1437,73 @@ func NewLRUClientSessionCache(capacity int) ClientSessionCache {
 	}
 }
 
+// NewLockedClientSessionCache returns a ClientSessionCache like the one
+// returned by NewLRUClientSessionCache, which keeps the secrets of the cached
+// sessions in locked memory, as provided by crypto/lockedmem. The secrets are
+// erased when their sessions are evicted or replaced.
+//
+// Get returns sessions whose secrets are copied to ordinary memory, since the
+// handshake uses them. Sessions are not cached if locked memory is not
+// available, for example because the limit on locked memory of the process
+// is reached.
+//
+// Locked memory is only released as sessions are evicted or replaced, so the
+// cache should live as long as the program, or at least as long as the
+// Config that uses it.
+func NewLockedClientSessionCache(capacity int) ClientSessionCache {
+	c := NewLRUClientSessionCache(capacity).(*lruSessionCache)
+	c.locked = true
+	return c
+}
+
+// lockSession returns a copy of cs whose masterSecret is stored in locked memory.
+func lockSession(cs *ClientSessionState) (*ClientSessionState, error) {
+	buf, err := lockedmem.New(len(cs.masterSecret))
+	if err != nil {
+		return nil, err
+	}
+	locked := *cs
+	copy(buf.Bytes(), cs.masterSecret)
+	locked.masterSecret = buf.Bytes()
+	locked.lockedSecret = buf
+	return &locked, nil
+}
+
+// unlockSession returns a copy of cs whose masterSecret is stored in
+// ordinary memory.
+func unlockSession(cs *ClientSessionState) *ClientSessionState {
+	unlocked := *cs
+	unlocked.masterSecret = bytes.Clone(cs.masterSecret)
+	unlocked.lockedSecret = nil
+	return &unlocked
+}
+
+// release erases the locked memory of a session that is removed from c.
+func (c *lruSessionCache) release(cs *ClientSessionState) {
+	if cs != nil && cs.lockedSecret != nil {
+		cs.lockedSecret.Destroy()
+	}
+}
+
 // Put adds the provided (sessionKey, cs) pair to the cache. If cs is nil, the entry
 // corresponding to sessionKey is removed from the cache instead.
 func (c *lruSessionCache) Put(sessionKey string, cs *ClientSessionState) {
+	if c.locked && cs != nil {
+		// If the secret can't be locked, drop any older session
+		// rather than keeping the secret in ordinary memory.
+		cs, _ = lockSession(cs)
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
 	if elem, ok := c.m[sessionKey]; ok {
+		entry := elem.Value.(*lruSessionCacheEntry)
+		c.release(entry.state)
 		if cs == nil {
 			c.q.Remove(elem)
 			delete(c.m, sessionKey)
 		} else {
-			entry := elem.Value.(*lruSessionCacheEntry)
 			entry.state = cs
 			c.q.MoveToFront(elem)
 		}
@@ -1455,6 +1518,7 @@ func (c *lruSessionCache) Put(sessionKey string, cs *ClientSessionState) {
 
 	elem := c.q.Back()
 	entry := elem.Value.(*lruSessionCacheEntry)
+	c.release(entry.state)
 	delete(c.m, entry.sessionKey)
 	entry.sessionKey = sessionKey
 	entry.state = cs
@@ -1470,7 +1534,11 @@ func (c *lruSessionCache) Get(sessionKey string) (*ClientSessionState, bool) {
 
 	if elem, ok := c.m[sessionKey]; ok {
 		c.q.MoveToFront(elem)
-		return elem.Value.(*lruSessionCacheEntry).state, true
+		cs := elem.Value.(*lruSessionCacheEntry).state
+		if c.locked && cs != nil {
+			cs = unlockSession(cs)
+		}
+		return cs, true
 	}
 	return nil, false
 }
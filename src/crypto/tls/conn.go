@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/cipher"
+	"crypto/internal/cryptotrace"
 	"crypto/subtle"
 	"crypto/x509"
 	"errors"
@@ -17,6 +18,7 @@ import (
 	"hash"
 	"io"
 	"net"
+	"runtime/trace"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -1488,7 +1490,14 @@ func (c *Conn) handshakeContext(ctx context.Context) (ret error) {
 	c.in.Lock()
 	defer c.in.Unlock()
 
-	c.handshakeErr = c.handshakeFn(handshakeCtx)
+	fnCtx := handshakeCtx
+	if cryptotrace.Enabled() {
+		var task *trace.Task
+		fnCtx, task = trace.NewTask(handshakeCtx, "crypto/tls.Handshake")
+		defer task.End()
+	}
+
+	c.handshakeErr = c.handshakeFn(fnCtx)
 	if c.handshakeErr == nil {
 		c.handshakes++
 	} else {
@@ -636,12 +636,19 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 			}
 		}
 
-		signed := hs.finishedHash.hashForClientCertificate(sigType, sigHash)
 		signOpts := crypto.SignerOpts(sigHash)
 		if sigType == signatureRSAPSS {
 			signOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
 		}
-		certVerify.signature, err = key.Sign(c.config.rand(), signed, signOpts)
+		region := startTraceRegion(hs.ctx, "crypto/tls.Sign")
+		if c.vers >= VersionTLS12 {
+			msg := hs.finishedHash.messageForClientCertificate()
+			certVerify.signature, err = signHandshakeMessage(hs.ctx, key, c.config.rand(), msg, signOpts)
+		} else {
+			signed := hs.finishedHash.hashForClientCertificate(sigType, sigHash)
+			certVerify.signature, err = crypto.SignContext(hs.ctx, key, c.config.rand(), signed, signOpts)
+		}
+		region.End()
 		if err != nil {
 			c.sendAlert(alertInternalError)
 			return err
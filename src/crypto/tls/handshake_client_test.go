@@ -7,6 +7,7 @@ package tls
 import (
 	"bytes"
 	"context"
+	"crypto/lockedmem"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
@@ -1129,6 +1130,42 @@ func TestLRUClientSessionCache(t *testing.T) {
 	}
 }
 
+func TestLockedClientSessionCache(t *testing.T) {
+	t.Run("TLSv12", func(t *testing.T) { testLockedClientSessionCache(t, VersionTLS12) })
+	t.Run("TLSv13", func(t *testing.T) { testLockedClientSessionCache(t, VersionTLS13) })
+}
+
+func testLockedClientSessionCache(t *testing.T, version uint16) {
+	if b, err := lockedmem.New(1); err != nil {
+		t.Skipf("locked memory is unavailable: %v", err)
+	} else {
+		b.Destroy()
+	}
+
+	serverConfig := testConfig.Clone()
+	serverConfig.MaxVersion = version
+	clientConfig := testConfig.Clone()
+	clientConfig.MaxVersion = version
+	clientConfig.ClientSessionCache = NewLockedClientSessionCache(32)
+
+	for i, wantResume := range []bool{false, true, true} {
+		_, cs, err := testHandshake(t, clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("handshake %d failed: %s", i, err)
+		}
+		if cs.DidResume != wantResume {
+			t.Fatalf("handshake %d: DidResume = %v, want %v", i, cs.DidResume, wantResume)
+		}
+		state := clientConfig.ClientSessionCache.(*lruSessionCache).q.Front().Value.(*lruSessionCacheEntry).state
+		if state.lockedSecret == nil {
+			t.Fatalf("handshake %d: session secret is not in locked memory", i)
+		}
+		if !bytes.Equal(state.masterSecret, state.lockedSecret.Bytes()) {
+			t.Fatalf("handshake %d: session secret does not match locked memory", i)
+		}
+	}
+}
+
 func TestKeyLogTLS12(t *testing.T) {
 	var serverBuf, clientBuf bytes.Buffer
 
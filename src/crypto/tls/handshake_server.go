@@ -527,7 +527,10 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 	}
 
 	keyAgreement := hs.suite.ka(c.vers)
-	skx, err := keyAgreement.generateServerKeyExchange(c.config, hs.cert, hs.clientHello, hs.hello)
+	// The ServerKeyExchange message is signed, except for RSA key exchange.
+	region := startTraceRegion(hs.ctx, "crypto/tls.ServerKeyExchange")
+	skx, err := keyAgreement.generateServerKeyExchange(hs.ctx, c.config, hs.cert, hs.clientHello, hs.hello)
+	region.End()
 	if err != nil {
 		c.sendAlert(alertHandshakeFailure)
 		return err
@@ -2045,3 +2045,108 @@ func TestHandshakeContextHierarchy(t *testing.T) {
 		t.Errorf("Unexpected client error: %v", err)
 	}
 }
+
+// ctxSigner is a crypto.SignerCtx that checks that it is passed the
+// context given to HandshakeContext.
+type ctxSigner struct {
+	crypto.Signer
+	key   any
+	calls int
+}
+
+func (s *ctxSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if ctx.Value(s.key) == nil {
+		return nil, errors.New("SignContext was not passed the handshake context")
+	}
+	s.calls++
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestHandshakeSignerCtx(t *testing.T) {
+	for name, v := range map[string]uint16{"TLSv12": VersionTLS12, "TLSv13": VersionTLS13} {
+		t.Run(name, func(t *testing.T) {
+			type ctxKey struct{}
+			ctx := context.WithValue(context.Background(), ctxKey{}, true)
+			clientSigner := &ctxSigner{Signer: testRSAPrivateKey, key: ctxKey{}}
+			serverSigner := &ctxSigner{Signer: testECDSAPrivateKey, key: ctxKey{}}
+
+			clientConfig := testConfig.Clone()
+			clientConfig.MaxVersion = v
+			clientConfig.Certificates = []Certificate{{
+				Certificate: [][]byte{testRSACertificate},
+				PrivateKey:  clientSigner,
+			}}
+			serverConfig := testConfig.Clone()
+			serverConfig.MaxVersion = v
+			serverConfig.ClientAuth = RequireAnyClientCert
+			serverConfig.Certificates = []Certificate{{
+				Certificate: [][]byte{testECDSACertificate},
+				PrivateKey:  serverSigner,
+			}}
+
+			c, s := localPipe(t)
+			clientErr := make(chan error, 1)
+			go func() {
+				defer c.Close()
+				clientErr <- Client(c, clientConfig).HandshakeContext(ctx)
+			}()
+			if err := Server(s, serverConfig).HandshakeContext(ctx); err != nil {
+				t.Errorf("server handshake failed: %v", err)
+			}
+			s.Close()
+			if err := <-clientErr; err != nil {
+				t.Errorf("client handshake failed: %v", err)
+			}
+			if clientSigner.calls != 1 || serverSigner.calls != 1 {
+				t.Errorf("SignContext called %d times by the client and %d by the server, want once each",
+					clientSigner.calls, serverSigner.calls)
+			}
+		})
+	}
+}
+
+// messageSigner is a crypto.MessageSigner that hashes the message itself.
+type messageSigner struct {
+	crypto.Signer
+	calls int
+}
+
+func (s *messageSigner) SignMessage(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.calls++
+	return crypto.SignMessage(s.Signer, rand, msg, opts)
+}
+
+func (s *messageSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("Sign called on a MessageSigner")
+}
+
+func TestHandshakeMessageSigner(t *testing.T) {
+	for name, v := range map[string]uint16{"TLSv12": VersionTLS12, "TLSv13": VersionTLS13} {
+		t.Run(name, func(t *testing.T) {
+			clientSigner := &messageSigner{Signer: testRSAPrivateKey}
+			serverSigner := &messageSigner{Signer: testECDSAPrivateKey}
+
+			clientConfig := testConfig.Clone()
+			clientConfig.MaxVersion = v
+			clientConfig.Certificates = []Certificate{{
+				Certificate: [][]byte{testRSACertificate},
+				PrivateKey:  clientSigner,
+			}}
+			serverConfig := testConfig.Clone()
+			serverConfig.MaxVersion = v
+			serverConfig.ClientAuth = RequireAnyClientCert
+			serverConfig.Certificates = []Certificate{{
+				Certificate: [][]byte{testECDSACertificate},
+				PrivateKey:  serverSigner,
+			}}
+
+			if _, _, err := testHandshake(t, clientConfig, serverConfig); err != nil {
+				t.Fatal(err)
+			}
+			if clientSigner.calls != 1 || serverSigner.calls != 1 {
+				t.Errorf("SignMessage called %d times by the client and %d by the server, want once each",
+					clientSigner.calls, serverSigner.calls)
+			}
+		})
+	}
+}
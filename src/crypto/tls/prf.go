@@ -237,6 +237,15 @@ func (h finishedHash) hashForClientCertificate(sigType uint8, hashAlg crypto.Has
 	return h.Sum()
 }
 
+// messageForClientCertificate returns the handshake messages covered by a
+// TLS 1.2 certificate verify message, before hashing.
+func (h finishedHash) messageForClientCertificate() []byte {
+	if h.buffer == nil {
+		panic("tls: handshake hash for a client certificate requested after discarding the handshake buffer")
+	}
+	return h.buffer
+}
+
 // discardHandshakeBuffer is called when there is no more need to
 // buffer the entirety of the handshake messages.
 func (h *finishedHash) discardHandshakeBuffer() {
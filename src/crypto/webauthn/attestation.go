@@ -0,0 +1,533 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cose"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/internal/cbor"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/attestation"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// An AttestationType is the kind of trust an attestation provides.
+type AttestationType int
+
+const (
+	// AttestationNone is the "none" format, which attests nothing.
+	AttestationNone AttestationType = iota
+	// AttestationSelf is signed by the credential key itself, and only
+	// proves possession of it.
+	AttestationSelf
+	// AttestationCertificate is signed by an attestation key with a
+	// certificate chain, which identifies the authenticator model if the
+	// chain is trusted. It is Basic or AttCA attestation in the terms of
+	// the specification.
+	AttestationCertificate
+)
+
+func (t AttestationType) String() string {
+	switch t {
+	case AttestationNone:
+		return "None"
+	case AttestationSelf:
+		return "Self"
+	case AttestationCertificate:
+		return "Certificate"
+	}
+	return "AttestationType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// An Attestation is a verified attestation statement.
+type Attestation struct {
+	// Format is the attestation statement format, such as "packed".
+	Format string
+
+	Type AttestationType
+
+	// Certificates is the attestation certificate chain, leaf first, if
+	// Type is AttestationCertificate.
+	Certificates []*x509.Certificate
+
+	// AndroidKey is the key description of the "android-key" format.
+	AndroidKey *attestation.AndroidKeyDescription
+}
+
+// An AttestationObject is the attestationObject of the response to a
+// navigator.credentials.create call.
+type AttestationObject struct {
+	// Format is the attestation statement format.
+	Format string
+
+	// AuthData is the authenticator data, which holds the new credential.
+	AuthData *AuthenticatorData
+
+	statement cbor.Map
+}
+
+// ParseAttestationObject parses an attestation object. It does not
+// verify its attestation statement.
+func ParseAttestationObject(data []byte) (*AttestationObject, error) {
+	errMalformed := errors.New("webauthn: malformed attestation object")
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, errMalformed
+	}
+	m, ok := v.(cbor.Map)
+	if !ok {
+		return nil, errMalformed
+	}
+	f, _ := m.Get("fmt")
+	stmt, _ := m.Get("attStmt")
+	authData, _ := m.Get("authData")
+	obj := &AttestationObject{}
+	if obj.Format, ok = f.(string); !ok {
+		return nil, errMalformed
+	}
+	if obj.statement, ok = stmt.(cbor.Map); !ok {
+		return nil, errMalformed
+	}
+	b, ok := authData.([]byte)
+	if !ok {
+		return nil, errMalformed
+	}
+	if obj.AuthData, err = ParseAuthenticatorData(b); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// AttestationOptions are the options of AttestationObject.Verify.
+type AttestationOptions struct {
+	// Roots, if not nil, are the roots the attestation certificate chain
+	// must chain to. Attestations without a certificate chain are then
+	// rejected.
+	Roots *x509.CertPool
+
+	// CurrentTime is the time at which the chain is verified. If zero,
+	// the current time is used.
+	CurrentTime time.Time
+}
+
+// Verify verifies the attestation statement of o, over its authenticator
+// data and clientDataHash, the SHA-256 hash of the client data JSON.
+//
+// For the "android-key" format, the authorizations of the key are checked
+// in the union of the software- and hardware-enforced lists; callers that
+// require keys in secure hardware must check AndroidKey.HardwareEnforced
+// themselves. For the "tpm" format, the signature may be a TPMT_SIGNATURE,
+// as specified, or the bare signature that some platforms send.
+func (o *AttestationObject) Verify(clientDataHash []byte, opts *AttestationOptions) (*Attestation, error) {
+	if opts == nil {
+		opts = &AttestationOptions{}
+	}
+	cred := o.AuthData.AttestedCredential
+	if cred == nil {
+		return nil, errors.New("webauthn: attestation has no attested credential data")
+	}
+	signed := append(o.AuthData.Raw[:len(o.AuthData.Raw):len(o.AuthData.Raw)], clientDataHash...)
+
+	var att *Attestation
+	var err error
+	switch o.Format {
+	case "none":
+		if len(o.statement) != 0 {
+			return nil, errors.New("webauthn: none attestation has a statement")
+		}
+		att = &Attestation{Format: o.Format, Type: AttestationNone}
+	case "packed":
+		att, err = o.verifyPacked(cred, signed)
+	case "tpm":
+		att, err = o.verifyTPM(cred, signed)
+	case "android-key":
+		att, err = o.verifyAndroidKey(cred, signed, clientDataHash)
+	default:
+		return nil, errors.New("webauthn: unsupported attestation format " + strconv.Quote(o.Format))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Roots != nil {
+		if att.Type != AttestationCertificate {
+			return nil, errors.New("webauthn: attestation has no certificate chain")
+		}
+		intermediates := x509.NewCertPool()
+		for _, c := range att.Certificates[1:] {
+			intermediates.AddCert(c)
+		}
+		_, err := att.Certificates[0].Verify(x509.VerifyOptions{
+			Roots:         opts.Roots,
+			Intermediates: intermediates,
+			CurrentTime:   opts.CurrentTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return att, nil
+}
+
+func (o *AttestationObject) statementAlgorithm() (cose.Algorithm, error) {
+	v, _ := o.statement.Get("alg")
+	alg, ok := v.(int64)
+	if !ok {
+		return 0, errors.New("webauthn: attestation statement has no algorithm")
+	}
+	return cose.Algorithm(alg), nil
+}
+
+func (o *AttestationObject) statementBytes(key string) ([]byte, error) {
+	v, _ := o.statement.Get(key)
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, errors.New("webauthn: attestation statement has no " + key)
+	}
+	return b, nil
+}
+
+// statementCertificates returns the x5c certificate chain of the
+// statement, or nil if there is none.
+func (o *AttestationObject) statementCertificates() ([]*x509.Certificate, error) {
+	v, ok := o.statement.Get("x5c")
+	if !ok {
+		return nil, nil
+	}
+	chain, ok := v.([]any)
+	if !ok || len(chain) == 0 {
+		return nil, errors.New("webauthn: malformed attestation certificate chain")
+	}
+	var certs []*x509.Certificate
+	for _, c := range chain {
+		der, ok := c.([]byte)
+		if !ok {
+			return nil, errors.New("webauthn: malformed attestation certificate chain")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// oidFIDOAAGUID is id-fido-gen-ce-aaguid, the extension of attestation
+// certificates that holds the AAGUID of the authenticator model.
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// checkAAGUID checks that the AAGUID extension of cert, if present,
+// matches aaguid.
+func checkAAGUID(cert *x509.Certificate, aaguid [16]byte) error {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidFIDOAAGUID) {
+			continue
+		}
+		var v []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &v); err != nil || len(rest) != 0 || ext.Critical {
+			return errors.New("webauthn: malformed AAGUID extension")
+		}
+		if !bytes.Equal(v, aaguid[:]) {
+			return errors.New("webauthn: attestation certificate AAGUID does not match")
+		}
+	}
+	return nil
+}
+
+// verifyPacked verifies a "packed" statement, from Section 8.2 of the
+// specification.
+func (o *AttestationObject) verifyPacked(cred *AttestedCredential, signed []byte) (*Attestation, error) {
+	alg, err := o.statementAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := o.statementBytes("sig")
+	if err != nil {
+		return nil, err
+	}
+	certs, err := o.statementCertificates()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := o.statement.Get("ecdaaKeyId"); ok {
+		return nil, errors.New("webauthn: ECDAA attestation is not supported")
+	}
+
+	if certs == nil {
+		if alg != cred.PublicKey.Algorithm {
+			return nil, errors.New("webauthn: self attestation algorithm does not match the credential")
+		}
+		if err := verifySignature(alg, cred.PublicKey.Public, signed, sig); err != nil {
+			return nil, err
+		}
+		return &Attestation{Format: o.Format, Type: AttestationSelf}, nil
+	}
+
+	leaf := certs[0]
+	if err := verifySignature(alg, leaf.PublicKey, signed, sig); err != nil {
+		return nil, err
+	}
+	s := leaf.Subject
+	if leaf.Version != 3 || len(s.Country) == 0 || len(s.Organization) == 0 || s.CommonName == "" ||
+		len(s.OrganizationalUnit) != 1 || s.OrganizationalUnit[0] != "Authenticator Attestation" {
+		return nil, errors.New("webauthn: packed attestation certificate has an invalid subject")
+	}
+	if !leaf.BasicConstraintsValid || leaf.IsCA {
+		return nil, errors.New("webauthn: packed attestation certificate is a CA")
+	}
+	if err := checkAAGUID(leaf, cred.AAGUID); err != nil {
+		return nil, err
+	}
+	return &Attestation{Format: o.Format, Type: AttestationCertificate, Certificates: certs}, nil
+}
+
+var (
+	oidExtensionSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidTCGKpAIKCertificate     = asn1.ObjectIdentifier{2, 23, 133, 8, 3}
+)
+
+// verifyTPM verifies a "tpm" statement, from Section 8.3 of the
+// specification.
+func (o *AttestationObject) verifyTPM(cred *AttestedCredential, signed []byte) (*Attestation, error) {
+	if v, _ := o.statement.Get("ver"); v != "2.0" {
+		return nil, errors.New("webauthn: unsupported TPM attestation version")
+	}
+	alg, err := o.statementAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := o.statementBytes("sig")
+	if err != nil {
+		return nil, err
+	}
+	certInfo, err := o.statementBytes("certInfo")
+	if err != nil {
+		return nil, err
+	}
+	pubArea, err := o.statementBytes("pubArea")
+	if err != nil {
+		return nil, err
+	}
+	certs, err := o.statementCertificates()
+	if err != nil {
+		return nil, err
+	}
+	if certs == nil {
+		return nil, errors.New("webauthn: TPM attestation has no certificate chain")
+	}
+
+	pub, err := parseTPMPublic(pubArea)
+	if err != nil {
+		return nil, err
+	}
+	if !equalKeys(pub, cred.PublicKey.Public) {
+		return nil, errors.New("webauthn: TPM public area does not match the credential")
+	}
+
+	info, err := attestation.ParseTPMAttestation(certInfo)
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != attestation.TPMAttestCertify {
+		return nil, errors.New("webauthn: TPM attestation is not a certification")
+	}
+	h := algorithmHash(alg)
+	if h == 0 {
+		return nil, errors.New("webauthn: unsupported TPM attestation algorithm " + alg.String())
+	}
+	d := h.New()
+	d.Write(signed)
+	if !bytes.Equal(info.ExtraData, d.Sum(nil)) {
+		return nil, errors.New("webauthn: TPM attestation extra data does not match")
+	}
+	name, err := attestation.TPMObjectName(pubArea)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.Certify.Name, name) {
+		return nil, errors.New("webauthn: TPM attestation does not certify the public area")
+	}
+
+	aik := certs[0]
+	if err := verifySignature(alg, aik.PublicKey, certInfo, sig); err != nil {
+		if info.CheckSignature(sig, aik.PublicKey) != nil {
+			return nil, err
+		}
+	}
+	if aik.Version != 3 || len(aik.Subject.Names) != 0 {
+		return nil, errors.New("webauthn: TPM attestation certificate has a subject")
+	}
+	if !aik.BasicConstraintsValid || aik.IsCA {
+		return nil, errors.New("webauthn: TPM attestation certificate is a CA")
+	}
+	hasEKU := false
+	for _, eku := range aik.UnknownExtKeyUsage {
+		if eku.Equal(oidTCGKpAIKCertificate) {
+			hasEKU = true
+		}
+	}
+	if !hasEKU {
+		return nil, errors.New("webauthn: TPM attestation certificate is not an AIK certificate")
+	}
+	if err := checkAAGUID(aik, cred.AAGUID); err != nil {
+		return nil, err
+	}
+	// The subject alternative name of the AIK certificate is a critical
+	// directory name identifying the TPM, which crypto/x509 doesn't parse.
+	for i, oid := range aik.UnhandledCriticalExtensions {
+		if oid.Equal(oidExtensionSubjectAltName) {
+			aik.UnhandledCriticalExtensions = append(aik.UnhandledCriticalExtensions[:i:i], aik.UnhandledCriticalExtensions[i+1:]...)
+			break
+		}
+	}
+	return &Attestation{Format: o.Format, Type: AttestationCertificate, Certificates: certs}, nil
+}
+
+// TPM_ALG_ID and TPM_ECC_CURVE values used in TPMT_PUBLIC.
+const (
+	tpmAlgRSA             = 0x0001
+	tpmAlgNull            = 0x0010
+	tpmAlgECC             = 0x0023
+	tpmECCP256            = 0x0003
+	tpmECCP384            = 0x0004
+	tpmECCP521            = 0x0005
+	tpmRSADefaultExponent = 65537
+)
+
+// parseTPMPublic returns the public key of a TPMT_PUBLIC structure.
+func parseTPMPublic(b []byte) (crypto.PublicKey, error) {
+	errMalformed := errors.New("webauthn: malformed TPM public area")
+	s := cryptobyte.String(b)
+	var typ, nameAlg, symmetric, scheme uint16
+	var attributes uint32
+	var authPolicy cryptobyte.String
+	if !s.ReadUint16(&typ) || !s.ReadUint16(&nameAlg) || !s.ReadUint32(&attributes) ||
+		!s.ReadUint16LengthPrefixed(&authPolicy) || !s.ReadUint16(&symmetric) {
+		return nil, errMalformed
+	}
+	// A signing key has no symmetric algorithm.
+	if symmetric != tpmAlgNull {
+		return nil, errors.New("webauthn: TPM key is not a signing key")
+	}
+	if !s.ReadUint16(&scheme) {
+		return nil, errMalformed
+	}
+	if scheme != tpmAlgNull && !s.Skip(2) {
+		return nil, errMalformed
+	}
+
+	switch typ {
+	case tpmAlgRSA:
+		var keyBits uint16
+		var exponent uint32
+		var n cryptobyte.String
+		if !s.ReadUint16(&keyBits) || !s.ReadUint32(&exponent) ||
+			!s.ReadUint16LengthPrefixed(&n) || !s.Empty() {
+			return nil, errMalformed
+		}
+		if exponent == 0 {
+			exponent = tpmRSADefaultExponent
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent)}, nil
+	case tpmAlgECC:
+		var curveID, kdf uint16
+		var x, y cryptobyte.String
+		if !s.ReadUint16(&curveID) || !s.ReadUint16(&kdf) ||
+			(kdf != tpmAlgNull && !s.Skip(2)) ||
+			!s.ReadUint16LengthPrefixed(&x) || !s.ReadUint16LengthPrefixed(&y) || !s.Empty() {
+			return nil, errMalformed
+		}
+		var curve elliptic.Curve
+		switch curveID {
+		case tpmECCP256:
+			curve = elliptic.P256()
+		case tpmECCP384:
+			curve = elliptic.P384()
+		case tpmECCP521:
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("webauthn: unsupported TPM curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errors.New("webauthn: unsupported TPM key type")
+}
+
+// Android Keystore authorization tags and values.
+const (
+	androidTagPurpose         = 1
+	androidTagAllApplications = 600
+	androidTagOrigin          = 702
+	androidPurposeSign        = 2
+	androidOriginGenerated    = 0
+)
+
+// verifyAndroidKey verifies an "android-key" statement, from Section 8.4
+// of the specification.
+func (o *AttestationObject) verifyAndroidKey(cred *AttestedCredential, signed, clientDataHash []byte) (*Attestation, error) {
+	alg, err := o.statementAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := o.statementBytes("sig")
+	if err != nil {
+		return nil, err
+	}
+	certs, err := o.statementCertificates()
+	if err != nil {
+		return nil, err
+	}
+	if certs == nil {
+		return nil, errors.New("webauthn: Android key attestation has no certificate chain")
+	}
+
+	leaf := certs[0]
+	if err := verifySignature(alg, leaf.PublicKey, signed, sig); err != nil {
+		return nil, err
+	}
+	if !equalKeys(leaf.PublicKey, cred.PublicKey.Public) {
+		return nil, errors.New("webauthn: Android attestation certificate does not match the credential")
+	}
+	kd, err := attestation.ParseAndroidKeyDescription(leaf)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(kd.AttestationChallenge, clientDataHash) {
+		return nil, errors.New("webauthn: Android attestation challenge does not match")
+	}
+	sw, hw := &kd.SoftwareEnforced, &kd.HardwareEnforced
+	if sw.Has(androidTagAllApplications) || hw.Has(androidTagAllApplications) {
+		return nil, errors.New("webauthn: Android key is not scoped to an application")
+	}
+	origin, ok := hw.Origin, hw.Has(androidTagOrigin)
+	if !ok {
+		origin, ok = sw.Origin, sw.Has(androidTagOrigin)
+	}
+	if !ok || origin != androidOriginGenerated {
+		return nil, errors.New("webauthn: Android key was not generated in the keystore")
+	}
+	canSign := false
+	for _, p := range append(sw.Purpose[:len(sw.Purpose):len(sw.Purpose)], hw.Purpose...) {
+		if p == androidPurposeSign {
+			canSign = true
+		}
+	}
+	if !canSign {
+		return nil, errors.New("webauthn: Android key is not a signing key")
+	}
+	return &Attestation{Format: o.Format, Type: AttestationCertificate, Certificates: certs, AndroidKey: kd}, nil
+}
@@ -0,0 +1,439 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package webauthn implements the relying party side of Web
+// Authentication (https://www.w3.org/TR/webauthn-2/): parsing authenticator
+// data and client data, verifying attestation statements, and checking
+// assertions.
+//
+// A RelyingParty verifies the result of navigator.credentials.create with
+// VerifyRegistration, and stores the returned credential ID, public key,
+// and signature counter. It then verifies the results of
+// navigator.credentials.get with VerifyAssertion. The challenges must be
+// random, generated by the server, and used once.
+//
+// The "none", "packed", "tpm", and "android-key" attestation statement
+// formats are supported, with the ES256, ES384, ES512, EdDSA, PS256, and
+// RS256 algorithms of package crypto/cose.
+package webauthn
+
+import (
+	"crypto"
+	"crypto/cose"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/internal/cbor"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+// Flags are the flags of authenticator data.
+type Flags byte
+
+const (
+	FlagUserPresent            Flags = 0x01 // UP
+	FlagUserVerified           Flags = 0x04 // UV
+	FlagBackupEligible         Flags = 0x08 // BE
+	FlagBackedUp               Flags = 0x10 // BS
+	FlagAttestedCredentialData Flags = 0x40 // AT
+	FlagExtensionData          Flags = 0x80 // ED
+)
+
+// AuthenticatorData is the data signed by an authenticator in attestations
+// and assertions.
+type AuthenticatorData struct {
+	// Raw is the encoding of the authenticator data.
+	Raw []byte
+
+	// RPIDHash is the SHA-256 hash of the RP ID the credential is scoped
+	// to.
+	RPIDHash [32]byte
+
+	Flags     Flags
+	SignCount uint32
+
+	// AttestedCredential is present if Flags has FlagAttestedCredentialData,
+	// which is only the case in attestations.
+	AttestedCredential *AttestedCredential
+
+	// Extensions is the CBOR encoding of the authenticator extension
+	// outputs, present if Flags has FlagExtensionData.
+	Extensions []byte
+}
+
+// AttestedCredential is the attested credential data of a new credential.
+type AttestedCredential struct {
+	// AAGUID identifies the model of the authenticator. It is all zeroes
+	// if the authenticator does not disclose it.
+	AAGUID [16]byte
+
+	// ID is the credential ID.
+	ID []byte
+
+	// PublicKey is the credential public key, and RawPublicKey its
+	// COSE_Key encoding.
+	PublicKey    *cose.Key
+	RawPublicKey []byte
+}
+
+var errMalformedAuthData = errors.New("webauthn: malformed authenticator data")
+
+// ParseAuthenticatorData parses authenticator data. A credential public
+// key must have an algorithm.
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errMalformedAuthData
+	}
+	ad := &AuthenticatorData{Raw: data}
+	copy(ad.RPIDHash[:], data)
+	ad.Flags = Flags(data[32])
+	ad.SignCount = binary.BigEndian.Uint32(data[33:])
+	rest := data[37:]
+
+	if ad.Flags&FlagAttestedCredentialData != 0 {
+		if len(rest) < 18 {
+			return nil, errMalformedAuthData
+		}
+		cred := &AttestedCredential{}
+		copy(cred.AAGUID[:], rest)
+		n := int(binary.BigEndian.Uint16(rest[16:]))
+		rest = rest[18:]
+		if n > 1023 || len(rest) < n {
+			return nil, errMalformedAuthData
+		}
+		cred.ID, rest = rest[:n], rest[n:]
+
+		// The public key is followed by the extensions, if any, so find
+		// its end by decoding it.
+		_, after, err := cbor.Decode(rest)
+		if err != nil {
+			return nil, errMalformedAuthData
+		}
+		cred.RawPublicKey, rest = rest[:len(rest)-len(after)], after
+		if cred.PublicKey, err = cose.ParseKey(cred.RawPublicKey); err != nil {
+			return nil, err
+		}
+		if cred.PublicKey.Algorithm == 0 {
+			return nil, errors.New("webauthn: credential public key has no algorithm")
+		}
+		ad.AttestedCredential = cred
+	}
+
+	if ad.Flags&FlagExtensionData != 0 {
+		v, after, err := cbor.Decode(rest)
+		if err != nil || len(after) != 0 {
+			return nil, errMalformedAuthData
+		}
+		if _, ok := v.(cbor.Map); !ok {
+			return nil, errMalformedAuthData
+		}
+		ad.Extensions, rest = rest, nil
+	}
+	if len(rest) != 0 {
+		return nil, errMalformedAuthData
+	}
+	return ad, nil
+}
+
+// ClientData is the client data collected by the browser, which the
+// authenticator signs a hash of.
+type ClientData struct {
+	// Type is "webauthn.create" for attestations and "webauthn.get" for
+	// assertions.
+	Type string
+
+	// Challenge is the decoded challenge of the ceremony.
+	Challenge []byte
+
+	// Origin is the origin of the page that started the ceremony, such
+	// as "https://example.com".
+	Origin string
+
+	// CrossOrigin reports whether the ceremony was started from a
+	// cross-origin iframe, in which case TopOrigin is the origin of the
+	// top-level page.
+	CrossOrigin bool
+	TopOrigin   string
+}
+
+// ParseClientData parses the JSON client data of a ceremony, the
+// clientDataJSON of the authenticator response.
+func ParseClientData(clientDataJSON []byte) (*ClientData, error) {
+	var v struct {
+		Type        string `json:"type"`
+		Challenge   string `json:"challenge"`
+		Origin      string `json:"origin"`
+		CrossOrigin bool   `json:"crossOrigin"`
+		TopOrigin   string `json:"topOrigin"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &v); err != nil {
+		return nil, errors.New("webauthn: malformed client data: " + err.Error())
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(v.Challenge)
+	if err != nil {
+		return nil, errors.New("webauthn: malformed client data challenge")
+	}
+	return &ClientData{
+		Type:        v.Type,
+		Challenge:   challenge,
+		Origin:      v.Origin,
+		CrossOrigin: v.CrossOrigin,
+		TopOrigin:   v.TopOrigin,
+	}, nil
+}
+
+// A RelyingParty verifies the ceremonies of a WebAuthn relying party.
+type RelyingParty struct {
+	// ID is the RP ID, the domain credentials are scoped to, such as
+	// "example.com".
+	ID string
+
+	// Origins are the allowed origins of ceremonies, such as
+	// "https://login.example.com".
+	Origins []string
+
+	// RequireUserVerification requires the authenticator to have verified
+	// the user, for example with a PIN or biometrics, and not only their
+	// presence.
+	RequireUserVerification bool
+
+	// AttestationRoots, if not nil, are the roots that the attestation
+	// certificates of new credentials must chain to. Self attestation
+	// and the "none" format are then rejected. If nil, the attestation
+	// signature is verified, but not its trust path.
+	AttestationRoots *x509.CertPool
+}
+
+// A Credential is a credential created by VerifyRegistration, which the
+// relying party stores with the user's account.
+type Credential struct {
+	// ID is the credential ID, which identifies the credential in
+	// assertions.
+	ID []byte
+
+	// PublicKey is the credential public key, and RawPublicKey its
+	// COSE_Key encoding, which can be stored and parsed again with
+	// cose.ParseKey.
+	PublicKey    *cose.Key
+	RawPublicKey []byte
+
+	// SignCount is the initial signature counter.
+	SignCount uint32
+
+	// AAGUID identifies the model of the authenticator.
+	AAGUID [16]byte
+
+	// Flags are the flags of the authenticator data. FlagBackupEligible
+	// identifies synced credentials, such as passkeys.
+	Flags Flags
+
+	// Attestation is the verified attestation of the credential.
+	Attestation *Attestation
+}
+
+// checkClientData checks the client data of a ceremony of the given type,
+// and returns its hash.
+func (rp *RelyingParty) checkClientData(typ string, challenge, clientDataJSON []byte) ([]byte, error) {
+	cd, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	if cd.Type != typ {
+		return nil, errors.New("webauthn: client data has type " + cd.Type + ", want " + typ)
+	}
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(cd.Challenge, challenge) != 1 {
+		return nil, errors.New("webauthn: challenge does not match")
+	}
+	allowed := false
+	for _, o := range rp.Origins {
+		if cd.Origin == o {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, errors.New("webauthn: origin " + cd.Origin + " is not allowed")
+	}
+	h := sha256.Sum256(clientDataJSON)
+	return h[:], nil
+}
+
+// checkAuthData checks the RP ID hash and the user flags of ad.
+func (rp *RelyingParty) checkAuthData(ad *AuthenticatorData) error {
+	if ad.RPIDHash != sha256.Sum256([]byte(rp.ID)) {
+		return errors.New("webauthn: authenticator data is for another RP ID")
+	}
+	if ad.Flags&FlagUserPresent == 0 {
+		return errors.New("webauthn: user was not present")
+	}
+	if rp.RequireUserVerification && ad.Flags&FlagUserVerified == 0 {
+		return errors.New("webauthn: user was not verified")
+	}
+	return nil
+}
+
+// VerifyRegistration verifies the response to a navigator.credentials.create
+// call with the given challenge, and returns the new credential.
+//
+// The caller must also check that the credential ID is not already
+// registered, and may check the attestation of the credential, or its
+// AAGUID, against its policy.
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	clientDataHash, err := rp.checkClientData("webauthn.create", challenge, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := ParseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	ad := obj.AuthData
+	if err := rp.checkAuthData(ad); err != nil {
+		return nil, err
+	}
+	cred := ad.AttestedCredential
+	if cred == nil {
+		return nil, errors.New("webauthn: attestation has no attested credential data")
+	}
+	if !supportedAlgorithm(cred.PublicKey.Algorithm) {
+		return nil, errors.New("webauthn: unsupported credential algorithm " + cred.PublicKey.Algorithm.String())
+	}
+	att, err := obj.Verify(clientDataHash, &AttestationOptions{Roots: rp.AttestationRoots})
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:           cred.ID,
+		PublicKey:    cred.PublicKey,
+		RawPublicKey: cred.RawPublicKey,
+		SignCount:    ad.SignCount,
+		AAGUID:       cred.AAGUID,
+		Flags:        ad.Flags,
+		Attestation:  att,
+	}, nil
+}
+
+// VerifyAssertion verifies the response to a navigator.credentials.get call
+// with the given challenge, signed by the credential with public key pub,
+// and returns its authenticator data.
+//
+// The caller must look up the credential by the ID of the response, and
+// check that it belongs to the expected user. It should then compare the
+// returned SignCount with the stored one: if either is not zero, a counter
+// that did not increase may reveal a cloned authenticator. Finally, it
+// stores the new counter.
+func (rp *RelyingParty) VerifyAssertion(pub *cose.Key, challenge, clientDataJSON, authenticatorData, signature []byte) (*AuthenticatorData, error) {
+	clientDataHash, err := rp.checkClientData("webauthn.get", challenge, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := ParseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthData(ad); err != nil {
+		return nil, err
+	}
+	signed := append(authenticatorData[:len(authenticatorData):len(authenticatorData)], clientDataHash...)
+	if err := verifySignature(pub.Algorithm, pub.Public, signed, signature); err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
+// ErrVerification is returned when an attestation or assertion signature
+// is invalid.
+var ErrVerification = errors.New("webauthn: signature verification failed")
+
+func supportedAlgorithm(alg cose.Algorithm) bool {
+	switch alg {
+	case cose.ES256, cose.ES384, cose.ES512, cose.EdDSA, cose.PS256, cose.RS256:
+		return true
+	}
+	return false
+}
+
+// algorithmHash returns the hash function of alg, or zero for EdDSA.
+func algorithmHash(alg cose.Algorithm) crypto.Hash {
+	switch alg {
+	case cose.ES256, cose.PS256, cose.RS256:
+		return crypto.SHA256
+	case cose.ES384:
+		return crypto.SHA384
+	case cose.ES512:
+		return crypto.SHA512
+	}
+	return 0
+}
+
+func curveBits(alg cose.Algorithm) int {
+	switch alg {
+	case cose.ES256:
+		return 256
+	case cose.ES384:
+		return 384
+	case cose.ES512:
+		return 521
+	}
+	return 0
+}
+
+// verifySignature verifies a WebAuthn signature, which for ECDSA is
+// ASN.1-encoded, unlike COSE signatures.
+func verifySignature(alg cose.Algorithm, pub crypto.PublicKey, data, sig []byte) error {
+	if !supportedAlgorithm(alg) {
+		return errors.New("webauthn: unsupported signature algorithm " + alg.String())
+	}
+	var digest []byte
+	if h := algorithmHash(alg); h != 0 {
+		d := h.New()
+		d.Write(data)
+		digest = d.Sum(nil)
+	}
+	mismatch := errors.New("webauthn: key does not match algorithm " + alg.String())
+	switch alg {
+	case cose.ES256, cose.ES384, cose.ES512:
+		key, ok := pub.(*ecdsa.PublicKey)
+		if !ok || key.Curve.Params().BitSize != curveBits(alg) {
+			return mismatch
+		}
+		if !ecdsa.VerifyASN1(key, digest, sig) {
+			return ErrVerification
+		}
+	case cose.EdDSA:
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return mismatch
+		}
+		if !ed25519.Verify(key, data, sig) {
+			return ErrVerification
+		}
+	case cose.PS256, cose.RS256:
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok || key.N.BitLen() < 2048 {
+			return mismatch
+		}
+		var err error
+		if alg == cose.PS256 {
+			err = rsa.VerifyPSS(key, crypto.SHA256, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig)
+		}
+		if err != nil {
+			return ErrVerification
+		}
+	}
+	return nil
+}
+
+// equalKeys reports whether two public keys are equal.
+func equalKeys(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
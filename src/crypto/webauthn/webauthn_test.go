@@ -0,0 +1,468 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cose"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/internal/cbor"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const testOrigin = "https://login.example.com"
+
+var (
+	testRP        = &RelyingParty{ID: "example.com", Origins: []string{testOrigin}}
+	testChallenge = []byte("0123456789abcdef")
+	testAAGUID    = [16]byte{0xf8, 0xa0, 0x11, 0xf3, 0x8c, 0x0a, 0x4d, 0x15, 0x80, 0x06, 0x17, 0x11, 0x1f, 0x9e, 0xdc, 0x7d}
+)
+
+// An authenticator is a test authenticator holding a single credential.
+type authenticator struct {
+	key    crypto.Signer
+	alg    cose.Algorithm
+	id     []byte
+	count  uint32
+	aaguid [16]byte
+}
+
+func newAuthenticator(t *testing.T, alg cose.Algorithm) *authenticator {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case cose.ES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case cose.EdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case cose.RS256, cose.PS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{key: key, alg: alg, id: []byte("credential id"), aaguid: testAAGUID}
+}
+
+func clientDataJSON(typ string, challenge []byte) []byte {
+	return []byte(`{"type":"` + typ + `","challenge":"` + base64.RawURLEncoding.EncodeToString(challenge) +
+		`","origin":"` + testOrigin + `","crossOrigin":false}`)
+}
+
+func (a *authenticator) authData(t *testing.T, rpID string, flags Flags, attested bool) []byte {
+	h := sha256.Sum256([]byte(rpID))
+	a.count++
+	b := append(h[:], byte(flags))
+	b = binary.BigEndian.AppendUint32(b, a.count)
+	if attested {
+		b[32] |= byte(FlagAttestedCredentialData)
+		b = append(b, a.aaguid[:]...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.id)))
+		b = append(b, a.id...)
+		k, err := (&cose.Key{Public: a.key.Public(), Algorithm: a.alg}).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, k...)
+	}
+	return b
+}
+
+func sign(t *testing.T, key crypto.Signer, alg cose.Algorithm, data []byte) []byte {
+	var sig []byte
+	var err error
+	if alg == cose.EdDSA {
+		sig, err = key.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		h := sha256.Sum256(data)
+		var opts crypto.SignerOpts = crypto.SHA256
+		if alg == cose.PS256 {
+			opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+		sig, err = key.Sign(rand.Reader, h[:], opts)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func attestationObject(t *testing.T, format string, stmt cbor.Map, authData []byte) []byte {
+	b, err := cbor.Marshal(cbor.Map{{Key: "fmt", Value: format}, {Key: "attStmt", Value: stmt}, {Key: "authData", Value: authData}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func signedData(authData, clientDataJSON []byte) []byte {
+	h := sha256.Sum256(clientDataJSON)
+	return append(authData[:len(authData):len(authData)], h[:]...)
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	for _, alg := range []cose.Algorithm{cose.ES256, cose.EdDSA, cose.RS256, cose.PS256} {
+		t.Run(alg.String(), func(t *testing.T) {
+			a := newAuthenticator(t, alg)
+			cd := clientDataJSON("webauthn.create", testChallenge)
+			obj := attestationObject(t, "none", cbor.Map{}, a.authData(t, "example.com", FlagUserPresent|FlagBackupEligible, true))
+			cred, err := testRP.VerifyRegistration(testChallenge, cd, obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(cred.ID, a.id) || cred.AAGUID != testAAGUID || cred.SignCount != 1 ||
+				cred.Flags&FlagBackupEligible == 0 || cred.Attestation.Type != AttestationNone {
+				t.Errorf("VerifyRegistration returned %+v", cred)
+			}
+			pub, err := cose.ParseKey(cred.RawPublicKey)
+			if err != nil || !equalKeys(a.key.Public(), pub.Public) {
+				t.Fatalf("stored public key = %v, %v", pub, err)
+			}
+
+			cd = clientDataJSON("webauthn.get", testChallenge)
+			ad := a.authData(t, "example.com", FlagUserPresent|FlagUserVerified, false)
+			sig := sign(t, a.key, alg, signedData(ad, cd))
+			got, err := testRP.VerifyAssertion(pub, testChallenge, cd, ad, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SignCount != 2 || got.AttestedCredential != nil {
+				t.Errorf("VerifyAssertion returned %+v", got)
+			}
+
+			if _, err := testRP.VerifyAssertion(pub, []byte("other challenge"), cd, ad, sig); err == nil {
+				t.Errorf("VerifyAssertion with the wrong challenge succeeded")
+			}
+			if _, err := testRP.VerifyAssertion(pub, testChallenge, clientDataJSON("webauthn.create", testChallenge), ad, sig); err == nil {
+				t.Errorf("VerifyAssertion of the wrong ceremony succeeded")
+			}
+			other := &RelyingParty{ID: "example.com", Origins: []string{"https://example.org"}}
+			if _, err := other.VerifyAssertion(pub, testChallenge, cd, ad, sig); err == nil {
+				t.Errorf("VerifyAssertion from the wrong origin succeeded")
+			}
+			other = &RelyingParty{ID: "example.org", Origins: []string{testOrigin}}
+			if _, err := other.VerifyAssertion(pub, testChallenge, cd, ad, sig); err == nil {
+				t.Errorf("VerifyAssertion for the wrong RP ID succeeded")
+			}
+			ad[len(ad)-1] ^= 1
+			if _, err := testRP.VerifyAssertion(pub, testChallenge, cd, ad, sig); err != ErrVerification {
+				t.Errorf("VerifyAssertion of modified authenticator data: %v", err)
+			}
+		})
+	}
+}
+
+func TestUserFlags(t *testing.T) {
+	a := newAuthenticator(t, cose.ES256)
+	cd := clientDataJSON("webauthn.create", testChallenge)
+	obj := attestationObject(t, "none", cbor.Map{}, a.authData(t, "example.com", 0, true))
+	if _, err := testRP.VerifyRegistration(testChallenge, cd, obj); err == nil {
+		t.Errorf("registration without user presence succeeded")
+	}
+	rp := &RelyingParty{ID: "example.com", Origins: []string{testOrigin}, RequireUserVerification: true}
+	obj = attestationObject(t, "none", cbor.Map{}, a.authData(t, "example.com", FlagUserPresent, true))
+	if _, err := rp.VerifyRegistration(testChallenge, cd, obj); err == nil {
+		t.Errorf("registration without user verification succeeded")
+	}
+	obj = attestationObject(t, "none", cbor.Map{}, a.authData(t, "example.com", FlagUserPresent|FlagUserVerified, true))
+	if _, err := rp.VerifyRegistration(testChallenge, cd, obj); err != nil {
+		t.Errorf("registration with user verification: %v", err)
+	}
+}
+
+func TestParseAuthenticatorDataExtensions(t *testing.T) {
+	a := newAuthenticator(t, cose.ES256)
+	ext, _ := cbor.Marshal(cbor.Map{{Key: "credProtect", Value: int64(2)}})
+	ad := append(a.authData(t, "example.com", FlagUserPresent|FlagExtensionData, true), ext...)
+	got, err := ParseAuthenticatorData(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Extensions, ext) || got.AttestedCredential == nil {
+		t.Errorf("ParseAuthenticatorData returned %+v", got)
+	}
+	for _, bad := range [][]byte{ad[:36], ad[:len(ad)-1], append(ad, 0)} {
+		if _, err := ParseAuthenticatorData(bad); err == nil {
+			t.Errorf("ParseAuthenticatorData(%x) succeeded", bad)
+		}
+	}
+}
+
+func TestPackedSelf(t *testing.T) {
+	a := newAuthenticator(t, cose.EdDSA)
+	cd := clientDataJSON("webauthn.create", testChallenge)
+	ad := a.authData(t, "example.com", FlagUserPresent, true)
+	stmt := cbor.Map{
+		{Key: "alg", Value: int64(cose.EdDSA)},
+		{Key: "sig", Value: sign(t, a.key, cose.EdDSA, signedData(ad, cd))},
+	}
+	cred, err := testRP.VerifyRegistration(testChallenge, cd, attestationObject(t, "packed", stmt, ad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Attestation.Type != AttestationSelf {
+		t.Errorf("attestation type = %v", cred.Attestation.Type)
+	}
+
+	stmt[0].Value = int64(cose.ES256)
+	if _, err := testRP.VerifyRegistration(testChallenge, cd, attestationObject(t, "packed", stmt, ad)); err == nil {
+		t.Errorf("self attestation with the wrong algorithm succeeded")
+	}
+	stmt[0].Value = int64(cose.EdDSA)
+	rp := &RelyingParty{ID: "example.com", Origins: []string{testOrigin}, AttestationRoots: x509.NewCertPool()}
+	if _, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "packed", stmt, ad)); err == nil {
+		t.Errorf("self attestation with required roots succeeded")
+	}
+}
+
+// testCA returns a new root certificate and its key.
+func testCA(t *testing.T) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func issue(t *testing.T, tmpl, parent *x509.Certificate, pub crypto.PublicKey, parentKey crypto.Signer) []byte {
+	tmpl.SerialNumber = big.NewInt(2)
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.BasicConstraintsValid = true
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func aaguidExtension(aaguid [16]byte) pkix.Extension {
+	v, _ := asn1.Marshal(aaguid[:])
+	return pkix.Extension{Id: oidFIDOAAGUID, Value: v}
+}
+
+func TestPackedCertificate(t *testing.T) {
+	root, rootKey := testCA(t)
+	attKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := issue(t, &x509.Certificate{
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{"Example Authenticators"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Example Key Series 1",
+		},
+		ExtraExtensions: []pkix.Extension{aaguidExtension(testAAGUID)},
+	}, root, attKey.Public(), rootKey)
+
+	a := newAuthenticator(t, cose.ES256)
+	cd := clientDataJSON("webauthn.create", testChallenge)
+	ad := a.authData(t, "example.com", FlagUserPresent, true)
+	stmt := cbor.Map{
+		{Key: "alg", Value: int64(cose.ES256)},
+		{Key: "sig", Value: sign(t, attKey, cose.ES256, signedData(ad, cd))},
+		{Key: "x5c", Value: []any{leaf}},
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	rp := &RelyingParty{ID: "example.com", Origins: []string{testOrigin}, AttestationRoots: roots}
+	cred, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "packed", stmt, ad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att := cred.Attestation; att.Type != AttestationCertificate || len(att.Certificates) != 1 {
+		t.Errorf("attestation = %+v", att)
+	}
+
+	other, _ := testCA(t)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other)
+	rp.AttestationRoots = otherRoots
+	if _, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "packed", stmt, ad)); err == nil {
+		t.Errorf("attestation from an untrusted root succeeded")
+	}
+
+	a.aaguid[0] ^= 1
+	ad = a.authData(t, "example.com", FlagUserPresent, true)
+	stmt[1].Value = sign(t, attKey, cose.ES256, signedData(ad, cd))
+	if _, err := testRP.VerifyRegistration(testChallenge, cd, attestationObject(t, "packed", stmt, ad)); err == nil {
+		t.Errorf("attestation with a mismatched AAGUID succeeded")
+	}
+}
+
+func TestTPM(t *testing.T) {
+	root, rootKey := testCA(t)
+	aikKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	san, _ := asn1.Marshal([]asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: []byte{0x30, 0x00}}})
+	aik := issue(t, &x509.Certificate{
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidTCGKpAIKCertificate},
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidExtensionSubjectAltName, Critical: true, Value: san},
+			aaguidExtension(testAAGUID),
+		},
+	}, root, aikKey.Public(), rootKey)
+
+	a := newAuthenticator(t, cose.RS256)
+	cd := clientDataJSON("webauthn.create", testChallenge)
+	ad := a.authData(t, "example.com", FlagUserPresent, true)
+	pub := a.key.Public().(*rsa.PublicKey)
+
+	var b cryptobyte.Builder
+	b.AddUint16(tpmAlgRSA)
+	b.AddUint16(0x000b) // SHA-256
+	b.AddUint32(0x00040072)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {})
+	b.AddUint16(tpmAlgNull)
+	b.AddUint16(0x0014) // RSASSA
+	b.AddUint16(0x000b)
+	b.AddUint16(2048)
+	b.AddUint32(0)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(pub.N.Bytes()) })
+	pubArea := b.BytesOrPanic()
+
+	name := sha256.Sum256(pubArea)
+	extraData := sha256.Sum256(signedData(ad, cd))
+	b = cryptobyte.Builder{}
+	b.AddUint32(0xff544347)
+	b.AddUint16(0x8017)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("signer")) })
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(extraData[:]) })
+	b.AddBytes(make([]byte, 17+8))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte{0x00, 0x0b}); b.AddBytes(name[:]) })
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {})
+	certInfo := b.BytesOrPanic()
+
+	stmt := cbor.Map{
+		{Key: "ver", Value: "2.0"},
+		{Key: "alg", Value: int64(cose.RS256)},
+		{Key: "x5c", Value: []any{aik}},
+		{Key: "sig", Value: sign(t, aikKey, cose.RS256, certInfo)},
+		{Key: "certInfo", Value: certInfo},
+		{Key: "pubArea", Value: pubArea},
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	rp := &RelyingParty{ID: "example.com", Origins: []string{testOrigin}, AttestationRoots: roots}
+	cred, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "tpm", stmt, ad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Attestation.Format != "tpm" || cred.Attestation.Type != AttestationCertificate {
+		t.Errorf("attestation = %+v", cred.Attestation)
+	}
+
+	// The credential must be the certified key.
+	other := newAuthenticator(t, cose.RS256)
+	other.count = a.count
+	otherAD := other.authData(t, "example.com", FlagUserPresent, true)
+	if _, err := testRP.VerifyRegistration(testChallenge, cd, attestationObject(t, "tpm", stmt, otherAD)); err == nil {
+		t.Errorf("TPM attestation of another key succeeded")
+	}
+	// certInfo must be bound to the authenticator data.
+	ad = a.authData(t, "example.com", FlagUserPresent, true)
+	if _, err := testRP.VerifyRegistration(testChallenge, cd, attestationObject(t, "tpm", stmt, ad)); err == nil {
+		t.Errorf("TPM attestation with mismatched extra data succeeded")
+	}
+}
+
+type testAuthorizationList struct {
+	Purpose []int `asn1:"optional,explicit,tag:1,set"`
+	Origin  int   `asn1:"explicit,tag:702"`
+}
+
+type testKeyDescription struct {
+	AttestationVersion       int
+	AttestationSecurityLevel asn1.Enumerated
+	KeyMintVersion           int
+	KeyMintSecurityLevel     asn1.Enumerated
+	AttestationChallenge     []byte
+	UniqueID                 []byte
+	SoftwareEnforced         struct{}
+	HardwareEnforced         testAuthorizationList
+}
+
+func TestAndroidKey(t *testing.T) {
+	root, rootKey := testCA(t)
+	a := newAuthenticator(t, cose.ES256)
+	cd := clientDataJSON("webauthn.create", testChallenge)
+	ad := a.authData(t, "example.com", FlagUserPresent, true)
+	cdHash := sha256.Sum256(cd)
+
+	leafFor := func(challenge []byte, purpose []int) []byte {
+		kd, err := asn1.Marshal(testKeyDescription{
+			AttestationVersion:       200,
+			AttestationSecurityLevel: 1,
+			KeyMintVersion:           200,
+			KeyMintSecurityLevel:     1,
+			AttestationChallenge:     challenge,
+			UniqueID:                 []byte{},
+			HardwareEnforced:         testAuthorizationList{Purpose: purpose},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return issue(t, &x509.Certificate{
+			Subject: pkix.Name{CommonName: "Android Keystore Key"},
+			ExtraExtensions: []pkix.Extension{
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}, Value: kd},
+			},
+		}, root, a.key.Public(), rootKey)
+	}
+	stmt := cbor.Map{
+		{Key: "alg", Value: int64(cose.ES256)},
+		{Key: "sig", Value: sign(t, a.key, cose.ES256, signedData(ad, cd))},
+		{Key: "x5c", Value: []any{leafFor(cdHash[:], []int{androidPurposeSign})}},
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	rp := &RelyingParty{ID: "example.com", Origins: []string{testOrigin}, AttestationRoots: roots}
+	cred, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "android-key", stmt, ad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att := cred.Attestation; att.AndroidKey == nil || att.AndroidKey.AttestationSecurityLevel != 1 {
+		t.Errorf("attestation = %+v", att)
+	}
+
+	stmt[2].Value = []any{leafFor([]byte("other"), []int{androidPurposeSign})}
+	if _, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "android-key", stmt, ad)); err == nil {
+		t.Errorf("Android attestation with the wrong challenge succeeded")
+	}
+	stmt[2].Value = []any{leafFor(cdHash[:], []int{3})}
+	if _, err := rp.VerifyRegistration(testChallenge, cd, attestationObject(t, "android-key", stmt, ad)); err == nil {
+		t.Errorf("Android attestation of a non-signing key succeeded")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+)
+
+// An AnchorPolicy is a restricted-algorithm policy for trust anchors. It can
+// be used to filter a CertPool, such as the one returned by SystemCertPool,
+// so that compliance-sensitive programs only trust roots that meet their
+// algorithm requirements.
+//
+// The zero value rejects RSA keys smaller than 2048 bits and roots signed
+// with MD2, MD5 or SHA-1 based algorithms.
+type AnchorPolicy struct {
+	// MinRSAKeySize is the minimum size, in bits, of RSA public keys. If
+	// zero, 2048 is used.
+	MinRSAKeySize int
+
+	// AllowSHA1 permits roots whose self-signature uses a SHA-1 based
+	// algorithm.
+	AllowSHA1 bool
+
+	// FIPS restricts public keys to RSA 2048, 3072 or 4096 bits and ECDSA
+	// P-256, P-384 or P-521, the keys allowed in certificate chains when
+	// crypto/tls/fipsonly is in effect.
+	FIPS bool
+}
+
+// Check returns an error describing why cert is not acceptable as a trust
+// anchor under p, or nil if it is.
+//
+// When FIPS-only mode is enforced, certificates that Verify would not use in
+// a chain are always rejected, regardless of p.
+func (p *AnchorPolicy) Check(cert *Certificate) error {
+	switch cert.SignatureAlgorithm {
+	case MD2WithRSA, MD5WithRSA:
+		return fmt.Errorf("x509: %v signature is not allowed", cert.SignatureAlgorithm)
+	case SHA1WithRSA, DSAWithSHA1, ECDSAWithSHA1:
+		if !p.AllowSHA1 {
+			return fmt.Errorf("x509: %v signature is not allowed", cert.SignatureAlgorithm)
+		}
+	}
+
+	minRSAKeySize := p.MinRSAKeySize
+	if minRSAKeySize == 0 {
+		minRSAKeySize = 2048
+	}
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		size := k.N.BitLen()
+		if size < minRSAKeySize {
+			return fmt.Errorf("x509: %d-bit RSA key is not allowed", size)
+		}
+		if p.FIPS && size != 2048 && size != 3072 && size != 4096 {
+			return fmt.Errorf("x509: %d-bit RSA key is not allowed in FIPS mode", size)
+		}
+	case *ecdsa.PublicKey:
+		if p.FIPS && k.Curve != elliptic.P256() && k.Curve != elliptic.P384() && k.Curve != elliptic.P521() {
+			return fmt.Errorf("x509: ECDSA key on curve %s is not allowed in FIPS mode", k.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		if p.FIPS {
+			return errors.New("x509: Ed25519 key is not allowed in FIPS mode")
+		}
+	default:
+		if p.FIPS {
+			return fmt.Errorf("x509: %v key is not allowed in FIPS mode", cert.PublicKeyAlgorithm)
+		}
+	}
+
+	if !boringAllowCert(cert) {
+		return errors.New("x509: certificate is not allowed by FIPS-only mode")
+	}
+	return nil
+}
+
+// A FilteredCertificate is a certificate removed from a pool by
+// AnchorPolicy.Filter, along with the reason it was removed.
+type FilteredCertificate struct {
+	Cert *Certificate
+	Err  error
+}
+
+// Filter returns a new pool with the certificates of pool that are
+// acceptable under p, and the list of certificates that were removed.
+//
+// Filter returns an error if pool is a platform pool whose certificates
+// cannot be enumerated, such as the pool returned by SystemCertPool on
+// macOS, iOS and Windows, where the platform verifier would ignore p.
+func (p *AnchorPolicy) Filter(pool *CertPool) (*CertPool, []FilteredCertificate, error) {
+	if pool.systemPool {
+		return nil, nil, errors.New("x509: cannot apply an anchor policy to the platform root store")
+	}
+	filteredPool := NewCertPool()
+	var filtered []FilteredCertificate
+	for i := 0; i < pool.len(); i++ {
+		cert, err := pool.cert(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := p.Check(cert); err != nil {
+			filtered = append(filtered, FilteredCertificate{Cert: cert, Err: err})
+			continue
+		}
+		filteredPool.AddCert(cert)
+	}
+	return filteredPool, filtered, nil
+}
+
+// SystemCertPoolWithPolicy returns a copy of the system cert pool, as
+// returned by SystemCertPool, containing only the certificates acceptable
+// under p, and the list of system certificates that were removed.
+//
+// See AnchorPolicy.Filter for the platforms where this is not supported.
+func SystemCertPoolWithPolicy(p *AnchorPolicy) (*CertPool, []FilteredCertificate, error) {
+	pool, err := SystemCertPool()
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.Filter(pool)
+}
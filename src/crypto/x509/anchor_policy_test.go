@@ -0,0 +1,119 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestAnchorPolicy(t *testing.T) {
+	newRoot := func(name string, key crypto.Signer, sigAlg SignatureAlgorithm) *Certificate {
+		t.Helper()
+		template := &Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			SignatureAlgorithm:    sigAlg,
+		}
+		der, err := CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsa2048Root := newRoot("rsa 2048", rsa2048, SHA256WithRSA)
+	rsa1024Root := newRoot("rsa 1024", rsa1024, SHA256WithRSA)
+	sha1Root := newRoot("sha1", rsa2048, SHA1WithRSA)
+	p224Root := newRoot("p224", p224, ECDSAWithSHA256)
+	p256Root := newRoot("p256", p256, ECDSAWithSHA256)
+	edRoot := newRoot("ed25519", ed, PureEd25519)
+
+	tests := []struct {
+		name   string
+		policy AnchorPolicy
+		cert   *Certificate
+		ok     bool
+	}{
+		{"default, rsa 2048", AnchorPolicy{}, rsa2048Root, true},
+		{"default, rsa 1024", AnchorPolicy{}, rsa1024Root, false},
+		{"lowered minimum, rsa 1024", AnchorPolicy{MinRSAKeySize: 1024}, rsa1024Root, true},
+		{"default, sha1", AnchorPolicy{}, sha1Root, false},
+		{"allow sha1", AnchorPolicy{AllowSHA1: true}, sha1Root, true},
+		{"default, p224", AnchorPolicy{}, p224Root, true},
+		{"fips, p224", AnchorPolicy{FIPS: true}, p224Root, false},
+		{"fips, p256", AnchorPolicy{FIPS: true}, p256Root, true},
+		{"default, ed25519", AnchorPolicy{}, edRoot, true},
+		{"fips, ed25519", AnchorPolicy{FIPS: true}, edRoot, false},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Check(tt.cert)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Check returned %v, want success %v", tt.name, err, tt.ok)
+		}
+	}
+
+	pool := NewCertPool()
+	for _, c := range []*Certificate{rsa2048Root, rsa1024Root, sha1Root, p224Root, p256Root, edRoot} {
+		pool.AddCert(c)
+	}
+	policy := &AnchorPolicy{FIPS: true}
+	filteredPool, filtered, err := policy.Filter(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filteredPool.len() != 2 || !filteredPool.contains(rsa2048Root) || !filteredPool.contains(p256Root) {
+		t.Errorf("unexpected filtered pool with %d certificates", filteredPool.len())
+	}
+	if len(filtered) != 4 {
+		t.Fatalf("got %d filtered certificates, want 4", len(filtered))
+	}
+	for _, f := range filtered {
+		if f.Cert == rsa2048Root || f.Cert == p256Root || f.Err == nil {
+			t.Errorf("unexpected filtered certificate %q: %v", f.Cert.Subject.CommonName, f.Err)
+		}
+	}
+
+	if _, _, err := policy.Filter(&CertPool{systemPool: true}); err == nil {
+		t.Errorf("Filter of a platform pool succeeded")
+	}
+}
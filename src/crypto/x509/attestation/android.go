@@ -0,0 +1,277 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var oidAndroidKeyAttestation = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}
+
+// AndroidSecurityLevel is the security level of an Android Keystore
+// attestation or key.
+type AndroidSecurityLevel int
+
+const (
+	AndroidSecurityLevelSoftware           AndroidSecurityLevel = 0
+	AndroidSecurityLevelTrustedEnvironment AndroidSecurityLevel = 1
+	AndroidSecurityLevelStrongBox          AndroidSecurityLevel = 2
+)
+
+func (l AndroidSecurityLevel) String() string {
+	switch l {
+	case AndroidSecurityLevelSoftware:
+		return "Software"
+	case AndroidSecurityLevelTrustedEnvironment:
+		return "TrustedEnvironment"
+	case AndroidSecurityLevelStrongBox:
+		return "StrongBox"
+	}
+	return "AndroidSecurityLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// AndroidVerifiedBootState is the Verified Boot state of an Android device.
+type AndroidVerifiedBootState int
+
+const (
+	AndroidVerifiedBootVerified   AndroidVerifiedBootState = 0
+	AndroidVerifiedBootSelfSigned AndroidVerifiedBootState = 1
+	AndroidVerifiedBootUnverified AndroidVerifiedBootState = 2
+	AndroidVerifiedBootFailed     AndroidVerifiedBootState = 3
+)
+
+func (s AndroidVerifiedBootState) String() string {
+	switch s {
+	case AndroidVerifiedBootVerified:
+		return "Verified"
+	case AndroidVerifiedBootSelfSigned:
+		return "SelfSigned"
+	case AndroidVerifiedBootUnverified:
+		return "Unverified"
+	case AndroidVerifiedBootFailed:
+		return "Failed"
+	}
+	return "AndroidVerifiedBootState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// AndroidKeyDescription is the Android Keystore key attestation extension
+// of a certificate.
+type AndroidKeyDescription struct {
+	AttestationVersion       int
+	AttestationSecurityLevel AndroidSecurityLevel
+	KeyMintVersion           int
+	KeyMintSecurityLevel     AndroidSecurityLevel
+
+	// AttestationChallenge is the challenge provided by the application
+	// requesting the attestation.
+	AttestationChallenge []byte
+	UniqueID             []byte
+
+	// SoftwareEnforced are the authorizations enforced by Android, and
+	// HardwareEnforced those enforced by the secure hardware. Only the
+	// latter can be trusted if the Android system is compromised.
+	SoftwareEnforced AndroidAuthorizationList
+	HardwareEnforced AndroidAuthorizationList
+}
+
+// AndroidAuthorizationList is a list of key authorizations. Integer fields
+// are zero if absent; Tags can be used to tell an absent authorization
+// apart from one with a zero value.
+type AndroidAuthorizationList struct {
+	// Tags lists the tag numbers of all authorizations present in the
+	// list, including those not parsed into other fields.
+	Tags []int
+
+	Purpose           []int // tag 1
+	Algorithm         int   // tag 2
+	KeySize           int   // tag 3
+	Digest            []int // tag 5
+	Padding           []int // tag 6
+	ECCurve           int   // tag 10
+	RSAPublicExponent int64 // tag 200
+	NoAuthRequired    bool  // tag 503
+
+	CreationDateTime time.Time // tag 701
+
+	// Origin is 0 for keys generated in the secure hardware, and 2 for
+	// imported keys.
+	Origin int // tag 702
+
+	RootOfTrust              *AndroidRootOfTrust // tag 704
+	OSVersion                int                 // tag 705
+	OSPatchLevel             int                 // tag 706
+	AttestationApplicationID []byte              // tag 709
+	VendorPatchLevel         int                 // tag 718
+	BootPatchLevel           int                 // tag 719
+}
+
+// Has reports whether the authorization with the given tag is present.
+func (l *AndroidAuthorizationList) Has(tag int) bool {
+	for _, t := range l.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AndroidRootOfTrust describes the boot state of the device.
+type AndroidRootOfTrust struct {
+	VerifiedBootKey   []byte
+	DeviceLocked      bool
+	VerifiedBootState AndroidVerifiedBootState
+	// VerifiedBootHash is only present from attestation version 3.
+	VerifiedBootHash []byte
+}
+
+// ParseAndroidKeyDescription parses the Android key attestation extension
+// of cert, a leaf certificate of an Android Keystore attestation chain.
+func ParseAndroidKeyDescription(cert *x509.Certificate) (*AndroidKeyDescription, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidAndroidKeyAttestation) {
+			return parseAndroidKeyDescription(ext.Value)
+		}
+	}
+	return nil, errors.New("attestation: certificate has no Android key attestation extension")
+}
+
+func parseAndroidKeyDescription(der []byte) (*AndroidKeyDescription, error) {
+	var kd struct {
+		AttestationVersion       int
+		AttestationSecurityLevel asn1.Enumerated
+		KeyMintVersion           int
+		KeyMintSecurityLevel     asn1.Enumerated
+		AttestationChallenge     []byte
+		UniqueID                 []byte
+		SoftwareEnforced         asn1.RawValue
+		HardwareEnforced         asn1.RawValue
+	}
+	if rest, err := asn1.Unmarshal(der, &kd); err != nil {
+		return nil, errors.New("attestation: malformed Android key description: " + err.Error())
+	} else if len(rest) != 0 {
+		return nil, errors.New("attestation: trailing data after Android key description")
+	}
+
+	out := &AndroidKeyDescription{
+		AttestationVersion:       kd.AttestationVersion,
+		AttestationSecurityLevel: AndroidSecurityLevel(kd.AttestationSecurityLevel),
+		KeyMintVersion:           kd.KeyMintVersion,
+		KeyMintSecurityLevel:     AndroidSecurityLevel(kd.KeyMintSecurityLevel),
+		AttestationChallenge:     kd.AttestationChallenge,
+		UniqueID:                 kd.UniqueID,
+	}
+	var err error
+	if out.SoftwareEnforced, err = parseAndroidAuthorizationList(kd.SoftwareEnforced); err != nil {
+		return nil, err
+	}
+	if out.HardwareEnforced, err = parseAndroidAuthorizationList(kd.HardwareEnforced); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func parseAndroidAuthorizationList(raw asn1.RawValue) (AndroidAuthorizationList, error) {
+	var out AndroidAuthorizationList
+	if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagSequence || !raw.IsCompound {
+		return out, errors.New("attestation: malformed Android authorization list")
+	}
+
+	// Every authorization is an EXPLICIT context-specific tagged value, and
+	// tags above 30 use the high-tag-number form, which encoding/asn1
+	// supports but cryptobyte doesn't.
+	rest := raw.Bytes
+	for len(rest) > 0 {
+		var field asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return out, errors.New("attestation: malformed Android authorization list: " + err.Error())
+		}
+		if field.Class != asn1.ClassContextSpecific || !field.IsCompound {
+			return out, errors.New("attestation: malformed Android authorization list")
+		}
+		tag := field.Tag
+		out.Tags = append(out.Tags, tag)
+
+		var value any
+		switch tag {
+		case 1:
+			value = &out.Purpose
+		case 2:
+			value = &out.Algorithm
+		case 3:
+			value = &out.KeySize
+		case 5:
+			value = &out.Digest
+		case 6:
+			value = &out.Padding
+		case 10:
+			value = &out.ECCurve
+		case 200:
+			value = &out.RSAPublicExponent
+		case 503:
+			out.NoAuthRequired = true
+			continue
+		case 701:
+			var ms int64
+			if err := unmarshalAndroidValue(field.Bytes, &ms, tag); err != nil {
+				return out, err
+			}
+			out.CreationDateTime = time.UnixMilli(ms).UTC()
+			continue
+		case 702:
+			value = &out.Origin
+		case 704:
+			var rot struct {
+				VerifiedBootKey   []byte
+				DeviceLocked      bool
+				VerifiedBootState asn1.Enumerated
+				VerifiedBootHash  []byte `asn1:"optional"`
+			}
+			if err := unmarshalAndroidValue(field.Bytes, &rot, tag); err != nil {
+				return out, err
+			}
+			out.RootOfTrust = &AndroidRootOfTrust{
+				VerifiedBootKey:   rot.VerifiedBootKey,
+				DeviceLocked:      rot.DeviceLocked,
+				VerifiedBootState: AndroidVerifiedBootState(rot.VerifiedBootState),
+				VerifiedBootHash:  rot.VerifiedBootHash,
+			}
+			continue
+		case 705:
+			value = &out.OSVersion
+		case 706:
+			value = &out.OSPatchLevel
+		case 709:
+			value = &out.AttestationApplicationID
+		case 718:
+			value = &out.VendorPatchLevel
+		case 719:
+			value = &out.BootPatchLevel
+		default:
+			continue
+		}
+		if err := unmarshalAndroidValue(field.Bytes, value, tag); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func unmarshalAndroidValue(b []byte, value any, tag int) error {
+	var params string
+	switch value.(type) {
+	case *[]int:
+		params = "set"
+	}
+	rest, err := asn1.UnmarshalWithParams(b, value, params)
+	if err != nil || len(rest) != 0 {
+		return errors.New("attestation: malformed Android authorization with tag " + strconv.Itoa(tag))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attestation
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRootOfTrust struct {
+	VerifiedBootKey   []byte
+	DeviceLocked      bool
+	VerifiedBootState asn1.Enumerated
+	VerifiedBootHash  []byte
+}
+
+type testHardwareEnforced struct {
+	Purpose          []int           `asn1:"optional,explicit,tag:1,set"`
+	Algorithm        int             `asn1:"optional,explicit,tag:2"`
+	KeySize          int             `asn1:"optional,explicit,tag:3"`
+	Digest           []int           `asn1:"optional,explicit,tag:5,set"`
+	ECCurve          int             `asn1:"optional,explicit,tag:10"`
+	NoAuthRequired   asn1.RawValue   `asn1:"optional"`
+	Origin           int             `asn1:"explicit,tag:702"`
+	RootOfTrust      testRootOfTrust `asn1:"optional,explicit,tag:704"`
+	OSVersion        int             `asn1:"optional,explicit,tag:705"`
+	OSPatchLevel     int             `asn1:"optional,explicit,tag:706"`
+	UnknownTag       int             `asn1:"optional,explicit,tag:710"`
+	VendorPatchLevel int             `asn1:"optional,explicit,tag:718"`
+	BootPatchLevel   int             `asn1:"optional,explicit,tag:719"`
+}
+
+type testSoftwareEnforced struct {
+	CreationDateTime         int64  `asn1:"optional,explicit,tag:701"`
+	AttestationApplicationID []byte `asn1:"optional,explicit,tag:709"`
+}
+
+type testKeyDescription struct {
+	AttestationVersion       int
+	AttestationSecurityLevel asn1.Enumerated
+	KeyMintVersion           int
+	KeyMintSecurityLevel     asn1.Enumerated
+	AttestationChallenge     []byte
+	UniqueID                 []byte
+	SoftwareEnforced         testSoftwareEnforced
+	HardwareEnforced         testHardwareEnforced
+}
+
+func TestParseAndroidKeyDescription(t *testing.T) {
+	created := time.Date(2023, 4, 1, 10, 30, 0, 0, time.UTC)
+	der, err := asn1.Marshal(testKeyDescription{
+		AttestationVersion:       200,
+		AttestationSecurityLevel: 2,
+		KeyMintVersion:           200,
+		KeyMintSecurityLevel:     2,
+		AttestationChallenge:     []byte("challenge"),
+		UniqueID:                 []byte{},
+		SoftwareEnforced: testSoftwareEnforced{
+			CreationDateTime:         created.UnixMilli(),
+			AttestationApplicationID: []byte("app"),
+		},
+		HardwareEnforced: testHardwareEnforced{
+			Purpose:   []int{2, 3},
+			Algorithm: 3,
+			KeySize:   256,
+			Digest:    []int{4},
+			ECCurve:   1,
+			// encoding/asn1 ignores the explicit tag of a RawValue.
+			NoAuthRequired: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 503, IsCompound: true, Bytes: []byte{0x05, 0x00}},
+			Origin:         0,
+			RootOfTrust: testRootOfTrust{
+				VerifiedBootKey:   make([]byte, 32),
+				DeviceLocked:      true,
+				VerifiedBootState: 0,
+				VerifiedBootHash:  []byte{1, 2, 3},
+			},
+			OSVersion:        130000,
+			OSPatchLevel:     202303,
+			UnknownTag:       1,
+			VendorPatchLevel: 20230301,
+			BootPatchLevel:   20230301,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := certWithExtensions(t, pkix.Extension{Id: oidAndroidKeyAttestation, Value: der})
+
+	kd, err := ParseAndroidKeyDescription(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kd.AttestationVersion != 200 || kd.AttestationSecurityLevel != AndroidSecurityLevelStrongBox ||
+		kd.KeyMintSecurityLevel != AndroidSecurityLevelStrongBox || string(kd.AttestationChallenge) != "challenge" {
+		t.Errorf("unexpected key description %+v", kd)
+	}
+
+	sw := kd.SoftwareEnforced
+	if !sw.CreationDateTime.Equal(created) || string(sw.AttestationApplicationID) != "app" ||
+		!reflect.DeepEqual(sw.Tags, []int{701, 709}) {
+		t.Errorf("unexpected software enforced list %+v", sw)
+	}
+
+	hw := kd.HardwareEnforced
+	want := AndroidAuthorizationList{
+		Tags:           []int{1, 2, 3, 5, 10, 503, 702, 704, 705, 706, 710, 718, 719},
+		Purpose:        []int{2, 3},
+		Algorithm:      3,
+		KeySize:        256,
+		Digest:         []int{4},
+		ECCurve:        1,
+		NoAuthRequired: true,
+		RootOfTrust: &AndroidRootOfTrust{
+			VerifiedBootKey:   make([]byte, 32),
+			DeviceLocked:      true,
+			VerifiedBootState: AndroidVerifiedBootVerified,
+			VerifiedBootHash:  []byte{1, 2, 3},
+		},
+		OSVersion:        130000,
+		OSPatchLevel:     202303,
+		VendorPatchLevel: 20230301,
+		BootPatchLevel:   20230301,
+	}
+	if !reflect.DeepEqual(hw, want) {
+		t.Errorf("HardwareEnforced = %+v, want %+v", hw, want)
+	}
+	if !hw.Has(702) || hw.Has(200) {
+		t.Error("Has reports the wrong tags")
+	}
+
+	if _, err := ParseAndroidKeyDescription(certWithExtensions(t)); err == nil {
+		t.Error("ParseAndroidKeyDescription succeeded without the extension")
+	}
+	truncated := certWithExtensions(t, pkix.Extension{Id: oidAndroidKeyAttestation, Value: der[:len(der)-3]})
+	if _, err := ParseAndroidKeyDescription(truncated); err == nil {
+		t.Error("ParseAndroidKeyDescription succeeded on a truncated extension")
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package attestation parses the key attestation formats of common
+// hardware security modules, so that certificate authorities can make
+// issuance decisions about hardware-backed keys.
+//
+// Supported formats are YubiKey PIV attestation certificates, TPM 2.0
+// attestation structures (TPMS_ATTEST) as produced by TPM2_Certify and
+// TPM2_Quote, and the Android Keystore key attestation extension.
+//
+// The parsers only decode the attestation data. Callers must verify the
+// attestation certificate chain or signature against the manufacturer's
+// roots themselves, for example with x509.Certificate.Verify or
+// TPMAttestation.CheckSignature, before trusting the result.
+package attestation
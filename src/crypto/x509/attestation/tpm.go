@@ -0,0 +1,246 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// tpmGeneratedValue is TPM_GENERATED_VALUE, the magic prefix of all
+// structures signed by a TPM over data it generated.
+const tpmGeneratedValue = 0xff544347
+
+// TPM 2.0 attestation structure types (TPM_ST).
+const (
+	TPMAttestCertify = 0x8017 // TPM_ST_ATTEST_CERTIFY
+	TPMAttestQuote   = 0x8018 // TPM_ST_ATTEST_QUOTE
+)
+
+// TPMAttestation is a TPM 2.0 TPMS_ATTEST structure, as signed by an
+// attestation key.
+type TPMAttestation struct {
+	// Raw is the TPM encoding of the TPMS_ATTEST structure.
+	Raw []byte
+
+	// Type is the type of attestation, such as TPMAttestCertify.
+	Type uint16
+
+	// QualifiedSigner is the qualified name of the signing key.
+	QualifiedSigner []byte
+
+	// ExtraData is the caller-provided qualifying data, usually a nonce.
+	ExtraData []byte
+
+	// Clock, ResetCount, RestartCount and Safe are the TPMS_CLOCK_INFO.
+	Clock        uint64
+	ResetCount   uint32
+	RestartCount uint32
+	Safe         bool
+
+	// FirmwareVersion is the vendor-specific TPM firmware version.
+	FirmwareVersion uint64
+
+	// Attested is the raw TPMU_ATTEST structure. It is parsed into
+	// Certify or Quote depending on Type.
+	Attested []byte
+
+	Certify *TPMCertifyInfo
+	Quote   *TPMQuoteInfo
+}
+
+// TPMCertifyInfo is the TPMS_CERTIFY_INFO produced by TPM2_Certify.
+type TPMCertifyInfo struct {
+	// Name is the name of the certified object. See TPMObjectName.
+	Name []byte
+
+	// QualifiedName is the qualified name of the certified object.
+	QualifiedName []byte
+}
+
+// TPMQuoteInfo is the TPMS_QUOTE_INFO produced by TPM2_Quote.
+type TPMQuoteInfo struct {
+	// PCRSelection are the quoted PCRs.
+	PCRSelection []TPMPCRSelection
+
+	// PCRDigest is the digest of the selected PCRs.
+	PCRDigest []byte
+}
+
+// TPMPCRSelection is a selection of PCRs in one bank.
+type TPMPCRSelection struct {
+	// Hash is the TPM_ALG_ID of the PCR bank, such as 0x000B for SHA-256.
+	Hash uint16
+
+	// PCRs are the selected PCR indexes, in increasing order.
+	PCRs []int
+}
+
+// ParseTPMAttestation parses a TPMS_ATTEST structure, as returned in the
+// TPM2B_ATTEST of TPM2_Certify or TPM2_Quote, without the size prefix.
+func ParseTPMAttestation(b []byte) (*TPMAttestation, error) {
+	errMalformed := errors.New("attestation: malformed TPMS_ATTEST")
+	s := cryptobyte.String(b)
+	out := &TPMAttestation{Raw: b}
+	var magic uint32
+	var qualifiedSigner, extraData cryptobyte.String
+	var safe uint8
+	if !s.ReadUint32(&magic) ||
+		!s.ReadUint16(&out.Type) ||
+		!s.ReadUint16LengthPrefixed(&qualifiedSigner) ||
+		!s.ReadUint16LengthPrefixed(&extraData) ||
+		!s.ReadUint64(&out.Clock) ||
+		!s.ReadUint32(&out.ResetCount) ||
+		!s.ReadUint32(&out.RestartCount) ||
+		!s.ReadUint8(&safe) ||
+		!s.ReadUint64(&out.FirmwareVersion) {
+		return nil, errMalformed
+	}
+	if magic != tpmGeneratedValue {
+		return nil, errors.New("attestation: TPMS_ATTEST was not generated by a TPM")
+	}
+	if safe > 1 {
+		return nil, errMalformed
+	}
+	out.QualifiedSigner = qualifiedSigner
+	out.ExtraData = extraData
+	out.Safe = safe == 1
+	out.Attested = s
+
+	switch out.Type {
+	case TPMAttestCertify:
+		var name, qualifiedName cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&name) ||
+			!s.ReadUint16LengthPrefixed(&qualifiedName) || !s.Empty() {
+			return nil, errMalformed
+		}
+		out.Certify = &TPMCertifyInfo{Name: name, QualifiedName: qualifiedName}
+	case TPMAttestQuote:
+		quote := &TPMQuoteInfo{}
+		var count uint32
+		if !s.ReadUint32(&count) {
+			return nil, errMalformed
+		}
+		for i := uint32(0); i < count; i++ {
+			var sel TPMPCRSelection
+			var bitmap cryptobyte.String
+			if !s.ReadUint16(&sel.Hash) || !s.ReadUint8LengthPrefixed(&bitmap) {
+				return nil, errMalformed
+			}
+			for j, octet := range bitmap {
+				for k := 0; k < 8; k++ {
+					if octet&(1<<k) != 0 {
+						sel.PCRs = append(sel.PCRs, j*8+k)
+					}
+				}
+			}
+			quote.PCRSelection = append(quote.PCRSelection, sel)
+		}
+		var digest cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&digest) || !s.Empty() {
+			return nil, errMalformed
+		}
+		quote.PCRDigest = digest
+		out.Quote = quote
+	}
+	return out, nil
+}
+
+// TPM_ALG_ID values for hash and signature algorithms.
+const (
+	tpmAlgSHA1   = 0x0004
+	tpmAlgSHA256 = 0x000b
+	tpmAlgSHA384 = 0x000c
+	tpmAlgSHA512 = 0x000d
+	tpmAlgRSASSA = 0x0014
+	tpmAlgRSAPSS = 0x0016
+	tpmAlgECDSA  = 0x0018
+)
+
+func tpmHash(alg uint16) (crypto.Hash, bool) {
+	switch alg {
+	case tpmAlgSHA1:
+		return crypto.SHA1, true
+	case tpmAlgSHA256:
+		return crypto.SHA256, true
+	case tpmAlgSHA384:
+		return crypto.SHA384, true
+	case tpmAlgSHA512:
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// CheckSignature verifies that sig, a TPMT_SIGNATURE, is a valid signature
+// over a by the attestation key pub. RSASSA-PKCS1-v1_5, RSASSA-PSS and ECDSA
+// signatures are supported.
+func (a *TPMAttestation) CheckSignature(sig []byte, pub crypto.PublicKey) error {
+	errMalformed := errors.New("attestation: malformed TPMT_SIGNATURE")
+	s := cryptobyte.String(sig)
+	var sigAlg, hashAlg uint16
+	if !s.ReadUint16(&sigAlg) || !s.ReadUint16(&hashAlg) {
+		return errMalformed
+	}
+	hash, ok := tpmHash(hashAlg)
+	if !ok || !hash.Available() {
+		return errors.New("attestation: unsupported TPM signature hash algorithm")
+	}
+	h := hash.New()
+	h.Write(a.Raw)
+	digest := h.Sum(nil)
+
+	switch sigAlg {
+	case tpmAlgRSASSA, tpmAlgRSAPSS:
+		var signature cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&signature) || !s.Empty() {
+			return errMalformed
+		}
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("attestation: TPM signature algorithm does not match key")
+		}
+		if sigAlg == tpmAlgRSASSA {
+			return rsa.VerifyPKCS1v15(key, hash, digest, signature)
+		}
+		return rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	case tpmAlgECDSA:
+		var r, sBytes cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&r) || !s.ReadUint16LengthPrefixed(&sBytes) || !s.Empty() {
+			return errMalformed
+		}
+		key, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("attestation: TPM signature algorithm does not match key")
+		}
+		if !ecdsa.Verify(key, digest, new(big.Int).SetBytes(r), new(big.Int).SetBytes(sBytes)) {
+			return errors.New("attestation: invalid TPM signature")
+		}
+		return nil
+	}
+	return errors.New("attestation: unsupported TPM signature algorithm")
+}
+
+// TPMObjectName returns the TPM name of the object with the given public
+// area, a TPMT_PUBLIC without size prefix. The name of a certified key must
+// match TPMCertifyInfo.Name.
+func TPMObjectName(public []byte) ([]byte, error) {
+	s := cryptobyte.String(public)
+	var typ, nameAlg uint16
+	if !s.ReadUint16(&typ) || !s.ReadUint16(&nameAlg) {
+		return nil, errors.New("attestation: malformed TPMT_PUBLIC")
+	}
+	hash, ok := tpmHash(nameAlg)
+	if !ok || !hash.Available() {
+		return nil, errors.New("attestation: unsupported TPM name algorithm")
+	}
+	h := hash.New()
+	h.Write(public)
+	return h.Sum([]byte{byte(nameAlg >> 8), byte(nameAlg)}), nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func marshalTPMAttestation(typ uint16, extraData []byte, attested func(*cryptobyte.Builder)) []byte {
+	var b cryptobyte.Builder
+	b.AddUint32(tpmGeneratedValue)
+	b.AddUint16(typ)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("signer")) })
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(extraData) })
+	b.AddUint64(123456)
+	b.AddUint32(7)
+	b.AddUint32(3)
+	b.AddUint8(1)
+	b.AddUint64(0x0102030405060708)
+	attested(&b)
+	return b.BytesOrPanic()
+}
+
+func TestParseTPMCertify(t *testing.T) {
+	// A fake TPMT_PUBLIC for an ECC key with nameAlg SHA-256.
+	public := []byte{0x00, 0x23, 0x00, 0x0b, 0xde, 0xad, 0xbe, 0xef}
+	name, err := TPMObjectName(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(public)
+	if want := append([]byte{0x00, 0x0b}, h[:]...); !bytes.Equal(name, want) {
+		t.Errorf("TPMObjectName = %x, want %x", name, want)
+	}
+
+	raw := marshalTPMAttestation(TPMAttestCertify, []byte("nonce"), func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(name) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("qualified")) })
+	})
+	a, err := ParseTPMAttestation(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Type != TPMAttestCertify || string(a.QualifiedSigner) != "signer" || string(a.ExtraData) != "nonce" ||
+		a.Clock != 123456 || a.ResetCount != 7 || a.RestartCount != 3 || !a.Safe ||
+		a.FirmwareVersion != 0x0102030405060708 {
+		t.Errorf("unexpected attestation %+v", a)
+	}
+	if a.Certify == nil || !bytes.Equal(a.Certify.Name, name) || string(a.Certify.QualifiedName) != "qualified" {
+		t.Errorf("unexpected certify info %+v", a.Certify)
+	}
+	if a.Quote != nil {
+		t.Error("Quote is set for a certify attestation")
+	}
+
+	for i := 0; i < len(raw); i++ {
+		if _, err := ParseTPMAttestation(raw[:i]); err == nil {
+			t.Errorf("ParseTPMAttestation succeeded on input truncated to %d bytes", i)
+		}
+	}
+	bad := bytes.Clone(raw)
+	bad[0] = 0
+	if _, err := ParseTPMAttestation(bad); err == nil {
+		t.Error("ParseTPMAttestation succeeded without TPM_GENERATED_VALUE")
+	}
+}
+
+func TestParseTPMQuote(t *testing.T) {
+	raw := marshalTPMAttestation(TPMAttestQuote, nil, func(b *cryptobyte.Builder) {
+		b.AddUint32(2)
+		b.AddUint16(tpmAlgSHA256)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte{0x81, 0x00, 0x01}) })
+		b.AddUint16(tpmAlgSHA1)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte{0x00, 0x00, 0x00}) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(make([]byte, 32)) })
+	})
+	a, err := ParseTPMAttestation(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &TPMQuoteInfo{
+		PCRSelection: []TPMPCRSelection{
+			{Hash: tpmAlgSHA256, PCRs: []int{0, 7, 16}},
+			{Hash: tpmAlgSHA1},
+		},
+		PCRDigest: make([]byte, 32),
+	}
+	if !reflect.DeepEqual(a.Quote, want) {
+		t.Errorf("Quote = %+v, want %+v", a.Quote, want)
+	}
+}
+
+func TestTPMCheckSignature(t *testing.T) {
+	raw := marshalTPMAttestation(TPMAttestCertify, []byte("nonce"), func(b *cryptobyte.Builder) {
+		b.AddUint16(0)
+		b.AddUint16(0)
+	})
+	a, err := ParseTPMAttestation(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(raw)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ecSig cryptobyte.Builder
+	ecSig.AddUint16(tpmAlgECDSA)
+	ecSig.AddUint16(tpmAlgSHA256)
+	ecSig.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(r.Bytes()) })
+	ecSig.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.Bytes()) })
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rsaSig cryptobyte.Builder
+	rsaSig.AddUint16(tpmAlgRSASSA)
+	rsaSig.AddUint16(tpmAlgSHA256)
+	rsaSig.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(pkcs1) })
+
+	pss, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pssSig cryptobyte.Builder
+	pssSig.AddUint16(tpmAlgRSAPSS)
+	pssSig.AddUint16(tpmAlgSHA256)
+	pssSig.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(pss) })
+
+	tests := []struct {
+		name string
+		sig  []byte
+		pub  crypto.PublicKey
+	}{
+		{"ECDSA", ecSig.BytesOrPanic(), &ecKey.PublicKey},
+		{"RSASSA", rsaSig.BytesOrPanic(), &rsaKey.PublicKey},
+		{"RSAPSS", pssSig.BytesOrPanic(), &rsaKey.PublicKey},
+	}
+	for _, test := range tests {
+		if err := a.CheckSignature(test.sig, test.pub); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.pub == crypto.PublicKey(&ecKey.PublicKey) {
+			if err := a.CheckSignature(test.sig, &rsaKey.PublicKey); err == nil {
+				t.Errorf("%s: CheckSignature succeeded with the wrong key type", test.name)
+			}
+		} else if err := a.CheckSignature(test.sig, &ecKey.PublicKey); err == nil {
+			t.Errorf("%s: CheckSignature succeeded with the wrong key type", test.name)
+		}
+		tampered := &TPMAttestation{Raw: append(bytes.Clone(raw), 0)}
+		if err := tampered.CheckSignature(test.sig, test.pub); err == nil {
+			t.Errorf("%s: CheckSignature succeeded over different data", test.name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"strconv"
+)
+
+var (
+	oidYubiKeyFirmwareVersion = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 3}
+	oidYubiKeySerialNumber    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 7}
+	oidYubiKeyPolicy          = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 8}
+	oidYubiKeyFormFactor      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 9}
+	oidYubiKeyFIPS            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 10}
+	oidYubiKeyCSPN            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 11}
+)
+
+// YubiKeyPINPolicy is the PIN policy of a YubiKey PIV key.
+type YubiKeyPINPolicy uint8
+
+const (
+	YubiKeyPINPolicyNever  YubiKeyPINPolicy = 1
+	YubiKeyPINPolicyOnce   YubiKeyPINPolicy = 2
+	YubiKeyPINPolicyAlways YubiKeyPINPolicy = 3
+)
+
+// YubiKeyTouchPolicy is the touch policy of a YubiKey PIV key.
+type YubiKeyTouchPolicy uint8
+
+const (
+	YubiKeyTouchPolicyNever  YubiKeyTouchPolicy = 1
+	YubiKeyTouchPolicyAlways YubiKeyTouchPolicy = 2
+	// YubiKeyTouchPolicyCached requires a touch, which is cached for 15
+	// seconds.
+	YubiKeyTouchPolicyCached YubiKeyTouchPolicy = 3
+)
+
+// YubiKeyAttestation is the information about a key held in a YubiKey PIV
+// slot, from the certificate generated by the YubiKey's attest command.
+type YubiKeyAttestation struct {
+	// FirmwareVersion is the major, minor and patch firmware version.
+	FirmwareVersion [3]uint8
+
+	// SerialNumber is the serial number of the device, or zero if it was
+	// not included, as on firmware versions before 4.3.
+	SerialNumber uint32
+
+	// PINPolicy and TouchPolicy are the policies of the key, or zero if
+	// they were not included.
+	PINPolicy   YubiKeyPINPolicy
+	TouchPolicy YubiKeyTouchPolicy
+
+	// FormFactor is the form factor of the device, such as 1 for a USB-A
+	// keychain or 3 for a USB-C keychain, with the FIPS (0x80) and CSPN
+	// (0x40) flags cleared. It is zero if it was not included.
+	FormFactor uint8
+
+	// FIPS and CSPN report whether the device is a FIPS or CSPN certified
+	// model.
+	FIPS bool
+	CSPN bool
+}
+
+// ParseYubiKeyAttestation parses the YubiKey PIV attestation extensions of
+// cert, the certificate returned by the attest command for a key slot.
+func ParseYubiKeyAttestation(cert *x509.Certificate) (*YubiKeyAttestation, error) {
+	var out YubiKeyAttestation
+	var found bool
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidYubiKeyFirmwareVersion):
+			// The firmware version is three raw bytes, not DER.
+			if len(ext.Value) != 3 {
+				return nil, errors.New("attestation: malformed YubiKey firmware version")
+			}
+			copy(out.FirmwareVersion[:], ext.Value)
+			found = true
+		case ext.Id.Equal(oidYubiKeySerialNumber):
+			var serial int64
+			if rest, err := asn1.Unmarshal(ext.Value, &serial); err != nil || len(rest) != 0 ||
+				serial < 0 || serial > 1<<32-1 {
+				return nil, errors.New("attestation: malformed YubiKey serial number")
+			}
+			out.SerialNumber = uint32(serial)
+		case ext.Id.Equal(oidYubiKeyPolicy):
+			if len(ext.Value) != 2 {
+				return nil, errors.New("attestation: malformed YubiKey PIN and touch policy")
+			}
+			out.PINPolicy = YubiKeyPINPolicy(ext.Value[0])
+			out.TouchPolicy = YubiKeyTouchPolicy(ext.Value[1])
+		case ext.Id.Equal(oidYubiKeyFormFactor):
+			if len(ext.Value) != 1 {
+				return nil, errors.New("attestation: malformed YubiKey form factor")
+			}
+			out.FormFactor = ext.Value[0] &^ 0xc0
+			out.FIPS = out.FIPS || ext.Value[0]&0x80 != 0
+			out.CSPN = out.CSPN || ext.Value[0]&0x40 != 0
+		case ext.Id.Equal(oidYubiKeyFIPS):
+			out.FIPS = true
+		case ext.Id.Equal(oidYubiKeyCSPN):
+			out.CSPN = true
+		}
+	}
+	if !found {
+		return nil, errors.New("attestation: certificate is not a YubiKey PIV attestation")
+	}
+	return &out, nil
+}
+
+func (p YubiKeyPINPolicy) String() string {
+	switch p {
+	case YubiKeyPINPolicyNever:
+		return "never"
+	case YubiKeyPINPolicyOnce:
+		return "once"
+	case YubiKeyPINPolicyAlways:
+		return "always"
+	}
+	return "YubiKeyPINPolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+func (p YubiKeyTouchPolicy) String() string {
+	switch p {
+	case YubiKeyTouchPolicyNever:
+		return "never"
+	case YubiKeyTouchPolicyAlways:
+		return "always"
+	case YubiKeyTouchPolicyCached:
+		return "cached"
+	}
+	return "YubiKeyTouchPolicy(" + strconv.Itoa(int(p)) + ")"
+}
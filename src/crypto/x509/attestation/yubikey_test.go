@@ -0,0 +1,81 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// certWithExtensions returns a self-signed certificate with the given
+// extensions.
+func certWithExtensions(t *testing.T, exts ...pkix.Extension) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "attestation test"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: exts,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestParseYubiKeyAttestation(t *testing.T) {
+	cert := certWithExtensions(t,
+		pkix.Extension{Id: oidYubiKeyFirmwareVersion, Value: []byte{5, 4, 3}},
+		pkix.Extension{Id: oidYubiKeySerialNumber, Value: []byte{0x02, 0x04, 0x00, 0xbc, 0x61, 0x4e}},
+		pkix.Extension{Id: oidYubiKeyPolicy, Value: []byte{2, 3}},
+		pkix.Extension{Id: oidYubiKeyFormFactor, Value: []byte{0x83}},
+	)
+	got, err := ParseYubiKeyAttestation(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := YubiKeyAttestation{
+		FirmwareVersion: [3]uint8{5, 4, 3},
+		SerialNumber:    12345678,
+		PINPolicy:       YubiKeyPINPolicyOnce,
+		TouchPolicy:     YubiKeyTouchPolicyCached,
+		FormFactor:      3,
+		FIPS:            true,
+	}
+	if *got != want {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+	if s := got.PINPolicy.String(); s != "once" {
+		t.Errorf("PINPolicy.String() = %q, want %q", s, "once")
+	}
+
+	if _, err := ParseYubiKeyAttestation(certWithExtensions(t)); err == nil {
+		t.Error("ParseYubiKeyAttestation succeeded on a certificate without YubiKey extensions")
+	}
+
+	malformed := certWithExtensions(t,
+		pkix.Extension{Id: oidYubiKeyFirmwareVersion, Value: []byte{5, 4, 3}},
+		pkix.Extension{Id: oidYubiKeyPolicy, Value: []byte{2}},
+	)
+	if _, err := ParseYubiKeyAttestation(malformed); err == nil {
+		t.Error("ParseYubiKeyAttestation succeeded with a malformed policy")
+	}
+}
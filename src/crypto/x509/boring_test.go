@@ -140,3 +140,27 @@ func testBoringCert(t *testing.T, name string, key interface{}, parent *boringCe
 	}
 	return &boringCertificate{name, org, parentOrg, der, cert, key, fipsOK}
 }
+
+func TestBoringAnchorPolicy(t *testing.T) {
+	R1 := testBoringCert(t, "R1", boringRSAKey(t, 2048), nil, boringCertCA|boringCertFIPSOK)
+	R2 := testBoringCert(t, "R2", boringECDSAKey(t, elliptic.P224()), nil, boringCertCA)
+
+	pool := NewCertPool()
+	pool.AddCert(R1.cert)
+	pool.AddCert(R2.cert)
+
+	fipstls.Force()
+	defer fipstls.Abandon()
+
+	// The zero policy still drops anchors that FIPS-only mode forbids.
+	filteredPool, filtered, err := new(AnchorPolicy).Filter(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filteredPool.len() != 1 || !filteredPool.contains(R1.cert) {
+		t.Errorf("unexpected filtered pool with %d certificates", filteredPool.len())
+	}
+	if len(filtered) != 1 || filtered[0].Cert != R2.cert {
+		t.Errorf("unexpected filtered certificates: %v", filtered)
+	}
+}
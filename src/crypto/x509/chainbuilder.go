@@ -0,0 +1,149 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"bytes"
+	"sort"
+)
+
+// ChainBuilder holds optional hooks into the path builder used by Verify and
+// VerifyChains, for environments such as bridge PKIs where the default
+// exploration of Roots and Intermediates is not sufficient.
+//
+// All fields are optional. The hooks are called synchronously while a chain
+// is being built, and must not modify the certificates passed to them.
+type ChainBuilder struct {
+	// FilterCandidate, if not nil, is called for each potential issuer of
+	// cert before its signature is checked. If it returns false, the
+	// candidate is not used to build chains for cert. isRoot reports
+	// whether candidate comes from VerifyOptions.Roots.
+	FilterCandidate func(cert, candidate *Certificate, isRoot bool) bool
+
+	// PreferCandidate, if not nil, orders the potential issuers of cert:
+	// it reports whether candidate a should be explored before candidate
+	// b. It can be used, for example, to prefer one cross-signed version
+	// of an intermediate over another. Roots are always explored before
+	// intermediates.
+	PreferCandidate func(cert, a, b *Certificate) bool
+
+	// FetchIssuers, if not nil, is called when no chain can be built from
+	// cert using Roots and Intermediates, to obtain additional candidate
+	// intermediates, for example by following cert.IssuingCertificateURL.
+	// The returned certificates must have been produced by ParseCertificate.
+	//
+	// FetchIssuers is called at most once per certificate, and at most ten
+	// times per verification.
+	FetchIssuers func(cert *Certificate) ([]*Certificate, error)
+
+	// Score, if not nil, computes the score of a verified chain for
+	// VerifyChains. Chains with higher scores are returned first. If nil,
+	// shorter chains score higher.
+	Score func(chain []*Certificate) int
+}
+
+// candidates returns the potential issuers of cert, filtered and ordered
+// according to cb, which may be nil.
+func (cb *ChainBuilder) candidates(cert *Certificate, candidates []*Certificate, isRoot bool) []*Certificate {
+	if cb == nil || (cb.FilterCandidate == nil && cb.PreferCandidate == nil) {
+		return candidates
+	}
+	if cb.FilterCandidate != nil {
+		filtered := make([]*Certificate, 0, len(candidates))
+		for _, candidate := range candidates {
+			if cb.FilterCandidate(cert, candidate, isRoot) {
+				filtered = append(filtered, candidate)
+			}
+		}
+		candidates = filtered
+	}
+	if cb.PreferCandidate != nil {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return cb.PreferCandidate(cert, candidates[i], candidates[j])
+		})
+	}
+	return candidates
+}
+
+// fetchIssuers calls cb.FetchIssuers for cert, at most once per certificate
+// and at most maxChainIssuerFetches times per verification. Only parsed
+// certificates whose subject matches the issuer of cert are returned.
+func (cb *ChainBuilder) fetchIssuers(cert *Certificate, state *chainBuildState) ([]*Certificate, error) {
+	if fetched, ok := state.fetched[cert]; ok {
+		return fetched, nil
+	}
+	if state.fetches >= maxChainIssuerFetches {
+		return nil, nil
+	}
+	state.fetches++
+	if state.fetched == nil {
+		state.fetched = make(map[*Certificate][]*Certificate)
+		state.fetchedCerts = make(map[*Certificate]bool)
+	}
+
+	certs, err := cb.FetchIssuers(cert)
+	var fetched []*Certificate
+	for _, candidate := range certs {
+		if candidate == nil || len(candidate.Raw) == 0 {
+			continue
+		}
+		if !bytes.Equal(candidate.RawSubject, cert.RawIssuer) {
+			continue
+		}
+		fetched = append(fetched, candidate)
+		state.fetchedCerts[candidate] = true
+	}
+	state.fetched[cert] = fetched
+	return fetched, err
+}
+
+// VerifiedChain is a chain returned by VerifyChains, with metadata about how
+// it was built.
+type VerifiedChain struct {
+	// Chain is the verified chain, starting with the leaf and ending with a
+	// certificate from VerifyOptions.Roots.
+	Chain []*Certificate
+
+	// Score is the score of the chain, computed by ChainBuilder.Score if
+	// set, or as the negated length of the chain otherwise.
+	Score int
+
+	// Fetched reports whether the chain includes certificates obtained
+	// from ChainBuilder.FetchIssuers.
+	Fetched bool
+}
+
+// VerifyChains is like Verify, but returns every valid chain along with
+// scoring metadata, ordered from the highest to the lowest score. Chains
+// with equal scores are returned in the order Verify would return them.
+//
+// When verification is delegated to the platform verifier, Fetched is
+// always false.
+func (c *Certificate) VerifyChains(opts VerifyOptions) ([]VerifiedChain, error) {
+	state := new(chainBuildState)
+	chains, err := c.verify(opts, state)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := make([]VerifiedChain, len(chains))
+	for i, chain := range chains {
+		v := VerifiedChain{Chain: chain, Score: -len(chain)}
+		if cb := opts.ChainBuilder; cb != nil && cb.Score != nil {
+			v.Score = cb.Score(chain)
+		}
+		for _, cert := range chain {
+			if state.fetchedCerts[cert] {
+				v.Fetched = true
+				break
+			}
+		}
+		verified[i] = v
+	}
+	sort.SliceStable(verified, func(i, j int) bool {
+		return verified[i].Score > verified[j].Score
+	})
+	return verified, nil
+}
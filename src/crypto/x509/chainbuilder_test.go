@@ -0,0 +1,138 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestChainBuilder(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %s", err)
+	}
+	rootA := genCertEdge(t, "root a", k, nil, rootCertificate, nil, k)
+	rootB := genCertEdge(t, "root b", k, nil, rootCertificate, nil, k)
+	// The same intermediate, cross-signed by both roots.
+	interA := genCertEdge(t, "inter", k, nil, intermediateCertificate, rootA, k)
+	interB := genCertEdge(t, "inter", k, nil, intermediateCertificate, rootB, k)
+	leaf := genCertEdge(t, "leaf", k, nil, leafCertificate, interA, k)
+
+	roots := NewCertPool()
+	roots.AddCert(rootA)
+	roots.AddCert(rootB)
+	intermediates := NewCertPool()
+	intermediates.AddCert(interA)
+	intermediates.AddCert(interB)
+
+	t.Run("all chains", func(t *testing.T) {
+		chains, err := leaf.VerifyChains(VerifyOptions{Roots: roots, Intermediates: intermediates})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chains) != 2 {
+			t.Fatalf("got %d chains, want 2", len(chains))
+		}
+		for _, c := range chains {
+			if c.Score != -3 || c.Fetched {
+				t.Errorf("unexpected metadata: score %d, fetched %v", c.Score, c.Fetched)
+			}
+		}
+	})
+
+	t.Run("score", func(t *testing.T) {
+		opts := VerifyOptions{Roots: roots, Intermediates: intermediates, ChainBuilder: &ChainBuilder{
+			Score: func(chain []*Certificate) int {
+				if chain[len(chain)-1] == rootB {
+					return 10
+				}
+				return 0
+			},
+		}}
+		chains, err := leaf.VerifyChains(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chains) != 2 || chains[0].Chain[2] != rootB || chains[0].Score != 10 || chains[1].Score != 0 {
+			t.Errorf("chains not ordered by score: %v", chains)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		var calls int
+		opts := VerifyOptions{Roots: roots, Intermediates: intermediates, ChainBuilder: &ChainBuilder{
+			FilterCandidate: func(cert, candidate *Certificate, isRoot bool) bool {
+				calls++
+				return candidate != interA
+			},
+		}}
+		chains, err := leaf.Verify(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chains) != 1 || chains[0][1] != interB || chains[0][2] != rootB {
+			t.Errorf("unexpected chains: %v", chainsToStrings(chains))
+		}
+		if calls == 0 {
+			t.Errorf("FilterCandidate was not called")
+		}
+	})
+
+	t.Run("prefer", func(t *testing.T) {
+		opts := VerifyOptions{Roots: roots, Intermediates: intermediates, ChainBuilder: &ChainBuilder{
+			PreferCandidate: func(cert, a, b *Certificate) bool {
+				return a == interB && b != interB
+			},
+		}}
+		chains, err := leaf.Verify(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chains) != 2 || chains[0][1] != interB {
+			t.Errorf("preferred candidate not explored first: %v", chainsToStrings(chains))
+		}
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		var fetches int
+		opts := VerifyOptions{Roots: roots, ChainBuilder: &ChainBuilder{
+			FetchIssuers: func(cert *Certificate) ([]*Certificate, error) {
+				fetches++
+				return []*Certificate{interA, rootB}, nil
+			},
+		}}
+		chains, err := leaf.VerifyChains(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chains) != 1 || chains[0].Chain[1] != interA || !chains[0].Fetched {
+			t.Errorf("unexpected chains: %v", chains)
+		}
+		if fetches != 1 {
+			t.Errorf("FetchIssuers called %d times, want 1", fetches)
+		}
+	})
+
+	t.Run("fetch error", func(t *testing.T) {
+		fetchErr := errors.New("fetch failed")
+		opts := VerifyOptions{Roots: roots, ChainBuilder: &ChainBuilder{
+			FetchIssuers: func(cert *Certificate) ([]*Certificate, error) {
+				return nil, fetchErr
+			},
+		}}
+		_, err := leaf.Verify(opts)
+		var uae UnknownAuthorityError
+		if !errors.As(err, &uae) || uae.hintErr != fetchErr {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if want := `x509: certificate signed by unknown authority (possibly because of "fetch failed")`; err.Error() != want {
+			t.Errorf("unexpected error string: got %q, want %q", err, want)
+		}
+	})
+}
@@ -0,0 +1,331 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ct implements parsing and verification of Certificate
+// Transparency Signed Certificate Timestamps (SCTs), as defined in RFC 6962.
+//
+// SCTs can be delivered embedded in a certificate, in a TLS extension, or in
+// a stapled OCSP response. ConnectionStateSCTs collects all of them from a
+// crypto/tls connection, and VerifyConnectionState checks them against a
+// LogList and a Policy, for example from a tls.Config.VerifyConnection
+// callback.
+package ct
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var (
+	oidExtensionSCTList     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidExtensionOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// Source indicates how an SCT was delivered.
+type Source int
+
+const (
+	// SourceUnknown is the Source of SCTs returned by ParseSCT and
+	// ParseSCTList.
+	SourceUnknown Source = iota
+	// SourceEmbedded is an SCT embedded in the certificate, signed over
+	// the precertificate.
+	SourceEmbedded
+	// SourceTLSExtension is an SCT delivered in the
+	// signed_certificate_timestamp TLS extension.
+	SourceTLSExtension
+	// SourceOCSP is an SCT delivered in a stapled OCSP response.
+	SourceOCSP
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceUnknown:
+		return "unknown"
+	case SourceEmbedded:
+		return "embedded"
+	case SourceTLSExtension:
+		return "TLS extension"
+	case SourceOCSP:
+		return "OCSP"
+	}
+	return "Source(" + strconv.Itoa(int(s)) + ")"
+}
+
+// SignedCertificateTimestamp is a promise by a log to incorporate a
+// certificate in its Merkle tree.
+type SignedCertificateTimestamp struct {
+	// Raw is the TLS encoding of the SCT.
+	Raw []byte
+
+	// LogID is the SHA-256 hash of the log's public key.
+	LogID [32]byte
+
+	// Timestamp is the time at which the SCT was issued, with millisecond
+	// precision.
+	Timestamp time.Time
+
+	// Extensions are the opaque CT extensions. No extensions are
+	// currently defined.
+	Extensions []byte
+
+	// SignatureAlgorithm is either x509.ECDSAWithSHA256 or
+	// x509.SHA256WithRSA.
+	SignatureAlgorithm x509.SignatureAlgorithm
+
+	// Signature is the log's signature.
+	Signature []byte
+
+	// Source is how the SCT was delivered, which determines what it is
+	// signed over.
+	Source Source
+}
+
+// ParseSCT parses a single TLS encoded SignedCertificateTimestamp. Only
+// version 1 SCTs are supported.
+func ParseSCT(b []byte) (*SignedCertificateTimestamp, error) {
+	s := cryptobyte.String(b)
+	var version, hashAlg, sigAlg uint8
+	var timestamp uint64
+	sct := &SignedCertificateTimestamp{Raw: b}
+	var logID, extensions, signature cryptobyte.String
+	if !s.ReadUint8(&version) {
+		return nil, errors.New("ct: malformed SCT")
+	}
+	if version != 0 {
+		return nil, errors.New("ct: unsupported SCT version " + strconv.Itoa(int(version)+1))
+	}
+	if !s.ReadBytes((*[]byte)(&logID), 32) ||
+		!s.ReadUint64(&timestamp) ||
+		!s.ReadUint16LengthPrefixed(&extensions) ||
+		!s.ReadUint8(&hashAlg) || !s.ReadUint8(&sigAlg) ||
+		!s.ReadUint16LengthPrefixed(&signature) ||
+		!s.Empty() || timestamp > 1<<63-1 {
+		return nil, errors.New("ct: malformed SCT")
+	}
+	copy(sct.LogID[:], logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp)).UTC()
+	sct.Extensions = extensions
+	sct.Signature = signature
+
+	// RFC 5246, Section 7.4.1.4.1: HashAlgorithm sha256(4), and
+	// SignatureAlgorithm rsa(1) or ecdsa(3).
+	switch {
+	case hashAlg == 4 && sigAlg == 1:
+		sct.SignatureAlgorithm = x509.SHA256WithRSA
+	case hashAlg == 4 && sigAlg == 3:
+		sct.SignatureAlgorithm = x509.ECDSAWithSHA256
+	default:
+		return nil, errors.New("ct: unsupported SCT signature algorithm")
+	}
+	return sct, nil
+}
+
+// ParseSCTList parses a TLS encoded SignedCertificateTimestampList, as
+// carried in the signed_certificate_timestamp TLS extension and, wrapped in
+// an OCTET STRING, in the certificate and OCSP extensions.
+func ParseSCTList(b []byte) ([]*SignedCertificateTimestamp, error) {
+	return parseSCTList(b, SourceUnknown)
+}
+
+func parseSCTList(b []byte, source Source) ([]*SignedCertificateTimestamp, error) {
+	s := cryptobyte.String(b)
+	var list cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&list) || !s.Empty() || list.Empty() {
+		return nil, errors.New("ct: malformed SCT list")
+	}
+	var scts []*SignedCertificateTimestamp
+	for !list.Empty() {
+		var raw cryptobyte.String
+		if !list.ReadUint16LengthPrefixed(&raw) {
+			return nil, errors.New("ct: malformed SCT list")
+		}
+		sct, err := ParseSCT(raw)
+		if err != nil {
+			return nil, err
+		}
+		sct.Source = source
+		scts = append(scts, sct)
+	}
+	return scts, nil
+}
+
+// parseSCTListExtension parses the value of a certificate or OCSP SCT list
+// extension, which is an OCTET STRING holding a SignedCertificateTimestampList.
+func parseSCTListExtension(value []byte, source Source) ([]*SignedCertificateTimestamp, error) {
+	s := cryptobyte.String(value)
+	var list cryptobyte.String
+	if !s.ReadASN1(&list, cryptobyte_asn1.OCTET_STRING) || !s.Empty() {
+		return nil, errors.New("ct: malformed SCT list extension")
+	}
+	return parseSCTList(list, source)
+}
+
+// EmbeddedSCTs returns the SCTs embedded in cert, or nil if there are none.
+func EmbeddedSCTs(cert *x509.Certificate) ([]*SignedCertificateTimestamp, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtensionSCTList) {
+			return parseSCTListExtension(ext.Value, SourceEmbedded)
+		}
+	}
+	return nil, nil
+}
+
+// signedInput returns the input to the log's signature over sct, for the
+// certificate chain[0], issued by chain[1] if present.
+func signedInput(sct *SignedCertificateTimestamp, chain []*x509.Certificate) ([]byte, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("ct: empty certificate chain")
+	}
+	leaf := chain[0]
+
+	// digitally-signed struct {
+	//     Version sct_version;
+	//     SignatureType signature_type = certificate_timestamp;
+	//     uint64 timestamp;
+	//     LogEntryType entry_type;
+	//     select(entry_type) {
+	//         case x509_entry: ASN.1Cert;
+	//         case precert_entry: PreCert;
+	//     } signed_entry;
+	//     CtExtensions extensions;
+	// };
+	var b cryptobyte.Builder
+	b.AddUint8(0) // v1
+	b.AddUint8(0) // certificate_timestamp
+	b.AddUint64(uint64(sct.Timestamp.UnixMilli()))
+	switch sct.Source {
+	case SourceEmbedded:
+		if len(chain) < 2 {
+			return nil, errors.New("ct: issuer needed to verify embedded SCT")
+		}
+		tbs, err := removeSCTListExtension(leaf.RawTBSCertificate)
+		if err != nil {
+			return nil, err
+		}
+		issuerKeyHash := sha256.Sum256(chain[1].RawSubjectPublicKeyInfo)
+		b.AddUint16(1) // precert_entry
+		b.AddBytes(issuerKeyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(tbs)
+		})
+	case SourceTLSExtension, SourceOCSP:
+		b.AddUint16(0) // x509_entry
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(leaf.Raw)
+		})
+	default:
+		return nil, errors.New("ct: SCT source is unknown")
+	}
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(sct.Extensions)
+	})
+	return b.Bytes()
+}
+
+// removeSCTListExtension returns tbs with the SCT list extension removed,
+// which is the TBSCertificate of the precertificate the log signed, once
+// the poison extension is removed (RFC 6962, Section 3.2).
+func removeSCTListExtension(tbs []byte) ([]byte, error) {
+	errMalformed := errors.New("ct: malformed TBSCertificate")
+	input := cryptobyte.String(tbs)
+	var fields cryptobyte.String
+	if !input.ReadASN1(&fields, cryptobyte_asn1.SEQUENCE) || !input.Empty() {
+		return nil, errMalformed
+	}
+
+	extensionsTag := cryptobyte_asn1.Tag(3).Constructed().ContextSpecific()
+	var b cryptobyte.Builder
+	var found bool
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !fields.Empty() {
+			var field cryptobyte.String
+			var tag cryptobyte_asn1.Tag
+			if !fields.ReadAnyASN1Element(&field, &tag) {
+				b.SetError(errMalformed)
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(field)
+				continue
+			}
+			var exts cryptobyte.String
+			if !field.ReadASN1(&field, extensionsTag) ||
+				!field.ReadASN1(&exts, cryptobyte_asn1.SEQUENCE) {
+				b.SetError(errMalformed)
+				return
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for !exts.Empty() {
+						var ext, extBody cryptobyte.String
+						var oid asn1.ObjectIdentifier
+						if !exts.ReadASN1Element(&ext, cryptobyte_asn1.SEQUENCE) {
+							b.SetError(errMalformed)
+							return
+						}
+						extBody = ext
+						if !extBody.ReadASN1(&extBody, cryptobyte_asn1.SEQUENCE) ||
+							!extBody.ReadASN1ObjectIdentifier(&oid) {
+							b.SetError(errMalformed)
+							return
+						}
+						if oid.Equal(oidExtensionSCTList) {
+							found = true
+							continue
+						}
+						b.AddBytes(ext)
+					}
+				})
+			})
+		}
+	})
+	out, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("ct: certificate has no embedded SCTs")
+	}
+	return out, nil
+}
+
+// verifySignature checks sct's signature over the certificate chain[0]
+// with the log's public key.
+func verifySignature(sct *SignedCertificateTimestamp, chain []*x509.Certificate, key any) error {
+	input, err := signedInput(sct, chain)
+	if err != nil {
+		return err
+	}
+	h := sha256.Sum256(input)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if sct.SignatureAlgorithm != x509.ECDSAWithSHA256 {
+			return errors.New("ct: SCT signature algorithm does not match log key")
+		}
+		if !ecdsa.VerifyASN1(key, h[:], sct.Signature) {
+			return errors.New("ct: invalid SCT signature")
+		}
+	case *rsa.PublicKey:
+		if sct.SignatureAlgorithm != x509.SHA256WithRSA {
+			return errors.New("ct: SCT signature algorithm does not match log key")
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], sct.Signature); err != nil {
+			return errors.New("ct: invalid SCT signature")
+		}
+	default:
+		return errors.New("ct: unsupported log key type")
+	}
+	return nil
+}
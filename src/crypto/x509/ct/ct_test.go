@@ -0,0 +1,478 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ct
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+type testLog struct {
+	*Log
+	key *ecdsa.PrivateKey
+}
+
+func newTestLog(t *testing.T, operator string) *testLog {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := NewLog(operator+" log", spki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Operator = operator
+	return &testLog{log, key}
+}
+
+// sign returns the TLS encoding of an SCT over the given entry, which is
+// either a certificate (entryType 0) or a precertificate TBSCertificate
+// (entryType 1), in which case issuer is used for the issuer key hash.
+func (l *testLog) sign(t *testing.T, timestamp time.Time, entryType uint16, entry []byte, issuer *x509.Certificate) []byte {
+	t.Helper()
+	var input cryptobyte.Builder
+	input.AddUint8(0)
+	input.AddUint8(0)
+	input.AddUint64(uint64(timestamp.UnixMilli()))
+	input.AddUint16(entryType)
+	if entryType == 1 {
+		h := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		input.AddBytes(h[:])
+	}
+	input.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(entry) })
+	input.AddUint16(0)
+	h := sha256.Sum256(input.BytesOrPanic())
+	sig, err := ecdsa.SignASN1(rand.Reader, l.key, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b cryptobyte.Builder
+	b.AddUint8(0)
+	b.AddBytes(l.ID[:])
+	b.AddUint64(uint64(timestamp.UnixMilli()))
+	b.AddUint16(0)
+	b.AddUint8(4)
+	b.AddUint8(3)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sig) })
+	return b.BytesOrPanic()
+}
+
+func marshalSCTList(scts ...[]byte) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct) })
+		}
+	})
+	return b.BytesOrPanic()
+}
+
+func marshalSCTListExtension(scts ...[]byte) []byte {
+	var b cryptobyte.Builder
+	b.AddASN1OctetString(marshalSCTList(scts...))
+	return b.BytesOrPanic()
+}
+
+type testPKI struct {
+	issuer    *x509.Certificate
+	issuerKey *ecdsa.PrivateKey
+	template  *x509.Certificate
+	leafKey   *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	issuerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CT Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &issuerKey.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(90 * 24 * time.Hour),
+	}
+	return &testPKI{issuer, issuerKey, template, leafKey}
+}
+
+// leaf issues the test leaf, with the given extra extensions.
+func (p *testPKI) leaf(t *testing.T, extra ...pkix.Extension) *x509.Certificate {
+	t.Helper()
+	template := *p.template
+	template.ExtraExtensions = extra
+	der, err := x509.CreateCertificate(rand.Reader, &template, p.issuer, &p.leafKey.PublicKey, p.issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// embeddedLeaf issues the test leaf with SCTs from logs embedded.
+func (p *testPKI) embeddedLeaf(t *testing.T, timestamp time.Time, logs ...*testLog) *x509.Certificate {
+	t.Helper()
+	precert := p.leaf(t)
+	var scts [][]byte
+	for _, l := range logs {
+		scts = append(scts, l.sign(t, timestamp, 1, precert.RawTBSCertificate, p.issuer))
+	}
+	return p.leaf(t, pkix.Extension{Id: oidExtensionSCTList, Value: marshalSCTListExtension(scts...)})
+}
+
+func TestEmbeddedSCTs(t *testing.T) {
+	pki := newTestPKI(t)
+	log1, log2 := newTestLog(t, "A"), newTestLog(t, "B")
+	timestamp := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	leaf := pki.embeddedLeaf(t, timestamp, log1, log2)
+	chain := []*x509.Certificate{leaf, pki.issuer}
+
+	scts, err := EmbeddedSCTs(leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scts) != 2 {
+		t.Fatalf("got %d SCTs, want 2", len(scts))
+	}
+	for i, l := range []*testLog{log1, log2} {
+		sct := scts[i]
+		if sct.Source != SourceEmbedded {
+			t.Errorf("SCT %d: Source = %v, want %v", i, sct.Source, SourceEmbedded)
+		}
+		if !sct.Timestamp.Equal(timestamp) {
+			t.Errorf("SCT %d: Timestamp = %v, want %v", i, sct.Timestamp, timestamp)
+		}
+		if sct.SignatureAlgorithm != x509.ECDSAWithSHA256 {
+			t.Errorf("SCT %d: SignatureAlgorithm = %v", i, sct.SignatureAlgorithm)
+		}
+		if err := l.Verify(sct, chain); err != nil {
+			t.Errorf("SCT %d: Verify: %v", i, err)
+		}
+		if err := l.Verify(sct, chain[:1]); err == nil {
+			t.Errorf("SCT %d: Verify succeeded without the issuer", i)
+		}
+	}
+	if err := log1.Verify(scts[1], chain); err == nil {
+		t.Error("Verify succeeded with the wrong log")
+	}
+
+	// An SCT signed over a different precertificate must not verify.
+	other := *pki.template
+	pki.template.SerialNumber = big.NewInt(3)
+	otherLeaf := pki.embeddedLeaf(t, timestamp, log1)
+	pki.template = &other
+	otherSCTs, err := EmbeddedSCTs(otherLeaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log1.Verify(otherSCTs[0], chain); err == nil {
+		t.Error("Verify succeeded with an SCT for another certificate")
+	}
+
+	if scts, err := EmbeddedSCTs(pki.issuer); scts != nil || err != nil {
+		t.Errorf("EmbeddedSCTs(issuer) = %v, %v, want nil, nil", scts, err)
+	}
+}
+
+func TestTLSExtensionSCT(t *testing.T) {
+	pki := newTestPKI(t)
+	log := newTestLog(t, "A")
+	leaf := pki.leaf(t)
+	timestamp := time.Now().Truncate(time.Millisecond)
+
+	raw := log.sign(t, timestamp, 0, leaf.Raw, nil)
+	scts, err := ParseSCTList(marshalSCTList(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sct := scts[0]
+	if sct.Source != SourceUnknown {
+		t.Errorf("Source = %v, want %v", sct.Source, SourceUnknown)
+	}
+	if err := log.Verify(sct, []*x509.Certificate{leaf}); err == nil {
+		t.Error("Verify succeeded with an unknown Source")
+	}
+	sct.Source = SourceTLSExtension
+	if err := log.Verify(sct, []*x509.Certificate{leaf}); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	sct.Signature[len(sct.Signature)-1] ^= 1
+	if err := log.Verify(sct, []*x509.Certificate{leaf}); err == nil {
+		t.Error("Verify succeeded with a corrupted signature")
+	}
+}
+
+// marshalOCSPResponse returns a minimal, unsigned OCSP response for cert
+// with the given SCTs.
+func marshalOCSPResponse(cert *x509.Certificate, scts ...[]byte) []byte {
+	var basic cryptobyte.Builder
+	basic.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.Tag(2).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+				b.AddASN1OctetString(make([]byte, 20))
+			})
+			b.AddASN1GeneralizedTime(time.Now())
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+							b.AddASN1ObjectIdentifier(asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26})
+						})
+						b.AddASN1OctetString(make([]byte, 20))
+						b.AddASN1OctetString(make([]byte, 20))
+						b.AddASN1BigInt(cert.SerialNumber)
+					})
+					b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific(), func(b *cryptobyte.Builder) {})
+					b.AddASN1GeneralizedTime(time.Now())
+					b.AddASN1(cryptobyte_asn1.Tag(1).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+						b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+							b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+								b.AddASN1ObjectIdentifier(oidExtensionOCSPSCTList)
+								b.AddASN1OctetString(marshalSCTListExtension(scts...))
+							})
+						})
+					})
+				})
+			})
+		})
+		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2})
+		})
+		b.AddASN1BitString(nil)
+	})
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Enum(0)
+		b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1ObjectIdentifier(oidOCSPBasic)
+				b.AddASN1OctetString(basic.BytesOrPanic())
+			})
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func TestOCSPSCTs(t *testing.T) {
+	pki := newTestPKI(t)
+	log := newTestLog(t, "A")
+	leaf := pki.leaf(t)
+	resp := marshalOCSPResponse(leaf, log.sign(t, time.Now(), 0, leaf.Raw, nil))
+
+	scts, err := OCSPSCTs(resp, leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scts) != 1 || scts[0].Source != SourceOCSP {
+		t.Fatalf("got %v, want one OCSP SCT", scts)
+	}
+	if err := log.Verify(scts[0], []*x509.Certificate{leaf}); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	if scts, err := OCSPSCTs(resp, pki.issuer); scts != nil || err != nil {
+		t.Errorf("OCSPSCTs for another certificate = %v, %v, want nil, nil", scts, err)
+	}
+	if _, err := OCSPSCTs(resp[:len(resp)-5], leaf); err == nil {
+		t.Error("OCSPSCTs succeeded on a truncated response")
+	}
+}
+
+func TestLogState(t *testing.T) {
+	pki := newTestPKI(t)
+	log := newTestLog(t, "A")
+	timestamp := time.Now().Add(-time.Hour)
+	leaf := pki.embeddedLeaf(t, timestamp, log)
+	chain := []*x509.Certificate{leaf, pki.issuer}
+	scts, err := EmbeddedSCTs(leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sct := scts[0]
+
+	tests := []struct {
+		state                 LogState
+		stateTime, start, end time.Time
+		ok                    bool
+	}{
+		{state: LogStateUsable, ok: true},
+		{state: LogStateQualified, ok: true},
+		{state: LogStateReadOnly, ok: true},
+		{state: LogStatePending},
+		{state: LogStateRejected},
+		{state: LogStateRetired, stateTime: timestamp.Add(time.Minute), ok: true},
+		{state: LogStateRetired, stateTime: timestamp.Add(-time.Minute)},
+		{state: LogStateUsable, start: leaf.NotAfter, end: leaf.NotAfter.Add(time.Hour), ok: true},
+		{state: LogStateUsable, start: leaf.NotAfter.Add(time.Second), end: leaf.NotAfter.Add(time.Hour)},
+		{state: LogStateUsable, start: leaf.NotAfter.Add(-time.Hour), end: leaf.NotAfter},
+	}
+	for i, test := range tests {
+		log.State, log.StateTime = test.state, test.stateTime
+		log.TemporalIntervalStart, log.TemporalIntervalEnd = test.start, test.end
+		err := log.Verify(sct, chain)
+		if test.ok && err != nil {
+			t.Errorf("#%d (%v): Verify: %v", i, test.state, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("#%d (%v): Verify succeeded", i, test.state)
+		}
+	}
+}
+
+func TestParseSCTMalformed(t *testing.T) {
+	log := newTestLog(t, "A")
+	raw := log.sign(t, time.Now(), 0, []byte("cert"), nil)
+	if _, err := ParseSCT(raw); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(raw); i++ {
+		if _, err := ParseSCT(raw[:i]); err == nil {
+			t.Errorf("ParseSCT succeeded on input truncated to %d bytes", i)
+		}
+	}
+	v2 := append([]byte{1}, raw[1:]...)
+	if _, err := ParseSCT(v2); err == nil {
+		t.Error("ParseSCT succeeded on a v2 SCT")
+	}
+	if _, err := ParseSCTList([]byte{0, 0}); err == nil {
+		t.Error("ParseSCTList succeeded on an empty list")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	pki := newTestPKI(t)
+	logA1, logA2, logB := newTestLog(t, "A"), newTestLog(t, "A"), newTestLog(t, "B")
+	unknown := newTestLog(t, "C")
+	logs := &LogList{Logs: []*Log{logA1.Log, logA2.Log, logB.Log}}
+	timestamp := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name   string
+		logs   []*testLog
+		policy Policy
+		valid  int
+		ok     bool
+	}{
+		{"two operators", []*testLog{logA1, logB}, Policy{}, 2, true},
+		{"same operator", []*testLog{logA1, logA2}, Policy{}, 2, false},
+		{"too few", []*testLog{logA1, unknown}, Policy{}, 1, false},
+		{"three required", []*testLog{logA1, logB}, Policy{MinSCTs: 3}, 2, false},
+		{"three", []*testLog{logA1, logA2, logB}, Policy{MinSCTs: 3}, 3, true},
+		{"one operator allowed", []*testLog{logA1, logA2}, Policy{MinOperators: 1}, 2, true},
+		{"future", []*testLog{logA1, logB}, Policy{CurrentTime: timestamp.Add(-time.Hour)}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			leaf := pki.embeddedLeaf(t, timestamp, test.logs...)
+			scts, err := EmbeddedSCTs(leaf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			valid, err := test.policy.Check(logs, scts, []*x509.Certificate{leaf, pki.issuer})
+			if len(valid) != test.valid {
+				t.Errorf("got %d valid SCTs, want %d", len(valid), test.valid)
+			}
+			if test.ok && err != nil {
+				t.Errorf("Check: %v", err)
+			} else if !test.ok {
+				var perr *PolicyError
+				if !errors.As(err, &perr) {
+					t.Errorf("Check error = %v, want a *PolicyError", err)
+				}
+			}
+		})
+	}
+
+	// Certificates valid for more than 180 days require three SCTs by
+	// default.
+	pki.template.NotAfter = pki.template.NotBefore.Add(365 * 24 * time.Hour)
+	leaf := pki.embeddedLeaf(t, timestamp, logA1, logB)
+	scts, _ := EmbeddedSCTs(leaf)
+	if _, err := (&Policy{}).Check(logs, scts, []*x509.Certificate{leaf, pki.issuer}); err == nil {
+		t.Error("Check succeeded with two SCTs for a long-lived certificate")
+	}
+}
+
+func TestVerifyConnectionState(t *testing.T) {
+	pki := newTestPKI(t)
+	logA, logB, logC := newTestLog(t, "A"), newTestLog(t, "B"), newTestLog(t, "C")
+	logs := &LogList{Logs: []*Log{logA.Log, logB.Log, logC.Log}}
+	timestamp := time.Now().Add(-time.Minute)
+	leaf := pki.embeddedLeaf(t, timestamp, logA)
+
+	cs := &tls.ConnectionState{
+		PeerCertificates:            []*x509.Certificate{leaf},
+		VerifiedChains:              [][]*x509.Certificate{{leaf, pki.issuer}},
+		SignedCertificateTimestamps: [][]byte{logB.sign(t, timestamp, 0, leaf.Raw, nil), {0xff}},
+		OCSPResponse:                marshalOCSPResponse(leaf, logC.sign(t, timestamp, 0, leaf.Raw, nil)),
+	}
+	scts := ConnectionStateSCTs(cs)
+	if len(scts) != 3 {
+		t.Fatalf("got %d SCTs, want 3", len(scts))
+	}
+	for i, want := range []Source{SourceEmbedded, SourceTLSExtension, SourceOCSP} {
+		if scts[i].Source != want {
+			t.Errorf("SCT %d: Source = %v, want %v", i, scts[i].Source, want)
+		}
+	}
+	if err := VerifyConnectionState(cs, logs, &Policy{MinSCTs: 3, MinOperators: 3}); err != nil {
+		t.Errorf("VerifyConnectionState: %v", err)
+	}
+
+	// Without a verified chain, the issuer is taken from the peer
+	// certificates, and the embedded SCT can't be verified without it.
+	cs.VerifiedChains = nil
+	if err := VerifyConnectionState(cs, logs, &Policy{MinSCTs: 3}); err == nil {
+		t.Error("VerifyConnectionState succeeded without the issuer")
+	}
+	cs.PeerCertificates = append(cs.PeerCertificates, pki.issuer)
+	if err := VerifyConnectionState(cs, logs, &Policy{MinSCTs: 3}); err != nil {
+		t.Errorf("VerifyConnectionState: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ct
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// LogState is the state of a log in its lifecycle, as defined by the log
+// list policies of the major CT programs.
+type LogState int
+
+const (
+	// LogStatePending logs are not yet trusted.
+	LogStatePending LogState = iota
+	// LogStateQualified logs are trusted.
+	LogStateQualified
+	// LogStateUsable logs are trusted.
+	LogStateUsable
+	// LogStateReadOnly logs no longer accept submissions, but their
+	// SCTs are trusted.
+	LogStateReadOnly
+	// LogStateRetired logs are only trusted for SCTs issued before the
+	// log was retired.
+	LogStateRetired
+	// LogStateRejected logs are not trusted.
+	LogStateRejected
+)
+
+var logStateNames = []string{"pending", "qualified", "usable", "readonly", "retired", "rejected"}
+
+func (s LogState) String() string {
+	if s >= 0 && int(s) < len(logStateNames) {
+		return logStateNames[s]
+	}
+	return "LogState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Log is a Certificate Transparency log.
+type Log struct {
+	// Description is a human-readable description of the log.
+	Description string
+
+	// Operator is the name of the log operator. SCTs from logs with the
+	// same non-empty Operator don't count as independent for a Policy.
+	Operator string
+
+	// URL is the submission URL of the log.
+	URL string
+
+	// ID is the log ID, the SHA-256 hash of the log's public key.
+	ID [32]byte
+
+	// Key is the log's public key, an *ecdsa.PublicKey or an
+	// *rsa.PublicKey.
+	Key crypto.PublicKey
+
+	// MMD is the Maximum Merge Delay of the log.
+	MMD time.Duration
+
+	// State is the state of the log, and StateTime the time at which it
+	// entered that state.
+	State     LogState
+	StateTime time.Time
+
+	// TemporalIntervalStart and TemporalIntervalEnd, if not zero, restrict
+	// the log to certificates whose NotAfter is in
+	// [TemporalIntervalStart, TemporalIntervalEnd).
+	TemporalIntervalStart time.Time
+	TemporalIntervalEnd   time.Time
+}
+
+// NewLog returns a usable Log with the given DER encoded
+// SubjectPublicKeyInfo.
+func NewLog(description string, publicKey []byte) (*Log, error) {
+	key, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{
+		Description: description,
+		ID:          sha256.Sum256(publicKey),
+		Key:         key,
+		State:       LogStateUsable,
+	}, nil
+}
+
+// Verify checks that sct was issued by l for the certificate chain[0], and
+// that l was trusted at the time. Embedded SCTs also require the issuer of
+// chain[0] as chain[1].
+func (l *Log) Verify(sct *SignedCertificateTimestamp, chain []*x509.Certificate) error {
+	if sct.LogID != l.ID {
+		return errors.New("ct: SCT was not issued by this log")
+	}
+	switch l.State {
+	case LogStateQualified, LogStateUsable, LogStateReadOnly:
+	case LogStateRetired:
+		if !sct.Timestamp.Before(l.StateTime) {
+			return errors.New("ct: SCT was issued after the log was retired")
+		}
+	default:
+		return errors.New("ct: log is " + l.State.String())
+	}
+	if len(chain) > 0 {
+		notAfter := chain[0].NotAfter
+		if !l.TemporalIntervalStart.IsZero() && notAfter.Before(l.TemporalIntervalStart) ||
+			!l.TemporalIntervalEnd.IsZero() && !notAfter.Before(l.TemporalIntervalEnd) {
+			return errors.New("ct: certificate expiry is outside of the log's temporal interval")
+		}
+	}
+	return verifySignature(sct, chain, l.Key)
+}
+
+// LogList is a list of Certificate Transparency logs.
+type LogList struct {
+	Logs []*Log
+}
+
+// Lookup returns the log with the given ID, or nil if there is none.
+func (l *LogList) Lookup(id [32]byte) *Log {
+	for _, log := range l.Logs {
+		if log.ID == id {
+			return log
+		}
+	}
+	return nil
+}
+
+// ParseLogList parses a log list in the JSON format (version 3) published
+// by the Chrome and Apple CT programs.
+func ParseLogList(data []byte) (*LogList, error) {
+	type logState struct {
+		Timestamp time.Time `json:"timestamp"`
+	}
+	type log struct {
+		Description      string              `json:"description"`
+		LogID            []byte              `json:"log_id"`
+		Key              []byte              `json:"key"`
+		URL              string              `json:"url"`
+		SubmissionURL    string              `json:"submission_url"`
+		MMD              int                 `json:"mmd"`
+		State            map[string]logState `json:"state"`
+		TemporalInterval *struct {
+			StartInclusive time.Time `json:"start_inclusive"`
+			EndExclusive   time.Time `json:"end_exclusive"`
+		} `json:"temporal_interval"`
+	}
+	var list struct {
+		Operators []struct {
+			Name      string `json:"name"`
+			Logs      []log  `json:"logs"`
+			TiledLogs []log  `json:"tiled_logs"`
+		} `json:"operators"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.New("ct: malformed log list: " + err.Error())
+	}
+
+	out := &LogList{}
+	for _, op := range list.Operators {
+		for _, l := range append(op.Logs, op.TiledLogs...) {
+			parsed, err := NewLog(l.Description, l.Key)
+			if err != nil {
+				return nil, errors.New("ct: invalid key for log " + strconv.Quote(l.Description) + ": " + err.Error())
+			}
+			if string(parsed.ID[:]) != string(l.LogID) {
+				return nil, errors.New("ct: log ID does not match key for log " + strconv.Quote(l.Description))
+			}
+			parsed.Operator = op.Name
+			parsed.URL = l.URL
+			if parsed.URL == "" {
+				parsed.URL = l.SubmissionURL
+			}
+			parsed.MMD = time.Duration(l.MMD) * time.Second
+			parsed.State = LogStatePending
+			for i, name := range logStateNames {
+				if s, ok := l.State[name]; ok {
+					parsed.State = LogState(i)
+					parsed.StateTime = s.Timestamp
+				}
+			}
+			if l.TemporalInterval != nil {
+				parsed.TemporalIntervalStart = l.TemporalInterval.StartInclusive
+				parsed.TemporalIntervalEnd = l.TemporalInterval.EndExclusive
+			}
+			out.Logs = append(out.Logs, parsed)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ct
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogList(t *testing.T) {
+	logA, logB := newTestLog(t, "A"), newTestLog(t, "B")
+	keyA, _ := x509.MarshalPKIXPublicKey(logA.Key)
+	keyB, _ := x509.MarshalPKIXPublicKey(logB.Key)
+	b64 := base64.StdEncoding.EncodeToString
+
+	list := `{
+  "version": "30.2",
+  "log_list_timestamp": "2023-06-01T12:52:03Z",
+  "operators": [
+    {
+      "name": "Operator A",
+      "email": ["ct@example.com"],
+      "logs": [
+        {
+          "description": "Operator A 'Shard2024' log",
+          "log_id": "` + b64(logA.ID[:]) + `",
+          "key": "` + b64(keyA) + `",
+          "url": "https://ct.example.com/shard2024/",
+          "mmd": 86400,
+          "state": {
+            "usable": {
+              "timestamp": "2022-11-01T18:54:00Z"
+            }
+          },
+          "temporal_interval": {
+            "start_inclusive": "2024-01-01T00:00:00Z",
+            "end_exclusive": "2025-01-01T00:00:00Z"
+          }
+        }
+      ]
+    },
+    {
+      "name": "Operator B",
+      "logs": [],
+      "tiled_logs": [
+        {
+          "description": "Operator B tiled log",
+          "log_id": "` + b64(logB.ID[:]) + `",
+          "key": "` + b64(keyB) + `",
+          "submission_url": "https://ct.example.org/",
+          "monitoring_url": "https://tiles.example.org/",
+          "mmd": 60,
+          "state": {
+            "retired": {
+              "timestamp": "2023-03-01T00:00:00Z"
+            }
+          }
+        }
+      ]
+    }
+  ]
+}`
+	logs, err := ParseLogList([]byte(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.Logs) != 2 {
+		t.Fatalf("got %d logs, want 2", len(logs.Logs))
+	}
+
+	a := logs.Lookup(logA.ID)
+	if a == nil {
+		t.Fatal("log A not found")
+	}
+	if a.Operator != "Operator A" || a.URL != "https://ct.example.com/shard2024/" ||
+		a.MMD != 24*time.Hour || a.State != LogStateUsable ||
+		!a.StateTime.Equal(time.Date(2022, 11, 1, 18, 54, 0, 0, time.UTC)) ||
+		!a.TemporalIntervalStart.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		!a.TemporalIntervalEnd.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected log A: %+v", a)
+	}
+	if !a.Key.(interface{ Equal(crypto.PublicKey) bool }).Equal(logA.Key) {
+		t.Error("log A has the wrong key")
+	}
+
+	b := logs.Lookup(logB.ID)
+	if b == nil {
+		t.Fatal("log B not found")
+	}
+	if b.Operator != "Operator B" || b.URL != "https://ct.example.org/" ||
+		b.State != LogStateRetired || !b.TemporalIntervalStart.IsZero() {
+		t.Errorf("unexpected log B: %+v", b)
+	}
+
+	if logs.Lookup([32]byte{}) != nil {
+		t.Error("Lookup found a log for an unknown ID")
+	}
+
+	mismatched := strings.Replace(list, b64(logA.ID[:]), b64(logB.ID[:]), 1)
+	if _, err := ParseLogList([]byte(mismatched)); err == nil {
+		t.Error("ParseLogList succeeded with a mismatched log ID")
+	}
+	if _, err := ParseLogList([]byte(list[:100])); err == nil {
+		t.Error("ParseLogList succeeded on truncated input")
+	}
+}
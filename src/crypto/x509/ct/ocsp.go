@@ -0,0 +1,126 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ct
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// OCSPSCTs returns the SCTs for cert carried in the DER encoded OCSP
+// response resp, or nil if there are none.
+//
+// The OCSP response signature is not checked, as the SCTs are signed by the
+// logs themselves.
+func OCSPSCTs(resp []byte, cert *x509.Certificate) ([]*SignedCertificateTimestamp, error) {
+	errMalformed := errors.New("ct: malformed OCSP response")
+
+	// OCSPResponse ::= SEQUENCE {
+	//      responseStatus         OCSPResponseStatus,
+	//      responseBytes          [0] EXPLICIT ResponseBytes OPTIONAL }
+	//
+	// ResponseBytes ::= SEQUENCE {
+	//      responseType   OBJECT IDENTIFIER,
+	//      response       OCTET STRING }
+	input := cryptobyte.String(resp)
+	var ocspResp, responseBytes, response cryptobyte.String
+	var status int64
+	var hasResponseBytes bool
+	var responseType asn1.ObjectIdentifier
+	if !input.ReadASN1(&ocspResp, cryptobyte_asn1.SEQUENCE) ||
+		!ocspResp.ReadASN1Int64WithTag(&status, cryptobyte_asn1.ENUM) ||
+		!ocspResp.ReadOptionalASN1(&responseBytes, &hasResponseBytes, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+		return nil, errMalformed
+	}
+	if status != 0 || !hasResponseBytes {
+		return nil, errors.New("ct: OCSP response is not successful")
+	}
+	if !responseBytes.ReadASN1(&responseBytes, cryptobyte_asn1.SEQUENCE) ||
+		!responseBytes.ReadASN1ObjectIdentifier(&responseType) ||
+		!responseBytes.ReadASN1(&response, cryptobyte_asn1.OCTET_STRING) {
+		return nil, errMalformed
+	}
+	if !responseType.Equal(oidOCSPBasic) {
+		return nil, errors.New("ct: unsupported OCSP response type")
+	}
+
+	// BasicOCSPResponse ::= SEQUENCE {
+	//      tbsResponseData      ResponseData,
+	//      ... }
+	//
+	// ResponseData ::= SEQUENCE {
+	//      version              [0] EXPLICIT Version DEFAULT v1,
+	//      responderID              ResponderID,
+	//      producedAt               GeneralizedTime,
+	//      responses                SEQUENCE OF SingleResponse,
+	//      responseExtensions   [1] EXPLICIT Extensions OPTIONAL }
+	var basic, tbs, responses, skipped cryptobyte.String
+	var skippedTag cryptobyte_asn1.Tag
+	if !response.ReadASN1(&basic, cryptobyte_asn1.SEQUENCE) ||
+		!basic.ReadASN1(&tbs, cryptobyte_asn1.SEQUENCE) ||
+		!tbs.SkipOptionalASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+		!tbs.ReadAnyASN1Element(&skipped, &skippedTag) ||
+		!tbs.SkipASN1(cryptobyte_asn1.GeneralizedTime) ||
+		!tbs.ReadASN1(&responses, cryptobyte_asn1.SEQUENCE) {
+		return nil, errMalformed
+	}
+
+	// SingleResponse ::= SEQUENCE {
+	//      certID                       CertID,
+	//      certStatus                   CertStatus,
+	//      thisUpdate                   GeneralizedTime,
+	//      nextUpdate         [0]       EXPLICIT GeneralizedTime OPTIONAL,
+	//      singleExtensions   [1]       EXPLICIT Extensions OPTIONAL }
+	//
+	// CertID ::= SEQUENCE {
+	//      hashAlgorithm       AlgorithmIdentifier,
+	//      issuerNameHash      OCTET STRING,
+	//      issuerKeyHash       OCTET STRING,
+	//      serialNumber        CertificateSerialNumber }
+	for !responses.Empty() {
+		var single, certID, extensions cryptobyte.String
+		var hasExtensions bool
+		serial := new(big.Int)
+		if !responses.ReadASN1(&single, cryptobyte_asn1.SEQUENCE) ||
+			!single.ReadASN1(&certID, cryptobyte_asn1.SEQUENCE) ||
+			!certID.SkipASN1(cryptobyte_asn1.SEQUENCE) ||
+			!certID.SkipASN1(cryptobyte_asn1.OCTET_STRING) ||
+			!certID.SkipASN1(cryptobyte_asn1.OCTET_STRING) ||
+			!certID.ReadASN1Integer(serial) ||
+			!single.ReadAnyASN1Element(&skipped, &skippedTag) ||
+			!single.SkipASN1(cryptobyte_asn1.GeneralizedTime) ||
+			!single.SkipOptionalASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) ||
+			!single.ReadOptionalASN1(&extensions, &hasExtensions, cryptobyte_asn1.Tag(1).Constructed().ContextSpecific()) {
+			return nil, errMalformed
+		}
+		if serial.Cmp(cert.SerialNumber) != 0 || !hasExtensions {
+			continue
+		}
+		if !extensions.ReadASN1(&extensions, cryptobyte_asn1.SEQUENCE) {
+			return nil, errMalformed
+		}
+		for !extensions.Empty() {
+			var ext, value cryptobyte.String
+			var oid asn1.ObjectIdentifier
+			if !extensions.ReadASN1(&ext, cryptobyte_asn1.SEQUENCE) ||
+				!ext.ReadASN1ObjectIdentifier(&oid) ||
+				!ext.SkipOptionalASN1(cryptobyte_asn1.BOOLEAN) ||
+				!ext.ReadASN1(&value, cryptobyte_asn1.OCTET_STRING) {
+				return nil, errMalformed
+			}
+			if oid.Equal(oidExtensionOCSPSCTList) {
+				return parseSCTListExtension(value, SourceOCSP)
+			}
+		}
+	}
+	return nil, nil
+}
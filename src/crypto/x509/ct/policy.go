@@ -0,0 +1,161 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ct
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Policy is a Certificate Transparency policy, requiring a certificate to
+// be accompanied by a number of valid SCTs from independent logs.
+type Policy struct {
+	// MinSCTs is the minimum number of valid SCTs from distinct logs. If
+	// zero, two SCTs are required for certificates valid for at most 180
+	// days, and three otherwise, as in the Chrome and Apple policies.
+	MinSCTs int
+
+	// MinOperators is the minimum number of distinct log operators among
+	// the valid SCTs. If zero, two are required.
+	MinOperators int
+
+	// CurrentTime is used to reject SCTs with a timestamp in the future.
+	// If zero, the current time is used.
+	CurrentTime time.Time
+}
+
+// PolicyError is returned when a certificate does not have enough valid
+// SCTs to satisfy a Policy.
+type PolicyError struct {
+	// Valid are the SCTs that were successfully verified.
+	Valid []*SignedCertificateTimestamp
+
+	// Errors are the verification errors of the other SCTs.
+	Errors []error
+
+	msg string
+}
+
+func (e *PolicyError) Error() string {
+	return "ct: " + e.msg
+}
+
+// Check verifies scts against logs for the certificate chain[0], whose
+// issuer is chain[1], and returns the valid SCTs. It returns a
+// *PolicyError if they do not satisfy p. SCTs from unknown logs are
+// ignored.
+func (p *Policy) Check(logs *LogList, scts []*SignedCertificateTimestamp, chain []*x509.Certificate) ([]*SignedCertificateTimestamp, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("ct: empty certificate chain")
+	}
+	now := p.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	perr := &PolicyError{}
+	seenLogs := make(map[[32]byte]bool)
+	operators := make(map[string]bool)
+	for _, sct := range scts {
+		log := logs.Lookup(sct.LogID)
+		if log == nil {
+			continue
+		}
+		if sct.Timestamp.After(now) {
+			perr.Errors = append(perr.Errors, errors.New("ct: SCT timestamp is in the future"))
+			continue
+		}
+		if err := log.Verify(sct, chain); err != nil {
+			perr.Errors = append(perr.Errors, err)
+			continue
+		}
+		perr.Valid = append(perr.Valid, sct)
+		if seenLogs[log.ID] {
+			continue
+		}
+		seenLogs[log.ID] = true
+		if log.Operator == "" {
+			operators[string(log.ID[:])] = true
+		} else {
+			operators[log.Operator] = true
+		}
+	}
+
+	minSCTs := p.MinSCTs
+	if minSCTs == 0 {
+		minSCTs = 3
+		leaf := chain[0]
+		if leaf.NotAfter.Sub(leaf.NotBefore) <= 180*24*time.Hour {
+			minSCTs = 2
+		}
+	}
+	minOperators := p.MinOperators
+	if minOperators == 0 {
+		minOperators = 2
+	}
+	switch {
+	case len(seenLogs) < minSCTs:
+		perr.msg = "certificate has valid SCTs from " + strconv.Itoa(len(seenLogs)) +
+			" logs, but policy requires " + strconv.Itoa(minSCTs)
+		return perr.Valid, perr
+	case len(operators) < minOperators:
+		perr.msg = "certificate has valid SCTs from " + strconv.Itoa(len(operators)) +
+			" log operators, but policy requires " + strconv.Itoa(minOperators)
+		return perr.Valid, perr
+	}
+	return perr.Valid, nil
+}
+
+// ConnectionStateSCTs returns the SCTs delivered for the peer certificate
+// of cs, from all sources: embedded in the certificate, in the TLS
+// extension, and in the stapled OCSP response. Malformed SCTs are skipped.
+func ConnectionStateSCTs(cs *tls.ConnectionState) []*SignedCertificateTimestamp {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := cs.PeerCertificates[0]
+
+	var scts []*SignedCertificateTimestamp
+	if embedded, err := EmbeddedSCTs(leaf); err == nil {
+		scts = append(scts, embedded...)
+	}
+	for _, raw := range cs.SignedCertificateTimestamps {
+		if sct, err := ParseSCT(raw); err == nil {
+			sct.Source = SourceTLSExtension
+			scts = append(scts, sct)
+		}
+	}
+	if cs.OCSPResponse != nil {
+		if ocsp, err := OCSPSCTs(cs.OCSPResponse, leaf); err == nil {
+			scts = append(scts, ocsp...)
+		}
+	}
+	return scts
+}
+
+// VerifyConnectionState checks that the peer certificate of cs satisfies
+// policy, with the SCTs returned by ConnectionStateSCTs. If policy is nil,
+// the default Policy is used.
+//
+// The issuer is taken from cs.VerifiedChains if available, and from
+// cs.PeerCertificates otherwise. It can be used as a
+// tls.Config.VerifyConnection callback.
+func VerifyConnectionState(cs *tls.ConnectionState, logs *LogList, policy *Policy) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("ct: no peer certificates")
+	}
+	if policy == nil {
+		policy = &Policy{}
+	}
+	chain := cs.PeerCertificates
+	if len(cs.VerifiedChains) > 0 {
+		chain = cs.VerifiedChains[0]
+	}
+	_, err := policy.Check(logs, ConnectionStateSCTs(cs), chain)
+	return err
+}
@@ -0,0 +1,348 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// NameConstraints represents the contents of an RFC 5280, Section 4.2.1.10
+// name constraints extension.
+//
+// It mirrors the name constraint fields of Certificate, and can be used to
+// build the extension for a certificate template, or to check names against
+// a set of constraints outside of chain verification, for example to lint
+// issuance requests before signing them. Only the DNS, IP address, email and
+// URI name forms are supported.
+type NameConstraints struct {
+	// Critical reports whether the extension is, or should be, marked
+	// critical. It corresponds to Certificate.PermittedDNSDomainsCritical.
+	Critical bool
+
+	PermittedDNSDomains     []string
+	ExcludedDNSDomains      []string
+	PermittedIPRanges       []*net.IPNet
+	ExcludedIPRanges        []*net.IPNet
+	PermittedEmailAddresses []string
+	ExcludedEmailAddresses  []string
+	PermittedURIDomains     []string
+	ExcludedURIDomains      []string
+}
+
+// ParseNameConstraints parses a name constraints extension, as found in
+// Certificate.Extensions.
+//
+// Constraints on name forms other than DNS names, IP addresses, email
+// addresses and URIs are ignored, unless the extension is critical, in which
+// case an error is returned, as Verify would reject a certificate carrying it.
+func ParseNameConstraints(ext pkix.Extension) (*NameConstraints, error) {
+	if !ext.Id.Equal(oidExtensionNameConstraints) {
+		return nil, errors.New("x509: extension is not a name constraints extension")
+	}
+	var c Certificate
+	unhandled, err := parseNameConstraintsExtension(&c, ext)
+	if err != nil {
+		return nil, err
+	}
+	if unhandled && ext.Critical {
+		return nil, errors.New("x509: critical name constraints extension contains unsupported name forms")
+	}
+	return c.nameConstraints(), nil
+}
+
+// NameConstraints returns the name constraints of c, or nil if c has none.
+//
+// The returned value shares its slices with c.
+func (c *Certificate) NameConstraints() *NameConstraints {
+	nc := c.nameConstraints()
+	if nc.isEmpty() {
+		return nil
+	}
+	return nc
+}
+
+func (c *Certificate) nameConstraints() *NameConstraints {
+	return &NameConstraints{
+		Critical:                c.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:     c.PermittedDNSDomains,
+		ExcludedDNSDomains:      c.ExcludedDNSDomains,
+		PermittedIPRanges:       c.PermittedIPRanges,
+		ExcludedIPRanges:        c.ExcludedIPRanges,
+		PermittedEmailAddresses: c.PermittedEmailAddresses,
+		ExcludedEmailAddresses:  c.ExcludedEmailAddresses,
+		PermittedURIDomains:     c.PermittedURIDomains,
+		ExcludedURIDomains:      c.ExcludedURIDomains,
+	}
+}
+
+func (nc *NameConstraints) isEmpty() bool {
+	return len(nc.PermittedDNSDomains) == 0 && len(nc.ExcludedDNSDomains) == 0 &&
+		len(nc.PermittedIPRanges) == 0 && len(nc.ExcludedIPRanges) == 0 &&
+		len(nc.PermittedEmailAddresses) == 0 && len(nc.ExcludedEmailAddresses) == 0 &&
+		len(nc.PermittedURIDomains) == 0 && len(nc.ExcludedURIDomains) == 0
+}
+
+// ApplyTo sets the name constraint fields of template from nc, replacing any
+// previous values, so that CreateCertificate includes them in the
+// certificate.
+func (nc *NameConstraints) ApplyTo(template *Certificate) {
+	template.PermittedDNSDomainsCritical = nc.Critical
+	template.PermittedDNSDomains = nc.PermittedDNSDomains
+	template.ExcludedDNSDomains = nc.ExcludedDNSDomains
+	template.PermittedIPRanges = nc.PermittedIPRanges
+	template.ExcludedIPRanges = nc.ExcludedIPRanges
+	template.PermittedEmailAddresses = nc.PermittedEmailAddresses
+	template.ExcludedEmailAddresses = nc.ExcludedEmailAddresses
+	template.PermittedURIDomains = nc.PermittedURIDomains
+	template.ExcludedURIDomains = nc.ExcludedURIDomains
+}
+
+// Extension returns nc encoded as a name constraints extension, suitable for
+// Certificate.ExtraExtensions.
+func (nc *NameConstraints) Extension() (pkix.Extension, error) {
+	if nc.isEmpty() {
+		// RFC 5280, 4.2.1.10: “either the permittedSubtrees field or the
+		// excludedSubtrees MUST be present”
+		return pkix.Extension{}, errors.New("x509: empty name constraints")
+	}
+	return marshalNameConstraints(nc)
+}
+
+func marshalNameConstraints(nc *NameConstraints) (ext pkix.Extension, err error) {
+	ext.Id = oidExtensionNameConstraints
+	ext.Critical = nc.Critical
+
+	ipAndMask := func(ipNet *net.IPNet) []byte {
+		maskedIP := ipNet.IP.Mask(ipNet.Mask)
+		ipAndMask := make([]byte, 0, len(maskedIP)+len(ipNet.Mask))
+		ipAndMask = append(ipAndMask, maskedIP...)
+		ipAndMask = append(ipAndMask, ipNet.Mask...)
+		return ipAndMask
+	}
+
+	serialiseConstraints := func(dns []string, ips []*net.IPNet, emails []string, uriDomains []string) (der []byte, err error) {
+		var b cryptobyte.Builder
+
+		for _, name := range dns {
+			if err = isIA5String(name); err != nil {
+				return nil, err
+			}
+
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.Tag(2).ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddBytes([]byte(name))
+				})
+			})
+		}
+
+		for _, ipNet := range ips {
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.Tag(7).ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddBytes(ipAndMask(ipNet))
+				})
+			})
+		}
+
+		for _, email := range emails {
+			if err = isIA5String(email); err != nil {
+				return nil, err
+			}
+
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddBytes([]byte(email))
+				})
+			})
+		}
+
+		for _, uriDomain := range uriDomains {
+			if err = isIA5String(uriDomain); err != nil {
+				return nil, err
+			}
+
+			b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.Tag(6).ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddBytes([]byte(uriDomain))
+				})
+			})
+		}
+
+		return b.Bytes()
+	}
+
+	permitted, err := serialiseConstraints(nc.PermittedDNSDomains, nc.PermittedIPRanges, nc.PermittedEmailAddresses, nc.PermittedURIDomains)
+	if err != nil {
+		return ext, err
+	}
+
+	excluded, err := serialiseConstraints(nc.ExcludedDNSDomains, nc.ExcludedIPRanges, nc.ExcludedEmailAddresses, nc.ExcludedURIDomains)
+	if err != nil {
+		return ext, err
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if len(permitted) > 0 {
+			b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddBytes(permitted)
+			})
+		}
+
+		if len(excluded) > 0 {
+			b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
+				b.AddBytes(excluded)
+			})
+		}
+	})
+
+	ext.Value, err = b.Bytes()
+	return ext, err
+}
+
+// CheckDNSName returns an error if the DNS name is excluded by, or not
+// permitted by, nc.
+//
+// Violations are reported as a CertificateInvalidError with a nil Cert and a
+// Reason of CANotAuthorizedForThisName, as Verify would report them for the
+// certificate carrying nc.
+func (nc *NameConstraints) CheckDNSName(name string) error {
+	return nc.check(nameTypeDNS, []byte(name))
+}
+
+// CheckEmailAddress returns an error if the email address is excluded by, or
+// not permitted by, nc. See CheckDNSName for the errors returned.
+func (nc *NameConstraints) CheckEmailAddress(address string) error {
+	return nc.check(nameTypeEmail, []byte(address))
+}
+
+// CheckIPAddress returns an error if the IP address is excluded by, or not
+// permitted by, nc. See CheckDNSName for the errors returned.
+func (nc *NameConstraints) CheckIPAddress(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return nc.check(nameTypeIP, ip)
+}
+
+// CheckURI returns an error if the URI is excluded by, or not permitted by,
+// nc. See CheckDNSName for the errors returned.
+func (nc *NameConstraints) CheckURI(uri *url.URL) error {
+	return nc.check(nameTypeURI, []byte(uri.String()))
+}
+
+// CheckCertificate checks all the subject alternative names of cert against
+// nc, as Verify would if cert were issued under a CA certificate carrying nc.
+func (nc *NameConstraints) CheckCertificate(cert *Certificate) error {
+	return nc.checkNames(cert.DNSNames, cert.EmailAddresses, cert.IPAddresses, cert.URIs)
+}
+
+// CheckCertificateRequest checks all the subject alternative names requested
+// by csr against nc.
+func (nc *NameConstraints) CheckCertificateRequest(csr *CertificateRequest) error {
+	return nc.checkNames(csr.DNSNames, csr.EmailAddresses, csr.IPAddresses, csr.URIs)
+}
+
+func (nc *NameConstraints) checkNames(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL) error {
+	for _, name := range dnsNames {
+		if err := nc.CheckDNSName(name); err != nil {
+			return err
+		}
+	}
+	for _, address := range emailAddresses {
+		if err := nc.CheckEmailAddress(address); err != nil {
+			return err
+		}
+	}
+	for _, ip := range ipAddresses {
+		if err := nc.CheckIPAddress(ip); err != nil {
+			return err
+		}
+	}
+	for _, uri := range uris {
+		if err := nc.CheckURI(uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nc *NameConstraints) check(tag int, data []byte) error {
+	count := 0
+	return nc.checkName(nil, &count, math.MaxInt, tag, data)
+}
+
+// checkName checks a single name, in its encoded SAN form with the given tag,
+// against nc. Violations are attributed to the CA certificate c, which may be
+// nil.
+func (nc *NameConstraints) checkName(c *Certificate, count *int, maxConstraintComparisons int, tag int, data []byte) error {
+	switch tag {
+	case nameTypeEmail:
+		name := string(data)
+		mailbox, ok := parseRFC2821Mailbox(name)
+		if !ok {
+			return fmt.Errorf("x509: cannot parse rfc822Name %q", mailbox)
+		}
+
+		if err := c.checkNameConstraints(count, maxConstraintComparisons, "email address", name, mailbox,
+			func(parsedName, constraint any) (bool, error) {
+				return matchEmailConstraint(parsedName.(rfc2821Mailbox), constraint.(string))
+			}, nc.PermittedEmailAddresses, nc.ExcludedEmailAddresses); err != nil {
+			return err
+		}
+
+	case nameTypeDNS:
+		name := string(data)
+		if _, ok := domainToReverseLabels(name); !ok {
+			return fmt.Errorf("x509: cannot parse dnsName %q", name)
+		}
+
+		if err := c.checkNameConstraints(count, maxConstraintComparisons, "DNS name", name, name,
+			func(parsedName, constraint any) (bool, error) {
+				return matchDomainConstraint(parsedName.(string), constraint.(string))
+			}, nc.PermittedDNSDomains, nc.ExcludedDNSDomains); err != nil {
+			return err
+		}
+
+	case nameTypeURI:
+		name := string(data)
+		uri, err := url.Parse(name)
+		if err != nil {
+			return fmt.Errorf("x509: internal error: URI SAN %q failed to parse", name)
+		}
+
+		if err := c.checkNameConstraints(count, maxConstraintComparisons, "URI", name, uri,
+			func(parsedName, constraint any) (bool, error) {
+				return matchURIConstraint(parsedName.(*url.URL), constraint.(string))
+			}, nc.PermittedURIDomains, nc.ExcludedURIDomains); err != nil {
+			return err
+		}
+
+	case nameTypeIP:
+		ip := net.IP(data)
+		if l := len(ip); l != net.IPv4len && l != net.IPv6len {
+			return fmt.Errorf("x509: internal error: IP SAN %x failed to parse", data)
+		}
+
+		if err := c.checkNameConstraints(count, maxConstraintComparisons, "IP address", ip.String(), ip,
+			func(parsedName, constraint any) (bool, error) {
+				return matchIPConstraint(parsedName.(net.IP), constraint.(*net.IPNet))
+			}, nc.PermittedIPRanges, nc.ExcludedIPRanges); err != nil {
+			return err
+		}
+
+	default:
+		// Unknown SAN types are ignored.
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestNameConstraintsRoundTrip(t *testing.T) {
+	nc := &NameConstraints{
+		Critical:                true,
+		PermittedDNSDomains:     []string{".example.com", "example.org"},
+		ExcludedDNSDomains:      []string{"bad.example.com"},
+		PermittedIPRanges:       []*net.IPNet{mustParseCIDR(t, "192.0.2.0/24"), mustParseCIDR(t, "2001:db8::/32")},
+		ExcludedIPRanges:        []*net.IPNet{mustParseCIDR(t, "192.0.2.128/25")},
+		PermittedEmailAddresses: []string{"example.com"},
+		ExcludedEmailAddresses:  []string{"root@example.com"},
+		PermittedURIDomains:     []string{".example.com"},
+		ExcludedURIDomains:      []string{"bad.example.com"},
+	}
+
+	template := &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Constrained CA"},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	nc.ApplyTo(template)
+	der, err := CreateCertificate(rand.Reader, template, template, testPrivateKey.Public(), testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cert.NameConstraints(); !reflect.DeepEqual(got, nc) {
+		t.Errorf("Certificate.NameConstraints() = %+v, want %+v", got, nc)
+	}
+
+	ext, err := nc.Extension()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseNameConstraints(ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, nc) {
+		t.Errorf("ParseNameConstraints() = %+v, want %+v", parsed, nc)
+	}
+
+	if (&Certificate{}).NameConstraints() != nil {
+		t.Errorf("NameConstraints of unconstrained certificate is not nil")
+	}
+	if _, err := (&NameConstraints{Critical: true}).Extension(); err == nil {
+		t.Errorf("Extension succeeded for empty constraints")
+	}
+	if _, err := ParseNameConstraints(pkix.Extension{Id: oidExtensionSubjectAltName, Value: ext.Value}); err == nil {
+		t.Errorf("ParseNameConstraints accepted a different extension")
+	}
+}
+
+func TestNameConstraintsCheck(t *testing.T) {
+	nc := &NameConstraints{
+		PermittedDNSDomains:     []string{"example.com"},
+		ExcludedDNSDomains:      []string{"bad.example.com"},
+		PermittedIPRanges:       []*net.IPNet{mustParseCIDR(t, "192.0.2.0/24")},
+		PermittedEmailAddresses: []string{"example.com"},
+		PermittedURIDomains:     []string{".example.com"},
+	}
+
+	mustURL := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		ok   bool
+	}{
+		{"dns permitted", nc.CheckDNSName("www.example.com"), true},
+		{"dns excluded", nc.CheckDNSName("www.bad.example.com"), false},
+		{"dns not permitted", nc.CheckDNSName("example.net"), false},
+		{"dns invalid", nc.CheckDNSName("example..com"), false},
+		{"ip permitted", nc.CheckIPAddress(net.ParseIP("192.0.2.1")), true},
+		{"ip not permitted", nc.CheckIPAddress(net.ParseIP("198.51.100.1")), false},
+		{"email permitted", nc.CheckEmailAddress("user@example.com"), true},
+		{"email not permitted", nc.CheckEmailAddress("user@example.net"), false},
+		{"uri permitted", nc.CheckURI(mustURL("https://www.example.com/path")), true},
+		{"uri not permitted", nc.CheckURI(mustURL("https://example.com/path")), false},
+		{"csr permitted", nc.CheckCertificateRequest(&CertificateRequest{
+			DNSNames:    []string{"example.com", "a.example.com"},
+			IPAddresses: []net.IP{net.ParseIP("192.0.2.10")},
+		}), true},
+		{"csr not permitted", nc.CheckCertificateRequest(&CertificateRequest{
+			DNSNames:    []string{"example.com"},
+			IPAddresses: []net.IP{net.ParseIP("192.0.3.10")},
+		}), false},
+		{"certificate not permitted", nc.CheckCertificate(&Certificate{
+			EmailAddresses: []string{"user@bad.example.net"},
+		}), false},
+	}
+	for _, tt := range tests {
+		if ok := tt.err == nil; ok != tt.ok {
+			t.Errorf("%s: got error %v, want success %v", tt.name, tt.err, tt.ok)
+		}
+	}
+
+	var cie CertificateInvalidError
+	if err := nc.CheckDNSName("www.bad.example.com"); !errors.As(err, &cie) || cie.Reason != CANotAuthorizedForThisName || cie.Cert != nil {
+		t.Errorf("unexpected error for excluded name: %#v", err)
+	}
+}
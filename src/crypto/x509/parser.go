@@ -455,6 +455,65 @@ func parseCertificatePoliciesExtension(der cryptobyte.String) ([]asn1.ObjectIden
 	return oids, nil
 }
 
+func parsePolicyMappingsExtension(der cryptobyte.String) ([]PolicyMapping, error) {
+	// RFC 5280, 4.2.1.5
+	//
+	// PolicyMappings ::= SEQUENCE SIZE (1..MAX) OF SEQUENCE {
+	//      issuerDomainPolicy      CertPolicyId,
+	//      subjectDomainPolicy     CertPolicyId }
+	var mappings []PolicyMapping
+	if !der.ReadASN1(&der, cryptobyte_asn1.SEQUENCE) {
+		return nil, errors.New("x509: invalid policy mappings")
+	}
+	for !der.Empty() {
+		var mapping cryptobyte.String
+		var pm PolicyMapping
+		if !der.ReadASN1(&mapping, cryptobyte_asn1.SEQUENCE) ||
+			!mapping.ReadASN1ObjectIdentifier(&pm.IssuerDomainPolicy) ||
+			!mapping.ReadASN1ObjectIdentifier(&pm.SubjectDomainPolicy) {
+			return nil, errors.New("x509: invalid policy mappings")
+		}
+		mappings = append(mappings, pm)
+	}
+	return mappings, nil
+}
+
+func parsePolicyConstraintsExtension(out *Certificate, der cryptobyte.String) error {
+	// RFC 5280, 4.2.1.11
+	//
+	// PolicyConstraints ::= SEQUENCE {
+	//      requireExplicitPolicy           [0] SkipCerts OPTIONAL,
+	//      inhibitPolicyMapping            [1] SkipCerts OPTIONAL }
+	//
+	// SkipCerts ::= INTEGER (0..MAX)
+	if !der.ReadASN1(&der, cryptobyte_asn1.SEQUENCE) {
+		return errors.New("x509: invalid policy constraints")
+	}
+	readSkipCerts := func(tag cryptobyte_asn1.Tag) (int, bool, error) {
+		if !der.PeekASN1Tag(tag) {
+			return 0, false, nil
+		}
+		var v int64
+		if !der.ReadASN1Int64WithTag(&v, tag) || v < 0 || int64(int(v)) != v {
+			return 0, false, errors.New("x509: invalid policy constraints")
+		}
+		return int(v), v == 0, nil
+	}
+	var err error
+	out.RequireExplicitPolicy, out.RequireExplicitPolicyZero, err = readSkipCerts(cryptobyte_asn1.Tag(0).ContextSpecific())
+	if err != nil {
+		return err
+	}
+	out.InhibitPolicyMapping, out.InhibitPolicyMappingZero, err = readSkipCerts(cryptobyte_asn1.Tag(1).ContextSpecific())
+	if err != nil {
+		return err
+	}
+	if !der.Empty() {
+		return errors.New("x509: invalid policy constraints")
+	}
+	return nil
+}
+
 // isValidIPMask reports whether mask consists of zero or more 1 bits, followed by zero bits.
 func isValidIPMask(mask []byte) bool {
 	seenZero := false
@@ -752,6 +811,25 @@ func processExtensions(out *Certificate) error {
 				if err != nil {
 					return err
 				}
+			case 33:
+				out.PolicyMappings, err = parsePolicyMappingsExtension(e.Value)
+				if err != nil {
+					return err
+				}
+			case 36:
+				err = parsePolicyConstraintsExtension(out, e.Value)
+				if err != nil {
+					return err
+				}
+			case 54:
+				// RFC 5280, 4.2.1.14
+				val := cryptobyte.String(e.Value)
+				var skipCerts int64
+				if !val.ReadASN1Int64WithTag(&skipCerts, cryptobyte_asn1.INTEGER) || skipCerts < 0 || int64(int(skipCerts)) != skipCerts {
+					return errors.New("x509: invalid inhibit any policy")
+				}
+				out.InhibitAnyPolicy = int(skipCerts)
+				out.InhibitAnyPolicyZero = skipCerts == 0
 			default:
 				// Unknown extensions are recorded if critical.
 				unhandled = true
@@ -8,11 +8,14 @@ import (
 	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/internal/pbes2"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"io"
 )
 
 // pkcs8 reflects an ASN.1, PKCS #8 PrivateKey. See
@@ -41,6 +44,9 @@ func ParsePKCS8PrivateKey(der []byte) (key any, err error) {
 		if _, err := asn1.Unmarshal(der, &pkcs1PrivateKey{}); err == nil {
 			return nil, errors.New("x509: failed to parse private key (use ParsePKCS1PrivateKey instead for this key format)")
 		}
+		if _, err := asn1.Unmarshal(der, &encryptedPrivateKeyInfo{}); err == nil {
+			return nil, errors.New("x509: failed to parse private key (use ParseEncryptedPKCS8PrivateKey instead for this key format)")
+		}
 		return nil, err
 	}
 	switch {
@@ -173,3 +179,145 @@ func MarshalPKCS8PrivateKey(key any) ([]byte, error) {
 
 	return asn1.Marshal(privKey)
 }
+
+// encryptedPrivateKeyInfo reflects an ASN.1, PKCS #8 EncryptedPrivateKeyInfo.
+// See RFC 5958, Section 3.
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// PKCS8Cipher identifies the content encryption algorithm used by
+// EncryptPKCS8PrivateKey.
+type PKCS8Cipher int
+
+const (
+	PKCS8AES256CBC PKCS8Cipher = iota
+	PKCS8AES128CBC
+	PKCS8AES256GCM
+	PKCS8AES128GCM
+)
+
+// PKCS8KDF identifies the password-based key derivation function used by
+// EncryptPKCS8PrivateKey.
+type PKCS8KDF int
+
+const (
+	PKCS8Scrypt PKCS8KDF = iota
+	PKCS8PBKDF2WithSHA256
+)
+
+// PKCS8EncryptionOptions configures EncryptPKCS8PrivateKey. The zero value
+// selects scrypt with N=16384, r=8, and p=1, and AES-256-CBC, which can be
+// read by OpenSSL 1.1.0 and later.
+//
+// AES-GCM, as specified in RFC 5084, authenticates the encrypted key but
+// is not supported by all other implementations.
+type PKCS8EncryptionOptions struct {
+	Cipher PKCS8Cipher
+	KDF    PKCS8KDF
+
+	// Iterations is the PBKDF2 iteration count. If zero, 600000 is used.
+	Iterations int
+
+	// ScryptN, ScryptR, and ScryptP are the scrypt cost parameters. If zero,
+	// 16384, 8, and 1 are used, respectively.
+	ScryptN, ScryptR, ScryptP int
+}
+
+// EncryptPKCS8PrivateKey converts a private key to PKCS #8 form, as with
+// MarshalPKCS8PrivateKey, and encrypts it under a key derived from password
+// using PBES2, as specified in RFC 8018. The salt and IV are read from rand.
+// If opts is nil, the zero PKCS8EncryptionOptions are used.
+//
+// This kind of key is commonly encoded in PEM blocks of type
+// "ENCRYPTED PRIVATE KEY".
+func EncryptPKCS8PrivateKey(rand io.Reader, key any, password []byte, opts *PKCS8EncryptionOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &PKCS8EncryptionOptions{}
+	}
+
+	pbesOpts := &pbes2.Options{SaltSize: 16}
+	switch opts.Cipher {
+	case PKCS8AES256CBC:
+		pbesOpts.Cipher = pbes2.AES256CBC
+	case PKCS8AES128CBC:
+		pbesOpts.Cipher = pbes2.AES128CBC
+	case PKCS8AES256GCM:
+		pbesOpts.Cipher = pbes2.AES256GCM
+	case PKCS8AES128GCM:
+		pbesOpts.Cipher = pbes2.AES128GCM
+	default:
+		return nil, errors.New("x509: unknown PKCS#8 encryption cipher")
+	}
+	switch opts.KDF {
+	case PKCS8Scrypt:
+		pbesOpts.KDF = pbes2.Scrypt
+		pbesOpts.N, pbesOpts.R, pbesOpts.P = 16384, 8, 1
+		if opts.ScryptN != 0 {
+			pbesOpts.N = opts.ScryptN
+		}
+		if opts.ScryptR != 0 {
+			pbesOpts.R = opts.ScryptR
+		}
+		if opts.ScryptP != 0 {
+			pbesOpts.P = opts.ScryptP
+		}
+	case PKCS8PBKDF2WithSHA256:
+		pbesOpts.KDF = pbes2.PBKDF2
+		pbesOpts.Hash = sha256.New
+		pbesOpts.Iterations = 600000
+		if opts.Iterations != 0 {
+			pbesOpts.Iterations = opts.Iterations
+		}
+	default:
+		return nil, errors.New("x509: unknown PKCS#8 key derivation function")
+	}
+
+	der, err := MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var info encryptedPrivateKeyInfo
+	info.Algo, info.EncryptedData, err = pbes2.Encrypt(rand, password, der, pbesOpts)
+	if err != nil {
+		return nil, errors.New("x509: failed to encrypt PKCS#8 private key: " + err.Error())
+	}
+	return asn1.Marshal(info)
+}
+
+// ParseEncryptedPKCS8PrivateKey decrypts a private key in encrypted PKCS #8,
+// ASN.1 DER form, and parses it as with ParsePKCS8PrivateKey.
+//
+// Only PBES2 encryption, with PBKDF2 or scrypt and AES-CBC or AES-GCM, is
+// supported. If the password is detected to be incorrect, the returned
+// error is IncorrectPasswordError.
+//
+// This kind of key is commonly encoded in PEM blocks of type
+// "ENCRYPTED PRIVATE KEY".
+func ParseEncryptedPKCS8PrivateKey(der, password []byte) (key any, err error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+	if !info.Algo.Algorithm.Equal(pbes2.OID) {
+		return nil, fmt.Errorf("x509: unsupported PKCS#8 encryption algorithm: %v", info.Algo.Algorithm)
+	}
+
+	plaintext, err := pbes2.Decrypt(info.Algo, password, info.EncryptedData)
+	if err == pbes2.ErrDecryption {
+		return nil, IncorrectPasswordError
+	} else if err != nil {
+		return nil, errors.New("x509: failed to decrypt PKCS#8 private key: " + err.Error())
+	}
+
+	// With CBC, a wrong password is only detected by the padding check with
+	// probability 255/256. Treat a garbled PrivateKeyInfo the same way.
+	if _, err := asn1.Unmarshal(plaintext, &pkcs8{}); err != nil {
+		return nil, IncorrectPasswordError
+	}
+	return ParsePKCS8PrivateKey(plaintext)
+}
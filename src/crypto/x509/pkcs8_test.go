@@ -6,10 +6,12 @@ package x509
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"encoding/hex"
 	"reflect"
@@ -162,6 +164,7 @@ var pkcs8MismatchKeyTests = []struct {
 }{
 	{hexKey: hexPKCS8TestECKey, errorContains: "use ParseECPrivateKey instead"},
 	{hexKey: hexPKCS8TestPKCS1Key, errorContains: "use ParsePKCS1PrivateKey instead"},
+	{hexKey: hexEncryptedPKCS8Scrypt, errorContains: "use ParseEncryptedPKCS8PrivateKey instead"},
 }
 
 func TestPKCS8MismatchKeyFormat(t *testing.T) {
@@ -173,3 +176,71 @@ func TestPKCS8MismatchKeyFormat(t *testing.T) {
 		}
 	}
 }
+
+// The encrypted keys below all contain the SEC 1 key hexEncryptedPKCS8ECKey,
+// and were generated using:
+//
+//	openssl pkcs8 -topk8 -scrypt -scrypt_N 1024 -scrypt_r 8 -scrypt_p 1
+//	openssl pkcs8 -topk8 -v2 aes-128-cbc -v2prf hmacWithSHA256 -iter 1000
+
+const hexEncryptedPKCS8ECKey = "307702010104205a680107654473e9d36751a8db6ba37c77c8842a39dfed86e20a80e95fbd5bfda00a06082a8648ce3d030107a144034200044b2b4025c2e1acf61697b46da2cbd61871650699942e10f488c93b7ff7ac5dc2d5b443f340a2b7aea137b5aa591cc405e050a2918e794155cb8858c6fdec6691"
+const hexEncryptedPKCS8Scrypt = "3081e4304f06092a864886f70d01050d3042302106092b06010401da47040b30140408e3fb06495ea503e002020400020108020101301d060960864801650304012a0410f68fd5ea95a2dc51664e54034e8b47090481909709cc8a6729cc910412c32a7fa2f97da551def75b9d6a54ef8c83951fd5c24f507d2e9d8f09d3136c6892ef7ed90dae59719dab4cd7810a91a86b98d01a6bcf486e3834c03bd5a86ff4e098bacaed52bbe431dbb66d343dad97edd9c3c975bf07efd322a0c33a19f3fa97397fd2d3cd6a947ed208e7762aec417cf8ae68a4eb598e3682df48ac7ae669c0c84e1c84d8"
+const hexEncryptedPKCS8PBKDF2 = "3081ec305706092a864886f70d01050d304a302906092a864886f70d01050c301c0408d1dc8684116f5f2d020203e8300c06082a864886f70d02090500301d060960864801650304010204106cadb0dba8b9ce54ebcc003c58b60392048190b61a2158f853582fda9c5e8cd8f0dc6626dcd541e4d6b6d94cd2c588478026698aa147c175c828c25d5eb29fa13446cd28f7249b8919e456392d644168af5c002a9f22199ce49a37fc1849504c3dcd36553854300a05e5abca8dddd4d310334dd4dc2c112dcba8d2ff2d0acdace1e9dd6ad3dbada3cf4c6af1bcf115c5e0aa5f9ca6799c9f48a46372f29c0b0698f413"
+
+func TestParseEncryptedPKCS8PrivateKey(t *testing.T) {
+	want, _ := hex.DecodeString(hexEncryptedPKCS8ECKey)
+	wantKey, err := ParseECPrivateKey(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{hexEncryptedPKCS8Scrypt, hexEncryptedPKCS8PBKDF2} {
+		der, _ := hex.DecodeString(h)
+		key, err := ParseEncryptedPKCS8PrivateKey(der, []byte("hunter2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !wantKey.Equal(key) {
+			t.Errorf("decrypted key does not match")
+		}
+		if _, err := ParseEncryptedPKCS8PrivateKey(der, []byte("hunter3")); err != IncorrectPasswordError {
+			t.Errorf("got %v for wrong password, want IncorrectPasswordError", err)
+		}
+	}
+}
+
+func TestEncryptPKCS8PrivateKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []interface{ Equal(crypto.PrivateKey) bool }{ecKey, edKey}
+
+	options := []*PKCS8EncryptionOptions{
+		{ScryptN: 1024},
+		{Cipher: PKCS8AES128CBC, ScryptN: 1024},
+		{Cipher: PKCS8AES256GCM, KDF: PKCS8PBKDF2WithSHA256, Iterations: 1000},
+		{Cipher: PKCS8AES128GCM, KDF: PKCS8PBKDF2WithSHA256, Iterations: 1000},
+	}
+	for _, key := range keys {
+		for _, opts := range options {
+			der, err := EncryptPKCS8PrivateKey(rand.Reader, key, []byte("hunter2"), opts)
+			if err != nil {
+				t.Fatalf("%T %+v: %v", key, opts, err)
+			}
+			got, err := ParseEncryptedPKCS8PrivateKey(der, []byte("hunter2"))
+			if err != nil {
+				t.Fatalf("%T %+v: %v", key, opts, err)
+			}
+			if !key.Equal(got) {
+				t.Errorf("%T %+v: decrypted key does not match", key, opts)
+			}
+			if _, err := ParseEncryptedPKCS8PrivateKey(der, []byte("hunter3")); err != IncorrectPasswordError {
+				t.Errorf("%T %+v: got %v for wrong password, want IncorrectPasswordError", key, opts, err)
+			}
+		}
+	}
+}
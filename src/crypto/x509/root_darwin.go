@@ -109,7 +109,7 @@ func (c *Certificate) systemVerify(opts *VerifyOptions) (chains [][]*Certificate
 		}
 	}
 
-	if !checkChainForKeyUsage(chain[0], keyUsages) {
+	if !checkChainForKeyUsage(chain[0], keyUsages, false) {
 		return nil, CertificateInvalidError{c, IncompatibleUsage, ""}
 	}
 
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 	"net"
@@ -56,6 +57,9 @@ const (
 	// CANotAuthorizedForExtKeyUsage results when an intermediate or root
 	// certificate does not permit a requested extended key usage.
 	CANotAuthorizedForExtKeyUsage
+	// NoValidPolicy results when no candidate chain satisfies the RFC 5280
+	// certificate policy requirements of the chain and of VerifyOptions.
+	NoValidPolicy
 )
 
 // CertificateInvalidError results when an odd error occurs. Users of this
@@ -86,6 +90,8 @@ func (e CertificateInvalidError) Error() string {
 		return "x509: issuer has name constraints but leaf doesn't have a SAN extension"
 	case UnconstrainedName:
 		return "x509: issuer has name constraints but leaf contains unknown or unconstrained name: " + e.Detail
+	case NoValidPolicy:
+		return "x509: no valid certificate policy for the chain"
 	}
 	return "x509: unknown error"
 }
@@ -139,7 +145,11 @@ type UnknownAuthorityError struct {
 
 func (e UnknownAuthorityError) Error() string {
 	s := "x509: certificate signed by unknown authority"
-	if e.hintErr != nil {
+	if e.hintErr != nil && e.hintCert == nil {
+		// The hint is not about a specific candidate, for example because
+		// ChainBuilder.FetchIssuers failed.
+		s += fmt.Sprintf(" (possibly because of %q)", e.hintErr)
+	} else if e.hintErr != nil {
 		certName := e.hintCert.Subject.CommonName
 		if len(certName) == 0 {
 			if len(e.hintCert.Subject.Organization) > 0 {
@@ -201,6 +211,41 @@ type VerifyOptions struct {
 	// certificates from consuming excessive amounts of CPU time when
 	// validating. It does not apply to the platform verifier.
 	MaxConstraintComparisions int
+
+	// StrictExtKeyUsage requires every certificate in the chain other than
+	// the trust anchor to carry an Extended Key Usage extension that
+	// explicitly lists one of KeyUsages. By default, certificates without
+	// the extension, or with ExtKeyUsageAny, are treated as permitting any
+	// usage. It does not apply to the platform verifier.
+	StrictExtKeyUsage bool
+
+	// CertificatePolicies is the RFC 5280, Section 6.1.1 user-initial-policy-set:
+	// the certificate policy OIDs, at least one of which a chain must be
+	// valid for once RequireExplicitPolicy or a policy constraint in the
+	// chain requires an explicit policy. If empty, any policy is acceptable.
+	//
+	// The policy processing of RFC 5280, Section 6.1, as updated by RFC 9618,
+	// is always applied to candidate chains. This field and the following
+	// ones only select its initial inputs. They do not apply to the platform
+	// verifier.
+	CertificatePolicies []asn1.ObjectIdentifier
+
+	// RequireExplicitPolicy sets the initial-explicit-policy input, which
+	// requires that each chain be valid for at least one of
+	// CertificatePolicies.
+	RequireExplicitPolicy bool
+
+	// InhibitPolicyMapping sets the initial-policy-mapping-inhibit input,
+	// which rejects the use of policy mappings in the chain.
+	InhibitPolicyMapping bool
+
+	// InhibitAnyPolicy sets the initial-any-policy-inhibit input, which
+	// stops anyPolicy in a certificate from matching other policies.
+	InhibitAnyPolicy bool
+
+	// ChainBuilder, if not nil, customizes how candidate chains are built.
+	// It does not apply to the platform verifier.
+	ChainBuilder *ChainBuilder
 }
 
 const (
@@ -608,68 +653,10 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 		if c.hasSANExtension() {
 			toCheck = append(toCheck, c)
 		}
+		nc := c.nameConstraints()
 		for _, sanCert := range toCheck {
 			err := forEachSAN(sanCert.getSANExtension(), func(tag int, data []byte) error {
-				switch tag {
-				case nameTypeEmail:
-					name := string(data)
-					mailbox, ok := parseRFC2821Mailbox(name)
-					if !ok {
-						return fmt.Errorf("x509: cannot parse rfc822Name %q", mailbox)
-					}
-
-					if err := c.checkNameConstraints(&comparisonCount, maxConstraintComparisons, "email address", name, mailbox,
-						func(parsedName, constraint any) (bool, error) {
-							return matchEmailConstraint(parsedName.(rfc2821Mailbox), constraint.(string))
-						}, c.PermittedEmailAddresses, c.ExcludedEmailAddresses); err != nil {
-						return err
-					}
-
-				case nameTypeDNS:
-					name := string(data)
-					if _, ok := domainToReverseLabels(name); !ok {
-						return fmt.Errorf("x509: cannot parse dnsName %q", name)
-					}
-
-					if err := c.checkNameConstraints(&comparisonCount, maxConstraintComparisons, "DNS name", name, name,
-						func(parsedName, constraint any) (bool, error) {
-							return matchDomainConstraint(parsedName.(string), constraint.(string))
-						}, c.PermittedDNSDomains, c.ExcludedDNSDomains); err != nil {
-						return err
-					}
-
-				case nameTypeURI:
-					name := string(data)
-					uri, err := url.Parse(name)
-					if err != nil {
-						return fmt.Errorf("x509: internal error: URI SAN %q failed to parse", name)
-					}
-
-					if err := c.checkNameConstraints(&comparisonCount, maxConstraintComparisons, "URI", name, uri,
-						func(parsedName, constraint any) (bool, error) {
-							return matchURIConstraint(parsedName.(*url.URL), constraint.(string))
-						}, c.PermittedURIDomains, c.ExcludedURIDomains); err != nil {
-						return err
-					}
-
-				case nameTypeIP:
-					ip := net.IP(data)
-					if l := len(ip); l != net.IPv4len && l != net.IPv6len {
-						return fmt.Errorf("x509: internal error: IP SAN %x failed to parse", data)
-					}
-
-					if err := c.checkNameConstraints(&comparisonCount, maxConstraintComparisons, "IP address", ip.String(), ip,
-						func(parsedName, constraint any) (bool, error) {
-							return matchIPConstraint(parsedName.(net.IP), constraint.(*net.IPNet))
-						}, c.PermittedIPRanges, c.ExcludedIPRanges); err != nil {
-						return err
-					}
-
-				default:
-					// Unknown SAN types are ignored.
-				}
-
-				return nil
+				return nc.checkName(c, &comparisonCount, maxConstraintComparisons, tag, data)
 			})
 
 			if err != nil {
@@ -749,6 +736,10 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 //
 // WARNING: this function doesn't do any revocation checking.
 func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err error) {
+	return c.verify(opts, new(chainBuildState))
+}
+
+func (c *Certificate) verify(opts VerifyOptions, state *chainBuildState) (chains [][]*Certificate, err error) {
 	// Platform-specific verification needs the ASN.1 contents so
 	// this makes the behavior consistent across platforms.
 	if len(c.Raw) == 0 {
@@ -806,7 +797,7 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 	if opts.Roots.contains(c) {
 		candidateChains = [][]*Certificate{{c}}
 	} else {
-		candidateChains, err = c.buildChains([]*Certificate{c}, nil, &opts)
+		candidateChains, err = c.buildChains([]*Certificate{c}, state, &opts)
 		if err != nil {
 			return nil, err
 		}
@@ -816,22 +807,32 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 		opts.KeyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth}
 	}
 
+	anyKeyUsage := false
 	for _, eku := range opts.KeyUsages {
 		if eku == ExtKeyUsageAny {
 			// If any key usage is acceptable, no need to check the chain for
-			// key usages.
-			return candidateChains, nil
+			// key usages, unless every certificate must list one.
+			anyKeyUsage = !opts.StrictExtKeyUsage
 		}
 	}
 
 	chains = make([][]*Certificate, 0, len(candidateChains))
+	var invalidPolicyChains int
 	for _, candidate := range candidateChains {
-		if checkChainForKeyUsage(candidate, opts.KeyUsages) {
-			chains = append(chains, candidate)
+		if !anyKeyUsage && !checkChainForKeyUsage(candidate, opts.KeyUsages, opts.StrictExtKeyUsage) {
+			continue
+		}
+		if !policiesValid(candidate, &opts) {
+			invalidPolicyChains++
+			continue
 		}
+		chains = append(chains, candidate)
 	}
 
 	if len(chains) == 0 {
+		if invalidPolicyChains > 0 {
+			return nil, CertificateInvalidError{c, NoValidPolicy, ""}
+		}
 		return nil, CertificateInvalidError{c, IncompatibleUsage, ""}
 	}
 
@@ -895,7 +896,21 @@ func alreadyInChain(candidate *Certificate, chain []*Certificate) bool {
 // for failed checks due to different intermediates having the same Subject.
 const maxChainSignatureChecks = 100
 
-func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, opts *VerifyOptions) (chains [][]*Certificate, err error) {
+// maxChainIssuerFetches is the maximum number of ChainBuilder.FetchIssuers
+// calls that an invocation of buildChains will (transitively) make.
+const maxChainIssuerFetches = 10
+
+// chainBuildState is the state shared by the recursive calls of buildChains.
+type chainBuildState struct {
+	sigChecks int
+	fetches   int
+	// fetched caches the results of ChainBuilder.FetchIssuers by child.
+	fetched map[*Certificate][]*Certificate
+	// fetchedCerts is the set of all certificates returned by FetchIssuers.
+	fetchedCerts map[*Certificate]bool
+}
+
+func (c *Certificate) buildChains(currentChain []*Certificate, state *chainBuildState, opts *VerifyOptions) (chains [][]*Certificate, err error) {
 	var (
 		hintErr  error
 		hintCert *Certificate
@@ -906,11 +921,8 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 			return
 		}
 
-		if sigChecks == nil {
-			sigChecks = new(int)
-		}
-		*sigChecks++
-		if *sigChecks > maxChainSignatureChecks {
+		state.sigChecks++
+		if state.sigChecks > maxChainSignatureChecks {
 			err = errors.New("x509: signature check attempts limit reached while verifying certificate chain")
 			return
 		}
@@ -937,18 +949,29 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 			chains = append(chains, appendToFreshChain(currentChain, candidate))
 		case intermediateCertificate:
 			var childChains [][]*Certificate
-			childChains, err = candidate.buildChains(appendToFreshChain(currentChain, candidate), sigChecks, opts)
+			childChains, err = candidate.buildChains(appendToFreshChain(currentChain, candidate), state, opts)
 			chains = append(chains, childChains...)
 		}
 	}
 
-	for _, root := range opts.Roots.findPotentialParents(c) {
+	cb := opts.ChainBuilder
+	for _, root := range cb.candidates(c, opts.Roots.findPotentialParents(c), true) {
 		considerCandidate(rootCertificate, root)
 	}
-	for _, intermediate := range opts.Intermediates.findPotentialParents(c) {
+	for _, intermediate := range cb.candidates(c, opts.Intermediates.findPotentialParents(c), false) {
 		considerCandidate(intermediateCertificate, intermediate)
 	}
 
+	if len(chains) == 0 && err == nil && cb != nil && cb.FetchIssuers != nil {
+		fetched, fetchErr := cb.fetchIssuers(c, state)
+		if fetchErr != nil && hintErr == nil {
+			hintErr = fetchErr
+		}
+		for _, intermediate := range cb.candidates(c, fetched, false) {
+			considerCandidate(intermediateCertificate, intermediate)
+		}
+	}
+
 	if len(chains) > 0 {
 		err = nil
 	}
@@ -1124,7 +1147,11 @@ func (c *Certificate) VerifyHostname(h string) error {
 	return HostnameError{c, h}
 }
 
-func checkChainForKeyUsage(chain []*Certificate, keyUsages []ExtKeyUsage) bool {
+// checkChainForKeyUsage reports whether chain permits at least one of
+// keyUsages. If strict is set, every certificate other than the trust anchor
+// must explicitly list one of them, and ExtKeyUsageAny in keyUsages only
+// requires that the extension be present.
+func checkChainForKeyUsage(chain []*Certificate, keyUsages []ExtKeyUsage, strict bool) bool {
 	usages := make([]ExtKeyUsage, len(keyUsages))
 	copy(usages, keyUsages)
 
@@ -1141,13 +1168,20 @@ func checkChainForKeyUsage(chain []*Certificate, keyUsages []ExtKeyUsage) bool {
 NextCert:
 	for i := len(chain) - 1; i >= 0; i-- {
 		cert := chain[i]
+		if strict && i == len(chain)-1 && len(chain) > 1 {
+			// Trust anchors are not constrained by their own extensions.
+			continue
+		}
 		if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+			if strict {
+				return false
+			}
 			// The certificate doesn't have any extended key usage specified.
 			continue
 		}
 
 		for _, usage := range cert.ExtKeyUsage {
-			if usage == ExtKeyUsageAny {
+			if usage == ExtKeyUsageAny && !strict {
 				// The certificate is explicitly good for any usage.
 				continue NextCert
 			}
@@ -1161,6 +1195,12 @@ NextCert:
 				continue
 			}
 
+			if requestedUsage == ExtKeyUsageAny {
+				// Only reached in strict mode, where the presence of the
+				// extension is all that was asked for.
+				continue
+			}
+
 			for _, usage := range cert.ExtKeyUsage {
 				if requestedUsage == usage {
 					continue NextRequestedUsage
@@ -1177,3 +1217,358 @@ NextCert:
 
 	return true
 }
+
+var oidAnyPolicy = asn1.ObjectIdentifier{2, 5, 29, 32, 0}
+
+// policyGraphNode is a node of the valid_policy_graph of RFC 9618. Policy
+// qualifiers are not implemented, so no qualifier_set is tracked.
+type policyGraphNode struct {
+	validPolicy       asn1.ObjectIdentifier
+	expectedPolicySet []asn1.ObjectIdentifier
+
+	parents  map[*policyGraphNode]bool
+	children map[*policyGraphNode]bool
+}
+
+func newPolicyGraphNode(valid asn1.ObjectIdentifier, parents []*policyGraphNode) *policyGraphNode {
+	n := &policyGraphNode{
+		validPolicy:       valid,
+		expectedPolicySet: []asn1.ObjectIdentifier{valid},
+		children:          map[*policyGraphNode]bool{},
+		parents:           map[*policyGraphNode]bool{},
+	}
+	for _, p := range parents {
+		p.children[n] = true
+		n.parents[p] = true
+	}
+	return n
+}
+
+// policyGraph is the valid_policy_graph of RFC 9618, stored as one map per
+// depth from policy OID string to node.
+type policyGraph struct {
+	strata []map[string]*policyGraphNode
+	// parentIndex maps a policy OID string to the nodes at depth-1 with that
+	// OID in their expectedPolicySet.
+	parentIndex map[string][]*policyGraphNode
+	depth       int
+}
+
+func newPolicyGraph() *policyGraph {
+	root := newPolicyGraphNode(oidAnyPolicy, nil)
+	return &policyGraph{
+		strata: []map[string]*policyGraphNode{{oidAnyPolicy.String(): root}},
+	}
+}
+
+func (pg *policyGraph) insert(n *policyGraphNode) {
+	pg.strata[pg.depth][n.validPolicy.String()] = n
+}
+
+func (pg *policyGraph) parentsWithExpected(expected asn1.ObjectIdentifier) []*policyGraphNode {
+	if pg.depth == 0 {
+		return nil
+	}
+	return pg.parentIndex[expected.String()]
+}
+
+func (pg *policyGraph) parentWithAnyPolicy() *policyGraphNode {
+	if pg.depth == 0 {
+		return nil
+	}
+	return pg.strata[pg.depth-1][oidAnyPolicy.String()]
+}
+
+func (pg *policyGraph) parents() map[string]*policyGraphNode {
+	if pg.depth == 0 {
+		return nil
+	}
+	return pg.strata[pg.depth-1]
+}
+
+func (pg *policyGraph) leaves() map[string]*policyGraphNode {
+	return pg.strata[pg.depth]
+}
+
+func (pg *policyGraph) leafWithPolicy(policy asn1.ObjectIdentifier) *policyGraphNode {
+	return pg.strata[pg.depth][policy.String()]
+}
+
+func (pg *policyGraph) deleteLeaf(policy asn1.ObjectIdentifier) {
+	n := pg.strata[pg.depth][policy.String()]
+	if n == nil {
+		return
+	}
+	for p := range n.parents {
+		delete(p.children, n)
+	}
+	for c := range n.children {
+		delete(c.parents, n)
+	}
+	delete(pg.strata[pg.depth], policy.String())
+}
+
+func (pg *policyGraph) validPolicyNodes() []*policyGraphNode {
+	var validNodes []*policyGraphNode
+	for i := pg.depth; i >= 0; i-- {
+		for _, n := range pg.strata[i] {
+			if n.validPolicy.Equal(oidAnyPolicy) {
+				continue
+			}
+			if len(n.parents) == 1 {
+				for p := range n.parents {
+					if p.validPolicy.Equal(oidAnyPolicy) {
+						validNodes = append(validNodes, n)
+					}
+				}
+			}
+		}
+	}
+	return validNodes
+}
+
+func (pg *policyGraph) prune() {
+	for i := pg.depth - 1; i > 0; i-- {
+		for key, n := range pg.strata[i] {
+			if len(n.children) == 0 {
+				for p := range n.parents {
+					delete(p.children, n)
+				}
+				delete(pg.strata[i], key)
+			}
+		}
+	}
+}
+
+func (pg *policyGraph) incrDepth() {
+	pg.parentIndex = map[string][]*policyGraphNode{}
+	for _, n := range pg.strata[pg.depth] {
+		for _, e := range n.expectedPolicySet {
+			pg.parentIndex[e.String()] = append(pg.parentIndex[e.String()], n)
+		}
+	}
+
+	pg.depth++
+	pg.strata = append(pg.strata, map[string]*policyGraphNode{})
+}
+
+// policiesValid reports whether chain, ordered from the leaf to the trust
+// anchor, passes the certificate policy processing of RFC 5280, Section 6.1,
+// as updated by RFC 9618, which replaces sections 6.1.2 (a), 6.1.3 (d), (e)
+// and (f), 6.1.4 (b), and 6.1.5 (g).
+func policiesValid(chain []*Certificate, opts *VerifyOptions) bool {
+	if len(chain) == 1 {
+		return true
+	}
+
+	// n is the length of the chain minus the trust anchor.
+	n := len(chain) - 1
+
+	pg := newPolicyGraph()
+	var inhibitAnyPolicy, explicitPolicy, policyMapping int
+	if !opts.InhibitAnyPolicy {
+		inhibitAnyPolicy = n + 1
+	}
+	if !opts.RequireExplicitPolicy {
+		explicitPolicy = n + 1
+	}
+	if !opts.InhibitPolicyMapping {
+		policyMapping = n + 1
+	}
+
+	initialUserPolicySet := map[string]bool{}
+	for _, p := range opts.CertificatePolicies {
+		initialUserPolicySet[p.String()] = true
+	}
+	// An empty user-initial-policy-set is equivalent to anyPolicy.
+	if len(initialUserPolicySet) == 0 {
+		initialUserPolicySet[oidAnyPolicy.String()] = true
+	}
+
+	// Our chains go from the leaf to the trust anchor, while the certificates
+	// of RFC 5280 are numbered from the trust anchor, so our i counts down.
+	for i := n - 1; i >= 0; i-- {
+		cert := chain[i]
+
+		isSelfIssued := bytes.Equal(cert.RawIssuer, cert.RawSubject)
+
+		// 6.1.3 (e)
+		if len(cert.PolicyIdentifiers) == 0 {
+			pg = nil
+		}
+
+		// 6.1.3 (f)
+		if explicitPolicy == 0 && pg == nil {
+			return false
+		}
+
+		if pg != nil {
+			pg.incrDepth()
+
+			policies := map[string]bool{}
+
+			// 6.1.3 (d) (1)
+			for _, policy := range cert.PolicyIdentifiers {
+				policies[policy.String()] = true
+
+				if policy.Equal(oidAnyPolicy) {
+					continue
+				}
+
+				// 6.1.3 (d) (1) (i)
+				parents := pg.parentsWithExpected(policy)
+				if len(parents) == 0 {
+					// 6.1.3 (d) (1) (ii)
+					if anyParent := pg.parentWithAnyPolicy(); anyParent != nil {
+						parents = []*policyGraphNode{anyParent}
+					}
+				}
+				if len(parents) > 0 {
+					pg.insert(newPolicyGraphNode(policy, parents))
+				}
+			}
+
+			// 6.1.3 (d) (2). The specification's "i < n" is "i > 0" here.
+			if policies[oidAnyPolicy.String()] && (inhibitAnyPolicy > 0 || (i > 0 && isSelfIssued)) {
+				missing := map[string][]*policyGraphNode{}
+				missingOIDs := map[string]asn1.ObjectIdentifier{}
+				leaves := pg.leaves()
+				for _, p := range pg.parents() {
+					for _, expected := range p.expectedPolicySet {
+						key := expected.String()
+						if leaves[key] == nil {
+							missing[key] = append(missing[key], p)
+							missingOIDs[key] = expected
+						}
+					}
+				}
+
+				for key, parents := range missing {
+					pg.insert(newPolicyGraphNode(missingOIDs[key], parents))
+				}
+			}
+
+			// 6.1.3 (d) (3)
+			pg.prune()
+
+			if i != 0 {
+				// 6.1.4 (b)
+				if len(cert.PolicyMappings) > 0 {
+					// Collect a map of issuer policy to subject policies.
+					mappings := map[string][]asn1.ObjectIdentifier{}
+					issuers := map[string]asn1.ObjectIdentifier{}
+
+					for _, mapping := range cert.PolicyMappings {
+						if policyMapping > 0 {
+							// 6.1.4 (a)
+							if mapping.IssuerDomainPolicy.Equal(oidAnyPolicy) || mapping.SubjectDomainPolicy.Equal(oidAnyPolicy) {
+								return false
+							}
+							key := mapping.IssuerDomainPolicy.String()
+							mappings[key] = append(mappings[key], mapping.SubjectDomainPolicy)
+							issuers[key] = mapping.IssuerDomainPolicy
+						} else {
+							// 6.1.4 (b) (3) (i)
+							pg.deleteLeaf(mapping.IssuerDomainPolicy)
+						}
+					}
+
+					// 6.1.4 (b) (3) (ii)
+					pg.prune()
+
+					for key, subjectPolicies := range mappings {
+						if matching := pg.leafWithPolicy(issuers[key]); matching != nil {
+							// 6.1.4 (b) (1)
+							matching.expectedPolicySet = subjectPolicies
+						} else if matching := pg.leafWithPolicy(oidAnyPolicy); matching != nil {
+							// 6.1.4 (b) (2)
+							n := newPolicyGraphNode(issuers[key], []*policyGraphNode{matching})
+							n.expectedPolicySet = subjectPolicies
+							pg.insert(n)
+						}
+					}
+				}
+			}
+		}
+
+		if i != 0 {
+			// 6.1.4 (h)
+			if !isSelfIssued {
+				if explicitPolicy > 0 {
+					explicitPolicy--
+				}
+				if policyMapping > 0 {
+					policyMapping--
+				}
+				if inhibitAnyPolicy > 0 {
+					inhibitAnyPolicy--
+				}
+			}
+
+			// 6.1.4 (i)
+			if (cert.RequireExplicitPolicy > 0 || cert.RequireExplicitPolicyZero) && cert.RequireExplicitPolicy < explicitPolicy {
+				explicitPolicy = cert.RequireExplicitPolicy
+			}
+			if (cert.InhibitPolicyMapping > 0 || cert.InhibitPolicyMappingZero) && cert.InhibitPolicyMapping < policyMapping {
+				policyMapping = cert.InhibitPolicyMapping
+			}
+			// 6.1.4 (j)
+			if (cert.InhibitAnyPolicy > 0 || cert.InhibitAnyPolicyZero) && cert.InhibitAnyPolicy < inhibitAnyPolicy {
+				inhibitAnyPolicy = cert.InhibitAnyPolicy
+			}
+		}
+	}
+
+	// 6.1.5 (a)
+	if explicitPolicy > 0 {
+		explicitPolicy--
+	}
+
+	// 6.1.5 (b)
+	if chain[0].RequireExplicitPolicyZero {
+		explicitPolicy = 0
+	}
+
+	// 6.1.5 (g) (1)
+	var validPolicyNodeSet []*policyGraphNode
+	// 6.1.5 (g) (2)
+	if pg != nil {
+		validPolicyNodeSet = pg.validPolicyNodes()
+		// 6.1.5 (g) (3)
+		if currentAny := pg.leafWithPolicy(oidAnyPolicy); currentAny != nil {
+			validPolicyNodeSet = append(validPolicyNodeSet, currentAny)
+		}
+	}
+
+	// 6.1.5 (g) (4)
+	authorityConstrainedPolicySet := map[string]bool{}
+	for _, n := range validPolicyNodeSet {
+		authorityConstrainedPolicySet[n.validPolicy.String()] = true
+	}
+	// 6.1.5 (g) (5)
+	userConstrainedPolicySet := make(map[string]bool, len(authorityConstrainedPolicySet))
+	for p := range authorityConstrainedPolicySet {
+		userConstrainedPolicySet[p] = true
+	}
+	// 6.1.5 (g) (6)
+	if len(initialUserPolicySet) != 1 || !initialUserPolicySet[oidAnyPolicy.String()] {
+		// 6.1.5 (g) (6) (i)
+		for p := range userConstrainedPolicySet {
+			if !initialUserPolicySet[p] {
+				delete(userConstrainedPolicySet, p)
+			}
+		}
+		// 6.1.5 (g) (6) (ii)
+		if authorityConstrainedPolicySet[oidAnyPolicy.String()] {
+			for policy := range initialUserPolicySet {
+				userConstrainedPolicySet[policy] = true
+			}
+		}
+	}
+
+	if explicitPolicy == 0 && len(userConstrainedPolicySet) == 0 {
+		return false
+	}
+
+	return true
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"internal/testenv"
 	"math/big"
+	"os"
 	"reflect"
 	"runtime"
 	"sort"
@@ -2431,6 +2432,7 @@ func TestEKUEnforcement(t *testing.T) {
 		inters     []ekuDescs
 		leaf       ekuDescs
 		verifyEKUs []ExtKeyUsage
+		strict     bool
 		err        string
 	}{
 		{
@@ -2498,6 +2500,49 @@ func TestEKUEnforcement(t *testing.T) {
 			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth},
 			err:        "x509: certificate specifies an incompatible key usage",
 		},
+		{
+			name:       "valid, strict, root has no EKU",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth},
+			strict:     true,
+		},
+		{
+			name:       "invalid, strict, intermediate has no EKU",
+			root:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			inters:     []ekuDescs{ekuDescs{}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth},
+			strict:     true,
+			err:        "x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:       "invalid, strict, intermediate has any EKU",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageAny}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageServerAuth}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageServerAuth},
+			strict:     true,
+			err:        "x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:       "invalid, strict, any EKU requested but leaf has none",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageCodeSigning}}},
+			leaf:       ekuDescs{},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageAny},
+			strict:     true,
+			err:        "x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:       "valid, strict, any EKU requested",
+			root:       ekuDescs{},
+			inters:     []ekuDescs{ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageCodeSigning}}},
+			leaf:       ekuDescs{EKUs: []ExtKeyUsage{ExtKeyUsageCodeSigning}},
+			verifyEKUs: []ExtKeyUsage{ExtKeyUsageAny},
+			strict:     true,
+		},
 	}
 
 	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -2530,7 +2575,7 @@ func TestEKUEnforcement(t *testing.T) {
 				c.UnknownExtKeyUsage = tc.leaf.Unknown
 			}, intermediateCertificate, parent, k)
 
-			_, err := leaf.Verify(VerifyOptions{Roots: rootPool, Intermediates: interPool, KeyUsages: tc.verifyEKUs})
+			_, err := leaf.Verify(VerifyOptions{Roots: rootPool, Intermediates: interPool, KeyUsages: tc.verifyEKUs, StrictExtKeyUsage: tc.strict})
 			if err == nil && tc.err != "" {
 				t.Errorf("expected error")
 			} else if err != nil && err.Error() != tc.err {
@@ -2540,6 +2585,111 @@ func TestEKUEnforcement(t *testing.T) {
 	}
 }
 
+func TestVerifyCertificatePolicies(t *testing.T) {
+	oid1 := asn1.ObjectIdentifier{1, 2, 3, 1}
+	oid2 := asn1.ObjectIdentifier{1, 2, 3, 2}
+
+	tests := []struct {
+		name  string
+		inter func(*Certificate)
+		leaf  func(*Certificate)
+		opts  VerifyOptions
+		err   string
+	}{
+		{
+			name:  "no policies",
+			inter: func(c *Certificate) {},
+			leaf:  func(c *Certificate) {},
+		},
+		{
+			name:  "required policy present",
+			inter: func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1} },
+			leaf:  func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1} },
+			opts:  VerifyOptions{CertificatePolicies: []asn1.ObjectIdentifier{oid1}, RequireExplicitPolicy: true},
+		},
+		{
+			name:  "required policy missing",
+			inter: func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1} },
+			leaf:  func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1} },
+			opts:  VerifyOptions{CertificatePolicies: []asn1.ObjectIdentifier{oid2}, RequireExplicitPolicy: true},
+			err:   "x509: no valid certificate policy for the chain",
+		},
+		{
+			name: "mapped policy",
+			inter: func(c *Certificate) {
+				c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1}
+				c.PolicyMappings = []PolicyMapping{{IssuerDomainPolicy: oid1, SubjectDomainPolicy: oid2}}
+			},
+			leaf: func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid2} },
+			opts: VerifyOptions{CertificatePolicies: []asn1.ObjectIdentifier{oid1}, RequireExplicitPolicy: true},
+		},
+		{
+			name: "mapped policy, mapping inhibited",
+			inter: func(c *Certificate) {
+				c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1}
+				c.PolicyMappings = []PolicyMapping{{IssuerDomainPolicy: oid1, SubjectDomainPolicy: oid2}}
+			},
+			leaf: func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid2} },
+			opts: VerifyOptions{CertificatePolicies: []asn1.ObjectIdentifier{oid1}, RequireExplicitPolicy: true, InhibitPolicyMapping: true},
+			err:  "x509: no valid certificate policy for the chain",
+		},
+		{
+			name: "CA requires explicit policy",
+			inter: func(c *Certificate) {
+				c.PolicyIdentifiers = []asn1.ObjectIdentifier{oid1}
+				c.RequireExplicitPolicyZero = true
+			},
+			leaf: func(c *Certificate) {},
+			err:  "x509: no valid certificate policy for the chain",
+		},
+		{
+			name: "CA inhibits anyPolicy",
+			inter: func(c *Certificate) {
+				c.PolicyIdentifiers = []asn1.ObjectIdentifier{oidAnyPolicy}
+				c.RequireExplicitPolicyZero = true
+				c.InhibitAnyPolicyZero = true
+			},
+			leaf: func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oidAnyPolicy} },
+			err:  "x509: no valid certificate policy for the chain",
+		},
+		{
+			name: "CA permits anyPolicy",
+			inter: func(c *Certificate) {
+				c.PolicyIdentifiers = []asn1.ObjectIdentifier{oidAnyPolicy}
+				c.RequireExplicitPolicyZero = true
+				c.InhibitAnyPolicy = 1
+			},
+			leaf: func(c *Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{oidAnyPolicy} },
+		},
+	}
+
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate test key: %s", err)
+	}
+	root := genCertEdge(t, "root", k, nil, rootCertificate, nil, k)
+	roots := NewCertPool()
+	roots.AddCert(root)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inter := genCertEdge(t, "inter", k, tc.inter, intermediateCertificate, root, k)
+			leaf := genCertEdge(t, "leaf", k, tc.leaf, leafCertificate, inter, k)
+
+			opts := tc.opts
+			opts.Roots = roots
+			opts.Intermediates = NewCertPool()
+			opts.Intermediates.AddCert(inter)
+			_, err := leaf.Verify(opts)
+			if err == nil && tc.err != "" {
+				t.Errorf("expected error %q", tc.err)
+			} else if err != nil && err.Error() != tc.err {
+				t.Errorf("unexpected error: got %q, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
 func TestVerifyEKURootAsLeaf(t *testing.T) {
 	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
@@ -2610,3 +2760,374 @@ func TestVerifyEKURootAsLeaf(t *testing.T) {
 	}
 
 }
+
+func TestPoliciesValid(t *testing.T) {
+	// These test cases, the comments, and the certificates they rely on, are
+	// stolen from BoringSSL [0]. We skip the tests which involve certificate
+	// parsing as part of the verification process.
+	//
+	// [0] https://boringssl.googlesource.com/boringssl/+/264f4f7a958af6c4ccb04662e302a99dfa7c5b85/crypto/x509/x509_test.cc#5913
+
+	testOID1 := asn1.ObjectIdentifier{1, 2, 840, 113554, 4, 1, 72585, 2, 1}
+	testOID2 := asn1.ObjectIdentifier{1, 2, 840, 113554, 4, 1, 72585, 2, 2}
+	testOID3 := asn1.ObjectIdentifier{1, 2, 840, 113554, 4, 1, 72585, 2, 3}
+	testOID4 := asn1.ObjectIdentifier{1, 2, 840, 113554, 4, 1, 72585, 2, 4}
+	testOID5 := asn1.ObjectIdentifier{1, 2, 840, 113554, 4, 1, 72585, 2, 5}
+
+	loadTestCert := func(t *testing.T, path string) *Certificate {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _ := pem.Decode(b)
+		c, err := ParseCertificate(p.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	root := loadTestCert(t, "testdata/policy/policy_root.pem")
+	root_cross_inhibit_mapping := loadTestCert(t, "testdata/policy/policy_root_cross_inhibit_mapping.pem")
+	root2 := loadTestCert(t, "testdata/policy/policy_root2.pem")
+	intermediate := loadTestCert(t, "testdata/policy/policy_intermediate.pem")
+	intermediate_any := loadTestCert(t, "testdata/policy/policy_intermediate_any.pem")
+	intermediate_mapped := loadTestCert(t, "testdata/policy/policy_intermediate_mapped.pem")
+	intermediate_mapped_any := loadTestCert(t, "testdata/policy/policy_intermediate_mapped_any.pem")
+	intermediate_mapped_oid3 := loadTestCert(t, "testdata/policy/policy_intermediate_mapped_oid3.pem")
+	intermediate_require := loadTestCert(t, "testdata/policy/policy_intermediate_require.pem")
+	intermediate_require1 := loadTestCert(t, "testdata/policy/policy_intermediate_require1.pem")
+	intermediate_require2 := loadTestCert(t, "testdata/policy/policy_intermediate_require2.pem")
+	intermediate_require_no_policies := loadTestCert(t, "testdata/policy/policy_intermediate_require_no_policies.pem")
+	leaf := loadTestCert(t, "testdata/policy/policy_leaf.pem")
+	leaf_any := loadTestCert(t, "testdata/policy/policy_leaf_any.pem")
+	leaf_none := loadTestCert(t, "testdata/policy/policy_leaf_none.pem")
+	leaf_oid1 := loadTestCert(t, "testdata/policy/policy_leaf_oid1.pem")
+	leaf_oid2 := loadTestCert(t, "testdata/policy/policy_leaf_oid2.pem")
+	leaf_oid3 := loadTestCert(t, "testdata/policy/policy_leaf_oid3.pem")
+	leaf_oid4 := loadTestCert(t, "testdata/policy/policy_leaf_oid4.pem")
+	leaf_oid5 := loadTestCert(t, "testdata/policy/policy_leaf_oid5.pem")
+	leaf_require := loadTestCert(t, "testdata/policy/policy_leaf_require.pem")
+	leaf_require1 := loadTestCert(t, "testdata/policy/policy_leaf_require1.pem")
+
+	type testCase struct {
+		chain                 []*Certificate
+		policies              []asn1.ObjectIdentifier
+		requireExplicitPolicy bool
+		inhibitPolicyMapping  bool
+		inhibitAnyPolicy      bool
+		valid                 bool
+	}
+
+	tests := []testCase{
+		// The chain is good for |oid1| and |oid2|, but not |oid3|.
+		{
+			chain:                 []*Certificate{leaf, intermediate, root},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID1},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID2},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			valid:                 false,
+		},
+		{
+			chain:                 []*Certificate{leaf, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID1, testOID2},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID1, testOID3},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		// Without |X509_V_FLAG_EXPLICIT_POLICY|, the policy tree is built and
+		// intersected with user-specified policies, but it is not required to result
+		// in any valid policies.
+		{
+			chain:    []*Certificate{leaf, intermediate, root},
+			policies: []asn1.ObjectIdentifier{testOID1},
+			valid:    true,
+		},
+		{
+			chain:    []*Certificate{leaf, intermediate, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    true,
+		},
+		// However, a CA with policy constraints can require an explicit policy.
+		{
+			chain:    []*Certificate{leaf, intermediate_require, root},
+			policies: []asn1.ObjectIdentifier{testOID1},
+			valid:    true,
+		},
+		{
+			chain:    []*Certificate{leaf, intermediate_require, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    false,
+		},
+		// requireExplicitPolicy applies even if the application does not configure a
+		// user-initial-policy-set. If the validation results in no policies, the
+		// chain is invalid.
+		{
+			chain:                 []*Certificate{leaf_none, intermediate_require, root},
+			requireExplicitPolicy: true,
+			valid:                 false,
+		},
+		// A leaf can also set requireExplicitPolicy.
+		{
+			chain: []*Certificate{leaf_require, intermediate, root},
+			valid: true,
+		},
+		{
+			chain:    []*Certificate{leaf_require, intermediate, root},
+			policies: []asn1.ObjectIdentifier{testOID1},
+			valid:    true,
+		},
+		{
+			chain:    []*Certificate{leaf_require, intermediate, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    false,
+		},
+		// requireExplicitPolicy is a count of certificates to skip. If the value is
+		// not zero by the end of the chain, it doesn't count.
+		{
+			chain:    []*Certificate{leaf, intermediate_require1, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    false,
+		},
+		{
+			chain:    []*Certificate{leaf, intermediate_require2, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    true,
+		},
+		{
+			chain:    []*Certificate{leaf_require1, intermediate, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    true,
+		},
+		// If multiple certificates specify the constraint, the more constrained value
+		// wins.
+		{
+			chain:    []*Certificate{leaf_require1, intermediate_require1, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    false,
+		},
+		{
+			chain:    []*Certificate{leaf_require, intermediate_require2, root},
+			policies: []asn1.ObjectIdentifier{testOID3},
+			valid:    false,
+		},
+		// An intermediate that requires an explicit policy, but then specifies no
+		// policies should fail verification as a result.
+		{
+			chain:    []*Certificate{leaf, intermediate_require_no_policies, root},
+			policies: []asn1.ObjectIdentifier{testOID1},
+			valid:    false,
+		},
+		// A constrained intermediate's policy extension has a duplicate policy, which
+		// is invalid.
+		// {
+		// 	chain:    []*Certificate{leaf, intermediate_require_duplicate, root},
+		// 	policies: []asn1.ObjectIdentifier{testOID1},
+		// 	valid:    false,
+		// },
+		// The leaf asserts anyPolicy, but the intermediate does not. The resulting
+		// valid policies are the intersection.
+		{
+			chain:                 []*Certificate{leaf_any, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID1},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf_any, intermediate, root},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			valid:                 false,
+		},
+		// The intermediate asserts anyPolicy, but the leaf does not. The resulting
+		// valid policies are the intersection.
+		{
+			chain:                 []*Certificate{leaf, intermediate_any, root},
+			policies:              []asn1.ObjectIdentifier{testOID1},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf, intermediate_any, root},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			valid:                 false,
+		},
+		// Both assert anyPolicy. All policies are valid.
+		{
+			chain:                 []*Certificate{leaf_any, intermediate_any, root},
+			policies:              []asn1.ObjectIdentifier{testOID1},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf_any, intermediate_any, root},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		// With just a trust anchor, policy checking silently succeeds.
+		{
+			chain:                 []*Certificate{root},
+			policies:              []asn1.ObjectIdentifier{testOID1},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		// Although |intermediate_mapped_oid3| contains many mappings, it only accepts
+		// OID3. Nodes should not be created for the other mappings.
+		{
+			chain:                 []*Certificate{leaf_oid1, intermediate_mapped_oid3, root},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			valid:                 true,
+		},
+		{
+			chain:                 []*Certificate{leaf_oid4, intermediate_mapped_oid3, root},
+			policies:              []asn1.ObjectIdentifier{testOID4},
+			requireExplicitPolicy: true,
+			valid:                 false,
+		},
+		// Policy mapping can be inhibited, either by the caller or a certificate in
+		// the chain, in which case mapped policies are unassertable (apart from some
+		// anyPolicy edge cases).
+		{
+			chain:                 []*Certificate{leaf_oid1, intermediate_mapped_oid3, root},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			inhibitPolicyMapping:  true,
+			valid:                 false,
+		},
+		{
+			chain:                 []*Certificate{leaf_oid1, intermediate_mapped_oid3, root_cross_inhibit_mapping, root2},
+			policies:              []asn1.ObjectIdentifier{testOID3},
+			requireExplicitPolicy: true,
+			valid:                 false,
+		},
+	}
+
+	for _, useAny := range []bool{false, true} {
+		var intermediate *Certificate
+		if useAny {
+			intermediate = intermediate_mapped_any
+		} else {
+			intermediate = intermediate_mapped
+		}
+		extraTests := []testCase{
+			// OID3 is mapped to {OID1, OID2}, which means OID1 and OID2 (or both) are
+			// acceptable for OID3.
+			{
+				chain:                 []*Certificate{leaf, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID3},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			{
+				chain:                 []*Certificate{leaf_oid1, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID3},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			{
+				chain:                 []*Certificate{leaf_oid2, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID3},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			// If the intermediate's policies were anyPolicy, OID3 at the leaf, despite
+			// being mapped, is still acceptable as OID3 at the root. Despite the OID3
+			// having expected_policy_set = {OID1, OID2}, it can match the anyPolicy
+			// node instead.
+			//
+			// If the intermediate's policies listed OIDs explicitly, OID3 at the leaf
+			// is not acceptable as OID3 at the root. OID3 has expected_polciy_set =
+			// {OID1, OID2} and no other node allows OID3.
+			{
+				chain:                 []*Certificate{leaf_oid3, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID3},
+				requireExplicitPolicy: true,
+				valid:                 useAny,
+			},
+			// If the intermediate's policies were anyPolicy, OID1 at the leaf is no
+			// longer acceptable as OID1 at the root because policies only match
+			// anyPolicy when they match no other policy.
+			//
+			// If the intermediate's policies listed OIDs explicitly, OID1 at the leaf
+			// is acceptable as OID1 at the root because it will match both OID1 and
+			// OID3 (mapped) policies.
+			{
+				chain:                 []*Certificate{leaf_oid1, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID1},
+				requireExplicitPolicy: true,
+				valid:                 !useAny,
+			},
+			// All pairs of OID4 and OID5 are mapped together, so either can stand for
+			// the other.
+			{
+				chain:                 []*Certificate{leaf_oid4, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID4},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			{
+				chain:                 []*Certificate{leaf_oid4, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID5},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			{
+				chain:                 []*Certificate{leaf_oid5, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID4},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			{
+				chain:                 []*Certificate{leaf_oid5, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID5},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+			{
+				chain:                 []*Certificate{leaf_oid4, intermediate, root},
+				policies:              []asn1.ObjectIdentifier{testOID4, testOID5},
+				requireExplicitPolicy: true,
+				valid:                 true,
+			},
+		}
+		tests = append(tests, extraTests...)
+	}
+
+	for i, tc := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			valid := policiesValid(tc.chain, &VerifyOptions{
+				CertificatePolicies:   tc.policies,
+				RequireExplicitPolicy: tc.requireExplicitPolicy,
+				InhibitPolicyMapping:  tc.inhibitPolicyMapping,
+				InhibitAnyPolicy:      tc.inhibitAnyPolicy,
+			})
+			if valid != tc.valid {
+				t.Errorf("policiesValid: got %t, want %t", valid, tc.valid)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ package x509
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/ecdh"
 	"crypto/ecdsa"
@@ -81,8 +82,34 @@ func ParsePKIXPublicKey(derBytes []byte) (pub any, err error) {
 	return parsePublicKey(&pki)
 }
 
+// OpaquePublicKey is implemented by public keys of algorithms that this
+// package does not otherwise support, such as post-quantum signature keys
+// held by an external crypto backend, hardware token or TPM. An
+// OpaquePublicKey may be passed as the public key to CreateCertificate and
+// MarshalPKIXPublicKey, and may be returned by the Public method of a
+// crypto.Signer used with CreateCertificate, CreateCertificateRequest and
+// CreateRevocationList.
+//
+// Signatures made with an OpaquePublicKey can't be verified by this package.
+type OpaquePublicKey interface {
+	// MarshalPKIXPublicKey returns the key as a DER encoded
+	// SubjectPublicKeyInfo structure (see RFC 5280, Section 4.1).
+	MarshalPKIXPublicKey() ([]byte, error)
+
+	// SignatureAlgorithmIdentifier returns the AlgorithmIdentifier to use
+	// for signatures made with the corresponding private key, and the
+	// options to pass to its Sign method. If opts.HashFunc() is not zero,
+	// the signed message is hashed with it before being passed to Sign.
+	// Otherwise, the whole message is passed to Sign. A nil opts is
+	// equivalent to crypto.Hash(0).
+	SignatureAlgorithmIdentifier() (sigAlgo pkix.AlgorithmIdentifier, opts crypto.SignerOpts, err error)
+
+	// Equal reports whether the key is equal to x.
+	Equal(x crypto.PublicKey) bool
+}
+
 func marshalPublicKey(pub any) (publicKeyBytes []byte, publicKeyAlgorithm pkix.AlgorithmIdentifier, err error) {
-	switch pub := pub.(type) {
+	switch pub := canonicalPublicKey(pub).(type) {
 	case *rsa.PublicKey:
 		publicKeyBytes, err = asn1.Marshal(pkcs1PublicKey{
 			N: pub.N,
@@ -131,6 +158,21 @@ func marshalPublicKey(pub any) (publicKeyBytes []byte, publicKeyAlgorithm pkix.A
 			}
 			publicKeyAlgorithm.Parameters.FullBytes = paramBytes
 		}
+	case OpaquePublicKey:
+		der, err := pub.MarshalPKIXPublicKey()
+		if err != nil {
+			return nil, pkix.AlgorithmIdentifier{}, err
+		}
+		var pki publicKeyInfo
+		if rest, err := asn1.Unmarshal(der, &pki); err != nil {
+			return nil, pkix.AlgorithmIdentifier{}, err
+		} else if len(rest) != 0 {
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: trailing data after ASN.1 of public-key")
+		}
+		if pki.PublicKey.BitLength%8 != 0 {
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: public key is not a whole number of bytes")
+		}
+		return pki.PublicKey.Bytes, pki.Algorithm, nil
 	default:
 		return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("x509: unsupported public key type: %T", pub)
 	}
@@ -143,11 +185,15 @@ func marshalPublicKey(pub any) (publicKeyBytes []byte, publicKeyAlgorithm pkix.A
 // (see RFC 5280, Section 4.1).
 //
 // The following key types are currently supported: *rsa.PublicKey,
-// *ecdsa.PublicKey, ed25519.PublicKey (not a pointer), and *ecdh.PublicKey.
-// Unsupported key types result in an error.
+// *ecdsa.PublicKey, ed25519.PublicKey (not a pointer), *ecdh.PublicKey and
+// OpaquePublicKey. Unsupported key types result in an error.
 //
 // This kind of key is commonly encoded in PEM blocks of type "PUBLIC KEY".
 func MarshalPKIXPublicKey(pub any) ([]byte, error) {
+	if pub, ok := pub.(OpaquePublicKey); ok {
+		return pub.MarshalPKIXPublicKey()
+	}
+
 	var publicKeyBytes []byte
 	var publicKeyAlgorithm pkix.AlgorithmIdentifier
 	var err error
@@ -539,7 +585,7 @@ func namedCurveFromOID(oid asn1.ObjectIdentifier) elliptic.Curve {
 }
 
 func oidFromNamedCurve(curve elliptic.Curve) (asn1.ObjectIdentifier, bool) {
-	switch curve {
+	switch canonicalCurve(curve) {
 	case elliptic.P224():
 		return oidNamedCurveP224, true
 	case elliptic.P256():
@@ -553,6 +599,58 @@ func oidFromNamedCurve(curve elliptic.Curve) (asn1.ObjectIdentifier, bool) {
 	return nil, false
 }
 
+// canonicalCurve returns the standard library implementation of curve if
+// curve is a different implementation of one of the NIST curves, such as one
+// provided by a hardware token or an external crypto backend. Otherwise, it
+// returns curve unchanged.
+func canonicalCurve(curve elliptic.Curve) elliptic.Curve {
+	if curve == nil {
+		return nil
+	}
+	params := curve.Params()
+	if params == nil {
+		return curve
+	}
+	var std elliptic.Curve
+	switch params.Name {
+	case "P-224":
+		std = elliptic.P224()
+	case "P-256":
+		std = elliptic.P256()
+	case "P-384":
+		std = elliptic.P384()
+	case "P-521":
+		std = elliptic.P521()
+	default:
+		return curve
+	}
+	if curve == std {
+		return std
+	}
+	stdParams := std.Params()
+	if params.P == nil || params.N == nil || params.B == nil || params.Gx == nil || params.Gy == nil ||
+		params.P.Cmp(stdParams.P) != 0 || params.N.Cmp(stdParams.N) != 0 ||
+		params.B.Cmp(stdParams.B) != 0 || params.Gx.Cmp(stdParams.Gx) != 0 ||
+		params.Gy.Cmp(stdParams.Gy) != 0 {
+		return curve
+	}
+	return std
+}
+
+// canonicalPublicKey returns pub with its curve replaced by the standard
+// library implementation, if pub is an *ecdsa.PublicKey on a NIST curve
+// implemented elsewhere. This lets keys that live in external backends be
+// encoded, compared and verified like in-memory keys. Other keys are returned
+// unchanged.
+func canonicalPublicKey(pub any) any {
+	if k, ok := pub.(*ecdsa.PublicKey); ok && k != nil {
+		if curve := canonicalCurve(k.Curve); curve != k.Curve {
+			return &ecdsa.PublicKey{Curve: curve, X: k.X, Y: k.Y}
+		}
+	}
+	return pub
+}
+
 func oidFromECDHCurve(curve ecdh.Curve) (asn1.ObjectIdentifier, bool) {
 	switch curve {
 	case ecdh.X25519():
@@ -773,6 +871,39 @@ type Certificate struct {
 	CRLDistributionPoints []string
 
 	PolicyIdentifiers []asn1.ObjectIdentifier
+
+	// PolicyMappings contains the policy mappings of the RFC 5280,
+	// 4.2.1.5 policyMappings extension.
+	PolicyMappings []PolicyMapping
+
+	// InhibitAnyPolicy and InhibitAnyPolicyZero indicate the presence and
+	// value of the RFC 5280, 4.2.1.14 inhibitAnyPolicy extension: the number
+	// of additional non-self-issued certificates that may appear in the path
+	// before anyPolicy is no longer permitted.
+	//
+	// A positive non-zero InhibitAnyPolicy means that the field was
+	// specified, and InhibitAnyPolicyZero being true means that the field was
+	// explicitly set to zero. The case of InhibitAnyPolicy==0 with
+	// InhibitAnyPolicyZero==false means the extension is absent.
+	InhibitAnyPolicy     int
+	InhibitAnyPolicyZero bool
+
+	// InhibitPolicyMapping and RequireExplicitPolicy, along with their
+	// corresponding Zero fields, indicate the presence and value of the
+	// fields of the RFC 5280, 4.2.1.11 policyConstraints extension. They
+	// follow the same conventions as InhibitAnyPolicy.
+	InhibitPolicyMapping      int
+	InhibitPolicyMappingZero  bool
+	RequireExplicitPolicy     int
+	RequireExplicitPolicyZero bool
+}
+
+// PolicyMapping represents an entry of the policyMappings extension, which
+// declares IssuerDomainPolicy in the issuing CA's domain to be equivalent to
+// SubjectDomainPolicy in the subject CA's domain.
+type PolicyMapping struct {
+	IssuerDomainPolicy  asn1.ObjectIdentifier
+	SubjectDomainPolicy asn1.ObjectIdentifier
 }
 
 // ErrUnsupportedAlgorithm results from attempting to perform an operation that
@@ -1024,6 +1155,9 @@ var (
 	oidExtensionBasicConstraints      = []int{2, 5, 29, 19}
 	oidExtensionSubjectAltName        = []int{2, 5, 29, 17}
 	oidExtensionCertificatePolicies   = []int{2, 5, 29, 32}
+	oidExtensionPolicyMappings        = []int{2, 5, 29, 33}
+	oidExtensionPolicyConstraints     = []int{2, 5, 29, 36}
+	oidExtensionInhibitAnyPolicy      = []int{2, 5, 29, 54}
 	oidExtensionNameConstraints       = []int{2, 5, 29, 30}
 	oidExtensionCRLDistributionPoints = []int{2, 5, 29, 31}
 	oidExtensionAuthorityInfoAccess   = []int{1, 3, 6, 1, 5, 5, 7, 1, 1}
@@ -1093,7 +1227,7 @@ func isIA5String(s string) error {
 }
 
 func buildCertExtensions(template *Certificate, subjectIsEmpty bool, authorityKeyId []byte, subjectKeyId []byte) (ret []pkix.Extension, err error) {
-	ret = make([]pkix.Extension, 10 /* maximum number of elements. */)
+	ret = make([]pkix.Extension, 13 /* maximum number of elements. */)
 	n := 0
 
 	if template.KeyUsage != 0 &&
@@ -1186,98 +1320,39 @@ func buildCertExtensions(template *Certificate, subjectIsEmpty bool, authorityKe
 		n++
 	}
 
-	if (len(template.PermittedDNSDomains) > 0 || len(template.ExcludedDNSDomains) > 0 ||
-		len(template.PermittedIPRanges) > 0 || len(template.ExcludedIPRanges) > 0 ||
-		len(template.PermittedEmailAddresses) > 0 || len(template.ExcludedEmailAddresses) > 0 ||
-		len(template.PermittedURIDomains) > 0 || len(template.ExcludedURIDomains) > 0) &&
-		!oidInExtensions(oidExtensionNameConstraints, template.ExtraExtensions) {
-		ret[n].Id = oidExtensionNameConstraints
-		ret[n].Critical = template.PermittedDNSDomainsCritical
-
-		ipAndMask := func(ipNet *net.IPNet) []byte {
-			maskedIP := ipNet.IP.Mask(ipNet.Mask)
-			ipAndMask := make([]byte, 0, len(maskedIP)+len(ipNet.Mask))
-			ipAndMask = append(ipAndMask, maskedIP...)
-			ipAndMask = append(ipAndMask, ipNet.Mask...)
-			return ipAndMask
-		}
-
-		serialiseConstraints := func(dns []string, ips []*net.IPNet, emails []string, uriDomains []string) (der []byte, err error) {
-			var b cryptobyte.Builder
-
-			for _, name := range dns {
-				if err = isIA5String(name); err != nil {
-					return nil, err
-				}
-
-				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
-					b.AddASN1(cryptobyte_asn1.Tag(2).ContextSpecific(), func(b *cryptobyte.Builder) {
-						b.AddBytes([]byte(name))
-					})
-				})
-			}
-
-			for _, ipNet := range ips {
-				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
-					b.AddASN1(cryptobyte_asn1.Tag(7).ContextSpecific(), func(b *cryptobyte.Builder) {
-						b.AddBytes(ipAndMask(ipNet))
-					})
-				})
-			}
-
-			for _, email := range emails {
-				if err = isIA5String(email); err != nil {
-					return nil, err
-				}
-
-				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
-					b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific(), func(b *cryptobyte.Builder) {
-						b.AddBytes([]byte(email))
-					})
-				})
-			}
-
-			for _, uriDomain := range uriDomains {
-				if err = isIA5String(uriDomain); err != nil {
-					return nil, err
-				}
-
-				b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
-					b.AddASN1(cryptobyte_asn1.Tag(6).ContextSpecific(), func(b *cryptobyte.Builder) {
-						b.AddBytes([]byte(uriDomain))
-					})
-				})
-			}
-
-			return b.Bytes()
+	if len(template.PolicyMappings) > 0 &&
+		!oidInExtensions(oidExtensionPolicyMappings, template.ExtraExtensions) {
+		ret[n], err = marshalPolicyMappings(template.PolicyMappings)
+		if err != nil {
+			return nil, err
 		}
+		n++
+	}
 
-		permitted, err := serialiseConstraints(template.PermittedDNSDomains, template.PermittedIPRanges, template.PermittedEmailAddresses, template.PermittedURIDomains)
+	if (template.RequireExplicitPolicy > 0 || template.RequireExplicitPolicyZero ||
+		template.InhibitPolicyMapping > 0 || template.InhibitPolicyMappingZero) &&
+		!oidInExtensions(oidExtensionPolicyConstraints, template.ExtraExtensions) {
+		ret[n], err = marshalPolicyConstraints(template)
 		if err != nil {
 			return nil, err
 		}
+		n++
+	}
 
-		excluded, err := serialiseConstraints(template.ExcludedDNSDomains, template.ExcludedIPRanges, template.ExcludedEmailAddresses, template.ExcludedURIDomains)
+	if (template.InhibitAnyPolicy > 0 || template.InhibitAnyPolicyZero) &&
+		!oidInExtensions(oidExtensionInhibitAnyPolicy, template.ExtraExtensions) {
+		ret[n].Id = oidExtensionInhibitAnyPolicy
+		ret[n].Critical = true
+		ret[n].Value, err = asn1.Marshal(template.InhibitAnyPolicy)
 		if err != nil {
 			return nil, err
 		}
+		n++
+	}
 
-		var b cryptobyte.Builder
-		b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
-			if len(permitted) > 0 {
-				b.AddASN1(cryptobyte_asn1.Tag(0).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
-					b.AddBytes(permitted)
-				})
-			}
-
-			if len(excluded) > 0 {
-				b.AddASN1(cryptobyte_asn1.Tag(1).ContextSpecific().Constructed(), func(b *cryptobyte.Builder) {
-					b.AddBytes(excluded)
-				})
-			}
-		})
-
-		ret[n].Value, err = b.Bytes()
+	if nc := template.nameConstraints(); !nc.isEmpty() &&
+		!oidInExtensions(oidExtensionNameConstraints, template.ExtraExtensions) {
+		ret[n], err = marshalNameConstraints(nc)
 		if err != nil {
 			return nil, err
 		}
@@ -1375,6 +1450,33 @@ func marshalCertificatePolicies(policyIdentifiers []asn1.ObjectIdentifier) (pkix
 	return ext, err
 }
 
+func marshalPolicyMappings(mappings []PolicyMapping) (pkix.Extension, error) {
+	// RFC 5280, 4.2.1.5: “Conforming CAs SHOULD mark this extension as
+	// critical.”
+	ext := pkix.Extension{Id: oidExtensionPolicyMappings, Critical: true}
+	var err error
+	ext.Value, err = asn1.Marshal(mappings)
+	return ext, err
+}
+
+func marshalPolicyConstraints(template *Certificate) (pkix.Extension, error) {
+	// RFC 5280, 4.2.1.11: “Conforming CAs MUST mark this extension as
+	// critical.”
+	ext := pkix.Extension{Id: oidExtensionPolicyConstraints, Critical: true}
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if template.RequireExplicitPolicy > 0 || template.RequireExplicitPolicyZero {
+			b.AddASN1Int64WithTag(int64(template.RequireExplicitPolicy), cryptobyte_asn1.Tag(0).ContextSpecific())
+		}
+		if template.InhibitPolicyMapping > 0 || template.InhibitPolicyMappingZero {
+			b.AddASN1Int64WithTag(int64(template.InhibitPolicyMapping), cryptobyte_asn1.Tag(1).ContextSpecific())
+		}
+	})
+	var err error
+	ext.Value, err = b.Bytes()
+	return ext, err
+}
+
 func buildCSRExtensions(template *CertificateRequest) ([]pkix.Extension, error) {
 	var ret []pkix.Extension
 
@@ -1405,10 +1507,15 @@ func subjectBytes(cert *Certificate) ([]byte, error) {
 // signingParamsForPublicKey returns the parameters to use for signing with
 // priv. If requestedSigAlgo is not zero then it overrides the default
 // signature algorithm.
-func signingParamsForPublicKey(pub any, requestedSigAlgo SignatureAlgorithm) (hashFunc crypto.Hash, sigAlgo pkix.AlgorithmIdentifier, err error) {
+//
+// The returned signerOpts are to be passed to the crypto.Signer. If
+// signerOpts.HashFunc() is not zero, the signed data must be hashed with it
+// first; see signTBS.
+func signingParamsForPublicKey(pub any, requestedSigAlgo SignatureAlgorithm) (signerOpts crypto.SignerOpts, sigAlgo pkix.AlgorithmIdentifier, err error) {
 	var pubType PublicKeyAlgorithm
+	var hashFunc crypto.Hash
 
-	switch pub := pub.(type) {
+	switch pub := canonicalPublicKey(pub).(type) {
 	case *rsa.PublicKey:
 		pubType = RSA
 		hashFunc = crypto.SHA256
@@ -1436,14 +1543,25 @@ func signingParamsForPublicKey(pub any, requestedSigAlgo SignatureAlgorithm) (ha
 		pubType = Ed25519
 		sigAlgo.Algorithm = oidSignatureEd25519
 
+	case OpaquePublicKey:
+		if requestedSigAlgo != 0 {
+			return nil, sigAlgo, errors.New("x509: SignatureAlgorithm cannot be specified for an OpaquePublicKey")
+		}
+		sigAlgo, signerOpts, err = pub.SignatureAlgorithmIdentifier()
+		if err == nil && signerOpts == nil {
+			signerOpts = crypto.Hash(0)
+		}
+		return
+
 	default:
-		err = errors.New("x509: only RSA, ECDSA and Ed25519 keys supported")
+		err = errors.New("x509: only RSA, ECDSA, Ed25519 and opaque keys supported")
 	}
 
 	if err != nil {
 		return
 	}
 
+	signerOpts = hashFunc
 	if requestedSigAlgo == 0 {
 		return
 	}
@@ -1464,8 +1582,13 @@ func signingParamsForPublicKey(pub any, requestedSigAlgo SignatureAlgorithm) (ha
 				err = errors.New("x509: signing with MD5 is not supported")
 				return
 			}
+			signerOpts = hashFunc
 			if requestedSigAlgo.isRSAPSS() {
 				sigAlgo.Parameters = hashToPSSParameters[hashFunc]
+				signerOpts = &rsa.PSSOptions{
+					SaltLength: rsa.PSSSaltLengthEqualsHash,
+					Hash:       hashFunc,
+				}
 			}
 			found = true
 			break
@@ -1479,6 +1602,36 @@ func signingParamsForPublicKey(pub any, requestedSigAlgo SignatureAlgorithm) (ha
 	return
 }
 
+// signTBS signs tbs with key, first hashing it if opts.HashFunc() is not
+// zero, as returned by signingParamsForPublicKey. A crypto.MessageSigner is
+// given tbs itself, after checking that ctx is not done.
+func signTBS(ctx context.Context, rand io.Reader, key crypto.Signer, tbs []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if ms, ok := key.(crypto.MessageSigner); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return ms.SignMessage(rand, tbs, opts)
+	}
+	signed := tbs
+	if hashFunc := opts.HashFunc(); hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
+	}
+	return crypto.SignContext(ctx, key, rand, signed, opts)
+}
+
+// checkSignedTBS checks the signature produced by key over tbs, to ensure
+// the crypto.Signer behaved correctly. Signatures by an OpaquePublicKey
+// can't be checked by this package and are assumed to be correct.
+func checkSignedTBS(sigAlgo pkix.AlgorithmIdentifier, tbs, signature []byte, key crypto.Signer) error {
+	pub := key.Public()
+	if _, ok := pub.(OpaquePublicKey); ok {
+		return nil
+	}
+	return checkSignature(getSignatureAlgorithmFromAI(sigAlgo), tbs, signature, canonicalPublicKey(pub), true)
+}
+
 // emptyASN1Subject is the ASN.1 DER encoding of an empty Subject, which is
 // just an empty SEQUENCE.
 var emptyASN1Subject = []byte{0x30, 0}
@@ -1511,7 +1664,14 @@ var emptyASN1Subject = []byte{0x30, 0}
 //   - PermittedEmailAddresses
 //   - PermittedIPRanges
 //   - PermittedURIDomains
+//   - InhibitAnyPolicy
+//   - InhibitAnyPolicyZero
+//   - InhibitPolicyMapping
+//   - InhibitPolicyMappingZero
 //   - PolicyIdentifiers
+//   - PolicyMappings
+//   - RequireExplicitPolicy
+//   - RequireExplicitPolicyZero
 //   - SerialNumber
 //   - SignatureAlgorithm
 //   - Subject
@@ -1525,9 +1685,14 @@ var emptyASN1Subject = []byte{0x30, 0}
 //
 // The returned slice is the certificate in DER encoding.
 //
-// The currently supported key types are *rsa.PublicKey, *ecdsa.PublicKey and
-// ed25519.PublicKey. pub must be a supported key type, and priv must be a
-// crypto.Signer with a supported public key.
+// The currently supported key types are *rsa.PublicKey, *ecdsa.PublicKey,
+// ed25519.PublicKey and OpaquePublicKey. pub must be a supported key type, and
+// priv must be a crypto.Signer with a supported public key. The private key
+// may be held by a hardware token or an external crypto backend; an
+// *ecdsa.PublicKey returned by such a signer may use its own implementation of
+// a NIST curve, which is matched by its parameters. If priv implements
+// crypto.MessageSigner, it is passed the TBSCertificate to hash and sign
+// rather than its digest.
 //
 // The AuthorityKeyId will be taken from the SubjectKeyId of parent, if any,
 // unless the resulting certificate is self-signed. Otherwise the value from
@@ -1536,6 +1701,13 @@ var emptyASN1Subject = []byte{0x30, 0}
 // If SubjectKeyId from template is empty and the template is a CA, SubjectKeyId
 // will be generated from the hash of the public key.
 func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv any) ([]byte, error) {
+	return CreateCertificateContext(context.Background(), rand, template, parent, pub, priv)
+}
+
+// CreateCertificateContext is like CreateCertificate, but signs the
+// certificate with crypto.SignContext, so that a priv implementing
+// crypto.SignerCtx can honor the deadline and cancellation of ctx.
+func CreateCertificateContext(ctx context.Context, rand io.Reader, template, parent *Certificate, pub, priv any) ([]byte, error) {
 	key, ok := priv.(crypto.Signer)
 	if !ok {
 		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
@@ -1558,7 +1730,7 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv
 		return nil, errors.New("x509: only CAs are allowed to specify MaxPathLen")
 	}
 
-	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(key.Public(), template.SignatureAlgorithm)
+	signerOpts, signatureAlgorithm, err := signingParamsForPublicKey(key.Public(), template.SignatureAlgorithm)
 	if err != nil {
 		return nil, err
 	}
@@ -1567,7 +1739,7 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv
 	if err != nil {
 		return nil, err
 	}
-	if getPublicKeyAlgorithmFromOID(publicKeyAlgorithm.Algorithm) == UnknownPublicKeyAlgorithm {
+	if _, ok := pub.(OpaquePublicKey); !ok && getPublicKeyAlgorithmFromOID(publicKeyAlgorithm.Algorithm) == UnknownPublicKeyAlgorithm {
 		return nil, fmt.Errorf("x509: unsupported public key type: %T", pub)
 	}
 
@@ -1600,7 +1772,7 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv
 	type privateKey interface {
 		Equal(crypto.PublicKey) bool
 	}
-	if privPub, ok := key.Public().(privateKey); !ok {
+	if privPub, ok := canonicalPublicKey(key.Public()).(privateKey); !ok {
 		return nil, errors.New("x509: internal error: supported public key does not implement Equal")
 	} else if parent.PublicKey != nil && !privPub.Equal(parent.PublicKey) {
 		return nil, errors.New("x509: provided PrivateKey doesn't match parent's PublicKey")
@@ -1629,23 +1801,7 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv
 	}
 	c.Raw = tbsCertContents
 
-	signed := tbsCertContents
-	if hashFunc != 0 {
-		h := hashFunc.New()
-		h.Write(signed)
-		signed = h.Sum(nil)
-	}
-
-	var signerOpts crypto.SignerOpts = hashFunc
-	if template.SignatureAlgorithm != 0 && template.SignatureAlgorithm.isRSAPSS() {
-		signerOpts = &rsa.PSSOptions{
-			SaltLength: rsa.PSSSaltLengthEqualsHash,
-			Hash:       hashFunc,
-		}
-	}
-
-	var signature []byte
-	signature, err = key.Sign(rand, signed, signerOpts)
+	signature, err := signTBS(ctx, rand, key, tbsCertContents, signerOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -1660,7 +1816,7 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv
 	}
 
 	// Check the signature to ensure the crypto.Signer behaved correctly.
-	if err := checkSignature(getSignatureAlgorithmFromAI(signatureAlgorithm), c.Raw, signature, key.Public(), true); err != nil {
+	if err := checkSignedTBS(signatureAlgorithm, c.Raw, signature, key); err != nil {
 		return nil, fmt.Errorf("x509: signature over certificate returned by signer is invalid: %w", err)
 	}
 
@@ -1714,7 +1870,7 @@ func (c *Certificate) CreateCRL(rand io.Reader, priv any, revokedCerts []pkix.Re
 		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
 	}
 
-	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(key.Public(), 0)
+	signerOpts, signatureAlgorithm, err := signingParamsForPublicKey(key.Public(), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -1751,15 +1907,8 @@ func (c *Certificate) CreateCRL(rand io.Reader, priv any, revokedCerts []pkix.Re
 		return
 	}
 
-	signed := tbsCertListContents
-	if hashFunc != 0 {
-		h := hashFunc.New()
-		h.Write(signed)
-		signed = h.Sum(nil)
-	}
-
 	var signature []byte
-	signature, err = key.Sign(rand, signed, hashFunc)
+	signature, err = signTBS(context.Background(), rand, key, tbsCertListContents, signerOpts)
 	if err != nil {
 		return
 	}
@@ -1813,6 +1962,28 @@ type CertificateRequest struct {
 	EmailAddresses []string
 	IPAddresses    []net.IP
 	URIs           []*url.URL
+
+	// ChallengePassword is the PKCS #9 challengePassword attribute, used
+	// by enrollment protocols such as SCEP to authenticate or later revoke
+	// the request. It is omitted from generated CSRs if empty.
+	ChallengePassword string
+
+	// RequestAttributes contains the CSR attributes in raw form, other
+	// than the extensionRequest and challengePassword attributes, which are
+	// exposed as Extensions and ChallengePassword. When parsing CSRs, it
+	// holds every other attribute, even those that don't parse as
+	// pkix.AttributeTypeAndValueSET. When generating CSRs, the attributes
+	// are copied after those produced from the other fields.
+	RequestAttributes []RequestAttribute
+}
+
+// RequestAttribute is an attribute of a certificate signature request, as
+// defined in RFC 2986, Section 4.1.
+type RequestAttribute struct {
+	Type asn1.ObjectIdentifier
+	// Values are the DER encoded attribute values. Each must have its
+	// FullBytes set, or its Class, Tag, IsCompound and Bytes.
+	Values []asn1.RawValue
 }
 
 // These structures reflect the ASN.1 structure of X.509 certificate
@@ -1833,10 +2004,20 @@ type certificateRequest struct {
 	SignatureValue     asn1.BitString
 }
 
+// pkcs10Attribute reflects the Attribute structure from RFC 2986, Section 4.1.
+type pkcs10Attribute struct {
+	Id     asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
 // oidExtensionRequest is a PKCS #9 OBJECT IDENTIFIER that indicates requested
 // extensions in a CSR.
 var oidExtensionRequest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
 
+// oidChallengePassword is the PKCS #9 challengePassword attribute, defined in
+// RFC 2985, Section 5.4.1.
+var oidChallengePassword = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+
 // newRawAttributes converts AttributeTypeAndValueSETs from a template
 // CertificateRequest's Attributes into tbsCertificateRequest RawAttributes.
 func newRawAttributes(attributes []pkix.AttributeTypeAndValueSET) ([]asn1.RawValue, error) {
@@ -1873,12 +2054,6 @@ func parseRawAttributes(rawAttributes []asn1.RawValue) []pkix.AttributeTypeAndVa
 // parseCSRExtensions parses the attributes from a CSR and extracts any
 // requested extensions.
 func parseCSRExtensions(rawAttributes []asn1.RawValue) ([]pkix.Extension, error) {
-	// pkcs10Attribute reflects the Attribute structure from RFC 2986, Section 4.1.
-	type pkcs10Attribute struct {
-		Id     asn1.ObjectIdentifier
-		Values []asn1.RawValue `asn1:"set"`
-	}
-
 	var ret []pkix.Extension
 	requestedExts := make(map[string]bool)
 	for _, rawAttr := range rawAttributes {
@@ -1909,6 +2084,75 @@ func parseCSRExtensions(rawAttributes []asn1.RawValue) ([]pkix.Extension, error)
 	return ret, nil
 }
 
+// parseCSRAttributes parses the attributes from a CSR other than the
+// requested extensions, returning the challengePassword separately.
+func parseCSRAttributes(rawAttributes []asn1.RawValue) (challengePassword string, attrs []RequestAttribute, err error) {
+	for _, rawAttr := range rawAttributes {
+		var attr pkcs10Attribute
+		if rest, err := asn1.Unmarshal(rawAttr.FullBytes, &attr); err != nil || len(rest) != 0 {
+			return "", nil, errors.New("x509: malformed certificate request attribute")
+		}
+
+		switch {
+		case attr.Id.Equal(oidExtensionRequest):
+			continue
+		case attr.Id.Equal(oidChallengePassword):
+			// challengePassword ATTRIBUTE ::= {
+			//	WITH SYNTAX DirectoryString {pkcs-9-ub-challengePassword}
+			//	EQUALITY MATCHING RULE caseExactMatch
+			//	SINGLE VALUE TRUE
+			//	ID pkcs-9-at-challengePassword
+			// }
+			if len(attr.Values) != 1 {
+				return "", nil, errors.New("x509: certificate request challengePassword must have a single value")
+			}
+			if rest, err := asn1.Unmarshal(attr.Values[0].FullBytes, &challengePassword); err != nil || len(rest) != 0 {
+				return "", nil, errors.New("x509: malformed certificate request challengePassword")
+			}
+		default:
+			attrs = append(attrs, RequestAttribute{Type: attr.Id, Values: attr.Values})
+		}
+	}
+	return challengePassword, attrs, nil
+}
+
+// marshalCSRAttributes marshals the challengePassword and RequestAttributes
+// of template into tbsCertificateRequest RawAttributes.
+func marshalCSRAttributes(template *CertificateRequest) ([]asn1.RawValue, error) {
+	attrs := make([]pkcs10Attribute, 0, len(template.RequestAttributes)+1)
+	if template.ChallengePassword != "" {
+		// A PrintableString is used if possible, and a UTF8String
+		// otherwise, as recommended by RFC 2985.
+		value, err := asn1.Marshal(template.ChallengePassword)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, pkcs10Attribute{
+			Id:     oidChallengePassword,
+			Values: []asn1.RawValue{{FullBytes: value}},
+		})
+	}
+	for _, attr := range template.RequestAttributes {
+		if attr.Type.Equal(oidExtensionRequest) {
+			return nil, errors.New("x509: extensionRequest attribute in RequestAttributes, use ExtraExtensions instead")
+		}
+		if attr.Type.Equal(oidChallengePassword) {
+			return nil, errors.New("x509: challengePassword attribute in RequestAttributes, use ChallengePassword instead")
+		}
+		attrs = append(attrs, pkcs10Attribute{Id: attr.Type, Values: attr.Values})
+	}
+
+	var rawAttributes []asn1.RawValue
+	for _, attr := range attrs {
+		b, err := asn1.Marshal(attr)
+		if err != nil {
+			return nil, errors.New("x509: failed to marshal certificate request attribute: " + err.Error())
+		}
+		rawAttributes = append(rawAttributes, asn1.RawValue{FullBytes: b})
+	}
+	return rawAttributes, nil
+}
+
 // CreateCertificateRequest creates a new certificate request based on a
 // template. The following members of template are used:
 //
@@ -1919,24 +2163,33 @@ func parseCSRExtensions(rawAttributes []asn1.RawValue) ([]pkix.Extension, error)
 //   - IPAddresses
 //   - URIs
 //   - ExtraExtensions
+//   - ChallengePassword
+//   - RequestAttributes
 //   - Attributes (deprecated)
 //
 // priv is the private key to sign the CSR with, and the corresponding public
 // key will be included in the CSR. It must implement crypto.Signer and its
-// Public() method must return a *rsa.PublicKey or a *ecdsa.PublicKey or a
-// ed25519.PublicKey. (A *rsa.PrivateKey, *ecdsa.PrivateKey or
-// ed25519.PrivateKey satisfies this.)
+// Public() method must return a *rsa.PublicKey, a *ecdsa.PublicKey, a
+// ed25519.PublicKey or an OpaquePublicKey. (A *rsa.PrivateKey,
+// *ecdsa.PrivateKey or ed25519.PrivateKey satisfies this.)
 //
 // The returned slice is the certificate request in DER encoding.
 func CreateCertificateRequest(rand io.Reader, template *CertificateRequest, priv any) (csr []byte, err error) {
+	return CreateCertificateRequestContext(context.Background(), rand, template, priv)
+}
+
+// CreateCertificateRequestContext is like CreateCertificateRequest, but
+// signs the request with crypto.SignContext, so that a priv implementing
+// crypto.SignerCtx can honor the deadline and cancellation of ctx.
+func CreateCertificateRequestContext(ctx context.Context, rand io.Reader, template *CertificateRequest, priv any) (csr []byte, err error) {
 	key, ok := priv.(crypto.Signer)
 	if !ok {
 		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
 	}
 
-	var hashFunc crypto.Hash
+	var signerOpts crypto.SignerOpts
 	var sigAlgo pkix.AlgorithmIdentifier
-	hashFunc, sigAlgo, err = signingParamsForPublicKey(key.Public(), template.SignatureAlgorithm)
+	signerOpts, sigAlgo, err = signingParamsForPublicKey(key.Public(), template.SignatureAlgorithm)
 	if err != nil {
 		return nil, err
 	}
@@ -2035,6 +2288,12 @@ func CreateCertificateRequest(rand io.Reader, template *CertificateRequest, priv
 		rawAttributes = append(rawAttributes, rawValue)
 	}
 
+	extraAttributes, err := marshalCSRAttributes(template)
+	if err != nil {
+		return nil, err
+	}
+	rawAttributes = append(rawAttributes, extraAttributes...)
+
 	asn1Subject := template.RawSubject
 	if len(asn1Subject) == 0 {
 		asn1Subject, err = asn1.Marshal(template.Subject.ToRDNSequence())
@@ -2062,19 +2321,17 @@ func CreateCertificateRequest(rand io.Reader, template *CertificateRequest, priv
 	}
 	tbsCSR.Raw = tbsCSRContents
 
-	signed := tbsCSRContents
-	if hashFunc != 0 {
-		h := hashFunc.New()
-		h.Write(signed)
-		signed = h.Sum(nil)
-	}
-
 	var signature []byte
-	signature, err = key.Sign(rand, signed, hashFunc)
+	signature, err = signTBS(ctx, rand, key, tbsCSRContents, signerOpts)
 	if err != nil {
 		return
 	}
 
+	// Check the signature to ensure the crypto.Signer behaved correctly.
+	if err := checkSignedTBS(sigAlgo, tbsCSRContents, signature, key); err != nil {
+		return nil, fmt.Errorf("x509: signature over certificate request returned by signer is invalid: %w", err)
+	}
+
 	return asn1.Marshal(certificateRequest{
 		TBSCSR:             tbsCSR,
 		SignatureAlgorithm: sigAlgo,
@@ -2136,6 +2393,9 @@ func parseCertificateRequest(in *certificateRequest) (*CertificateRequest, error
 	if out.Extensions, err = parseCSRExtensions(in.TBSCSR.RawAttributes); err != nil {
 		return nil, err
 	}
+	if out.ChallengePassword, out.RequestAttributes, err = parseCSRAttributes(in.TBSCSR.RawAttributes); err != nil {
+		return nil, err
+	}
 
 	for _, extension := range out.Extensions {
 		switch {
@@ -2290,6 +2550,13 @@ type tbsCertificateList struct {
 // extension are populated using the issuer certificate. issuer must have
 // SubjectKeyId set.
 func CreateRevocationList(rand io.Reader, template *RevocationList, issuer *Certificate, priv crypto.Signer) ([]byte, error) {
+	return CreateRevocationListContext(context.Background(), rand, template, issuer, priv)
+}
+
+// CreateRevocationListContext is like CreateRevocationList, but signs the
+// CRL with crypto.SignContext, so that a priv implementing crypto.SignerCtx
+// can honor the deadline and cancellation of ctx.
+func CreateRevocationListContext(ctx context.Context, rand io.Reader, template *RevocationList, issuer *Certificate, priv crypto.Signer) ([]byte, error) {
 	if template == nil {
 		return nil, errors.New("x509: template can not be nil")
 	}
@@ -2309,7 +2576,7 @@ func CreateRevocationList(rand io.Reader, template *RevocationList, issuer *Cert
 		return nil, errors.New("x509: template contains nil Number field")
 	}
 
-	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
+	signerOpts, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
 	if err != nil {
 		return nil, err
 	}
@@ -2425,21 +2692,7 @@ func CreateRevocationList(rand io.Reader, template *RevocationList, issuer *Cert
 	// then embedding in certificateList below.
 	tbsCertList.Raw = tbsCertListContents
 
-	input := tbsCertListContents
-	if hashFunc != 0 {
-		h := hashFunc.New()
-		h.Write(tbsCertListContents)
-		input = h.Sum(nil)
-	}
-	var signerOpts crypto.SignerOpts = hashFunc
-	if template.SignatureAlgorithm.isRSAPSS() {
-		signerOpts = &rsa.PSSOptions{
-			SaltLength: rsa.PSSSaltLengthEqualsHash,
-			Hash:       hashFunc,
-		}
-	}
-
-	signature, err := priv.Sign(rand, input, signerOpts)
+	signature, err := signTBS(ctx, rand, priv, tbsCertListContents, signerOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -6,6 +6,7 @@ package x509
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/dsa"
 	"crypto/ecdh"
@@ -21,6 +22,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"internal/testenv"
 	"io"
@@ -3159,6 +3161,222 @@ func TestCreateCertificateLegacy(t *testing.T) {
 	}
 }
 
+// backendCurve is an elliptic.Curve implementation that is not one of the
+// standard library instances, as returned by hardware and external backends.
+type backendCurve struct {
+	elliptic.Curve
+}
+
+// backendSigner is an opaque crypto.Signer that only exposes Public and Sign,
+// like a key handle held by a hardware token or an external crypto backend.
+type backendSigner struct {
+	signer crypto.Signer
+	pub    crypto.PublicKey
+}
+
+func (s *backendSigner) Public() crypto.PublicKey { return s.pub }
+
+func (s *backendSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.signer.Sign(rand, digest, opts)
+}
+
+func TestCreateCertificateBackendSigner(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPub := &ecdsa.PublicKey{Curve: backendCurve{elliptic.P521()}, X: ecKey.X, Y: ecKey.Y}
+
+	tests := []struct {
+		name    string
+		signer  crypto.Signer
+		sigAlgo SignatureAlgorithm
+	}{
+		{"Ed25519", &backendSigner{edKey, edKey.Public()}, PureEd25519},
+		{"P-521", &backendSigner{ecKey, ecPub}, ECDSAWithSHA512},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := &Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "backend"},
+				NotBefore:             time.Unix(1000, 0),
+				NotAfter:              time.Unix(100000, 0),
+				BasicConstraintsValid: true,
+				IsCA:                  true,
+				KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+			}
+			der, err := CreateCertificate(rand.Reader, template, template, test.signer.Public(), test.signer)
+			if err != nil {
+				t.Fatalf("CreateCertificate failed: %s", err)
+			}
+			cert, err := ParseCertificate(der)
+			if err != nil {
+				t.Fatalf("ParseCertificate failed: %s", err)
+			}
+			if cert.SignatureAlgorithm != test.sigAlgo {
+				t.Errorf("SignatureAlgorithm = %v, want %v", cert.SignatureAlgorithm, test.sigAlgo)
+			}
+			if err := cert.CheckSignatureFrom(cert); err != nil {
+				t.Errorf("CheckSignatureFrom failed: %s", err)
+			}
+
+			leafTemplate := &Certificate{
+				SerialNumber: big.NewInt(2),
+				Subject:      pkix.Name{CommonName: "leaf"},
+				NotBefore:    time.Unix(1000, 0),
+				NotAfter:     time.Unix(100000, 0),
+			}
+			leafDER, err := CreateCertificate(rand.Reader, leafTemplate, cert, test.signer.Public(), test.signer)
+			if err != nil {
+				t.Fatalf("CreateCertificate with parsed parent failed: %s", err)
+			}
+			leaf, err := ParseCertificate(leafDER)
+			if err != nil {
+				t.Fatalf("ParseCertificate failed: %s", err)
+			}
+			if err := leaf.CheckSignatureFrom(cert); err != nil {
+				t.Errorf("CheckSignatureFrom failed: %s", err)
+			}
+
+			csrDER, err := CreateCertificateRequest(rand.Reader, &CertificateRequest{Subject: template.Subject}, test.signer)
+			if err != nil {
+				t.Fatalf("CreateCertificateRequest failed: %s", err)
+			}
+			csr, err := ParseCertificateRequest(csrDER)
+			if err != nil {
+				t.Fatalf("ParseCertificateRequest failed: %s", err)
+			}
+			if csr.SignatureAlgorithm != test.sigAlgo {
+				t.Errorf("CSR SignatureAlgorithm = %v, want %v", csr.SignatureAlgorithm, test.sigAlgo)
+			}
+			if err := csr.CheckSignature(); err != nil {
+				t.Errorf("CSR CheckSignature failed: %s", err)
+			}
+
+			crlDER, err := CreateRevocationList(rand.Reader, &RevocationList{
+				Number:     big.NewInt(1),
+				ThisUpdate: time.Unix(1000, 0),
+				NextUpdate: time.Unix(2000, 0),
+			}, cert, test.signer)
+			if err != nil {
+				t.Fatalf("CreateRevocationList failed: %s", err)
+			}
+			crl, err := ParseRevocationList(crlDER)
+			if err != nil {
+				t.Fatalf("ParseRevocationList failed: %s", err)
+			}
+			if err := crl.CheckSignatureFrom(cert); err != nil {
+				t.Errorf("CRL CheckSignatureFrom failed: %s", err)
+			}
+		})
+	}
+}
+
+// opaqueKey is an OpaquePublicKey for an algorithm identified by oid. The
+// signatures are made with an Ed25519 key, so that they can be checked by
+// the test when oid is the Ed25519 OID.
+type opaqueKey struct {
+	oid asn1.ObjectIdentifier
+	pub ed25519.PublicKey
+}
+
+func (k *opaqueKey) MarshalPKIXPublicKey() ([]byte, error) {
+	return asn1.Marshal(pkixPublicKey{
+		Algo:      pkix.AlgorithmIdentifier{Algorithm: k.oid},
+		BitString: asn1.BitString{Bytes: k.pub, BitLength: 8 * len(k.pub)},
+	})
+}
+
+func (k *opaqueKey) SignatureAlgorithmIdentifier() (pkix.AlgorithmIdentifier, crypto.SignerOpts, error) {
+	return pkix.AlgorithmIdentifier{Algorithm: k.oid}, nil, nil
+}
+
+func (k *opaqueKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*opaqueKey)
+	return ok && k.oid.Equal(xx.oid) && k.pub.Equal(xx.pub)
+}
+
+func TestCreateCertificateOpaqueKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "opaque"},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	// An opaque key using a known algorithm produces a certificate that
+	// this package can verify.
+	known := &backendSigner{edKey, &opaqueKey{oidPublicKeyEd25519, edKey.Public().(ed25519.PublicKey)}}
+	der, err := CreateCertificate(rand.Reader, template, template, known.Public(), known)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %s", err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %s", err)
+	}
+	if cert.PublicKeyAlgorithm != Ed25519 || cert.SignatureAlgorithm != PureEd25519 {
+		t.Errorf("got %v/%v, want Ed25519/Ed25519", cert.PublicKeyAlgorithm, cert.SignatureAlgorithm)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Errorf("CheckSignatureFrom failed: %s", err)
+	}
+
+	// An opaque key using an unknown algorithm, such as ML-DSA-65.
+	oidMLDSA65 := asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18}
+	unknown := &backendSigner{edKey, &opaqueKey{oidMLDSA65, edKey.Public().(ed25519.PublicKey)}}
+	der, err = CreateCertificate(rand.Reader, template, template, unknown.Public(), unknown)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %s", err)
+	}
+	cert, err = ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %s", err)
+	}
+	if cert.PublicKeyAlgorithm != UnknownPublicKeyAlgorithm || cert.SignatureAlgorithm != UnknownSignatureAlgorithm {
+		t.Errorf("got %v/%v, want unknown algorithms", cert.PublicKeyAlgorithm, cert.SignatureAlgorithm)
+	}
+	spki, err := MarshalPKIXPublicKey(unknown.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cert.RawSubjectPublicKeyInfo, spki) {
+		t.Errorf("RawSubjectPublicKeyInfo = %x, want %x", cert.RawSubjectPublicKeyInfo, spki)
+	}
+	if !ed25519.Verify(edKey.Public().(ed25519.PublicKey), cert.RawTBSCertificate, cert.Signature) {
+		t.Error("signature over certificate is invalid")
+	}
+
+	csrDER, err := CreateCertificateRequest(rand.Reader, &CertificateRequest{Subject: template.Subject}, unknown)
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest failed: %s", err)
+	}
+	csr, err := ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatalf("ParseCertificateRequest failed: %s", err)
+	}
+	if !bytes.Equal(csr.RawSubjectPublicKeyInfo, spki) {
+		t.Errorf("CSR RawSubjectPublicKeyInfo = %x, want %x", csr.RawSubjectPublicKeyInfo, spki)
+	}
+
+	// A SignatureAlgorithm can't be requested for an opaque key.
+	template.SignatureAlgorithm = PureEd25519
+	if _, err := CreateCertificate(rand.Reader, template, template, unknown.Public(), unknown); err == nil {
+		t.Error("CreateCertificate didn't fail with SignatureAlgorithm set for an opaque key")
+	}
+}
+
 func (s *CertPool) mustCert(t *testing.T, n int) *Certificate {
 	c, err := s.lazyCerts[n].getCert()
 	if err != nil {
@@ -3917,3 +4135,208 @@ func TestDuplicateAttributesCSR(t *testing.T) {
 		t.Fatal("ParseCertificateRequest should succeed when parsing CSR with duplicate attributes")
 	}
 }
+
+// challengePasswordCSR was generated with OpenSSL, with a challengePassword
+// of "s3cr3t pass" and an unstructuredName of "device 42".
+const challengePasswordCSR = `-----BEGIN CERTIFICATE REQUEST-----
+MIICkTCCAXkCAQAwFjEUMBIGA1UEAwwLc2NlcCBjbGllbnQwggEiMA0GCSqGSIb3
+DQEBAQUAA4IBDwAwggEKAoIBAQDHTNmriyBzhNLGCjpKnnwoyxOhq9oZlWkN5Q62
+K+NXofJxw8+fapCe3KsTFxARD057u+XI9gwg/enxq4aceD8bdCiIp/7FTdQ/yilN
+VFywLKEMOwm7Qx8WNq7sD+XrjZDL/l/JdMmmAwM6S40mbGZYTFLX1uQ03ElEU/qD
+ey9RbJTjyvrXQi3hBjQjvq2Dc15zjjvxphHooVmg5JsS9TzVAWoUGqaoVp2uqo8g
+cFl5OKfnpcuvcVGP8+hxLAw+6ievp4hrOYVedRtJpz0gOkvRCgW20YP86pFgFRqq
+jezmfTYBqjIxez7xPIMKRbZ87lSwtpVk3DztJCOoR+zP82CnAgMBAAGgNjAYBgkq
+hkiG9w0BCQIxCwwJZGV2aWNlIDQyMBoGCSqGSIb3DQEJBzENDAtzM2NyM3QgcGFz
+czANBgkqhkiG9w0BAQsFAAOCAQEAXqbAlVj0FNnxx+pcW7gZ+ZVo3FJ8rOHp0Nbg
+L8OPK/b3iuviG3mAvA60hI4xTCbDdI9ueCpcMza1pjIJY2XRHoqGcQgBik/k7bLO
+nx28G3zGccOj2JdRZuTdG8RqbYKd7u9knxfnr73ayIYtPg61HrvdbQ5PtCgrLWMf
+sXnuqUntWCBRa2mZjDgBN2T0gZSJjX+riTBr/edMjO41t3B6U0XV4pWngP5xeQue
+Tp9Rg/k2DdpwEgoDyc+OceZsgGrlJM+b78EaCSlF63mQI3jbozl7d8jxUmG5qjot
+54lAeZAchsBNyJevHi4IFxDSLo6gkgJVvQohWm4iOB6bakTUTg==
+-----END CERTIFICATE REQUEST-----`
+
+func TestParseCertificateRequestAttributes(t *testing.T) {
+	b, _ := pem.Decode([]byte(challengePasswordCSR))
+	csr, err := ParseCertificateRequest(b.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+	if csr.ChallengePassword != "s3cr3t pass" {
+		t.Errorf("ChallengePassword = %q, want %q", csr.ChallengePassword, "s3cr3t pass")
+	}
+	if len(csr.RequestAttributes) != 1 {
+		t.Fatalf("got %d RequestAttributes, want 1", len(csr.RequestAttributes))
+	}
+	attr := csr.RequestAttributes[0]
+	oidUnstructuredName := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 2}
+	if !attr.Type.Equal(oidUnstructuredName) || len(attr.Values) != 1 {
+		t.Fatalf("unexpected attribute %v", attr)
+	}
+	var name string
+	if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &name); err != nil || name != "device 42" {
+		t.Errorf("unstructuredName = %q, %v, want %q", name, err, "device 42")
+	}
+}
+
+func TestCertificateRequestAttributesRoundtrip(t *testing.T) {
+	oidUnstructuredName := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 2}
+	name, _ := asn1.Marshal("device 42")
+	for _, password := range []string{"s3cr3t pass", "päss_wörd", ""} {
+		in := &CertificateRequest{
+			Subject:           pkix.Name{CommonName: "scep client"},
+			DNSNames:          []string{"example.com"},
+			ChallengePassword: password,
+			RequestAttributes: []RequestAttribute{{
+				Type:   oidUnstructuredName,
+				Values: []asn1.RawValue{{FullBytes: name}},
+			}, {
+				Type: asn1.ObjectIdentifier{1, 2, 3, 4},
+				// DER sorts the values of a SET OF.
+				Values: []asn1.RawValue{{Tag: asn1.TagBoolean, Bytes: []byte{0xff}}, {Tag: asn1.TagInteger, Bytes: []byte{42}}},
+			}},
+		}
+		out := marshalAndParseCSR(t, in)
+		if out.ChallengePassword != password {
+			t.Errorf("ChallengePassword = %q, want %q", out.ChallengePassword, password)
+		}
+		if !reflect.DeepEqual(out.DNSNames, in.DNSNames) {
+			t.Errorf("DNSNames = %v, want %v", out.DNSNames, in.DNSNames)
+		}
+		if len(out.RequestAttributes) != 2 {
+			t.Fatalf("got %d RequestAttributes, want 2", len(out.RequestAttributes))
+		}
+		for i, attr := range out.RequestAttributes {
+			want := in.RequestAttributes[i]
+			if !attr.Type.Equal(want.Type) || len(attr.Values) != len(want.Values) {
+				t.Fatalf("RequestAttributes[%d] = %v, want %v", i, attr, want)
+			}
+			for j, v := range attr.Values {
+				wantBytes := want.Values[j].FullBytes
+				if wantBytes == nil {
+					wantBytes, _ = asn1.Marshal(want.Values[j])
+				}
+				if !bytes.Equal(v.FullBytes, wantBytes) {
+					t.Errorf("RequestAttributes[%d].Values[%d] = %x, want %x", i, j, v.FullBytes, wantBytes)
+				}
+			}
+		}
+
+		// A parsed request can be used as a template.
+		again := marshalAndParseCSR(t, out)
+		if again.ChallengePassword != password || len(again.RequestAttributes) != 2 {
+			t.Errorf("unexpected request after second roundtrip: %+v", again)
+		}
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for _, oid := range []asn1.ObjectIdentifier{oidExtensionRequest, oidChallengePassword} {
+		template := &CertificateRequest{RequestAttributes: []RequestAttribute{{Type: oid}}}
+		if _, err := CreateCertificateRequest(rand.Reader, template, key); err == nil {
+			t.Errorf("CreateCertificateRequest succeeded with a %v attribute in RequestAttributes", oid)
+		}
+	}
+}
+
+// ctxSigner is a crypto.SignerCtx that records the context it is passed.
+type ctxSigner struct {
+	crypto.Signer
+	ctx context.Context
+}
+
+func (s *ctxSigner) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.ctx = ctx
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestCreateContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, true)
+	template := &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "context"},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		SubjectKeyId:          []byte{1, 2, 3},
+	}
+	create := map[string]func(context.Context, crypto.Signer) error{
+		"Certificate": func(ctx context.Context, priv crypto.Signer) error {
+			_, err := CreateCertificateContext(ctx, rand.Reader, template, template, priv.Public(), priv)
+			return err
+		},
+		"CertificateRequest": func(ctx context.Context, priv crypto.Signer) error {
+			_, err := CreateCertificateRequestContext(ctx, rand.Reader, &CertificateRequest{Subject: template.Subject}, priv)
+			return err
+		},
+		"RevocationList": func(ctx context.Context, priv crypto.Signer) error {
+			_, err := CreateRevocationListContext(ctx, rand.Reader, &RevocationList{
+				Number:     big.NewInt(1),
+				ThisUpdate: time.Unix(1000, 0),
+				NextUpdate: time.Unix(2000, 0),
+			}, template, priv)
+			return err
+		},
+	}
+	for name, f := range create {
+		t.Run(name, func(t *testing.T) {
+			s := &ctxSigner{Signer: testPrivateKey}
+			if err := f(ctx, s); err != nil {
+				t.Fatal(err)
+			}
+			if s.ctx == nil || s.ctx.Value(ctxKey{}) == nil {
+				t.Errorf("SignContext was not passed the caller's context")
+			}
+
+			// A plain Signer is not called once the context is done.
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			if err := f(canceled, &brokenSigner{testPrivateKey.Public()}); !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+// messageSigner is a crypto.MessageSigner that hashes the message itself.
+type messageSigner struct {
+	crypto.Signer
+	msg []byte
+}
+
+func (s *messageSigner) SignMessage(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.msg = msg
+	return crypto.SignMessage(s.Signer, rand, msg, opts)
+}
+
+func (s *messageSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("Sign called on a MessageSigner")
+}
+
+func TestCreateCertificateMessageSigner(t *testing.T) {
+	template := &Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "message"},
+		NotBefore:    time.Unix(1000, 0),
+		NotAfter:     time.Unix(100000, 0),
+	}
+	s := &messageSigner{Signer: testPrivateKey}
+	der, err := CreateCertificate(rand.Reader, template, template, testPrivateKey.Public(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.msg, cert.RawTBSCertificate) {
+		t.Errorf("SignMessage was not passed the TBSCertificate")
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		t.Error(err)
+	}
+}
@@ -466,6 +466,21 @@ func parseIA5String(bytes []byte) (ret string, err error) {
 	return
 }
 
+// VisibleString
+
+// parseVisibleString parses an ASN.1 VisibleString (printable ASCII and
+// space) from the given byte slice and returns it.
+func parseVisibleString(bytes []byte) (ret string, err error) {
+	for _, b := range bytes {
+		if b < ' ' || b > '~' {
+			err = SyntaxError{"VisibleString contains invalid character"}
+			return
+		}
+	}
+	ret = string(bytes)
+	return
+}
+
 // T61String
 
 // parseT61String parses an ASN.1 T61String (8-bit clean string) from the given
@@ -508,6 +523,26 @@ func parseBMPString(bmpString []byte) (string, error) {
 	return string(utf16.Decode(s)), nil
 }
 
+// UniversalString
+
+// parseUniversalString parses an ASN.1 UniversalString (UCS-4 encoded
+// ISO/IEC/ITU 10646-1) from the given byte slice and returns it.
+func parseUniversalString(universalString []byte) (string, error) {
+	if len(universalString)%4 != 0 {
+		return "", SyntaxError{"UniversalString length is not a multiple of four"}
+	}
+
+	b := make([]byte, 0, len(universalString)/4)
+	for ; len(universalString) > 0; universalString = universalString[4:] {
+		r := rune(universalString[0])<<24 | rune(universalString[1])<<16 | rune(universalString[2])<<8 | rune(universalString[3])
+		if !utf8.ValidRune(r) {
+			return "", SyntaxError{"UniversalString contains invalid character"}
+		}
+		b = utf8.AppendRune(b, r)
+	}
+	return string(b), nil
+}
+
 // A RawValue represents an undecoded ASN.1 object.
 type RawValue struct {
 	Class, Tag int
@@ -622,7 +657,8 @@ func parseSequenceOf(bytes []byte, sliceType reflect.Type, elemType reflect.Type
 			return
 		}
 		switch t.tag {
-		case TagIA5String, TagGeneralString, TagT61String, TagUTF8String, TagNumericString, TagBMPString:
+		case TagIA5String, TagGeneralString, TagT61String, TagUTF8String, TagNumericString,
+			TagBMPString, TagVisibleString, TagUniversalString:
 			// We pretend that various other string types are
 			// PRINTABLE STRINGs so that a sequence of them can be
 			// parsed into a []string.
@@ -726,6 +762,10 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 				result = innerBytes
 			case TagBMPString:
 				result, err = parseBMPString(innerBytes)
+			case TagVisibleString:
+				result, err = parseVisibleString(innerBytes)
+			case TagUniversalString:
+				result, err = parseUniversalString(innerBytes)
 			default:
 				// If we don't know how to handle the type, we just leave Value as nil.
 			}
@@ -794,7 +834,8 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 	if universalTag == TagPrintableString {
 		if t.class == ClassUniversal {
 			switch t.tag {
-			case TagIA5String, TagGeneralString, TagT61String, TagUTF8String, TagNumericString, TagBMPString:
+			case TagIA5String, TagGeneralString, TagT61String, TagUTF8String, TagNumericString,
+				TagBMPString, TagVisibleString, TagUniversalString:
 				universalTag = t.tag
 			}
 		} else if params.stringType != 0 {
@@ -978,7 +1019,10 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 			v, err = parseT61String(innerBytes)
 		case TagBMPString:
 			v, err = parseBMPString(innerBytes)
-
+		case TagVisibleString:
+			v, err = parseVisibleString(innerBytes)
+		case TagUniversalString:
+			v, err = parseUniversalString(innerBytes)
 		default:
 			err = SyntaxError{fmt.Sprintf("internal error: unknown string type %d", universalTag)}
 		}
@@ -1046,8 +1090,12 @@ func setDefaultValue(v reflect.Value, params fieldParameters) (ok bool) {
 // An ASN.1 ENUMERATED can be written to an Enumerated.
 //
 // An ASN.1 UTCTIME or GENERALIZEDTIME can be written to a time.Time.
+// A GENERALIZEDTIME may have fractional seconds, without trailing zeroes
+// as DER requires.
 //
-// An ASN.1 PrintableString, IA5String, or NumericString can be written to a string.
+// An ASN.1 PrintableString, IA5String, NumericString, UTF8String,
+// T61String, GeneralString, VisibleString, BMPString, or UniversalString
+// can be written to a string.
 //
 // Any of the above ASN.1 values can be written to an interface{}.
 // The value stored in the interface has the corresponding Go type.
@@ -1068,16 +1116,20 @@ func setDefaultValue(v reflect.Value, params fieldParameters) (ok bool) {
 //	explicit    specifies that an additional, explicit tag wraps the implicit one
 //	optional    marks the field as ASN.1 OPTIONAL
 //	set         causes a SET, rather than a SEQUENCE type to be expected
-//	tag:x       specifies the ASN.1 tag number; implies ASN.1 CONTEXT SPECIFIC
+//	tag:x       specifies the ASN.1 tag number; implies ASN.1 CONTEXT SPECIFIC.
+//	            Tag numbers of 31 and above use the multi-byte form.
 //
 // When decoding an ASN.1 value with an IMPLICIT tag into a string field,
 // Unmarshal will default to a PrintableString, which doesn't support
 // characters such as '@' and '&'. To force other encodings, use the following
 // tags:
 //
-//	ia5     causes strings to be unmarshaled as ASN.1 IA5String values
-//	numeric causes strings to be unmarshaled as ASN.1 NumericString values
-//	utf8    causes strings to be unmarshaled as ASN.1 UTF8String values
+//	ia5       causes strings to be unmarshaled as ASN.1 IA5String values
+//	numeric   causes strings to be unmarshaled as ASN.1 NumericString values
+//	utf8      causes strings to be unmarshaled as ASN.1 UTF8String values
+//	visible   causes strings to be unmarshaled as ASN.1 VisibleString values
+//	bmp       causes strings to be unmarshaled as ASN.1 BMPString values
+//	universal causes strings to be unmarshaled as ASN.1 UniversalString values
 //
 // If the type of the first field of a structure is RawContent then the raw
 // ASN1 contents of the struct will be stored in it.
@@ -325,6 +325,9 @@ var generalizedTimeTestData = []timeTest{
 	{"20100102030405", false, time.Time{}},
 	{"20100102030405.123456Z", true, time.Date(2010, 01, 02, 03, 04, 05, 123456e3, time.UTC)},
 	{"20100102030405.123456", false, time.Time{}},
+	{"20100102030405.5+0100", true, time.Date(2010, 01, 02, 03, 04, 05, 5e8, time.FixedZone("", 60*60))},
+	{"20100102030405.50Z", false, time.Time{}}, // trailing zero is not DER
+	{"20100102030405,5Z", false, time.Time{}},
 	{"20100102030405.Z", false, time.Time{}},
 	{"20100102030405.", false, time.Time{}},
 	{"20100102030405+0607", true, time.Date(2010, 01, 02, 03, 04, 05, 0, time.FixedZone("", 6*60*60+7*60))},
@@ -506,6 +509,14 @@ var unmarshalTestData = []struct {
 	{[]byte{0x30, 0x05, 0x02, 0x03, 0x12, 0x34, 0x56}, &TestBigInt{big.NewInt(0x123456)}},
 	{[]byte{0x30, 0x0b, 0x31, 0x09, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x03}, &TestSet{Ints: []int{1, 2, 3}}},
 	{[]byte{0x12, 0x0b, '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', ' '}, newString("0123456789 ")},
+	{[]byte{0x1a, 0x04, 't', 'e', 's', 't'}, newString("test")},
+	{[]byte{0x1c, 0x08, 0, 0, 0, 'a', 0, 1, 0xf6, 0}, newString("a\U0001F600")},
+	{[]byte{0x30, 0x04, 0x80, 0x02, 0x00, 'a'}, &implicitBMPStringTest{"a"}},
+	{[]byte{0x30, 0x05, 0x9f, 0x87, 0x68, 0x01, 0x01}, &TestLargeTag{1}},
+}
+
+type TestLargeTag struct {
+	A int `asn1:"tag:1000"`
 }
 
 func TestUnmarshal(t *testing.T) {
@@ -1157,6 +1168,21 @@ func TestBMPString(t *testing.T) {
 	}
 }
 
+func TestBadStringTypes(t *testing.T) {
+	for _, in := range [][]byte{
+		{0x1a, 0x01, '\n'},          // control character in VisibleString
+		{0x1a, 0x01, 0x80},          // non-ASCII VisibleString
+		{0x1c, 0x03, 0, 0, 'a'},     // truncated UniversalString
+		{0x1c, 0x04, 0, 0x11, 0, 0}, // UniversalString beyond Unicode
+		{0x1c, 0x04, 0, 0, 0xd8, 0}, // surrogate in UniversalString
+	} {
+		var s string
+		if _, err := Unmarshal(in, &s); err == nil {
+			t.Errorf("Unmarshal(%x) succeeded with %q", in, s)
+		}
+	}
+}
+
 func TestNonMinimalEncodedOID(t *testing.T) {
 	h, err := hex.DecodeString("060a2a80864886f70d01010b")
 	if err != nil {
@@ -36,7 +36,9 @@ const (
 	TagIA5String       = 22
 	TagUTCTime         = 23
 	TagGeneralizedTime = 24
+	TagVisibleString   = 26
 	TagGeneralString   = 27
+	TagUniversalString = 28
 	TagBMPString       = 30
 )
 
@@ -115,6 +117,12 @@ func parseFieldParameters(str string) (ret fieldParameters) {
 			ret.stringType = TagNumericString
 		case part == "utf8":
 			ret.stringType = TagUTF8String
+		case part == "visible":
+			ret.stringType = TagVisibleString
+		case part == "universal":
+			ret.stringType = TagUniversalString
+		case part == "bmp":
+			ret.stringType = TagBMPString
 		case strings.HasPrefix(part, "default:"):
 			i, err := strconv.ParseInt(part[8:], 10, 64)
 			if err == nil {
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"sort"
 	"time"
+	"unicode/utf16"
 	"unicode/utf8"
 )
 
@@ -350,6 +351,43 @@ func makeUTF8String(s string) encoder {
 	return stringEncoder(s)
 }
 
+func makeVisibleString(s string) (e encoder, err error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return nil, StructuralError{"VisibleString contains invalid character"}
+		}
+	}
+
+	return stringEncoder(s), nil
+}
+
+func makeBMPString(s string) (e encoder, err error) {
+	if !utf8.ValidString(s) {
+		return nil, StructuralError{"BMPString is not valid UTF-8"}
+	}
+	b := make([]byte, 0, 2*len(s))
+	for _, r := range s {
+		if r > 0xffff || utf16.IsSurrogate(r) {
+			return nil, StructuralError{"BMPString contains character outside the Basic Multilingual Plane"}
+		}
+		b = append(b, byte(r>>8), byte(r))
+	}
+
+	return bytesEncoder(b), nil
+}
+
+func makeUniversalString(s string) (e encoder, err error) {
+	if !utf8.ValidString(s) {
+		return nil, StructuralError{"UniversalString is not valid UTF-8"}
+	}
+	b := make([]byte, 0, 4*len(s))
+	for _, r := range s {
+		b = append(b, byte(r>>24), byte(r>>16), byte(r>>8), byte(r))
+	}
+
+	return bytesEncoder(b), nil
+}
+
 func appendTwoDigits(dst []byte, v int) []byte {
 	return append(dst, byte('0'+(v/10)%10), byte('0'+v%10))
 }
@@ -568,6 +606,12 @@ func makeBody(value reflect.Value, params fieldParameters) (e encoder, err error
 			return makePrintableString(v.String())
 		case TagNumericString:
 			return makeNumericString(v.String())
+		case TagVisibleString:
+			return makeVisibleString(v.String())
+		case TagBMPString:
+			return makeBMPString(v.String())
+		case TagUniversalString:
+			return makeUniversalString(v.String())
 		default:
 			return makeUTF8String(v.String()), nil
 		}
@@ -728,8 +772,13 @@ func makeField(v reflect.Value, params fieldParameters) (e encoder, err error) {
 //	omitempty:   causes empty slices to be skipped
 //	printable:   causes strings to be marshaled as ASN.1, PrintableString values
 //	utf8:        causes strings to be marshaled as ASN.1, UTF8String values
+//	visible:     causes strings to be marshaled as ASN.1, VisibleString values
+//	bmp:         causes strings to be marshaled as ASN.1, BMPString values
+//	universal:   causes strings to be marshaled as ASN.1, UniversalString values
 //	utc:         causes time.Time to be marshaled as ASN.1, UTCTime values
 //	generalized: causes time.Time to be marshaled as ASN.1, GeneralizedTime values
+//
+// Times are marshaled with whole seconds.
 func Marshal(val any) ([]byte, error) {
 	return MarshalWithParams(val, "")
 }
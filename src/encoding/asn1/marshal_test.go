@@ -92,6 +92,22 @@ type numericStringTest struct {
 	A string `asn1:"numeric"`
 }
 
+type visibleStringTest struct {
+	A string `asn1:"visible"`
+}
+
+type bmpStringTest struct {
+	A string `asn1:"bmp"`
+}
+
+type universalStringTest struct {
+	A string `asn1:"universal"`
+}
+
+type implicitBMPStringTest struct {
+	A string `asn1:"tag:0,bmp"`
+}
+
 type testSET []int
 
 var PST = time.FixedZone("PST", -8*60*60)
@@ -179,6 +195,10 @@ var marshalTests = []marshalTest{
 	{applicationTest{1, 2}, "30084001016103020102"},
 	{privateTest{1, 2, 3, 4}, "3011c00101e103020102df1f0103df81000104"},
 	{numericStringTest{"1 9"}, "30051203312039"},
+	{visibleStringTest{"a b"}, "30051a03612062"},
+	{bmpStringTest{"a\u2115"}, "30061e0400612115"},
+	{universalStringTest{"a\U0001F600"}, "300a1c08000000610001f600"},
+	{implicitBMPStringTest{"a"}, "300480020061"},
 }
 
 func TestMarshal(t *testing.T) {
@@ -231,6 +251,9 @@ var marshalErrTests = []marshalErrTest{
 	{numericStringTest{"a"}, "invalid character"},
 	{ia5StringTest{"\xb0"}, "invalid character"},
 	{printableStringTest{"!"}, "invalid character"},
+	{visibleStringTest{"\n"}, "invalid character"},
+	{bmpStringTest{"\U0001F600"}, "outside the Basic Multilingual Plane"},
+	{universalStringTest{"\xff"}, "not valid UTF-8"},
 }
 
 func TestMarshalError(t *testing.T) {
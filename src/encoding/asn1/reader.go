@@ -0,0 +1,436 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asn1
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+)
+
+// maxReaderDepth is the maximum nesting of constructed elements accepted by a
+// Reader. It bounds the memory used by the Reader on hostile input.
+const maxReaderDepth = 256
+
+// A Token is returned by Reader.Next. It describes either the start of an
+// ASN.1 element or, if End is set, the end of a constructed element.
+type Token struct {
+	Class      int
+	Tag        int
+	IsCompound bool
+
+	// End is set for the token that closes the most recently started
+	// constructed element that is still open. The other fields of an end
+	// token, except Offset and Depth, are zero.
+	End bool
+
+	// Length is the length of the element's contents, or -1 if the element
+	// is constructed and uses the BER indefinite-length form.
+	Length int64
+
+	// Offset is the position in the input of the first byte of the element's
+	// identifier octets, or of the end-of-contents octets or the byte after
+	// the element for an end token.
+	Offset int64
+
+	// Depth is the number of enclosing constructed elements. An end token
+	// has the same Depth as the token that started the element.
+	Depth int
+}
+
+// frame is a constructed element opened by a Reader.
+type frame struct {
+	// end is the offset of the byte after the element's contents, or -1
+	// for an indefinite-length element.
+	end int64
+	// limit is the end of the innermost definite-length element enclosing
+	// this one, including itself, or -1 if there is none.
+	limit int64
+}
+
+// A Reader reads ASN.1 elements from an io.Reader as a stream of tokens,
+// without holding more than one element header in memory. This allows
+// processing very large structures, such as CRLs with millions of entries or
+// CMS messages with large encapsulated content, with bounded memory.
+//
+// The input must be DER, except that constructed elements may also use the
+// indefinite-length form of BER, as produced by streaming encoders.
+//
+// Each call to Next returns a token for the start of the next element. For
+// primitive elements, the contents may then be read by calling Read; any
+// contents not read are skipped by the next call to Next. Constructed
+// elements are followed by the tokens for their children and then an end
+// token.
+type Reader struct {
+	r         *bufio.Reader
+	offset    int64
+	stack     []frame
+	remaining int64 // unread contents of the current primitive element
+	err       error
+}
+
+// NewReader returns a Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// InputOffset returns the current position in the input.
+func (r *Reader) InputOffset() int64 {
+	return r.offset
+}
+
+// Depth returns the number of constructed elements that have been started
+// but not yet ended.
+func (r *Reader) Depth() int {
+	return len(r.stack)
+}
+
+// Next returns the next token in the input. At the end of the input, outside
+// of any constructed element, it returns io.EOF. A truncated input results in
+// io.ErrUnexpectedEOF.
+func (r *Reader) Next() (Token, error) {
+	if r.err != nil {
+		return Token{}, r.err
+	}
+	if err := r.discard(); err != nil {
+		return Token{}, err
+	}
+	if tok, ok, err := r.end(); err != nil || ok {
+		return tok, err
+	}
+	if len(r.stack) == 0 {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			return Token{}, io.EOF
+		}
+	}
+
+	start := r.offset
+	tok, _, err := r.readHeader(nil)
+	if err != nil {
+		return Token{}, r.fail(err)
+	}
+	tok.Offset = start
+	tok.Depth = len(r.stack)
+	if !tok.IsCompound {
+		r.remaining = tok.Length
+		return tok, nil
+	}
+	if len(r.stack) >= maxReaderDepth {
+		return Token{}, r.fail(StructuralError{"nesting too deep"})
+	}
+	f := frame{end: -1, limit: r.limit()}
+	if tok.Length >= 0 {
+		f.end = r.offset + tok.Length
+		f.limit = f.end
+	}
+	r.stack = append(r.stack, f)
+	return tok, nil
+}
+
+// Read reads the contents of the primitive element started by the most
+// recent token returned by Next. It returns io.EOF once all the contents have
+// been read, and immediately if the most recent token was not the start of a
+// primitive element.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.r.Read(p)
+	r.offset += int64(n)
+	r.remaining -= int64(n)
+	if err == io.EOF {
+		if r.remaining == 0 {
+			err = nil
+		} else {
+			err = r.fail(io.ErrUnexpectedEOF)
+		}
+	} else if err != nil {
+		r.fail(err)
+	}
+	return n, err
+}
+
+// More reports whether there is another element in the current constructed
+// element, or in the input if no constructed element is open.
+func (r *Reader) More() bool {
+	if r.err != nil || r.discard() != nil {
+		return false
+	}
+	if len(r.stack) == 0 {
+		_, err := r.r.Peek(1)
+		return err == nil
+	}
+	f := r.stack[len(r.stack)-1]
+	if f.end >= 0 {
+		return r.offset < f.end
+	}
+	b, err := r.r.Peek(2)
+	return err == nil && (b[0] != 0 || b[1] != 0)
+}
+
+// Skip skips the rest of the most recently started constructed element that
+// is still open, including its end token. If no constructed element is open,
+// Skip only skips the unread contents of the current primitive element.
+func (r *Reader) Skip() error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.discard(); err != nil {
+		return err
+	}
+	depth := len(r.stack)
+	if depth == 0 {
+		return nil
+	}
+	if f := r.stack[depth-1]; f.end >= 0 {
+		if err := r.skipN(f.end - r.offset); err != nil {
+			return err
+		}
+		r.stack = r.stack[:depth-1]
+		return nil
+	}
+	for {
+		tok, err := r.Next()
+		if err == io.EOF {
+			err = r.fail(io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return err
+		}
+		if tok.End && len(r.stack) < depth {
+			return nil
+		}
+	}
+}
+
+// ReadElement reads the next element in full and returns it as a RawValue,
+// without returning any tokens for it. This is useful to decode individual
+// elements of a large structure with Unmarshal. The element must use the
+// definite-length form and its contents may not be longer than maxLen bytes.
+//
+// At the end of the input, outside of any constructed element, ReadElement
+// returns io.EOF. It is an error to call ReadElement at the end of a
+// constructed element; use More to check for that.
+func (r *Reader) ReadElement(maxLen int) (RawValue, error) {
+	if r.err != nil {
+		return RawValue{}, r.err
+	}
+	if !r.More() {
+		if r.err != nil {
+			return RawValue{}, r.err
+		}
+		if len(r.stack) == 0 {
+			return RawValue{}, io.EOF
+		}
+		return RawValue{}, errors.New("asn1: no more elements in constructed value")
+	}
+
+	tok, header, err := r.readHeader(make([]byte, 0, 16))
+	if err != nil {
+		return RawValue{}, r.fail(err)
+	}
+	if tok.Length < 0 {
+		return RawValue{}, r.fail(StructuralError{"indefinite length element cannot be read as a RawValue"})
+	}
+	if tok.Length > int64(maxLen) {
+		return RawValue{}, r.fail(StructuralError{"element too large"})
+	}
+	full := make([]byte, len(header)+int(tok.Length))
+	copy(full, header)
+	n, err := io.ReadFull(r.r, full[len(header):])
+	r.offset += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return RawValue{}, r.fail(err)
+	}
+	return RawValue{
+		Class:      tok.Class,
+		Tag:        tok.Tag,
+		IsCompound: tok.IsCompound,
+		Bytes:      full[len(header):],
+		FullBytes:  full,
+	}, nil
+}
+
+// end checks whether the innermost open constructed element ends at the
+// current position and, if so, consumes its end-of-contents octets, if any,
+// and returns its end token.
+func (r *Reader) end() (tok Token, ok bool, err error) {
+	if len(r.stack) == 0 {
+		return Token{}, false, nil
+	}
+	f := r.stack[len(r.stack)-1]
+	start := r.offset
+	if f.end >= 0 {
+		if r.offset != f.end {
+			return Token{}, false, nil
+		}
+	} else {
+		b, err := r.r.Peek(2)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Token{}, false, r.fail(err)
+		}
+		if b[0] != 0 || b[1] != 0 {
+			if f.limit >= 0 && r.offset >= f.limit {
+				return Token{}, false, r.fail(SyntaxError{"missing end-of-contents octets"})
+			}
+			return Token{}, false, nil
+		}
+		if f.limit >= 0 && r.offset+2 > f.limit {
+			return Token{}, false, r.fail(SyntaxError{"data truncated"})
+		}
+		r.r.Discard(2)
+		r.offset += 2
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+	return Token{End: true, Offset: start, Depth: len(r.stack)}, true, nil
+}
+
+// readHeader reads the identifier and length octets of an element, appending
+// them to header, and checks that the element fits in its enclosing elements.
+func (r *Reader) readHeader(header []byte) (tok Token, _ []byte, err error) {
+	limit := r.limit()
+	readByte := func() (byte, error) {
+		if limit >= 0 && r.offset >= limit {
+			return 0, SyntaxError{"data truncated"}
+		}
+		b, err := r.r.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.offset++
+		header = append(header, b)
+		return b, nil
+	}
+
+	b, err := readByte()
+	if err != nil {
+		return Token{}, nil, err
+	}
+	tok.Class = int(b >> 6)
+	tok.IsCompound = b&0x20 == 0x20
+	tok.Tag = int(b & 0x1f)
+	if tok.Tag == 0x1f {
+		// The tag number is base 128 encoded in the following octets.
+		var tag int64
+		for shifted := 0; ; shifted++ {
+			if shifted == 5 {
+				return Token{}, nil, StructuralError{"base 128 integer too large"}
+			}
+			if b, err = readByte(); err != nil {
+				return Token{}, nil, err
+			}
+			if shifted == 0 && b == 0x80 {
+				return Token{}, nil, SyntaxError{"integer is not minimally encoded"}
+			}
+			tag = tag<<7 | int64(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		if tag > math.MaxInt32 {
+			return Token{}, nil, StructuralError{"base 128 integer too large"}
+		}
+		if tag < 0x1f {
+			return Token{}, nil, SyntaxError{"non-minimal tag"}
+		}
+		tok.Tag = int(tag)
+	}
+
+	if b, err = readByte(); err != nil {
+		return Token{}, nil, err
+	}
+	if tok.Class == ClassUniversal && tok.Tag == 0 && !tok.IsCompound && b == 0 {
+		return Token{}, nil, SyntaxError{"unexpected end-of-contents octets"}
+	}
+	if b&0x80 == 0 {
+		tok.Length = int64(b)
+	} else if numBytes := int(b & 0x7f); numBytes == 0 {
+		if !tok.IsCompound {
+			return Token{}, nil, SyntaxError{"indefinite length primitive element"}
+		}
+		tok.Length = -1
+	} else {
+		for i := 0; i < numBytes; i++ {
+			if b, err = readByte(); err != nil {
+				return Token{}, nil, err
+			}
+			if tok.Length >= 1<<55 {
+				return Token{}, nil, StructuralError{"length too large"}
+			}
+			tok.Length = tok.Length<<8 | int64(b)
+			if tok.Length == 0 {
+				return Token{}, nil, StructuralError{"superfluous leading zeros in length"}
+			}
+		}
+		if tok.Length < 0x80 {
+			return Token{}, nil, StructuralError{"non-minimal length"}
+		}
+	}
+	if limit >= 0 && tok.Length > limit-r.offset {
+		return Token{}, nil, SyntaxError{"data truncated"}
+	}
+	return tok, header, nil
+}
+
+// limit returns the end of the innermost open definite-length element, or -1
+// if there is none.
+func (r *Reader) limit() int64 {
+	if len(r.stack) == 0 {
+		return -1
+	}
+	return r.stack[len(r.stack)-1].limit
+}
+
+// discard skips the unread contents of the current primitive element.
+func (r *Reader) discard() error {
+	if r.remaining == 0 {
+		return nil
+	}
+	n := r.remaining
+	r.remaining = 0
+	return r.skipN(n)
+}
+
+// skipN skips n bytes of input.
+func (r *Reader) skipN(n int64) error {
+	for n > 0 {
+		m := n
+		if m > math.MaxInt32 {
+			m = math.MaxInt32
+		}
+		d, err := r.r.Discard(int(m))
+		r.offset += int64(d)
+		n -= int64(d)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return r.fail(err)
+		}
+	}
+	return nil
+}
+
+// fail records err, so that all subsequent calls return it.
+func (r *Reader) fail(err error) error {
+	r.err = err
+	return err
+}
@@ -0,0 +1,296 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asn1
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func readAllTokens(t *testing.T, r *Reader) []Token {
+	t.Helper()
+	var toks []Token
+	for {
+		tok, err := r.Next()
+		if err == io.EOF {
+			return toks
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		toks = append(toks, tok)
+	}
+}
+
+func TestReaderTokens(t *testing.T) {
+	type inner struct {
+		A int
+		B []byte
+	}
+	type outer struct {
+		I inner
+		S string `asn1:"tag:100,explicit"`
+	}
+	der, err := Marshal(outer{inner{5, []byte{1, 2, 3}}, "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 30 13
+	//    30 08 02 01 05 04 03 01 02 03
+	//    bf 64 04 13 02 68 69
+	want := []Token{
+		{Tag: TagSequence, IsCompound: true, Length: 17, Offset: 0, Depth: 0},
+		{Tag: TagSequence, IsCompound: true, Length: 8, Offset: 2, Depth: 1},
+		{Tag: TagInteger, Length: 1, Offset: 4, Depth: 2},
+		{Tag: TagOctetString, Length: 3, Offset: 7, Depth: 2},
+		{End: true, Offset: 12, Depth: 1},
+		{Class: ClassContextSpecific, Tag: 100, IsCompound: true, Length: 4, Offset: 12, Depth: 1},
+		{Tag: TagPrintableString, Length: 2, Offset: 15, Depth: 2},
+		{End: true, Offset: 19, Depth: 1},
+		{End: true, Offset: 19, Depth: 0},
+	}
+	got := readAllTokens(t, NewReader(bytes.NewReader(der)))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got tokens\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReaderRead(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	der, err := Marshal(struct {
+		A []byte
+		B []byte
+	}{content, []byte("skipped")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(iotest.OneByteReader(bytes.NewReader(der)))
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Errorf("Read on constructed element = %d, %v; want 0, io.EOF", n, err)
+	}
+	tok, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Length != int64(len(content)) {
+		t.Fatalf("Length = %d, want %d", tok.Length, len(content))
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("contents read do not match")
+	}
+	// The contents of the second element are skipped by Next.
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	tok, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tok.End || tok.Offset != int64(len(der)) {
+		t.Errorf("got %+v, want end token at %d", tok, len(der))
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next at end of input = %v, want io.EOF", err)
+	}
+}
+
+func TestReaderIndefiniteLength(t *testing.T) {
+	ber := []byte{
+		0x30, 0x80, // SEQUENCE, indefinite length
+		0x02, 0x01, 0x05, // INTEGER 5
+		0x24, 0x80, // constructed OCTET STRING, indefinite length
+		0x04, 0x02, 0xaa, 0xbb,
+		0x04, 0x01, 0xcc,
+		0x00, 0x00,
+		0x00, 0x00,
+	}
+	want := []Token{
+		{Tag: TagSequence, IsCompound: true, Length: -1, Offset: 0, Depth: 0},
+		{Tag: TagInteger, Length: 1, Offset: 2, Depth: 1},
+		{Tag: TagOctetString, IsCompound: true, Length: -1, Offset: 5, Depth: 1},
+		{Tag: TagOctetString, Length: 2, Offset: 7, Depth: 2},
+		{Tag: TagOctetString, Length: 1, Offset: 11, Depth: 2},
+		{End: true, Offset: 14, Depth: 1},
+		{End: true, Offset: 16, Depth: 0},
+	}
+	got := readAllTokens(t, NewReader(bytes.NewReader(ber)))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got tokens\n%+v\nwant\n%+v", got, want)
+	}
+
+	r := NewReader(bytes.NewReader(ber))
+	r.Next()
+	r.Next()
+	if tok, _ := r.Next(); !tok.IsCompound {
+		t.Fatalf("got %+v, want constructed OCTET STRING", tok)
+	}
+	if err := r.Skip(); err != nil {
+		t.Fatalf("Skip: %v", err)
+	}
+	if tok, err := r.Next(); err != nil || !tok.End || tok.Depth != 0 {
+		t.Errorf("Next after Skip = %+v, %v; want end of SEQUENCE", tok, err)
+	}
+}
+
+func TestReaderSkipAndMore(t *testing.T) {
+	der, err := Marshal(struct {
+		A []int
+		B int
+	}{[]int{1, 2, 3}, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(bytes.NewReader(der))
+	r.Next()
+	r.Next()
+	if !r.More() {
+		t.Fatal("More = false at start of SEQUENCE OF")
+	}
+	if err := r.Skip(); err != nil {
+		t.Fatalf("Skip: %v", err)
+	}
+	if r.Depth() != 1 {
+		t.Errorf("Depth after Skip = %d, want 1", r.Depth())
+	}
+	if !r.More() {
+		t.Fatal("More = false before last element")
+	}
+	v, err := r.ReadElement(10)
+	if err != nil {
+		t.Fatalf("ReadElement: %v", err)
+	}
+	var b int
+	if _, err := Unmarshal(v.FullBytes, &b); err != nil || b != 4 {
+		t.Errorf("Unmarshal of ReadElement result = %d, %v; want 4", b, err)
+	}
+	if r.More() {
+		t.Error("More = true at end of SEQUENCE")
+	}
+	if _, err := r.ReadElement(10); err == nil {
+		t.Error("ReadElement at end of SEQUENCE succeeded")
+	}
+}
+
+// sequenceOfReader generates a DER SEQUENCE OF n INTEGERs without holding it
+// in memory.
+type sequenceOfReader struct {
+	header []byte
+	i, n   int
+	buf    []byte
+}
+
+func newSequenceOfReader(n int) *sequenceOfReader {
+	// Each element is an INTEGER with a three byte value, and the total
+	// length must fit in three bytes.
+	length := n * 5
+	return &sequenceOfReader{
+		header: []byte{0x30, 0x83, byte(length >> 16), byte(length >> 8), byte(length)},
+		n:      n,
+	}
+}
+
+func (s *sequenceOfReader) Read(p []byte) (int, error) {
+	if len(s.header) > 0 {
+		n := copy(p, s.header)
+		s.header = s.header[n:]
+		return n, nil
+	}
+	if len(s.buf) == 0 {
+		if s.i == s.n {
+			return 0, io.EOF
+		}
+		v := s.i + 0x100000
+		s.buf = []byte{0x02, 0x03, byte(v >> 16), byte(v >> 8), byte(v)}
+		s.i++
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func TestReaderLargeSequence(t *testing.T) {
+	const n = 100000
+	r := NewReader(newSequenceOfReader(n))
+	if tok, err := r.Next(); err != nil || tok.Length != n*5 {
+		t.Fatalf("Next = %+v, %v", tok, err)
+	}
+	i := 0
+	for r.More() {
+		v, err := r.ReadElement(16)
+		if err != nil {
+			t.Fatalf("ReadElement: %v", err)
+		}
+		var got int
+		if _, err := Unmarshal(v.FullBytes, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != i+0x100000 {
+			t.Fatalf("element %d = %#x", i, got)
+		}
+		i++
+	}
+	if i != n {
+		t.Errorf("read %d elements, want %d", i, n)
+	}
+	if tok, err := r.Next(); err != nil || !tok.End {
+		t.Errorf("Next = %+v, %v; want end token", tok, err)
+	}
+	if _, err := r.ReadElement(16); err != io.EOF {
+		t.Errorf("ReadElement at end of input = %v, want io.EOF", err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		err  string
+	}{
+		{"truncated header", []byte{0x30}, io.ErrUnexpectedEOF.Error()},
+		{"truncated contents", []byte{0x30, 0x03, 0x02, 0x01}, io.ErrUnexpectedEOF.Error()},
+		{"child too long", []byte{0x30, 0x03, 0x02, 0x02, 0x01, 0x02}, "data truncated"},
+		{"non-minimal length", []byte{0x04, 0x81, 0x01, 0x00}, "non-minimal length"},
+		{"leading zero length", []byte{0x04, 0x82, 0x00, 0x80}, "superfluous leading zeros"},
+		{"non-minimal tag", []byte{0x1f, 0x01, 0x00}, "non-minimal tag"},
+		{"indefinite primitive", []byte{0x04, 0x80, 0x00, 0x00}, "indefinite length primitive"},
+		{"unexpected end-of-contents", []byte{0x30, 0x02, 0x00, 0x00}, "unexpected end-of-contents"},
+		{"missing end-of-contents", []byte{0x30, 0x05, 0x30, 0x80, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00}, "missing end-of-contents"},
+		{"end-of-contents outside parent", []byte{0x30, 0x05, 0x30, 0x80, 0x02, 0x01, 0x00, 0x00, 0x00}, "data truncated"},
+		{"unterminated indefinite", []byte{0x30, 0x80, 0x02, 0x01, 0x00}, io.ErrUnexpectedEOF.Error()},
+		{"too deep", bytes.Repeat([]byte{0x30, 0x80}, maxReaderDepth+1), "nesting too deep"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(test.in))
+			var err error
+			for err == nil {
+				_, err = r.Next()
+			}
+			if err == io.EOF || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+			// Errors are sticky.
+			if _, err2 := r.Next(); err2 != err {
+				t.Errorf("second Next returned %v, want %v", err2, err)
+			}
+		})
+	}
+
+	r := NewReader(bytes.NewReader([]byte{0x04, 0x05, 1, 2, 3, 4, 5}))
+	if _, err := r.ReadElement(4); err == nil {
+		t.Error("ReadElement of element longer than maxLen succeeded")
+	}
+}
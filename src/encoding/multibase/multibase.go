@@ -0,0 +1,190 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package multibase implements the multibase self-describing encoding,
+// which prefixes base-encoded data with a character identifying the
+// base, as specified at https://github.com/multiformats/multibase.
+//
+// Multibase is used with multihash digests and CIDs by content-addressed
+// systems. Only the common bases are supported.
+package multibase
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// A Base is a multibase encoding, identified by its prefix character.
+type Base byte
+
+const (
+	Base16       Base = 'f' // lowercase hexadecimal
+	Base16Upper  Base = 'F' // uppercase hexadecimal
+	Base32       Base = 'b' // lowercase RFC 4648 base32, no padding
+	Base32Upper  Base = 'B' // uppercase RFC 4648 base32, no padding
+	Base58BTC    Base = 'z' // Bitcoin base58
+	Base64       Base = 'm' // RFC 4648 base64, no padding
+	Base64Pad    Base = 'M' // RFC 4648 base64, with padding
+	Base64URL    Base = 'u' // RFC 4648 base64url, no padding
+	Base64URLPad Base = 'U' // RFC 4648 base64url, with padding
+)
+
+var (
+	base32Lower = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+	base32Upper = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// String returns the name of b in the multibase table.
+func (b Base) String() string {
+	switch b {
+	case Base16:
+		return "base16"
+	case Base16Upper:
+		return "base16upper"
+	case Base32:
+		return "base32"
+	case Base32Upper:
+		return "base32upper"
+	case Base58BTC:
+		return "base58btc"
+	case Base64:
+		return "base64"
+	case Base64Pad:
+		return "base64pad"
+	case Base64URL:
+		return "base64url"
+	case Base64URLPad:
+		return "base64urlpad"
+	}
+	return "unknown base " + string(rune(b))
+}
+
+// Encode returns the multibase encoding of data in base b.
+func Encode(b Base, data []byte) (string, error) {
+	var s string
+	switch b {
+	case Base16:
+		s = hex.EncodeToString(data)
+	case Base16Upper:
+		s = strings.ToUpper(hex.EncodeToString(data))
+	case Base32:
+		s = base32Lower.EncodeToString(data)
+	case Base32Upper:
+		s = base32Upper.EncodeToString(data)
+	case Base58BTC:
+		s = encodeBase58(data)
+	case Base64:
+		s = base64.RawStdEncoding.EncodeToString(data)
+	case Base64Pad:
+		s = base64.StdEncoding.EncodeToString(data)
+	case Base64URL:
+		s = base64.RawURLEncoding.EncodeToString(data)
+	case Base64URLPad:
+		s = base64.URLEncoding.EncodeToString(data)
+	default:
+		return "", errors.New("multibase: unsupported " + b.String())
+	}
+	return string(rune(b)) + s, nil
+}
+
+// Decode decodes a multibase string. The encoding must be canonical: for
+// example, letters must be in the case of the base, and unused bits must
+// be zero.
+func Decode(s string) (Base, []byte, error) {
+	if s == "" {
+		return 0, nil, errors.New("multibase: empty string")
+	}
+	b := Base(s[0])
+	var data []byte
+	var err error
+	switch b {
+	case Base16, Base16Upper:
+		data, err = hex.DecodeString(s[1:])
+	case Base32:
+		data, err = base32Lower.DecodeString(s[1:])
+	case Base32Upper:
+		data, err = base32Upper.DecodeString(s[1:])
+	case Base58BTC:
+		data, err = decodeBase58(s[1:])
+	case Base64:
+		data, err = base64.RawStdEncoding.DecodeString(s[1:])
+	case Base64Pad:
+		data, err = base64.StdEncoding.DecodeString(s[1:])
+	case Base64URL:
+		data, err = base64.RawURLEncoding.DecodeString(s[1:])
+	case Base64URLPad:
+		data, err = base64.URLEncoding.DecodeString(s[1:])
+	default:
+		return 0, nil, errors.New("multibase: unsupported " + b.String())
+	}
+	if err != nil {
+		return 0, nil, errors.New("multibase: invalid " + b.String() + " data: " + err.Error())
+	}
+	// The decoders accept newlines, and some accept either case or
+	// non-zero trailing bits.
+	if enc, _ := Encode(b, data); enc != s {
+		return 0, nil, errors.New("multibase: non-canonical " + b.String() + " data")
+	}
+	return b, data, nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encodeBase58 encodes data as a big-endian number in base 58, with a '1'
+// for each leading zero byte.
+func encodeBase58(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+	// log(256) / log(58) is less than 1.37.
+	digits := make([]byte, (len(data)-zeros)*137/100+1)
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for j := len(digits) - 1; j >= 0; j-- {
+			carry += int(digits[j]) << 8
+			digits[j] = byte(carry % 58)
+			carry /= 58
+		}
+	}
+	for len(digits) > 0 && digits[0] == 0 {
+		digits = digits[1:]
+	}
+
+	out := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		out[i] = '1'
+	}
+	for i, d := range digits {
+		out[zeros+i] = base58Alphabet[d]
+	}
+	return string(out)
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	// log(58) / log(256) is less than 0.74.
+	bytes := make([]byte, (len(s)-zeros)*74/100+1)
+	for i := zeros; i < len(s); i++ {
+		carry := strings.IndexByte(base58Alphabet, s[i])
+		if carry < 0 {
+			return nil, errors.New("invalid character")
+		}
+		for j := len(bytes) - 1; j >= 0; j-- {
+			carry += int(bytes[j]) * 58
+			bytes[j] = byte(carry)
+			carry >>= 8
+		}
+	}
+	for len(bytes) > 0 && bytes[0] == 0 {
+		bytes = bytes[1:]
+	}
+	return append(make([]byte, zeros, zeros+len(bytes)), bytes...), nil
+}
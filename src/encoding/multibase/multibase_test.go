@@ -0,0 +1,95 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multibase
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Test vectors from the multibase specification.
+var encodeTests = []struct {
+	base Base
+	data string
+	out  string
+}{
+	{Base16, "yes mani !", "f796573206d616e692021"},
+	{Base16Upper, "yes mani !", "F796573206D616E692021"},
+	{Base32, "yes mani !", "bpfsxgidnmfxgsibb"},
+	{Base32Upper, "yes mani !", "BPFSXGIDNMFXGSIBB"},
+	{Base58BTC, "yes mani !", "z7paNL19xttacUY"},
+	{Base64, "yes mani !", "meWVzIG1hbmkgIQ"},
+	{Base64Pad, "yes mani !", "MeWVzIG1hbmkgIQ=="},
+	{Base64URL, "yes mani !", "ueWVzIG1hbmkgIQ"},
+	{Base64URLPad, "yes mani !", "UeWVzIG1hbmkgIQ=="},
+	{Base58BTC, "Hello World!", "z2NEpo7TZRRrLZSi2U"},
+	{Base58BTC, "\x00\x00\x28\x7f\xb4\xcd", "z11233QC4"},
+	{Base58BTC, "\x00", "z1"},
+	{Base58BTC, "", "z"},
+	{Base64URL, "\xfb\xff", "u-_8"},
+	{Base32, "", "b"},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, tt := range encodeTests {
+		got, err := Encode(tt.base, []byte(tt.data))
+		if err != nil {
+			t.Errorf("Encode(%v, %q): %v", tt.base, tt.data, err)
+			continue
+		}
+		if got != tt.out {
+			t.Errorf("Encode(%v, %q) = %q, want %q", tt.base, tt.data, got, tt.out)
+		}
+		b, data, err := Decode(tt.out)
+		if err != nil {
+			t.Errorf("Decode(%q): %v", tt.out, err)
+			continue
+		}
+		if b != tt.base || !bytes.Equal(data, []byte(tt.data)) {
+			t.Errorf("Decode(%q) = %v, %q; want %v, %q", tt.out, b, data, tt.base, tt.data)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"f7",
+		"F796573206d616e692021",
+		"f796573206D616E692021",
+		"bPFSXGIDNMFXGSIBB",
+		"bpf",
+		"bpfsxgidnm\nfxgsibb",
+		"z0OIl",
+		"meWVzIG1hbmkgIQ==",
+		"meWVzIG1hbmkgIR",
+		"meWVzIG1h\nbmkgIQ",
+		"MeWVzIG1hbmkgIQ",
+		"ueWVzIG1hbmkgIQ+",
+		"k2jmj7l5rSw0yVb",
+		"\x00",
+	} {
+		if b, data, err := Decode(s); err == nil {
+			t.Errorf("Decode(%q) = %v, %q; want error", s, b, data)
+		}
+	}
+	if _, err := Encode('k', []byte("x")); err == nil {
+		t.Errorf("Encode with an unsupported base succeeded")
+	}
+}
+
+func TestBase58RoundTrip(t *testing.T) {
+	for n := 0; n < 100; n++ {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i * 37 * n)
+		}
+		s, _ := Encode(Base58BTC, data)
+		_, got, err := Decode(s)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Decode(Encode(%x)) = %x, %v", data, got, err)
+		}
+	}
+}
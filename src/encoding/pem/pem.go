@@ -238,6 +238,15 @@ func writeHeader(out io.Writer, k, v string) error {
 
 // Encode writes the PEM encoding of b to out.
 func Encode(out io.Writer, b *Block) error {
+	w, err := NewEncoder(out, b)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// writeBlockStart writes the BEGIN line and headers of b.
+func writeBlockStart(out io.Writer, b *Block) error {
 	// Check for invalid block before writing any output.
 	for k := range b.Headers {
 		if strings.Contains(k, ":") {
@@ -284,21 +293,15 @@ func Encode(out io.Writer, b *Block) error {
 			return err
 		}
 	}
+	return nil
+}
 
-	var breaker lineBreaker
-	breaker.out = out
-
-	b64 := base64.NewEncoder(base64.StdEncoding, &breaker)
-	if _, err := b64.Write(b.Bytes); err != nil {
-		return err
-	}
-	b64.Close()
-	breaker.Close()
-
+// writeBlockEnd writes the END line of a block of type typ.
+func writeBlockEnd(out io.Writer, typ string) error {
 	if _, err := out.Write(pemEnd[1:]); err != nil {
 		return err
 	}
-	_, err := out.Write([]byte(b.Type + "-----\n"))
+	_, err := out.Write([]byte(typ + "-----\n"))
 	return err
 }
 
@@ -0,0 +1,364 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// decoderBufferSize is the size of the Decoder's read buffer. BEGIN, END,
+// and header lines must fit in it; base64 lines of any length are
+// processed in pieces.
+const decoderBufferSize = 64 << 10
+
+// A Decoder reads a sequence of PEM blocks from an input stream. Unlike
+// Decode, it never holds more than one block in memory, and NextReader
+// streams the contents of a block without holding it at all.
+type Decoder struct {
+	// Strict makes the Decoder report malformed input as an error. By
+	// default, like Decode, the Decoder skips text outside of blocks and
+	// blocks that are malformed, truncated, or whose END line doesn't
+	// match their BEGIN line. In strict mode only blank lines may appear
+	// outside of blocks, and after reporting an error the Decoder returns
+	// it from every subsequent call.
+	Strict bool
+
+	// MaxBlockSize, if positive, is the largest decoded block that
+	// Decode will return. Larger blocks cause an error, after which
+	// decoding can resume with the next block.
+	MaxBlockSize int
+
+	r    *bufio.Reader
+	body *blockBody
+	err  error // sticky error in strict mode
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, decoderBufferSize)}
+}
+
+// syntaxError reports malformed PEM input.
+type syntaxError string
+
+func (e syntaxError) Error() string { return "pem: " + string(e) }
+
+var (
+	errLineTooLong  = syntaxError("line too long")
+	errMissingEnd   = syntaxError("missing END line")
+	errMismatchEnd  = syntaxError("END line does not match BEGIN line")
+	errOutsideBlock = syntaxError("unexpected data outside of a block")
+	errMissingBlank = syntaxError("missing blank line after headers")
+)
+
+func isSyntaxError(err error) bool {
+	var s syntaxError
+	var c base64.CorruptInputError
+	return errors.As(err, &s) || errors.As(err, &c)
+}
+
+var (
+	beginPrefix = pemStart[1:]
+	endPrefix   = pemEnd[1:]
+)
+
+// Decode returns the next PEM block in the input, or io.EOF if there are
+// no more blocks.
+func (d *Decoder) Decode() (*Block, error) {
+	for {
+		b, body, err := d.NextReader()
+		if err != nil {
+			return nil, err
+		}
+		r := body
+		if d.MaxBlockSize > 0 {
+			r = io.LimitReader(body, int64(d.MaxBlockSize)+1)
+		}
+		b.Bytes, err = io.ReadAll(r)
+		if err == nil && d.MaxBlockSize > 0 && len(b.Bytes) > d.MaxBlockSize {
+			return nil, errors.New("pem: block larger than MaxBlockSize")
+		}
+		if err == nil {
+			return b, nil
+		}
+		if d.Strict || !isSyntaxError(err) {
+			return nil, d.fail(err)
+		}
+	}
+}
+
+// NextReader returns the Type and Headers of the next PEM block in the
+// input, and a reader of the block's decoded contents, which stays valid
+// until the next call to Decode or NextReader. The reader returns an error
+// if the contents are malformed, even if Strict is false. NextReader
+// returns io.EOF if there are no more blocks.
+func (d *Decoder) NextReader() (*Block, io.Reader, error) {
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+	if d.body != nil {
+		body := d.body
+		d.body = nil
+		if _, err := io.Copy(io.Discard, body); err != nil && (d.Strict || !isSyntaxError(err)) {
+			return nil, nil, d.fail(err)
+		}
+	}
+
+	for {
+		line, err := d.readLine()
+		if err == io.EOF {
+			return nil, nil, io.EOF
+		}
+		if err != nil {
+			if d.Strict || !isSyntaxError(err) {
+				return nil, nil, d.fail(err)
+			}
+			continue
+		}
+		typ, ok := parseBoundary(line, beginPrefix)
+		if !ok {
+			if d.Strict && len(line) != 0 {
+				return nil, nil, d.fail(errOutsideBlock)
+			}
+			continue
+		}
+
+		b := &Block{Type: typ, Headers: make(map[string]string)}
+		body := &blockBody{d: d, typ: typ, atLineStart: true}
+		for {
+			if d.atBegin() {
+				// A BEGIN line before the end of this block.
+				if d.Strict {
+					return nil, nil, d.fail(errMissingEnd)
+				}
+				break
+			}
+			chunk, err := d.r.ReadSlice('\n')
+			if err == bufio.ErrBufferFull {
+				// Too long for a header, so this is a long line of
+				// contents.
+				body.buf = appendBase64(body.buf[:0], chunk)
+				body.pending = body.buf
+				body.atLineStart = false
+				d.body = body
+				return b, base64.NewDecoder(base64.StdEncoding, body), nil
+			}
+			if err == io.EOF && len(chunk) == 0 {
+				if d.Strict {
+					return nil, nil, d.fail(errMissingEnd)
+				}
+				return nil, nil, io.EOF
+			}
+			if err != nil && err != io.EOF {
+				return nil, nil, d.fail(err)
+			}
+			line := bytes.TrimRight(chunk, " \t\r\n")
+			key, val, ok := bytes.Cut(line, colon)
+			if !ok {
+				// The first line that is not a header is either the
+				// END line or the start of the contents.
+				if endTyp, ok := parseBoundary(line, endPrefix); ok {
+					if len(b.Headers) > 0 {
+						// Headers must be followed by a blank line,
+						// as Decode requires.
+						if d.Strict {
+							return nil, nil, d.fail(errMissingBlank)
+						}
+						break
+					}
+					body.finish(endTyp)
+				} else {
+					body.buf = appendBase64(body.buf[:0], line)
+					body.pending = body.buf
+				}
+				d.body = body
+				return b, base64.NewDecoder(base64.StdEncoding, body), nil
+			}
+			b.Headers[string(bytes.TrimSpace(key))] = string(bytes.TrimSpace(val))
+		}
+	}
+}
+
+// atBegin reports whether the next line is a BEGIN line.
+func (d *Decoder) atBegin() bool {
+	peek, _ := d.r.Peek(len(beginPrefix))
+	return bytes.Equal(peek, beginPrefix)
+}
+
+func (d *Decoder) fail(err error) error {
+	if d.Strict {
+		d.err = err
+	}
+	return err
+}
+
+// readLine returns the next line of input without its line ending and
+// trailing spaces and tabs. Lines longer than the buffer are consumed and
+// reported as errLineTooLong.
+func (d *Decoder) readLine() ([]byte, error) {
+	line, err := d.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		for err == bufio.ErrBufferFull {
+			_, err = d.r.ReadSlice('\n')
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return nil, errLineTooLong
+	}
+	if err == io.EOF && len(line) == 0 {
+		return nil, io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return bytes.TrimRight(line, " \t\r\n"), nil
+}
+
+// parseBoundary returns the type of a "-----BEGIN Type-----" or
+// "-----END Type-----" line, depending on prefix.
+func parseBoundary(line, prefix []byte) (string, bool) {
+	if !bytes.HasPrefix(line, prefix) || len(line) < len(prefix)+len(pemEndOfLine) ||
+		!bytes.HasSuffix(line, pemEndOfLine) {
+		return "", false
+	}
+	return string(line[len(prefix) : len(line)-len(pemEndOfLine)]), true
+}
+
+// appendBase64 appends b to dst without whitespace.
+func appendBase64(dst, b []byte) []byte {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// blockBody reads the base64 text of a block, up to its END line.
+type blockBody struct {
+	d           *Decoder
+	typ         string
+	buf         []byte
+	pending     []byte
+	atLineStart bool
+	err         error // io.EOF once the END line has been read
+}
+
+func (b *blockBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		b.fill()
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *blockBody) finish(endTyp string) {
+	if endTyp != b.typ {
+		b.err = errMismatchEnd
+		return
+	}
+	b.err = io.EOF
+}
+
+// fill reads the next line, or the next part of a long line.
+func (b *blockBody) fill() {
+	r := b.d.r
+	if b.atLineStart {
+		// Leave the BEGIN line of a following block for NextReader, so
+		// that a truncated block doesn't hide the next one.
+		if b.d.atBegin() {
+			b.err = errMissingEnd
+			return
+		}
+		if peek, _ := r.Peek(len(endPrefix)); bytes.Equal(peek, endPrefix) {
+			line, err := b.d.readLine()
+			if err == io.EOF {
+				err = errMissingEnd
+			}
+			if err != nil {
+				b.err = err
+				return
+			}
+			endTyp, ok := parseBoundary(line, endPrefix)
+			if !ok {
+				b.err = errMismatchEnd
+				return
+			}
+			b.finish(endTyp)
+			return
+		}
+	}
+	chunk, err := r.ReadSlice('\n')
+	switch err {
+	case nil:
+		b.atLineStart = true
+	case bufio.ErrBufferFull:
+		b.atLineStart = false
+	case io.EOF:
+		b.err = errMissingEnd
+	default:
+		b.err = err
+	}
+	b.buf = appendBase64(b.buf[:0], chunk)
+	b.pending = b.buf
+}
+
+// NewEncoder writes the BEGIN line and headers of b to out, and returns a
+// WriteCloser that writes the base64 encoding of the data written to it,
+// after b.Bytes, as the contents of the block. Close writes the END line.
+// Together they produce the same output as Encode, without holding the
+// contents in memory.
+func NewEncoder(out io.Writer, b *Block) (io.WriteCloser, error) {
+	if err := writeBlockStart(out, b); err != nil {
+		return nil, err
+	}
+	e := &encoder{out: out, typ: b.Type}
+	e.breaker.out = out
+	e.b64 = base64.NewEncoder(base64.StdEncoding, &e.breaker)
+	if _, err := e.b64.Write(b.Bytes); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+type encoder struct {
+	out     io.Writer
+	typ     string
+	breaker lineBreaker
+	b64     io.WriteCloser
+	closed  bool
+}
+
+func (e *encoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("pem: write after Close")
+	}
+	return e.b64.Write(p)
+}
+
+func (e *encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	if err := e.b64.Close(); err != nil {
+		return err
+	}
+	if err := e.breaker.Close(); err != nil {
+		return err
+	}
+	return writeBlockEnd(e.out, e.typ)
+}
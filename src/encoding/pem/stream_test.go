@@ -0,0 +1,191 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pem
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// decodeAll returns the blocks found by Decode in data.
+func decodeAll(data []byte) []*Block {
+	var blocks []*Block
+	for {
+		b, rest := Decode(data)
+		if b == nil {
+			return blocks
+		}
+		blocks = append(blocks, b)
+		data = rest
+	}
+}
+
+// decodeStream returns the blocks found by a Decoder in data.
+func decodeStream(data string, strict bool) ([]*Block, error) {
+	d := NewDecoder(iotest.OneByteReader(strings.NewReader(data)))
+	d.Strict = strict
+	var blocks []*Block
+	for {
+		b, err := d.Decode()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, b)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	blocks, err := decodeStream(pemData, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := decodeAll([]byte(pemData)); !reflect.DeepEqual(blocks, want) {
+		t.Errorf("got %d blocks, want %d matching Decode", len(blocks), len(want))
+	}
+
+	// pemData has text between blocks.
+	if _, err := decodeStream(pemData, true); err == nil {
+		t.Errorf("strict Decoder accepted text outside of blocks")
+	}
+
+	var buf bytes.Buffer
+	Encode(&buf, certificate)
+	buf.WriteString("\n\n")
+	Encode(&buf, privateKey2)
+	blocks, err = decodeStream(buf.String(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || !reflect.DeepEqual(blocks[0], certificate) || !reflect.DeepEqual(blocks[1], privateKey2) {
+		t.Errorf("strict Decoder returned %#v", blocks)
+	}
+}
+
+func TestDecoderBad(t *testing.T) {
+	good := EncodeToMemory(&Block{Type: "GOOD", Bytes: []byte("good")})
+	for _, test := range badPEMTests {
+		input := test.input + "\n" + string(good)
+		if _, err := decodeStream(input, true); err == nil {
+			t.Errorf("%s: strict Decoder accepted input", test.name)
+		}
+		blocks, err := decodeStream(input, false)
+		if err != nil {
+			t.Errorf("%s: lenient Decoder failed: %v", test.name, err)
+			continue
+		}
+		if len(blocks) != 1 || blocks[0].Type != "GOOD" || string(blocks[0].Bytes) != "good" {
+			t.Errorf("%s: lenient Decoder returned %#v, want only the good block", test.name, blocks)
+		}
+	}
+
+	// Invalid base64 is a syntax error too.
+	input := "-----BEGIN BAD-----\n!!!!\n-----END BAD-----\n" + string(good)
+	if _, err := decodeStream(input, true); err == nil {
+		t.Errorf("strict Decoder accepted invalid base64")
+	}
+	if blocks, err := decodeStream(input, false); err != nil || len(blocks) != 1 {
+		t.Errorf("lenient Decoder returned %#v, %v; want only the good block", blocks, err)
+	}
+}
+
+func TestDecoderLongLine(t *testing.T) {
+	// A single base64 line much longer than the Decoder's buffer.
+	data := bytes.Repeat([]byte{0, 1, 2, 0xfe, 0xff}, 3*decoderBufferSize)
+	input := "-----BEGIN LONG-----\n" + base64.StdEncoding.EncodeToString(data) + "\n-----END LONG-----\n"
+	blocks, err := decodeStream(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || !bytes.Equal(blocks[0].Bytes, data) {
+		t.Errorf("long line did not round trip")
+	}
+}
+
+func TestDecoderNextReader(t *testing.T) {
+	var buf bytes.Buffer
+	Encode(&buf, privateKey2)
+	Encode(&buf, certificate)
+	d := NewDecoder(&buf)
+
+	// Skip the first block without reading it.
+	b, _, err := d.NextReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Type != privateKey2.Type || !reflect.DeepEqual(b.Headers, privateKey2.Headers) || b.Bytes != nil {
+		t.Errorf("got %#v, want the headers of privateKey2", b)
+	}
+	b, r, err := d.NextReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := io.ReadAll(iotest.HalfReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Type != certificate.Type || !bytes.Equal(contents, certificate.Bytes) {
+		t.Errorf("second block does not match certificate")
+	}
+	if _, _, err := d.NextReader(); err != io.EOF {
+		t.Errorf("got %v at end of input, want io.EOF", err)
+	}
+}
+
+func TestDecoderMaxBlockSize(t *testing.T) {
+	var buf bytes.Buffer
+	Encode(&buf, &Block{Type: "BIG", Bytes: make([]byte, 100)})
+	Encode(&buf, &Block{Type: "SMALL", Bytes: make([]byte, 10)})
+	d := NewDecoder(&buf)
+	d.MaxBlockSize = 50
+	if _, err := d.Decode(); err == nil {
+		t.Errorf("Decode returned a block larger than MaxBlockSize")
+	}
+	b, err := d.Decode()
+	if err != nil || b.Type != "SMALL" {
+		t.Errorf("got %#v, %v; want the SMALL block", b, err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	for _, b := range []*Block{certificate, privateKey, privateKey2} {
+		want := EncodeToMemory(b)
+		var buf bytes.Buffer
+		w, err := NewEncoder(&buf, &Block{Type: b.Type, Headers: b.Headers, Bytes: b.Bytes[:7]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rest := b.Bytes[7:]; len(rest) > 0; {
+			n := 13
+			if n > len(rest) {
+				n = len(rest)
+			}
+			if _, err := w.Write(rest[:n]); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[n:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", b.Type, buf.Bytes(), want)
+		}
+		if _, err := w.Write([]byte{0}); err == nil {
+			t.Errorf("Write after Close succeeded")
+		}
+	}
+
+	if _, err := NewEncoder(io.Discard, &Block{Type: "BAD", Headers: map[string]string{"a:b": "c"}}); err == nil {
+		t.Errorf("NewEncoder accepted a header key with a colon")
+	}
+}
@@ -42,7 +42,7 @@ var depsRules = `
 	< constraints, container/list, container/ring,
 	  internal/cfg, internal/coverage, internal/coverage/rtcov,
 	  internal/coverage/uleb128, internal/coverage/calloc,
-	  internal/cpu, internal/goarch, internal/godebugs,
+	  internal/cpu, internal/cryptometrics, internal/goarch, internal/godebugs,
 	  internal/goexperiment, internal/goos,
 	  internal/goversion, internal/nettrace, internal/platform,
 	  log/internal,
@@ -55,7 +55,7 @@ var depsRules = `
 
 	# RUNTIME is the core runtime group of packages, all of them very light-weight.
 	internal/abi, internal/cpu, internal/goarch,
-	internal/coverage/rtcov, internal/godebugs, internal/goexperiment,
+	internal/coverage/rtcov, internal/cryptometrics, internal/godebugs, internal/goexperiment,
 	internal/goos, unsafe
 	< internal/bytealg
 	< internal/itoa
@@ -215,10 +215,13 @@ var depsRules = `
 	< encoding/ascii85, encoding/csv, encoding/gob, encoding/hex,
 	  encoding/json, encoding/pem, encoding/xml, mime;
 
+	encoding/hex
+	< encoding/multibase;
+
 	# hashes
 	io
 	< hash
-	< hash/adler32, hash/crc32, hash/crc64, hash/fnv;
+	< hash/adler32, hash/cdc, hash/crc32, hash/crc64, hash/fnv;
 
 	# math/big
 	FMT, encoding/binary, math/rand
@@ -409,16 +412,20 @@ var depsRules = `
 	crypto/internal/boring/syso,
 	encoding/binary,
 	golang.org/x/sys/cpu,
-	hash, embed
+	context, embed, hash
 	< crypto
 	< crypto/subtle
 	< crypto/internal/alias
 	< crypto/cipher;
 
-	crypto/cipher,
-	crypto/internal/boring/bcache
-	< crypto/internal/boring
-	< crypto/boring;
+	crypto/subtle, syscall
+	< crypto/internal/keyguard;
+
+	crypto/cipher, internal/cryptometrics
+	< crypto/internal/backend/openssl;
+
+	crypto/cipher, internal/cryptometrics, internal/syscall/windows/sysdll
+	< crypto/internal/backend/cng;
 
 	crypto/internal/alias
 	< crypto/internal/randutil
@@ -427,27 +434,70 @@ var depsRules = `
 	< crypto/internal/edwards25519/field
 	< crypto/internal/edwards25519;
 
-	crypto/boring
-	< crypto/aes, crypto/des, crypto/hmac, crypto/md5, crypto/rc4,
-	  crypto/sha1, crypto/sha256, crypto/sha512;
+	crypto/cipher, crypto/internal/nistec, internal/cryptometrics
+	< crypto/internal/backend/darwin;
 
-	crypto/boring, crypto/internal/edwards25519/field
+	crypto/cipher,
+	crypto/internal/backend/cng,
+	crypto/internal/backend/darwin,
+	crypto/internal/backend/openssl,
+	crypto/internal/boring/bcache,
+	crypto/internal/keyguard
+	< crypto/internal/boring
+	< crypto/boring;
+
+	crypto/internal/boring
+	< crypto/internal/fips;
+
+	math/bits
+	< crypto/internal/keccak;
+
+	internal/godebug
+	< crypto/internal/cryptotrace;
+
+	crypto/boring, crypto/internal/cryptotrace
+	< crypto/aes, crypto/blake2b, crypto/blake2s, crypto/des, crypto/hmac,
+	  crypto/md5, crypto/rc4, crypto/sha1, crypto/sha256, crypto/sha512;
+
+	crypto/boring, crypto/internal/keccak
+	< crypto/sha3;
+
+	crypto/boring, crypto/internal/edwards25519/field, encoding/base64
 	< crypto/ecdh;
 
+	crypto/blake2b
+	< crypto/argon2;
+
 	crypto/aes,
+	crypto/argon2,
+	crypto/blake2s,
 	crypto/des,
 	crypto/ecdh,
 	crypto/hmac,
 	crypto/internal/edwards25519,
+	crypto/internal/fips,
 	crypto/md5,
 	crypto/rc4,
 	crypto/sha1,
 	crypto/sha256,
+	crypto/sha3,
 	crypto/sha512
 	< CRYPTO;
 
 	CGO, fmt, net !< CRYPTO;
 
+	CRYPTO, internal/cryptometrics, internal/itoa, sort
+	< crypto/fips;
+
+	CRYPTO, encoding/hex
+	< crypto/merkle;
+
+	CRYPTO
+	< crypto/multihash;
+
+	CRYPTO, os
+	< crypto/sri;
+
 	# CRYPTO-MATH is core bignum-based crypto - no cgo, net; fmt now ok.
 	CRYPTO, FMT, math/big
 	< crypto/internal/boring/bbig
@@ -474,10 +524,106 @@ var depsRules = `
 	< crypto/x509/internal/macos
 	< crypto/x509/pkix;
 
-	crypto/internal/boring/fipstls, crypto/x509/pkix
-	< crypto/x509
+	CRYPTO-MATH
+	< crypto/internal/pbkdf2
+	< crypto/internal/scrypt;
+
+	CRYPTO-MATH
+	< crypto/internal/blowfish
+	< crypto/internal/bcrypt;
+
+	crypto/internal/bcrypt, crypto/internal/scrypt
+	< crypto/passwordhash;
+
+	crypto/internal/scrypt, crypto/x509/pkix
+	< crypto/internal/pbes2;
+
+	crypto/internal/scrypt, golang.org/x/crypto/hkdf
+	< crypto/age;
+
+	CRYPTO-MATH, golang.org/x/crypto/hkdf
+	< crypto/hpke;
+
+	CRYPTO-MATH, syscall
+	< crypto/lockedmem;
+
+	CRYPTO-MATH, context
+	< crypto/kms
+	< crypto/keyset;
+
+	CRYPTO-MATH
+	< crypto/bigmod;
+
+	CRYPTO-MATH
+	< crypto/keywrap;
+
+	CRYPTO-MATH
+	< crypto/cmac;
+
+	CRYPTO-MATH
+	< crypto/digest;
+
+	FMT, encoding/hex
+	< crypto/secret;
+
+	CRYPTO-MATH, crypto/secret, golang.org/x/crypto/hkdf
+	< crypto/noise;
+
+	CRYPTO, FMT, encoding/hex
+	< crypto/internal/difftest;
+
+	crypto/internal/boring/fipstls, crypto/internal/pbes2
+	< crypto/x509;
+
+	crypto/lockedmem, crypto/x509, runtime/trace
 	< crypto/tls;
 
+	CRYPTO-MATH
+	< crypto/pkcs12/internal/rc2;
+
+	crypto/internal/pbes2, crypto/pkcs12/internal/rc2, crypto/x509
+	< crypto/pkcs12;
+
+	crypto/digest, crypto/keywrap, crypto/x509
+	< crypto/cms;
+
+	crypto/x509
+	< crypto/keystore;
+
+	CRYPTO-MATH, encoding/pem
+	< crypto/sshsig;
+
+	CRYPTO-MATH
+	< crypto/openpgp;
+
+	CGO
+	< crypto/internal/backend/pkcs11;
+
+	CRYPTO-MATH, crypto/internal/backend/pkcs11, strconv
+	< crypto/pkcs11;
+
+	crypto/digest, encoding/hex, io/fs
+	< crypto/manifest;
+
+	CRYPTO-MATH, encoding/json
+	< crypto/jose;
+
+	CRYPTO-MATH, encoding/json
+	< crypto/objecthash;
+
+	CRYPTO-MATH, unicode/utf8
+	< crypto/internal/cbor
+	< crypto/cose;
+
+	crypto/tls, encoding/json
+	< crypto/x509/ct;
+
+	crypto/x509
+	< crypto/x509/attestation;
+
+	crypto/cose, crypto/x509/attestation, encoding/json
+	< crypto/webauthn;
+
 	# crypto-aware packages
 
 	DEBUG, go/build, go/types, text/scanner, crypto/md5
@@ -590,6 +736,9 @@ var depsRules = `
 	NET, testing, math/rand
 	< golang.org/x/net/nettest;
 
+	CRYPTO, encoding/hex, testing
+	< crypto/internal/backend/internal/backendtest;
+
 	syscall
 	< os/exec/internal/fdtest;
 
@@ -706,7 +855,7 @@ func findImports(pkg string) ([]string, error) {
 	}
 	var imports []string
 	var haveImport = map[string]bool{}
-	if pkg == "crypto/internal/boring" {
+	if pkg == "crypto/internal/boring" || pkg == "crypto/internal/backend/openssl" {
 		haveImport["C"] = true // kludge: prevent C from appearing in crypto/internal/boring imports
 	}
 	fset := token.NewFileSet()
@@ -759,6 +908,27 @@ func depsPolicy(t *testing.T) *dag.Graph {
 	return g
 }
 
+// TestMaphashDependencies checks that hash/maphash only depends on
+// packages below the runtime outside the purego build. Its depsRules entry
+// allows crypto/rand, which only the purego implementation needs, and
+// findImports reads the files of every build.
+func TestMaphashDependencies(t *testing.T) {
+	if !testenv.HasSrc() {
+		t.Skipf("skipping on %s/%s, missing full GOROOT", runtime.GOOS, runtime.GOARCH)
+	}
+
+	p, err := Default.Import("hash/maphash", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := depsPolicy(t)
+	for _, imp := range p.Imports {
+		if !policy.HasEdge("RUNTIME", imp) {
+			t.Errorf("unexpected dependency: hash/maphash imports %s outside the purego build", imp)
+		}
+	}
+}
+
 // TestStdlibLowercase tests that all standard library package names are
 // lowercase. See Issue 40065.
 func TestStdlibLowercase(t *testing.T) {
@@ -0,0 +1,237 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cdc implements content-defined chunking, which splits a stream
+// into variable-size chunks at boundaries chosen by its content, with the
+// buzhash rolling hash.
+//
+// Since a boundary only depends on the bytes that precede it, an insertion
+// or deletion in a stream only changes the chunks around it, which makes
+// content-defined chunks suited to deduplication and incremental backups.
+//
+// The boundaries found for a given stream, Config, and seed are stable
+// across Go releases.
+package cdc
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"math/bits"
+)
+
+// The size of a buzhash checksum in bytes.
+const Size = 4
+
+// A Buzhash is a rolling hash over the last bytes written to it.
+//
+// Its checksum is a function of the window of the last window bytes
+// written, where bytes before the first one written are zero, and of the
+// seed. Buzhash implements hash.Hash32, although it is not a hash of all
+// the data written.
+type Buzhash struct {
+	table  *[256]uint32
+	window []byte
+	pos    int // the position in window of the oldest byte
+	sum    uint32
+	init   uint32 // the checksum of a zero window
+}
+
+// NewBuzhash returns a rolling hash over window bytes, with a table of
+// random values derived from seed. It panics if window is not positive.
+func NewBuzhash(window int, seed uint64) *Buzhash {
+	if window <= 0 {
+		panic("cdc: non-positive window size")
+	}
+	b := &Buzhash{table: newTable(seed), window: make([]byte, window)}
+	for i := 0; i < window; i++ {
+		b.init = bits.RotateLeft32(b.init, 1) ^ b.table[0]
+	}
+	b.sum = b.init
+	return b
+}
+
+// newTable returns the buzhash table for seed, made of the outputs of the
+// SplitMix64 generator.
+func newTable(seed uint64) *[256]uint32 {
+	t := new([256]uint32)
+	for i := range t {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		z ^= z >> 31
+		t[i] = uint32(z >> 32)
+	}
+	return t
+}
+
+// Roll adds c to the window, removing its oldest byte, and returns the new
+// checksum.
+func (b *Buzhash) Roll(c byte) uint32 {
+	out := b.window[b.pos]
+	b.window[b.pos] = c
+	if b.pos++; b.pos == len(b.window) {
+		b.pos = 0
+	}
+	b.sum = bits.RotateLeft32(b.sum, 1) ^ bits.RotateLeft32(b.table[out], len(b.window)) ^ b.table[c]
+	return b.sum
+}
+
+// Write rolls each byte of p into the window. It never returns an error.
+func (b *Buzhash) Write(p []byte) (int, error) {
+	for _, c := range p {
+		b.Roll(c)
+	}
+	return len(p), nil
+}
+
+// Sum32 returns the checksum of the window.
+func (b *Buzhash) Sum32() uint32 { return b.sum }
+
+// Sum appends the big-endian checksum of the window to in.
+func (b *Buzhash) Sum(in []byte) []byte {
+	s := b.sum
+	return append(in, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
+
+// Reset fills the window with zeros.
+func (b *Buzhash) Reset() {
+	for i := range b.window {
+		b.window[i] = 0
+	}
+	b.pos = 0
+	b.sum = b.init
+}
+
+// Size returns Size.
+func (b *Buzhash) Size() int { return Size }
+
+// BlockSize returns 1.
+func (b *Buzhash) BlockSize() int { return 1 }
+
+// The size of the window of the rolling hash of a Chunker.
+const WindowSize = 64
+
+// A Config configures a Chunker. The zero value selects the defaults.
+type Config struct {
+	// MinSize, AvgSize, and MaxSize are the minimum, average, and maximum
+	// sizes of chunks, except for the last one, which may be shorter than
+	// MinSize. They default to 512 KiB, 1 MiB, and 8 MiB. It must be that
+	// WindowSize <= MinSize < AvgSize < MaxSize.
+	MinSize, AvgSize, MaxSize int
+
+	// Seed selects the rolling hash, so that the boundaries of chunks do
+	// not reveal the contents of a stream to observers who don't know it.
+	Seed uint64
+
+	// Hash, if not nil, returns the hash applied to each chunk.
+	Hash func() hash.Hash
+}
+
+// A Chunk is a chunk of a stream.
+type Chunk struct {
+	// Offset is the position of the chunk in the stream.
+	Offset int64
+
+	// Data is the content of the chunk. It is only valid until the next
+	// call to Next.
+	Data []byte
+
+	// Sum is the digest of Data by Config.Hash, or nil if Config.Hash is
+	// nil.
+	Sum []byte
+}
+
+// A Chunker splits a stream into content-defined chunks.
+type Chunker struct {
+	r         io.Reader
+	cfg       Config
+	threshold uint64
+	rh        *Buzhash
+	h         hash.Hash
+	buf       []byte // buf[start:end] is read but not yet returned
+	start     int
+	end       int
+	offset    int64
+	err       error
+}
+
+// NewChunker returns a Chunker that reads the stream from r.
+func NewChunker(r io.Reader, cfg Config) (*Chunker, error) {
+	if cfg.MinSize == 0 && cfg.AvgSize == 0 && cfg.MaxSize == 0 {
+		cfg.MinSize, cfg.AvgSize, cfg.MaxSize = 512<<10, 1<<20, 8<<20
+	}
+	if cfg.MinSize < WindowSize || cfg.AvgSize <= cfg.MinSize || cfg.MaxSize <= cfg.AvgSize {
+		return nil, errors.New("cdc: invalid chunk sizes")
+	}
+	c := &Chunker{
+		r:   r,
+		cfg: cfg,
+		// A boundary follows each byte past MinSize with probability
+		// 1/(AvgSize-MinSize), so that chunks are AvgSize bytes on average,
+		// ignoring MaxSize.
+		threshold: (1 << 32) / uint64(cfg.AvgSize-cfg.MinSize),
+		rh:        NewBuzhash(WindowSize, cfg.Seed),
+		buf:       make([]byte, 2*cfg.MaxSize),
+	}
+	if cfg.Hash != nil {
+		c.h = cfg.Hash()
+	}
+	return c, nil
+}
+
+// Next returns the next chunk of the stream. At the end of the stream, it
+// returns io.EOF.
+func (c *Chunker) Next() (Chunk, error) {
+	// Keep at least MaxSize bytes available, compacting buf when needed.
+	if c.end-c.start < c.cfg.MaxSize && c.err == nil {
+		if c.start+c.cfg.MaxSize > len(c.buf) {
+			c.end = copy(c.buf, c.buf[c.start:c.end])
+			c.start = 0
+		}
+		for c.end-c.start < c.cfg.MaxSize && c.err == nil {
+			var n int
+			n, c.err = c.r.Read(c.buf[c.end:])
+			c.end += n
+		}
+	}
+	if c.err != nil && c.err != io.EOF {
+		return Chunk{}, c.err
+	}
+	if c.start == c.end {
+		return Chunk{}, io.EOF
+	}
+
+	n := c.boundary(c.buf[c.start:c.end])
+	ch := Chunk{Offset: c.offset, Data: c.buf[c.start : c.start+n : c.start+n]}
+	if c.h != nil {
+		c.h.Reset()
+		c.h.Write(ch.Data)
+		ch.Sum = c.h.Sum(nil)
+	}
+	c.start += n
+	c.offset += int64(n)
+	return ch, nil
+}
+
+// boundary returns the length of the chunk that starts data.
+func (c *Chunker) boundary(data []byte) int {
+	if len(data) <= c.cfg.MinSize {
+		return len(data)
+	}
+	if len(data) > c.cfg.MaxSize {
+		data = data[:c.cfg.MaxSize]
+	}
+	c.rh.Reset()
+	for _, b := range data[c.cfg.MinSize-WindowSize : c.cfg.MinSize-1] {
+		c.rh.Roll(b)
+	}
+	for i := c.cfg.MinSize - 1; i < len(data); i++ {
+		if uint64(c.rh.Roll(data[i])) < c.threshold {
+			return i + 1
+		}
+	}
+	return len(data)
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+var _ hash.Hash32 = (*Buzhash)(nil)
+
+func TestBuzhashRolling(t *testing.T) {
+	data := make([]byte, 1000)
+	rand.New(rand.NewSource(1)).Read(data)
+	for _, window := range []int{1, 16, 32, 48, 64} {
+		rolling := NewBuzhash(window, 42)
+		rolling.Write(data[:300])
+		for i := 300; i < len(data); i++ {
+			sum := rolling.Roll(data[i])
+			fresh := NewBuzhash(window, 42)
+			fresh.Write(data[i+1-window : i+1])
+			if sum != fresh.Sum32() {
+				t.Fatalf("window %d: rolling checksum at %d = %#x, want %#x", window, i, sum, fresh.Sum32())
+			}
+		}
+		rolling.Reset()
+		if fresh := NewBuzhash(window, 42); rolling.Sum32() != fresh.Sum32() {
+			t.Errorf("window %d: checksum after Reset = %#x, want %#x", window, rolling.Sum32(), fresh.Sum32())
+		}
+	}
+
+	a, b := NewBuzhash(64, 1), NewBuzhash(64, 2)
+	a.Write(data)
+	b.Write(data)
+	if a.Sum32() == b.Sum32() {
+		t.Errorf("checksums with different seeds are equal")
+	}
+}
+
+func chunks(t *testing.T, r io.Reader, cfg Config) [][]byte {
+	t.Helper()
+	c, err := NewChunker(r, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out [][]byte
+	var offset int64
+	for {
+		ch, err := c.Next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ch.Offset != offset {
+			t.Fatalf("chunk offset = %d, want %d", ch.Offset, offset)
+		}
+		offset += int64(len(ch.Data))
+		if cfg.Hash != nil {
+			want := sha256.Sum256(ch.Data)
+			if !bytes.Equal(ch.Sum, want[:]) {
+				t.Fatalf("chunk digest = %x, want %x", ch.Sum, want)
+			}
+		}
+		out = append(out, bytes.Clone(ch.Data))
+	}
+}
+
+var testConfig = Config{MinSize: 1 << 10, AvgSize: 4 << 10, MaxSize: 16 << 10, Hash: sha256.New}
+
+func TestChunker(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	got := chunks(t, bytes.NewReader(data), testConfig)
+	if !bytes.Equal(bytes.Join(got, nil), data) {
+		t.Fatal("chunks do not add up to the stream")
+	}
+	for i, c := range got {
+		if len(c) > testConfig.MaxSize || len(c) < testConfig.MinSize && i != len(got)-1 {
+			t.Errorf("chunk %d has %d bytes", i, len(c))
+		}
+	}
+	if avg := len(data) / len(got); avg < testConfig.AvgSize*3/4 || avg > testConfig.AvgSize*5/4 {
+		t.Errorf("average chunk size is %d, want about %d", avg, testConfig.AvgSize)
+	}
+
+	// The boundaries don't depend on how the stream is read.
+	oneByte := chunks(t, iotest.OneByteReader(bytes.NewReader(data)), testConfig)
+	if len(oneByte) != len(got) {
+		t.Errorf("got %d chunks with a one-byte reader, want %d", len(oneByte), len(got))
+	}
+
+	// An insertion only changes the chunks around it.
+	edited := append(append(bytes.Clone(data[:1000]), "inserted"...), data[1000:]...)
+	seen := make(map[string]bool)
+	for _, c := range got {
+		seen[string(c)] = true
+	}
+	changed := 0
+	for _, c := range chunks(t, bytes.NewReader(edited), testConfig) {
+		if !seen[string(c)] {
+			changed++
+		}
+	}
+	if changed > 2 {
+		t.Errorf("%d chunks changed after an insertion", changed)
+	}
+
+	// A different seed moves the boundaries.
+	cfg := testConfig
+	cfg.Seed = 1
+	if seeded := chunks(t, bytes.NewReader(data), cfg); len(seeded[0]) == len(got[0]) && len(seeded[1]) == len(got[1]) {
+		t.Errorf("boundaries did not change with the seed")
+	}
+
+	// Zeros hit MaxSize, or MinSize, depending on the seed.
+	zeros := chunks(t, bytes.NewReader(make([]byte, 100<<10)), testConfig)
+	if n := len(zeros[0]); n != testConfig.MinSize && n != testConfig.MaxSize {
+		t.Errorf("chunk of zeros has %d bytes", n)
+	}
+}
+
+func TestChunkerShort(t *testing.T) {
+	if got := chunks(t, bytes.NewReader(nil), testConfig); len(got) != 0 {
+		t.Errorf("got %d chunks of an empty stream", len(got))
+	}
+	got := chunks(t, bytes.NewReader([]byte("short")), testConfig)
+	if len(got) != 1 || string(got[0]) != "short" {
+		t.Errorf("chunks of a short stream = %q", got)
+	}
+}
+
+func TestChunkerErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{MinSize: 32, AvgSize: 1024, MaxSize: 4096},
+		{MinSize: 1024, AvgSize: 1024, MaxSize: 4096},
+		{MinSize: 1024, AvgSize: 4096, MaxSize: 4096},
+		{AvgSize: 4096},
+	} {
+		if _, err := NewChunker(bytes.NewReader(nil), cfg); err == nil {
+			t.Errorf("NewChunker with %+v succeeded", cfg)
+		}
+	}
+
+	errRead := errors.New("read error")
+	c, err := NewChunker(io.MultiReader(bytes.NewReader(make([]byte, 100)), iotest.ErrReader(errRead)), testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Next(); err != errRead {
+		t.Errorf("Next = %v, want %v", err, errRead)
+	}
+}
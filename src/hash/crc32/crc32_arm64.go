@@ -13,6 +13,27 @@ import "internal/cpu"
 func castagnoliUpdate(crc uint32, p []byte) uint32
 func ieeeUpdate(crc uint32, p []byte) uint32
 
+// castagnoliUpdateTriple updates three non-inverted crcs with 24*rounds
+// bytes from each buffer, interleaving the CRC32CX instructions so that
+// they can execute in parallel.
+//
+//go:noescape
+func castagnoliUpdateTriple(
+	crcA, crcB, crcC uint32,
+	a, b, c []byte,
+	rounds uint32,
+) (retA uint32, retB uint32, retC uint32)
+
+// The buffer lengths and combining tables are the same as for amd64; see
+// archUpdateCastagnoli in crc32_amd64.go for the derivation.
+const castagnoliK1 = 168
+const castagnoliK2 = 1344
+
+type shiftTable [4]Table
+
+var castagnoliTableK1 *shiftTable
+var castagnoliTableK2 *shiftTable
+
 func archAvailableCastagnoli() bool {
 	return cpu.ARM64.HasCRC32
 }
@@ -21,6 +42,25 @@ func archInitCastagnoli() {
 	if !cpu.ARM64.HasCRC32 {
 		panic("arch-specific crc32 instruction for Castagnoli not available")
 	}
+	castagnoliTableK1 = new(shiftTable)
+	castagnoliTableK2 = new(shiftTable)
+	var tmp [castagnoliK2]byte
+	for b := 0; b < 4; b++ {
+		for i := 0; i < 256; i++ {
+			val := uint32(i) << uint32(b*8)
+			castagnoliTableK1[b][i] = castagnoliUpdate(val, tmp[:castagnoliK1])
+			castagnoliTableK2[b][i] = castagnoliUpdate(val, tmp[:])
+		}
+	}
+}
+
+// castagnoliShift computes the CRC32-C of K1 or K2 zeroes (depending on the
+// table given) with the given initial crc value.
+func castagnoliShift(table *shiftTable, crc uint32) uint32 {
+	return table[3][crc>>24] ^
+		table[2][(crc>>16)&0xFF] ^
+		table[1][(crc>>8)&0xFF] ^
+		table[0][crc&0xFF]
 }
 
 func archUpdateCastagnoli(crc uint32, p []byte) uint32 {
@@ -28,7 +68,32 @@ func archUpdateCastagnoli(crc uint32, p []byte) uint32 {
 		panic("arch-specific crc32 instruction for Castagnoli not available")
 	}
 
-	return ^castagnoliUpdate(^crc, p)
+	crc = ^crc
+
+	// Process 3*K2 at a time.
+	for len(p) >= castagnoliK2*3 {
+		crcA, crcB, crcC := castagnoliUpdateTriple(
+			crc, 0, 0,
+			p, p[castagnoliK2:], p[castagnoliK2*2:],
+			castagnoliK2/24)
+		crcAB := castagnoliShift(castagnoliTableK2, crcA) ^ crcB
+		crc = castagnoliShift(castagnoliTableK2, crcAB) ^ crcC
+		p = p[castagnoliK2*3:]
+	}
+
+	// Process 3*K1 at a time.
+	for len(p) >= castagnoliK1*3 {
+		crcA, crcB, crcC := castagnoliUpdateTriple(
+			crc, 0, 0,
+			p, p[castagnoliK1:], p[castagnoliK1*2:],
+			castagnoliK1/24)
+		crcAB := castagnoliShift(castagnoliTableK1, crcA) ^ crcB
+		crc = castagnoliShift(castagnoliTableK1, crcAB) ^ crcC
+		p = p[castagnoliK1*3:]
+	}
+
+	// Use the simple implementation for what's left.
+	return ^castagnoliUpdate(crc, p)
 }
 
 func archAvailableIEEE() bool {
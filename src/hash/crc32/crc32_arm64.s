@@ -50,6 +50,49 @@ done:
 	MOVWU	R9, ret+32(FP)
 	RET
 
+// castagnoliUpdateTriple updates three (non-inverted) crcs with
+// (24*rounds) bytes from each buffer.
+
+// func castagnoliUpdateTriple(
+//     crcA, crcB, crcC uint32,
+//     a, b, c []byte,
+//     rounds uint32,
+// ) (retA uint32, retB uint32, retC uint32)
+TEXT ·castagnoliUpdateTriple(SB),NOSPLIT,$0-108
+	MOVWU	crcA+0(FP), R0
+	MOVWU	crcB+4(FP), R1
+	MOVWU	crcC+8(FP), R2
+	MOVD	a_base+16(FP), R3  // data pointer
+	MOVD	b_base+40(FP), R4  // data pointer
+	MOVD	c_base+64(FP), R5  // data pointer
+	MOVWU	rounds+88(FP), R6
+
+loop:
+	LDP.P	16(R3), (R7, R8)
+	LDP.P	16(R4), (R9, R10)
+	LDP.P	16(R5), (R11, R12)
+	CRC32CX	R7, R0
+	CRC32CX	R9, R1
+	CRC32CX	R11, R2
+	CRC32CX	R8, R0
+	CRC32CX	R10, R1
+	CRC32CX	R12, R2
+
+	MOVD.P	8(R3), R7
+	MOVD.P	8(R4), R9
+	MOVD.P	8(R5), R11
+	CRC32CX	R7, R0
+	CRC32CX	R9, R1
+	CRC32CX	R11, R2
+
+	SUBS	$1, R6
+	BNE	loop
+
+	MOVWU	R0, retA+96(FP)
+	MOVWU	R1, retB+100(FP)
+	MOVWU	R2, retC+104(FP)
+	RET
+
 // ieeeUpdate updates the non-inverted crc with the given data.
 
 // func ieeeUpdate(crc uint32, p []byte) uint32
@@ -32,6 +32,8 @@ var (
 	slicing8TablesBuildOnce sync.Once
 	slicing8TableISO        *[8]Table
 	slicing8TableECMA       *[8]Table
+	foldConstantsISO        *foldConstants
+	foldConstantsECMA       *foldConstants
 )
 
 func buildSlicing8TablesOnce() {
@@ -41,6 +43,8 @@ func buildSlicing8TablesOnce() {
 func buildSlicing8Tables() {
 	slicing8TableISO = makeSlicingBy8Table(makeTable(ISO))
 	slicing8TableECMA = makeSlicingBy8Table(makeTable(ECMA))
+	foldConstantsISO = makeFoldConstants(ISO)
+	foldConstantsECMA = makeFoldConstants(ECMA)
 }
 
 // MakeTable returns a Table constructed from the specified polynomial.
@@ -86,6 +90,36 @@ func makeSlicingBy8Table(t *Table) *[8]Table {
 	return &helperTable
 }
 
+// foldConstants holds the multipliers used by the carry-less
+// multiplication implementations to fold 128 bits of input forward by 512
+// bits (the first pair) or by 128 bits (the second pair).
+type foldConstants [4]uint64
+
+func makeFoldConstants(poly uint64) *foldConstants {
+	// The low half of a 128-bit block holds the coefficients of x^127 to
+	// x^64, and the high half those of x^63 to x^0, so moving the block
+	// forward by n bits multiplies the halves by x^(n+64) and x^n. A
+	// carry-less multiplication of two bit-reflected values yields a
+	// product shifted by one, which the constants compensate for.
+	return &foldConstants{
+		xPowMod(512+64-1, poly), xPowMod(512-1, poly),
+		xPowMod(128+64-1, poly), xPowMod(128-1, poly),
+	}
+}
+
+// xPowMod returns x^n modulo the polynomial, bit-reflected like poly.
+func xPowMod(n int, poly uint64) uint64 {
+	r := uint64(1) << 63
+	for i := 0; i < n; i++ {
+		if r&1 == 1 {
+			r = (r >> 1) ^ poly
+		} else {
+			r >>= 1
+		}
+	}
+	return r
+}
+
 // digest represents the partial evaluation of a checksum.
 type digest struct {
 	crc uint64
@@ -158,10 +192,13 @@ func update(crc uint64, tab *Table, p []byte) uint64 {
 	// Table comparison is somewhat expensive, so avoid it for small sizes
 	for len(p) >= 64 {
 		var helperTable *[8]Table
+		var k *foldConstants
 		if *tab == slicing8TableECMA[0] {
 			helperTable = slicing8TableECMA
+			k = foldConstantsECMA
 		} else if *tab == slicing8TableISO[0] {
 			helperTable = slicing8TableISO
+			k = foldConstantsISO
 			// For smaller sizes creating extended table takes too much time
 		} else if len(p) >= 2048 {
 			// According to the tests between various x86 and arm CPUs, 2k is a reasonable
@@ -170,6 +207,20 @@ func update(crc uint64, tab *Table, p []byte) uint64 {
 		} else {
 			break
 		}
+		if k != nil && archAvailable() {
+			// Fold all whole 16-byte blocks into one, which has the same
+			// CRC, and compute that from scratch.
+			n := len(p) &^ 15
+			lo, hi := archFold(crc, p[:n], k)
+			crc = 0
+			for _, v := range [2]uint64{lo, hi} {
+				for i := 0; i < 64; i += 8 {
+					crc = tab[byte(crc)^byte(v>>i)] ^ (crc >> 8)
+				}
+			}
+			p = p[n:]
+			break
+		}
 		// Update using slicing-by-8
 		for len(p) > 8 {
 			crc ^= uint64(p[0]) | uint64(p[1])<<8 | uint64(p[2])<<16 | uint64(p[3])<<24 |
@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package crc64
+
+import "internal/cpu"
+
+// foldCLMUL is defined in crc64_amd64.s and uses the PCLMULQDQ
+// instruction. It folds p, whose first eight bytes are xored with crc,
+// into 128 bits with the same CRC, which it returns as two little-endian
+// halves. len(p) must be at least 64, and must be a multiple of 16.
+//
+//go:noescape
+func foldCLMUL(crc uint64, p []byte, k *foldConstants) (lo, hi uint64)
+
+func archAvailable() bool {
+	return cpu.X86.HasPCLMULQDQ
+}
+
+func archFold(crc uint64, p []byte, k *foldConstants) (lo, hi uint64) {
+	return foldCLMUL(crc, p, k)
+}
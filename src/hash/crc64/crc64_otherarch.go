@@ -0,0 +1,13 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package crc64
+
+func archAvailable() bool { return false }
+
+func archFold(crc uint64, p []byte, k *foldConstants) (lo, hi uint64) {
+	panic("not available")
+}
@@ -7,6 +7,7 @@ package crc64
 import (
 	"encoding"
 	"io"
+	"math/rand"
 	"testing"
 )
 
@@ -154,6 +155,39 @@ func TestMarshalTableMismatch(t *testing.T) {
 	}
 }
 
+// simpleUpdate is the byte-at-a-time table implementation.
+func simpleUpdate(crc uint64, tab *Table, p []byte) uint64 {
+	crc = ^crc
+	for _, v := range p {
+		crc = tab[byte(crc)^v] ^ (crc >> 8)
+	}
+	return ^crc
+}
+
+func TestArchUpdate(t *testing.T) {
+	if !archAvailable() {
+		t.Skip("no arch-specific implementation")
+	}
+	rng := rand.New(rand.NewSource(1))
+	buf := make([]byte, 4096+16)
+	rng.Read(buf)
+	for _, poly := range []uint64{ISO, ECMA} {
+		tab := MakeTable(poly)
+		for i := 0; i < 1000; i++ {
+			off := rng.Intn(16)
+			n := rng.Intn(len(buf) - off)
+			if i < 200 {
+				n = 48 + i // cover every length around the threshold
+			}
+			p := buf[off : off+n]
+			crc := rng.Uint64()
+			if got, want := Update(crc, tab, p), simpleUpdate(crc, tab, p); got != want {
+				t.Fatalf("Update(%#x, %#x, buf[%d:%d]) = %#x, want %#x", crc, poly, off, off+n, got, want)
+			}
+		}
+	}
+}
+
 func bench(b *testing.B, poly uint64, size int64) {
 	b.SetBytes(size)
 	data := make([]byte, size)
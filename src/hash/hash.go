@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package hash provides interfaces for hash functions.
+// Package hash provides interfaces for hash functions, and readers and
+// writers that hash the data flowing through them.
 package hash
 
 import "io"
@@ -56,3 +57,18 @@ type Hash64 interface {
 	Hash
 	Sum64() uint64
 }
+
+// XOF is the common interface implemented by extendable-output functions,
+// such as SHAKE and BLAKE2X, which produce output of any length.
+type XOF interface {
+	// Write absorbs more data into the XOF's state. Implementations may
+	// panic if it is called after Read.
+	io.Writer
+
+	// Read reads more output from the XOF. It returns io.EOF if the XOF
+	// has a limit to its output length, and it has been reached.
+	io.Reader
+
+	// Reset resets the XOF to its initial state.
+	Reset()
+}
@@ -0,0 +1,205 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hash
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrMismatch is returned by a verifying Reader or Writer when the digest
+// of the data does not match the expected digest. The *IntegrityError
+// returned by a Reader made by Verify matches it with errors.Is.
+var ErrMismatch = errors.New("hash: digest mismatch")
+
+// An IntegrityError is returned by a Reader made by Verify when the data
+// does not match its expected digest or size.
+type IntegrityError struct {
+	// Want and Got are the expected digest and the digest of the data.
+	// Got is nil if the data was longer than Size, and was not read to
+	// its end.
+	Want, Got []byte
+
+	// Size is the expected size of the data, or -1 if it is unknown.
+	Size int64
+
+	// N is the size of the data, or the number of bytes read so far if the
+	// data was longer than Size.
+	N int64
+}
+
+func (e *IntegrityError) Error() string {
+	if e.Size >= 0 && e.N != e.Size {
+		return "hash: size mismatch: got " + decString(e.N) + " bytes, want " + decString(e.Size)
+	}
+	return "hash: digest mismatch: got " + hexString(e.Got) + ", want " + hexString(e.Want)
+}
+
+// Is reports whether target is ErrMismatch.
+func (e *IntegrityError) Is(target error) bool {
+	return target == ErrMismatch
+}
+
+func decString(n int64) string {
+	var b [20]byte
+	i := len(b)
+	for {
+		i--
+		b[i] = byte('0' + n%10)
+		if n /= 10; n == 0 {
+			return string(b[i:])
+		}
+	}
+}
+
+func hexString(b []byte) string {
+	const digits = "0123456789abcdef"
+	s := make([]byte, 0, 2*len(b))
+	for _, c := range b {
+		s = append(s, digits[c>>4], digits[c&0xf])
+	}
+	return string(s)
+}
+
+// A Reader hashes the data read through it.
+type Reader struct {
+	r    io.Reader
+	h    Hash
+	want []byte
+	size int64 // expected size, or -1
+	n    int64 // bytes read so far
+	err  error // sticky verification error
+
+	// detailed is set by Verify, whose Reader returns *IntegrityError
+	// instead of ErrMismatch.
+	detailed bool
+}
+
+// NewReader returns a Reader that reads from r and writes the data read
+// to h.
+func NewReader(r io.Reader, h Hash) *Reader {
+	return &Reader{r: r, h: h, size: -1}
+}
+
+// NewVerifyingReader returns a Reader that reads from r and writes the data
+// read to h. When r returns io.EOF, the Reader compares the digest of the
+// data with want, and returns ErrMismatch instead of io.EOF if they differ.
+//
+// The data returned before ErrMismatch is unverified, and callers must not
+// act on it until Read returns io.EOF.
+func NewVerifyingReader(r io.Reader, h Hash, want []byte) *Reader {
+	return &Reader{r: r, h: h, want: want, size: -1}
+}
+
+// Verify returns a verifying Reader, like NewVerifyingReader, that hashes
+// the data read from r with a hash returned by newHash, such as
+// crypto.SHA256.New, and checks it against want. Its errors are
+// *IntegrityError values, which match ErrMismatch with errors.Is.
+//
+// If size is not negative, it is the expected size of the data, and the
+// Reader also returns an *IntegrityError, without reading further, as soon
+// as r returns more than size bytes, or at io.EOF if it returned fewer.
+func Verify(r io.Reader, newHash func() Hash, want []byte, size int64) *Reader {
+	if size < 0 {
+		size = -1
+	}
+	return &Reader{r: r, h: newHash(), want: want, size: size, detailed: true}
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.size >= 0 && int64(len(p)) > r.size-r.n {
+		// Read one byte past the expected size, to detect longer data.
+		p = p[:r.size-r.n+1]
+	}
+	n, err := r.r.Read(p)
+	if r.size >= 0 && r.n+int64(n) > r.size {
+		r.n += int64(n)
+		r.err = &IntegrityError{Want: r.want, Size: r.size, N: r.n}
+		return 0, r.err
+	}
+	r.n += int64(n)
+	r.h.Write(p[:n])
+	if err == io.EOF && (r.want != nil || r.size >= 0) {
+		if got := r.h.Sum(nil); r.size >= 0 && r.n != r.size || r.want != nil && !equal(got, r.want) {
+			r.err = ErrMismatch
+			if r.detailed {
+				r.err = &IntegrityError{Want: r.want, Got: got, Size: r.size, N: r.n}
+			}
+			err = r.err
+		}
+	}
+	return n, err
+}
+
+// Sum appends the digest of the data read so far to b and returns the
+// resulting slice.
+func (r *Reader) Sum(b []byte) []byte {
+	return r.h.Sum(b)
+}
+
+// A Writer hashes the data written through it.
+type Writer struct {
+	w    io.Writer
+	h    Hash
+	want []byte
+}
+
+// NewWriter returns a Writer that writes to both w and h.
+func NewWriter(w io.Writer, h Hash) *Writer {
+	return &Writer{w: w, h: h}
+}
+
+// NewVerifyingWriter returns a Writer that writes to both w and h, and
+// whose Close method returns ErrMismatch if the digest of the data written
+// does not match want.
+func NewVerifyingWriter(w io.Writer, h Hash, want []byte) *Writer {
+	return &Writer{w: w, h: h, want: want}
+}
+
+// Write implements io.Writer. Only the data accepted by the underlying
+// writer is hashed.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.h.Write(p[:n])
+	return n, err
+}
+
+// Close closes the underlying writer, if it implements io.Closer. Then,
+// for a verifying Writer, it returns ErrMismatch if the digest of the data
+// written does not match the expected digest.
+func (w *Writer) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	if w.want != nil && !equal(w.h.Sum(nil), w.want) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// Sum appends the digest of the data written so far to b and returns the
+// resulting slice.
+func (w *Writer) Sum(b []byte) []byte {
+	return w.h.Sum(b)
+}
+
+// equal reports whether a and b are equal, in time independent of their
+// contents.
+func equal(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var v byte
+	for i := range a {
+		v |= a[i] ^ b[i]
+	}
+	return v == 0
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hash_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const ioInput = "The tunneling gopher digs downwards, unaware of what he will find."
+
+func ioDigest(s string) []byte {
+	d := sha256.Sum256([]byte(s))
+	return d[:]
+}
+
+func TestReader(t *testing.T) {
+	r := hash.NewReader(iotest.OneByteReader(strings.NewReader(ioInput)), sha256.New())
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != ioInput {
+		t.Fatalf("ReadAll = %q, %v", data, err)
+	}
+	if got := r.Sum(nil); !bytes.Equal(got, ioDigest(ioInput)) {
+		t.Errorf("Sum = %x, want %x", got, ioDigest(ioInput))
+	}
+
+	r = hash.NewVerifyingReader(iotest.DataErrReader(strings.NewReader(ioInput)), sha256.New(), ioDigest(ioInput))
+	if data, err := io.ReadAll(r); err != nil || string(data) != ioInput {
+		t.Errorf("verifying ReadAll = %q, %v", data, err)
+	}
+
+	for _, input := range []string{ioInput[1:], ioInput + ".", ""} {
+		r = hash.NewVerifyingReader(strings.NewReader(input), sha256.New(), ioDigest(ioInput))
+		if _, err := io.ReadAll(r); err != hash.ErrMismatch {
+			t.Errorf("verifying ReadAll of %q: %v, want ErrMismatch", input, err)
+		}
+		if n, err := r.Read(make([]byte, 1)); n != 0 || err != hash.ErrMismatch {
+			t.Errorf("Read after mismatch = %d, %v", n, err)
+		}
+	}
+
+	errRead := errors.New("read error")
+	r = hash.NewVerifyingReader(iotest.ErrReader(errRead), sha256.New(), ioDigest(ioInput))
+	if _, err := io.ReadAll(r); err != errRead {
+		t.Errorf("ReadAll = %v, want %v", err, errRead)
+	}
+}
+
+type closer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	var buf closer
+	w := hash.NewWriter(&buf, sha256.New())
+	io.WriteString(w, ioInput[:10])
+	io.WriteString(w, ioInput[10:])
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != ioInput || !buf.closed {
+		t.Errorf("buffer = %q, closed = %v", buf.String(), buf.closed)
+	}
+	if got := w.Sum(nil); !bytes.Equal(got, ioDigest(ioInput)) {
+		t.Errorf("Sum = %x, want %x", got, ioDigest(ioInput))
+	}
+
+	w = hash.NewVerifyingWriter(io.Discard, sha256.New(), ioDigest(ioInput))
+	io.WriteString(w, ioInput)
+	if err := w.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+	w = hash.NewVerifyingWriter(io.Discard, sha256.New(), ioDigest(ioInput))
+	io.WriteString(w, ioInput[1:])
+	if err := w.Close(); err != hash.ErrMismatch {
+		t.Errorf("Close = %v, want ErrMismatch", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	want := ioDigest(ioInput)
+	for _, size := range []int64{-1, int64(len(ioInput))} {
+		r := hash.Verify(iotest.HalfReader(strings.NewReader(ioInput)), sha256.New, want, size)
+		if data, err := io.ReadAll(r); err != nil || string(data) != ioInput {
+			t.Errorf("size %d: ReadAll = %q, %v", size, data, err)
+		}
+	}
+
+	var ierr *hash.IntegrityError
+	r := hash.Verify(strings.NewReader(ioInput), sha256.New, ioDigest("other"), -1)
+	if _, err := io.ReadAll(r); !errors.As(err, &ierr) || !bytes.Equal(ierr.Got, want) || ierr.Size != -1 {
+		t.Errorf("ReadAll with the wrong digest = %v", err)
+	} else if !errors.Is(err, hash.ErrMismatch) {
+		t.Errorf("errors.Is(%v, ErrMismatch) = false", err)
+	} else if msg := "hash: digest mismatch: got " + hex.EncodeToString(want) + ", want " + hex.EncodeToString(ioDigest("other")); err.Error() != msg {
+		t.Errorf("error = %q, want %q", err, msg)
+	}
+
+	// Longer data is detected before it is read to the end.
+	long := strings.NewReader(ioInput + strings.Repeat("x", 1<<20))
+	r = hash.Verify(long, sha256.New, want, int64(len(ioInput)))
+	data, err := io.ReadAll(r)
+	if !errors.As(err, &ierr) || ierr.Got != nil || ierr.Size != int64(len(ioInput)) {
+		t.Errorf("ReadAll of longer data = %v", err)
+	}
+	if len(data) > len(ioInput) || long.Len() < 1<<20-512 {
+		t.Errorf("read %d bytes of longer data, and left %d", len(data), long.Len())
+	}
+	if err != nil && !strings.Contains(err.Error(), "size mismatch") {
+		t.Errorf("error = %q", err)
+	}
+
+	r = hash.Verify(strings.NewReader(ioInput[1:]), sha256.New, want, int64(len(ioInput)))
+	if _, err := io.ReadAll(r); !errors.As(err, &ierr) || ierr.N != int64(len(ioInput)-1) {
+		t.Errorf("ReadAll of shorter data = %v", err)
+	} else if want := "hash: size mismatch: got 65 bytes, want 66"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
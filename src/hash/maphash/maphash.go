@@ -10,6 +10,7 @@
 //
 // The hash functions are not cryptographically secure.
 // (See crypto/sha256 and crypto/sha512 for cryptographic use.)
+// For stronger resistance to hash flooding, see MakeStrongSeed.
 package maphash
 
 // A Seed is a random value that selects the specific hash function
@@ -25,6 +26,8 @@ package maphash
 // or otherwise recreated in a different process.
 type Seed struct {
 	s uint64
+	// key is the SipHash key of a seed made by MakeStrongSeed.
+	key *[2]uint64
 }
 
 // Bytes returns the hash of b with the given seed.
@@ -44,11 +47,11 @@ func Bytes(seed Seed, b []byte) uint64 {
 	if len(b) > bufSize {
 		b = b[:len(b):len(b)] // merge len and cap calculations when reslicing
 		for len(b) > bufSize {
-			state = rthash(b[:bufSize], state)
+			state = seed.hash(b[:bufSize], state)
 			b = b[bufSize:]
 		}
 	}
-	return rthash(b, state)
+	return seed.hash(b, state)
 }
 
 // String returns the hash of s with the given seed.
@@ -65,10 +68,10 @@ func String(seed Seed, s string) uint64 {
 		panic("maphash: use of uninitialized Seed")
 	}
 	for len(s) > bufSize {
-		state = rthashString(s[:bufSize], state)
+		state = seed.hashString(s[:bufSize], state)
 		s = s[bufSize:]
 	}
-	return rthashString(s, state)
+	return seed.hashString(s, state)
 }
 
 // A Hash computes a seeded hash of a byte sequence.
@@ -154,7 +157,7 @@ func (h *Hash) Write(b []byte) (int, error) {
 	if len(b) > bufSize {
 		h.initSeed()
 		for len(b) > bufSize {
-			h.state.s = rthash(b[:bufSize], h.state.s)
+			h.state.s = h.seed.hash(b[:bufSize], h.state.s)
 			b = b[bufSize:]
 		}
 	}
@@ -181,7 +184,7 @@ func (h *Hash) WriteString(s string) (int, error) {
 	if len(s) > bufSize {
 		h.initSeed()
 		for len(s) > bufSize {
-			h.state.s = rthashString(s[:bufSize], h.state.s)
+			h.state.s = h.seed.hashString(s[:bufSize], h.state.s)
 			s = s[bufSize:]
 		}
 	}
@@ -196,8 +199,8 @@ func (h *Hash) Seed() Seed {
 	return h.seed
 }
 
-// SetSeed sets h to use seed, which must have been returned by MakeSeed
-// or by another Hash's Seed method.
+// SetSeed sets h to use seed, which must have been returned by MakeSeed,
+// MakeStrongSeed, or another Hash's Seed method.
 // Two Hash objects with the same seed behave identically.
 // Two Hash objects with different seeds will very likely behave differently.
 // Any bytes added to h before this call will be discarded.
@@ -224,7 +227,7 @@ func (h *Hash) flush() {
 		panic("maphash: flush of partially full buffer")
 	}
 	h.initSeed()
-	h.state.s = rthash(h.buf[:h.n], h.state.s)
+	h.state.s = h.seed.hash(h.buf[:h.n], h.state.s)
 	h.n = 0
 }
 
@@ -237,7 +240,7 @@ func (h *Hash) flush() {
 // by using bit masking, shifting, or modular arithmetic.
 func (h *Hash) Sum64() uint64 {
 	h.initSeed()
-	return rthash(h.buf[:h.n], h.state.s)
+	return h.seed.hash(h.buf[:h.n], h.state.s)
 }
 
 // MakeSeed returns a new random seed.
@@ -22,12 +22,21 @@ func rthashString(s string, state uint64) uint64 {
 	return rthash([]byte(s), state)
 }
 
+func stringBytes(s string) []byte {
+	return []byte(s)
+}
+
 func randUint64() uint64 {
 	buf := make([]byte, 8)
 	_, _ = rand.Read(buf)
 	return leUint64(buf)
 }
 
+func randRead(b []byte) bool {
+	_, err := rand.Read(b)
+	return err == nil
+}
+
 // This is a port of wyhash implementation in runtime/hash64.go,
 // without using unsafe for purego.
 
@@ -96,9 +105,3 @@ func leUint32(b []byte) uint32 {
 	_ = b[3] // bounds check hint to compiler; see golang.org/issue/14808
 	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
 }
-
-func leUint64(b []byte) uint64 {
-	_ = b[7] // bounds check hint to compiler; see golang.org/issue/14808
-	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
-		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
-}
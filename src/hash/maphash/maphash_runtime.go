@@ -13,6 +13,9 @@ import (
 //go:linkname runtime_fastrand64 runtime.fastrand64
 func runtime_fastrand64() uint64
 
+//go:linkname runtime_readRandom runtime.readRandom
+func runtime_readRandom(r []byte) int
+
 //go:linkname runtime_memhash runtime.memhash
 //go:noescape
 func runtime_memhash(p unsafe.Pointer, seed, s uintptr) uintptr
@@ -34,10 +37,20 @@ func rthash(buf []byte, seed uint64) uint64 {
 }
 
 func rthashString(s string, state uint64) uint64 {
-	buf := unsafe.Slice(unsafe.StringData(s), len(s))
-	return rthash(buf, state)
+	return rthash(stringBytes(s), state)
+}
+
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
 
 func randUint64() uint64 {
 	return runtime_fastrand64()
 }
+
+// randRead fills b with fresh random bytes from the operating system, and
+// reports whether it succeeded. Unlike randUint64, its output is suitable
+// for keys.
+func randRead(b []byte) bool {
+	return runtime_readRandom(b) == len(b)
+}
@@ -131,6 +131,65 @@ func TestHashHighBytes(t *testing.T) {
 	}
 }
 
+func TestSipHash(t *testing.T) {
+	// Test vectors from the SipHash reference implementation, for the key
+	// 00 01 ... 0f and the message 00 01 ... n-1. sipHash takes the first
+	// eight bytes of the message as the chaining state.
+	key := &[2]uint64{0x0706050403020100, 0x0f0e0d0c0b0a0908}
+	msg := make([]byte, 63)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, tt := range []struct {
+		n    int
+		want uint64
+	}{
+		{8, 0x93f5f5799a932462},
+		{9, 0x9e0082df0ba9e4b0},
+		{15, 0xa129ca6149be45e5},
+		{16, 0x3f2acc7f57c29bdb},
+		{17, 0x699ae9f52cbe4794},
+		{63, 0x958a324ceb064572},
+	} {
+		if got := sipHash(key, leUint64(msg), msg[8:tt.n]); got != tt.want {
+			t.Errorf("SipHash of %d bytes = %#x, want %#x", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestStrongSeed(t *testing.T) {
+	seed := MakeStrongSeed()
+	if seed.key == nil {
+		t.Fatal("MakeStrongSeed returned a seed without a SipHash key")
+	}
+	b := bytes.Repeat([]byte("abcdefg"), 100)
+	for _, n := range []int{0, 1, 8, bufSize, bufSize + 1, len(b)} {
+		var h Hash
+		h.SetSeed(seed)
+		for _, c := range b[:n] {
+			h.WriteByte(c)
+		}
+		want := h.Sum64()
+		if got := Bytes(seed, b[:n]); got != want {
+			t.Errorf("Bytes of %d bytes = %#x, want %#x", n, got, want)
+		}
+		if got := String(seed, string(b[:n])); got != want {
+			t.Errorf("String of %d bytes = %#x, want %#x", n, got, want)
+		}
+		if got := Bytes(MakeStrongSeed(), b[:n]); got == want {
+			t.Errorf("different strong seeds hash %d bytes to the same value", n)
+		}
+	}
+	var h Hash
+	h.SetSeed(seed)
+	if h.Seed() != seed {
+		t.Errorf("Seed did not return the strong seed set by SetSeed")
+	}
+	if h.Sum64() == seed.s {
+		t.Errorf("empty strong hash returned the seed")
+	}
+}
+
 func TestRepeat(t *testing.T) {
 	h1 := new(Hash)
 	h1.WriteString("testing")
@@ -245,6 +304,19 @@ func benchmarkSize(b *testing.B, size int) {
 	})
 }
 
+func BenchmarkStrongBytes(b *testing.B) {
+	seed := MakeStrongSeed()
+	for _, size := range []int{8, 64, 1024} {
+		buf := make([]byte, size)
+		b.Run(fmt.Sprint("n=", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				Bytes(seed, buf)
+			}
+		})
+	}
+}
+
 func BenchmarkHash(b *testing.B) {
 	sizes := []int{4, 8, 16, 32, 64, 256, 320, 1024, 4096, 16384}
 	for _, size := range sizes {
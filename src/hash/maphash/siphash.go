@@ -0,0 +1,114 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package maphash
+
+import "math/bits"
+
+// MakeStrongSeed returns a new seed, read from the operating system's random
+// number generator, that selects a keyed SipHash-2-4 function instead of the
+// runtime's hash function.
+//
+// The hash functions selected by MakeSeed resist collisions chosen by an
+// adversary only as long as the adversary cannot observe hash values or
+// learn about the seed from the behavior of a program. SipHash is a
+// pseudorandom function, so no set of inputs and hash values reveals
+// anything useful about the 128-bit key. It is several times slower, and
+// is meant for hash tables keyed by attacker-controlled data that must
+// withstand denial of service attacks even when hash values can leak.
+//
+// MakeStrongSeed panics if the random number generator fails. Seeds made by
+// it can be used anywhere a Seed made by MakeSeed can.
+func MakeStrongSeed() Seed {
+	var b [24]byte
+	for {
+		if !randRead(b[:]) {
+			panic("maphash: failed to read random seed")
+		}
+		s := leUint64(b[16:])
+		if s != 0 {
+			return Seed{s: s, key: &[2]uint64{leUint64(b[:8]), leUint64(b[8:16])}}
+		}
+	}
+}
+
+// hash returns the hash of buf chained from state, with the function
+// selected by seed.
+func (seed Seed) hash(buf []byte, state uint64) uint64 {
+	if seed.key != nil {
+		return sipHash(seed.key, state, buf)
+	}
+	return rthash(buf, state)
+}
+
+func (seed Seed) hashString(s string, state uint64) uint64 {
+	if seed.key != nil {
+		return sipHash(seed.key, state, stringBytes(s))
+	}
+	return rthashString(s, state)
+}
+
+// sipHash returns the SipHash-2-4 of the little-endian encoding of state
+// followed by p, with the given key.
+func sipHash(key *[2]uint64, state uint64, p []byte) uint64 {
+	k0, k1 := key[0], key[1]
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	n := 8 + len(p)
+	m := state
+	for {
+		v3 ^= m
+		v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+		v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+		v0 ^= m
+		if len(p) < 8 {
+			break
+		}
+		m = leUint64(p)
+		p = p[8:]
+	}
+
+	m = uint64(n) << 56
+	for i, b := range p {
+		m |= uint64(b) << (8 * i)
+	}
+	v3 ^= m
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0 ^= m
+
+	v2 ^= 0xff
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+func sipRound(v0, v1, v2, v3 uint64) (uint64, uint64, uint64, uint64) {
+	v0 += v1
+	v1 = bits.RotateLeft64(v1, 13)
+	v1 ^= v0
+	v0 = bits.RotateLeft64(v0, 32)
+	v2 += v3
+	v3 = bits.RotateLeft64(v3, 16)
+	v3 ^= v2
+	v0 += v3
+	v3 = bits.RotateLeft64(v3, 21)
+	v3 ^= v0
+	v2 += v1
+	v1 = bits.RotateLeft64(v1, 17)
+	v1 ^= v2
+	v2 = bits.RotateLeft64(v2, 32)
+	return v0, v1, v2, v3
+}
+
+func leUint64(b []byte) uint64 {
+	_ = b[7] // bounds check hint to compiler; see golang.org/issue/14808
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hash_test
+
+import (
+	"bytes"
+	"crypto/blake2b"
+	"crypto/blake2s"
+	"crypto/sha3"
+	"hash"
+	"io"
+	"testing"
+)
+
+var (
+	_ hash.XOF = sha3.ShakeHash(nil)
+	_ hash.XOF = blake2b.XOF(nil)
+	_ hash.XOF = blake2s.XOF(nil)
+)
+
+func readXOF(x hash.XOF, data []byte, n int) []byte {
+	x.Reset()
+	x.Write(data)
+	out := make([]byte, n)
+	if _, err := io.ReadFull(x, out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func TestXOF(t *testing.T) {
+	data := []byte("The tunneling gopher digs downwards")
+
+	want := make([]byte, 100)
+	sha3.ShakeSum256(want, data)
+	if got := readXOF(sha3.NewShake256(), data, 100); !bytes.Equal(got, want) {
+		t.Errorf("SHAKE256 = %x, want %x", got, want)
+	}
+
+	x, err := blake2b.NewXOF(blake2b.OutputLengthUnknown, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reset makes the output independent of the previous input.
+	x.Write([]byte("ignored"))
+	if a, b := readXOF(x, data, 100), readXOF(x, data, 100); !bytes.Equal(a, b) {
+		t.Errorf("BLAKE2Xb output after Reset = %x, want %x", b, a)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cryptometrics provides the table of runtime/metrics counters that
+// report how the crypto packages use the cryptographic backend, for use by
+// runtime, runtime/metrics, and crypto/internal/boring.
+package cryptometrics
+
+// Algorithm identifiers, indexing Algorithms.
+const (
+	AESCMAC = iota
+	AESGCM
+	AESKW
+	AES
+	BLAKE2b
+	BLAKE2s
+	ECDH
+	ECDSA
+	HMAC
+	RSA
+	SHA1
+	SHA224
+	SHA256
+	SHA3_224
+	SHA3_256
+	SHA3_384
+	SHA3_512
+	SHA384
+	SHA512_224
+	SHA512_256
+	SHA512
+	SHAKE128
+	SHAKE256
+	NumAlgorithms
+)
+
+// An Algorithm describes an algorithm for which counters are kept.
+type Algorithm struct {
+	Name    string // name in metric names ("sha256")
+	Display string // name in metric descriptions ("SHA-256")
+}
+
+// Algorithms is the table of algorithms, sorted by metric name.
+//
+// Note: After adding entries to this table, run 'go generate runtime/metrics'
+// to update the runtime/metrics doc comment.
+// (Otherwise the runtime/metrics test will fail.)
+var Algorithms = [NumAlgorithms]Algorithm{
+	AESCMAC:    {"aes-cmac", "AES-CMAC"},
+	AESGCM:     {"aes-gcm", "AES-GCM"},
+	AESKW:      {"aes-kw", "AES-KW"},
+	AES:        {"aes", "AES"},
+	BLAKE2b:    {"blake2b", "BLAKE2b"},
+	BLAKE2s:    {"blake2s", "BLAKE2s"},
+	ECDH:       {"ecdh", "ECDH"},
+	ECDSA:      {"ecdsa", "ECDSA"},
+	HMAC:       {"hmac", "HMAC"},
+	RSA:        {"rsa", "RSA"},
+	SHA1:       {"sha1", "SHA-1"},
+	SHA224:     {"sha224", "SHA-224"},
+	SHA256:     {"sha256", "SHA-256"},
+	SHA3_224:   {"sha3-224", "SHA3-224"},
+	SHA3_256:   {"sha3-256", "SHA3-256"},
+	SHA3_384:   {"sha3-384", "SHA3-384"},
+	SHA3_512:   {"sha3-512", "SHA3-512"},
+	SHA384:     {"sha384", "SHA-384"},
+	SHA512_224: {"sha512-224", "SHA-512/224"},
+	SHA512_256: {"sha512-256", "SHA-512/256"},
+	SHA512:     {"sha512", "SHA-512"},
+	SHAKE128:   {"shake128", "SHAKE128"},
+	SHAKE256:   {"shake256", "SHAKE256"},
+}
+
+// Counter identifiers, indexing Counters.
+const (
+	Failures = iota
+	Fallbacks
+	Operations
+	Processed
+	NumCounters
+)
+
+// A Counter describes one of the counters kept for every algorithm.
+type Counter struct {
+	Name string // last element of the metric name, including the unit
+
+	// The metric description is DescPrefix, followed by the display name
+	// of the algorithm, followed by DescSuffix.
+	DescPrefix, DescSuffix string
+}
+
+// Counters is the table of counters, sorted by name.
+var Counters = [NumCounters]Counter{
+	Failures: {"failures:calls", "The number of ",
+		" operations that the cryptographic backend failed or rejected, " +
+			"including failed signature verifications and decryptions."},
+	Fallbacks: {"fallbacks:calls", "The number of ",
+		" operations that were performed by the pure Go implementation " +
+			"while a cryptographic backend was in use, because the backend " +
+			"does not support the requested parameters."},
+	Operations: {"operations:calls", "The number of ",
+		" operations performed by the cryptographic backend, counting each " +
+			"digest or MAC computed, each call to encrypt or decrypt data, " +
+			"and each public key operation."},
+	Processed: {"processed:bytes", "The number of bytes of input processed by ",
+		" operations in the cryptographic backend."},
+}
+
+// Name returns the runtime/metrics name of a counter for an algorithm.
+func Name(alg, counter int) string {
+	return "/crypto/backend/" + Algorithms[alg].Name + "/" + Counters[counter].Name
+}
+
+// Description returns the runtime/metrics description of a counter for an
+// algorithm.
+func Description(alg, counter int) string {
+	c := &Counters[counter]
+	return c.DescPrefix + Algorithms[alg].Display + c.DescSuffix
+}
@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cryptometrics_test
+
+import (
+	"internal/cryptometrics"
+	"testing"
+)
+
+func TestSorted(t *testing.T) {
+	last := ""
+	for alg := range cryptometrics.Algorithms {
+		for c := range cryptometrics.Counters {
+			name := cryptometrics.Name(alg, c)
+			if name <= last {
+				t.Errorf("names not sorted: %s then %s", last, name)
+			}
+			last = name
+		}
+	}
+}
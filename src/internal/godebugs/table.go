@@ -25,7 +25,14 @@ type Info struct {
 // Note: After adding entries to this table, update the list in doc/godebug.md as well.
 // (Otherwise the test in this package will fail.)
 var All = []Info{
+	{Name: "boringbuffer", Package: "crypto", Opaque: true},
+	{Name: "boringcast", Package: "crypto", Opaque: true},
+	{Name: "boringfallback", Package: "crypto", Opaque: true},
+	{Name: "cryptokeyguard", Package: "crypto", Opaque: true},
+	{Name: "cryptosummary", Package: "crypto", Opaque: true},
+	{Name: "cryptotrace", Package: "crypto", Opaque: true},
 	{Name: "execerrdot", Package: "os/exec"},
+	{Name: "fips140", Package: "crypto", Opaque: true},
 	{Name: "http2client", Package: "net/http"},
 	{Name: "http2debug", Package: "net/http", Opaque: true},
 	{Name: "http2server", Package: "net/http"},
@@ -0,0 +1,9 @@
+// Code generated by mkconsts.go. DO NOT EDIT.
+
+//go:build !goexperiment.darwincrypto
+// +build !goexperiment.darwincrypto
+
+package goexperiment
+
+const DarwinCrypto = false
+const DarwinCryptoInt = 0
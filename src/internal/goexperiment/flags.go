@@ -105,4 +105,19 @@ type Flags struct {
 	// LoopVar changes loop semantics so that each iteration gets its own
 	// copy of the iteration variable.
 	LoopVar bool
+
+	// OpenSSLCrypto routes the crypto packages through the system
+	// OpenSSL 3 libcrypto, loaded at run time, instead of BoringCrypto.
+	// It has no effect when BoringCrypto is also enabled.
+	OpenSSLCrypto bool
+
+	// CNGCrypto routes the crypto packages through Windows Cryptography
+	// API: Next Generation (CNG) on windows. It has no effect when
+	// BoringCrypto or OpenSSLCrypto is also enabled.
+	CNGCrypto bool
+
+	// DarwinCrypto routes the crypto packages through CommonCrypto and
+	// Security.framework on darwin. It has no effect when BoringCrypto,
+	// OpenSSLCrypto or CNGCrypto is also enabled.
+	DarwinCrypto bool
 }
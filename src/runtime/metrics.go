@@ -7,6 +7,7 @@ package runtime
 // Metrics implementation exported to runtime/metrics.
 
 import (
+	"internal/cryptometrics"
 	"internal/godebugs"
 	"unsafe"
 )
@@ -426,6 +427,13 @@ func initMetrics() {
 		}
 	}
 
+	for alg := range cryptometrics.Algorithms {
+		for counter := range cryptometrics.Counters {
+			metrics[cryptometrics.Name(alg, counter)] = metricData{compute: compute0}
+		}
+	}
+	metrics["/crypto/rsa/limited:calls"] = metricData{compute: compute0}
+
 	metricsInit = true
 }
 
@@ -443,6 +451,22 @@ func (f metricReader) compute(_ *statAggregate, out *metricValue) {
 
 //go:linkname godebug_registerMetric internal/godebug.registerMetric
 func godebug_registerMetric(name string, read func() uint64) {
+	registerMetricReader(name, read)
+}
+
+//go:linkname boring_registerMetric crypto/internal/boring.registerMetric
+func boring_registerMetric(name string, read func() uint64) {
+	registerMetricReader(name, read)
+}
+
+//go:linkname rsa_registerMetric crypto/rsa.registerMetric
+func rsa_registerMetric(name string, read func() uint64) {
+	registerMetricReader(name, read)
+}
+
+// registerMetricReader sets the function that reads the uint64 metric name,
+// which must already be known to initMetrics.
+func registerMetricReader(name string, read func() uint64) {
 	metricsLock()
 	initMetrics()
 	d, ok := metrics[name]
@@ -4,7 +4,10 @@
 
 package metrics
 
-import "internal/godebugs"
+import (
+	"internal/cryptometrics"
+	"internal/godebugs"
+)
 
 // Description describes a runtime metric.
 type Description struct {
@@ -175,6 +178,13 @@ var allDesc = []Description{
 		Kind:       KindFloat64,
 		Cumulative: true,
 	},
+	{
+		Name: "/crypto/rsa/limited:calls",
+		Description: "The number of RSA private key operations rejected by the " +
+			"crypto/rsa.Limiter installed with crypto/rsa.SetLimiter.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
 	{
 		Name:        "/gc/cycles/automatic:gc-cycles",
 		Description: "Count of completed GC cycles generated by the Go runtime.",
@@ -380,13 +390,33 @@ var allDesc = []Description{
 }
 
 func init() {
-	// Insert all the the non-default-reporting GODEBUGs into the table,
+	// Insert the cryptographic backend counters into the table,
 	// preserving the overall sort order.
 	i := 0
+	for i < len(allDesc) && allDesc[i].Name < "/crypto/" {
+		i++
+	}
+	more := make([]Description, i, len(allDesc)+len(cryptometrics.Algorithms)*len(cryptometrics.Counters))
+	copy(more, allDesc)
+	for alg := range cryptometrics.Algorithms {
+		for counter := range cryptometrics.Counters {
+			more = append(more, Description{
+				Name:        cryptometrics.Name(alg, counter),
+				Description: cryptometrics.Description(alg, counter),
+				Kind:        KindUint64,
+				Cumulative:  true,
+			})
+		}
+	}
+	allDesc = append(more, allDesc[i:]...)
+
+	// Insert all the the non-default-reporting GODEBUGs into the table,
+	// preserving the overall sort order.
+	i = 0
 	for i < len(allDesc) && allDesc[i].Name < "/godebug/" {
 		i++
 	}
-	more := make([]Description, i, len(allDesc)+len(godebugs.All))
+	more = make([]Description, i, len(allDesc)+len(godebugs.All))
 	copy(more, allDesc)
 	for _, info := range godebugs.All {
 		if !info.Opaque {
@@ -138,6 +138,449 @@ Below is the full list of supported metrics, ordered lexicographically.
 		to system CPU time measurements. Compare only with other
 		/cpu/classes metrics.
 
+	/crypto/backend/aes-cmac/failures:calls
+		The number of AES-CMAC operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/aes-cmac/fallbacks:calls
+		The number of AES-CMAC operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/aes-cmac/operations:calls
+		The number of AES-CMAC operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/aes-cmac/processed:bytes
+		The number of bytes of input processed by AES-CMAC operations in
+		the cryptographic backend.
+
+	/crypto/backend/aes-gcm/failures:calls
+		The number of AES-GCM operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/aes-gcm/fallbacks:calls
+		The number of AES-GCM operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/aes-gcm/operations:calls
+		The number of AES-GCM operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/aes-gcm/processed:bytes
+		The number of bytes of input processed by AES-GCM operations in
+		the cryptographic backend.
+
+	/crypto/backend/aes-kw/failures:calls
+		The number of AES-KW operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/aes-kw/fallbacks:calls
+		The number of AES-KW operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/aes-kw/operations:calls
+		The number of AES-KW operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/aes-kw/processed:bytes
+		The number of bytes of input processed by AES-KW operations in
+		the cryptographic backend.
+
+	/crypto/backend/aes/failures:calls
+		The number of AES operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/aes/fallbacks:calls
+		The number of AES operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/aes/operations:calls
+		The number of AES operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/aes/processed:bytes
+		The number of bytes of input processed by AES operations in the
+		cryptographic backend.
+
+	/crypto/backend/blake2b/failures:calls
+		The number of BLAKE2b operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/blake2b/fallbacks:calls
+		The number of BLAKE2b operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/blake2b/operations:calls
+		The number of BLAKE2b operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/blake2b/processed:bytes
+		The number of bytes of input processed by BLAKE2b operations in
+		the cryptographic backend.
+
+	/crypto/backend/blake2s/failures:calls
+		The number of BLAKE2s operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/blake2s/fallbacks:calls
+		The number of BLAKE2s operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/blake2s/operations:calls
+		The number of BLAKE2s operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/blake2s/processed:bytes
+		The number of bytes of input processed by BLAKE2s operations in
+		the cryptographic backend.
+
+	/crypto/backend/ecdh/failures:calls
+		The number of ECDH operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/ecdh/fallbacks:calls
+		The number of ECDH operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/ecdh/operations:calls
+		The number of ECDH operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/ecdh/processed:bytes
+		The number of bytes of input processed by ECDH operations in the
+		cryptographic backend.
+
+	/crypto/backend/ecdsa/failures:calls
+		The number of ECDSA operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/ecdsa/fallbacks:calls
+		The number of ECDSA operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/ecdsa/operations:calls
+		The number of ECDSA operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/ecdsa/processed:bytes
+		The number of bytes of input processed by ECDSA operations in
+		the cryptographic backend.
+
+	/crypto/backend/hmac/failures:calls
+		The number of HMAC operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/hmac/fallbacks:calls
+		The number of HMAC operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/hmac/operations:calls
+		The number of HMAC operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/hmac/processed:bytes
+		The number of bytes of input processed by HMAC operations in the
+		cryptographic backend.
+
+	/crypto/backend/rsa/failures:calls
+		The number of RSA operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/rsa/fallbacks:calls
+		The number of RSA operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/rsa/operations:calls
+		The number of RSA operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/rsa/processed:bytes
+		The number of bytes of input processed by RSA operations in the
+		cryptographic backend.
+
+	/crypto/backend/sha1/failures:calls
+		The number of SHA-1 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha1/fallbacks:calls
+		The number of SHA-1 operations that were performed by the pure
+		Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha1/operations:calls
+		The number of SHA-1 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha1/processed:bytes
+		The number of bytes of input processed by SHA-1 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha224/failures:calls
+		The number of SHA-224 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha224/fallbacks:calls
+		The number of SHA-224 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha224/operations:calls
+		The number of SHA-224 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha224/processed:bytes
+		The number of bytes of input processed by SHA-224 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha256/failures:calls
+		The number of SHA-256 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha256/fallbacks:calls
+		The number of SHA-256 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha256/operations:calls
+		The number of SHA-256 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha256/processed:bytes
+		The number of bytes of input processed by SHA-256 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha3-224/failures:calls
+		The number of SHA3-224 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha3-224/fallbacks:calls
+		The number of SHA3-224 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha3-224/operations:calls
+		The number of SHA3-224 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha3-224/processed:bytes
+		The number of bytes of input processed by SHA3-224 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha3-256/failures:calls
+		The number of SHA3-256 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha3-256/fallbacks:calls
+		The number of SHA3-256 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha3-256/operations:calls
+		The number of SHA3-256 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha3-256/processed:bytes
+		The number of bytes of input processed by SHA3-256 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha3-384/failures:calls
+		The number of SHA3-384 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha3-384/fallbacks:calls
+		The number of SHA3-384 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha3-384/operations:calls
+		The number of SHA3-384 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha3-384/processed:bytes
+		The number of bytes of input processed by SHA3-384 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha3-512/failures:calls
+		The number of SHA3-512 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha3-512/fallbacks:calls
+		The number of SHA3-512 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha3-512/operations:calls
+		The number of SHA3-512 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha3-512/processed:bytes
+		The number of bytes of input processed by SHA3-512 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha384/failures:calls
+		The number of SHA-384 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha384/fallbacks:calls
+		The number of SHA-384 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha384/operations:calls
+		The number of SHA-384 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha384/processed:bytes
+		The number of bytes of input processed by SHA-384 operations in
+		the cryptographic backend.
+
+	/crypto/backend/sha512-224/failures:calls
+		The number of SHA-512/224 operations that the cryptographic
+		backend failed or rejected, including failed signature
+		verifications and decryptions.
+
+	/crypto/backend/sha512-224/fallbacks:calls
+		The number of SHA-512/224 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha512-224/operations:calls
+		The number of SHA-512/224 operations performed by the
+		cryptographic backend, counting each digest or MAC computed,
+		each call to encrypt or decrypt data, and each public key
+		operation.
+
+	/crypto/backend/sha512-224/processed:bytes
+		The number of bytes of input processed by SHA-512/224 operations
+		in the cryptographic backend.
+
+	/crypto/backend/sha512-256/failures:calls
+		The number of SHA-512/256 operations that the cryptographic
+		backend failed or rejected, including failed signature
+		verifications and decryptions.
+
+	/crypto/backend/sha512-256/fallbacks:calls
+		The number of SHA-512/256 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha512-256/operations:calls
+		The number of SHA-512/256 operations performed by the
+		cryptographic backend, counting each digest or MAC computed,
+		each call to encrypt or decrypt data, and each public key
+		operation.
+
+	/crypto/backend/sha512-256/processed:bytes
+		The number of bytes of input processed by SHA-512/256 operations
+		in the cryptographic backend.
+
+	/crypto/backend/sha512/failures:calls
+		The number of SHA-512 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/sha512/fallbacks:calls
+		The number of SHA-512 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/sha512/operations:calls
+		The number of SHA-512 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/sha512/processed:bytes
+		The number of bytes of input processed by SHA-512 operations in
+		the cryptographic backend.
+
+	/crypto/backend/shake128/failures:calls
+		The number of SHAKE128 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/shake128/fallbacks:calls
+		The number of SHAKE128 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/shake128/operations:calls
+		The number of SHAKE128 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/shake128/processed:bytes
+		The number of bytes of input processed by SHAKE128 operations in
+		the cryptographic backend.
+
+	/crypto/backend/shake256/failures:calls
+		The number of SHAKE256 operations that the cryptographic backend
+		failed or rejected, including failed signature verifications and
+		decryptions.
+
+	/crypto/backend/shake256/fallbacks:calls
+		The number of SHAKE256 operations that were performed by the
+		pure Go implementation while a cryptographic backend was in use,
+		because the backend does not support the requested parameters.
+
+	/crypto/backend/shake256/operations:calls
+		The number of SHAKE256 operations performed by the cryptographic
+		backend, counting each digest or MAC computed, each call to
+		encrypt or decrypt data, and each public key operation.
+
+	/crypto/backend/shake256/processed:bytes
+		The number of bytes of input processed by SHAKE256 operations in
+		the cryptographic backend.
+
+	/crypto/rsa/limited:calls
+		The number of RSA private key operations rejected by the
+		crypto/rsa.Limiter installed with crypto/rsa.SetLimiter.
+
 	/gc/cycles/automatic:gc-cycles
 		Count of completed GC cycles generated by the Go runtime.
 
@@ -32,6 +32,13 @@ func usleep(usec uint32) {
 //go:noescape
 func getRandomData(r []byte)
 
+// readRandom fills r with random bytes from crypto.getRandomValues, which
+// throws if it fails, and returns len(r).
+func readRandom(r []byte) int {
+	getRandomData(r)
+	return len(r)
+}
+
 func goenvs() {
 	goenvs_unix()
 }
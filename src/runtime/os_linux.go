@@ -368,10 +368,24 @@ func getRandomData(r []byte) {
 		extendRandom(r, n)
 		return
 	}
+	extendRandom(r, readRandom(r))
+}
+
+// readRandom reads fresh random bytes from /dev/urandom into r, and returns
+// how many it read, which is negative if reading failed. Unlike getRandomData,
+// it never returns the bytes of startupRandomData, which are the same on
+// every call.
+func readRandom(r []byte) int {
+	if len(r) == 0 {
+		return 0
+	}
 	fd := open(&urandom_dev[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return -1
+	}
 	n := read(fd, unsafe.Pointer(&r[0]), int32(len(r)))
 	closefd(fd)
-	extendRandom(r, int(n))
+	return int(n)
 }
 
 func goenvs() {
@@ -352,6 +352,24 @@ func getRandomData(r []byte) {
 	}
 }
 
+var random_dev = []byte("/dev/random\x00")
+
+// readRandom reads random bytes from /dev/random into r, and returns how
+// many it read, which is negative if reading failed. Unlike getRandomData,
+// which derives its output from the time, it is suitable for keys.
+func readRandom(r []byte) int {
+	if len(r) == 0 {
+		return 0
+	}
+	fd := open(&random_dev[0], _OREAD, 0)
+	if fd < 0 {
+		return -1
+	}
+	n := read(fd, unsafe.Pointer(&r[0]), int32(len(r)))
+	closefd(fd)
+	return int(n)
+}
+
 func initsig(preinit bool) {
 	if !preinit {
 		notify(unsafe.Pointer(abi.FuncPCABI0(sigtramp)))
@@ -173,11 +173,23 @@ func usleep(usec uint32) {
 }
 
 func getRandomData(r []byte) {
-	if random_get(unsafe.Pointer(&r[0]), size(len(r))) != 0 {
+	if readRandom(r) < 0 {
 		throw("random_get failed")
 	}
 }
 
+// readRandom fills r with random bytes from random_get, and returns len(r),
+// or -1 if random_get failed.
+func readRandom(r []byte) int {
+	if len(r) == 0 {
+		return 0
+	}
+	if random_get(unsafe.Pointer(&r[0]), size(len(r))) != 0 {
+		return -1
+	}
+	return len(r)
+}
+
 func goenvs() {
 	// arguments
 	var argc size
@@ -621,11 +621,19 @@ func initWine(k32 uintptr) {
 
 //go:nosplit
 func getRandomData(r []byte) {
-	n := 0
-	if stdcall2(_RtlGenRandom, uintptr(unsafe.Pointer(&r[0])), uintptr(len(r)))&0xff != 0 {
-		n = len(r)
+	extendRandom(r, readRandom(r))
+}
+
+// readRandom fills r with random bytes from RtlGenRandom, and returns
+// len(r), or -1 if RtlGenRandom failed.
+func readRandom(r []byte) int {
+	if len(r) == 0 {
+		return 0
+	}
+	if stdcall2(_RtlGenRandom, uintptr(unsafe.Pointer(&r[0])), uintptr(len(r)))&0xff == 0 {
+		return -1
 	}
-	extendRandom(r, n)
+	return len(r)
 }
 
 func goenvs() {
@@ -3645,6 +3645,10 @@ func goexit0(gp *g) {
 	gp.param = nil
 	gp.labels = nil
 	gp.timer = nil
+	// newproc1 reuses dead gs without clearing the FIPS service indicator,
+	// so clear it here for crypto/fips.Approved to start unset in every
+	// new goroutine.
+	gp.fipsIndicator = 0
 
 	if gcBlackenEnabled != 0 && gp.gcAssistBytes > 0 {
 		// Flush assist credit to the global pool. This gives
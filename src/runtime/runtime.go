@@ -160,3 +160,17 @@ func writeErrStr(s string) {
 var auxv []uintptr
 
 func getAuxv() []uintptr { return auxv } // accessed from x/sys/cpu; see issue 57336
+
+// The FIPS service indicator of crypto/internal/boring is kept per
+// goroutine, so that it reflects the operations of the goroutine that
+// reads it.
+
+//go:linkname boring_getIndicator crypto/internal/boring.getIndicator
+func boring_getIndicator() uint8 {
+	return getg().fipsIndicator
+}
+
+//go:linkname boring_setIndicator crypto/internal/boring.setIndicator
+func boring_setIndicator(indicator uint8) {
+	getg().fipsIndicator = indicator
+}
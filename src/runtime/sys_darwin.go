@@ -115,6 +115,23 @@ func crypto_x509_syscall(fn, a1, a2, a3, a4, a5 uintptr, f1 float64) (r1 uintptr
 }
 func syscall_x509()
 
+// crypto_backend_syscall is used in crypto/internal/backend/darwin to call into
+// CommonCrypto, Security.framework and CF.
+
+//go:linkname crypto_backend_syscall crypto/internal/backend/darwin.syscall
+//go:nosplit
+func crypto_backend_syscall(fn, a1, a2, a3, a4, a5, a6, a7 uintptr) (r1 uintptr) {
+	args := struct {
+		fn, a1, a2, a3, a4, a5, a6, a7 uintptr
+		r1                             uintptr
+	}{fn, a1, a2, a3, a4, a5, a6, a7, r1}
+	entersyscall()
+	libcCall(unsafe.Pointer(abi.FuncPCABI0(syscall_crypto)), unsafe.Pointer(&args))
+	exitsyscall()
+	return args.r1
+}
+func syscall_crypto()
+
 // The *_trampoline functions convert from the Go calling convention to the C calling convention
 // and then call the underlying libc function.  They are defined in sys_darwin_$ARCH.s.
 
@@ -782,3 +782,27 @@ TEXT runtime·syscall_x509(SB),NOSPLIT,$16
 
 	XORL	AX, AX        // no error (it's ignored anyway)
 	RET
+
+// syscall_crypto is for crypto/internal/backend/darwin. It is like
+// syscall_x509 but takes 7 uintptrs and no float64. The seventh
+// argument is passed on the stack.
+TEXT runtime·syscall_crypto(SB),NOSPLIT,$32
+	MOVQ	(0*8)(DI), R11// fn
+	MOVQ	(7*8)(DI), AX // a7
+	MOVQ	AX, 0(SP)
+	MOVQ	(2*8)(DI), SI // a2
+	MOVQ	(3*8)(DI), DX // a3
+	MOVQ	(4*8)(DI), CX // a4
+	MOVQ	(5*8)(DI), R8 // a5
+	MOVQ	(6*8)(DI), R9 // a6
+	MOVQ	DI, 16(SP)
+	MOVQ	(1*8)(DI), DI // a1
+	XORL	AX, AX	      // vararg: say "no float args"
+
+	CALL	R11
+
+	MOVQ	16(SP), DI
+	MOVQ	AX, (8*8)(DI) // r1
+
+	XORL	AX, AX        // no error (it's ignored anyway)
+	RET